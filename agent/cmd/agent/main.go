@@ -3,8 +3,11 @@ package main
 import (
 	"flag"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -12,6 +15,7 @@ import (
 	"time"
 
 	"github.com/user/server-ops-agent/config"
+	"github.com/user/server-ops-agent/internal/backups"
 	"github.com/user/server-ops-agent/internal/monitor"
 	"github.com/user/server-ops-agent/internal/server"
 	"github.com/user/server-ops-agent/pkg/logger"
@@ -134,6 +138,9 @@ func main() {
 	if logLevel != "" {
 		cfg.LogLevel = logLevel
 	}
+	if err := cfg.ValidateCertPins(); err != nil {
+		panic("加载配置失败: " + err.Error())
+	}
 
 	// 初始化日志
 	log, err := logger.New(cfg.LogFile, cfg.LogLevel)
@@ -144,6 +151,7 @@ func main() {
 
 	// 创建服务器客户端
 	client := server.New(cfg, log)
+	client.SetConfigPath(configFile)
 
 	// 创建监控器
 	mon := monitor.New(log)
@@ -155,10 +163,97 @@ func main() {
 		if !strings.HasPrefix(serverURL, "http://") && !strings.HasPrefix(serverURL, "https://") {
 			serverURL = "http://" + serverURL
 		}
-		mon.SetServerURL(serverURL)
+		mon.SetServerURL(serverURL, server.BackendTLSConfig(cfg.ServerCertFingerprints))
 		log.Info("已配置延迟检测目标: %s", serverURL)
 	}
 
+	// 配置自定义采集插件（远程修改配置后重新应用）
+	applyPlugins := func() {
+		mon.SetPlugins(monitor.PluginConfig{
+			Dir:       cfg.PluginDir,
+			Scripts:   cfg.Plugins,
+			Timeout:   cfg.PluginTimeout,
+			MaxOutput: cfg.PluginMaxOutput,
+		})
+	}
+	if cfg.PluginDir != "" && len(cfg.Plugins) > 0 {
+		applyPlugins()
+		log.Info("已启用 %d 个自定义采集插件 (目录: %s)", len(cfg.Plugins), cfg.PluginDir)
+	}
+
+	// SMART 磁盘健康检查
+	mon.SetSMARTInterval(cfg.SMARTInterval)
+	// 定期扫描本机证书并上报到期时间
+	mon.SetCertScanInterval(cfg.CertScanInterval)
+	// Nginx stub_status 指标和上游可用性
+	mon.SetNginxStatus(cfg.NginxStatusURL, cfg.NginxUpstreams)
+	// MySQL、PostgreSQL 和 Redis 的健康指标
+	applyDatabaseChecks := func() {
+		checks := make([]monitor.DatabaseCheck, 0, len(cfg.DatabaseChecks))
+		for _, c := range cfg.DatabaseChecks {
+			checks = append(checks, monitor.DatabaseCheck{
+				Name:     c.Name,
+				Type:     c.Type,
+				Address:  c.Address,
+				Username: c.Username,
+				Password: c.Password,
+				Database: c.Database,
+			})
+		}
+		mon.SetDatabaseChecks(checks)
+	}
+	applyDatabaseChecks()
+	if len(cfg.DatabaseChecks) > 0 {
+		log.Info("已启用 %d 个数据库健康检查", len(cfg.DatabaseChecks))
+	}
+	// 上报 CPU 和内存占用最高的进程
+	mon.SetTopProcesses(cfg.TopProcesses)
+
+	// 累计流量的统计网卡；基线保存在配置文件所在目录，Agent 重启后补上停机期间的流量
+	trafficStateDir := "./config"
+	if configFile != "" {
+		trafficStateDir = filepath.Dir(configFile)
+	}
+	mon.SetTrafficStatePath(filepath.Join(trafficStateDir, "traffic_state.json"))
+	mon.SetTrafficInterface(cfg.TrafficInterface)
+
+	// Agent 创建的备份文件登记表，与流量基线放在同一目录
+	if err := backups.SetStatePath(filepath.Join(trafficStateDir, "backups.json")); err != nil {
+		log.Warn("加载备份文件登记表失败: %v", err)
+	}
+
+	// Nginx 配置的版本快照，同样保存在配置目录下
+	monitor.ConfigureNginxSnapshots(filepath.Join(trafficStateDir, "nginx-snapshots"), cfg.NginxSnapshotKeep, log)
+
+	// 与面板断开期间的监控样本缓冲，重新连接后补发
+	if cfg.MonitorBufferSize > 0 {
+		buffer, err := monitor.NewSampleBuffer(filepath.Join(trafficStateDir, "monitor_buffer.jsonl"), cfg.MonitorBufferSize)
+		if err != nil {
+			log.Warn("加载监控样本缓冲失败: %v", err)
+		} else {
+			if n := buffer.Len(); n > 0 {
+				log.Info("上次运行遗留 %d 个未发送的监控样本，连接面板后补发", n)
+			}
+			client.SetSampleBuffer(buffer)
+		}
+	}
+
+	// 本机 Prometheus 导出，提供与上报面板相同的监控数据
+	var exporter *monitor.Exporter
+	if cfg.ExporterPort > 0 {
+		exporter = monitor.NewExporter(map[string]string{
+			"version":    version.Version,
+			"agent_type": cfg.AgentType,
+			"server_id":  strconv.FormatUint(uint64(cfg.ServerID), 10),
+		})
+		if err := exporter.Start(cfg.ExporterListen, cfg.ExporterPort); err != nil {
+			log.Warn("启动 Prometheus 导出失败: %v", err)
+			exporter = nil
+		} else {
+			log.Info("Prometheus 导出已启动: http://%s/metrics", net.JoinHostPort(cfg.ExporterListen, strconv.Itoa(cfg.ExporterPort)))
+		}
+	}
+
 	// 创建等待组和停止通道
 	var wg sync.WaitGroup
 	stopCh := make(chan struct{})
@@ -221,8 +316,12 @@ func main() {
 				log.Info("WebSocket连接成功")
 				return true
 			} else if cfg.RegisterToken != "" {
-				// 尝试使用注册令牌注册
-				serverID, secretKey, err := client.RegisterAgent(cfg.RegisterToken)
+				// 尝试使用注册令牌注册，附带系统信息让面板预先填充服务器详情
+				sysInfo, err := mon.GetSystemInfo()
+				if err != nil {
+					log.Warn("注册前获取系统信息失败，将在连接后上报: %v", err)
+				}
+				serverID, secretKey, err := client.RegisterAgent(cfg.RegisterToken, sysInfo)
 				if err != nil {
 					log.Error("注册服务器失败: %s", err)
 					return false
@@ -303,6 +402,7 @@ func main() {
 
 				if !connected {
 					log.Info("当前连接状态为离线，开始重连流程")
+					client.RecordError(server.ErrorReconnect, "连接断开")
 					// 使用指数退避策略
 					for retryAttempt := 0; retryAttempt < maxRetries; retryAttempt++ {
 						// 计算退避时间
@@ -389,14 +489,56 @@ func main() {
 	// 创建一个配置更新通道
 	configUpdateCh := make(chan struct{}, 1)
 
+	// 面板远程修改配置后，通知监控任务重新加载
+	client.SetConfigUpdateHandler(func() {
+		select {
+		case configUpdateCh <- struct{}{}:
+		default:
+			// 通道已满，跳过
+		}
+	})
+
+	// 聚焦查看开始/结束时，通知监控任务调整上报间隔
+	monitorRateCh := make(chan struct{}, 1)
+	client.SetMonitorRateHandler(func() {
+		select {
+		case monitorRateCh <- struct{}{}:
+		default:
+			// 通道已满，跳过
+		}
+	})
+
+	// 面板要求立即心跳时，通知监控任务马上上报一次
+	pokeCh := make(chan struct{}, 1)
+	client.SetPokeHandler(func() {
+		select {
+		case pokeCh <- struct{}{}:
+		default:
+			// 通道已满，跳过
+		}
+	})
+
 	// 启动监控任务（同时承担心跳功能）
 	// 监控数据上报时会更新 LastHeartbeat，因此不需要单独的心跳机制
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		monitorTicker := time.NewTicker(cfg.MonitorInterval)
+		reportInterval, _ := client.ReportInterval()
+		monitorTicker := time.NewTicker(reportInterval)
 		defer monitorTicker.Stop()
 
+		// 聚焦查看期间按更短的间隔上报，其中大约每个配置间隔只有一个样本入库，
+		// 其余样本标记为实时样本，面板只推送给查看者
+		var lastPersisted time.Time
+		markLive := func(data *monitor.MonitorData) {
+			interval, focused := client.ReportInterval()
+			if focused && time.Since(lastPersisted)+interval/2 < cfg.MonitorInterval {
+				data.Live = true
+				return
+			}
+			lastPersisted = time.Now()
+		}
+
 		for {
 			select {
 			case <-monitorTicker.C:
@@ -406,12 +548,19 @@ func main() {
 					data, err := mon.GetMonitorData()
 					if err != nil {
 						log.Error("收集监控数据失败: %s", err)
+						client.RecordError(server.ErrorCollect, err)
 						continue
 					}
 
 					// 发送监控数据
 					if cfg.ServerID > 0 && cfg.SecretKey != "" {
-						log.Info("发送最新监控数据（间隔：%s）...", cfg.MonitorInterval)
+						client.ObserveActivity(data)
+						markLive(data)
+						if data.Live {
+							log.Debug("发送实时监控数据（间隔：%s）...", reportInterval)
+						} else {
+							log.Info("发送最新监控数据（间隔：%s）...", reportInterval)
+						}
 						if err := client.SendMonitorData(data); err != nil {
 							log.Error("发送监控数据失败: %s", err)
 
@@ -424,11 +573,30 @@ func main() {
 							}
 						}
 					}
+
+					// 发送时会补充 Agent 错误数，之后再交给本机导出
+					if exporter != nil {
+						exporter.Observe(data)
+					}
 				}
 			case <-configUpdateCh:
-				// 重置监控间隔
-				monitorTicker.Reset(cfg.MonitorInterval)
-				log.Info("已更新监控间隔为: %s", cfg.MonitorInterval)
+				// 在监控任务内重新应用插件配置，避免与采集并发
+				applyPlugins()
+				mon.SetTrafficInterface(cfg.TrafficInterface)
+				mon.SetSMARTInterval(cfg.SMARTInterval)
+				mon.SetCertScanInterval(cfg.CertScanInterval)
+				mon.SetNginxStatus(cfg.NginxStatusURL, cfg.NginxUpstreams)
+				applyDatabaseChecks()
+				mon.SetTopProcesses(cfg.TopProcesses)
+				mon.SetUptimeChecks(client.UptimeChecks(), client.ProbePolicy())
+				mon.SetMeshConfig(client.MeshConfig())
+				mon.SetLogPaths(client.LogPaths())
+				mon.SetCheckScripts(client.CheckScripts())
+
+				// 重置监控间隔（聚焦查看期间保持更短的间隔）
+				reportInterval, _ = client.ReportInterval()
+				monitorTicker.Reset(reportInterval)
+				log.Info("已更新监控间隔为: %s", reportInterval)
 
 				// 配置更新后立即获取并发送一次最新数据
 				if cfg.EnableCPUMonitor || cfg.EnableMemMonitor || cfg.EnableDiskMonitor || cfg.EnableNetworkMonitor {
@@ -436,11 +604,44 @@ func main() {
 						data, err := mon.GetMonitorData()
 						if err != nil {
 							log.Error("收集监控数据失败: %s", err)
+							client.RecordError(server.ErrorCollect, err)
 						} else {
 							log.Info("配置更新后立即发送最新监控数据...")
+							client.ObserveActivity(data)
+							markLive(data)
 							if err := client.SendMonitorData(data); err != nil {
 								log.Error("发送监控数据失败: %s", err)
 							}
+							if exporter != nil {
+								exporter.Observe(data)
+							}
+						}
+					}
+				}
+			case <-monitorRateCh:
+				reportInterval, _ = client.ReportInterval()
+				monitorTicker.Reset(reportInterval)
+			case <-pokeCh:
+				// 立即上报系统信息和监控数据，面板据此确认 Agent 存活
+				if cfg.ServerID > 0 && cfg.SecretKey != "" {
+					if sysInfo, err := mon.GetSystemInfo(); err != nil {
+						log.Error("获取系统信息失败: %s", err)
+					} else if err := client.SendSystemInfo(sysInfo); err != nil {
+						log.Error("发送系统信息失败: %s", err)
+					}
+					data, err := mon.GetMonitorData()
+					if err != nil {
+						log.Error("收集监控数据失败: %s", err)
+						client.RecordError(server.ErrorCollect, err)
+					} else {
+						log.Info("面板要求立即心跳，发送最新监控数据...")
+						client.ObserveActivity(data)
+						markLive(data)
+						if err := client.SendMonitorData(data); err != nil {
+							log.Error("发送监控数据失败: %s", err)
+						}
+						if exporter != nil {
+							exporter.Observe(data)
 						}
 					}
 				}
@@ -493,6 +694,48 @@ func main() {
 		}
 	}()
 
+	// 定时快照 Nginx 配置，内容未变化时不生成新版本
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		monitor.RunNginxSnapshotSchedule(cfg.NginxSnapshotInterval, stopCh)
+	}()
+
+	// 定时上报容器资源统计
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		client.RunDockerStatsSchedule(cfg.DockerStatsInterval, stopCh)
+	}()
+
+	// 定期清理残留的备份文件，仅在配置了 backup_max_age 时生效
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		cleanupTicker := time.NewTicker(1 * time.Hour)
+		defer cleanupTicker.Stop()
+
+		cleanup := func() {
+			removed, errs := backups.Cleanup(cfg.BackupMaxAge, time.Now())
+			for _, path := range removed {
+				log.Info("已清理超过 %s 的备份文件: %s", cfg.BackupMaxAge, path)
+			}
+			for _, err := range errs {
+				log.Warn("清理备份文件失败: %v", err)
+			}
+		}
+		cleanup()
+
+		for {
+			select {
+			case <-cleanupTicker.C:
+				cleanup()
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+
 	// 处理信号
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
@@ -507,6 +750,10 @@ func main() {
 	// 关闭WebSocket连接
 	client.CloseWebSocket()
 
+	if exporter != nil {
+		exporter.Stop()
+	}
+
 	// 等待所有goroutine退出
 	wg.Wait()
 	log.Info("服务器监控Agent已关闭")
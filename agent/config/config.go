@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
+	"github.com/user/server-ops-agent/pkg/certpin"
 )
 
 // Config 存储agent的配置项
@@ -17,12 +19,31 @@ type Config struct {
 	SecretKey     string `mapstructure:"secret_key"`
 	RegisterToken string `mapstructure:"register_token"`
 
+	// 备用面板地址，配置后监控数据和系统信息会同时镜像上报，备用面板下发的命令一律忽略
+	SecondaryServerURL string `mapstructure:"secondary_server_url"`
+
+	// 面板证书的 SHA-256 指纹白名单，配置后只接受指纹匹配的证书，不再依赖系统信任库（只能在本机修改）
+	ServerCertFingerprints []string `mapstructure:"server_cert_fingerprints"`
+
+	// 使用注册令牌注册时一并提交的服务器名称和标签，为空则保持面板中的设置
+	RegisterName        string   `mapstructure:"register_name"`
+	RegisterTags        []string `mapstructure:"register_tags"`
+	RegisterEnvironment string   `mapstructure:"register_environment"`
+	RegisterGroup       string   `mapstructure:"register_group"`
+
 	// Agent类型: "full" 或 "monitor"
 	AgentType string `mapstructure:"agent_type"`
 
 	// 监控设置
 	MonitorInterval time.Duration `mapstructure:"monitor_interval"`
 
+	// 空闲降频：CPU 使用率和网络速率持续 idle_after 低于阈值时改按 idle_monitor_interval 上报，
+	// 有活动时恢复 monitor_interval；idle_monitor_interval 为 0 或不大于 monitor_interval 时不降频
+	IdleMonitorInterval  time.Duration `mapstructure:"idle_monitor_interval"`
+	IdleAfter            time.Duration `mapstructure:"idle_after"`
+	IdleCPUThreshold     float64       `mapstructure:"idle_cpu_threshold"`     // CPU 使用率阈值(%)
+	IdleNetworkThreshold int           `mapstructure:"idle_network_threshold"` // 入站加出站速率阈值(KB/s)
+
 	// 日志设置
 	LogLevel string `mapstructure:"log_level"`
 	LogFile  string `mapstructure:"log_file"`
@@ -33,10 +54,95 @@ type Config struct {
 	EnableDiskMonitor    bool `mapstructure:"enable_disk_monitor"`
 	EnableNetworkMonitor bool `mapstructure:"enable_network_monitor"`
 
+	// 统计累计流量（面板中的总流量）所用的网卡，为空表示累加所有非虚拟网卡（排除回环、docker、veth 等）
+	TrafficInterface string `mapstructure:"traffic_interface"`
+
 	// 升级设置
 	UpdateRepo    string `mapstructure:"update_repo"`
 	UpdateChannel string `mapstructure:"update_channel"`
 	UpdateMirror  string `mapstructure:"update_mirror"`
+	PinnedVersion string `mapstructure:"pinned_version"` // 固定版本，非空时拒绝安装其他版本（由面板设置下发）
+
+	// 带宽限制(KB/s)，0 表示不限速（由面板设置下发）
+	TransferRateLimit   int `mapstructure:"transfer_rate_limit"`   // 单个文件传输或日志流的上限
+	AgentBandwidthLimit int `mapstructure:"agent_bandwidth_limit"` // 所有传输合计的上限
+
+	// 每次上报携带的 CPU 和内存占用最高的进程数，0 表示不上报（由面板设置下发）
+	TopProcesses int `mapstructure:"top_processes"`
+
+	// 自定义采集插件设置
+	PluginDir       string        `mapstructure:"plugin_dir"`        // 插件脚本所在目录，只允许执行该目录下的脚本
+	Plugins         []string      `mapstructure:"plugins"`           // 启用的插件脚本文件名
+	PluginTimeout   time.Duration `mapstructure:"plugin_timeout"`    // 单个插件执行超时
+	PluginMaxOutput int           `mapstructure:"plugin_max_output"` // 单个插件输出大小上限(bytes)
+
+	// 容器文件管理设置，为空表示不限制
+	ContainerFileRoots            []string            `mapstructure:"container_file_roots"`              // 所有容器允许访问的目录前缀
+	ContainerFileRootsByContainer map[string][]string `mapstructure:"container_file_roots_by_container"` // 按容器名称或ID单独配置，优先于全局配置
+
+	// 允许从面板清空/轮转日志文件的目录，为空表示禁用该功能（只能在本机修改）
+	LogRotateRoots []string `mapstructure:"log_rotate_roots"`
+
+	// 命令输出采集：允许写入输出文件的目录（为空表示禁用该功能，只能在本机修改）、单个输出文件的大小上限(MB)和最长执行时间
+	CaptureRoots     []string      `mapstructure:"capture_roots"`
+	CaptureMaxSizeMB int           `mapstructure:"capture_max_size_mb"`
+	CaptureTimeout   time.Duration `mapstructure:"capture_timeout"`
+
+	// Agent 创建的备份文件（.bak/.backup/.old）的最长保留时间，超过后自动清理，0 表示不清理
+	BackupMaxAge time.Duration `mapstructure:"backup_max_age"`
+
+	// Nginx 配置目录的版本快照：保留的版本数（0 表示不做快照）和定时快照间隔（0 表示只在修改配置时快照）
+	NginxSnapshotKeep     int           `mapstructure:"nginx_snapshot_keep"`
+	NginxSnapshotInterval time.Duration `mapstructure:"nginx_snapshot_interval"`
+
+	// SMART 磁盘健康检查间隔，0 表示不检查。smartctl 会唤醒休眠的机械硬盘，不宜过于频繁
+	SMARTInterval time.Duration `mapstructure:"smart_interval"`
+
+	// 扫描本机 SSL 证书并上报到期时间的间隔，0 表示不上报。扫描会执行 certbot 并探测本机 HTTPS 端口
+	CertScanInterval time.Duration `mapstructure:"cert_scan_interval"`
+
+	// Nginx stub_status 地址（如 http://127.0.0.1/nginx_status），为空表示不采集连接和请求指标
+	NginxStatusURL string `mapstructure:"nginx_status_url"`
+	// 每次采集时探测 TCP 连通性的 Nginx 上游地址（host:port）
+	NginxUpstreams []string `mapstructure:"nginx_upstreams"`
+
+	// 采集健康指标的 MySQL、PostgreSQL 和 Redis，含登录凭据（只能在本机修改）
+	DatabaseChecks []DatabaseCheck `mapstructure:"database_checks"`
+
+	// 上报容器资源统计（CPU、内存、网络和块设备 IO）的间隔，0 表示不上报（修改后重启生效）
+	DockerStatsInterval time.Duration `mapstructure:"docker_stats_interval"`
+
+	// 与面板断开期间缓冲在本地磁盘的监控样本数上限，重新连接后按顺序补发，0 表示不缓冲（修改后重启生效）
+	MonitorBufferSize int `mapstructure:"monitor_buffer_size"`
+
+	// Prometheus 导出端口，大于 0 时在该端口提供 /metrics，0 表示关闭（只能在本机修改，修改后重启生效）
+	ExporterPort int `mapstructure:"exporter_port"`
+	// Prometheus 导出监听的地址，默认只监听本机；Prometheus 在其他机器上抓取时改为 0.0.0.0 或指定网卡的地址
+	// （/metrics 没有鉴权，只能在本机修改，修改后重启生效）
+	ExporterListen string `mapstructure:"exporter_listen"`
+
+	// 单个响应序列化后的大小上限(MB)，超过时返回错误并提示改用分页或流式接口，0 表示不限制
+	MaxResponseMB int `mapstructure:"max_response_mb"`
+
+	// 只读模式：拒绝文件写入、终止进程、Docker/Nginx 修改、终端输入等所有修改类操作，监控和查看不受影响。
+	// 只能在本机修改；面板也可以通过服务器设置临时开启，两者任一开启即生效
+	ReadOnlyMode bool `mapstructure:"read_only_mode"`
+
+	// 是否允许面板远程修改本配置文件（该项本身只能在本机修改）
+	AllowRemoteConfig bool `mapstructure:"allow_remote_config"`
+
+	// 是否执行面板下发的检查脚本（只能在本机修改），只读模式下同样不执行
+	AllowCheckScripts bool `mapstructure:"allow_check_scripts"`
+}
+
+// DatabaseCheck 一个需要采集健康指标的数据库，建议使用只读的监控账号
+type DatabaseCheck struct {
+	Name     string `mapstructure:"name" yaml:"name"`
+	Type     string `mapstructure:"type" yaml:"type"`         // mysql、postgres 或 redis
+	Address  string `mapstructure:"address" yaml:"address"`   // host:port，或 unix socket 路径（PostgreSQL 为 socket 所在目录）
+	Username string `mapstructure:"username" yaml:"username"` // Redis 未启用 ACL 时留空
+	Password string `mapstructure:"password" yaml:"password"`
+	Database string `mapstructure:"database" yaml:"database"` // PostgreSQL 连接的库名（默认 postgres），Redis 的库编号
 }
 
 // LoadConfig 从配置文件加载配置{error: "发送命令失败: Agent错误: 重启Nginx失败: exit status 1"}
@@ -48,17 +154,57 @@ func LoadConfig(configPath string) (*Config, error) {
 	v.SetDefault("server_id", 0)
 	v.SetDefault("secret_key", "")
 	v.SetDefault("register_token", "")
+	v.SetDefault("secondary_server_url", "")
+	v.SetDefault("server_cert_fingerprints", []string{})
+	v.SetDefault("register_name", "")
+	v.SetDefault("register_tags", []string{})
+	v.SetDefault("register_environment", "")
+	v.SetDefault("register_group", "")
 	v.SetDefault("monitor_interval", "30s")
+	v.SetDefault("idle_monitor_interval", "0s")
+	v.SetDefault("idle_after", "10m")
+	v.SetDefault("idle_cpu_threshold", 5.0)
+	v.SetDefault("idle_network_threshold", 10)
 	v.SetDefault("log_level", "info")
 	v.SetDefault("log_file", "./agent.log")
 	v.SetDefault("enable_cpu_monitor", true)
 	v.SetDefault("enable_mem_monitor", true)
 	v.SetDefault("enable_disk_monitor", true)
 	v.SetDefault("enable_network_monitor", true)
+	v.SetDefault("traffic_interface", "")
 	v.SetDefault("update_repo", "EnderKC/BetterMonitor")
 	v.SetDefault("update_channel", "stable")
 	v.SetDefault("update_mirror", "")
+	v.SetDefault("pinned_version", "")
+	v.SetDefault("transfer_rate_limit", 0)
+	v.SetDefault("top_processes", 0)
+	v.SetDefault("agent_bandwidth_limit", 0)
 	v.SetDefault("agent_type", "full")
+	v.SetDefault("plugin_dir", "")
+	v.SetDefault("plugins", []string{})
+	v.SetDefault("plugin_timeout", "5s")
+	v.SetDefault("plugin_max_output", 64*1024)
+	v.SetDefault("container_file_roots", []string{})
+	v.SetDefault("container_file_roots_by_container", map[string][]string{})
+	v.SetDefault("log_rotate_roots", []string{"/var/log"})
+	v.SetDefault("backup_max_age", "0s")
+	v.SetDefault("nginx_snapshot_keep", 10)
+	v.SetDefault("nginx_snapshot_interval", "1h")
+	v.SetDefault("smart_interval", "30m")
+	v.SetDefault("cert_scan_interval", "6h")
+	v.SetDefault("nginx_status_url", "")
+	v.SetDefault("nginx_upstreams", []string{})
+	v.SetDefault("database_checks", []DatabaseCheck{})
+	v.SetDefault("docker_stats_interval", "1m")
+	v.SetDefault("capture_max_size_mb", 1024)
+	v.SetDefault("capture_timeout", "30m")
+	v.SetDefault("monitor_buffer_size", 2880)
+	v.SetDefault("exporter_port", 0)
+	v.SetDefault("exporter_listen", "127.0.0.1")
+	v.SetDefault("max_response_mb", 64)
+	v.SetDefault("read_only_mode", false)
+	v.SetDefault("allow_remote_config", true)
+	v.SetDefault("allow_check_scripts", true)
 
 	// 配置文件路径
 	if configPath != "" {
@@ -102,26 +248,124 @@ func LoadConfig(configPath string) (*Config, error) {
 		config.MonitorInterval = 30 * time.Second
 	}
 
+	if idleInterval, err := time.ParseDuration(v.GetString("idle_monitor_interval")); err == nil && idleInterval > 0 {
+		config.IdleMonitorInterval = idleInterval
+	} else {
+		config.IdleMonitorInterval = 0
+	}
+	if idleAfter, err := time.ParseDuration(v.GetString("idle_after")); err == nil && idleAfter >= 0 {
+		config.IdleAfter = idleAfter
+	} else {
+		config.IdleAfter = 10 * time.Minute
+	}
+
+	pluginTimeout, err := time.ParseDuration(v.GetString("plugin_timeout"))
+	if err == nil && pluginTimeout > 0 {
+		config.PluginTimeout = pluginTimeout
+	} else {
+		config.PluginTimeout = 5 * time.Second
+	}
+	if backupMaxAge, err := time.ParseDuration(v.GetString("backup_max_age")); err == nil && backupMaxAge > 0 {
+		config.BackupMaxAge = backupMaxAge
+	} else {
+		config.BackupMaxAge = 0
+	}
+	if snapshotInterval, err := time.ParseDuration(v.GetString("nginx_snapshot_interval")); err == nil && snapshotInterval > 0 {
+		config.NginxSnapshotInterval = snapshotInterval
+	} else {
+		config.NginxSnapshotInterval = 0
+	}
+	if smartInterval, err := time.ParseDuration(v.GetString("smart_interval")); err == nil && smartInterval > 0 {
+		config.SMARTInterval = smartInterval
+	} else {
+		config.SMARTInterval = 0
+	}
+	if certScanInterval, err := time.ParseDuration(v.GetString("cert_scan_interval")); err == nil && certScanInterval > 0 {
+		config.CertScanInterval = certScanInterval
+	} else {
+		config.CertScanInterval = 0
+	}
+	if dockerStatsInterval, err := time.ParseDuration(v.GetString("docker_stats_interval")); err == nil && dockerStatsInterval > 0 {
+		config.DockerStatsInterval = dockerStatsInterval
+	} else {
+		config.DockerStatsInterval = 0
+	}
+	if captureTimeout, err := time.ParseDuration(v.GetString("capture_timeout")); err == nil && captureTimeout > 0 {
+		config.CaptureTimeout = captureTimeout
+	} else {
+		config.CaptureTimeout = 30 * time.Minute
+	}
+	if config.CaptureMaxSizeMB <= 0 {
+		config.CaptureMaxSizeMB = 1024
+	}
+	if config.PluginMaxOutput <= 0 {
+		config.PluginMaxOutput = 64 * 1024
+	}
+
 	// 兼容旧版配置文件（无 agent_type 字段）
 	if config.AgentType == "" {
 		config.AgentType = "full"
 	}
 
+	// 证书指纹配置错误时拒绝启动，避免在管理员以为已启用证书固定时静默退回系统信任库
+	if err := config.ValidateCertPins(); err != nil {
+		return nil, err
+	}
+
 	// 配置加载完成后输出配置值
 	fmt.Println("配置值:")
 	fmt.Printf("ServerURL: %s\n", config.ServerURL)
 	fmt.Printf("ServerID: %d\n", config.ServerID)
 	fmt.Printf("SecretKey: %s\n", config.SecretKey)
 	fmt.Printf("RegisterToken: %s\n", config.RegisterToken)
+	fmt.Printf("SecondaryServerURL: %s\n", config.SecondaryServerURL)
+	fmt.Printf("ServerCertFingerprints: %v\n", config.ServerCertFingerprints)
+	fmt.Printf("RegisterName: %s\n", config.RegisterName)
+	fmt.Printf("RegisterTags: %v\n", config.RegisterTags)
+	fmt.Printf("RegisterEnvironment: %s\n", config.RegisterEnvironment)
+	fmt.Printf("RegisterGroup: %s\n", config.RegisterGroup)
 	fmt.Printf("AgentType: %s\n", config.AgentType)
 	fmt.Printf("MonitorInterval: %s\n", config.MonitorInterval)
+	fmt.Printf("IdleMonitorInterval: %s\n", config.IdleMonitorInterval)
+	fmt.Printf("IdleAfter: %s\n", config.IdleAfter)
+	fmt.Printf("IdleCPUThreshold: %.1f%%\n", config.IdleCPUThreshold)
+	fmt.Printf("IdleNetworkThreshold: %d KB/s\n", config.IdleNetworkThreshold)
 	fmt.Printf("LogLevel: %s\n", config.LogLevel)
 	fmt.Printf("LogFile: %s\n", config.LogFile)
 	fmt.Printf("EnableCPUMonitor: %t\n", config.EnableCPUMonitor)
 	fmt.Printf("EnableMemMonitor: %t\n", config.EnableMemMonitor)
+	fmt.Printf("TrafficInterface: %s\n", config.TrafficInterface)
 	fmt.Printf("UpdateRepo: %s\n", config.UpdateRepo)
 	fmt.Printf("UpdateChannel: %s\n", config.UpdateChannel)
 	fmt.Printf("UpdateMirror: %s\n", config.UpdateMirror)
+	fmt.Printf("PinnedVersion: %s\n", config.PinnedVersion)
+	fmt.Printf("TransferRateLimit: %d KB/s\n", config.TransferRateLimit)
+	fmt.Printf("AgentBandwidthLimit: %d KB/s\n", config.AgentBandwidthLimit)
+	fmt.Printf("TopProcesses: %d\n", config.TopProcesses)
+	fmt.Printf("PluginDir: %s\n", config.PluginDir)
+	fmt.Printf("Plugins: %v\n", config.Plugins)
+	fmt.Printf("ContainerFileRoots: %v\n", config.ContainerFileRoots)
+	fmt.Printf("ContainerFileRootsByContainer: %v\n", config.ContainerFileRootsByContainer)
+	fmt.Printf("LogRotateRoots: %v\n", config.LogRotateRoots)
+	fmt.Printf("CaptureRoots: %v\n", config.CaptureRoots)
+	fmt.Printf("CaptureMaxSizeMB: %d\n", config.CaptureMaxSizeMB)
+	fmt.Printf("CaptureTimeout: %s\n", config.CaptureTimeout)
+	fmt.Printf("BackupMaxAge: %s\n", config.BackupMaxAge)
+	fmt.Printf("NginxSnapshotKeep: %d\n", config.NginxSnapshotKeep)
+	fmt.Printf("NginxSnapshotInterval: %s\n", config.NginxSnapshotInterval)
+	fmt.Printf("SMARTInterval: %s\n", config.SMARTInterval)
+	fmt.Printf("CertScanInterval: %s\n", config.CertScanInterval)
+	fmt.Printf("NginxStatusURL: %s\n", config.NginxStatusURL)
+	fmt.Printf("NginxUpstreams: %v\n", config.NginxUpstreams)
+	fmt.Printf("DatabaseChecks: %v\n", config.DatabaseCheckNames())
+	fmt.Printf("DockerStatsInterval: %s\n", config.DockerStatsInterval)
+	fmt.Printf("MonitorBufferSize: %d\n", config.MonitorBufferSize)
+	fmt.Printf("ExporterPort: %d\n", config.ExporterPort)
+	fmt.Printf("ExporterListen: %s\n", config.ExporterListen)
+	fmt.Printf("MaxResponseMB: %d\n", config.MaxResponseMB)
+	fmt.Printf("ReadOnlyMode: %t\n", config.ReadOnlyMode)
+	fmt.Printf("AllowRemoteConfig: %t\n", config.AllowRemoteConfig)
+	fmt.Printf("AllowCheckScripts: %t\n", config.AllowCheckScripts)
 
 	return &config, nil
 }
@@ -131,21 +375,9 @@ func SaveConfig(config *Config, configPath string) error {
 	v := viper.New()
 
 	// 设置配置值
-	v.Set("server_url", config.ServerURL)
-	v.Set("server_id", config.ServerID)
-	v.Set("secret_key", config.SecretKey)
-	v.Set("register_token", config.RegisterToken)
-	v.Set("agent_type", config.AgentType)
-	v.Set("monitor_interval", config.MonitorInterval.String())
-	v.Set("log_level", config.LogLevel)
-	v.Set("log_file", config.LogFile)
-	v.Set("enable_cpu_monitor", config.EnableCPUMonitor)
-	v.Set("enable_mem_monitor", config.EnableMemMonitor)
-	v.Set("enable_disk_monitor", config.EnableDiskMonitor)
-	v.Set("enable_network_monitor", config.EnableNetworkMonitor)
-	v.Set("update_repo", config.UpdateRepo)
-	v.Set("update_channel", config.UpdateChannel)
-	v.Set("update_mirror", config.UpdateMirror)
+	for key, value := range settingsMap(config) {
+		v.Set(key, value)
+	}
 
 	// 设置配置文件
 	if configPath == "" {
@@ -164,3 +396,92 @@ func SaveConfig(config *Config, configPath string) error {
 	// 写入配置文件
 	return v.WriteConfig()
 }
+
+// settingsMap 返回写入配置文件的键值，时间间隔以字符串形式保存
+func settingsMap(config *Config) map[string]interface{} {
+	return map[string]interface{}{
+		"server_url":                        config.ServerURL,
+		"server_id":                         config.ServerID,
+		"secret_key":                        config.SecretKey,
+		"register_token":                    config.RegisterToken,
+		"secondary_server_url":              config.SecondaryServerURL,
+		"server_cert_fingerprints":          config.ServerCertFingerprints,
+		"register_name":                     config.RegisterName,
+		"register_tags":                     config.RegisterTags,
+		"register_environment":              config.RegisterEnvironment,
+		"register_group":                    config.RegisterGroup,
+		"agent_type":                        config.AgentType,
+		"monitor_interval":                  config.MonitorInterval.String(),
+		"idle_monitor_interval":             config.IdleMonitorInterval.String(),
+		"idle_after":                        config.IdleAfter.String(),
+		"idle_cpu_threshold":                config.IdleCPUThreshold,
+		"idle_network_threshold":            config.IdleNetworkThreshold,
+		"log_level":                         config.LogLevel,
+		"log_file":                          config.LogFile,
+		"enable_cpu_monitor":                config.EnableCPUMonitor,
+		"enable_mem_monitor":                config.EnableMemMonitor,
+		"enable_disk_monitor":               config.EnableDiskMonitor,
+		"enable_network_monitor":            config.EnableNetworkMonitor,
+		"traffic_interface":                 config.TrafficInterface,
+		"update_repo":                       config.UpdateRepo,
+		"update_channel":                    config.UpdateChannel,
+		"update_mirror":                     config.UpdateMirror,
+		"pinned_version":                    config.PinnedVersion,
+		"transfer_rate_limit":               config.TransferRateLimit,
+		"agent_bandwidth_limit":             config.AgentBandwidthLimit,
+		"top_processes":                     config.TopProcesses,
+		"plugin_dir":                        config.PluginDir,
+		"plugins":                           config.Plugins,
+		"plugin_timeout":                    config.PluginTimeout.String(),
+		"plugin_max_output":                 config.PluginMaxOutput,
+		"container_file_roots":              config.ContainerFileRoots,
+		"container_file_roots_by_container": config.ContainerFileRootsByContainer,
+		"log_rotate_roots":                  config.LogRotateRoots,
+		"capture_roots":                     config.CaptureRoots,
+		"capture_max_size_mb":               config.CaptureMaxSizeMB,
+		"capture_timeout":                   config.CaptureTimeout.String(),
+		"backup_max_age":                    config.BackupMaxAge.String(),
+		"nginx_snapshot_keep":               config.NginxSnapshotKeep,
+		"nginx_snapshot_interval":           config.NginxSnapshotInterval.String(),
+		"smart_interval":                    config.SMARTInterval.String(),
+		"cert_scan_interval":                config.CertScanInterval.String(),
+		"nginx_status_url":                  config.NginxStatusURL,
+		"nginx_upstreams":                   config.NginxUpstreams,
+		"database_checks":                   config.DatabaseChecks,
+		"docker_stats_interval":             config.DockerStatsInterval.String(),
+		"monitor_buffer_size":               config.MonitorBufferSize,
+		"exporter_port":                     config.ExporterPort,
+		"exporter_listen":                   config.ExporterListen,
+		"max_response_mb":                   config.MaxResponseMB,
+		"read_only_mode":                    config.ReadOnlyMode,
+		"allow_remote_config":               config.AllowRemoteConfig,
+		"allow_check_scripts":               config.AllowCheckScripts,
+	}
+}
+
+// DatabaseCheckNames 返回数据库检查项的名称和地址，用于输出日志，不包含凭据
+func (c *Config) DatabaseCheckNames() []string {
+	names := make([]string, 0, len(c.DatabaseChecks))
+	for _, check := range c.DatabaseChecks {
+		names = append(names, fmt.Sprintf("%s(%s %s)", check.Name, check.Type, check.Address))
+	}
+	return names
+}
+
+// ValidateCertPins 校验证书指纹格式。配置了指纹时面板地址必须使用 https，否则证书固定不起作用
+func (c *Config) ValidateCertPins() error {
+	if len(c.ServerCertFingerprints) == 0 {
+		return nil
+	}
+	for _, fp := range c.ServerCertFingerprints {
+		if _, err := certpin.Normalize(fp); err != nil {
+			return fmt.Errorf("无效的 server_cert_fingerprints: %w", err)
+		}
+	}
+	for key, url := range map[string]string{"server_url": c.ServerURL, "secondary_server_url": c.SecondaryServerURL} {
+		if url != "" && !strings.HasPrefix(url, "https://") {
+			return fmt.Errorf("配置了 server_cert_fingerprints 时 %s 必须使用 https://", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+// remoteEditableKeys 允许面板远程修改的配置项。
+// 服务器地址（含备用面板地址）、身份凭据、数据库检查的登录凭据、面板证书指纹、日志轮转和命令输出采集允许的目录、只读模式、allow_check_scripts 以及 allow_remote_config 本身只能在本机修改，
+// 避免面板账号被盗用时把 Agent 劫持到其他服务器；
+// 插件目录和插件列表决定 Agent 执行哪些程序，日志文件决定 Agent 写入哪个路径，同样只能在本机修改，
+// 否则面板可以借此执行任意程序或覆盖任意文件；
+// Prometheus 导出的端口和监听地址决定 Agent 在哪些网卡上暴露未鉴权的监控数据，也只能在本机修改；
+// 监控间隔、升级和带宽限制相关配置由面板设置统一下发（见 FetchSettings），不在此列。
+var remoteEditableKeys = map[string]bool{
+	"log_level":                         true,
+	"agent_type":                        true,
+	"enable_cpu_monitor":                true,
+	"enable_mem_monitor":                true,
+	"enable_disk_monitor":               true,
+	"enable_network_monitor":            true,
+	"traffic_interface":                 true,
+	"idle_monitor_interval":             true,
+	"idle_after":                        true,
+	"idle_cpu_threshold":                true,
+	"idle_network_threshold":            true,
+	"plugin_timeout":                    true,
+	"plugin_max_output":                 true,
+	"container_file_roots":              true,
+	"container_file_roots_by_container": true,
+	"capture_max_size_mb":               true,
+	"capture_timeout":                   true,
+	"backup_max_age":                    true,
+	"nginx_snapshot_keep":               true,
+	"nginx_snapshot_interval":           true,
+	"smart_interval":                    true,
+	"cert_scan_interval":                true,
+	"nginx_status_url":                  true,
+	"nginx_upstreams":                   true,
+	"docker_stats_interval":             true,
+	"max_response_mb":                   true,
+	"monitor_buffer_size":               true,
+}
+
+// restartRequiredKeys 修改后需要重启 Agent 才能生效的配置项，值为把该项从旧配置复制回运行中配置的函数
+var restartRequiredKeys = map[string]func(dst, src *Config){
+	"agent_type":              func(dst, src *Config) { dst.AgentType = src.AgentType },
+	"nginx_snapshot_interval": func(dst, src *Config) { dst.NginxSnapshotInterval = src.NginxSnapshotInterval },
+	"docker_stats_interval":   func(dst, src *Config) { dst.DockerStatsInterval = src.DockerStatsInterval },
+	"monitor_buffer_size":     func(dst, src *Config) { dst.MonitorBufferSize = src.MonitorBufferSize },
+}
+
+// RequiresRestart 判断配置项修改后是否需要重启才能生效
+func RequiresRestart(key string) bool {
+	return restartRequiredKeys[key] != nil
+}
+
+// KeepRestartRequired 把需要重启才能生效的配置项从 previous 复制回 running，
+// 这些配置项修改后只写入文件，运行中保持原值
+func KeepRestartRequired(running, previous *Config) {
+	for _, restore := range restartRequiredKeys {
+		restore(running, previous)
+	}
+}
+
+// RemoteSettings 返回允许远程修改的配置项及其当前值
+func RemoteSettings(config *Config) map[string]interface{} {
+	settings := make(map[string]interface{}, len(remoteEditableKeys))
+	for key, value := range settingsMap(config) {
+		if remoteEditableKeys[key] {
+			settings[key] = value
+		}
+	}
+	return settings
+}
+
+// ApplyPatch 将配置补丁合并到当前配置的副本上并校验，不修改 base。
+// 返回合并后的配置以及实际发生变化的配置项（按名称排序）。
+func ApplyPatch(base *Config, patch map[string]interface{}) (*Config, []string, error) {
+	if len(patch) == 0 {
+		return nil, nil, fmt.Errorf("配置补丁为空")
+	}
+
+	v := viper.New()
+	current := settingsMap(base)
+	for key, value := range current {
+		v.Set(key, value)
+	}
+	for key, value := range patch {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if _, known := current[key]; !known {
+			return nil, nil, fmt.Errorf("未知的配置项: %s", key)
+		}
+		if !remoteEditableKeys[key] {
+			return nil, nil, fmt.Errorf("配置项 %s 不允许远程修改", key)
+		}
+		v.Set(key, value)
+	}
+
+	var merged Config
+	if err := v.Unmarshal(&merged); err != nil {
+		return nil, nil, fmt.Errorf("解析配置补丁失败: %w", err)
+	}
+	if err := merged.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	updated := settingsMap(&merged)
+	var changed []string
+	for key, value := range updated {
+		// 按字符串形式比较，避免 nil 与空切片/空映射被误判为变化
+		if fmt.Sprint(value) != fmt.Sprint(current[key]) {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return &merged, changed, nil
+}
+
+// Validate 校验配置项的取值
+func (c *Config) Validate() error {
+	if c.MonitorInterval < time.Second {
+		return fmt.Errorf("monitor_interval 不能小于 1s")
+	}
+	if c.IdleMonitorInterval != 0 && c.IdleMonitorInterval < time.Second {
+		return fmt.Errorf("idle_monitor_interval 不能小于 1s")
+	}
+	if c.IdleAfter < 0 {
+		return fmt.Errorf("idle_after 不能为负数")
+	}
+	if c.IdleCPUThreshold < 0 || c.IdleCPUThreshold > 100 {
+		return fmt.Errorf("idle_cpu_threshold 应在 0-100 之间")
+	}
+	if c.IdleNetworkThreshold < 0 {
+		return fmt.Errorf("idle_network_threshold 不能为负数")
+	}
+	if !logger.ValidLevel(c.LogLevel) {
+		return fmt.Errorf("无效的 log_level: %s", c.LogLevel)
+	}
+	if c.AgentType != "full" && c.AgentType != "monitor" {
+		return fmt.Errorf("无效的 agent_type: %s", c.AgentType)
+	}
+	if strings.ContainsAny(c.TrafficInterface, " \t/") {
+		return fmt.Errorf("无效的 traffic_interface: %q", c.TrafficInterface)
+	}
+	if c.PluginTimeout <= 0 {
+		return fmt.Errorf("plugin_timeout 必须大于 0")
+	}
+	if c.PluginMaxOutput <= 0 {
+		return fmt.Errorf("plugin_max_output 必须大于 0")
+	}
+	if len(c.Plugins) > 0 && strings.TrimSpace(c.PluginDir) == "" {
+		return fmt.Errorf("启用插件时必须配置 plugin_dir")
+	}
+	for _, script := range c.Plugins {
+		if strings.TrimSpace(script) == "" || filepath.IsAbs(script) || strings.Contains(filepath.ToSlash(script), "..") {
+			return fmt.Errorf("插件只能填写 plugin_dir 下的相对路径: %q", script)
+		}
+	}
+	if c.BackupMaxAge < 0 {
+		return fmt.Errorf("backup_max_age 不能为负数")
+	}
+	if c.NginxSnapshotKeep < 0 {
+		return fmt.Errorf("nginx_snapshot_keep 不能为负数")
+	}
+	if c.NginxSnapshotInterval < 0 {
+		return fmt.Errorf("nginx_snapshot_interval 不能为负数")
+	}
+	if c.SMARTInterval < 0 {
+		return fmt.Errorf("smart_interval 不能为负数")
+	}
+	if c.CertScanInterval < 0 {
+		return fmt.Errorf("cert_scan_interval 不能为负数")
+	}
+	if c.NginxStatusURL != "" {
+		if u, err := url.Parse(c.NginxStatusURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("nginx_status_url 必须是 http(s) 地址: %q", c.NginxStatusURL)
+		}
+	}
+	for _, addr := range c.NginxUpstreams {
+		if _, port, err := net.SplitHostPort(addr); err != nil || port == "" {
+			return fmt.Errorf("nginx_upstreams 必须是 host:port 格式: %q", addr)
+		}
+	}
+	if err := c.validateDatabaseChecks(); err != nil {
+		return err
+	}
+	if c.DockerStatsInterval < 0 {
+		return fmt.Errorf("docker_stats_interval 不能为负数")
+	}
+	if err := c.ValidateCertPins(); err != nil {
+		return err
+	}
+	if c.MonitorBufferSize < 0 {
+		return fmt.Errorf("monitor_buffer_size 不能为负数")
+	}
+	if c.ExporterPort < 0 || c.ExporterPort > 65535 {
+		return fmt.Errorf("exporter_port 必须在 0-65535 之间")
+	}
+	if net.ParseIP(c.ExporterListen) == nil {
+		return fmt.Errorf("exporter_listen 必须是 IP 地址，如 127.0.0.1 或 0.0.0.0")
+	}
+	if c.MaxResponseMB < 0 {
+		return fmt.Errorf("max_response_mb 不能为负数")
+	}
+	for _, root := range c.LogRotateRoots {
+		if !filepath.IsAbs(root) {
+			return fmt.Errorf("log_rotate_roots 必须是绝对路径: %q", root)
+		}
+	}
+	for _, root := range c.CaptureRoots {
+		if !filepath.IsAbs(root) {
+			return fmt.Errorf("capture_roots 必须是绝对路径: %q", root)
+		}
+	}
+	if c.CaptureMaxSizeMB < 0 {
+		return fmt.Errorf("capture_max_size_mb 不能为负数")
+	}
+	if c.CaptureTimeout < 0 {
+		return fmt.Errorf("capture_timeout 不能为负数")
+	}
+	for _, root := range c.ContainerFileRoots {
+		if !strings.HasPrefix(root, "/") {
+			return fmt.Errorf("container_file_roots 必须是绝对路径: %q", root)
+		}
+	}
+	for name, roots := range c.ContainerFileRootsByContainer {
+		for _, root := range roots {
+			if !strings.HasPrefix(root, "/") {
+				return fmt.Errorf("container_file_roots_by_container[%s] 必须是绝对路径: %q", name, root)
+			}
+		}
+	}
+	return nil
+}
+
+// 支持的数据库类型，postgresql 是 postgres 的别名
+var databaseCheckTypes = map[string]bool{"mysql": true, "postgres": true, "postgresql": true, "redis": true}
+
+// validateDatabaseChecks 校验数据库检查项：名称唯一、类型受支持，地址为 host:port 或 unix socket 的绝对路径
+func (c *Config) validateDatabaseChecks() error {
+	if len(c.DatabaseChecks) > 16 {
+		return fmt.Errorf("database_checks 最多 16 项")
+	}
+	names := make(map[string]bool, len(c.DatabaseChecks))
+	for _, check := range c.DatabaseChecks {
+		name := strings.TrimSpace(check.Name)
+		if name == "" {
+			return fmt.Errorf("database_checks 的 name 不能为空")
+		}
+		if names[name] {
+			return fmt.Errorf("database_checks 的 name 重复: %q", name)
+		}
+		names[name] = true
+		if !databaseCheckTypes[check.Type] {
+			return fmt.Errorf("database_checks[%s] 的 type 必须是 mysql、postgres 或 redis: %q", name, check.Type)
+		}
+		if filepath.IsAbs(check.Address) {
+			continue
+		}
+		if _, port, err := net.SplitHostPort(check.Address); err != nil || port == "" {
+			return fmt.Errorf("database_checks[%s] 的 address 必须是 host:port 或 unix socket 路径: %q", name, check.Address)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func testConfig() *Config {
+	return &Config{
+		ServerURL:         "127.0.0.1:8080",
+		ServerID:          1,
+		SecretKey:         "secret",
+		AgentType:         "full",
+		MonitorInterval:   30 * time.Second,
+		LogLevel:          "info",
+		LogFile:           "./agent.log",
+		EnableCPUMonitor:  true,
+		PluginTimeout:     5 * time.Second,
+		PluginMaxOutput:   64 * 1024,
+		AllowRemoteConfig: true,
+		ExporterListen:    "127.0.0.1",
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	base := testConfig()
+	updated, changed, err := ApplyPatch(base, map[string]interface{}{
+		"log_level":            "debug",
+		"enable_cpu_monitor":   false,
+		"container_file_roots": []interface{}{"/data"},
+		"plugin_max_output":    float64(1024),
+		"agent_type":           "full",
+	})
+	if err != nil {
+		t.Fatalf("ApplyPatch 失败: %v", err)
+	}
+
+	want := []string{"container_file_roots", "enable_cpu_monitor", "log_level", "plugin_max_output"}
+	if len(changed) != len(want) {
+		t.Fatalf("changed = %v, want %v", changed, want)
+	}
+	for i := range want {
+		if changed[i] != want[i] {
+			t.Fatalf("changed = %v, want %v", changed, want)
+		}
+	}
+	if updated.LogLevel != "debug" || updated.EnableCPUMonitor || updated.PluginMaxOutput != 1024 {
+		t.Fatalf("补丁未生效: %+v", updated)
+	}
+	if updated.SecretKey != "secret" || updated.MonitorInterval != 30*time.Second {
+		t.Fatalf("未修改的配置项被改变: %+v", updated)
+	}
+	if base.LogLevel != "info" {
+		t.Fatalf("ApplyPatch 不应修改原配置")
+	}
+}
+
+func TestApplyPatchRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch map[string]interface{}
+	}{
+		{"empty", map[string]interface{}{}},
+		{"unknown key", map[string]interface{}{"no_such_key": 1}},
+		{"identity", map[string]interface{}{"server_url": "evil.example.com"}},
+		{"guard itself", map[string]interface{}{"allow_remote_config": false}},
+		{"cert pins", map[string]interface{}{"server_cert_fingerprints": []interface{}{}}},
+		{"capture roots", map[string]interface{}{"capture_roots": []interface{}{"/tmp"}}},
+		{"server managed", map[string]interface{}{"monitor_interval": "5s"}},
+		{"invalid level", map[string]interface{}{"log_level": "verbose"}},
+		{"idle threshold", map[string]interface{}{"idle_cpu_threshold": 150}},
+		{"relative root", map[string]interface{}{"container_file_roots": []interface{}{"data"}}},
+		{"plugin dir", map[string]interface{}{"plugin_dir": "/usr/sbin"}},
+		{"plugin list", map[string]interface{}{"plugins": []interface{}{"reboot"}}},
+		{"log file", map[string]interface{}{"log_file": "/etc/cron.d/x"}},
+		{"nginx status scheme", map[string]interface{}{"nginx_status_url": "file:///etc/passwd"}},
+		{"nginx upstream port", map[string]interface{}{"nginx_upstreams": []interface{}{"127.0.0.1"}}},
+		{"database credentials", map[string]interface{}{"database_checks": []interface{}{}}},
+		{"check scripts guard", map[string]interface{}{"allow_check_scripts": true}},
+		{"exporter port", map[string]interface{}{"exporter_port": 9110}},
+		{"exporter listen", map[string]interface{}{"exporter_listen": "0.0.0.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := ApplyPatch(testConfig(), tt.patch); err == nil {
+				t.Fatalf("期望补丁 %v 被拒绝", tt.patch)
+			}
+		})
+	}
+}
+
+func TestValidateDatabaseChecks(t *testing.T) {
+	valid := []DatabaseCheck{
+		{Name: "main", Type: "mysql", Address: "127.0.0.1:3306", Username: "monitor", Password: "secret"},
+		{Name: "pg", Type: "postgres", Address: "/var/run/postgresql"},
+		{Name: "cache", Type: "redis", Address: "[::1]:6379", Database: "2"},
+	}
+	cfg := testConfig()
+	cfg.DatabaseChecks = valid
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("有效的数据库检查被拒绝: %v", err)
+	}
+	if _, ok := RemoteSettings(cfg)["database_checks"]; ok {
+		t.Fatalf("数据库凭据不应出现在远程配置中")
+	}
+
+	invalid := [][]DatabaseCheck{
+		{{Name: "", Type: "mysql", Address: "127.0.0.1:3306"}},
+		{{Name: "a", Type: "mongodb", Address: "127.0.0.1:27017"}},
+		{{Name: "a", Type: "redis", Address: "127.0.0.1"}},
+		{{Name: "a", Type: "redis", Address: "127.0.0.1:6379"}, {Name: "a", Type: "mysql", Address: "127.0.0.1:3306"}},
+	}
+	for _, checks := range invalid {
+		cfg.DatabaseChecks = checks
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("期望 %+v 被拒绝", checks)
+		}
+	}
+}
+
+func TestValidateExporter(t *testing.T) {
+	cfg := testConfig()
+	cfg.ExporterPort = 9110
+	for _, listen := range []string{"127.0.0.1", "0.0.0.0", "::1"} {
+		cfg.ExporterListen = listen
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("有效的监听地址 %q 被拒绝: %v", listen, err)
+		}
+	}
+	// 为空时会监听所有网卡，必须显式写 0.0.0.0
+	for _, listen := range []string{"", "localhost", "127.0.0.1:9110"} {
+		cfg.ExporterListen = listen
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("期望监听地址 %q 被拒绝", listen)
+		}
+	}
+	settings := RemoteSettings(cfg)
+	for _, key := range []string{"exporter_port", "exporter_listen"} {
+		if _, ok := settings[key]; ok {
+			t.Fatalf("%s 不应出现在远程配置中", key)
+		}
+	}
+}
+
+func TestValidatePlugins(t *testing.T) {
+	cfg := testConfig()
+	cfg.PluginDir = "/opt/plugins"
+	cfg.Plugins = []string{"queue.sh", "sub/disk.py"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("有效的插件配置被拒绝: %v", err)
+	}
+	if _, ok := RemoteSettings(cfg)["plugin_dir"]; ok {
+		t.Fatalf("插件目录不应出现在远程配置中")
+	}
+	for _, script := range []string{"../x.sh", "/usr/sbin/reboot", " "} {
+		cfg.Plugins = []string{script}
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("期望插件 %q 被拒绝", script)
+		}
+	}
+}
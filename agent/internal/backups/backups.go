@@ -0,0 +1,144 @@
+// Package backups 记录 Agent 自己创建的备份文件（保存文件时的 .bak、Nginx 配置的 .backup、
+// 升级留下的 .old），并按配置的最长保留时间清理。
+// 正常流程中这些备份会被删除或覆盖，但操作中途失败时会残留下来，反复失败后会在主机上越积越多。
+// 只清理登记过的文件，不会扫描删除用户自己的备份
+package backups
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 允许清理的备份文件后缀，登记表被篡改时也不会删除其他文件
+var backupSuffixes = []string{".bak", ".backup", ".old"}
+
+var (
+	mu        sync.Mutex
+	statePath string
+	entries   = make(map[string]time.Time) // 备份路径 -> 最近一次创建时间
+)
+
+// SetStatePath 设置登记表的保存路径并加载已有记录，Agent 重启后仍能清理之前残留的备份
+func SetStatePath(path string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	statePath = path
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var saved map[string]time.Time
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	for p, t := range saved {
+		if !isBackupPath(p) {
+			continue
+		}
+		if existing, ok := entries[p]; !ok || t.After(existing) {
+			entries[p] = t
+		}
+	}
+	return nil
+}
+
+// Track 登记 Agent 创建的备份文件，重复登记时刷新创建时间
+func Track(path string) {
+	if !isBackupPath(path) {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	entries[filepath.Clean(path)] = time.Now()
+	save()
+}
+
+// Untrack 备份已被正常删除时取消登记
+func Untrack(path string) {
+	mu.Lock()
+	defer mu.Unlock()
+	path = filepath.Clean(path)
+	if _, ok := entries[path]; ok {
+		delete(entries, path)
+		save()
+	}
+}
+
+// Cleanup 删除登记时间早于 maxAge 的备份文件，返回实际删除的路径。
+// maxAge <= 0 表示未启用清理。已不存在的文件直接取消登记
+func Cleanup(maxAge time.Duration, now time.Time) ([]string, []error) {
+	if maxAge <= 0 {
+		return nil, nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+
+	var removed []string
+	var errs []error
+	changed := false
+	for path, created := range entries {
+		info, err := os.Lstat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				delete(entries, path)
+				changed = true
+			}
+			continue
+		}
+		if now.Sub(created) < maxAge {
+			continue
+		}
+		// 备份被替换成目录或符号链接时不删除，只取消登记
+		if info.Mode().IsRegular() {
+			if err := os.Remove(path); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			removed = append(removed, path)
+		}
+		delete(entries, path)
+		changed = true
+	}
+	if changed {
+		save()
+	}
+	sort.Strings(removed)
+	return removed, errs
+}
+
+func isBackupPath(path string) bool {
+	for _, suffix := range backupSuffixes {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// save 保存登记表，调用方需持有 mu。保存失败不影响备份本身，下次变更时重试
+func save() {
+	if statePath == "" {
+		return
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(statePath), 0755); err != nil {
+		return
+	}
+	tmp := statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, statePath)
+}
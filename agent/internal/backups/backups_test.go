@@ -0,0 +1,58 @@
+package backups
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanup(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, SetStatePath(filepath.Join(dir, "backups.json")))
+
+	stale := filepath.Join(dir, "nginx.conf.bak")
+	fresh := filepath.Join(dir, "agent.old")
+	untracked := filepath.Join(dir, "user.bak")
+	for _, p := range []string{stale, fresh, untracked} {
+		assert.NoError(t, os.WriteFile(p, []byte("x"), 0644))
+	}
+	Track(stale)
+	Track(fresh)
+	// 非备份后缀的文件不登记
+	Track(filepath.Join(dir, "agent.conf"))
+
+	now := time.Now()
+	mu.Lock()
+	entries[stale] = now.Add(-48 * time.Hour)
+	mu.Unlock()
+
+	// 未启用时不清理
+	removed, _ := Cleanup(0, now)
+	assert.Empty(t, removed)
+	assert.FileExists(t, stale)
+
+	removed, errs := Cleanup(24*time.Hour, now)
+	assert.Empty(t, errs)
+	assert.Equal(t, []string{stale}, removed)
+	assert.NoFileExists(t, stale)
+	assert.FileExists(t, fresh)
+	assert.FileExists(t, untracked)
+
+	// 登记表持久化，重启后仍能清理
+	mu.Lock()
+	entries = make(map[string]time.Time)
+	mu.Unlock()
+	assert.NoError(t, SetStatePath(filepath.Join(dir, "backups.json")))
+	removed, _ = Cleanup(time.Minute, now.Add(time.Hour))
+	assert.Equal(t, []string{fresh}, removed)
+
+	// 正常删除后取消登记
+	Track(untracked)
+	Untrack(untracked)
+	removed, _ = Cleanup(time.Nanosecond, now.Add(time.Hour))
+	assert.Empty(t, removed)
+	assert.FileExists(t, untracked)
+}
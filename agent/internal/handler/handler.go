@@ -61,6 +61,7 @@ func New(configPath string) (*App, error) {
 
 	// 创建服务器客户端
 	app.client = server.New(cfg, log)
+	app.client.SetConfigPath(configPath)
 
 	return app, nil
 }
@@ -170,7 +171,7 @@ func (a *App) startInitMode() error {
 		// 尝试使用令牌注册
 		a.log.Info("正在尝试使用令牌注册到服务器...")
 
-		serverID, secretKey, err := a.client.RegisterAgent(a.config.RegisterToken)
+		serverID, secretKey, err := a.client.RegisterAgent(a.config.RegisterToken, sysInfo)
 		if err != nil {
 			a.log.Error("注册失败: %v", err)
 		} else {
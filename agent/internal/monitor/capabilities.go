@@ -0,0 +1,24 @@
+package monitor
+
+// Capabilities Agent 能力上报，供前端根据实际环境引导用户
+type Capabilities struct {
+	Docker    *DockerCapability    `json:"docker,omitempty"`
+	Privilege *PrivilegeCapability `json:"privilege,omitempty"`
+}
+
+// DockerCapability Docker 可用状态
+type DockerCapability struct {
+	Available bool   `json:"available"`
+	Mode      string `json:"mode,omitempty"`     // api 或 cli
+	Status    string `json:"status"`             // ok / cli_fallback / not_installed / permission_denied / unavailable
+	Message   string `json:"message,omitempty"`  // 原始错误信息
+	Guidance  string `json:"guidance,omitempty"` // 可操作的处理建议
+}
+
+// PrivilegeCapability Agent 的运行权限。非 root 运行时部分操作会失败，前端据此提前提示
+type PrivilegeCapability struct {
+	Root         bool     `json:"root"`                   // Linux/macOS 为 euid 0，Windows 为管理员（已提权）
+	User         string   `json:"user,omitempty"`         // 运行 Agent 的用户
+	Capabilities []string `json:"capabilities,omitempty"` // Linux 非 root 时拥有的有效 capability
+	Limited      []string `json:"limited,omitempty"`      // 当前权限下不可用或受限的操作
+}
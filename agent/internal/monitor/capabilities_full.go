@@ -0,0 +1,18 @@
+//go:build !monitor_only
+
+package monitor
+
+import "strings"
+
+// detectCapabilities 检测全功能版的运行环境能力
+func (m *Monitor) detectCapabilities() *Capabilities {
+	docker := ProbeDocker()
+	if docker.Status != DockerStatusOK {
+		m.log.Warn("Docker不可用或受限: status=%s, %s", docker.Status, docker.Message)
+	}
+	privilege := DetectPrivilege()
+	if !privilege.Root {
+		m.log.Warn("Agent 未以 root/管理员权限运行，以下操作不可用或受限: %s", strings.Join(privilege.Limited, "；"))
+	}
+	return &Capabilities{Docker: docker, Privilege: privilege}
+}
@@ -0,0 +1,8 @@
+//go:build monitor_only
+
+package monitor
+
+// detectCapabilities 监控版不包含操作能力，无需检测
+func (m *Monitor) detectCapabilities() *Capabilities {
+	return &Capabilities{}
+}
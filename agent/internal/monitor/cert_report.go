@@ -0,0 +1,68 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"sync"
+	"time"
+)
+
+// CertificateScan 一次本机证书扫描的结果，面板用它替换该服务器的证书列表。
+// 没有证书时 Certificates 为空但仍会上报，面板据此清除已删除的证书
+type CertificateScan struct {
+	Certificates []SSLCertificate `json:"certificates"`
+}
+
+// certScanState 定期扫描证书，扫描完成后结果暂存在 result，随下一次采集上报
+type certScanState struct {
+	mu        sync.Mutex
+	interval  time.Duration
+	checkedAt time.Time
+	running   bool
+	result    *CertificateScan
+}
+
+// SetCertScanInterval 设置证书扫描间隔，0 表示不扫描
+func (m *Monitor) SetCertScanInterval(interval time.Duration) {
+	m.certs.mu.Lock()
+	defer m.certs.mu.Unlock()
+	m.certs.interval = interval
+}
+
+// collectCertificates 返回上次取出以来完成的证书扫描结果，没有新结果时返回 nil。
+// 扫描需要执行 certbot 并逐个连接本机 HTTPS 端口，到期后在后台进行
+func (m *Monitor) collectCertificates() *CertificateScan {
+	s := &m.certs
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.interval <= 0 {
+		return nil
+	}
+	if s.result != nil {
+		scan := s.result
+		s.result = nil
+		return scan
+	}
+	if s.running || time.Since(s.checkedAt) < s.interval {
+		return nil
+	}
+
+	s.running = true
+	s.checkedAt = time.Now()
+	go func() {
+		certificates, err := ListCertificates()
+		if err != nil {
+			m.log.Warn("扫描本机证书失败: %v", err)
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.running = false
+		// 所有扫描方式都失败时不上报，避免面板误把证书清空
+		if err == nil {
+			s.result = &CertificateScan{Certificates: certificates}
+		}
+	}()
+	return nil
+}
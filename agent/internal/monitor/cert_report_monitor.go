@@ -0,0 +1,16 @@
+//go:build monitor_only
+
+package monitor
+
+import "time"
+
+// CertificateScan 监控版不扫描证书
+type CertificateScan struct{}
+
+// certScanState 监控版不扫描证书
+type certScanState struct{}
+
+// SetCertScanInterval 监控版不扫描证书，忽略配置
+func (m *Monitor) SetCertScanInterval(interval time.Duration) {}
+
+func (m *Monitor) collectCertificates() *CertificateScan { return nil }
@@ -0,0 +1,85 @@
+package monitor
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// 面板下发的检查脚本数量上限
+	maxCheckScripts = 20
+	// 间隔和超时的默认值、下限与上限
+	defaultCheckScriptInterval = 60
+	minCheckScriptInterval     = 30
+	defaultCheckScriptTimeout  = 10
+	maxCheckScriptTimeout      = 60
+	// 标准输出和标准错误各自的大小上限(bytes)，超出部分丢弃
+	maxCheckScriptOutput = 64 * 1024
+	maxCheckScriptStderr = 4 * 1024
+	// 单个脚本解析的指标数上限，以及上报的输出和错误信息的最大长度（字符）
+	maxCheckScriptMetrics   = 32
+	maxCheckScriptOutputLen = 1000
+)
+
+// 脚本指标名只允许字母、数字、下划线和点，且以字母或下划线开头，可直接用作预警表达式中的变量
+var checkScriptMetricPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]{0,63}$`)
+
+// CheckScript 面板为本机配置的检查脚本，由 Agent 按间隔执行
+type CheckScript struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Interpreter string `json:"interpreter"` // sh、bash、python3、powershell 或 cmd
+	Content     string `json:"content"`
+	Interval    int    `json:"interval"` // 执行间隔(秒)
+	Timeout     int    `json:"timeout"`  // 单次执行超时(秒)
+}
+
+// CheckScriptResult 检查脚本最近一次的执行结果，每次上报都携带所有脚本的最新结果
+type CheckScriptResult struct {
+	CheckID    uint               `json:"check_id"`
+	Timestamp  int64              `json:"timestamp"` // 开始执行的时间，Unix 毫秒
+	ExitCode   int                `json:"exit_code"` // 未能启动或超时为 -1
+	DurationMs int64              `json:"duration_ms"`
+	Metrics    map[string]float64 `json:"metrics,omitempty"` // 标准输出中 key=value 行解析出的数值
+	Output     string             `json:"output,omitempty"`  // 标准输出中的其余内容
+	Error      string             `json:"error,omitempty"`
+}
+
+// parseCheckScriptOutput 从标准输出中解析 key=value 形式的数值指标，其余非空行作为输出文本返回；
+// 指标名不合法或值不是数值的行按普通输出处理
+func parseCheckScriptOutput(output string) (map[string]float64, string) {
+	var metrics map[string]float64
+	var text []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok && len(metrics) < maxCheckScriptMetrics {
+			key = strings.TrimSpace(key)
+			v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			// NaN 和 Inf 无法序列化为 JSON，按普通输出处理
+			if err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) && checkScriptMetricPattern.MatchString(key) {
+				if metrics == nil {
+					metrics = make(map[string]float64)
+				}
+				metrics[key] = v
+				continue
+			}
+		}
+		text = append(text, line)
+	}
+	return metrics, truncateCheckScriptText(strings.Join(text, "\n"))
+}
+
+// truncateCheckScriptText 去掉无效的 UTF-8 字节并截断到 maxCheckScriptOutputLen 个字符
+func truncateCheckScriptText(s string) string {
+	s = strings.ToValidUTF8(s, "")
+	if utf8.RuneCountInString(s) <= maxCheckScriptOutputLen {
+		return s
+	}
+	return string([]rune(s)[:maxCheckScriptOutputLen])
+}
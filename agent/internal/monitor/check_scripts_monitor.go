@@ -0,0 +1,11 @@
+//go:build monitor_only
+
+package monitor
+
+// checkScriptState 监控版不执行检查脚本
+type checkScriptState struct{}
+
+// SetCheckScripts 监控版不执行面板下发的脚本，忽略配置
+func (m *Monitor) SetCheckScripts(scripts []CheckScript) {}
+
+func (m *Monitor) collectCheckScripts() []CheckScriptResult { return nil }
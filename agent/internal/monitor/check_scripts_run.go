@@ -0,0 +1,228 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// checkScriptInterpreter 一种解释器的命令和脚本文件扩展名，脚本路径追加在 args 之后
+type checkScriptInterpreter struct {
+	command []string // 依次查找，使用第一个存在的命令
+	args    []string
+	ext     string
+}
+
+// 支持的解释器，powershell 在 Linux/macOS 上使用 pwsh
+var checkScriptInterpreters = map[string]checkScriptInterpreter{
+	"sh":         {command: []string{"sh"}, ext: ".sh"},
+	"bash":       {command: []string{"bash"}, ext: ".sh"},
+	"python3":    {command: []string{"python3", "python"}, ext: ".py"},
+	"powershell": {command: []string{"powershell", "pwsh"}, args: []string{"-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File"}, ext: ".ps1"},
+	"cmd":        {command: []string{"cmd"}, args: []string{"/D", "/C"}, ext: ".cmd"},
+}
+
+// checkScriptState 检查脚本的调度状态和各脚本的最新结果
+type checkScriptState struct {
+	mu      sync.Mutex
+	scripts []CheckScript
+	lastRun map[uint]time.Time
+	running map[uint]bool
+	results map[uint]CheckScriptResult
+	started bool
+}
+
+// SetCheckScripts 更新面板下发的检查脚本，内容或间隔变化的脚本在下一轮立即重新执行，首次设置时启动后台调度
+func (m *Monitor) SetCheckScripts(scripts []CheckScript) {
+	s := &m.checkScripts
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(scripts) > maxCheckScripts {
+		scripts = scripts[:maxCheckScripts]
+	}
+	if s.lastRun == nil {
+		s.lastRun = make(map[uint]time.Time)
+		s.running = make(map[uint]bool)
+		s.results = make(map[uint]CheckScriptResult)
+	}
+	previous := make(map[uint]CheckScript, len(s.scripts))
+	for _, script := range s.scripts {
+		previous[script.ID] = script
+	}
+	keep := make(map[uint]bool, len(scripts))
+	for _, script := range scripts {
+		keep[script.ID] = true
+		if old, ok := previous[script.ID]; ok && old != script {
+			delete(s.lastRun, script.ID)
+		}
+	}
+	for id := range s.lastRun {
+		if !keep[id] {
+			delete(s.lastRun, id)
+		}
+	}
+	for id := range s.results {
+		if !keep[id] {
+			delete(s.results, id)
+		}
+	}
+	s.scripts = append([]CheckScript(nil), scripts...)
+
+	if !s.started && len(scripts) > 0 {
+		s.started = true
+		go m.runCheckScriptLoop()
+	}
+}
+
+// runCheckScriptLoop 每 5 秒执行一次到期的脚本
+func (m *Monitor) runCheckScriptLoop() {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		m.runDueCheckScripts(time.Now())
+	}
+}
+
+// runDueCheckScripts 并发执行到期的脚本，同一脚本上一次尚未结束时跳过
+func (m *Monitor) runDueCheckScripts(now time.Time) {
+	s := &m.checkScripts
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, script := range s.scripts {
+		interval := time.Duration(script.Interval) * time.Second
+		if script.Interval <= 0 {
+			interval = defaultCheckScriptInterval * time.Second
+		}
+		interval = max(interval, minCheckScriptInterval*time.Second)
+		if s.running[script.ID] || now.Sub(s.lastRun[script.ID]) < interval {
+			continue
+		}
+		s.running[script.ID] = true
+		s.lastRun[script.ID] = now
+		go func(script CheckScript) {
+			result := runCheckScript(script)
+			if result.Error != "" {
+				m.log.Debug("检查脚本 %s 执行失败: %s", script.Name, result.Error)
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			delete(s.running, script.ID)
+			// 执行期间脚本已被删除时丢弃结果
+			for _, current := range s.scripts {
+				if current.ID == script.ID {
+					s.results[script.ID] = result
+					break
+				}
+			}
+		}(script)
+	}
+}
+
+// collectCheckScripts 返回各脚本的最新结果，按面板下发的顺序排列，尚未执行过的脚本不包含在内
+func (m *Monitor) collectCheckScripts() []CheckScriptResult {
+	s := &m.checkScripts
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var results []CheckScriptResult
+	for _, script := range s.scripts {
+		if result, ok := s.results[script.ID]; ok {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// runCheckScript 在独立的临时目录中执行一次脚本：只传递最小的环境变量，超时后结束整个进程组，
+// 标准输出和标准错误超过上限的部分丢弃
+func runCheckScript(script CheckScript) CheckScriptResult {
+	start := time.Now()
+	result := CheckScriptResult{CheckID: script.ID, Timestamp: start.UnixMilli(), ExitCode: -1}
+	fail := func(err error) CheckScriptResult {
+		result.DurationMs = time.Since(start).Milliseconds()
+		result.Error = truncateCheckScriptText(err.Error())
+		return result
+	}
+
+	interpreter, ok := checkScriptInterpreters[script.Interpreter]
+	if !ok {
+		return fail(fmt.Errorf("不支持的解释器: %s", script.Interpreter))
+	}
+	var command string
+	for _, name := range interpreter.command {
+		if path, err := exec.LookPath(name); err == nil {
+			command = path
+			break
+		}
+	}
+	if command == "" {
+		return fail(fmt.Errorf("未找到解释器 %s", script.Interpreter))
+	}
+
+	dir, err := os.MkdirTemp("", "bm-check-")
+	if err != nil {
+		return fail(fmt.Errorf("创建临时目录失败: %w", err))
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "check"+interpreter.ext)
+	content := script.Content
+	if script.Interpreter == "cmd" {
+		// cmd 按行读取批处理文件，统一为 CRLF 避免标签和多行命令解析错误
+		content = strings.ReplaceAll(strings.ReplaceAll(content, "\r\n", "\n"), "\n", "\r\n")
+	}
+	if err := os.WriteFile(path, []byte(content), 0700); err != nil {
+		return fail(fmt.Errorf("写入脚本失败: %w", err))
+	}
+
+	timeout := time.Duration(script.Timeout) * time.Second
+	if script.Timeout <= 0 {
+		timeout = defaultCheckScriptTimeout * time.Second
+	}
+	timeout = min(timeout, maxCheckScriptTimeout*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	stdout := &limitedBuffer{limit: maxCheckScriptOutput}
+	stderr := &limitedBuffer{limit: maxCheckScriptStderr}
+	cmd := exec.CommandContext(ctx, command, append(append([]string(nil), interpreter.args...), path)...)
+	cmd.Dir = dir
+	cmd.Env = checkScriptEnv(dir, script)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	setCheckScriptProcessGroup(cmd)
+	// 脚本退出后仍有后台子进程持有输出管道时不再等待
+	cmd.WaitDelay = time.Second
+
+	runErr := cmd.Run()
+	result.DurationMs = time.Since(start).Milliseconds()
+	result.Metrics, result.Output = parseCheckScriptOutput(stdout.String())
+
+	var exitErr *exec.ExitError
+	switch {
+	case runErr != nil && ctx.Err() == context.DeadlineExceeded:
+		result.Error = fmt.Sprintf("执行超时(%s)", timeout)
+	case runErr == nil:
+		result.ExitCode = 0
+	case errors.Is(runErr, exec.ErrWaitDelay):
+		// 脚本本身已退出，只是遗留的子进程未关闭输出
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	case errors.As(runErr, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		result.Error = truncateCheckScriptText(strings.TrimSpace(stderr.String()))
+	default:
+		result.Error = truncateCheckScriptText(runErr.Error())
+	}
+	if stdout.overflow && result.Error == "" {
+		result.Error = fmt.Sprintf("输出超过上限 %d 字节，已截断", maxCheckScriptOutput)
+	}
+	return result
+}
@@ -0,0 +1,103 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func TestParseCheckScriptOutput(t *testing.T) {
+	metrics, output := parseCheckScriptOutput("queue_depth=12\n  lag.seconds = 3.5 \nstatus: ok\nbad-name=1\nrate=NaN\nversion=v1\n\n")
+	assert.Equal(t, map[string]float64{"queue_depth": 12, "lag.seconds": 3.5}, metrics)
+	assert.Equal(t, "status: ok\nbad-name=1\nrate=NaN\nversion=v1", output)
+
+	metrics, output = parseCheckScriptOutput("")
+	assert.Nil(t, metrics)
+	assert.Empty(t, output)
+
+	_, output = parseCheckScriptOutput(strings.Repeat("中", maxCheckScriptOutputLen+10))
+	assert.Equal(t, maxCheckScriptOutputLen, len([]rune(output)))
+}
+
+func TestRunCheckScript(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("使用 sh 执行测试脚本")
+	}
+
+	result := runCheckScript(CheckScript{ID: 1, Name: "queue", Interpreter: "sh", Content: "echo depth=7\necho \"name=$CHECK_NAME\"\necho \"secret=${SECRET_TOKEN:-none}\"\npwd\n"})
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Empty(t, result.Error)
+	assert.Equal(t, map[string]float64{"depth": 7}, result.Metrics)
+	// 不继承 Agent 的环境变量，在独立的临时目录中执行
+	assert.Contains(t, result.Output, "name=queue")
+	assert.Contains(t, result.Output, "secret=none")
+	assert.Contains(t, result.Output, "bm-check-")
+
+	result = runCheckScript(CheckScript{ID: 2, Interpreter: "sh", Content: "echo disk full >&2\nexit 2\n"})
+	assert.Equal(t, 2, result.ExitCode)
+	assert.Equal(t, "disk full", result.Error)
+
+	// 超时后连同子进程一起结束
+	start := time.Now()
+	result = runCheckScript(CheckScript{ID: 3, Interpreter: "sh", Timeout: 1, Content: "sleep 30 &\nsleep 30\n"})
+	assert.Equal(t, -1, result.ExitCode)
+	assert.Contains(t, result.Error, "执行超时")
+	assert.Less(t, time.Since(start), 10*time.Second)
+
+	result = runCheckScript(CheckScript{ID: 4, Interpreter: "sh", Content: "i=0\nwhile [ $i -lt 20000 ]; do echo x=1234567; i=$((i+1)); done\n"})
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Contains(t, result.Error, "输出超过上限")
+
+	result = runCheckScript(CheckScript{ID: 5, Interpreter: "ruby", Content: "puts 1"})
+	assert.Equal(t, -1, result.ExitCode)
+	assert.Contains(t, result.Error, "不支持的解释器")
+}
+
+func TestCheckScriptSchedule(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("使用 sh 执行测试脚本")
+	}
+
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	m := New(log)
+	// 不通过 SetCheckScripts 设置，避免启动后台调度
+	m.checkScripts = checkScriptState{
+		scripts: []CheckScript{{ID: 1, Name: "a", Interpreter: "sh", Content: "echo v=1"}},
+		lastRun: map[uint]time.Time{}, running: map[uint]bool{}, results: map[uint]CheckScriptResult{},
+	}
+	wait := func() {
+		assert.Eventually(t, func() bool {
+			m.checkScripts.mu.Lock()
+			defer m.checkScripts.mu.Unlock()
+			return len(m.checkScripts.running) == 0
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	now := time.Now()
+	m.runDueCheckScripts(now)
+	wait()
+	results := m.collectCheckScripts()
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, 1.0, results[0].Metrics["v"])
+	}
+	// 未到间隔不重复执行，最新结果在每次上报中都保留
+	m.checkScripts.scripts[0].Content = "echo v=2"
+	m.runDueCheckScripts(now.Add(10 * time.Second))
+	wait()
+	assert.Equal(t, 1.0, m.collectCheckScripts()[0].Metrics["v"])
+	m.runDueCheckScripts(now.Add(time.Minute))
+	wait()
+	assert.Equal(t, 2.0, m.collectCheckScripts()[0].Metrics["v"])
+
+	// 删除的脚本不再上报结果
+	m.checkScripts.started = true
+	m.SetCheckScripts(nil)
+	assert.Empty(t, m.collectCheckScripts())
+}
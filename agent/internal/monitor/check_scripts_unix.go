@@ -0,0 +1,27 @@
+//go:build !monitor_only && !windows
+
+package monitor
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setCheckScriptProcessGroup 让脚本在独立的进程组中运行，超时后连同其启动的子进程一起结束
+func setCheckScriptProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// checkScriptEnv 脚本的环境变量，不继承 Agent 的环境，避免泄露其中的凭据
+func checkScriptEnv(dir string, script CheckScript) []string {
+	return []string{
+		"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+		"HOME=" + dir,
+		"TMPDIR=" + dir,
+		"LANG=C.UTF-8",
+		"CHECK_NAME=" + script.Name,
+	}
+}
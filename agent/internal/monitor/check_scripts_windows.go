@@ -0,0 +1,32 @@
+//go:build !monitor_only && windows
+
+package monitor
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// setCheckScriptProcessGroup 超时后结束整个进程树
+func setCheckScriptProcessGroup(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		return exec.Command("taskkill", "/F", "/T", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	}
+}
+
+// checkScriptEnv 脚本的环境变量，只保留系统运行所需的几项，避免泄露 Agent 环境中的凭据
+func checkScriptEnv(dir string, script CheckScript) []string {
+	env := []string{
+		"TEMP=" + dir,
+		"TMP=" + dir,
+		"USERPROFILE=" + dir,
+		"CHECK_NAME=" + script.Name,
+	}
+	for _, name := range []string{"SystemRoot", "SystemDrive", "windir", "ComSpec", "PATH", "PATHEXT"} {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
@@ -0,0 +1,385 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrComposeInvalid 表示 Compose 配置内容无法解析或结构不正确
+var ErrComposeInvalid = errors.New("Compose配置无效")
+
+// ComposeServiceInfo 解析后的 Compose 服务
+type ComposeServiceInfo struct {
+	Name          string            `json:"name"`
+	Image         string            `json:"image,omitempty"`
+	Build         string            `json:"build,omitempty"` // 构建上下文
+	ContainerName string            `json:"container_name,omitempty"`
+	Restart       string            `json:"restart,omitempty"`
+	Ports         []string          `json:"ports"`   // [host_ip:]published:target[/protocol]
+	Volumes       []string          `json:"volumes"` // source:target[:ro]，匿名卷只有 target
+	Environment   map[string]string `json:"environment"`
+	DependsOn     []string          `json:"depends_on"`
+	Networks      []string          `json:"networks"`
+}
+
+// ComposeProjectInfo 解析后的 Compose 项目结构，服务按名称排序
+type ComposeProjectInfo struct {
+	Name     string               `json:"name,omitempty"`
+	Services []ComposeServiceInfo `json:"services"`
+	Networks []string             `json:"networks"`
+	Volumes  []string             `json:"volumes"`
+}
+
+// 覆盖文件中与基础文件合并（而不是替换）的服务列表字段，按 Compose 规范取并集
+var composeUnionKeys = map[string]bool{
+	"ports": true, "expose": true, "dns": true, "dns_search": true,
+	"tmpfs": true, "external_links": true, "cap_add": true, "cap_drop": true,
+}
+
+// ParseComposeConfig 解析一个或多个 Compose 文件内容，后面的文件按 Compose 的覆盖规则合并到前面的文件上。
+// YAML 锚点、别名和 << 合并键在解码时展开
+func ParseComposeConfig(contents ...string) (*ComposeProjectInfo, error) {
+	if len(contents) == 0 {
+		return nil, fmt.Errorf("%w: 内容为空", ErrComposeInvalid)
+	}
+
+	var merged map[string]interface{}
+	for i, content := range contents {
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+			return nil, fmt.Errorf("%w: 第 %d 个文件: %v", ErrComposeInvalid, i+1, err)
+		}
+		if doc == nil {
+			continue
+		}
+		if merged == nil {
+			merged = doc
+		} else {
+			merged = mergeComposeDocuments(merged, doc)
+		}
+	}
+	if merged == nil {
+		return nil, fmt.Errorf("%w: 内容为空", ErrComposeInvalid)
+	}
+	return buildComposeProjectInfo(merged)
+}
+
+// parseComposeFiles 读取并解析磁盘上的 Compose 文件，用于在写入前校验新内容与覆盖文件合并后的结果
+func parseComposeFiles(mainContent string, overrideFiles []string) (*ComposeProjectInfo, error) {
+	contents := []string{mainContent}
+	for _, f := range overrideFiles {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("读取覆盖文件 %s 失败: %v", f, err)
+		}
+		contents = append(contents, string(data))
+	}
+	return ParseComposeConfig(contents...)
+}
+
+// mergeComposeDocuments 将 override 合并到 base 上：services 按服务合并，其余映射递归合并，标量和列表直接替换
+func mergeComposeDocuments(base, override map[string]interface{}) map[string]interface{} {
+	for key, value := range override {
+		if key == "services" {
+			baseServices, ok1 := base[key].(map[string]interface{})
+			overrideServices, ok2 := value.(map[string]interface{})
+			if ok1 && ok2 {
+				for name, svc := range overrideServices {
+					baseSvc, ok1 := baseServices[name].(map[string]interface{})
+					overrideSvc, ok2 := svc.(map[string]interface{})
+					if ok1 && ok2 {
+						baseServices[name] = mergeComposeService(baseSvc, overrideSvc)
+					} else {
+						baseServices[name] = svc
+					}
+				}
+				continue
+			}
+		}
+		base[key] = mergeComposeValue(base[key], value)
+	}
+	return base
+}
+
+// mergeComposeService 按 Compose 规范合并单个服务：
+// environment/labels 按键合并，volumes/devices 按容器内路径合并，ports 等取并集，其他字段同 mergeComposeValue
+func mergeComposeService(base, override map[string]interface{}) map[string]interface{} {
+	for key, value := range override {
+		switch {
+		case key == "environment" || key == "labels":
+			merged := composeKeyValues(base[key])
+			for k, v := range composeKeyValues(value) {
+				merged[k] = v
+			}
+			result := make(map[string]interface{}, len(merged))
+			for k, v := range merged {
+				result[k] = v
+			}
+			base[key] = result
+		case key == "volumes" || key == "devices":
+			base[key] = mergeComposeMounts(base[key], value)
+		case composeUnionKeys[key]:
+			baseList, ok1 := base[key].([]interface{})
+			overrideList, ok2 := value.([]interface{})
+			if !ok1 || !ok2 {
+				base[key] = value
+				continue
+			}
+			seen := make(map[string]bool, len(baseList))
+			for _, item := range baseList {
+				seen[fmt.Sprint(item)] = true
+			}
+			for _, item := range overrideList {
+				if !seen[fmt.Sprint(item)] {
+					baseList = append(baseList, item)
+				}
+			}
+			base[key] = baseList
+		default:
+			base[key] = mergeComposeValue(base[key], value)
+		}
+	}
+	return base
+}
+
+// mergeComposeValue 两边都是映射时递归合并，否则以覆盖值为准
+func mergeComposeValue(base, override interface{}) interface{} {
+	baseMap, ok1 := base.(map[string]interface{})
+	overrideMap, ok2 := override.(map[string]interface{})
+	if !ok1 || !ok2 {
+		return override
+	}
+	for k, v := range overrideMap {
+		baseMap[k] = mergeComposeValue(baseMap[k], v)
+	}
+	return baseMap
+}
+
+// mergeComposeMounts 合并挂载列表，容器内路径相同的条目以覆盖文件为准
+func mergeComposeMounts(base, override interface{}) interface{} {
+	baseList, ok1 := base.([]interface{})
+	overrideList, ok2 := override.([]interface{})
+	if !ok1 || !ok2 {
+		return override
+	}
+	index := make(map[string]int, len(baseList))
+	for i, item := range baseList {
+		index[composeMountTarget(item)] = i
+	}
+	for _, item := range overrideList {
+		if i, ok := index[composeMountTarget(item)]; ok {
+			baseList[i] = item
+			continue
+		}
+		index[composeMountTarget(item)] = len(baseList)
+		baseList = append(baseList, item)
+	}
+	return baseList
+}
+
+func composeMountTarget(item interface{}) string {
+	switch v := item.(type) {
+	case string:
+		parts := strings.Split(v, ":")
+		if len(parts) == 1 {
+			return parts[0]
+		}
+		return parts[1]
+	case map[string]interface{}:
+		return composeScalar(v["target"])
+	}
+	return fmt.Sprint(item)
+}
+
+// buildComposeProjectInfo 将合并后的文档转换为结构化的项目信息
+func buildComposeProjectInfo(doc map[string]interface{}) (*ComposeProjectInfo, error) {
+	project := &ComposeProjectInfo{
+		Name:     composeScalar(doc["name"]),
+		Services: []ComposeServiceInfo{},
+		Networks: composeMapKeys(doc["networks"]),
+		Volumes:  composeMapKeys(doc["volumes"]),
+	}
+
+	// 使用 include 的项目可能由被引用的文件定义服务
+	_, hasInclude := doc["include"]
+	rawServices, exists := doc["services"]
+	if !exists || rawServices == nil {
+		if hasInclude {
+			return project, nil
+		}
+		return nil, fmt.Errorf("%w: 缺少 services", ErrComposeInvalid)
+	}
+	services, ok := rawServices.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: services 必须是映射", ErrComposeInvalid)
+	}
+
+	for name, raw := range services {
+		svc, ok := raw.(map[string]interface{})
+		if !ok {
+			if raw == nil {
+				svc = map[string]interface{}{}
+			} else {
+				return nil, fmt.Errorf("%w: 服务 %s 必须是映射", ErrComposeInvalid, name)
+			}
+		}
+		info := ComposeServiceInfo{
+			Name:          name,
+			Image:         composeScalar(svc["image"]),
+			ContainerName: composeScalar(svc["container_name"]),
+			Restart:       composeScalar(svc["restart"]),
+			Ports:         []string{},
+			Volumes:       []string{},
+			Environment:   composeKeyValues(svc["environment"]),
+			DependsOn:     composeNameList(svc["depends_on"]),
+			Networks:      composeNameList(svc["networks"]),
+		}
+		switch build := svc["build"].(type) {
+		case string:
+			info.Build = build
+		case map[string]interface{}:
+			info.Build = composeScalar(build["context"])
+			if info.Build == "" {
+				info.Build = "."
+			}
+		}
+		if ports, ok := svc["ports"].([]interface{}); ok {
+			for _, p := range ports {
+				info.Ports = append(info.Ports, formatComposePort(p))
+			}
+		}
+		if volumes, ok := svc["volumes"].([]interface{}); ok {
+			for _, v := range volumes {
+				info.Volumes = append(info.Volumes, formatComposeVolume(v))
+			}
+		}
+		project.Services = append(project.Services, info)
+	}
+	sort.Slice(project.Services, func(i, j int) bool { return project.Services[i].Name < project.Services[j].Name })
+
+	for _, svc := range project.Services {
+		if hasInclude {
+			break
+		}
+		for _, dep := range svc.DependsOn {
+			if _, ok := services[dep]; !ok {
+				return nil, fmt.Errorf("%w: 服务 %s 依赖的服务 %s 不存在", ErrComposeInvalid, svc.Name, dep)
+			}
+		}
+	}
+	return project, nil
+}
+
+// formatComposePort 将短格式（"8080:80"、80）或长格式（target/published/host_ip/protocol）的端口统一为字符串
+func formatComposePort(p interface{}) string {
+	m, ok := p.(map[string]interface{})
+	if !ok {
+		return composeScalar(p)
+	}
+	port := composeScalar(m["target"])
+	if published := composeScalar(m["published"]); published != "" {
+		port = published + ":" + port
+		if hostIP := composeScalar(m["host_ip"]); hostIP != "" {
+			port = hostIP + ":" + port
+		}
+	}
+	if protocol := composeScalar(m["protocol"]); protocol != "" && protocol != "tcp" {
+		port += "/" + protocol
+	}
+	return port
+}
+
+// formatComposeVolume 将短格式或长格式（type/source/target/read_only）的挂载统一为字符串
+func formatComposeVolume(v interface{}) string {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return composeScalar(v)
+	}
+	volume := composeScalar(m["target"])
+	if source := composeScalar(m["source"]); source != "" {
+		volume = source + ":" + volume
+	}
+	if readOnly, _ := m["read_only"].(bool); readOnly {
+		volume += ":ro"
+	}
+	return volume
+}
+
+// composeKeyValues 解析映射形式（KEY: value）或列表形式（KEY=value）的 environment/labels，
+// 只有键没有值的条目值为空字符串
+func composeKeyValues(v interface{}) map[string]string {
+	result := map[string]string{}
+	switch items := v.(type) {
+	case map[string]interface{}:
+		for k, val := range items {
+			result[k] = composeScalar(val)
+		}
+	case []interface{}:
+		for _, item := range items {
+			key, value, _ := strings.Cut(composeScalar(item), "=")
+			if key != "" {
+				result[key] = value
+			}
+		}
+	}
+	return result
+}
+
+// composeNameList 解析列表形式或映射形式（取键）的 depends_on/networks，结果排序
+func composeNameList(v interface{}) []string {
+	var names []string
+	switch items := v.(type) {
+	case []interface{}:
+		for _, item := range items {
+			if name := composeScalar(item); name != "" {
+				names = append(names, name)
+			}
+		}
+	case map[string]interface{}:
+		names = composeMapKeys(items)
+	}
+	if names == nil {
+		return []string{}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func composeMapKeys(v interface{}) []string {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return []string{}
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// composeScalar 将 YAML 标量转换为字符串，null 转换为空字符串
+func composeScalar(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case int:
+		return strconv.Itoa(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		return fmt.Sprint(val)
+	}
+}
@@ -0,0 +1,152 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/net"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+const (
+	// 默认返回的连接数上限
+	defaultConnectionLimit = 500
+	// 允许请求的连接数上限，避免连接数很多的服务器返回过大的响应
+	maxConnectionLimit = 2000
+)
+
+// ConnectionInfo 网络连接信息
+type ConnectionInfo struct {
+	Protocol   string `json:"protocol"` // tcp / tcp6
+	LocalAddr  string `json:"local_addr"`
+	RemoteAddr string `json:"remote_addr"`
+	Status     string `json:"status"`
+	PID        int32  `json:"pid"`
+	Process    string `json:"process"`
+}
+
+// ConnectionList 连接列表及截断信息
+type ConnectionList struct {
+	Connections []ConnectionInfo `json:"connections"`
+	Total       int              `json:"total"`     // 截断前符合条件的连接数
+	Truncated   bool             `json:"truncated"` // 是否超过上限被截断
+}
+
+// ListConnections 列出 TCP 连接及其所属进程。
+// status 为空时只返回 ESTABLISHED 状态，为 all 时返回全部；limit 超出范围时使用默认值
+func (pm *ProcessManager) ListConnections(status string, limit int) (*ConnectionList, error) {
+	conns, err := net.Connections("tcp")
+	if err != nil {
+		return nil, fmt.Errorf("获取网络连接失败: %w", err)
+	}
+	return buildConnectionList(conns, status, limit, func(pid int32) string {
+		if p, err := process.NewProcess(pid); err == nil {
+			name, _ := p.Name()
+			return name
+		}
+		return ""
+	}), nil
+}
+
+// buildConnectionList 按状态过滤连接，按进程名和远端地址排序后再截断到 limit，
+// 保证截断结果与请求更大的 limit 时的前若干项一致
+func buildConnectionList(conns []net.ConnectionStat, status string, limit int, processName func(pid int32) string) *ConnectionList {
+	if limit <= 0 || limit > maxConnectionLimit {
+		limit = defaultConnectionLimit
+	}
+	status = strings.ToUpper(strings.TrimSpace(status))
+	if status == "" {
+		status = "ESTABLISHED"
+	}
+
+	result := &ConnectionList{Connections: make([]ConnectionInfo, 0)}
+	names := make(map[int32]string)
+	for _, conn := range conns {
+		if status != "ALL" && conn.Status != status {
+			continue
+		}
+		info := ConnectionInfo{
+			Protocol:   connectionProtocol(conn),
+			LocalAddr:  formatConnAddr(conn.Laddr),
+			RemoteAddr: formatConnAddr(conn.Raddr),
+			Status:     conn.Status,
+			PID:        conn.Pid,
+		}
+		// 同一进程通常有大量连接，进程名只查询一次；无权限时 PID 为 0
+		if conn.Pid > 0 {
+			name, ok := names[conn.Pid]
+			if !ok {
+				name = processName(conn.Pid)
+				names[conn.Pid] = name
+			}
+			info.Process = name
+		}
+		result.Connections = append(result.Connections, info)
+	}
+	result.Total = len(result.Connections)
+
+	sort.Slice(result.Connections, func(i, j int) bool {
+		a, b := result.Connections[i], result.Connections[j]
+		if a.Process != b.Process {
+			return a.Process < b.Process
+		}
+		return a.RemoteAddr < b.RemoteAddr
+	})
+	if len(result.Connections) > limit {
+		result.Connections = result.Connections[:limit]
+		result.Truncated = true
+	}
+	return result
+}
+
+// KillProcessWithSignal 按指定信号终止进程：TERM 让进程正常退出，KILL 强制结束。
+// signal 为空时沿用 KillProcess 的行为；Windows 不支持信号，一律强制结束
+func (pm *ProcessManager) KillProcessWithSignal(pid int32, signal string) error {
+	signal = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(signal)), "SIG")
+	if signal == "" || runtime.GOOS == "windows" {
+		return pm.KillProcess(pid)
+	}
+
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return fmt.Errorf("获取进程 %d 失败: %w", pid, err)
+	}
+	name, _ := p.Name()
+
+	switch signal {
+	case "TERM":
+		pm.log.Info("发送 SIGTERM 终止进程: PID=%d, 名称=%s", pid, name)
+		err = p.Terminate()
+	case "KILL":
+		pm.log.Info("发送 SIGKILL 终止进程: PID=%d, 名称=%s", pid, name)
+		err = p.Kill()
+	default:
+		return fmt.Errorf("不支持的信号: %s", signal)
+	}
+	if err != nil {
+		return fmt.Errorf("终止进程 %d 失败: %w", pid, err)
+	}
+	return nil
+}
+
+func connectionProtocol(conn net.ConnectionStat) string {
+	// AF_INET6 = 10 (Linux) / 30 (darwin) / 23 (windows)
+	if conn.Family == 10 || conn.Family == 30 || conn.Family == 23 {
+		return "tcp6"
+	}
+	return "tcp"
+}
+
+func formatConnAddr(addr net.Addr) string {
+	if addr.IP == "" && addr.Port == 0 {
+		return ""
+	}
+	if strings.Contains(addr.IP, ":") {
+		return fmt.Sprintf("[%s]:%d", addr.IP, addr.Port)
+	}
+	return fmt.Sprintf("%s:%d", addr.IP, addr.Port)
+}
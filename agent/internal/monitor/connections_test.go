@@ -0,0 +1,125 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/net"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func TestFormatConnAddr(t *testing.T) {
+	assert.Equal(t, "", formatConnAddr(net.Addr{}))
+	assert.Equal(t, "10.0.0.1:443", formatConnAddr(net.Addr{IP: "10.0.0.1", Port: 443}))
+	assert.Equal(t, "[2001:db8::1]:22", formatConnAddr(net.Addr{IP: "2001:db8::1", Port: 22}))
+	assert.Equal(t, "0.0.0.0:0", formatConnAddr(net.Addr{IP: "0.0.0.0"}))
+}
+
+func TestBuildConnectionList(t *testing.T) {
+	conn := func(remote string, port uint32, status string, pid int32) net.ConnectionStat {
+		return net.ConnectionStat{
+			Family: 2,
+			Laddr:  net.Addr{IP: "10.0.0.1", Port: 8080},
+			Raddr:  net.Addr{IP: remote, Port: port},
+			Status: status,
+			Pid:    pid,
+		}
+	}
+	conns := []net.ConnectionStat{
+		conn("10.0.0.9", 5000, "ESTABLISHED", 300),
+		conn("10.0.0.3", 5000, "ESTABLISHED", 100),
+		conn("10.0.0.2", 5000, "TIME_WAIT", 0),
+		conn("10.0.0.1", 5000, "ESTABLISHED", 100),
+		conn("10.0.0.5", 5000, "ESTABLISHED", 200),
+		{Family: 10, Laddr: net.Addr{IP: "::1", Port: 22}, Raddr: net.Addr{IP: "::1", Port: 6000}, Status: "ESTABLISHED", Pid: 200},
+	}
+	lookups := map[int32]int{}
+	names := map[int32]string{100: "nginx", 200: "app", 300: "redis"}
+	processName := func(pid int32) string {
+		lookups[pid]++
+		return names[pid]
+	}
+
+	// 默认只返回 ESTABLISHED，按进程名和远端地址排序；同一进程只查询一次进程名
+	list := buildConnectionList(conns, "", 0, processName)
+	assert.Equal(t, 5, list.Total)
+	assert.False(t, list.Truncated)
+	var got []string
+	for _, c := range list.Connections {
+		got = append(got, c.Process+" "+c.RemoteAddr)
+	}
+	assert.Equal(t, []string{"app 10.0.0.5:5000", "app [::1]:6000", "nginx 10.0.0.1:5000", "nginx 10.0.0.3:5000", "redis 10.0.0.9:5000"}, got)
+	assert.Equal(t, "tcp6", list.Connections[1].Protocol)
+	assert.Equal(t, map[int32]int{100: 1, 200: 1, 300: 1}, lookups)
+
+	// 先排序再截断：无论连接的原始顺序如何，截断后都是排序后的前若干项
+	list = buildConnectionList(conns, "established", 2, processName)
+	assert.Equal(t, 5, list.Total)
+	assert.True(t, list.Truncated)
+	if assert.Len(t, list.Connections, 2) {
+		assert.Equal(t, "10.0.0.5:5000", list.Connections[0].RemoteAddr)
+		assert.Equal(t, "[::1]:6000", list.Connections[1].RemoteAddr)
+	}
+
+	// all 返回全部状态，无权限时 PID 为 0 不查询进程名
+	list = buildConnectionList(conns, "all", maxConnectionLimit+1, processName)
+	assert.Equal(t, 6, list.Total)
+	assert.Equal(t, "", list.Connections[0].Process)
+	assert.Equal(t, "TIME_WAIT", list.Connections[0].Status)
+	assert.Zero(t, lookups[0])
+
+	// 读取本机连接时同样受 limit 限制
+	local, err := (&ProcessManager{}).ListConnections("all", 1)
+	if assert.NoError(t, err) {
+		assert.LessOrEqual(t, len(local.Connections), 1)
+		assert.Equal(t, local.Total > 1, local.Truncated)
+	}
+}
+
+func TestKillProcessWithSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows 不支持信号")
+	}
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	pm := NewProcessManager(log)
+
+	start := func() (*exec.Cmd, chan error) {
+		cmd := exec.Command("sleep", "30")
+		assert.NoError(t, cmd.Start())
+		done := make(chan error, 1)
+		go func() { done <- cmd.Wait() }()
+		return cmd, done
+	}
+	waitExit := func(done chan error, signal string) {
+		select {
+		case err := <-done:
+			var exitErr *exec.ExitError
+			if assert.ErrorAs(t, err, &exitErr) {
+				assert.Contains(t, exitErr.Error(), signal)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("进程未在 %s 后退出", signal)
+		}
+	}
+
+	// 不支持的信号不会发送给进程
+	cmd, done := start()
+	assert.ErrorContains(t, pm.KillProcessWithSignal(int32(cmd.Process.Pid), "HUP"), "不支持的信号")
+
+	// 信号名不区分大小写，可以带 SIG 前缀
+	assert.NoError(t, pm.KillProcessWithSignal(int32(cmd.Process.Pid), "sigterm"))
+	waitExit(done, "terminated")
+
+	cmd, done = start()
+	assert.NoError(t, pm.KillProcessWithSignal(int32(cmd.Process.Pid), "KILL"))
+	waitExit(done, "killed")
+
+	// 已退出的进程返回错误
+	assert.Error(t, pm.KillProcessWithSignal(int32(cmd.Process.Pid), "TERM"))
+}
@@ -0,0 +1,195 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	// 单个数据库采集的超时，采集时同步执行，不宜过长
+	databaseTimeout = 3 * time.Second
+	// 最多采集的数据库数量
+	databaseMaxChecks = 16
+)
+
+// DatabaseCheck 一个需要采集健康指标的数据库服务，凭据保存在 Agent 本机配置中
+type DatabaseCheck struct {
+	Name     string // 显示名称，同一 Agent 内唯一
+	Type     string // mysql、postgres 或 redis
+	Address  string // host:port，或 unix socket 路径
+	Username string
+	Password string
+	Database string // PostgreSQL 连接的库名（默认 postgres），Redis 的库编号
+}
+
+// DatabaseStatus 一个数据库服务的健康指标
+type DatabaseStatus struct {
+	Name    string  `json:"name"`
+	Type    string  `json:"type"`
+	Up      bool    `json:"up"`
+	Error   string  `json:"error,omitempty"`
+	Version string  `json:"version,omitempty"`
+	Latency float64 `json:"latency"` // 完成一次采集的耗时(ms)
+
+	Connections    int     `json:"connections"`     // 当前连接数（Redis 为已连接的客户端数）
+	MaxConnections int     `json:"max_connections"` // 连接数上限，0 表示未知
+	QueryRate      float64 `json:"query_rate"`      // 两次采集之间的每秒查询数（PostgreSQL 为事务数，Redis 为命令数）
+	SlowQueries    uint64  `json:"slow_queries"`    // 两次采集之间新增的慢查询数，PostgreSQL 为当前执行超过 5 秒的查询数
+
+	Role             string  `json:"role,omitempty"`              // primary 或 replica
+	ReplicationLag   float64 `json:"replication_lag"`             // 从库落后主库的秒数
+	ReplicationError string  `json:"replication_error,omitempty"` // 从库的复制线程停止或与主库断开
+
+	MemoryUsed  uint64 `json:"memory_used"`  // bytes：MySQL 为 InnoDB 缓冲池中的数据量，Redis 为 used_memory
+	MemoryLimit uint64 `json:"memory_limit"` // MySQL 的 innodb_buffer_pool_size，Redis 的 maxmemory，0 表示不限制或未知
+}
+
+// databaseCounters 采集到的累计值，由 collectDatabases 换算为速率和增量
+type databaseCounters struct {
+	Queries     uint64
+	SlowQueries uint64
+	HasQueries  bool
+	HasSlow     bool
+}
+
+// databasePlugin 一种数据库的采集实现
+type databasePlugin interface {
+	// open 为检查项创建常驻的连接池，不使用 database/sql 的插件返回 nil
+	open(check DatabaseCheck) (*sql.DB, error)
+	// collect 采集一次指标写入 status，返回需要计算速率的累计值
+	collect(ctx context.Context, check DatabaseCheck, db *sql.DB, status *DatabaseStatus) (databaseCounters, error)
+}
+
+// 已支持的数据库，postgresql 是 postgres 的别名
+var databasePlugins = map[string]databasePlugin{
+	"mysql":      mysqlPlugin{},
+	"postgres":   postgresPlugin{},
+	"postgresql": postgresPlugin{},
+	"redis":      redisPlugin{},
+}
+
+// databaseSample 上次采集的累计值
+type databaseSample struct {
+	counters databaseCounters
+	at       time.Time
+}
+
+// databaseState 保存检查项、各检查项的连接池以及上次的累计值
+type databaseState struct {
+	mu     sync.Mutex
+	checks []DatabaseCheck
+	pools  map[string]*sql.DB
+	prev   map[string]databaseSample
+}
+
+// SetDatabaseChecks 设置需要采集的数据库，配置变化时关闭已有的连接池
+func (m *Monitor) SetDatabaseChecks(checks []DatabaseCheck) {
+	s := &m.databases
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(checks) > databaseMaxChecks {
+		checks = checks[:databaseMaxChecks]
+	}
+	if reflect.DeepEqual(checks, s.checks) {
+		return
+	}
+	for _, db := range s.pools {
+		db.Close()
+	}
+	s.checks = append([]DatabaseCheck(nil), checks...)
+	s.pools = make(map[string]*sql.DB)
+	s.prev = make(map[string]databaseSample)
+}
+
+// collectDatabases 并发采集所有数据库，未配置时返回 nil。单个数据库失败只记录在其 Error 中
+func (m *Monitor) collectDatabases() []DatabaseStatus {
+	s := &m.databases
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.checks) == 0 {
+		return nil
+	}
+
+	results := make([]DatabaseStatus, len(s.checks))
+	counters := make([]databaseCounters, len(s.checks))
+	var wg sync.WaitGroup
+	for i, check := range s.checks {
+		results[i] = DatabaseStatus{Name: check.Name, Type: check.Type}
+		plugin, ok := databasePlugins[check.Type]
+		if !ok {
+			results[i].Error = fmt.Sprintf("不支持的数据库类型: %s", check.Type)
+			continue
+		}
+		db, err := s.pool(plugin, check)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		wg.Add(1)
+		go func(i int, check DatabaseCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), databaseTimeout)
+			defer cancel()
+			start := time.Now()
+			c, err := plugin.collect(ctx, check, db, &results[i])
+			results[i].Latency = float64(time.Since(start).Microseconds()) / 1000
+			if err != nil {
+				results[i].Error = err.Error()
+				return
+			}
+			results[i].Up = true
+			counters[i] = c
+		}(i, check)
+	}
+	wg.Wait()
+
+	now := time.Now()
+	for i := range results {
+		status := &results[i]
+		if !status.Up {
+			delete(s.prev, status.Name)
+			m.log.Debug("采集数据库 %s 失败: %s", status.Name, status.Error)
+			continue
+		}
+		prev, ok := s.prev[status.Name]
+		s.prev[status.Name] = databaseSample{counters: counters[i], at: now}
+		if ok {
+			applyDatabaseCounters(status, prev, counters[i], now)
+		}
+	}
+	return results
+}
+
+// applyDatabaseCounters 根据两次采集的累计值计算每秒查询数和新增慢查询数，累计值变小说明服务重启过，本次不计算
+func applyDatabaseCounters(status *DatabaseStatus, prev databaseSample, cur databaseCounters, now time.Time) {
+	elapsed := now.Sub(prev.at).Seconds()
+	if cur.HasQueries && prev.counters.HasQueries && cur.Queries >= prev.counters.Queries && elapsed > 0 {
+		status.QueryRate = float64(cur.Queries-prev.counters.Queries) / elapsed
+	}
+	if cur.HasSlow && prev.counters.HasSlow && cur.SlowQueries >= prev.counters.SlowQueries {
+		status.SlowQueries = cur.SlowQueries - prev.counters.SlowQueries
+	}
+}
+
+// pool 返回检查项的连接池，首次使用时创建
+func (s *databaseState) pool(plugin databasePlugin, check DatabaseCheck) (*sql.DB, error) {
+	if db, ok := s.pools[check.Name]; ok {
+		return db, nil
+	}
+	db, err := plugin.open(check)
+	if err != nil {
+		return nil, err
+	}
+	if db != nil {
+		// 每次采集只执行几条查询，保留一个空闲连接即可，避免占用数据库的连接数
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+		db.SetConnMaxIdleTime(5 * time.Minute)
+		s.pools[check.Name] = db
+	}
+	return db, nil
+}
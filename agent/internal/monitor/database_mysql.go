@@ -0,0 +1,161 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlPlugin MySQL 和 MariaDB，监控账号需要 PROCESS 和 REPLICATION CLIENT 权限才能读取复制状态
+type mysqlPlugin struct{}
+
+func (mysqlPlugin) open(check DatabaseCheck) (*sql.DB, error) {
+	cfg := mysql.NewConfig()
+	cfg.User = check.Username
+	cfg.Passwd = check.Password
+	cfg.DBName = check.Database
+	cfg.Net = "tcp"
+	if strings.HasPrefix(check.Address, "/") {
+		cfg.Net = "unix"
+	}
+	cfg.Addr = check.Address
+	cfg.Timeout = databaseTimeout
+	cfg.ReadTimeout = databaseTimeout
+	cfg.WriteTimeout = databaseTimeout
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(connector), nil
+}
+
+func (mysqlPlugin) collect(ctx context.Context, check DatabaseCheck, db *sql.DB, status *DatabaseStatus) (databaseCounters, error) {
+	vars, err := queryMySQLPairs(ctx, db, "SHOW GLOBAL STATUS WHERE Variable_name IN ('Threads_connected', 'Questions', 'Slow_queries', 'Innodb_buffer_pool_bytes_data')")
+	if err != nil {
+		return databaseCounters{}, err
+	}
+	settings, err := queryMySQLPairs(ctx, db, "SHOW GLOBAL VARIABLES WHERE Variable_name IN ('max_connections', 'innodb_buffer_pool_size', 'version')")
+	if err != nil {
+		return databaseCounters{}, err
+	}
+	for key, value := range settings {
+		vars[key] = value
+	}
+	counters := applyMySQLStatus(vars, status)
+
+	// MySQL 8.0.22 和 MariaDB 10.5.1 起改名为 SHOW REPLICA STATUS，旧版本只支持 SHOW SLAVE STATUS
+	replica, err := queryMySQLRow(ctx, db, "SHOW REPLICA STATUS")
+	if err != nil {
+		replica, err = queryMySQLRow(ctx, db, "SHOW SLAVE STATUS")
+	}
+	if err != nil {
+		// 没有 REPLICATION CLIENT 权限时不上报角色，不影响其他指标
+		return counters, nil
+	}
+	applyMySQLReplicaStatus(replica, status)
+	return counters, nil
+}
+
+// applyMySQLStatus 把 SHOW GLOBAL STATUS 和 SHOW GLOBAL VARIABLES 的结果（名称统一为小写）写入 status
+func applyMySQLStatus(vars map[string]string, status *DatabaseStatus) databaseCounters {
+	var counters databaseCounters
+	status.Version = vars["version"]
+	status.Connections, _ = strconv.Atoi(vars["threads_connected"])
+	status.MaxConnections, _ = strconv.Atoi(vars["max_connections"])
+	status.MemoryUsed, _ = strconv.ParseUint(vars["innodb_buffer_pool_bytes_data"], 10, 64)
+	status.MemoryLimit, _ = strconv.ParseUint(vars["innodb_buffer_pool_size"], 10, 64)
+	if questions, err := strconv.ParseUint(vars["questions"], 10, 64); err == nil {
+		counters.Queries, counters.HasQueries = questions, true
+	}
+	if slow, err := strconv.ParseUint(vars["slow_queries"], 10, 64); err == nil {
+		counters.SlowQueries, counters.HasSlow = slow, true
+	}
+	return counters
+}
+
+// applyMySQLReplicaStatus 解析 SHOW REPLICA STATUS 的一行，为空时说明不是从库
+func applyMySQLReplicaStatus(row map[string]string, status *DatabaseStatus) {
+	if len(row) == 0 {
+		status.Role = "primary"
+		return
+	}
+	status.Role = "replica"
+	// 新版本的列名使用 Source/Replica，旧版本使用 Master/Slave
+	get := func(names ...string) string {
+		for _, name := range names {
+			if value, ok := row[name]; ok {
+				return value
+			}
+		}
+		return ""
+	}
+	ioRunning := get("Replica_IO_Running", "Slave_IO_Running")
+	sqlRunning := get("Replica_SQL_Running", "Slave_SQL_Running")
+	if ioRunning != "Yes" || sqlRunning != "Yes" {
+		reason := get("Last_IO_Error")
+		if reason == "" {
+			reason = get("Last_SQL_Error")
+		}
+		status.ReplicationError = fmt.Sprintf("复制线程未运行 (IO: %s, SQL: %s)", ioRunning, sqlRunning)
+		if reason != "" {
+			status.ReplicationError += ": " + reason
+		}
+	}
+	// 复制中断时 Seconds_Behind_Source 为 NULL
+	if lag, err := strconv.ParseFloat(get("Seconds_Behind_Source", "Seconds_Behind_Master"), 64); err == nil {
+		status.ReplicationLag = lag
+	}
+}
+
+// queryMySQLPairs 执行返回 (名称, 值) 两列的 SHOW 语句，名称转为小写
+func queryMySQLPairs(ctx context.Context, db *sql.DB, query string) (map[string]string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	values := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		values[strings.ToLower(name)] = value
+	}
+	return values, rows.Err()
+}
+
+// queryMySQLRow 执行列不固定的 SHOW 语句，返回第一行的 列名->值，没有结果时返回空 map
+func queryMySQLRow(ctx context.Context, db *sql.DB, query string) (map[string]string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	row := make(map[string]string)
+	if !rows.Next() {
+		return row, rows.Err()
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+	for i, column := range columns {
+		if values[i].Valid {
+			row[column] = values[i].String
+		}
+	}
+	return row, nil
+}
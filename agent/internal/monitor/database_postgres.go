@@ -0,0 +1,94 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// postgresPlugin PostgreSQL，监控账号需要 pg_monitor 角色才能看到其他用户的查询
+type postgresPlugin struct{}
+
+// 执行时间超过该时长的查询计为慢查询
+const postgresSlowQueryInterval = "5 seconds"
+
+func (postgresPlugin) open(check DatabaseCheck) (*sql.DB, error) {
+	cfg, err := pq.NewConfig("")
+	if err != nil {
+		return nil, err
+	}
+	cfg.User = check.Username
+	cfg.Password = check.Password
+	cfg.Database = check.Database
+	if cfg.Database == "" {
+		cfg.Database = "postgres"
+	}
+	cfg.ConnectTimeout = databaseTimeout
+	cfg.ApplicationName = "server-ops-agent"
+	// unix socket 填写所在目录，如 /var/run/postgresql
+	if strings.HasPrefix(check.Address, "/") {
+		cfg.Host = check.Address
+		cfg.SSLMode = pq.SSLModeDisable
+	} else {
+		host, port, err := net.SplitHostPort(check.Address)
+		if err != nil {
+			return nil, err
+		}
+		p, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Host, cfg.Port = host, uint16(p)
+		// 服务器启用了 TLS 时使用，否则回退到明文连接（本机的 PostgreSQL 通常未启用 TLS）
+		cfg.SSLMode = pq.SSLModePrefer
+	}
+	connector, err := pq.NewConnectorConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(connector), nil
+}
+
+func (postgresPlugin) collect(ctx context.Context, check DatabaseCheck, db *sql.DB, status *DatabaseStatus) (databaseCounters, error) {
+	var counters databaseCounters
+	var version, maxConnections string
+	var inRecovery bool
+	var lag sql.NullFloat64
+	err := db.QueryRowContext(ctx, `SELECT current_setting('server_version'), current_setting('max_connections'), pg_is_in_recovery(),
+		CASE WHEN pg_is_in_recovery() THEN EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()) END`).
+		Scan(&version, &maxConnections, &inRecovery, &lag)
+	if err != nil {
+		return counters, err
+	}
+	status.Version = version
+	status.MaxConnections, _ = strconv.Atoi(maxConnections)
+	status.Role = "primary"
+	if inRecovery {
+		status.Role = "replica"
+		// 主库没有写入时回放时间不会更新，落后时间会持续增长，需结合主库的写入情况判断
+		if lag.Valid && lag.Float64 > 0 {
+			status.ReplicationLag = lag.Float64
+		}
+		var receiving bool
+		if err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pg_stat_wal_receiver WHERE status = 'streaming')").Scan(&receiving); err == nil && !receiving {
+			status.ReplicationError = "WAL 接收进程未连接到主库"
+		}
+	}
+
+	err = db.QueryRowContext(ctx, `SELECT count(*), count(*) FILTER (WHERE state = 'active' AND now() - query_start > interval '`+postgresSlowQueryInterval+`')
+		FROM pg_stat_activity WHERE backend_type = 'client backend'`).
+		Scan(&status.Connections, &status.SlowQueries)
+	if err != nil {
+		return counters, err
+	}
+
+	var transactions sql.NullInt64
+	if err := db.QueryRowContext(ctx, "SELECT sum(xact_commit + xact_rollback) FROM pg_stat_database").Scan(&transactions); err == nil && transactions.Valid {
+		counters.Queries, counters.HasQueries = uint64(transactions.Int64), true
+	}
+	return counters, nil
+}
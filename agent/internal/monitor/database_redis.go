@@ -0,0 +1,202 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	stdnet "net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 单个 RESP 回复的大小上限，INFO 的输出通常只有几 KB
+const redisMaxReply = 1024 * 1024
+
+// redisPlugin Redis，只执行 AUTH、SELECT、INFO 和 SLOWLOG GET，每次采集新建连接
+type redisPlugin struct{}
+
+func (redisPlugin) open(check DatabaseCheck) (*sql.DB, error) {
+	return nil, nil
+}
+
+func (redisPlugin) collect(ctx context.Context, check DatabaseCheck, _ *sql.DB, status *DatabaseStatus) (databaseCounters, error) {
+	var counters databaseCounters
+	network := "tcp"
+	if strings.HasPrefix(check.Address, "/") {
+		network = "unix"
+	}
+	conn, err := (&stdnet.Dialer{}).DialContext(ctx, network, check.Address)
+	if err != nil {
+		return counters, err
+	}
+	defer conn.Close()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(databaseTimeout)
+	}
+	conn.SetDeadline(deadline)
+	client := &redisConn{rw: bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))}
+
+	// Redis 6 起支持 ACL 用户名，未填写用户名时使用 requirepass 的单参数形式
+	if check.Password != "" {
+		args := []string{"AUTH", check.Password}
+		if check.Username != "" {
+			args = []string{"AUTH", check.Username, check.Password}
+		}
+		if _, err := client.do(args...); err != nil {
+			return counters, fmt.Errorf("认证失败: %w", err)
+		}
+	}
+	if check.Database != "" && check.Database != "0" {
+		if _, err := client.do("SELECT", check.Database); err != nil {
+			return counters, err
+		}
+	}
+
+	reply, err := client.do("INFO")
+	if err != nil {
+		return counters, err
+	}
+	info, ok := reply.(string)
+	if !ok {
+		return counters, fmt.Errorf("INFO 返回了意外的格式")
+	}
+	counters = applyRedisInfo(parseRedisInfo(info), status)
+
+	// 慢查询日志的 ID 单调递增，最新一条的 ID+1 即累计的慢查询数；命令被禁用时不上报
+	if reply, err := client.do("SLOWLOG", "GET", "1"); err == nil {
+		if entries, ok := reply.([]interface{}); ok {
+			counters.HasSlow = true
+			if len(entries) > 0 {
+				if entry, ok := entries[0].([]interface{}); ok && len(entry) > 0 {
+					if id, ok := entry[0].(int64); ok && id >= 0 {
+						counters.SlowQueries = uint64(id) + 1
+					}
+				}
+			}
+		}
+	}
+	return counters, nil
+}
+
+// parseRedisInfo 解析 INFO 输出的 key:value 行
+func parseRedisInfo(info string) map[string]string {
+	values := make(map[string]string)
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// applyRedisInfo 把 INFO 的指标写入 status，返回累计的命令数
+func applyRedisInfo(info map[string]string, status *DatabaseStatus) databaseCounters {
+	var counters databaseCounters
+	status.Version = info["redis_version"]
+	status.Connections, _ = strconv.Atoi(info["connected_clients"])
+	// maxclients 从 Redis 7 起出现在 INFO 的 clients 部分
+	status.MaxConnections, _ = strconv.Atoi(info["maxclients"])
+	status.MemoryUsed, _ = strconv.ParseUint(info["used_memory"], 10, 64)
+	status.MemoryLimit, _ = strconv.ParseUint(info["maxmemory"], 10, 64)
+	if commands, err := strconv.ParseUint(info["total_commands_processed"], 10, 64); err == nil {
+		counters.Queries, counters.HasQueries = commands, true
+	}
+
+	switch info["role"] {
+	case "master":
+		status.Role = "primary"
+	case "slave":
+		status.Role = "replica"
+		if info["master_link_status"] != "up" {
+			status.ReplicationError = "与主库的连接已断开"
+			if seconds, err := strconv.ParseFloat(info["master_link_down_since_seconds"], 64); err == nil && seconds >= 0 {
+				status.ReplicationLag = seconds
+			}
+		} else if seconds, err := strconv.ParseFloat(info["master_last_io_seconds_ago"], 64); err == nil && seconds >= 0 {
+			// 主库每 10 秒（repl-ping-replica-period）发送一次心跳，正常时不超过该值
+			status.ReplicationLag = seconds
+		}
+	}
+	return counters
+}
+
+// redisConn 最小的 RESP 客户端
+type redisConn struct {
+	rw *bufio.ReadWriter
+}
+
+// do 发送命令并读取一个回复，错误回复作为 error 返回
+func (c *redisConn) do(args ...string) (interface{}, error) {
+	fmt.Fprintf(c.rw, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.rw, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := c.rw.Flush(); err != nil {
+		return nil, err
+	}
+	return readRedisReply(c.rw.Reader, 0)
+}
+
+// readRedisReply 读取一个 RESP 回复：简单字符串和批量字符串返回 string，整数返回 int64，数组返回 []interface{}，空值返回 nil
+func readRedisReply(r *bufio.Reader, depth int) (interface{}, error) {
+	if depth > 8 {
+		return nil, fmt.Errorf("RESP 回复嵌套过深")
+	}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	if line == "" {
+		return nil, fmt.Errorf("无效的 RESP 回复")
+	}
+	payload := line[1:]
+	switch line[0] {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, errors.New(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil || size > redisMaxReply {
+			return nil, fmt.Errorf("无效的 RESP 字符串长度: %s", payload)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(payload)
+		if err != nil || count > redisMaxReply {
+			return nil, fmt.Errorf("无效的 RESP 数组长度: %s", payload)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, 0, min(count, 64))
+		for i := 0; i < count; i++ {
+			item, err := readRedisReply(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("无效的 RESP 回复: %q", line)
+}
@@ -0,0 +1,186 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	stdnet "net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func TestApplyMySQLStatus(t *testing.T) {
+	var status DatabaseStatus
+	counters := applyMySQLStatus(map[string]string{
+		"threads_connected":             "12",
+		"questions":                     "1000",
+		"slow_queries":                  "3",
+		"innodb_buffer_pool_bytes_data": "1048576",
+		"innodb_buffer_pool_size":       "134217728",
+		"max_connections":               "151",
+		"version":                       "8.0.36",
+	}, &status)
+	assert.Equal(t, 12, status.Connections)
+	assert.Equal(t, 151, status.MaxConnections)
+	assert.Equal(t, "8.0.36", status.Version)
+	assert.Equal(t, uint64(1048576), status.MemoryUsed)
+	assert.Equal(t, uint64(134217728), status.MemoryLimit)
+	assert.Equal(t, databaseCounters{Queries: 1000, SlowQueries: 3, HasQueries: true, HasSlow: true}, counters)
+}
+
+func TestApplyMySQLReplicaStatus(t *testing.T) {
+	var status DatabaseStatus
+	applyMySQLReplicaStatus(map[string]string{}, &status)
+	assert.Equal(t, "primary", status.Role)
+
+	status = DatabaseStatus{}
+	applyMySQLReplicaStatus(map[string]string{
+		"Replica_IO_Running":    "Yes",
+		"Replica_SQL_Running":   "Yes",
+		"Seconds_Behind_Source": "42",
+	}, &status)
+	assert.Equal(t, "replica", status.Role)
+	assert.Equal(t, float64(42), status.ReplicationLag)
+	assert.Empty(t, status.ReplicationError)
+
+	// 旧版本的列名，复制中断时 Seconds_Behind_Master 为 NULL
+	status = DatabaseStatus{}
+	applyMySQLReplicaStatus(map[string]string{
+		"Slave_IO_Running":  "Connecting",
+		"Slave_SQL_Running": "Yes",
+		"Last_IO_Error":     "error connecting to master",
+	}, &status)
+	assert.Equal(t, "replica", status.Role)
+	assert.Zero(t, status.ReplicationLag)
+	assert.Contains(t, status.ReplicationError, "error connecting to master")
+}
+
+func TestApplyRedisInfo(t *testing.T) {
+	info := "# Server\r\nredis_version:7.2.4\r\n# Clients\r\nconnected_clients:8\r\nmaxclients:10000\r\n# Memory\r\nused_memory:2097152\r\nmaxmemory:0\r\n" +
+		"# Stats\r\ntotal_commands_processed:5000\r\n# Replication\r\nrole:slave\r\nmaster_link_status:up\r\nmaster_last_io_seconds_ago:3\r\n"
+	var status DatabaseStatus
+	counters := applyRedisInfo(parseRedisInfo(info), &status)
+	assert.Equal(t, "7.2.4", status.Version)
+	assert.Equal(t, 8, status.Connections)
+	assert.Equal(t, 10000, status.MaxConnections)
+	assert.Equal(t, uint64(2097152), status.MemoryUsed)
+	assert.Equal(t, "replica", status.Role)
+	assert.Equal(t, float64(3), status.ReplicationLag)
+	assert.Empty(t, status.ReplicationError)
+	assert.Equal(t, uint64(5000), counters.Queries)
+
+	status = DatabaseStatus{}
+	applyRedisInfo(parseRedisInfo("role:slave\r\nmaster_link_status:down\r\nmaster_link_down_since_seconds:120\r\n"), &status)
+	assert.NotEmpty(t, status.ReplicationError)
+	assert.Equal(t, float64(120), status.ReplicationLag)
+}
+
+func TestReadRedisReply(t *testing.T) {
+	reply, err := readRedisReply(bufio.NewReader(strings.NewReader("*2\r\n*3\r\n:7\r\n$3\r\nfoo\r\n$-1\r\n+OK\r\n")), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{[]interface{}{int64(7), "foo", nil}, "OK"}, reply)
+
+	_, err = readRedisReply(bufio.NewReader(strings.NewReader("-NOAUTH Authentication required.\r\n")), 0)
+	assert.EqualError(t, err, "NOAUTH Authentication required.")
+
+	_, err = readRedisReply(bufio.NewReader(strings.NewReader("$99999999\r\n")), 0)
+	assert.Error(t, err)
+}
+
+// fakeRedis 按命令名返回 reply 给出的回复的 Redis 服务端
+func fakeRedis(t *testing.T, reply func(command string) string) string {
+	ln, err := stdnet.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn stdnet.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					req, err := readRedisReply(r, 0)
+					if err != nil {
+						return
+					}
+					args, _ := req.([]interface{})
+					if len(args) == 0 {
+						return
+					}
+					fmt.Fprint(conn, reply(strings.ToUpper(args[0].(string))))
+				}
+			}(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestCollectDatabases(t *testing.T) {
+	info := "role:master\r\nconnected_clients:4\r\ntotal_commands_processed:%d\r\n"
+	commands := 1000
+	infoReply := func() string {
+		body := fmt.Sprintf(info, commands)
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(body), body)
+	}
+	var mu sync.Mutex
+	replies := map[string]string{
+		"AUTH":    "+OK\r\n",
+		"INFO":    infoReply(),
+		"SLOWLOG": "*1\r\n*2\r\n:9\r\n:1700000000\r\n",
+	}
+	addr := fakeRedis(t, func(command string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		if reply, ok := replies[command]; ok {
+			return reply
+		}
+		return "-ERR unknown command\r\n"
+	})
+	closed, err := stdnet.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	m := New(log)
+	assert.Nil(t, m.collectDatabases())
+
+	m.SetDatabaseChecks([]DatabaseCheck{
+		{Name: "cache", Type: "redis", Address: addr, Password: "secret"},
+		{Name: "down", Type: "redis", Address: closedAddr},
+		{Name: "mongo", Type: "mongodb", Address: "127.0.0.1:27017"},
+	})
+	statuses := m.collectDatabases()
+	if assert.Len(t, statuses, 3) {
+		assert.True(t, statuses[0].Up)
+		assert.Equal(t, 4, statuses[0].Connections)
+		assert.Equal(t, "primary", statuses[0].Role)
+		// 首次采集只记录基准
+		assert.Zero(t, statuses[0].QueryRate)
+		assert.False(t, statuses[1].Up)
+		assert.NotEmpty(t, statuses[1].Error)
+		assert.Contains(t, statuses[2].Error, "mongodb")
+	}
+
+	prev := m.databases.prev["cache"]
+	prev.at = time.Now().Add(-10 * time.Second)
+	m.databases.prev["cache"] = prev
+	mu.Lock()
+	commands = 1500
+	replies["INFO"] = infoReply()
+	replies["SLOWLOG"] = "*1\r\n*2\r\n:11\r\n:1700000010\r\n"
+	mu.Unlock()
+	statuses = m.collectDatabases()
+	if assert.Len(t, statuses, 3) {
+		assert.InDelta(t, 50, statuses[0].QueryRate, 1)
+		assert.Equal(t, uint64(2), statuses[0].SlowQueries)
+	}
+}
@@ -0,0 +1,79 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ping 的默认次数，mtr 的默认轮数
+	defaultDiagnosticCount = 10
+	// 允许请求的最大次数
+	maxDiagnosticCount = 100
+	// traceroute 和 mtr 的最大跳数
+	diagnosticMaxHops = 30
+	// DiagnosticTimeout 单次诊断的最长时间
+	DiagnosticTimeout = 5 * time.Minute
+)
+
+// 诊断工具
+const (
+	DiagnosticPing       = "ping"
+	DiagnosticTraceroute = "traceroute"
+	DiagnosticMTR        = "mtr"
+)
+
+// DiagnosticCommand 构造在本机执行 ping、traceroute 或 mtr 的命令。
+// count 为 ping 的次数或 mtr 的轮数，超出范围时使用默认值，对 traceroute 无效
+func DiagnosticCommand(tool, host string, count int) (string, []string, error) {
+	return diagnosticCommand(runtime.GOOS, exec.LookPath, tool, host, count)
+}
+
+func diagnosticCommand(goos string, lookPath func(string) (string, error), tool, host string, count int) (string, []string, error) {
+	host = strings.TrimSpace(host)
+	if host == "" {
+		return "", nil, errors.New("请指定目标主机")
+	}
+	if len(host) > 253 || !hostArgPattern.MatchString(host) {
+		return "", nil, fmt.Errorf("无效的目标主机: %s", host)
+	}
+	if count <= 0 || count > maxDiagnosticCount {
+		count = defaultDiagnosticCount
+	}
+	hops := strconv.Itoa(diagnosticMaxHops)
+
+	switch strings.ToLower(strings.TrimSpace(tool)) {
+	case DiagnosticPing:
+		if goos == "windows" {
+			return "ping", []string{"-n", strconv.Itoa(count), host}, nil
+		}
+		return "ping", []string{"-c", strconv.Itoa(count), host}, nil
+	case DiagnosticTraceroute:
+		if goos == "windows" {
+			return "tracert", []string{"-d", "-w", "2000", "-h", hops, host}, nil
+		}
+		if _, err := lookPath("traceroute"); err == nil {
+			return "traceroute", []string{"-n", "-q", "1", "-w", "2", "-m", hops, host}, nil
+		}
+		// 精简系统通常没有 traceroute，但 iputils 自带 tracepath
+		if _, err := lookPath("tracepath"); err == nil {
+			return "tracepath", []string{"-n", "-m", hops, host}, nil
+		}
+		return "", nil, errors.New("未安装 traceroute 或 tracepath")
+	case DiagnosticMTR:
+		if _, err := lookPath("mtr"); err != nil {
+			return "", nil, errors.New("未安装 mtr")
+		}
+		// 报告模式在全部轮次结束后一次性输出
+		return "mtr", []string{"-r", "-w", "-b", "-c", strconv.Itoa(count), "-m", hops, host}, nil
+	default:
+		return "", nil, fmt.Errorf("不支持的诊断工具: %s", tool)
+	}
+}
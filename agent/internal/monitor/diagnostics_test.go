@@ -0,0 +1,60 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnosticCommand(t *testing.T) {
+	all := func(string) (string, error) { return "/usr/bin/x", nil }
+	none := func(string) (string, error) { return "", exec.ErrNotFound }
+	tracepathOnly := func(file string) (string, error) {
+		if file == "tracepath" {
+			return "/usr/bin/tracepath", nil
+		}
+		return "", exec.ErrNotFound
+	}
+
+	name, args, err := diagnosticCommand("linux", all, "ping", "1.1.1.1", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", name)
+	assert.Equal(t, []string{"-c", "10", "1.1.1.1"}, args)
+
+	_, args, err = diagnosticCommand("windows", none, "PING", "example.com", 4)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-n", "4", "example.com"}, args)
+
+	name, args, err = diagnosticCommand("linux", all, "traceroute", "2001:db8::1", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "traceroute", name)
+	assert.Equal(t, []string{"-n", "-q", "1", "-w", "2", "-m", "30", "2001:db8::1"}, args)
+
+	name, _, err = diagnosticCommand("linux", tracepathOnly, "traceroute", "example.com", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "tracepath", name)
+
+	name, _, err = diagnosticCommand("windows", none, "traceroute", "example.com", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "tracert", name)
+
+	name, args, err = diagnosticCommand("linux", all, "mtr", "example.com", 500)
+	assert.NoError(t, err)
+	assert.Equal(t, "mtr", name)
+	assert.Equal(t, []string{"-r", "-w", "-b", "-c", "10", "-m", "30", "example.com"}, args)
+
+	_, _, err = diagnosticCommand("linux", none, "mtr", "example.com", 0)
+	assert.ErrorContains(t, err, "未安装 mtr")
+	_, _, err = diagnosticCommand("linux", none, "traceroute", "example.com", 0)
+	assert.Error(t, err)
+
+	for _, bad := range []struct{ tool, host string }{
+		{"ping", ""}, {"ping", "-f"}, {"ping", "a b"}, {"ping", "host;reboot"}, {"nmap", "example.com"},
+	} {
+		_, _, err := diagnosticCommand("linux", all, bad.tool, bad.host, 0)
+		assert.Error(t, err, bad)
+	}
+}
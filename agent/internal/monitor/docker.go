@@ -92,8 +92,11 @@ type ComposeInfo struct {
 	Name           string   `json:"name"`
 	Status         string   `json:"status"`
 	ContainerCount int      `json:"container_count"`
-	ConfigFiles    []string `json:"config_files,omitempty"`
+	ConfigFiles    []string `json:"config_files,omitempty"`   // 全部配置文件的绝对路径，按 -f 的加载顺序
+	ComposeFile    string   `json:"compose_file,omitempty"`   // 主配置文件，面板据此跳转到文件管理编辑
+	OverrideFiles  []string `json:"override_files,omitempty"` // 叠加在主配置文件之上的覆盖文件
 	WorkingDir     string   `json:"working_dir,omitempty"`
+	Managed        bool     `json:"managed"` // 是否位于 Agent 托管目录（通过面板创建）
 	UpdatedAt      string   `json:"updated_at"`
 }
 
@@ -217,6 +220,10 @@ type DockerManager struct {
 	ctx        context.Context
 	composeDir string
 	execConns  map[string]types.HijackedResponse
+
+	// cliMode 为 true 时 Docker API 套接字不可访问，基础容器操作回退到 docker 命令行，
+	// 交互终端、实时日志和容器文件操作不可用（见 docker_access.go）
+	cliMode bool
 }
 
 // NewDockerManager 创建Docker管理器
@@ -227,6 +234,20 @@ func NewDockerManager(log *logger.Logger) (*DockerManager, error) {
 		return nil, fmt.Errorf("创建Docker客户端失败: %v", err)
 	}
 
+	// 检测 Docker API 是否可访问，区分未安装、无权限和守护进程不可用
+	cliMode := false
+	if accessErr := probeDockerAPI(cli); accessErr != nil {
+		if errors.Is(accessErr, ErrDockerNotInstalled) || !dockerCLIUsable() {
+			cli.Close()
+			if guidance := dockerAccessGuidance(accessErr); guidance != "" {
+				return nil, fmt.Errorf("%w。%s", accessErr, guidance)
+			}
+			return nil, accessErr
+		}
+		log.Warn("Docker API不可访问(%v)，回退到docker命令行模式", accessErr)
+		cliMode = true
+	}
+
 	// 创建Compose项目目录
 	composeDir := "/tmp/docker-compose"
 	if err := os.MkdirAll(composeDir, 0755); err != nil {
@@ -243,6 +264,7 @@ func NewDockerManager(log *logger.Logger) (*DockerManager, error) {
 		ctx:        ctx,
 		composeDir: composeDir,
 		execConns:  make(map[string]types.HijackedResponse),
+		cliMode:    cliMode,
 	}, nil
 }
 
@@ -278,6 +300,15 @@ func (dm *DockerManager) StatContainerPath(containerID, path string) (*container
 	return &stat, nil
 }
 
+// ContainerIdentity 返回容器的完整ID和名称（不含前导 /）
+func (dm *DockerManager) ContainerIdentity(containerID string) (string, string, error) {
+	inspect, err := dm.client.ContainerInspect(dm.ctx, containerID)
+	if err != nil {
+		return "", "", err
+	}
+	return inspect.ID, strings.TrimPrefix(inspect.Name, "/"), nil
+}
+
 // RunCommand 在容器内执行命令并返回 stdout/stderr
 func (dm *DockerManager) RunCommand(containerID string, cmd []string, env []string) (string, string, error) {
 	execIDResp, err := dm.client.ContainerExecCreate(dm.ctx, containerID, container.ExecOptions{
@@ -316,6 +347,10 @@ func (dm *DockerManager) RunCommand(containerID string, cmd []string, env []stri
 
 // GetContainers 获取容器列表
 func (dm *DockerManager) GetContainers(all bool) ([]ContainerInfo, error) {
+	if dm.cliMode {
+		return dm.cliGetContainers(all)
+	}
+
 	options := container.ListOptions{
 		All: all,
 	}
@@ -377,6 +412,10 @@ func (dm *DockerManager) GetContainers(all bool) ([]ContainerInfo, error) {
 
 // GetContainerLogs 获取容器日志
 func (dm *DockerManager) GetContainerLogs(containerID string, tail int) (string, error) {
+	if dm.cliMode {
+		return dm.cliGetContainerLogs(containerID, tail)
+	}
+
 	tailStr := "all"
 	if tail > 0 {
 		tailStr = fmt.Sprintf("%d", tail)
@@ -454,6 +493,13 @@ func (dm *DockerManager) StreamContainerLogs(ctx context.Context, containerID st
 
 // StartContainer 启动容器
 func (dm *DockerManager) StartContainer(containerID string) error {
+	if dm.cliMode {
+		if err := dm.cliContainerAction("start", containerID, 0, false); err != nil {
+			return fmt.Errorf("启动容器失败: %v", err)
+		}
+		return nil
+	}
+
 	if err := dm.client.ContainerStart(dm.ctx, containerID, container.StartOptions{}); err != nil {
 		return fmt.Errorf("启动容器失败: %v", err)
 	}
@@ -543,6 +589,13 @@ func (dm *DockerManager) IsExecRunning(execID string) bool {
 
 // StopContainer 停止容器
 func (dm *DockerManager) StopContainer(containerID string, timeout int) error {
+	if dm.cliMode {
+		if err := dm.cliContainerAction("stop", containerID, timeout, false); err != nil {
+			return fmt.Errorf("停止容器失败: %v", err)
+		}
+		return nil
+	}
+
 	stopOpts := container.StopOptions{}
 	if timeout > 0 {
 		stopOpts.Timeout = &timeout
@@ -556,6 +609,13 @@ func (dm *DockerManager) StopContainer(containerID string, timeout int) error {
 
 // RestartContainer 重启容器
 func (dm *DockerManager) RestartContainer(containerID string, timeout int) error {
+	if dm.cliMode {
+		if err := dm.cliContainerAction("restart", containerID, timeout, false); err != nil {
+			return fmt.Errorf("重启容器失败: %v", err)
+		}
+		return nil
+	}
+
 	stopOpts := container.StopOptions{}
 	if timeout > 0 {
 		stopOpts.Timeout = &timeout
@@ -569,6 +629,13 @@ func (dm *DockerManager) RestartContainer(containerID string, timeout int) error
 
 // RemoveContainer 删除容器
 func (dm *DockerManager) RemoveContainer(containerID string, force bool) error {
+	if dm.cliMode {
+		if err := dm.cliContainerAction("rm", containerID, 0, force); err != nil {
+			return fmt.Errorf("删除容器失败: %v", err)
+		}
+		return nil
+	}
+
 	// 如果不是强制删除，先检查容器状态
 	if !force {
 		containerJSON, err := dm.client.ContainerInspect(dm.ctx, containerID)
@@ -643,8 +710,17 @@ func (dm *DockerManager) GetImages() ([]ImageInfo, error) {
 }
 
 // PullImage 拉取镜像
-func (dm *DockerManager) PullImage(imageRef string) error {
-	// 使用命令行方式拉取镜像，避免认证问题
+func (dm *DockerManager) PullImage(imageRef string, auth *RegistryAuth) error {
+	// 使用命令行方式拉取镜像，避免认证问题；面板下发了仓库凭据时在临时配置目录中登录后拉取
+	if auth != nil {
+		return withRegistryConfig(dm.ctx, auth, func(configDir string) error {
+			output, err := exec.Command("docker", "--config", configDir, "pull", imageRef).CombinedOutput()
+			if err != nil {
+				return fmt.Errorf("拉取镜像失败: %v, 输出: %s", err, string(output))
+			}
+			return nil
+		})
+	}
 	cmd := exec.Command("docker", "pull", imageRef)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -751,19 +827,35 @@ func (dm *DockerManager) GetComposes() ([]ComposeInfo, error) {
 			}
 		}
 
-		composes = append(composes, ComposeInfo{
+		info := ComposeInfo{
 			Name:           item.Name,
 			Status:         status,
 			ContainerCount: containerCount,
-			ConfigFiles:    configFiles,
 			WorkingDir:     workingDir,
 			UpdatedAt:      time.Now().Format(time.RFC3339),
-		})
+		}
+		dm.fillComposeFiles(&info, configFiles)
+		composes = append(composes, info)
 	}
 
 	return composes, nil
 }
 
+// fillComposeFiles 把配置文件解析为绝对路径，并区分主配置文件和覆盖文件
+func (dm *DockerManager) fillComposeFiles(info *ComposeInfo, configFiles []string) {
+	files, err := resolveConfigFilePaths(info.WorkingDir, configFiles)
+	if err != nil {
+		// 缺少工作目录时无法解析相对路径，原样返回
+		files = configFiles
+	}
+	info.ConfigFiles = files
+	info.ComposeFile, info.OverrideFiles = splitComposeFiles(files)
+	if info.WorkingDir == "" && info.ComposeFile != "" && filepath.IsAbs(info.ComposeFile) {
+		info.WorkingDir = filepath.Dir(info.ComposeFile)
+	}
+	info.Managed = info.WorkingDir != "" && isSubPath(dm.composeDir, info.WorkingDir)
+}
+
 // getComposesFromManagedDir 从托管目录读取 Compose 项目列表（docker compose ls 不可用时的回退）
 func (dm *DockerManager) getComposesFromManagedDir() ([]ComposeInfo, error) {
 	entries, err := os.ReadDir(dm.composeDir)
@@ -779,19 +871,20 @@ func (dm *DockerManager) getComposesFromManagedDir() ([]ComposeInfo, error) {
 		projectName := entry.Name()
 		projectPath := filepath.Join(dm.composeDir, projectName)
 
-		configFile := findComposeFile(projectPath)
-		if configFile == "" {
+		configFiles := findComposeFiles(projectPath)
+		if len(configFiles) == 0 {
 			continue
 		}
 
-		composes = append(composes, ComposeInfo{
+		info := ComposeInfo{
 			Name:           projectName,
 			Status:         "unknown",
 			ContainerCount: 0,
-			ConfigFiles:    []string{configFile},
 			WorkingDir:     projectPath,
 			UpdatedAt:      time.Now().Format(time.RFC3339),
-		})
+		}
+		dm.fillComposeFiles(&info, configFiles)
+		composes = append(composes, info)
 	}
 
 	return composes, nil
@@ -826,8 +919,8 @@ func (dm *DockerManager) GetComposeConfig(projectName string) (string, error) {
 
 	// 回退：尝试托管目录 /tmp/docker-compose/{projectName}
 	projectPath := filepath.Join(dm.composeDir, projectName)
-	configFile := findComposeFile(projectPath)
-	if configFile == "" {
+	configFiles := findComposeFiles(projectPath)
+	if len(configFiles) == 0 {
 		// 如果之前有发现错误，返回该错误
 		if discoverErr != nil {
 			return "", fmt.Errorf("%w: %s", ErrComposeConfigUnknownPath, projectName)
@@ -836,11 +929,11 @@ func (dm *DockerManager) GetComposeConfig(projectName string) (string, error) {
 	}
 
 	// 检查配置文件是否可访问
-	if err := checkFilesAccessible([]string{configFile}); err != nil {
+	if err := checkFilesAccessible(configFiles); err != nil {
 		return "", err
 	}
 
-	return dm.runComposeConfig(projectName, projectPath, []string{configFile})
+	return dm.runComposeConfig(projectName, projectPath, configFiles)
 }
 
 // ComposeUp 启动Compose项目
@@ -850,21 +943,7 @@ func (dm *DockerManager) ComposeUp(projectName string) error {
 		return err
 	}
 
-	// 检查配置文件
-	projectPath := filepath.Join(dm.composeDir, projectName)
-	configFile := filepath.Join(projectPath, "docker-compose.yml")
-	if _, err := os.Stat(configFile); os.IsNotExist(err) {
-		// 尝试检查docker-compose.yaml
-		configFile = filepath.Join(projectPath, "docker-compose.yaml")
-		if _, err := os.Stat(configFile); os.IsNotExist(err) {
-			return fmt.Errorf("Compose配置文件不存在")
-		}
-	}
-
-	// 执行docker-compose up命令
-	cmd := exec.Command("docker", "compose", "-f", configFile, "-p", projectName, "up", "-d")
-	cmd.Dir = projectPath
-	output, err := cmd.CombinedOutput()
+	output, err := dm.runCompose(projectName, "up", "-d")
 	if err != nil {
 		return fmt.Errorf("启动Compose项目失败: %v, 输出: %s", err, string(output))
 	}
@@ -879,21 +958,7 @@ func (dm *DockerManager) ComposeDown(projectName string) error {
 		return err
 	}
 
-	// 检查配置文件
-	projectPath := filepath.Join(dm.composeDir, projectName)
-	configFile := filepath.Join(projectPath, "docker-compose.yml")
-	if _, err := os.Stat(configFile); os.IsNotExist(err) {
-		// 尝试检查docker-compose.yaml
-		configFile = filepath.Join(projectPath, "docker-compose.yaml")
-		if _, err := os.Stat(configFile); os.IsNotExist(err) {
-			return fmt.Errorf("Compose配置文件不存在")
-		}
-	}
-
-	// 执行docker-compose down命令
-	cmd := exec.Command("docker", "compose", "-f", configFile, "-p", projectName, "down")
-	cmd.Dir = projectPath
-	output, err := cmd.CombinedOutput()
+	output, err := dm.runCompose(projectName, "down")
 	if err != nil {
 		return fmt.Errorf("停止Compose项目失败: %v, 输出: %s", err, string(output))
 	}
@@ -901,6 +966,43 @@ func (dm *DockerManager) ComposeDown(projectName string) error {
 	return nil
 }
 
+// runCompose 在项目的实际目录下执行 docker compose 子命令，带上全部配置文件（含覆盖文件）
+func (dm *DockerManager) runCompose(projectName string, subcommand ...string) ([]byte, error) {
+	workingDir, configFiles, err := dm.locateComposeProject(projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"compose", "--project-directory", workingDir, "-p", projectName}
+	for _, f := range configFiles {
+		args = append(args, "-f", f)
+	}
+	args = append(args, subcommand...)
+
+	cmd := exec.Command("docker", args...)
+	cmd.Dir = workingDir
+	return cmd.CombinedOutput()
+}
+
+// locateComposeProject 返回项目的工作目录和全部配置文件的绝对路径。
+// 优先使用 docker compose ls / 容器 labels 记录的实际位置，找不到时回退到托管目录
+func (dm *DockerManager) locateComposeProject(projectName string) (string, []string, error) {
+	if meta, err := dm.discoverComposeProjectMeta(projectName); err == nil {
+		if files, err := resolveConfigFilePaths(meta.workingDir, meta.configFiles); err == nil {
+			if err := checkFilesAccessible(files); err == nil {
+				return meta.workingDir, files, nil
+			}
+		}
+	}
+
+	projectPath := filepath.Join(dm.composeDir, projectName)
+	configFiles := findComposeFiles(projectPath)
+	if len(configFiles) == 0 {
+		return "", nil, fmt.Errorf("Compose配置文件不存在")
+	}
+	return projectPath, configFiles, nil
+}
+
 // RemoveCompose 删除Compose项目
 func (dm *DockerManager) RemoveCompose(projectName string) error {
 	projectName, err := sanitizeComposeProjectName(projectName)
@@ -924,13 +1026,32 @@ func (dm *DockerManager) RemoveCompose(projectName string) error {
 	return nil
 }
 
-// CreateCompose 创建Compose项目
+// CreateCompose 创建Compose项目，或更新已有项目的主配置文件。
+// 已存在的项目（包括不在托管目录中的）直接写回其主配置文件，覆盖文件保持不变；
+// 新项目写入托管目录
 func (dm *DockerManager) CreateCompose(projectName string, content string) error {
 	projectName, err := sanitizeComposeProjectName(projectName)
 	if err != nil {
 		return err
 	}
 
+	if _, err := dm.ValidateCompose(projectName, content); err != nil {
+		return err
+	}
+
+	if _, configFiles, err := dm.locateComposeProject(projectName); err == nil {
+		configFile, _ := splitComposeFiles(configFiles)
+		mode := os.FileMode(0644)
+		if info, err := os.Stat(configFile); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if err := os.WriteFile(configFile, []byte(content), mode); err != nil {
+			return fmt.Errorf("写入配置文件失败: %v", err)
+		}
+		dm.log.Info("已更新Compose项目 %s 的配置文件: %s", projectName, configFile)
+		return nil
+	}
+
 	// 创建项目目录
 	projectPath := filepath.Join(dm.composeDir, projectName)
 	if err := os.MkdirAll(projectPath, 0755); err != nil {
@@ -946,6 +1067,21 @@ func (dm *DockerManager) CreateCompose(projectName string, content string) error
 	return nil
 }
 
+// ValidateCompose 解析待写入的主配置文件内容并返回结构化结果，不写入磁盘。
+// 已存在的项目会与其覆盖文件合并后再校验，与 docker compose 实际加载的结果一致
+func (dm *DockerManager) ValidateCompose(projectName string, content string) (*ComposeProjectInfo, error) {
+	projectName, err := sanitizeComposeProjectName(projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	var overrideFiles []string
+	if _, configFiles, err := dm.locateComposeProject(projectName); err == nil {
+		_, overrideFiles = splitComposeFiles(configFiles)
+	}
+	return parseComposeFiles(content, overrideFiles)
+}
+
 // CreateContainer 创建容器
 func (dm *DockerManager) CreateContainer(name string, image string, ports []string, volumes []string,
 	env map[string]string, cmd string, restart string, network string) (string, error) {
@@ -1332,6 +1468,52 @@ func findComposeFile(dir string) string {
 	return ""
 }
 
+// composeOverrideNames 与主配置文件同目录、未指定 -f 时 docker compose 自动叠加的覆盖文件
+var composeOverrideNames = []string{
+	"docker-compose.override.yml",
+	"docker-compose.override.yaml",
+	"compose.override.yml",
+	"compose.override.yaml",
+}
+
+// findComposeFiles 查找目录下的主配置文件及自动加载的覆盖文件，主配置文件在前
+func findComposeFiles(dir string) []string {
+	configFile := findComposeFile(dir)
+	if configFile == "" {
+		return nil
+	}
+	files := []string{configFile}
+	for _, name := range composeOverrideNames {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			files = append(files, path)
+			// docker compose 只加载第一个找到的覆盖文件
+			break
+		}
+	}
+	return files
+}
+
+// splitComposeFiles 按加载顺序拆分出主配置文件和覆盖文件（第一个之后的都叠加在其上）
+func splitComposeFiles(files []string) (string, []string) {
+	if len(files) == 0 {
+		return "", nil
+	}
+	if len(files) == 1 {
+		return files[0], nil
+	}
+	return files[0], append([]string(nil), files[1:]...)
+}
+
+// isSubPath 判断 path 是否位于 base 目录内（含 base 本身）
+func isSubPath(base, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(base), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
 // firstNonEmpty 返回第一个非空字符串
 func firstNonEmpty(values ...string) string {
 	for _, v := range values {
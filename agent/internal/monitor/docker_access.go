@@ -0,0 +1,246 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+// Docker 访问相关错误类型，用于区分 "未安装" 与 "无权限" 等场景
+var (
+	// ErrDockerNotInstalled 表示主机上既没有 Docker 守护进程套接字，也找不到 docker 命令
+	ErrDockerNotInstalled = errors.New("Docker未安装")
+	// ErrDockerPermissionDenied 表示 Docker 套接字存在但当前用户无权访问
+	ErrDockerPermissionDenied = errors.New("无权访问Docker套接字")
+	// ErrDockerDaemonUnavailable 表示 docker 命令存在但守护进程未运行或无法连接
+	ErrDockerDaemonUnavailable = errors.New("Docker守护进程不可用")
+)
+
+// Docker 访问状态
+const (
+	DockerStatusOK               = "ok"
+	DockerStatusCLIFallback      = "cli_fallback"
+	DockerStatusNotInstalled     = "not_installed"
+	DockerStatusPermissionDenied = "permission_denied"
+	DockerStatusUnavailable      = "unavailable"
+)
+
+const dockerProbeTimeout = 3 * time.Second
+
+// probeDockerAPI 通过 Ping 检测 Docker API 是否可用，并对失败原因分类
+func probeDockerAPI(cli *client.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerProbeTimeout)
+	defer cancel()
+
+	if _, err := cli.Ping(ctx); err != nil {
+		return classifyDockerError(err)
+	}
+	return nil
+}
+
+// classifyDockerError 将 Docker SDK 的连接错误归类为可识别的错误类型。
+// 套接字不存在时 SDK 只返回 "Cannot connect to the Docker daemon"，不带底层的文件错误，
+// 因此连接失败时同样根据是否存在 docker 命令区分未安装和守护进程未运行
+func classifyDockerError(err error) error {
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "permission denied"):
+		return fmt.Errorf("%w: %v", ErrDockerPermissionDenied, err)
+	case strings.Contains(msg, "no such file or directory"), strings.Contains(msg, "cannot find the file specified"),
+		client.IsErrConnectionFailed(err):
+		if _, lookErr := exec.LookPath("docker"); lookErr != nil {
+			return fmt.Errorf("%w: %v", ErrDockerNotInstalled, err)
+		}
+		return fmt.Errorf("%w: %v", ErrDockerDaemonUnavailable, err)
+	default:
+		return fmt.Errorf("%w: %v", ErrDockerDaemonUnavailable, err)
+	}
+}
+
+// dockerCLIUsable 检测 docker 命令行是否能够访问守护进程
+func dockerCLIUsable() bool {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dockerProbeTimeout)
+	defer cancel()
+	return exec.CommandContext(ctx, "docker", "version", "--format", "{{.Server.Version}}").Run() == nil
+}
+
+// dockerAccessGuidance 根据错误类型返回可操作的处理建议
+func dockerAccessGuidance(err error) string {
+	switch {
+	case errors.Is(err, ErrDockerPermissionDenied):
+		return "请将运行Agent的用户加入docker组 (sudo usermod -aG docker <用户名>) 并重启Agent，或以root身份运行Agent"
+	case errors.Is(err, ErrDockerNotInstalled):
+		return "未检测到Docker，请先安装Docker后再使用容器管理功能"
+	case errors.Is(err, ErrDockerDaemonUnavailable):
+		return "请确认Docker服务已启动 (systemctl start docker)，并检查DOCKER_HOST配置"
+	default:
+		return ""
+	}
+}
+
+// DockerStatusForError 将访问错误映射为能力上报中的状态值
+func DockerStatusForError(err error) string {
+	switch {
+	case err == nil:
+		return DockerStatusOK
+	case errors.Is(err, ErrDockerPermissionDenied):
+		return DockerStatusPermissionDenied
+	case errors.Is(err, ErrDockerNotInstalled):
+		return DockerStatusNotInstalled
+	default:
+		return DockerStatusUnavailable
+	}
+}
+
+// ProbeDocker 检测 Docker 的可用状态，供能力上报使用
+func ProbeDocker() *DockerCapability {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return &DockerCapability{Status: DockerStatusUnavailable, Message: err.Error()}
+	}
+	defer cli.Close()
+
+	accessErr := probeDockerAPI(cli)
+	if accessErr == nil {
+		return &DockerCapability{Available: true, Mode: "api", Status: DockerStatusOK}
+	}
+
+	capability := &DockerCapability{
+		Status:   DockerStatusForError(accessErr),
+		Message:  accessErr.Error(),
+		Guidance: dockerAccessGuidance(accessErr),
+	}
+	if !errors.Is(accessErr, ErrDockerNotInstalled) && dockerCLIUsable() {
+		capability.Available = true
+		capability.Mode = "cli"
+		capability.Status = DockerStatusCLIFallback
+	}
+	return capability
+}
+
+// ─── docker CLI 回退实现 ──────────────────────────────────────────────────────
+// 当 Docker API 套接字不可访问但 docker 命令可用时（例如通过 DOCKER_CONTEXT 访问
+// rootless 守护进程），以下方法替代 SDK 调用，保证基础容器管理功能可用。
+//
+// 这里的回退只覆盖容器列表、日志和 start/stop/restart/rm 四种生命周期操作；
+// 网络、卷、资源统计、单次 exec 和镜像拉取在各自的文件中有命令行分支，
+// 镜像列表/删除、创建容器和 Compose 本身就通过 docker 命令执行。
+// 以下功能必须使用 Docker API，命令行模式下不可用，调用时返回 API 连接错误：
+//   - 容器交互终端（StartExecSession 及其 Write/Resize）
+//   - 实时跟随日志（StreamContainerLogs）
+//   - 容器内文件浏览、上传和下载（CopyFromContainer/CopyToContainer/StatContainerPath）
+//   - RunCommand 和 ContainerIdentity
+
+// runDockerCLI 执行 docker 命令并返回标准输出
+func (dm *DockerManager) runDockerCLI(args ...string) (string, error) {
+	return dm.runDockerCLIContext(dm.ctx, args...)
+}
+
+// runDockerCLIContext 执行 docker 命令，ctx 取消时终止命令
+func (dm *DockerManager) runDockerCLIContext(ctx context.Context, args ...string) (string, error) {
+	output, err := exec.CommandContext(ctx, "docker", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%v, 输出: %s", err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// cliGetContainers 通过 docker ps 获取容器列表
+func (dm *DockerManager) cliGetContainers(all bool) ([]ContainerInfo, error) {
+	args := []string{"ps", "--no-trunc", "--format", "{{json .}}"}
+	if all {
+		args = append(args, "-a")
+	}
+	output, err := dm.runDockerCLI(args...)
+	if err != nil {
+		return nil, fmt.Errorf("获取容器列表失败: %v", err)
+	}
+
+	var containerInfos []ContainerInfo
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		var item struct {
+			ID        string
+			Names     string
+			Image     string
+			Status    string
+			State     string
+			CreatedAt string
+			Ports     string
+			Command   string
+			Mounts    string
+		}
+		if err := json.Unmarshal([]byte(line), &item); err != nil {
+			dm.log.Warn("解析容器信息失败: %v", err)
+			continue
+		}
+
+		containerInfos = append(containerInfos, ContainerInfo{
+			ID:      item.ID,
+			Name:    item.Names,
+			Image:   item.Image,
+			Status:  item.Status,
+			State:   item.State,
+			Created: item.CreatedAt,
+			Ports:   splitCLIList(item.Ports),
+			Command: strings.Trim(item.Command, "\""),
+			Mounts:  splitCLIList(item.Mounts),
+		})
+	}
+	return containerInfos, nil
+}
+
+// cliContainerAction 通过 docker CLI 执行容器生命周期操作
+func (dm *DockerManager) cliContainerAction(action, containerID string, timeout int, force bool) error {
+	args := []string{action}
+	if (action == "stop" || action == "restart") && timeout > 0 {
+		args = append(args, "-t", strconv.Itoa(timeout))
+	}
+	if action == "rm" && force {
+		args = append(args, "-f")
+	}
+	args = append(args, containerID)
+
+	_, err := dm.runDockerCLI(args...)
+	return err
+}
+
+// cliGetContainerLogs 通过 docker logs 获取容器日志
+func (dm *DockerManager) cliGetContainerLogs(containerID string, tail int) (string, error) {
+	tailStr := "all"
+	if tail > 0 {
+		tailStr = strconv.Itoa(tail)
+	}
+	output, err := dm.runDockerCLI("logs", "--timestamps", "--tail", tailStr, containerID)
+	if err != nil {
+		return "", fmt.Errorf("获取容器日志失败: %v", err)
+	}
+	return output, nil
+}
+
+// splitCLIList 拆分 docker CLI 输出中逗号分隔的列表字段
+func splitCLIList(s string) []string {
+	var result []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
@@ -0,0 +1,200 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+// fakeDockerScript 模拟 docker 命令：记录每次调用的参数，docker version 的退出码由
+// FAKE_DOCKER_VERSION_EXIT 控制，docker ps 和 docker logs 输出固定内容
+const fakeDockerScript = `#!/bin/sh
+echo "$*" >> "$FAKE_DOCKER_LOG"
+case "$1" in
+version) exit "${FAKE_DOCKER_VERSION_EXIT:-0}" ;;
+ps)
+  echo '{"ID":"abc123","Names":"web","Image":"nginx:latest","Status":"Up 2 hours","State":"running","CreatedAt":"2026-01-01 00:00:00 +0000 UTC","Ports":"0.0.0.0:80->80/tcp, :::80->80/tcp","Command":"\"nginx -g\"","Mounts":"data,/srv"}'
+  echo 'not json'
+  echo ''
+  echo '{"ID":"def456","Names":"db","Image":"postgres:16","State":"exited"}'
+  ;;
+logs) echo "2026-01-01T00:00:00Z hello" ;;
+rm) [ "$2" = "missing" ] && { echo "No such container: missing" >&2; exit 1; } ;;
+esac
+exit 0
+`
+
+// installFakeDocker 把模拟的 docker 命令放到 PATH 最前面，返回记录调用参数的函数
+func installFakeDocker(t *testing.T) func() []string {
+	if runtime.GOOS == "windows" {
+		t.Skip("模拟的 docker 命令是 shell 脚本")
+	}
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "docker"), []byte(fakeDockerScript), 0755))
+	logFile := filepath.Join(dir, "calls.log")
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("FAKE_DOCKER_LOG", logFile)
+	return func() []string {
+		data, _ := os.ReadFile(logFile)
+		os.Remove(logFile)
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+}
+
+func TestClassifyDockerError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		path   string
+		want   error
+		status string
+	}{
+		{"无权限", errors.New("dial unix /var/run/docker.sock: connect: permission denied"), "", ErrDockerPermissionDenied, DockerStatusPermissionDenied},
+		{"套接字不存在且没有docker命令", errors.New("dial unix /var/run/docker.sock: connect: no such file or directory"), "empty", ErrDockerNotInstalled, DockerStatusNotInstalled},
+		{"套接字不存在但有docker命令", errors.New("dial unix /var/run/docker.sock: connect: no such file or directory"), "fake", ErrDockerDaemonUnavailable, DockerStatusUnavailable},
+		{"Windows命名管道不存在", errors.New("open //./pipe/docker_engine: The system cannot find the file specified."), "empty", ErrDockerNotInstalled, DockerStatusNotInstalled},
+		{"其他错误", errors.New("Cannot connect to the Docker daemon at tcp://10.0.0.1:2375"), "", ErrDockerDaemonUnavailable, DockerStatusUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			switch tt.path {
+			case "empty":
+				t.Setenv("PATH", t.TempDir())
+			case "fake":
+				installFakeDocker(t)
+			}
+			err := classifyDockerError(tt.err)
+			assert.ErrorIs(t, err, tt.want)
+			assert.Contains(t, err.Error(), tt.err.Error())
+			assert.Equal(t, tt.status, DockerStatusForError(err))
+			assert.NotEmpty(t, dockerAccessGuidance(err))
+		})
+	}
+
+	assert.Equal(t, DockerStatusOK, DockerStatusForError(nil))
+	assert.Empty(t, dockerAccessGuidance(errors.New("其他错误")))
+}
+
+func TestProbeDockerFallsBackToCLI(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("使用 unix 套接字模拟 Docker API 不可访问")
+	}
+	// Docker API 套接字不存在
+	t.Setenv("DOCKER_HOST", "unix://"+filepath.Join(t.TempDir(), "docker.sock"))
+
+	// 没有 docker 命令：未安装，不回退
+	t.Setenv("PATH", t.TempDir())
+	capability := ProbeDocker()
+	assert.False(t, capability.Available)
+	assert.Equal(t, DockerStatusNotInstalled, capability.Status)
+	assert.NotEmpty(t, capability.Guidance)
+
+	// docker 命令可以访问守护进程：回退到命令行模式，仍然上报失败原因
+	calls := installFakeDocker(t)
+	capability = ProbeDocker()
+	assert.True(t, capability.Available)
+	assert.Equal(t, "cli", capability.Mode)
+	assert.Equal(t, DockerStatusCLIFallback, capability.Status)
+	assert.Contains(t, capability.Message, "Cannot connect to the Docker daemon")
+	assert.Equal(t, []string{"version --format {{.Server.Version}}"}, calls())
+
+	// docker 命令也无法访问守护进程：不可用
+	t.Setenv("FAKE_DOCKER_VERSION_EXIT", "1")
+	capability = ProbeDocker()
+	assert.False(t, capability.Available)
+	assert.Empty(t, capability.Mode)
+	assert.Equal(t, DockerStatusUnavailable, capability.Status)
+
+	// 创建管理器时同样按命令行是否可用决定回退还是报错
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	_, err = NewDockerManager(log)
+	assert.ErrorIs(t, err, ErrDockerDaemonUnavailable)
+	assert.Contains(t, err.Error(), "systemctl start docker")
+
+	t.Setenv("FAKE_DOCKER_VERSION_EXIT", "0")
+	dm, err := NewDockerManager(log)
+	if assert.NoError(t, err) {
+		assert.True(t, dm.cliMode)
+		dm.Close()
+	}
+}
+
+func TestDockerCLIFallback(t *testing.T) {
+	calls := installFakeDocker(t)
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	dm := &DockerManager{log: log, ctx: context.Background(), cliMode: true}
+
+	// 容器列表：逐行解析 JSON，跳过无法解析的行
+	containers, err := dm.GetContainers(true)
+	assert.NoError(t, err)
+	if assert.Len(t, containers, 2) {
+		assert.Equal(t, ContainerInfo{
+			ID:      "abc123",
+			Name:    "web",
+			Image:   "nginx:latest",
+			Status:  "Up 2 hours",
+			State:   "running",
+			Created: "2026-01-01 00:00:00 +0000 UTC",
+			Ports:   []string{"0.0.0.0:80->80/tcp", ":::80->80/tcp"},
+			Command: "nginx -g",
+			Mounts:  []string{"data", "/srv"},
+		}, containers[0])
+		assert.Equal(t, "db", containers[1].Name)
+		assert.Nil(t, containers[1].Ports)
+	}
+	_, err = dm.GetContainers(false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"ps --no-trunc --format {{json .}} -a",
+		"ps --no-trunc --format {{json .}}",
+	}, calls())
+
+	// 日志：tail 为 0 时读取全部
+	logs, err := dm.GetContainerLogs("abc123", 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "2026-01-01T00:00:00Z hello\n", logs)
+	_, err = dm.GetContainerLogs("abc123", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"logs --timestamps --tail 100 abc123",
+		"logs --timestamps --tail all abc123",
+	}, calls())
+
+	// 生命周期操作：只有 stop/restart 带超时，只有 rm 带 -f
+	assert.NoError(t, dm.StartContainer("abc123"))
+	assert.NoError(t, dm.StopContainer("abc123", 5))
+	assert.NoError(t, dm.StopContainer("abc123", 0))
+	assert.NoError(t, dm.RestartContainer("abc123", 10))
+	assert.NoError(t, dm.RemoveContainer("abc123", true))
+	assert.NoError(t, dm.RemoveContainer("abc123", false))
+	assert.Equal(t, []string{
+		"start abc123",
+		"stop -t 5 abc123",
+		"stop abc123",
+		"restart -t 10 abc123",
+		"rm -f abc123",
+		"rm abc123",
+	}, calls())
+
+	// 命令失败时错误中带上命令输出
+	err = dm.RemoveContainer("missing", false)
+	assert.ErrorContains(t, err, "删除容器失败")
+	assert.ErrorContains(t, err, "No such container: missing")
+}
+
+func TestSplitCLIList(t *testing.T) {
+	assert.Nil(t, splitCLIList(""))
+	assert.Nil(t, splitCLIList(" , "))
+	assert.Equal(t, []string{"a", "b"}, splitCLIList("a, ,b,"))
+}
@@ -0,0 +1,243 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxComposeReplicas 单个服务允许扩缩到的最大副本数
+const maxComposeReplicas = 100
+
+// composeServiceNamePattern Compose 服务名的合法格式，同时用于过滤 docker compose 输出中的警告行
+var composeServiceNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// ComposeServiceContainer Compose 服务下的容器
+type ComposeServiceContainer struct {
+	Name   string `json:"name"`
+	State  string `json:"state"`
+	Status string `json:"status"`
+}
+
+// ComposeServiceStatus Compose 项目中服务的运行状态及其容器
+type ComposeServiceStatus struct {
+	Name       string                    `json:"name"`
+	Running    int                       `json:"running"`
+	Containers []ComposeServiceContainer `json:"containers"`
+}
+
+// composePSItem docker compose ps --format json 的输出项
+type composePSItem struct {
+	Name    string
+	Service string
+	State   string
+	Status  string
+}
+
+// UpdateCompose 更新已有Compose项目的主配置文件，写入前用 docker compose config 校验新内容
+// （与覆盖文件合并，按项目目录解析相对路径和 .env）。apply 为 true 时随后执行 up -d，
+// 失败则恢复原配置文件
+func (dm *DockerManager) UpdateCompose(projectName string, content string, apply bool) error {
+	projectName, err := sanitizeComposeProjectName(projectName)
+	if err != nil {
+		return err
+	}
+
+	workingDir, configFiles, err := dm.locateComposeProject(projectName)
+	if err != nil {
+		return fmt.Errorf("Compose项目 %s 不存在: %v", projectName, err)
+	}
+	configFile, overrideFiles := splitComposeFiles(configFiles)
+
+	// 新内容先写入同目录的临时文件校验，相对路径与正式文件一致
+	tmp, err := os.CreateTemp(filepath.Dir(configFile), ".compose-validate-*.yml")
+	if err != nil {
+		return fmt.Errorf("创建临时配置文件失败: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("写入临时配置文件失败: %v", err)
+	}
+	if _, err := dm.runComposeConfig(projectName, workingDir, append([]string{tmp.Name()}, overrideFiles...)); err != nil {
+		return fmt.Errorf("配置校验失败: %v", err)
+	}
+
+	info, err := os.Stat(configFile)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	previous, err := os.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	if err := os.WriteFile(configFile, []byte(content), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("写入配置文件失败: %v", err)
+	}
+	dm.log.Info("已更新Compose项目 %s 的配置文件: %s", projectName, configFile)
+
+	if !apply {
+		return nil
+	}
+	if output, err := dm.runCompose(projectName, "up", "-d", "--remove-orphans"); err != nil {
+		if restoreErr := os.WriteFile(configFile, previous, info.Mode().Perm()); restoreErr != nil {
+			dm.log.Error("恢复Compose项目 %s 的配置文件失败: %v", projectName, restoreErr)
+		}
+		return fmt.Errorf("应用配置失败，已恢复原配置文件: %v, 输出: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// GetComposeSource 读取Compose项目主配置文件的原始内容，供编辑使用
+func (dm *DockerManager) GetComposeSource(projectName string) (string, string, error) {
+	projectName, err := sanitizeComposeProjectName(projectName)
+	if err != nil {
+		return "", "", err
+	}
+
+	_, configFiles, err := dm.locateComposeProject(projectName)
+	if err != nil {
+		return "", "", err
+	}
+	configFile, _ := splitComposeFiles(configFiles)
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		return "", "", fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	return configFile, string(content), nil
+}
+
+// GetComposeServices 获取Compose项目的服务列表及各服务的容器状态
+func (dm *DockerManager) GetComposeServices(projectName string) ([]ComposeServiceStatus, error) {
+	projectName, err := sanitizeComposeProjectName(projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := dm.composeServiceNames(projectName)
+	if err != nil {
+		return nil, err
+	}
+	output, err := dm.runCompose(projectName, "ps", "-a", "--format", "json")
+	if err != nil {
+		return nil, fmt.Errorf("获取Compose服务状态失败: %v, 输出: %s", err, strings.TrimSpace(string(output)))
+	}
+	return buildComposeServices(names, parseComposePS(string(output))), nil
+}
+
+// ComposeServiceAction 对Compose项目中的单个服务执行 restart/start/stop，
+// 或通过 scale 将服务扩缩到 replicas 个副本
+func (dm *DockerManager) ComposeServiceAction(projectName, service, action string, replicas int) error {
+	projectName, err := sanitizeComposeProjectName(projectName)
+	if err != nil {
+		return err
+	}
+	if !composeServiceNamePattern.MatchString(service) {
+		return fmt.Errorf("服务名无效: %q", service)
+	}
+
+	names, err := dm.composeServiceNames(projectName)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, name := range names {
+		if name == service {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Compose项目 %s 中不存在服务 %s", projectName, service)
+	}
+
+	var args []string
+	switch action {
+	case "restart", "start", "stop":
+		args = []string{action, service}
+	case "scale":
+		if replicas < 0 || replicas > maxComposeReplicas {
+			return fmt.Errorf("副本数必须在 0 到 %d 之间", maxComposeReplicas)
+		}
+		args = []string{"up", "-d", "--no-deps", "--scale", service + "=" + strconv.Itoa(replicas), service}
+	default:
+		return fmt.Errorf("未知的服务操作: %s", action)
+	}
+
+	output, err := dm.runCompose(projectName, args...)
+	if err != nil {
+		return fmt.Errorf("服务 %s 执行 %s 失败: %v, 输出: %s", service, action, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// composeServiceNames 通过 docker compose config --services 获取项目中定义的服务名
+func (dm *DockerManager) composeServiceNames(projectName string) ([]string, error) {
+	output, err := dm.runCompose(projectName, "config", "--services")
+	if err != nil {
+		return nil, fmt.Errorf("获取Compose服务列表失败: %v, 输出: %s", err, strings.TrimSpace(string(output)))
+	}
+	var names []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); composeServiceNamePattern.MatchString(line) {
+			names = append(names, line)
+		}
+	}
+	return names, nil
+}
+
+// parseComposePS 解析 docker compose ps --format json 的输出，
+// 旧版本输出 JSON 数组，新版本每行一个对象；混在输出中的警告行会被忽略
+func parseComposePS(output string) []composePSItem {
+	var items []composePSItem
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "["):
+			var batch []composePSItem
+			if err := json.Unmarshal([]byte(line), &batch); err == nil {
+				items = append(items, batch...)
+			}
+		case strings.HasPrefix(line, "{"):
+			var item composePSItem
+			if err := json.Unmarshal([]byte(line), &item); err == nil {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// buildComposeServices 按服务名汇总容器，保持 docker compose config 中的服务顺序
+func buildComposeServices(names []string, items []composePSItem) []ComposeServiceStatus {
+	services := make([]ComposeServiceStatus, 0, len(names))
+	index := make(map[string]int, len(names))
+	for _, name := range names {
+		index[name] = len(services)
+		services = append(services, ComposeServiceStatus{Name: name, Containers: []ComposeServiceContainer{}})
+	}
+	for _, item := range items {
+		i, ok := index[item.Service]
+		if !ok {
+			continue
+		}
+		services[i].Containers = append(services[i].Containers, ComposeServiceContainer{
+			Name:   item.Name,
+			State:  item.State,
+			Status: item.Status,
+		})
+		if item.State == "running" {
+			services[i].Running++
+		}
+	}
+	return services
+}
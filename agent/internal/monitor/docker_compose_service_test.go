@@ -0,0 +1,40 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseComposePS(t *testing.T) {
+	// 新版本每行一个对象，可能混有警告行
+	output := `time="2024-05-01T10:00:00Z" level=warning msg="the attribute version is obsolete"
+{"Name":"app-web-1","Service":"web","State":"running","Status":"Up 2 hours"}
+{"Name":"app-web-2","Service":"web","State":"exited","Status":"Exited (0) 1 minute ago"}
+`
+	items := parseComposePS(output)
+	assert.Len(t, items, 2)
+	assert.Equal(t, "web", items[1].Service)
+
+	// 旧版本输出 JSON 数组
+	items = parseComposePS(`[{"Name":"app-db-1","Service":"db","State":"running","Status":"Up"}]`)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "app-db-1", items[0].Name)
+
+	services := buildComposeServices([]string{"db", "web", "worker"}, parseComposePS(output))
+	assert.Len(t, services, 3)
+	assert.Empty(t, services[0].Containers)
+	assert.Equal(t, "web", services[1].Name)
+	assert.Len(t, services[1].Containers, 2)
+	assert.Equal(t, 1, services[1].Running)
+	assert.NotNil(t, services[2].Containers)
+}
+
+func TestComposeServiceNamePattern(t *testing.T) {
+	assert.True(t, composeServiceNamePattern.MatchString("web-1.api_v2"))
+	assert.False(t, composeServiceNamePattern.MatchString("-web"))
+	assert.False(t, composeServiceNamePattern.MatchString("web; rm -rf /"))
+	assert.False(t, composeServiceNamePattern.MatchString(`time="x" level=warning`))
+}
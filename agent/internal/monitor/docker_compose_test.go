@@ -0,0 +1,135 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindComposeFilesWithOverride(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, findComposeFiles(dir))
+
+	main := filepath.Join(dir, "compose.yaml")
+	override := filepath.Join(dir, "compose.override.yaml")
+	assert.NoError(t, os.WriteFile(main, []byte("services: {}\n"), 0644))
+	assert.Equal(t, []string{main}, findComposeFiles(dir))
+
+	assert.NoError(t, os.WriteFile(override, []byte("services: {}\n"), 0644))
+	assert.Equal(t, []string{main, override}, findComposeFiles(dir))
+}
+
+func TestFillComposeFiles(t *testing.T) {
+	dm := &DockerManager{composeDir: "/tmp/docker-compose"}
+
+	info := ComposeInfo{WorkingDir: "/srv/app"}
+	dm.fillComposeFiles(&info, []string{"docker-compose.yml", "/srv/shared/prod.yml"})
+	assert.Equal(t, []string{"/srv/app/docker-compose.yml", "/srv/shared/prod.yml"}, info.ConfigFiles)
+	assert.Equal(t, "/srv/app/docker-compose.yml", info.ComposeFile)
+	assert.Equal(t, []string{"/srv/shared/prod.yml"}, info.OverrideFiles)
+	assert.False(t, info.Managed)
+
+	// 缺少工作目录时按主配置文件所在目录补全
+	info = ComposeInfo{}
+	dm.fillComposeFiles(&info, []string{"/tmp/docker-compose/web/docker-compose.yml"})
+	assert.Equal(t, "/tmp/docker-compose/web", info.WorkingDir)
+	assert.Empty(t, info.OverrideFiles)
+	assert.True(t, info.Managed)
+}
+
+func TestParseComposeConfigMergesOverridesAndAnchors(t *testing.T) {
+	base := `
+x-common: &common
+  restart: unless-stopped
+  environment:
+    TZ: Asia/Shanghai
+services:
+  web:
+    <<: *common
+    image: nginx:1.25
+    ports: ["8080:80"]
+    volumes:
+      - ./html:/usr/share/nginx/html:ro
+    depends_on: [db]
+  db:
+    <<: *common
+    image: postgres:16
+    environment:
+      - POSTGRES_PASSWORD=secret
+    volumes:
+      - db-data:/var/lib/postgresql/data
+volumes:
+  db-data: {}
+`
+	override := `
+services:
+  web:
+    image: nginx:1.27
+    ports:
+      - target: 443
+        published: "8443"
+        host_ip: 127.0.0.1
+    volumes:
+      - type: bind
+        source: /srv/html
+        target: /usr/share/nginx/html
+        read_only: true
+    environment:
+      DEBUG: "1"
+`
+	project, err := ParseComposeConfig(base, override)
+	assert.NoError(t, err)
+	assert.Len(t, project.Services, 2)
+	assert.Equal(t, []string{"db-data"}, project.Volumes)
+
+	db, web := project.Services[0], project.Services[1]
+	assert.Equal(t, "postgres:16", db.Image)
+	assert.Equal(t, "unless-stopped", db.Restart)
+	assert.Equal(t, map[string]string{"POSTGRES_PASSWORD": "secret"}, db.Environment)
+	assert.Equal(t, []string{"db-data:/var/lib/postgresql/data"}, db.Volumes)
+
+	assert.Equal(t, "nginx:1.27", web.Image)
+	assert.Equal(t, "unless-stopped", web.Restart)
+	assert.Equal(t, []string{"8080:80", "127.0.0.1:8443:443"}, web.Ports)
+	assert.Equal(t, []string{"/srv/html:/usr/share/nginx/html:ro"}, web.Volumes)
+	assert.Equal(t, map[string]string{"TZ": "Asia/Shanghai", "DEBUG": "1"}, web.Environment)
+	assert.Equal(t, []string{"db"}, web.DependsOn)
+}
+
+func TestParseComposeConfigRejectsInvalidContent(t *testing.T) {
+	for _, content := range []string{
+		"",
+		"services: [web]",
+		"services:\n  web:\n    image: nginx\n  bad: [",
+		"services:\n  web:\n    image: nginx\n    depends_on:\n      db:\n        condition: service_healthy\n",
+	} {
+		_, err := ParseComposeConfig(content)
+		assert.ErrorIs(t, err, ErrComposeInvalid, content)
+	}
+
+	// 使用 include 时依赖的服务可能定义在被引用的文件中
+	_, err := ParseComposeConfig("include: [db.yaml]\nservices:\n  web:\n    image: nginx\n    depends_on: [db]\n")
+	assert.NoError(t, err)
+}
+
+func TestValidateComposeUsesExistingOverrides(t *testing.T) {
+	dm := &DockerManager{composeDir: t.TempDir()}
+	dir := filepath.Join(dm.composeDir, "web")
+	assert.NoError(t, os.MkdirAll(dir, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte("services: {}\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "docker-compose.override.yml"), []byte("services:\n  web:\n    ports: [\"8080:80\"]\n"), 0644))
+
+	project, err := dm.ValidateCompose("web", "services:\n  web:\n    image: nginx\n")
+	assert.NoError(t, err)
+	if assert.Len(t, project.Services, 1) {
+		assert.Equal(t, "nginx", project.Services[0].Image)
+		assert.Equal(t, []string{"8080:80"}, project.Services[0].Ports)
+	}
+
+	// 无效内容不会写入磁盘
+	assert.Error(t, dm.CreateCompose("web", "services: ["))
+	data, _ := os.ReadFile(filepath.Join(dir, "docker-compose.yml"))
+	assert.Equal(t, "services: {}\n", string(data))
+}
@@ -0,0 +1,168 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	// DefaultExecTimeout 一次性命令的默认超时
+	DefaultExecTimeout = 30 * time.Second
+	// MaxExecTimeout 一次性命令允许的最长超时
+	MaxExecTimeout = 5 * time.Minute
+	// execOutputLimit stdout/stderr 各自保留的最大字节数
+	execOutputLimit = 1 << 20
+)
+
+// ExecOptions 容器内一次性命令的参数
+type ExecOptions struct {
+	Cmd     []string
+	User    string
+	WorkDir string
+	Env     []string
+	Timeout time.Duration
+}
+
+// ExecResult 容器内一次性命令的执行结果，命令以非零码退出不视为错误
+type ExecResult struct {
+	Stdout     string `json:"stdout"`
+	Stderr     string `json:"stderr"`
+	ExitCode   int    `json:"exit_code"`
+	TimedOut   bool   `json:"timed_out"`
+	Truncated  bool   `json:"truncated"` // 输出超过 1MB 被截断
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// ExecCommand 在容器内以非交互方式执行一条命令，等待结束后返回输出和退出码。
+// 超时后返回 TimedOut=true 及已收到的输出；Docker API 无法终止 exec 进程，命令可能仍在容器内运行
+func (dm *DockerManager) ExecCommand(containerID string, opts ExecOptions) (*ExecResult, error) {
+	if containerID == "" {
+		return nil, fmt.Errorf("容器ID不能为空")
+	}
+	if len(opts.Cmd) == 0 || opts.Cmd[0] == "" {
+		return nil, fmt.Errorf("命令不能为空")
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultExecTimeout
+	}
+	if opts.Timeout > MaxExecTimeout {
+		opts.Timeout = MaxExecTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(dm.ctx, opts.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	stdout := &limitedBuffer{limit: execOutputLimit}
+	stderr := &limitedBuffer{limit: execOutputLimit}
+	var exitCode int
+	var err error
+	if dm.cliMode {
+		exitCode, err = dm.cliExecCommand(ctx, containerID, opts, stdout, stderr)
+	} else {
+		exitCode, err = dm.sdkExecCommand(ctx, containerID, opts, stdout, stderr)
+	}
+
+	result := &ExecResult{
+		Stdout:     stdout.String(),
+		Stderr:     stderr.String(),
+		ExitCode:   exitCode,
+		Truncated:  stdout.overflow || stderr.overflow,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		result.TimedOut = true
+		result.ExitCode = -1
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// sdkExecCommand 通过 Docker API 执行命令
+func (dm *DockerManager) sdkExecCommand(ctx context.Context, containerID string, opts ExecOptions, stdout, stderr *limitedBuffer) (int, error) {
+	execResp, err := dm.client.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd:          opts.Cmd,
+		User:         opts.User,
+		WorkingDir:   opts.WorkDir,
+		Env:          opts.Env,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("创建 exec 失败: %w", err)
+	}
+
+	attachResp, err := dm.client.ContainerExecAttach(ctx, execResp.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("附加 exec 失败: %w", err)
+	}
+	defer attachResp.Close()
+
+	// 连接本身不感知 ctx，超时时关闭连接以结束读取
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			attachResp.Close()
+		case <-done:
+		}
+	}()
+
+	if _, err := stdcopy.StdCopy(stdout, stderr, attachResp.Reader); err != nil && ctx.Err() == nil {
+		return 0, fmt.Errorf("读取 exec 输出失败: %w", err)
+	}
+	if ctx.Err() != nil {
+		return -1, ctx.Err()
+	}
+
+	inspect, err := dm.client.ContainerExecInspect(ctx, execResp.ID)
+	if err != nil {
+		return 0, fmt.Errorf("inspect exec 失败: %w", err)
+	}
+	return inspect.ExitCode, nil
+}
+
+// cliExecCommand 通过 docker exec 执行命令
+func (dm *DockerManager) cliExecCommand(ctx context.Context, containerID string, opts ExecOptions, stdout, stderr *limitedBuffer) (int, error) {
+	args := []string{"exec"}
+	if opts.User != "" {
+		args = append(args, "--user", opts.User)
+	}
+	if opts.WorkDir != "" {
+		args = append(args, "--workdir", opts.WorkDir)
+	}
+	for _, env := range opts.Env {
+		args = append(args, "--env", env)
+	}
+	args = append(args, containerID)
+	args = append(args, opts.Cmd...)
+
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0, nil
+	case ctx.Err() != nil:
+		return -1, ctx.Err()
+	case errors.As(err, &exitErr):
+		// docker exec 自身出错（如容器不存在）时同样以非零码退出，原因在 stderr 中
+		return exitErr.ExitCode(), nil
+	default:
+		return 0, fmt.Errorf("执行 docker exec 失败: %v", err)
+	}
+}
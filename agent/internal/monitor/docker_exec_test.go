@@ -0,0 +1,22 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecCommandValidation(t *testing.T) {
+	dm := &DockerManager{}
+
+	_, err := dm.ExecCommand("", ExecOptions{Cmd: []string{"true"}})
+	assert.Error(t, err)
+
+	_, err = dm.ExecCommand("web", ExecOptions{})
+	assert.Error(t, err)
+
+	_, err = dm.ExecCommand("web", ExecOptions{Cmd: []string{""}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,507 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// NetworkInfo Docker网络信息
+type NetworkInfo struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Driver     string            `json:"driver"`
+	Scope      string            `json:"scope"`
+	Internal   bool              `json:"internal"`
+	Attachable bool              `json:"attachable"`
+	IPv6       bool              `json:"ipv6"`
+	Subnets    []string          `json:"subnets"`
+	Gateways   []string          `json:"gateways"`
+	Containers int               `json:"containers"`
+	Created    string            `json:"created"`
+	Labels     map[string]string `json:"labels"`
+	Predefined bool              `json:"predefined"` // bridge/host/none 等内置网络，不可删除
+}
+
+// NetworkEndpoint 连接到网络的容器
+type NetworkEndpoint struct {
+	ContainerID string `json:"container_id"`
+	Name        string `json:"name"`
+	IPv4Address string `json:"ipv4_address"`
+	IPv6Address string `json:"ipv6_address"`
+	MacAddress  string `json:"mac_address"`
+}
+
+// NetworkDetail Docker网络详情
+type NetworkDetail struct {
+	NetworkInfo
+	Options   map[string]string `json:"options"`
+	Endpoints []NetworkEndpoint `json:"endpoints"`
+}
+
+// NetworkCreateOptions 创建网络的参数
+type NetworkCreateOptions struct {
+	Name     string            `json:"name"`
+	Driver   string            `json:"driver"`
+	Subnet   string            `json:"subnet"`
+	Gateway  string            `json:"gateway"`
+	Internal bool              `json:"internal"`
+	IPv6     bool              `json:"ipv6"`
+	Labels   map[string]string `json:"labels"`
+}
+
+// VolumeInfo Docker卷信息
+type VolumeInfo struct {
+	Name       string            `json:"name"`
+	Driver     string            `json:"driver"`
+	Scope      string            `json:"scope"`
+	Mountpoint string            `json:"mountpoint"`
+	Created    string            `json:"created"`
+	Labels     map[string]string `json:"labels"`
+}
+
+// VolumeDetail Docker卷详情
+type VolumeDetail struct {
+	VolumeInfo
+	Options    map[string]string `json:"options"`
+	Containers []string          `json:"containers"` // 挂载了该卷的容器名称
+}
+
+// VolumeCreateOptions 创建卷的参数
+type VolumeCreateOptions struct {
+	Name    string            `json:"name"`
+	Driver  string            `json:"driver"`
+	Labels  map[string]string `json:"labels"`
+	Options map[string]string `json:"options"`
+}
+
+// PruneResult 清理未使用的网络或卷的结果
+type PruneResult struct {
+	Deleted        []string `json:"deleted"`
+	SpaceReclaimed uint64   `json:"space_reclaimed"`
+}
+
+// predefinedNetworks Docker 内置的网络，无法删除
+var predefinedNetworks = map[string]bool{"bridge": true, "host": true, "none": true}
+
+// ─── 网络 ────────────────────────────────────────────────────────────────────
+
+// GetNetworks 获取网络列表
+func (dm *DockerManager) GetNetworks() ([]NetworkInfo, error) {
+	inspects, err := dm.listNetworks()
+	if err != nil {
+		return nil, fmt.Errorf("获取网络列表失败: %v", err)
+	}
+	networks := make([]NetworkInfo, 0, len(inspects))
+	for _, n := range inspects {
+		networks = append(networks, toNetworkInfo(n))
+	}
+	sort.Slice(networks, func(i, j int) bool { return networks[i].Name < networks[j].Name })
+	return networks, nil
+}
+
+// listNetworks 读取所有网络的完整信息，命令行模式下通过 docker network inspect 获取
+func (dm *DockerManager) listNetworks() ([]network.Inspect, error) {
+	if !dm.cliMode {
+		return dm.client.NetworkList(dm.ctx, network.ListOptions{})
+	}
+	output, err := dm.runDockerCLI("network", "ls", "-q", "--no-trunc")
+	if err != nil {
+		return nil, err
+	}
+	ids := strings.Fields(output)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	output, err = dm.runDockerCLI(append([]string{"network", "inspect"}, ids...)...)
+	if err != nil {
+		return nil, err
+	}
+	var inspects []network.Inspect
+	if err := json.Unmarshal([]byte(output), &inspects); err != nil {
+		return nil, fmt.Errorf("解析网络信息失败: %v", err)
+	}
+	return inspects, nil
+}
+
+// InspectNetwork 获取网络详情，包括已连接的容器
+func (dm *DockerManager) InspectNetwork(networkID string) (*NetworkDetail, error) {
+	var inspect network.Inspect
+	if dm.cliMode {
+		output, err := dm.runDockerCLI("network", "inspect", networkID)
+		if err != nil {
+			return nil, fmt.Errorf("获取网络详情失败: %v", err)
+		}
+		var inspects []network.Inspect
+		if err := json.Unmarshal([]byte(output), &inspects); err != nil || len(inspects) == 0 {
+			return nil, fmt.Errorf("解析网络信息失败: %v", err)
+		}
+		inspect = inspects[0]
+	} else {
+		var err error
+		inspect, err = dm.client.NetworkInspect(dm.ctx, networkID, network.InspectOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("获取网络详情失败: %v", err)
+		}
+	}
+
+	detail := &NetworkDetail{
+		NetworkInfo: toNetworkInfo(inspect),
+		Options:     inspect.Options,
+		Endpoints:   make([]NetworkEndpoint, 0, len(inspect.Containers)),
+	}
+	for id, ep := range inspect.Containers {
+		detail.Endpoints = append(detail.Endpoints, NetworkEndpoint{
+			ContainerID: id,
+			Name:        ep.Name,
+			IPv4Address: ep.IPv4Address,
+			IPv6Address: ep.IPv6Address,
+			MacAddress:  ep.MacAddress,
+		})
+	}
+	sort.Slice(detail.Endpoints, func(i, j int) bool { return detail.Endpoints[i].Name < detail.Endpoints[j].Name })
+	return detail, nil
+}
+
+// CreateNetwork 创建网络，返回网络ID
+func (dm *DockerManager) CreateNetwork(opts NetworkCreateOptions) (string, error) {
+	if strings.TrimSpace(opts.Name) == "" {
+		return "", fmt.Errorf("网络名称不能为空")
+	}
+	if opts.Gateway != "" && opts.Subnet == "" {
+		return "", fmt.Errorf("指定网关时必须同时指定子网")
+	}
+
+	if dm.cliMode {
+		args := []string{"network", "create"}
+		if opts.Driver != "" {
+			args = append(args, "--driver", opts.Driver)
+		}
+		if opts.Subnet != "" {
+			args = append(args, "--subnet", opts.Subnet)
+		}
+		if opts.Gateway != "" {
+			args = append(args, "--gateway", opts.Gateway)
+		}
+		if opts.Internal {
+			args = append(args, "--internal")
+		}
+		if opts.IPv6 {
+			args = append(args, "--ipv6")
+		}
+		for k, v := range opts.Labels {
+			args = append(args, "--label", k+"="+v)
+		}
+		output, err := dm.runDockerCLI(append(args, opts.Name)...)
+		if err != nil {
+			return "", fmt.Errorf("创建网络失败: %v", err)
+		}
+		return strings.TrimSpace(output), nil
+	}
+
+	createOpts := network.CreateOptions{
+		Driver:   opts.Driver,
+		Internal: opts.Internal,
+		Labels:   opts.Labels,
+	}
+	if opts.IPv6 {
+		enableIPv6 := true
+		createOpts.EnableIPv6 = &enableIPv6
+	}
+	if opts.Subnet != "" {
+		createOpts.IPAM = &network.IPAM{
+			Config: []network.IPAMConfig{{Subnet: opts.Subnet, Gateway: opts.Gateway}},
+		}
+	}
+	resp, err := dm.client.NetworkCreate(dm.ctx, opts.Name, createOpts)
+	if err != nil {
+		return "", fmt.Errorf("创建网络失败: %v", err)
+	}
+	if resp.Warning != "" {
+		dm.log.Warn("创建网络 %s: %s", opts.Name, resp.Warning)
+	}
+	return resp.ID, nil
+}
+
+// RemoveNetwork 删除网络
+func (dm *DockerManager) RemoveNetwork(networkID string) error {
+	if predefinedNetworks[networkID] {
+		return fmt.Errorf("%s 是Docker内置网络，无法删除", networkID)
+	}
+	if dm.cliMode {
+		if _, err := dm.runDockerCLI("network", "rm", networkID); err != nil {
+			return fmt.Errorf("删除网络失败: %v", err)
+		}
+		return nil
+	}
+	if err := dm.client.NetworkRemove(dm.ctx, networkID); err != nil {
+		return fmt.Errorf("删除网络失败: %v", err)
+	}
+	return nil
+}
+
+// PruneNetworks 删除所有未被容器使用的自定义网络
+func (dm *DockerManager) PruneNetworks() (*PruneResult, error) {
+	if dm.cliMode {
+		output, err := dm.runDockerCLI("network", "prune", "-f")
+		if err != nil {
+			return nil, fmt.Errorf("清理网络失败: %v", err)
+		}
+		return parseCLIPruneOutput(output), nil
+	}
+	report, err := dm.client.NetworksPrune(dm.ctx, filters.NewArgs())
+	if err != nil {
+		return nil, fmt.Errorf("清理网络失败: %v", err)
+	}
+	return &PruneResult{Deleted: nonNilStrings(report.NetworksDeleted)}, nil
+}
+
+// toNetworkInfo 从 inspect 结果提取列表展示的字段
+func toNetworkInfo(n network.Inspect) NetworkInfo {
+	info := NetworkInfo{
+		ID:         n.ID,
+		Name:       n.Name,
+		Driver:     n.Driver,
+		Scope:      n.Scope,
+		Internal:   n.Internal,
+		Attachable: n.Attachable,
+		IPv6:       n.EnableIPv6,
+		Subnets:    []string{},
+		Gateways:   []string{},
+		Containers: len(n.Containers),
+		Labels:     n.Labels,
+		Predefined: predefinedNetworks[n.Name],
+	}
+	if !n.Created.IsZero() {
+		info.Created = n.Created.Format(time.RFC3339)
+	}
+	for _, cfg := range n.IPAM.Config {
+		if cfg.Subnet != "" {
+			info.Subnets = append(info.Subnets, cfg.Subnet)
+		}
+		if cfg.Gateway != "" {
+			info.Gateways = append(info.Gateways, cfg.Gateway)
+		}
+	}
+	return info
+}
+
+// ─── 卷 ──────────────────────────────────────────────────────────────────────
+
+// GetVolumes 获取卷列表
+func (dm *DockerManager) GetVolumes() ([]VolumeInfo, error) {
+	raw, err := dm.listVolumes()
+	if err != nil {
+		return nil, fmt.Errorf("获取卷列表失败: %v", err)
+	}
+	volumes := make([]VolumeInfo, 0, len(raw))
+	for _, v := range raw {
+		volumes = append(volumes, toVolumeInfo(v))
+	}
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].Name < volumes[j].Name })
+	return volumes, nil
+}
+
+// listVolumes 读取所有卷的完整信息，命令行模式下通过 docker volume inspect 获取
+func (dm *DockerManager) listVolumes() ([]volume.Volume, error) {
+	if !dm.cliMode {
+		resp, err := dm.client.VolumeList(dm.ctx, volume.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		volumes := make([]volume.Volume, 0, len(resp.Volumes))
+		for _, v := range resp.Volumes {
+			if v != nil {
+				volumes = append(volumes, *v)
+			}
+		}
+		return volumes, nil
+	}
+	output, err := dm.runDockerCLI("volume", "ls", "-q")
+	if err != nil {
+		return nil, err
+	}
+	names := strings.Fields(output)
+	if len(names) == 0 {
+		return nil, nil
+	}
+	output, err = dm.runDockerCLI(append([]string{"volume", "inspect"}, names...)...)
+	if err != nil {
+		return nil, err
+	}
+	var volumes []volume.Volume
+	if err := json.Unmarshal([]byte(output), &volumes); err != nil {
+		return nil, fmt.Errorf("解析卷信息失败: %v", err)
+	}
+	return volumes, nil
+}
+
+// InspectVolume 获取卷详情，包括挂载了该卷的容器
+func (dm *DockerManager) InspectVolume(name string) (*VolumeDetail, error) {
+	var v volume.Volume
+	var containers []string
+	if dm.cliMode {
+		output, err := dm.runDockerCLI("volume", "inspect", name)
+		if err != nil {
+			return nil, fmt.Errorf("获取卷详情失败: %v", err)
+		}
+		var volumes []volume.Volume
+		if err := json.Unmarshal([]byte(output), &volumes); err != nil || len(volumes) == 0 {
+			return nil, fmt.Errorf("解析卷信息失败: %v", err)
+		}
+		v = volumes[0]
+		if output, err := dm.runDockerCLI("ps", "-a", "--filter", "volume="+name, "--format", "{{.Names}}"); err == nil {
+			containers = strings.Fields(output)
+		}
+	} else {
+		var err error
+		v, err = dm.client.VolumeInspect(dm.ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("获取卷详情失败: %v", err)
+		}
+		list, err := dm.client.ContainerList(dm.ctx, container.ListOptions{
+			All:     true,
+			Filters: filters.NewArgs(filters.Arg("volume", name)),
+		})
+		if err == nil {
+			for _, c := range list {
+				if len(c.Names) > 0 {
+					containers = append(containers, strings.TrimPrefix(c.Names[0], "/"))
+				}
+			}
+		}
+	}
+
+	return &VolumeDetail{
+		VolumeInfo: toVolumeInfo(v),
+		Options:    v.Options,
+		Containers: nonNilStrings(containers),
+	}, nil
+}
+
+// CreateVolume 创建卷，名称为空时由 Docker 生成，返回卷名称
+func (dm *DockerManager) CreateVolume(opts VolumeCreateOptions) (string, error) {
+	if dm.cliMode {
+		args := []string{"volume", "create"}
+		if opts.Driver != "" {
+			args = append(args, "--driver", opts.Driver)
+		}
+		for k, v := range opts.Labels {
+			args = append(args, "--label", k+"="+v)
+		}
+		for k, v := range opts.Options {
+			args = append(args, "--opt", k+"="+v)
+		}
+		if opts.Name != "" {
+			args = append(args, opts.Name)
+		}
+		output, err := dm.runDockerCLI(args...)
+		if err != nil {
+			return "", fmt.Errorf("创建卷失败: %v", err)
+		}
+		return strings.TrimSpace(output), nil
+	}
+
+	v, err := dm.client.VolumeCreate(dm.ctx, volume.CreateOptions{
+		Name:       opts.Name,
+		Driver:     opts.Driver,
+		Labels:     opts.Labels,
+		DriverOpts: opts.Options,
+	})
+	if err != nil {
+		return "", fmt.Errorf("创建卷失败: %v", err)
+	}
+	return v.Name, nil
+}
+
+// RemoveVolume 删除卷，卷被容器使用时 Docker 会拒绝删除
+func (dm *DockerManager) RemoveVolume(name string, force bool) error {
+	if dm.cliMode {
+		args := []string{"volume", "rm"}
+		if force {
+			args = append(args, "-f")
+		}
+		if _, err := dm.runDockerCLI(append(args, name)...); err != nil {
+			return fmt.Errorf("删除卷失败: %v", err)
+		}
+		return nil
+	}
+	if err := dm.client.VolumeRemove(dm.ctx, name, force); err != nil {
+		return fmt.Errorf("删除卷失败: %v", err)
+	}
+	return nil
+}
+
+// PruneVolumes 删除未被容器使用的卷。Docker 23 起默认只清理匿名卷，all 为 true 时同时清理具名卷
+func (dm *DockerManager) PruneVolumes(all bool) (*PruneResult, error) {
+	if dm.cliMode {
+		args := []string{"volume", "prune", "-f"}
+		if all {
+			args = append(args, "--all")
+		}
+		output, err := dm.runDockerCLI(args...)
+		if err != nil {
+			return nil, fmt.Errorf("清理卷失败: %v", err)
+		}
+		return parseCLIPruneOutput(output), nil
+	}
+	args := filters.NewArgs()
+	if all {
+		args.Add("all", "true")
+	}
+	report, err := dm.client.VolumesPrune(dm.ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("清理卷失败: %v", err)
+	}
+	return &PruneResult{Deleted: nonNilStrings(report.VolumesDeleted), SpaceReclaimed: report.SpaceReclaimed}, nil
+}
+
+// toVolumeInfo 从 inspect 结果提取列表展示的字段
+func toVolumeInfo(v volume.Volume) VolumeInfo {
+	return VolumeInfo{
+		Name:       v.Name,
+		Driver:     v.Driver,
+		Scope:      v.Scope,
+		Mountpoint: v.Mountpoint,
+		Created:    v.CreatedAt,
+		Labels:     v.Labels,
+	}
+}
+
+// parseCLIPruneOutput 解析 docker network/volume prune 的输出，例如：
+//
+//	Deleted Volumes:
+//	data
+//
+//	Total reclaimed space: 1.2MB
+func parseCLIPruneOutput(output string) *PruneResult {
+	result := &PruneResult{Deleted: []string{}}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "Deleted "):
+		case strings.HasPrefix(line, "Total reclaimed space:"):
+			result.SpaceReclaimed = parseCLISize(strings.TrimSpace(strings.TrimPrefix(line, "Total reclaimed space:")))
+		default:
+			result.Deleted = append(result.Deleted, line)
+		}
+	}
+	return result
+}
+
+// nonNilStrings 保证返回给面板的列表序列化为 [] 而不是 null
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
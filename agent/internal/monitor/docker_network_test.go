@@ -0,0 +1,45 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToNetworkInfo(t *testing.T) {
+	var inspect network.Inspect
+	assert.NoError(t, json.Unmarshal([]byte(`{
+	  "Name": "bridge", "Id": "f2de39df4171", "Created": "2024-05-01T10:00:00Z", "Scope": "local", "Driver": "bridge",
+	  "IPAM": {"Driver": "default", "Config": [{"Subnet": "172.17.0.0/16", "Gateway": "172.17.0.1"}, {"Subnet": "fd00::/64"}]},
+	  "Containers": {"abc": {"Name": "web", "IPv4Address": "172.17.0.2/16"}}
+	}`), &inspect))
+
+	info := toNetworkInfo(inspect)
+	assert.Equal(t, "bridge", info.Name)
+	assert.True(t, info.Predefined)
+	assert.Equal(t, []string{"172.17.0.0/16", "fd00::/64"}, info.Subnets)
+	assert.Equal(t, []string{"172.17.0.1"}, info.Gateways)
+	assert.Equal(t, 1, info.Containers)
+	assert.Equal(t, "2024-05-01T10:00:00Z", info.Created)
+
+	info = toNetworkInfo(network.Inspect{Name: "app_default"})
+	assert.False(t, info.Predefined)
+	assert.Empty(t, info.Created)
+	assert.NotNil(t, info.Subnets)
+}
+
+func TestParseCLIPruneOutput(t *testing.T) {
+	result := parseCLIPruneOutput("Deleted Volumes:\ndata\n3f2a9c\n\nTotal reclaimed space: 1.5MB\n")
+	assert.Equal(t, []string{"data", "3f2a9c"}, result.Deleted)
+	assert.Equal(t, uint64(1500000), result.SpaceReclaimed)
+
+	result = parseCLIPruneOutput("Deleted Networks:\napp_default\n")
+	assert.Equal(t, []string{"app_default"}, result.Deleted)
+	assert.Zero(t, result.SpaceReclaimed)
+
+	assert.Empty(t, parseCLIPruneOutput("").Deleted)
+}
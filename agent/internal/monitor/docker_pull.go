@@ -0,0 +1,230 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/docker/docker/api/types/image"
+)
+
+// PullLayer 单个镜像层的拉取进度
+type PullLayer struct {
+	ID      string `json:"id"`
+	Status  string `json:"status"`
+	Current int64  `json:"current"`
+	Total   int64  `json:"total"`
+}
+
+// PullProgress 镜像拉取进度快照，Current/Total 为已知大小的镜像层下载字节数之和
+type PullProgress struct {
+	Image     string      `json:"image"`
+	Status    string      `json:"status"`
+	Layers    []PullLayer `json:"layers"`
+	Current   int64       `json:"current"`
+	Total     int64       `json:"total"`
+	Completed int         `json:"completed"` // 已完成（下载并解压或本地已存在）的镜像层数
+}
+
+// pullMessage Docker 拉取镜像时逐行输出的 JSON 进度消息
+type pullMessage struct {
+	ID       string `json:"id"`
+	Status   string `json:"status"`
+	Progress *struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+	ErrorMessage string `json:"error"`
+}
+
+// pullTracker 汇总各镜像层的拉取进度
+type pullTracker struct {
+	image  string
+	status string
+	order  []string
+	layers map[string]*PullLayer
+}
+
+func newPullTracker(imageRef string) *pullTracker {
+	return &pullTracker{image: imageRef, layers: make(map[string]*PullLayer)}
+}
+
+// apply 合并一条进度消息，消息携带错误时返回该错误
+func (t *pullTracker) apply(msg pullMessage) error {
+	if msg.Error != nil && msg.Error.Message != "" {
+		return errors.New(msg.Error.Message)
+	}
+	if msg.ErrorMessage != "" {
+		return errors.New(msg.ErrorMessage)
+	}
+
+	// 只有 ID 为 12 位以上十六进制摘要的消息属于镜像层，"7: Pulling from ..." 等为整体状态
+	if len(msg.ID) < 12 || !isHexString(msg.ID) {
+		if msg.Status != "" {
+			t.status = strings.TrimSpace(strings.TrimPrefix(msg.ID+": "+msg.Status, ": "))
+		}
+		return nil
+	}
+
+	layer, ok := t.layers[msg.ID]
+	if !ok {
+		layer = &PullLayer{ID: msg.ID}
+		t.layers[msg.ID] = layer
+		t.order = append(t.order, msg.ID)
+	}
+	layer.Status = msg.Status
+	switch {
+	case msg.Status == "Downloading" && msg.Progress != nil:
+		layer.Current, layer.Total = msg.Progress.Current, msg.Progress.Total
+	case msg.Status == "Download complete", strings.HasPrefix(msg.Status, "Extracting"),
+		msg.Status == "Verifying Checksum", msg.Status == "Pull complete":
+		// 下载完成后的解压进度不计入下载字节数
+		layer.Current = layer.Total
+	}
+	return nil
+}
+
+// snapshot 返回当前进度
+func (t *pullTracker) snapshot() PullProgress {
+	progress := PullProgress{Image: t.image, Status: t.status, Layers: make([]PullLayer, 0, len(t.order))}
+	for _, id := range t.order {
+		layer := *t.layers[id]
+		progress.Layers = append(progress.Layers, layer)
+		progress.Current += layer.Current
+		progress.Total += layer.Total
+		if layer.Status == "Pull complete" || layer.Status == "Already exists" {
+			progress.Completed++
+		}
+	}
+	return progress
+}
+
+// PullImageWithProgress 拉取镜像并在每条进度消息后回调 onProgress，ctx 取消时中止拉取。
+// 优先通过 Docker API 获取逐层的字节进度；命令行模式或仓库需要认证时改用 docker pull，
+// 以便使用本机 docker login 保存的凭据，此时只有逐层的状态而没有字节数。
+// auth 为面板下发的仓库凭据，为 nil 时匿名或使用本机凭据拉取
+func (dm *DockerManager) PullImageWithProgress(ctx context.Context, imageRef string, auth *RegistryAuth, onProgress func(PullProgress)) error {
+	imageRef = strings.TrimSpace(imageRef)
+	if imageRef == "" {
+		return fmt.Errorf("镜像名称不能为空")
+	}
+
+	if !dm.cliMode {
+		err := dm.sdkPullImage(ctx, imageRef, auth, onProgress)
+		if err == nil || ctx.Err() != nil || auth != nil || !isRegistryAuthError(err) {
+			return err
+		}
+		dm.log.Info("拉取镜像 %s 需要认证，改用docker命令行拉取: %v", imageRef, err)
+	}
+	return dm.cliPullImage(ctx, imageRef, auth, onProgress)
+}
+
+// sdkPullImage 通过 Docker API 拉取镜像
+func (dm *DockerManager) sdkPullImage(ctx context.Context, imageRef string, auth *RegistryAuth, onProgress func(PullProgress)) error {
+	authHeader, err := registryAuthHeader(auth)
+	if err != nil {
+		return fmt.Errorf("编码仓库凭据失败: %v", err)
+	}
+	reader, err := dm.client.ImagePull(ctx, imageRef, image.PullOptions{RegistryAuth: authHeader})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	tracker := newPullTracker(imageRef)
+	decoder := json.NewDecoder(reader)
+	for {
+		var msg pullMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("读取拉取进度失败: %v", err)
+		}
+		if err := tracker.apply(msg); err != nil {
+			return err
+		}
+		onProgress(tracker.snapshot())
+	}
+}
+
+// cliPullImage 通过 docker pull 拉取镜像并逐行解析输出，有仓库凭据时在临时配置目录中登录后拉取
+func (dm *DockerManager) cliPullImage(ctx context.Context, imageRef string, auth *RegistryAuth, onProgress func(PullProgress)) error {
+	if auth != nil {
+		return withRegistryConfig(ctx, auth, func(configDir string) error {
+			return runCLIPull(ctx, imageRef, []string{"--config", configDir, "pull", imageRef}, onProgress)
+		})
+	}
+	return runCLIPull(ctx, imageRef, []string{"pull", imageRef}, onProgress)
+}
+
+// runCLIPull 执行 docker pull 并逐行解析输出
+func runCLIPull(ctx context.Context, imageRef string, args []string, onProgress func(PullProgress)) error {
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("拉取镜像失败: %v", err)
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("拉取镜像失败: %v", err)
+	}
+
+	tracker := newPullTracker(imageRef)
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		if msg, ok := parseCLIPullLine(scanner.Text()); ok {
+			_ = tracker.apply(msg)
+			onProgress(tracker.snapshot())
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return errors.New(detail)
+		}
+		return fmt.Errorf("拉取镜像失败: %v", err)
+	}
+	return nil
+}
+
+// parseCLIPullLine 解析 docker pull 在非终端下的输出行，例如 "a2abf6c4d29d: Pull complete"
+func parseCLIPullLine(line string) (pullMessage, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return pullMessage{}, false
+	}
+	id, status, found := strings.Cut(line, ": ")
+	if !found || strings.ContainsAny(id, " /") {
+		return pullMessage{Status: line}, true
+	}
+	return pullMessage{ID: id, Status: status}, true
+}
+
+// isRegistryAuthError 判断拉取失败是否因为仓库需要认证
+func isRegistryAuthError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, keyword := range []string{"unauthorized", "authentication required", "access denied", "docker login", "no basic auth credentials"} {
+		if strings.Contains(msg, keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPullTracker(t *testing.T) {
+	tracker := newPullTracker("redis:7")
+	lines := []string{
+		`{"status":"Pulling from library/redis","id":"7"}`,
+		`{"status":"Already exists","progressDetail":{},"id":"aaaaaaaaaaaa"}`,
+		`{"status":"Pulling fs layer","progressDetail":{},"id":"bbbbbbbbbbbb"}`,
+		`{"status":"Downloading","progressDetail":{"current":1024,"total":4096},"id":"bbbbbbbbbbbb"}`,
+		`{"status":"Pulling fs layer","progressDetail":{},"id":"cccccccccccc"}`,
+		`{"status":"Downloading","progressDetail":{"current":100,"total":1000},"id":"cccccccccccc"}`,
+		`{"status":"Download complete","progressDetail":{},"id":"cccccccccccc"}`,
+		`{"status":"Extracting","progressDetail":{"current":50,"total":1000},"id":"cccccccccccc"}`,
+		`{"status":"Pull complete","progressDetail":{},"id":"cccccccccccc"}`,
+	}
+	for _, line := range lines {
+		var msg pullMessage
+		assert.NoError(t, json.Unmarshal([]byte(line), &msg))
+		assert.NoError(t, tracker.apply(msg))
+	}
+
+	progress := tracker.snapshot()
+	assert.Equal(t, "7: Pulling from library/redis", progress.Status)
+	assert.Len(t, progress.Layers, 3)
+	assert.Equal(t, int64(1024+1000), progress.Current)
+	assert.Equal(t, int64(4096+1000), progress.Total)
+	assert.Equal(t, 2, progress.Completed)
+
+	var msg pullMessage
+	assert.NoError(t, json.Unmarshal([]byte(`{"errorDetail":{"message":"manifest unknown"},"error":"manifest unknown"}`), &msg))
+	assert.EqualError(t, tracker.apply(msg), "manifest unknown")
+}
+
+func TestParseCLIPullLine(t *testing.T) {
+	msg, ok := parseCLIPullLine("a2abf6c4d29d: Pull complete")
+	assert.True(t, ok)
+	assert.Equal(t, "a2abf6c4d29d", msg.ID)
+	assert.Equal(t, "Pull complete", msg.Status)
+
+	msg, _ = parseCLIPullLine("docker.io/library/nginx:latest")
+	assert.Empty(t, msg.ID)
+	assert.Equal(t, "docker.io/library/nginx:latest", msg.Status)
+
+	tracker := newPullTracker("nginx")
+	msg, _ = parseCLIPullLine("Digest: sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31")
+	assert.NoError(t, tracker.apply(msg))
+	assert.Empty(t, tracker.snapshot().Layers)
+
+	_, ok = parseCLIPullLine("  ")
+	assert.False(t, ok)
+
+	assert.True(t, isRegistryAuthError(errors.New("pull access denied for private/app, repository does not exist or may require 'docker login'")))
+	assert.False(t, isRegistryAuthError(errors.New("manifest unknown")))
+}
@@ -0,0 +1,81 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/docker/docker/api/types/registry"
+)
+
+// RegistryAuth 面板下发的镜像仓库凭据，仅在单次请求中使用，Agent 不保存
+type RegistryAuth struct {
+	ServerAddress string `json:"server_address"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+}
+
+// registryAuthHeader 生成 Docker API 的 X-Registry-Auth 请求头，auth 为 nil 时返回空字符串
+func registryAuthHeader(auth *RegistryAuth) (string, error) {
+	if auth == nil {
+		return "", nil
+	}
+	return registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      auth.Username,
+		Password:      auth.Password,
+		ServerAddress: auth.ServerAddress,
+	})
+}
+
+// registryLoginArgs 构造 docker login 参数，密码通过标准输入传递，不出现在进程参数中；
+// configDir 非空时登录信息写入该目录而不是当前用户的 ~/.docker
+func registryLoginArgs(configDir string, auth *RegistryAuth) []string {
+	var args []string
+	if configDir != "" {
+		args = append(args, "--config", configDir)
+	}
+	args = append(args, "login", "--username", auth.Username, "--password-stdin")
+	if auth.ServerAddress != "" {
+		args = append(args, auth.ServerAddress)
+	}
+	return args
+}
+
+// runRegistryLogin 执行 docker login
+func runRegistryLogin(ctx context.Context, configDir string, auth *RegistryAuth) error {
+	if auth == nil || auth.Username == "" || auth.Password == "" {
+		return fmt.Errorf("仓库凭据不完整")
+	}
+	cmd := exec.CommandContext(ctx, "docker", registryLoginArgs(configDir, auth)...)
+	cmd.Stdin = strings.NewReader(auth.Password)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("登录镜像仓库 %s 失败: %v, 输出: %s", auth.ServerAddress, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// RegistryLogin 使用面板下发的凭据在本机执行 docker login，登录信息保存在运行 Agent 的用户的 Docker 配置中，
+// 之后 docker compose 等直接调用 docker 命令的操作也能访问该仓库
+func (dm *DockerManager) RegistryLogin(auth *RegistryAuth) error {
+	return runRegistryLogin(dm.ctx, "", auth)
+}
+
+// withRegistryConfig 在临时 Docker 配置目录中登录仓库后执行 fn，结束后删除该目录，
+// 不影响本机已有的 docker login 信息
+func withRegistryConfig(ctx context.Context, auth *RegistryAuth, fn func(configDir string) error) error {
+	configDir, err := os.MkdirTemp("", "agent-docker-config-")
+	if err != nil {
+		return fmt.Errorf("创建临时Docker配置目录失败: %v", err)
+	}
+	defer os.RemoveAll(configDir)
+
+	if err := runRegistryLogin(ctx, configDir, auth); err != nil {
+		return err
+	}
+	return fn(configDir)
+}
@@ -0,0 +1,36 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryAuthHeader(t *testing.T) {
+	header, err := registryAuthHeader(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, header)
+
+	header, err = registryAuthHeader(&RegistryAuth{ServerAddress: "ghcr.io", Username: "bot", Password: "token"})
+	assert.NoError(t, err)
+	data, err := base64.URLEncoding.DecodeString(header)
+	assert.NoError(t, err)
+	var decoded map[string]string
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "bot", decoded["username"])
+	assert.Equal(t, "ghcr.io", decoded["serveraddress"])
+}
+
+func TestRegistryLoginArgs(t *testing.T) {
+	auth := &RegistryAuth{ServerAddress: "ghcr.io", Username: "bot", Password: "s3cret"}
+	args := registryLoginArgs("/tmp/cfg", auth)
+	assert.Equal(t, []string{"--config", "/tmp/cfg", "login", "--username", "bot", "--password-stdin", "ghcr.io"}, args)
+	// 密码只通过标准输入传递
+	assert.NotContains(t, args, "s3cret")
+
+	assert.Equal(t, []string{"login", "--username", "bot", "--password-stdin"}, registryLoginArgs("", &RegistryAuth{Username: "bot"}))
+}
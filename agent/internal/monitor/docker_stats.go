@@ -0,0 +1,212 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// 同时读取资源统计的容器数，Docker 为计算 CPU 使用率每次读取约需 1 秒
+const dockerStatsConcurrency = 8
+
+// ContainerStats 单个运行中容器的资源使用情况，与 docker stats 一致
+type ContainerStats struct {
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	CPUPercent    float64 `json:"cpu_percent"`    // 按单核计，多核容器可超过 100
+	MemoryUsage   uint64  `json:"memory_usage"`   // 不含页缓存的内存占用(bytes)
+	MemoryLimit   uint64  `json:"memory_limit"`   // 内存上限(bytes)，未限制时为宿主机内存
+	MemoryPercent float64 `json:"memory_percent"` // 内存占用相对上限的百分比
+	NetworkRx     uint64  `json:"network_rx"`     // 容器启动以来的累计入站流量(bytes)
+	NetworkTx     uint64  `json:"network_tx"`     // 容器启动以来的累计出站流量(bytes)
+	BlockRead     uint64  `json:"block_read"`     // 容器启动以来的累计块设备读取(bytes)
+	BlockWrite    uint64  `json:"block_write"`    // 容器启动以来的累计块设备写入(bytes)
+	PIDs          uint64  `json:"pids"`
+}
+
+// GetContainerStats 获取所有运行中容器的资源使用情况，单个容器读取失败时跳过
+func (dm *DockerManager) GetContainerStats(ctx context.Context) ([]ContainerStats, error) {
+	if dm.cliMode {
+		return dm.cliGetContainerStats(ctx)
+	}
+
+	containers, err := dm.client.ContainerList(ctx, container.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("获取容器列表失败: %v", err)
+	}
+
+	results := make([]*ContainerStats, len(containers))
+	sem := make(chan struct{}, dockerStatsConcurrency)
+	var wg sync.WaitGroup
+	for i, c := range containers {
+		name := c.ID[:min(12, len(c.ID))]
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		wg.Add(1)
+		go func(i int, id, name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			// stream=false 时 Docker 会等待一个采样周期并填充 precpu_stats，用于计算 CPU 使用率
+			resp, err := dm.client.ContainerStats(ctx, id, false)
+			if err != nil {
+				dm.log.Debug("获取容器 %s 资源统计失败: %v", name, err)
+				return
+			}
+			defer resp.Body.Close()
+
+			var raw container.StatsResponse
+			if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+				dm.log.Debug("解析容器 %s 资源统计失败: %v", name, err)
+				return
+			}
+			stats := calculateContainerStats(id, name, &raw)
+			results[i] = &stats
+		}(i, c.ID, name)
+	}
+	wg.Wait()
+
+	stats := make([]ContainerStats, 0, len(results))
+	for _, s := range results {
+		if s != nil {
+			stats = append(stats, *s)
+		}
+	}
+	return stats, nil
+}
+
+// calculateContainerStats 按 docker stats 的算法从原始统计数据计算资源使用情况
+func calculateContainerStats(id, name string, raw *container.StatsResponse) ContainerStats {
+	stats := ContainerStats{
+		ID:          id,
+		Name:        name,
+		MemoryLimit: raw.MemoryStats.Limit,
+		PIDs:        raw.PidsStats.Current,
+	}
+
+	cpuDelta := float64(raw.CPUStats.CPUUsage.TotalUsage) - float64(raw.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(raw.CPUStats.SystemUsage) - float64(raw.PreCPUStats.SystemUsage)
+	onlineCPUs := float64(raw.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(raw.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		stats.CPUPercent = cpuDelta / systemDelta * onlineCPUs * 100
+	}
+
+	// 与 docker stats 一致，内存占用扣除可回收的页缓存（cgroup v1 为 total_inactive_file，v2 为 inactive_file）
+	stats.MemoryUsage = raw.MemoryStats.Usage
+	for _, key := range []string{"total_inactive_file", "inactive_file"} {
+		if inactive, ok := raw.MemoryStats.Stats[key]; ok {
+			if inactive < stats.MemoryUsage {
+				stats.MemoryUsage -= inactive
+			}
+			break
+		}
+	}
+	if stats.MemoryLimit > 0 {
+		stats.MemoryPercent = float64(stats.MemoryUsage) / float64(stats.MemoryLimit) * 100
+	}
+
+	for _, network := range raw.Networks {
+		stats.NetworkRx += network.RxBytes
+		stats.NetworkTx += network.TxBytes
+	}
+	for _, entry := range raw.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			stats.BlockRead += entry.Value
+		case "write":
+			stats.BlockWrite += entry.Value
+		}
+	}
+	return stats
+}
+
+// cliGetContainerStats 通过 docker stats --no-stream 获取资源使用情况
+func (dm *DockerManager) cliGetContainerStats(ctx context.Context) ([]ContainerStats, error) {
+	output, err := dm.runDockerCLIContext(ctx, "stats", "--no-stream", "--no-trunc", "--format", "{{json .}}")
+	if err != nil {
+		return nil, fmt.Errorf("获取容器资源统计失败: %v", err)
+	}
+
+	var stats []ContainerStats
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		s, err := parseCLIContainerStats(line)
+		if err != nil {
+			dm.log.Warn("解析容器资源统计失败: %v", err)
+			continue
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// parseCLIContainerStats 解析 docker stats --format '{{json .}}' 输出的一行，
+// 各字段均为带单位的文本，如 "12.5MiB / 1.944GiB"、"1.2kB / 648B"
+func parseCLIContainerStats(line string) (ContainerStats, error) {
+	var item struct {
+		ID       string
+		Name     string
+		CPUPerc  string
+		MemUsage string
+		MemPerc  string
+		NetIO    string
+		BlockIO  string
+		PIDs     string
+	}
+	if err := json.Unmarshal([]byte(line), &item); err != nil {
+		return ContainerStats{}, err
+	}
+
+	stats := ContainerStats{ID: item.ID, Name: item.Name}
+	stats.CPUPercent, _ = strconv.ParseFloat(strings.TrimSuffix(item.CPUPerc, "%"), 64)
+	stats.MemoryPercent, _ = strconv.ParseFloat(strings.TrimSuffix(item.MemPerc, "%"), 64)
+	stats.MemoryUsage, stats.MemoryLimit = parseCLISizePair(item.MemUsage)
+	stats.NetworkRx, stats.NetworkTx = parseCLISizePair(item.NetIO)
+	stats.BlockRead, stats.BlockWrite = parseCLISizePair(item.BlockIO)
+	stats.PIDs, _ = strconv.ParseUint(item.PIDs, 10, 64)
+	return stats, nil
+}
+
+// parseCLISizePair 解析 "a / b" 形式的两个容量
+func parseCLISizePair(s string) (uint64, uint64) {
+	first, second, _ := strings.Cut(s, "/")
+	return parseCLISize(first), parseCLISize(second)
+}
+
+// docker CLI 输出的容量单位：内存为二进制单位(KiB、MiB)，网络和块设备为十进制单位(kB、MB)
+var cliSizeUnits = map[string]float64{
+	"b":  1,
+	"kb": 1e3, "mb": 1e6, "gb": 1e9, "tb": 1e12,
+	"kib": 1 << 10, "mib": 1 << 20, "gib": 1 << 30, "tib": 1 << 40,
+}
+
+// parseCLISize 解析带单位的容量文本，无法解析时返回 0
+func parseCLISize(s string) uint64 {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i <= 0 {
+		return 0
+	}
+	value, err := strconv.ParseFloat(s[:i], 64)
+	unit, ok := cliSizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if err != nil || !ok {
+		return 0
+	}
+	return uint64(math.Round(value * unit))
+}
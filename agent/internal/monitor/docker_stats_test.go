@@ -0,0 +1,59 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateContainerStats(t *testing.T) {
+	var raw container.StatsResponse
+	assert.NoError(t, json.Unmarshal([]byte(`{
+	  "pids_stats": {"current": 12},
+	  "blkio_stats": {"io_service_bytes_recursive": [
+	    {"major": 8, "minor": 0, "op": "read", "value": 4096},
+	    {"major": 8, "minor": 0, "op": "write", "value": 8192},
+	    {"major": 8, "minor": 16, "op": "Read", "value": 1024}
+	  ]},
+	  "cpu_stats": {"cpu_usage": {"total_usage": 3000000000}, "system_cpu_usage": 20000000000, "online_cpus": 4},
+	  "precpu_stats": {"cpu_usage": {"total_usage": 2000000000}, "system_cpu_usage": 16000000000},
+	  "memory_stats": {"usage": 209715200, "limit": 1073741824, "stats": {"inactive_file": 104857600}},
+	  "networks": {"eth0": {"rx_bytes": 1000, "tx_bytes": 500}, "eth1": {"rx_bytes": 24, "tx_bytes": 12}}
+	}`), &raw))
+
+	stats := calculateContainerStats("abc", "web", &raw)
+	// 1s CPU 时间 / 4s 系统时间 * 4 核 = 100%
+	assert.InDelta(t, 100, stats.CPUPercent, 0.001)
+	assert.Equal(t, uint64(100<<20), stats.MemoryUsage)
+	assert.InDelta(t, 9.765625, stats.MemoryPercent, 0.0001)
+	assert.Equal(t, uint64(1024), stats.NetworkRx)
+	assert.Equal(t, uint64(512), stats.NetworkTx)
+	assert.Equal(t, uint64(5120), stats.BlockRead)
+	assert.Equal(t, uint64(8192), stats.BlockWrite)
+	assert.Equal(t, uint64(12), stats.PIDs)
+
+	// 容器刚启动、没有上一次采样时 CPU 使用率为 0
+	stats = calculateContainerStats("abc", "web", &container.StatsResponse{})
+	assert.Zero(t, stats.CPUPercent)
+	assert.Zero(t, stats.MemoryPercent)
+}
+
+func TestParseCLIContainerStats(t *testing.T) {
+	stats, err := parseCLIContainerStats(`{"BlockIO":"4.1MB / 0B","CPUPerc":"12.50%","Container":"abc","ID":"abc","MemPerc":"1.26%","MemUsage":"25MiB / 1.944GiB","Name":"web","NetIO":"1.2kB / 648B","PIDs":"7"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "web", stats.Name)
+	assert.Equal(t, 12.5, stats.CPUPercent)
+	assert.Equal(t, uint64(25<<20), stats.MemoryUsage)
+	assert.InDelta(t, 1.944*(1<<30), float64(stats.MemoryLimit), 1)
+	assert.Equal(t, uint64(1200), stats.NetworkRx)
+	assert.Equal(t, uint64(648), stats.NetworkTx)
+	assert.Equal(t, uint64(4100000), stats.BlockRead)
+	assert.Equal(t, uint64(0), stats.BlockWrite)
+	assert.Equal(t, uint64(7), stats.PIDs)
+
+	assert.Equal(t, uint64(0), parseCLISize("--"))
+}
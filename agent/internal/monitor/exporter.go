@@ -0,0 +1,232 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exporter 以 Prometheus 文本格式提供 Agent 最近一次采集的监控数据，
+// 便于不经过面板直接抓取。数据来自上报给面板的同一份样本，抓取时不额外采集，
+// 避免打乱流量增量等按采样窗口计算的指标
+type Exporter struct {
+	mu        sync.Mutex
+	latest    *MonitorData
+	updatedAt time.Time
+	bytesIn   uint64       // Agent 启动以来累计的入站字节
+	bytesOut  uint64       // Agent 启动以来累计的出站字节
+	oomKills  uint64       // Agent 启动以来累计的 OOM kill 次数
+	disks     []DiskHealth // 最近一次 SMART 检查的结果，SMART 信息只在部分样本中携带
+	labels    map[string]string
+
+	server *http.Server
+}
+
+// NewExporter 创建导出器，labels 作为 bettermonitor_agent_info 的标签输出
+func NewExporter(labels map[string]string) *Exporter {
+	return &Exporter{labels: labels}
+}
+
+// Observe 记录一次采集的样本，累计计数器类指标
+func (e *Exporter) Observe(data *MonitorData) {
+	if data == nil {
+		return
+	}
+	sample := *data
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.latest = &sample
+	e.updatedAt = time.Now()
+	e.bytesIn += data.NetworkInDelta
+	e.bytesOut += data.NetworkOutDelta
+	if data.OOMKills > 0 {
+		e.oomKills += uint64(data.OOMKills)
+	}
+	if len(data.SMART) > 0 {
+		e.disks = data.SMART
+	}
+}
+
+// Start 在指定地址和端口上监听 /metrics，监听失败时返回错误
+func (e *Exporter) Start(listen string, port int) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+	ln, err := net.Listen("tcp", net.JoinHostPort(listen, strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	e.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go e.server.Serve(ln)
+	return nil
+}
+
+// Stop 关闭监听
+func (e *Exporter) Stop() {
+	if e.server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	e.server.Shutdown(ctx)
+}
+
+// ServeHTTP 输出 Prometheus 文本格式的指标
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	e.WriteMetrics(w)
+}
+
+// WriteMetrics 按 Prometheus 文本格式写出指标；尚未采集到样本时只输出 Agent 信息
+func (e *Exporter) WriteMetrics(w io.Writer) {
+	e.mu.Lock()
+	var data MonitorData
+	hasData := e.latest != nil
+	if hasData {
+		data = *e.latest
+	}
+	updatedAt, bytesIn, bytesOut, oomKills, disks := e.updatedAt, e.bytesIn, e.bytesOut, e.oomKills, e.disks
+	e.mu.Unlock()
+
+	writeMetric(w, "bettermonitor_agent_info", "gauge", "Agent 信息，值恒为 1", e.labels, 1)
+	if !hasData {
+		return
+	}
+
+	gauges := []struct {
+		name  string
+		help  string
+		value float64
+	}{
+		{"bettermonitor_cpu_usage_percent", "CPU 使用率(%)", data.CPUUsage},
+		{"bettermonitor_memory_used_bytes", "已用内存(bytes)", float64(data.MemoryUsed)},
+		{"bettermonitor_memory_total_bytes", "内存总量(bytes)", float64(data.MemoryTotal)},
+		{"bettermonitor_swap_used_bytes", "已用交换分区(bytes)", float64(data.SwapUsed)},
+		{"bettermonitor_swap_total_bytes", "交换分区总量(bytes)", float64(data.SwapTotal)},
+		{"bettermonitor_disk_used_bytes", "已用磁盘(bytes)", float64(data.DiskUsed)},
+		{"bettermonitor_disk_total_bytes", "磁盘总量(bytes)", float64(data.DiskTotal)},
+		{"bettermonitor_network_receive_bytes_per_second", "网络入站速率(bytes/s)", data.NetworkIn},
+		{"bettermonitor_network_transmit_bytes_per_second", "网络出站速率(bytes/s)", data.NetworkOut},
+		{"bettermonitor_load1", "1 分钟平均负载", data.LoadAvg1},
+		{"bettermonitor_load5", "5 分钟平均负载", data.LoadAvg5},
+		{"bettermonitor_load15", "15 分钟平均负载", data.LoadAvg15},
+		{"bettermonitor_boot_time_seconds", "系统启动时间(Unix 秒)", float64(data.BootTime)},
+		{"bettermonitor_latency_milliseconds", "到面板的延迟(ms)", data.Latency},
+		{"bettermonitor_packet_loss_percent", "到面板的丢包率(%)", data.PacketLoss},
+		{"bettermonitor_processes", "进程数", float64(data.Processes)},
+		{"bettermonitor_tcp_connections", "TCP 连接数", float64(data.TCPConnections)},
+		{"bettermonitor_udp_connections", "UDP 连接数", float64(data.UDPConnections)},
+		{"bettermonitor_zombie_processes", "僵尸进程数", float64(data.Zombies)},
+		{"bettermonitor_agent_errors", "最近 5 分钟 Agent 自身的错误数", float64(data.AgentErrors)},
+		{"bettermonitor_last_sample_timestamp_seconds", "最近一次采集的时间(Unix 秒)", float64(updatedAt.UnixMilli()) / 1000},
+	}
+	for _, g := range gauges {
+		writeMetric(w, g.name, "gauge", g.help, nil, g.value)
+	}
+	writeMetric(w, "bettermonitor_network_receive_bytes_total", "counter", "Agent 启动以来的入站流量(bytes)", nil, float64(bytesIn))
+	writeMetric(w, "bettermonitor_network_transmit_bytes_total", "counter", "Agent 启动以来的出站流量(bytes)", nil, float64(bytesOut))
+	writeMetric(w, "bettermonitor_oom_kills_total", "counter", "Agent 启动以来观察到的 OOM kill 次数", nil, float64(oomKills))
+
+	if len(data.Custom) > 0 {
+		fmt.Fprintf(w, "# HELP bettermonitor_custom_metric 自定义插件采集的指标\n# TYPE bettermonitor_custom_metric gauge\n")
+		for _, m := range data.Custom {
+			writeSample(w, "bettermonitor_custom_metric", map[string]string{"name": m.Name, "plugin": m.Plugin, "unit": m.Unit}, m.Value)
+		}
+	}
+
+	if len(data.Interfaces) > 0 {
+		for _, metric := range []struct {
+			name, help string
+			value      func(i InterfaceStat) float64
+		}{
+			{"bettermonitor_interface_receive_bytes_per_second", "网卡入站速率(bytes/s)", func(i InterfaceStat) float64 { return i.RxRate }},
+			{"bettermonitor_interface_transmit_bytes_per_second", "网卡出站速率(bytes/s)", func(i InterfaceStat) float64 { return i.TxRate }},
+		} {
+			fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", metric.name, metric.help, metric.name)
+			for _, i := range data.Interfaces {
+				writeSample(w, metric.name, map[string]string{"interface": i.Name}, metric.value(i))
+			}
+		}
+	}
+
+	if len(data.Temperatures) > 0 {
+		fmt.Fprintf(w, "# HELP bettermonitor_hwmon_temperature_celsius 硬件传感器温度(°C)\n# TYPE bettermonitor_hwmon_temperature_celsius gauge\n")
+		for _, t := range data.Temperatures {
+			writeSample(w, "bettermonitor_hwmon_temperature_celsius", map[string]string{"chip": t.Chip, "sensor": t.Label}, t.Celsius)
+		}
+	}
+	if len(data.Fans) > 0 {
+		fmt.Fprintf(w, "# HELP bettermonitor_hwmon_fan_rpm 风扇转速(RPM)\n# TYPE bettermonitor_hwmon_fan_rpm gauge\n")
+		for _, f := range data.Fans {
+			writeSample(w, "bettermonitor_hwmon_fan_rpm", map[string]string{"chip": f.Chip, "sensor": f.Label}, float64(f.RPM))
+		}
+	}
+
+	if len(disks) > 0 {
+		writeDiskMetrics(w, disks)
+	}
+}
+
+// writeDiskMetrics 按磁盘输出 SMART 健康指标，未知的温度和寿命不输出
+func writeDiskMetrics(w io.Writer, disks []DiskHealth) {
+	metrics := []struct {
+		name  string
+		help  string
+		value func(d DiskHealth) (float64, bool)
+	}{
+		{"bettermonitor_disk_temperature_celsius", "磁盘温度(°C)", func(d DiskHealth) (float64, bool) {
+			return float64(d.Temperature), d.Temperature > 0
+		}},
+		{"bettermonitor_disk_reallocated_sectors", "磁盘已重映射扇区数", func(d DiskHealth) (float64, bool) {
+			return float64(d.ReallocatedSectors), true
+		}},
+		{"bettermonitor_disk_wear_percent", "SSD 已用寿命(%)", func(d DiskHealth) (float64, bool) {
+			return float64(d.WearLevel), d.WearLevel >= 0
+		}},
+		{"bettermonitor_disk_predicted_failure", "SMART 预测磁盘即将故障时为 1", func(d DiskHealth) (float64, bool) {
+			if d.PredictedFailure {
+				return 1, true
+			}
+			return 0, true
+		}},
+	}
+	for _, metric := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", metric.name, metric.help, metric.name)
+		for _, d := range disks {
+			if value, ok := metric.value(d); ok {
+				writeSample(w, metric.name, map[string]string{"device": d.Device, "model": d.Model, "type": d.Type}, value)
+			}
+		}
+	}
+}
+
+func writeMetric(w io.Writer, name, metricType, help string, labels map[string]string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+	writeSample(w, name, labels, value)
+}
+
+func writeSample(w io.Writer, name string, labels map[string]string, value float64) {
+	if len(labels) == 0 {
+		fmt.Fprintf(w, "%s %s\n", name, strconv.FormatFloat(value, 'f', -1, 64))
+		return
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+`="`+labelEscaper.Replace(labels[k])+`"`)
+	}
+	fmt.Fprintf(w, "%s{%s} %s\n", name, strings.Join(pairs, ","), strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// labelEscaper 按 Prometheus 文本格式转义标签值中的反斜杠、双引号和换行
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
@@ -0,0 +1,72 @@
+package monitor
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExporterWritesPrometheusMetrics(t *testing.T) {
+	e := NewExporter(map[string]string{"version": "1.0.0", "agent_type": "full"})
+
+	var before strings.Builder
+	e.WriteMetrics(&before)
+	assert.Contains(t, before.String(), `bettermonitor_agent_info{agent_type="full",version="1.0.0"} 1`)
+	assert.NotContains(t, before.String(), "bettermonitor_cpu_usage_percent")
+
+	e.Observe(&MonitorData{CPUUsage: 12.5, NetworkInDelta: 1000, OOMKills: 1,
+		SMART: []DiskHealth{{Device: "/dev/sda", Model: "HDD", Type: "hdd", Temperature: 38, WearLevel: -1, PredictedFailure: true}}})
+	e.Observe(&MonitorData{
+		CPUUsage:       42.5,
+		MemoryUsed:     1 << 30,
+		NetworkInDelta: 500,
+		Custom:         []CustomMetric{{Name: `queue "main"`, Value: 7, Plugin: "queue.sh"}},
+		Temperatures:   []TemperatureSensor{{Chip: "coretemp", Label: "Package id 0", Celsius: 61}},
+		Fans:           []FanSensor{{Chip: "nct6775", Label: "fan1", RPM: 1250}},
+		Interfaces:     []InterfaceStat{{Name: "eth0", RxRate: 2048, TxRate: 512}, {Name: "docker0", Virtual: true, TxRate: 64}},
+	})
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, body, "# TYPE bettermonitor_cpu_usage_percent gauge\nbettermonitor_cpu_usage_percent 42.5\n")
+	assert.Contains(t, body, "bettermonitor_memory_used_bytes 1073741824\n")
+	// 计数器累加所有样本的增量
+	assert.Contains(t, body, "# TYPE bettermonitor_network_receive_bytes_total counter\nbettermonitor_network_receive_bytes_total 1500\n")
+	assert.Contains(t, body, "bettermonitor_oom_kills_total 1\n")
+	assert.Contains(t, body, `bettermonitor_custom_metric{name="queue \"main\"",plugin="queue.sh",unit=""} 7`)
+	assert.Contains(t, body, `bettermonitor_hwmon_temperature_celsius{chip="coretemp",sensor="Package id 0"} 61`)
+	assert.Contains(t, body, `bettermonitor_hwmon_fan_rpm{chip="nct6775",sensor="fan1"} 1250`)
+	assert.Contains(t, body, `bettermonitor_interface_receive_bytes_per_second{interface="eth0"} 2048`)
+	assert.Contains(t, body, `bettermonitor_interface_transmit_bytes_per_second{interface="docker0"} 64`)
+	// 没有携带 SMART 信息的样本保留上一次检查的结果，未知的寿命不输出
+	assert.Contains(t, body, `bettermonitor_disk_temperature_celsius{device="/dev/sda",model="HDD",type="hdd"} 38`)
+	assert.Contains(t, body, `bettermonitor_disk_predicted_failure{device="/dev/sda",model="HDD",type="hdd"} 1`)
+	assert.NotContains(t, body, `bettermonitor_disk_wear_percent{`)
+}
+
+func TestExporterListensOnConfiguredAddress(t *testing.T) {
+	// 先占用一个空闲端口再释放，交给导出器监听
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	e := NewExporter(nil)
+	assert.NoError(t, e.Start("127.0.0.1", port))
+	defer e.Stop()
+
+	resp, err := http.Get("http://127.0.0.1:" + strconv.Itoa(port) + "/metrics")
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	// 同一地址和端口不能重复监听
+	assert.Error(t, NewExporter(nil).Start("127.0.0.1", port))
+}
@@ -0,0 +1,171 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	// 读取末尾行时最多回溯的字节数，也是每次 Poll 最多读取的字节数
+	maxFollowReadBytes = 4 << 20
+	// 单行的最大长度，超出部分截断
+	maxFollowLineLen = 64 * 1024
+)
+
+// FileFollower 跟踪文件新写入的内容。
+// 路径指向的文件被替换（logrotate 重命名后新建）时先读完旧文件再切换到新文件，
+// 文件被截断时从头读取；路径暂时不存在时继续读取已打开的文件
+type FileFollower struct {
+	path    string
+	file    *os.File
+	info    os.FileInfo
+	offset  int64
+	pending []byte // 尚未遇到换行符的不完整行
+}
+
+// OpenFileFollower 打开普通文件，返回其末尾最多 lines 行，之后从文件末尾开始跟踪
+func OpenFileFollower(path string, lines int) (*FileFollower, []string, error) {
+	file, info, err := openRegularFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	f := &FileFollower{path: path, file: file, info: info}
+
+	start := max(info.Size()-maxFollowReadBytes, 0)
+	buf := make([]byte, info.Size()-start)
+	if _, err := file.ReadAt(buf, start); err != nil && !errors.Is(err, io.EOF) {
+		file.Close()
+		return nil, nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+	// 末尾不完整的行留到写完后再发送，跟踪时从该行开头读取
+	end := bytes.LastIndexByte(buf, '\n') + 1
+	f.offset = start + int64(end)
+	buf = buf[:end]
+	// 从文件中间开始读取时，第一行可能不完整
+	if start > 0 {
+		if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+			buf = buf[i+1:]
+		}
+	}
+
+	all := splitTailLines(buf)
+	return f, all[max(len(all)-lines, 0):], nil
+}
+
+// openRegularFile 打开文件并确认是普通文件，避免跟踪设备文件或命名管道时阻塞
+func openRegularFile(path string) (*os.File, os.FileInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("无法访问文件: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, nil, fmt.Errorf("%s 不是普通文件", path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("打开文件失败: %w", err)
+	}
+	return file, info, nil
+}
+
+// FollowFileFromStart 打开普通文件并从头开始跟踪，用于跟踪期间新出现的文件
+func FollowFileFromStart(path string) (*FileFollower, error) {
+	file, info, err := openRegularFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileFollower{path: path, file: file, info: info}, nil
+}
+
+// Poll 读取上次以来新写入的完整行
+func (f *FileFollower) Poll() ([]string, error) {
+	lines, err := f.readNew()
+	if err != nil {
+		return lines, err
+	}
+	if cur, err := f.file.Stat(); err == nil && f.offset < cur.Size() {
+		// 本次没有读完，下次继续
+		return lines, nil
+	}
+
+	if info, statErr := os.Stat(f.path); statErr == nil && !os.SameFile(info, f.info) {
+		// 文件已轮转：旧文件已经读完，不完整的行视为结束
+		if len(f.pending) > 0 {
+			lines = append(lines, splitTailLines(append(f.pending, '\n'))...)
+		}
+		file, newInfo, err := openRegularFile(f.path)
+		if err != nil {
+			// 新文件可能尚未创建完成，下次再试
+			return lines, nil
+		}
+		f.file.Close()
+		f.file, f.info, f.offset, f.pending = file, newInfo, 0, nil
+		more, err := f.readNew()
+		return append(lines, more...), err
+	}
+	return lines, nil
+}
+
+// readNew 从当前位置读到文件末尾，每次最多读取 maxFollowReadBytes 字节，其余留到下次；
+// 文件变短时视为被截断并从头读取
+func (f *FileFollower) readNew() ([]string, error) {
+	info, err := f.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < f.offset {
+		f.offset, f.pending = 0, nil
+	}
+
+	var lines []string
+	buf := make([]byte, 64*1024)
+	end := min(info.Size(), f.offset+maxFollowReadBytes)
+	for f.offset < end {
+		n, err := f.file.ReadAt(buf, f.offset)
+		f.offset += int64(n)
+		f.pending = append(f.pending, buf[:n]...)
+		if i := bytes.LastIndexByte(f.pending, '\n'); i >= 0 {
+			lines = append(lines, splitTailLines(f.pending[:i+1])...)
+			f.pending = append(f.pending[:0], f.pending[i+1:]...)
+		}
+		if len(f.pending) > maxFollowLineLen {
+			lines = append(lines, splitTailLines(append(f.pending[:maxFollowLineLen], '\n'))...)
+			f.pending = f.pending[:0]
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return lines, err
+		}
+	}
+	return lines, nil
+}
+
+// Close 关闭文件
+func (f *FileFollower) Close() {
+	f.file.Close()
+}
+
+// splitTailLines 将以换行符结尾的内容拆分为行，去掉 \r，超长的行截断，非法 UTF-8 替换为 ?
+func splitTailLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	parts := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	lines := make([]string, 0, len(parts))
+	for _, part := range parts {
+		part = bytes.TrimSuffix(part, []byte("\r"))
+		if len(part) > maxFollowLineLen {
+			part = part[:maxFollowLineLen]
+		}
+		lines = append(lines, strings.ToValidUTF8(string(part), "?"))
+	}
+	return lines
+}
@@ -0,0 +1,71 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileFollower(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writeFile := func(name, content string) {
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(path, "one\ntwo\r\nthree\npart")
+
+	f, initial, err := OpenFileFollower(path, 2)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer f.Close()
+	// 末尾不完整的行等写完后再发送
+	assert.Equal(t, []string{"two", "three"}, initial)
+
+	appendFile := func(content string) {
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if _, err := file.WriteString(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lines, err := f.Poll()
+	assert.NoError(t, err)
+	assert.Empty(t, lines)
+
+	appendFile("ial\nfour\nfi")
+	lines, err = f.Poll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"partial", "four"}, lines)
+
+	// 截断后从头读取
+	writeFile(path, "new\n")
+	lines, err = f.Poll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"new"}, lines)
+
+	// 轮转：先读完旧文件，再从头读取新文件
+	appendFile("last")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(path, "rotated\n")
+	lines, err = f.Poll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"last", "rotated"}, lines)
+
+	// 不是普通文件时拒绝
+	_, _, err = OpenFileFollower(filepath.Dir(path), 10)
+	assert.ErrorContains(t, err, "不是普通文件")
+	_, _, err = OpenFileFollower(path+".missing", 10)
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package monitor
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// 每次上报最多携带的网卡数量
+const maxInterfaces = 32
+
+// InterfaceStat 单个网卡在两次采集之间的流量、错误和丢包，面板据此区分 eth0 和 docker0 等网卡的流量
+type InterfaceStat struct {
+	Name      string  `json:"name"`
+	Virtual   bool    `json:"virtual,omitempty"` // 容器网桥等虚拟网卡，自动统计累计流量时不计入
+	RxRate    float64 `json:"rx_rate"`           // 入站速率(bytes/s)
+	TxRate    float64 `json:"tx_rate"`           // 出站速率(bytes/s)
+	RxBytes   uint64  `json:"rx_bytes"`          // 周期内的入站字节增量
+	TxBytes   uint64  `json:"tx_bytes"`          // 周期内的出站字节增量
+	RxErrors  uint64  `json:"rx_errors"`         // 周期内新增的接收错误
+	TxErrors  uint64  `json:"tx_errors"`
+	RxDropped uint64  `json:"rx_dropped"` // 周期内新增的接收丢包
+	TxDropped uint64  `json:"tx_dropped"`
+}
+
+// interfaceBaseline 各网卡上一次采集的计数器，用于计算增量
+type interfaceBaseline struct {
+	mu       sync.Mutex
+	counters map[string]net.IOCountersStat
+	at       time.Time
+}
+
+// skipInterface 回环不出本机，veth 是每个容器一块，数量多且已计入对应网桥，均不上报
+func skipInterface(name string) bool {
+	return name == "lo" || strings.HasPrefix(name, "veth") || strings.HasPrefix(strings.ToLower(name), "loopback")
+}
+
+// collect 计算自上次采集以来各网卡的增量，第一次调用只建立基线。
+// 间隔超过 5 分钟时增量照常上报，但速率失真，置为 0
+func (b *interfaceBaseline) collect(stats []net.IOCountersStat, now time.Time) []InterfaceStat {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	last, lastAt := b.counters, b.at
+	b.counters = make(map[string]net.IOCountersStat, len(stats))
+	b.at = now
+	for _, s := range stats {
+		if !skipInterface(s.Name) {
+			b.counters[s.Name] = s
+		}
+	}
+	if last == nil {
+		return nil
+	}
+
+	seconds := now.Sub(lastAt).Seconds()
+	result := make([]InterfaceStat, 0, len(b.counters))
+	for name, current := range b.counters {
+		prev, ok := last[name]
+		if !ok {
+			// 新出现的网卡从下一次采集开始统计
+			continue
+		}
+		delta := func(last, current uint64) uint64 {
+			d, _ := counterDelta(last, current)
+			return d
+		}
+		stat := InterfaceStat{
+			Name:      name,
+			Virtual:   isVirtualInterface(name),
+			RxBytes:   delta(prev.BytesRecv, current.BytesRecv),
+			TxBytes:   delta(prev.BytesSent, current.BytesSent),
+			RxErrors:  delta(prev.Errin, current.Errin),
+			TxErrors:  delta(prev.Errout, current.Errout),
+			RxDropped: delta(prev.Dropin, current.Dropin),
+			TxDropped: delta(prev.Dropout, current.Dropout),
+		}
+		if seconds > 0 && seconds <= 300 {
+			stat.RxRate = float64(stat.RxBytes) / seconds
+			stat.TxRate = float64(stat.TxBytes) / seconds
+		}
+		result = append(result, stat)
+	}
+
+	// 物理网卡在前，同类按名称排序，超出上限时优先保留物理网卡
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Virtual != result[j].Virtual {
+			return !result[i].Virtual
+		}
+		return result[i].Name < result[j].Name
+	})
+	if len(result) > maxInterfaces {
+		result = result[:maxInterfaces]
+	}
+	return result
+}
@@ -0,0 +1,56 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/net"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterfaceBaseline(t *testing.T) {
+	var baseline interfaceBaseline
+	start := time.Now()
+
+	first := []net.IOCountersStat{
+		{Name: "lo", BytesRecv: 1000},
+		{Name: "eth0", BytesRecv: 1000, BytesSent: 500, Errin: 1, Dropin: 2},
+		{Name: "docker0", BytesRecv: 100, BytesSent: 100},
+		{Name: "veth12ab", BytesRecv: 100},
+	}
+	assert.Nil(t, baseline.collect(first, start), "第一次只建立基线")
+
+	second := []net.IOCountersStat{
+		{Name: "lo", BytesRecv: 5000},
+		{Name: "eth0", BytesRecv: 11000, BytesSent: 2500, Errin: 4, Dropin: 2, Dropout: 1},
+		{Name: "docker0", BytesRecv: 50, BytesSent: 600}, // 计数器回退按重置后的值计入
+		{Name: "veth12ab", BytesRecv: 900},
+		{Name: "eth1", BytesRecv: 100},
+	}
+	stats := baseline.collect(second, start.Add(10*time.Second))
+	if assert.Len(t, stats, 2) {
+		eth0 := stats[0]
+		assert.Equal(t, "eth0", eth0.Name)
+		assert.False(t, eth0.Virtual)
+		assert.Equal(t, uint64(10000), eth0.RxBytes)
+		assert.Equal(t, 1000.0, eth0.RxRate)
+		assert.Equal(t, 200.0, eth0.TxRate)
+		assert.Equal(t, uint64(3), eth0.RxErrors)
+		assert.Equal(t, uint64(0), eth0.RxDropped)
+		assert.Equal(t, uint64(1), eth0.TxDropped)
+
+		docker0 := stats[1]
+		assert.Equal(t, "docker0", docker0.Name)
+		assert.True(t, docker0.Virtual)
+		assert.Equal(t, uint64(50), docker0.RxBytes)
+		assert.Equal(t, uint64(500), docker0.TxBytes)
+	}
+
+	// 间隔过长时只上报增量，不计算速率
+	stats = baseline.collect(second, start.Add(20*time.Minute))
+	if assert.Len(t, stats, 3) {
+		assert.Equal(t, "eth0", stats[0].Name)
+		assert.Equal(t, "eth1", stats[1].Name)
+		assert.Zero(t, stats[0].RxRate)
+	}
+}
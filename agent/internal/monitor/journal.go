@@ -0,0 +1,192 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// 查询时默认和最多返回的条数（follow 时为开始跟踪前先返回的条数）
+	defaultJournalLines = 200
+	maxJournalLines     = 5000
+	// 一次查询最多指定的 unit 数
+	maxJournalUnits = 10
+	// JournalQueryTimeout 不跟踪时单次查询的最长时间
+	JournalQueryTimeout = 2 * time.Minute
+	// 单条日志消息的最大长度（字节），超出部分截断
+	maxJournalMessageLen = 8192
+)
+
+// journalUnitPattern systemd unit 名称，允许模板实例（foo@bar.service）和通配符
+var journalUnitPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9@._:\\*-]{0,255}$`)
+
+// journalPriorities syslog 优先级名称，下标即优先级数值
+var journalPriorities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// JournalQuery systemd journal 查询条件
+type JournalQuery struct {
+	Units    []string `json:"units"`    // 只返回这些 unit 的日志，为空表示全部
+	Priority string   `json:"priority"` // 最低优先级（0-7 或 emerg...debug），只返回该级别及更严重的日志
+	Since    int64    `json:"since"`    // 开始时间，Unix 秒，0 表示不限
+	Until    int64    `json:"until"`    // 结束时间，Unix 秒，0 表示不限；follow 时忽略
+	Grep     string   `json:"grep"`     // 消息需要匹配的正则，由 journalctl 过滤（systemd 237 以上）
+	Lines    int      `json:"lines"`    // 最多返回的条数（取时间范围内最新的若干条）
+	Follow   bool     `json:"follow"`   // 返回已有日志后继续推送新日志
+}
+
+// JournalEntry 一条 journal 日志
+type JournalEntry struct {
+	Timestamp  int64  `json:"timestamp"` // Unix 毫秒
+	Priority   int    `json:"priority"`  // 0-7，日志未携带时为 6（info）
+	Unit       string `json:"unit,omitempty"`
+	Identifier string `json:"identifier,omitempty"` // SYSLOG_IDENTIFIER，通常为进程名
+	PID        int    `json:"pid,omitempty"`
+	Message    string `json:"message"`
+}
+
+// LineLimit 返回实际查询的条数，超出范围时使用默认值或上限
+func (q JournalQuery) LineLimit() int {
+	if q.Lines <= 0 {
+		return defaultJournalLines
+	}
+	return min(q.Lines, maxJournalLines)
+}
+
+// JournalCommand 构造执行 journalctl 查询的命令
+func JournalCommand(q JournalQuery) (string, []string, error) {
+	return journalCommand(runtime.GOOS, exec.LookPath, q)
+}
+
+func journalCommand(goos string, lookPath func(string) (string, error), q JournalQuery) (string, []string, error) {
+	if goos != "linux" {
+		return "", nil, errors.New("journal 仅支持 Linux")
+	}
+	if _, err := lookPath("journalctl"); err != nil {
+		return "", nil, errors.New("未找到 journalctl，系统可能未使用 systemd")
+	}
+
+	args := []string{"--no-pager", "--output=json", "--lines=" + strconv.Itoa(q.LineLimit())}
+
+	if len(q.Units) > maxJournalUnits {
+		return "", nil, fmt.Errorf("最多指定 %d 个 unit", maxJournalUnits)
+	}
+	for _, unit := range q.Units {
+		unit = strings.TrimSpace(unit)
+		if !journalUnitPattern.MatchString(unit) {
+			return "", nil, fmt.Errorf("无效的 unit: %s", unit)
+		}
+		args = append(args, "--unit="+unit)
+	}
+
+	if q.Priority != "" {
+		priority, err := parseJournalPriority(q.Priority)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, "--priority=0.."+strconv.Itoa(priority))
+	}
+
+	if q.Since < 0 || q.Until < 0 {
+		return "", nil, errors.New("无效的时间范围")
+	}
+	if q.Since > 0 {
+		args = append(args, "--since=@"+strconv.FormatInt(q.Since, 10))
+	}
+	if q.Follow {
+		args = append(args, "--follow")
+	} else if q.Until > 0 {
+		if q.Since > 0 && q.Until <= q.Since {
+			return "", nil, errors.New("开始时间必须早于结束时间")
+		}
+		args = append(args, "--until=@"+strconv.FormatInt(q.Until, 10))
+	}
+
+	if q.Grep != "" {
+		if len(q.Grep) > 512 {
+			return "", nil, errors.New("匹配规则过长")
+		}
+		args = append(args, "--grep="+q.Grep)
+	}
+	return "journalctl", args, nil
+}
+
+// parseJournalPriority 解析数值或名称形式的优先级
+func parseJournalPriority(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n < len(journalPriorities) {
+		return n, nil
+	}
+	for i, name := range journalPriorities {
+		if s == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("无效的优先级: %s", s)
+}
+
+// ParseJournalEntry 解析 journalctl --output=json 输出的一行
+func ParseJournalEntry(line []byte) (JournalEntry, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return JournalEntry{}, err
+	}
+
+	entry := JournalEntry{Priority: 6}
+	if us, err := strconv.ParseInt(journalField(raw, "__REALTIME_TIMESTAMP"), 10, 64); err == nil {
+		entry.Timestamp = us / 1000
+	}
+	if p, err := strconv.Atoi(journalField(raw, "PRIORITY")); err == nil {
+		entry.Priority = p
+	}
+	entry.Unit = journalField(raw, "_SYSTEMD_UNIT")
+	if entry.Unit == "" {
+		entry.Unit = journalField(raw, "UNIT")
+	}
+	entry.Identifier = journalField(raw, "SYSLOG_IDENTIFIER")
+	if entry.Identifier == "" {
+		entry.Identifier = journalField(raw, "_COMM")
+	}
+	entry.PID, _ = strconv.Atoi(journalField(raw, "_PID"))
+
+	msg := journalField(raw, "MESSAGE")
+	if len(msg) > maxJournalMessageLen {
+		msg = msg[:maxJournalMessageLen]
+	}
+	entry.Message = strings.ToValidUTF8(msg, "?")
+	return entry, nil
+}
+
+// journalField 读取字段的字符串值：普通字段为字符串，含非 UTF-8 或控制字符的字段为字节数组，
+// 同一字段出现多次时为数组，取第一个值
+func journalField(raw map[string]json.RawMessage, key string) string {
+	value, ok := raw[key]
+	if !ok {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(value, &s) == nil {
+		return s
+	}
+	var ints []int
+	if json.Unmarshal(value, &ints) == nil {
+		b := make([]byte, len(ints))
+		for i, v := range ints {
+			b[i] = byte(v)
+		}
+		return string(b)
+	}
+	var values []json.RawMessage
+	if json.Unmarshal(value, &values) == nil && len(values) > 0 {
+		return journalField(map[string]json.RawMessage{key: values[0]}, key)
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournalCommand(t *testing.T) {
+	found := func(string) (string, error) { return "/usr/bin/journalctl", nil }
+
+	name, args, err := journalCommand("linux", found, JournalQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, "journalctl", name)
+	assert.Equal(t, []string{"--no-pager", "--output=json", "--lines=200"}, args)
+
+	_, args, err = journalCommand("linux", found, JournalQuery{
+		Units:    []string{"nginx.service", "getty@tty1.service"},
+		Priority: "warning",
+		Since:    1700000000,
+		Until:    1700003600,
+		Grep:     "timed? out",
+		Lines:    99999,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"--no-pager", "--output=json", "--lines=5000",
+		"--unit=nginx.service", "--unit=getty@tty1.service",
+		"--priority=0..4", "--since=@1700000000", "--until=@1700003600", "--grep=timed? out",
+	}, args)
+
+	// 跟踪时忽略结束时间
+	_, args, err = journalCommand("linux", found, JournalQuery{Priority: "3", Until: 1700003600, Follow: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"--no-pager", "--output=json", "--lines=200", "--priority=0..3", "--follow"}, args)
+
+	for _, q := range []JournalQuery{
+		{Units: []string{"--help"}},
+		{Units: []string{"nginx service"}},
+		{Priority: "8"},
+		{Priority: "verbose"},
+		{Since: 200, Until: 100},
+		{Since: -1},
+	} {
+		_, _, err = journalCommand("linux", found, q)
+		assert.Error(t, err, q)
+	}
+
+	_, _, err = journalCommand("linux", func(string) (string, error) { return "", exec.ErrNotFound }, JournalQuery{})
+	assert.Error(t, err)
+	_, _, err = journalCommand("windows", found, JournalQuery{})
+	assert.Error(t, err)
+}
+
+func TestParseJournalEntry(t *testing.T) {
+	entry, err := ParseJournalEntry([]byte(`{"__REALTIME_TIMESTAMP":"1700000000123456","PRIORITY":"3",` +
+		`"_SYSTEMD_UNIT":"nginx.service","SYSLOG_IDENTIFIER":"nginx","_PID":"1234","MESSAGE":"upstream timed out"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, JournalEntry{
+		Timestamp: 1700000000123, Priority: 3, Unit: "nginx.service", Identifier: "nginx", PID: 1234,
+		Message: "upstream timed out",
+	}, entry)
+
+	// 含控制字符的消息以字节数组输出，缺少优先级时按 info 处理
+	entry, err = ParseJournalEntry([]byte(`{"__REALTIME_TIMESTAMP":"1700000000000000","_COMM":"app","MESSAGE":[104,105,27,255]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 6, entry.Priority)
+	assert.Equal(t, "app", entry.Identifier)
+	assert.Equal(t, "hi\x1b?", entry.Message)
+
+	_, err = ParseJournalEntry([]byte("not json"))
+	assert.Error(t, err)
+}
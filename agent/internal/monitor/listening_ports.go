@@ -0,0 +1,242 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/net"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+const (
+	// 默认返回的监听端口数上限
+	defaultListeningPortLimit = 1000
+	// 允许请求的监听端口数上限
+	maxListeningPortLimit = 5000
+)
+
+// ListeningPort 监听中的端口及其所属进程
+type ListeningPort struct {
+	Protocol string `json:"protocol"` // tcp / tcp6 / udp / udp6
+	Address  string `json:"address"`  // 绑定地址，0.0.0.0、:: 或 * 表示所有地址
+	Port     int    `json:"port"`
+	PID      int32  `json:"pid"`     // 无权限查看其他用户的进程时为 0
+	Process  string `json:"process"` // 进程名
+}
+
+// ListeningPortList 监听端口列表及截断信息
+type ListeningPortList struct {
+	Ports     []ListeningPort `json:"ports"`
+	Total     int             `json:"total"`     // 截断前的端口数
+	Truncated bool            `json:"truncated"` // 是否超过上限被截断
+	Source    string          `json:"source"`    // 数据来源：ss / lsof / gopsutil
+}
+
+var ssUserPattern = regexp.MustCompile(`\("([^"]*)",pid=(\d+)`)
+
+// ListListeningPorts 列出主机上所有监听中的 TCP/UDP 端口及其所属进程。
+// Linux 使用 ss，macOS 使用 lsof，命令不可用或其他系统时改用 gopsutil；limit 超出范围时使用默认值
+func (pm *ProcessManager) ListListeningPorts(limit int) (*ListeningPortList, error) {
+	if limit <= 0 || limit > maxListeningPortLimit {
+		limit = defaultListeningPortLimit
+	}
+
+	var ports []ListeningPort
+	source := ""
+	switch runtime.GOOS {
+	case "linux":
+		if output, err := exec.Command("ss", "-tulnp").Output(); err == nil {
+			ports, source = parseSSListening(string(output)), "ss"
+		} else {
+			pm.log.Debug("执行 ss 失败，改用 gopsutil: %v", err)
+		}
+	case "darwin":
+		// lsof 没有匹配的文件时退出码为 1 且输出为空，按无结果处理
+		tcpOutput, err := exec.Command("lsof", "-nP", "-iTCP", "-sTCP:LISTEN").Output()
+		if errors.Is(err, exec.ErrNotFound) {
+			pm.log.Debug("未找到 lsof，改用 gopsutil")
+			break
+		}
+		udpOutput, _ := exec.Command("lsof", "-nP", "-iUDP").Output()
+		ports, source = parseLsofListening(string(tcpOutput)+"\n"+string(udpOutput)), "lsof"
+	}
+	if source == "" {
+		var err error
+		if ports, err = listeningPortsFromGopsutil(); err != nil {
+			return nil, fmt.Errorf("获取监听端口失败: %w", err)
+		}
+		source = "gopsutil"
+	}
+
+	ports = dedupeListeningPorts(ports)
+	result := &ListeningPortList{Ports: ports, Total: len(ports), Source: source}
+	if len(ports) > limit {
+		result.Ports = ports[:limit]
+		result.Truncated = true
+	}
+	return result, nil
+}
+
+// parseSSListening 解析 ss -tulnp 的输出：
+// tcp LISTEN 0 511 0.0.0.0:80 0.0.0.0:* users:(("nginx",pid=1234,fd=6),("nginx",pid=1235,fd=6))
+func parseSSListening(output string) []ListeningPort {
+	var ports []ListeningPort
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || fields[0] == "Netid" {
+			continue
+		}
+		netid := fields[0]
+		if netid != "tcp" && netid != "udp" {
+			continue
+		}
+		address, port, ok := splitListenAddr(fields[4])
+		if !ok {
+			continue
+		}
+		entry := ListeningPort{Protocol: listenProtocol(netid, address), Address: address, Port: port}
+		if match := ssUserPattern.FindStringSubmatch(line); match != nil {
+			entry.Process = match[1]
+			if pid, err := strconv.Atoi(match[2]); err == nil {
+				entry.PID = int32(pid)
+			}
+		}
+		ports = append(ports, entry)
+	}
+	return ports
+}
+
+// parseLsofListening 解析 lsof -nP -i 的输出：
+// nginx 1234 root 6u IPv4 0x1234567890abcdef 0t0 TCP *:80 (LISTEN)
+func parseLsofListening(output string) []ListeningPort {
+	var ports []ListeningPort
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 9 || fields[0] == "COMMAND" {
+			continue
+		}
+		for i := 4; i < len(fields)-1; i++ {
+			node := fields[i]
+			if node != "TCP" && node != "UDP" {
+				continue
+			}
+			name := fields[i+1]
+			// 已建立的连接（含 -> 对端地址）不是监听端口
+			if strings.Contains(name, "->") {
+				break
+			}
+			address, port, ok := splitListenAddr(name)
+			if !ok {
+				break
+			}
+			protocol := strings.ToLower(node)
+			if fields[4] == "IPv6" {
+				protocol += "6"
+			}
+			entry := ListeningPort{Protocol: protocol, Address: address, Port: port, Process: strings.ReplaceAll(fields[0], `\x20`, " ")}
+			if pid, err := strconv.Atoi(fields[1]); err == nil {
+				entry.PID = int32(pid)
+			}
+			ports = append(ports, entry)
+			break
+		}
+	}
+	return ports
+}
+
+// listeningPortsFromGopsutil 在没有 ss/lsof 的系统上通过 gopsutil 获取监听端口
+func listeningPortsFromGopsutil() ([]ListeningPort, error) {
+	conns, err := net.Connections("inet")
+	if err != nil {
+		return nil, err
+	}
+	var ports []ListeningPort
+	names := make(map[int32]string)
+	for _, conn := range conns {
+		protocol := ""
+		switch {
+		case conn.Type == 1 && conn.Status == "LISTEN":
+			protocol = connectionProtocol(conn)
+		case conn.Type == 2 && conn.Raddr.IP == "" && conn.Raddr.Port == 0:
+			protocol = strings.Replace(connectionProtocol(conn), "tcp", "udp", 1)
+		default:
+			continue
+		}
+		entry := ListeningPort{Protocol: protocol, Address: conn.Laddr.IP, Port: int(conn.Laddr.Port), PID: conn.Pid}
+		if conn.Pid > 0 {
+			name, ok := names[conn.Pid]
+			if !ok {
+				if p, err := process.NewProcess(conn.Pid); err == nil {
+					name, _ = p.Name()
+				}
+				names[conn.Pid] = name
+			}
+			entry.Process = name
+		}
+		ports = append(ports, entry)
+	}
+	return ports, nil
+}
+
+// dedupeListeningPorts 合并同一协议、地址和端口的条目（如 Nginx 的多个 worker 共享同一个监听套接字），
+// 保留 PID 最小的进程（通常是主进程），结果按端口、协议、地址排序
+func dedupeListeningPorts(ports []ListeningPort) []ListeningPort {
+	sort.Slice(ports, func(i, j int) bool {
+		a, b := ports[i], ports[j]
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		if a.Address != b.Address {
+			return a.Address < b.Address
+		}
+		// 有 PID 的条目排在前面
+		if (a.PID > 0) != (b.PID > 0) {
+			return a.PID > 0
+		}
+		return a.PID < b.PID
+	})
+
+	result := make([]ListeningPort, 0, len(ports))
+	for _, p := range ports {
+		if n := len(result); n > 0 {
+			last := result[n-1]
+			if last.Port == p.Port && last.Protocol == p.Protocol && last.Address == p.Address {
+				continue
+			}
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// splitListenAddr 拆分 0.0.0.0:80、[::]:22、*:53、127.0.0.53%lo:53 形式的地址
+func splitListenAddr(addr string) (string, int, bool) {
+	idx := strings.LastIndex(addr, ":")
+	if idx <= 0 {
+		return "", 0, false
+	}
+	port, err := strconv.Atoi(addr[idx+1:])
+	if err != nil || port <= 0 {
+		return "", 0, false
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(addr[:idx], "["), "]")
+	return host, port, true
+}
+
+func listenProtocol(netid, address string) string {
+	if strings.Contains(address, ":") {
+		return netid + "6"
+	}
+	return netid
+}
@@ -0,0 +1,41 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSSListening(t *testing.T) {
+	output := `Netid State  Recv-Q Send-Q  Local Address:Port  Peer Address:Port Process
+udp   UNCONN 0      0       127.0.0.53%lo:53         0.0.0.0:*     users:(("systemd-resolve",pid=612,fd=13))
+tcp   LISTEN 0      511           0.0.0.0:80         0.0.0.0:*     users:(("nginx",pid=1235,fd=6),("nginx",pid=1234,fd=6))
+tcp   LISTEN 0      4096             [::]:22            [::]:*     users:(("sshd",pid=800,fd=4))
+tcp   LISTEN 0      128         127.0.0.1:5432       0.0.0.0:*
+`
+	ports := dedupeListeningPorts(parseSSListening(output))
+	assert.Equal(t, []ListeningPort{
+		{Protocol: "tcp6", Address: "::", Port: 22, PID: 800, Process: "sshd"},
+		{Protocol: "udp", Address: "127.0.0.53%lo", Port: 53, PID: 612, Process: "systemd-resolve"},
+		{Protocol: "tcp", Address: "0.0.0.0", Port: 80, PID: 1235, Process: "nginx"},
+		{Protocol: "tcp", Address: "127.0.0.1", Port: 5432},
+	}, ports)
+}
+
+func TestParseLsofListening(t *testing.T) {
+	output := `COMMAND   PID USER   FD   TYPE             DEVICE SIZE/OFF NODE NAME
+nginx    1234 root    6u  IPv4 0x1234567890abcdef      0t0  TCP *:80 (LISTEN)
+nginx    1240 www     6u  IPv4 0x1234567890abcdef      0t0  TCP *:80 (LISTEN)
+Google\x20 900 me    30u  IPv6 0x2234567890abcdef      0t0  TCP [::1]:9222 (LISTEN)
+mDNSRespo 300 root    8u  IPv4 0x3234567890abcdef      0t0  UDP *:5353
+curl     4000 me     5u  IPv4 0x4234567890abcdef      0t0  UDP 10.0.0.2:50000->8.8.8.8:53
+`
+	ports := dedupeListeningPorts(parseLsofListening(output))
+	assert.Equal(t, []ListeningPort{
+		{Protocol: "tcp", Address: "*", Port: 80, PID: 1234, Process: "nginx"},
+		{Protocol: "udp", Address: "*", Port: 5353, PID: 300, Process: "mDNSRespo"},
+		{Protocol: "tcp6", Address: "::1", Port: 9222, PID: 900, Process: "Google "},
+	}, ports)
+}
@@ -0,0 +1,177 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// 读取新内容和重新匹配路径的间隔
+	logShipPollInterval = 2 * time.Second
+	logShipGlobInterval = 30 * time.Second
+	// 同时跟踪的文件数上限
+	maxLogShipFiles = 50
+	// 两次上报之间最多暂存的行数和字节数，超出后丢弃新行并计数
+	maxLogShipLines = 5000
+	maxLogShipBytes = 2 << 20
+	// 上报的单行最大长度（字节），超出部分截断
+	maxLogShipLineLen = 4096
+)
+
+// LogLine 采集到的一行日志，随下一次监控数据上报
+type LogLine struct {
+	Path      string `json:"path"`
+	Timestamp int64  `json:"timestamp"` // 读取到该行的时间，Unix 毫秒
+	Line      string `json:"line"`
+}
+
+// logShipState 日志采集的调度状态
+type logShipState struct {
+	mu        sync.Mutex
+	patterns  []string
+	followers map[string]*FileFollower
+	globbedAt time.Time
+	scanned   bool // 是否已完成首次匹配，之后新出现的文件从头读取
+	lines     []LogLine
+	bytes     int
+	dropped   int
+	started   bool
+}
+
+// SetLogPaths 更新面板配置的日志路径（支持通配符），首次设置时启动后台采集；
+// 开始采集时已存在的文件只采集之后写入的内容，之后新出现的文件（如轮转产生的新文件）从头读取
+func (m *Monitor) SetLogPaths(patterns []string) {
+	s := &m.logs
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.Equal(s.patterns, patterns) {
+		return
+	}
+	s.patterns = append([]string(nil), patterns...)
+	s.globbedAt = time.Time{}
+	// 配置变更后新匹配到的已有文件同样只采集之后写入的内容
+	s.scanned = false
+	if !s.started && len(patterns) > 0 {
+		s.started = true
+		go m.runLogShipLoop()
+	}
+}
+
+// runLogShipLoop 定期读取各文件新写入的行
+func (m *Monitor) runLogShipLoop() {
+	ticker := time.NewTicker(logShipPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		m.pollLogFiles(time.Now())
+	}
+}
+
+// pollLogFiles 按需重新匹配路径，然后读取所有跟踪中的文件
+func (m *Monitor) pollLogFiles(now time.Time) {
+	s := &m.logs
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.followers == nil {
+		s.followers = make(map[string]*FileFollower)
+	}
+	if now.Sub(s.globbedAt) >= logShipGlobInterval {
+		s.globbedAt = now
+		m.refreshLogFiles(s)
+	}
+
+	paths := make([]string, 0, len(s.followers))
+	for path := range s.followers {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		lines, err := s.followers[path].Poll()
+		for _, line := range lines {
+			s.append(LogLine{Path: path, Timestamp: now.UnixMilli(), Line: line})
+		}
+		if err != nil {
+			m.log.Warn("读取日志文件 %s 失败: %v", path, err)
+			s.followers[path].Close()
+			delete(s.followers, path)
+		}
+	}
+}
+
+// refreshLogFiles 展开路径中的通配符，开始跟踪新匹配的文件，停止跟踪不再匹配的文件
+func (m *Monitor) refreshLogFiles(s *logShipState) {
+	matched := make(map[string]bool)
+	for _, pattern := range s.patterns {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			m.log.Warn("日志路径 %s 无效: %v", pattern, err)
+			continue
+		}
+		for _, path := range paths {
+			if len(matched) >= maxLogShipFiles {
+				break
+			}
+			matched[path] = true
+		}
+	}
+
+	for path, f := range s.followers {
+		if !matched[path] {
+			f.Close()
+			delete(s.followers, path)
+		}
+	}
+	for path := range matched {
+		if _, ok := s.followers[path]; ok {
+			continue
+		}
+		var f *FileFollower
+		var err error
+		if s.scanned {
+			f, err = FollowFileFromStart(path)
+		} else {
+			f, _, err = OpenFileFollower(path, 0)
+		}
+		if err != nil {
+			// 目录等非普通文件同样会被通配符匹配到，不视为错误
+			m.log.Debug("跳过日志文件 %s: %v", path, err)
+			continue
+		}
+		s.followers[path] = f
+	}
+	s.scanned = true
+}
+
+// append 暂存一行，超出上限时丢弃
+func (s *logShipState) append(line LogLine) {
+	if len(line.Line) > maxLogShipLineLen {
+		cut := maxLogShipLineLen
+		for cut > 0 && !utf8.RuneStart(line.Line[cut]) {
+			cut--
+		}
+		line.Line = line.Line[:cut]
+	}
+	if len(s.lines) >= maxLogShipLines || s.bytes+len(line.Line) > maxLogShipBytes {
+		s.dropped++
+		return
+	}
+	s.lines = append(s.lines, line)
+	s.bytes += len(line.Line)
+}
+
+// collectLogLines 取出上次上报以来采集到的行及因超出上限丢弃的行数
+func (m *Monitor) collectLogLines() ([]LogLine, int) {
+	s := &m.logs
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lines, dropped := s.lines, s.dropped
+	s.lines, s.bytes, s.dropped = nil, 0, 0
+	return lines, dropped
+}
@@ -0,0 +1,18 @@
+//go:build monitor_only
+
+package monitor
+
+// LogLine 采集到的一行日志
+type LogLine struct {
+	Path      string `json:"path"`
+	Timestamp int64  `json:"timestamp"`
+	Line      string `json:"line"`
+}
+
+// logShipState 监控版不采集日志
+type logShipState struct{}
+
+// SetLogPaths 监控版不采集日志，忽略配置
+func (m *Monitor) SetLogPaths(patterns []string) {}
+
+func (m *Monitor) collectLogLines() ([]LogLine, int) { return nil, 0 }
@@ -0,0 +1,66 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func TestLogShipping(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(existing, []byte("old line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := logger.New("", "error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Monitor{log: log}
+	m.logs.patterns = []string{filepath.Join(dir, "*.log")}
+	now := time.Now()
+	m.pollLogFiles(now)
+	lines, dropped := m.collectLogLines()
+	assert.Empty(t, lines, "已有内容不采集")
+	assert.Zero(t, dropped)
+
+	appendFile(t, existing, "new line\n")
+	// 首次匹配之后出现的文件从头读取
+	if err := os.WriteFile(filepath.Join(dir, "other.log"), []byte("first\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.pollLogFiles(now.Add(logShipGlobInterval))
+	lines, _ = m.collectLogLines()
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, LogLine{Path: existing, Timestamp: now.Add(logShipGlobInterval).UnixMilli(), Line: "new line"}, lines[0])
+		assert.Equal(t, "first", lines[1].Line)
+	}
+
+	// 超长行截断，超出暂存上限的行丢弃
+	appendFile(t, existing, strings.Repeat("x", maxLogShipLineLen+10)+"\n"+strings.Repeat("line\n", maxLogShipLines))
+	m.pollLogFiles(now.Add(logShipGlobInterval + time.Second))
+	lines, dropped = m.collectLogLines()
+	assert.Len(t, lines, maxLogShipLines)
+	assert.Len(t, lines[0].Line, maxLogShipLineLen)
+	assert.Equal(t, 1, dropped)
+}
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,145 @@
+package monitor
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// 节点互测的默认间隔和下限
+	defaultMeshInterval = 60 * time.Second
+	minMeshInterval     = 10 * time.Second
+	// 单个节点的 ping 超时
+	meshProbeTimeout = 3 * time.Second
+	// 同时探测的节点数
+	meshConcurrency = 8
+	// 两次上报之间最多暂存的结果数，断线较久时丢弃最早的结果
+	maxMeshResults = 2000
+)
+
+// MeshPeer 面板下发的其他被监控节点
+type MeshPeer struct {
+	ServerID uint   `json:"server_id"`
+	Target   string `json:"target"` // 节点的 IP 或主机名
+}
+
+// MeshConfig 节点互测配置，Peers 为空表示未开启
+type MeshConfig struct {
+	Interval int        `json:"interval"` // 秒
+	Peers    []MeshPeer `json:"peers"`
+}
+
+// MeshResult 对单个节点的一次 ping 结果，随下一次监控数据上报
+type MeshResult struct {
+	PeerID    uint    `json:"peer_id"`
+	Timestamp int64   `json:"timestamp"` // Unix 毫秒
+	Success   bool    `json:"success"`
+	LatencyMs float64 `json:"latency_ms,omitempty"`
+}
+
+// meshState 节点互测的调度状态
+type meshState struct {
+	mu      sync.Mutex
+	config  MeshConfig
+	lastRun time.Time
+	running bool
+	results []MeshResult
+	started bool
+	// 探测函数，测试时替换
+	probe func(ctx context.Context, target string) (time.Duration, error)
+}
+
+// SetMeshConfig 更新面板下发的节点列表，首次设置时启动后台调度
+func (m *Monitor) SetMeshConfig(config MeshConfig) {
+	s := &m.mesh
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.config = config
+	if !s.started && len(config.Peers) > 0 {
+		s.started = true
+		go m.runMeshLoop()
+	}
+}
+
+// runMeshLoop 每 5 秒检查一次是否到了下一轮互测
+func (m *Monitor) runMeshLoop() {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		m.runDueMeshRound(time.Now())
+	}
+}
+
+// runDueMeshRound 到期时在后台 ping 全部节点，上一轮尚未结束时跳过
+func (m *Monitor) runDueMeshRound(now time.Time) {
+	s := &m.mesh
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	interval := defaultMeshInterval
+	if s.config.Interval > 0 {
+		interval = max(time.Duration(s.config.Interval)*time.Second, minMeshInterval)
+	}
+	if len(s.config.Peers) == 0 || s.running || now.Sub(s.lastRun) < interval {
+		return
+	}
+	s.running = true
+	s.lastRun = now
+	peers := append([]MeshPeer(nil), s.config.Peers...)
+	probe := s.probe
+	if probe == nil {
+		probe = func(ctx context.Context, target string) (time.Duration, error) {
+			return probeUptimeICMP(ctx, target, meshProbeTimeout, defaultProbePolicy)
+		}
+	}
+
+	go func() {
+		results := probeMeshPeers(peers, probe)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.running = false
+		s.results = append(s.results, results...)
+		if len(s.results) > maxMeshResults {
+			s.results = s.results[len(s.results)-maxMeshResults:]
+		}
+	}()
+}
+
+// probeMeshPeers 并发 ping 各节点，结果顺序与 peers 一致
+func probeMeshPeers(peers []MeshPeer, probe func(ctx context.Context, target string) (time.Duration, error)) []MeshResult {
+	results := make([]MeshResult, len(peers))
+	sem := make(chan struct{}, meshConcurrency)
+	var wg sync.WaitGroup
+	for i, peer := range peers {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, peer MeshPeer) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// 超时多留 2 秒给 ping 进程启动和域名解析
+			ctx, cancel := context.WithTimeout(context.Background(), meshProbeTimeout+2*time.Second)
+			defer cancel()
+			result := MeshResult{PeerID: peer.ServerID, Timestamp: time.Now().UnixMilli()}
+			if elapsed, err := probe(ctx, peer.Target); err == nil {
+				result.Success = true
+				result.LatencyMs = float64(elapsed.Microseconds()) / 1000
+			}
+			results[i] = result
+		}(i, peer)
+	}
+	wg.Wait()
+	return results
+}
+
+// collectMeshResults 取出上次上报以来的互测结果
+func (m *Monitor) collectMeshResults() []MeshResult {
+	s := &m.mesh
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := s.results
+	s.results = nil
+	return results
+}
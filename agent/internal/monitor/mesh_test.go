@@ -0,0 +1,48 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeshRound(t *testing.T) {
+	m := &Monitor{}
+	m.mesh.probe = func(ctx context.Context, target string) (time.Duration, error) {
+		if target == "10.0.0.2" {
+			return 1500 * time.Microsecond, nil
+		}
+		return 0, errors.New("无响应")
+	}
+	m.mesh.config = MeshConfig{Interval: 30, Peers: []MeshPeer{{ServerID: 2, Target: "10.0.0.2"}, {ServerID: 3, Target: "10.0.0.3"}}}
+
+	now := time.Now()
+	m.runDueMeshRound(now)
+	assert.Eventually(t, func() bool {
+		m.mesh.mu.Lock()
+		defer m.mesh.mu.Unlock()
+		return !m.mesh.running
+	}, 5*time.Second, 10*time.Millisecond)
+
+	results := m.collectMeshResults()
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, uint(2), results[0].PeerID)
+		assert.True(t, results[0].Success)
+		assert.Equal(t, 1.5, results[0].LatencyMs)
+		assert.Equal(t, uint(3), results[1].PeerID)
+		assert.False(t, results[1].Success)
+	}
+	assert.Empty(t, m.collectMeshResults())
+
+	// 未到间隔不重复执行
+	m.runDueMeshRound(now.Add(20 * time.Second))
+	assert.False(t, m.mesh.running)
+
+	// 关闭互测后不再探测
+	m.mesh.config = MeshConfig{}
+	m.runDueMeshRound(now.Add(time.Hour))
+	assert.False(t, m.mesh.running)
+}
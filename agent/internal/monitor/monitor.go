@@ -2,11 +2,13 @@ package monitor
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	stdnet "net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/shirou/gopsutil/v4/cpu"
@@ -36,6 +38,8 @@ type SystemInfo struct {
 	PublicIP        string `json:"public_ip"` // 出口IP
 	AgentVersion    string `json:"agent_version"`
 	AgentType       string `json:"agent_type"` // full 或 monitor
+
+	Capabilities *Capabilities `json:"capabilities,omitempty"` // 运行环境能力（Docker 可用性等）
 }
 
 // MonitorData 监控数据结构
@@ -56,23 +60,110 @@ type MonitorData struct {
 	SwapUsed        uint64  `json:"swap_used"`
 	SwapTotal       uint64  `json:"swap_total"`
 	BootTime        uint64  `json:"boot_time"`
-	Latency         float64 `json:"latency"`         // 延迟(ms)
-	PacketLoss      float64 `json:"packet_loss"`     // 丢包率(%)
-	Processes       int     `json:"processes"`       // 进程数
-	TCPConnections  int     `json:"tcp_connections"` // TCP连接数
-	UDPConnections  int     `json:"udp_connections"` // UDP连接数
+	Latency         float64 `json:"latency"`                // 延迟(ms)
+	PacketLoss      float64 `json:"packet_loss"`            // 丢包率(%)
+	Processes       int     `json:"processes"`              // 进程数
+	TCPConnections  int     `json:"tcp_connections"`        // TCP连接数
+	UDPConnections  int     `json:"udp_connections"`        // UDP连接数
+	Zombies         int     `json:"zombies"`                // 僵尸进程数
+	OOMKills        int     `json:"oom_kills"`              // 采样窗口内新增的 OOM kill 次数
+	Live            bool    `json:"live,omitempty"`         // 聚焦查看期间的高频样本，面板只推送不入库
+	ReportInterval  uint64  `json:"report_interval"`        // 当前生效的上报间隔(ms)
+	Idle            bool    `json:"idle,omitempty"`         // 是否处于空闲降频状态
+	AgentErrors     int     `json:"agent_errors"`           // 最近 5 分钟 Agent 自身的错误数
+	CollectedAt     int64   `json:"collected_at,omitempty"` // 采集时间(Unix 毫秒)，只在断线期间缓冲的样本中填写
+	Replay          bool    `json:"replay,omitempty"`       // 重新连接后重放的缓冲样本，面板按采集时间入库并去重
+
+	Custom    []CustomMetric `json:"custom,omitempty"`     // 自定义插件采集的指标
+	OOMEvents []OOMEvent     `json:"oom_events,omitempty"` // 新增 OOM kill 对应的内核日志事件
+	SMART     []DiskHealth   `json:"smart,omitempty"`      // 磁盘 SMART 健康信息，只在完成一次检查后的样本中携带
+	TopCPU    []TopProcess   `json:"top_cpu,omitempty"`    // CPU 占用最高的进程，未开启进程排行时为空
+	TopMemory []TopProcess   `json:"top_memory,omitempty"` // 内存占用最高的进程
+
+	Temperatures []TemperatureSensor `json:"temperatures,omitempty"` // hwmon 温度传感器（CPU、NVMe、主板等）
+	Fans         []FanSensor         `json:"fans,omitempty"`         // hwmon 风扇转速
+
+	Checks []UptimeResult `json:"checks,omitempty"` // 面板分配的可用性检查自上次上报以来的结果
+	Mesh   []MeshResult   `json:"mesh,omitempty"`   // 对其他节点的 ping 结果
+
+	Logs        []LogLine `json:"logs,omitempty"`         // 日志采集自上次上报以来读取到的行
+	LogsDropped int       `json:"logs_dropped,omitempty"` // 超出暂存上限被丢弃的行数
+
+	Certificates *CertificateScan `json:"certificates,omitempty"` // 本机证书列表，只在完成一次扫描后的样本中携带
+
+	Nginx *NginxStatus `json:"nginx,omitempty"` // Nginx stub_status 指标和上游可用性，未配置时为空
+
+	Databases []DatabaseStatus `json:"databases,omitempty"` // MySQL、PostgreSQL 和 Redis 的健康指标，未配置时为空
+
+	Scripts []CheckScriptResult `json:"scripts,omitempty"` // 面板下发的检查脚本的最新结果
+
+	Mounts     []MountUsage    `json:"mounts,omitempty"`     // 各挂载点的空间使用情况
+	Interfaces []InterfaceStat `json:"interfaces,omitempty"` // 各网卡的流量、错误和丢包
+	TCPStates  map[string]int  `json:"tcp_states,omitempty"` // 各状态的 TCP 连接数，如 ESTABLISHED、TIME_WAIT
 }
 
 // Monitor 系统监控器
 type Monitor struct {
 	log       *logger.Logger
 	serverURL string // 后端服务器URL，用于ping检测
+	// 配置了面板证书指纹时，延迟检测同样只接受匹配的证书
+	pingTransport http.RoundTripper
 
 	// 用于计算上报周期内的流量增量（准确的总流量统计）
 	lastReportBytesRecv uint64    // 上次上报时的系统累计接收字节数
 	lastReportBytesSent uint64    // 上次上报时的系统累计发送字节数
 	lastReportTime      time.Time // 上次上报时间
 	hasLastReport       bool      // 是否有上次上报的基线数据
+	trafficInterface    string    // 统计累计流量的网卡，为空表示所有非虚拟网卡
+	trafficStatePath    string    // 基线持久化文件，为空表示不持久化
+	trafficBootTime     uint64    // 建立基线时的系统启动时间
+
+	// 各网卡的计数器基线，与累计流量的基线分开，按每次采集的实际间隔计算
+	ifaces interfaceBaseline
+
+	// OOM kill 计数基线，用于只上报新发生的事件
+	oomMu          sync.Mutex
+	oomKillCount   uint64
+	hasOOMBaseline bool
+	oomCheckedAt   time.Time // 上次检查时间，读取内核日志的起点
+	oomLastEvent   time.Time // 已上报的最后一条事件的时间
+
+	// 自定义采集插件，后台执行，采集时上报各插件最近一次的结果
+	plugins pluginState
+
+	// SMART 磁盘健康检查，后台检查完成后结果暂存在 smartResult，随下一次采集上报
+	smartMu        sync.Mutex
+	smartInterval  time.Duration
+	smartCheckedAt time.Time
+	smartRunning   bool
+	smartResult    []DiskHealth
+
+	// 进程排行，保存上次采集时各进程的累计 CPU 时间用于计算使用率
+	topMu         sync.Mutex
+	topProcesses  int
+	procCPUTimes  map[int32]float64
+	procSampledAt time.Time
+
+	// 面板分配的可用性检查，后台按各自间隔执行，结果随下一次采集上报
+	uptime uptimeState
+
+	// 节点互测，后台定期 ping 面板下发的其他节点
+	mesh meshState
+
+	// 日志采集，后台跟踪面板配置的日志文件，新行随下一次采集上报
+	logs logShipState
+
+	// 证书扫描，后台定期列出本机证书，结果随下一次采集上报
+	certs certScanState
+
+	// Nginx stub_status 和上游探测，保存上次的累计值用于计算请求速率
+	nginx nginxStatusState
+
+	// 数据库健康检查，保存各数据库的连接池和上次的累计值
+	databases databaseState
+
+	// 面板下发的检查脚本及其最新结果
+	checkScripts checkScriptState
 }
 
 // New 创建一个新的监控器
@@ -82,9 +173,15 @@ func New(log *logger.Logger) *Monitor {
 	}
 }
 
-// SetServerURL 设置服务器URL用于延迟检测
-func (m *Monitor) SetServerURL(url string) {
+// SetServerURL 设置服务器URL用于延迟检测，tlsConfig 为连接面板使用的 TLS 配置（可为 nil）
+func (m *Monitor) SetServerURL(url string, tlsConfig *tls.Config) {
 	m.serverURL = url
+	m.pingTransport = nil
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		m.pingTransport = transport
+	}
 }
 
 // GetPublicIP 获取出口IP地址
@@ -169,7 +266,8 @@ func (m *Monitor) MeasureLatency() (latency float64, packetLoss float64) {
 
 		// 使用HTTP HEAD请求模拟ping
 		client := &http.Client{
-			Timeout: 2 * time.Second,
+			Transport: m.pingTransport,
+			Timeout:   2 * time.Second,
 		}
 
 		req, err := http.NewRequest("HEAD", m.serverURL, nil)
@@ -290,6 +388,7 @@ func (m *Monitor) GetSystemInfo() (*SystemInfo, error) {
 		PublicIP:        publicIP,
 		AgentVersion:    version.Version,
 		AgentType:       version.AgentType,
+		Capabilities:    m.detectCapabilities(),
 	}, nil
 }
 
@@ -368,82 +467,73 @@ func (m *Monitor) GetMonitorData() (*MonitorData, error) {
 	var sampleDuration uint64 = 0
 
 	// 获取当前系统网络累计计数器
+	// 累计流量只统计配置的网卡（默认所有非虚拟网卡），回环和容器网桥上的流量不出本机，
+	// 计入会导致总流量虚高；基线持久化到文件，Agent 重启后补上停机期间的流量
 	now := time.Now()
-	netStats, err := net.IOCounters(false)
+	netStats, err := net.IOCounters(true)
 	if err != nil {
 		m.log.Warn("获取网络IO信息失败: %v，本次上报流量数据为0", err)
 		// 获取失败时，delta 和速率保持为 0
-	} else if len(netStats) > 0 {
-		currentBytesRecv := netStats[0].BytesRecv
-		currentBytesSent := netStats[0].BytesSent
-
-		// 检查是否有上次上报的基线数据
-		if !m.hasLastReport {
-			// 第一次上报：建立基线，但不产生 delta
-			// 原因：没有"上一次"可以做差，delta 为 0 是正确的
-			m.lastReportBytesRecv = currentBytesRecv
-			m.lastReportBytesSent = currentBytesSent
-			m.lastReportTime = now
-			m.hasLastReport = true
-			m.log.Info("初始化网络流量基线 (入站=%d B, 出站=%d B)", currentBytesRecv, currentBytesSent)
-			// networkInDelta/OutDelta/sampleDuration 保持为 0
+	} else if currentBytesRecv, currentBytesSent, err := selectTrafficCounters(netStats, m.trafficInterface); err != nil {
+		m.log.Warn("%v，本次上报流量数据为0", err)
+	} else if !m.hasLastReport {
+		// 第一次上报：建立基线，仅计入从持久化基线恢复的停机期间流量
+		networkInDelta, networkOutDelta = m.restoreTrafficBaseline(currentBytesRecv, currentBytesSent)
+		m.lastReportBytesRecv = currentBytesRecv
+		m.lastReportBytesSent = currentBytesSent
+		m.lastReportTime = now
+		m.hasLastReport = true
+		m.saveTrafficBaseline()
+		m.log.Info("初始化网络流量基线 (网卡=%s, 入站=%d B, 出站=%d B)",
+			trafficInterfaceName(m.trafficInterface), currentBytesRecv, currentBytesSent)
+	} else {
+		// 计算自上次上报以来的时间间隔
+		reportInterval := now.Sub(m.lastReportTime)
+		reportIntervalSec := reportInterval.Seconds()
+
+		// 防御性检查：确保时间间隔合理
+		if reportIntervalSec <= 0 {
+			m.log.Warn("上报时间间隔异常 (<=0)，跳过本次流量计算")
+			// 保持 delta 和速率为 0，但不更新基线
 		} else {
-			// 计算自上次上报以来的时间间隔
-			reportInterval := now.Sub(m.lastReportTime)
-			reportIntervalSec := reportInterval.Seconds()
-
-			// 防御性检查：确保时间间隔合理
-			if reportIntervalSec <= 0 {
-				m.log.Warn("上报时间间隔异常 (<=0)，跳过本次流量计算")
-				// 保持 delta 和速率为 0，但不更新基线
-			} else if reportIntervalSec > 300 {
-				// 超过 5 分钟，可能是断线重连或系统时钟问题
-				m.log.Warn("上报时间间隔过长 (%.1f 秒)，重置基线避免异常大值", reportIntervalSec)
-				// 重置基线，本次 delta 为 0
-				m.lastReportBytesRecv = currentBytesRecv
-				m.lastReportBytesSent = currentBytesSent
-				m.lastReportTime = now
-			} else {
-				// 正常情况：计算上报周期内的流量增量
-				sampleDuration = uint64(reportInterval / time.Millisecond)
+			// 计数器回退说明网卡被重置（重启、驱动重载等），计数从 0 重新开始，按重置后的值计入
+			var inReset, outReset bool
+			networkInDelta, inReset = counterDelta(m.lastReportBytesRecv, currentBytesRecv)
+			networkOutDelta, outReset = counterDelta(m.lastReportBytesSent, currentBytesSent)
+			if inReset || outReset {
+				m.log.Warn("检测到网卡计数器回退 (入站 %d -> %d, 出站 %d -> %d)，可能是网卡重置，按重置后的流量计入",
+					m.lastReportBytesRecv, currentBytesRecv, m.lastReportBytesSent, currentBytesSent)
+			}
 
-				// 处理计数器回绕/重置
-				// 说明：
-				// - 操作系统网卡计数器在重启、接口重置、驱动问题时可能回退
-				// - 如果检测到回退（当前值 < 上次值），本次 delta 置 0
-				// - 同时重置基线到当前值，避免下次继续回退
-				if currentBytesRecv >= m.lastReportBytesRecv {
-					networkInDelta = currentBytesRecv - m.lastReportBytesRecv
-				} else {
-					networkInDelta = 0
-					m.log.Warn("检测到入站计数器回退 (上次:%d 当前:%d)，可能是网卡重置，本次增量置0",
-						m.lastReportBytesRecv, currentBytesRecv)
-				}
-
-				if currentBytesSent >= m.lastReportBytesSent {
-					networkOutDelta = currentBytesSent - m.lastReportBytesSent
-				} else {
-					networkOutDelta = 0
-					m.log.Warn("检测到出站计数器回退 (上次:%d 当前:%d)，可能是网卡重置，本次增量置0",
-						m.lastReportBytesSent, currentBytesSent)
-				}
-
-				// 计算上报周期内的平均速率 (字节/秒)
+			if reportIntervalSec > 300 {
+				// 超过 5 分钟（断线重连、系统休眠等），流量照常计入总量，
+				// 但平均速率失真，不用于折线图
+				m.log.Warn("上报时间间隔过长 (%.1f 秒)，本次仅累计流量不计算速率", reportIntervalSec)
+			} else {
+				// 正常情况：计算上报周期内的平均速率 (字节/秒)
 				// 注意：这是平均值，不是瞬时值，但对于 30 秒周期的折线图已经足够平滑
+				sampleDuration = uint64(reportInterval / time.Millisecond)
 				networkIn = float64(networkInDelta) / reportIntervalSec
 				networkOut = float64(networkOutDelta) / reportIntervalSec
+			}
 
-				// 更新基线到当前值（无论是否回退都要更新）
-				m.lastReportBytesRecv = currentBytesRecv
-				m.lastReportBytesSent = currentBytesSent
-				m.lastReportTime = now
+			// 更新基线到当前值（无论是否回退都要更新）
+			m.lastReportBytesRecv = currentBytesRecv
+			m.lastReportBytesSent = currentBytesSent
+			m.lastReportTime = now
+			m.saveTrafficBaseline()
 
-				m.log.Debug("网络IO统计: 周期=%.1fs, 入站增量=%d B (速率=%.2f B/s), 出站增量=%d B (速率=%.2f B/s)",
-					reportIntervalSec, networkInDelta, networkIn, networkOutDelta, networkOut)
-			}
+			m.log.Debug("网络IO统计: 周期=%.1fs, 入站增量=%d B (速率=%.2f B/s), 出站增量=%d B (速率=%.2f B/s)",
+				reportIntervalSec, networkInDelta, networkIn, networkOutDelta, networkOut)
 		}
 	}
 
+	// 各网卡分别统计，包括累计流量不计入的虚拟网卡
+	var interfaces []InterfaceStat
+	if len(netStats) > 0 {
+		interfaces = m.ifaces.collect(netStats, now)
+	}
+
 	// 获取Swap信息
 	var swapUsed uint64 = 0
 	var swapTotal uint64 = 0
@@ -474,8 +564,10 @@ func (m *Monitor) GetMonitorData() (*MonitorData, error) {
 	// 测量延迟和丢包率
 	latency, packetLoss := m.MeasureLatency()
 
-	// 获取进程数
+	// 获取进程数和僵尸进程数
 	var processCount int = 0
+	var zombieCount int = 0
+	var topCPU, topMemory []TopProcess
 	procs, err := process.Processes()
 	if err != nil {
 		m.log.Warn("获取进程列表失败: %v", err)
@@ -483,12 +575,15 @@ func (m *Monitor) GetMonitorData() (*MonitorData, error) {
 		processCount = 0
 	} else {
 		processCount = len(procs)
-		m.log.Debug("进程数: %d", processCount)
+		zombieCount = countZombies(procs)
+		topCPU, topMemory = m.collectTopProcesses(procs)
+		m.log.Debug("进程数: %d，僵尸进程数: %d", processCount, zombieCount)
 	}
 
 	// 获取TCP/UDP连接数 - 分别获取以提高稳定性
 	var tcpCount int = 0
 	var udpCount int = 0
+	var tcpStates map[string]int
 
 	// 先尝试获取TCP连接
 	tcpConnections, err := net.Connections("tcp")
@@ -496,6 +591,7 @@ func (m *Monitor) GetMonitorData() (*MonitorData, error) {
 		m.log.Warn("获取TCP连接失败: %v", err)
 	} else {
 		tcpCount = len(tcpConnections)
+		tcpStates = tcpStateCounts(tcpConnections)
 		m.log.Debug("TCP连接数: %d", tcpCount)
 	}
 
@@ -508,6 +604,30 @@ func (m *Monitor) GetMonitorData() (*MonitorData, error) {
 		m.log.Debug("UDP连接数: %d", udpCount)
 	}
 
+	// 检查新发生的 OOM kill
+	oomKills, oomEvents := m.collectOOMKills()
+
+	// 取出自定义插件最近一次的结果，插件在后台执行
+	customMetrics := m.collectCustomMetrics()
+
+	// 读取温度和风扇传感器
+	temperatures, fans := collectSensors()
+
+	// 读取到期检查的磁盘 SMART 信息
+	diskHealth := m.collectDiskHealth()
+
+	// 取出可用性检查结果
+	uptimeResults := m.collectUptimeResults()
+	meshResults := m.collectMeshResults()
+	logLines, logsDropped := m.collectLogLines()
+	certificates := m.collectCertificates()
+	nginxStatus := m.collectNginxStatus()
+	databases := m.collectDatabases()
+	scripts := m.collectCheckScripts()
+
+	// 各挂载点的空间使用情况
+	mounts := collectMounts()
+
 	// 构造监控数据
 	return &MonitorData{
 		CPUUsage:        cpuUsage,
@@ -531,6 +651,26 @@ func (m *Monitor) GetMonitorData() (*MonitorData, error) {
 		Processes:       processCount,
 		TCPConnections:  tcpCount,
 		UDPConnections:  udpCount,
+		Zombies:         zombieCount,
+		OOMKills:        oomKills,
+		Custom:          customMetrics,
+		OOMEvents:       oomEvents,
+		SMART:           diskHealth,
+		TopCPU:          topCPU,
+		TopMemory:       topMemory,
+		Temperatures:    temperatures,
+		Fans:            fans,
+		Checks:          uptimeResults,
+		Mesh:            meshResults,
+		Logs:            logLines,
+		LogsDropped:     logsDropped,
+		Certificates:    certificates,
+		Nginx:           nginxStatus,
+		Databases:       databases,
+		Scripts:         scripts,
+		Mounts:          mounts,
+		Interfaces:      interfaces,
+		TCPStates:       tcpStates,
 	}, nil
 }
 
@@ -0,0 +1,84 @@
+package monitor
+
+import (
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// 每次上报最多携带的挂载点数量
+const maxMounts = 32
+
+// 不占用实际磁盘空间的文件系统，不上报
+var pseudoFilesystems = map[string]bool{
+	"tmpfs": true, "devtmpfs": true, "devfs": true, "overlay": true, "squashfs": true,
+	"proc": true, "sysfs": true, "cgroup": true, "cgroup2": true, "autofs": true,
+	"nsfs": true, "tracefs": true, "debugfs": true, "securityfs": true, "pstore": true,
+	"bpf": true, "configfs": true, "fusectl": true, "mqueue": true, "hugetlbfs": true,
+	"ramfs": true, "efivarfs": true, "binfmt_misc": true, "rpc_pipefs": true, "iso9660": true,
+}
+
+// MountUsage 单个挂载点的空间和 inode 使用情况，供面板按挂载点设置预警规则
+type MountUsage struct {
+	Mount       string `json:"mount"`
+	FSType      string `json:"fstype"`
+	Total       uint64 `json:"total"`
+	Used        uint64 `json:"used"`
+	Free        uint64 `json:"free"`
+	InodesTotal uint64 `json:"inodes_total"` // 部分文件系统（如 btrfs）不限制 inode，为 0
+	InodesUsed  uint64 `json:"inodes_used"`
+	InodesFree  uint64 `json:"inodes_free"`
+}
+
+// skipMount 判断挂载点是否不需要上报：伪文件系统、容器和 snap 的挂载。
+// 单独挂载的 /var/lib/docker 等容器数据目录本身需要上报，只跳过其中的容器挂载
+func skipMount(p disk.PartitionStat) bool {
+	if pseudoFilesystems[strings.ToLower(p.Fstype)] {
+		return true
+	}
+	for _, prefix := range []string{"/proc", "/sys", "/dev", "/run", "/snap"} {
+		if p.Mountpoint == prefix || strings.HasPrefix(p.Mountpoint, prefix+"/") {
+			return true
+		}
+	}
+	for _, dataDir := range []string{"/var/lib/docker", "/var/lib/containers"} {
+		if strings.HasPrefix(p.Mountpoint, dataDir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// collectMounts 读取各挂载点的空间使用情况，同一设备挂载多次时只取第一个挂载点
+func collectMounts() []MountUsage {
+	partitions, err := disk.Partitions(false)
+	if err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var mounts []MountUsage
+	for _, p := range partitions {
+		if skipMount(p) || (p.Device != "" && seen[p.Device]) {
+			continue
+		}
+		usage, err := disk.Usage(p.Mountpoint)
+		if err != nil || usage.Total == 0 {
+			continue
+		}
+		seen[p.Device] = true
+		mounts = append(mounts, MountUsage{
+			Mount:       p.Mountpoint,
+			FSType:      p.Fstype,
+			Total:       usage.Total,
+			Used:        usage.Used,
+			Free:        usage.Free,
+			InodesTotal: usage.InodesTotal,
+			InodesUsed:  usage.InodesUsed,
+			InodesFree:  usage.InodesFree,
+		})
+		if len(mounts) >= maxMounts {
+			break
+		}
+	}
+	return mounts
+}
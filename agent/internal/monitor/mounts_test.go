@@ -0,0 +1,19 @@
+package monitor
+
+import (
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkipMount(t *testing.T) {
+	assert.False(t, skipMount(disk.PartitionStat{Mountpoint: "/", Fstype: "ext4"}))
+	assert.False(t, skipMount(disk.PartitionStat{Mountpoint: "/data", Fstype: "xfs"}))
+	assert.False(t, skipMount(disk.PartitionStat{Mountpoint: "/running", Fstype: "ext4"}))
+	assert.True(t, skipMount(disk.PartitionStat{Mountpoint: "/dev/shm", Fstype: "tmpfs"}))
+	assert.True(t, skipMount(disk.PartitionStat{Mountpoint: "/snap/core/1", Fstype: "squashfs"}))
+	assert.True(t, skipMount(disk.PartitionStat{Mountpoint: "/var/lib/docker/overlay2/x/merged", Fstype: "ext4"}))
+	assert.False(t, skipMount(disk.PartitionStat{Mountpoint: "/var/lib/docker", Fstype: "xfs"}))
+	assert.True(t, skipMount(disk.PartitionStat{Mountpoint: "/run/user/0", Fstype: "ext4"}))
+}
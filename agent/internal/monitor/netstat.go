@@ -0,0 +1,35 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"fmt"
+
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// NetstatResult TCP 连接的状态分布及监听端口清单
+type NetstatResult struct {
+	TCPStates map[string]int     `json:"tcp_states"`
+	TCPTotal  int                `json:"tcp_total"`
+	Listening *ListeningPortList `json:"listening,omitempty"`
+}
+
+// Netstat 统计各状态的 TCP 连接数，withListening 为 true 时附带监听端口及所属进程
+func (pm *ProcessManager) Netstat(withListening bool, limit int) (*NetstatResult, error) {
+	conns, err := net.Connections("tcp")
+	if err != nil {
+		return nil, fmt.Errorf("获取网络连接失败: %w", err)
+	}
+
+	result := &NetstatResult{TCPStates: tcpStateCounts(conns), TCPTotal: len(conns)}
+	if result.TCPStates == nil {
+		result.TCPStates = map[string]int{}
+	}
+	if withListening {
+		if result.Listening, err = pm.ListListeningPorts(limit); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
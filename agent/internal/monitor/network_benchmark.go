@@ -0,0 +1,325 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+const (
+	// iperf3 上传、下载各自的默认测试时长(秒)
+	defaultBenchmarkDuration = 10
+	// 允许请求的最长测试时长(秒)
+	maxBenchmarkDuration = 30
+	// iperf3 测量抖动和丢包的 UDP 测试时长(秒)，使用 iperf3 默认的 1Mbit/s，几乎不占带宽
+	benchmarkUDPDuration = 5
+	// 单次测速的最长时间，需小于面板等待响应的超时
+	benchmarkTimeout = 3 * time.Minute
+	// iperf3 服务端的默认端口
+	defaultIperfPort = 5201
+)
+
+// 测速工具
+const (
+	BenchmarkIperf3    = "iperf3"
+	BenchmarkSpeedtest = "speedtest"
+)
+
+// 作为命令行参数的主机只允许主机名和 IP 中出现的字符，且不能以 - 开头，避免被当作选项
+var hostArgPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.:-]*$`)
+
+// ErrBenchmarkRunning 同一时间只允许一次测速，避免多次测速互相争抢带宽
+var ErrBenchmarkRunning = errors.New("已有测速正在进行，请稍后重试")
+
+// benchmarkMu 保证同一时间只有一次测速
+var benchmarkMu sync.Mutex
+
+// NetworkBenchmarkResult 一次测速的结果，速率单位为 bits/s
+type NetworkBenchmarkResult struct {
+	Tool        string    `json:"tool"`                  // iperf3 / speedtest
+	Target      string    `json:"target"`                // iperf3 服务端地址或 speedtest 节点 ID，为空表示自动选择节点
+	ServerName  string    `json:"server_name,omitempty"` // speedtest 实际使用的测速节点
+	DownloadBps float64   `json:"download_bps"`
+	UploadBps   float64   `json:"upload_bps"`
+	LatencyMs   float64   `json:"latency_ms"`  // iperf3 取 TCP 平均 RTT，仅 Linux 提供
+	JitterMs    float64   `json:"jitter_ms"`   // speedtest-cli 不提供抖动，为 0
+	PacketLoss  float64   `json:"packet_loss"` // 丢包率(%)
+	Duration    int       `json:"duration"`    // iperf3 上传、下载各自的测试时长(秒)
+	StartedAt   time.Time `json:"started_at"`
+}
+
+// NetworkBenchmark 调用系统中的 iperf3 或 speedtest 测量带宽
+type NetworkBenchmark struct {
+	log *logger.Logger
+	// run 执行命令并返回标准输出，测试时替换
+	run func(ctx context.Context, name string, args ...string) ([]byte, error)
+	// lookPath 查找可执行文件，测试时替换
+	lookPath func(file string) (string, error)
+}
+
+// NewNetworkBenchmark 创建测速器
+func NewNetworkBenchmark(log *logger.Logger) *NetworkBenchmark {
+	return &NetworkBenchmark{log: log, run: runBenchmarkCommand, lookPath: exec.LookPath}
+}
+
+// runBenchmarkCommand 执行测速命令；iperf3 出错时仍以 JSON 输出错误信息，因此失败时同样返回标准输出
+func runBenchmarkCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return output, fmt.Errorf("%s 执行超时", name)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return output, fmt.Errorf("%v: %s", err, tailOutput(msg, 1024))
+		}
+	}
+	return output, err
+}
+
+// Run 执行一次测速。tool 为空时，指定了非数字的 target 使用 iperf3，否则使用 speedtest；
+// duration 只对 iperf3 生效，超出范围时使用默认值
+func (b *NetworkBenchmark) Run(tool, target string, duration int) (*NetworkBenchmarkResult, error) {
+	tool = strings.ToLower(strings.TrimSpace(tool))
+	target = strings.TrimSpace(target)
+	if tool == "" {
+		tool = BenchmarkSpeedtest
+		if _, err := strconv.Atoi(target); target != "" && err != nil {
+			tool = BenchmarkIperf3
+		}
+	}
+	if duration <= 0 || duration > maxBenchmarkDuration {
+		duration = defaultBenchmarkDuration
+	}
+
+	if !benchmarkMu.TryLock() {
+		return nil, ErrBenchmarkRunning
+	}
+	defer benchmarkMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), benchmarkTimeout)
+	defer cancel()
+
+	result := &NetworkBenchmarkResult{Tool: tool, Target: target, StartedAt: time.Now()}
+	var err error
+	switch tool {
+	case BenchmarkIperf3:
+		result.Duration = duration
+		err = b.runIperf3(ctx, result)
+	case BenchmarkSpeedtest:
+		err = b.runSpeedtest(ctx, result)
+	default:
+		return nil, fmt.Errorf("不支持的测速工具: %s", tool)
+	}
+	if err != nil {
+		return nil, err
+	}
+	b.log.Info("测速完成: 工具=%s, 下载=%.2f Mbps, 上传=%.2f Mbps, 延迟=%.2f ms",
+		tool, result.DownloadBps/1e6, result.UploadBps/1e6, result.LatencyMs)
+	return result, nil
+}
+
+// parseIperfTarget 解析 iperf3 服务端地址 host[:port]，IPv6 地址带端口时需写成 [::1]:5201
+func parseIperfTarget(target string) (string, int, error) {
+	if target == "" {
+		return "", 0, errors.New("iperf3 需要指定服务端地址")
+	}
+	host, port := target, defaultIperfPort
+	if strings.HasPrefix(target, "[") || strings.Count(target, ":") == 1 {
+		h, p, err := net.SplitHostPort(target)
+		if err != nil {
+			return "", 0, fmt.Errorf("无效的 iperf3 服务端地址: %s", target)
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil || n <= 0 || n > 65535 {
+			return "", 0, fmt.Errorf("无效的 iperf3 端口: %s", p)
+		}
+		host, port = h, n
+	}
+	if len(host) > 253 || !hostArgPattern.MatchString(host) {
+		return "", 0, fmt.Errorf("无效的 iperf3 服务端地址: %s", target)
+	}
+	return host, port, nil
+}
+
+// iperfReport iperf3 -J 输出中用到的字段
+type iperfReport struct {
+	End struct {
+		Streams []struct {
+			Sender struct {
+				MeanRTT float64 `json:"mean_rtt"` // 微秒
+			} `json:"sender"`
+		} `json:"streams"`
+		SumReceived struct {
+			BitsPerSecond float64 `json:"bits_per_second"`
+		} `json:"sum_received"`
+		Sum struct {
+			JitterMs    float64 `json:"jitter_ms"`
+			LostPercent float64 `json:"lost_percent"`
+		} `json:"sum"`
+	} `json:"end"`
+	Error string `json:"error"`
+}
+
+// parseIperfReport 解析 iperf3 的 JSON 输出，iperf3 出错时错误信息在 error 字段中
+func parseIperfReport(output []byte, runErr error) (*iperfReport, error) {
+	var report iperfReport
+	if err := json.Unmarshal(output, &report); err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("执行 iperf3 失败: %w", runErr)
+		}
+		return nil, fmt.Errorf("解析 iperf3 输出失败: %w", err)
+	}
+	if report.Error != "" {
+		return nil, fmt.Errorf("iperf3: %s", report.Error)
+	}
+	if runErr != nil {
+		return nil, fmt.Errorf("执行 iperf3 失败: %w", runErr)
+	}
+	return &report, nil
+}
+
+// runIperf3 依次测量上传、下载（-R 反向）和 UDP 抖动丢包；服务端通常只接受一个客户端，因此不能并行
+func (b *NetworkBenchmark) runIperf3(ctx context.Context, result *NetworkBenchmarkResult) error {
+	if _, err := b.lookPath("iperf3"); err != nil {
+		return errors.New("未安装 iperf3")
+	}
+	host, port, err := parseIperfTarget(result.Target)
+	if err != nil {
+		return err
+	}
+	args := []string{"-c", host, "-p", strconv.Itoa(port), "-J"}
+	seconds := strconv.Itoa(result.Duration)
+
+	output, runErr := b.run(ctx, "iperf3", append(args, "-t", seconds)...)
+	upload, err := parseIperfReport(output, runErr)
+	if err != nil {
+		return err
+	}
+	result.UploadBps = upload.End.SumReceived.BitsPerSecond
+	if len(upload.End.Streams) > 0 {
+		result.LatencyMs = upload.End.Streams[0].Sender.MeanRTT / 1000
+	}
+
+	output, runErr = b.run(ctx, "iperf3", append(args, "-t", seconds, "-R")...)
+	download, err := parseIperfReport(output, runErr)
+	if err != nil {
+		return err
+	}
+	result.DownloadBps = download.End.SumReceived.BitsPerSecond
+
+	// 防火墙常常只放行 TCP，UDP 测试失败时只缺少抖动和丢包
+	output, runErr = b.run(ctx, "iperf3", append(args, "-u", "-t", strconv.Itoa(benchmarkUDPDuration))...)
+	if udp, err := parseIperfReport(output, runErr); err != nil {
+		b.log.Warn("iperf3 UDP 测试失败，不记录抖动和丢包: %v", err)
+	} else {
+		result.JitterMs = udp.End.Sum.JitterMs
+		result.PacketLoss = udp.End.Sum.LostPercent
+	}
+	return nil
+}
+
+// runSpeedtest 优先使用 Ookla 官方的 speedtest，其次使用 speedtest-cli，target 为测速节点 ID
+func (b *NetworkBenchmark) runSpeedtest(ctx context.Context, result *NetworkBenchmarkResult) error {
+	if result.Target != "" {
+		if _, err := strconv.Atoi(result.Target); err != nil {
+			return fmt.Errorf("无效的 speedtest 节点 ID: %s", result.Target)
+		}
+	}
+
+	if _, err := b.lookPath("speedtest"); err == nil {
+		// speedtest-cli 也会安装名为 speedtest 的命令，通过版本信息区分
+		version, _ := b.run(ctx, "speedtest", "--version")
+		if strings.Contains(string(version), "Ookla") {
+			args := []string{"--accept-license", "--accept-gdpr", "-f", "json"}
+			if result.Target != "" {
+				args = append(args, "-s", result.Target)
+			}
+			output, err := b.run(ctx, "speedtest", args...)
+			if err != nil {
+				return fmt.Errorf("执行 speedtest 失败: %w", err)
+			}
+			return parseOoklaSpeedtest(output, result)
+		}
+	}
+
+	if _, err := b.lookPath("speedtest-cli"); err != nil {
+		return errors.New("未安装 speedtest 或 speedtest-cli")
+	}
+	args := []string{"--json", "--secure"}
+	if result.Target != "" {
+		args = append(args, "--server", result.Target)
+	}
+	output, err := b.run(ctx, "speedtest-cli", args...)
+	if err != nil {
+		return fmt.Errorf("执行 speedtest-cli 失败: %w", err)
+	}
+	return parseSpeedtestCLI(output, result)
+}
+
+// parseOoklaSpeedtest 解析 Ookla speedtest -f json 的输出，其中带宽单位为 bytes/s
+func parseOoklaSpeedtest(output []byte, result *NetworkBenchmarkResult) error {
+	var report struct {
+		Ping struct {
+			Jitter  float64 `json:"jitter"`
+			Latency float64 `json:"latency"`
+		} `json:"ping"`
+		Download struct {
+			Bandwidth float64 `json:"bandwidth"`
+		} `json:"download"`
+		Upload struct {
+			Bandwidth float64 `json:"bandwidth"`
+		} `json:"upload"`
+		PacketLoss float64 `json:"packetLoss"`
+		Server     struct {
+			Name     string `json:"name"`
+			Location string `json:"location"`
+		} `json:"server"`
+	}
+	if err := json.Unmarshal(output, &report); err != nil {
+		return fmt.Errorf("解析 speedtest 输出失败: %w", err)
+	}
+	result.DownloadBps = report.Download.Bandwidth * 8
+	result.UploadBps = report.Upload.Bandwidth * 8
+	result.LatencyMs = report.Ping.Latency
+	result.JitterMs = report.Ping.Jitter
+	result.PacketLoss = report.PacketLoss
+	result.ServerName = strings.TrimSpace(report.Server.Name + " " + report.Server.Location)
+	return nil
+}
+
+// parseSpeedtestCLI 解析 speedtest-cli --json 的输出，其中带宽单位为 bits/s
+func parseSpeedtestCLI(output []byte, result *NetworkBenchmarkResult) error {
+	var report struct {
+		Download float64 `json:"download"`
+		Upload   float64 `json:"upload"`
+		Ping     float64 `json:"ping"`
+		Server   struct {
+			Name    string `json:"name"`
+			Sponsor string `json:"sponsor"`
+		} `json:"server"`
+	}
+	if err := json.Unmarshal(output, &report); err != nil {
+		return fmt.Errorf("解析 speedtest-cli 输出失败: %w", err)
+	}
+	result.DownloadBps = report.Download
+	result.UploadBps = report.Upload
+	result.LatencyMs = report.Ping
+	result.ServerName = strings.TrimSpace(report.Server.Sponsor + " " + report.Server.Name)
+	return nil
+}
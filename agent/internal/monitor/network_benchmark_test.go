@@ -0,0 +1,129 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func TestParseIperfTarget(t *testing.T) {
+	for target, want := range map[string]struct {
+		host string
+		port int
+	}{
+		"iperf.example.com":      {"iperf.example.com", 5201},
+		"10.0.0.2:5202":          {"10.0.0.2", 5202},
+		"2001:db8::1":            {"2001:db8::1", 5201},
+		"[2001:db8::1]:9000":     {"2001:db8::1", 9000},
+		"speed.example.com:5201": {"speed.example.com", 5201},
+	} {
+		host, port, err := parseIperfTarget(target)
+		assert.NoError(t, err, target)
+		assert.Equal(t, want.host, host, target)
+		assert.Equal(t, want.port, port, target)
+	}
+
+	for _, bad := range []string{"", "-R", "host:0", "host:abc", "a b", "host;reboot"} {
+		_, _, err := parseIperfTarget(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestNetworkBenchmarkIperf3(t *testing.T) {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	b := &NetworkBenchmark{log: log, lookPath: func(file string) (string, error) { return "/usr/bin/" + file, nil }}
+	var commands []string
+	b.run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		command := strings.Join(args, " ")
+		commands = append(commands, command)
+		switch {
+		case strings.Contains(command, "-R"):
+			return []byte(`{"end":{"sum_received":{"bits_per_second":940000000}}}`), nil
+		case strings.Contains(command, "-u"):
+			return []byte(`{"end":{"sum":{"jitter_ms":0.25,"lost_percent":1.5}}}`), nil
+		default:
+			return []byte(`{"end":{"streams":[{"sender":{"mean_rtt":1800}}],"sum_received":{"bits_per_second":480000000}}}`), nil
+		}
+	}
+
+	result, err := b.Run("", "10.0.0.2:5202", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, BenchmarkIperf3, result.Tool)
+	assert.Equal(t, 940000000.0, result.DownloadBps)
+	assert.Equal(t, 480000000.0, result.UploadBps)
+	assert.Equal(t, 1.8, result.LatencyMs)
+	assert.Equal(t, 0.25, result.JitterMs)
+	assert.Equal(t, 1.5, result.PacketLoss)
+	assert.Equal(t, defaultBenchmarkDuration, result.Duration)
+	assert.Equal(t, []string{
+		"-c 10.0.0.2 -p 5202 -J -t 10",
+		"-c 10.0.0.2 -p 5202 -J -t 10 -R",
+		"-c 10.0.0.2 -p 5202 -J -u -t 5",
+	}, commands)
+
+	// 服务端忙碌时 iperf3 以 JSON 返回错误
+	b.run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte(`{"error":"the server is busy running a test. try again later"}`), errors.New("exit status 1")
+	}
+	_, err = b.Run(BenchmarkIperf3, "10.0.0.2", 5)
+	assert.ErrorContains(t, err, "server is busy")
+
+	_, err = b.Run(BenchmarkIperf3, "", 5)
+	assert.Error(t, err)
+}
+
+func TestNetworkBenchmarkSpeedtest(t *testing.T) {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+
+	// Ookla 官方客户端的带宽单位为 bytes/s
+	ookla := &NetworkBenchmark{log: log, lookPath: func(file string) (string, error) { return "/usr/bin/" + file, nil }}
+	ookla.run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if args[0] == "--version" {
+			return []byte("Speedtest by Ookla 1.2.0"), nil
+		}
+		assert.Equal(t, []string{"--accept-license", "--accept-gdpr", "-f", "json", "-s", "1234"}, args)
+		return []byte(`{"type":"result","ping":{"jitter":0.8,"latency":4.2},"download":{"bandwidth":12500000},` +
+			`"upload":{"bandwidth":2500000},"packetLoss":0,"server":{"name":"Example ISP","location":"Tokyo"}}`), nil
+	}
+	result, err := ookla.Run("", "1234", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, BenchmarkSpeedtest, result.Tool)
+	assert.Equal(t, 100000000.0, result.DownloadBps)
+	assert.Equal(t, 20000000.0, result.UploadBps)
+	assert.Equal(t, 0.8, result.JitterMs)
+	assert.Equal(t, "Example ISP Tokyo", result.ServerName)
+
+	// 只安装了 speedtest-cli
+	cli := &NetworkBenchmark{log: log, lookPath: func(file string) (string, error) {
+		if file == "speedtest-cli" {
+			return "/usr/bin/speedtest-cli", nil
+		}
+		return "", exec.ErrNotFound
+	}}
+	cli.run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		assert.Equal(t, "speedtest-cli", name)
+		return []byte(`{"download":93000000.5,"upload":41000000,"ping":12.5,"server":{"name":"Osaka","sponsor":"Example"}}`), nil
+	}
+	result, err = cli.Run(BenchmarkSpeedtest, "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 93000000.5, result.DownloadBps)
+	assert.Equal(t, 12.5, result.LatencyMs)
+	assert.Zero(t, result.JitterMs)
+	assert.Equal(t, "Example Osaka", result.ServerName)
+
+	_, err = cli.Run(BenchmarkSpeedtest, "abc", 0)
+	assert.Error(t, err)
+
+	none := &NetworkBenchmark{log: log, lookPath: func(string) (string, error) { return "", exec.ErrNotFound }}
+	_, err = none.Run(BenchmarkSpeedtest, "", 0)
+	assert.ErrorContains(t, err, "未安装")
+}
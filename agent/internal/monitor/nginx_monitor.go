@@ -106,19 +106,19 @@ var (
 	}{}
 )
 
+// 常见的Nginx配置路径
+var nginxConfigPaths = []string{
+	"/etc/nginx/nginx.conf",
+	"/usr/local/nginx/conf/nginx.conf",
+	"/usr/local/etc/nginx/nginx.conf",
+}
+
 // DetectNginxPaths 检测Nginx安装路径
 func DetectNginxPaths() (string, string, string) {
 	var configPath, nginxBin, nginxConfDir string
 
-	// 常见的Nginx配置路径
-	configPaths := []string{
-		"/etc/nginx/nginx.conf",
-		"/usr/local/nginx/conf/nginx.conf",
-		"/usr/local/etc/nginx/nginx.conf",
-	}
-
 	// 检查配置文件是否存在
-	for _, path := range configPaths {
+	for _, path := range nginxConfigPaths {
 		if _, err := os.Stat(path); err == nil {
 			configPath = path
 			nginxConfDir = filepath.Dir(path)
@@ -1371,6 +1371,11 @@ func HandleNginxCommand(action string, params map[string]interface{}) (string, e
 	var result interface{}
 	var err error
 
+	// 修改配置前先快照，配置改坏后可以回滚到修改前的版本
+	if nginxMutatingActions[action] {
+		autoSnapshotNginx("修改前自动快照 (" + action + ")")
+	}
+
 	switch action {
 	case "apply_config":
 		result, err = handleApplyConfigAction(params)
@@ -1630,13 +1635,17 @@ func HandleNginxCommand(action string, params map[string]interface{}) (string, e
 
 	case "nginx_test_config":
 		success, output, testErr := TestNginxConfig()
-		if testErr != nil {
+		if testErr != nil && output == "" {
+			// 未能执行 nginx -t（如找不到可执行文件）
 			err = testErr
+		} else {
+			// 配置有误属于正常的测试结果，解析出错位置并与原始输出一起返回
+			result = ParseNginxTestOutput(success, output)
 		}
-		result = map[string]interface{}{
-			"success": success,
-			"output":  output,
-		}
+
+	case "webserver_detect", "webserver_start", "webserver_stop", "webserver_reload", "webserver_test", "webserver_vhosts":
+		// Nginx、Apache httpd 和 Caddy 的通用操作，params["server"] 指定类型
+		result, err = handleWebServerAction(action, params)
 
 	case "nginx_processes":
 		result, err = GetNginxProcesses()
@@ -1766,6 +1775,20 @@ func HandleNginxCommand(action string, params map[string]interface{}) (string, e
 		// 调用异步卸载方法
 		result = UninstallCertbotAsync()
 
+	case "nginx_snapshot_list":
+		result, err = ListNginxSnapshots()
+
+	case "nginx_snapshot_create":
+		snapshot, created, snapErr := SnapshotNginxConfig("手动快照")
+		err = snapErr
+		result = map[string]interface{}{
+			"snapshot": snapshot,
+			"created":  created,
+		}
+
+	case "nginx_snapshot_restore":
+		result, err = RestoreNginxSnapshot(getStringParam(params["id"]))
+
 	default:
 		return "", fmt.Errorf("未知的Nginx命令: %s", action)
 	}
@@ -1776,6 +1799,10 @@ func HandleNginxCommand(action string, params map[string]interface{}) (string, e
 		return "", err
 	}
 
+	if nginxMutatingActions[action] {
+		autoSnapshotNginx(action)
+	}
+
 	// 将结果转换为JSON字符串
 	jsonResult, err := json.Marshal(result)
 	if err != nil {
@@ -1786,6 +1813,10 @@ func HandleNginxCommand(action string, params map[string]interface{}) (string, e
 }
 
 func handleApplyConfigAction(params map[string]interface{}) (interface{}, error) {
+	if getStringParam(params["template"]) != "" {
+		return handleApplyTemplateAction(params)
+	}
+
 	configPayload, ok := params["config"].(map[string]interface{})
 	if !ok {
 		return nil, fmt.Errorf("缺少config配置")
@@ -1833,6 +1864,43 @@ func handleApplyConfigAction(params map[string]interface{}) (interface{}, error)
 	}, nil
 }
 
+// handleApplyTemplateAction 按站点模板（反向代理、静态站点、PHP、WebSocket）生成完整配置并应用，
+// 参数与 nginx.SiteTemplateParams 相同，domain 可代替 domains 只填写一个域名
+func handleApplyTemplateAction(params map[string]interface{}) (interface{}, error) {
+	var req nginx.SiteTemplateParams
+	paramBytes, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("序列化模板参数失败: %w", err)
+	}
+	if err := json.Unmarshal(paramBytes, &req); err != nil {
+		return nil, fmt.Errorf("解析模板参数失败: %w", err)
+	}
+	if len(req.Domains) == 0 {
+		if primary := getStringParam(params["domain"]); primary != "" {
+			req.Domains = []string{primary}
+		}
+	}
+
+	client, err := nginx.NewNginxClient(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	site, configPath, err := client.CreateWebsiteFromTemplate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"success":     true,
+		"config_path": configPath,
+		"site":        site,
+		// 启用了 HTTPS 但还没有可用的证书，签发证书后重新应用
+		"https_pending": site.EnableHTTPS && site.SSL.Certificate == "",
+	}, nil
+}
+
 func handleIssueSSLAction(params map[string]interface{}) (interface{}, error) {
 	domains := getStringSlice(params["domains"])
 	if len(domains) == 0 {
@@ -1862,13 +1930,14 @@ func handleIssueSSLAction(params map[string]interface{}) (interface{}, error) {
 	switch provider {
 	case "http01":
 		req.Webroot = getStringParam(params["webroot"])
-	case "alidns", "aliyun", "cloudflare", "cf":
-		req.DNSConfig = getStringMap(params["dns_config"])
-		if len(req.DNSConfig) == 0 {
-			return nil, fmt.Errorf("DNS验证需要提供凭证配置")
-		}
 	default:
-		return nil, fmt.Errorf("暂不支持provider: %s", provider)
+		name := nginx.DNSProviderName(provider)
+		if name == "" {
+			return nil, fmt.Errorf("暂不支持provider: %s", provider)
+		}
+		req.Provider = name
+		// Route53 可以使用 Agent 本机的 AWS 默认凭证，凭证是否完整由各提供商检查
+		req.DNSConfig = getStringMap(params["dns_config"])
 	}
 
 	if staging, ok := params["use_staging"].(bool); ok {
@@ -0,0 +1,577 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/user/server-ops-agent/internal/nginx"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+const (
+	// 单个文件的大小上限，超过的文件（通常是误放在配置目录的日志或压缩包）不纳入快照
+	nginxSnapshotMaxFileSize = 10 * 1024 * 1024
+	// 单个快照的总大小上限，避免配置目录异常时占满磁盘
+	nginxSnapshotMaxTotalSize = 50 * 1024 * 1024
+	nginxSnapshotTimeFormat   = "20060102-150405"
+	nginxSnapshotIndexFile    = "index.json"
+)
+
+// 快照ID：时间戳加内容摘要前缀，同时作为压缩包文件名的一部分
+var nginxSnapshotIDPattern = regexp.MustCompile(`^\d{8}-\d{6}-[0-9a-f]{8}$`)
+
+// 会修改 Nginx 配置的命令，执行前后各做一次快照
+var nginxMutatingActions = map[string]bool{
+	"apply_config":          true,
+	"issue_ssl":             true,
+	"nginx_save_config":     true,
+	"nginx_create_config":   true,
+	"nginx_delete_config":   true,
+	"nginx_save_raw_config": true,
+}
+
+// NginxSnapshot Nginx 配置目录的一个历史版本
+type NginxSnapshot struct {
+	ID     string    `json:"id"`
+	Time   time.Time `json:"time"`
+	Reason string    `json:"reason"`
+	Dirs   []string  `json:"dirs"` // 快照包含的配置目录
+	Files  int       `json:"files"`
+	Size   int64     `json:"size"` // 压缩包大小(bytes)
+	Hash   string    `json:"hash"` // 配置内容摘要，内容未变化时不重复快照
+}
+
+// NginxRestoreResult 恢复快照的结果
+type NginxRestoreResult struct {
+	Snapshot NginxSnapshot    `json:"snapshot"`
+	BackupID string           `json:"backup_id,omitempty"` // 恢复前自动创建的快照，可用于撤销本次恢复
+	Restored int              `json:"restored"`
+	Removed  []string         `json:"removed,omitempty"` // 快照之后新增、恢复时删除的文件
+	Test     *NginxTestResult `json:"test,omitempty"`    // 恢复后 nginx -t 的结果，未安装系统 Nginx 时为空
+}
+
+// 快照存储位置和保留数量，由 ConfigureNginxSnapshots 设置
+var nginxSnapshotState = struct {
+	sync.Mutex
+	dir  string
+	keep int
+	log  *logger.Logger
+}{}
+
+// ConfigureNginxSnapshots 设置快照保存目录和保留的版本数，keep <= 0 表示不做快照
+func ConfigureNginxSnapshots(dir string, keep int, log *logger.Logger) {
+	nginxSnapshotState.Lock()
+	defer nginxSnapshotState.Unlock()
+	nginxSnapshotState.dir = dir
+	nginxSnapshotState.keep = keep
+	nginxSnapshotState.log = log
+}
+
+// SetNginxSnapshotKeep 配置热更新后调整保留的版本数，下次快照时清理多余的旧版本
+func SetNginxSnapshotKeep(keep int) {
+	nginxSnapshotState.Lock()
+	defer nginxSnapshotState.Unlock()
+	nginxSnapshotState.keep = keep
+}
+
+// RunNginxSnapshotSchedule 按间隔定时快照，内容未变化时不会生成新版本。interval <= 0 时直接返回
+func RunNginxSnapshotSchedule(interval time.Duration, stopCh <-chan struct{}) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	autoSnapshotNginx("定时快照")
+	for {
+		select {
+		case <-ticker.C:
+			autoSnapshotNginx("定时快照")
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// autoSnapshotNginx 自动快照，未启用或未安装 Nginx 时静默跳过，失败只记录日志
+func autoSnapshotNginx(reason string) {
+	nginxSnapshotState.Lock()
+	defer nginxSnapshotState.Unlock()
+
+	if nginxSnapshotState.dir == "" || nginxSnapshotState.keep <= 0 {
+		return
+	}
+	dirs := nginxSnapshotDirs()
+	if len(dirs) == 0 {
+		return
+	}
+	snapshot, created, err := createNginxSnapshot(nginxSnapshotState.dir, dirs, reason, time.Now(), nginxSnapshotState.keep)
+	log := nginxSnapshotState.log
+	if log == nil {
+		return
+	}
+	if err != nil {
+		log.Warn("Nginx 配置快照失败 (%s): %v", reason, err)
+	} else if created {
+		log.Info("已创建 Nginx 配置快照 %s (%s)", snapshot.ID, reason)
+	}
+}
+
+// SnapshotNginxConfig 立即快照当前的 Nginx 配置，内容与最近一个版本相同时返回该版本且 created 为 false
+func SnapshotNginxConfig(reason string) (*NginxSnapshot, bool, error) {
+	nginxSnapshotState.Lock()
+	defer nginxSnapshotState.Unlock()
+
+	if err := checkNginxSnapshotEnabled(); err != nil {
+		return nil, false, err
+	}
+	dirs := nginxSnapshotDirs()
+	if len(dirs) == 0 {
+		return nil, false, fmt.Errorf("未找到Nginx配置目录")
+	}
+	return createNginxSnapshot(nginxSnapshotState.dir, dirs, reason, time.Now(), nginxSnapshotState.keep)
+}
+
+// ListNginxSnapshots 返回已保存的快照，最新的在前
+func ListNginxSnapshots() ([]NginxSnapshot, error) {
+	nginxSnapshotState.Lock()
+	defer nginxSnapshotState.Unlock()
+
+	if err := checkNginxSnapshotEnabled(); err != nil {
+		return nil, err
+	}
+	snapshots, err := loadNginxSnapshotIndex(nginxSnapshotState.dir)
+	if err != nil {
+		return nil, err
+	}
+	if snapshots == nil {
+		snapshots = []NginxSnapshot{}
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Time.After(snapshots[j].Time) })
+	return snapshots, nil
+}
+
+// RestoreNginxSnapshot 把配置目录恢复到指定快照的状态，恢复前先快照当前配置以便撤销。
+// 恢复后只做 nginx -t 检查，不自动重载
+func RestoreNginxSnapshot(id string) (*NginxRestoreResult, error) {
+	nginxSnapshotState.Lock()
+	defer nginxSnapshotState.Unlock()
+
+	if err := checkNginxSnapshotEnabled(); err != nil {
+		return nil, err
+	}
+	result, err := restoreNginxSnapshot(nginxSnapshotState.dir, id, nginxConfigDirCandidates(), time.Now(), nginxSnapshotState.keep)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, nginxBin, _ := DetectNginxPaths(); nginxBin != "" {
+		success, output, testErr := TestNginxConfig()
+		if testErr == nil || output != "" {
+			result.Test = ParseNginxTestOutput(success, output)
+		}
+	}
+	return result, nil
+}
+
+func checkNginxSnapshotEnabled() error {
+	if nginxSnapshotState.dir == "" || nginxSnapshotState.keep <= 0 {
+		return fmt.Errorf("未启用Nginx配置快照（nginx_snapshot_keep 为 0）")
+	}
+	return nil
+}
+
+// nginxConfigDirCandidates 允许快照和恢复的配置目录：系统 Nginx 的常见配置目录和 OpenResty 容器的配置目录
+func nginxConfigDirCandidates() []string {
+	dirs := make([]string, 0, len(nginxConfigPaths)+1)
+	for _, path := range nginxConfigPaths {
+		dirs = append(dirs, filepath.Dir(path))
+	}
+	return append(dirs, filepath.Join(nginx.DefaultHostBaseDir, "conf"))
+}
+
+// nginxSnapshotDirs 返回本机实际存在的配置目录
+func nginxSnapshotDirs() []string {
+	var dirs []string
+	for _, dir := range nginxConfigDirCandidates() {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// nginxSnapshotIncludes 判断文件是否纳入快照：目录、符号链接和大小不超限的普通文件，
+// 跳过原子写入时留下的临时文件
+func nginxSnapshotIncludes(path string, info fs.FileInfo) bool {
+	if strings.HasSuffix(path, ".tmp") {
+		return false
+	}
+	mode := info.Mode()
+	switch {
+	case mode.IsDir(), mode&fs.ModeSymlink != 0:
+		return true
+	case mode.IsRegular():
+		return info.Size() <= nginxSnapshotMaxFileSize
+	}
+	return false
+}
+
+// createNginxSnapshot 把 dirs 打包为 tar.gz 保存到 storeDir，内容与最近一个版本相同时不保存
+func createNginxSnapshot(storeDir string, dirs []string, reason string, now time.Time, keep int) (*NginxSnapshot, bool, error) {
+	if err := os.MkdirAll(storeDir, 0700); err != nil {
+		return nil, false, fmt.Errorf("创建快照目录失败: %v", err)
+	}
+	snapshots, err := loadNginxSnapshotIndex(storeDir)
+	if err != nil {
+		return nil, false, err
+	}
+
+	tmp, err := os.CreateTemp(storeDir, "snapshot-*.tmp")
+	if err != nil {
+		return nil, false, fmt.Errorf("创建快照文件失败: %v", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	files, sum, err := writeNginxSnapshotArchive(tmp, storeDir, dirs)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if n := len(snapshots); n > 0 && snapshots[n-1].Hash == sum {
+		return &snapshots[n-1], false, nil
+	}
+
+	snapshot := NginxSnapshot{
+		ID:     now.Format(nginxSnapshotTimeFormat) + "-" + sum[:8],
+		Time:   now,
+		Reason: reason,
+		Dirs:   dirs,
+		Files:  files,
+		Hash:   sum,
+	}
+	archivePath := nginxSnapshotArchivePath(storeDir, snapshot.ID)
+	if err := os.Rename(tmpPath, archivePath); err != nil {
+		return nil, false, fmt.Errorf("保存快照失败: %v", err)
+	}
+	if info, err := os.Stat(archivePath); err == nil {
+		snapshot.Size = info.Size()
+	}
+
+	snapshots = append(snapshots, snapshot)
+	snapshots = pruneNginxSnapshots(storeDir, snapshots, keep)
+	if err := saveNginxSnapshotIndex(storeDir, snapshots); err != nil {
+		return nil, false, err
+	}
+	return &snapshot, true, nil
+}
+
+// writeNginxSnapshotArchive 写入压缩包并计算配置内容摘要（不含修改时间，仅 touch 不算变化）
+func writeNginxSnapshotArchive(w io.Writer, storeDir string, dirs []string) (int, string, error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	digest := sha256.New()
+
+	files := 0
+	var total int64
+	for _, root := range dirs {
+		err := filepath.Walk(root, func(path string, info fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// 快照目录放在配置目录内时不打包自身
+			if info.IsDir() && path == storeDir {
+				return filepath.SkipDir
+			}
+			if !nginxSnapshotIncludes(path, info) {
+				return nil
+			}
+
+			link := ""
+			if info.Mode()&fs.ModeSymlink != 0 {
+				if link, err = os.Readlink(path); err != nil {
+					return err
+				}
+			}
+			header, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return err
+			}
+			header.Name = strings.TrimPrefix(filepath.ToSlash(path), "/")
+			if info.IsDir() {
+				header.Name += "/"
+			}
+			fmt.Fprintf(digest, "%s\x00%c\x00%o\x00%s\x00", header.Name, header.Typeflag, header.Mode, header.Linkname)
+
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+
+			total += info.Size()
+			if total > nginxSnapshotMaxTotalSize {
+				return fmt.Errorf("配置目录过大，超过 %d 字节", nginxSnapshotMaxTotalSize)
+			}
+			files++
+			return copyFileTo(path, tw, digest)
+		})
+		if err != nil {
+			return 0, "", fmt.Errorf("打包配置目录 %s 失败: %v", root, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return 0, "", err
+	}
+	if err := gz.Close(); err != nil {
+		return 0, "", err
+	}
+	return files, hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+func copyFileTo(path string, w io.Writer, digest hash.Hash) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(io.MultiWriter(w, digest), f)
+	return err
+}
+
+// restoreNginxSnapshot 恢复快照中的文件，并删除快照之后新增的文件。
+// 只允许写入快照记录的、且属于 allowedDirs 的配置目录，防止篡改的压缩包写到其他位置
+func restoreNginxSnapshot(storeDir, id string, allowedDirs []string, now time.Time, keep int) (*NginxRestoreResult, error) {
+	if !nginxSnapshotIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("无效的快照ID: %s", id)
+	}
+	snapshots, err := loadNginxSnapshotIndex(storeDir)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot *NginxSnapshot
+	for i := range snapshots {
+		if snapshots[i].ID == id {
+			snapshot = &snapshots[i]
+			break
+		}
+	}
+	if snapshot == nil {
+		return nil, fmt.Errorf("快照不存在: %s", id)
+	}
+	for _, dir := range snapshot.Dirs {
+		if !containsString(allowedDirs, dir) {
+			return nil, fmt.Errorf("快照包含不允许恢复的目录: %s", dir)
+		}
+	}
+
+	// 多保留一个版本，避免恢复最旧的版本时它被恢复前的快照挤掉
+	result := &NginxRestoreResult{Snapshot: *snapshot}
+	backup, _, err := createNginxSnapshot(storeDir, snapshot.Dirs, "恢复 "+id+" 前的自动快照", now, keep+1)
+	if err != nil {
+		return nil, fmt.Errorf("恢复前快照当前配置失败: %v", err)
+	}
+	result.BackupID = backup.ID
+
+	f, err := os.Open(nginxSnapshotArchivePath(storeDir, id))
+	if err != nil {
+		return nil, fmt.Errorf("打开快照失败: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("读取快照失败: %v", err)
+	}
+	defer gz.Close()
+
+	restored := make(map[string]bool)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("读取快照失败: %v", err)
+		}
+
+		target := filepath.Clean("/" + header.Name)
+		if !pathInDirs(target, snapshot.Dirs) {
+			return result, fmt.Errorf("快照中的路径超出配置目录: %s", header.Name)
+		}
+		if err := restoreNginxSnapshotEntry(target, header, tr); err != nil {
+			return result, fmt.Errorf("恢复 %s 失败: %v", target, err)
+		}
+		restored[target] = true
+		if header.Typeflag == tar.TypeReg {
+			result.Restored++
+		}
+	}
+
+	// 删除快照之后新增的文件，只删除本会纳入快照的文件，超限的大文件保持不动
+	for _, root := range snapshot.Dirs {
+		filepath.Walk(root, func(path string, info fs.FileInfo, err error) error {
+			if err != nil || restored[path] {
+				return nil
+			}
+			if info.IsDir() {
+				if path == storeDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if nginxSnapshotIncludes(path, info) && os.Remove(path) == nil {
+				result.Removed = append(result.Removed, path)
+			}
+			return nil
+		})
+	}
+	return result, nil
+}
+
+func restoreNginxSnapshotEntry(target string, header *tar.Header, r io.Reader) error {
+	mode := fs.FileMode(header.Mode).Perm()
+	switch header.Typeflag {
+	case tar.TypeDir:
+		if err := os.MkdirAll(target, mode); err != nil {
+			return err
+		}
+		return os.Chmod(target, mode)
+
+	case tar.TypeSymlink:
+		if info, err := os.Lstat(target); err == nil {
+			if info.IsDir() {
+				return fmt.Errorf("目标是目录")
+			}
+			if err := os.Remove(target); err != nil {
+				return err
+			}
+		}
+		if err := os.Symlink(header.Linkname, target); err != nil {
+			return err
+		}
+		os.Lchown(target, header.Uid, header.Gid)
+		return nil
+
+	case tar.TypeReg:
+		// 写入临时文件后原子替换，目标为符号链接时替换链接本身
+		tmp, err := os.CreateTemp(filepath.Dir(target), filepath.Base(target)+".*.restore.tmp")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		if _, err := io.Copy(tmp, r); err != nil {
+			tmp.Close()
+			return err
+		}
+		if err := tmp.Close(); err != nil {
+			return err
+		}
+		if err := os.Chmod(tmp.Name(), mode); err != nil {
+			return err
+		}
+		os.Chown(tmp.Name(), header.Uid, header.Gid)
+		return os.Rename(tmp.Name(), target)
+	}
+	return nil
+}
+
+// pruneNginxSnapshots 只保留最近 keep 个版本，删除更早的压缩包
+func pruneNginxSnapshots(storeDir string, snapshots []NginxSnapshot, keep int) []NginxSnapshot {
+	if keep <= 0 || len(snapshots) <= keep {
+		return snapshots
+	}
+	for _, old := range snapshots[:len(snapshots)-keep] {
+		os.Remove(nginxSnapshotArchivePath(storeDir, old.ID))
+	}
+	return append([]NginxSnapshot(nil), snapshots[len(snapshots)-keep:]...)
+}
+
+func nginxSnapshotArchivePath(storeDir, id string) string {
+	return filepath.Join(storeDir, "nginx-"+id+".tar.gz")
+}
+
+// loadNginxSnapshotIndex 读取快照索引（按时间升序），压缩包已被删除的条目不返回
+func loadNginxSnapshotIndex(storeDir string) ([]NginxSnapshot, error) {
+	data, err := os.ReadFile(filepath.Join(storeDir, nginxSnapshotIndexFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取快照索引失败: %v", err)
+	}
+	var snapshots []NginxSnapshot
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return nil, fmt.Errorf("解析快照索引失败: %v", err)
+	}
+
+	existing := snapshots[:0]
+	for _, snapshot := range snapshots {
+		if !nginxSnapshotIDPattern.MatchString(snapshot.ID) {
+			continue
+		}
+		if _, err := os.Stat(nginxSnapshotArchivePath(storeDir, snapshot.ID)); err == nil {
+			existing = append(existing, snapshot)
+		}
+	}
+	sort.SliceStable(existing, func(i, j int) bool { return existing[i].Time.Before(existing[j].Time) })
+	return existing, nil
+}
+
+func saveNginxSnapshotIndex(storeDir string, snapshots []NginxSnapshot) error {
+	data, err := json.MarshalIndent(snapshots, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(storeDir, nginxSnapshotIndexFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("保存快照索引失败: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("保存快照索引失败: %v", err)
+	}
+	return nil
+}
+
+// pathInDirs 判断 path 是否位于 dirs 中某个目录之内（含目录本身）
+func pathInDirs(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		dir = filepath.Clean(dir)
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,15 @@
+//go:build monitor_only
+
+package monitor
+
+import (
+	"time"
+
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+// ConfigureNginxSnapshots 监控版不管理 Nginx 配置，无需快照
+func ConfigureNginxSnapshots(dir string, keep int, log *logger.Logger) {}
+
+// RunNginxSnapshotSchedule 监控版不做定时快照
+func RunNginxSnapshotSchedule(interval time.Duration, stopCh <-chan struct{}) {}
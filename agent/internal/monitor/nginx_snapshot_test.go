@@ -0,0 +1,82 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNginxSnapshotCreateAndRestore(t *testing.T) {
+	confDir := filepath.Join(t.TempDir(), "nginx")
+	storeDir := filepath.Join(t.TempDir(), "snapshots")
+	sitesAvailable := filepath.Join(confDir, "sites-available")
+	sitesEnabled := filepath.Join(confDir, "sites-enabled")
+	assert.NoError(t, os.MkdirAll(sitesAvailable, 0755))
+	assert.NoError(t, os.MkdirAll(sitesEnabled, 0755))
+	mainConf := filepath.Join(confDir, "nginx.conf")
+	siteConf := filepath.Join(sitesAvailable, "example.conf")
+	assert.NoError(t, os.WriteFile(mainConf, []byte("worker_processes 1;\n"), 0644))
+	assert.NoError(t, os.WriteFile(siteConf, []byte("server { listen 80; }\n"), 0644))
+	assert.NoError(t, os.Symlink("../sites-available/example.conf", filepath.Join(sitesEnabled, "example.conf")))
+
+	now := time.Date(2026, 10, 16, 15, 4, 5, 0, time.Local)
+	dirs := []string{confDir}
+
+	first, created, err := createNginxSnapshot(storeDir, dirs, "定时快照", now, 2)
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, 2, first.Files)
+
+	// 内容未变化时不生成新版本
+	same, created, err := createNginxSnapshot(storeDir, dirs, "定时快照", now.Add(time.Minute), 2)
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, first.ID, same.ID)
+
+	// 改坏配置：修改主配置、删除站点链接、新增文件
+	assert.NoError(t, os.WriteFile(mainConf, []byte("worker_processes oops\n"), 0644))
+	assert.NoError(t, os.Remove(filepath.Join(sitesEnabled, "example.conf")))
+	extra := filepath.Join(confDir, "broken.conf")
+	assert.NoError(t, os.WriteFile(extra, []byte("server {\n"), 0644))
+
+	result, err := restoreNginxSnapshot(storeDir, first.ID, dirs, now.Add(2*time.Minute), 2)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.ID, result.BackupID)
+	assert.Equal(t, 2, result.Restored)
+	assert.Equal(t, []string{extra}, result.Removed)
+
+	data, _ := os.ReadFile(mainConf)
+	assert.Equal(t, "worker_processes 1;\n", string(data))
+	link, err := os.Readlink(filepath.Join(sitesEnabled, "example.conf"))
+	assert.NoError(t, err)
+	assert.Equal(t, "../sites-available/example.conf", link)
+
+	// 恢复前的快照保留了改坏的配置，可以撤销本次恢复
+	snapshots, err := loadNginxSnapshotIndex(storeDir)
+	assert.NoError(t, err)
+	assert.Len(t, snapshots, 2)
+
+	// 超出保留数量时删除最旧的版本
+	assert.NoError(t, os.WriteFile(mainConf, []byte("worker_processes 2;\n"), 0644))
+	_, _, err = createNginxSnapshot(storeDir, dirs, "定时快照", now.Add(3*time.Minute), 2)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(mainConf, []byte("worker_processes 3;\n"), 0644))
+	_, _, err = createNginxSnapshot(storeDir, dirs, "定时快照", now.Add(4*time.Minute), 2)
+	assert.NoError(t, err)
+	snapshots, err = loadNginxSnapshotIndex(storeDir)
+	assert.NoError(t, err)
+	assert.Len(t, snapshots, 2)
+	_, err = os.Stat(nginxSnapshotArchivePath(storeDir, first.ID))
+	assert.True(t, os.IsNotExist(err))
+
+	// 快照目录不在允许范围内时拒绝恢复
+	_, err = restoreNginxSnapshot(storeDir, snapshots[0].ID, []string{"/etc/nginx"}, now, 2)
+	assert.Error(t, err)
+	_, err = restoreNginxSnapshot(storeDir, "../../etc/passwd", dirs, now, 2)
+	assert.Error(t, err)
+}
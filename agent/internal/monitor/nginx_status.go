@@ -0,0 +1,219 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	stdnet "net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 读取 stub_status 和探测上游的超时，采集时同步执行，不宜过长
+	nginxStatusTimeout = 2 * time.Second
+	// stub_status 页面很小，限制读取大小避免误配置成其他页面时读取大量内容
+	nginxStatusMaxBody = 4 * 1024
+	// 最多探测的上游数量
+	nginxMaxUpstreams = 32
+)
+
+// NginxStatus Nginx 的 stub_status 指标和上游可用性
+type NginxStatus struct {
+	Active   int    `json:"active"`   // 当前活动连接数（含等待中的 keep-alive 连接）
+	Reading  int    `json:"reading"`  // 正在读取请求头的连接数
+	Writing  int    `json:"writing"`  // 正在写响应的连接数
+	Waiting  int    `json:"waiting"`  // 空闲的 keep-alive 连接数
+	Accepts  uint64 `json:"accepts"`  // 累计接受的连接数
+	Handled  uint64 `json:"handled"`  // 累计处理的连接数，小于 accepts 说明达到了 worker_connections 上限
+	Requests uint64 `json:"requests"` // 累计请求数
+
+	RequestRate float64 `json:"request_rate"` // 两次采集之间的每秒请求数，首次采集或 Nginx 重启后为 0
+	Dropped     uint64  `json:"dropped"`      // 两次采集之间新增的未处理连接数（accepts - handled 的增量）
+	Up          bool    `json:"up"`           // 成功读取了 stub_status，未配置 nginx_status_url 时为 false 且 Error 为空
+	Error       string  `json:"error,omitempty"`
+
+	Upstreams []NginxUpstreamHealth `json:"upstreams,omitempty"`
+}
+
+// NginxUpstreamHealth 一个上游地址的 TCP 连通性
+type NginxUpstreamHealth struct {
+	Address string  `json:"address"`
+	Up      bool    `json:"up"`
+	Latency float64 `json:"latency,omitempty"` // 建立连接耗时(ms)
+	Error   string  `json:"error,omitempty"`
+}
+
+// nginxStatusState 保存 stub_status 地址、需要探测的上游以及上次读取的累计值，用于计算速率
+type nginxStatusState struct {
+	mu        sync.Mutex
+	url       string
+	upstreams []string
+	client    *http.Client
+
+	prevAccepts  uint64
+	prevHandled  uint64
+	prevRequests uint64
+	prevAt       time.Time
+}
+
+// SetNginxStatus 设置 stub_status 地址和需要探测的上游地址（host:port），两者都为空时不采集
+func (m *Monitor) SetNginxStatus(url string, upstreams []string) {
+	s := &m.nginx
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	url = strings.TrimSpace(url)
+	if url != s.url {
+		s.prevAt = time.Time{}
+	}
+	s.url = url
+	s.upstreams = nil
+	for _, addr := range upstreams {
+		if addr = strings.TrimSpace(addr); addr != "" && len(s.upstreams) < nginxMaxUpstreams {
+			s.upstreams = append(s.upstreams, addr)
+		}
+	}
+	if s.client == nil {
+		s.client = &http.Client{Timeout: nginxStatusTimeout}
+	}
+}
+
+// collectNginxStatus 读取 stub_status 并探测上游，未配置时返回 nil
+func (m *Monitor) collectNginxStatus() *NginxStatus {
+	s := &m.nginx
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.url == "" && len(s.upstreams) == 0 {
+		return nil
+	}
+
+	status := &NginxStatus{}
+	if s.url != "" {
+		if err := s.readStubStatus(status); err != nil {
+			status.Error = err.Error()
+			s.prevAt = time.Time{}
+			m.log.Debug("读取 Nginx stub_status 失败: %v", err)
+		} else {
+			status.Up = true
+		}
+	}
+	if len(s.upstreams) > 0 {
+		status.Upstreams = probeNginxUpstreams(s.upstreams)
+	}
+	return status
+}
+
+// readStubStatus 读取 stub_status 并根据上次的累计值计算请求速率和新增的未处理连接
+func (s *nginxStatusState) readStubStatus(status *NginxStatus) error {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("stub_status 返回 HTTP %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, nginxStatusMaxBody))
+	if err != nil {
+		return err
+	}
+	if err := parseStubStatus(string(body), status); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	// 累计值变小说明 Nginx 重启过，本次只记录基准
+	if !s.prevAt.IsZero() && status.Requests >= s.prevRequests && status.Accepts >= s.prevAccepts {
+		if elapsed := now.Sub(s.prevAt).Seconds(); elapsed > 0 {
+			status.RequestRate = float64(status.Requests-s.prevRequests) / elapsed
+		}
+		prevDropped := s.prevAccepts - min(s.prevHandled, s.prevAccepts)
+		if dropped := status.Accepts - min(status.Handled, status.Accepts); dropped > prevDropped {
+			status.Dropped = dropped - prevDropped
+		}
+	}
+	s.prevAccepts, s.prevHandled, s.prevRequests, s.prevAt = status.Accepts, status.Handled, status.Requests, now
+	return nil
+}
+
+// parseStubStatus 解析 ngx_http_stub_status_module 的输出：
+//
+//	Active connections: 291
+//	server accepts handled requests
+//	 16630948 16630948 31070465
+//	Reading: 6 Writing: 179 Waiting: 106
+func parseStubStatus(body string, status *NginxStatus) error {
+	var hasActive, hasCounters bool
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(line)
+		switch {
+		case strings.HasPrefix(line, "Active connections:") && len(fields) == 3:
+			active, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return fmt.Errorf("无效的活动连接数: %s", fields[2])
+			}
+			status.Active = active
+			hasActive = true
+		case strings.HasPrefix(line, "Reading:") && len(fields) == 6:
+			status.Reading, _ = strconv.Atoi(fields[1])
+			status.Writing, _ = strconv.Atoi(fields[3])
+			status.Waiting, _ = strconv.Atoi(fields[5])
+		case len(fields) == 3 && !hasCounters:
+			var counters [3]uint64
+			valid := true
+			for i, field := range fields {
+				value, err := strconv.ParseUint(field, 10, 64)
+				if err != nil {
+					valid = false
+					break
+				}
+				counters[i] = value
+			}
+			if valid {
+				status.Accepts, status.Handled, status.Requests = counters[0], counters[1], counters[2]
+				hasCounters = true
+			}
+		}
+	}
+	if !hasActive || !hasCounters {
+		return fmt.Errorf("不是 stub_status 格式的响应，请确认地址指向 stub_status 所在的 location")
+	}
+	return nil
+}
+
+// probeNginxUpstreams 并发地与每个上游建立 TCP 连接，判断是否可用
+func probeNginxUpstreams(addresses []string) []NginxUpstreamHealth {
+	results := make([]NginxUpstreamHealth, len(addresses))
+	var wg sync.WaitGroup
+	for i, addr := range addresses {
+		wg.Add(1)
+		go func(i int, addr string) {
+			defer wg.Done()
+			results[i] = probeNginxUpstream(addr)
+		}(i, addr)
+	}
+	wg.Wait()
+	return results
+}
+
+func probeNginxUpstream(addr string) NginxUpstreamHealth {
+	result := NginxUpstreamHealth{Address: addr}
+	ctx, cancel := context.WithTimeout(context.Background(), nginxStatusTimeout)
+	defer cancel()
+	start := time.Now()
+	conn, err := (&stdnet.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	conn.Close()
+	result.Up = true
+	result.Latency = float64(time.Since(start).Microseconds()) / 1000
+	return result
+}
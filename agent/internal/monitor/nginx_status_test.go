@@ -0,0 +1,80 @@
+package monitor
+
+import (
+	"fmt"
+	stdnet "net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func TestParseStubStatus(t *testing.T) {
+	var status NginxStatus
+	err := parseStubStatus("Active connections: 291 \nserver accepts handled requests\n 16630948 16630940 31070465 \nReading: 6 Writing: 179 Waiting: 106 \n", &status)
+	assert.NoError(t, err)
+	assert.Equal(t, 291, status.Active)
+	assert.Equal(t, uint64(16630948), status.Accepts)
+	assert.Equal(t, uint64(16630940), status.Handled)
+	assert.Equal(t, uint64(31070465), status.Requests)
+	assert.Equal(t, 6, status.Reading)
+	assert.Equal(t, 179, status.Writing)
+	assert.Equal(t, 106, status.Waiting)
+
+	assert.Error(t, parseStubStatus("<html>Welcome to nginx!</html>", &NginxStatus{}))
+}
+
+func TestCollectNginxStatus(t *testing.T) {
+	handled, requests := 10, 100
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "Active connections: 3\nserver accepts handled requests\n 12 %d %d\nReading: 0 Writing: 1 Waiting: 2\n", handled, requests)
+	}))
+	defer srv.Close()
+
+	// 一个可连接的上游和一个已关闭的端口
+	ln, err := stdnet.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+	closed, err := stdnet.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	m := New(log)
+	assert.Nil(t, m.collectNginxStatus())
+
+	m.SetNginxStatus(srv.URL, []string{ln.Addr().String(), closedAddr, " "})
+	status := m.collectNginxStatus()
+	if assert.NotNil(t, status) {
+		assert.True(t, status.Up)
+		assert.Empty(t, status.Error)
+		assert.Equal(t, 3, status.Active)
+		// 首次采集只记录基准
+		assert.Zero(t, status.RequestRate)
+		if assert.Len(t, status.Upstreams, 2) {
+			assert.True(t, status.Upstreams[0].Up)
+			assert.False(t, status.Upstreams[1].Up)
+			assert.NotEmpty(t, status.Upstreams[1].Error)
+		}
+	}
+
+	m.nginx.prevAt = time.Now().Add(-10 * time.Second)
+	handled, requests = 9, 300
+	status = m.collectNginxStatus()
+	if assert.NotNil(t, status) {
+		assert.InDelta(t, 20, status.RequestRate, 1)
+		assert.Equal(t, uint64(1), status.Dropped)
+	}
+
+	m.SetNginxStatus(srv.URL+"/missing\x7f", nil)
+	status = m.collectNginxStatus()
+	if assert.NotNil(t, status) {
+		assert.False(t, status.Up)
+		assert.NotEmpty(t, status.Error)
+	}
+}
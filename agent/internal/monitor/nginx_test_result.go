@@ -0,0 +1,62 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// NginxConfigIssue nginx -t 输出中的一条错误或警告
+type NginxConfigIssue struct {
+	Level   string `json:"level"`          // emerg/alert/crit/error/warn/notice 等
+	Message string `json:"message"`        // 去掉级别和位置后的描述
+	File    string `json:"file,omitempty"` // 出错的配置文件，部分错误（如端口占用）没有位置信息
+	Line    int    `json:"line,omitempty"` // 出错的行号，从 1 开始
+}
+
+// NginxTestResult 配置测试结果，同时保留原始输出
+type NginxTestResult struct {
+	Success  bool               `json:"success"`
+	Output   string             `json:"output"`
+	Errors   []NginxConfigIssue `json:"errors"`
+	Warnings []NginxConfigIssue `json:"warnings"`
+}
+
+// nginx 的日志格式：nginx: [emerg] unknown directive "foo" in /etc/nginx/conf.d/a.conf:12
+var nginxIssuePattern = regexp.MustCompile(`^(?:nginx: )?\[(\w+)\] (.*?)(?: in (\S+):(\d+))?$`)
+
+// ParseNginxTestOutput 将 nginx -t 的输出解析为结构化结果，
+// 前端据此定位到出错的文件和行，无需用户阅读原始输出
+func ParseNginxTestOutput(success bool, output string) *NginxTestResult {
+	result := &NginxTestResult{
+		Success:  success,
+		Output:   output,
+		Errors:   []NginxConfigIssue{},
+		Warnings: []NginxConfigIssue{},
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		m := nginxIssuePattern.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		issue := NginxConfigIssue{
+			Level:   m[1],
+			Message: m[2],
+			File:    m[3],
+		}
+		if m[4] != "" {
+			issue.Line, _ = strconv.Atoi(m[4])
+		}
+
+		switch issue.Level {
+		case "warn", "notice", "info":
+			result.Warnings = append(result.Warnings, issue)
+		default:
+			result.Errors = append(result.Errors, issue)
+		}
+	}
+	return result
+}
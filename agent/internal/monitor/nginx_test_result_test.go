@@ -0,0 +1,42 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNginxTestOutput(t *testing.T) {
+	output := `nginx: [warn] conflicting server name "example.com" on 0.0.0.0:80, ignored
+nginx: [emerg] unknown directive "proxy_pas" in /etc/nginx/conf.d/example.com.conf:12
+nginx: configuration file /etc/nginx/nginx.conf test failed
+`
+	result := ParseNginxTestOutput(false, output)
+	assert.False(t, result.Success)
+	assert.Equal(t, output, result.Output)
+	assert.Equal(t, []NginxConfigIssue{{
+		Level:   "emerg",
+		Message: `unknown directive "proxy_pas"`,
+		File:    "/etc/nginx/conf.d/example.com.conf",
+		Line:    12,
+	}}, result.Errors)
+	assert.Equal(t, []NginxConfigIssue{{
+		Level:   "warn",
+		Message: `conflicting server name "example.com" on 0.0.0.0:80, ignored`,
+	}}, result.Warnings)
+
+	// 没有位置信息的错误
+	result = ParseNginxTestOutput(false, `nginx: [emerg] bind() to 0.0.0.0:80 failed (98: Address already in use)`)
+	assert.Len(t, result.Errors, 1)
+	assert.Empty(t, result.Errors[0].File)
+	assert.Zero(t, result.Errors[0].Line)
+
+	// 测试通过
+	result = ParseNginxTestOutput(true, `nginx: the configuration file /etc/nginx/nginx.conf syntax is ok
+nginx: configuration file /etc/nginx/nginx.conf test is successful`)
+	assert.True(t, result.Success)
+	assert.Empty(t, result.Errors)
+	assert.Empty(t, result.Warnings)
+}
@@ -0,0 +1,184 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/host"
+)
+
+const (
+	// 读取内核日志的最长时间
+	oomLogTimeout = 5 * time.Second
+	// 单次上报携带的 OOM 事件上限，避免内存反复耗尽时上报数据过大
+	maxOOMEvents = 20
+)
+
+// OOMEvent 内核 OOM killer 杀死进程的事件
+type OOMEvent struct {
+	Time    time.Time `json:"time"`
+	PID     int       `json:"pid"`
+	Process string    `json:"process"`
+	Message string    `json:"message"` // 内核日志原文
+}
+
+// 匹配 "Out of memory: Killed process 1234 (java) ..." 和
+// "Memory cgroup out of memory: Killed process 1234 (java) ..."
+var oomKilledPattern = regexp.MustCompile(`Killed process (\d+) \(([^)]*)\)`)
+
+// readOOMKillCount 读取内核累计的 OOM kill 次数（/proc/vmstat 的 oom_kill，Linux 4.13+）。
+// 计数器读取开销很小，只有在次数增加时才去查内核日志
+func readOOMKillCount() (uint64, bool) {
+	data, err := os.ReadFile("/proc/vmstat")
+	if err != nil {
+		return 0, false
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			count, err := strconv.ParseUint(fields[1], 10, 64)
+			return count, err == nil
+		}
+	}
+	return 0, false
+}
+
+// parseOOMLine 从内核日志行中解析被杀死的进程
+func parseOOMLine(line string) (OOMEvent, bool) {
+	match := oomKilledPattern.FindStringSubmatch(line)
+	if match == nil {
+		return OOMEvent{}, false
+	}
+	pid, _ := strconv.Atoi(match[1])
+	return OOMEvent{PID: pid, Process: match[2], Message: strings.TrimSpace(line)}, true
+}
+
+// parseJournalOOM 解析 journalctl -o short-unix 的输出，行首为 Unix 时间戳
+func parseJournalOOM(output string) []OOMEvent {
+	var events []OOMEvent
+	for _, line := range strings.Split(output, "\n") {
+		event, ok := parseOOMLine(line)
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			if ts, err := strconv.ParseFloat(fields[0], 64); err == nil {
+				event.Time = time.Unix(0, int64(ts*float64(time.Second)))
+			}
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// 匹配 dmesg 行首的 "[ 1234.567890]"（开机以来的秒数）
+var dmesgTimestampPattern = regexp.MustCompile(`^\[\s*(\d+\.\d+)\]`)
+
+// parseDmesgOOM 解析 dmesg 原始输出，按系统启动时间换算事件时间
+func parseDmesgOOM(output string, bootTime uint64) []OOMEvent {
+	var events []OOMEvent
+	for _, line := range strings.Split(output, "\n") {
+		event, ok := parseOOMLine(line)
+		if !ok {
+			continue
+		}
+		if match := dmesgTimestampPattern.FindStringSubmatch(line); match != nil && bootTime > 0 {
+			if offset, err := strconv.ParseFloat(match[1], 64); err == nil {
+				event.Time = time.Unix(int64(bootTime), 0).Add(time.Duration(offset * float64(time.Second)))
+			}
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// readOOMEvents 从内核日志中读取 since 之后的 OOM 事件，优先使用 journal，不可用时退回 dmesg
+func readOOMEvents(since time.Time) []OOMEvent {
+	ctx, cancel := context.WithTimeout(context.Background(), oomLogTimeout)
+	defer cancel()
+
+	var events []OOMEvent
+	if _, err := exec.LookPath("journalctl"); err == nil {
+		output, err := exec.CommandContext(ctx, "journalctl", "-k", "-o", "short-unix", "--no-pager",
+			"--since", "@"+strconv.FormatInt(since.Unix(), 10)).Output()
+		if err == nil {
+			events = parseJournalOOM(string(output))
+		}
+	}
+	if events == nil {
+		output, err := exec.CommandContext(ctx, "dmesg").Output()
+		if err == nil {
+			bootTime, _ := host.BootTime()
+			events = parseDmesgOOM(string(output), bootTime)
+		}
+	}
+
+	filtered := events[:0]
+	for _, event := range events {
+		// 没有时间的事件无法判断是否已上报过，直接丢弃
+		if !event.Time.IsZero() && !event.Time.Before(since) {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
+
+// collectOOMKills 返回上次采集以来新增的 OOM kill 次数和对应的事件。
+// 首次采集只建立基线，不上报 Agent 启动前发生的事件，避免每次重启重复告警。
+// 没有权限读取内核日志时只上报次数
+func (m *Monitor) collectOOMKills() (int, []OOMEvent) {
+	count, ok := readOOMKillCount()
+	if !ok {
+		return 0, nil
+	}
+
+	m.oomMu.Lock()
+	defer m.oomMu.Unlock()
+
+	now := time.Now()
+	if !m.hasOOMBaseline || count < m.oomKillCount {
+		m.hasOOMBaseline = true
+		m.oomKillCount = count
+		m.oomCheckedAt = now
+		return 0, nil
+	}
+
+	kills := int(count - m.oomKillCount)
+	if kills == 0 {
+		m.oomCheckedAt = now
+		return 0, nil
+	}
+
+	// 内核日志时间戳精度有限，向前多取一秒，再按上次上报的最后一条事件去重
+	events := readOOMEvents(m.oomCheckedAt.Add(-time.Second))
+	fresh := events[:0]
+	for _, event := range events {
+		if event.Time.After(m.oomLastEvent) {
+			fresh = append(fresh, event)
+		}
+	}
+	// 只保留与计数器增量对应的最近几条
+	if len(fresh) > kills {
+		fresh = fresh[len(fresh)-kills:]
+	}
+	if len(fresh) > maxOOMEvents {
+		fresh = fresh[len(fresh)-maxOOMEvents:]
+	}
+	if len(fresh) > 0 {
+		m.oomLastEvent = fresh[len(fresh)-1].Time
+	}
+
+	m.oomKillCount = count
+	m.oomCheckedAt = now
+	m.log.Warn("检测到 %d 次 OOM kill，解析到 %d 条内核日志", kills, len(fresh))
+	return kills, fresh
+}
@@ -0,0 +1,36 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJournalOOM(t *testing.T) {
+	output := `1792137600.123456 web-1 kernel: java invoked oom-killer: gfp_mask=0x140cca(GFP_HIGHUSER_MOVABLE|__GFP_COMP), order=0, oom_score_adj=0
+1792137600.234567 web-1 kernel: Out of memory: Killed process 4321 (java) total-vm:8123456kB, anon-rss:3456789kB, file-rss:0kB, shmem-rss:0kB, UID:1000 pgtables:8000kB oom_score_adj:0
+1792137660.000000 web-1 kernel: Memory cgroup out of memory: Killed process 987 (php-fpm: pool www) total-vm:512000kB`
+
+	events := parseJournalOOM(output)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, 4321, events[0].PID)
+		assert.Equal(t, "java", events[0].Process)
+		assert.Equal(t, int64(1792137600), events[0].Time.Unix())
+		assert.Contains(t, events[0].Message, "Out of memory")
+		assert.Equal(t, 987, events[1].PID)
+		assert.Equal(t, "php-fpm: pool www", events[1].Process)
+	}
+}
+
+func TestParseDmesgOOM(t *testing.T) {
+	output := `[    1.000000] Linux version 6.1.0
+[ 3600.500000] Out of memory: Killed process 555 (mysqld) total-vm:2000000kB`
+
+	events := parseDmesgOOM(output, 1792130000)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, 555, events[0].PID)
+		assert.Equal(t, "mysqld", events[0].Process)
+		assert.Equal(t, time.Unix(1792133600, 500000000), events[0].Time)
+	}
+}
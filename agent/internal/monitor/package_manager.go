@@ -0,0 +1,418 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+const (
+	// 读取已安装软件包和可用更新的最长时间
+	packageQueryTimeout = 2 * time.Minute
+	// 刷新软件源索引（apt-get update / dnf makecache）的最长时间
+	packageRefreshTimeout = 3 * time.Minute
+	// 安装、升级、卸载的最长时间，需小于面板等待响应的超时
+	packageActionTimeout = 9 * time.Minute
+	// 单次操作最多指定的软件包数量
+	maxPackagesPerAction = 50
+	// 返回给面板的命令输出上限，超出时保留末尾
+	maxPackageOutput = 64 * 1024
+)
+
+// 软件包名只允许 dpkg/rpm 包名中出现的字符，且不能以 - 开头，避免被当作命令行参数
+var packageNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9+._:~-]*$`)
+
+// ErrNoPackageManager 系统没有受支持的包管理器
+var ErrNoPackageManager = errors.New("未检测到受支持的包管理器（apt、dnf、yum）")
+
+// PackageInfo 已安装的软件包
+type PackageInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Arch    string `json:"arch"`
+}
+
+// PackageUpdate 可升级的软件包
+type PackageUpdate struct {
+	Name           string `json:"name"`
+	Arch           string `json:"arch"`
+	CurrentVersion string `json:"current_version"`
+	Version        string `json:"version"` // 可升级到的版本
+	Repo           string `json:"repo"`
+	Security       bool   `json:"security"` // 是否为安全更新
+}
+
+// PackageInventory 已安装软件包和待安装更新的清单
+type PackageInventory struct {
+	Manager     string          `json:"manager"`
+	Packages    []PackageInfo   `json:"packages"`
+	Updates     []PackageUpdate `json:"updates"`
+	CollectedAt time.Time       `json:"collected_at"`
+}
+
+// PackageCommandResult 安装、升级、卸载的执行结果
+type PackageCommandResult struct {
+	Action   string   `json:"action"`
+	Packages []string `json:"packages"`
+	DryRun   bool     `json:"dry_run"`
+	Command  string   `json:"command"`
+	Output   string   `json:"output"`
+}
+
+// PackageManager 通过系统的 apt、dnf 或 yum 查询和管理软件包
+type PackageManager struct {
+	log     *logger.Logger
+	manager string // apt、dnf、yum，未检测到时为空
+	// run 执行命令并返回标准输出，失败时错误中带有标准错误的内容，测试时替换
+	run func(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// NewPackageManager 创建软件包管理器，自动检测系统的包管理器
+func NewPackageManager(log *logger.Logger) *PackageManager {
+	return &PackageManager{log: log, manager: detectPackageManager(), run: runPackageCommand}
+}
+
+// detectPackageManager 按 apt、dnf、yum 的顺序检测包管理器
+func detectPackageManager() string {
+	for _, candidate := range []struct{ name, binary string }{
+		{"apt", "apt-get"}, {"dnf", "dnf"}, {"yum", "yum"},
+	} {
+		if _, err := exec.LookPath(candidate.binary); err == nil {
+			return candidate.name
+		}
+	}
+	return ""
+}
+
+// runPackageCommand 以非交互方式执行包管理命令
+func runPackageCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), "DEBIAN_FRONTEND=noninteractive", "LC_ALL=C")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return output, fmt.Errorf("%s 执行超时", name)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return output, fmt.Errorf("%v: %s", err, tailOutput(msg, 4096))
+		}
+	}
+	return output, err
+}
+
+// command 在超时时间内执行命令
+func (pm *PackageManager) command(timeout time.Duration, name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return pm.run(ctx, name, args...)
+}
+
+// ValidatePackageNames 校验软件包名列表
+func ValidatePackageNames(packages []string) ([]string, error) {
+	if len(packages) > maxPackagesPerAction {
+		return nil, fmt.Errorf("单次最多操作 %d 个软件包", maxPackagesPerAction)
+	}
+	names := make([]string, 0, len(packages))
+	for _, p := range packages {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if len(p) > 200 || !packageNamePattern.MatchString(p) {
+			return nil, fmt.Errorf("无效的软件包名: %s", p)
+		}
+		names = append(names, p)
+	}
+	return names, nil
+}
+
+// Inventory 读取已安装的软件包和可用更新，refresh 时先刷新软件源索引。
+// 读取可用更新失败时仍返回已安装列表
+func (pm *PackageManager) Inventory(refresh bool) (*PackageInventory, error) {
+	if pm.manager == "" {
+		return nil, ErrNoPackageManager
+	}
+	pm.log.Debug("读取软件包清单: manager=%s, refresh=%v", pm.manager, refresh)
+
+	if refresh {
+		var err error
+		if pm.manager == "apt" {
+			_, err = pm.command(packageRefreshTimeout, "apt-get", "update", "-q")
+		} else {
+			_, err = pm.command(packageRefreshTimeout, pm.manager, "-q", "makecache")
+		}
+		if err != nil {
+			pm.log.Warn("刷新软件源索引失败: %v", err)
+		}
+	}
+
+	inventory := &PackageInventory{Manager: pm.manager, CollectedAt: time.Now()}
+	var err error
+	if pm.manager == "apt" {
+		output, queryErr := pm.command(packageQueryTimeout, "dpkg-query", "-W",
+			"-f=${db:Status-Abbrev}\t${Package}\t${Version}\t${Architecture}\n")
+		if queryErr != nil {
+			return nil, fmt.Errorf("读取已安装软件包失败: %v", queryErr)
+		}
+		inventory.Packages = parseDpkgPackages(string(output))
+		inventory.Updates, err = pm.aptUpdates()
+	} else {
+		output, queryErr := pm.command(packageQueryTimeout, "rpm", "-qa",
+			"--qf", "%{NAME}\t%|EPOCH?{%{EPOCH}:}:{}|%{VERSION}-%{RELEASE}\t%{ARCH}\n")
+		if queryErr != nil {
+			return nil, fmt.Errorf("读取已安装软件包失败: %v", queryErr)
+		}
+		inventory.Packages = parseRPMPackages(string(output))
+		inventory.Updates, err = pm.rpmUpdates(inventory.Packages)
+	}
+	if err != nil {
+		pm.log.Warn("读取可用更新失败: %v", err)
+	}
+	if inventory.Updates == nil {
+		inventory.Updates = make([]PackageUpdate, 0)
+	}
+	return inventory, nil
+}
+
+// aptUpdates 读取 apt 的可升级列表，来源包含 -security 的视为安全更新
+func (pm *PackageManager) aptUpdates() ([]PackageUpdate, error) {
+	output, err := pm.command(packageQueryTimeout, "apt", "list", "--upgradable")
+	if err != nil {
+		return nil, err
+	}
+	return parseAptUpgradable(string(output)), nil
+}
+
+// rpmUpdates 读取 dnf/yum 的可升级列表，并用 updateinfo 标记安全更新
+func (pm *PackageManager) rpmUpdates(installed []PackageInfo) ([]PackageUpdate, error) {
+	output, err := pm.command(packageQueryTimeout, pm.manager, "-q", "list", "updates")
+	if err != nil {
+		// 没有可用更新时 yum/dnf 以 "No matching Packages to list" 报错退出
+		if !strings.Contains(err.Error(), "No matching Packages") {
+			return nil, err
+		}
+		return make([]PackageUpdate, 0), nil
+	}
+	updates := parseRPMUpdates(string(output), installed)
+
+	// 没有 updateinfo 元数据的软件源（如 CentOS 官方源）无法区分安全更新
+	security, err := pm.command(packageQueryTimeout, pm.manager, "-q", "updateinfo", "list", "security")
+	if err != nil {
+		pm.log.Debug("读取安全更新列表失败: %v", err)
+		return updates, nil
+	}
+	secure := parseRPMSecurityNames(string(security))
+	for i := range updates {
+		updates[i].Security = secure[updates[i].Name]
+	}
+	return updates, nil
+}
+
+// parseDpkgPackages 解析 dpkg-query 的输出：状态 包名 版本 架构，只保留已安装（ii）的软件包
+func parseDpkgPackages(output string) []PackageInfo {
+	packages := make([]PackageInfo, 0)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 4 || strings.TrimSpace(fields[0]) != "ii" {
+			continue
+		}
+		packages = append(packages, PackageInfo{Name: fields[1], Version: fields[2], Arch: fields[3]})
+	}
+	return packages
+}
+
+// parseRPMPackages 解析 rpm -qa 的输出：包名 [epoch:]版本-发布号 架构，跳过 gpg-pubkey 等没有架构的伪软件包
+func parseRPMPackages(output string) []PackageInfo {
+	packages := make([]PackageInfo, 0)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 3 || fields[2] == "(none)" {
+			continue
+		}
+		packages = append(packages, PackageInfo{Name: fields[0], Version: fields[1], Arch: fields[2]})
+	}
+	return packages
+}
+
+// 匹配 "openssl/jammy-updates,jammy-security 3.0.2-0ubuntu1.15 amd64 [upgradable from: 3.0.2-0ubuntu1.14]"
+var aptUpgradablePattern = regexp.MustCompile(`^(\S+)/(\S+)\s+(\S+)\s+(\S+)\s+\[upgradable from:\s*([^\]]+)\]`)
+
+// parseAptUpgradable 解析 apt list --upgradable 的输出
+func parseAptUpgradable(output string) []PackageUpdate {
+	updates := make([]PackageUpdate, 0)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		m := aptUpgradablePattern.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		updates = append(updates, PackageUpdate{
+			Name:           m[1],
+			Repo:           m[2],
+			Version:        m[3],
+			Arch:           m[4],
+			CurrentVersion: strings.TrimSpace(m[5]),
+			Security:       strings.Contains(m[2], "-security"),
+		})
+	}
+	return updates
+}
+
+// parseRPMUpdates 解析 dnf/yum list updates 的输出：包名.架构 版本 软件源，当前版本取自已安装列表
+func parseRPMUpdates(output string, installed []PackageInfo) []PackageUpdate {
+	current := make(map[string]string, len(installed))
+	for _, p := range installed {
+		current[p.Name+"."+p.Arch] = p.Version
+	}
+
+	updates := make([]PackageUpdate, 0)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// 跳过 "Available Upgrades"、"Updated Packages" 等标题行
+		if len(fields) != 3 {
+			continue
+		}
+		dot := strings.LastIndex(fields[0], ".")
+		if dot <= 0 {
+			continue
+		}
+		updates = append(updates, PackageUpdate{
+			Name:           fields[0][:dot],
+			Arch:           fields[0][dot+1:],
+			Version:        fields[1],
+			Repo:           fields[2],
+			CurrentVersion: current[fields[0]],
+		})
+	}
+	return updates
+}
+
+// parseRPMSecurityNames 解析 updateinfo list security 的输出（公告号 类型 NEVRA），返回涉及的包名
+func parseRPMSecurityNames(output string) map[string]bool {
+	names := make(map[string]bool)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		if name := rpmNameFromNEVRA(fields[len(fields)-1]); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// rpmNameFromNEVRA 从 "openssl-1:3.0.7-25.el9_3.x86_64" 中取出包名 openssl
+func rpmNameFromNEVRA(nevra string) string {
+	if dot := strings.LastIndex(nevra, "."); dot > 0 {
+		nevra = nevra[:dot]
+	}
+	// 去掉 -发布号 和 -[epoch:]版本
+	for i := 0; i < 2; i++ {
+		dash := strings.LastIndex(nevra, "-")
+		if dash <= 0 {
+			return ""
+		}
+		nevra = nevra[:dash]
+	}
+	return nevra
+}
+
+// packageCommandArgs 生成安装、升级、卸载的命令行。upgrade 不指定软件包时升级全部。
+// 包名已经过 ValidatePackageNames 校验，不会以 - 开头被当作选项
+func packageCommandArgs(manager, action string, packages []string, dryRun bool) (string, []string, error) {
+	if action != "upgrade" && len(packages) == 0 {
+		return "", nil, fmt.Errorf("请指定软件包")
+	}
+
+	if manager == "apt" {
+		// 保留本地修改过的配置文件，避免 dpkg 询问而卡住
+		args := []string{"-y", "-q", "-o", "Dpkg::Options::=--force-confold"}
+		if dryRun {
+			args = append(args, "--simulate")
+		}
+		switch action {
+		case "install":
+			args = append(args, "install")
+		case "upgrade":
+			if len(packages) == 0 {
+				args = append(args, "upgrade")
+			} else {
+				args = append(args, "install", "--only-upgrade")
+			}
+		case "remove":
+			args = append(args, "remove")
+		default:
+			return "", nil, fmt.Errorf("不支持的操作: %s", action)
+		}
+		return "apt-get", append(args, packages...), nil
+	}
+
+	if action != "install" && action != "upgrade" && action != "remove" {
+		return "", nil, fmt.Errorf("不支持的操作: %s", action)
+	}
+	// dnf/yum 没有模拟执行，--assumeno 只解析依赖并列出事务，不做修改
+	confirm := "-y"
+	if dryRun {
+		confirm = "--assumeno"
+	}
+	return manager, append([]string{confirm, action}, packages...), nil
+}
+
+// RunCommand 安装、升级或卸载软件包，dryRun 时只列出将要执行的变更
+func (pm *PackageManager) RunCommand(action string, packages []string, dryRun bool) (*PackageCommandResult, error) {
+	if pm.manager == "" {
+		return nil, ErrNoPackageManager
+	}
+	action = strings.ToLower(strings.TrimSpace(action))
+	packages, err := ValidatePackageNames(packages)
+	if err != nil {
+		return nil, err
+	}
+	name, args, err := packageCommandArgs(pm.manager, action, packages, dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	commandLine := name + " " + strings.Join(args, " ")
+	pm.log.Info("执行软件包操作: %s", commandLine)
+	output, err := pm.command(packageActionTimeout, name, args...)
+	result := &PackageCommandResult{
+		Action:   action,
+		Packages: packages,
+		DryRun:   dryRun,
+		Command:  commandLine,
+		Output:   tailOutput(string(output), maxPackageOutput),
+	}
+	// dnf/yum 使用 --assumeno 时总是以非零状态退出
+	if err != nil && !(dryRun && pm.manager != "apt" && len(output) > 0) {
+		pm.log.Error("软件包操作失败: %s: %v", commandLine, err)
+		return result, fmt.Errorf("%s 失败: %v", action, err)
+	}
+	return result, nil
+}
+
+// tailOutput 超过 limit 时只保留输出末尾
+func tailOutput(output string, limit int) string {
+	if len(output) <= limit {
+		return output
+	}
+	return "...\n" + strings.ToValidUTF8(output[len(output)-limit:], "")
+}
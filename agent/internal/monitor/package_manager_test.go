@@ -0,0 +1,105 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func TestParseAptInventory(t *testing.T) {
+	packages := parseDpkgPackages("ii \topenssl\t3.0.2-0ubuntu1.14\tamd64\n" +
+		"rc \told-kernel\t5.15.0-1\tamd64\n" +
+		"ii \tlibc6\t2.35-0ubuntu3.6\tamd64\n")
+	assert.Equal(t, []PackageInfo{
+		{Name: "openssl", Version: "3.0.2-0ubuntu1.14", Arch: "amd64"},
+		{Name: "libc6", Version: "2.35-0ubuntu3.6", Arch: "amd64"},
+	}, packages)
+
+	updates := parseAptUpgradable(`Listing...
+openssl/jammy-updates,jammy-security 3.0.2-0ubuntu1.15 amd64 [upgradable from: 3.0.2-0ubuntu1.14]
+curl/jammy-updates 7.81.0-1ubuntu1.16 amd64 [upgradable from: 7.81.0-1ubuntu1.15]
+`)
+	assert.Len(t, updates, 2)
+	assert.Equal(t, PackageUpdate{
+		Name: "openssl", Arch: "amd64", CurrentVersion: "3.0.2-0ubuntu1.14", Version: "3.0.2-0ubuntu1.15",
+		Repo: "jammy-updates,jammy-security", Security: true,
+	}, updates[0])
+	assert.False(t, updates[1].Security)
+}
+
+func TestParseRPMInventory(t *testing.T) {
+	installed := parseRPMPackages("openssl\t1:3.0.7-24.el9\tx86_64\ngpg-pubkey\t5a6340b3-6229229e\t(none)\nbash\t5.1.8-6.el9\tx86_64\n")
+	assert.Len(t, installed, 2)
+	assert.Equal(t, "1:3.0.7-24.el9", installed[0].Version)
+
+	updates := parseRPMUpdates(`Available Upgrades
+openssl.x86_64            1:3.0.7-25.el9_3            baseos
+kernel-core.x86_64        5.14.0-362.el9              baseos
+`, installed)
+	assert.Len(t, updates, 2)
+	assert.Equal(t, PackageUpdate{
+		Name: "openssl", Arch: "x86_64", CurrentVersion: "1:3.0.7-24.el9", Version: "1:3.0.7-25.el9_3", Repo: "baseos",
+	}, updates[0])
+
+	names := parseRPMSecurityNames("RHSA-2024:0310 Moderate/Sec.  openssl-1:3.0.7-25.el9_3.x86_64\n" +
+		"RHSA-2024:0448 Important/Sec. kernel-core-5.14.0-362.18.1.el9_3.x86_64\n")
+	assert.Equal(t, map[string]bool{"openssl": true, "kernel-core": true}, names)
+}
+
+func TestPackageCommandArgs(t *testing.T) {
+	name, args, err := packageCommandArgs("apt", "install", []string{"nginx"}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "apt-get", name)
+	assert.Equal(t, []string{"-y", "-q", "-o", "Dpkg::Options::=--force-confold", "--simulate", "install", "nginx"}, args)
+
+	_, args, err = packageCommandArgs("apt", "upgrade", nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "upgrade", args[len(args)-1])
+
+	name, args, err = packageCommandArgs("dnf", "remove", []string{"httpd"}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "dnf", name)
+	assert.Equal(t, []string{"--assumeno", "remove", "httpd"}, args)
+
+	_, _, err = packageCommandArgs("apt", "remove", nil, false)
+	assert.Error(t, err)
+	_, _, err = packageCommandArgs("yum", "autoremove", []string{"x"}, false)
+	assert.Error(t, err)
+}
+
+func TestPackageRunCommand(t *testing.T) {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	pm := &PackageManager{log: log, manager: "dnf"}
+	var commands []string
+	pm.run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		return []byte("Transaction Summary\nInstall  1 Package\nOperation aborted."), errors.New("exit status 1")
+	}
+
+	// dnf 模拟执行时非零退出不视为失败
+	result, err := pm.RunCommand("INSTALL", []string{" nginx "}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"nginx"}, result.Packages)
+	assert.Contains(t, result.Output, "Transaction Summary")
+	assert.Equal(t, []string{"dnf --assumeno install nginx"}, commands)
+
+	result, err = pm.RunCommand("install", []string{"nginx"}, false)
+	assert.Error(t, err)
+	assert.NotNil(t, result)
+
+	for _, bad := range [][]string{{"-y"}, {"nginx; reboot"}, {"a b"}} {
+		_, err := pm.RunCommand("install", bad, false)
+		assert.Error(t, err, bad)
+	}
+	assert.Len(t, commands, 2)
+
+	_, err = (&PackageManager{log: log}).Inventory(false)
+	assert.ErrorIs(t, err, ErrNoPackageManager)
+}
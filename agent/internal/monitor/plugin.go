@@ -0,0 +1,255 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CustomMetric 自定义插件上报的单个指标
+type CustomMetric struct {
+	Name   string  `json:"name"`
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit,omitempty"`
+	Plugin string  `json:"plugin"` // 产生该指标的插件名
+}
+
+// PluginConfig 自定义采集插件配置
+type PluginConfig struct {
+	Dir       string        // 允许执行的插件目录
+	Scripts   []string      // 启用的插件脚本文件名（相对于 Dir）
+	Timeout   time.Duration // 单个插件执行超时
+	MaxOutput int           // 单个插件输出大小上限(bytes)
+}
+
+// 插件指标名只允许字母、数字、下划线、点和中划线，避免注入到前端展示或存储字段
+var pluginMetricNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.\-]{1,128}$`)
+
+// pluginState 自定义插件的配置、执行状态和各插件最近一次成功的结果
+type pluginState struct {
+	mu      sync.Mutex
+	cfg     PluginConfig
+	running map[string]bool
+	results map[string][]CustomMetric
+}
+
+// SetPlugins 配置自定义采集插件，已移除插件的结果随之丢弃
+func (m *Monitor) SetPlugins(cfg PluginConfig) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.MaxOutput <= 0 {
+		cfg.MaxOutput = 64 * 1024
+	}
+
+	s := &m.plugins
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.results == nil {
+		s.running = make(map[string]bool)
+		s.results = make(map[string][]CustomMetric)
+	}
+	keep := make(map[string]bool, len(cfg.Scripts))
+	for _, script := range cfg.Scripts {
+		keep[script] = true
+	}
+	// 插件目录变化后旧结果不再对应同一个程序
+	for script := range s.results {
+		if !keep[script] || cfg.Dir != s.cfg.Dir {
+			delete(s.results, script)
+		}
+	}
+	s.cfg = cfg
+}
+
+// collectCustomMetrics 在后台启动各插件的新一轮执行，立即返回各插件最近一次成功的结果，
+// 慢插件不会拖慢内置指标的采集和上报。单个插件失败只记录日志并清除其结果，不影响其他插件
+func (m *Monitor) collectCustomMetrics() []CustomMetric {
+	m.runDuePlugins()
+
+	s := &m.plugins
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var metrics []CustomMetric
+	for _, script := range s.cfg.Scripts {
+		metrics = append(metrics, s.results[script]...)
+	}
+	return metrics
+}
+
+// runDuePlugins 并发执行已配置的插件，同一插件上一次尚未结束时跳过
+func (m *Monitor) runDuePlugins() {
+	s := &m.plugins
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cfg.Dir == "" {
+		return
+	}
+	for _, script := range s.cfg.Scripts {
+		if s.running[script] {
+			continue
+		}
+		s.running[script] = true
+		go func(cfg PluginConfig, script string) {
+			result, err := runPlugin(cfg, script)
+			if err != nil {
+				m.log.Warn("执行自定义插件 %s 失败: %v", script, err)
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			delete(s.running, script)
+			// 执行期间配置已变化时丢弃结果
+			if cfg.Dir != s.cfg.Dir || !slices.Contains(s.cfg.Scripts, script) {
+				return
+			}
+			if err != nil {
+				delete(s.results, script)
+				return
+			}
+			s.results[script] = result
+		}(s.cfg, script)
+	}
+}
+
+// runPlugin 执行单个插件脚本并解析其 JSON 输出
+func runPlugin(cfg PluginConfig, script string) ([]CustomMetric, error) {
+	path, err := resolvePluginPath(cfg.Dir, script)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+
+	stdout := &limitedBuffer{limit: cfg.MaxOutput}
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Dir = filepath.Dir(path)
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("执行超时(%s)", cfg.Timeout)
+		}
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.overflow {
+		return nil, fmt.Errorf("输出超过上限 %d 字节", cfg.MaxOutput)
+	}
+
+	return parsePluginOutput(filepath.Base(path), stdout.Bytes())
+}
+
+// resolvePluginPath 将插件名解析为插件目录下的绝对路径，并校验执行策略：
+// 必须位于插件目录内、是普通文件且不可被其他用户写入
+func resolvePluginPath(dir, script string) (string, error) {
+	if strings.TrimSpace(script) == "" {
+		return "", fmt.Errorf("插件名为空")
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("解析插件目录失败: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(absDir); err == nil {
+		absDir = resolved
+	}
+
+	path := filepath.Join(absDir, filepath.Clean("/"+script))
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("插件不存在: %w", err)
+	}
+	if resolved != absDir && !strings.HasPrefix(resolved, absDir+string(filepath.Separator)) {
+		return "", fmt.Errorf("插件 %s 不在允许的目录 %s 内", script, absDir)
+	}
+
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", fmt.Errorf("读取插件信息失败: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("插件 %s 不是普通文件", script)
+	}
+	if runtime.GOOS != "windows" {
+		if info.Mode().Perm()&0002 != 0 {
+			return "", fmt.Errorf("插件 %s 可被任意用户写入，拒绝执行", script)
+		}
+		if info.Mode().Perm()&0111 == 0 {
+			return "", fmt.Errorf("插件 %s 没有执行权限", script)
+		}
+	}
+
+	return resolved, nil
+}
+
+// parsePluginOutput 解析插件输出，支持单个对象或对象数组：
+// {"name": "queue_depth", "value": 12, "unit": "items"}
+func parsePluginOutput(plugin string, output []byte) ([]CustomMetric, error) {
+	output = bytes.TrimSpace(output)
+	if len(output) == 0 {
+		return nil, fmt.Errorf("插件没有输出")
+	}
+
+	var raw []CustomMetric
+	if output[0] == '[' {
+		if err := json.Unmarshal(output, &raw); err != nil {
+			return nil, fmt.Errorf("解析插件输出失败: %w", err)
+		}
+	} else {
+		var single CustomMetric
+		if err := json.Unmarshal(output, &single); err != nil {
+			return nil, fmt.Errorf("解析插件输出失败: %w", err)
+		}
+		raw = []CustomMetric{single}
+	}
+
+	metrics := make([]CustomMetric, 0, len(raw))
+	for _, metric := range raw {
+		if !pluginMetricNamePattern.MatchString(metric.Name) {
+			return nil, fmt.Errorf("无效的指标名: %q", metric.Name)
+		}
+		metric.Plugin = plugin
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
+}
+
+// limitedBuffer 限制写入大小的缓冲区，超出部分丢弃并标记溢出。
+// 不嵌入 bytes.Buffer：io.Copy 会优先使用其 ReadFrom 和 WriteString，绕过 Write 的大小限制
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	remaining := b.limit - b.buf.Len()
+	if remaining <= 0 {
+		b.overflow = true
+		return len(p), nil
+	}
+	if len(p) > remaining {
+		b.overflow = true
+		b.buf.Write(p[:remaining])
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) Bytes() []byte  { return b.buf.Bytes() }
+func (b *limitedBuffer) String() string { return b.buf.String() }
+func (b *limitedBuffer) Len() int       { return b.buf.Len() }
@@ -0,0 +1,120 @@
+package monitor
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func TestParsePluginOutput(t *testing.T) {
+	// 单个对象
+	metrics, err := parsePluginOutput("queue.sh", []byte(`{"name":"queue_depth","value":12,"unit":"items"}`))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, "queue_depth", metrics[0].Name)
+	assert.Equal(t, 12.0, metrics[0].Value)
+	assert.Equal(t, "items", metrics[0].Unit)
+	assert.Equal(t, "queue.sh", metrics[0].Plugin)
+
+	// 对象数组
+	metrics, err = parsePluginOutput("orders.sh", []byte(`[{"name":"orders","value":3},{"name":"refunds","value":1}]`))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 2)
+
+	// 空输出和非法指标名
+	_, err = parsePluginOutput("empty.sh", []byte("  "))
+	assert.Error(t, err)
+	_, err = parsePluginOutput("bad.sh", []byte(`{"name":"<script>","value":1}`))
+	assert.Error(t, err)
+}
+
+func TestResolvePluginPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("权限校验仅适用于类Unix系统")
+	}
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "ok.sh")
+	assert.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho '{}'\n"), 0755))
+
+	path, err := resolvePluginPath(dir, "ok.sh")
+	assert.NoError(t, err)
+	assert.Equal(t, "ok.sh", filepath.Base(path))
+
+	// 路径穿越会被限制在插件目录内
+	_, err = resolvePluginPath(dir, "../../etc/passwd")
+	assert.Error(t, err)
+
+	// 没有执行权限的脚本被拒绝
+	noExec := filepath.Join(dir, "noexec.sh")
+	assert.NoError(t, os.WriteFile(noExec, []byte("#!/bin/sh\n"), 0644))
+	_, err = resolvePluginPath(dir, "noexec.sh")
+	assert.Error(t, err)
+}
+
+func TestCollectCustomMetricsInBackground(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("使用 sh 执行测试插件")
+	}
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+content), 0755))
+	}
+	// slow.sh 记录启动次数，等待 release 文件出现后才输出
+	write("slow.sh", "echo x >> starts\nwhile [ ! -f release ]; do sleep 0.05; done\necho '{\"name\":\"slow\",\"value\":1}'\n")
+	write("fast.sh", "[ -f fail ] && exit 1\necho '{\"name\":\"fast\",\"value\":2}'\n")
+
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	m := New(log)
+	m.SetPlugins(PluginConfig{Dir: dir, Scripts: []string{"slow.sh", "fast.sh"}})
+	names := func() []string {
+		var names []string
+		for _, metric := range m.collectCustomMetrics() {
+			names = append(names, metric.Name)
+		}
+		return names
+	}
+	defer os.WriteFile(filepath.Join(dir, "release"), nil, 0644)
+
+	// 首次采集不等待插件执行完成
+	start := time.Now()
+	assert.Empty(t, names())
+	assert.Less(t, time.Since(start), time.Second)
+
+	// 快插件的结果先上报，慢插件尚未结束时不重复启动
+	assert.Eventually(t, func() bool { return assert.ObjectsAreEqual([]string{"fast"}, names()) }, 5*time.Second, 20*time.Millisecond)
+	starts, err := os.ReadFile(filepath.Join(dir, "starts"))
+	assert.NoError(t, err)
+	assert.Equal(t, "x\n", string(starts))
+
+	// 慢插件结束后按配置顺序上报两者的结果
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "release"), nil, 0644))
+	assert.Eventually(t, func() bool { return assert.ObjectsAreEqual([]string{"slow", "fast"}, names()) }, 5*time.Second, 20*time.Millisecond)
+
+	// 执行失败的插件清除上一次的结果
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "fail"), nil, 0644))
+	assert.Eventually(t, func() bool { return assert.ObjectsAreEqual([]string{"slow"}, names()) }, 5*time.Second, 20*time.Millisecond)
+
+	// 移除的插件不再上报
+	m.SetPlugins(PluginConfig{Dir: dir, Scripts: []string{"fast.sh"}})
+	assert.Empty(t, names())
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	// exec 通过 io.Copy 写入，同样受大小限制
+	buf := &limitedBuffer{limit: 10}
+	n, err := io.Copy(buf, strings.NewReader(strings.Repeat("x", 100)))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), n)
+	assert.Equal(t, 10, buf.Len())
+	assert.True(t, buf.overflow)
+}
@@ -0,0 +1,72 @@
+package monitor
+
+import (
+	"bufio"
+	"io"
+	"os/user"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Linux capability 编号及非 root 运行时缺少该 capability 会受限的操作
+var linuxCapabilities = []struct {
+	bit     uint
+	name    string
+	limited string
+}{
+	{1, "CAP_DAC_OVERRIDE", "读写其他用户的文件和系统配置"},
+	{5, "CAP_KILL", "终止其他用户的进程"},
+	{10, "CAP_NET_BIND_SERVICE", "监听 1024 以下的端口（如 Nginx 的 80/443）"},
+	{12, "CAP_NET_ADMIN", "修改网络和防火墙配置"},
+	{21, "CAP_SYS_ADMIN", "挂载文件系统等系统管理操作"},
+}
+
+// DetectPrivilege 检测 Agent 的运行权限
+func DetectPrivilege() *PrivilegeCapability {
+	privilege := &PrivilegeCapability{Root: isPrivileged()}
+	if u, err := user.Current(); err == nil {
+		privilege.User = u.Username
+	}
+	if privilege.Root {
+		return privilege
+	}
+
+	if runtime.GOOS == "linux" {
+		effective, ok := readEffectiveCapabilities()
+		privilege.Capabilities, privilege.Limited = describeCapabilities(effective, ok)
+	} else {
+		for _, c := range linuxCapabilities {
+			privilege.Limited = append(privilege.Limited, c.limited)
+		}
+	}
+	privilege.Limited = append(privilege.Limited, "管理系统服务", "签发 SSL 证书并写入 Nginx 配置目录")
+	return privilege
+}
+
+// describeCapabilities 根据有效 capability 位图列出拥有的 capability 和受限的操作；
+// 读取失败时按没有任何 capability 处理
+func describeCapabilities(effective uint64, ok bool) (have []string, limited []string) {
+	for _, c := range linuxCapabilities {
+		if ok && effective&(1<<c.bit) != 0 {
+			have = append(have, c.name)
+		} else {
+			limited = append(limited, c.limited)
+		}
+	}
+	return have, limited
+}
+
+// parseCapEff 解析 /proc/<pid>/status 中的 CapEff 行
+func parseCapEff(r io.Reader) (uint64, bool) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		value, found := strings.CutPrefix(scanner.Text(), "CapEff:")
+		if !found {
+			continue
+		}
+		caps, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		return caps, err == nil
+	}
+	return 0, false
+}
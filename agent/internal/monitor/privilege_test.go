@@ -0,0 +1,26 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCapEff(t *testing.T) {
+	status := "Name:\tagent\nCapInh:\t0000000000000000\nCapPrm:\t0000000000000420\nCapEff:\t0000000000000420\n"
+	caps, ok := parseCapEff(strings.NewReader(status))
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0x420), caps)
+
+	// CAP_KILL(5) 和 CAP_NET_BIND_SERVICE(10)
+	have, limited := describeCapabilities(caps, ok)
+	assert.Equal(t, []string{"CAP_KILL", "CAP_NET_BIND_SERVICE"}, have)
+	assert.Len(t, limited, len(linuxCapabilities)-2)
+
+	_, ok = parseCapEff(strings.NewReader("Name:\tagent\n"))
+	assert.False(t, ok)
+	have, limited = describeCapabilities(0xffff, false)
+	assert.Empty(t, have)
+	assert.Len(t, limited, len(linuxCapabilities))
+}
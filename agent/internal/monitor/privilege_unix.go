@@ -0,0 +1,19 @@
+//go:build !windows
+
+package monitor
+
+import "os"
+
+func isPrivileged() bool {
+	return os.Geteuid() == 0
+}
+
+// readEffectiveCapabilities 读取 /proc/self/status 中的 CapEff（十六进制位图）
+func readEffectiveCapabilities() (uint64, bool) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	return parseCapEff(f)
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package monitor
+
+import "golang.org/x/sys/windows"
+
+// isPrivileged Windows 下以进程令牌是否已提权判断管理员权限（UAC 未提权的管理员账户视为非管理员）
+func isPrivileged() bool {
+	return windows.GetCurrentProcessToken().IsElevated()
+}
+
+func readEffectiveCapabilities() (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,173 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	stdnet "net"
+	"strconv"
+	"strings"
+)
+
+// ProbePolicy 面板下发的探测目标策略，规则文本与面板系统设置中的「探测目标策略」相同，
+// 每行一条 "网段 [端口列表]"，如 "10.0.0.0/8 80,443"、"* 443"
+type ProbePolicy struct {
+	Allow string `json:"allow"` // 为空表示允许所有未被禁止的目标
+	Deny  string `json:"deny"`
+}
+
+// 始终禁止探测的地址：链路本地地址和云厂商的实例元数据服务，与面板一致，即使允许列表包含也不能探测
+var defaultDeniedProbeNetworks = []string{
+	"169.254.0.0/16",
+	"fe80::/10",
+	"100.100.100.200/32",
+	"fd00:ec2::254/128",
+}
+
+// probePortRange 闭区间端口范围
+type probePortRange struct {
+	from int
+	to   int
+}
+
+// probeRule 一条探测目标规则，network 为 nil 表示所有地址，ports 为空表示所有端口
+type probeRule struct {
+	network *stdnet.IPNet
+	ports   []probePortRange
+	raw     string
+}
+
+// matches 判断地址和端口是否命中规则，port 为 0 表示 ICMP：只有不限端口的规则才会命中
+func (r probeRule) matches(ip stdnet.IP, port int) bool {
+	if r.network != nil && !r.network.Contains(ip) {
+		return false
+	}
+	if len(r.ports) == 0 {
+		return true
+	}
+	for _, p := range r.ports {
+		if port >= p.from && port <= p.to {
+			return true
+		}
+	}
+	return false
+}
+
+// probeTargetPolicy 解析后的探测目标策略：先检查禁止列表（含内置禁止网段），再检查允许列表
+type probeTargetPolicy struct {
+	allow []probeRule
+	deny  []probeRule
+}
+
+// defaultProbePolicy 面板未下发策略（旧版面板）时只使用内置禁止网段
+var defaultProbePolicy, _ = newProbeTargetPolicy(ProbePolicy{})
+
+// newProbeTargetPolicy 解析面板下发的策略并附加内置禁止网段
+func newProbeTargetPolicy(policy ProbePolicy) (*probeTargetPolicy, error) {
+	allow, err := parseProbeRules(policy.Allow)
+	if err != nil {
+		return nil, fmt.Errorf("允许列表: %w", err)
+	}
+	deny, err := parseProbeRules(policy.Deny)
+	if err != nil {
+		return nil, fmt.Errorf("禁止列表: %w", err)
+	}
+	for _, cidr := range defaultDeniedProbeNetworks {
+		rule, _ := parseProbeRule(cidr)
+		deny = append(deny, rule)
+	}
+	return &probeTargetPolicy{allow: allow, deny: deny}, nil
+}
+
+// parseProbeRules 按行解析规则，忽略空行和以 # 开头的注释
+func parseProbeRules(text string) ([]probeRule, error) {
+	var rules []probeRule
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseProbeRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行规则无效（%s）: %w", i+1, line, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseProbeRule(line string) (probeRule, error) {
+	fields := strings.Fields(line)
+	if len(fields) > 2 {
+		return probeRule{}, errors.New("格式应为 \"网段 [端口列表]\"")
+	}
+	rule := probeRule{raw: line}
+
+	switch network := fields[0]; {
+	case network == "*":
+	case strings.Contains(network, "/"):
+		_, ipNet, err := stdnet.ParseCIDR(network)
+		if err != nil {
+			return probeRule{}, errors.New("无效的网段")
+		}
+		rule.network = ipNet
+	default:
+		ip := stdnet.ParseIP(network)
+		if ip == nil {
+			return probeRule{}, errors.New("无效的IP地址")
+		}
+		if v4 := ip.To4(); v4 != nil {
+			rule.network = &stdnet.IPNet{IP: v4, Mask: stdnet.CIDRMask(32, 32)}
+		} else {
+			rule.network = &stdnet.IPNet{IP: ip, Mask: stdnet.CIDRMask(128, 128)}
+		}
+	}
+
+	if len(fields) == 2 {
+		for _, part := range strings.Split(fields[1], ",") {
+			if part == "" {
+				continue
+			}
+			from, to, found := strings.Cut(part, "-")
+			if !found {
+				to = from
+			}
+			start, err1 := strconv.Atoi(from)
+			end, err2 := strconv.Atoi(to)
+			if err1 != nil || err2 != nil || start < 1 || end > 65535 || start > end {
+				return probeRule{}, fmt.Errorf("无效的端口范围 %s", part)
+			}
+			rule.ports = append(rule.ports, probePortRange{from: start, to: end})
+		}
+	}
+	return rule, nil
+}
+
+// check 检查地址和端口是否允许探测，port 为 0 表示 ICMP
+func (p *probeTargetPolicy) check(ip stdnet.IP, port int) error {
+	// IPv4 映射的 IPv6 地址按 IPv4 处理，避免绕过 IPv4 规则
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	for _, rule := range p.deny {
+		if rule.matches(ip, port) {
+			return fmt.Errorf("目标 %s 被禁止探测（规则: %s）", probeAddrString(ip, port), rule.raw)
+		}
+	}
+	if len(p.allow) == 0 {
+		return nil
+	}
+	for _, rule := range p.allow {
+		if rule.matches(ip, port) {
+			return nil
+		}
+	}
+	return fmt.Errorf("目标 %s 不在允许探测的范围内", probeAddrString(ip, port))
+}
+
+// probeAddrString 格式化探测目标，ICMP 目标不带端口
+func probeAddrString(ip stdnet.IP, port int) string {
+	if port == 0 {
+		return ip.String()
+	}
+	return stdnet.JoinHostPort(ip.String(), strconv.Itoa(port))
+}
@@ -29,6 +29,7 @@ type ProcessInfo struct {
 	Cmd        string   `json:"cmd"`
 	Ports      []string `json:"ports"`
 	IsSystem   bool     `json:"is_system"`
+	Zombie     bool     `json:"zombie"` // 僵尸进程（已退出但未被父进程回收），CPU、内存等统计均为空
 }
 
 // ProcessManager 进程管理器
@@ -88,10 +89,21 @@ func (pm *ProcessManager) GetProcessList() ([]*ProcessInfo, error) {
 		processList = append(processList, procInfo)
 	}
 
-	pm.log.Debug("已获取 %d 个进程", len(processList))
+	pm.log.Debug("已获取 %d 个进程，其中僵尸进程 %d 个", len(processList), CountZombieProcesses(processList))
 	return processList, nil
 }
 
+// CountZombieProcesses 统计进程列表中的僵尸进程数量
+func CountZombieProcesses(processList []*ProcessInfo) int {
+	count := 0
+	for _, p := range processList {
+		if p.Zombie {
+			count++
+		}
+	}
+	return count
+}
+
 // getProcessInfo 获取单个进程详细信息
 func (pm *ProcessManager) getProcessInfo(p *process.Process) (*ProcessInfo, error) {
 	// 创建进程信息对象
@@ -102,6 +114,10 @@ func (pm *ProcessManager) getProcessInfo(p *process.Process) (*ProcessInfo, erro
 	// 获取进程名称
 	name, err := p.Name()
 	if err != nil {
+		// 进程在扫描期间已退出
+		if exists, _ := process.PidExists(p.Pid); !exists {
+			return nil, fmt.Errorf("进程 %d 已退出", p.Pid)
+		}
 		name = "未知"
 	}
 	info.Name = name
@@ -128,6 +144,15 @@ func (pm *ProcessManager) getProcessInfo(p *process.Process) (*ProcessInfo, erro
 		info.Status = "未知"
 	}
 
+	// 僵尸进程已释放内存和命令行，只保留创建时间，其余统计读取会失败或为 0
+	if isZombieStatus(status) {
+		info.Zombie = true
+		if createTime, err := p.CreateTime(); err == nil {
+			info.CreateTime = createTime / 1000
+		}
+		return info, nil
+	}
+
 	// 获取CPU使用率
 	cpuPercent, err := p.CPUPercent()
 	if err == nil {
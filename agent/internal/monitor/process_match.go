@@ -0,0 +1,158 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// 批量终止时一律跳过的关键进程名称（小写）
+var protectedProcessNames = map[string]bool{
+	"init": true, "systemd": true, "launchd": true, "kernel_task": true, "kthreadd": true,
+	"system": true, "smss.exe": true, "csrss.exe": true, "wininit.exe": true,
+	"winlogon.exe": true, "services.exe": true, "lsass.exe": true,
+}
+
+// ProcessMatch 按名称或命令行匹配到的进程
+type ProcessMatch struct {
+	PID       int32  `json:"pid"`
+	PPID      int32  `json:"ppid"`
+	Name      string `json:"name"`
+	Username  string `json:"username"`
+	Cmd       string `json:"cmd"`
+	Protected bool   `json:"protected"`        // 受保护的进程不会被终止
+	Reason    string `json:"reason,omitempty"` // 受保护的原因
+}
+
+// ProcessKillResult 批量终止时单个进程的结果
+type ProcessKillResult struct {
+	PID     int32  `json:"pid"`
+	Name    string `json:"name"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// processCandidate 匹配时使用的进程基本信息
+type processCandidate struct {
+	pid, ppid      int32
+	name, username string
+	cmd            string
+}
+
+// MatchProcesses 按正则（不区分大小写）匹配进程名称，cmdline 为 true 时同时匹配完整命令行。
+// 结果按 PID 排序，受保护的进程也会列出以便预览时说明原因
+func (pm *ProcessManager) MatchProcesses(pattern string, cmdline bool) ([]ProcessMatch, error) {
+	re, err := compileProcessPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, fmt.Errorf("获取进程列表失败: %w", err)
+	}
+
+	candidates := make([]processCandidate, 0, len(procs))
+	for _, p := range procs {
+		name, err := p.Name()
+		if err != nil {
+			continue
+		}
+		candidate := processCandidate{pid: p.Pid, name: name}
+		candidate.ppid, _ = p.Ppid()
+		candidate.username, _ = p.Username()
+		candidate.cmd, _ = p.Cmdline()
+		candidates = append(candidates, candidate)
+	}
+	return matchProcessCandidates(candidates, re, cmdline, int32(os.Getpid()), int32(os.Getppid())), nil
+}
+
+// KillMatchedProcesses 终止预览中选定的进程。
+// 终止前重新匹配一次，只处理仍然匹配且未受保护的 PID，避免预览之后 PID 被复用导致误杀
+func (pm *ProcessManager) KillMatchedProcesses(pattern string, cmdline bool, pids []int32, signal string) ([]ProcessKillResult, error) {
+	if len(pids) == 0 {
+		return nil, fmt.Errorf("未选择要终止的进程")
+	}
+	matches, err := pm.MatchProcesses(pattern, cmdline)
+	if err != nil {
+		return nil, err
+	}
+	byPID := make(map[int32]ProcessMatch, len(matches))
+	for _, m := range matches {
+		byPID[m.PID] = m
+	}
+
+	results := make([]ProcessKillResult, 0, len(pids))
+	for _, pid := range pids {
+		m, ok := byPID[pid]
+		result := ProcessKillResult{PID: pid, Name: m.Name}
+		switch {
+		case !ok:
+			result.Error = "进程已退出或不再匹配"
+		case m.Protected:
+			result.Error = "受保护的进程: " + m.Reason
+		default:
+			if err := pm.KillProcessWithSignal(pid, signal); err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Success = true
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func compileProcessPattern(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil, fmt.Errorf("匹配模式不能为空")
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("无效的匹配模式: %w", err)
+	}
+	if re.MatchString("") {
+		return nil, fmt.Errorf("匹配模式会匹配所有进程，请填写更具体的名称")
+	}
+	return re, nil
+}
+
+func matchProcessCandidates(candidates []processCandidate, re *regexp.Regexp, cmdline bool, self, parent int32) []ProcessMatch {
+	matches := make([]ProcessMatch, 0)
+	for _, c := range candidates {
+		if !re.MatchString(c.name) && !(cmdline && re.MatchString(c.cmd)) {
+			continue
+		}
+		m := ProcessMatch{PID: c.pid, PPID: c.ppid, Name: c.name, Username: c.username, Cmd: c.cmd}
+		m.Reason = protectedProcessReason(c, self, parent)
+		m.Protected = m.Reason != ""
+		matches = append(matches, m)
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].PID < matches[j].PID })
+	return matches
+}
+
+// protectedProcessReason 返回进程受保护的原因，空字符串表示可以终止
+func protectedProcessReason(c processCandidate, self, parent int32) string {
+	switch {
+	case c.pid <= 1:
+		return "系统初始进程"
+	case c.pid == self:
+		return "Agent 自身"
+	case c.pid == parent:
+		return "Agent 的父进程"
+	case protectedProcessNames[strings.ToLower(c.name)]:
+		return "关键系统进程"
+	case runtime.GOOS == "linux" && (c.pid == 2 || c.ppid == 2):
+		return "内核线程"
+	}
+	return ""
+}
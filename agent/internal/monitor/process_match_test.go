@@ -0,0 +1,49 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchProcessCandidates(t *testing.T) {
+	candidates := []processCandidate{
+		{pid: 1, name: "systemd", cmd: "/sbin/init"},
+		{pid: 300, ppid: 1, name: "python3", cmd: "python3 worker.py"},
+		{pid: 200, ppid: 1, name: "gunicorn", cmd: "/usr/bin/python3 /srv/app/gunicorn"},
+		{pid: 400, ppid: 1, name: "agent", cmd: "/opt/agent python-helper"},
+		{pid: 500, ppid: 1, name: "nginx", cmd: "nginx: worker process"},
+	}
+
+	re, err := compileProcessPattern("PYTHON")
+	assert.NoError(t, err)
+
+	matches := matchProcessCandidates(candidates, re, false, 400, 1)
+	assert.Len(t, matches, 1)
+	assert.Equal(t, int32(300), matches[0].PID)
+
+	// 匹配命令行时，Agent 自身也会被列出但受保护
+	matches = matchProcessCandidates(candidates, re, true, 400, 1)
+	var pids []int32
+	for _, m := range matches {
+		pids = append(pids, m.PID)
+	}
+	assert.Equal(t, []int32{200, 300, 400}, pids)
+	assert.False(t, matches[0].Protected)
+	assert.True(t, matches[2].Protected)
+	assert.Equal(t, "Agent 自身", matches[2].Reason)
+
+	re, _ = compileProcessPattern("init|systemd")
+	matches = matchProcessCandidates(candidates, re, true, 400, 1)
+	assert.Len(t, matches, 1)
+	assert.True(t, matches[0].Protected)
+}
+
+func TestCompileProcessPatternRejects(t *testing.T) {
+	for _, pattern := range []string{"", "  ", ".*", "a|", "(["} {
+		_, err := compileProcessPattern(pattern)
+		assert.Error(t, err, pattern)
+	}
+}
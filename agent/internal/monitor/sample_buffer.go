@@ -0,0 +1,161 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SampleBuffer 与面板断开期间的监控样本缓冲区。
+// 样本按采集顺序逐行保存在磁盘文件中（JSON Lines），超过容量时丢弃最早的样本，
+// Agent 重启后仍能恢复；重新连接面板后按顺序重放，发送成功的样本从缓冲区移除
+type SampleBuffer struct {
+	mu      sync.Mutex
+	path    string
+	max     int
+	samples []*MonitorData
+}
+
+// NewSampleBuffer 创建缓冲区并加载上次未发送的样本，max 为最多保存的样本数。
+// 文件中无法解析的行（例如写入中途断电）直接跳过
+func NewSampleBuffer(path string, max int) (*SampleBuffer, error) {
+	b := &SampleBuffer{path: path, max: max}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var data MonitorData
+		if err := json.Unmarshal(scanner.Bytes(), &data); err != nil || data.CollectedAt == 0 {
+			continue
+		}
+		b.samples = append(b.samples, &data)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(b.samples) > max {
+		b.samples = b.samples[len(b.samples)-max:]
+		if err := b.rewrite(); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Len 返回缓冲区中等待重放的样本数
+func (b *SampleBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.samples)
+}
+
+// Push 保存一个未能发送的样本，记录采集时间供面板按原时间入库和去重。
+// 缓冲区已满时丢弃最早的样本
+func (b *SampleBuffer) Push(data *MonitorData) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sample := *data
+	if sample.CollectedAt == 0 {
+		sample.CollectedAt = time.Now().UnixMilli()
+	}
+	// 同一毫秒内的样本会被面板当作重复样本丢弃，保证采集时间严格递增
+	if n := len(b.samples); n > 0 && sample.CollectedAt <= b.samples[n-1].CollectedAt {
+		sample.CollectedAt = b.samples[n-1].CollectedAt + 1
+	}
+	b.samples = append(b.samples, &sample)
+
+	if len(b.samples) > b.max {
+		b.samples = b.samples[len(b.samples)-b.max:]
+		return b.rewrite()
+	}
+	return b.appendLine(&sample)
+}
+
+// Replay 按采集顺序发送缓冲的样本，遇到发送失败时停止，未发送的样本留待下次重放。
+// 返回成功发送的样本数
+func (b *SampleBuffer) Replay(send func(*MonitorData) error) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sent := 0
+	var sendErr error
+	for _, sample := range b.samples {
+		replay := *sample
+		replay.Replay = true
+		if sendErr = send(&replay); sendErr != nil {
+			break
+		}
+		sent++
+	}
+	if sent == 0 {
+		return 0, sendErr
+	}
+	b.samples = b.samples[sent:]
+	if err := b.rewrite(); err != nil {
+		return sent, err
+	}
+	return sent, sendErr
+}
+
+func (b *SampleBuffer) appendLine(sample *MonitorData) error {
+	line, err := json.Marshal(sample)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(b.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// rewrite 用内存中的样本重写缓冲文件，先写临时文件再重命名，避免中途退出留下损坏的文件
+func (b *SampleBuffer) rewrite() error {
+	if len(b.samples) == 0 {
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0755); err != nil {
+		return err
+	}
+	tmp := b.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, sample := range b.samples {
+		if err := enc.Encode(sample); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, b.path)
+}
@@ -0,0 +1,72 @@
+package monitor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleBufferDropsOldestAndSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitor_buffer.jsonl")
+	buffer, err := NewSampleBuffer(path, 3)
+	assert.NoError(t, err)
+
+	for i := 1; i <= 5; i++ {
+		assert.NoError(t, buffer.Push(&MonitorData{CPUUsage: float64(i)}))
+	}
+	assert.Equal(t, 3, buffer.Len())
+
+	reloaded, err := NewSampleBuffer(path, 3)
+	assert.NoError(t, err)
+
+	var cpu []float64
+	var last int64
+	sent, err := reloaded.Replay(func(data *MonitorData) error {
+		assert.True(t, data.Replay)
+		assert.Greater(t, data.CollectedAt, last)
+		last = data.CollectedAt
+		cpu = append(cpu, data.CPUUsage)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, sent)
+	assert.Equal(t, []float64{3, 4, 5}, cpu)
+	assert.Equal(t, 0, reloaded.Len())
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSampleBufferReplayKeepsUnsentSamples(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitor_buffer.jsonl")
+	buffer, err := NewSampleBuffer(path, 10)
+	assert.NoError(t, err)
+	for i := 1; i <= 3; i++ {
+		assert.NoError(t, buffer.Push(&MonitorData{CPUUsage: float64(i)}))
+	}
+
+	calls := 0
+	sent, err := buffer.Replay(func(data *MonitorData) error {
+		calls++
+		if calls == 2 {
+			return errors.New("连接已断开")
+		}
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, 2, buffer.Len())
+
+	reloaded, err := NewSampleBuffer(path, 10)
+	assert.NoError(t, err)
+	var cpu []float64
+	_, err = reloaded.Replay(func(data *MonitorData) error {
+		cpu = append(cpu, data.CPUUsage)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{2, 3}, cpu)
+}
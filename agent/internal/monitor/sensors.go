@@ -0,0 +1,115 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Linux hwmon 接口目录，lm-sensors 读取的也是这里的数据
+	hwmonRoot = "/sys/class/hwmon"
+	// 单次上报的传感器数上限，避免多路服务器上报数据过大
+	maxSensors = 64
+)
+
+// TemperatureSensor 温度传感器读数
+type TemperatureSensor struct {
+	Chip     string  `json:"chip"`               // 芯片名，如 coretemp、k10temp、nvme
+	Label    string  `json:"label"`              // 传感器标签，如 Package id 0、Composite，没有标签时为 temp1 等
+	Celsius  float64 `json:"celsius"`            // 当前温度(°C)
+	High     float64 `json:"high,omitempty"`     // 芯片给出的高温阈值(°C)
+	Critical float64 `json:"critical,omitempty"` // 芯片给出的临界温度(°C)
+}
+
+// FanSensor 风扇转速读数
+type FanSensor struct {
+	Chip  string `json:"chip"`
+	Label string `json:"label"`
+	RPM   int    `json:"rpm"`
+}
+
+// collectSensors 读取 hwmon 中的温度和风扇转速，非 Linux 或没有传感器时返回空
+func collectSensors() ([]TemperatureSensor, []FanSensor) {
+	return readHWMon(hwmonRoot)
+}
+
+// readHWMon 读取 root 下各 hwmon 设备的 temp*_input 和 fan*_input。
+// 驱动异常时会上报明显不合理的温度（如 -128、255），直接丢弃
+func readHWMon(root string) ([]TemperatureSensor, []FanSensor) {
+	devices, err := os.ReadDir(root)
+	if err != nil {
+		return nil, nil
+	}
+
+	var temps []TemperatureSensor
+	var fans []FanSensor
+	for _, device := range devices {
+		dir := filepath.Join(root, device.Name())
+		chip := readSysString(filepath.Join(dir, "name"))
+		if chip == "" {
+			chip = device.Name()
+		}
+
+		inputs, _ := filepath.Glob(filepath.Join(dir, "*_input"))
+		sort.Strings(inputs)
+		for _, input := range inputs {
+			prefix := strings.TrimSuffix(filepath.Base(input), "_input")
+			label := readSysString(filepath.Join(dir, prefix+"_label"))
+			if label == "" {
+				label = prefix
+			}
+			value, ok := readSysInt(input)
+			if !ok {
+				continue
+			}
+
+			switch {
+			case strings.HasPrefix(prefix, "temp"):
+				// hwmon 温度单位为毫摄氏度
+				celsius := float64(value) / 1000
+				if celsius <= -40 || celsius >= 150 {
+					continue
+				}
+				sensor := TemperatureSensor{Chip: chip, Label: label, Celsius: celsius}
+				if high, ok := readSysInt(filepath.Join(dir, prefix+"_max")); ok && high > 0 {
+					sensor.High = float64(high) / 1000
+				}
+				if crit, ok := readSysInt(filepath.Join(dir, prefix+"_crit")); ok && crit > 0 {
+					sensor.Critical = float64(crit) / 1000
+				}
+				temps = append(temps, sensor)
+			case strings.HasPrefix(prefix, "fan"):
+				if value < 0 {
+					continue
+				}
+				fans = append(fans, FanSensor{Chip: chip, Label: label, RPM: int(value)})
+			}
+		}
+	}
+
+	if len(temps) > maxSensors {
+		temps = temps[:maxSensors]
+	}
+	if len(fans) > maxSensors {
+		fans = fans[:maxSensors]
+	}
+	return temps, fans
+}
+
+// readSysString 读取 sysfs 文件内容，失败时返回空字符串
+func readSysString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// readSysInt 读取 sysfs 中的整数值
+func readSysInt(path string) (int64, bool) {
+	value, err := strconv.ParseInt(readSysString(path), 10, 64)
+	return value, err == nil
+}
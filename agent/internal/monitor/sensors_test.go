@@ -0,0 +1,60 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeSysFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	assert.NoError(t, os.MkdirAll(dir, 0755))
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content+"\n"), 0644))
+	}
+}
+
+func TestReadHWMon(t *testing.T) {
+	root := t.TempDir()
+	writeSysFiles(t, filepath.Join(root, "hwmon0"), map[string]string{
+		"name":        "coretemp",
+		"temp1_input": "52000",
+		"temp1_label": "Package id 0",
+		"temp1_max":   "84000",
+		"temp1_crit":  "100000",
+		"temp2_input": "49500",
+		"temp2_label": "Core 0",
+	})
+	writeSysFiles(t, filepath.Join(root, "hwmon1"), map[string]string{
+		"name":        "nvme",
+		"temp1_input": "38850",
+		"temp1_label": "Composite",
+		// 驱动未接入的传感器会报出 -128°C
+		"temp2_input": "-128000",
+	})
+	writeSysFiles(t, filepath.Join(root, "hwmon2"), map[string]string{
+		"name":        "nct6775",
+		"fan1_input":  "1250",
+		"fan2_input":  "0",
+		"fan2_label":  "SYSFAN",
+		"in0_input":   "1040",
+		"temp7_input": "bad",
+	})
+
+	temps, fans := readHWMon(root)
+	assert.Equal(t, []TemperatureSensor{
+		{Chip: "coretemp", Label: "Package id 0", Celsius: 52, High: 84, Critical: 100},
+		{Chip: "coretemp", Label: "Core 0", Celsius: 49.5},
+		{Chip: "nvme", Label: "Composite", Celsius: 38.85},
+	}, temps)
+	assert.Equal(t, []FanSensor{
+		{Chip: "nct6775", Label: "fan1", RPM: 1250},
+		{Chip: "nct6775", Label: "SYSFAN", RPM: 0},
+	}, fans)
+
+	temps, fans = readHWMon(filepath.Join(root, "missing"))
+	assert.Nil(t, temps)
+	assert.Nil(t, fans)
+}
@@ -0,0 +1,288 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+const (
+	// 查询类 systemctl/journalctl 命令的最长执行时间
+	serviceQueryTimeout = 15 * time.Second
+	// start/stop/restart 等待服务状态切换的最长时间，需小于面板等待响应的超时
+	serviceActionTimeout = 90 * time.Second
+	// 日志默认返回的行数和上限
+	defaultServiceLogLines = 200
+	maxServiceLogLines     = 2000
+)
+
+// systemd 单元名只允许字母、数字和 :_.@-，且不能以 - 开头，避免被当作命令行参数
+var serviceNamePattern = regexp.MustCompile(`^[A-Za-z0-9:_.@\\][A-Za-z0-9:_.@\\-]*$`)
+
+// serviceActions 支持的修改类操作
+var serviceActions = map[string]bool{
+	"start": true, "stop": true, "restart": true, "enable": true, "disable": true,
+}
+
+// showProperties systemctl show 查询的服务属性
+var showProperties = []string{
+	"Id", "Description", "LoadState", "ActiveState", "SubState", "UnitFileState",
+	"MainPID", "ExecMainStartTimestamp", "FragmentPath", "MemoryCurrent", "NRestarts", "Result",
+}
+
+// ErrSystemdUnavailable 系统没有 systemctl（非 systemd 发行版、容器或 Windows）
+var ErrSystemdUnavailable = errors.New("当前系统不支持 systemd 服务管理")
+
+// ServiceUnit systemd 服务列表中的一项
+type ServiceUnit struct {
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	LoadState     string `json:"load_state"`
+	ActiveState   string `json:"active_state"`
+	SubState      string `json:"sub_state"`
+	UnitFileState string `json:"unit_file_state"` // enabled、disabled、static 等，未安装单元文件时为空
+}
+
+// ServiceStatus 单个服务的详细状态
+type ServiceStatus struct {
+	ServiceUnit
+	MainPID      int    `json:"main_pid"`
+	StartedAt    string `json:"started_at"`
+	FragmentPath string `json:"fragment_path"`
+	MemoryBytes  uint64 `json:"memory_bytes"`
+	Restarts     int    `json:"restarts"`
+	Result       string `json:"result"`
+}
+
+// ServiceManager 通过 systemctl 和 journalctl 管理 systemd 服务
+type ServiceManager struct {
+	log *logger.Logger
+	// run 执行命令并返回合并后的输出，测试时替换
+	run func(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// NewServiceManager 创建一个新的服务管理器
+func NewServiceManager(log *logger.Logger) *ServiceManager {
+	return &ServiceManager{
+		log: log,
+		run: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if _, err := exec.LookPath(name); err != nil {
+				return nil, ErrSystemdUnavailable
+			}
+			return exec.CommandContext(ctx, name, args...).CombinedOutput()
+		},
+	}
+}
+
+// NormalizeServiceName 校验服务名，省略 .service 后缀时补全（如 nginx、php7.4-fpm）
+func NormalizeServiceName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 256 || !serviceNamePattern.MatchString(name) {
+		return "", fmt.Errorf("无效的服务名: %s", name)
+	}
+	if !strings.HasSuffix(name, ".service") {
+		name += ".service"
+	}
+	return name, nil
+}
+
+// command 执行命令，失败时把命令输出作为错误信息返回
+func (sm *ServiceManager) command(timeout time.Duration, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	output, err := sm.run(ctx, name, args...)
+	if err != nil {
+		if errors.Is(err, ErrSystemdUnavailable) {
+			return "", err
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("%s 执行超时", name)
+		}
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return "", errors.New(msg)
+		}
+		return "", fmt.Errorf("%s 执行失败: %v", name, err)
+	}
+	return string(output), nil
+}
+
+// ListServices 列出所有已加载的服务以及已安装但未加载的服务单元
+func (sm *ServiceManager) ListServices() ([]*ServiceUnit, error) {
+	sm.log.Debug("获取服务列表...")
+
+	output, err := sm.command(serviceQueryTimeout, "systemctl", "list-units", "--type=service", "--all",
+		"--no-pager", "--no-legend", "--plain")
+	if err != nil {
+		return nil, err
+	}
+	units := parseServiceUnits(output)
+
+	// 单元文件状态（是否开机启动）只能从 list-unit-files 获取，失败时不影响列表
+	files, err := sm.command(serviceQueryTimeout, "systemctl", "list-unit-files", "--type=service",
+		"--no-pager", "--no-legend")
+	if err != nil {
+		sm.log.Warn("获取服务单元文件状态失败: %v", err)
+		return units, nil
+	}
+	return mergeUnitFileStates(units, parseUnitFileStates(files)), nil
+}
+
+// parseServiceUnits 解析 systemctl list-units --plain --no-legend 的输出：UNIT LOAD ACTIVE SUB DESCRIPTION
+func parseServiceUnits(output string) []*ServiceUnit {
+	units := make([]*ServiceUnit, 0)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// 加载失败的单元前面带有 ● 标记
+		if len(fields) > 0 && fields[0] == "●" {
+			fields = fields[1:]
+		}
+		if len(fields) < 4 || !strings.HasSuffix(fields[0], ".service") {
+			continue
+		}
+		units = append(units, &ServiceUnit{
+			Name:        fields[0],
+			LoadState:   fields[1],
+			ActiveState: fields[2],
+			SubState:    fields[3],
+			Description: strings.Join(fields[4:], " "),
+		})
+	}
+	return units
+}
+
+// parseUnitFileStates 解析 systemctl list-unit-files --no-legend 的输出：UNIT STATE [PRESET]
+func parseUnitFileStates(output string) map[string]string {
+	states := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && strings.HasSuffix(fields[0], ".service") {
+			states[fields[0]] = fields[1]
+		}
+	}
+	return states
+}
+
+// mergeUnitFileStates 为服务补充单元文件状态，并追加已安装但未加载的服务（如已禁用的服务），按名称排序。
+// 模板单元（foo@.service）不能直接启动，不追加
+func mergeUnitFileStates(units []*ServiceUnit, states map[string]string) []*ServiceUnit {
+	seen := make(map[string]bool, len(units))
+	for _, unit := range units {
+		unit.UnitFileState = states[unit.Name]
+		seen[unit.Name] = true
+	}
+	for name, state := range states {
+		if seen[name] || strings.HasSuffix(name, "@.service") {
+			continue
+		}
+		units = append(units, &ServiceUnit{
+			Name:          name,
+			LoadState:     "not-loaded",
+			ActiveState:   "inactive",
+			SubState:      "dead",
+			UnitFileState: state,
+		})
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i].Name < units[j].Name })
+	return units
+}
+
+// GetServiceStatus 获取服务的详细状态
+func (sm *ServiceManager) GetServiceStatus(name string) (*ServiceStatus, error) {
+	name, err := NormalizeServiceName(name)
+	if err != nil {
+		return nil, err
+	}
+	sm.log.Debug("获取服务状态: %s", name)
+
+	output, err := sm.command(serviceQueryTimeout, "systemctl", "show", "--no-pager",
+		"--property="+strings.Join(showProperties, ","), "--", name)
+	if err != nil {
+		return nil, err
+	}
+	status := parseServiceShow(output)
+	if status.LoadState == "not-found" {
+		return nil, fmt.Errorf("服务不存在: %s", name)
+	}
+	return status, nil
+}
+
+// parseServiceShow 解析 systemctl show 输出的 Key=Value
+func parseServiceShow(output string) *ServiceStatus {
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), "="); ok {
+			props[key] = value
+		}
+	}
+
+	status := &ServiceStatus{
+		ServiceUnit: ServiceUnit{
+			Name:          props["Id"],
+			Description:   props["Description"],
+			LoadState:     props["LoadState"],
+			ActiveState:   props["ActiveState"],
+			SubState:      props["SubState"],
+			UnitFileState: props["UnitFileState"],
+		},
+		StartedAt:    props["ExecMainStartTimestamp"],
+		FragmentPath: props["FragmentPath"],
+		Result:       props["Result"],
+	}
+	status.MainPID, _ = strconv.Atoi(props["MainPID"])
+	status.Restarts, _ = strconv.Atoi(props["NRestarts"])
+	// 未启用内存统计时 MemoryCurrent 为 [not set] 或 uint64 最大值
+	if memory, err := strconv.ParseUint(props["MemoryCurrent"], 10, 64); err == nil && memory != ^uint64(0) {
+		status.MemoryBytes = memory
+	}
+	return status
+}
+
+// GetServiceLogs 读取服务最近的日志，lines 为 0 时使用默认行数
+func (sm *ServiceManager) GetServiceLogs(name string, lines int) (string, error) {
+	name, err := NormalizeServiceName(name)
+	if err != nil {
+		return "", err
+	}
+	if lines <= 0 {
+		lines = defaultServiceLogLines
+	}
+	lines = min(lines, maxServiceLogLines)
+	sm.log.Debug("获取服务日志: %s, 行数=%d", name, lines)
+
+	return sm.command(serviceQueryTimeout, "journalctl", "--no-pager", "-o", "short-iso",
+		"-n", strconv.Itoa(lines), "-u", name)
+}
+
+// ControlService 对服务执行 start/stop/restart/enable/disable，返回操作后的状态
+func (sm *ServiceManager) ControlService(name, action string) (*ServiceStatus, error) {
+	name, err := NormalizeServiceName(name)
+	if err != nil {
+		return nil, err
+	}
+	action = strings.ToLower(strings.TrimSpace(action))
+	if !serviceActions[action] {
+		return nil, fmt.Errorf("不支持的服务操作: %s", action)
+	}
+	sm.log.Info("执行服务操作: %s %s", action, name)
+
+	if _, err := sm.command(serviceActionTimeout, "systemctl", action, "--", name); err != nil {
+		sm.log.Error("服务操作失败: %s %s: %v", action, name, err)
+		return nil, fmt.Errorf("%s %s 失败: %v", action, name, err)
+	}
+	return sm.GetServiceStatus(name)
+}
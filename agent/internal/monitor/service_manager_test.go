@@ -0,0 +1,114 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func TestNormalizeServiceName(t *testing.T) {
+	for input, want := range map[string]string{
+		"nginx":                 "nginx.service",
+		" php7.4-fpm ":          "php7.4-fpm.service",
+		"getty@tty1.service":    "getty@tty1.service",
+		"dev-sda1\\x2dboot.mnt": "dev-sda1\\x2dboot.mnt.service",
+	} {
+		name, err := NormalizeServiceName(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, want, name)
+	}
+	for _, bad := range []string{"", "--now", "-foo", "nginx; reboot", "a b", "../etc", "x/y"} {
+		_, err := NormalizeServiceName(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestParseServiceList(t *testing.T) {
+	units := parseServiceUnits(`cron.service       loaded    active   running Regular background program processing daemon
+● mysql.service    not-found inactive dead    mysql.service
+nginx.service      loaded    failed   failed  A high performance web server
+systemd-journald.socket loaded active running Journal Socket
+`)
+	assert.Len(t, units, 3)
+	assert.Equal(t, "Regular background program processing daemon", units[0].Description)
+	assert.Equal(t, "not-found", units[1].LoadState)
+
+	states := parseUnitFileStates(`cron.service    enabled  enabled
+nginx.service   disabled enabled
+redis.service   disabled enabled
+getty@.service  enabled  enabled
+`)
+	units = mergeUnitFileStates(units, states)
+	var names []string
+	for _, u := range units {
+		names = append(names, u.Name)
+	}
+	// 未加载的 redis 被追加，模板单元不追加，结果按名称排序
+	assert.Equal(t, []string{"cron.service", "mysql.service", "nginx.service", "redis.service"}, names)
+	assert.Equal(t, "disabled", units[2].UnitFileState)
+	assert.Equal(t, "not-loaded", units[3].LoadState)
+}
+
+func TestParseServiceShow(t *testing.T) {
+	status := parseServiceShow(`Id=nginx.service
+Description=A high performance web server
+LoadState=loaded
+ActiveState=active
+SubState=running
+UnitFileState=enabled
+MainPID=812
+ExecMainStartTimestamp=Mon 2026-10-12 08:00:01 UTC
+MemoryCurrent=18874368
+NRestarts=2
+Result=success
+`)
+	assert.Equal(t, "nginx.service", status.Name)
+	assert.Equal(t, 812, status.MainPID)
+	assert.Equal(t, uint64(18874368), status.MemoryBytes)
+	assert.Equal(t, 2, status.Restarts)
+
+	status = parseServiceShow("Id=x.service\nMemoryCurrent=[not set]\n")
+	assert.Zero(t, status.MemoryBytes)
+	status = parseServiceShow("Id=x.service\nMemoryCurrent=18446744073709551615\n")
+	assert.Zero(t, status.MemoryBytes)
+}
+
+func TestControlService(t *testing.T) {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	sm := NewServiceManager(log)
+
+	var calls []string
+	sm.run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, name+" "+strings.Join(args, " "))
+		if args[0] == "start" {
+			return []byte("Job for nginx.service failed because the control process exited with error code."), errors.New("exit status 1")
+		}
+		return []byte("Id=nginx.service\nLoadState=loaded\nActiveState=active\n"), nil
+	}
+
+	status, err := sm.ControlService("nginx", "RESTART")
+	assert.NoError(t, err)
+	assert.Equal(t, "active", status.ActiveState)
+	assert.Equal(t, "systemctl restart -- nginx.service", calls[0])
+
+	// 失败时返回 systemctl 的输出
+	_, err = sm.ControlService("nginx", "start")
+	assert.ErrorContains(t, err, "control process exited")
+
+	_, err = sm.ControlService("nginx", "mask")
+	assert.Error(t, err)
+	_, err = sm.ControlService("--all", "stop")
+	assert.Error(t, err)
+
+	calls = nil
+	_, err = sm.GetServiceLogs("nginx", 100000)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"journalctl --no-pager -o short-iso -n 2000 -u nginx.service"}, calls)
+}
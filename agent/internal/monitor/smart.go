@@ -0,0 +1,320 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// 单块磁盘读取 SMART 信息的最长时间
+	smartDiskTimeout = 30 * time.Second
+	// 单次检查的磁盘数上限，避免挂载了大量磁盘的存储服务器上报数据过大
+	maxSMARTDisks = 64
+)
+
+// smartctl 退出码的位含义（见 smartctl(8) RETURN VALUES）
+const (
+	smartExitCommandLine = 1 << 0 // 命令行参数错误
+	smartExitOpenFailed  = 1 << 1 // 无法打开设备（无权限或设备不支持）
+	smartExitFailing     = 1 << 3 // SMART 自检状态为 FAILING
+	smartExitPrefail     = 1 << 4 // 有预失效属性低于阈值
+)
+
+// SSD 剩余寿命相关的 ATA 属性，归一化值表示剩余寿命百分比，按优先级排列
+var ssdWearAttributes = []int{
+	177, // Wear_Leveling_Count（三星）
+	231, // SSD_Life_Left
+	233, // Media_Wearout_Indicator（Intel）
+	202, // Percent_Lifetime_Remain（美光）
+}
+
+// ErrSmartctlUnavailable 系统没有安装 smartmontools
+var ErrSmartctlUnavailable = errors.New("未安装 smartctl (smartmontools)")
+
+// DiskHealth 单块物理磁盘的 SMART 健康信息
+type DiskHealth struct {
+	Device               string   `json:"device"`
+	Model                string   `json:"model"`
+	Serial               string   `json:"serial"`
+	Type                 string   `json:"type"` // hdd、ssd、nvme
+	CapacityBytes        uint64   `json:"capacity_bytes"`
+	Passed               bool     `json:"passed"`                // SMART 整体自检结果
+	Temperature          int      `json:"temperature"`           // 当前温度(°C)，0 表示未知
+	ReallocatedSectors   int64    `json:"reallocated_sectors"`   // 已重映射扇区数（属性 5）
+	PendingSectors       int64    `json:"pending_sectors"`       // 等待重映射的扇区数（属性 197）
+	UncorrectableSectors int64    `json:"uncorrectable_sectors"` // 无法修复的扇区数（属性 198）
+	MediaErrors          int64    `json:"media_errors"`          // NVMe 介质错误数
+	WearLevel            int      `json:"wear_level"`            // SSD/NVMe 已用寿命百分比，-1 表示未知
+	PowerOnHours         int64    `json:"power_on_hours"`
+	FailingAttributes    []string `json:"failing_attributes,omitempty"` // 低于阈值的属性
+	PredictedFailure     bool     `json:"predicted_failure"`            // SMART 预测磁盘即将故障
+}
+
+// smartScanResult smartctl --scan -j 的输出
+type smartScanResult struct {
+	Devices []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"devices"`
+}
+
+// smartReport smartctl -a -j 输出中用到的字段
+type smartReport struct {
+	Smartctl struct {
+		ExitStatus int `json:"exit_status"`
+		Messages   []struct {
+			String   string `json:"string"`
+			Severity string `json:"severity"`
+		} `json:"messages"`
+	} `json:"smartctl"`
+	Device struct {
+		Name     string `json:"name"`
+		Protocol string `json:"protocol"`
+	} `json:"device"`
+	ModelName    string `json:"model_name"`
+	SerialNumber string `json:"serial_number"`
+	UserCapacity struct {
+		Bytes uint64 `json:"bytes"`
+	} `json:"user_capacity"`
+	RotationRate *int `json:"rotation_rate"` // 0 表示固态盘
+	SmartStatus  *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature struct {
+		Current int `json:"current"`
+	} `json:"temperature"`
+	PowerOnTime struct {
+		Hours int64 `json:"hours"`
+	} `json:"power_on_time"`
+	ATASmartAttributes struct {
+		Table []struct {
+			ID         int    `json:"id"`
+			Name       string `json:"name"`
+			Value      int    `json:"value"`
+			Thresh     int    `json:"thresh"`
+			WhenFailed string `json:"when_failed"`
+			Flags      struct {
+				Prefailure bool `json:"prefailure"`
+			} `json:"flags"`
+			Raw struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+	NVMeHealth *struct {
+		CriticalWarning int   `json:"critical_warning"`
+		Temperature     int   `json:"temperature"`
+		PercentageUsed  int   `json:"percentage_used"`
+		PowerOnHours    int64 `json:"power_on_hours"`
+		MediaErrors     int64 `json:"media_errors"`
+	} `json:"nvme_smart_health_information_log"`
+}
+
+// smartRunner 执行 smartctl 并返回标准输出和退出码，测试时替换
+type smartRunner func(ctx context.Context, args ...string) ([]byte, int, error)
+
+// runSmartctl 执行 smartctl。smartctl 用退出码的各个位表示磁盘状态，非 0 退出码不代表执行失败
+func runSmartctl(ctx context.Context, args ...string) ([]byte, int, error) {
+	if _, err := exec.LookPath("smartctl"); err != nil {
+		return nil, 0, ErrSmartctlUnavailable
+	}
+	output, err := exec.CommandContext(ctx, "smartctl", args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			return output, exitErr.ExitCode(), nil
+		}
+		return nil, 0, err
+	}
+	return output, 0, nil
+}
+
+// readDiskHealth 扫描物理磁盘并逐块读取 SMART 信息。单块磁盘失败只记录在返回的错误列表中，不影响其他磁盘
+func readDiskHealth(run smartRunner) ([]DiskHealth, []error) {
+	ctx, cancel := context.WithTimeout(context.Background(), smartDiskTimeout)
+	output, _, err := run(ctx, "--scan", "-j")
+	cancel()
+	if err != nil {
+		return nil, []error{err}
+	}
+	var scan smartScanResult
+	if err := json.Unmarshal(output, &scan); err != nil {
+		return nil, []error{fmt.Errorf("解析 smartctl --scan 输出失败: %w", err)}
+	}
+	if len(scan.Devices) > maxSMARTDisks {
+		scan.Devices = scan.Devices[:maxSMARTDisks]
+	}
+
+	disks := make([]DiskHealth, 0, len(scan.Devices))
+	var errs []error
+	for _, device := range scan.Devices {
+		args := []string{"-a", "-j"}
+		if device.Type != "" {
+			args = append(args, "-d", device.Type)
+		}
+		args = append(args, device.Name)
+
+		ctx, cancel := context.WithTimeout(context.Background(), smartDiskTimeout)
+		output, exitStatus, err := run(ctx, args...)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", device.Name, err))
+			continue
+		}
+		disk, err := parseSMARTReport(output, exitStatus)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", device.Name, err))
+			continue
+		}
+		if disk.Device == "" {
+			disk.Device = device.Name
+		}
+		disks = append(disks, *disk)
+	}
+	sort.Slice(disks, func(i, j int) bool { return disks[i].Device < disks[j].Device })
+	return disks, errs
+}
+
+// parseSMARTReport 解析 smartctl -a -j 的输出。exitStatus 为 smartctl 的退出码，
+// 低两位表示命令本身失败，第 3、4 位表示磁盘已经或即将故障
+func parseSMARTReport(output []byte, exitStatus int) (*DiskHealth, error) {
+	var report smartReport
+	if err := json.Unmarshal(output, &report); err != nil {
+		return nil, fmt.Errorf("解析 smartctl 输出失败: %w", err)
+	}
+	if report.Smartctl.ExitStatus != 0 {
+		exitStatus = report.Smartctl.ExitStatus
+	}
+	if exitStatus&(smartExitCommandLine|smartExitOpenFailed) != 0 {
+		for _, msg := range report.Smartctl.Messages {
+			if msg.Severity == "error" {
+				return nil, errors.New(msg.String)
+			}
+		}
+		return nil, fmt.Errorf("smartctl 退出码 %d", exitStatus)
+	}
+
+	disk := &DiskHealth{
+		Device:        report.Device.Name,
+		Model:         report.ModelName,
+		Serial:        report.SerialNumber,
+		CapacityBytes: report.UserCapacity.Bytes,
+		Passed:        report.SmartStatus == nil || report.SmartStatus.Passed,
+		Temperature:   report.Temperature.Current,
+		PowerOnHours:  report.PowerOnTime.Hours,
+		WearLevel:     -1,
+	}
+
+	if report.NVMeHealth != nil || strings.EqualFold(report.Device.Protocol, "NVMe") {
+		disk.Type = "nvme"
+		if nvme := report.NVMeHealth; nvme != nil {
+			disk.WearLevel = nvme.PercentageUsed
+			disk.MediaErrors = nvme.MediaErrors
+			if disk.Temperature == 0 {
+				disk.Temperature = nvme.Temperature
+			}
+			if disk.PowerOnHours == 0 {
+				disk.PowerOnHours = nvme.PowerOnHours
+			}
+			// critical_warning 的任意一位（备用空间不足、温度过高、可靠性下降、只读等）都视为即将故障
+			if nvme.CriticalWarning != 0 {
+				disk.FailingAttributes = append(disk.FailingAttributes, fmt.Sprintf("critical_warning=0x%02x", nvme.CriticalWarning))
+			}
+		}
+	} else if report.RotationRate != nil && *report.RotationRate == 0 {
+		disk.Type = "ssd"
+	} else {
+		disk.Type = "hdd"
+	}
+
+	wear := make(map[int]int)
+	for _, attr := range report.ATASmartAttributes.Table {
+		switch attr.ID {
+		case 5:
+			disk.ReallocatedSectors = attr.Raw.Value
+		case 197:
+			disk.PendingSectors = attr.Raw.Value
+		case 198:
+			disk.UncorrectableSectors = attr.Raw.Value
+		}
+		for _, id := range ssdWearAttributes {
+			if attr.ID == id {
+				wear[id] = attr.Value
+			}
+		}
+		// 只有预失效属性低于阈值才预示故障，寿命类（old_age）属性低于阈值只表示老化
+		if attr.Flags.Prefailure && attr.WhenFailed == "now" {
+			disk.FailingAttributes = append(disk.FailingAttributes, attr.Name)
+		}
+	}
+	if disk.Type == "ssd" {
+		for _, id := range ssdWearAttributes {
+			if value, ok := wear[id]; ok && value <= 100 {
+				disk.WearLevel = 100 - value
+				break
+			}
+		}
+	}
+
+	disk.PredictedFailure = !disk.Passed || len(disk.FailingAttributes) > 0 ||
+		exitStatus&(smartExitFailing|smartExitPrefail) != 0
+	return disk, nil
+}
+
+// SetSMARTInterval 设置 SMART 磁盘健康检查间隔，0 表示不检查
+func (m *Monitor) SetSMARTInterval(interval time.Duration) {
+	m.smartMu.Lock()
+	defer m.smartMu.Unlock()
+	m.smartInterval = interval
+}
+
+// collectDiskHealth 返回上次检查以来新读取到的磁盘健康信息，没有新结果时返回 nil。
+// smartctl 逐块读取磁盘较慢，到期后在后台检查，结果随下一次监控数据上报
+func (m *Monitor) collectDiskHealth() []DiskHealth {
+	m.smartMu.Lock()
+	defer m.smartMu.Unlock()
+
+	if m.smartInterval <= 0 {
+		return nil
+	}
+	if m.smartResult != nil {
+		disks := m.smartResult
+		m.smartResult = nil
+		return disks
+	}
+	if m.smartRunning || time.Since(m.smartCheckedAt) < m.smartInterval {
+		return nil
+	}
+
+	m.smartRunning = true
+	m.smartCheckedAt = time.Now()
+	go func() {
+		disks, errs := readDiskHealth(runSmartctl)
+		if len(errs) == 1 && errors.Is(errs[0], ErrSmartctlUnavailable) {
+			m.log.Debug("跳过 SMART 检查: %v", errs[0])
+		} else {
+			for _, err := range errs {
+				m.log.Warn("读取磁盘 SMART 信息失败: %v", err)
+			}
+		}
+		for _, disk := range disks {
+			if disk.PredictedFailure {
+				m.log.Warn("磁盘 %s (%s) SMART 预测即将故障: %s", disk.Device, disk.Model, strings.Join(disk.FailingAttributes, ", "))
+			}
+		}
+
+		m.smartMu.Lock()
+		defer m.smartMu.Unlock()
+		m.smartRunning = false
+		if len(disks) > 0 {
+			m.smartResult = disks
+		}
+	}()
+	return nil
+}
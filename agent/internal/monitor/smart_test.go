@@ -0,0 +1,107 @@
+package monitor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const smartSATAReport = `{
+  "smartctl": {"exit_status": 0},
+  "device": {"name": "/dev/sda", "type": "sat", "protocol": "ATA"},
+  "model_name": "Samsung SSD 860 EVO 500GB",
+  "serial_number": "S3Z1NB0K123456",
+  "user_capacity": {"bytes": 500107862016},
+  "rotation_rate": 0,
+  "smart_status": {"passed": true},
+  "temperature": {"current": 34},
+  "power_on_time": {"hours": 21034},
+  "ata_smart_attributes": {"table": [
+    {"id": 5, "name": "Reallocated_Sector_Ct", "value": 100, "thresh": 10, "when_failed": "", "flags": {"prefailure": true}, "raw": {"value": 8}},
+    {"id": 177, "name": "Wear_Leveling_Count", "value": 93, "thresh": 0, "when_failed": "", "flags": {"prefailure": true}, "raw": {"value": 61}},
+    {"id": 197, "name": "Current_Pending_Sector", "value": 100, "thresh": 0, "when_failed": "", "flags": {"prefailure": false}, "raw": {"value": 2}}
+  ]}
+}`
+
+const smartFailingHDDReport = `{
+  "smartctl": {"exit_status": 24},
+  "device": {"name": "/dev/sdb", "type": "sat", "protocol": "ATA"},
+  "model_name": "WDC WD40EFRX-68N32N0",
+  "rotation_rate": 5400,
+  "smart_status": {"passed": false},
+  "temperature": {"current": 41},
+  "ata_smart_attributes": {"table": [
+    {"id": 5, "name": "Reallocated_Sector_Ct", "value": 1, "thresh": 140, "when_failed": "now", "flags": {"prefailure": true}, "raw": {"value": 3952}},
+    {"id": 194, "name": "Temperature_Celsius", "value": 20, "thresh": 30, "when_failed": "now", "flags": {"prefailure": false}, "raw": {"value": 41}}
+  ]}
+}`
+
+const smartNVMeReport = `{
+  "smartctl": {"exit_status": 0},
+  "device": {"name": "/dev/nvme0", "type": "nvme", "protocol": "NVMe"},
+  "model_name": "WDC WDS100T2B0C",
+  "smart_status": {"passed": true},
+  "nvme_smart_health_information_log": {"critical_warning": 4, "temperature": 45, "percentage_used": 12, "power_on_hours": 3000, "media_errors": 0}
+}`
+
+func TestParseSMARTReport(t *testing.T) {
+	disk, err := parseSMARTReport([]byte(smartSATAReport), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "ssd", disk.Type)
+	assert.Equal(t, 34, disk.Temperature)
+	assert.Equal(t, int64(8), disk.ReallocatedSectors)
+	assert.Equal(t, int64(2), disk.PendingSectors)
+	assert.Equal(t, 7, disk.WearLevel)
+	assert.False(t, disk.PredictedFailure)
+
+	// 只有预失效属性计入故障属性，温度这类寿命属性低于阈值不算
+	disk, err = parseSMARTReport([]byte(smartFailingHDDReport), 24)
+	assert.NoError(t, err)
+	assert.Equal(t, "hdd", disk.Type)
+	assert.Equal(t, -1, disk.WearLevel)
+	assert.Equal(t, []string{"Reallocated_Sector_Ct"}, disk.FailingAttributes)
+	assert.True(t, disk.PredictedFailure)
+
+	disk, err = parseSMARTReport([]byte(smartNVMeReport), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "nvme", disk.Type)
+	assert.Equal(t, 45, disk.Temperature)
+	assert.Equal(t, 12, disk.WearLevel)
+	assert.Equal(t, int64(3000), disk.PowerOnHours)
+	assert.True(t, disk.PredictedFailure)
+
+	// 无法打开设备时返回 smartctl 的错误信息
+	_, err = parseSMARTReport([]byte(`{"smartctl": {"exit_status": 2, "messages": [{"string": "Smartctl open device: /dev/sda failed: Permission denied", "severity": "error"}]}}`), 2)
+	assert.ErrorContains(t, err, "Permission denied")
+}
+
+func TestReadDiskHealth(t *testing.T) {
+	var calls []string
+	run := func(ctx context.Context, args ...string) ([]byte, int, error) {
+		calls = append(calls, strings.Join(args, " "))
+		switch args[len(args)-1] {
+		case "-j":
+			return []byte(`{"devices": [{"name": "/dev/sdb", "type": "sat"}, {"name": "/dev/sda", "type": "sat"}, {"name": "/dev/sdc", "type": "scsi"}]}`), 0, nil
+		case "/dev/sda":
+			return []byte(smartSATAReport), 0, nil
+		case "/dev/sdb":
+			return []byte(smartFailingHDDReport), 24, nil
+		default:
+			return []byte(`{"smartctl": {"exit_status": 2}}`), 2, nil
+		}
+	}
+
+	disks, errs := readDiskHealth(run)
+	assert.Len(t, disks, 2)
+	assert.Equal(t, "/dev/sda", disks[0].Device)
+	assert.True(t, disks[1].PredictedFailure)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "-a -j -d sat /dev/sdb", calls[1])
+
+	_, errs = readDiskHealth(func(ctx context.Context, args ...string) ([]byte, int, error) {
+		return nil, 0, ErrSmartctlUnavailable
+	})
+	assert.ErrorIs(t, errs[0], ErrSmartctlUnavailable)
+}
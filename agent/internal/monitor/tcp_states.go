@@ -0,0 +1,21 @@
+package monitor
+
+import (
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// tcpStateCounts 按状态统计 TCP 连接数，如 ESTABLISHED、TIME_WAIT、CLOSE_WAIT
+func tcpStateCounts(conns []net.ConnectionStat) map[string]int {
+	if len(conns) == 0 {
+		return nil
+	}
+	counts := make(map[string]int)
+	for _, conn := range conns {
+		status := conn.Status
+		if status == "" || status == "NONE" {
+			status = "UNKNOWN"
+		}
+		counts[status]++
+	}
+	return counts
+}
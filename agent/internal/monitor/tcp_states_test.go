@@ -0,0 +1,22 @@
+package monitor
+
+import (
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/net"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTCPStateCounts(t *testing.T) {
+	assert.Nil(t, tcpStateCounts(nil))
+
+	counts := tcpStateCounts([]net.ConnectionStat{
+		{Status: "ESTABLISHED"},
+		{Status: "ESTABLISHED"},
+		{Status: "TIME_WAIT"},
+		{Status: "LISTEN"},
+		{Status: "NONE"},
+		{Status: ""},
+	})
+	assert.Equal(t, map[string]int{"ESTABLISHED": 2, "TIME_WAIT": 1, "LISTEN": 1, "UNKNOWN": 2}, counts)
+}
@@ -0,0 +1,108 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// 单次上报的进程排行数上限，避免监控数据过大
+const maxTopProcesses = 20
+
+// TopProcess 监控数据中 CPU 或内存占用排名靠前的进程
+type TopProcess struct {
+	PID        int32   `json:"pid"`
+	Name       string  `json:"name"`
+	CPUPercent float64 `json:"cpu_percent"` // 采样窗口内的平均 CPU 使用率，与 top 一致按单核计，多线程进程可超过 100
+	MemoryRSS  uint64  `json:"memory_rss"`  // 常驻内存(bytes)
+}
+
+// SetTopProcesses 设置每次上报携带的 CPU 和内存排行进程数，0 表示不上报
+func (m *Monitor) SetTopProcesses(n int) {
+	m.topMu.Lock()
+	defer m.topMu.Unlock()
+	m.topProcesses = min(max(n, 0), maxTopProcesses)
+	if m.topProcesses == 0 {
+		m.procCPUTimes = nil
+	}
+}
+
+// collectTopProcesses 返回 CPU 和内存占用最高的进程。
+// CPU 使用率按两次采集之间累计 CPU 时间的差值计算，开启后的第一次采集只建立基线，不返回 CPU 排行
+func (m *Monitor) collectTopProcesses(procs []*process.Process) (topCPU, topMemory []TopProcess) {
+	m.topMu.Lock()
+	defer m.topMu.Unlock()
+
+	if m.topProcesses <= 0 || len(procs) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	samples := make([]TopProcess, 0, len(procs))
+	cpuTimes := make(map[int32]float64, len(procs))
+	byPID := make(map[int32]*process.Process, len(procs))
+	for _, p := range procs {
+		sample := TopProcess{PID: p.Pid}
+		// 扫描期间已退出的进程读取失败，直接跳过
+		if times, err := p.Times(); err == nil {
+			cpuTimes[p.Pid] = times.User + times.System
+		}
+		if mem, err := p.MemoryInfo(); err == nil {
+			sample.MemoryRSS = mem.RSS
+		}
+		samples = append(samples, sample)
+		byPID[p.Pid] = p
+	}
+
+	if m.procCPUTimes != nil {
+		elapsed := now.Sub(m.procSampledAt).Seconds()
+		for i := range samples {
+			samples[i].CPUPercent = processCPUPercent(m.procCPUTimes, cpuTimes, samples[i].PID, elapsed)
+		}
+		topCPU = rankTopProcesses(samples, m.topProcesses, func(p TopProcess) float64 { return p.CPUPercent })
+	}
+	m.procCPUTimes = cpuTimes
+	m.procSampledAt = now
+
+	topMemory = rankTopProcesses(samples, m.topProcesses, func(p TopProcess) float64 { return float64(p.MemoryRSS) })
+
+	// 只为上榜的进程读取进程名
+	for _, list := range [][]TopProcess{topCPU, topMemory} {
+		for i := range list {
+			if name, err := byPID[list[i].PID].Name(); err == nil {
+				list[i].Name = name
+			}
+		}
+	}
+	return topCPU, topMemory
+}
+
+// processCPUPercent 根据两次采集的累计 CPU 时间（秒）计算采样窗口内的 CPU 使用率。
+// 新出现的进程没有基线，PID 被复用时累计时间会变小，都按 0 处理
+func processCPUPercent(prev, cur map[int32]float64, pid int32, elapsed float64) float64 {
+	before, ok := prev[pid]
+	after, ok2 := cur[pid]
+	if !ok || !ok2 || elapsed <= 0 || after < before {
+		return 0
+	}
+	return (after - before) / elapsed * 100
+}
+
+// rankTopProcesses 按 value 从高到低取前 n 个进程，值为 0 的进程不上榜
+func rankTopProcesses(samples []TopProcess, n int, value func(TopProcess) float64) []TopProcess {
+	ranked := make([]TopProcess, 0, len(samples))
+	for _, sample := range samples {
+		if value(sample) > 0 {
+			ranked = append(ranked, sample)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return value(ranked[i]) > value(ranked[j]) })
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	if len(ranked) == 0 {
+		return nil
+	}
+	return ranked
+}
@@ -0,0 +1,57 @@
+package monitor
+
+import (
+	"os"
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func TestRankTopProcesses(t *testing.T) {
+	prev := map[int32]float64{1: 10, 2: 5, 3: 100}
+	cur := map[int32]float64{1: 13, 2: 5, 3: 1, 4: 50}
+	// 30 秒内用了 3 秒 CPU
+	assert.InDelta(t, 10, processCPUPercent(prev, cur, 1, 30), 0.001)
+	// 新进程和 PID 复用都按 0 处理
+	assert.Zero(t, processCPUPercent(prev, cur, 4, 30))
+	assert.Zero(t, processCPUPercent(prev, cur, 3, 30))
+
+	samples := []TopProcess{
+		{PID: 1, CPUPercent: 10, MemoryRSS: 300},
+		{PID: 2, CPUPercent: 0, MemoryRSS: 900},
+		{PID: 3, CPUPercent: 150, MemoryRSS: 100},
+	}
+	top := rankTopProcesses(samples, 5, func(p TopProcess) float64 { return p.CPUPercent })
+	assert.Equal(t, []int32{3, 1}, []int32{top[0].PID, top[1].PID})
+	top = rankTopProcesses(samples, 1, func(p TopProcess) float64 { return float64(p.MemoryRSS) })
+	assert.Equal(t, int32(2), top[0].PID)
+	assert.Nil(t, rankTopProcesses(nil, 5, func(p TopProcess) float64 { return p.CPUPercent }))
+}
+
+func TestCollectTopProcesses(t *testing.T) {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	m := New(log)
+	self, err := process.NewProcess(int32(os.Getpid()))
+	assert.NoError(t, err)
+	procs := []*process.Process{self}
+
+	// 未开启时不采集
+	topCPU, topMemory := m.collectTopProcesses(procs)
+	assert.Nil(t, topCPU)
+	assert.Nil(t, topMemory)
+
+	m.SetTopProcesses(100)
+	assert.Equal(t, maxTopProcesses, m.topProcesses)
+
+	// 第一次只建立 CPU 基线
+	topCPU, topMemory = m.collectTopProcesses(procs)
+	assert.Nil(t, topCPU)
+	if assert.Len(t, topMemory, 1) {
+		assert.Equal(t, self.Pid, topMemory[0].PID)
+		assert.NotEmpty(t, topMemory[0].Name)
+	}
+	assert.Contains(t, m.procCPUTimes, self.Pid)
+}
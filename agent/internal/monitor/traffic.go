@@ -0,0 +1,168 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/host"
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// 自动选择网卡时排除的虚拟网卡前缀：回环、容器网桥、veth、虚拟机网桥及常见 CNI 插件，
+// 这些网卡上的流量要么不出本机，要么已经计入物理网卡，累加会重复统计
+var virtualInterfacePrefixes = []string{
+	"lo", "docker", "br-", "veth", "virbr", "vnet", "cni", "flannel", "cali", "vxlan", "kube-", "podman", "tunl",
+}
+
+// isVirtualInterface 判断网卡是否应在自动统计时排除
+func isVirtualInterface(name string) bool {
+	for _, prefix := range virtualInterfacePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectTrafficCounters 返回用于统计累计流量的计数器。
+// 指定了网卡时只取该网卡；否则累加所有非虚拟网卡
+func selectTrafficCounters(stats []net.IOCountersStat, iface string) (recv, sent uint64, err error) {
+	if iface != "" {
+		for _, s := range stats {
+			if s.Name == iface {
+				return s.BytesRecv, s.BytesSent, nil
+			}
+		}
+		return 0, 0, fmt.Errorf("未找到网卡 %s", iface)
+	}
+	for _, s := range stats {
+		if isVirtualInterface(s.Name) {
+			continue
+		}
+		recv += s.BytesRecv
+		sent += s.BytesSent
+	}
+	return recv, sent, nil
+}
+
+// trafficInterfaceName 返回用于日志的网卡名称
+func trafficInterfaceName(iface string) string {
+	if iface == "" {
+		return "自动"
+	}
+	return iface
+}
+
+// counterDelta 计算两次读数之间的增量。
+// 计数器回退说明网卡被重置（重启、驱动重载等），计数从 0 重新开始，当前值即为重置后的流量
+func counterDelta(last, current uint64) (delta uint64, reset bool) {
+	if current >= last {
+		return current - last, false
+	}
+	return current, true
+}
+
+// trafficState 持久化的流量基线，Agent 重启后据此补上停机期间的流量
+type trafficState struct {
+	BootTime  uint64    `json:"boot_time"` // 记录基线时的系统启动时间，用于判断是否发生过重启
+	Interface string    `json:"interface"`
+	BytesRecv uint64    `json:"bytes_recv"`
+	BytesSent uint64    `json:"bytes_sent"`
+	SavedAt   time.Time `json:"saved_at"`
+}
+
+// SetTrafficInterface 设置统计累计流量的网卡，为空表示自动选择所有物理网卡。
+// 切换网卡后计数器不可比较，重新建立基线
+func (m *Monitor) SetTrafficInterface(iface string) {
+	iface = strings.TrimSpace(iface)
+	if iface == m.trafficInterface {
+		return
+	}
+	m.trafficInterface = iface
+	m.hasLastReport = false
+}
+
+// SetTrafficStatePath 设置流量基线的持久化文件，为空表示不持久化
+func (m *Monitor) SetTrafficStatePath(path string) {
+	m.trafficStatePath = path
+}
+
+// restoreTrafficBaseline 首次采样时读取上次保存的基线，返回 Agent 停机期间产生的流量。
+//   - 系统未重启：计数器连续，当前值减去保存的基线即为停机期间的流量
+//   - 系统已重启：计数器从 0 开始，当前值即为开机以来的流量（关机前最后一个上报周期内的流量无法找回）
+//   - 没有基线或网卡已变更：无法判断，从当前值开始统计
+func (m *Monitor) restoreTrafficBaseline(recv, sent uint64) (inDelta, outDelta uint64) {
+	bootTime, err := host.BootTime()
+	if err != nil {
+		m.log.Debug("获取系统启动时间失败: %v", err)
+	}
+	m.trafficBootTime = bootTime
+
+	if m.trafficStatePath == "" {
+		return 0, 0
+	}
+	data, err := os.ReadFile(m.trafficStatePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			m.log.Warn("读取流量基线失败: %v", err)
+		}
+		return 0, 0
+	}
+	var state trafficState
+	if err := json.Unmarshal(data, &state); err != nil {
+		m.log.Warn("解析流量基线失败: %v", err)
+		return 0, 0
+	}
+	if state.Interface != m.trafficInterface || bootTime == 0 {
+		return 0, 0
+	}
+
+	if state.BootTime != bootTime {
+		m.log.Info("检测到系统重启，计入开机以来的流量 (入站=%d B, 出站=%d B)", recv, sent)
+		return recv, sent
+	}
+	inDelta, inReset := counterDelta(state.BytesRecv, recv)
+	outDelta, outReset := counterDelta(state.BytesSent, sent)
+	if inReset || outReset {
+		m.log.Warn("检测到网卡计数器重置，按重置后的流量计入")
+	}
+	m.log.Info("恢复流量基线 (保存于 %s)，计入停机期间的流量 (入站=%d B, 出站=%d B)",
+		state.SavedAt.Format(time.RFC3339), inDelta, outDelta)
+	return inDelta, outDelta
+}
+
+// saveTrafficBaseline 保存当前基线。每次上报后都保存，
+// 保证重启后计入的流量不会与已上报的部分重复
+func (m *Monitor) saveTrafficBaseline() {
+	if m.trafficStatePath == "" {
+		return
+	}
+	data, err := json.Marshal(trafficState{
+		BootTime:  m.trafficBootTime,
+		Interface: m.trafficInterface,
+		BytesRecv: m.lastReportBytesRecv,
+		BytesSent: m.lastReportBytesSent,
+		SavedAt:   m.lastReportTime,
+	})
+	if err != nil {
+		return
+	}
+
+	// 先写临时文件再重命名，避免写入中途退出留下损坏的文件
+	if err := os.MkdirAll(filepath.Dir(m.trafficStatePath), 0755); err != nil {
+		m.log.Warn("保存流量基线失败: %v", err)
+		return
+	}
+	tmp := m.trafficStatePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		m.log.Warn("保存流量基线失败: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, m.trafficStatePath); err != nil {
+		m.log.Warn("保存流量基线失败: %v", err)
+	}
+}
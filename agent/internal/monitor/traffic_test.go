@@ -0,0 +1,90 @@
+package monitor
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/host"
+	"github.com/shirou/gopsutil/v4/net"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func TestSelectTrafficCounters(t *testing.T) {
+	stats := []net.IOCountersStat{
+		{Name: "lo", BytesRecv: 1000, BytesSent: 1000},
+		{Name: "eth0", BytesRecv: 100, BytesSent: 10},
+		{Name: "eth1", BytesRecv: 200, BytesSent: 20},
+		{Name: "docker0", BytesRecv: 500, BytesSent: 500},
+		{Name: "veth12ab", BytesRecv: 500, BytesSent: 500},
+	}
+
+	// 自动模式累加物理网卡
+	recv, sent, err := selectTrafficCounters(stats, "")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(300), recv)
+	assert.Equal(t, uint64(30), sent)
+
+	recv, sent, err = selectTrafficCounters(stats, "eth1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(200), recv)
+	assert.Equal(t, uint64(20), sent)
+
+	_, _, err = selectTrafficCounters(stats, "wlan0")
+	assert.Error(t, err)
+}
+
+func TestCounterDelta(t *testing.T) {
+	delta, reset := counterDelta(100, 150)
+	assert.Equal(t, uint64(50), delta)
+	assert.False(t, reset)
+
+	// 计数器回退时按重置后的值计入
+	delta, reset = counterDelta(100, 30)
+	assert.Equal(t, uint64(30), delta)
+	assert.True(t, reset)
+}
+
+func TestRestoreTrafficBaseline(t *testing.T) {
+	bootTime, err := host.BootTime()
+	if err != nil || bootTime == 0 {
+		t.Skip("无法获取系统启动时间")
+	}
+	log, err := logger.New("", "info")
+	assert.NoError(t, err)
+	statePath := filepath.Join(t.TempDir(), "traffic_state.json")
+
+	// 没有基线文件时不补计
+	m := New(log)
+	m.SetTrafficStatePath(statePath)
+	in, out := m.restoreTrafficBaseline(1000, 2000)
+	assert.Zero(t, in)
+	assert.Zero(t, out)
+	m.lastReportBytesRecv, m.lastReportBytesSent = 1000, 2000
+	m.saveTrafficBaseline()
+
+	// 未重启：补计停机期间的增量
+	m = New(log)
+	m.SetTrafficStatePath(statePath)
+	in, out = m.restoreTrafficBaseline(1500, 2100)
+	assert.Equal(t, uint64(500), in)
+	assert.Equal(t, uint64(100), out)
+
+	// 切换网卡后基线不可比较
+	m = New(log)
+	m.SetTrafficStatePath(statePath)
+	m.SetTrafficInterface("eth0")
+	in, out = m.restoreTrafficBaseline(1500, 2100)
+	assert.Zero(t, in)
+	assert.Zero(t, out)
+
+	// 已重启：计入开机以来的全部流量
+	m = New(log)
+	m.SetTrafficStatePath(statePath)
+	m.trafficBootTime = bootTime - 3600
+	m.lastReportBytesRecv, m.lastReportBytesSent = 1000, 2000
+	m.saveTrafficBaseline()
+	in, out = m.restoreTrafficBaseline(300, 400)
+	assert.Equal(t, uint64(300), in)
+	assert.Equal(t, uint64(400), out)
+}
@@ -0,0 +1,413 @@
+package monitor
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	stdnet "net"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// 面板下发的检查间隔和超时的下限与上限
+	minUptimeInterval = 10 * time.Second
+	maxUptimeTimeout  = 60 * time.Second
+	// 两次上报之间最多暂存的检查结果数，断线较久时丢弃最早的结果
+	maxUptimeResults = 500
+	// 错误信息的最大长度
+	maxUptimeErrorLen = 500
+)
+
+// pingTimePattern 从 ping 输出中提取往返时间，兼容 "time=12.3 ms"、"time<1ms" 及中文系统的 "时间=12ms"
+var pingTimePattern = regexp.MustCompile(`[=<]\s*([0-9.]+)\s*ms`)
+
+// UptimeCheck 面板分配给本 Agent 执行的可用性检查
+type UptimeCheck struct {
+	ID             uint   `json:"id"`
+	Type           string `json:"type"`   // http, tcp, icmp, tls
+	Target         string `json:"target"` // http 为 URL，tcp 和 tls 为 host:port，icmp 为主机名或 IP
+	Interval       int    `json:"interval"`
+	Timeout        int    `json:"timeout"`
+	ExpectedStatus int    `json:"expected_status"`         // http 期望的状态码，0 表示 2xx/3xx 均视为正常
+	ServerName     string `json:"server_name,omitempty"`   // tls 的 SNI 和校验使用的主机名，为空时使用目标主机
+	SkipVerify     bool   `json:"skip_verify,omitempty"`   // tls 不校验证书链和主机名，仍检查有效期
+	CertMinDays    int    `json:"cert_min_days,omitempty"` // tls 证书剩余天数少于该值时视为失败
+}
+
+// UptimeResult 单次检查结果，随下一次监控数据上报
+type UptimeResult struct {
+	CheckID        uint   `json:"check_id"`
+	Timestamp      int64  `json:"timestamp"` // Unix 毫秒
+	Success        bool   `json:"success"`
+	ResponseTimeMs int64  `json:"response_time_ms"`
+	StatusCode     int    `json:"status_code,omitempty"`
+	Error          string `json:"error,omitempty"`
+
+	TLS *UptimeTLSInfo `json:"tls,omitempty"` // tls 检查握手成功时的协议和证书链
+}
+
+// UptimeTLSInfo tls 检查握手得到的协议版本、密码套件和证书链（第一张为服务器证书）
+type UptimeTLSInfo struct {
+	Version     string                 `json:"version"`
+	CipherSuite string                 `json:"cipher_suite"`
+	ServerName  string                 `json:"server_name"`
+	VerifyError string                 `json:"verify_error,omitempty"`
+	Chain       []UptimeTLSCertificate `json:"chain"`
+}
+
+// UptimeTLSCertificate 证书链中的一张证书
+type UptimeTLSCertificate struct {
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	DNSNames     []string  `json:"dns_names,omitempty"`
+	SerialNumber string    `json:"serial_number"`
+	Fingerprint  string    `json:"fingerprint"` // SHA-256，十六进制
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+}
+
+// uptimeState 可用性检查的调度状态
+type uptimeState struct {
+	mu        sync.Mutex
+	checks    []UptimeCheck
+	lastRun   map[uint]time.Time
+	running   map[uint]bool
+	results   []UptimeResult
+	started   bool
+	policy    *probeTargetPolicy // 探测目标策略，为 nil 时所有检查都失败
+	policyErr error
+}
+
+// SetUptimeChecks 更新面板分配的检查列表和探测目标策略，首次设置时启动后台调度。
+// 面板保存检查时已按策略校验，但主机名在 Agent 所在网络中的解析结果可能不同，
+// 因此每次连接前都按策略检查实际连接的地址。policy 为 nil（旧版面板）时只禁止内置网段；
+// 策略无法解析时所有检查都失败，不退回到宽松的默认策略
+func (m *Monitor) SetUptimeChecks(checks []UptimeCheck, policy *ProbePolicy) {
+	s := &m.uptime
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checks = checks
+	s.policy, s.policyErr = defaultProbePolicy, nil
+	if policy != nil {
+		s.policy, s.policyErr = newProbeTargetPolicy(*policy)
+	}
+	if s.lastRun == nil {
+		s.lastRun = make(map[uint]time.Time)
+		s.running = make(map[uint]bool)
+	}
+	keep := make(map[uint]bool, len(checks))
+	for _, check := range checks {
+		keep[check.ID] = true
+	}
+	for id := range s.lastRun {
+		if !keep[id] {
+			delete(s.lastRun, id)
+		}
+	}
+
+	if !s.started && len(checks) > 0 {
+		s.started = true
+		go m.runUptimeLoop()
+	}
+}
+
+// runUptimeLoop 每 5 秒执行一次到期的检查
+func (m *Monitor) runUptimeLoop() {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		m.runDueUptimeChecks(time.Now())
+	}
+}
+
+// runDueUptimeChecks 并发执行到期的检查，同一检查上一次尚未结束时跳过
+func (m *Monitor) runDueUptimeChecks(now time.Time) {
+	s := &m.uptime
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, check := range s.checks {
+		interval := max(time.Duration(check.Interval)*time.Second, minUptimeInterval)
+		if s.running[check.ID] || now.Sub(s.lastRun[check.ID]) < interval {
+			continue
+		}
+		s.running[check.ID] = true
+		s.lastRun[check.ID] = now
+		go func(check UptimeCheck, policy *probeTargetPolicy, policyErr error) {
+			var result UptimeResult
+			if policyErr != nil {
+				result = UptimeResult{CheckID: check.ID, Timestamp: time.Now().UnixMilli(), Error: "探测目标策略无效: " + policyErr.Error()}
+			} else {
+				result = probeUptime(check, policy)
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			delete(s.running, check.ID)
+			s.results = append(s.results, result)
+			if len(s.results) > maxUptimeResults {
+				s.results = s.results[len(s.results)-maxUptimeResults:]
+			}
+		}(check, s.policy, s.policyErr)
+	}
+}
+
+// collectUptimeResults 取出上次上报以来的检查结果
+func (m *Monitor) collectUptimeResults() []UptimeResult {
+	s := &m.uptime
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := s.results
+	s.results = nil
+	return results
+}
+
+// probeUptime 按探测目标策略执行一次检查
+func probeUptime(check UptimeCheck, policy *probeTargetPolicy) UptimeResult {
+	result := UptimeResult{CheckID: check.ID, Timestamp: time.Now().UnixMilli()}
+
+	timeout := time.Duration(check.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	timeout = min(timeout, maxUptimeTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var elapsed time.Duration
+	var err error
+	switch check.Type {
+	case "http":
+		result.StatusCode, elapsed, err = probeUptimeHTTP(ctx, check, policy)
+	case "tcp":
+		elapsed, err = probeUptimeTCP(ctx, check.Target, policy)
+	case "icmp":
+		elapsed, err = probeUptimeICMP(ctx, check.Target, timeout, policy)
+	case "tls":
+		result.TLS, elapsed, err = probeUptimeTLS(ctx, check, policy)
+	default:
+		err = fmt.Errorf("不支持的检查类型: %s", check.Type)
+	}
+
+	result.ResponseTimeMs = elapsed.Milliseconds()
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("超过 %d 秒未响应", int(timeout/time.Second))
+		}
+		msg := []rune(err.Error())
+		result.Error = string(msg[:min(len(msg), maxUptimeErrorLen)])
+		return result
+	}
+	result.Success = true
+	return result
+}
+
+// uptimeDialer 返回连接前按探测目标策略检查实际连接的地址和端口的 Dialer，
+// DNS 解析结果和 HTTP 重定向的目标都会经过检查
+func uptimeDialer(policy *probeTargetPolicy) *stdnet.Dialer {
+	return &stdnet.Dialer{
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, portStr, err := stdnet.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := stdnet.ParseIP(host)
+			if ip == nil {
+				return fmt.Errorf("无效的目标地址 %s", address)
+			}
+			port, err := strconv.Atoi(portStr)
+			if err != nil || port < 1 || port > 65535 {
+				return fmt.Errorf("无效的目标端口 %s", portStr)
+			}
+			return policy.check(ip, port)
+		},
+	}
+}
+
+// probeUptimeHTTP 发起 GET 请求，返回状态码和收到响应头的耗时
+func probeUptimeHTTP(ctx context.Context, check UptimeCheck, policy *probeTargetPolicy) (int, time.Duration, error) {
+	transport := &http.Transport{
+		Proxy:             nil,
+		DialContext:       uptimeDialer(policy).DialContext,
+		DisableKeepAlives: true,
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{Transport: transport}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, check.Target, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("User-Agent", "BetterMonitor-Uptime/1.0")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	elapsed := time.Since(start)
+	if err != nil {
+		return 0, elapsed, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if check.ExpectedStatus != 0 {
+		if resp.StatusCode != check.ExpectedStatus {
+			return resp.StatusCode, elapsed, fmt.Errorf("状态码 %d，期望 %d", resp.StatusCode, check.ExpectedStatus)
+		}
+	} else if resp.StatusCode >= 400 {
+		return resp.StatusCode, elapsed, fmt.Errorf("状态码 %d", resp.StatusCode)
+	}
+	return resp.StatusCode, elapsed, nil
+}
+
+// probeUptimeTCP 建立 TCP 连接，返回连接耗时
+func probeUptimeTCP(ctx context.Context, target string, policy *probeTargetPolicy) (time.Duration, error) {
+	start := time.Now()
+	conn, err := uptimeDialer(policy).DialContext(ctx, "tcp", target)
+	elapsed := time.Since(start)
+	if err != nil {
+		return elapsed, err
+	}
+	conn.Close()
+	return elapsed, nil
+}
+
+// probeUptimeTLS 建立 TLS 连接，返回握手得到的协议、密码套件和证书链以及连接加握手的耗时。
+// 证书校验失败、已过期或剩余天数不足时返回错误，握手成功时仍返回证书信息
+func probeUptimeTLS(ctx context.Context, check UptimeCheck, policy *probeTargetPolicy) (*UptimeTLSInfo, time.Duration, error) {
+	host, _, err := stdnet.SplitHostPort(check.Target)
+	if err != nil {
+		return nil, 0, err
+	}
+	serverName := check.ServerName
+	if serverName == "" {
+		serverName = host
+	}
+
+	start := time.Now()
+	rawConn, err := uptimeDialer(policy).DialContext(ctx, "tcp", check.Target)
+	if err != nil {
+		return nil, time.Since(start), err
+	}
+	defer rawConn.Close()
+	// 证书在握手后单独校验，校验失败时仍能拿到证书链；IP 地址不能作为 SNI 发送
+	config := &tls.Config{InsecureSkipVerify: true}
+	if stdnet.ParseIP(serverName) == nil {
+		config.ServerName = serverName
+	}
+	conn := tls.Client(rawConn, config)
+	err = conn.HandshakeContext(ctx)
+	elapsed := time.Since(start)
+	if err != nil {
+		return nil, elapsed, fmt.Errorf("TLS 握手失败: %v", err)
+	}
+
+	state := conn.ConnectionState()
+	info := &UptimeTLSInfo{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ServerName:  serverName,
+	}
+	for _, cert := range state.PeerCertificates {
+		sum := sha256.Sum256(cert.Raw)
+		info.Chain = append(info.Chain, UptimeTLSCertificate{
+			Subject:      cert.Subject.String(),
+			Issuer:       cert.Issuer.String(),
+			DNSNames:     cert.DNSNames,
+			SerialNumber: cert.SerialNumber.Text(16),
+			Fingerprint:  hex.EncodeToString(sum[:]),
+			NotBefore:    cert.NotBefore,
+			NotAfter:     cert.NotAfter,
+		})
+	}
+	if len(state.PeerCertificates) == 0 {
+		return info, elapsed, errors.New("服务器没有提供证书")
+	}
+
+	leaf := state.PeerCertificates[0]
+	if !check.SkipVerify {
+		intermediates := x509.NewCertPool()
+		for _, cert := range state.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: serverName, Intermediates: intermediates}); err != nil {
+			info.VerifyError = err.Error()
+			return info, elapsed, fmt.Errorf("证书校验失败: %v", err)
+		}
+	}
+	now := time.Now()
+	if now.After(leaf.NotAfter) {
+		return info, elapsed, fmt.Errorf("证书已于 %s 过期", leaf.NotAfter.Local().Format("2006-01-02 15:04"))
+	}
+	if now.Before(leaf.NotBefore) {
+		return info, elapsed, fmt.Errorf("证书在 %s 之后才生效", leaf.NotBefore.Local().Format("2006-01-02 15:04"))
+	}
+	if check.CertMinDays > 0 {
+		if left := int(leaf.NotAfter.Sub(now).Hours() / 24); left < check.CertMinDays {
+			return info, elapsed, fmt.Errorf("证书 %d 天后过期（%s），少于 %d 天",
+				left, leaf.NotAfter.Local().Format("2006-01-02"), check.CertMinDays)
+		}
+	}
+	return info, elapsed, nil
+}
+
+// probeUptimeICMP 调用系统 ping 发送一个 ICMP 请求，不需要 root 权限。
+// 所有解析结果都需要通过探测目标策略，直接 ping 检查过的地址，避免检查与探测之间 DNS 结果变化
+func probeUptimeICMP(ctx context.Context, target string, timeout time.Duration, policy *probeTargetPolicy) (time.Duration, error) {
+	addrs, err := stdnet.DefaultResolver.LookupIPAddr(ctx, target)
+	if err != nil {
+		return 0, fmt.Errorf("解析 %s 失败: %w", target, err)
+	}
+	if len(addrs) == 0 {
+		return 0, fmt.Errorf("解析 %s 没有得到地址", target)
+	}
+	for _, addr := range addrs {
+		if err := policy.check(addr.IP, 0); err != nil {
+			return 0, err
+		}
+	}
+	ip := addrs[0].IP
+
+	waitSeconds := max(int(timeout/time.Second), 1)
+	var args []string
+	switch runtime.GOOS {
+	case "windows":
+		args = []string{"-n", "1", "-w", strconv.Itoa(waitSeconds * 1000), ip.String()}
+	case "darwin":
+		args = []string{"-c", "1", "-t", strconv.Itoa(waitSeconds), ip.String()}
+	default:
+		args = []string{"-c", "1", "-W", strconv.Itoa(waitSeconds), ip.String()}
+	}
+	start := time.Now()
+	output, err := exec.CommandContext(ctx, "ping", args...).CombinedOutput()
+	elapsed := time.Since(start)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return elapsed, fmt.Errorf("%s 无响应", ip)
+		}
+		return elapsed, fmt.Errorf("执行 ping 失败: %v", err)
+	}
+	return parsePingTime(output, elapsed), nil
+}
+
+// parsePingTime 从 ping 输出中解析往返时间，解析失败时返回 fallback
+func parsePingTime(output []byte, fallback time.Duration) time.Duration {
+	if m := pingTimePattern.FindSubmatch(output); m != nil {
+		if ms, err := strconv.ParseFloat(string(m[1]), 64); err == nil {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	return fallback
+}
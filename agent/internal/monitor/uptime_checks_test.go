@@ -0,0 +1,146 @@
+package monitor
+
+import (
+	stdnet "net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePingTime(t *testing.T) {
+	fallback := 50 * time.Millisecond
+	assert.Equal(t, 12300*time.Microsecond, parsePingTime([]byte("64 bytes from 1.1.1.1: icmp_seq=1 ttl=57 time=12.3 ms"), fallback))
+	assert.Equal(t, time.Millisecond, parsePingTime([]byte("Reply from 1.1.1.1: bytes=32 time<1ms TTL=57"), fallback))
+	assert.Equal(t, 8*time.Millisecond, parsePingTime([]byte("来自 1.1.1.1 的回复: 字节=32 时间=8ms TTL=57"), fallback))
+	assert.Equal(t, fallback, parsePingTime([]byte("no answer"), fallback))
+}
+
+func TestProbeTargetPolicy(t *testing.T) {
+	policy, err := newProbeTargetPolicy(ProbePolicy{
+		Allow: "# 内网服务\n10.0.0.0/8 80,443,8000-8100\n192.168.1.10\n* 443",
+		Deny:  "10.0.0.5\n::ffff:10.1.0.0/120",
+	})
+	assert.NoError(t, err)
+
+	cases := []struct {
+		ip      string
+		port    int
+		allowed bool
+	}{
+		{"10.1.2.3", 443, true},
+		{"10.1.2.3", 8050, true},
+		{"10.1.2.3", 22, false},
+		{"10.1.2.3", 0, false}, // ICMP 只匹配不限端口的规则
+		{"192.168.1.10", 22, true},
+		{"192.168.1.10", 0, true},
+		{"8.8.8.8", 443, true},
+		{"8.8.8.8", 80, false},
+		{"10.0.0.5", 443, false},
+		{"::ffff:10.0.0.5", 443, false}, // IPv4 映射地址按 IPv4 处理
+		{"10.1.0.9", 443, false},
+		{"169.254.169.254", 80, false}, // 内置禁止网段优先于允许列表
+		{"100.100.100.200", 443, false},
+		{"fe80::1", 443, false},
+	}
+	for _, c := range cases {
+		err := policy.check(stdnet.ParseIP(c.ip), c.port)
+		assert.Equal(t, c.allowed, err == nil, "%s:%d %v", c.ip, c.port, err)
+	}
+
+	// 旧版面板未下发策略时只禁止内置网段
+	assert.NoError(t, defaultProbePolicy.check(stdnet.ParseIP("127.0.0.1"), 80))
+	assert.NoError(t, defaultProbePolicy.check(stdnet.ParseIP("8.8.8.8"), 0))
+	assert.Error(t, defaultProbePolicy.check(stdnet.ParseIP("169.254.169.254"), 80))
+
+	for _, text := range []string{"10.0.0.0/33", "example.com", "10.0.0.1 0", "10.0.0.1 90-80", "10.0.0.1 80 extra"} {
+		_, err := newProbeTargetPolicy(ProbePolicy{Allow: text})
+		assert.Error(t, err, text)
+	}
+}
+
+func TestProbeUptime(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer target.Close()
+
+	result := probeUptime(UptimeCheck{ID: 1, Type: "http", Target: target.URL, Timeout: 5}, defaultProbePolicy)
+	assert.True(t, result.Success)
+	assert.Equal(t, http.StatusNoContent, result.StatusCode)
+
+	result = probeUptime(UptimeCheck{ID: 2, Type: "http", Target: target.URL, Timeout: 5, ExpectedStatus: http.StatusOK}, defaultProbePolicy)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "期望 200")
+
+	result = probeUptime(UptimeCheck{ID: 3, Type: "tcp", Target: target.Listener.Addr().String(), Timeout: 5}, defaultProbePolicy)
+	assert.True(t, result.Success)
+
+	result = probeUptime(UptimeCheck{ID: 4, Type: "http", Target: "http://169.254.169.254/", Timeout: 5}, defaultProbePolicy)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "被禁止探测")
+
+	// 连接前按面板下发的策略检查实际地址，允许列表之外的目标不会被探测
+	restricted, err := newProbeTargetPolicy(ProbePolicy{Allow: "10.0.0.0/8"})
+	assert.NoError(t, err)
+	result = probeUptime(UptimeCheck{ID: 4, Type: "tcp", Target: target.Listener.Addr().String(), Timeout: 5}, restricted)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "不在允许探测的范围内")
+
+	// 结果暂存到下一次上报，取出后清空
+	m := &Monitor{}
+	m.SetUptimeChecks(nil, nil)
+	m.uptime.checks = []UptimeCheck{{ID: 5, Type: "tcp", Target: target.Listener.Addr().String(), Interval: 10, Timeout: 5}}
+	m.runDueUptimeChecks(time.Now())
+	assert.Eventually(t, func() bool {
+		m.uptime.mu.Lock()
+		defer m.uptime.mu.Unlock()
+		return len(m.uptime.results) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	// 未到间隔不重复执行
+	m.runDueUptimeChecks(time.Now())
+	results := m.collectUptimeResults()
+	assert.Len(t, results, 1)
+	assert.Equal(t, uint(5), results[0].CheckID)
+	assert.Empty(t, m.collectUptimeResults())
+}
+
+func TestProbeUptimeTLS(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+	addr := target.Listener.Addr().String()
+
+	// 测试服务器使用自签名证书，默认校验失败但仍返回证书链
+	result := probeUptime(UptimeCheck{ID: 1, Type: "tls", Target: addr, Timeout: 5}, defaultProbePolicy)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "证书校验失败")
+	if assert.NotNil(t, result.TLS) {
+		assert.NotEmpty(t, result.TLS.VerifyError)
+		assert.Len(t, result.TLS.Chain, 1)
+	}
+
+	result = probeUptime(UptimeCheck{ID: 2, Type: "tls", Target: addr, Timeout: 5, SkipVerify: true, ServerName: "example.com"}, defaultProbePolicy)
+	assert.True(t, result.Success, result.Error)
+	if assert.NotNil(t, result.TLS) {
+		assert.Equal(t, "example.com", result.TLS.ServerName)
+		assert.NotEmpty(t, result.TLS.Version)
+		assert.NotEmpty(t, result.TLS.CipherSuite)
+		assert.Len(t, result.TLS.Chain[0].Fingerprint, 64)
+	}
+
+	// 剩余天数不足视为失败
+	left := int(time.Until(target.Certificate().NotAfter).Hours() / 24)
+	result = probeUptime(UptimeCheck{ID: 3, Type: "tls", Target: addr, Timeout: 5, SkipVerify: true, CertMinDays: left + 1}, defaultProbePolicy)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "天后过期")
+
+	// 非 TLS 端口握手失败
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer plain.Close()
+	result = probeUptime(UptimeCheck{ID: 4, Type: "tls", Target: plain.Listener.Addr().String(), Timeout: 5}, defaultProbePolicy)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "TLS 握手失败")
+	assert.Nil(t, result.TLS)
+}
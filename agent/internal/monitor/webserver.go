@@ -0,0 +1,346 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// WebServer 一种 Web 服务器的检测、启停和虚拟主机列表，Nginx、Apache httpd 和 Caddy 各有一个实现，
+// 面板通过 nginx_command 的 webserver_* 操作按类型调用
+type WebServer interface {
+	// Type 类型名称：nginx、apache 或 caddy
+	Type() string
+	// Detect 检测是否安装，未安装时返回 nil
+	Detect() *WebServerInfo
+	Start() (string, error)
+	Stop() (string, error)
+	// Reload 平滑重载配置，不中断已有连接
+	Reload() (string, error)
+	// Test 检查配置语法，配置有误属于正常的测试结果，只有无法执行检查时返回错误
+	Test() (*NginxTestResult, error)
+	// VirtualHosts 解析配置文件中的虚拟主机
+	VirtualHosts() ([]VirtualHost, error)
+}
+
+// WebServerInfo 检测到的 Web 服务器
+type WebServerInfo struct {
+	Type       string `json:"type"`
+	Version    string `json:"version"`
+	Binary     string `json:"binary"`
+	ConfigPath string `json:"config_path"`
+	Running    bool   `json:"running"`
+}
+
+// VirtualHost 配置文件中的一个虚拟主机（Nginx 的 server 块、Apache 的 VirtualHost、Caddy 的站点块）
+type VirtualHost struct {
+	ServerNames []string `json:"server_names"`
+	Listen      []string `json:"listen,omitempty"`
+	Root        string   `json:"root,omitempty"`
+	ProxyPass   string   `json:"proxy_pass,omitempty"`
+	SSL         bool     `json:"ssl"`
+	ConfigFile  string   `json:"config_file"`
+	Line        int      `json:"line"`
+}
+
+// 已支持的 Web 服务器，检测结果按此顺序返回
+var webServers = []WebServer{nginxWebServer{}, apacheWebServer{}, caddyWebServer{}}
+
+// 单个配置文件的大小上限，超过的文件不解析虚拟主机
+const webServerMaxConfigSize = 2 * 1024 * 1024
+
+// DetectWebServers 返回本机安装的所有 Web 服务器
+func DetectWebServers() []WebServerInfo {
+	detected := []WebServerInfo{}
+	for _, server := range webServers {
+		if info := server.Detect(); info != nil {
+			detected = append(detected, *info)
+		}
+	}
+	return detected
+}
+
+// findWebServer 按类型查找 Web 服务器，类型为空时返回第一个检测到的
+func findWebServer(serverType string) (WebServer, error) {
+	serverType = strings.ToLower(strings.TrimSpace(serverType))
+	if serverType == "httpd" {
+		serverType = "apache"
+	}
+	for _, server := range webServers {
+		if serverType == "" {
+			if server.Detect() != nil {
+				return server, nil
+			}
+			continue
+		}
+		if server.Type() == serverType {
+			return server, nil
+		}
+	}
+	if serverType == "" {
+		return nil, fmt.Errorf("未检测到 Nginx、Apache 或 Caddy")
+	}
+	return nil, fmt.Errorf("不支持的 Web 服务器类型: %s", serverType)
+}
+
+// handleWebServerAction 处理 webserver_* 操作，params["server"] 指定 Web 服务器类型
+func handleWebServerAction(action string, params map[string]interface{}) (interface{}, error) {
+	if action == "webserver_detect" {
+		return DetectWebServers(), nil
+	}
+
+	server, err := findWebServer(getStringParam(params["server"]))
+	if err != nil {
+		return nil, err
+	}
+
+	var control func() (string, error)
+	switch action {
+	case "webserver_start":
+		control = server.Start
+	case "webserver_stop":
+		control = server.Stop
+	case "webserver_reload":
+		control = server.Reload
+	case "webserver_test":
+		return server.Test()
+	case "webserver_vhosts":
+		hosts, err := server.VirtualHosts()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"server": server.Type(), "vhosts": hosts}, nil
+	default:
+		return nil, fmt.Errorf("未知的Web服务器操作: %s", action)
+	}
+
+	output, err := control()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"success": true,
+		"server":  server.Type(),
+		"output":  output,
+	}, nil
+}
+
+// errWebServerNotFound 未找到 Web 服务器的可执行文件或配置文件
+func errWebServerNotFound(what string) error {
+	return fmt.Errorf("未找到%s", what)
+}
+
+// findExecutable 在 PATH 和常见安装位置中查找可执行文件
+func findExecutable(names []string, dirs []string) string {
+	for _, name := range names {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	for _, dir := range dirs {
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// firstExistingFile 返回第一个存在的文件
+func firstExistingFile(paths []string) string {
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// processRunning 判断是否有指定名称的进程在运行
+func processRunning(names ...string) bool {
+	for _, name := range names {
+		if err := exec.Command("pgrep", "-x", name).Run(); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// controlWebServerService 先通过 systemd 执行 start/stop/reload，失败时使用服务器自带的命令
+func controlWebServerService(units []string, action string, fallback []string) (string, error) {
+	var output []byte
+	var err error
+	for _, unit := range units {
+		output, err = exec.Command("systemctl", action, unit).CombinedOutput()
+		if err == nil {
+			return strings.TrimSpace(string(output)), nil
+		}
+	}
+	if len(fallback) > 0 {
+		output, err = exec.Command(fallback[0], fallback[1:]...).CombinedOutput()
+		if err == nil {
+			return strings.TrimSpace(string(output)), nil
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("没有可用的服务管理方式")
+	}
+	names := map[string]string{"start": "启动", "stop": "停止", "reload": "重载"}
+	return strings.TrimSpace(string(output)), fmt.Errorf("%s失败: %v %s", names[action], err, strings.TrimSpace(string(output)))
+}
+
+// collectConfigFiles 收集配置目录下的配置文件（不跟随子目录中的符号链接目录），match 为空时收集所有文件
+func collectConfigFiles(dirs []string, match func(name string) bool) []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if info.IsDir() {
+				return nil
+			}
+			// sites-enabled 中通常是指向 sites-available 的符号链接
+			resolved, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				return nil
+			}
+			target, err := os.Stat(resolved)
+			if err != nil || target.IsDir() || target.Size() > webServerMaxConfigSize || seen[resolved] {
+				return nil
+			}
+			if match != nil && !match(info.Name()) {
+				return nil
+			}
+			seen[resolved] = true
+			files = append(files, path)
+			return nil
+		})
+	}
+	return files
+}
+
+// parseConfigFiles 读取配置文件并用 parse 解析其中的虚拟主机
+func parseConfigFiles(files []string, parse func(content, file string) []VirtualHost) []VirtualHost {
+	hosts := []VirtualHost{}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		hosts = append(hosts, parse(string(content), file)...)
+	}
+	return hosts
+}
+
+// stripConfigComment 去掉 # 开头的注释
+func stripConfigComment(line string) string {
+	if i := strings.Index(line, "#"); i >= 0 {
+		line = line[:i]
+	}
+	return strings.TrimSpace(line)
+}
+
+// nginxWebServer 系统安装的 Nginx，启停和重载沿用 nginx_start/nginx_stop/nginx_restart 的实现
+type nginxWebServer struct{}
+
+func (nginxWebServer) Type() string { return "nginx" }
+
+func (nginxWebServer) Detect() *WebServerInfo {
+	configPath, bin, _ := DetectNginxPaths()
+	if bin == "" {
+		return nil
+	}
+	info := &WebServerInfo{Type: "nginx", Binary: bin, ConfigPath: configPath}
+	if status, err := GetNginxStatus(); err == nil && status != nil {
+		info.Version = status.Version
+		info.Running = status.Running
+	}
+	return info
+}
+
+func (nginxWebServer) Start() (string, error) {
+	_, output, err := StartNginx()
+	return output, err
+}
+
+func (nginxWebServer) Stop() (string, error) {
+	_, output, err := StopNginx()
+	return output, err
+}
+
+func (nginxWebServer) Reload() (string, error) {
+	_, output, err := RestartNginx()
+	return output, err
+}
+
+func (nginxWebServer) Test() (*NginxTestResult, error) {
+	success, output, err := TestNginxConfig()
+	if err != nil && output == "" {
+		return nil, err
+	}
+	return ParseNginxTestOutput(success, output), nil
+}
+
+func (nginxWebServer) VirtualHosts() ([]VirtualHost, error) {
+	_, _, confDir := DetectNginxPaths()
+	if confDir == "" {
+		return nil, fmt.Errorf("未找到Nginx配置目录")
+	}
+	files := collectConfigFiles([]string{confDir}, func(name string) bool {
+		return strings.HasSuffix(name, ".conf") || !strings.Contains(name, ".")
+	})
+	return parseConfigFiles(files, parseNginxVirtualHosts), nil
+}
+
+// parseNginxVirtualHosts 按大括号层级解析 server 块中的 server_name、listen、root 和 proxy_pass
+func parseNginxVirtualHosts(content, file string) []VirtualHost {
+	var hosts []VirtualHost
+	var current *VirtualHost
+	depth, serverDepth := 0, -1
+	for i, raw := range strings.Split(content, "\n") {
+		line := stripConfigComment(raw)
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(strings.TrimSuffix(strings.TrimSuffix(line, "{"), ";"))
+		if current == nil && len(fields) == 1 && fields[0] == "server" && strings.HasSuffix(line, "{") {
+			current = &VirtualHost{ConfigFile: file, Line: i + 1}
+			serverDepth = depth
+		} else if current != nil && depth == serverDepth+1 && len(fields) > 1 {
+			args := fields[1:]
+			switch fields[0] {
+			case "server_name":
+				current.ServerNames = append(current.ServerNames, args...)
+			case "listen":
+				current.Listen = append(current.Listen, args[0])
+				for _, arg := range args[1:] {
+					if arg == "ssl" {
+						current.SSL = true
+					}
+				}
+			case "root":
+				current.Root = args[0]
+			case "ssl_certificate":
+				current.SSL = true
+			}
+		}
+		if current != nil && len(fields) > 1 && fields[0] == "proxy_pass" && current.ProxyPass == "" {
+			current.ProxyPass = fields[1]
+		}
+
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		if current != nil && depth <= serverDepth {
+			hosts = append(hosts, *current)
+			current = nil
+		}
+	}
+	return hosts
+}
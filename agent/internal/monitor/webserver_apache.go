@@ -0,0 +1,193 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// apachectl -v 的输出：Server version: Apache/2.4.52 (Ubuntu)
+	apacheVersionPattern = regexp.MustCompile(`Apache/(\d+\.\d+\.\d+)`)
+	// apachectl configtest 的错误：AH00526: Syntax error on line 12 of /etc/apache2/sites-enabled/a.conf:
+	apacheIssuePattern = regexp.MustCompile(`Syntax error on line (\d+) of (\S+?):?$`)
+)
+
+// Debian 系使用 apache2，RHEL 系使用 httpd
+var (
+	apacheConfigPaths = []string{
+		"/etc/apache2/apache2.conf",
+		"/etc/httpd/conf/httpd.conf",
+		"/usr/local/apache2/conf/httpd.conf",
+		"/usr/local/etc/apache24/httpd.conf",
+	}
+	apacheBinDirs = []string{"/usr/sbin", "/usr/local/sbin", "/usr/local/apache2/bin"}
+)
+
+// apacheWebServer Apache httpd
+type apacheWebServer struct{}
+
+func (apacheWebServer) Type() string { return "apache" }
+
+// ctl 返回 apachectl（Debian 系为 apache2ctl）的路径
+func (apacheWebServer) ctl() string {
+	return findExecutable([]string{"apachectl", "apache2ctl"}, apacheBinDirs)
+}
+
+func (a apacheWebServer) Detect() *WebServerInfo {
+	bin := findExecutable([]string{"apache2", "httpd"}, apacheBinDirs)
+	ctl := a.ctl()
+	if bin == "" && ctl == "" {
+		return nil
+	}
+	info := &WebServerInfo{
+		Type:       "apache",
+		Binary:     bin,
+		ConfigPath: firstExistingFile(apacheConfigPaths),
+		Running:    processRunning("apache2", "httpd"),
+	}
+	if info.Binary == "" {
+		info.Binary = ctl
+	}
+	// Debian 系直接执行 apache2 -v 会因缺少环境变量报错，优先使用 apachectl
+	versionBin := ctl
+	if versionBin == "" {
+		versionBin = bin
+	}
+	if output, err := exec.Command(versionBin, "-v").CombinedOutput(); err == nil {
+		if matches := apacheVersionPattern.FindStringSubmatch(string(output)); len(matches) > 1 {
+			info.Version = matches[1]
+		}
+	}
+	return info
+}
+
+func (a apacheWebServer) control(action string, ctlAction string) (string, error) {
+	var fallback []string
+	if ctl := a.ctl(); ctl != "" {
+		fallback = []string{ctl, "-k", ctlAction}
+	}
+	return controlWebServerService([]string{"apache2", "httpd"}, action, fallback)
+}
+
+func (a apacheWebServer) Start() (string, error)  { return a.control("start", "start") }
+func (a apacheWebServer) Stop() (string, error)   { return a.control("stop", "stop") }
+func (a apacheWebServer) Reload() (string, error) { return a.control("reload", "graceful") }
+
+func (a apacheWebServer) Test() (*NginxTestResult, error) {
+	ctl := a.ctl()
+	if ctl == "" {
+		return nil, errWebServerNotFound("Apache")
+	}
+	output, err := exec.Command(ctl, "configtest").CombinedOutput()
+	if err != nil && len(output) == 0 {
+		return nil, err
+	}
+	return parseApacheTestOutput(err == nil, string(output)), nil
+}
+
+// parseApacheTestOutput 解析 apachectl configtest 的输出，出错时下一行是具体原因
+func parseApacheTestOutput(success bool, output string) *NginxTestResult {
+	result := &NginxTestResult{
+		Success:  success,
+		Output:   output,
+		Errors:   []NginxConfigIssue{},
+		Warnings: []NginxConfigIssue{},
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || line == "Syntax OK" {
+			continue
+		}
+		if matches := apacheIssuePattern.FindStringSubmatch(line); len(matches) == 3 {
+			issue := NginxConfigIssue{Level: "error", Message: line, File: matches[2]}
+			issue.Line, _ = strconv.Atoi(matches[1])
+			if i+1 < len(lines) {
+				issue.Message = strings.TrimSpace(lines[i+1])
+				i++
+			}
+			result.Errors = append(result.Errors, issue)
+			continue
+		}
+		// AH00558 等提示不影响启动，作为警告
+		issue := NginxConfigIssue{Level: "warn", Message: line}
+		if !success && len(result.Errors) == 0 && i == len(lines)-1 {
+			issue.Level = "error"
+			result.Errors = append(result.Errors, issue)
+			continue
+		}
+		result.Warnings = append(result.Warnings, issue)
+	}
+	return result
+}
+
+func (apacheWebServer) VirtualHosts() ([]VirtualHost, error) {
+	configPath := firstExistingFile(apacheConfigPaths)
+	if configPath == "" {
+		return nil, errWebServerNotFound("Apache 配置文件")
+	}
+	dir := filepath.Dir(configPath)
+	// Debian 系的站点在 sites-enabled，RHEL 系在 conf.d，其余目录按 .conf 收集
+	files := collectConfigFiles([]string{dir}, func(name string) bool {
+		return strings.HasSuffix(name, ".conf")
+	})
+	return parseConfigFiles(files, parseApacheVirtualHosts), nil
+}
+
+// parseApacheVirtualHosts 解析 <VirtualHost> 块中的 ServerName、ServerAlias、DocumentRoot、ProxyPass 和 SSLEngine
+func parseApacheVirtualHosts(content, file string) []VirtualHost {
+	var hosts []VirtualHost
+	var current *VirtualHost
+	for i, raw := range strings.Split(content, "\n") {
+		line := stripConfigComment(raw)
+		if line == "" {
+			continue
+		}
+		lower := strings.ToLower(line)
+		switch {
+		case strings.HasPrefix(lower, "<virtualhost"):
+			current = &VirtualHost{ConfigFile: file, Line: i + 1}
+			addresses := strings.TrimSuffix(strings.TrimSpace(line[len("<virtualhost"):]), ">")
+			current.Listen = strings.Fields(addresses)
+			for _, addr := range current.Listen {
+				if strings.HasSuffix(addr, ":443") {
+					current.SSL = true
+				}
+			}
+		case strings.HasPrefix(lower, "</virtualhost"):
+			if current != nil {
+				hosts = append(hosts, *current)
+				current = nil
+			}
+		case current != nil:
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			value := strings.Trim(fields[1], `"`)
+			switch strings.ToLower(fields[0]) {
+			case "servername":
+				current.ServerNames = append([]string{value}, current.ServerNames...)
+			case "serveralias":
+				for _, alias := range fields[1:] {
+					current.ServerNames = append(current.ServerNames, strings.Trim(alias, `"`))
+				}
+			case "documentroot":
+				current.Root = value
+			case "proxypass":
+				// ProxyPass /path http://backend/，也可以省略路径写在 <Location> 中
+				if current.ProxyPass == "" {
+					current.ProxyPass = strings.Trim(fields[len(fields)-1], `"`)
+				}
+			case "sslengine":
+				current.SSL = current.SSL || strings.EqualFold(value, "on")
+			}
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,193 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// caddy version 的输出：v2.7.6 h1:w0NymbG2m9PcvKWsrXO6EEkY9Ru4FJK8uQbYcev1p3A=
+	caddyVersionPattern = regexp.MustCompile(`v(\d+\.\d+\.\d+)`)
+	// caddy validate 的错误：Error: adapting config using caddyfile: /etc/caddy/Caddyfile:12: unrecognized directive: foo
+	caddyIssuePattern = regexp.MustCompile(`(/\S+?):(\d+)(?:: (.*))?$`)
+)
+
+var caddyConfigPaths = []string{
+	"/etc/caddy/Caddyfile",
+	"/usr/local/etc/caddy/Caddyfile",
+	"/usr/local/etc/Caddyfile",
+}
+
+// caddyWebServer Caddy 2，只解析 Caddyfile 格式的配置
+type caddyWebServer struct{}
+
+func (caddyWebServer) Type() string { return "caddy" }
+
+func (caddyWebServer) bin() string {
+	return findExecutable([]string{"caddy"}, []string{"/usr/bin", "/usr/local/bin"})
+}
+
+func (c caddyWebServer) Detect() *WebServerInfo {
+	bin := c.bin()
+	if bin == "" {
+		return nil
+	}
+	info := &WebServerInfo{
+		Type:       "caddy",
+		Binary:     bin,
+		ConfigPath: firstExistingFile(caddyConfigPaths),
+		Running:    processRunning("caddy"),
+	}
+	if output, err := exec.Command(bin, "version").CombinedOutput(); err == nil {
+		if matches := caddyVersionPattern.FindStringSubmatch(string(output)); len(matches) > 1 {
+			info.Version = matches[1]
+		}
+	}
+	return info
+}
+
+// command 返回带 --config 的 caddy 子命令，未找到 Caddyfile 时返回 nil
+func (c caddyWebServer) command(sub string) []string {
+	bin, configPath := c.bin(), firstExistingFile(caddyConfigPaths)
+	if bin == "" || configPath == "" {
+		return nil
+	}
+	return []string{bin, sub, "--config", configPath, "--adapter", "caddyfile"}
+}
+
+func (c caddyWebServer) Start() (string, error) {
+	return controlWebServerService([]string{"caddy"}, "start", c.command("start"))
+}
+
+func (c caddyWebServer) Stop() (string, error) {
+	var fallback []string
+	if bin := c.bin(); bin != "" {
+		fallback = []string{bin, "stop"}
+	}
+	return controlWebServerService([]string{"caddy"}, "stop", fallback)
+}
+
+func (c caddyWebServer) Reload() (string, error) {
+	return controlWebServerService([]string{"caddy"}, "reload", c.command("reload"))
+}
+
+func (c caddyWebServer) Test() (*NginxTestResult, error) {
+	cmd := c.command("validate")
+	if cmd == nil {
+		return nil, errWebServerNotFound("Caddy 或 Caddyfile")
+	}
+	output, err := exec.Command(cmd[0], cmd[1:]...).CombinedOutput()
+	if err != nil && len(output) == 0 {
+		return nil, err
+	}
+	return parseCaddyTestOutput(err == nil, string(output)), nil
+}
+
+// parseCaddyTestOutput 解析 caddy validate 的输出，Caddy 的日志是 JSON 行，只取 Error: 开头的错误
+func parseCaddyTestOutput(success bool, output string) *NginxTestResult {
+	result := &NginxTestResult{
+		Success:  success,
+		Output:   output,
+		Errors:   []NginxConfigIssue{},
+		Warnings: []NginxConfigIssue{},
+	}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "Error:") {
+			continue
+		}
+		issue := NginxConfigIssue{Level: "error", Message: strings.TrimSpace(strings.TrimPrefix(line, "Error:"))}
+		if matches := caddyIssuePattern.FindStringSubmatch(line); len(matches) == 4 {
+			issue.File = matches[1]
+			issue.Line, _ = strconv.Atoi(matches[2])
+			if matches[3] != "" {
+				issue.Message = matches[3]
+			}
+		}
+		result.Errors = append(result.Errors, issue)
+	}
+	return result
+}
+
+func (caddyWebServer) VirtualHosts() ([]VirtualHost, error) {
+	configPath := firstExistingFile(caddyConfigPaths)
+	if configPath == "" {
+		return nil, errWebServerNotFound("Caddyfile")
+	}
+	// import 引入的站点配置通常放在同目录或 sites-enabled、conf.d 等子目录中
+	files := collectConfigFiles([]string{filepath.Dir(configPath)}, func(name string) bool {
+		switch filepath.Ext(name) {
+		case "", ".caddy", ".caddyfile", ".conf":
+			return true
+		}
+		return false
+	})
+	return parseConfigFiles(files, parseCaddyVirtualHosts), nil
+}
+
+// parseCaddyVirtualHosts 解析 Caddyfile 顶层的站点块，跳过全局选项块和 (snippet) 片段。
+// 没有 http:// 前缀或 :80 端口的站点由 Caddy 自动启用 HTTPS
+func parseCaddyVirtualHosts(content, file string) []VirtualHost {
+	var hosts []VirtualHost
+	var current *VirtualHost
+	depth := 0
+	for i, raw := range strings.Split(content, "\n") {
+		line := stripConfigComment(raw)
+		if line == "" {
+			continue
+		}
+		if depth == 0 && strings.HasSuffix(line, "{") {
+			addresses := strings.TrimSpace(strings.TrimSuffix(line, "{"))
+			if addresses != "" && !strings.HasPrefix(addresses, "(") {
+				current = &VirtualHost{ConfigFile: file, Line: i + 1}
+				for _, addr := range strings.FieldsFunc(addresses, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+					current.ServerNames = append(current.ServerNames, addr)
+					if !strings.HasPrefix(addr, "http://") && !strings.HasSuffix(addr, ":80") {
+						current.SSL = true
+					}
+				}
+			}
+		} else if current != nil && depth == 1 {
+			fields := strings.Fields(line)
+			switch {
+			case len(fields) >= 2 && fields[0] == "root":
+				// root * /var/www 或 root /var/www
+				current.Root = fields[len(fields)-1]
+			case len(fields) >= 2 && fields[0] == "reverse_proxy" && current.ProxyPass == "":
+				current.ProxyPass = caddyProxyUpstream(fields[1:])
+			}
+		} else if current != nil && depth > 1 {
+			// handle /api/* { reverse_proxy ... } 等嵌套块
+			fields := strings.Fields(line)
+			if len(fields) >= 2 && fields[0] == "reverse_proxy" && current.ProxyPass == "" {
+				current.ProxyPass = caddyProxyUpstream(fields[1:])
+			}
+		}
+
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		if depth < 0 {
+			depth = 0
+		}
+		if depth == 0 && current != nil {
+			hosts = append(hosts, *current)
+			current = nil
+		}
+	}
+	return hosts
+}
+
+// caddyProxyUpstream 取 reverse_proxy 的第一个上游，跳过路径匹配器（/api/*、@name、*）
+func caddyProxyUpstream(args []string) string {
+	for _, arg := range args {
+		if arg == "{" || strings.HasPrefix(arg, "/") || strings.HasPrefix(arg, "@") || arg == "*" {
+			continue
+		}
+		return arg
+	}
+	return ""
+}
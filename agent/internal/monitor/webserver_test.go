@@ -0,0 +1,139 @@
+//go:build !monitor_only
+
+package monitor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNginxVirtualHosts(t *testing.T) {
+	content := `server {
+    listen 80;
+    server_name example.com www.example.com;
+    return 301 https://$host$request_uri;
+}
+
+server {
+    listen 443 ssl http2; # 注释
+    server_name example.com;
+    root /var/www/example;
+    location /api/ {
+        proxy_pass http://127.0.0.1:8080;
+    }
+}
+`
+	hosts := parseNginxVirtualHosts(content, "/etc/nginx/conf.d/example.conf")
+	assert.Len(t, hosts, 2)
+	assert.Equal(t, []string{"example.com", "www.example.com"}, hosts[0].ServerNames)
+	assert.Equal(t, []string{"80"}, hosts[0].Listen)
+	assert.False(t, hosts[0].SSL)
+	assert.Equal(t, 1, hosts[0].Line)
+
+	assert.Equal(t, []string{"443"}, hosts[1].Listen)
+	assert.True(t, hosts[1].SSL)
+	assert.Equal(t, "/var/www/example", hosts[1].Root)
+	assert.Equal(t, "http://127.0.0.1:8080", hosts[1].ProxyPass)
+	assert.Equal(t, 7, hosts[1].Line)
+}
+
+func TestParseApacheVirtualHosts(t *testing.T) {
+	content := `<VirtualHost *:80>
+    ServerName example.com
+    ServerAlias www.example.com
+    DocumentRoot "/var/www/example"
+</VirtualHost>
+
+<IfModule mod_ssl.c>
+<VirtualHost *:8443>
+    ServerName api.example.com
+    SSLEngine on
+    ProxyPass / http://127.0.0.1:3000/
+</VirtualHost>
+</IfModule>
+`
+	hosts := parseApacheVirtualHosts(content, "/etc/apache2/sites-enabled/example.conf")
+	assert.Len(t, hosts, 2)
+	assert.Equal(t, []string{"example.com", "www.example.com"}, hosts[0].ServerNames)
+	assert.Equal(t, []string{"*:80"}, hosts[0].Listen)
+	assert.Equal(t, "/var/www/example", hosts[0].Root)
+	assert.False(t, hosts[0].SSL)
+
+	assert.Equal(t, []string{"api.example.com"}, hosts[1].ServerNames)
+	assert.True(t, hosts[1].SSL)
+	assert.Equal(t, "http://127.0.0.1:3000/", hosts[1].ProxyPass)
+	assert.Equal(t, 8, hosts[1].Line)
+}
+
+func TestParseCaddyVirtualHosts(t *testing.T) {
+	content := `{
+    email admin@example.com
+}
+
+(common) {
+    encode gzip
+}
+
+example.com, www.example.com {
+    import common
+    root * /var/www/example
+    file_server
+}
+
+http://internal.example.com {
+    handle /api/* {
+        reverse_proxy localhost:8080
+    }
+}
+`
+	hosts := parseCaddyVirtualHosts(content, "/etc/caddy/Caddyfile")
+	assert.Len(t, hosts, 2)
+	assert.Equal(t, []string{"example.com", "www.example.com"}, hosts[0].ServerNames)
+	assert.Equal(t, "/var/www/example", hosts[0].Root)
+	assert.True(t, hosts[0].SSL)
+	assert.Equal(t, 9, hosts[0].Line)
+
+	assert.Equal(t, []string{"http://internal.example.com"}, hosts[1].ServerNames)
+	assert.Equal(t, "localhost:8080", hosts[1].ProxyPass)
+	assert.False(t, hosts[1].SSL)
+}
+
+func TestParseApacheTestOutput(t *testing.T) {
+	result := parseApacheTestOutput(true, "AH00558: apache2: Could not reliably determine the server's fully qualified domain name\nSyntax OK\n")
+	assert.True(t, result.Success)
+	assert.Empty(t, result.Errors)
+	assert.Len(t, result.Warnings, 1)
+
+	result = parseApacheTestOutput(false, "AH00526: Syntax error on line 12 of /etc/apache2/sites-enabled/a.conf:\nInvalid command 'Foo', perhaps misspelled\n")
+	assert.False(t, result.Success)
+	assert.Len(t, result.Errors, 1)
+	assert.Equal(t, "/etc/apache2/sites-enabled/a.conf", result.Errors[0].File)
+	assert.Equal(t, 12, result.Errors[0].Line)
+	assert.Equal(t, "Invalid command 'Foo', perhaps misspelled", result.Errors[0].Message)
+}
+
+func TestParseCaddyTestOutput(t *testing.T) {
+	output := `{"level":"info","msg":"using provided configuration"}
+Error: adapting config using caddyfile: /etc/caddy/Caddyfile:12: unrecognized directive: foo
+`
+	result := parseCaddyTestOutput(false, output)
+	assert.False(t, result.Success)
+	assert.Len(t, result.Errors, 1)
+	assert.Equal(t, "/etc/caddy/Caddyfile", result.Errors[0].File)
+	assert.Equal(t, 12, result.Errors[0].Line)
+	assert.Equal(t, "unrecognized directive: foo", result.Errors[0].Message)
+
+	result = parseCaddyTestOutput(true, "Valid configuration\n")
+	assert.True(t, result.Success)
+	assert.Empty(t, result.Errors)
+}
+
+func TestFindWebServer(t *testing.T) {
+	server, err := findWebServer("httpd")
+	assert.NoError(t, err)
+	assert.Equal(t, "apache", server.Type())
+
+	_, err = findWebServer("lighttpd")
+	assert.Error(t, err)
+}
@@ -0,0 +1,32 @@
+package monitor
+
+import (
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// isZombieStatus 判断进程状态是否为僵尸进程（已退出但未被父进程回收）
+func isZombieStatus(status []string) bool {
+	for _, s := range status {
+		if s == process.Zombie {
+			return true
+		}
+	}
+	return false
+}
+
+// countZombies 统计僵尸进程数量。
+// 僵尸进程不占用CPU和内存，在汇总指标中无法体现，数量持续增长通常说明父进程没有回收子进程；
+// 扫描期间已退出的进程读取状态会失败，直接跳过
+func countZombies(procs []*process.Process) int {
+	count := 0
+	for _, p := range procs {
+		status, err := p.Status()
+		if err != nil {
+			continue
+		}
+		if isZombieStatus(status) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,44 @@
+package monitor
+
+import (
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountZombies(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("仅在 Linux 上验证")
+	}
+
+	// 启动子进程但不回收，退出后成为僵尸进程
+	cmd := exec.Command("true")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("无法启动子进程: %v", err)
+	}
+	defer cmd.Wait()
+
+	p, err := process.NewProcess(int32(cmd.Process.Pid))
+	assert.NoError(t, err)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, _ := p.Status()
+		if isZombieStatus(status) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("子进程未进入僵尸状态")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Equal(t, 1, countZombies([]*process.Process{p}))
+
+	// 已不存在的进程直接跳过
+	gone := &process.Process{Pid: 1 << 30}
+	assert.Equal(t, 1, countZombies([]*process.Process{p, gone}))
+}
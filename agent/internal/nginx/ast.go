@@ -62,6 +62,8 @@ type ServerBlock struct {
 	ForceSSL          bool            `json:"force_ssl"`
 	ChallengeRoot     string          `json:"challenge_root"`
 	ClientMaxBodySize string          `json:"client_max_body_size"` // 文件上传大小限制
+	Gzip              bool            `json:"gzip"`
+	CacheExpires      string          `json:"cache_expires"` // 静态资源的 expires 时间，为空时不输出缓存 location
 	Extra             []Directive     `json:"extra"`
 }
 
@@ -96,6 +98,8 @@ func (sb *ServerBlock) templateData() map[string]interface{} {
 		"AccessLog":     sb.AccessLog,
 		"ErrorLog":      sb.ErrorLog,
 		"ClientMaxBodySize": sb.ClientMaxBodySize,
+		"Gzip":          sb.Gzip,
+		"CacheExpires":  sb.CacheExpires,
 		"Proxy":         sb.Proxy,
 		"PHP":           sb.PHP,
 		"Locations":     sb.Locations,
@@ -165,6 +169,15 @@ server {
 	client_max_body_size {{ .ClientMaxBodySize }};
 	{{- end }}
 
+	{{- if .Gzip }}
+	gzip on;
+	gzip_vary on;
+	gzip_proxied any;
+	gzip_comp_level 5;
+	gzip_min_length 1024;
+	gzip_types text/plain text/css text/xml text/javascript application/json application/javascript application/xml application/rss+xml image/svg+xml;
+	{{- end }}
+
 	location ^~ /.well-known/acme-challenge/ {
 		root {{ .ChallengeRoot }};
 		default_type "text/plain";
@@ -182,6 +195,8 @@ server {
 		proxy_http_version 1.1;
 		proxy_set_header Upgrade $http_upgrade;
 		proxy_set_header Connection "upgrade";
+		proxy_read_timeout 3600s;
+		proxy_send_timeout 3600s;
 		{{- end }}
 		{{- range $key, $val := .Proxy.Headers }}
 		proxy_set_header {{ $key }} {{ $val }};
@@ -206,6 +221,23 @@ server {
 	}
 	{{- end }}
 
+	{{- if .CacheExpires }}
+	location ~* \.(?:css|js|mjs|map|jpe?g|png|gif|ico|svg|webp|avif|woff2?|ttf|otf|eot)$ {
+		expires {{ .CacheExpires }};
+		add_header Cache-Control "public";
+		access_log off;
+		{{- if .Proxy }}
+		proxy_pass {{ .Proxy.Pass }};
+		proxy_set_header Host $host;
+		proxy_set_header X-Real-IP $remote_addr;
+		proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+		proxy_set_header X-Forwarded-Proto $scheme;
+		{{- else }}
+		try_files $uri =404;
+		{{- end }}
+	}
+	{{- end }}
+
 	{{- range .Locations }}
 	location {{ .Path }} {
 		{{- range .Directives }}
@@ -23,6 +23,7 @@ import (
 	imagetypes "github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/user/server-ops-agent/internal/backups"
 	"github.com/user/server-ops-agent/pkg/logger"
 	"golang.org/x/sys/unix"
 )
@@ -39,7 +40,10 @@ type SiteConfig struct {
 	ForceSSL          bool              `json:"force_ssl"`
 	SSL               SSLPaths          `json:"ssl"`
 	HTTPChallengeDir  string            `json:"http_challenge_dir"`
-	ClientMaxBodySize string            `json:"client_max_body_size"` // 文件上传大小限制，如"10m", "100m"
+	ClientMaxBodySize string            `json:"client_max_body_size"`    // 文件上传大小限制，如"10m", "100m"
+	Gzip              bool              `json:"gzip"`                    // 开启 gzip 压缩
+	CacheExpires      string            `json:"cache_expires,omitempty"` // 静态资源的 expires 缓存时间，为空时不缓存
+	Template          string            `json:"template,omitempty"`      // 按模板创建时的模板名称
 	Labels            map[string]string `json:"labels,omitempty"`
 	UpdatedAt         time.Time         `json:"updated_at"`
 }
@@ -149,7 +153,7 @@ func NewNginxClient(log *logger.Logger, opts ...ClientOption) (*NginxClient, err
 		containerName: "openresty",
 		image:         "openresty/openresty:latest",
 		hostPaths: HostPaths{
-			Base: "/opt/node/openresty",
+			Base: DefaultHostBaseDir,
 		},
 		containerPaths: ContainerPaths{
 			Conf: "/usr/local/openresty/nginx/conf",
@@ -229,6 +233,42 @@ func (c *NginxClient) CreateWebsite(config SiteConfig) (string, error) {
 	return c.applySiteConfig(normalized)
 }
 
+// CreateWebsiteFromTemplate 按模板生成并应用站点。启用 HTTPS 但未指定证书时使用该域名已签发的证书，
+// 尚未签发时先以 HTTP 站点应用，签发证书后再次应用即可启用 HTTPS
+func (c *NginxClient) CreateWebsiteFromTemplate(params SiteTemplateParams) (*SiteConfig, string, error) {
+	site, err := BuildSiteFromTemplate(params)
+	if err != nil {
+		return nil, "", err
+	}
+	if site.EnableHTTPS && site.SSL.Certificate == "" {
+		if paths, ok := c.issuedCertificatePaths(site.PrimaryDomain); ok {
+			site.SSL = paths
+		}
+	}
+
+	configPath, err := c.CreateWebsite(site)
+	if err != nil {
+		return nil, "", err
+	}
+	return &site, configPath, nil
+}
+
+// issuedCertificatePaths 返回通过 IssueCertificate 为域名签发的证书在容器内的路径
+func (c *NginxClient) issuedCertificatePaths(domain string) (SSLPaths, bool) {
+	sslDir := filepath.Join(c.hostPaths.SSL, sanitizeName(domain))
+	certPath := filepath.Join(sslDir, "fullchain.pem")
+	keyPath := filepath.Join(sslDir, "privkey.pem")
+	for _, path := range []string{certPath, keyPath} {
+		if _, err := os.Stat(path); err != nil {
+			return SSLPaths{}, false
+		}
+	}
+	return SSLPaths{
+		Certificate:    c.containerPathFromHost(certPath),
+		CertificateKey: c.containerPathFromHost(keyPath),
+	}, true
+}
+
 // IssueCertificate 调用ACME流程并写入证书
 func (c *NginxClient) IssueCertificate(req CertificateRequest) (*CertificateResult, error) {
 	c.mu.Lock()
@@ -1174,6 +1214,7 @@ func (c *NginxClient) SaveRawConfig(domain, content string) error {
 			return fmt.Errorf("创建备份文件失败: %w", err)
 		}
 		backupCreated = true
+		backups.Track(backupPath)
 	}
 
 	// 回滚函数：从备份恢复配置文件
@@ -1383,6 +1424,12 @@ func (site *SiteConfig) toServerBlock(paths ContainerPaths) *ServerBlock {
 		}
 	}
 
+	// 静态资源缓存时间同样只接受 expires 的合法取值
+	cacheExpires := strings.TrimSpace(site.CacheExpires)
+	if cacheExpires == "off" || !cacheExpiresPattern.MatchString(cacheExpires) {
+		cacheExpires = ""
+	}
+
 	return &ServerBlock{
 		Listen:            listen,
 		ServerNames:       site.AllDomains(),
@@ -1391,6 +1438,8 @@ func (site *SiteConfig) toServerBlock(paths ContainerPaths) *ServerBlock {
 		AccessLog:         filepath.Join(paths.Logs, fmt.Sprintf("%s.access.log", sanitizeName(site.PrimaryDomain))),
 		ErrorLog:          filepath.Join(paths.Logs, fmt.Sprintf("%s.error.log", sanitizeName(site.PrimaryDomain))),
 		ClientMaxBodySize: clientMaxBodySize,
+		Gzip:              site.Gzip,
+		CacheExpires:      cacheExpires,
 		Proxy:             proxyBlock,
 		PHP:               phpBlock,
 		SSL:               sslBlock,
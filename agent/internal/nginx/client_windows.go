@@ -52,6 +52,10 @@ func (c *NginxClient) CreateWebsite(config SiteConfig) (string, error) {
 	return "", ErrNotSupported
 }
 
+func (c *NginxClient) CreateWebsiteFromTemplate(params SiteTemplateParams) (*SiteConfig, string, error) {
+	return nil, "", ErrNotSupported
+}
+
 func (c *NginxClient) GetRawConfig(domain string) (string, error) {
 	return "", ErrNotSupported
 }
@@ -148,6 +152,9 @@ type SiteConfig struct {
 	SSL               SSLPaths          `json:"ssl"`
 	HTTPChallengeDir  string            `json:"http_challenge_dir"`
 	ClientMaxBodySize string            `json:"client_max_body_size"`
+	Gzip              bool              `json:"gzip"`
+	CacheExpires      string            `json:"cache_expires,omitempty"`
+	Template          string            `json:"template,omitempty"`
 	Labels            map[string]string `json:"labels,omitempty"`
 	UpdatedAt         time.Time         `json:"updated_at"`
 }
@@ -0,0 +1,180 @@
+//go:build !monitor_only
+
+package nginx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/providers/dns/alidns"
+	"github.com/go-acme/lego/v4/providers/dns/cloudflare"
+	"github.com/go-acme/lego/v4/providers/dns/dnspod"
+	"github.com/go-acme/lego/v4/providers/dns/godaddy"
+	"github.com/go-acme/lego/v4/providers/dns/rfc2136"
+	"github.com/go-acme/lego/v4/providers/dns/route53"
+)
+
+// DNSProviderFactory 根据面板下发的 dns_config 创建DNS-01验证使用的提供器，凭证缺失时返回错误
+type DNSProviderFactory func(config map[string]string) (challenge.Provider, error)
+
+var (
+	dnsProviders       = map[string]DNSProviderFactory{}
+	dnsProviderAliases = map[string]string{}
+)
+
+func init() {
+	RegisterDNSProvider("alidns", newAliDNSProvider, "aliyun")
+	RegisterDNSProvider("cloudflare", newCloudflareProvider, "cf")
+	RegisterDNSProvider("route53", newRoute53Provider, "aws")
+	RegisterDNSProvider("dnspod", newDNSPodProvider)
+	RegisterDNSProvider("godaddy", newGoDaddyProvider)
+	RegisterDNSProvider("rfc2136", newRFC2136Provider)
+}
+
+// RegisterDNSProvider 注册DNS提供商，aliases 为 provider 参数可使用的别名
+func RegisterDNSProvider(name string, factory DNSProviderFactory, aliases ...string) {
+	name = strings.ToLower(name)
+	dnsProviders[name] = factory
+	dnsProviderAliases[name] = name
+	for _, alias := range aliases {
+		dnsProviderAliases[strings.ToLower(alias)] = name
+	}
+}
+
+// DNSProviderName 返回 provider 参数对应的DNS提供商名称（别名转换为注册名称），不支持时返回空字符串
+func DNSProviderName(provider string) string {
+	return dnsProviderAliases[strings.ToLower(strings.TrimSpace(provider))]
+}
+
+// DNSProviderNames 返回所有已注册的DNS提供商名称
+func DNSProviderNames() []string {
+	names := make([]string, 0, len(dnsProviders))
+	for name := range dnsProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func buildDNSProvider(provider string, config map[string]string) (challenge.Provider, error) {
+	name := DNSProviderName(provider)
+	if name == "" {
+		return nil, fmt.Errorf("暂不支持的DNS提供商: %s，可用: %s", provider, strings.Join(DNSProviderNames(), ", "))
+	}
+	if config == nil {
+		config = map[string]string{}
+	}
+	return dnsProviders[name](config)
+}
+
+// dnsConfigValue 读取去掉首尾空白的配置项
+func dnsConfigValue(config map[string]string, key string) string {
+	return strings.TrimSpace(config[key])
+}
+
+func newAliDNSProvider(config map[string]string) (challenge.Provider, error) {
+	apiKey := dnsConfigValue(config, "access_key_id")
+	apiSecret := dnsConfigValue(config, "access_key_secret")
+	if apiKey == "" || apiSecret == "" {
+		return nil, fmt.Errorf("阿里云DNS需要提供access_key_id和access_key_secret")
+	}
+	cfg := alidns.NewDefaultConfig()
+	cfg.APIKey = apiKey
+	cfg.SecretKey = apiSecret
+	return alidns.NewDNSProviderConfig(cfg)
+}
+
+func newCloudflareProvider(config map[string]string) (challenge.Provider, error) {
+	cfg := cloudflare.NewDefaultConfig()
+	if token := dnsConfigValue(config, "api_token"); token != "" {
+		cfg.AuthToken = token
+	} else {
+		cfg.AuthEmail = dnsConfigValue(config, "api_email")
+		cfg.AuthKey = dnsConfigValue(config, "api_key")
+	}
+	if zoneToken := dnsConfigValue(config, "zone_token"); zoneToken != "" {
+		cfg.ZoneToken = zoneToken
+	}
+	if cfg.AuthToken == "" && (cfg.AuthEmail == "" || cfg.AuthKey == "") {
+		return nil, fmt.Errorf("Cloudflare需要提供api_token或api_email+api_key")
+	}
+	return cloudflare.NewDNSProviderConfig(cfg)
+}
+
+// newRoute53Provider 未提供 access_key_id 时使用 Agent 所在机器的 AWS 默认凭证（环境变量、~/.aws/credentials 或实例角色）
+func newRoute53Provider(config map[string]string) (challenge.Provider, error) {
+	cfg := route53.NewDefaultConfig()
+	cfg.AccessKeyID = dnsConfigValue(config, "access_key_id")
+	cfg.SecretAccessKey = dnsConfigValue(config, "secret_access_key")
+	if (cfg.AccessKeyID == "") != (cfg.SecretAccessKey == "") {
+		return nil, fmt.Errorf("Route53需要同时提供access_key_id和secret_access_key")
+	}
+	cfg.SessionToken = dnsConfigValue(config, "session_token")
+	if region := dnsConfigValue(config, "region"); region != "" {
+		cfg.Region = region
+	} else if cfg.AccessKeyID != "" {
+		// Route53 是全局服务，静态凭证未指定区域时使用 us-east-1
+		cfg.Region = "us-east-1"
+	}
+	if zoneID := dnsConfigValue(config, "hosted_zone_id"); zoneID != "" {
+		cfg.HostedZoneID = zoneID
+	}
+	return route53.NewDNSProviderConfig(cfg)
+}
+
+// newDNSPodProvider DNSPod 的 API Token 由 ID 和 Token 组成，可以分别填写 api_id 和 api_token，或直接填写 login_token（ID,Token）
+func newDNSPodProvider(config map[string]string) (challenge.Provider, error) {
+	loginToken := dnsConfigValue(config, "login_token")
+	if loginToken == "" {
+		id := dnsConfigValue(config, "api_id")
+		token := dnsConfigValue(config, "api_token")
+		if id != "" && token != "" {
+			loginToken = id + "," + token
+		}
+	}
+	if loginToken == "" {
+		return nil, fmt.Errorf("DNSPod需要提供api_id和api_token")
+	}
+	cfg := dnspod.NewDefaultConfig()
+	cfg.LoginToken = loginToken
+	return dnspod.NewDNSProviderConfig(cfg)
+}
+
+func newGoDaddyProvider(config map[string]string) (challenge.Provider, error) {
+	apiKey := dnsConfigValue(config, "api_key")
+	apiSecret := dnsConfigValue(config, "api_secret")
+	if apiKey == "" || apiSecret == "" {
+		return nil, fmt.Errorf("GoDaddy需要提供api_key和api_secret")
+	}
+	cfg := godaddy.NewDefaultConfig()
+	cfg.APIKey = apiKey
+	cfg.APISecret = apiSecret
+	return godaddy.NewDNSProviderConfig(cfg)
+}
+
+// newRFC2136Provider 通过 DNS 动态更新（RFC 2136）写入验证记录，适用于 BIND、PowerDNS 等自建DNS服务器；
+// 未提供 TSIG 密钥时发送不签名的更新请求
+func newRFC2136Provider(config map[string]string) (challenge.Provider, error) {
+	nameserver := dnsConfigValue(config, "nameserver")
+	if nameserver == "" {
+		return nil, fmt.Errorf("RFC2136需要提供nameserver（主机或主机:端口）")
+	}
+	tsigKey := dnsConfigValue(config, "tsig_key")
+	tsigSecret := dnsConfigValue(config, "tsig_secret")
+	if (tsigKey == "") != (tsigSecret == "") {
+		return nil, fmt.Errorf("RFC2136需要同时提供tsig_key和tsig_secret")
+	}
+	cfg := rfc2136.NewDefaultConfig()
+	cfg.Nameserver = nameserver
+	cfg.TSIGKey = tsigKey
+	cfg.TSIGSecret = tsigSecret
+	if algorithm := dnsConfigValue(config, "tsig_algorithm"); algorithm != "" {
+		if !strings.HasSuffix(algorithm, ".") {
+			algorithm += "."
+		}
+		cfg.TSIGAlgorithm = algorithm
+	}
+	return rfc2136.NewDNSProviderConfig(cfg)
+}
@@ -0,0 +1,66 @@
+//go:build !monitor_only
+
+package nginx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDNSProviderName(t *testing.T) {
+	cases := map[string]string{
+		"alidns":    "alidns",
+		"aliyun":    "alidns",
+		"CF":        "cloudflare",
+		"aws":       "route53",
+		" dnspod ":  "dnspod",
+		"GoDaddy":   "godaddy",
+		"rfc2136":   "rfc2136",
+		"http01":    "",
+		"unknown":   "",
+		"namecheap": "",
+	}
+	for provider, want := range cases {
+		if got := DNSProviderName(provider); got != want {
+			t.Errorf("DNSProviderName(%q) = %q, want %q", provider, got, want)
+		}
+	}
+}
+
+func TestBuildDNSProvider(t *testing.T) {
+	valid := []struct {
+		provider string
+		config   map[string]string
+	}{
+		{"route53", map[string]string{"access_key_id": "AKID", "secret_access_key": "secret", "hosted_zone_id": "Z123"}},
+		{"dnspod", map[string]string{"api_id": "12345", "api_token": "token"}},
+		{"dnspod", map[string]string{"login_token": "12345,token"}},
+		{"godaddy", map[string]string{"api_key": "key", "api_secret": "secret"}},
+		{"rfc2136", map[string]string{"nameserver": "127.0.0.1", "tsig_key": "acme.", "tsig_secret": "c2VjcmV0", "tsig_algorithm": "hmac-sha256"}},
+		{"rfc2136", map[string]string{"nameserver": "ns1.example.com:5353"}},
+	}
+	for _, tc := range valid {
+		if _, err := buildDNSProvider(tc.provider, tc.config); err != nil {
+			t.Errorf("buildDNSProvider(%s, %v) error: %v", tc.provider, tc.config, err)
+		}
+	}
+
+	invalid := []struct {
+		provider string
+		config   map[string]string
+		want     string
+	}{
+		{"route53", map[string]string{"access_key_id": "AKID"}, "secret_access_key"},
+		{"dnspod", map[string]string{"api_id": "12345"}, "api_token"},
+		{"godaddy", nil, "api_key"},
+		{"rfc2136", map[string]string{"tsig_key": "acme."}, "nameserver"},
+		{"rfc2136", map[string]string{"nameserver": "127.0.0.1", "tsig_key": "acme."}, "tsig_secret"},
+		{"namecheap", nil, "暂不支持"},
+	}
+	for _, tc := range invalid {
+		_, err := buildDNSProvider(tc.provider, tc.config)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("buildDNSProvider(%s, %v) error = %v, want containing %q", tc.provider, tc.config, err, tc.want)
+		}
+	}
+}
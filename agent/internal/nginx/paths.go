@@ -0,0 +1,6 @@
+//go:build !monitor_only
+
+package nginx
+
+// DefaultHostBaseDir OpenResty 容器在宿主机上的默认根目录，配置位于其下的 conf 目录
+const DefaultHostBaseDir = "/opt/node/openresty"
@@ -0,0 +1,150 @@
+//go:build !monitor_only
+
+package nginx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 站点模板
+const (
+	SiteTemplateReverseProxy = "reverse_proxy"
+	SiteTemplateStatic       = "static"
+	SiteTemplatePHP          = "php"
+	SiteTemplateWebsocket    = "websocket"
+)
+
+// 静态站点和 PHP 站点默认的静态资源缓存时间
+const defaultStaticCacheExpires = "30d"
+
+var (
+	siteDomainPattern   = regexp.MustCompile(`^(\*\.)?[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+	siteUpstreamPattern = regexp.MustCompile(`^https?://[A-Za-z0-9.\-_:\[\]]+(/[A-Za-z0-9._~\-/]*)?$`)
+	// nginx expires 指令的时间，如 30d、12h、max
+	cacheExpiresPattern = regexp.MustCompile(`^([1-9][0-9]*(ms|s|m|h|d|w|M|y)?|max|epoch|off)$`)
+)
+
+// SiteTemplateParams 按模板生成站点配置的参数，只需少量字段即可得到完整配置
+type SiteTemplateParams struct {
+	Template          string   `json:"template"`        // reverse_proxy、static、php 或 websocket
+	Domains           []string `json:"domains"`         // 第一个为主域名
+	Upstream          string   `json:"upstream"`        // reverse_proxy/websocket 的上游地址，如 127.0.0.1:8080，未写协议时使用 http://
+	RootDir           string   `json:"root_dir"`        // static/php 的站点根目录，为空时使用默认目录
+	PHPVersion        string   `json:"php_version"`     // php 的版本号（如 8.2）或 FastCGI 地址（如 127.0.0.1:9000）
+	HTTPS             bool     `json:"https"`           // 启用 HTTPS 并自动把 HTTP 跳转到 HTTPS
+	Certificate       string   `json:"certificate"`     // 证书路径，为空时使用该域名已签发的证书
+	CertificateKey    string   `json:"certificate_key"` // 私钥路径
+	Gzip              *bool    `json:"gzip"`            // 为空时开启
+	CacheExpires      string   `json:"cache_expires"`   // 静态资源缓存时间，为空时 static/php 使用 30d，off 关闭
+	ClientMaxBodySize string   `json:"client_max_body_size"`
+}
+
+// SiteTemplateNames 返回支持的站点模板
+func SiteTemplateNames() []string {
+	return []string{SiteTemplateReverseProxy, SiteTemplateStatic, SiteTemplatePHP, SiteTemplateWebsocket}
+}
+
+// BuildSiteFromTemplate 根据模板参数生成站点配置。证书路径为空时保持 HTTPS 待启用，由调用方补充已签发的证书
+func BuildSiteFromTemplate(p SiteTemplateParams) (SiteConfig, error) {
+	var site SiteConfig
+
+	domains := make([]string, 0, len(p.Domains))
+	seen := make(map[string]bool, len(p.Domains))
+	for _, d := range p.Domains {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if d == "" || seen[d] {
+			continue
+		}
+		if !siteDomainPattern.MatchString(d) {
+			return site, fmt.Errorf("无效的域名: %s", d)
+		}
+		seen[d] = true
+		domains = append(domains, d)
+	}
+	if len(domains) == 0 {
+		return site, fmt.Errorf("至少需要一个域名")
+	}
+	site.PrimaryDomain = domains[0]
+	site.ExtraDomains = domains[1:]
+	site.Template = p.Template
+	site.ClientMaxBodySize = p.ClientMaxBodySize
+
+	switch p.Template {
+	case SiteTemplateReverseProxy, SiteTemplateWebsocket:
+		upstream, err := normalizeUpstream(p.Upstream)
+		if err != nil {
+			return site, err
+		}
+		site.Proxy = ProxyConfig{
+			Enable:       true,
+			Pass:         upstream,
+			Websocket:    p.Template == SiteTemplateWebsocket,
+			PreserveHost: true,
+		}
+	case SiteTemplateStatic, SiteTemplatePHP:
+		if strings.ContainsAny(p.RootDir, " ;{}\"'\n") {
+			return site, fmt.Errorf("无效的站点根目录: %s", p.RootDir)
+		}
+		site.RootDir = strings.TrimSpace(p.RootDir)
+		site.CacheExpires = defaultStaticCacheExpires
+		if p.Template == SiteTemplatePHP {
+			version := strings.TrimSpace(p.PHPVersion)
+			if version == "" {
+				return site, fmt.Errorf("PHP站点需要提供php_version")
+			}
+			if strings.ContainsAny(version, " ;{}\"'\n") {
+				return site, fmt.Errorf("无效的PHP版本或FastCGI地址: %s", version)
+			}
+			site.PHPVersion = version
+			site.Index = []string{"index.php", "index.html", "index.htm"}
+		} else {
+			site.Index = []string{"index.html", "index.htm"}
+		}
+	default:
+		return site, fmt.Errorf("不支持的站点模板: %s，可用: %s", p.Template, strings.Join(SiteTemplateNames(), ", "))
+	}
+
+	site.Gzip = p.Gzip == nil || *p.Gzip
+	if expires := strings.TrimSpace(p.CacheExpires); expires != "" {
+		if !cacheExpiresPattern.MatchString(expires) {
+			return site, fmt.Errorf("无效的缓存时间: %s（示例：30d、12h、max、off）", expires)
+		}
+		site.CacheExpires = expires
+	}
+	if site.CacheExpires == "off" {
+		site.CacheExpires = ""
+	}
+
+	if p.HTTPS {
+		site.EnableHTTPS = true
+		site.ForceSSL = true
+		site.SSL = SSLPaths{
+			Certificate:    strings.TrimSpace(p.Certificate),
+			CertificateKey: strings.TrimSpace(p.CertificateKey),
+		}
+		if (site.SSL.Certificate == "") != (site.SSL.CertificateKey == "") {
+			return site, fmt.Errorf("证书和私钥路径需要同时提供")
+		}
+		if strings.ContainsAny(site.SSL.Certificate+site.SSL.CertificateKey, " ;{}\"'\n") {
+			return site, fmt.Errorf("无效的证书路径")
+		}
+	}
+	return site, nil
+}
+
+// normalizeUpstream 校验上游地址，未写协议时补全 http://
+func normalizeUpstream(upstream string) (string, error) {
+	upstream = strings.TrimSpace(upstream)
+	if upstream == "" {
+		return "", fmt.Errorf("反向代理站点需要提供upstream")
+	}
+	if !strings.HasPrefix(upstream, "http://") && !strings.HasPrefix(upstream, "https://") {
+		upstream = "http://" + upstream
+	}
+	if !siteUpstreamPattern.MatchString(upstream) {
+		return "", fmt.Errorf("无效的上游地址: %s", upstream)
+	}
+	return upstream, nil
+}
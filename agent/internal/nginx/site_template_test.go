@@ -0,0 +1,94 @@
+//go:build !monitor_only
+
+package nginx
+
+import (
+	"strings"
+	"testing"
+)
+
+func renderTemplateSite(t *testing.T, params SiteTemplateParams) string {
+	t.Helper()
+	site, err := BuildSiteFromTemplate(params)
+	if err != nil {
+		t.Fatalf("build %s: %v", params.Template, err)
+	}
+	if site.RootDir == "" {
+		site.RootDir = "/www/sites/" + sanitizeName(site.PrimaryDomain)
+	}
+	site.HTTPChallengeDir = "/www/common"
+	cfg := &NginxConfig{Servers: []*ServerBlock{site.toServerBlock(ContainerPaths{Logs: "/var/log/nginx"})}}
+	out, err := cfg.Render()
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	return out
+}
+
+func TestSiteTemplate_ReverseProxyWithHTTPS(t *testing.T) {
+	out := renderTemplateSite(t, SiteTemplateParams{
+		Template:       SiteTemplateReverseProxy,
+		Domains:        []string{"App.example.com", "www.example.com", "app.example.com"},
+		Upstream:       "127.0.0.1:8080",
+		HTTPS:          true,
+		Certificate:    "/www/ssl/app/fullchain.pem",
+		CertificateKey: "/www/ssl/app/privkey.pem",
+	})
+	for _, want := range []string{
+		"server_name app.example.com www.example.com;",
+		"listen 443 ssl http2;",
+		"return 301 https://$host$request_uri;",
+		"proxy_pass http://127.0.0.1:8080;",
+		"gzip on;",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("reverse proxy site should contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "expires") {
+		t.Fatalf("reverse proxy site should not cache static assets by default, got:\n%s", out)
+	}
+}
+
+func TestSiteTemplate_StaticAndPHP(t *testing.T) {
+	gzip := false
+	out := renderTemplateSite(t, SiteTemplateParams{Template: SiteTemplateStatic, Domains: []string{"example.com"}, Gzip: &gzip})
+	if !strings.Contains(out, "expires 30d;") || strings.Contains(out, "gzip on;") {
+		t.Fatalf("static site should cache assets without gzip, got:\n%s", out)
+	}
+	if strings.Contains(out, "listen 443") || strings.Contains(out, "return 301") {
+		t.Fatalf("http-only site should not redirect, got:\n%s", out)
+	}
+
+	out = renderTemplateSite(t, SiteTemplateParams{Template: SiteTemplatePHP, Domains: []string{"blog.example.com"}, PHPVersion: "8.2", CacheExpires: "off"})
+	if !strings.Contains(out, "fastcgi_pass unix:/run/php/php8.2-fpm.sock;") || strings.Contains(out, "expires") {
+		t.Fatalf("php site should use fpm socket without caching, got:\n%s", out)
+	}
+}
+
+func TestSiteTemplate_Websocket(t *testing.T) {
+	out := renderTemplateSite(t, SiteTemplateParams{Template: SiteTemplateWebsocket, Domains: []string{"ws.example.com"}, Upstream: "https://10.0.0.2:8443/socket"})
+	for _, want := range []string{"proxy_pass https://10.0.0.2:8443/socket;", "proxy_set_header Upgrade $http_upgrade;", "proxy_read_timeout 3600s;"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("websocket site should contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestSiteTemplate_Invalid(t *testing.T) {
+	cases := []SiteTemplateParams{
+		{Template: "unknown", Domains: []string{"example.com"}},
+		{Template: SiteTemplateStatic},
+		{Template: SiteTemplateStatic, Domains: []string{"example.com; include /etc/passwd"}},
+		{Template: SiteTemplateReverseProxy, Domains: []string{"example.com"}},
+		{Template: SiteTemplateReverseProxy, Domains: []string{"example.com"}, Upstream: "127.0.0.1:8080; return 200"},
+		{Template: SiteTemplatePHP, Domains: []string{"example.com"}},
+		{Template: SiteTemplateStatic, Domains: []string{"example.com"}, CacheExpires: "30d; add_header X 1"},
+		{Template: SiteTemplateStatic, Domains: []string{"example.com"}, HTTPS: true, Certificate: "/www/ssl/a.pem"},
+	}
+	for _, params := range cases {
+		if _, err := BuildSiteFromTemplate(params); err == nil {
+			t.Errorf("expected error for %+v", params)
+		}
+	}
+}
@@ -14,10 +14,7 @@ import (
 
 	"github.com/go-acme/lego/v4/certcrypto"
 	"github.com/go-acme/lego/v4/certificate"
-	"github.com/go-acme/lego/v4/challenge"
 	"github.com/go-acme/lego/v4/lego"
-	"github.com/go-acme/lego/v4/providers/dns/alidns"
-	"github.com/go-acme/lego/v4/providers/dns/cloudflare"
 	"github.com/go-acme/lego/v4/registration"
 )
 
@@ -190,39 +187,6 @@ func (p *webrootProvider) CleanUp(domain, token, keyAuth string) error {
 	return nil
 }
 
-func buildDNSProvider(name string, config map[string]string) (challenge.Provider, error) {
-	switch strings.ToLower(name) {
-	case "alidns", "aliyun":
-		apiKey := config["access_key_id"]
-		apiSecret := config["access_key_secret"]
-		if apiKey == "" || apiSecret == "" {
-			return nil, fmt.Errorf("阿里云DNS需要提供access_key_id和access_key_secret")
-		}
-		cfg := alidns.NewDefaultConfig()
-		cfg.APIKey = apiKey
-		cfg.SecretKey = apiSecret
-		return alidns.NewDNSProviderConfig(cfg)
-	case "cloudflare", "cf":
-		cfg := cloudflare.NewDefaultConfig()
-		token := config["api_token"]
-		if token != "" {
-			cfg.AuthToken = token
-		} else {
-			cfg.AuthEmail = config["api_email"]
-			cfg.AuthKey = config["api_key"]
-		}
-		if zoneToken := config["zone_token"]; zoneToken != "" {
-			cfg.ZoneToken = zoneToken
-		}
-		if cfg.AuthToken == "" && (cfg.AuthEmail == "" || cfg.AuthKey == "") {
-			return nil, fmt.Errorf("Cloudflare需要提供api_token或api_email+api_key")
-		}
-		return cloudflare.NewDNSProviderConfig(cfg)
-	default:
-		return nil, fmt.Errorf("暂不支持的DNS提供商: %s", name)
-	}
-}
-
 func wrapACMEProviderError(err error, provider string) error {
 	if err == nil {
 		return nil
@@ -0,0 +1,311 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// archiveIdleTimeout 面板超过该时间没有请求下一个分片时丢弃打包会话
+const archiveIdleTimeout = 2 * time.Minute
+
+var errArchiveCanceled = errors.New("打包下载已取消")
+
+// archiveSession 一次目录打包下载。tar.gz 由后台 goroutine 边打包边写入管道，
+// 面板按 seq 顺序逐片读取，不落盘也不在内存中保留整个压缩包
+type archiveSession struct {
+	mu     sync.Mutex
+	reader *io.PipeReader
+	timer  *time.Timer
+	sent   int64 // 已发送的字节数，即下一个分片的偏移量
+
+	// 最近一次发送的分片，面板校验失败重试同一序号时原样重发
+	lastSeq    int
+	lastOffset int64
+	lastData   []byte
+	lastEOF    bool
+}
+
+// writeHostArchive 把宿主机目录打包为 tar.gz 写入 w，包内路径以目录名开头。
+// 符号链接按链接本身打包，不跟随；设备、管道等特殊文件和无法读取的文件被跳过
+func (c *Client) writeHostArchive(w io.Writer, root string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	base := filepath.Dir(root)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			c.log.Warn("打包时跳过无法访问的路径: %s, %v", path, err)
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			c.log.Warn("打包时跳过无法访问的路径: %s, %v", path, err)
+			return nil
+		}
+
+		var link string
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			if link, err = os.Readlink(path); err != nil {
+				c.log.Warn("打包时跳过无法读取的符号链接: %s, %v", path, err)
+				return nil
+			}
+		case !info.Mode().IsRegular() && !info.IsDir():
+			return nil
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		if !info.Mode().IsRegular() {
+			return tw.WriteHeader(header)
+		}
+
+		// 先打开文件再写入头，打不开的文件整体跳过，不会留下只有头的条目
+		f, err := os.Open(path)
+		if err != nil {
+			c.log.Warn("打包时跳过无法读取的文件: %s, %v", path, err)
+			return nil
+		}
+		defer f.Close()
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(tw, f, header.Size); err != nil {
+			return fmt.Errorf("读取 %s 失败（文件可能在打包过程中被修改）: %v", path, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// writeContainerArchive 把容器内目录打包为 tar.gz 写入 w。Docker 返回的本身就是 tar 流，这里只做压缩
+func writeContainerArchive(w io.Writer, manager *ContainerFileManager, path string) error {
+	reader, _, err := manager.docker.CopyFromContainer(manager.containerID, path)
+	if err != nil {
+		return fmt.Errorf("读取容器目录失败: %w", err)
+	}
+	defer reader.Close()
+
+	gz := gzip.NewWriter(w)
+	if _, err := io.Copy(gz, reader); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// startArchive 校验目录并启动后台打包
+func (c *Client) startArchive(archiveID, path, containerID string) error {
+	if archiveID == "" {
+		return fmt.Errorf("archive_id 不能为空")
+	}
+	if _, exists := c.archives.Load(archiveID); exists {
+		return fmt.Errorf("打包会话已存在: %s", archiveID)
+	}
+
+	var write func(w io.Writer) error
+	if containerID != "" {
+		manager, err := NewContainerFileManager(c.log, containerID, c.containerFileRoots())
+		if err != nil {
+			return err
+		}
+		checked, err := manager.checkPath(path)
+		if err == nil {
+			if stat, statErr := manager.statPath(checked); statErr != nil {
+				err = fmt.Errorf("检查目录失败: %w", statErr)
+			} else if !stat.Mode.IsDir() {
+				err = fmt.Errorf("只能打包目录")
+			}
+		}
+		if err != nil {
+			manager.Close()
+			return err
+		}
+		write = func(w io.Writer) error {
+			defer manager.Close()
+			return writeContainerArchive(w, manager, checked)
+		}
+	} else {
+		root, err := normalizeHostPath(path)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(root)
+		if err != nil {
+			return fmt.Errorf("检查目录失败: %v", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("只能打包目录")
+		}
+		write = func(w io.Writer) error {
+			return c.writeHostArchive(w, root)
+		}
+	}
+
+	pr, pw := io.Pipe()
+	session := &archiveSession{reader: pr, lastSeq: -1}
+	session.timer = time.AfterFunc(archiveIdleTimeout, func() {
+		c.log.Warn("打包下载会话空闲超时，已丢弃: %s", archiveID)
+		c.closeArchive(archiveID)
+	})
+	c.archives.Store(archiveID, session)
+
+	go func() {
+		err := write(pw)
+		if err != nil && !errors.Is(err, errArchiveCanceled) {
+			c.log.Error("打包目录失败: path=%s, error=%v", path, err)
+		}
+		pw.CloseWithError(err)
+	}()
+	return nil
+}
+
+// nextArchiveChunk 读取下一个分片；seq 与上一次相同时重发上一个分片
+func (c *Client) nextArchiveChunk(archiveID string, seq int, length int64) (offset int64, data []byte, eof bool, err error) {
+	value, ok := c.archives.Load(archiveID)
+	if !ok {
+		return 0, nil, false, fmt.Errorf("打包会话不存在或已过期: %s", archiveID)
+	}
+	session := value.(*archiveSession)
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.timer.Reset(archiveIdleTimeout)
+
+	if seq == session.lastSeq {
+		return session.lastOffset, session.lastData, session.lastEOF, nil
+	}
+	if seq != session.lastSeq+1 {
+		return 0, nil, false, fmt.Errorf("分片序号不连续: 期望 %d，收到 %d", session.lastSeq+1, seq)
+	}
+
+	buf := make([]byte, length)
+	n, err := io.ReadFull(session.reader, buf)
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		eof = true
+	case err != nil:
+		return 0, nil, false, err
+	}
+
+	session.lastSeq, session.lastOffset, session.lastData, session.lastEOF = seq, session.sent, buf[:n], eof
+	session.sent += int64(n)
+	return session.lastOffset, session.lastData, eof, nil
+}
+
+// closeArchive 结束打包会话，后台打包 goroutine 随之退出
+func (c *Client) closeArchive(archiveID string) {
+	value, ok := c.archives.LoadAndDelete(archiveID)
+	if !ok {
+		return
+	}
+	session := value.(*archiveSession)
+	session.timer.Stop()
+	session.reader.CloseWithError(errArchiveCanceled)
+}
+
+// handleFileArchive 处理目录打包下载：start 开始打包，chunk 按序读取下一个分片，cancel 结束会话
+func (c *Client) handleFileArchive(message []byte) {
+	var msg struct {
+		RequestID string `json:"request_id"`
+		Payload   struct {
+			Action      string `json:"action"`
+			ArchiveID   string `json:"archive_id"`
+			Path        string `json:"path"`
+			ContainerID string `json:"container_id"`
+			Seq         int    `json:"seq"`
+			Length      int64  `json:"length"`
+		} `json:"payload"`
+	}
+
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.log.Error("解析打包下载请求失败: %v", err)
+		return
+	}
+
+	archiveID := msg.Payload.ArchiveID
+	fail := func(err error) {
+		c.sendResponse(msg.RequestID, "file_archive_ack", map[string]interface{}{
+			"archive_id": archiveID,
+			"seq":        msg.Payload.Seq,
+			"success":    false,
+			"error":      err.Error(),
+		})
+	}
+
+	switch msg.Payload.Action {
+	case "start":
+		c.log.Info("开始打包下载: archive_id=%s, path=%s, container=%s", archiveID, msg.Payload.Path, msg.Payload.ContainerID)
+		if err := c.startArchive(archiveID, msg.Payload.Path, msg.Payload.ContainerID); err != nil {
+			c.log.Error("开始打包下载失败: %v", err)
+			fail(err)
+			return
+		}
+		c.sendResponse(msg.RequestID, "file_archive_ack", map[string]interface{}{
+			"archive_id": archiveID,
+			"success":    true,
+		})
+
+	case "chunk":
+		offset, data, eof, err := c.nextArchiveChunk(archiveID, msg.Payload.Seq, c.downloadChunkLimit(msg.Payload.Length))
+		if err != nil {
+			c.log.Error("读取打包分片失败: archive_id=%s, seq=%d, error=%v", archiveID, msg.Payload.Seq, err)
+			c.closeArchive(archiveID)
+			fail(err)
+			return
+		}
+		sum := sha256.Sum256(data)
+		c.sendTransferResponse(msg.RequestID, "file_archive_ack", map[string]interface{}{
+			"archive_id": archiveID,
+			"seq":        msg.Payload.Seq,
+			"offset":     offset,
+			"size":       len(data),
+			"eof":        eof,
+			"chunk_hash": hex.EncodeToString(sum[:]),
+			"content":    base64.StdEncoding.EncodeToString(data),
+			"success":    true,
+		})
+
+	case "cancel":
+		c.closeArchive(archiveID)
+		c.sendResponse(msg.RequestID, "file_archive_ack", map[string]interface{}{
+			"archive_id": archiveID,
+			"success":    true,
+		})
+
+	default:
+		fail(fmt.Errorf("未知的打包操作: %s", msg.Payload.Action))
+	}
+}
@@ -0,0 +1,93 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/config"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func TestHostArchiveChunks(t *testing.T) {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	c := &Client{cfg: &config.Config{}, log: log}
+
+	root := filepath.Join(t.TempDir(), "site")
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "conf"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "index.html"), []byte("<h1>hi</h1>"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "conf", "app.conf"), bytes.Repeat([]byte("x"), 5000), 0600))
+	assert.NoError(t, os.Symlink("index.html", filepath.Join(root, "home.html")))
+
+	assert.NoError(t, c.startArchive("a1", root, ""))
+	assert.Error(t, c.startArchive("a1", root, ""))
+
+	// 分片很小以覆盖多次读取；同一序号重复请求返回相同的分片
+	var archive bytes.Buffer
+	for seq := 0; ; seq++ {
+		offset, data, eof, err := c.nextArchiveChunk("a1", seq, 64)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(archive.Len()), offset)
+		again, retryData, _, err := c.nextArchiveChunk("a1", seq, 64)
+		assert.NoError(t, err)
+		assert.Equal(t, offset, again)
+		assert.Equal(t, data, retryData)
+		archive.Write(data)
+		if eof {
+			break
+		}
+	}
+	c.closeArchive("a1")
+
+	gz, err := gzip.NewReader(&archive)
+	assert.NoError(t, err)
+	tr := tar.NewReader(gz)
+	entries := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		content, _ := io.ReadAll(tr)
+		entries[header.Name] = string(content)
+		if header.Name == "site/home.html" {
+			assert.Equal(t, byte(tar.TypeSymlink), header.Typeflag)
+			assert.Equal(t, "index.html", header.Linkname)
+		}
+	}
+	assert.Contains(t, entries, "site/")
+	assert.Contains(t, entries, "site/conf/")
+	assert.Contains(t, entries, "site/home.html")
+	assert.Equal(t, "<h1>hi</h1>", entries["site/index.html"])
+	assert.Len(t, entries["site/conf/app.conf"], 5000)
+}
+
+func TestHostArchiveErrors(t *testing.T) {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	c := &Client{cfg: &config.Config{}, log: log}
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("a"), 0644))
+	assert.Error(t, c.startArchive("f", file, ""))
+	assert.Error(t, c.startArchive("", dir, ""))
+
+	assert.NoError(t, c.startArchive("a2", dir, ""))
+	_, _, _, err = c.nextArchiveChunk("a2", 1, 64)
+	assert.Error(t, err)
+
+	// 取消后会话不再可用
+	c.closeArchive("a2")
+	_, _, _, err = c.nextArchiveChunk("a2", 0, 64)
+	assert.Error(t, err)
+}
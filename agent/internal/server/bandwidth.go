@@ -0,0 +1,193 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 限速写入时的分块大小：大消息按块写入，每块写入前等待令牌
+const throttleChunkSize = 32 * 1024
+
+// rateLimiter 基于令牌桶的字节限速器，速率为 0 表示不限速。
+// 令牌允许透支，透支部分通过等待偿还，因此单次可以申请超过桶容量的字节数
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // 每秒字节数
+	burst  float64 // 桶容量
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter 创建限速器，bytesPerSec <= 0 表示不限速
+func newRateLimiter(bytesPerSec int) *rateLimiter {
+	l := &rateLimiter{}
+	l.setRate(bytesPerSec)
+	return l
+}
+
+// setRate 调整速率，正在等待的写入在下一块生效
+func (l *rateLimiter) setRate(bytesPerSec int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if bytesPerSec <= 0 {
+		l.rate = 0
+		return
+	}
+	l.rate = float64(bytesPerSec)
+	// 桶容量取一秒的流量，至少容纳一个分块
+	l.burst = l.rate
+	if l.burst < throttleChunkSize {
+		l.burst = throttleChunkSize
+	}
+	l.tokens = l.burst
+	l.last = time.Now()
+}
+
+// limited 是否启用了限速，nil 视为不限速
+func (l *rateLimiter) limited() bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate > 0
+}
+
+// reserve 申请 n 个字节的令牌，返回需要等待的时间
+func (l *rateLimiter) reserve(n int) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// waitAll 依次向所有限速器申请令牌并等待，取最长的等待时间
+func waitAll(n int, limiters ...*rateLimiter) {
+	var wait time.Duration
+	for _, l := range limiters {
+		if d := l.reserve(n); d > wait {
+			wait = d
+		}
+	}
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// throttledWriter 按分块写入并在每块写入前等待令牌
+type throttledWriter struct {
+	w        io.Writer
+	limiters []*rateLimiter
+}
+
+func (t *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := len(p)
+		if n > throttleChunkSize {
+			n = throttleChunkSize
+		}
+		waitAll(n, t.limiters...)
+		m, err := t.w.Write(p[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// transferLimiter 为一次文件传输或一个日志流创建独立的限速器
+func (c *Client) transferLimiter() *rateLimiter {
+	return newRateLimiter(c.cfg.TransferRateLimit * 1024)
+}
+
+// applyBandwidthLimit 按配置调整 Agent 级别的总带宽上限
+func (c *Client) applyBandwidthLimit() {
+	c.bandwidth.setRate(c.cfg.AgentBandwidthLimit * 1024)
+}
+
+// writeThrottled 按带宽上限发送一条消息，单个传输的上限和 Agent 总上限同时生效。
+// 小消息在获取写锁之前等待，不影响其他消息；大消息需要分块写入，
+// 写入期间持有写锁，其他消息（监控数据、终端输出等）会等待其写完
+func (c *Client) writeThrottled(v interface{}, transfer *rateLimiter) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.writeThrottledBytes(data, transfer)
+}
+
+// writeThrottledBytes 与 writeThrottled 相同，发送已序列化的消息
+func (c *Client) writeThrottledBytes(data []byte, transfer *rateLimiter) error {
+	limiters := make([]*rateLimiter, 0, 2)
+	for _, l := range []*rateLimiter{transfer, c.bandwidth} {
+		if l.limited() {
+			limiters = append(limiters, l)
+		}
+	}
+
+	if len(data) <= throttleChunkSize {
+		waitAll(len(data), limiters...)
+	}
+
+	c.wsWriteMutex.Lock()
+	defer c.wsWriteMutex.Unlock()
+
+	if c.wsConn == nil {
+		return fmt.Errorf("WebSocket连接为空")
+	}
+	if len(limiters) == 0 || len(data) <= throttleChunkSize {
+		return c.wsConn.WriteMessage(websocket.TextMessage, data)
+	}
+
+	w, err := c.wsConn.NextWriter(websocket.TextMessage)
+	if err != nil {
+		return err
+	}
+	if _, err := (&throttledWriter{w: w, limiters: limiters}).Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// sendTransferResponse 与 sendResponse 相同，但按带宽上限发送，用于文件下载等大块数据
+func (c *Client) sendTransferResponse(requestID, responseType string, data map[string]interface{}) {
+	response, err := json.Marshal(map[string]interface{}{
+		"type":       responseType,
+		"request_id": requestID,
+		"data":       data,
+	})
+	if err != nil {
+		c.log.Error("序列化WebSocket响应失败: type=%s, requestID=%s, error=%v", responseType, requestID, err)
+		return
+	}
+	response = c.limitResponse(requestID, responseType, response)
+	if err := c.writeThrottledBytes(response, c.transferLimiter()); err != nil {
+		c.log.Error("发送WebSocket响应失败: type=%s, requestID=%s, error=%v", responseType, requestID, err)
+	}
+}
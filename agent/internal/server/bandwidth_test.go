@@ -0,0 +1,44 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	// 未设置速率时不限速
+	var unlimited *rateLimiter
+	assert.False(t, unlimited.limited())
+	assert.Zero(t, unlimited.reserve(1<<20))
+	assert.Zero(t, newRateLimiter(0).reserve(1<<20))
+
+	// 桶容量内的申请无需等待，超出部分按速率计算等待时间
+	l := newRateLimiter(64 * 1024)
+	assert.True(t, l.limited())
+	assert.Zero(t, l.reserve(64*1024))
+	wait := l.reserve(32 * 1024)
+	assert.InDelta(t, float64(500*time.Millisecond), float64(wait), float64(20*time.Millisecond))
+
+	// 取消限速后立即生效
+	l.setRate(0)
+	assert.Zero(t, l.reserve(1<<20))
+}
+
+func TestThrottledWriter(t *testing.T) {
+	var buf bytes.Buffer
+	l := newRateLimiter(throttleChunkSize * 10)
+	w := &throttledWriter{w: &buf, limiters: []*rateLimiter{l}}
+
+	// 先用掉桶内令牌，之后写入 5 个分块约需 0.5s
+	l.reserve(throttleChunkSize * 10)
+	data := bytes.Repeat([]byte("x"), throttleChunkSize*5)
+	start := time.Now()
+	n, err := w.Write(data)
+	assert.NoError(t, err)
+	assert.Equal(t, len(data), n)
+	assert.Equal(t, data, buf.Bytes())
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+}
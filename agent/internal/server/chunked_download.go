@@ -0,0 +1,196 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// downloadChunkMaxSize 单个下载分片的上限，面板请求更大的分片时按此截断
+const downloadChunkMaxSize = 4 * 1024 * 1024
+
+// downloadChunk 分片下载读取到的一段文件内容
+type downloadChunk struct {
+	Data      []byte
+	TotalSize int64 // 文件当前的总大小
+	ModTime   int64 // 文件修改时间（Unix 秒），面板据此判断续传期间文件是否被改动
+}
+
+// readHostFileRange 读取宿主机文件从 offset 开始的至多 length 字节。
+// offset 超出文件末尾时返回空数据而不是错误，面板据此得到文件大小
+func readHostFileRange(path string, offset, length int64) (*downloadChunk, error) {
+	path, err := normalizeHostPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("检查文件失败: %v", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("不能下载目录")
+	}
+
+	chunk := &downloadChunk{TotalSize: info.Size(), ModTime: info.ModTime().Unix()}
+	if offset >= info.Size() {
+		return chunk, nil
+	}
+	if remaining := info.Size() - offset; length > remaining {
+		length = remaining
+	}
+
+	buf := make([]byte, length)
+	n, err := f.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("读取文件内容失败: %v", err)
+	}
+	chunk.Data = buf[:n]
+	return chunk, nil
+}
+
+// ReadRange 读取容器文件从 offset 开始的至多 length 字节。
+// Docker 只提供整个文件的 tar 流，这里跳过 offset 之前的内容，只在内存中保留当前分片
+func (cfm *ContainerFileManager) ReadRange(path string, offset, length int64) (*downloadChunk, error) {
+	path, err := cfm.checkPath(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := cfm.statPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取容器文件失败: %w", err)
+	}
+	if stat.Mode.IsDir() {
+		return nil, fmt.Errorf("不能下载目录")
+	}
+
+	chunk := &downloadChunk{TotalSize: stat.Size, ModTime: stat.Mtime.Unix()}
+	if offset >= stat.Size {
+		return chunk, nil
+	}
+
+	reader, _, err := cfm.docker.CopyFromContainer(cfm.containerID, path)
+	if err != nil {
+		return nil, fmt.Errorf("读取容器文件失败: %w", err)
+	}
+	defer reader.Close()
+
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("未找到文件: %s", path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析容器文件失败: %w", err)
+		}
+		if header.FileInfo().IsDir() {
+			continue
+		}
+		if _, err := io.CopyN(io.Discard, tr, offset); err != nil {
+			return nil, fmt.Errorf("读取容器文件内容失败: %w", err)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, length))
+		if err != nil {
+			return nil, fmt.Errorf("读取容器文件内容失败: %w", err)
+		}
+		chunk.Data = data
+		return chunk, nil
+	}
+}
+
+// downloadChunkLimit 计算本次分片实际读取的字节数：不超过 downloadChunkMaxSize，
+// 且 Base64 编码后不超过 max_response_mb，避免分片响应被大小上限拒绝
+func (c *Client) downloadChunkLimit(requested int64) int64 {
+	if requested <= 0 || requested > downloadChunkMaxSize {
+		requested = downloadChunkMaxSize
+	}
+	if limit := c.maxResponseBytes(); limit > 0 {
+		// 预留 4KB 给 JSON 中的其他字段
+		if fit := int64(limit-4096) / 4 * 3; fit > 0 && requested > fit {
+			requested = fit
+		}
+	}
+	return requested
+}
+
+// handleChunkedDownloadChunk 按偏移量读取文件的一个分片。
+// 面板按 seq 顺序逐片请求，校验 chunk_hash 后再写给浏览器；中断后从已收到的偏移量继续请求即可续传
+func (c *Client) handleChunkedDownloadChunk(message []byte) {
+	var msg struct {
+		RequestID string `json:"request_id"`
+		Payload   struct {
+			Path        string `json:"path"`
+			ContainerID string `json:"container_id"`
+			Seq         int    `json:"seq"`
+			Offset      int64  `json:"offset"`
+			Length      int64  `json:"length"`
+		} `json:"payload"`
+	}
+
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.log.Error("解析分片下载请求失败: %v", err)
+		return
+	}
+
+	fail := func(err error) {
+		c.log.Error("读取下载分片失败: path=%s, offset=%d, error=%v", msg.Payload.Path, msg.Payload.Offset, err)
+		c.sendResponse(msg.RequestID, "chunked_download_chunk_ack", map[string]interface{}{
+			"path":    msg.Payload.Path,
+			"seq":     msg.Payload.Seq,
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	if msg.Payload.Offset < 0 {
+		fail(fmt.Errorf("offset 不能为负数"))
+		return
+	}
+	length := c.downloadChunkLimit(msg.Payload.Length)
+
+	var chunk *downloadChunk
+	var err error
+	if msg.Payload.ContainerID != "" {
+		manager, mErr := NewContainerFileManager(c.log, msg.Payload.ContainerID, c.containerFileRoots())
+		if mErr != nil {
+			fail(mErr)
+			return
+		}
+		defer manager.Close()
+		chunk, err = manager.ReadRange(msg.Payload.Path, msg.Payload.Offset, length)
+	} else {
+		chunk, err = readHostFileRange(msg.Payload.Path, msg.Payload.Offset, length)
+	}
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	sum := sha256.Sum256(chunk.Data)
+	c.sendTransferResponse(msg.RequestID, "chunked_download_chunk_ack", map[string]interface{}{
+		"path":       msg.Payload.Path,
+		"seq":        msg.Payload.Seq,
+		"offset":     msg.Payload.Offset,
+		"size":       len(chunk.Data),
+		"total_size": chunk.TotalSize,
+		"mod_time":   chunk.ModTime,
+		"eof":        msg.Payload.Offset+int64(len(chunk.Data)) >= chunk.TotalSize,
+		"chunk_hash": hex.EncodeToString(sum[:]),
+		"content":    base64.StdEncoding.EncodeToString(chunk.Data),
+		"success":    true,
+	})
+}
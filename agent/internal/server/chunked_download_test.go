@@ -0,0 +1,52 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/config"
+)
+
+func TestReadHostFileRange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.bin")
+	assert.NoError(t, os.WriteFile(path, []byte("0123456789"), 0644))
+
+	chunk, err := readHostFileRange(path, 0, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, "0123", string(chunk.Data))
+	assert.Equal(t, int64(10), chunk.TotalSize)
+	assert.NotZero(t, chunk.ModTime)
+
+	// 最后一片不足 length 时只返回剩余部分
+	chunk, err = readHostFileRange(path, 8, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, "89", string(chunk.Data))
+
+	// 超出文件末尾返回空数据和文件大小
+	chunk, err = readHostFileRange(path, 10, 4)
+	assert.NoError(t, err)
+	assert.Empty(t, chunk.Data)
+	assert.Equal(t, int64(10), chunk.TotalSize)
+
+	_, err = readHostFileRange(filepath.Dir(path), 0, 4)
+	assert.Error(t, err)
+	_, err = readHostFileRange(filepath.Join(filepath.Dir(path), "..", "data.bin"), 0, 4)
+	assert.Error(t, err)
+}
+
+func TestDownloadChunkLimit(t *testing.T) {
+	c := &Client{cfg: &config.Config{}}
+	assert.Equal(t, int64(1024), c.downloadChunkLimit(1024))
+	assert.Equal(t, int64(downloadChunkMaxSize), c.downloadChunkLimit(0))
+	assert.Equal(t, int64(downloadChunkMaxSize), c.downloadChunkLimit(64*1024*1024))
+
+	// Base64 编码后的分片不能超过 max_response_mb
+	c.cfg.MaxResponseMB = 1
+	limit := c.downloadChunkLimit(downloadChunkMaxSize)
+	assert.Less(t, limit, int64(1024*1024))
+	assert.LessOrEqual(t, (limit+2)/3*4+4096, int64(1024*1024))
+}
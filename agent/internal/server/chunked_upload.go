@@ -35,7 +35,8 @@ type ChunkedUploadSession struct {
 	ContainerID string       // 非空则为容器上传
 	CreatedAt   time.Time
 	completing  bool         // 标记是否正在合并，阻止新分片写入
-	mu          sync.Mutex   // 保护 Received 和 completing 字段
+	limiter     *rateLimiter // 上传限速，首个分片到达时按当前配置创建
+	mu          sync.Mutex   // 保护 Received、completing 和 limiter 字段
 }
 
 // ChunkedUploadManager 管理多个分片上传会话
@@ -45,17 +46,27 @@ type ChunkedUploadManager struct {
 	log      *logger.Logger
 	stopCh   chan struct{}
 	once     sync.Once
+
+	containerRoots ContainerFileRoots // 写入容器时允许的目录前缀
 }
 
 // NewChunkedUploadManager 创建分片上传管理器
-func NewChunkedUploadManager(log *logger.Logger) *ChunkedUploadManager {
+func NewChunkedUploadManager(log *logger.Logger, containerRoots ContainerFileRoots) *ChunkedUploadManager {
 	return &ChunkedUploadManager{
-		sessions: make(map[string]*ChunkedUploadSession),
-		log:      log,
-		stopCh:   make(chan struct{}),
+		sessions:       make(map[string]*ChunkedUploadSession),
+		log:            log,
+		stopCh:         make(chan struct{}),
+		containerRoots: containerRoots,
 	}
 }
 
+// SetContainerRoots 更新写入容器时允许的目录前缀（配置热更新时调用）
+func (m *ChunkedUploadManager) SetContainerRoots(roots ContainerFileRoots) {
+	m.mu.Lock()
+	m.containerRoots = roots
+	m.mu.Unlock()
+}
+
 // Init 初始化一个分片上传会话，创建临时目录
 func (m *ChunkedUploadManager) Init(uploadID, path, filename string, totalSize, chunkSize int64, totalChunks int, containerID string) error {
 	if uploadID == "" || path == "" || filename == "" {
@@ -65,6 +76,30 @@ func (m *ChunkedUploadManager) Init(uploadID, path, filename string, totalSize,
 		return fmt.Errorf("totalSize, chunkSize, totalChunks 必须大于0")
 	}
 
+	// 与文件管理器共用路径校验，避免通过分片上传绕过目录穿越防护
+	flavor := hostPathFlavor
+	if containerID != "" {
+		flavor = containerPathFlavor
+	}
+	path, err := normalizePath(flavor, path, "")
+	if err != nil {
+		return err
+	}
+	filename, err = sanitizeFileName(filename)
+	if err != nil {
+		return err
+	}
+
+	// 分片先写入系统临时目录，再合并后移动到目标目录，两处空间不足时在传输开始前拒绝
+	if err := checkFreeSpace(os.TempDir(), totalSize); err != nil {
+		return err
+	}
+	if containerID == "" {
+		if err := checkFreeSpace(path, totalSize); err != nil {
+			return err
+		}
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -94,6 +129,20 @@ func (m *ChunkedUploadManager) Init(uploadID, path, filename string, totalSize,
 	return nil
 }
 
+// Limiter 返回会话的上传限速器，首次调用时按 bytesPerSec 创建；会话不存在时返回 nil（不限速）
+func (m *ChunkedUploadManager) Limiter(uploadID string, bytesPerSec int) *rateLimiter {
+	session, err := m.getSession(uploadID)
+	if err != nil {
+		return nil
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.limiter == nil {
+		session.limiter = newRateLimiter(bytesPerSec)
+	}
+	return session.limiter
+}
+
 // SaveChunk 保存一个分片到临时文件，并校验 SHA-256 哈希
 // 当 compressed=true 时，哈希校验针对传输数据（压缩后），然后解压再存储
 func (m *ChunkedUploadManager) SaveChunk(uploadID string, index int, data []byte, hash string, compressed bool) error {
@@ -153,7 +202,8 @@ func (m *ChunkedUploadManager) SaveChunk(uploadID string, index int, data []byte
 	// 幂等：如果已存在且重复写入，直接覆盖
 	chunkPath := filepath.Join(session.TempDir, fmt.Sprintf("%06d.part", index))
 	if err := os.WriteFile(chunkPath, writeData, 0644); err != nil {
-		return fmt.Errorf("写入分片失败: %w", err)
+		os.Remove(chunkPath)
+		return wrapNoSpace(session.TempDir, int64(len(writeData)), fmt.Errorf("写入分片失败: %w", err))
 	}
 
 	session.mu.Lock()
@@ -163,18 +213,19 @@ func (m *ChunkedUploadManager) SaveChunk(uploadID string, index int, data []byte
 	return nil
 }
 
-// Complete 合并所有分片为最终文件
-func (m *ChunkedUploadManager) Complete(uploadID, fileHash string) error {
+// Complete 合并所有分片为最终文件。verify 为 true 且目标是主机时，写入后回读最终文件，
+// 返回其 SHA-256 并与 fileHash 比对，用于发现 rename/copy 过程中的静默损坏
+func (m *ChunkedUploadManager) Complete(uploadID, fileHash string, verify bool) (string, error) {
 	session, err := m.getSession(uploadID)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// 标记为合并中，阻止新分片写入
 	session.mu.Lock()
 	if session.completing {
 		session.mu.Unlock()
-		return fmt.Errorf("上传会话已在合并中")
+		return "", fmt.Errorf("上传会话已在合并中")
 	}
 	session.completing = true
 	// 校验所有分片已接收
@@ -182,7 +233,7 @@ func (m *ChunkedUploadManager) Complete(uploadID, fileHash string) error {
 		if !session.Received[i] {
 			session.completing = false
 			session.mu.Unlock()
-			return fmt.Errorf("缺少分片 index=%d (已收到 %d/%d)", i, len(session.Received), session.TotalChunks)
+			return "", fmt.Errorf("缺少分片 index=%d (已收到 %d/%d)", i, len(session.Received), session.TotalChunks)
 		}
 	}
 	session.mu.Unlock()
@@ -190,28 +241,38 @@ func (m *ChunkedUploadManager) Complete(uploadID, fileHash string) error {
 	// 合并分片到临时文件
 	mergedPath := filepath.Join(session.TempDir, "merged_"+session.Filename)
 	if err := m.mergeChunks(session, mergedPath); err != nil {
-		return err
+		return "", err
 	}
 
 	// 校验最终文件哈希
 	if fileHash != "" {
 		ok, err := verifyFileHash(mergedPath, fileHash)
 		if err != nil {
-			return fmt.Errorf("校验文件哈希失败: %w", err)
+			return "", fmt.Errorf("校验文件哈希失败: %w", err)
 		}
 		if !ok {
-			return fmt.Errorf("最终文件哈希不匹配")
+			return "", fmt.Errorf("最终文件哈希不匹配")
 		}
 	}
 
 	// 写入最终位置
+	var sum string
 	if session.ContainerID == "" {
 		if err := m.completeToHost(session, mergedPath); err != nil {
-			return err
+			return "", err
+		}
+		if verify {
+			finalPath := filepath.Join(session.Path, session.Filename)
+			if sum, err = hashFile(finalPath); err != nil {
+				return "", fmt.Errorf("回读最终文件失败: %w", err)
+			}
+			if fileHash != "" && sum != fileHash {
+				return "", fmt.Errorf("写入后的文件哈希不匹配")
+			}
 		}
 	} else {
 		if err := m.completeToContainer(session, mergedPath); err != nil {
-			return err
+			return "", err
 		}
 	}
 
@@ -223,7 +284,7 @@ func (m *ChunkedUploadManager) Complete(uploadID, fileHash string) error {
 	m.mu.Unlock()
 	_ = os.RemoveAll(session.TempDir)
 
-	return nil
+	return sum, nil
 }
 
 // Cancel 取消上传并清理临时文件
@@ -313,13 +374,18 @@ func (m *ChunkedUploadManager) mergeChunks(session *ChunkedUploadSession, merged
 		}
 		if _, err := io.Copy(target, partFile); err != nil {
 			partFile.Close()
-			return fmt.Errorf("合并分片 %d 失败: %w", i, err)
+			return wrapNoSpace(session.TempDir, session.TotalSize, fmt.Errorf("合并分片 %d 失败: %w", i, err))
 		}
 		partFile.Close()
+		// 合并后立即删除分片，临时目录的峰值占用约为一份文件大小
+		os.Remove(chunkPath)
 	}
 
 	// 确保数据落盘
-	return target.Sync()
+	if err := target.Sync(); err != nil {
+		return wrapNoSpace(session.TempDir, session.TotalSize, err)
+	}
+	return nil
 }
 
 func (m *ChunkedUploadManager) completeToHost(session *ChunkedUploadSession, mergedPath string) error {
@@ -333,7 +399,9 @@ func (m *ChunkedUploadManager) completeToHost(session *ChunkedUploadSession, mer
 	// 尝试原子 rename（同一文件系统时高效）
 	if err := os.Rename(mergedPath, finalPath); err != nil {
 		// 跨文件系统时 fallback 到 copy
-		return copyFile(mergedPath, finalPath)
+		if err := copyFile(mergedPath, finalPath); err != nil {
+			return wrapNoSpace(session.Path, session.TotalSize, fmt.Errorf("写入目标文件失败: %w", err))
+		}
 	}
 	return nil
 }
@@ -346,7 +414,10 @@ func (m *ChunkedUploadManager) completeToContainer(session *ChunkedUploadSession
 	}
 
 	// 使用现有的 ContainerFileManager 写入容器
-	cfm, err := NewContainerFileManager(m.log, session.ContainerID)
+	m.mu.RLock()
+	roots := m.containerRoots
+	m.mu.RUnlock()
+	cfm, err := NewContainerFileManager(m.log, session.ContainerID, roots)
 	if err != nil {
 		return fmt.Errorf("创建容器文件管理器失败: %w", err)
 	}
@@ -375,17 +446,10 @@ func (m *ChunkedUploadManager) cleanupExpired() {
 }
 
 func verifyFileHash(path, expectedHash string) (bool, error) {
-	f, err := os.Open(path)
+	actual, err := hashFile(path)
 	if err != nil {
 		return false, err
 	}
-	defer f.Close()
-
-	h := sha256.New()
-	if _, err := io.Copy(h, f); err != nil {
-		return false, err
-	}
-	actual := hex.EncodeToString(h.Sum(nil))
 	return actual == expectedHash, nil
 }
 
@@ -2,6 +2,9 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -26,14 +29,27 @@ type Client struct {
 	cfg        *config.Config
 	log        *logger.Logger
 	httpClient *http.Client
+	tlsConfig  *tls.Config // 配置了面板证书指纹时只接受匹配的证书
 	wsConn     *websocket.Conn
 	secretKey  string // 服务器密钥
+	instanceID string // 本进程的随机实例标识，面板据此识别同一配置被部署到多台机器
 
 	// WebSocket连接状态管理
 	wsConnected      bool
 	wsMutex          sync.Mutex
 	wsShutdown       bool
 	reconnectHandler func()
+	pokeHandler      func() // 面板要求立即心跳时通知监控任务上报
+
+	// 配置文件路径及远程修改配置后的回调（通知监控任务重新加载）
+	configPath          string
+	configUpdateHandler func()
+	configMu            sync.Mutex
+	uptimeChecks        []monitor.UptimeCheck // 面板分配给本机执行的可用性检查
+	probePolicy         *monitor.ProbePolicy  // 面板下发的探测目标策略，旧版面板为 nil
+	meshConfig          monitor.MeshConfig    // 面板下发的节点互测列表
+	logPaths            []string              // 面板配置的日志采集路径
+	checkScripts        []monitor.CheckScript // 面板为本机配置的检查脚本
 
 	// WebSocket写入锁，防止并发写入
 	wsWriteMutex sync.Mutex // WebSocket写入锁
@@ -41,20 +57,56 @@ type Client struct {
 	// 升级并发保护：同一时间只允许一个升级任务
 	upgrading int32
 
+	// 升级前的排空状态：拒绝新命令并等待进行中的操作完成
+	drain drainState
+
+	// 面板设置的只读模式（本机配置见 cfg.ReadOnlyMode）
+	panelReadOnly atomic.Bool
+
+	// 运行时临时日志级别，到期自动恢复
+	logLevelMu       sync.Mutex
+	logLevelRevert   *time.Timer
+	logLevelRevertAt time.Time
+
+	// 聚焦查看时的临时上报间隔，按租约到期自动恢复
+	monitorRateMu      sync.Mutex
+	focusInterval      time.Duration
+	focusRevert        *time.Timer
+	monitorRateHandler func()
+
+	// 空闲降频状态：lowActivitySince 为连续低活动的起始时间
+	idle             bool
+	lowActivitySince time.Time
+
+	// Agent 自身的错误计数（发送失败、采集失败、重连、panic）
+	errStats errorStats
+
+	// Agent 级别的带宽上限，文件传输和日志流共享
+	bandwidth *rateLimiter
+
+	// 备用面板的只读镜像连接
+	secondary secondaryLink
+
+	// 断线期间的监控样本缓冲区，重新连接后补发
+	sampleBuffer *monitor.SampleBuffer
+
 	// 操作类功能字段（通过 build tag 控制）
 	clientOpsFields
 }
 
 // New 创建一个新的服务器客户端
 func New(config *config.Config, log *logger.Logger) *Client {
+	tlsConfig := BackendTLSConfig(config.ServerCertFingerprints)
 	c := &Client{
-		cfg: config,
-		log: log,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-		secretKey: config.SecretKey,
-	}
+		cfg:        config,
+		log:        log,
+		httpClient: newBackendHTTPClient(tlsConfig),
+		tlsConfig:  tlsConfig,
+		secretKey:  config.SecretKey,
+		instanceID: newInstanceID(),
+		bandwidth:  newRateLimiter(config.AgentBandwidthLimit * 1024),
+	}
+	c.errStats.since = time.Now()
 	c.initOpsFields()
 
 	// 将升级相关配置同步到环境变量，供 upgrader 包使用
@@ -65,6 +117,15 @@ func New(config *config.Config, log *logger.Logger) *Client {
 	return c
 }
 
+// newInstanceID 生成实例标识。不使用 machine-id：克隆的虚拟机通常带有相同的 machine-id
+func newInstanceID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
 // SetReconnectHandler 设置重连回调，供外部统一调度
 func (c *Client) SetReconnectHandler(handler func()) {
 	c.wsMutex.Lock()
@@ -72,6 +133,18 @@ func (c *Client) SetReconnectHandler(handler func()) {
 	c.reconnectHandler = handler
 }
 
+// SetConfigPath 设置配置文件路径，保存配置时写回该文件
+func (c *Client) SetConfigPath(path string) {
+	c.configPath = path
+}
+
+// SetConfigUpdateHandler 设置配置变更回调，用于通知监控任务应用新配置
+func (c *Client) SetConfigUpdateHandler(handler func()) {
+	c.wsMutex.Lock()
+	defer c.wsMutex.Unlock()
+	c.configUpdateHandler = handler
+}
+
 func (c *Client) triggerReconnect() {
 	c.wsMutex.Lock()
 	handler := c.reconnectHandler
@@ -94,26 +167,36 @@ func (c *Client) SendMonitorData(data *monitor.MonitorData) error {
 
 	c.log.Debug("通过WebSocket发送监控数据...")
 
+	// 随监控数据上报近期的错误数，面板据此发现错误频繁但仍在线的 Agent
+	data.AgentErrors = c.errStats.recent(recentErrorWindow, time.Now())
+
+	msg := struct {
+		Type    string               `json:"type"`
+		Payload *monitor.MonitorData `json:"payload"`
+	}{
+		Type:    "monitor",
+		Payload: data,
+	}
+
+	// 无论主面板是否发送成功，都镜像一份到备用面板
+	defer c.mirrorToSecondary(msg)
+
 	c.wsMutex.Lock()
 	wsConnected := c.wsConnected && c.wsConn != nil
 	c.wsMutex.Unlock()
 
 	if !wsConnected {
 		c.log.Warn("WebSocket未连接，无法发送监控数据")
+		c.RecordError(ErrorSend, "websocket未连接")
+		c.bufferMonitorSample(data)
 		c.triggerReconnect()
 		return fmt.Errorf("websocket未连接")
 	}
 
-	msg := struct {
-		Type    string               `json:"type"`
-		Payload *monitor.MonitorData `json:"payload"`
-	}{
-		Type:    "monitor",
-		Payload: data,
-	}
-
 	if err := c.writeJSON(msg); err != nil {
 		c.log.Warn("通过WebSocket发送监控数据失败: %v", err)
+		c.RecordError(ErrorSend, err)
+		c.bufferMonitorSample(data)
 
 		c.wsMutex.Lock()
 		c.wsConnected = false
@@ -140,6 +223,19 @@ func (c *Client) SendSystemInfo(info *monitor.SystemInfo) error {
 
 	c.log.Debug("通过WebSocket发送系统信息...")
 
+	msg := struct {
+		Type    string              `json:"type"`
+		Payload *monitor.SystemInfo `json:"payload"`
+		SentAt  int64               `json:"sent_at"` // 发送时间(Unix毫秒)，面板据此回复 time_sync 以估算时钟偏差
+	}{
+		Type:    "system_info",
+		Payload: info,
+		SentAt:  time.Now().UnixMilli(),
+	}
+
+	c.rememberSystemInfo(msg)
+	defer c.mirrorToSecondary(msg)
+
 	c.wsMutex.Lock()
 	wsConnected := c.wsConnected && c.wsConn != nil
 	c.wsMutex.Unlock()
@@ -150,14 +246,6 @@ func (c *Client) SendSystemInfo(info *monitor.SystemInfo) error {
 		return fmt.Errorf("websocket未连接")
 	}
 
-	msg := struct {
-		Type    string              `json:"type"`
-		Payload *monitor.SystemInfo `json:"payload"`
-	}{
-		Type:    "system_info",
-		Payload: info,
-	}
-
 	if err := c.writeJSON(msg); err != nil {
 		c.log.Warn("通过WebSocket发送系统信息失败: %v", err)
 
@@ -198,8 +286,31 @@ func (c *Client) ConnectWebSocket() error {
 
 	c.log.Debug("连接WebSocket...")
 
+	conn, url, err := c.dialAgentWebSocket(c.cfg.ServerURL)
+	if err != nil {
+		c.wsConnected = false // 确保连接状态为断开
+		return err
+	}
+
+	c.wsConn = conn
+	c.wsConnected = true // 设置连接状态
+	c.log.Info("WebSocket连接成功: %s", url)
+
+	// 聚焦状态以面板重新下发的为准，重连前的状态不再沿用
+	c.clearMonitorFocus()
+
+	// 开始监听消息
+	go c.handleWebSocketMessages()
+
+	// 补发断线期间缓冲的监控样本
+	go c.replayBufferedSamples()
+
+	return nil
+}
+
+// dialAgentWebSocket 依次尝试面板可能的 WebSocket 路径，返回建立的连接和实际使用的地址
+func (c *Client) dialAgentWebSocket(serverURL string) (*websocket.Conn, string, error) {
 	// 获取服务器URL（不带协议前缀）
-	serverURL := c.cfg.ServerURL
 	serverHost := removeProtocolPrefix(serverURL)
 
 	// 尝试可能的WebSocket URL路径
@@ -214,39 +325,35 @@ func (c *Client) ConnectWebSocket() error {
 	for _, path := range paths {
 		// 构建完整的WebSocket URL
 		wsProtocol := "ws://"
-		if strings.HasPrefix(c.cfg.ServerURL, "https://") {
+		if strings.HasPrefix(serverURL, "https://") {
 			wsProtocol = "wss://"
 		}
-		url := wsProtocol + serverHost + path + "?token=" + c.secretKey
+		url := wsProtocol + serverHost + path + "?token=" + c.secretKey + "&instance=" + c.instanceID
 
 		c.log.Debug("尝试连接WebSocket: %s", url)
 
 		// 尝试连接
-		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		conn, resp, err := c.wsDialer().Dial(url, nil)
+		if resp != nil && resp.StatusCode == http.StatusConflict {
+			// 面板检测到同一服务器ID的另一个 Agent 正在连接，换路径重试没有意义
+			return nil, "", fmt.Errorf("面板拒绝连接：服务器ID %d 已被另一台机器上的 Agent 使用，请检查是否重复部署了相同的配置", c.cfg.ServerID)
+		}
 		if err != nil {
 			c.log.Debug("连接失败: %v，尝试下一个路径", err)
 			lastError = err
 			continue
 		}
-
-		// 如果连接成功
-		c.wsConn = conn
-		c.wsConnected = true // 设置连接状态
-		c.log.Info("WebSocket连接成功: %s", url)
-
-		// 开始监听消息
-		go c.handleWebSocketMessages()
-
-		return nil
+		return conn, url, nil
 	}
 
 	// 所有路径都失败了
-	c.wsConnected = false // 确保连接状态为断开
-	return fmt.Errorf("WebSocket连接失败，尝试了所有可能的路径: %w", lastError)
+	return nil, "", fmt.Errorf("WebSocket连接失败，尝试了所有可能的路径: %w", lastError)
 }
 
 // CloseWebSocket 关闭WebSocket连接
 func (c *Client) CloseWebSocket() {
+	c.closeSecondary()
+
 	c.wsMutex.Lock()
 	defer c.wsMutex.Unlock()
 
@@ -303,12 +410,45 @@ func (c *Client) handleWebSocketMessages() {
 		msgCopy := make([]byte, len(message))
 		copy(msgCopy, message)
 
+		// 升级和修改配置在下面直接分发，不经过 handleOperationMessage，只读检查在分发前统一进行
+		if c.rejectControlIfReadOnly(baseMsg.Type, message) {
+			continue
+		}
+
 		// 根据消息类型使用不同的结构体解析
 		switch baseMsg.Type {
 		case "agent_upgrade":
 			// 处理Agent升级请求 - 委托给 upgrader 包的统一升级流程
 			go c.handleAgentUpgrade(msgCopy)
 
+		case "agent_log_level":
+			// 查询或临时调整日志级别
+			go c.handleLogLevel(msgCopy)
+
+		case "agent_error_stats":
+			// 查询或清零 Agent 内部错误计数
+			go c.handleErrorStats(msgCopy)
+
+		case "time_sync":
+			// 面板回复的时间戳，估算时钟偏差
+			go c.handleTimeSync(msgCopy)
+
+		case "update_config":
+			// 查询或远程修改 Agent 配置文件
+			go c.handleUpdateConfig(msgCopy)
+
+		case "agent_poke":
+			// 面板要求立即心跳或重新建立连接
+			go c.handlePoke(msgCopy)
+
+		case "read_only_mode":
+			// 面板切换只读模式
+			c.handleReadOnlyMode(msgCopy)
+
+		case "monitor_rate":
+			// 聚焦查看时临时调整上报间隔
+			c.handleMonitorRate(msgCopy)
+
 		case "error":
 			// Dashboard/Server 可能会返回 error 消息（例如服务端不识别某些响应类型）。
 			// 解析并输出可读信息，避免误报"未知类型"。
@@ -333,6 +473,11 @@ func (c *Client) handleWebSocketMessages() {
 			}
 
 		default:
+			// 升级排空期间不再接收新的操作命令
+			if c.isDraining() {
+				c.rejectWhileDraining(baseMsg.Type, message)
+				continue
+			}
 			// 将操作类消息和未知消息委托给 handleOperationMessage
 			// 该方法在 full 版本中处理所有操作命令，在 monitor 版本中拒绝操作命令
 			c.handleOperationMessage(baseMsg.Type, message, msgCopy)
@@ -358,6 +503,7 @@ func (c *Client) sendResponse(requestID, responseType string, data map[string]in
 	defer func() {
 		if r := recover(); r != nil {
 			c.log.Error("发送响应时panic: %v", r)
+			c.RecordError(ErrorPanic, r)
 		}
 	}()
 
@@ -367,35 +513,57 @@ func (c *Client) sendResponse(requestID, responseType string, data map[string]in
 		"data":       data,
 	}
 
+	payload, err := json.Marshal(response)
+	if err != nil {
+		c.log.Error("序列化WebSocket响应失败: type=%s, requestID=%s, error=%v", responseType, requestID, err)
+		return
+	}
+	payload = c.limitResponse(requestID, responseType, payload)
+
 	c.wsWriteMutex.Lock()
 	defer c.wsWriteMutex.Unlock()
 
 	if c.wsConn != nil {
-		if err := c.wsConn.WriteJSON(response); err != nil {
+		if err := c.wsConn.WriteMessage(websocket.TextMessage, payload); err != nil {
 			c.log.Error("发送WebSocket响应失败: type=%s, requestID=%s, error=%v", responseType, requestID, err)
+			c.RecordError(ErrorSend, err)
 		}
 	} else {
 		c.log.Error("WebSocket连接未建立，无法发送响应")
 	}
 }
 
-// RegisterAgent 向服务端注册 Agent
-func (c *Client) RegisterAgent(token string) (uint, string, error) {
+// RegisterAgent 向服务端注册 Agent。
+// info 为注册前探测的系统信息（可为 nil），面板据此预先填充操作系统、架构和能力，
+// 无需等待连接后的首次 system_info 上报
+func (c *Client) RegisterAgent(token string, info *monitor.SystemInfo) (uint, string, error) {
 	serverURL := ensureURLProtocol(c.cfg.ServerURL)
 	url := fmt.Sprintf("%s/api/servers/register", serverURL)
 
 	hostname, _ := os.Hostname()
 
+	// 名称和标签由面板校验，未配置的字段不提交，保持面板中的设置
 	payload := struct {
-		Token    string `json:"token"`
-		Hostname string `json:"hostname"`
+		Token       string   `json:"token"`
+		Hostname    string   `json:"hostname"`
+		Name        string   `json:"name,omitempty"`
+		Tags        []string `json:"tags,omitempty"`
+		Environment string   `json:"environment,omitempty"`
+		Group       string   `json:"group,omitempty"`
+
+		SystemInfo *monitor.SystemInfo `json:"system_info,omitempty"`
 	}{
-		Token:    token,
-		Hostname: hostname,
+		Token:       token,
+		Hostname:    hostname,
+		Name:        c.cfg.RegisterName,
+		Tags:        c.cfg.RegisterTags,
+		Environment: c.cfg.RegisterEnvironment,
+		Group:       c.cfg.RegisterGroup,
+		SystemInfo:  info,
 	}
 
 	body, _ := json.Marshal(payload)
-	resp, err := http.Post(url, "application/json", strings.NewReader(string(body)))
+	resp, err := c.httpClient.Post(url, "application/json", strings.NewReader(string(body)))
 	if err != nil {
 		return 0, "", fmt.Errorf("注册请求失败: %w", err)
 	}
@@ -406,11 +574,15 @@ func (c *Client) RegisterAgent(token string) (uint, string, error) {
 		ServerID uint   `json:"server_id"`
 		Secret   string `json:"secret_key"`
 		Message  string `json:"message"`
+		Error    string `json:"error"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return 0, "", fmt.Errorf("解析注册响应失败: %w", err)
 	}
 	if !result.Success {
+		if result.Error != "" {
+			return 0, "", fmt.Errorf("注册失败: %s", result.Error)
+		}
 		return 0, "", fmt.Errorf("注册失败: %s", result.Message)
 	}
 
@@ -495,6 +667,25 @@ func (c *Client) FetchSettings() error {
 		AgentReleaseRepo    string `json:"agent_release_repo"`
 		AgentReleaseChannel string `json:"agent_release_channel"`
 		AgentReleaseMirror  string `json:"agent_release_mirror"`
+		// 旧版面板不返回该字段，使用指针区分"未返回"和"已取消固定"
+		AgentPinnedVersion *string `json:"agent_pinned_version"`
+		// 带宽限制(KB/s)，旧版面板不返回时保持本地配置
+		TransferRateLimit   *int `json:"transfer_rate_limit"`
+		AgentBandwidthLimit *int `json:"agent_bandwidth_limit"`
+		// 上报的进程排行数，旧版面板不返回时保持本地配置
+		TopProcessCount *int `json:"top_process_count"`
+		// 面板设置的只读模式，旧版面板不返回时保持当前状态
+		ReadOnlyMode *bool `json:"read_only_mode"`
+		// 分配给本机的可用性检查，旧版面板不返回
+		UptimeChecks *[]monitor.UptimeCheck `json:"uptime_checks"`
+		// 探测目标策略，旧版面板不返回
+		ProbePolicy *monitor.ProbePolicy `json:"probe_policy"`
+		// 节点互测列表，旧版面板不返回
+		Mesh *monitor.MeshConfig `json:"mesh"`
+		// 日志采集路径，旧版面板不返回
+		LogPaths *[]string `json:"log_paths"`
+		// 检查脚本，旧版面板不返回
+		CheckScripts *[]monitor.CheckScript `json:"check_scripts"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
@@ -506,6 +697,10 @@ func (c *Client) FetchSettings() error {
 		return fmt.Errorf("服务器返回错误: %s", response.Message)
 	}
 
+	// 与远程修改配置互斥，避免两者同时写配置文件
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+
 	configChanged := false
 
 	// 更新Secret Key (如果服务器返回了新的值)
@@ -549,10 +744,58 @@ func (c *Client) FetchSettings() error {
 		configChanged = true
 	}
 
+	if response.AgentPinnedVersion != nil {
+		if pinned := version.Normalize(*response.AgentPinnedVersion); pinned != c.cfg.PinnedVersion {
+			c.log.Info("更新固定版本: %q -> %q", c.cfg.PinnedVersion, pinned)
+			c.cfg.PinnedVersion = pinned
+			configChanged = true
+		}
+	}
+
+	if limit := response.TransferRateLimit; limit != nil && *limit >= 0 && *limit != c.cfg.TransferRateLimit {
+		c.log.Info("更新单个传输带宽上限: %d KB/s -> %d KB/s", c.cfg.TransferRateLimit, *limit)
+		c.cfg.TransferRateLimit = *limit
+		configChanged = true
+	}
+
+	if limit := response.AgentBandwidthLimit; limit != nil && *limit >= 0 && *limit != c.cfg.AgentBandwidthLimit {
+		c.log.Info("更新Agent总带宽上限: %d KB/s -> %d KB/s", c.cfg.AgentBandwidthLimit, *limit)
+		c.cfg.AgentBandwidthLimit = *limit
+		c.applyBandwidthLimit()
+		configChanged = true
+	}
+
+	if count := response.TopProcessCount; count != nil && *count >= 0 && *count != c.cfg.TopProcesses {
+		c.log.Info("更新上报的进程排行数: %d -> %d", c.cfg.TopProcesses, *count)
+		c.cfg.TopProcesses = *count
+		configChanged = true
+	}
+
+	// 只读模式只在内存中生效，不写入配置文件，面板关闭后即可恢复
+	if response.ReadOnlyMode != nil {
+		c.setPanelReadOnly(*response.ReadOnlyMode)
+	}
+
+	if response.UptimeChecks != nil {
+		c.uptimeChecks = *response.UptimeChecks
+	}
+	if response.ProbePolicy != nil {
+		c.probePolicy = response.ProbePolicy
+	}
+	if response.Mesh != nil {
+		c.meshConfig = *response.Mesh
+	}
+	if response.LogPaths != nil {
+		c.logPaths = *response.LogPaths
+	}
+	if response.CheckScripts != nil {
+		c.checkScripts = *response.CheckScripts
+	}
+
 	// 保存更新后的配置
 	if configChanged {
 		c.log.Info("配置已更新，正在保存...")
-		if err := config.SaveConfig(c.cfg, ""); err != nil {
+		if err := config.SaveConfig(c.cfg, c.configPath); err != nil {
 			c.log.Error("保存配置失败: %s", err)
 		} else {
 			c.log.Info("配置已保存")
@@ -564,6 +807,44 @@ func (c *Client) FetchSettings() error {
 	return nil
 }
 
+// UptimeChecks 返回面板分配给本机执行的可用性检查
+func (c *Client) UptimeChecks() []monitor.UptimeCheck {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	return c.uptimeChecks
+}
+
+// ProbePolicy 返回面板下发的探测目标策略，旧版面板未下发时为 nil
+func (c *Client) ProbePolicy() *monitor.ProbePolicy {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	return c.probePolicy
+}
+
+// MeshConfig 返回面板下发的节点互测列表
+func (c *Client) MeshConfig() monitor.MeshConfig {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	return c.meshConfig
+}
+
+// LogPaths 返回面板配置的日志采集路径
+func (c *Client) LogPaths() []string {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	return c.logPaths
+}
+
+// CheckScripts 返回面板配置的检查脚本，本机禁用了检查脚本（allow_check_scripts=false）或处于只读模式时返回空
+func (c *Client) CheckScripts() []monitor.CheckScript {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	if !c.cfg.AllowCheckScripts || c.isReadOnly() {
+		return nil
+	}
+	return c.checkScripts
+}
+
 // IsConnected 检查WebSocket连接是否正常连接
 func (c *Client) IsConnected() bool {
 	c.wsMutex.Lock()
@@ -619,6 +900,7 @@ type agentUpgradePayload struct {
 	DownloadURL     string `json:"download_url,omitempty"`
 	SHA256          string `json:"sha256,omitempty"`
 	TargetAgentType string `json:"target_agent_type,omitempty"`
+	AllowDowngrade  bool   `json:"allow_downgrade,omitempty"` // 显式允许安装低于当前版本的目标
 }
 
 // handleAgentUpgrade 处理面板端下发的升级指令，委托给 upgrader 包执行
@@ -685,6 +967,17 @@ func (c *Client) handleAgentUpgrade(message []byte) {
 		return
 	}
 
+	if err := c.checkUpgradeTarget(safeVersion(current), p); err != nil {
+		c.log.Warn("拒绝升级: %v", err)
+		c.sendUpgradeStatus(requestID, "failed", err.Error(), map[string]interface{}{
+			"rejected":        true,
+			"current_version": safeVersion(current),
+			"target_version":  p.TargetVersion,
+			"pinned_version":  c.cfg.PinnedVersion,
+		})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
 	defer cancel()
 
@@ -699,6 +992,9 @@ func (c *Client) handleAgentUpgrade(message []byte) {
 		SecretKey:       c.secretKey,
 		Args:            os.Args,
 		Env:             os.Environ(),
+		BeforeRestart: func() {
+			c.drainForUpgrade(requestID)
+		},
 	}
 
 	c.sendUpgradeStatus(requestID, "starting", "开始执行升级流程", map[string]interface{}{
@@ -725,6 +1021,7 @@ func (c *Client) handleAgentUpgrade(message []byte) {
 		c.sendUpgradeStatus(requestID, pr.Status, pr.Message, fields)
 	})
 	if err != nil {
+		c.abortDrain()
 		c.sendUpgradeStatus(requestID, "failed", fmt.Sprintf("升级失败: %v", err), nil)
 		return
 	}
@@ -734,6 +1031,18 @@ func (c *Client) handleAgentUpgrade(message []byte) {
 	c.sendUpgradeStatus(requestID, "success", "升级流程完成", nil)
 }
 
+// checkUpgradeTarget 校验目标版本：固定了版本时只允许安装固定版本，降级需要显式允许
+func (c *Client) checkUpgradeTarget(current string, p agentUpgradePayload) error {
+	target := version.Normalize(p.TargetVersion)
+	if pinned := version.Normalize(c.cfg.PinnedVersion); pinned != "" && target != pinned {
+		return fmt.Errorf("Agent已固定在版本 %s，拒绝安装 %s；如需变更请先修改固定版本", pinned, target)
+	}
+	if cmp, ok := version.Compare(target, current); ok && cmp < 0 && !p.AllowDowngrade {
+		return fmt.Errorf("目标版本 %s 低于当前版本 %s，降级需要显式指定 allow_downgrade", target, current)
+	}
+	return nil
+}
+
 func safeVersion(info *version.Info) string {
 	if info == nil {
 		return ""
@@ -20,11 +20,38 @@ type clientOpsFields struct {
 	logStreams     map[string]*logStreamSession
 	logStreamsLock sync.Mutex
 
+	// 容器资源统计流，key: streamID
+	statsStreams     map[string]context.CancelFunc
+	statsStreamsLock sync.Mutex
+
+	// 进行中的镜像拉取，key: streamID
+	pullStreams     map[string]context.CancelFunc
+	pullStreamsLock sync.Mutex
+
 	// 容器文件管理器临时缓存（按请求周期使用）
 	dockerFileManagers sync.Map // key: requestID, value: *ContainerFileManager
 
 	// 分片上传管理器
 	chunkedUploadMgr *ChunkedUploadManager
+
+	// 进行中的目录打包下载
+	archives sync.Map // key: archiveID, value: *archiveSession
+
+	// 进行中的文件搜索/磁盘占用扫描
+	fileScans    sync.Map   // key: scanID, value: context.CancelFunc
+	fileScanLock sync.Mutex // 保护扫描统计信息
+
+	// 进行中的网络诊断（ping/traceroute/mtr）
+	diagnostics sync.Map // key: streamID, value: context.CancelFunc
+
+	// 进行中的宿主机文件跟踪
+	fileTails sync.Map // key: streamID, value: context.CancelFunc
+
+	// 进行中的 journal 查询，用于响应 stop 请求
+	journalQueries sync.Map // key: streamID, value: context.CancelFunc
+
+	// 命令输出采集
+	captures captureManager
 }
 
 // containerExecSession 容器 exec 会话
@@ -42,12 +69,30 @@ type logStreamSession struct {
 	stopCh      chan struct{}            // 通知读取 goroutine 停止
 	containerID string
 	manager     *monitor.DockerManager  // 持有引用以便关闭时释放
+	filter      *logLineFilter          // 行过滤器，nil 表示不过滤
+	limiter     *rateLimiter            // 单个日志流的带宽上限
 }
 
 // initOpsFields 初始化操作类字段
 func (c *Client) initOpsFields() {
 	c.dockerSessions = make(map[string]*containerExecSession)
 	c.logStreams = make(map[string]*logStreamSession)
-	c.chunkedUploadMgr = NewChunkedUploadManager(c.log)
+	c.statsStreams = make(map[string]context.CancelFunc)
+	c.pullStreams = make(map[string]context.CancelFunc)
+	c.chunkedUploadMgr = NewChunkedUploadManager(c.log, c.containerFileRoots())
 	c.chunkedUploadMgr.StartCleanup()
 }
+
+// applyOpsConfig 配置热更新后同步操作类组件持有的配置副本
+func (c *Client) applyOpsConfig() {
+	c.chunkedUploadMgr.SetContainerRoots(c.containerFileRoots())
+	monitor.SetNginxSnapshotKeep(c.cfg.NginxSnapshotKeep)
+}
+
+// containerFileRoots 从配置构造容器文件操作的目录前缀限制
+func (c *Client) containerFileRoots() ContainerFileRoots {
+	return ContainerFileRoots{
+		Global:       c.cfg.ContainerFileRoots,
+		PerContainer: c.cfg.ContainerFileRootsByContainer,
+	}
+}
@@ -7,3 +7,6 @@ type clientOpsFields struct{}
 
 // initOpsFields 监控版无需初始化操作类字段
 func (c *Client) initOpsFields() {}
+
+// applyOpsConfig 监控版无操作类组件需要同步配置
+func (c *Client) applyOpsConfig() {}
@@ -14,12 +14,18 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gorilla/websocket"
+	"github.com/user/server-ops-agent/internal/backups"
 	"github.com/user/server-ops-agent/internal/monitor"
 )
 
 // handleOperationMessage 处理操作类消息（全功能版）
 // 包含终端、文件、进程、Docker、Nginx、Shell 等操作命令的路由
 func (c *Client) handleOperationMessage(msgType string, message []byte, msgCopy []byte) {
+	if c.rejectIfReadOnly(msgType, message) {
+		return
+	}
+
 	switch msgType {
 	case "terminal_input":
 		var termMsg struct {
@@ -68,46 +74,97 @@ func (c *Client) handleOperationMessage(msgType string, message []byte, msgCopy
 		c.handleTerminalClose(closeMsg.SessionID)
 
 	case "file_list":
-		go c.handleFileList(msgCopy)
+		c.runOperation(c.handleFileList, msgCopy)
 
 	case "file_content":
-		go c.handleFileContent(msgCopy)
+		c.runOperation(c.handleFileContent, msgCopy)
 
 	case "file_upload":
-		go c.handleFileUpload(msgCopy)
+		c.runOperation(c.handleFileUpload, msgCopy)
 
 	case "docker_file":
-		go c.handleDockerFile(msgCopy)
+		c.runOperation(c.handleDockerFile, msgCopy)
 
 	case "process_list":
-		go c.handleProcessList(msgCopy)
+		c.runOperation(c.handleProcessList, msgCopy)
 
 	case "process_kill":
-		go c.handleProcessKill(msgCopy)
+		c.runOperation(c.handleProcessKill, msgCopy)
+
+	case "process_kill_by_name":
+		c.runOperation(c.handleProcessKillByName, msgCopy)
+
+	case "connection_list":
+		c.runOperation(c.handleConnectionList, msgCopy)
+	case "listening_ports":
+		c.runOperation(c.handleListeningPorts, msgCopy)
+	case "netstat":
+		c.runOperation(c.handleNetstat, msgCopy)
+	case "network_benchmark":
+		c.runOperation(c.handleNetworkBenchmark, msgCopy)
 
 	case "docker_command":
-		go c.handleDockerCommand(msgCopy)
+		c.runOperation(c.handleDockerCommand, msgCopy)
 
 	case "docker_logs_stream":
-		go c.handleDockerLogsStream(msgCopy)
+		c.runStream(c.handleDockerLogsStream, msgCopy)
+	case "docker_stats_stream":
+		c.runStream(c.handleDockerStatsStream, msgCopy)
+	case "docker_pull_stream":
+		c.runOperation(c.handleDockerPullStream, msgCopy)
+	case "diagnostic_stream":
+		c.runOperation(c.handleDiagnosticStream, msgCopy)
+
+	case "file_tail_stream":
+		c.runStream(c.handleFileTailStream, msgCopy)
+	case "journal_query":
+		c.runOperation(c.handleJournalQuery, msgCopy)
+
+	case "file_scan":
+		c.runOperation(c.handleFileScan, msgCopy)
+	case "file_search":
+		c.runOperation(c.handleFileSearch, msgCopy)
+
+	case "log_rotate":
+		c.runOperation(c.handleLogRotate, msgCopy)
+	case "command_capture":
+		c.runOperation(c.handleCommandCapture, msgCopy)
+
+	case "file_diff":
+		c.runOperation(c.handleFileDiff, msgCopy)
+
+	case "file_snapshot":
+		c.runOperation(c.handleFileSnapshot, msgCopy)
 
 	case "nginx_command":
-		go c.handleNginxCommand(msgCopy)
+		c.runOperation(c.handleNginxCommand, msgCopy)
+
+	case "service_command":
+		c.runOperation(c.handleServiceCommand, msgCopy)
+
+	case "package_command":
+		c.runOperation(c.handlePackageCommand, msgCopy)
 
 	case "shell_command":
-		go c.handleShellCommand(msgCopy)
+		c.runStream(c.handleShellCommand, msgCopy)
 
 	case "chunked_upload_init":
-		go c.handleChunkedUploadInit(msgCopy)
+		c.runOperation(c.handleChunkedUploadInit, msgCopy)
 
 	case "chunked_upload_chunk":
-		go c.handleChunkedUploadChunk(msgCopy)
+		c.runOperation(c.handleChunkedUploadChunk, msgCopy)
 
 	case "chunked_upload_complete":
-		go c.handleChunkedUploadComplete(msgCopy)
+		c.runOperation(c.handleChunkedUploadComplete, msgCopy)
 
 	case "chunked_upload_cancel":
-		go c.handleChunkedUploadCancel(msgCopy)
+		c.runOperation(c.handleChunkedUploadCancel, msgCopy)
+
+	case "chunked_download_chunk":
+		c.runOperation(c.handleChunkedDownloadChunk, msgCopy)
+
+	case "file_archive":
+		c.runOperation(c.handleFileArchive, msgCopy)
 
 	default:
 		c.log.Warn("收到未知类型的WebSocket消息: %s", msgType)
@@ -641,6 +698,15 @@ func (c *Client) handleFileContent(message []byte) {
 			Path    string `json:"path"`
 			Action  string `json:"action"`
 			Content string `json:"content"`
+			Verify  bool   `json:"verify"` // 写入后回读文件并返回 SHA-256
+
+			// chmod/chown/rename/move/copy 使用的参数
+			Mode      string `json:"mode"`      // chmod: 八进制权限，如 "755"
+			Owner     string `json:"owner"`     // chown: 用户名或 UID
+			Group     string `json:"group"`     // chown: 组名或 GID
+			Recursive bool   `json:"recursive"` // chmod/chown: 是否递归修改目录下的条目
+			Name      string `json:"name"`      // rename: 新名称
+			Target    string `json:"target"`    // move/copy: 目标路径，已存在的目录表示放入其中
 		} `json:"payload"`
 	}
 
@@ -667,10 +733,15 @@ func (c *Client) handleFileContent(message []byte) {
 			return
 		}
 
-		c.sendResponse(req.RequestID, "file_content_response", map[string]interface{}{
+		data := map[string]interface{}{
 			"path":    req.Payload.Path,
 			"content": content,
-		})
+		}
+		// 附带权限和所有者，便于编辑前确认文件归属
+		if info, err := fileManager.StatFile(req.Payload.Path); err == nil {
+			data["info"] = info
+		}
+		c.sendTransferResponse(req.RequestID, "file_content_response", data)
 		c.log.Debug("文件内容获取成功: %s (%d字节)", req.Payload.Path, len(content))
 
 	case "save":
@@ -679,6 +750,7 @@ func (c *Client) handleFileContent(message []byte) {
 		defer func() {
 			if r := recover(); r != nil {
 				c.log.Error("保存文件时发生严重错误: %v", r)
+				c.RecordError(ErrorPanic, r)
 				c.sendResponse(req.RequestID, "error", map[string]interface{}{
 					"error": fmt.Sprintf("保存文件时发生严重错误: %v", r),
 				})
@@ -686,59 +758,71 @@ func (c *Client) handleFileContent(message []byte) {
 		}()
 
 		backupPath := req.Payload.Path + ".bak"
+		hasBackup := false
 		if _, err := os.Stat(req.Payload.Path); err == nil {
 			c.log.Debug("创建文件备份: %s -> %s", req.Payload.Path, backupPath)
 			backupContent, readErr := os.ReadFile(req.Payload.Path)
 			if readErr == nil {
-				_ = os.WriteFile(backupPath, backupContent, 0644)
+				if writeErr := os.WriteFile(backupPath, backupContent, 0644); writeErr == nil {
+					backups.Track(backupPath)
+					hasBackup = true
+				} else {
+					// 写了一半的备份不能用于恢复，删除后继续保存
+					os.Remove(backupPath)
+					c.log.Warn("创建文件备份失败，继续保存: %v", writeErr)
+				}
 			}
 		}
 
 		if err := fileManager.SaveFileContent(req.Payload.Path, req.Payload.Content); err != nil {
 			c.log.Error("保存文件内容失败: %v", err)
+			resp := fileErrorData(err)
 
-			if _, statErr := os.Stat(backupPath); statErr == nil {
-				c.log.Info("尝试从备份恢复文件: %s", backupPath)
-				if backupContent, readErr := os.ReadFile(backupPath); readErr == nil {
-					if writeErr := os.WriteFile(req.Payload.Path, backupContent, 0644); writeErr == nil {
-						c.log.Info("成功从备份恢复文件")
-					} else {
-						c.log.Error("从备份恢复文件失败: %v", writeErr)
-					}
+			if hasBackup {
+				if restoreErr := restoreFromBackup(req.Payload.Path, backupPath); restoreErr != nil {
+					c.log.Error("从备份恢复文件失败: %v", restoreErr)
+					resp["restore_error"] = restoreErr.Error()
+					resp["backup_path"] = backupPath
 				}
 			}
 
-			c.sendResponse(req.RequestID, "error", map[string]interface{}{
-				"error": err.Error(),
-			})
+			c.sendResponse(req.RequestID, "error", resp)
 			return
 		}
 
-		if _, err := os.Stat(backupPath); err == nil {
-			_ = os.Remove(backupPath)
-		}
+		// 保留备份用于查看本次改动（file_diff），由 backup_max_age 统一清理
 
 		c.log.Debug("文件保存成功: %s", req.Payload.Path)
-		c.sendResponse(req.RequestID, "file_content_response", map[string]interface{}{
+		resp := map[string]interface{}{
 			"path":    req.Payload.Path,
 			"success": true,
 			"message": "文件保存成功",
-		})
+		}
+		if req.Payload.Verify {
+			c.attachChecksum(resp, func() (string, int64, error) {
+				return fileManager.FileChecksum(req.Payload.Path)
+			})
+		}
+		c.sendResponse(req.RequestID, "file_content_response", resp)
 
 	case "create":
 		if err := fileManager.CreateFile(req.Payload.Path, req.Payload.Content); err != nil {
 			c.log.Error("创建文件失败: %v", err)
-			c.sendResponse(req.RequestID, "error", map[string]interface{}{
-				"error": err.Error(),
-			})
+			c.sendResponse(req.RequestID, "error", fileErrorData(err))
 			return
 		}
 
-		c.sendResponse(req.RequestID, "file_content_response", map[string]interface{}{
+		resp := map[string]interface{}{
 			"path":    req.Payload.Path,
 			"success": true,
 			"message": "文件创建成功",
-		})
+		}
+		if req.Payload.Verify {
+			c.attachChecksum(resp, func() (string, int64, error) {
+				return fileManager.FileChecksum(req.Payload.Path)
+			})
+		}
+		c.sendResponse(req.RequestID, "file_content_response", resp)
 
 	case "mkdir":
 		if err := fileManager.CreateDirectory(req.Payload.Path); err != nil {
@@ -777,6 +861,55 @@ func (c *Client) handleFileContent(message []byte) {
 			"tree": tree,
 		})
 
+	case "chmod", "chown":
+		var err error
+		if req.Payload.Action == "chmod" {
+			err = fileManager.Chmod(req.Payload.Path, req.Payload.Mode, req.Payload.Recursive)
+		} else {
+			err = fileManager.Chown(req.Payload.Path, req.Payload.Owner, req.Payload.Group, req.Payload.Recursive)
+		}
+		if err != nil {
+			c.log.Error("修改文件属性失败: %v", err)
+			c.sendResponse(req.RequestID, "error", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		resp := map[string]interface{}{
+			"path":    req.Payload.Path,
+			"success": true,
+			"message": "文件属性修改成功",
+		}
+		if info, err := fileManager.StatFile(req.Payload.Path); err == nil {
+			resp["info"] = info
+		}
+		c.sendResponse(req.RequestID, "file_content_response", resp)
+
+	case "rename", "move", "copy":
+		var target string
+		var err error
+		switch req.Payload.Action {
+		case "rename":
+			target, err = fileManager.Rename(req.Payload.Path, req.Payload.Name)
+		case "move":
+			target, err = fileManager.Move(req.Payload.Path, req.Payload.Target)
+		default:
+			target, err = fileManager.Copy(req.Payload.Path, req.Payload.Target)
+		}
+		if err != nil {
+			c.log.Error("文件操作 %s 失败: %v", req.Payload.Action, err)
+			c.sendResponse(req.RequestID, "error", fileErrorData(err))
+			return
+		}
+
+		c.sendResponse(req.RequestID, "file_content_response", map[string]interface{}{
+			"path":    req.Payload.Path,
+			"target":  target,
+			"success": true,
+			"message": "文件操作成功",
+		})
+
 	default:
 		c.log.Error("未知的文件操作: %s", req.Payload.Action)
 		c.sendResponse(req.RequestID, "error", map[string]interface{}{
@@ -793,6 +926,7 @@ func (c *Client) handleFileUpload(message []byte) {
 			Path     string `json:"path"`
 			Filename string `json:"filename"`
 			Content  string `json:"content"` // Base64编码的文件内容
+			Verify   bool   `json:"verify"`  // 写入后回读文件并返回 SHA-256
 		} `json:"payload"`
 	}
 
@@ -811,18 +945,24 @@ func (c *Client) handleFileUpload(message []byte) {
 	err := fileManager.UploadFile(msg.Payload.Path, msg.Payload.Filename, msg.Payload.Content)
 	if err != nil {
 		c.log.Error("上传文件失败: %v", err)
-		c.sendResponse(msg.RequestID, "error", map[string]interface{}{
+		c.sendResponse(msg.RequestID, "error", addDiskFullFields(map[string]interface{}{
 			"error": fmt.Sprintf("上传文件失败: %v", err),
-		})
+		}, err))
 		return
 	}
 
-	c.sendResponse(msg.RequestID, "file_upload_response", map[string]interface{}{
+	resp := map[string]interface{}{
 		"path":     msg.Payload.Path,
 		"filename": msg.Payload.Filename,
 		"success":  true,
 		"message":  "文件上传成功",
-	})
+	}
+	if msg.Payload.Verify {
+		c.attachChecksum(resp, func() (string, int64, error) {
+			return fileManager.uploadChecksum(msg.Payload.Path, msg.Payload.Filename)
+		})
+	}
+	c.sendResponse(msg.RequestID, "file_upload_response", resp)
 
 	c.log.Info("文件已上传: %s/%s", msg.Payload.Path, msg.Payload.Filename)
 }
@@ -863,11 +1003,11 @@ func (c *Client) handleChunkedUploadInit(message []byte) {
 	)
 	if err != nil {
 		c.log.Error("分片上传初始化失败: %v", err)
-		c.sendResponse(msg.RequestID, "chunked_upload_init_ack", map[string]interface{}{
+		c.sendResponse(msg.RequestID, "chunked_upload_init_ack", addDiskFullFields(map[string]interface{}{
 			"upload_id": msg.Payload.UploadID,
 			"success":   false,
 			"error":     err.Error(),
-		})
+		}, err))
 		return
 	}
 
@@ -912,15 +1052,19 @@ func (c *Client) handleChunkedUploadChunk(message []byte) {
 	if err := c.chunkedUploadMgr.SaveChunk(msg.Payload.UploadID, msg.Payload.ChunkIndex, data, msg.Payload.ChunkHash, msg.Payload.Compressed); err != nil {
 		c.log.Error("保存分片失败: upload_id=%s, index=%d, error=%v",
 			msg.Payload.UploadID, msg.Payload.ChunkIndex, err)
-		c.sendResponse(msg.RequestID, "chunked_upload_chunk_ack", map[string]interface{}{
+		c.sendResponse(msg.RequestID, "chunked_upload_chunk_ack", addDiskFullFields(map[string]interface{}{
 			"upload_id":   msg.Payload.UploadID,
 			"chunk_index": msg.Payload.ChunkIndex,
 			"success":     false,
 			"error":       err.Error(),
-		})
+		}, err))
 		return
 	}
 
+	// 前端收到确认后才发送下一个分片，按带宽上限延迟确认即可限制上传速度
+	limiter := c.chunkedUploadMgr.Limiter(msg.Payload.UploadID, c.cfg.TransferRateLimit*1024)
+	waitAll(len(data), limiter, c.bandwidth)
+
 	c.sendResponse(msg.RequestID, "chunked_upload_chunk_ack", map[string]interface{}{
 		"upload_id":   msg.Payload.UploadID,
 		"chunk_index": msg.Payload.ChunkIndex,
@@ -935,6 +1079,7 @@ func (c *Client) handleChunkedUploadComplete(message []byte) {
 		Payload   struct {
 			UploadID string `json:"upload_id"`
 			FileHash string `json:"file_hash"`
+			Verify   bool   `json:"verify"` // 写入最终位置后回读文件并返回 SHA-256
 		} `json:"payload"`
 	}
 
@@ -945,20 +1090,25 @@ func (c *Client) handleChunkedUploadComplete(message []byte) {
 
 	c.log.Info("收到分片上传完成请求: upload_id=%s", msg.Payload.UploadID)
 
-	if err := c.chunkedUploadMgr.Complete(msg.Payload.UploadID, msg.Payload.FileHash); err != nil {
+	sum, err := c.chunkedUploadMgr.Complete(msg.Payload.UploadID, msg.Payload.FileHash, msg.Payload.Verify)
+	if err != nil {
 		c.log.Error("分片上传合并失败: upload_id=%s, error=%v", msg.Payload.UploadID, err)
-		c.sendResponse(msg.RequestID, "chunked_upload_complete_ack", map[string]interface{}{
+		c.sendResponse(msg.RequestID, "chunked_upload_complete_ack", addDiskFullFields(map[string]interface{}{
 			"upload_id": msg.Payload.UploadID,
 			"success":   false,
 			"error":     err.Error(),
-		})
+		}, err))
 		return
 	}
 
-	c.sendResponse(msg.RequestID, "chunked_upload_complete_ack", map[string]interface{}{
+	resp := map[string]interface{}{
 		"upload_id": msg.Payload.UploadID,
 		"success":   true,
-	})
+	}
+	if sum != "" {
+		resp["sha256"] = sum
+	}
+	c.sendResponse(msg.RequestID, "chunked_upload_complete_ack", resp)
 }
 
 // handleChunkedUploadCancel 处理取消分片上传请求
@@ -1024,7 +1174,7 @@ func (c *Client) handleDockerFile(message []byte) {
 		return
 	}
 
-	manager, err := NewContainerFileManager(c.log, msg.Payload.ContainerID)
+	manager, err := NewContainerFileManager(c.log, msg.Payload.ContainerID, c.containerFileRoots())
 	if err != nil {
 		c.log.Error("创建容器文件管理器失败: %v", err)
 		c.sendResponse(msg.RequestID, "error", map[string]interface{}{
@@ -1140,7 +1290,7 @@ func (c *Client) handleDockerFile(message []byte) {
 			})
 			return
 		}
-		c.sendResponse(msg.RequestID, "docker_file_content", map[string]interface{}{
+		c.sendTransferResponse(msg.RequestID, "docker_file_content", map[string]interface{}{
 			"path":    msg.Payload.Path,
 			"content": base64.StdEncoding.EncodeToString(data),
 		})
@@ -1203,6 +1353,7 @@ func (c *Client) handleProcessList(message []byte) {
 	c.sendResponse(msg.RequestID, "process_list_response", map[string]interface{}{
 		"processes": processes,
 		"count":     len(processes),
+		"zombies":   monitor.CountZombieProcesses(processes),
 		"timestamp": time.Now().Unix(),
 	})
 
@@ -1214,7 +1365,8 @@ func (c *Client) handleProcessKill(message []byte) {
 	var msg struct {
 		RequestID string `json:"request_id"`
 		Payload   struct {
-			PID int32 `json:"pid"`
+			PID    int32  `json:"pid"`
+			Signal string `json:"signal"` // TERM / KILL，为空时保持原有的强制终止行为
 		} `json:"payload"`
 	}
 
@@ -1226,7 +1378,7 @@ func (c *Client) handleProcessKill(message []byte) {
 		return
 	}
 
-	c.log.Info("收到进程终止请求: PID=%d", msg.Payload.PID)
+	c.log.Info("收到进程终止请求: PID=%d, 信号=%s", msg.Payload.PID, msg.Payload.Signal)
 
 	pm := monitor.NewProcessManager(c.log)
 
@@ -1239,7 +1391,7 @@ func (c *Client) handleProcessKill(message []byte) {
 		return
 	}
 
-	if err := pm.KillProcess(msg.Payload.PID); err != nil {
+	if err := pm.KillProcessWithSignal(msg.Payload.PID, msg.Payload.Signal); err != nil {
 		c.log.Error("终止进程 %d 失败: %v", msg.Payload.PID, err)
 		c.sendResponse(msg.RequestID, "error", map[string]interface{}{
 			"error": fmt.Sprintf("终止进程失败: %v", err),
@@ -1258,6 +1410,205 @@ func (c *Client) handleProcessKill(message []byte) {
 	c.log.Info("进程 %d(%s) 已成功终止", msg.Payload.PID, proc.Name)
 }
 
+// handleProcessKillByName 按名称或命令行模式批量终止进程。
+// dry_run 时只返回匹配的进程供预览；确认时只终止预览中选定的 PID，受保护的进程始终跳过
+func (c *Client) handleProcessKillByName(message []byte) {
+	var msg struct {
+		RequestID string `json:"request_id"`
+		Payload   struct {
+			Pattern string  `json:"pattern"`
+			Cmdline bool    `json:"cmdline"` // 同时匹配完整命令行
+			DryRun  bool    `json:"dry_run"`
+			PIDs    []int32 `json:"pids"`
+			Signal  string  `json:"signal"`
+		} `json:"payload"`
+	}
+
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.log.Error("解析批量终止进程请求失败: %v", err)
+		return
+	}
+
+	pm := monitor.NewProcessManager(c.log)
+	if msg.Payload.DryRun {
+		matches, err := pm.MatchProcesses(msg.Payload.Pattern, msg.Payload.Cmdline)
+		if err != nil {
+			c.sendResponse(msg.RequestID, "process_kill_by_name_response", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.sendResponse(msg.RequestID, "process_kill_by_name_response", map[string]interface{}{
+			"dry_run": true,
+			"matches": matches,
+		})
+		return
+	}
+
+	c.log.Info("收到批量终止进程请求: 模式=%s, 命令行匹配=%t, PID=%v, 信号=%s",
+		msg.Payload.Pattern, msg.Payload.Cmdline, msg.Payload.PIDs, msg.Payload.Signal)
+	results, err := pm.KillMatchedProcesses(msg.Payload.Pattern, msg.Payload.Cmdline, msg.Payload.PIDs, msg.Payload.Signal)
+	if err != nil {
+		c.sendResponse(msg.RequestID, "process_kill_by_name_response", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	killed := 0
+	for _, r := range results {
+		if r.Success {
+			killed++
+		}
+	}
+	c.sendResponse(msg.RequestID, "process_kill_by_name_response", map[string]interface{}{
+		"results":   results,
+		"killed":    killed,
+		"timestamp": time.Now().Unix(),
+	})
+	c.log.Info("批量终止进程完成: 成功 %d/%d", killed, len(results))
+}
+
+// handleConnectionList 列出 TCP 连接及其所属进程，用于在面板上排查卡住的客户端等问题
+func (c *Client) handleConnectionList(message []byte) {
+	var msg struct {
+		RequestID string `json:"request_id"`
+		Payload   struct {
+			Status string `json:"status"` // 为空时只返回 ESTABLISHED，all 返回全部状态
+			Limit  int    `json:"limit"`
+		} `json:"payload"`
+	}
+
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.log.Error("解析连接列表请求失败: %v", err)
+		c.sendResponse(msg.RequestID, "error", map[string]interface{}{
+			"error": "无效的请求参数",
+		})
+		return
+	}
+
+	pm := monitor.NewProcessManager(c.log)
+	list, err := pm.ListConnections(msg.Payload.Status, msg.Payload.Limit)
+	if err != nil {
+		c.log.Error("获取连接列表失败: %v", err)
+		c.sendResponse(msg.RequestID, "error", map[string]interface{}{
+			"error": fmt.Sprintf("获取连接列表失败: %v", err),
+		})
+		return
+	}
+
+	c.sendResponse(msg.RequestID, "connection_list_response", map[string]interface{}{
+		"connections": list.Connections,
+		"total":       list.Total,
+		"truncated":   list.Truncated,
+		"timestamp":   time.Now().Unix(),
+	})
+	c.log.Debug("已发送连接列表，共 %d 个连接（返回 %d 个）", list.Total, len(list.Connections))
+}
+
+// handleListeningPorts 列出主机上所有监听中的 TCP/UDP 端口及其所属进程
+func (c *Client) handleListeningPorts(message []byte) {
+	var msg struct {
+		RequestID string `json:"request_id"`
+		Payload   struct {
+			Limit int `json:"limit"`
+		} `json:"payload"`
+	}
+
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.log.Error("解析监听端口请求失败: %v", err)
+		return
+	}
+
+	pm := monitor.NewProcessManager(c.log)
+	list, err := pm.ListListeningPorts(msg.Payload.Limit)
+	if err != nil {
+		c.log.Error("获取监听端口失败: %v", err)
+		c.sendResponse(msg.RequestID, "listening_ports_response", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.sendResponse(msg.RequestID, "listening_ports_response", map[string]interface{}{
+		"ports":     list.Ports,
+		"total":     list.Total,
+		"truncated": list.Truncated,
+		"source":    list.Source,
+		"timestamp": time.Now().Unix(),
+	})
+	c.log.Debug("已发送监听端口列表，共 %d 个（来源 %s）", list.Total, list.Source)
+}
+
+// handleNetstat 统计 TCP 连接的状态分布，默认附带监听端口清单
+func (c *Client) handleNetstat(message []byte) {
+	var msg struct {
+		RequestID string `json:"request_id"`
+		Payload   struct {
+			Listening *bool `json:"listening"`
+			Limit     int   `json:"limit"`
+		} `json:"payload"`
+	}
+
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.log.Error("解析 netstat 请求失败: %v", err)
+		return
+	}
+
+	withListening := msg.Payload.Listening == nil || *msg.Payload.Listening
+	pm := monitor.NewProcessManager(c.log)
+	result, err := pm.Netstat(withListening, msg.Payload.Limit)
+	if err != nil {
+		c.log.Error("获取 netstat 失败: %v", err)
+		c.sendResponse(msg.RequestID, "netstat_response", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.sendResponse(msg.RequestID, "netstat_response", map[string]interface{}{
+		"tcp_states": result.TCPStates,
+		"tcp_total":  result.TCPTotal,
+		"listening":  result.Listening,
+		"timestamp":  time.Now().Unix(),
+	})
+	c.log.Debug("已发送 netstat，共 %d 个 TCP 连接", result.TCPTotal)
+}
+
+// handleNetworkBenchmark 使用 iperf3 或 speedtest 测量带宽，耗时可达数十秒
+func (c *Client) handleNetworkBenchmark(message []byte) {
+	var msg struct {
+		RequestID string `json:"request_id"`
+		Payload   struct {
+			Tool     string `json:"tool"`
+			Target   string `json:"target"`
+			Duration int    `json:"duration"`
+		} `json:"payload"`
+	}
+
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.log.Error("解析测速请求失败: %v", err)
+		c.sendResponse(msg.RequestID, "network_benchmark_response", map[string]interface{}{
+			"error": "无效的请求参数",
+		})
+		return
+	}
+
+	c.log.Info("收到测速请求: 工具=%s, 目标=%s", msg.Payload.Tool, msg.Payload.Target)
+	result, err := monitor.NewNetworkBenchmark(c.log).Run(msg.Payload.Tool, msg.Payload.Target, msg.Payload.Duration)
+	if err != nil {
+		c.log.Warn("测速失败: %v", err)
+		c.sendResponse(msg.RequestID, "network_benchmark_response", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.sendResponse(msg.RequestID, "network_benchmark_response", map[string]interface{}{
+		"result": result,
+	})
+}
+
 // ─── Docker 命令处理 ──────────────────────────────────────────────────────────
 
 // handleDockerCommand 处理Docker命令
@@ -1285,7 +1636,8 @@ func (c *Client) handleDockerCommand(message []byte) {
 	if err != nil {
 		c.log.Error("创建Docker管理器失败: %v", err)
 		c.sendResponse(msg.RequestID, "docker_error", map[string]interface{}{
-			"error": fmt.Sprintf("创建Docker管理器失败: %v", err),
+			"error":         fmt.Sprintf("创建Docker管理器失败: %v", err),
+			"docker_status": monitor.DockerStatusForError(err),
 		})
 		return
 	}
@@ -1298,6 +1650,12 @@ func (c *Client) handleDockerCommand(message []byte) {
 		c.handleImagesCommand(msg.RequestID, msg.Payload.Action, msg.Payload.Params, dockerManager)
 	case "composes":
 		c.handleComposesCommand(msg.RequestID, msg.Payload.Action, msg.Payload.Params, dockerManager)
+	case "networks":
+		c.handleNetworksCommand(msg.RequestID, msg.Payload.Action, msg.Payload.Params, dockerManager)
+	case "volumes":
+		c.handleVolumesCommand(msg.RequestID, msg.Payload.Action, msg.Payload.Params, dockerManager)
+	case "registry":
+		c.handleRegistryCommand(msg.RequestID, msg.Payload.Action, msg.Payload.Params, dockerManager)
 	default:
 		c.log.Error("未知的Docker命令: %s", msg.Payload.Command)
 		c.sendResponse(msg.RequestID, "docker_error", map[string]interface{}{
@@ -1351,6 +1709,48 @@ func (c *Client) handleContainersCommand(requestID string, action string, params
 			"logs": logs,
 		})
 
+	case "exec":
+		var execParams struct {
+			ContainerID string   `json:"container_id"`
+			Cmd         []string `json:"cmd"`
+			Command     string   `json:"command"`
+			User        string   `json:"user"`
+			WorkDir     string   `json:"workdir"`
+			Env         []string `json:"env"`
+			Timeout     int      `json:"timeout"`
+		}
+		if err := json.Unmarshal(params, &execParams); err != nil {
+			c.log.Error("解析容器命令参数失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": "无效的容器命令参数",
+			})
+			return
+		}
+
+		// cmd 按参数数组直接执行；只给出 command 字符串时交给容器内的 sh 解析
+		cmd := execParams.Cmd
+		if len(cmd) == 0 && strings.TrimSpace(execParams.Command) != "" {
+			cmd = []string{"sh", "-c", execParams.Command}
+		}
+		c.log.Info("在容器 %s 中执行命令: %q", execParams.ContainerID, cmd)
+		result, err := dockerManager.ExecCommand(execParams.ContainerID, monitor.ExecOptions{
+			Cmd:     cmd,
+			User:    execParams.User,
+			WorkDir: execParams.WorkDir,
+			Env:     execParams.Env,
+			Timeout: time.Duration(execParams.Timeout) * time.Second,
+		})
+		if err != nil {
+			c.log.Error("执行容器命令失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": fmt.Sprintf("执行容器命令失败: %v", err),
+			})
+			return
+		}
+		c.sendResponse(requestID, "docker_container_exec", map[string]interface{}{
+			"result": result,
+		})
+
 	case "start":
 		var startParams struct {
 			ContainerID string `json:"container_id"`
@@ -1507,7 +1907,8 @@ func (c *Client) handleImagesCommand(requestID string, action string, params jso
 
 	case "pull":
 		var pullParams struct {
-			Image string `json:"image"`
+			Image string                `json:"image"`
+			Auth  *monitor.RegistryAuth `json:"auth"`
 		}
 		if err := json.Unmarshal(params, &pullParams); err != nil {
 			c.log.Error("解析拉取镜像参数失败: %v", err)
@@ -1518,7 +1919,7 @@ func (c *Client) handleImagesCommand(requestID string, action string, params jso
 		}
 
 		go func() {
-			if err := dockerManager.PullImage(pullParams.Image); err != nil {
+			if err := dockerManager.PullImage(pullParams.Image, pullParams.Auth); err != nil {
 				c.log.Error("拉取镜像失败: %v", err)
 				return
 			}
@@ -1643,8 +2044,47 @@ func (c *Client) handleComposesCommand(requestID string, action string, params j
 			})
 			return
 		}
-		c.sendResponse(requestID, "docker_compose_config", map[string]interface{}{
+		response := map[string]interface{}{
 			"config": config,
+		}
+		// 结构化结果仅供展示，解析失败时仍返回原始配置
+		if parsed, err := monitor.ParseComposeConfig(config); err == nil {
+			response["parsed"] = parsed
+		} else {
+			response["parse_error"] = err.Error()
+		}
+		// 主配置文件原始内容用于在线编辑，渲染后的配置会展开变量、丢失注释
+		if sourceFile, source, err := dockerManager.GetComposeSource(configParams.Name); err == nil {
+			response["source_file"] = sourceFile
+			response["source"] = source
+		}
+		c.sendResponse(requestID, "docker_compose_config", response)
+
+	case "validate":
+		var validateParams struct {
+			Name    string `json:"name"`
+			Content string `json:"content"`
+		}
+		if err := json.Unmarshal(params, &validateParams); err != nil {
+			c.log.Error("解析校验Compose配置参数失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": "无效的校验Compose配置参数",
+			})
+			return
+		}
+
+		// 配置无效不是请求失败，通过 valid/reason 返回，便于面板在保存前提示
+		parsed, err := dockerManager.ValidateCompose(validateParams.Name, validateParams.Content)
+		if err != nil {
+			c.sendResponse(requestID, "docker_compose_config", map[string]interface{}{
+				"valid":  false,
+				"reason": err.Error(),
+			})
+			return
+		}
+		c.sendResponse(requestID, "docker_compose_config", map[string]interface{}{
+			"valid":  true,
+			"parsed": parsed,
 		})
 
 	case "create":
@@ -1694,6 +2134,85 @@ func (c *Client) handleComposesCommand(requestID string, action string, params j
 			"message": "Compose项目删除成功",
 		})
 
+	case "update":
+		var updateParams struct {
+			Name    string `json:"name"`
+			Content string `json:"content"`
+			Apply   bool   `json:"apply"`
+		}
+		if err := json.Unmarshal(params, &updateParams); err != nil {
+			c.log.Error("解析更新Compose项目参数失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": "无效的更新Compose项目参数",
+			})
+			return
+		}
+
+		if err := dockerManager.UpdateCompose(updateParams.Name, updateParams.Content, updateParams.Apply); err != nil {
+			c.log.Error("更新Compose项目失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": fmt.Sprintf("更新Compose项目失败: %v", err),
+			})
+			return
+		}
+		message := "Compose配置已保存"
+		if updateParams.Apply {
+			message = "Compose配置已保存并应用"
+		}
+		c.sendResponse(requestID, "success", map[string]interface{}{
+			"message": message,
+		})
+
+	case "services":
+		var servicesParams struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(params, &servicesParams); err != nil {
+			c.log.Error("解析获取Compose服务参数失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": "无效的获取Compose服务参数",
+			})
+			return
+		}
+
+		services, err := dockerManager.GetComposeServices(servicesParams.Name)
+		if err != nil {
+			c.log.Error("获取Compose服务列表失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": fmt.Sprintf("获取Compose服务列表失败: %v", err),
+			})
+			return
+		}
+		c.sendResponse(requestID, "docker_compose_services", map[string]interface{}{
+			"services": services,
+		})
+
+	case "service":
+		var serviceParams struct {
+			Name     string `json:"name"`
+			Service  string `json:"service"`
+			Op       string `json:"op"`
+			Replicas int    `json:"replicas"`
+		}
+		if err := json.Unmarshal(params, &serviceParams); err != nil {
+			c.log.Error("解析Compose服务操作参数失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": "无效的Compose服务操作参数",
+			})
+			return
+		}
+
+		if err := dockerManager.ComposeServiceAction(serviceParams.Name, serviceParams.Service, serviceParams.Op, serviceParams.Replicas); err != nil {
+			c.log.Error("Compose服务操作失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": fmt.Sprintf("Compose服务操作失败: %v", err),
+			})
+			return
+		}
+		c.sendResponse(requestID, "success", map[string]interface{}{
+			"message": fmt.Sprintf("服务 %s 执行 %s 成功", serviceParams.Service, serviceParams.Op),
+		})
+
 	default:
 		c.log.Error("未知的Compose操作: %s", action)
 		c.sendResponse(requestID, "error", map[string]interface{}{
@@ -1702,6 +2221,379 @@ func (c *Client) handleComposesCommand(requestID string, action string, params j
 	}
 }
 
+// handleNetworksCommand 处理网络相关命令
+func (c *Client) handleNetworksCommand(requestID string, action string, params json.RawMessage, dockerManager *monitor.DockerManager) {
+	switch action {
+	case "list":
+		networks, err := dockerManager.GetNetworks()
+		if err != nil {
+			c.log.Error("获取网络列表失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": fmt.Sprintf("获取网络列表失败: %v", err),
+			})
+			return
+		}
+		c.sendResponse(requestID, "docker_networks", map[string]interface{}{
+			"networks": networks,
+		})
+
+	case "inspect":
+		var inspectParams struct {
+			NetworkID string `json:"network_id"`
+		}
+		if err := json.Unmarshal(params, &inspectParams); err != nil || inspectParams.NetworkID == "" {
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": "无效的网络详情参数",
+			})
+			return
+		}
+
+		detail, err := dockerManager.InspectNetwork(inspectParams.NetworkID)
+		if err != nil {
+			c.log.Error("获取网络详情失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.sendResponse(requestID, "docker_network_detail", map[string]interface{}{
+			"network": detail,
+		})
+
+	case "create":
+		var createParams monitor.NetworkCreateOptions
+		if err := json.Unmarshal(params, &createParams); err != nil {
+			c.log.Error("解析创建网络参数失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": "无效的创建网络参数",
+			})
+			return
+		}
+
+		networkID, err := dockerManager.CreateNetwork(createParams)
+		if err != nil {
+			c.log.Error("创建网络失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.sendResponse(requestID, "success", map[string]interface{}{
+			"message":    "网络创建成功",
+			"network_id": networkID,
+		})
+
+	case "remove":
+		var removeParams struct {
+			NetworkID string `json:"network_id"`
+		}
+		if err := json.Unmarshal(params, &removeParams); err != nil || removeParams.NetworkID == "" {
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": "无效的删除网络参数",
+			})
+			return
+		}
+
+		if err := dockerManager.RemoveNetwork(removeParams.NetworkID); err != nil {
+			c.log.Error("删除网络失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.sendResponse(requestID, "success", map[string]interface{}{
+			"message": "网络删除成功",
+		})
+
+	case "prune":
+		result, err := dockerManager.PruneNetworks()
+		if err != nil {
+			c.log.Error("清理网络失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.sendResponse(requestID, "success", map[string]interface{}{
+			"message": fmt.Sprintf("已清理 %d 个未使用的网络", len(result.Deleted)),
+			"deleted": result.Deleted,
+		})
+
+	default:
+		c.log.Error("未知的网络操作: %s", action)
+		c.sendResponse(requestID, "error", map[string]interface{}{
+			"error": fmt.Sprintf("未知的网络操作: %s", action),
+		})
+	}
+}
+
+// handleVolumesCommand 处理卷相关命令
+func (c *Client) handleVolumesCommand(requestID string, action string, params json.RawMessage, dockerManager *monitor.DockerManager) {
+	switch action {
+	case "list":
+		volumes, err := dockerManager.GetVolumes()
+		if err != nil {
+			c.log.Error("获取卷列表失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": fmt.Sprintf("获取卷列表失败: %v", err),
+			})
+			return
+		}
+		c.sendResponse(requestID, "docker_volumes", map[string]interface{}{
+			"volumes": volumes,
+		})
+
+	case "inspect":
+		var inspectParams struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(params, &inspectParams); err != nil || inspectParams.Name == "" {
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": "无效的卷详情参数",
+			})
+			return
+		}
+
+		detail, err := dockerManager.InspectVolume(inspectParams.Name)
+		if err != nil {
+			c.log.Error("获取卷详情失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.sendResponse(requestID, "docker_volume_detail", map[string]interface{}{
+			"volume": detail,
+		})
+
+	case "create":
+		var createParams monitor.VolumeCreateOptions
+		if err := json.Unmarshal(params, &createParams); err != nil {
+			c.log.Error("解析创建卷参数失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": "无效的创建卷参数",
+			})
+			return
+		}
+
+		name, err := dockerManager.CreateVolume(createParams)
+		if err != nil {
+			c.log.Error("创建卷失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.sendResponse(requestID, "success", map[string]interface{}{
+			"message": "卷创建成功",
+			"name":    name,
+		})
+
+	case "remove":
+		var removeParams struct {
+			Name  string `json:"name"`
+			Force bool   `json:"force,omitempty"`
+		}
+		if err := json.Unmarshal(params, &removeParams); err != nil || removeParams.Name == "" {
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": "无效的删除卷参数",
+			})
+			return
+		}
+
+		if err := dockerManager.RemoveVolume(removeParams.Name, removeParams.Force); err != nil {
+			c.log.Error("删除卷失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.sendResponse(requestID, "success", map[string]interface{}{
+			"message": "卷删除成功",
+		})
+
+	case "prune":
+		var pruneParams struct {
+			All bool `json:"all,omitempty"`
+		}
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &pruneParams); err != nil {
+				c.sendResponse(requestID, "error", map[string]interface{}{
+					"error": "无效的清理卷参数",
+				})
+				return
+			}
+		}
+
+		result, err := dockerManager.PruneVolumes(pruneParams.All)
+		if err != nil {
+			c.log.Error("清理卷失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.sendResponse(requestID, "success", map[string]interface{}{
+			"message":         fmt.Sprintf("已清理 %d 个未使用的卷", len(result.Deleted)),
+			"deleted":         result.Deleted,
+			"space_reclaimed": result.SpaceReclaimed,
+		})
+
+	default:
+		c.log.Error("未知的卷操作: %s", action)
+		c.sendResponse(requestID, "error", map[string]interface{}{
+			"error": fmt.Sprintf("未知的卷操作: %s", action),
+		})
+	}
+}
+
+// handleRegistryCommand 处理镜像仓库相关命令
+func (c *Client) handleRegistryCommand(requestID string, action string, params json.RawMessage, dockerManager *monitor.DockerManager) {
+	switch action {
+	case "login":
+		var loginParams struct {
+			Auth *monitor.RegistryAuth `json:"auth"`
+		}
+		if err := json.Unmarshal(params, &loginParams); err != nil || loginParams.Auth == nil {
+			c.log.Error("解析登录镜像仓库参数失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": "无效的登录镜像仓库参数",
+			})
+			return
+		}
+
+		if err := dockerManager.RegistryLogin(loginParams.Auth); err != nil {
+			c.log.Error("登录镜像仓库失败: %v", err)
+			c.sendResponse(requestID, "error", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.log.Info("已登录镜像仓库 %s（用户 %s）", loginParams.Auth.ServerAddress, loginParams.Auth.Username)
+		c.sendResponse(requestID, "success", map[string]interface{}{
+			"message": fmt.Sprintf("已登录镜像仓库 %s", loginParams.Auth.ServerAddress),
+		})
+
+	default:
+		c.log.Error("未知的镜像仓库操作: %s", action)
+		c.sendResponse(requestID, "error", map[string]interface{}{
+			"error": fmt.Sprintf("未知的镜像仓库操作: %s", action),
+		})
+	}
+}
+
+// ─── 系统服务与软件包处理 ──────────────────────────────────────────────────────
+
+// handleServiceCommand 处理 systemd 服务的列表、状态、日志查询和启停操作
+func (c *Client) handleServiceCommand(message []byte) {
+	var msg struct {
+		RequestID string `json:"request_id"`
+		Payload   struct {
+			Action string `json:"action"`
+			Name   string `json:"name"`
+			Lines  int    `json:"lines"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.log.Error("解析服务管理请求失败: %v", err)
+		c.sendResponse(msg.RequestID, "service_command_response", map[string]interface{}{
+			"error": "无效的请求参数",
+		})
+		return
+	}
+
+	action := strings.ToLower(strings.TrimSpace(msg.Payload.Action))
+	c.log.Info("收到服务管理请求: action=%s, name=%s", action, msg.Payload.Name)
+
+	sm := monitor.NewServiceManager(c.log)
+	var data map[string]interface{}
+	var err error
+	switch action {
+	case "list":
+		var services []*monitor.ServiceUnit
+		if services, err = sm.ListServices(); err == nil {
+			data = map[string]interface{}{"services": services, "count": len(services)}
+		}
+	case "status":
+		var status *monitor.ServiceStatus
+		if status, err = sm.GetServiceStatus(msg.Payload.Name); err == nil {
+			data = map[string]interface{}{"service": status}
+		}
+	case "logs":
+		var logs string
+		if logs, err = sm.GetServiceLogs(msg.Payload.Name, msg.Payload.Lines); err == nil {
+			data = map[string]interface{}{"name": msg.Payload.Name, "logs": logs}
+		}
+	default:
+		var status *monitor.ServiceStatus
+		if status, err = sm.ControlService(msg.Payload.Name, action); err == nil {
+			data = map[string]interface{}{"service": status, "action": action}
+		}
+	}
+	if err != nil {
+		c.log.Warn("服务管理操作失败: action=%s, name=%s, error=%v", action, msg.Payload.Name, err)
+		c.sendResponse(msg.RequestID, "service_command_response", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.sendTransferResponse(msg.RequestID, "service_command_response", data)
+}
+
+// handlePackageCommand 处理软件包清单查询以及安装、升级、卸载请求
+func (c *Client) handlePackageCommand(message []byte) {
+	var msg struct {
+		RequestID string `json:"request_id"`
+		Payload   struct {
+			Action   string   `json:"action"`
+			Packages []string `json:"packages"`
+			DryRun   bool     `json:"dry_run"`
+			Refresh  bool     `json:"refresh"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.log.Error("解析软件包管理请求失败: %v", err)
+		c.sendResponse(msg.RequestID, "package_command_response", map[string]interface{}{
+			"error": "无效的请求参数",
+		})
+		return
+	}
+
+	action := strings.ToLower(strings.TrimSpace(msg.Payload.Action))
+	c.log.Info("收到软件包管理请求: action=%s, packages=%v, dry_run=%v", action, msg.Payload.Packages, msg.Payload.DryRun)
+
+	pm := monitor.NewPackageManager(c.log)
+	if action == "inventory" {
+		inventory, err := pm.Inventory(msg.Payload.Refresh)
+		if err != nil {
+			c.log.Warn("读取软件包清单失败: %v", err)
+			c.sendResponse(msg.RequestID, "package_command_response", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.log.Info("已读取软件包清单: %d 个软件包, %d 个可用更新", len(inventory.Packages), len(inventory.Updates))
+		c.sendTransferResponse(msg.RequestID, "package_command_response", map[string]interface{}{
+			"inventory": inventory,
+		})
+		return
+	}
+
+	result, err := pm.RunCommand(action, msg.Payload.Packages, msg.Payload.DryRun)
+	if err != nil {
+		data := map[string]interface{}{"error": err.Error()}
+		if result != nil {
+			data["result"] = result
+		}
+		c.sendResponse(msg.RequestID, "package_command_response", data)
+		return
+	}
+	c.sendTransferResponse(msg.RequestID, "package_command_response", map[string]interface{}{
+		"result": result,
+	})
+}
+
 // ─── Nginx 命令处理 ──────────────────────────────────────────────────────────
 
 // handleNginxCommand 处理Nginx命令
@@ -1773,8 +2665,15 @@ func (c *Client) sendRawResponse(requestID, responseType, jsonData string) {
 		Data:      json.RawMessage(jsonData),
 	}
 
+	payload, err := json.Marshal(response)
+	if err != nil {
+		c.log.Error("序列化WebSocket响应失败: %v", err)
+		return
+	}
+	payload = c.limitResponse(requestID, responseType, payload)
+
 	if c.wsConn != nil {
-		if err := c.wsConn.WriteJSON(response); err != nil {
+		if err := c.wsConn.WriteMessage(websocket.TextMessage, payload); err != nil {
 			c.log.Error("发送WebSocket响应失败: %v", err)
 		}
 	} else {
@@ -1793,6 +2692,8 @@ func (c *Client) handleDockerLogsStream(message []byte) {
 			StreamID    string `json:"stream_id"`
 			ContainerID string `json:"container_id"`
 			Tail        int    `json:"tail"`
+			Include     string `json:"include"` // 只发送匹配该正则的行
+			Exclude     string `json:"exclude"` // 丢弃匹配该正则的行
 		} `json:"payload"`
 	}
 
@@ -1803,7 +2704,15 @@ func (c *Client) handleDockerLogsStream(message []byte) {
 
 	switch msg.Payload.Action {
 	case "start":
-		c.startLogStream(msg.Payload.StreamID, msg.Payload.ContainerID, msg.Payload.Tail)
+		filter, err := newLogLineFilter(msg.Payload.Include, msg.Payload.Exclude)
+		if err != nil {
+			c.log.Warn("日志流 %s 过滤条件无效: %v", msg.Payload.StreamID, err)
+			c.sendStreamMessage(msg.Payload.StreamID, "docker_logs_stream_end", map[string]interface{}{
+				"reason": err.Error(),
+			})
+			return
+		}
+		c.startLogStream(msg.Payload.StreamID, msg.Payload.ContainerID, msg.Payload.Tail, filter)
 	case "stop":
 		c.closeLogStream(msg.Payload.StreamID)
 	default:
@@ -1812,7 +2721,7 @@ func (c *Client) handleDockerLogsStream(message []byte) {
 }
 
 // startLogStream 启动一个容器日志流
-func (c *Client) startLogStream(streamID, containerID string, tail int) {
+func (c *Client) startLogStream(streamID, containerID string, tail int, filter *logLineFilter) {
 	if streamID == "" || containerID == "" {
 		c.log.Error("日志流参数不完整: streamID=%s, containerID=%s", streamID, containerID)
 		return
@@ -1856,6 +2765,8 @@ func (c *Client) startLogStream(streamID, containerID string, tail int) {
 		stopCh:      make(chan struct{}),
 		containerID: containerID,
 		manager:     dockerManager,
+		filter:      filter,
+		limiter:     c.transferLimiter(),
 	}
 
 	c.logStreamsLock.Lock()
@@ -1882,7 +2793,11 @@ func (c *Client) streamDockerLogs(streamID string, sess *logStreamSession) {
 	go func() {
 		defer close(lineCh)
 		for scanner.Scan() {
-			lineCh <- scanner.Text()
+			line := scanner.Text()
+			if !sess.filter.Match(line) {
+				continue
+			}
+			lineCh <- line
 		}
 		scanDone <- scanner.Err()
 	}()
@@ -1897,9 +2812,17 @@ func (c *Client) streamDockerLogs(streamID string, sess *logStreamSession) {
 			return
 		}
 		logs := strings.Join(batch, "\n") + "\n"
-		c.sendStreamMessage(streamID, "docker_logs_stream_data", map[string]interface{}{
-			"logs": logs,
-		})
+		// 限速时在此阻塞，读取 goroutine 随之暂停，由 Docker 连接缓冲日志
+		msg := map[string]interface{}{
+			"type":      "docker_logs_stream_data",
+			"stream_id": streamID,
+			"data": map[string]interface{}{
+				"logs": logs,
+			},
+		}
+		if err := c.writeThrottled(msg, sess.limiter); err != nil {
+			c.log.Error("发送日志流消息失败: streamID=%s, error=%v", streamID, err)
+		}
 		batch = batch[:0]
 	}
 
@@ -1994,6 +2917,7 @@ func (c *Client) sendStreamMessage(streamID, msgType string, data map[string]int
 	defer func() {
 		if r := recover(); r != nil {
 			c.log.Error("发送日志流消息时 panic: %v", r)
+			c.RecordError(ErrorPanic, r)
 		}
 	}()
 
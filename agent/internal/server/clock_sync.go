@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 时钟偏差超过该值时记录警告日志
+const clockOffsetWarnThreshold = 5 * time.Second
+
+// timeSyncMessage 面板对 system_info 的时间戳回复（均为 Unix 毫秒）
+type timeSyncMessage struct {
+	Payload struct {
+		AgentSentAt      int64 `json:"agent_sent_at"`
+		ServerReceivedAt int64 `json:"server_received_at"`
+		ServerSentAt     int64 `json:"server_sent_at"`
+	} `json:"payload"`
+}
+
+// handleTimeSync 按 NTP 方式估算本机与面板的时钟偏差，并上报给面板
+func (c *Client) handleTimeSync(message []byte) {
+	receivedAt := time.Now().UnixMilli()
+
+	var msg timeSyncMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.log.Error("解析时钟同步消息失败: %v", err)
+		return
+	}
+	p := msg.Payload
+	if p.AgentSentAt <= 0 || p.ServerReceivedAt <= 0 || p.ServerSentAt <= 0 {
+		return
+	}
+
+	offset, rtt := clockOffset(p.AgentSentAt, p.ServerReceivedAt, p.ServerSentAt, receivedAt)
+	if d := time.Duration(offset) * time.Millisecond; d > clockOffsetWarnThreshold || d < -clockOffsetWarnThreshold {
+		c.log.Warn("本机时钟与面板相差 %s（往返时延 %dms），请检查 NTP 同步", d, rtt)
+	} else {
+		c.log.Debug("时钟偏差 %dms，往返时延 %dms", offset, rtt)
+	}
+
+	result := map[string]interface{}{
+		"type": "time_sync_result",
+		"payload": map[string]interface{}{
+			"offset_ms": offset,
+			"rtt_ms":    rtt,
+		},
+	}
+	if err := c.writeJSON(result); err != nil {
+		c.log.Warn("上报时钟偏差失败: %v", err)
+	}
+}
+
+// clockOffset 由四个时间戳计算时钟偏差和往返时延：
+// t0 Agent发送，t1 面板接收，t2 面板发送，t3 Agent接收。
+// 偏差为 Agent 时钟减去面板时钟，正数表示 Agent 时钟超前。
+func clockOffset(t0, t1, t2, t3 int64) (offset, rtt int64) {
+	offset = ((t0 - t1) + (t3 - t2)) / 2
+	rtt = (t3 - t0) - (t2 - t1)
+	if rtt < 0 {
+		rtt = 0
+	}
+	return offset, rtt
+}
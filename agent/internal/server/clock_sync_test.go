@@ -0,0 +1,23 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClockOffset(t *testing.T) {
+	// Agent 时钟超前 42s，单程时延 10ms，面板处理耗时 5ms
+	t0 := int64(1_000_000)
+	t1 := t0 - 42_000 + 10
+	t2 := t1 + 5
+	t3 := t2 + 42_000 + 10
+
+	offset, rtt := clockOffset(t0, t1, t2, t3)
+	assert.Equal(t, int64(42_000), offset)
+	assert.Equal(t, int64(20), rtt)
+
+	// Agent 时钟落后
+	offset, _ = clockOffset(1000, 4010, 4015, 1025)
+	assert.Equal(t, int64(-3000), offset)
+}
@@ -0,0 +1,388 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 输出文件名中的时间戳格式
+	captureFileTimeFormat = "20060102-150405"
+	// 保留的已结束采集记录数，输出文件本身不会被删除
+	maxFinishedCaptures = 20
+	// 命令退出后等待子进程关闭输出管道的最长时间
+	captureWaitDelay = 5 * time.Second
+)
+
+// 采集状态
+const (
+	captureRunning   = "running"
+	captureCompleted = "completed" // 命令正常退出
+	captureFailed    = "failed"    // 命令以非 0 状态退出或写入失败
+	captureTimeout   = "timeout"   // 超过最长执行时间被终止
+	captureTruncated = "truncated" // 输出达到大小上限被终止
+	captureStopped   = "stopped"   // 面板手动停止
+)
+
+// 输出文件名只保留字母、数字、点、下划线和短横线
+var captureNamePattern = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// commandCapture 一次命令输出采集
+type commandCapture struct {
+	ID         string     `json:"id"`
+	Command    string     `json:"command"`
+	Path       string     `json:"path"` // 输出文件，可通过文件管理下载
+	Status     string     `json:"status"`
+	ExitCode   int        `json:"exit_code"`
+	Size       int64      `json:"size"`
+	MaxSize    int64      `json:"max_size"`
+	TimeoutSec int64      `json:"timeout_sec"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	cancel  context.CancelFunc
+	stopped bool
+}
+
+// captureManager 管理进行中和最近结束的采集，零值可直接使用
+type captureManager struct {
+	mu       sync.Mutex
+	captures []*commandCapture // 按开始时间排序
+}
+
+// add 登记新的采集，并只保留最近 maxFinishedCaptures 条已结束的记录
+func (m *captureManager) add(capture *commandCapture) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.captures = append(m.captures, capture)
+	finished := 0
+	for _, item := range m.captures {
+		if item.Status != captureRunning {
+			finished++
+		}
+	}
+	kept := m.captures[:0]
+	for _, item := range m.captures {
+		if item.Status != captureRunning && finished > maxFinishedCaptures {
+			finished--
+			continue
+		}
+		kept = append(kept, item)
+	}
+	m.captures = kept
+}
+
+// list 返回所有采集记录的副本，最新的在前
+func (m *captureManager) list() []commandCapture {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make([]commandCapture, 0, len(m.captures))
+	for i := len(m.captures) - 1; i >= 0; i-- {
+		result = append(result, *m.captures[i])
+	}
+	return result
+}
+
+// stop 终止进行中的采集，已写入的输出保留
+func (m *captureManager) stop(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, item := range m.captures {
+		if item.ID != id {
+			continue
+		}
+		if item.Status != captureRunning {
+			return fmt.Errorf("采集已结束: %s", item.Status)
+		}
+		item.stopped = true
+		item.cancel()
+		return nil
+	}
+	return fmt.Errorf("采集不存在: %s", id)
+}
+
+// finish 记录采集结果，返回最终状态
+func (m *captureManager) finish(capture *commandCapture, size int64, limitHit bool, writeErr, ctxErr, runErr error) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	capture.FinishedAt = &now
+	capture.Size = size
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) {
+		capture.ExitCode = exitErr.ExitCode()
+	}
+	switch {
+	case capture.stopped:
+		capture.Status = captureStopped
+	case writeErr != nil:
+		capture.Status = captureFailed
+		capture.Error = writeErr.Error()
+	case limitHit:
+		capture.Status = captureTruncated
+	case errors.Is(ctxErr, context.DeadlineExceeded):
+		capture.Status = captureTimeout
+	case runErr != nil:
+		capture.Status = captureFailed
+		capture.Error = runErr.Error()
+	default:
+		capture.Status = captureCompleted
+	}
+	return capture.Status
+}
+
+// cappedWriter 把命令输出写入文件，写满 limit 字节或写入失败后丢弃后续输出并调用 stop 终止命令
+type cappedWriter struct {
+	mu      sync.Mutex
+	file    *os.File
+	limit   int64
+	written int64
+	hit     bool
+	err     error // 写入失败的原因，例如磁盘已满
+	stop    func()
+}
+
+func (w *cappedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.hit || w.err != nil {
+		return len(p), nil
+	}
+	chunk := p
+	if remain := w.limit - w.written; int64(len(chunk)) > remain {
+		chunk = chunk[:remain]
+		w.hit = true
+		defer w.stop()
+	}
+	n, err := w.file.Write(chunk)
+	w.written += int64(n)
+	if err != nil {
+		// 继续读取并丢弃输出，避免命令阻塞在写管道上，同时终止命令
+		w.err = wrapNoSpace(filepath.Dir(w.file.Name()), int64(len(chunk)), err)
+		w.stop()
+	}
+	return len(p), nil
+}
+
+func (w *cappedWriter) state() (int64, bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.written, w.hit, w.err
+}
+
+// resolveCaptureDir 校验输出目录：必须位于 capture_roots 内，不存在时创建，解析符号链接后再次校验
+func resolveCaptureDir(dir string, roots []string) (string, error) {
+	if len(roots) == 0 {
+		return "", fmt.Errorf("未配置 capture_roots，不允许采集命令输出")
+	}
+	if strings.TrimSpace(dir) == "" {
+		dir = roots[0]
+	}
+	cleaned, err := normalizeHostPath(dir)
+	if err != nil {
+		return "", err
+	}
+	if !pathInRoots(cleaned, roots) {
+		return "", fmt.Errorf("目录 %s 不在允许的目录 %s 内", cleaned, strings.Join(roots, ", "))
+	}
+	if err := os.MkdirAll(cleaned, 0755); err != nil {
+		return "", fmt.Errorf("创建输出目录失败: %w", err)
+	}
+	resolved, err := filepath.EvalSymlinks(cleaned)
+	if err != nil {
+		return "", fmt.Errorf("输出目录无法访问: %w", err)
+	}
+	if !pathInRoots(resolved, roots) {
+		return "", fmt.Errorf("目录 %s 不在允许的目录 %s 内", resolved, strings.Join(roots, ", "))
+	}
+	return resolved, nil
+}
+
+// captureFileName 生成输出文件名：名称-时间戳.log
+func captureFileName(name string, now time.Time) string {
+	name = strings.Trim(captureNamePattern.ReplaceAllString(name, "_"), "._")
+	if name == "" {
+		name = "capture"
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return fmt.Sprintf("%s-%s.log", name, now.Format(captureFileTimeFormat))
+}
+
+// captureShell 返回通过 shell 执行命令的进程，ctx 结束时终止
+func captureShell(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "/bin/sh", "-c", command)
+}
+
+// startCapture 在后台执行命令，标准输出和标准错误写入 capture_roots 下的文件，返回采集记录
+func (c *Client) startCapture(command, dir, name string, timeout time.Duration, maxSize int64) (*commandCapture, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, fmt.Errorf("命令不能为空")
+	}
+	dir, err := resolveCaptureDir(dir, c.cfg.CaptureRoots)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	path := filepath.Join(dir, captureFileName(name, now))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("创建输出文件失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	capture := &commandCapture{
+		ID:         strconv.FormatInt(now.UnixNano(), 36),
+		Command:    command,
+		Path:       path,
+		Status:     captureRunning,
+		MaxSize:    maxSize,
+		TimeoutSec: int64(timeout / time.Second),
+		StartedAt:  now,
+		cancel:     cancel,
+	}
+	snapshot := *capture
+	writer := &cappedWriter{file: file, limit: maxSize, stop: cancel}
+
+	// 超时、达到大小上限或手动停止时通过 ctx 终止命令
+	cmd := captureShell(ctx, command)
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	cmd.WaitDelay = captureWaitDelay
+	setCaptureProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		cancel()
+		file.Close()
+		os.Remove(path)
+		return nil, fmt.Errorf("启动命令失败: %w", err)
+	}
+
+	c.captures.add(capture)
+	c.log.Info("开始采集命令输出: id=%s, 命令=%s, 输出=%s", capture.ID, command, path)
+
+	go func() {
+		runErr := cmd.Wait()
+		ctxErr := ctx.Err()
+		cancel()
+		syncErr := file.Sync()
+		file.Close()
+
+		size, limitHit, writeErr := writer.state()
+		if writeErr == nil && syncErr != nil {
+			writeErr = wrapNoSpace(dir, 0, syncErr)
+		}
+		status := c.captures.finish(capture, size, limitHit, writeErr, ctxErr, runErr)
+		c.log.Info("命令输出采集结束: id=%s, 状态=%s, 大小=%d 字节", snapshot.ID, status, size)
+	}()
+
+	return &snapshot, nil
+}
+
+// commandCaptureRequest 面板的命令输出采集请求
+type commandCaptureRequest struct {
+	RequestID string `json:"request_id"`
+	Payload   struct {
+		Action     string `json:"action"` // start / list / stop
+		ID         string `json:"id"`
+		Command    string `json:"command"`
+		Dir        string `json:"dir"`         // 输出目录，默认 capture_roots 中的第一个
+		Name       string `json:"name"`        // 输出文件名前缀
+		TimeoutSec int    `json:"timeout_sec"` // 不超过 capture_timeout
+		MaxSizeMB  int    `json:"max_size_mb"` // 不超过 capture_max_size_mb
+	} `json:"payload"`
+}
+
+// handleCommandCapture 执行非交互命令并把输出写入 Agent 上的文件，用于采集 strace、抓包等体量很大的诊断输出，
+// 避免通过终端实时传输；完成后通过文件管理下载
+func (c *Client) handleCommandCapture(message []byte) {
+	var req commandCaptureRequest
+	if err := json.Unmarshal(message, &req); err != nil {
+		c.log.Error("解析命令输出采集请求失败: %v", err)
+		return
+	}
+
+	switch strings.ToLower(req.Payload.Action) {
+	case "", "list":
+		c.sendResponse(req.RequestID, "command_capture_response", map[string]interface{}{
+			"captures":    c.captures.list(),
+			"roots":       c.cfg.CaptureRoots,
+			"max_size_mb": c.captureMaxSize() / 1024 / 1024,
+			"timeout_sec": int64(c.captureTimeout() / time.Second),
+		})
+	case "start":
+		timeout := c.captureTimeout()
+		if requested := time.Duration(req.Payload.TimeoutSec) * time.Second; requested > 0 && requested < timeout {
+			timeout = requested
+		}
+		maxSize := c.captureMaxSize()
+		if requested := int64(req.Payload.MaxSizeMB) * 1024 * 1024; requested > 0 && requested < maxSize {
+			maxSize = requested
+		}
+		capture, err := c.startCapture(req.Payload.Command, req.Payload.Dir, req.Payload.Name, timeout, maxSize)
+		if err != nil {
+			c.log.Warn("启动命令输出采集失败: %v", err)
+			c.sendResponse(req.RequestID, "command_capture_response", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.sendResponse(req.RequestID, "command_capture_response", map[string]interface{}{
+			"capture": capture,
+		})
+	case "stop":
+		if err := c.captures.stop(req.Payload.ID); err != nil {
+			c.sendResponse(req.RequestID, "command_capture_response", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.log.Info("面板停止了命令输出采集: id=%s", req.Payload.ID)
+		c.sendResponse(req.RequestID, "command_capture_response", map[string]interface{}{
+			"success": true,
+		})
+	default:
+		c.sendResponse(req.RequestID, "command_capture_response", map[string]interface{}{
+			"error": "不支持的操作: " + req.Payload.Action,
+		})
+	}
+}
+
+// captureTimeout 返回配置的最长执行时间，未配置时为 30 分钟
+func (c *Client) captureTimeout() time.Duration {
+	if c.cfg.CaptureTimeout > 0 {
+		return c.cfg.CaptureTimeout
+	}
+	return 30 * time.Minute
+}
+
+// captureMaxSize 返回配置的输出文件大小上限（字节），未配置时为 1GB
+func (c *Client) captureMaxSize() int64 {
+	if c.cfg.CaptureMaxSizeMB > 0 {
+		return int64(c.cfg.CaptureMaxSizeMB) * 1024 * 1024
+	}
+	return 1024 * 1024 * 1024
+}
@@ -0,0 +1,70 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/config"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+// waitCapture 等待采集结束并返回最终记录
+func waitCapture(t *testing.T, c *Client, id string) commandCapture {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, item := range c.captures.list() {
+			if item.ID == id && item.Status != captureRunning {
+				return item
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("采集 %s 未在期限内结束", id)
+	return commandCapture{}
+}
+
+func TestCommandCapture(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("使用 /bin/sh 的命令")
+	}
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	root := t.TempDir()
+	c := &Client{cfg: &config.Config{CaptureRoots: []string{root}}, log: log}
+
+	// 标准输出和标准错误写入同一个文件
+	capture, err := c.startCapture("echo out; echo err 1>&2", "", "diag run", time.Minute, 1024)
+	assert.NoError(t, err)
+	assert.Equal(t, root, filepath.Dir(capture.Path))
+	assert.Contains(t, filepath.Base(capture.Path), "diag_run-")
+	done := waitCapture(t, c, capture.ID)
+	assert.Equal(t, captureCompleted, done.Status)
+	content, err := os.ReadFile(capture.Path)
+	assert.NoError(t, err)
+	assert.Equal(t, "out\nerr\n", string(content))
+
+	// 达到大小上限后终止命令
+	capture, err = c.startCapture("yes", filepath.Join(root, "sub"), "yes", time.Minute, 4096)
+	assert.NoError(t, err)
+	done = waitCapture(t, c, capture.ID)
+	assert.Equal(t, captureTruncated, done.Status)
+	assert.Equal(t, int64(4096), done.Size)
+
+	// 超时
+	capture, err = c.startCapture("sleep 5", "", "", 100*time.Millisecond, 1024)
+	assert.NoError(t, err)
+	assert.Equal(t, captureTimeout, waitCapture(t, c, capture.ID).Status)
+
+	// 输出目录必须位于 capture_roots 内
+	_, err = c.startCapture("echo x", t.TempDir(), "", time.Minute, 1024)
+	assert.Error(t, err)
+	c.cfg.CaptureRoots = nil
+	_, err = c.startCapture("echo x", "", "", time.Minute, 1024)
+	assert.Error(t, err)
+}
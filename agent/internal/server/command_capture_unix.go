@@ -0,0 +1,17 @@
+//go:build !monitor_only && !windows
+
+package server
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setCaptureProcessGroup 让命令在独立的进程组中运行，终止时连同管道中的其他进程一起结束，
+// 避免 sh -c "tcpdump | gzip" 这类命令在超时后留下仍在写文件的子进程
+func setCaptureProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
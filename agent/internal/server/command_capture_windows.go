@@ -0,0 +1,15 @@
+//go:build !monitor_only && windows
+
+package server
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// setCaptureProcessGroup 终止时结束整个进程树
+func setCaptureProcessGroup(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		return exec.Command("taskkill", "/F", "/T", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	}
+}
@@ -30,10 +30,12 @@ type ContainerFileManager struct {
 	log         *logger.Logger
 	docker      *monitor.DockerManager
 	containerID string
+	roots       []string // 允许访问的目录前缀，为空表示不限制
+	realRoots   []string // roots 解析符号链接后的结果，按需填充
 }
 
-// NewContainerFileManager 创建容器文件管理器
-func NewContainerFileManager(log *logger.Logger, containerID string) (*ContainerFileManager, error) {
+// NewContainerFileManager 创建容器文件管理器，allowed 限定可访问的目录前缀
+func NewContainerFileManager(log *logger.Logger, containerID string, allowed ContainerFileRoots) (*ContainerFileManager, error) {
 	if containerID == "" {
 		return nil, fmt.Errorf("容器ID不能为空")
 	}
@@ -43,10 +45,25 @@ func NewContainerFileManager(log *logger.Logger, containerID string) (*Container
 		return nil, fmt.Errorf("创建Docker管理器失败: %w", err)
 	}
 
+	var fullID, name string
+	if allowed.hasPerContainer() {
+		fullID, name, err = manager.ContainerIdentity(containerID)
+		if err != nil {
+			_ = manager.Close()
+			return nil, fmt.Errorf("获取容器信息失败: %w", err)
+		}
+	}
+	roots, err := normalizeContainerRoots(allowed.rootsFor(containerID, fullID, name))
+	if err != nil {
+		_ = manager.Close()
+		return nil, err
+	}
+
 	return &ContainerFileManager{
 		log:         log,
 		docker:      manager,
 		containerID: containerID,
+		roots:       roots,
 	}, nil
 }
 
@@ -62,7 +79,11 @@ func (cfm *ContainerFileManager) Close() {
 // 如果容器镜像过于精简（busybox 等）导致命令不可用，则回退到 tar 方案。
 func (cfm *ContainerFileManager) ListFiles(path string) ([]*FileInfo, error) {
 	if path == "" {
-		path = "/"
+		path = cfm.defaultPath()
+	}
+	path, err := cfm.checkPath(path)
+	if err != nil {
+		return nil, err
 	}
 
 	output, err := cfm.runListCommand(path)
@@ -93,6 +114,10 @@ func (cfm *ContainerFileManager) ListFiles(path string) ([]*FileInfo, error) {
 
 // GetFileContent 读取文件内容
 func (cfm *ContainerFileManager) GetFileContent(path string) (string, error) {
+	path, err := cfm.checkPath(path)
+	if err != nil {
+		return "", err
+	}
 	data, err := cfm.readFile(path)
 	if err != nil {
 		return "", err
@@ -103,6 +128,10 @@ func (cfm *ContainerFileManager) GetFileContent(path string) (string, error) {
 // SaveFileContent 保存文件内容。
 // 如果目标文件已存在，会尝试复用原文件的权限位，避免破坏容器内既有权限设定。
 func (cfm *ContainerFileManager) SaveFileContent(path, content string) error {
+	path, err := cfm.checkPath(path)
+	if err != nil {
+		return err
+	}
 	data := []byte(content)
 	mode := os.FileMode(0644)
 
@@ -116,8 +145,9 @@ func (cfm *ContainerFileManager) SaveFileContent(path, content string) error {
 // CreateFile 创建新文件（若已存在则报错）。
 // 由于容器端不存在“触摸文件”接口，所以直接复用 writeFile 逻辑。
 func (cfm *ContainerFileManager) CreateFile(path, content string) error {
-	if path == "" {
-		return fmt.Errorf("文件路径不能为空")
+	path, err := cfm.checkPath(path)
+	if err != nil {
+		return err
 	}
 
 	if _, err := cfm.statPath(path); err == nil {
@@ -132,12 +162,16 @@ func (cfm *ContainerFileManager) CreateFile(path, content string) error {
 
 // CreateDirectory 创建目录，通过在容器内执行 mkdir -p 完成。
 func (cfm *ContainerFileManager) CreateDirectory(path string) error {
-	if path == "" || path == "/" {
+	path, err := cfm.checkPath(path)
+	if err != nil {
+		return err
+	}
+	if path == "/" {
 		return fmt.Errorf("目录路径无效")
 	}
 
 	script := fmt.Sprintf("set -e\nmkdir -p %s", shellEscape(path))
-	_, err := cfm.runShell(script)
+	_, err = cfm.runShell(script)
 	if err != nil {
 		return fmt.Errorf("创建容器目录失败: %w", err)
 	}
@@ -146,33 +180,38 @@ func (cfm *ContainerFileManager) CreateDirectory(path string) error {
 
 // UploadFile 上传文件，前端的内容以 Base64 方式发送，解析后交给 writeFile。
 func (cfm *ContainerFileManager) UploadFile(path, filename, content string) error {
-	safeName, err := sanitizeContainerFilename(filename)
-	if err != nil {
-		return err
-	}
-
 	data, err := base64.StdEncoding.DecodeString(content)
 	if err != nil {
 		return fmt.Errorf("解码文件内容失败: %w", err)
 	}
 
-	targetPath := joinContainerPath(path, safeName)
-	return cfm.writeFile(targetPath, data, 0644)
+	return cfm.WriteFileFromBytes(path, filename, data)
 }
 
 // WriteFileFromBytes 直接将字节数据写入容器文件，跳过 Base64 解码步骤。
 // 用于分片上传合并后直接写入容器，避免不必要的编解码开销。
 func (cfm *ContainerFileManager) WriteFileFromBytes(dir, filename string, data []byte) error {
-	safeName, err := sanitizeContainerFilename(filename)
+	dir, err := cfm.checkPath(dir)
+	if err != nil {
+		return err
+	}
+	safeName, err := sanitizeFileName(filename)
+	if err != nil {
+		return err
+	}
+	targetPath, err := cfm.checkPath(joinContainerPath(dir, safeName))
 	if err != nil {
 		return err
 	}
-	targetPath := joinContainerPath(dir, safeName)
 	return cfm.writeFile(targetPath, data, 0644)
 }
 
 // DownloadFile 下载文件
 func (cfm *ContainerFileManager) DownloadFile(path string) ([]byte, error) {
+	path, err := cfm.checkPath(path)
+	if err != nil {
+		return nil, err
+	}
 	return cfm.readFile(path)
 }
 
@@ -185,9 +224,17 @@ func (cfm *ContainerFileManager) DeleteFiles(paths []string) error {
 
 	var parts []string
 	for _, p := range paths {
-		p = strings.TrimSpace(p)
-		if p == "" || p == "/" {
-			return fmt.Errorf("不允许删除根目录或空路径")
+		p, err := cfm.checkEntryPath(strings.TrimSpace(p))
+		if err != nil {
+			return err
+		}
+		if p == "/" {
+			return fmt.Errorf("不允许删除根目录")
+		}
+		for _, root := range cfm.roots {
+			if p == root {
+				return fmt.Errorf("不允许删除允许访问的顶层目录: %s", p)
+			}
 		}
 		parts = append(parts, shellEscape(p))
 	}
@@ -208,6 +255,13 @@ func (cfm *ContainerFileManager) GetDirectoryTree(path string, depth int) ([]*Fi
 	if depth <= 0 {
 		return []*FileInfo{}, nil
 	}
+	if path == "" {
+		path = cfm.defaultPath()
+	}
+	path, err := cfm.checkPath(path)
+	if err != nil {
+		return nil, err
+	}
 
 	// 尝试使用 find 命令批量获取
 	// 只有当深度大于1时才使用 find，因为对于单层目录（depth=1），ListFiles (ls) 更可靠且已在文件列表中验证过
@@ -832,23 +886,3 @@ func fileModeFromUnixMode(mode uint32) os.FileMode {
 
 	return fm
 }
-
-func sanitizeContainerFilename(filename string) (string, error) {
-	name := strings.TrimSpace(filename)
-	if name == "" {
-		return "", fmt.Errorf("文件名不能为空")
-	}
-
-	// 兼容部分客户端（或旧浏览器）传入的 Windows 风格路径。
-	name = strings.ReplaceAll(name, "\\", "/")
-	name = pathpkg.Base(name)
-
-	if name == "" || name == "." || name == "/" || name == ".." {
-		return "", fmt.Errorf("文件名无效")
-	}
-	if strings.Contains(name, "/") {
-		return "", fmt.Errorf("文件名不能包含路径分隔符")
-	}
-
-	return name, nil
-}
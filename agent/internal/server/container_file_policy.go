@@ -0,0 +1,164 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"fmt"
+	pathpkg "path"
+	"strings"
+)
+
+// ContainerFileRoots 容器文件操作允许访问的目录前缀。
+// PerContainer 的键可以是容器名称、完整ID或ID前缀，命中时优先于 Global；
+// 两者都为空表示不限制。
+type ContainerFileRoots struct {
+	Global       []string
+	PerContainer map[string][]string
+}
+
+// 容器ID前缀匹配的最短长度，与 docker ps 显示的短ID一致
+const minContainerIDPrefix = 12
+
+// rootsFor 返回指定容器适用的目录前缀
+func (r ContainerFileRoots) rootsFor(containerID, fullID, name string) []string {
+	var prefixMatch []string
+	for key, roots := range r.PerContainer {
+		key = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(key), "/"))
+		if key == "" {
+			continue
+		}
+		// 名称或ID完全匹配优先于ID前缀匹配
+		if key == strings.ToLower(name) || key == strings.ToLower(containerID) {
+			return roots
+		}
+		if len(key) >= minContainerIDPrefix && strings.HasPrefix(strings.ToLower(fullID), key) {
+			prefixMatch = roots
+		}
+	}
+	if prefixMatch != nil {
+		return prefixMatch
+	}
+	return r.Global
+}
+
+// hasPerContainer 是否存在按容器的配置（需要查询容器名称和完整ID才能匹配）
+func (r ContainerFileRoots) hasPerContainer() bool {
+	return len(r.PerContainer) > 0
+}
+
+// normalizeContainerRoots 校验并规范化目录前缀列表
+func normalizeContainerRoots(roots []string) ([]string, error) {
+	result := make([]string, 0, len(roots))
+	for _, root := range roots {
+		root = strings.TrimSpace(root)
+		if root == "" {
+			continue
+		}
+		cleaned, err := normalizeContainerPath(root)
+		if err != nil {
+			return nil, fmt.Errorf("无效的容器文件目录配置 %q: %w", root, err)
+		}
+		result = append(result, cleaned)
+	}
+	return result, nil
+}
+
+// containerPathAllowed 判断已规范化的路径是否位于任一目录前缀内
+func containerPathAllowed(roots []string, p string) bool {
+	for _, root := range roots {
+		if pathWithinRoot(containerPathFlavor, root, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultPath 未指定路径时的默认目录：有目录前缀限制时使用第一个前缀
+func (cfm *ContainerFileManager) defaultPath() string {
+	if len(cfm.roots) > 0 {
+		return cfm.roots[0]
+	}
+	return "/"
+}
+
+// checkPath 校验容器路径：拒绝目录穿越，配置了目录前缀时要求路径位于其中，
+// 并解析符号链接，防止通过指向前缀之外的链接访问敏感位置
+func (cfm *ContainerFileManager) checkPath(p string) (string, error) {
+	return cfm.checkPathResolved(p, true)
+}
+
+// checkEntryPath 与 checkPath 相同，但不跟随最后一级符号链接。
+// 用于删除等作用于链接本身的操作：删除指向前缀之外的链接是安全的。
+func (cfm *ContainerFileManager) checkEntryPath(p string) (string, error) {
+	return cfm.checkPathResolved(p, false)
+}
+
+func (cfm *ContainerFileManager) checkPathResolved(p string, followFinal bool) (string, error) {
+	cleaned, err := normalizeContainerPath(p)
+	if err != nil {
+		return "", err
+	}
+	if len(cfm.roots) == 0 {
+		return cleaned, nil
+	}
+	if !containerPathAllowed(cfm.roots, cleaned) {
+		return "", fmt.Errorf("路径 %s 超出允许的容器目录 %s", cleaned, strings.Join(cfm.roots, ", "))
+	}
+
+	target := cleaned
+	if !followFinal && cleaned != "/" {
+		target = pathpkg.Dir(cleaned)
+	}
+	resolved, err := cfm.resolveRealPath(target)
+	if err != nil {
+		return "", fmt.Errorf("解析容器路径失败: %w", err)
+	}
+	if !followFinal && cleaned != "/" {
+		resolved = joinContainerPath(resolved, pathpkg.Base(cleaned))
+	}
+
+	if !containerPathAllowed(cfm.roots, resolved) && !containerPathAllowed(cfm.resolvedRoots(), resolved) {
+		cfm.log.Warn("容器路径 %s 通过符号链接指向允许目录之外: %s", cleaned, resolved)
+		return "", fmt.Errorf("路径 %s 指向允许的容器目录之外", cleaned)
+	}
+	return cleaned, nil
+}
+
+// resolvedRoots 返回符号链接解析后的目录前缀，允许前缀本身是链接的情况
+func (cfm *ContainerFileManager) resolvedRoots() []string {
+	if cfm.realRoots != nil {
+		return cfm.realRoots
+	}
+	cfm.realRoots = make([]string, 0, len(cfm.roots))
+	for _, root := range cfm.roots {
+		if real, err := cfm.resolveRealPath(root); err == nil {
+			cfm.realRoots = append(cfm.realRoots, real)
+		}
+	}
+	return cfm.realRoots
+}
+
+// resolveRealPath 在容器内解析路径的真实位置。
+// 路径不存在时（例如即将创建的文件），解析最近的已存在祖先目录再拼接剩余部分。
+func (cfm *ContainerFileManager) resolveRealPath(p string) (string, error) {
+	script := fmt.Sprintf(`CUR=%s
+REST=""
+while [ ! -e "$CUR" ] && [ ! -L "$CUR" ] && [ "$CUR" != "/" ]; do
+  REST="/$(basename -- "$CUR")$REST"
+  CUR=$(dirname -- "$CUR")
+done
+REAL=$(readlink -f -- "$CUR") || exit 93
+[ -n "$REAL" ] || exit 93
+printf '%%s%%s\n' "$REAL" "$REST"
+`, shellEscape(p))
+
+	output, err := cfm.runShell(script)
+	if err != nil {
+		return "", err
+	}
+	resolved := strings.TrimSpace(output)
+	if !pathpkg.IsAbs(resolved) {
+		return "", fmt.Errorf("无法解析路径: %s", p)
+	}
+	return pathpkg.Clean(resolved), nil
+}
@@ -0,0 +1,150 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/user/server-ops-agent/internal/monitor"
+)
+
+// 单次诊断最多推送的输出行数，超出后终止命令
+const maxDiagnosticLines = 2000
+
+// handleDiagnosticStream 处理网络诊断请求（start / stop），
+// start 在本机执行 ping、traceroute 或 mtr 并逐行推送输出，stop 终止进行中的诊断
+func (c *Client) handleDiagnosticStream(message []byte) {
+	var msg struct {
+		Payload struct {
+			Action   string `json:"action"`
+			StreamID string `json:"stream_id"`
+			Tool     string `json:"tool"`
+			Host     string `json:"host"`
+			Count    int    `json:"count"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.log.Error("解析网络诊断请求失败: %v", err)
+		return
+	}
+	if msg.Payload.StreamID == "" {
+		c.log.Error("网络诊断缺少 stream_id")
+		return
+	}
+
+	switch msg.Payload.Action {
+	case "start":
+		c.startDiagnostic(msg.Payload.StreamID, msg.Payload.Tool, msg.Payload.Host, msg.Payload.Count)
+	case "stop":
+		if cancel, ok := c.diagnostics.Load(msg.Payload.StreamID); ok {
+			cancel.(context.CancelFunc)()
+		}
+	default:
+		c.log.Warn("未知的网络诊断操作: %s", msg.Payload.Action)
+	}
+}
+
+// startDiagnostic 执行诊断命令，结束时推送退出状态
+func (c *Client) startDiagnostic(streamID, tool, host string, count int) {
+	name, args, err := monitor.DiagnosticCommand(tool, host, count)
+	if err != nil {
+		c.sendDiagnosticEnd(streamID, -1, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), monitor.DiagnosticTimeout)
+	defer cancel()
+	if _, loaded := c.diagnostics.LoadOrStore(streamID, cancel); loaded {
+		c.log.Warn("网络诊断 %s 已存在，忽略重复 start 请求", streamID)
+		return
+	}
+	defer c.diagnostics.Delete(streamID)
+
+	c.log.Info("开始网络诊断: %s %s [%s]", name, strings.Join(args, " "), streamID)
+	c.sendStreamMessage(streamID, "diagnostic_stream_data", map[string]interface{}{
+		"line": "$ " + name + " " + strings.Join(args, " "),
+	})
+
+	exitCode, err := c.runDiagnostic(ctx, streamID, name, args)
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		err = errors.New("诊断已停止")
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		err = fmt.Errorf("诊断超时（%s）", monitor.DiagnosticTimeout)
+	}
+	c.sendDiagnosticEnd(streamID, exitCode, err)
+}
+
+// runDiagnostic 执行命令并逐行推送标准输出和标准错误，返回退出码
+func (c *Client) runDiagnostic(ctx context.Context, streamID, name string, args []string) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	reader, writer := io.Pipe()
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	cmd.WaitDelay = time.Second
+	if err := cmd.Start(); err != nil {
+		return -1, fmt.Errorf("执行 %s 失败: %w", name, err)
+	}
+	waitErr := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		writer.Close()
+		waitErr <- err
+	}()
+
+	lines := 0
+	truncated := false
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		if lines >= maxDiagnosticLines {
+			// 继续读取直到命令退出，避免写入端阻塞
+			if !truncated {
+				truncated = true
+				cancel()
+			}
+			continue
+		}
+		lines++
+		c.sendStreamMessage(streamID, "diagnostic_stream_data", map[string]interface{}{
+			"line": strings.ToValidUTF8(scanner.Text(), "?"),
+		})
+	}
+	// 超长的行会让 Scanner 提前停止，读完剩余输出后才能等到命令退出
+	io.Copy(io.Discard, reader)
+
+	err := <-waitErr
+	if truncated {
+		return -1, fmt.Errorf("输出超过 %d 行，已终止", maxDiagnosticLines)
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		// ping 在目标不可达时以非 0 退出，输出本身就是诊断结果
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return 0, nil
+}
+
+// sendDiagnosticEnd 推送诊断结束消息，err 为 nil 时 exit_code 为命令的退出码
+func (c *Client) sendDiagnosticEnd(streamID string, exitCode int, err error) {
+	data := map[string]interface{}{
+		"success":   err == nil,
+		"exit_code": exitCode,
+	}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	c.sendStreamMessage(streamID, "diagnostic_stream_end", data)
+}
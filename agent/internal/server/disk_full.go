@@ -0,0 +1,142 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// Windows 下磁盘已满的错误码：ERROR_HANDLE_DISK_FULL、ERROR_DISK_FULL
+const (
+	winErrorHandleDiskFull syscall.Errno = 39
+	winErrorDiskFull       syscall.Errno = 112
+)
+
+// DiskFullError 目标磁盘空间不足，面板据此提示用户清理空间而不是显示原始 errno
+type DiskFullError struct {
+	Path      string
+	Available uint64 // 剩余可用字节，获取失败时为 0
+	Required  uint64 // 本次写入需要的字节，未知时为 0
+	Err       error  // 写入时的原始错误，写入前预检查拒绝时为 nil
+}
+
+func (e *DiskFullError) Error() string {
+	msg := fmt.Sprintf("磁盘空间不足: %s 剩余 %d 字节", e.Path, e.Available)
+	if e.Required > 0 {
+		msg += fmt.Sprintf("，需要 %d 字节", e.Required)
+	}
+	if e.Err != nil {
+		msg += fmt.Sprintf("（%v）", e.Err)
+	}
+	return msg
+}
+
+func (e *DiskFullError) Unwrap() error { return e.Err }
+
+// isNoSpace 判断错误是否由磁盘已满（ENOSPC）引起
+func isNoSpace(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return errno == winErrorDiskFull || errno == winErrorHandleDiskFull
+	}
+	return errno == syscall.ENOSPC
+}
+
+// freeSpace 返回 path 所在文件系统的可用字节数，path 不存在时取最近的已存在的上级目录
+func freeSpace(path string) (uint64, bool) {
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil {
+			usage, err := disk.Usage(dir)
+			if err != nil {
+				return 0, false
+			}
+			return usage.Free, true
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return 0, false
+		}
+	}
+}
+
+// checkFreeSpace 在写入前检查剩余空间，不足 required 字节时提前拒绝；无法获取剩余空间时放行
+func checkFreeSpace(path string, required int64) error {
+	if required <= 0 {
+		return nil
+	}
+	free, ok := freeSpace(path)
+	if !ok || free >= uint64(required) {
+		return nil
+	}
+	return &DiskFullError{Path: path, Available: free, Required: uint64(required)}
+}
+
+// wrapNoSpace 把磁盘已满引起的写入错误转换为 DiskFullError，其他错误原样返回
+func wrapNoSpace(path string, required int64, err error) error {
+	if err == nil || !isNoSpace(err) {
+		return err
+	}
+	var diskFull *DiskFullError
+	if errors.As(err, &diskFull) {
+		return err
+	}
+	free, _ := freeSpace(path)
+	if required < 0 {
+		required = 0
+	}
+	return &DiskFullError{Path: path, Available: free, Required: uint64(required), Err: err}
+}
+
+// addDiskFullFields 磁盘已满时在错误响应中附加结构化字段，便于面板给出明确提示
+func addDiskFullFields(resp map[string]interface{}, err error) map[string]interface{} {
+	var diskFull *DiskFullError
+	if errors.As(err, &diskFull) {
+		resp["code"] = "disk_full"
+		resp["available"] = diskFull.Available
+		resp["required"] = diskFull.Required
+	}
+	return resp
+}
+
+// fileErrorData 构造文件操作失败的响应数据
+func fileErrorData(err error) map[string]interface{} {
+	return addDiskFullFields(map[string]interface{}{"error": err.Error()}, err)
+}
+
+// restoreFromBackup 保存失败后用备份恢复文件。文件内容与备份一致时无需恢复；
+// 恢复通过临时文件加重命名完成，恢复本身失败（例如磁盘仍然已满）时不会截断现有文件
+func restoreFromBackup(path, backupPath string) error {
+	backup, err := os.ReadFile(backupPath)
+	if err != nil {
+		return fmt.Errorf("读取备份失败: %w", err)
+	}
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, backup) {
+		return nil
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(backupPath); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tempPath := path + fmt.Sprintf(".restore-%d", time.Now().UnixNano())
+	if err := os.WriteFile(tempPath, backup, mode); err != nil {
+		os.Remove(tempPath)
+		return wrapNoSpace(path, int64(len(backup)), fmt.Errorf("写入恢复文件失败: %w", err))
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("替换为备份失败: %w", err)
+	}
+	return nil
+}
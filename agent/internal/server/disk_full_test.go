@@ -0,0 +1,63 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskFullError(t *testing.T) {
+	dir := t.TempDir()
+
+	// 剩余空间不足时提前拒绝，路径不存在时取上级目录
+	err := checkFreeSpace(filepath.Join(dir, "missing", "sub"), math.MaxInt64)
+	var diskFull *DiskFullError
+	assert.True(t, errors.As(err, &diskFull))
+	assert.Equal(t, uint64(math.MaxInt64), diskFull.Required)
+	assert.NoError(t, checkFreeSpace(dir, 1))
+
+	data := fileErrorData(err)
+	assert.Equal(t, "disk_full", data["code"])
+	assert.Equal(t, uint64(math.MaxInt64), data["required"])
+
+	// 写入时的 ENOSPC 转换为 DiskFullError，其他错误原样返回
+	if runtime.GOOS != "windows" {
+		writeErr := fmt.Errorf("写入临时文件失败: %w", &os.PathError{Op: "write", Path: "/x", Err: syscall.ENOSPC})
+		err = wrapNoSpace(dir, 100, writeErr)
+		assert.True(t, errors.As(err, &diskFull))
+		assert.Equal(t, uint64(100), diskFull.Required)
+		assert.True(t, errors.Is(err, syscall.ENOSPC))
+	}
+	other := errors.New("permission denied")
+	assert.Equal(t, other, wrapNoSpace(dir, 100, other))
+	assert.NotContains(t, fileErrorData(other), "code")
+}
+
+func TestRestoreFromBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.conf")
+	backup := path + ".bak"
+	assert.NoError(t, os.WriteFile(backup, []byte("listen 80;\n"), 0644))
+
+	// 文件被截断时从备份恢复
+	assert.NoError(t, os.WriteFile(path, []byte("lis"), 0644))
+	assert.NoError(t, restoreFromBackup(path, backup))
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "listen 80;\n", string(content))
+
+	// 内容一致时无需恢复
+	assert.NoError(t, restoreFromBackup(path, backup))
+
+	// 备份缺失时报告错误
+	assert.Error(t, restoreFromBackup(path, filepath.Join(dir, "missing.bak")))
+}
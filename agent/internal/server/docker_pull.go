@@ -0,0 +1,138 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/user/server-ops-agent/internal/monitor"
+)
+
+const (
+	// 拉取进度推送的最小间隔，避免大镜像的逐字节进度占满连接
+	pullProgressInterval = 500 * time.Millisecond
+	// 单次拉取的最长时间
+	pullImageTimeout = 30 * time.Minute
+)
+
+// handleDockerPullStream 处理镜像拉取流请求（start / stop），
+// start 开始拉取并持续推送逐层进度，stop 取消进行中的拉取
+func (c *Client) handleDockerPullStream(message []byte) {
+	var msg struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Action   string `json:"action"`
+			StreamID string `json:"stream_id"`
+			Image    string `json:"image"`
+			// 面板保存了该仓库的凭据时随请求下发
+			Auth *monitor.RegistryAuth `json:"auth"`
+		} `json:"payload"`
+	}
+
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.log.Error("解析镜像拉取请求失败: %v", err)
+		return
+	}
+
+	switch msg.Payload.Action {
+	case "start":
+		c.startPullStream(msg.Payload.StreamID, msg.Payload.Image, msg.Payload.Auth)
+	case "stop":
+		c.closePullStream(msg.Payload.StreamID)
+	default:
+		c.log.Warn("未知的镜像拉取操作: %s", msg.Payload.Action)
+	}
+}
+
+// startPullStream 开始拉取镜像
+func (c *Client) startPullStream(streamID, imageRef string, auth *monitor.RegistryAuth) {
+	if streamID == "" {
+		c.log.Error("镜像拉取缺少 stream_id")
+		return
+	}
+
+	c.pullStreamsLock.Lock()
+	if _, exists := c.pullStreams[streamID]; exists {
+		c.pullStreamsLock.Unlock()
+		c.log.Warn("镜像拉取 %s 已存在，忽略重复 start 请求", streamID)
+		return
+	}
+	c.pullStreamsLock.Unlock()
+
+	dockerManager, err := monitor.NewDockerManager(c.log)
+	if err != nil {
+		c.log.Error("创建Docker管理器失败: %v", err)
+		c.sendPullEnd(streamID, imageRef, fmt.Errorf("创建Docker管理器失败: %v", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pullImageTimeout)
+	c.pullStreamsLock.Lock()
+	c.pullStreams[streamID] = cancel
+	c.pullStreamsLock.Unlock()
+
+	c.log.Info("开始拉取镜像 %s [%s]", imageRef, streamID)
+
+	go c.pullImage(ctx, streamID, imageRef, auth, dockerManager)
+}
+
+// pullImage 拉取镜像并按间隔推送进度，结束时推送结果
+func (c *Client) pullImage(ctx context.Context, streamID, imageRef string, auth *monitor.RegistryAuth, dm *monitor.DockerManager) {
+	defer dm.Close()
+	defer c.closePullStream(streamID)
+
+	var lastSent time.Time
+	err := dm.PullImageWithProgress(ctx, imageRef, auth, func(progress monitor.PullProgress) {
+		if time.Since(lastSent) < pullProgressInterval {
+			return
+		}
+		lastSent = time.Now()
+		c.sendStreamMessage(streamID, "docker_pull_stream_data", map[string]interface{}{
+			"progress": progress,
+		})
+	})
+
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		c.log.Info("镜像 %s 的拉取已取消 [%s]", imageRef, streamID)
+		c.sendPullEnd(streamID, imageRef, errors.New("拉取已取消"))
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		c.sendPullEnd(streamID, imageRef, fmt.Errorf("拉取超时（%s）", pullImageTimeout))
+	case err != nil:
+		c.log.Error("拉取镜像 %s 失败: %v", imageRef, err)
+		c.sendPullEnd(streamID, imageRef, err)
+	default:
+		c.log.Info("镜像 %s 拉取成功", imageRef)
+		c.sendPullEnd(streamID, imageRef, nil)
+	}
+}
+
+// sendPullEnd 推送拉取结果，err 为 nil 表示成功
+func (c *Client) sendPullEnd(streamID, imageRef string, err error) {
+	data := map[string]interface{}{
+		"image":   imageRef,
+		"success": err == nil,
+	}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	c.sendStreamMessage(streamID, "docker_pull_stream_end", data)
+}
+
+// closePullStream 取消指定的镜像拉取
+func (c *Client) closePullStream(streamID string) {
+	c.pullStreamsLock.Lock()
+	cancel, ok := c.pullStreams[streamID]
+	if ok {
+		delete(c.pullStreams, streamID)
+	}
+	c.pullStreamsLock.Unlock()
+
+	if ok {
+		cancel()
+	}
+}
@@ -0,0 +1,206 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/user/server-ops-agent/internal/monitor"
+)
+
+const (
+	// 容器资源统计流的默认推送间隔和允许范围
+	defaultStatsStreamInterval = 2 * time.Second
+	minStatsStreamInterval     = time.Second
+	maxStatsStreamInterval     = time.Minute
+	// 单次读取所有容器资源统计的超时时间
+	dockerStatsTimeout = 30 * time.Second
+)
+
+// RunDockerStatsSchedule 按间隔采集运行中容器的资源统计并上报面板保存，interval <= 0 时直接返回。
+// 主机未安装 Docker 时停止上报，无权限或守护进程暂不可用时下次继续尝试
+func (c *Client) RunDockerStatsSchedule(interval time.Duration, stopCh <-chan struct{}) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var dockerManager *monitor.DockerManager
+	defer func() {
+		if dockerManager != nil {
+			dockerManager.Close()
+		}
+	}()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !c.IsConnected() {
+				continue
+			}
+			if dockerManager == nil {
+				dm, err := monitor.NewDockerManager(c.log)
+				if errors.Is(err, monitor.ErrDockerNotInstalled) {
+					c.log.Debug("未安装Docker，停止上报容器资源统计")
+					return
+				}
+				if err != nil {
+					c.log.Debug("创建Docker管理器失败，跳过本次容器资源统计: %v", err)
+					continue
+				}
+				dockerManager = dm
+			}
+
+			stats, err := c.collectDockerStats(dockerManager)
+			if err != nil {
+				c.log.Warn("采集容器资源统计失败: %v", err)
+				c.RecordError(ErrorCollect, err)
+				continue
+			}
+			if len(stats) == 0 {
+				continue
+			}
+			msg := map[string]interface{}{
+				"type": "docker_stats",
+				"payload": map[string]interface{}{
+					"containers": stats,
+				},
+			}
+			if err := c.writeJSON(msg); err != nil {
+				c.log.Warn("发送容器资源统计失败: %v", err)
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// collectDockerStats 在超时时间内读取所有运行中容器的资源统计
+func (c *Client) collectDockerStats(dm *monitor.DockerManager) ([]monitor.ContainerStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerStatsTimeout)
+	defer cancel()
+	return dm.GetContainerStats(ctx)
+}
+
+// ==================== 容器资源统计流 ====================
+
+// handleDockerStatsStream 处理容器资源统计流请求（start / stop），
+// 面板实时查看容器资源时按 interval（秒）持续推送所有运行中容器的统计
+func (c *Client) handleDockerStatsStream(message []byte) {
+	var msg struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Action   string `json:"action"`
+			StreamID string `json:"stream_id"`
+			Interval int    `json:"interval"`
+		} `json:"payload"`
+	}
+
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.log.Error("解析资源统计流请求失败: %v", err)
+		return
+	}
+
+	switch msg.Payload.Action {
+	case "start":
+		interval := defaultStatsStreamInterval
+		if msg.Payload.Interval > 0 {
+			interval = min(max(time.Duration(msg.Payload.Interval)*time.Second, minStatsStreamInterval), maxStatsStreamInterval)
+		}
+		c.startStatsStream(msg.Payload.StreamID, interval)
+	case "stop":
+		c.closeStatsStream(msg.Payload.StreamID)
+	default:
+		c.log.Warn("未知的资源统计流操作: %s", msg.Payload.Action)
+	}
+}
+
+// startStatsStream 启动一个容器资源统计流
+func (c *Client) startStatsStream(streamID string, interval time.Duration) {
+	if streamID == "" {
+		c.log.Error("资源统计流缺少 stream_id")
+		return
+	}
+
+	c.statsStreamsLock.Lock()
+	if _, exists := c.statsStreams[streamID]; exists {
+		c.statsStreamsLock.Unlock()
+		c.log.Warn("资源统计流 %s 已存在，忽略重复 start 请求", streamID)
+		return
+	}
+	c.statsStreamsLock.Unlock()
+
+	dockerManager, err := monitor.NewDockerManager(c.log)
+	if err != nil {
+		c.log.Error("创建Docker管理器失败: %v", err)
+		c.sendStreamMessage(streamID, "docker_stats_stream_end", map[string]interface{}{
+			"reason": fmt.Sprintf("创建Docker管理器失败: %v", err),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.statsStreamsLock.Lock()
+	c.statsStreams[streamID] = cancel
+	c.statsStreamsLock.Unlock()
+
+	c.log.Info("资源统计流 %s 已启动，间隔: %s", streamID, interval)
+
+	go c.streamDockerStats(ctx, streamID, interval, dockerManager)
+}
+
+// streamDockerStats 按间隔读取容器资源统计并发送给面板，直到流被关闭
+func (c *Client) streamDockerStats(ctx context.Context, streamID string, interval time.Duration, dm *monitor.DockerManager) {
+	defer dm.Close()
+	defer c.closeStatsStream(streamID)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// 读取本身约需 1 秒，读取期间流被关闭时丢弃结果
+		readCtx, cancel := context.WithTimeout(ctx, dockerStatsTimeout)
+		stats, err := dm.GetContainerStats(readCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			c.log.Error("读取容器资源统计失败 [%s]: %v", streamID, err)
+			c.sendStreamMessage(streamID, "docker_stats_stream_end", map[string]interface{}{
+				"reason": fmt.Sprintf("读取资源统计失败: %v", err),
+			})
+			return
+		}
+		c.sendStreamMessage(streamID, "docker_stats_stream_data", map[string]interface{}{
+			"timestamp":  time.Now().UnixMilli(),
+			"containers": stats,
+		})
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// closeStatsStream 关闭指定的资源统计流
+func (c *Client) closeStatsStream(streamID string) {
+	c.statsStreamsLock.Lock()
+	cancel, ok := c.statsStreams[streamID]
+	if ok {
+		delete(c.statsStreams, streamID)
+	}
+	c.statsStreamsLock.Unlock()
+
+	if ok {
+		cancel()
+		c.log.Info("资源统计流 %s 已关闭", streamID)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build monitor_only
+
+package server
+
+import "time"
+
+// RunDockerStatsSchedule 监控版不管理 Docker，不上报容器资源统计
+func (c *Client) RunDockerStatsSchedule(interval time.Duration, stopCh <-chan struct{}) {}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 升级重启前等待进行中操作完成的最长时间
+const upgradeDrainTimeout = 10 * time.Second
+
+// drainState 升级前的排空状态和进行中的操作数，二者由同一把锁保护，
+// 保证进入排空状态后不会再有新的操作被计入
+type drainState struct {
+	mu       sync.Mutex
+	draining bool
+	inflight int
+	idle     chan struct{} // 排空期间进行中的操作全部完成时关闭
+}
+
+// runOperation 在独立 goroutine 中执行操作类请求，并计入进行中的操作，
+// 以便升级重启前能够等待其完成；已进入排空状态时拒绝该请求
+func (c *Client) runOperation(handler func([]byte), message []byte) {
+	if !c.beginOperation() {
+		c.rejectWhileDraining(messageType(message), message)
+		return
+	}
+	go func() {
+		defer c.endOperation()
+		handler(message)
+	}()
+}
+
+// runStream 在独立 goroutine 中处理流式会话（终端、日志和文件跟踪、容器统计）的请求。
+// 这些会话会一直持续到面板关闭，不计入进行中的操作，排空时不等待，连接关闭时随之结束
+func (c *Client) runStream(handler func([]byte), message []byte) {
+	if c.isDraining() {
+		c.rejectWhileDraining(messageType(message), message)
+		return
+	}
+	go handler(message)
+}
+
+// beginOperation 未处于排空状态时计入一个进行中的操作
+func (c *Client) beginOperation() bool {
+	c.drain.mu.Lock()
+	defer c.drain.mu.Unlock()
+	if c.drain.draining {
+		return false
+	}
+	c.drain.inflight++
+	return true
+}
+
+// endOperation 结束一个进行中的操作，排空期间最后一个操作结束时通知等待方
+func (c *Client) endOperation() {
+	c.drain.mu.Lock()
+	defer c.drain.mu.Unlock()
+	c.drain.inflight--
+	if c.drain.inflight == 0 && c.drain.idle != nil {
+		close(c.drain.idle)
+		c.drain.idle = nil
+	}
+}
+
+// startDrain 进入排空状态，返回进行中的操作全部完成时关闭的通道
+func (c *Client) startDrain() <-chan struct{} {
+	c.drain.mu.Lock()
+	defer c.drain.mu.Unlock()
+	c.drain.draining = true
+	done := make(chan struct{})
+	if c.drain.inflight == 0 {
+		close(done)
+	} else {
+		c.drain.idle = done
+	}
+	return done
+}
+
+// isDraining 是否处于升级前的排空状态
+func (c *Client) isDraining() bool {
+	c.drain.mu.Lock()
+	defer c.drain.mu.Unlock()
+	return c.drain.draining
+}
+
+// messageType 读取消息的 type 字段
+func messageType(message []byte) string {
+	var baseMsg struct {
+		Type string `json:"type"`
+	}
+	_ = json.Unmarshal(message, &baseMsg)
+	return baseMsg.Type
+}
+
+// rejectWhileDraining 排空期间拒绝新的操作命令，告知面板端 Agent 正在升级
+func (c *Client) rejectWhileDraining(msgType string, message []byte) {
+	var baseMsg struct {
+		RequestID string `json:"request_id"`
+	}
+	_ = json.Unmarshal(message, &baseMsg)
+
+	c.log.Warn("Agent正在升级，拒绝新的操作命令: %s", msgType)
+
+	resp := map[string]interface{}{
+		"type":       msgType + "_error",
+		"request_id": baseMsg.RequestID,
+		"payload": map[string]interface{}{
+			"error": "Agent正在升级，请稍后重试",
+			"code":  "ERR_AGENT_UPGRADING",
+			"time":  time.Now().UTC().Format(time.RFC3339),
+		},
+	}
+	if err := c.writeJSON(resp); err != nil {
+		c.log.Warn("发送升级中错误响应失败: %v", err)
+	}
+}
+
+// drainForUpgrade 进入排空状态：停止接收新命令，等待进行中的操作完成（或超时），
+// 然后发送正常的关闭帧并停止重连，随后由升级流程重启进程
+func (c *Client) drainForUpgrade(requestID string) {
+	done := c.startDrain()
+	c.sendUpgradeStatus(requestID, "draining", "等待进行中的操作完成后重启", nil)
+
+	select {
+	case <-done:
+		c.log.Info("进行中的操作已全部完成")
+	case <-time.After(upgradeDrainTimeout):
+		c.log.Warn("等待进行中的操作超时(%s)，继续重启", upgradeDrainTimeout)
+	}
+
+	c.wsMutex.Lock()
+	defer c.wsMutex.Unlock()
+	c.wsShutdown = true
+
+	if c.wsConn == nil {
+		return
+	}
+
+	c.wsWriteMutex.Lock()
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseServiceRestart, "agent upgrading")
+	if err := c.wsConn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second)); err != nil {
+		c.log.Warn("发送关闭帧失败: %v", err)
+	}
+	c.wsWriteMutex.Unlock()
+
+	c.wsConn.Close()
+	c.wsConn = nil
+	c.wsConnected = false
+	c.log.Info("升级前已关闭WebSocket连接")
+}
+
+// abortDrain 重启失败时退出排空状态并恢复连接
+func (c *Client) abortDrain() {
+	c.drain.mu.Lock()
+	if !c.drain.draining {
+		c.drain.mu.Unlock()
+		return
+	}
+	c.drain.draining = false
+	c.drain.idle = nil
+	c.drain.mu.Unlock()
+
+	c.wsMutex.Lock()
+	c.wsShutdown = false
+	c.wsMutex.Unlock()
+
+	c.log.Warn("升级重启失败，恢复正常工作状态")
+	c.triggerReconnect()
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/config"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func TestDrainWaitsForOperations(t *testing.T) {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	c := &Client{cfg: &config.Config{}, log: log}
+
+	release := make(chan struct{})
+	var ran sync.WaitGroup
+	ran.Add(1)
+	c.runOperation(func([]byte) {
+		ran.Done()
+		<-release
+	}, []byte(`{"type":"file_list"}`))
+	ran.Wait()
+
+	// 流式会话不计入进行中的操作
+	streamDone := make(chan struct{})
+	c.runStream(func([]byte) { <-streamDone }, []byte(`{"type":"file_tail_stream"}`))
+	defer close(streamDone)
+
+	drained := make(chan struct{})
+	go func() {
+		c.drainForUpgrade("req-1")
+		close(drained)
+	}()
+	assert.Eventually(t, c.isDraining, time.Second, 5*time.Millisecond)
+
+	// 排空期间的新操作被拒绝，不会再被计入
+	called := false
+	c.runOperation(func([]byte) { called = true }, []byte(`{"type":"file_list"}`))
+	c.runStream(func([]byte) { called = true }, []byte(`{"type":"docker_logs_stream"}`))
+	select {
+	case <-drained:
+		t.Fatal("进行中的操作未完成时不应结束排空")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatal("进行中的操作完成后应立即结束排空")
+	}
+	assert.False(t, called)
+
+	// 重启失败时恢复接收操作，再次排空时没有进行中的操作立即结束
+	c.abortDrain()
+	assert.False(t, c.isDraining())
+	done := make(chan struct{})
+	c.runOperation(func([]byte) { close(done) }, []byte(`{"type":"file_list"}`))
+	<-done
+	assert.Eventually(t, func() bool {
+		c.drain.mu.Lock()
+		defer c.drain.mu.Unlock()
+		return c.drain.inflight == 0
+	}, time.Second, 5*time.Millisecond)
+	select {
+	case <-c.startDrain():
+	default:
+		t.Fatal("没有进行中的操作时应立即结束排空")
+	}
+}
+
+// TestDrainConcurrentOperations 排空与新操作并发时，计入的操作数不会出错（配合 -race 运行）
+func TestDrainConcurrentOperations(t *testing.T) {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	c := &Client{cfg: &config.Config{}, log: log}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.runOperation(func([]byte) { time.Sleep(time.Millisecond) }, []byte(`{"type":"file_list"}`))
+		}()
+	}
+	done := c.startDrain()
+	wg.Wait()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("排空未结束")
+	}
+	c.drain.mu.Lock()
+	assert.Equal(t, 0, c.drain.inflight)
+	c.drain.mu.Unlock()
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Agent 内部错误的分类
+const (
+	ErrorSend      = "send"      // 发送监控数据或响应失败
+	ErrorCollect   = "collect"   // 采集监控数据失败
+	ErrorReconnect = "reconnect" // 连接断开后重连
+	ErrorPanic     = "panic"     // 处理消息时 panic 并已恢复
+)
+
+const (
+	// recentErrorWindow 随监控数据上报的近期错误数的统计窗口，面板据此判断错误是否突增
+	recentErrorWindow = 5 * time.Minute
+	// errorHistoryWindow 保留每个错误时间的时长，用于统计最近一小时的错误数
+	errorHistoryWindow = time.Hour
+	// maxErrorHistory 每个分类最多保留的错误时间数，避免错误风暴时占用过多内存
+	maxErrorHistory = 1000
+)
+
+// errorCounter 单个分类的错误计数
+type errorCounter struct {
+	total     uint64
+	lastAt    time.Time
+	lastError string
+	history   []time.Time // 最近 errorHistoryWindow 内的错误时间，按时间排序
+}
+
+// errorStats 按分类统计 Agent 自身的错误，零值可直接使用
+type errorStats struct {
+	mu       sync.Mutex
+	counters map[string]*errorCounter
+	since    time.Time // 开始统计（启动或上次清零）的时间
+}
+
+// record 记录一次错误
+func (s *errorStats) record(category string, reason interface{}, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.counters == nil {
+		s.counters = make(map[string]*errorCounter)
+	}
+	counter := s.counters[category]
+	if counter == nil {
+		counter = &errorCounter{}
+		s.counters[category] = counter
+	}
+	counter.total++
+	counter.lastAt = now
+	if reason != nil {
+		counter.lastError = fmt.Sprint(reason)
+	}
+	counter.history = append(pruneErrorHistory(counter.history, now), now)
+	if len(counter.history) > maxErrorHistory {
+		counter.history = counter.history[len(counter.history)-maxErrorHistory:]
+	}
+}
+
+// pruneErrorHistory 删除超出 errorHistoryWindow 的错误时间
+func pruneErrorHistory(history []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-errorHistoryWindow)
+	i := 0
+	for i < len(history) && history[i].Before(cutoff) {
+		i++
+	}
+	return history[i:]
+}
+
+// countSince 统计 history 中晚于 cutoff 的错误数
+func countSince(history []time.Time, cutoff time.Time) int {
+	n := 0
+	for i := len(history) - 1; i >= 0 && history[i].After(cutoff); i-- {
+		n++
+	}
+	return n
+}
+
+// recent 返回所有分类在 window 内的错误总数
+func (s *errorStats) recent(window time.Duration, now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := 0
+	for _, counter := range s.counters {
+		total += countSince(counter.history, now.Add(-window))
+	}
+	return total
+}
+
+// snapshot 返回各分类的累计错误数、最近 5 分钟和 1 小时的错误数以及最后一次错误
+func (s *errorStats) snapshot(now time.Time) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	categories := make(map[string]interface{})
+	for _, category := range []string{ErrorSend, ErrorCollect, ErrorReconnect, ErrorPanic} {
+		item := map[string]interface{}{"total": uint64(0), "last_5m": 0, "last_1h": 0}
+		if counter := s.counters[category]; counter != nil {
+			counter.history = pruneErrorHistory(counter.history, now)
+			item["total"] = counter.total
+			item["last_5m"] = countSince(counter.history, now.Add(-recentErrorWindow))
+			item["last_1h"] = len(counter.history)
+			item["last_at"] = counter.lastAt.UTC().Format(time.RFC3339)
+			item["last_error"] = counter.lastError
+		}
+		categories[category] = item
+	}
+	return map[string]interface{}{
+		"since":      s.since.UTC().Format(time.RFC3339),
+		"categories": categories,
+	}
+}
+
+// reset 清零所有计数
+func (s *errorStats) reset(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counters = nil
+	s.since = now
+}
+
+// RecordError 记录一次 Agent 内部错误，reason 为错误或 panic 的值
+func (c *Client) RecordError(category string, reason interface{}) {
+	c.errStats.record(category, reason, time.Now())
+}
+
+// errorStatsRequest 面板查询或清零错误计数的请求
+type errorStatsRequest struct {
+	Type      string `json:"type"`
+	RequestID string `json:"request_id"`
+	Payload   struct {
+		Action string `json:"action"` // get 或 reset
+	} `json:"payload"`
+}
+
+// handleErrorStats 返回 Agent 内部错误的分类计数，reset 时先清零
+func (c *Client) handleErrorStats(message []byte) {
+	var req errorStatsRequest
+	if err := json.Unmarshal(message, &req); err != nil {
+		c.log.Error("解析错误计数请求失败: %v", err)
+		return
+	}
+
+	switch strings.ToLower(req.Payload.Action) {
+	case "", "get":
+	case "reset":
+		c.errStats.reset(time.Now())
+		c.log.Info("面板已清零错误计数")
+	default:
+		c.sendResponse(req.RequestID, "agent_error_stats_response", map[string]interface{}{
+			"error": "不支持的操作: " + req.Payload.Action,
+		})
+		return
+	}
+
+	c.sendResponse(req.RequestID, "agent_error_stats_response", c.errStats.snapshot(time.Now()))
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorStats(t *testing.T) {
+	var s errorStats
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	s.reset(now)
+
+	s.record(ErrorSend, errors.New("broken pipe"), now.Add(-2*time.Hour))
+	s.record(ErrorSend, errors.New("timeout"), now.Add(-30*time.Minute))
+	s.record(ErrorSend, errors.New("timeout"), now.Add(-time.Minute))
+	s.record(ErrorPanic, "nil map", now.Add(-10*time.Second))
+
+	assert.Equal(t, 2, s.recent(recentErrorWindow, now))
+
+	snap := s.snapshot(now)
+	categories := snap["categories"].(map[string]interface{})
+	send := categories[ErrorSend].(map[string]interface{})
+	assert.Equal(t, uint64(3), send["total"])
+	assert.Equal(t, 1, send["last_5m"])
+	assert.Equal(t, 2, send["last_1h"])
+	assert.Equal(t, "timeout", send["last_error"])
+	collect := categories[ErrorCollect].(map[string]interface{})
+	assert.Equal(t, uint64(0), collect["total"])
+	assert.Nil(t, collect["last_at"])
+
+	s.reset(now)
+	assert.Equal(t, 0, s.recent(recentErrorWindow, now))
+	assert.Equal(t, now.Format(time.RFC3339), s.snapshot(now)["since"])
+
+	// 错误风暴时只保留最近的错误时间
+	for i := 0; i < maxErrorHistory+10; i++ {
+		s.record(ErrorCollect, nil, now)
+	}
+	assert.Equal(t, maxErrorHistory, s.recent(recentErrorWindow, now.Add(time.Second)))
+}
@@ -21,6 +21,10 @@ type FileInfo struct {
 	ModTime  string      `json:"mod_time"`           // 修改时间
 	IsDir    bool        `json:"is_dir"`             // 是否是目录
 	Mode     string      `json:"mode"`               // 文件权限
+	Owner    string      `json:"owner,omitempty"`    // 所有者用户名，无法解析时为UID（Windows 上为空）
+	Group    string      `json:"group,omitempty"`    // 所属组名，无法解析时为GID
+	UID      *uint32     `json:"uid,omitempty"`      // 所有者UID
+	GID      *uint32     `json:"gid,omitempty"`      // 所属组GID
 	Children []*FileInfo `json:"children,omitempty"` // 子文件（目录树使用）
 }
 
@@ -44,6 +48,10 @@ func (fm *FileManager) ListFiles(path string) ([]*FileInfo, error) {
 	if path == "" {
 		path = "/"
 	}
+	path, err := normalizeHostPath(path)
+	if err != nil {
+		return nil, err
+	}
 
 	// 打开目录
 	dir, err := os.Open(path)
@@ -63,13 +71,7 @@ func (fm *FileManager) ListFiles(path string) ([]*FileInfo, error) {
 	// 转换为FileInfo结构
 	files := make([]*FileInfo, 0, len(entries))
 	for _, entry := range entries {
-		files = append(files, &FileInfo{
-			Name:    entry.Name(),
-			Size:    entry.Size(),
-			ModTime: entry.ModTime().Format(time.RFC3339),
-			IsDir:   entry.IsDir(),
-			Mode:    entry.Mode().String(),
-		})
+		files = append(files, newFileInfo(entry))
 	}
 
 	// 排序：目录在前，文件在后，然后按名称排序
@@ -87,6 +89,11 @@ func (fm *FileManager) ListFiles(path string) ([]*FileInfo, error) {
 func (fm *FileManager) GetFileContent(path string) (string, error) {
 	fm.log.Debug("获取文件内容: %s", path)
 
+	path, err := normalizeHostPath(path)
+	if err != nil {
+		return "", err
+	}
+
 	// 检查文件大小
 	fileInfo, err := os.Stat(path)
 	if err != nil {
@@ -114,6 +121,11 @@ func (fm *FileManager) GetFileContent(path string) (string, error) {
 func (fm *FileManager) SaveFileContent(path, content string) error {
 	fm.log.Debug("保存文件内容: %s", path)
 
+	path, err := normalizeHostPath(path)
+	if err != nil {
+		return err
+	}
+
 	// 确保目录存在
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -142,6 +154,12 @@ func (fm *FileManager) SaveFileContent(path, content string) error {
 		}
 	}
 
+	// 临时文件需要完整写入一份内容，空间不足时提前拒绝
+	if err := checkFreeSpace(dir, int64(len(content))); err != nil {
+		fm.log.Error("保存文件失败: %v", err)
+		return err
+	}
+
 	// 创建临时文件，使用随机后缀防止冲突
 	tempPath = path + fmt.Sprintf(".tmp-%d", time.Now().UnixNano())
 	
@@ -150,7 +168,7 @@ func (fm *FileManager) SaveFileContent(path, content string) error {
 		fm.log.Error("写入临时文件失败: %v", err)
 		// 清理临时文件
 		os.Remove(tempPath)
-		return fmt.Errorf("写入临时文件失败: %v", err)
+		return wrapNoSpace(dir, int64(len(content)), fmt.Errorf("写入临时文件失败: %w", err))
 	}
 
 	// 确保临时文件被写入磁盘
@@ -158,7 +176,7 @@ func (fm *FileManager) SaveFileContent(path, content string) error {
 		fm.log.Error("同步临时文件到磁盘失败: %v", err)
 		// 清理临时文件
 		os.Remove(tempPath)
-		return fmt.Errorf("同步临时文件到磁盘失败: %v", err)
+		return wrapNoSpace(dir, int64(len(content)), fmt.Errorf("同步临时文件到磁盘失败: %w", err))
 	}
 
 	// 重命名临时文件为目标文件
@@ -201,6 +219,11 @@ func syncFile(path string) error {
 func (fm *FileManager) CreateFile(path, content string) error {
 	fm.log.Debug("创建文件: %s", path)
 
+	path, err := normalizeHostPath(path)
+	if err != nil {
+		return err
+	}
+
 	// 检查文件是否已存在
 	if _, err := os.Stat(path); err == nil {
 		fm.log.Error("文件已存在: %s", path)
@@ -214,10 +237,16 @@ func (fm *FileManager) CreateFile(path, content string) error {
 		return fmt.Errorf("创建目录失败: %v", err)
 	}
 
-	// 写入文件内容
+	if err := checkFreeSpace(dir, int64(len(content))); err != nil {
+		fm.log.Error("创建文件失败: %v", err)
+		return err
+	}
+
+	// 写入文件内容，失败时删除写了一半的文件
 	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
 		fm.log.Error("写入文件内容失败: %v", err)
-		return fmt.Errorf("写入文件内容失败: %v", err)
+		os.Remove(path)
+		return wrapNoSpace(dir, int64(len(content)), fmt.Errorf("写入文件内容失败: %w", err))
 	}
 
 	return nil
@@ -227,6 +256,11 @@ func (fm *FileManager) CreateFile(path, content string) error {
 func (fm *FileManager) CreateDirectory(path string) error {
 	fm.log.Debug("创建目录: %s", path)
 
+	path, err := normalizeHostPath(path)
+	if err != nil {
+		return err
+	}
+
 	// 检查目录是否已存在
 	if _, err := os.Stat(path); err == nil {
 		fm.log.Error("目录已存在: %s", path)
@@ -246,14 +280,26 @@ func (fm *FileManager) CreateDirectory(path string) error {
 func (fm *FileManager) UploadFile(path, filename, content string) error {
 	fm.log.Debug("上传文件: %s/%s", path, filename)
 
+	path, err := normalizeHostPath(path)
+	if err != nil {
+		return err
+	}
+	safeName, err := sanitizeFileName(filename)
+	if err != nil {
+		return err
+	}
+
 	// 确保目录存在
 	if err := os.MkdirAll(path, 0755); err != nil {
 		fm.log.Error("创建目录失败: %v", err)
 		return fmt.Errorf("创建目录失败: %v", err)
 	}
 
-	// 构造完整的文件路径
-	fullPath := filepath.Join(path, filename)
+	// 构造完整的文件路径，并确认仍位于目标目录内
+	fullPath, err := normalizePath(hostPathFlavor, filepath.Join(path, safeName), path)
+	if err != nil {
+		return err
+	}
 
 	// 解码Base64内容
 	fileContent, err := base64.StdEncoding.DecodeString(content)
@@ -262,11 +308,17 @@ func (fm *FileManager) UploadFile(path, filename, content string) error {
 		return fmt.Errorf("解码文件内容失败: %v", err)
 	}
 
+	if err := checkFreeSpace(path, int64(len(fileContent))); err != nil {
+		fm.log.Error("上传文件失败: %v", err)
+		return err
+	}
+
 	// 创建临时文件
 	tempPath := fullPath + ".tmp"
 	if err := os.WriteFile(tempPath, fileContent, 0644); err != nil {
 		fm.log.Error("写入临时文件失败: %v", err)
-		return fmt.Errorf("写入临时文件失败: %v", err)
+		os.Remove(tempPath)
+		return wrapNoSpace(path, int64(len(fileContent)), fmt.Errorf("写入临时文件失败: %w", err))
 	}
 
 	// 重命名临时文件为目标文件
@@ -283,6 +335,11 @@ func (fm *FileManager) UploadFile(path, filename, content string) error {
 func (fm *FileManager) DownloadFile(path string) ([]byte, error) {
 	fm.log.Debug("下载文件: %s", path)
 
+	path, err := normalizeHostPath(path)
+	if err != nil {
+		return nil, err
+	}
+
 	// 检查文件大小
 	fileInfo, err := os.Stat(path)
 	if err != nil {
@@ -317,6 +374,11 @@ func (fm *FileManager) DeleteFiles(paths []string) error {
 	for _, path := range paths {
 		fm.log.Debug("删除文件或目录: %s", path)
 
+		path, err := normalizeHostPath(path)
+		if err != nil {
+			return err
+		}
+
 		// 检查文件是否存在
 		fileInfo, err := os.Stat(path)
 		if err != nil {
@@ -351,6 +413,10 @@ func (fm *FileManager) GetDirectoryTree(path string, depth int) ([]*FileInfo, er
 	if path == "" {
 		path = "/"
 	}
+	path, err := normalizeHostPath(path)
+	if err != nil {
+		return nil, err
+	}
 
 	// 检查路径是否存在
 	fileInfo, err := os.Stat(path)
@@ -411,13 +477,7 @@ func (fm *FileManager) buildDirectoryTree(rootPath, currentPath string, depth in
 			continue
 		}
 
-		info := &FileInfo{
-			Name:    entry.Name(),
-			Size:    entry.Size(),
-			ModTime: entry.ModTime().Format(time.RFC3339),
-			IsDir:   entry.IsDir(),
-			Mode:    entry.Mode().String(),
-		}
+		info := newFileInfo(entry)
 
 		// 如果是目录且深度大于1，则递归获取子目录
 		if entry.IsDir() && depth > 1 {
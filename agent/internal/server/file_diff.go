@@ -0,0 +1,162 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	// 参与对比的单个文件大小上限，diff 按行在内存中计算
+	maxDiffFileSize = 2 * 1024 * 1024
+	// 统一 diff 的上下文行数
+	diffContextLines = 3
+)
+
+// 保存文件时生成的备份后缀：文件管理保存为 .bak，Nginx 配置保存为 .backup
+var diffBackupSuffixes = []string{".bak", ".backup"}
+
+// fileDiffRequest 查看文件变更的请求
+type fileDiffRequest struct {
+	RequestID string `json:"request_id"`
+	Payload   struct {
+		Path     string  `json:"path"`      // 新版本文件
+		BasePath string  `json:"base_path"` // 旧版本文件，为空时使用 path 最近的备份
+		Content  *string `json:"content"`   // 不为空时以此内容作为新版本（如编辑器中尚未保存的内容）
+	} `json:"payload"`
+}
+
+// fileDiffResult 对比结果
+type fileDiffResult struct {
+	Path         string    `json:"path"`
+	BasePath     string    `json:"base_path"`
+	BaseModified time.Time `json:"base_modified"`
+	Changed      bool      `json:"changed"`
+	Diff         string    `json:"diff"` // 统一 diff 格式文本，无变化时为空
+}
+
+// handleFileDiff 返回文件与其最近备份（或指定的另一个文件）之间的统一 diff，
+// 用于保存后确认改动内容，或在重载 Nginx 之前检查配置变更
+func (c *Client) handleFileDiff(message []byte) {
+	var req fileDiffRequest
+	if err := json.Unmarshal(message, &req); err != nil {
+		c.log.Error("解析文件对比请求失败: %v", err)
+		return
+	}
+
+	result, err := diffFile(req.Payload.Path, req.Payload.BasePath, req.Payload.Content)
+	if err != nil {
+		c.log.Warn("文件对比失败: path=%s, base=%s, error=%v", req.Payload.Path, req.Payload.BasePath, err)
+		c.sendResponse(req.RequestID, "file_diff_response", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.sendTransferResponse(req.RequestID, "file_diff_response", map[string]interface{}{
+		"result": result,
+	})
+}
+
+// diffFile 计算 basePath（为空时取最近的备份）到 path 的统一 diff，content 不为空时替代 path 的内容
+func diffFile(path, basePath string, content *string) (*fileDiffResult, error) {
+	path, err := normalizeHostPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if basePath == "" {
+		basePath, err = latestBackup(path)
+		if err != nil {
+			return nil, err
+		}
+	} else if basePath, err = normalizeHostPath(basePath); err != nil {
+		return nil, err
+	}
+
+	baseData, baseInfo, err := readDiffFile(basePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var newData []byte
+	if content != nil {
+		newData = []byte(*content)
+		if len(newData) > maxDiffFileSize {
+			return nil, fmt.Errorf("内容过大，最多对比 %d 字节", maxDiffFileSize)
+		}
+	} else if newData, _, err = readDiffFile(path); err != nil {
+		return nil, err
+	}
+
+	result := &fileDiffResult{
+		Path:         path,
+		BasePath:     basePath,
+		BaseModified: baseInfo.ModTime(),
+		Changed:      !bytes.Equal(baseData, newData),
+	}
+	if !result.Changed {
+		return result, nil
+	}
+
+	result.Diff, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(baseData)),
+		B:        difflib.SplitLines(string(newData)),
+		FromFile: basePath,
+		FromDate: baseInfo.ModTime().Format(time.RFC3339),
+		ToFile:   path,
+		Context:  diffContextLines,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("生成差异失败: %v", err)
+	}
+	return result, nil
+}
+
+// latestBackup 返回 path 最近一次保存时留下的备份
+func latestBackup(path string) (string, error) {
+	var latest string
+	var latestTime time.Time
+	for _, suffix := range diffBackupSuffixes {
+		info, err := os.Stat(path + suffix)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if latest == "" || info.ModTime().After(latestTime) {
+			latest = path + suffix
+			latestTime = info.ModTime()
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("没有找到 %s 的备份文件，无法对比", path)
+	}
+	return latest, nil
+}
+
+// readDiffFile 读取参与对比的文本文件，拒绝目录、过大的文件和二进制文件
+func readDiffFile(path string) ([]byte, os.FileInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取文件信息失败: %v", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, nil, fmt.Errorf("%s 不是普通文件", path)
+	}
+	if info.Size() > maxDiffFileSize {
+		return nil, nil, fmt.Errorf("%s 过大，最多对比 %d 字节", path, maxDiffFileSize)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取文件失败: %v", err)
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return nil, nil, fmt.Errorf("%s 是二进制文件，无法对比", path)
+	}
+	return data, info, nil
+}
@@ -0,0 +1,52 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nginx.conf")
+	assert.NoError(t, os.WriteFile(path, []byte("worker_processes 2;\nevents {}\n"), 0644))
+
+	// 没有备份时无法对比
+	_, err := diffFile(path, "", nil)
+	assert.Error(t, err)
+
+	// 取最近的备份：.backup 比 .bak 新
+	assert.NoError(t, os.WriteFile(path+".bak", []byte("worker_processes 2;\nevents {}\n"), 0644))
+	assert.NoError(t, os.WriteFile(path+".backup", []byte("worker_processes 1;\nevents {}\n"), 0644))
+	old := time.Now().Add(-time.Hour)
+	assert.NoError(t, os.Chtimes(path+".bak", old, old))
+
+	result, err := diffFile(path, "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, path+".backup", result.BasePath)
+	assert.True(t, result.Changed)
+	assert.True(t, strings.Contains(result.Diff, "-worker_processes 1;\n+worker_processes 2;\n"))
+
+	// 指定旧版本文件且内容相同时无差异
+	result, err = diffFile(path, path+".bak", nil)
+	assert.NoError(t, err)
+	assert.False(t, result.Changed)
+	assert.Empty(t, result.Diff)
+
+	// 以尚未保存的内容作为新版本
+	content := "worker_processes 4;\nevents {}\n"
+	result, err = diffFile(path, path+".bak", &content)
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(result.Diff, "+worker_processes 4;\n"))
+
+	// 拒绝二进制文件
+	assert.NoError(t, os.WriteFile(path+".bak", []byte{0x7f, 'E', 'L', 'F', 0}, 0644))
+	_, err = diffFile(path, path+".bak", nil)
+	assert.Error(t, err)
+}
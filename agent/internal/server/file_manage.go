@@ -0,0 +1,319 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// parseFileMode 解析八进制权限字符串（如 "755"、"0644"、"4755"），包括 setuid/setgid/sticky 位
+func parseFileMode(mode string) (os.FileMode, error) {
+	mode = strings.TrimSpace(mode)
+	value, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || mode == "" || value > 07777 {
+		return 0, fmt.Errorf("无效的权限: %s", mode)
+	}
+	perm := os.FileMode(value & 0777)
+	if value&04000 != 0 {
+		perm |= os.ModeSetuid
+	}
+	if value&02000 != 0 {
+		perm |= os.ModeSetgid
+	}
+	if value&01000 != 0 {
+		perm |= os.ModeSticky
+	}
+	return perm, nil
+}
+
+// lookupOwnerID 把用户名或组名解析为数字ID，已经是数字时直接使用，为空时返回 -1 表示不修改
+func lookupOwnerID(name string, lookup func(string) (string, error)) (int, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return -1, nil
+	}
+	if id, err := strconv.Atoi(name); err == nil && id >= 0 {
+		return id, nil
+	}
+	idStr, err := lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(idStr)
+}
+
+// walkManaged 对 path 执行 apply，recursive 时对目录下的所有条目执行。
+// 递归时跳过符号链接，避免修改链接指向的目录树之外的文件
+func walkManaged(path string, recursive bool, apply func(string) error) error {
+	if err := apply(path); err != nil {
+		return err
+	}
+	info, err := os.Lstat(path)
+	if err != nil || !recursive || !info.IsDir() {
+		return err
+	}
+	return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == path || d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		return apply(p)
+	})
+}
+
+// Chmod 修改文件或目录的权限，recursive 时同时修改目录下的所有条目
+func (fm *FileManager) Chmod(path, mode string, recursive bool) error {
+	fm.log.Debug("修改权限: %s -> %s (递归: %v)", path, mode, recursive)
+
+	path, err := normalizeHostPath(path)
+	if err != nil {
+		return err
+	}
+	perm, err := parseFileMode(mode)
+	if err != nil {
+		return err
+	}
+
+	if err := walkManaged(path, recursive, func(p string) error { return os.Chmod(p, perm) }); err != nil {
+		fm.log.Error("修改权限失败: %v", err)
+		return fmt.Errorf("修改权限失败: %v", err)
+	}
+	return nil
+}
+
+// Chown 修改文件或目录的所有者和所属组，owner/group 可以是名称或数字ID，为空时不修改。
+// 不跟随符号链接，修改的是链接本身
+func (fm *FileManager) Chown(path, owner, group string, recursive bool) error {
+	fm.log.Debug("修改所有者: %s -> %s:%s (递归: %v)", path, owner, group, recursive)
+
+	path, err := normalizeHostPath(path)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(owner) == "" && strings.TrimSpace(group) == "" {
+		return fmt.Errorf("所有者和所属组不能同时为空")
+	}
+	uid, err := lookupOwnerID(owner, func(name string) (string, error) {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return "", err
+		}
+		return u.Uid, nil
+	})
+	if err != nil {
+		return fmt.Errorf("用户不存在: %s", owner)
+	}
+	gid, err := lookupOwnerID(group, func(name string) (string, error) {
+		g, err := user.LookupGroup(name)
+		if err != nil {
+			return "", err
+		}
+		return g.Gid, nil
+	})
+	if err != nil {
+		return fmt.Errorf("用户组不存在: %s", group)
+	}
+
+	if err := walkManaged(path, recursive, func(p string) error { return os.Lchown(p, uid, gid) }); err != nil {
+		fm.log.Error("修改所有者失败: %v", err)
+		return fmt.Errorf("修改所有者失败: %v", err)
+	}
+	return nil
+}
+
+// Rename 在原目录内重命名文件或目录，返回新路径；目标已存在时拒绝
+func (fm *FileManager) Rename(path, name string) (string, error) {
+	fm.log.Debug("重命名: %s -> %s", path, name)
+
+	path, err := normalizeHostPath(path)
+	if err != nil {
+		return "", err
+	}
+	safeName, err := sanitizeFileName(name)
+	if err != nil {
+		return "", err
+	}
+	if safeName != strings.TrimSpace(name) {
+		return "", fmt.Errorf("新名称不能包含路径: %s", name)
+	}
+	target := filepath.Join(filepath.Dir(path), safeName)
+
+	if _, err := os.Lstat(path); err != nil {
+		return "", fmt.Errorf("检查文件失败: %v", err)
+	}
+	if _, err := os.Lstat(target); err == nil {
+		return "", fmt.Errorf("目标已存在: %s", target)
+	}
+	if err := os.Rename(path, target); err != nil {
+		fm.log.Error("重命名失败: %v", err)
+		return "", fmt.Errorf("重命名失败: %v", err)
+	}
+	return target, nil
+}
+
+// resolveTransferTarget 计算移动/复制的目标路径：target 是已存在的目录时放入其中并保留原名称。
+// 目标已存在、与源相同或位于源目录内时拒绝
+func resolveTransferTarget(src, target string) (string, error) {
+	if info, err := os.Stat(target); err == nil && info.IsDir() {
+		target = filepath.Join(target, filepath.Base(src))
+	}
+	if target == src {
+		return "", fmt.Errorf("目标与源路径相同: %s", src)
+	}
+	if pathWithinRoot(hostPathFlavor, src, target) {
+		return "", fmt.Errorf("不能移动或复制到自身的子目录: %s", target)
+	}
+	if _, err := os.Lstat(target); err == nil {
+		return "", fmt.Errorf("目标已存在: %s", target)
+	}
+	if _, err := os.Stat(filepath.Dir(target)); err != nil {
+		return "", fmt.Errorf("目标目录不存在: %s", filepath.Dir(target))
+	}
+	return target, nil
+}
+
+// Move 移动文件或目录，返回最终路径。跨文件系统时先复制再删除源
+func (fm *FileManager) Move(src, target string) (string, error) {
+	fm.log.Debug("移动: %s -> %s", src, target)
+
+	src, err := normalizeHostPath(src)
+	if err != nil {
+		return "", err
+	}
+	target, err = normalizeHostPath(target)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Lstat(src); err != nil {
+		return "", fmt.Errorf("检查文件失败: %v", err)
+	}
+	if target, err = resolveTransferTarget(src, target); err != nil {
+		return "", err
+	}
+
+	err = os.Rename(src, target)
+	if err == nil {
+		return target, nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		fm.log.Error("移动失败: %v", err)
+		return "", fmt.Errorf("移动失败: %v", err)
+	}
+
+	if err := fm.copyTree(src, target); err != nil {
+		return "", err
+	}
+	if err := os.RemoveAll(src); err != nil {
+		fm.log.Error("移动后删除源文件失败: %v", err)
+		return "", fmt.Errorf("已复制到 %s，但删除源文件失败: %v", target, err)
+	}
+	return target, nil
+}
+
+// Copy 复制文件或目录，返回最终路径。权限取自源文件（受 umask 影响），符号链接按链接本身复制
+func (fm *FileManager) Copy(src, target string) (string, error) {
+	fm.log.Debug("复制: %s -> %s", src, target)
+
+	src, err := normalizeHostPath(src)
+	if err != nil {
+		return "", err
+	}
+	target, err = normalizeHostPath(target)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Lstat(src); err != nil {
+		return "", fmt.Errorf("检查文件失败: %v", err)
+	}
+	if target, err = resolveTransferTarget(src, target); err != nil {
+		return "", err
+	}
+	if err := fm.copyTree(src, target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// copyTree 把 src 复制到不存在的 target，先检查剩余空间；失败时删除已复制的部分
+func (fm *FileManager) copyTree(src, target string) error {
+	var total int64
+	_ = filepath.WalkDir(src, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	if err := checkFreeSpace(filepath.Dir(target), total); err != nil {
+		return err
+	}
+
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(target, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			// 保证所有者可写，否则只读目录下的条目无法复制进去
+			return os.Mkdir(dst, info.Mode().Perm()|0700)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, dst)
+		case d.Type().IsRegular():
+			return copyRegularFile(p, dst, info.Mode().Perm())
+		default:
+			fm.log.Warn("复制时跳过特殊文件: %s", p)
+			return nil
+		}
+	})
+	if err != nil {
+		os.RemoveAll(target)
+		fm.log.Error("复制失败: %v", err)
+		return wrapNoSpace(filepath.Dir(target), total, fmt.Errorf("复制失败: %w", err))
+	}
+	return nil
+}
+
+// copyRegularFile 复制单个普通文件
+func copyRegularFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,118 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func newTestFileManager(t *testing.T) *FileManager {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	return NewFileManager(log)
+}
+
+func TestParseFileMode(t *testing.T) {
+	mode, err := parseFileMode("755")
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), mode)
+
+	mode, err = parseFileMode("4750")
+	assert.NoError(t, err)
+	assert.Equal(t, os.ModeSetuid|0750, mode)
+
+	for _, bad := range []string{"", "abc", "888", "17777"} {
+		_, err := parseFileMode(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestChmodRecursive(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows 不支持 Unix 权限")
+	}
+	fm := newTestFileManager(t)
+	root := filepath.Join(t.TempDir(), "site")
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "sub"), 0755))
+	file := filepath.Join(root, "sub", "a.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("a"), 0644))
+
+	assert.NoError(t, fm.Chmod(root, "750", false))
+	info, _ := os.Stat(file)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	assert.NoError(t, fm.Chmod(root, "0700", true))
+	info, _ = os.Stat(file)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	assert.Error(t, fm.Chmod(root, "999", false))
+	assert.Error(t, fm.Chmod(filepath.Join(root, "missing"), "644", false))
+}
+
+func TestChownValidation(t *testing.T) {
+	fm := newTestFileManager(t)
+	file := filepath.Join(t.TempDir(), "a.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("a"), 0644))
+
+	assert.Error(t, fm.Chown(file, "", "", false))
+	assert.Error(t, fm.Chown(file, "no-such-user-bettermonitor", "", false))
+
+	uid, err := lookupOwnerID("1000", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, uid)
+	uid, err = lookupOwnerID(" ", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, -1, uid)
+}
+
+func TestRenameMoveCopy(t *testing.T) {
+	fm := newTestFileManager(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "conf")
+	assert.NoError(t, os.MkdirAll(filepath.Join(src, "sub"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "sub", "app.conf"), []byte("listen 80;"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "other.txt"), []byte("x"), 0644))
+
+	// 重命名只改名称，不能带路径，也不能覆盖已有文件
+	renamed, err := fm.Rename(src, "conf.d")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "conf.d"), renamed)
+	_, err = fm.Rename(renamed, "../escape")
+	assert.Error(t, err)
+	_, err = fm.Rename(renamed, "other.txt")
+	assert.Error(t, err)
+
+	// 复制到已存在的目录时放入其中
+	backup := filepath.Join(dir, "backup")
+	assert.NoError(t, os.Mkdir(backup, 0755))
+	copied, err := fm.Copy(renamed, backup)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(backup, "conf.d"), copied)
+	content, err := os.ReadFile(filepath.Join(copied, "sub", "app.conf"))
+	assert.NoError(t, err)
+	assert.Equal(t, "listen 80;", string(content))
+
+	// 目标已存在、复制到自身子目录时拒绝
+	_, err = fm.Copy(renamed, backup)
+	assert.Error(t, err)
+	_, err = fm.Copy(renamed, filepath.Join(renamed, "sub"))
+	assert.Error(t, err)
+
+	// 移动到新路径
+	moved, err := fm.Move(filepath.Join(dir, "other.txt"), filepath.Join(backup, "moved.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(backup, "moved.txt"), moved)
+	_, err = os.Stat(filepath.Join(dir, "other.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = fm.Move(filepath.Join(dir, "missing"), backup)
+	assert.Error(t, err)
+	_, err = fm.Move(moved, filepath.Join(dir, "no-such-dir", "x"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,56 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 用户名和组名的解析结果缓存，列目录时同一所有者会重复出现，避免每个文件都读取一次 /etc/passwd
+var (
+	ownerNameCache sync.Map // uid -> 用户名
+	groupNameCache sync.Map // gid -> 组名
+)
+
+// cachedName 从缓存中取名称，未命中时调用 lookup 解析，解析失败时使用数字ID
+func cachedName(cache *sync.Map, id uint32, lookup func(string) (string, error)) string {
+	if name, ok := cache.Load(id); ok {
+		return name.(string)
+	}
+	idStr := strconv.FormatUint(uint64(id), 10)
+	name, err := lookup(idStr)
+	if err != nil || name == "" {
+		name = idStr
+	}
+	cache.Store(id, name)
+	return name
+}
+
+// newFileInfo 由 os.FileInfo 构造文件信息，并在支持的平台上附带所有者和所属组
+func newFileInfo(fi os.FileInfo) *FileInfo {
+	info := &FileInfo{
+		Name:    fi.Name(),
+		Size:    fi.Size(),
+		ModTime: fi.ModTime().Format(time.RFC3339),
+		IsDir:   fi.IsDir(),
+		Mode:    fi.Mode().String(),
+	}
+	fillFileOwner(info, fi)
+	return info
+}
+
+// StatFile 获取单个文件的信息（不跟随符号链接），用于编辑文件前查看权限和所有者
+func (fm *FileManager) StatFile(path string) (*FileInfo, error) {
+	path, err := normalizeHostPath(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	return newFileInfo(fi), nil
+}
@@ -0,0 +1,40 @@
+//go:build !monitor_only && !windows
+
+package server
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func TestListFilesReportsOwner(t *testing.T) {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	fm := NewFileManager(log)
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.conf"), []byte("x"), 0644))
+
+	files, err := fm.ListFiles(dir)
+	assert.NoError(t, err)
+	if assert.Len(t, files, 1) && assert.NotNil(t, files[0].UID) {
+		assert.Equal(t, uint32(os.Getuid()), *files[0].UID)
+		assert.Equal(t, uint32(os.Getgid()), *files[0].GID)
+		want := strconv.Itoa(os.Getuid())
+		if u, err := user.Current(); err == nil {
+			want = u.Username
+		}
+		assert.Equal(t, want, files[0].Owner)
+		assert.NotEmpty(t, files[0].Group)
+	}
+
+	info, err := fm.StatFile(filepath.Join(dir, "a.conf"))
+	assert.NoError(t, err)
+	assert.Equal(t, files[0].Owner, info.Owner)
+}
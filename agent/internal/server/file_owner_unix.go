@@ -0,0 +1,34 @@
+//go:build !monitor_only && !windows
+
+package server
+
+import (
+	"os"
+	"os/user"
+	"syscall"
+)
+
+// fillFileOwner 从 stat 结果中读取 UID/GID 并解析为用户名和组名
+func fillFileOwner(info *FileInfo, fi os.FileInfo) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	uid, gid := uint32(st.Uid), uint32(st.Gid)
+	info.UID = &uid
+	info.GID = &gid
+	info.Owner = cachedName(&ownerNameCache, uid, func(id string) (string, error) {
+		u, err := user.LookupId(id)
+		if err != nil {
+			return "", err
+		}
+		return u.Username, nil
+	})
+	info.Group = cachedName(&groupNameCache, gid, func(id string) (string, error) {
+		g, err := user.LookupGroupId(id)
+		if err != nil {
+			return "", err
+		}
+		return g.Name, nil
+	})
+}
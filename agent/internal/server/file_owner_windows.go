@@ -0,0 +1,8 @@
+//go:build !monitor_only && windows
+
+package server
+
+import "os"
+
+// fillFileOwner Windows 上的文件所有者由 ACL 描述，没有 UID/GID，不填写
+func fillFileOwner(info *FileInfo, fi os.FileInfo) {}
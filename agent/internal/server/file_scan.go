@@ -0,0 +1,301 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// 扫描的默认/最长运行时间，超时后返回已收集的部分结果
+	defaultFileScanTimeout = time.Minute
+	maxFileScanTimeout     = 10 * time.Minute
+	// 进度事件的发送间隔
+	fileScanProgressInterval = time.Second
+	// 搜索结果数量的默认值和上限
+	defaultFileSearchResults = 200
+	maxFileSearchResults     = 5000
+	// 磁盘占用只返回最大的若干项
+	diskUsageTopEntries = 50
+)
+
+// fileScanRequest 文件搜索/磁盘占用扫描请求，search 的条件与一次性的 file_search 相同
+type fileScanRequest struct {
+	Action string `json:"action"` // start 或 cancel
+	ScanID string `json:"scan_id"`
+	Kind   string `json:"kind"` // search 或 disk_usage
+	fileSearchOptions
+}
+
+// fileScanStats 扫描过程中的统计信息
+type fileScanStats struct {
+	start       time.Time
+	files       int64
+	dirs        int64
+	bytes       int64
+	errors      int64
+	currentPath string
+}
+
+func (s *fileScanStats) snapshot() map[string]interface{} {
+	return map[string]interface{}{
+		"files_scanned": s.files,
+		"dirs_scanned":  s.dirs,
+		"bytes_scanned": s.bytes,
+		"errors":        s.errors,
+		"current_path":  s.currentPath,
+		"elapsed_ms":    time.Since(s.start).Milliseconds(),
+	}
+}
+
+// diskUsageEntry 根目录下一级条目的占用
+type diskUsageEntry struct {
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	Files int64  `json:"files"`
+	IsDir bool   `json:"is_dir"`
+}
+
+// fileSearchMatch 搜索命中的文件
+type fileSearchMatch struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	IsDir   bool      `json:"is_dir"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// handleFileScan 处理文件搜索/磁盘占用扫描。
+// 扫描期间定期发送 file_scan_progress，结束时发送 file_scan_result；
+// 超时或被取消时返回已收集的部分结果并标记 truncated。
+func (c *Client) handleFileScan(message []byte) {
+	var msg struct {
+		Payload fileScanRequest `json:"payload"`
+	}
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.log.Error("解析文件扫描请求失败: %v", err)
+		return
+	}
+	req := msg.Payload
+	if req.ScanID == "" {
+		c.log.Warn("文件扫描请求缺少 scan_id")
+		return
+	}
+
+	switch req.Action {
+	case "cancel":
+		if cancel, ok := c.fileScans.Load(req.ScanID); ok {
+			cancel.(context.CancelFunc)()
+			c.log.Info("已取消文件扫描: %s", req.ScanID)
+		}
+		return
+	case "", "start":
+	default:
+		c.sendFileScanMessage(req.ScanID, "file_scan_result", map[string]interface{}{
+			"error": "未知的扫描操作: " + req.Action,
+		})
+		return
+	}
+
+	if req.Kind != "search" && req.Kind != "disk_usage" {
+		c.sendFileScanMessage(req.ScanID, "file_scan_result", map[string]interface{}{
+			"error": "未知的扫描类型: " + req.Kind,
+		})
+		return
+	}
+	opts := req.fileSearchOptions
+	var err error
+	if req.Kind == "search" {
+		err = opts.normalize()
+	} else {
+		opts.Path, err = normalizeHostPath(opts.Path)
+	}
+	if err != nil {
+		c.sendFileScanMessage(req.ScanID, "file_scan_result", map[string]interface{}{"error": err.Error()})
+		return
+	}
+	root := opts.Path
+
+	timeout := time.Duration(req.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultFileScanTimeout
+	}
+	if timeout > maxFileScanTimeout {
+		timeout = maxFileScanTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if _, loaded := c.fileScans.LoadOrStore(req.ScanID, cancel); loaded {
+		c.log.Warn("文件扫描 %s 已存在，忽略重复请求", req.ScanID)
+		return
+	}
+	defer c.fileScans.Delete(req.ScanID)
+
+	stats := &fileScanStats{start: time.Now()}
+	progressDone := make(chan struct{})
+	progressStopped := make(chan struct{})
+	go func() {
+		defer close(progressStopped)
+		ticker := time.NewTicker(fileScanProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-progressDone:
+				return
+			case <-ticker.C:
+				c.sendFileScanMessage(req.ScanID, "file_scan_progress", c.fileScanProgress(stats))
+			}
+		}
+	}()
+
+	c.log.Info("开始文件扫描: id=%s, kind=%s, path=%s", req.ScanID, req.Kind, root)
+
+	result := map[string]interface{}{
+		"kind": req.Kind,
+		"path": root,
+	}
+	var limitReached bool
+	if req.Kind == "search" {
+		search := c.runFileSearch(ctx, &opts, stats)
+		result["mode"] = search.Mode
+		result["matches"] = search.Matches
+		result["skipped"] = search.Skipped
+		limitReached = search.Reason == "limit"
+	} else {
+		entries, total := c.runDiskUsage(ctx, root, stats)
+		result["entries"] = entries
+		result["total_size"] = total
+	}
+
+	close(progressDone)
+	<-progressStopped
+
+	c.fileScanLock.Lock()
+	for k, v := range stats.snapshot() {
+		result[k] = v
+	}
+	c.fileScanLock.Unlock()
+
+	result["truncated"] = false
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result["truncated"] = true
+		result["reason"] = "timeout"
+	case errors.Is(ctx.Err(), context.Canceled):
+		result["truncated"] = true
+		result["reason"] = "cancelled"
+	case limitReached:
+		result["truncated"] = true
+		result["reason"] = "limit"
+	}
+
+	c.sendFileScanMessage(req.ScanID, "file_scan_result", result)
+	c.log.Info("文件扫描结束: id=%s, truncated=%v", req.ScanID, result["truncated"])
+}
+
+// fileScanProgress 在锁内读取统计信息，避免与遍历协程竞争
+func (c *Client) fileScanProgress(stats *fileScanStats) map[string]interface{} {
+	c.fileScanLock.Lock()
+	defer c.fileScanLock.Unlock()
+	return stats.snapshot()
+}
+
+// walkForScan 遍历目录并更新统计信息，ctx 结束时停止遍历。
+// visit 返回 filepath.SkipDir 跳过当前目录，返回 filepath.SkipAll 结束遍历
+func (c *Client) walkForScan(ctx context.Context, root string, stats *fileScanStats, visit func(path string, d fs.DirEntry, info fs.FileInfo) error) {
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			c.fileScanLock.Lock()
+			stats.errors++
+			c.fileScanLock.Unlock()
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			c.fileScanLock.Lock()
+			stats.errors++
+			c.fileScanLock.Unlock()
+			return nil
+		}
+
+		c.fileScanLock.Lock()
+		stats.currentPath = path
+		if d.IsDir() {
+			stats.dirs++
+		} else {
+			stats.files++
+			stats.bytes += info.Size()
+		}
+		c.fileScanLock.Unlock()
+
+		return visit(path, d, info)
+	})
+}
+
+// runDiskUsage 统计根目录下每个一级条目的占用，按大小降序返回前若干项
+func (c *Client) runDiskUsage(ctx context.Context, root string, stats *fileScanStats) ([]diskUsageEntry, int64) {
+	entries := make(map[string]*diskUsageEntry)
+	var total int64
+
+	c.walkForScan(ctx, root, stats, func(path string, d fs.DirEntry, info fs.FileInfo) error {
+		if path == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		top := strings.SplitN(filepath.ToSlash(rel), "/", 2)[0]
+		entry, ok := entries[top]
+		if !ok {
+			entry = &diskUsageEntry{Name: top, Path: filepath.Join(root, top)}
+			entries[top] = entry
+		}
+		if path == entry.Path {
+			entry.IsDir = d.IsDir()
+		}
+		if !d.IsDir() {
+			entry.Size += info.Size()
+			entry.Files++
+			total += info.Size()
+		}
+		return nil
+	})
+
+	result := make([]diskUsageEntry, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, *e)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Size > result[j].Size })
+	if len(result) > diskUsageTopEntries {
+		result = result[:diskUsageTopEntries]
+	}
+	return result, total
+}
+
+// sendFileScanMessage 发送扫描进度或结果，按 scan_id 关联到发起请求的用户
+func (c *Client) sendFileScanMessage(scanID, msgType string, data map[string]interface{}) {
+	msg := map[string]interface{}{
+		"type":    msgType,
+		"scan_id": scanID,
+		"data":    data,
+	}
+	if err := c.writeJSON(msg); err != nil {
+		c.log.Error("发送文件扫描消息失败: scanID=%s, type=%s, error=%v", scanID, msgType, err)
+	}
+}
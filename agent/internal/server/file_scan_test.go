@@ -0,0 +1,291 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+// fileScanMessage Agent 发送的扫描进度/结果
+type fileScanMessage struct {
+	Type   string                 `json:"type"`
+	ScanID string                 `json:"scan_id"`
+	Data   map[string]interface{} `json:"data"`
+}
+
+// newFileScanTestClient 创建连接到模拟面板的 Client，返回面板收到的扫描消息
+func newFileScanTestClient(t *testing.T) (*Client, <-chan fileScanMessage) {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+
+	received := make(chan fileScanMessage, 64)
+	upgrader := websocket.Upgrader{}
+	panel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var msg fileScanMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			received <- msg
+		}
+	}))
+	t.Cleanup(panel.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(panel.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("连接模拟面板失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &Client{log: log, wsConn: conn}, received
+}
+
+// nextFileScanMessage 读取下一条扫描消息
+func nextFileScanMessage(t *testing.T, received <-chan fileScanMessage) fileScanMessage {
+	t.Helper()
+	select {
+	case msg := <-received:
+		return msg
+	case <-time.After(10 * time.Second):
+		t.Fatal("未收到文件扫描消息")
+		return fileScanMessage{}
+	}
+}
+
+// fileScanRequestMessage 构造面板发给 Agent 的 file_scan 消息
+func fileScanRequestMessage(t *testing.T, payload map[string]interface{}) []byte {
+	data, err := json.Marshal(map[string]interface{}{"type": "file_scan", "payload": payload})
+	assert.NoError(t, err)
+	return data
+}
+
+// writeScanTree 在 root 下创建用于扫描的文件，sizes 为相对路径到文件大小的映射
+func writeScanTree(t *testing.T, root string, sizes map[string]int) {
+	for rel, size := range sizes {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, make([]byte, size), 0644))
+	}
+}
+
+func TestRunDiskUsage(t *testing.T) {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	c := &Client{log: log}
+
+	root := t.TempDir()
+	writeScanTree(t, root, map[string]int{
+		"logs/app.log":          3000,
+		"logs/old/app.log.1":    2000,
+		"cache/a.bin":           1000,
+		"cache/b.bin":           1500,
+		"big.iso":               4000,
+		"empty/.keep":           0,
+		"data/db/pages/page.db": 100,
+	})
+
+	stats := &fileScanStats{start: time.Now()}
+	entries, total := c.runDiskUsage(context.Background(), root, stats)
+
+	// 按一级条目汇总，按大小降序排列
+	var got []string
+	for _, e := range entries {
+		got = append(got, fmt.Sprintf("%s %d %d %v", e.Name, e.Size, e.Files, e.IsDir))
+		assert.Equal(t, filepath.Join(root, e.Name), e.Path)
+	}
+	assert.Equal(t, []string{
+		"logs 5000 2 true",
+		"big.iso 4000 1 false",
+		"cache 2500 2 true",
+		"data 100 1 true",
+		"empty 0 1 true",
+	}, got)
+	assert.Equal(t, int64(11600), total)
+
+	// 统计信息包含所有遍历过的文件和目录（含根目录）
+	assert.Equal(t, int64(7), stats.files)
+	assert.Equal(t, int64(8), stats.dirs)
+	assert.Equal(t, total, stats.bytes)
+	assert.Zero(t, stats.errors)
+
+	// 只返回最大的若干项，总占用仍包含全部条目
+	root = t.TempDir()
+	sizes := make(map[string]int)
+	for i := 0; i < diskUsageTopEntries+10; i++ {
+		sizes[fmt.Sprintf("f%03d", i)] = i + 1
+	}
+	writeScanTree(t, root, sizes)
+	entries, total = c.runDiskUsage(context.Background(), root, &fileScanStats{start: time.Now()})
+	if assert.Len(t, entries, diskUsageTopEntries) {
+		assert.Equal(t, "f059", entries[0].Name)
+		assert.Equal(t, "f010", entries[len(entries)-1].Name)
+	}
+	n := int64(diskUsageTopEntries + 10)
+	assert.Equal(t, n*(n+1)/2, total)
+
+	// ctx 已结束时不遍历
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	entries, total = c.runDiskUsage(ctx, root, &fileScanStats{start: time.Now()})
+	assert.Empty(t, entries)
+	assert.Zero(t, total)
+}
+
+func TestHandleFileScanResult(t *testing.T) {
+	c, received := newFileScanTestClient(t)
+	root := t.TempDir()
+	writeScanTree(t, root, map[string]int{"a/one.conf": 10, "a/two.conf": 20, "b/three.txt": 30})
+
+	// 磁盘占用：扫描完成时未截断，结果中带最终统计
+	c.handleFileScan(fileScanRequestMessage(t, map[string]interface{}{
+		"scan_id": "du", "kind": "disk_usage", "path": root,
+	}))
+	msg := nextFileScanMessage(t, received)
+	assert.Equal(t, "file_scan_result", msg.Type)
+	assert.Equal(t, "du", msg.ScanID)
+	assert.Equal(t, false, msg.Data["truncated"])
+	assert.NotContains(t, msg.Data, "reason")
+	assert.Equal(t, float64(60), msg.Data["total_size"])
+	assert.Equal(t, float64(3), msg.Data["files_scanned"])
+	assert.Equal(t, float64(3), msg.Data["dirs_scanned"])
+	assert.Equal(t, float64(60), msg.Data["bytes_scanned"])
+	assert.Len(t, msg.Data["entries"], 2)
+
+	// 搜索达到结果数量上限时标记截断，原因是 limit
+	c.handleFileScan(fileScanRequestMessage(t, map[string]interface{}{
+		"scan_id": "search", "kind": "search", "path": root, "pattern": "*.conf", "max_results": 1,
+	}))
+	msg = nextFileScanMessage(t, received)
+	assert.Equal(t, "glob", msg.Data["mode"])
+	assert.Len(t, msg.Data["matches"], 1)
+	assert.Equal(t, true, msg.Data["truncated"])
+	assert.Equal(t, "limit", msg.Data["reason"])
+
+	// 无效的请求直接返回错误
+	for _, payload := range []map[string]interface{}{
+		{"scan_id": "bad-kind", "kind": "unknown", "path": root},
+		{"scan_id": "bad-action", "action": "pause", "kind": "search", "path": root},
+		{"scan_id": "bad-path", "kind": "disk_usage", "path": "relative/path"},
+	} {
+		c.handleFileScan(fileScanRequestMessage(t, payload))
+		msg = nextFileScanMessage(t, received)
+		assert.Equal(t, payload["scan_id"], msg.ScanID)
+		assert.NotEmpty(t, msg.Data["error"])
+	}
+
+	// 缺少 scan_id 时无法关联请求，不回复
+	c.handleFileScan(fileScanRequestMessage(t, map[string]interface{}{"kind": "disk_usage", "path": root}))
+	select {
+	case msg := <-received:
+		t.Fatalf("缺少 scan_id 时不应回复: %+v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestHandleFileScanTimeoutWithProgress(t *testing.T) {
+	c, received := newFileScanTestClient(t)
+	root := t.TempDir()
+	writeScanTree(t, root, map[string]int{"a/one": 10, "b/two": 20})
+
+	// 占住统计锁，让遍历停在第一个条目上，直到超过 1 秒的超时时间
+	c.fileScanLock.Lock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.handleFileScan(fileScanRequestMessage(t, map[string]interface{}{
+			"scan_id": "slow", "kind": "disk_usage", "path": root, "timeout": 1,
+		}))
+	}()
+	time.Sleep(fileScanProgressInterval + 200*time.Millisecond)
+	c.fileScanLock.Unlock()
+	<-done
+
+	// 结果之前至少有一次进度，进度中的统计不超过最终结果
+	var progress []fileScanMessage
+	msg := nextFileScanMessage(t, received)
+	for msg.Type == "file_scan_progress" {
+		progress = append(progress, msg)
+		msg = nextFileScanMessage(t, received)
+	}
+	if assert.NotEmpty(t, progress) {
+		for _, p := range progress {
+			assert.Equal(t, "slow", p.ScanID)
+			for _, key := range []string{"files_scanned", "dirs_scanned", "bytes_scanned", "errors", "current_path", "elapsed_ms"} {
+				assert.Contains(t, p.Data, key)
+			}
+			assert.LessOrEqual(t, p.Data["files_scanned"], msg.Data["files_scanned"])
+		}
+	}
+
+	// 超时后返回已收集的部分结果
+	assert.Equal(t, "file_scan_result", msg.Type)
+	assert.Equal(t, true, msg.Data["truncated"])
+	assert.Equal(t, "timeout", msg.Data["reason"])
+	assert.GreaterOrEqual(t, msg.Data["elapsed_ms"], float64(1000))
+	assert.Less(t, msg.Data["dirs_scanned"], float64(3))
+
+	// 扫描结束后不再保留取消函数
+	_, ok := c.fileScans.Load("slow")
+	assert.False(t, ok)
+}
+
+func TestHandleFileScanCancel(t *testing.T) {
+	c, received := newFileScanTestClient(t)
+	root := t.TempDir()
+	writeScanTree(t, root, map[string]int{"a/one": 10, "b/two": 20})
+
+	c.fileScanLock.Lock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.handleFileScan(fileScanRequestMessage(t, map[string]interface{}{
+			"scan_id": "cancel-me", "kind": "search", "path": root, "pattern": "one",
+		}))
+	}()
+	assert.Eventually(t, func() bool {
+		_, ok := c.fileScans.Load("cancel-me")
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// 同一 scan_id 的重复请求被忽略
+	c.handleFileScan(fileScanRequestMessage(t, map[string]interface{}{
+		"scan_id": "cancel-me", "kind": "search", "path": root, "pattern": "one",
+	}))
+
+	// 取消后遍历在下一个条目停止，返回带 cancelled 标记的部分结果
+	c.handleFileScan(fileScanRequestMessage(t, map[string]interface{}{"scan_id": "cancel-me", "action": "cancel"}))
+	c.fileScanLock.Unlock()
+	<-done
+
+	msg := nextFileScanMessage(t, received)
+	for msg.Type == "file_scan_progress" {
+		msg = nextFileScanMessage(t, received)
+	}
+	assert.Equal(t, "file_scan_result", msg.Type)
+	assert.Equal(t, true, msg.Data["truncated"])
+	assert.Equal(t, "cancelled", msg.Data["reason"])
+	assert.Empty(t, msg.Data["matches"])
+	assert.Equal(t, float64(1), msg.Data["dirs_scanned"])
+	assert.Empty(t, received)
+
+	// 取消不存在的扫描没有任何效果
+	c.handleFileScan(fileScanRequestMessage(t, map[string]interface{}{"scan_id": "missing", "action": "cancel"}))
+	assert.Empty(t, received)
+}
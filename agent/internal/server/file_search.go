@@ -0,0 +1,331 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// 搜索深度的默认值和上限，以搜索目录的直接子项为第 1 层
+	defaultFileSearchDepth = 10
+	maxFileSearchDepth     = 64
+	// 内容搜索时单个文件大小的默认值和上限，超过的文件不读取
+	defaultContentSearchFileSize = 1024 * 1024
+	maxContentSearchFileSize     = 10 * 1024 * 1024
+	// 单个文件最多返回的命中行数，以及每行保留的最大长度
+	maxContentMatchesPerFile = 5
+	maxContentMatchLineLen   = 200
+	// 搜索的默认/最长运行时间，需小于面板等待响应的超时，超时后返回已收集的部分结果
+	defaultFileSearchTimeout = 30 * time.Second
+	maxFileSearchTimeout     = 90 * time.Second
+	// 判断二进制文件时检查的前缀长度
+	binarySniffLen = 8000
+)
+
+// fileSearchOptions 文件搜索条件
+type fileSearchOptions struct {
+	Path          string `json:"path"`
+	Mode          string `json:"mode"`           // name（默认，文件名子串）、glob（文件名通配符）或 content（文件内容子串）
+	Pattern       string `json:"pattern"`        // 搜索条件
+	Include       string `json:"include"`        // content: 只搜索文件名匹配该通配符的文件，如 *.conf
+	CaseSensitive bool   `json:"case_sensitive"` // 是否区分大小写，默认不区分
+	MaxDepth      int    `json:"max_depth"`      // 最大深度，0 使用默认值
+	MaxFileSize   int64  `json:"max_file_size"`  // content: 单个文件大小上限（字节），0 使用默认值
+	MaxResults    int    `json:"max_results"`    // 结果数量上限，0 使用默认值
+	Timeout       int    `json:"timeout"`        // 最长运行时间（秒），0 使用默认值
+}
+
+// fileSearchLine 内容搜索命中的行
+type fileSearchLine struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// fileSearchHit 一个命中的文件，内容搜索时附带命中的行
+type fileSearchHit struct {
+	fileSearchMatch
+	Lines []fileSearchLine `json:"lines,omitempty"`
+}
+
+// fileSearchResult 文件搜索结果
+type fileSearchResult struct {
+	Path         string          `json:"path"`
+	Mode         string          `json:"mode"`
+	Matches      []fileSearchHit `json:"matches"`
+	FilesScanned int64           `json:"files_scanned"`
+	DirsScanned  int64           `json:"dirs_scanned"`
+	Skipped      int64           `json:"skipped"` // content: 因过大、为空或为二进制而未搜索内容的文件数
+	Errors       int64           `json:"errors"`
+	Truncated    bool            `json:"truncated"`
+	Reason       string          `json:"reason,omitempty"` // limit、timeout
+	ElapsedMs    int64           `json:"elapsed_ms"`
+}
+
+// normalize 校验搜索条件并补全默认值
+func (o *fileSearchOptions) normalize() error {
+	root, err := normalizeHostPath(o.Path)
+	if err != nil {
+		return err
+	}
+	o.Path = root
+	info, err := os.Stat(root)
+	if err != nil {
+		return fmt.Errorf("检查目录失败: %v", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("只能在目录中搜索")
+	}
+
+	o.Mode = strings.ToLower(strings.TrimSpace(o.Mode))
+	if o.Mode == "" {
+		// 未指定搜索方式时按条件中是否包含通配符判断，兼容旧的 file_scan 请求
+		o.Mode = "name"
+		if strings.ContainsAny(o.Pattern, "*?[") {
+			o.Mode = "glob"
+		}
+	}
+	if o.Mode != "name" && o.Mode != "glob" && o.Mode != "content" {
+		return fmt.Errorf("未知的搜索方式: %s", o.Mode)
+	}
+	if strings.TrimSpace(o.Pattern) == "" {
+		return fmt.Errorf("搜索条件不能为空")
+	}
+	if o.Mode != "content" {
+		o.Pattern = strings.TrimSpace(o.Pattern)
+	}
+	if !o.CaseSensitive {
+		o.Pattern = strings.ToLower(o.Pattern)
+		o.Include = strings.ToLower(o.Include)
+	}
+	if o.Mode == "glob" {
+		if _, err := filepath.Match(o.Pattern, ""); err != nil {
+			return fmt.Errorf("无效的通配符: %s", o.Pattern)
+		}
+	}
+	if o.Include != "" {
+		if _, err := filepath.Match(o.Include, ""); err != nil {
+			return fmt.Errorf("无效的通配符: %s", o.Include)
+		}
+	}
+
+	if o.MaxDepth <= 0 {
+		o.MaxDepth = defaultFileSearchDepth
+	}
+	o.MaxDepth = min(o.MaxDepth, maxFileSearchDepth)
+	if o.MaxFileSize <= 0 {
+		o.MaxFileSize = defaultContentSearchFileSize
+	}
+	o.MaxFileSize = min(o.MaxFileSize, maxContentSearchFileSize)
+	if o.MaxResults <= 0 {
+		o.MaxResults = defaultFileSearchResults
+	}
+	o.MaxResults = min(o.MaxResults, maxFileSearchResults)
+	return nil
+}
+
+// timeout 返回本次搜索的最长运行时间
+func (o *fileSearchOptions) timeout() time.Duration {
+	timeout := time.Duration(o.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultFileSearchTimeout
+	}
+	return min(timeout, maxFileSearchTimeout)
+}
+
+// matchName 判断文件名是否满足 name/glob 搜索条件
+func (o *fileSearchOptions) matchName(name string) bool {
+	if !o.CaseSensitive {
+		name = strings.ToLower(name)
+	}
+	if o.Mode == "glob" {
+		hit, _ := filepath.Match(o.Pattern, name)
+		return hit
+	}
+	return strings.Contains(name, o.Pattern)
+}
+
+// SearchFiles 在目录下按文件名（子串或通配符）或文件内容搜索，一次性返回结果。
+// ctx 结束或结果达到上限时返回已收集的部分结果并标记 truncated
+func (c *Client) SearchFiles(ctx context.Context, opts fileSearchOptions) (*fileSearchResult, error) {
+	if err := opts.normalize(); err != nil {
+		return nil, err
+	}
+	return c.runFileSearch(ctx, &opts, &fileScanStats{start: time.Now()}), nil
+}
+
+// runFileSearch 在 walkForScan 上执行搜索，文件数、目录数和错误数记录在 stats 中供进度事件读取。
+// 不跟随符号链接；ctx 结束或结果达到上限时返回已收集的部分结果并标记 truncated
+func (c *Client) runFileSearch(ctx context.Context, opts *fileSearchOptions, stats *fileScanStats) *fileSearchResult {
+	result := &fileSearchResult{Path: opts.Path, Mode: opts.Mode, Matches: make([]fileSearchHit, 0)}
+
+	c.walkForScan(ctx, opts.Path, stats, func(path string, d fs.DirEntry, info fs.FileInfo) error {
+		if path == opts.Path {
+			return nil
+		}
+		hit, err := opts.searchEntry(path, d, info, result)
+		if err != nil {
+			c.fileScanLock.Lock()
+			stats.errors++
+			c.fileScanLock.Unlock()
+		}
+		if hit != nil {
+			result.Matches = append(result.Matches, *hit)
+			if len(result.Matches) >= opts.MaxResults {
+				result.Truncated = true
+				result.Reason = "limit"
+				return filepath.SkipAll
+			}
+		}
+		if d.IsDir() && opts.depth(path) >= opts.MaxDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if !result.Truncated && ctx.Err() != nil {
+		result.Truncated = true
+		result.Reason = "timeout"
+	}
+
+	c.fileScanLock.Lock()
+	result.FilesScanned = stats.files
+	result.DirsScanned = stats.dirs
+	result.Errors = stats.errors
+	c.fileScanLock.Unlock()
+	result.ElapsedMs = time.Since(stats.start).Milliseconds()
+	return result
+}
+
+// depth 返回条目相对搜索目录的深度，搜索目录的直接子项为第 1 层
+func (o *fileSearchOptions) depth(path string) int {
+	depth := strings.Count(strings.TrimPrefix(path, o.Path), string(filepath.Separator))
+	if strings.HasSuffix(o.Path, string(filepath.Separator)) {
+		depth++
+	}
+	return depth
+}
+
+// searchEntry 检查单个条目是否命中，未命中返回 nil
+func (o *fileSearchOptions) searchEntry(path string, d fs.DirEntry, info fs.FileInfo, result *fileSearchResult) (*fileSearchHit, error) {
+	if o.Mode != "content" {
+		if !o.matchName(d.Name()) {
+			return nil, nil
+		}
+		return &fileSearchHit{fileSearchMatch: fileSearchMatch{
+			Path: path, Size: info.Size(), IsDir: d.IsDir(), ModTime: info.ModTime(),
+		}}, nil
+	}
+
+	if !d.Type().IsRegular() {
+		return nil, nil
+	}
+	if o.Include != "" {
+		name := d.Name()
+		if !o.CaseSensitive {
+			name = strings.ToLower(name)
+		}
+		if hit, _ := filepath.Match(o.Include, name); !hit {
+			return nil, nil
+		}
+	}
+	// /proc、/sys 下的伪文件大小为 0，读取可能阻塞，与空文件一起跳过
+	if info.Size() == 0 || info.Size() > o.MaxFileSize {
+		result.Skipped++
+		return nil, nil
+	}
+
+	lines, err := grepFile(path, o.Pattern, o.CaseSensitive)
+	if err != nil {
+		if errors.Is(err, errBinaryFile) {
+			result.Skipped++
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	return &fileSearchHit{
+		fileSearchMatch: fileSearchMatch{Path: path, Size: info.Size(), ModTime: info.ModTime()},
+		Lines:           lines,
+	}, nil
+}
+
+var errBinaryFile = errors.New("二进制文件")
+
+// grepFile 返回文件中包含 pattern 的行（最多 maxContentMatchesPerFile 行），二进制文件返回 errBinaryFile
+func grepFile(path, pattern string, caseSensitive bool) ([]fileSearchLine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte(data[:min(len(data), binarySniffLen)], 0) >= 0 {
+		return nil, errBinaryFile
+	}
+
+	haystack := data
+	if !caseSensitive {
+		haystack = bytes.ToLower(data)
+	}
+	needle := []byte(pattern)
+	if !bytes.Contains(haystack, needle) {
+		return nil, nil
+	}
+
+	// ToLower 可能改变多字节字符的长度，命中行的原文按行号从原始内容中取出
+	original := bytes.Split(data, []byte("\n"))
+	var lines []fileSearchLine
+	for lineNo, line := range bytes.Split(haystack, []byte("\n")) {
+		if !bytes.Contains(line, needle) {
+			continue
+		}
+		text := strings.TrimRight(string(original[lineNo]), "\r")
+		if len(text) > maxContentMatchLineLen {
+			text = strings.ToValidUTF8(text[:maxContentMatchLineLen], "") + "..."
+		}
+		lines = append(lines, fileSearchLine{Line: lineNo + 1, Text: text})
+		if len(lines) >= maxContentMatchesPerFile {
+			break
+		}
+	}
+	return lines, nil
+}
+
+// handleFileSearch 处理文件搜索请求，一次性返回结果
+func (c *Client) handleFileSearch(message []byte) {
+	var req struct {
+		RequestID string            `json:"request_id"`
+		Payload   fileSearchOptions `json:"payload"`
+	}
+	if err := json.Unmarshal(message, &req); err != nil {
+		c.log.Error("解析文件搜索请求失败: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), req.Payload.timeout())
+	defer cancel()
+
+	c.log.Info("开始文件搜索: path=%s, mode=%s, pattern=%s", req.Payload.Path, req.Payload.Mode, req.Payload.Pattern)
+	result, err := c.SearchFiles(ctx, req.Payload)
+	if err != nil {
+		c.log.Warn("文件搜索失败: path=%s, error=%v", req.Payload.Path, err)
+		c.sendResponse(req.RequestID, "file_search_response", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.log.Info("文件搜索结束: path=%s, matches=%d, truncated=%v", result.Path, len(result.Matches), result.Truncated)
+	c.sendTransferResponse(req.RequestID, "file_search_response", map[string]interface{}{
+		"result": result,
+	})
+}
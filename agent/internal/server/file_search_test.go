@@ -0,0 +1,99 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func TestSearchFiles(t *testing.T) {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	c := &Client{log: log}
+
+	root := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "etc", "nginx", "conf.d"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "etc", "nginx", "nginx.conf"), []byte("user www;\nlisten 80;\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "etc", "nginx", "conf.d", "site.conf"), []byte("server {\n  Listen 443 ssl;\n}\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "etc", "notes.txt"), []byte("listen here"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "etc", "blob.conf"), []byte("listen\x00\x01"), 0644))
+
+	search := func(opts fileSearchOptions) *fileSearchResult {
+		opts.Path = root
+		result, err := c.SearchFiles(context.Background(), opts)
+		assert.NoError(t, err)
+		return result
+	}
+	paths := func(result *fileSearchResult) []string {
+		var out []string
+		for _, m := range result.Matches {
+			rel, _ := filepath.Rel(root, m.Path)
+			out = append(out, filepath.ToSlash(rel))
+		}
+		return out
+	}
+
+	// 文件名子串，默认不区分大小写
+	result := search(fileSearchOptions{Pattern: "NGINX"})
+	assert.ElementsMatch(t, []string{"etc/nginx", "etc/nginx/nginx.conf"}, paths(result))
+
+	// 未指定搜索方式时条件包含通配符按通配符匹配
+	result = search(fileSearchOptions{Pattern: "*.CONF"})
+	assert.Equal(t, "glob", result.Mode)
+	assert.ElementsMatch(t, []string{"etc/blob.conf", "etc/nginx/nginx.conf", "etc/nginx/conf.d/site.conf"}, paths(result))
+
+	// 通配符并限制深度：site.conf 位于第 4 层
+	result = search(fileSearchOptions{Mode: "glob", Pattern: "*.conf"})
+	assert.ElementsMatch(t, []string{"etc/blob.conf", "etc/nginx/nginx.conf", "etc/nginx/conf.d/site.conf"}, paths(result))
+	result = search(fileSearchOptions{Mode: "glob", Pattern: "*.conf", MaxDepth: 3})
+	assert.ElementsMatch(t, []string{"etc/blob.conf", "etc/nginx/nginx.conf"}, paths(result))
+
+	// 内容搜索跳过二进制文件，返回命中的行号和原文
+	result = search(fileSearchOptions{Mode: "content", Pattern: "listen", Include: "*.conf"})
+	assert.ElementsMatch(t, []string{"etc/nginx/nginx.conf", "etc/nginx/conf.d/site.conf"}, paths(result))
+	assert.Equal(t, int64(1), result.Skipped)
+	for _, m := range result.Matches {
+		if filepath.Base(m.Path) == "site.conf" {
+			assert.Equal(t, []fileSearchLine{{Line: 2, Text: "  Listen 443 ssl;"}}, m.Lines)
+		}
+	}
+	result = search(fileSearchOptions{Mode: "content", Pattern: "Listen", CaseSensitive: true})
+	assert.Equal(t, []string{"etc/nginx/conf.d/site.conf"}, paths(result))
+
+	// 超过大小上限的文件不读取
+	result = search(fileSearchOptions{Mode: "content", Pattern: "listen", MaxFileSize: 5})
+	assert.Empty(t, result.Matches)
+
+	// 结果数量上限
+	result = search(fileSearchOptions{Mode: "glob", Pattern: "*", MaxResults: 2})
+	assert.Len(t, result.Matches, 2)
+	assert.True(t, result.Truncated)
+	assert.Equal(t, "limit", result.Reason)
+}
+
+func TestSearchFilesInvalid(t *testing.T) {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	c := &Client{log: log}
+	root := t.TempDir()
+	file := filepath.Join(root, "a.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("a"), 0644))
+
+	cases := []fileSearchOptions{
+		{Path: root, Pattern: "  "},
+		{Path: root, Mode: "regex", Pattern: "a"},
+		{Path: root, Mode: "glob", Pattern: "[a"},
+		{Path: file, Pattern: "a"},
+		{Path: "relative", Pattern: "a"},
+	}
+	for _, opts := range cases {
+		_, err := c.SearchFiles(context.Background(), opts)
+		assert.Error(t, err, opts)
+	}
+}
@@ -0,0 +1,202 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// 快照记录的条目数默认值和上限，超过后停止遍历并标记 truncated
+	defaultSnapshotEntries = 20000
+	maxSnapshotEntries     = 100000
+	// 计算哈希的单个文件大小默认值和上限，更大的文件只记录大小和修改时间
+	defaultSnapshotHashFileSize = 1024 * 1024
+	maxSnapshotHashFileSize     = 16 * 1024 * 1024
+	// 单次快照计算哈希读取的总字节数上限，超过后剩余文件不再计算哈希
+	maxSnapshotHashBytes = 256 * 1024 * 1024
+	// 单次快照的最长运行时间
+	fileSnapshotTimeout = 2 * time.Minute
+)
+
+// fileSnapshotEntry 快照中的单个文件或目录，Path 为相对于快照根目录的路径（使用 / 分隔）
+type fileSnapshotEntry struct {
+	Path    string `json:"path"`
+	IsDir   bool   `json:"is_dir,omitempty"`
+	Size    int64  `json:"size"`
+	Mode    string `json:"mode"`
+	ModTime int64  `json:"mod_time"`
+	Link    string `json:"link,omitempty"`   // 符号链接的目标，不跟随链接遍历
+	SHA256  string `json:"sha256,omitempty"` // 仅在请求计算哈希且文件未超过大小上限时填写
+}
+
+// fileSnapshotOptions 快照的范围和开销限制
+type fileSnapshotOptions struct {
+	Hash         bool  `json:"hash"`
+	MaxEntries   int   `json:"max_entries"`
+	HashFileSize int64 `json:"hash_max_size"`
+}
+
+// fileSnapshot 目录树的元数据快照
+type fileSnapshot struct {
+	Path      string              `json:"path"`
+	Hashed    bool                `json:"hashed"`
+	Entries   []fileSnapshotEntry `json:"entries"`
+	Errors    int                 `json:"errors"`
+	Truncated bool                `json:"truncated"`
+	Reason    string              `json:"reason,omitempty"` // limit 或 timeout
+	ElapsedMs int64               `json:"elapsed_ms"`
+}
+
+func (o *fileSnapshotOptions) normalize() {
+	if o.MaxEntries <= 0 {
+		o.MaxEntries = defaultSnapshotEntries
+	}
+	if o.MaxEntries > maxSnapshotEntries {
+		o.MaxEntries = maxSnapshotEntries
+	}
+	if o.HashFileSize <= 0 {
+		o.HashFileSize = defaultSnapshotHashFileSize
+	}
+	if o.HashFileSize > maxSnapshotHashFileSize {
+		o.HashFileSize = maxSnapshotHashFileSize
+	}
+}
+
+// handleFileSnapshot 记录目录树的元数据快照，由面板保存并与之前的快照对比，
+// 用于发现 /etc、网站目录等敏感目录中的意外变更
+func (c *Client) handleFileSnapshot(message []byte) {
+	var req struct {
+		RequestID string `json:"request_id"`
+		Payload   struct {
+			Path string `json:"path"`
+			fileSnapshotOptions
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(message, &req); err != nil {
+		c.log.Error("解析文件快照请求失败: %v", err)
+		return
+	}
+
+	root, err := normalizeHostPath(req.Payload.Path)
+	if err != nil {
+		c.sendResponse(req.RequestID, "file_snapshot_response", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fileSnapshotTimeout)
+	defer cancel()
+	snapshot, err := collectFileSnapshot(ctx, root, req.Payload.fileSnapshotOptions)
+	if err != nil {
+		c.log.Warn("记录文件快照失败: path=%s, error=%v", root, err)
+		c.sendResponse(req.RequestID, "file_snapshot_response", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	c.log.Info("已记录文件快照: path=%s, 条目=%d, 哈希=%v, truncated=%v", root, len(snapshot.Entries), snapshot.Hashed, snapshot.Truncated)
+	c.sendTransferResponse(req.RequestID, "file_snapshot_response", map[string]interface{}{
+		"snapshot": snapshot,
+	})
+}
+
+// collectFileSnapshot 遍历 root 记录每个条目的元数据，不跟随符号链接。
+// 条目数、哈希的文件大小和读取总量都有上限，超时或达到条目上限时返回部分结果
+func collectFileSnapshot(ctx context.Context, root string, opts fileSnapshotOptions) (*fileSnapshot, error) {
+	info, err := os.Lstat(root)
+	if err != nil {
+		return nil, fmt.Errorf("无法访问目录: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s 不是目录", root)
+	}
+
+	opts.normalize()
+	start := time.Now()
+	snapshot := &fileSnapshot{Path: root, Hashed: opts.Hash, Entries: make([]fileSnapshotEntry, 0)}
+	var hashedBytes int64
+
+	errLimit := errors.New("limit")
+	walkErr := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			snapshot.Errors++
+			if d != nil && d.IsDir() && path != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if path == root {
+			return nil
+		}
+		if len(snapshot.Entries) >= opts.MaxEntries {
+			return errLimit
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			snapshot.Errors++
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		entry := fileSnapshotEntry{
+			Path:    filepath.ToSlash(rel),
+			IsDir:   d.IsDir(),
+			Mode:    info.Mode().String(),
+			ModTime: info.ModTime().Unix(),
+		}
+		switch {
+		case d.IsDir():
+		case info.Mode()&fs.ModeSymlink != 0:
+			entry.Link, _ = os.Readlink(path)
+		default:
+			entry.Size = info.Size()
+			if opts.Hash && info.Mode().IsRegular() && info.Size() <= opts.HashFileSize && hashedBytes+info.Size() <= maxSnapshotHashBytes {
+				if sum, err := hashFile(path); err == nil {
+					entry.SHA256 = sum
+					hashedBytes += info.Size()
+				} else {
+					snapshot.Errors++
+				}
+			}
+		}
+		snapshot.Entries = append(snapshot.Entries, entry)
+		return nil
+	})
+
+	switch {
+	case errors.Is(walkErr, errLimit):
+		snapshot.Truncated, snapshot.Reason = true, "limit"
+	case errors.Is(walkErr, context.DeadlineExceeded), errors.Is(walkErr, context.Canceled):
+		snapshot.Truncated, snapshot.Reason = true, "timeout"
+	}
+	snapshot.ElapsedMs = time.Since(start).Milliseconds()
+	return snapshot, nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,42 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectFileSnapshot(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "conf.d"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "conf.d", "site.conf"), []byte("server {}"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "large.bin"), make([]byte, 2048), 0644))
+
+	snapshot, err := collectFileSnapshot(context.Background(), root, fileSnapshotOptions{Hash: true, HashFileSize: 1024})
+	assert.NoError(t, err)
+	assert.False(t, snapshot.Truncated)
+
+	byPath := make(map[string]fileSnapshotEntry)
+	for _, e := range snapshot.Entries {
+		byPath[e.Path] = e
+	}
+	assert.True(t, byPath["conf.d"].IsDir)
+	assert.Equal(t, int64(9), byPath["conf.d/site.conf"].Size)
+	assert.NotEmpty(t, byPath["conf.d/site.conf"].SHA256)
+	// 超过哈希大小上限的文件只记录元数据
+	assert.Empty(t, byPath["large.bin"].SHA256)
+
+	snapshot, err = collectFileSnapshot(context.Background(), root, fileSnapshotOptions{MaxEntries: 1})
+	assert.NoError(t, err)
+	assert.True(t, snapshot.Truncated)
+	assert.Equal(t, "limit", snapshot.Reason)
+	assert.Len(t, snapshot.Entries, 1)
+
+	_, err = collectFileSnapshot(context.Background(), filepath.Join(root, "large.bin"), fileSnapshotOptions{})
+	assert.Error(t, err)
+}
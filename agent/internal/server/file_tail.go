@@ -0,0 +1,214 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/user/server-ops-agent/internal/monitor"
+)
+
+const (
+	// 开始跟踪时发送的末尾行数
+	defaultFileTailLines = 100
+	maxFileTailLines     = 5000
+	// 跟踪阶段每秒最多发送的行数，超出的行丢弃并计数
+	defaultFileTailRate = 200
+	maxFileTailRate     = 2000
+	// 检查文件新内容、截断和轮转的间隔
+	fileTailPollInterval = 500 * time.Millisecond
+)
+
+// handleFileTailStream 处理宿主机文件跟踪请求（start / stop），
+// start 先发送文件末尾若干行，follow 时继续推送新写入的行，支持日志轮转和截断
+func (c *Client) handleFileTailStream(message []byte) {
+	var msg struct {
+		Payload struct {
+			Action   string `json:"action"`
+			StreamID string `json:"stream_id"`
+			Path     string `json:"path"`
+			Lines    int    `json:"lines"`
+			Follow   *bool  `json:"follow"`   // 默认 true
+			MaxRate  int    `json:"max_rate"` // 行/秒
+			Include  string `json:"include"`  // 只发送匹配该正则的行
+			Exclude  string `json:"exclude"`  // 丢弃匹配该正则的行
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.log.Error("解析文件跟踪请求失败: %v", err)
+		return
+	}
+	p := msg.Payload
+	if p.StreamID == "" {
+		c.log.Error("文件跟踪缺少 stream_id")
+		return
+	}
+
+	switch p.Action {
+	case "start":
+		filter, err := newLogLineFilter(p.Include, p.Exclude)
+		if err != nil {
+			c.sendFileTailEnd(p.StreamID, err.Error())
+			return
+		}
+		follow := p.Follow == nil || *p.Follow
+		c.startFileTail(p.StreamID, p.Path, p.Lines, follow, p.MaxRate, filter)
+	case "stop":
+		if cancel, ok := c.fileTails.Load(p.StreamID); ok {
+			cancel.(context.CancelFunc)()
+		}
+	default:
+		c.log.Warn("未知的文件跟踪操作: %s", p.Action)
+	}
+}
+
+// startFileTail 打开文件并在后台推送内容
+func (c *Client) startFileTail(streamID, path string, lines int, follow bool, maxRate int, filter *logLineFilter) {
+	if lines <= 0 {
+		lines = defaultFileTailLines
+	}
+	lines = min(lines, maxFileTailLines)
+	if maxRate <= 0 {
+		maxRate = defaultFileTailRate
+	}
+	maxRate = min(maxRate, maxFileTailRate)
+
+	cleanPath, err := normalizeHostPath(path)
+	if err != nil {
+		c.sendFileTailEnd(streamID, err.Error())
+		return
+	}
+	follower, initial, err := monitor.OpenFileFollower(cleanPath, lines)
+	if err != nil {
+		c.sendFileTailEnd(streamID, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, loaded := c.fileTails.LoadOrStore(streamID, context.CancelFunc(cancel)); loaded {
+		cancel()
+		follower.Close()
+		c.log.Warn("文件跟踪 %s 已存在，忽略重复 start 请求", streamID)
+		return
+	}
+
+	c.log.Info("文件跟踪 %s 已启动: %s", streamID, cleanPath)
+	go func() {
+		defer c.fileTails.Delete(streamID)
+		defer cancel()
+		defer follower.Close()
+		c.streamFileTail(ctx, streamID, follower, initial, follow, newLineRateLimiter(maxRate), filter)
+	}()
+}
+
+// streamFileTail 发送末尾行后轮询新内容，按批发送：每次轮询或累积 200 行时发送一次
+func (c *Client) streamFileTail(ctx context.Context, streamID string, follower *monitor.FileFollower, initial []string,
+	follow bool, rate *lineRateLimiter, filter *logLineFilter) {
+	limiter := c.transferLimiter()
+	var batch []string
+	send := func() {
+		dropped := rate.takeDropped()
+		if len(batch) == 0 && dropped == 0 {
+			return
+		}
+		data := map[string]interface{}{"logs": ""}
+		if len(batch) > 0 {
+			data["logs"] = strings.Join(batch, "\n") + "\n"
+		}
+		if dropped > 0 {
+			data["dropped"] = dropped
+		}
+		if err := c.writeThrottled(map[string]interface{}{
+			"type":      "file_tail_stream_data",
+			"stream_id": streamID,
+			"data":      data,
+		}, limiter); err != nil {
+			c.log.Error("发送文件跟踪消息失败: streamID=%s, error=%v", streamID, err)
+		}
+		batch = batch[:0]
+	}
+
+	// 末尾行数量有限，不受速率限制
+	for _, line := range initial {
+		if filter.Match(line) {
+			batch = append(batch, line)
+		}
+		if len(batch) >= 200 {
+			send()
+		}
+	}
+	send()
+	if !follow {
+		c.sendFileTailEnd(streamID, "eof")
+		return
+	}
+
+	ticker := time.NewTicker(fileTailPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			c.log.Info("文件跟踪 %s 已停止", streamID)
+			return
+		case now := <-ticker.C:
+			lines, err := follower.Poll()
+			for _, line := range lines {
+				if !filter.Match(line) || !rate.allow(now) {
+					continue
+				}
+				batch = append(batch, line)
+				if len(batch) >= 200 {
+					send()
+				}
+			}
+			send()
+			if err != nil {
+				c.log.Error("读取文件失败 [%s]: %v", streamID, err)
+				c.sendFileTailEnd(streamID, fmt.Sprintf("读取文件失败: %v", err))
+				return
+			}
+		}
+	}
+}
+
+// sendFileTailEnd 推送文件跟踪结束消息
+func (c *Client) sendFileTailEnd(streamID, reason string) {
+	c.sendStreamMessage(streamID, "file_tail_stream_end", map[string]interface{}{"reason": reason})
+}
+
+// lineRateLimiter 按秒限制发送的行数，记录被丢弃的行数
+type lineRateLimiter struct {
+	rate        int
+	windowStart time.Time
+	count       int
+	dropped     int
+}
+
+func newLineRateLimiter(rate int) *lineRateLimiter {
+	return &lineRateLimiter{rate: rate}
+}
+
+// allow 判断当前秒内是否还能发送一行
+func (l *lineRateLimiter) allow(now time.Time) bool {
+	if now.Sub(l.windowStart) >= time.Second {
+		l.windowStart = now
+		l.count = 0
+	}
+	if l.count >= l.rate {
+		l.dropped++
+		return false
+	}
+	l.count++
+	return true
+}
+
+// takeDropped 返回并清零上次调用以来丢弃的行数
+func (l *lineRateLimiter) takeDropped() int {
+	dropped := l.dropped
+	l.dropped = 0
+	return dropped
+}
@@ -0,0 +1,22 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineRateLimiter(t *testing.T) {
+	l := newLineRateLimiter(2)
+	now := time.Now()
+	assert.True(t, l.allow(now))
+	assert.True(t, l.allow(now.Add(100*time.Millisecond)))
+	assert.False(t, l.allow(now.Add(200*time.Millisecond)))
+	assert.False(t, l.allow(now.Add(300*time.Millisecond)))
+	assert.Equal(t, 2, l.takeDropped())
+	assert.Zero(t, l.takeDropped())
+	assert.True(t, l.allow(now.Add(time.Second)))
+}
@@ -0,0 +1,47 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// FileChecksum 重新读取磁盘上的文件并计算 SHA-256，用于确认写入的内容与发送的一致
+func (fm *FileManager) FileChecksum(path string) (string, int64, error) {
+	path, err := normalizeHostPath(path)
+	if err != nil {
+		return "", 0, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", 0, err
+	}
+	sum, err := hashFile(path)
+	if err != nil {
+		return "", 0, err
+	}
+	return sum, info.Size(), nil
+}
+
+// uploadChecksum 计算 UploadFile 写入的目标文件的 SHA-256，文件名按上传时的规则清洗
+func (fm *FileManager) uploadChecksum(dir, filename string) (string, int64, error) {
+	safeName, err := sanitizeFileName(filename)
+	if err != nil {
+		return "", 0, err
+	}
+	return fm.FileChecksum(filepath.Join(dir, safeName))
+}
+
+// attachChecksum 在写入成功的响应中附加文件的 SHA-256 和大小。
+// 写入已经成功，计算哈希失败只通过 verify_error 告知面板，不改变操作结果
+func (c *Client) attachChecksum(resp map[string]interface{}, checksum func() (string, int64, error)) {
+	sum, size, err := checksum()
+	if err != nil {
+		c.log.Warn("计算文件哈希失败: %v", err)
+		resp["verify_error"] = err.Error()
+		return
+	}
+	resp["sha256"] = sum
+	resp["size"] = size
+}
@@ -0,0 +1,48 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func TestChunkedUploadCompleteVerify(t *testing.T) {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	m := NewChunkedUploadManager(log, ContainerFileRoots{})
+	dir := t.TempDir()
+
+	data := []byte("hello, chunked upload")
+	sum := sha256.Sum256(data)
+	want := hex.EncodeToString(sum[:])
+
+	assert.NoError(t, m.Init("u1", dir, "a.txt", int64(len(data)), int64(len(data)), 1, ""))
+	assert.NoError(t, m.SaveChunk("u1", 0, data, want, false))
+	got, err := m.Complete("u1", want, true)
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	fm := NewFileManager(log)
+	got, size, err := fm.FileChecksum(filepath.Join(dir, "a.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, int64(len(data)), size)
+
+	// 不要求校验时不回读文件
+	assert.NoError(t, m.Init("u2", dir, "b.txt", int64(len(data)), int64(len(data)), 1, ""))
+	assert.NoError(t, m.SaveChunk("u2", 0, data, want, false))
+	got, err = m.Complete("u2", "", false)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	_, err = os.Stat(filepath.Join(dir, "b.txt"))
+	assert.NoError(t, err)
+}
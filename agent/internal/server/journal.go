@@ -0,0 +1,200 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/user/server-ops-agent/internal/monitor"
+)
+
+const (
+	// 跟踪阶段每秒最多推送的条数，超出的丢弃并计数
+	journalFollowRate = 500
+	// 推送间隔：每 300ms 或累积 200 条时推送一批
+	journalFlushInterval = 300 * time.Millisecond
+	journalBatchSize     = 200
+)
+
+// handleJournalQuery 处理 systemd journal 查询请求（start / stop），
+// start 按 unit、优先级和时间范围查询，follow 时继续推送新日志，stop 终止进行中的查询
+func (c *Client) handleJournalQuery(message []byte) {
+	var msg struct {
+		Payload struct {
+			Action   string `json:"action"`
+			StreamID string `json:"stream_id"`
+			monitor.JournalQuery
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.log.Error("解析 journal 查询请求失败: %v", err)
+		return
+	}
+	p := msg.Payload
+	if p.StreamID == "" {
+		c.log.Error("journal 查询缺少 stream_id")
+		return
+	}
+
+	switch p.Action {
+	case "start":
+		c.startJournalQuery(p.StreamID, p.JournalQuery)
+	case "stop":
+		if cancel, ok := c.journalQueries.Load(p.StreamID); ok {
+			cancel.(context.CancelFunc)()
+		}
+	default:
+		c.log.Warn("未知的 journal 查询操作: %s", p.Action)
+	}
+}
+
+// startJournalQuery 执行 journalctl 并按批推送日志，结束时推送结束消息
+func (c *Client) startJournalQuery(streamID string, q monitor.JournalQuery) {
+	name, args, err := monitor.JournalCommand(q)
+	if err != nil {
+		c.sendJournalEnd(streamID, 0, err)
+		return
+	}
+
+	// 跟踪没有时间限制，直到收到 stop 请求
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if q.Follow {
+		ctx, cancel = context.WithCancel(context.Background())
+	} else {
+		ctx, cancel = context.WithTimeout(context.Background(), monitor.JournalQueryTimeout)
+	}
+	defer cancel()
+	if _, loaded := c.journalQueries.LoadOrStore(streamID, cancel); loaded {
+		c.log.Warn("journal 查询 %s 已存在，忽略重复 start 请求", streamID)
+		return
+	}
+	defer c.journalQueries.Delete(streamID)
+
+	c.log.Info("开始 journal 查询: %s %s [%s]", name, strings.Join(args, " "), streamID)
+	count, err := c.runJournalQuery(ctx, streamID, name, args, q.Follow, q.LineLimit())
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		err = nil
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		err = fmt.Errorf("查询超时（%s）", monitor.JournalQueryTimeout)
+	}
+	c.sendJournalEnd(streamID, count, err)
+}
+
+// runJournalQuery 执行命令并推送解析后的日志，返回推送的条数。
+// 跟踪时 journalctl 先输出最多 backlog 条已有日志，之后的新日志按速率限制推送
+func (c *Client) runJournalQuery(ctx context.Context, streamID, name string, args []string, follow bool, backlog int) (int, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("执行 %s 失败: %w", name, err)
+	}
+
+	// 读取在单独的 goroutine 中进行，跟踪时日志稀疏也能按间隔推送
+	entries := make(chan monitor.JournalEntry, journalBatchSize)
+	go func() {
+		defer close(entries)
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			entry, err := monitor.ParseJournalEntry(scanner.Bytes())
+			if err != nil {
+				continue
+			}
+			select {
+			case entries <- entry:
+			case <-ctx.Done():
+				return
+			}
+		}
+		// 超长的行会让 Scanner 提前停止，读完剩余输出后命令才能退出
+		io.Copy(io.Discard, stdout)
+	}()
+
+	limiter := c.transferLimiter()
+	rate := newLineRateLimiter(journalFollowRate)
+	var batch []monitor.JournalEntry
+	count := 0
+	send := func() {
+		dropped := rate.takeDropped()
+		if len(batch) == 0 && dropped == 0 {
+			return
+		}
+		data := map[string]interface{}{"entries": batch}
+		if dropped > 0 {
+			data["dropped"] = dropped
+		}
+		if err := c.writeThrottled(map[string]interface{}{
+			"type":      "journal_query_data",
+			"stream_id": streamID,
+			"data":      data,
+		}, limiter); err != nil {
+			c.log.Error("发送 journal 查询结果失败: streamID=%s, error=%v", streamID, err)
+		}
+		count += len(batch)
+		batch = nil
+	}
+
+	ticker := time.NewTicker(journalFlushInterval)
+	defer ticker.Stop()
+	received := 0
+	for done := false; !done; {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				done = true
+				break
+			}
+			received++
+			if follow && received > backlog && !rate.allow(time.Now()) {
+				continue
+			}
+			batch = append(batch, entry)
+			if len(batch) >= journalBatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		}
+	}
+	send()
+
+	err = cmd.Wait()
+	if ctx.Err() != nil {
+		return count, nil
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return count, fmt.Errorf("journalctl 执行失败: %s", msg)
+		}
+		return count, fmt.Errorf("journalctl 执行失败: %w", err)
+	}
+	return count, nil
+}
+
+// sendJournalEnd 推送 journal 查询结束消息
+func (c *Client) sendJournalEnd(streamID string, count int, err error) {
+	data := map[string]interface{}{
+		"success": err == nil,
+		"count":   count,
+	}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	c.sendStreamMessage(streamID, "journal_query_end", data)
+}
@@ -0,0 +1,63 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// 过滤正则的最大长度，避免超长表达式拖慢逐行匹配
+const maxLogFilterPatternLength = 512
+
+// logLineFilter 日志流的行过滤器：
+// include 非空时只保留匹配的行，exclude 非空时丢弃匹配的行，两者可同时使用
+type logLineFilter struct {
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// newLogLineFilter 编译 include/exclude 正则；两者都为空时返回 nil，表示不过滤
+func newLogLineFilter(include, exclude string) (*logLineFilter, error) {
+	if include == "" && exclude == "" {
+		return nil, nil
+	}
+
+	filter := &logLineFilter{}
+	var err error
+	if filter.include, err = compileLogFilterPattern("include", include); err != nil {
+		return nil, err
+	}
+	if filter.exclude, err = compileLogFilterPattern("exclude", exclude); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+func compileLogFilterPattern(name, pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	if len(pattern) > maxLogFilterPatternLength {
+		return nil, fmt.Errorf("%s 过滤正则过长（最多 %d 个字符）", name, maxLogFilterPatternLength)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 %s 过滤正则: %v", name, err)
+	}
+	return re, nil
+}
+
+// Match 判断日志行是否应当发送；nil 过滤器放行所有行
+func (f *logLineFilter) Match(line string) bool {
+	if f == nil {
+		return true
+	}
+	if f.include != nil && !f.include.MatchString(line) {
+		return false
+	}
+	if f.exclude != nil && f.exclude.MatchString(line) {
+		return false
+	}
+	return true
+}
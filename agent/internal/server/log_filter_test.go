@@ -0,0 +1,82 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogLineFilter(t *testing.T) {
+	lines := []string{
+		"2026-01-01T00:00:00Z INFO server started",
+		"2026-01-01T00:00:01Z DEBUG GET /healthz 200",
+		"2026-01-01T00:00:02Z WARN slow request GET /api/servers 1200ms",
+		"2026-01-01T00:00:03Z ERROR database is locked",
+		"2026-01-01T00:00:04Z error: connection reset by peer",
+		"",
+	}
+	tests := []struct {
+		name    string
+		include string
+		exclude string
+		want    []int // 保留的行在 lines 中的下标
+	}{
+		{"不过滤", "", "", []int{0, 1, 2, 3, 4, 5}},
+		{"只保留匹配的行", "GET /", "", []int{1, 2}},
+		{"丢弃匹配的行", "", "healthz", []int{0, 2, 3, 4, 5}},
+		{"同时使用时先包含再排除", "GET /", "healthz", []int{2}},
+		{"按级别过滤：只看警告和错误", `\b(WARN|ERROR)\b`, "", []int{2, 3}},
+		{"按级别过滤：区分大小写", `\bERROR\b`, "", []int{3}},
+		{"按级别过滤：忽略大小写", `(?i)\berror\b`, "", []int{3, 4}},
+		{"按级别过滤：去掉调试日志", "", `\bDEBUG\b`, []int{0, 2, 3, 4, 5}},
+		{"按级别过滤：错误中排除已知噪声", `(?i)\berror\b`, "connection reset", []int{3}},
+		{"正则可以匹配空行", "^$", "", []int{5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newLogLineFilter(tt.include, tt.exclude)
+			assert.NoError(t, err)
+			var got []int
+			for i, line := range lines {
+				if filter.Match(line) {
+					got = append(got, i)
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewLogLineFilter(t *testing.T) {
+	// 两者都为空时不创建过滤器
+	filter, err := newLogLineFilter("", "")
+	assert.NoError(t, err)
+	assert.Nil(t, filter)
+
+	tests := []struct {
+		name    string
+		include string
+		exclude string
+		errMsg  string
+	}{
+		{"无效的包含正则", "(", "", "无效的 include 过滤正则"},
+		{"无效的排除正则", "ok", "[a-", "无效的 exclude 过滤正则"},
+		{"包含正则过长", strings.Repeat("a", maxLogFilterPatternLength+1), "", "include 过滤正则过长"},
+		{"排除正则过长", "", strings.Repeat("a", maxLogFilterPatternLength+1), "exclude 过滤正则过长"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newLogLineFilter(tt.include, tt.exclude)
+			assert.ErrorContains(t, err, tt.errMsg)
+			assert.Nil(t, filter)
+		})
+	}
+
+	// 最大长度本身是允许的
+	filter, err = newLogLineFilter(strings.Repeat("a", maxLogFilterPatternLength), "")
+	assert.NoError(t, err)
+	assert.NotNil(t, filter)
+}
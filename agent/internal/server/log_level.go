@@ -0,0 +1,122 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+const (
+	// 临时日志级别的默认有效期，到期后恢复为配置文件中的级别
+	defaultLogLevelDuration = 30 * time.Minute
+	// 临时日志级别的最长有效期，避免调试日志长期开启
+	maxLogLevelDuration = 24 * time.Hour
+)
+
+// logLevelRequest 面板端查询/调整日志级别的请求
+type logLevelRequest struct {
+	Type      string `json:"type"`
+	RequestID string `json:"request_id"`
+	Payload   struct {
+		Action   string `json:"action"`   // get 或 set
+		Level    string `json:"level"`    // set 时的目标级别
+		Duration int    `json:"duration"` // 有效期（秒），0 表示使用默认值
+	} `json:"payload"`
+}
+
+// handleLogLevel 处理运行时日志级别的查询和调整，
+// 临时级别到期后自动恢复为配置的级别，无需重启 Agent
+func (c *Client) handleLogLevel(message []byte) {
+	var req logLevelRequest
+	if err := json.Unmarshal(message, &req); err != nil {
+		c.log.Error("解析日志级别请求失败: %v", err)
+		return
+	}
+
+	switch strings.ToLower(req.Payload.Action) {
+	case "", "get":
+	case "set":
+		level := strings.TrimSpace(req.Payload.Level)
+		if !logger.ValidLevel(level) {
+			c.sendResponse(req.RequestID, "agent_log_level_response", map[string]interface{}{
+				"error": "无效的日志级别: " + level,
+			})
+			return
+		}
+		duration := time.Duration(req.Payload.Duration) * time.Second
+		if duration <= 0 {
+			duration = defaultLogLevelDuration
+		}
+		if duration > maxLogLevelDuration {
+			duration = maxLogLevelDuration
+		}
+		c.setTemporaryLogLevel(logger.ParseLevel(level), duration)
+	case "reset":
+		c.resetLogLevel()
+	default:
+		c.sendResponse(req.RequestID, "agent_log_level_response", map[string]interface{}{
+			"error": "不支持的操作: " + req.Payload.Action,
+		})
+		return
+	}
+
+	c.sendResponse(req.RequestID, "agent_log_level_response", c.logLevelStatus())
+}
+
+// setTemporaryLogLevel 临时调整日志级别，duration 后恢复为配置的级别
+func (c *Client) setTemporaryLogLevel(level logger.Level, duration time.Duration) {
+	c.logLevelMu.Lock()
+	defer c.logLevelMu.Unlock()
+
+	if c.logLevelRevert != nil {
+		c.logLevelRevert.Stop()
+	}
+
+	configured := logger.ParseLevel(c.cfg.LogLevel)
+	c.log.Info("临时调整日志级别: %s -> %s，%s 后恢复", c.log.GetLevel(), level, duration)
+	c.log.SetLevel(level)
+
+	if level == configured {
+		c.logLevelRevert = nil
+		c.logLevelRevertAt = time.Time{}
+		return
+	}
+
+	c.logLevelRevertAt = time.Now().Add(duration)
+	c.logLevelRevert = time.AfterFunc(duration, c.resetLogLevel)
+}
+
+// resetLogLevel 恢复为配置文件中的日志级别
+func (c *Client) resetLogLevel() {
+	c.logLevelMu.Lock()
+	defer c.logLevelMu.Unlock()
+
+	if c.logLevelRevert != nil {
+		c.logLevelRevert.Stop()
+		c.logLevelRevert = nil
+	}
+	c.logLevelRevertAt = time.Time{}
+
+	configured := logger.ParseLevel(c.cfg.LogLevel)
+	if c.log.GetLevel() != configured {
+		c.log.SetLevel(configured)
+		c.log.Info("日志级别已恢复为配置值: %s", configured)
+	}
+}
+
+// logLevelStatus 返回当前日志级别状态
+func (c *Client) logLevelStatus() map[string]interface{} {
+	c.logLevelMu.Lock()
+	defer c.logLevelMu.Unlock()
+
+	status := map[string]interface{}{
+		"level":            c.log.GetLevel().String(),
+		"configured_level": logger.ParseLevel(c.cfg.LogLevel).String(),
+	}
+	if !c.logLevelRevertAt.IsZero() {
+		status["revert_at"] = c.logLevelRevertAt.UTC().Format(time.RFC3339)
+	}
+	return status
+}
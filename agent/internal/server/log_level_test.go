@@ -0,0 +1,93 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/config"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func newLogLevelTestClient(t *testing.T) *Client {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	c := &Client{cfg: &config.Config{LogLevel: "error"}, log: log}
+	t.Cleanup(c.resetLogLevel)
+	return c
+}
+
+func TestTemporaryLogLevelRevertsAfterTTL(t *testing.T) {
+	c := newLogLevelTestClient(t)
+
+	c.setTemporaryLogLevel(logger.WarnLevel, 200*time.Millisecond)
+	status := c.logLevelStatus()
+	assert.Equal(t, "WARN", status["level"])
+	assert.Equal(t, "ERROR", status["configured_level"])
+	assert.NotEmpty(t, status["revert_at"])
+
+	// 到期后自动恢复为配置的级别，并清除恢复时间
+	assert.Eventually(t, func() bool {
+		return c.log.GetLevel() == logger.ErrorLevel
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NotContains(t, c.logLevelStatus(), "revert_at")
+}
+
+func TestTemporaryLogLevelReplacesTimer(t *testing.T) {
+	c := newLogLevelTestClient(t)
+
+	// 再次调整时取消上一次的恢复计时，按新的有效期计算
+	c.setTemporaryLogLevel(logger.DebugLevel, 100*time.Millisecond)
+	c.setTemporaryLogLevel(logger.WarnLevel, time.Hour)
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, logger.WarnLevel, c.log.GetLevel())
+	c.logLevelMu.Lock()
+	revertAt := c.logLevelRevertAt
+	c.logLevelMu.Unlock()
+	assert.WithinDuration(t, time.Now().Add(time.Hour), revertAt, time.Minute)
+
+	// 调整为配置的级别时不需要恢复
+	c.setTemporaryLogLevel(logger.ErrorLevel, time.Hour)
+	assert.NotContains(t, c.logLevelStatus(), "revert_at")
+	assert.Nil(t, c.logLevelRevert)
+
+	// reset 立即恢复并取消计时
+	c.setTemporaryLogLevel(logger.WarnLevel, time.Hour)
+	c.resetLogLevel()
+	assert.Equal(t, logger.ErrorLevel, c.log.GetLevel())
+	assert.Nil(t, c.logLevelRevert)
+	assert.NotContains(t, c.logLevelStatus(), "revert_at")
+}
+
+func TestHandleLogLevelDuration(t *testing.T) {
+	c := newLogLevelTestClient(t)
+	revertIn := func() time.Duration {
+		c.logLevelMu.Lock()
+		defer c.logLevelMu.Unlock()
+		return time.Until(c.logLevelRevertAt)
+	}
+
+	tests := []struct {
+		name    string
+		message string
+		want    time.Duration
+	}{
+		{"未指定有效期时使用默认值", `{"payload":{"action":"set","level":"warn"}}`, defaultLogLevelDuration},
+		{"按指定的秒数", `{"payload":{"action":"set","level":"warn","duration":90}}`, 90 * time.Second},
+		{"超过上限时按上限", `{"payload":{"action":"set","level":"warn","duration":604800}}`, maxLogLevelDuration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.handleLogLevel([]byte(tt.message))
+			assert.Equal(t, logger.WarnLevel, c.log.GetLevel())
+			assert.InDelta(t, tt.want.Seconds(), revertIn().Seconds(), 5)
+		})
+	}
+
+	// 无效的级别不改变当前状态
+	c.handleLogLevel([]byte(`{"payload":{"action":"set","level":"verbose"}}`))
+	assert.Equal(t, logger.WarnLevel, c.log.GetLevel())
+
+	c.handleLogLevel([]byte(`{"payload":{"action":"reset"}}`))
+	assert.Equal(t, logger.ErrorLevel, c.log.GetLevel())
+}
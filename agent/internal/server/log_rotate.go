@@ -0,0 +1,234 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+const (
+	// logrotate 的最长执行时间
+	logrotateTimeout = 2 * time.Minute
+	// 备份文件名中的时间戳格式
+	logBackupTimeFormat = "20060102-150405"
+)
+
+// 允许 logrotate 模式使用的配置文件：主配置和 logrotate.d 下的配置
+var logrotateConfigRoots = []string{"/etc/logrotate.conf", "/etc/logrotate.d"}
+
+// logRotateRequest 面板端清理/轮转日志文件的请求
+type logRotateRequest struct {
+	RequestID string `json:"request_id"`
+	Payload   struct {
+		Path   string `json:"path"`   // 日志文件路径，logrotate 模式下可选，用于返回轮转前后的大小
+		Mode   string `json:"mode"`   // truncate / copytruncate / logrotate
+		Config string `json:"config"` // logrotate 模式使用的配置文件
+	} `json:"payload"`
+}
+
+// logRotateResult 处理结果，大小单位为字节
+type logRotateResult struct {
+	Path       string `json:"path,omitempty"`
+	Mode       string `json:"mode"`
+	SizeBefore int64  `json:"size_before"`
+	SizeAfter  int64  `json:"size_after"`
+	BackupPath string `json:"backup_path,omitempty"`
+	BackupSize int64  `json:"backup_size,omitempty"`
+	Output     string `json:"output,omitempty"` // logrotate 的输出
+}
+
+// handleLogRotate 清空或轮转指定的日志文件，用于日志占满磁盘时无需登录终端即可处理。
+// 只允许操作 log_rotate_roots 下的普通文件
+func (c *Client) handleLogRotate(message []byte) {
+	var req logRotateRequest
+	if err := json.Unmarshal(message, &req); err != nil {
+		c.log.Error("解析日志轮转请求失败: %v", err)
+		return
+	}
+
+	result, err := rotateLog(req.Payload.Path, req.Payload.Mode, req.Payload.Config, c.cfg.LogRotateRoots, time.Now())
+	if err != nil {
+		c.log.Warn("日志轮转失败: path=%s, mode=%s, error=%v", req.Payload.Path, req.Payload.Mode, err)
+		c.sendResponse(req.RequestID, "log_rotate_response", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.log.Info("日志轮转完成: path=%s, mode=%s, %d -> %d 字节", result.Path, result.Mode, result.SizeBefore, result.SizeAfter)
+	c.sendResponse(req.RequestID, "log_rotate_response", map[string]interface{}{
+		"result": result,
+	})
+}
+
+// rotateLog 按模式处理日志文件
+func rotateLog(path, mode, config string, roots []string, now time.Time) (*logRotateResult, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode == "" {
+		mode = "copytruncate"
+	}
+
+	result := &logRotateResult{Mode: mode}
+	if path != "" || mode != "logrotate" {
+		resolved, err := resolveLogPath(path, roots)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(resolved)
+		if err != nil {
+			return nil, fmt.Errorf("读取文件信息失败: %w", err)
+		}
+		result.Path = resolved
+		result.SizeBefore = info.Size()
+	}
+
+	switch mode {
+	case "truncate":
+		if err := os.Truncate(result.Path, 0); err != nil {
+			return nil, fmt.Errorf("清空文件失败: %w", err)
+		}
+	case "copytruncate":
+		backup, size, err := copyTruncateLog(result.Path, result.SizeBefore, now)
+		if err != nil {
+			return nil, err
+		}
+		result.BackupPath = backup
+		result.BackupSize = size
+	case "logrotate":
+		output, err := runLogrotate(config)
+		result.Output = output
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("不支持的模式: %s", mode)
+	}
+
+	if result.Path != "" {
+		// logrotate 可能把文件移走（非 copytruncate 配置），视为大小为 0
+		if info, err := os.Stat(result.Path); err == nil {
+			result.SizeAfter = info.Size()
+		}
+	}
+	return result, nil
+}
+
+// resolveLogPath 校验日志路径：解析符号链接后必须位于允许的目录内，且是普通文件
+func resolveLogPath(path string, roots []string) (string, error) {
+	if len(roots) == 0 {
+		return "", fmt.Errorf("未配置 log_rotate_roots，不允许清理日志文件")
+	}
+	cleaned, err := normalizeHostPath(path)
+	if err != nil {
+		return "", err
+	}
+	// 解析符号链接，避免通过链接操作允许目录外的文件
+	resolved, err := filepath.EvalSymlinks(cleaned)
+	if err != nil {
+		return "", fmt.Errorf("文件不存在或无法访问: %w", err)
+	}
+	if !pathInRoots(resolved, roots) {
+		return "", fmt.Errorf("路径 %s 不在允许的目录 %s 内", resolved, strings.Join(roots, ", "))
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", fmt.Errorf("读取文件信息失败: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%s 不是普通文件", resolved)
+	}
+	return resolved, nil
+}
+
+// pathInRoots 判断已规范化的路径是否位于任一目录内，目录本身是符号链接时按解析后的路径比较
+func pathInRoots(p string, roots []string) bool {
+	for _, root := range roots {
+		if root == "" {
+			continue
+		}
+		if resolved, err := filepath.EvalSymlinks(root); err == nil {
+			root = resolved
+		}
+		if pathWithinRoot(hostPathFlavor, filepath.Clean(root), p) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyTruncateLog 先把日志复制到带时间戳的备份文件再清空原文件，
+// 写日志的进程持有的文件描述符不受影响，无需重启或发信号。
+// 复制与清空之间写入的少量日志会丢失，这是 copytruncate 方式固有的限制
+func copyTruncateLog(path string, size int64, now time.Time) (string, int64, error) {
+	// 日志占满磁盘时复制必然失败，提前检查并提示改用清空模式
+	if usage, err := disk.Usage(filepath.Dir(path)); err == nil && uint64(size) > usage.Free {
+		return "", 0, fmt.Errorf("磁盘剩余空间 %d 字节不足以备份 %d 字节的日志，请改用 truncate 模式", usage.Free, size)
+	}
+
+	src, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return "", 0, fmt.Errorf("打开日志文件失败: %w", err)
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return "", 0, fmt.Errorf("读取文件信息失败: %w", err)
+	}
+	backup := path + "." + now.Format(logBackupTimeFormat)
+	dst, err := os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return "", 0, fmt.Errorf("创建备份文件失败: %w", err)
+	}
+
+	copied, err := io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(backup)
+		return "", 0, fmt.Errorf("备份日志失败: %w", err)
+	}
+
+	if err := src.Truncate(0); err != nil {
+		return backup, copied, fmt.Errorf("已备份到 %s，但清空原文件失败: %w", backup, err)
+	}
+	return backup, copied, nil
+}
+
+// runLogrotate 使用指定配置强制执行一次 logrotate
+func runLogrotate(config string) (string, error) {
+	if config == "" {
+		return "", fmt.Errorf("logrotate 模式需要指定配置文件")
+	}
+	cleaned, err := normalizeHostPath(config)
+	if err != nil {
+		return "", err
+	}
+	if !pathInRoots(cleaned, logrotateConfigRoots) {
+		return "", fmt.Errorf("只允许使用 %s 下的 logrotate 配置", strings.Join(logrotateConfigRoots, " 或 "))
+	}
+
+	bin, err := exec.LookPath("logrotate")
+	if err != nil {
+		return "", fmt.Errorf("未找到 logrotate: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), logrotateTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, bin, "-f", cleaned).CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("执行 logrotate 失败: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
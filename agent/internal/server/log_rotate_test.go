@@ -0,0 +1,74 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotateLog(t *testing.T) {
+	root := t.TempDir()
+	logPath := filepath.Join(root, "app.log")
+	now := time.Date(2026, 10, 16, 15, 4, 5, 0, time.Local)
+
+	// copytruncate：备份后清空
+	assert.NoError(t, os.WriteFile(logPath, []byte("line1\nline2\n"), 0640))
+	result, err := rotateLog(logPath, "", "", []string{root}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "copytruncate", result.Mode)
+	assert.Equal(t, int64(12), result.SizeBefore)
+	assert.Zero(t, result.SizeAfter)
+	assert.Equal(t, int64(12), result.BackupSize)
+	assert.Equal(t, filepath.Base(logPath)+".20261016-150405", filepath.Base(result.BackupPath))
+	backup, err := os.ReadFile(result.BackupPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "line1\nline2\n", string(backup))
+
+	// 同一时刻重复执行不覆盖已有备份
+	_, err = rotateLog(logPath, "copytruncate", "", []string{root}, now)
+	assert.Error(t, err)
+
+	// truncate：直接清空
+	assert.NoError(t, os.WriteFile(logPath, []byte("line3\n"), 0640))
+	result, err = rotateLog(logPath, "truncate", "", []string{root}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), result.SizeBefore)
+	assert.Zero(t, result.SizeAfter)
+	assert.Empty(t, result.BackupPath)
+}
+
+func TestRotateLogRejects(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	outsideLog := filepath.Join(outside, "secret.log")
+	assert.NoError(t, os.WriteFile(outsideLog, []byte("secret"), 0600))
+
+	// 目录外的文件
+	_, err := rotateLog(outsideLog, "truncate", "", []string{root}, time.Now())
+	assert.Error(t, err)
+
+	// 通过符号链接指向目录外的文件
+	link := filepath.Join(root, "link.log")
+	if err := os.Symlink(outsideLog, link); err == nil {
+		_, err = rotateLog(link, "truncate", "", []string{root}, time.Now())
+		assert.Error(t, err)
+	}
+
+	// 目录和未配置允许目录
+	_, err = rotateLog(root, "truncate", "", []string{root}, time.Now())
+	assert.Error(t, err)
+	_, err = rotateLog(outsideLog, "truncate", "", nil, time.Now())
+	assert.Error(t, err)
+
+	// logrotate 只允许使用系统配置目录下的配置
+	_, err = rotateLog("", "logrotate", outsideLog, []string{root}, time.Now())
+	assert.Error(t, err)
+
+	content, _ := os.ReadFile(outsideLog)
+	assert.Equal(t, "secret", string(content))
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"time"
+
+	"github.com/user/server-ops-agent/internal/monitor"
+)
+
+// idleEnabled 判断是否启用空闲降频，调用方需持有 monitorRateMu
+func (c *Client) idleEnabled() bool {
+	return c.cfg.IdleMonitorInterval > c.cfg.MonitorInterval
+}
+
+// ObserveActivity 根据本次采集的 CPU 使用率和网络速率更新空闲状态，并在样本中填写当前的上报间隔。
+// CPU 和网络持续 idle_after 低于阈值时降频为 idle_monitor_interval；任一指标超过阈值立即恢复。
+// 降频期间的样本覆盖整个较长的间隔，因此短暂的活动同样会体现在平均值中
+func (c *Client) ObserveActivity(data *monitor.MonitorData) {
+	now := time.Now()
+	active := data.CPUUsage > c.cfg.IdleCPUThreshold ||
+		data.NetworkIn+data.NetworkOut > float64(c.cfg.IdleNetworkThreshold)*1024
+
+	c.monitorRateMu.Lock()
+	before := c.idle
+	switch {
+	case !c.idleEnabled() || active:
+		c.idle = false
+		c.lowActivitySince = time.Time{}
+	case c.lowActivitySince.IsZero():
+		c.lowActivitySince = now
+	case now.Sub(c.lowActivitySince) >= c.cfg.IdleAfter:
+		c.idle = true
+	}
+	idle := c.idle
+	c.monitorRateMu.Unlock()
+
+	if idle != before {
+		if idle {
+			c.log.Info("系统持续空闲，上报间隔调整为 %s", c.cfg.IdleMonitorInterval)
+		} else {
+			c.log.Info("系统恢复活动，上报间隔恢复为 %s", c.cfg.MonitorInterval)
+		}
+		c.notifyMonitorRate()
+	}
+
+	interval, focused := c.ReportInterval()
+	data.ReportInterval = uint64(interval / time.Millisecond)
+	data.Idle = idle && !focused
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/config"
+	"github.com/user/server-ops-agent/internal/monitor"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func TestObserveActivity(t *testing.T) {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	c := &Client{cfg: &config.Config{
+		MonitorInterval:      5 * time.Second,
+		IdleMonitorInterval:  time.Minute,
+		IdleAfter:            0,
+		IdleCPUThreshold:     5,
+		IdleNetworkThreshold: 10,
+	}, log: log}
+	notified := 0
+	c.SetMonitorRateHandler(func() { notified++ })
+
+	quiet := &monitor.MonitorData{CPUUsage: 1, NetworkIn: 1024}
+	c.ObserveActivity(quiet)
+	assert.False(t, quiet.Idle)
+	assert.Equal(t, uint64(5000), quiet.ReportInterval)
+
+	// 连续低活动达到 idle_after 后降频
+	c.ObserveActivity(quiet)
+	assert.True(t, quiet.Idle)
+	assert.Equal(t, uint64(60000), quiet.ReportInterval)
+	assert.Equal(t, 1, notified)
+
+	// 聚焦查看优先于空闲降频
+	c.setMonitorFocus(time.Second, time.Minute)
+	c.ObserveActivity(quiet)
+	assert.False(t, quiet.Idle)
+	assert.Equal(t, uint64(1000), quiet.ReportInterval)
+	c.clearMonitorFocus()
+
+	// 网络速率超过阈值立即恢复
+	busy := &monitor.MonitorData{CPUUsage: 1, NetworkIn: 8 * 1024, NetworkOut: 4 * 1024}
+	c.ObserveActivity(busy)
+	assert.False(t, busy.Idle)
+	assert.Equal(t, uint64(5000), busy.ReportInterval)
+	assert.Equal(t, 4, notified)
+
+	// 空闲间隔不大于常规间隔时不降频
+	c.cfg.IdleMonitorInterval = 5 * time.Second
+	c.ObserveActivity(quiet)
+	c.ObserveActivity(quiet)
+	assert.False(t, quiet.Idle)
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 聚焦查看时允许的最短上报间隔，避免面板把采集频率调得过高
+const minFocusInterval = time.Second
+
+// monitorRateMessage 面板下发的临时上报间隔，interval_ms 为 0 表示恢复配置的间隔
+type monitorRateMessage struct {
+	Type    string `json:"type"`
+	Payload struct {
+		IntervalMs int64 `json:"interval_ms"`
+		LeaseMs    int64 `json:"lease_ms"` // 租约时长，到期未续约则自动恢复
+	} `json:"payload"`
+}
+
+// handleMonitorRate 处理面板的聚焦查看请求：有人查看服务器详情时临时提高上报频率
+func (c *Client) handleMonitorRate(message []byte) {
+	var msg monitorRateMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.log.Error("解析上报间隔消息失败: %v", err)
+		return
+	}
+
+	interval := time.Duration(msg.Payload.IntervalMs) * time.Millisecond
+	lease := time.Duration(msg.Payload.LeaseMs) * time.Millisecond
+	if interval <= 0 || lease <= 0 {
+		c.clearMonitorFocus()
+		return
+	}
+	c.setMonitorFocus(interval, lease)
+}
+
+// setMonitorFocus 在租约期内使用更短的上报间隔，不短于 minFocusInterval，
+// 不低于配置的间隔时等同于取消聚焦
+func (c *Client) setMonitorFocus(interval, lease time.Duration) {
+	if interval < minFocusInterval {
+		interval = minFocusInterval
+	}
+	if interval >= c.cfg.MonitorInterval {
+		c.clearMonitorFocus()
+		return
+	}
+
+	c.monitorRateMu.Lock()
+	changed := c.focusInterval != interval
+	c.focusInterval = interval
+	if c.focusRevert != nil {
+		c.focusRevert.Stop()
+	}
+	c.focusRevert = time.AfterFunc(lease, c.clearMonitorFocus)
+	c.monitorRateMu.Unlock()
+
+	if changed {
+		c.log.Info("聚焦查看：上报间隔临时调整为 %s", interval)
+		c.notifyMonitorRate()
+	}
+}
+
+// clearMonitorFocus 取消聚焦，恢复配置的上报间隔
+func (c *Client) clearMonitorFocus() {
+	c.monitorRateMu.Lock()
+	changed := c.focusInterval != 0
+	c.focusInterval = 0
+	if c.focusRevert != nil {
+		c.focusRevert.Stop()
+		c.focusRevert = nil
+	}
+	c.monitorRateMu.Unlock()
+
+	if changed {
+		c.log.Info("聚焦查看结束，恢复上报间隔为 %s", c.cfg.MonitorInterval)
+		c.notifyMonitorRate()
+	}
+}
+
+// ReportInterval 返回当前的上报间隔，以及是否处于聚焦查看的高频上报状态。
+// 聚焦查看优先于空闲降频
+func (c *Client) ReportInterval() (time.Duration, bool) {
+	c.monitorRateMu.Lock()
+	defer c.monitorRateMu.Unlock()
+	if c.focusInterval > 0 && c.focusInterval < c.cfg.MonitorInterval {
+		return c.focusInterval, true
+	}
+	if c.idle && c.idleEnabled() {
+		return c.cfg.IdleMonitorInterval, false
+	}
+	return c.cfg.MonitorInterval, false
+}
+
+// SetMonitorRateHandler 设置上报间隔变化的回调，用于通知监控任务重置定时器
+func (c *Client) SetMonitorRateHandler(handler func()) {
+	c.monitorRateMu.Lock()
+	defer c.monitorRateMu.Unlock()
+	c.monitorRateHandler = handler
+}
+
+func (c *Client) notifyMonitorRate() {
+	c.monitorRateMu.Lock()
+	handler := c.monitorRateHandler
+	c.monitorRateMu.Unlock()
+	if handler != nil {
+		handler()
+	}
+}
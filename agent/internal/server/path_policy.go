@@ -0,0 +1,116 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"fmt"
+	pathpkg "path"
+	"path/filepath"
+	"strings"
+)
+
+// 文件路径校验与规范化。
+// FileManager（宿主机）和 ContainerFileManager（容器内）共用同一套规则，
+// 避免某个入口遗漏检查而被用来绕过目录穿越防护：
+//   - 拒绝空路径和包含空字节的路径
+//   - 在 Clean 之前拒绝任何 ".." 路径段（Clean 会把它规范化掉，导致后续检查失效）
+//   - 要求绝对路径
+//   - 可选地限制在指定根目录内
+
+// pathFlavor 描述一类路径的语法：宿主机路径遵循当前系统规则，容器路径始终是 POSIX 风格
+type pathFlavor struct {
+	clean func(string) string
+	isAbs func(string) bool
+	// separator 规范化后路径使用的分隔符
+	separator string
+}
+
+var (
+	hostPathFlavor = pathFlavor{
+		clean: filepath.Clean,
+		isAbs: func(p string) bool {
+			// Windows 下 "/" 开头的路径表示当前盘符根目录，同样视为绝对路径
+			return filepath.IsAbs(p) || strings.HasPrefix(filepath.ToSlash(p), "/")
+		},
+		separator: string(filepath.Separator),
+	}
+	containerPathFlavor = pathFlavor{
+		clean:     pathpkg.Clean,
+		isAbs:     pathpkg.IsAbs,
+		separator: "/",
+	}
+)
+
+// normalizePath 按给定语法校验并规范化路径；root 非空时要求结果位于 root 内
+func normalizePath(flavor pathFlavor, p, root string) (string, error) {
+	if p == "" {
+		return "", fmt.Errorf("路径不能为空")
+	}
+	if strings.ContainsRune(p, 0) {
+		return "", fmt.Errorf("路径包含空字节")
+	}
+
+	// 同时按 / 和 \ 拆分，避免通过另一种分隔符绕过检查
+	segments := strings.FieldsFunc(p, func(r rune) bool { return r == '/' || r == '\\' })
+	for _, seg := range segments {
+		if seg == ".." {
+			return "", fmt.Errorf("路径不能包含上级目录引用: %s", p)
+		}
+	}
+
+	if !flavor.isAbs(p) {
+		return "", fmt.Errorf("必须使用绝对路径: %s", p)
+	}
+
+	cleaned := flavor.clean(p)
+	if root != "" && !pathWithinRoot(flavor, flavor.clean(root), cleaned) {
+		return "", fmt.Errorf("路径 %s 超出允许的目录 %s", p, root)
+	}
+	return cleaned, nil
+}
+
+// pathWithinRoot 判断已规范化的路径是否位于 root 内（含 root 本身）
+func pathWithinRoot(flavor pathFlavor, root, p string) bool {
+	if p == root {
+		return true
+	}
+	prefix := root
+	if !strings.HasSuffix(prefix, flavor.separator) {
+		prefix += flavor.separator
+	}
+	return strings.HasPrefix(p, prefix)
+}
+
+// normalizeHostPath 校验并规范化宿主机路径
+func normalizeHostPath(p string) (string, error) {
+	return normalizePath(hostPathFlavor, p, "")
+}
+
+// normalizeContainerPath 校验并规范化容器内路径
+func normalizeContainerPath(p string) (string, error) {
+	return normalizePath(containerPathFlavor, p, "")
+}
+
+// sanitizeFileName 校验上传文件名，只保留最后一级名称，禁止携带路径
+func sanitizeFileName(filename string) (string, error) {
+	name := strings.TrimSpace(filename)
+	if name == "" {
+		return "", fmt.Errorf("文件名不能为空")
+	}
+	if strings.ContainsRune(name, 0) {
+		return "", fmt.Errorf("文件名包含空字节")
+	}
+
+	// 兼容部分客户端（或旧浏览器）传入的 Windows 风格路径。
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = pathpkg.Base(name)
+
+	if name == "" || name == "." || name == "/" || name == ".." {
+		return "", fmt.Errorf("文件名无效")
+	}
+	if strings.Contains(name, "/") {
+		return "", fmt.Errorf("文件名不能包含路径分隔符")
+	}
+
+	return name, nil
+}
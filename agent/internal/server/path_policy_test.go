@@ -0,0 +1,112 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeContainerPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"根目录", "/", "/", false},
+		{"普通路径", "/var/log/nginx", "/var/log/nginx", false},
+		{"多余分隔符", "//var///log/", "/var/log", false},
+		{"当前目录段", "/var/./log/.", "/var/log", false},
+		{"名称中包含点", "/data/..backup/a..b", "/data/..backup/a..b", false},
+		{"空路径", "", "", true},
+		{"相对路径", "var/log", "", true},
+		{"点开头相对路径", "./etc/passwd", "", true},
+		{"上级目录", "/var/../etc/shadow", "", true},
+		{"结尾上级目录", "/var/log/..", "", true},
+		{"仅上级目录", "..", "", true},
+		{"反斜杠上级目录", "/var/..\\etc", "", true},
+		{"空字节截断", "/var/log/app.log\x00.txt", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeContainerPath(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNormalizePathWithinRoot(t *testing.T) {
+	_, err := normalizePath(containerPathFlavor, "/srv/app/uploads/a.txt", "/srv/app")
+	assert.NoError(t, err)
+
+	// 前缀相同但不在根目录内
+	_, err = normalizePath(containerPathFlavor, "/srv/application/a.txt", "/srv/app")
+	assert.Error(t, err)
+
+	_, err = normalizePath(containerPathFlavor, "/etc/passwd", "/srv/app")
+	assert.Error(t, err)
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"report.pdf", "report.pdf", false},
+		{"  report.pdf  ", "report.pdf", false},
+		{"C:\\Users\\me\\report.pdf", "report.pdf", false},
+		{"../../etc/cron.d/job", "job", false},
+		{"..", "", true},
+		{".", "", true},
+		{"", "", true},
+		{"a\x00b", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := sanitizeFileName(tt.input)
+		if tt.wantErr {
+			assert.Error(t, err, tt.input)
+			continue
+		}
+		assert.NoError(t, err, tt.input)
+		assert.Equal(t, tt.want, got)
+	}
+}
+
+func TestContainerFileRootsFor(t *testing.T) {
+	roots := ContainerFileRoots{
+		Global: []string{"/srv"},
+		PerContainer: map[string][]string{
+			"nginx":        {"/etc/nginx", "/usr/share/nginx/html"},
+			"0123456789ab": {"/data"},
+		},
+	}
+
+	assert.Equal(t, []string{"/etc/nginx", "/usr/share/nginx/html"}, roots.rootsFor("web", "ffff", "nginx"))
+	assert.Equal(t, []string{"/data"}, roots.rootsFor("0123", "0123456789abcdef", "db"))
+	assert.Equal(t, []string{"/srv"}, roots.rootsFor("other", "fedcba987654", "other"))
+	assert.Empty(t, ContainerFileRoots{}.rootsFor("any", "", ""))
+}
+
+func TestContainerPathAllowed(t *testing.T) {
+	roots, err := normalizeContainerRoots([]string{"/srv/app/", " ", "/data"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/srv/app", "/data"}, roots)
+
+	assert.True(t, containerPathAllowed(roots, "/srv/app/config.yml"))
+	assert.True(t, containerPathAllowed(roots, "/data"))
+	assert.False(t, containerPathAllowed(roots, "/srv/application"))
+	assert.False(t, containerPathAllowed(roots, "/etc/shadow"))
+
+	_, err = normalizeContainerRoots([]string{"relative/dir"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// pokeRequest 面板要求立即心跳或重新建立连接的请求
+type pokeRequest struct {
+	Type      string `json:"type"`
+	RequestID string `json:"request_id"`
+	Payload   struct {
+		Action string `json:"action"` // heartbeat 或 reconnect
+	} `json:"payload"`
+}
+
+// SetPokeHandler 设置面板要求立即心跳时的回调，用于通知监控任务马上上报监控数据和系统信息
+func (c *Client) SetPokeHandler(handler func()) {
+	c.wsMutex.Lock()
+	defer c.wsMutex.Unlock()
+	c.pokeHandler = handler
+}
+
+// handlePoke 处理面板的心跳和重连请求。
+// heartbeat 立即回复并触发一次监控数据和系统信息上报，用于确认 Agent 存活；
+// reconnect 回复后关闭当前连接，由现有的重连流程重新建立，用于从卡住的连接中恢复
+func (c *Client) handlePoke(message []byte) {
+	var req pokeRequest
+	if err := json.Unmarshal(message, &req); err != nil {
+		c.log.Error("解析心跳请求失败: %v", err)
+		return
+	}
+
+	action := strings.ToLower(req.Payload.Action)
+	switch action {
+	case "", "heartbeat":
+		c.sendResponse(req.RequestID, "agent_poke_response", map[string]interface{}{
+			"action":  "heartbeat",
+			"sent_at": time.Now().UnixMilli(),
+		})
+
+		c.wsMutex.Lock()
+		handler := c.pokeHandler
+		c.wsMutex.Unlock()
+		if handler != nil {
+			handler()
+		}
+	case "reconnect":
+		c.sendResponse(req.RequestID, "agent_poke_response", map[string]interface{}{
+			"action":  "reconnect",
+			"sent_at": time.Now().UnixMilli(),
+		})
+		c.log.Warn("面板要求重新建立连接，关闭当前WebSocket连接")
+		c.dropConnection()
+	default:
+		c.sendResponse(req.RequestID, "agent_poke_response", map[string]interface{}{
+			"error": "不支持的操作: " + req.Payload.Action,
+		})
+	}
+}
+
+// dropConnection 关闭当前连接但不设置关闭标志，消息处理协程退出后触发重连
+func (c *Client) dropConnection() {
+	c.wsMutex.Lock()
+	conn := c.wsConn
+	c.wsConnected = false
+	c.wsMutex.Unlock()
+
+	if conn != nil {
+		// WriteControl 可与其他写入并发调用；不获取写入锁，连接卡在写入时也能关闭
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "reconnect"), time.Now().Add(time.Second))
+		conn.Close()
+	}
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// readOnlyControlActions 在消息分发前检查的控制类消息：这些消息不经过 handleOperationMessage，
+// 只读模式下只放行列出的 action，其余一律拒绝。agent_upgrade 会替换 Agent 程序，始终拒绝
+var readOnlyControlActions = map[string]map[string]bool{
+	"agent_upgrade": {},
+	"update_config": {"": true, "get": true},
+}
+
+// isReadOnly Agent 是否处于只读模式：本机配置或面板设置任一开启即生效
+func (c *Client) isReadOnly() bool {
+	return c.cfg.ReadOnlyMode || c.panelReadOnly.Load()
+}
+
+// setPanelReadOnly 应用面板下发的只读模式设置，本机配置开启时面板无法关闭
+func (c *Client) setPanelReadOnly(enabled bool) {
+	if c.panelReadOnly.Swap(enabled) == enabled {
+		return
+	}
+	if enabled {
+		c.log.Warn("面板已开启只读模式，将拒绝所有修改类操作")
+	} else if c.cfg.ReadOnlyMode {
+		c.log.Info("面板已关闭只读模式，但本机配置 read_only_mode=true，仍保持只读")
+	} else {
+		c.log.Info("面板已关闭只读模式")
+	}
+}
+
+// handleReadOnlyMode 处理面板推送的只读模式切换，使设置立即生效而不必等待下一次拉取配置
+func (c *Client) handleReadOnlyMode(message []byte) {
+	var req struct {
+		RequestID string `json:"request_id"`
+		Payload   struct {
+			Enabled bool `json:"enabled"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(message, &req); err != nil {
+		c.log.Error("解析只读模式消息失败: %v", err)
+		return
+	}
+	c.setPanelReadOnly(req.Payload.Enabled)
+	c.sendResponse(req.RequestID, "read_only_mode_response", map[string]interface{}{
+		"read_only":       c.isReadOnly(),
+		"panel_read_only": c.panelReadOnly.Load(),
+		"local_read_only": c.cfg.ReadOnlyMode,
+	})
+}
+
+// rejectControlIfReadOnly 只读模式下拒绝升级和修改配置等控制类消息，返回 true 表示已拒绝。
+// 按各消息原有的响应类型回复，面板端等待中的请求可以立即返回错误
+func (c *Client) rejectControlIfReadOnly(msgType string, message []byte) bool {
+	actions, ok := readOnlyControlActions[msgType]
+	if !ok || !c.isReadOnly() {
+		return false
+	}
+	var msg struct {
+		RequestID string `json:"request_id"`
+		Payload   struct {
+			Action string `json:"action"`
+		} `json:"payload"`
+	}
+	_ = json.Unmarshal(message, &msg)
+	if actions[strings.ToLower(strings.TrimSpace(msg.Payload.Action))] {
+		return false
+	}
+
+	const errMsg = "Agent 处于只读模式，已拒绝修改类操作"
+	c.log.Warn("只读模式拒绝操作: type=%s, action=%s", msgType, msg.Payload.Action)
+	switch msgType {
+	case "agent_upgrade":
+		c.sendUpgradeStatus(msg.RequestID, "failed", errMsg, map[string]interface{}{"code": "ERR_READ_ONLY"})
+	default:
+		c.sendResponse(msg.RequestID, msgType+"_response", map[string]interface{}{
+			"error": errMsg,
+			"code":  "ERR_READ_ONLY",
+		})
+	}
+	return true
+}
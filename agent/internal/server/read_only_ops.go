@@ -0,0 +1,138 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// readOnlyTypes 只读模式下不受限制的操作类消息
+var readOnlyTypes = map[string]bool{
+	"file_list":              true,
+	"process_list":           true,
+	"connection_list":        true,
+	"listening_ports":        true,
+	"netstat":                true,
+	"docker_logs_stream":     true,
+	"docker_stats_stream":    true,
+	"diagnostic_stream":      true,
+	"file_tail_stream":       true,
+	"journal_query":          true,
+	"file_scan":              true,
+	"file_search":            true,
+	"file_diff":              true,
+	"file_snapshot":          true,
+	"terminal_resize":        true,
+	"terminal_close":         true,
+	"chunked_upload_cancel":  true,
+	"chunked_download_chunk": true,
+	"file_archive":           true,
+}
+
+// readOnlyActions 只读模式下按 action 放行的操作类消息，未列出的 action 一律拒绝。
+// docker_command 的 action 以 "command/action" 表示
+var readOnlyActions = map[string]map[string]bool{
+	"file_content":    {"get": true, "tree": true},
+	"docker_file":     {"list": true, "get": true, "tree": true, "download": true},
+	"command_capture": {"": true, "list": true, "stop": true},
+	"shell_command":   {"resize": true, "close": true, "get_cwd": true},
+	"service_command": {"list": true, "status": true, "logs": true},
+	"package_command": {"inventory": true},
+	"docker_command": {
+		"containers/list": true, "containers/logs": true, "images/list": true,
+		"composes/list": true, "composes/config": true, "composes/validate": true, "composes/services": true,
+		"networks/list": true, "networks/inspect": true, "volumes/list": true, "volumes/inspect": true,
+	},
+	"nginx_command": {
+		"nginx_status": true, "nginx_configs_list": true, "nginx_config_content": true,
+		"nginx_logs_list": true, "nginx_log_content": true, "nginx_log_download": true,
+		"nginx_test_config": true, "nginx_processes": true, "nginx_ports": true,
+		"nginx_sites_list": true, "nginx_site_detail": true, "nginx_get_raw_config": true,
+		"openresty_status": true, "openresty_install_logs": true, "certificate_content": true,
+		"certbot_check_installation": true, "certbot_install_status": true, "certbot_list": true,
+		"ssl_scan_certificates": true, "nginx_snapshot_list": true,
+		"webserver_detect": true, "webserver_test": true, "webserver_vhosts": true,
+	},
+}
+
+// readOnlyMessage 只读模式判断所需的请求字段
+type readOnlyMessage struct {
+	RequestID string `json:"request_id"`
+	SessionID string `json:"session_id"`
+	Payload   struct {
+		Type        string `json:"type"`
+		Action      string `json:"action"`
+		Command     string `json:"command"`
+		Session     string `json:"session"`
+		ContainerID string `json:"container_id"`
+		StreamID    string `json:"stream_id"`
+	} `json:"payload"`
+}
+
+// readOnlyAllowed 判断操作类消息在只读模式下是否放行
+func readOnlyAllowed(msgType string, msg *readOnlyMessage) bool {
+	if readOnlyTypes[msgType] {
+		return true
+	}
+	actions, ok := readOnlyActions[msgType]
+	if !ok {
+		return false
+	}
+	action := strings.ToLower(strings.TrimSpace(msg.Payload.Action))
+	switch msgType {
+	case "shell_command":
+		action = msg.Payload.Type
+	case "docker_command":
+		action = msg.Payload.Command + "/" + action
+	}
+	return actions[action]
+}
+
+// rejectIfReadOnly 只读模式下拒绝修改类操作并告知面板，返回 true 表示已拒绝。
+// 终端输入被拒绝时同时关闭会话，避免每次按键都返回错误
+func (c *Client) rejectIfReadOnly(msgType string, message []byte) bool {
+	if !c.isReadOnly() {
+		return false
+	}
+	var msg readOnlyMessage
+	_ = json.Unmarshal(message, &msg)
+	if readOnlyAllowed(msgType, &msg) {
+		return false
+	}
+
+	const errMsg = "Agent 处于只读模式，已拒绝修改类操作"
+	c.log.Warn("只读模式拒绝操作: type=%s, action=%s", msgType, msg.Payload.Action)
+
+	switch msgType {
+	case "terminal_input", "terminal_create":
+		c.sendTerminalError(msg.SessionID, errMsg)
+		c.handleTerminalClose(msg.SessionID)
+	case "shell_command":
+		c.sendTerminalError(msg.Payload.Session, errMsg)
+		if msg.Payload.ContainerID != "" {
+			c.handleContainerTerminalCommand(msg.Payload.ContainerID, msg.Payload.Session, "close", "", nil)
+		} else {
+			c.handleTerminalClose(msg.Payload.Session)
+		}
+	case "docker_pull_stream":
+		if msg.Payload.Action == "start" {
+			c.sendStreamMessage(msg.Payload.StreamID, "docker_pull_stream_end", map[string]interface{}{
+				"success": false,
+				"error":   errMsg,
+				"code":    "ERR_READ_ONLY",
+			})
+		}
+	default:
+		// 按各类请求原有的错误响应类型回复，面板端等待中的请求可以立即返回错误
+		responseType := "error"
+		if msgType == "nginx_command" {
+			responseType = "nginx_error"
+		}
+		c.sendResponse(msg.RequestID, responseType, map[string]interface{}{
+			"error": errMsg,
+			"code":  "ERR_READ_ONLY",
+		})
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/config"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func TestReadOnlyAllowed(t *testing.T) {
+	cases := []struct {
+		msgType string
+		payload string
+		allowed bool
+	}{
+		{"file_list", `{}`, true},
+		{"file_content", `{"action":"get"}`, true},
+		{"file_content", `{"action":"save"}`, false},
+		{"file_upload", `{}`, false},
+		{"process_kill", `{}`, false},
+		{"docker_command", `{"command":"containers","action":"list"}`, true},
+		{"docker_command", `{"command":"containers","action":"stop"}`, false},
+		{"docker_command", `{"command":"containers","action":"exec"}`, false},
+		{"docker_command", `{"command":"images","action":"pull"}`, false},
+		{"docker_command", `{"command":"volumes","action":"inspect"}`, true},
+		{"docker_command", `{"command":"volumes","action":"prune"}`, false},
+		{"docker_command", `{"command":"composes","action":"services"}`, true},
+		{"docker_command", `{"command":"composes","action":"update"}`, false},
+		{"docker_command", `{"command":"registry","action":"login"}`, false},
+		{"docker_pull_stream", `{"action":"start","stream_id":"s1","image":"nginx"}`, false},
+		{"nginx_command", `{"action":"NGINX_STATUS"}`, true},
+		{"nginx_command", `{"action":"nginx_restart"}`, false},
+		{"nginx_command", `{"action":"certbot_request"}`, false},
+		{"shell_command", `{"type":"resize"}`, true},
+		{"shell_command", `{"type":"input"}`, false},
+		{"terminal_input", `{}`, false},
+		{"command_capture", `{"action":"stop"}`, true},
+		{"command_capture", `{"action":"start"}`, false},
+		{"service_command", `{"action":"logs"}`, true},
+		{"service_command", `{"action":"restart"}`, false},
+		{"package_command", `{"action":"inventory"}`, true},
+		{"package_command", `{"action":"install"}`, false},
+		{"some_future_op", `{}`, false},
+	}
+	for _, tc := range cases {
+		var msg readOnlyMessage
+		assert.NoError(t, json.Unmarshal([]byte(`{"payload":`+tc.payload+`}`), &msg))
+		assert.Equal(t, tc.allowed, readOnlyAllowed(tc.msgType, &msg), "%s %s", tc.msgType, tc.payload)
+	}
+}
+
+func TestReadOnlyRejectsControlMessages(t *testing.T) {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	configPath := filepath.Join(t.TempDir(), "agent.yaml")
+	assert.NoError(t, os.WriteFile(configPath, []byte("log_level: error\n"), 0600))
+	cfg := &config.Config{ReadOnlyMode: true, AllowRemoteConfig: true, LogLevel: "error"}
+
+	// 模拟面板：依次发送请求，记录 Agent 的回复
+	requests := []string{
+		`{"type":"agent_upgrade","request_id":"u1","payload":{"action":"upgrade","target_version":"9.9.9"}}`,
+		`{"type":"update_config","request_id":"c1","payload":{"action":"update","patch":{"log_level":"debug"}}}`,
+		`{"type":"update_config","request_id":"c2","payload":{"action":"UPDATE","patch":{"log_level":"debug"},"dry_run":true}}`,
+		`{"type":"update_config","request_id":"c3","payload":{"action":"get"}}`,
+	}
+	replies := make(chan map[string]interface{}, len(requests))
+	upgrader := websocket.Upgrader{}
+	panel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for _, req := range requests {
+			assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(req)))
+			var reply map[string]interface{}
+			if err := conn.ReadJSON(&reply); err != nil {
+				return
+			}
+			replies <- reply
+		}
+	}))
+	t.Cleanup(panel.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(panel.URL, "http"), nil)
+	assert.NoError(t, err)
+	c := &Client{cfg: cfg, log: log, wsConn: conn, wsConnected: true, wsShutdown: true, configPath: configPath}
+	t.Cleanup(func() { conn.Close() })
+	go c.handleWebSocketMessages()
+
+	next := func() map[string]interface{} {
+		select {
+		case reply := <-replies:
+			return reply
+		case <-time.After(5 * time.Second):
+			t.Fatal("未收到 Agent 回复")
+			return nil
+		}
+	}
+
+	// 升级直接拒绝，不进入升级流程
+	reply := next()
+	assert.Equal(t, "agent_upgrade_status", reply["type"])
+	assert.Equal(t, "u1", reply["request_id"])
+	payload := reply["payload"].(map[string]interface{})
+	assert.Equal(t, "failed", payload["status"])
+	assert.Equal(t, "ERR_READ_ONLY", payload["code"])
+	assert.Zero(t, atomic.LoadInt32(&c.upgrading))
+
+	// 修改配置（包括只校验）被拒绝，配置文件和运行中的配置都不变
+	for _, id := range []string{"c1", "c2"} {
+		reply = next()
+		assert.Equal(t, "update_config_response", reply["type"])
+		assert.Equal(t, id, reply["request_id"])
+		assert.Equal(t, "ERR_READ_ONLY", reply["data"].(map[string]interface{})["code"])
+	}
+	data, err := os.ReadFile(configPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "log_level: error\n", string(data))
+	assert.Equal(t, "error", c.cfg.LogLevel)
+
+	// 查询配置仍然放行
+	reply = next()
+	assert.Equal(t, "c3", reply["request_id"])
+	assert.NotContains(t, reply["data"], "error")
+	assert.Contains(t, reply["data"], "settings")
+}
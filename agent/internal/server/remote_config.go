@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+
+	"github.com/user/server-ops-agent/config"
+)
+
+// updateConfigRequest 面板端查询/修改 Agent 配置的请求
+type updateConfigRequest struct {
+	Type      string `json:"type"`
+	RequestID string `json:"request_id"`
+	Payload   struct {
+		Action string                 `json:"action"`  // get 或 update
+		Patch  map[string]interface{} `json:"patch"`   // update 时要修改的配置项
+		DryRun bool                   `json:"dry_run"` // 只校验不保存
+	} `json:"payload"`
+}
+
+// handleUpdateConfig 处理配置的远程查询和修改。
+// 修改时合并补丁并校验，保存到配置文件后立即应用可热更新的配置项，
+// 需要重启才能生效的配置项在响应中单独列出。
+func (c *Client) handleUpdateConfig(message []byte) {
+	var req updateConfigRequest
+	if err := json.Unmarshal(message, &req); err != nil {
+		c.log.Error("解析配置修改请求失败: %v", err)
+		return
+	}
+
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+
+	switch req.Payload.Action {
+	case "", "get":
+		c.sendResponse(req.RequestID, "update_config_response", map[string]interface{}{
+			"settings":      config.RemoteSettings(c.cfg),
+			"remote_config": c.cfg.AllowRemoteConfig,
+		})
+		return
+	case "update":
+	default:
+		c.sendUpdateConfigError(req.RequestID, "不支持的操作: "+req.Payload.Action)
+		return
+	}
+
+	if !c.cfg.AllowRemoteConfig {
+		c.sendUpdateConfigError(req.RequestID, "Agent 已禁用远程修改配置（allow_remote_config=false）")
+		return
+	}
+
+	updated, changed, err := config.ApplyPatch(c.cfg, req.Payload.Patch)
+	if err != nil {
+		c.sendUpdateConfigError(req.RequestID, err.Error())
+		return
+	}
+
+	restartRequired := make([]string, 0)
+	applied := make([]string, 0, len(changed))
+	for _, key := range changed {
+		if config.RequiresRestart(key) {
+			restartRequired = append(restartRequired, key)
+		} else {
+			applied = append(applied, key)
+		}
+	}
+
+	if req.Payload.DryRun || len(changed) == 0 {
+		c.sendResponse(req.RequestID, "update_config_response", map[string]interface{}{
+			"dry_run":          req.Payload.DryRun,
+			"changed":          changed,
+			"restart_required": restartRequired,
+			"settings":         config.RemoteSettings(updated),
+		})
+		return
+	}
+
+	if err := config.SaveConfig(updated, c.configPath); err != nil {
+		c.log.Error("保存远程修改的配置失败: %v", err)
+		c.sendUpdateConfigError(req.RequestID, "保存配置失败: "+err.Error())
+		return
+	}
+	c.log.Info("已保存远程修改的配置: %v", changed)
+
+	// 需要重启的配置项只写入文件，运行中保持原值，避免半生效的状态
+	previous := *c.cfg
+	*c.cfg = *updated
+	config.KeepRestartRequired(c.cfg, &previous)
+	c.applyConfigChanges(applied)
+
+	c.sendResponse(req.RequestID, "update_config_response", map[string]interface{}{
+		"changed":          changed,
+		"applied":          applied,
+		"restart_required": restartRequired,
+		"settings":         config.RemoteSettings(updated),
+	})
+}
+
+// applyConfigChanges 立即应用可热更新的配置项，并通知监控任务重新加载
+func (c *Client) applyConfigChanges(changed []string) {
+	for _, key := range changed {
+		if key == "log_level" {
+			// 同时取消临时日志级别，以新配置为准
+			c.resetLogLevel()
+		}
+	}
+	c.applyOpsConfig()
+
+	c.wsMutex.Lock()
+	handler := c.configUpdateHandler
+	c.wsMutex.Unlock()
+	if handler != nil {
+		handler()
+	}
+}
+
+func (c *Client) sendUpdateConfigError(requestID, message string) {
+	c.log.Warn("远程修改配置失败: %s", message)
+	c.sendResponse(requestID, "update_config_response", map[string]interface{}{
+		"error": message,
+	})
+}
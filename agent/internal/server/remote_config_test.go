@@ -0,0 +1,48 @@
+//go:build !monitor_only
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/config"
+)
+
+func TestUpdateConfigKeepsRestartRequiredValues(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "agent.yaml")
+	assert.NoError(t, os.WriteFile(configPath, []byte("log_level: error\nallow_remote_config: true\nnginx_snapshot_interval: 30m\n"), 0600))
+	cfg, err := config.LoadConfig(configPath)
+	assert.NoError(t, err)
+
+	// 复用文件扫描测试的模拟面板接收回复
+	c, received := newFileScanTestClient(t)
+	c.cfg = cfg
+	c.configPath = configPath
+	c.chunkedUploadMgr = NewChunkedUploadManager(c.log, ContainerFileRoots{})
+
+	c.handleUpdateConfig([]byte(`{"type":"update_config","request_id":"r1","payload":{"action":"update","patch":{
+		"log_level":"warn","nginx_snapshot_interval":"2h","docker_stats_interval":"5m","monitor_buffer_size":64,"agent_type":"monitor"}}}`))
+	reply := nextFileScanMessage(t, received)
+	assert.Equal(t, "update_config_response", reply.Type)
+	assert.ElementsMatch(t, []interface{}{"agent_type", "docker_stats_interval", "monitor_buffer_size", "nginx_snapshot_interval"}, reply.Data["restart_required"])
+
+	// 可热更新的配置项立即生效，需要重启的配置项运行中保持原值
+	settings := config.RemoteSettings(c.cfg)
+	assert.Equal(t, "warn", settings["log_level"])
+	assert.Equal(t, "30m0s", settings["nginx_snapshot_interval"])
+	assert.Equal(t, "1m0s", settings["docker_stats_interval"])
+	assert.Equal(t, 2880, settings["monitor_buffer_size"])
+	assert.Equal(t, "full", settings["agent_type"])
+
+	// 配置文件中保存新值，重启后生效
+	saved, err := config.LoadConfig(configPath)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Hour, saved.NginxSnapshotInterval)
+	assert.Equal(t, 5*time.Minute, saved.DockerStatsInterval)
+	assert.Equal(t, 64, saved.MonitorBufferSize)
+	assert.Equal(t, "monitor", saved.AgentType)
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// 超过大小上限时返回给面板的错误码，面板可据此提示改用分页或流式接口
+const responseTooLargeCode = "response_too_large"
+
+// responseAlternatives 各类响应超过上限时建议改用的分页或流式接口
+var responseAlternatives = map[string]string{
+	"file_content_response":  "请使用文件下载接口（GET /servers/:id/files/download）获取大文件",
+	"file_diff_response":     "请下载文件后在本地对比",
+	"docker_file_content":    "请使用容器文件下载接口（GET /servers/:id/docker/containers/:container_id/files/download）",
+	"file_list_response":     "请使用按目录逐级加载的接口（GET /servers/:id/files/children）",
+	"file_tree_response":     "请使用按目录逐级加载的接口（GET /servers/:id/files/children）",
+	"process_list_response":  "请使用按名称匹配进程的接口（POST /servers/:id/processes/kill-by-name，dry_run 预览）缩小范围",
+	"nginx_success":          "日志等大文件请使用下载接口（GET /servers/:id/nginx/logs/:log_id/download）",
+	"file_snapshot_response": "请对更小的子目录创建快照，或减小 max_entries",
+}
+
+// maxResponseBytes 单个响应序列化后的大小上限，0 表示不限制
+func (c *Client) maxResponseBytes() int {
+	if c.cfg == nil || c.cfg.MaxResponseMB <= 0 {
+		return 0
+	}
+	return c.cfg.MaxResponseMB * 1024 * 1024
+}
+
+// limitResponse 检查序列化后的响应是否超过 max_response_mb。
+// 超过时丢弃原响应，改为在同一响应类型上返回错误，避免单个请求占用大量内存和链路带宽，
+// 面板侧等待该响应的请求也能立即得到明确的失败原因
+func (c *Client) limitResponse(requestID, responseType string, data []byte) []byte {
+	limit := c.maxResponseBytes()
+	if limit == 0 || len(data) <= limit {
+		return data
+	}
+
+	c.log.Warn("响应过大，已拒绝发送: type=%s, requestID=%s, 大小=%d字节, 上限=%d字节",
+		responseType, requestID, len(data), limit)
+
+	message := fmt.Sprintf("响应过大（%.1f MB，上限 %d MB）", float64(len(data))/1024/1024, c.cfg.MaxResponseMB)
+	if alternative := responseAlternatives[responseType]; alternative != "" {
+		message += "，" + alternative
+	} else {
+		message += "，请缩小请求范围或使用分页/流式接口"
+	}
+
+	replacement, err := json.Marshal(map[string]interface{}{
+		"type":       responseType,
+		"request_id": requestID,
+		"data": map[string]interface{}{
+			"error":      message,
+			"error_code": responseTooLargeCode,
+			"size":       len(data),
+			"limit":      limit,
+		},
+	})
+	if err != nil {
+		return nil
+	}
+	return replacement
+}
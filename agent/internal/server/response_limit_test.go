@@ -0,0 +1,36 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/config"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+func TestLimitResponse(t *testing.T) {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	c := &Client{cfg: &config.Config{MaxResponseMB: 1}, log: log}
+
+	small := []byte(`{"type":"file_list_response","request_id":"r1","data":{}}`)
+	assert.Equal(t, small, c.limitResponse("r1", "file_list_response", small))
+
+	large := []byte(strings.Repeat("x", 1024*1024+1))
+	var resp struct {
+		Type      string                 `json:"type"`
+		RequestID string                 `json:"request_id"`
+		Data      map[string]interface{} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(c.limitResponse("r2", "file_content_response", large), &resp))
+	assert.Equal(t, "file_content_response", resp.Type)
+	assert.Equal(t, "r2", resp.RequestID)
+	assert.Equal(t, responseTooLargeCode, resp.Data["error_code"])
+	assert.Contains(t, resp.Data["error"], "files/download")
+
+	// 0 表示不限制
+	c.cfg.MaxResponseMB = 0
+	assert.Equal(t, large, c.limitResponse("r3", "file_content_response", large))
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"github.com/user/server-ops-agent/internal/monitor"
+)
+
+// SetSampleBuffer 设置断线期间的监控样本缓冲区，为 nil 时不缓冲
+func (c *Client) SetSampleBuffer(buffer *monitor.SampleBuffer) {
+	c.wsMutex.Lock()
+	defer c.wsMutex.Unlock()
+	c.sampleBuffer = buffer
+}
+
+// bufferMonitorSample 保存未能发送给面板的监控样本，聚焦查看的高频样本不入库，无需缓冲
+func (c *Client) bufferMonitorSample(data *monitor.MonitorData) {
+	c.wsMutex.Lock()
+	buffer := c.sampleBuffer
+	c.wsMutex.Unlock()
+
+	if buffer == nil || data.Live || data.Replay {
+		return
+	}
+	if err := buffer.Push(data); err != nil {
+		c.log.Warn("缓冲监控样本失败: %v", err)
+	}
+}
+
+// replayBufferedSamples 重新连接后按采集顺序补发断线期间缓冲的样本。
+// 重放的样本不镜像到备用面板：断线期间备用面板已经实时收到过这些样本
+func (c *Client) replayBufferedSamples() {
+	c.wsMutex.Lock()
+	buffer := c.sampleBuffer
+	c.wsMutex.Unlock()
+
+	if buffer == nil || buffer.Len() == 0 {
+		return
+	}
+
+	sent, err := buffer.Replay(func(data *monitor.MonitorData) error {
+		return c.writeJSON(struct {
+			Type    string               `json:"type"`
+			Payload *monitor.MonitorData `json:"payload"`
+		}{
+			Type:    "monitor",
+			Payload: data,
+		})
+	})
+	if err != nil {
+		c.log.Warn("补发缓冲的监控样本中断，已发送 %d 个，剩余 %d 个: %v", sent, buffer.Len(), err)
+		return
+	}
+	c.log.Info("已补发断线期间缓冲的监控样本 %d 个", sent)
+}
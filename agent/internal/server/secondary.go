@@ -0,0 +1,185 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// 备用面板连接失败后的重试间隔
+	secondaryRetryInterval = 30 * time.Second
+	// 向备用面板写入的超时时间，避免备用面板卡住时长期占用发送协程
+	secondaryWriteTimeout = 5 * time.Second
+	// 等待发往备用面板的消息数，备用面板较慢或正在重连时超出的消息直接丢弃
+	secondaryQueueSize = 32
+)
+
+// secondaryLink 到备用面板的只读镜像连接。
+// 备用面板只接收监控数据和系统信息，不执行它下发的任何命令；
+// 与主面板同时在线，用于异地容灾时让备用面板保持最新数据。
+// 消息经缓冲队列由单独的协程发送，连接、重连和写入都不会阻塞主面板的上报
+type secondaryLink struct {
+	mu          sync.Mutex
+	conn        *websocket.Conn
+	shutdown    bool
+	lastAttempt time.Time
+	systemInfo  interface{} // 最近一次的系统信息，连接建立后先补发
+	queue       chan interface{}
+	done        chan struct{}
+	dropped     int64 // 因队列已满丢弃的消息数
+
+	writeMu sync.Mutex
+}
+
+// mirrorToSecondary 把发往主面板的消息放入备用面板的发送队列后立即返回，首次调用时启动发送协程。
+// 尽力而为：队列已满时丢弃本条消息，不影响主面板的发送结果
+func (c *Client) mirrorToSecondary(msg interface{}) {
+	if c.cfg.SecondaryServerURL == "" {
+		return
+	}
+
+	s := &c.secondary
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shutdown {
+		return
+	}
+	if s.queue == nil {
+		s.queue = make(chan interface{}, secondaryQueueSize)
+		s.done = make(chan struct{})
+		go c.runSecondarySender(s.queue, s.done)
+	}
+	select {
+	case s.queue <- msg:
+	default:
+		s.dropped++
+		if s.dropped == 1 || s.dropped%100 == 0 {
+			c.log.Warn("备用面板发送队列已满，已累计丢弃 %d 条消息", s.dropped)
+		}
+	}
+}
+
+// rememberSystemInfo 记录最近的系统信息，备用面板连接（或重连）后先发送，保证备用面板上的主机信息完整
+func (c *Client) rememberSystemInfo(msg interface{}) {
+	c.secondary.mu.Lock()
+	c.secondary.systemInfo = msg
+	c.secondary.mu.Unlock()
+}
+
+// runSecondarySender 按顺序发送队列中的消息，直到 closeSecondary
+func (c *Client) runSecondarySender(queue <-chan interface{}, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case msg := <-queue:
+			c.sendToSecondary(msg)
+		}
+	}
+}
+
+// sendToSecondary 发送一条消息，未连接且到了重试时间时先连接，否则丢弃本条消息
+func (c *Client) sendToSecondary(msg interface{}) {
+	s := &c.secondary
+	s.mu.Lock()
+	conn := s.conn
+	if conn == nil {
+		if s.shutdown || time.Since(s.lastAttempt) < secondaryRetryInterval {
+			s.mu.Unlock()
+			return
+		}
+		s.lastAttempt = time.Now()
+		s.mu.Unlock()
+		if conn = c.connectSecondary(); conn == nil {
+			return
+		}
+	} else {
+		s.mu.Unlock()
+	}
+
+	if err := s.write(conn, msg); err != nil {
+		c.log.Warn("向备用面板发送数据失败: %v", err)
+		s.drop(conn)
+	}
+}
+
+// connectSecondary 连接备用面板，使用与主面板相同的服务器ID和密钥，连接后先补发系统信息。
+// 在发送协程中执行，保证系统信息先于队列中的监控数据到达；失败时返回 nil
+func (c *Client) connectSecondary() *websocket.Conn {
+	s := &c.secondary
+	conn, url, err := c.dialAgentWebSocket(c.cfg.SecondaryServerURL)
+	if err != nil {
+		c.log.Warn("连接备用面板失败，%s 后重试: %v", secondaryRetryInterval, err)
+		return nil
+	}
+
+	s.mu.Lock()
+	if s.shutdown {
+		s.mu.Unlock()
+		conn.Close()
+		return nil
+	}
+	s.conn = conn
+	systemInfo := s.systemInfo
+	s.mu.Unlock()
+
+	c.log.Info("已连接备用面板: %s", url)
+	go c.readSecondary(conn)
+
+	if systemInfo != nil {
+		if err := s.write(conn, systemInfo); err != nil {
+			c.log.Warn("向备用面板发送系统信息失败: %v", err)
+			s.drop(conn)
+			return nil
+		}
+	}
+	return conn
+}
+
+// readSecondary 读取并丢弃备用面板下发的消息（只读镜像不执行命令），连接断开时清理
+func (c *Client) readSecondary(conn *websocket.Conn) {
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			c.log.Debug("备用面板连接已断开: %v", err)
+			c.secondary.drop(conn)
+			return
+		}
+		c.log.Debug("忽略备用面板下发的消息 (%d 字节)", len(message))
+	}
+}
+
+// closeSecondary 关闭备用面板连接并停止重连
+func (c *Client) closeSecondary() {
+	s := &c.secondary
+	s.mu.Lock()
+	if !s.shutdown && s.done != nil {
+		close(s.done)
+	}
+	s.shutdown = true
+	conn := s.conn
+	s.conn = nil
+	s.mu.Unlock()
+	if conn != nil {
+		conn.Close()
+	}
+}
+
+func (s *secondaryLink) write(conn *websocket.Conn, msg interface{}) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(secondaryWriteTimeout))
+	return conn.WriteJSON(msg)
+}
+
+// drop 关闭出错的连接，只清理仍是当前连接的情况，避免误关新建立的连接
+func (s *secondaryLink) drop(conn *websocket.Conn) {
+	s.mu.Lock()
+	if s.conn == conn {
+		s.conn = nil
+	}
+	s.mu.Unlock()
+	conn.Close()
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-agent/config"
+	"github.com/user/server-ops-agent/pkg/logger"
+)
+
+// fakeSecondaryPanel 模拟备用面板：记录每个连接收到的消息类型
+type fakeSecondaryPanel struct {
+	server   *httptest.Server
+	conns    chan *websocket.Conn
+	received chan string
+}
+
+func newFakeSecondaryPanel(t *testing.T) *fakeSecondaryPanel {
+	p := &fakeSecondaryPanel{conns: make(chan *websocket.Conn, 4), received: make(chan string, 64)}
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/servers/1/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		p.conns <- conn
+		for {
+			var msg struct {
+				Type string `json:"type"`
+			}
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			p.received <- msg.Type
+		}
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *fakeSecondaryPanel) next(t *testing.T) string {
+	select {
+	case msgType := <-p.received:
+		return msgType
+	case <-time.After(5 * time.Second):
+		t.Fatal("备用面板未收到消息")
+		return ""
+	}
+}
+
+func newSecondaryTestClient(t *testing.T, url string) *Client {
+	log, err := logger.New("", "error")
+	assert.NoError(t, err)
+	c := &Client{cfg: &config.Config{ServerID: 1, SecondaryServerURL: url}, log: log, secretKey: "secret"}
+	t.Cleanup(c.closeSecondary)
+	return c
+}
+
+func TestSecondaryReplaysSystemInfoAndReconnects(t *testing.T) {
+	panel := newFakeSecondaryPanel(t)
+	c := newSecondaryTestClient(t, panel.server.URL)
+
+	// 连接建立后先补发最近的系统信息，再发送队列中的消息
+	c.rememberSystemInfo(map[string]string{"type": "system_info"})
+	c.mirrorToSecondary(map[string]string{"type": "monitor"})
+	assert.Equal(t, "system_info", panel.next(t))
+	assert.Equal(t, "monitor", panel.next(t))
+
+	// 备用面板断开后清理连接，到了重试时间再次连接并重新补发系统信息
+	first := <-panel.conns
+	first.Close()
+	assert.Eventually(t, func() bool {
+		c.secondary.mu.Lock()
+		defer c.secondary.mu.Unlock()
+		return c.secondary.conn == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// 未到重试时间的消息直接丢弃，不尝试连接（发送协程空闲，这里直接同步发送）
+	c.sendToSecondary(map[string]string{"type": "dropped"})
+	assert.Empty(t, panel.conns)
+	c.secondary.mu.Lock()
+	c.secondary.lastAttempt = time.Now().Add(-secondaryRetryInterval)
+	c.secondary.mu.Unlock()
+	c.mirrorToSecondary(map[string]string{"type": "monitor"})
+	assert.Equal(t, "system_info", panel.next(t))
+	assert.Equal(t, "monitor", panel.next(t))
+	assert.Len(t, panel.conns, 1)
+}
+
+func TestSecondaryDropsWhenQueueFull(t *testing.T) {
+	c := newSecondaryTestClient(t, "http://127.0.0.1:1")
+	// 不启动发送协程，模拟备用面板卡住时队列被占满
+	c.secondary.queue = make(chan interface{}, 1)
+	c.secondary.done = make(chan struct{})
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			c.mirrorToSecondary(map[string]string{"type": "monitor"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("队列已满时镜像不应阻塞")
+	}
+	assert.Len(t, c.secondary.queue, 1)
+	assert.Equal(t, int64(2), c.secondary.dropped)
+
+	// 关闭后不再接收消息
+	c.closeSecondary()
+	<-c.secondary.queue
+	c.mirrorToSecondary(map[string]string{"type": "monitor"})
+	assert.Empty(t, c.secondary.queue)
+
+	// 未配置备用面板时不创建队列
+	c = newSecondaryTestClient(t, "")
+	c.mirrorToSecondary(map[string]string{"type": "monitor"})
+	assert.Nil(t, c.secondary.queue)
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/user/server-ops-agent/pkg/certpin"
+)
+
+// BackendTLSConfig 返回连接面板（含备用面板）使用的 TLS 配置，未配置证书指纹时为 nil。
+// 指纹已在加载配置时校验；这里仍然出错时拒绝所有 TLS 连接，而不是退回系统信任库
+func BackendTLSConfig(pins []string) *tls.Config {
+	tlsConfig, err := certpin.TLSConfig(pins)
+	if err != nil {
+		return &tls.Config{
+			VerifyConnection: func(tls.ConnectionState) error { return err },
+		}
+	}
+	return tlsConfig
+}
+
+// newBackendHTTPClient 创建访问面板 HTTP 接口的客户端
+func newBackendHTTPClient(tlsConfig *tls.Config) *http.Client {
+	client := &http.Client{Timeout: 10 * time.Second}
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		client.Transport = transport
+	}
+	return client
+}
+
+// wsDialer 返回连接面板 WebSocket 的拨号器，除 TLS 配置外与 websocket.DefaultDialer 相同
+func (c *Client) wsDialer() *websocket.Dialer {
+	if c.tlsConfig == nil {
+		return websocket.DefaultDialer
+	}
+	return &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 45 * time.Second,
+		TLSClientConfig:  c.tlsConfig,
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"path/filepath"
 	"syscall"
 	"time"
+
+	"github.com/user/server-ops-agent/internal/backups"
 )
 
 func applyAndRestart(_ context.Context, req UpgradeRequest, exePath, newBinaryPath string, report ProgressFunc) error {
@@ -20,7 +22,9 @@ func applyAndRestart(_ context.Context, req UpgradeRequest, exePath, newBinaryPa
 	// 备份旧二进制（best-effort，不影响主流程）
 	backupPath := exePath + ".old"
 	_ = os.Remove(backupPath)
-	_ = tryHardlinkOrCopy(exePath, backupPath)
+	if tryHardlinkOrCopy(exePath, backupPath) == nil {
+		backups.Track(backupPath)
+	}
 
 	// 原子替换：同目录 rename 覆盖旧文件（Unix 下是原子操作）
 	if err := os.Rename(newBinaryPath, exePath); err != nil {
@@ -46,6 +50,10 @@ func applyAndRestart(_ context.Context, req UpgradeRequest, exePath, newBinaryPa
 		env = os.Environ()
 	}
 
+	if req.BeforeRestart != nil {
+		req.BeforeRestart()
+	}
+
 	// 使用 syscall.Exec 替换当前进程
 	return syscall.Exec(exePath, argv, env)
 }
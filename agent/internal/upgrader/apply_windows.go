@@ -13,6 +13,8 @@ import (
 	"strings"
 	"syscall"
 	"time"
+
+	"github.com/user/server-ops-agent/internal/backups"
 )
 
 func applyAndRestart(_ context.Context, req UpgradeRequest, exePath, newBinaryPath string, report ProgressFunc) error {
@@ -69,6 +71,12 @@ func applyAndRestart(_ context.Context, req UpgradeRequest, exePath, newBinaryPa
 		_ = os.Remove(scriptPath)
 		return fmt.Errorf("start updater script: %w", err)
 	}
+	// 旧二进制由 updater 在当前进程退出后备份为 .old，提前登记以便新进程按期清理
+	backups.Track(exePath + ".old")
+
+	if req.BeforeRestart != nil {
+		req.BeforeRestart()
+	}
 
 	// 当前进程退出，由 updater 负责替换/启动新进程
 	os.Exit(0)
@@ -39,6 +39,9 @@ type UpgradeRequest struct {
 	Env  []string
 
 	HTTPClient *http.Client
+
+	// 可选：替换完成、即将重启进程前调用，用于排空进行中的请求并正常关闭连接
+	BeforeRestart func()
 }
 
 type Progress struct {
@@ -0,0 +1,65 @@
+package certpin
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Fingerprint 计算证书 DER 编码的 SHA-256 指纹（小写十六进制，无分隔符），
+// 与 openssl x509 -noout -fingerprint -sha256 的结果去掉冒号后一致
+func Fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// Normalize 将 "AB:CD:..." 或 "sha256:abcd..." 形式的指纹统一为小写十六进制
+func Normalize(fingerprint string) (string, error) {
+	fp := strings.ToLower(strings.TrimSpace(fingerprint))
+	fp = strings.TrimPrefix(fp, "sha256:")
+	fp = strings.NewReplacer(":", "", " ", "").Replace(fp)
+	if len(fp) != sha256.Size*2 {
+		return "", fmt.Errorf("证书指纹应为 SHA-256（64 位十六进制）: %q", fingerprint)
+	}
+	if _, err := hex.DecodeString(fp); err != nil {
+		return "", fmt.Errorf("证书指纹包含非十六进制字符: %q", fingerprint)
+	}
+	return fp, nil
+}
+
+// TLSConfig 返回只接受指定证书的 TLS 配置，pins 为空时返回 nil（使用系统信任库）。
+// 面板证书指纹必须在列表中，不论系统信任库是否信任该证书：
+// 被攻破的 CA 或注入了自签根证书的代理都无法冒充面板；同时也允许面板使用自签证书。
+// 只比对服务器证书本身（证书链中的第一张），对方无法通过附带中间证书绕过
+func TLSConfig(pins []string) (*tls.Config, error) {
+	if len(pins) == 0 {
+		return nil, nil
+	}
+	allowed := make(map[string]bool, len(pins))
+	for _, pin := range pins {
+		fp, err := Normalize(pin)
+		if err != nil {
+			return nil, err
+		}
+		allowed[fp] = true
+	}
+
+	return &tls.Config{
+		// 不使用系统信任库校验证书链和主机名，改由 VerifyConnection 比对指纹
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS12,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("面板未提供证书")
+			}
+			fp := Fingerprint(state.PeerCertificates[0].Raw)
+			if !allowed[fp] {
+				return fmt.Errorf("面板证书指纹 %s 不在 server_cert_fingerprints 中，已拒绝连接", fp)
+			}
+			return nil
+		},
+	}, nil
+}
@@ -0,0 +1,59 @@
+package certpin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	want := strings.Repeat("ab", 32)
+	for _, input := range []string{
+		want,
+		strings.ToUpper(want),
+		"sha256:" + want,
+		strings.TrimSuffix(strings.Repeat("AB:", 32), ":"),
+	} {
+		fp, err := Normalize(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, want, fp)
+	}
+
+	for _, input := range []string{"", "abcd", strings.Repeat("zz", 32)} {
+		_, err := Normalize(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestTLSConfigPinsServerCertificate(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	pin := Fingerprint(srv.Certificate().Raw)
+
+	get := func(pins []string) error {
+		tlsConfig, err := TLSConfig(pins)
+		assert.NoError(t, err)
+		transport := srv.Client().Transport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		resp, err := (&http.Client{Transport: transport}).Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	// 自签证书不在系统信任库中，但指纹匹配即可连接；列表中的其他指纹不影响
+	assert.NoError(t, get([]string{strings.Repeat("00", 32), strings.ToUpper(pin)}))
+
+	err := get([]string{strings.Repeat("00", 32)})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), pin)
+	}
+
+	tlsConfig, err := TLSConfig(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 
 	"gopkg.in/natefinch/lumberjack.v2"
 )
@@ -36,6 +37,23 @@ var levelNames = map[Level]string{
 	FatalLevel: "FATAL",
 }
 
+// String 返回日志级别名称
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return "UNKNOWN"
+}
+
+// ValidLevel 判断字符串是否为可识别的日志级别
+func ValidLevel(level string) bool {
+	switch strings.ToLower(level) {
+	case "debug", "info", "warn", "warning", "error", "fatal":
+		return true
+	}
+	return false
+}
+
 // ParseLevel 从字符串解析日志级别
 func ParseLevel(level string) Level {
 	switch strings.ToLower(level) {
@@ -56,7 +74,7 @@ func ParseLevel(level string) Level {
 
 // Logger 日志器结构
 type Logger struct {
-	level  Level
+	level  atomic.Int32 // 运行时可调整，使用原子操作避免并发读写
 	debug  *log.Logger
 	info   *log.Logger
 	warn   *log.Logger
@@ -94,7 +112,6 @@ func New(logFile string, level string) (*Logger, error) {
 
 	// 创建日志器
 	logger := &Logger{
-		level:  ParseLevel(level),
 		debug:  log.New(output, "DEBUG: ", log.Ldate|log.Ltime|log.Lmicroseconds|log.Lshortfile),
 		info:   log.New(output, "INFO: ", log.Ldate|log.Ltime|log.Lmicroseconds),
 		warn:   log.New(output, "WARN: ", log.Ldate|log.Ltime|log.Lmicroseconds),
@@ -102,10 +119,21 @@ func New(logFile string, level string) (*Logger, error) {
 		fatal:  log.New(output, "FATAL: ", log.Ldate|log.Ltime|log.Lmicroseconds|log.Lshortfile),
 		writer: lj,
 	}
+	logger.SetLevel(ParseLevel(level))
 
 	return logger, nil
 }
 
+// SetLevel 在运行时调整日志级别
+func (l *Logger) SetLevel(level Level) {
+	l.level.Store(int32(level))
+}
+
+// GetLevel 返回当前日志级别
+func (l *Logger) GetLevel() Level {
+	return Level(l.level.Load())
+}
+
 // Close 关闭日志文件
 func (l *Logger) Close() {
 	if l.writer != nil {
@@ -115,35 +143,35 @@ func (l *Logger) Close() {
 
 // Debug 输出调试级别日志
 func (l *Logger) Debug(format string, v ...interface{}) {
-	if l.level <= DebugLevel {
+	if l.GetLevel() <= DebugLevel {
 		l.debug.Printf(format, v...)
 	}
 }
 
 // Info 输出信息级别日志
 func (l *Logger) Info(format string, v ...interface{}) {
-	if l.level <= InfoLevel {
+	if l.GetLevel() <= InfoLevel {
 		l.info.Printf(format, v...)
 	}
 }
 
 // Warn 输出警告级别日志
 func (l *Logger) Warn(format string, v ...interface{}) {
-	if l.level <= WarnLevel {
+	if l.GetLevel() <= WarnLevel {
 		l.warn.Printf(format, v...)
 	}
 }
 
 // Error 输出错误级别日志
 func (l *Logger) Error(format string, v ...interface{}) {
-	if l.level <= ErrorLevel {
+	if l.GetLevel() <= ErrorLevel {
 		l.error.Printf(format, v...)
 	}
 }
 
 // Fatal 输出致命错误级别日志
 func (l *Logger) Fatal(format string, v ...interface{}) {
-	if l.level <= FatalLevel {
+	if l.GetLevel() <= FatalLevel {
 		l.fatal.Printf(format, v...)
 		os.Exit(1)
 	}
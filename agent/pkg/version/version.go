@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -103,3 +104,54 @@ func GetVersionString() string {
 	return fmt.Sprintf("Better-Monitor Agent v%s (commit: %s, %s/%s)",
 		Version, Commit, runtime.GOOS, runtime.GOARCH)
 }
+
+// Normalize 去除版本号首尾空白和 v 前缀
+func Normalize(v string) string {
+	return strings.TrimPrefix(strings.TrimSpace(v), "v")
+}
+
+// Compare 比较两个语义化版本号的数字部分（忽略 -beta 等后缀）。
+// 返回 -1/0/1 表示 a 小于/等于/大于 b；任一版本无法解析（如 dev 构建）时 ok 为 false。
+func Compare(a, b string) (result int, ok bool) {
+	pa, okA := parseNumericVersion(a)
+	pb, okB := parseNumericVersion(b)
+	if !okA || !okB {
+		return 0, false
+	}
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, true
+}
+
+func parseNumericVersion(v string) ([]int, bool) {
+	v = Normalize(v)
+	if idx := strings.IndexAny(v, "-+"); idx >= 0 {
+		v = v[:idx]
+	}
+	if v == "" {
+		return nil, false
+	}
+	parts := strings.Split(v, ".")
+	nums := make([]int, 0, len(parts))
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		nums = append(nums, n)
+	}
+	return nums, true
+}
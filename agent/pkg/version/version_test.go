@@ -0,0 +1,26 @@
+package version
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b   string
+		want   int
+		wantOK bool
+	}{
+		{"1.2.3", "1.2.3", 0, true},
+		{"v1.2.3", "1.2.3", 0, true},
+		{"1.2.10", "1.2.9", 1, true},
+		{"1.2", "1.2.1", -1, true},
+		{"2.0.0-beta.1", "1.9.9", 1, true},
+		{"dev", "1.0.0", 0, false},
+		{"dev-abc123", "dev", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Compare(tt.a, tt.b)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Compare(%q, %q) = %d, %t; want %d, %t", tt.a, tt.b, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/base64"
 	"log"
 	"os"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -18,6 +20,32 @@ type Config struct {
 	DBPath          string
 	JWTSecret       string
 	TokenExpiration int
+
+	// 监控订阅连接空闲超时：超过该时间既没有客户端消息也没有pong响应则断开，0表示不限制
+	MonitorIdleTimeout time.Duration
+
+	// 发往Agent的请求调度：单个Agent的最大并发请求数、全局最大并发请求数、排队等待超时
+	AgentMaxConcurrency  int
+	AgentGlobalMaxActive int
+	AgentQueueTimeout    time.Duration
+
+	// 监控数据批量写入：批量大小(<=1 表示逐条写入)和刷新间隔
+	MonitorBatchSize     int
+	MonitorFlushInterval time.Duration
+
+	// GitHub Release API：失败后的重试次数和单次请求超时
+	ReleaseAPIRetries int
+	ReleaseAPITimeout time.Duration
+
+	// 文件列表/目录树响应的缓存时间，0 表示不缓存
+	FileListCacheTTL time.Duration
+
+	// 检测到同一服务器ID被多台机器上的 Agent 使用时，拒绝后来的连接（默认只记录日志并告警）
+	AgentDuplicateReject bool
+
+	// 加密保存镜像仓库凭据等敏感数据的密钥；未设置时使用 CredentialKeyFile 中的随机密钥（不存在则自动生成）
+	CredentialKey     string
+	CredentialKeyFile string
 }
 
 var (
@@ -54,11 +82,85 @@ func LoadConfig() *Config {
 			log.Printf("警告: 使用随机生成的JWT密钥，重启后所有token将失效")
 		}
 
+		// 监控订阅空闲超时，默认较宽松（10分钟）
+		monitorIdleTimeout, err := time.ParseDuration(getEnv("MONITOR_IDLE_TIMEOUT", "10m"))
+		if err != nil || monitorIdleTimeout < 0 {
+			log.Printf("MONITOR_IDLE_TIMEOUT 配置无效，使用默认值10m")
+			monitorIdleTimeout = 10 * time.Minute
+		}
+
+		// Agent请求公平调度参数
+		agentMaxConcurrency := getEnvInt("AGENT_MAX_CONCURRENCY", 4)
+		agentGlobalMaxActive := getEnvInt("AGENT_GLOBAL_MAX_ACTIVE", 64)
+		agentQueueTimeout, err := time.ParseDuration(getEnv("AGENT_QUEUE_TIMEOUT", "30s"))
+		if err != nil || agentQueueTimeout <= 0 {
+			log.Printf("AGENT_QUEUE_TIMEOUT 配置无效，使用默认值30s")
+			agentQueueTimeout = 30 * time.Second
+		}
+
+		// 监控数据批量写入参数，默认逐条写入
+		monitorBatchSize := getEnvInt("MONITOR_BATCH_SIZE", 1)
+		monitorFlushInterval, err := time.ParseDuration(getEnv("MONITOR_FLUSH_INTERVAL", "2s"))
+		if err != nil || monitorFlushInterval <= 0 {
+			log.Printf("MONITOR_FLUSH_INTERVAL 配置无效，使用默认值2s")
+			monitorFlushInterval = 2 * time.Second
+		}
+
+		// GitHub Release API 重试参数，0 表示不重试
+		releaseAPIRetries := 2
+		if v := os.Getenv("RELEASE_API_RETRIES"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+				releaseAPIRetries = n
+			} else {
+				log.Printf("RELEASE_API_RETRIES 配置无效，使用默认值2")
+			}
+		}
+		releaseAPITimeout, err := time.ParseDuration(getEnv("RELEASE_API_TIMEOUT", "10s"))
+		if err != nil || releaseAPITimeout <= 0 {
+			log.Printf("RELEASE_API_TIMEOUT 配置无效，使用默认值10s")
+			releaseAPITimeout = 10 * time.Second
+		}
+
+		// 文件列表缓存，默认缓存5秒，0 表示关闭
+		fileListCacheTTL, err := time.ParseDuration(getEnv("FILE_LIST_CACHE_TTL", "5s"))
+		if err != nil || fileListCacheTTL < 0 {
+			log.Printf("FILE_LIST_CACHE_TTL 配置无效，使用默认值5s")
+			fileListCacheTTL = 5 * time.Second
+		}
+
+		// 重复 Agent 处理方式，默认不拒绝连接
+		agentDuplicateReject := false
+		if v := os.Getenv("AGENT_DUPLICATE_REJECT"); v != "" {
+			if b, err := strconv.ParseBool(v); err == nil {
+				agentDuplicateReject = b
+			} else {
+				log.Printf("AGENT_DUPLICATE_REJECT 配置无效，使用默认值false")
+			}
+		}
+
 		instance = &Config{
-			Port:            port,
-			DBPath:          dbPath,
-			JWTSecret:       jwtSecret,
-			TokenExpiration: 24, // 默认24小时
+			Port:               port,
+			DBPath:             dbPath,
+			JWTSecret:          jwtSecret,
+			TokenExpiration:    24, // 默认24小时
+			MonitorIdleTimeout: monitorIdleTimeout,
+
+			AgentMaxConcurrency:  agentMaxConcurrency,
+			AgentGlobalMaxActive: agentGlobalMaxActive,
+			AgentQueueTimeout:    agentQueueTimeout,
+
+			MonitorBatchSize:     monitorBatchSize,
+			MonitorFlushInterval: monitorFlushInterval,
+
+			ReleaseAPIRetries: releaseAPIRetries,
+			ReleaseAPITimeout: releaseAPITimeout,
+
+			FileListCacheTTL: fileListCacheTTL,
+
+			AgentDuplicateReject: agentDuplicateReject,
+
+			CredentialKey:     os.Getenv("CREDENTIAL_KEY"),
+			CredentialKeyFile: getEnv("CREDENTIAL_KEY_FILE", "./data/credential.key"),
 		}
 	})
 
@@ -84,3 +186,17 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// getEnvInt 从环境变量读取正整数，缺失或无效时返回默认值
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Printf("%s 配置无效，使用默认值%d", key, defaultValue)
+		return defaultValue
+	}
+	return n
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 远程配置请求的响应通道
+var agentConfigChannels sync.Map
+
+// agentConfigRequest 修改Agent配置文件的请求参数
+type agentConfigRequest struct {
+	Patch  map[string]interface{} `json:"patch" binding:"required"` // 要修改的配置项，键与Agent配置文件一致
+	DryRun bool                   `json:"dry_run"`                  // 只校验不保存
+}
+
+// GetAgentConfig 查询Agent允许远程修改的配置项
+func GetAgentConfig(c *gin.Context) {
+	requestAgent(c, "update_config", &agentConfigChannels, map[string]interface{}{"action": "get"})
+}
+
+// UpdateAgentConfig 远程修改Agent配置文件，可热更新的配置项立即生效，
+// 其余配置项在响应的 restart_required 中列出，需重启Agent后生效
+func UpdateAgentConfig(c *gin.Context) {
+	var req agentConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Patch) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+
+	requestAgent(c, "update_config", &agentConfigChannels, map[string]interface{}{
+		"action":  "update",
+		"patch":   req.Patch,
+		"dry_run": req.DryRun,
+	})
+}
+
+// HandleAgentConfigResponse 将Agent的配置响应传递给等待中的HTTP请求
+func HandleAgentConfigResponse(requestID string, data map[string]interface{}) {
+	deliverAgentResponse(&agentConfigChannels, requestID, data)
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/config"
+	"github.com/user/server-ops-backend/models"
+	"github.com/user/server-ops-backend/services"
+)
+
+const (
+	// 统计连接切换的时间窗口，窗口内没有新的切换后自动解除疑似状态
+	duplicateAgentWindow = 5 * time.Minute
+	// 窗口内不同 Agent 之间的连接切换达到该次数时判定为疑似重复部署
+	duplicateAgentSwitches = 3
+)
+
+// agentSwitch 一次连接切换：旧连接仍然在线时，另一个 Agent 实例用同一服务器ID连了上来
+type agentSwitch struct {
+	addr string
+	at   time.Time
+}
+
+type duplicateAgentState struct {
+	active   string // 当前在线连接的实例指纹
+	switches []agentSwitch
+	notified bool // 本轮疑似状态是否已生成告警
+}
+
+// duplicateAgentTracker 检测同一服务器ID被多台机器上的 Agent 使用（如克隆了预装 Agent 的虚拟机）。
+// 两个 Agent 会不断互相顶替连接，导致监控数据错乱；正常的断线重连不会在旧连接在线时发生，
+// 同一实例的重连也不计入，因此只有持续抢占才会被判定为重复
+type duplicateAgentTracker struct {
+	mu      sync.Mutex
+	servers map[uint]*duplicateAgentState
+}
+
+var agentDuplicates = &duplicateAgentTracker{servers: make(map[uint]*duplicateAgentState)}
+
+// agentFingerprint 新版 Agent 在连接参数中携带随机实例标识，旧版 Agent 退化为按来源IP区分
+func agentFingerprint(c *gin.Context) string {
+	if instance := strings.TrimSpace(c.Query("instance")); instance != "" {
+		return instance
+	}
+	return c.ClientIP()
+}
+
+// admit 记录一次 Agent 连接。occupied 表示该服务器当前已有在线的 Agent 连接。
+// 返回本次连接是否使服务器处于疑似重复状态、窗口内的切换次数和来源地址，以及是否应拒绝本次连接
+func (t *duplicateAgentTracker) admit(serverID uint, fingerprint, addr string, occupied, reject bool, now time.Time) (suspected bool, switches int, addrs []string, refuse bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state := t.servers[serverID]
+	if state == nil {
+		state = &duplicateAgentState{}
+		t.servers[serverID] = state
+	}
+
+	kept := state.switches[:0]
+	for _, sw := range state.switches {
+		if now.Sub(sw.at) < duplicateAgentWindow {
+			kept = append(kept, sw)
+		}
+	}
+	state.switches = kept
+	if len(state.switches) == 0 {
+		state.notified = false
+	}
+
+	if occupied && state.active != "" && state.active != fingerprint {
+		state.switches = append(state.switches, agentSwitch{addr: addr, at: now})
+	}
+
+	switches = len(state.switches)
+	suspected = switches >= duplicateAgentSwitches
+	if suspected {
+		seen := make(map[string]bool)
+		for _, sw := range state.switches {
+			if !seen[sw.addr] {
+				seen[sw.addr] = true
+				addrs = append(addrs, sw.addr)
+			}
+		}
+		sort.Strings(addrs)
+	}
+
+	// 拒绝时保留当前在线的连接，不更新实例指纹
+	if suspected && reject && occupied && state.active != fingerprint {
+		return suspected, switches, addrs, true
+	}
+	state.active = fingerprint
+	return suspected, switches, addrs, false
+}
+
+// claimNotify 占用本轮疑似状态的告警，窗口内只告警一次；返回 false 表示已告警或正在告警
+func (t *duplicateAgentTracker) claimNotify(serverID uint) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state := t.servers[serverID]
+	if state == nil || state.notified {
+		return false
+	}
+	state.notified = true
+	return true
+}
+
+// releaseNotify 未达到告警阈值（或未启用告警）时释放占用，后续切换时重新判断
+func (t *duplicateAgentTracker) releaseNotify(serverID uint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if state := t.servers[serverID]; state != nil {
+		state.notified = false
+	}
+}
+
+// checkDuplicateAgent 在 Agent 连接升级为 WebSocket 前调用，返回 false 表示拒绝本次连接
+func checkDuplicateAgent(c *gin.Context, server *models.Server) bool {
+	_, occupied := ActiveAgentConnections.Load(server.ID)
+	reject := config.LoadConfig().AgentDuplicateReject
+	suspected, switches, addrs, refuse := agentDuplicates.admit(server.ID, agentFingerprint(c), c.ClientIP(), occupied, reject, time.Now())
+	if !suspected {
+		return true
+	}
+
+	log.Printf("[WARN] 服务器 %d 疑似存在重复的 Agent：%s 内连接被不同实例抢占 %d 次，来源地址: %s",
+		server.ID, duplicateAgentWindow, switches, strings.Join(addrs, ", "))
+	if agentDuplicates.claimNotify(server.ID) {
+		go func(server models.Server) {
+			if !services.GetAlertService().NotifyDuplicateAgent(server, switches, addrs) {
+				agentDuplicates.releaseNotify(server.ID)
+			}
+		}(*server)
+	}
+	if refuse {
+		log.Printf("[WARN] 拒绝服务器 %d 来自 %s 的重复 Agent 连接，保留当前在线的连接", server.ID, c.ClientIP())
+	}
+	return !refuse
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicateAgentTracker(t *testing.T) {
+	tracker := &duplicateAgentTracker{servers: make(map[uint]*duplicateAgentState)}
+	now := time.Now()
+
+	// 同一实例重连、旧连接已断开后的重连都不计入
+	suspected, switches, _, _ := tracker.admit(1, "a", "10.0.0.1", false, true, now)
+	assert.False(t, suspected)
+	_, switches, _, _ = tracker.admit(1, "a", "10.0.0.1", true, true, now)
+	assert.Equal(t, 0, switches)
+	_, switches, _, _ = tracker.admit(1, "b", "10.0.0.2", false, true, now)
+	assert.Equal(t, 0, switches)
+
+	// 两个实例在旧连接在线时互相抢占
+	tracker.admit(1, "a", "10.0.0.1", true, true, now)
+	tracker.admit(1, "b", "10.0.0.2", true, true, now.Add(time.Second))
+	suspected, switches, addrs, refuse := tracker.admit(1, "a", "10.0.0.1", true, true, now.Add(2*time.Second))
+	assert.True(t, suspected)
+	assert.Equal(t, 3, switches)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, addrs)
+	assert.True(t, refuse)
+
+	// 被拒绝后当前在线的仍是 b，b 自身重连不被拒绝
+	_, _, _, refuse = tracker.admit(1, "b", "10.0.0.2", true, true, now.Add(3*time.Second))
+	assert.False(t, refuse)
+
+	// 只告警一次，直到窗口过期
+	assert.True(t, tracker.claimNotify(1))
+	assert.False(t, tracker.claimNotify(1))
+	suspected, switches, _, _ = tracker.admit(1, "b", "10.0.0.2", true, true, now.Add(duplicateAgentWindow+5*time.Second))
+	assert.False(t, suspected)
+	assert.Equal(t, 0, switches)
+	assert.True(t, tracker.claimNotify(1))
+}
@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 错误计数请求的响应通道
+var agentErrorStatsChannels sync.Map
+
+// GetAgentErrorStats 查询Agent内部错误的分类计数（发送失败、采集失败、重连、panic）
+func GetAgentErrorStats(c *gin.Context) {
+	requestAgent(c, "agent_error_stats", &agentErrorStatsChannels, map[string]interface{}{"action": "get"})
+}
+
+// ResetAgentErrorStats 清零Agent的错误计数，返回清零后的计数
+func ResetAgentErrorStats(c *gin.Context) {
+	requestAgent(c, "agent_error_stats", &agentErrorStatsChannels, map[string]interface{}{"action": "reset"})
+}
+
+// HandleAgentErrorStatsResponse 将Agent的错误计数响应传递给等待中的HTTP请求
+func HandleAgentErrorStatsResponse(requestID string, data map[string]interface{}) {
+	deliverAgentResponse(&agentErrorStatsChannels, requestID, data)
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 日志级别请求的响应通道
+var agentLogLevelChannels sync.Map
+
+// agentLogLevelRequest 调整Agent日志级别的请求参数
+type agentLogLevelRequest struct {
+	Level    string `json:"level" binding:"required"` // 目标级别，reset 表示立即恢复为配置的级别
+	Duration int    `json:"duration"`                 // 有效期（秒），到期后Agent自动恢复为配置的级别，0 表示使用Agent默认值
+}
+
+// GetAgentLogLevel 查询Agent当前的日志级别
+func GetAgentLogLevel(c *gin.Context) {
+	sendAgentLogLevelCommand(c, map[string]interface{}{"action": "get"})
+}
+
+// SetAgentLogLevel 临时调整Agent的日志级别，无需重启
+func SetAgentLogLevel(c *gin.Context) {
+	var req agentLogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+	if req.Duration < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "有效期不能为负数"})
+		return
+	}
+
+	action := "set"
+	if req.Level == "reset" {
+		action = "reset"
+	}
+	sendAgentLogLevelCommand(c, map[string]interface{}{
+		"action":   action,
+		"level":    req.Level,
+		"duration": req.Duration,
+	})
+}
+
+// sendAgentLogLevelCommand 向Agent发送日志级别命令并等待响应
+func sendAgentLogLevelCommand(c *gin.Context, payload map[string]interface{}) {
+	requestAgent(c, "agent_log_level", &agentLogLevelChannels, payload)
+}
+
+// HandleAgentLogLevelResponse 将Agent的日志级别响应传递给等待中的HTTP请求
+func HandleAgentLogLevelResponse(requestID string, data map[string]interface{}) {
+	deliverAgentResponse(&agentLogLevelChannels, requestID, data)
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestSetAgentLogLevelForwardsDuration(t *testing.T) {
+	setupTestDB(t)
+	server := models.Server{Name: "log-level-agent", Status: "online", SecretKey: "secret"}
+	assert.NoError(t, models.DB.Create(&server).Error)
+	t.Cleanup(func() { models.DB.Unscoped().Delete(&server) })
+
+	// 模拟Agent：记录收到的命令，set 时返回带恢复时间的状态
+	payloads := make(chan map[string]interface{}, 4)
+	connectReverseAgent(t, server.ID, func(msg map[string]interface{}) map[string]interface{} {
+		if msg["type"] != "agent_log_level" {
+			return nil
+		}
+		payload, _ := msg["payload"].(map[string]interface{})
+		payloads <- payload
+		data := map[string]interface{}{"level": "INFO", "configured_level": "INFO"}
+		if payload["action"] == "set" {
+			data["level"] = strings.ToUpper(payload["level"].(string))
+			data["revert_at"] = "2026-01-01T00:10:00Z"
+		}
+		return map[string]interface{}{"type": "agent_log_level_response", "data": data}
+	})
+
+	request := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(server.ID), 10)}}
+		SetAgentLogLevel(c)
+		return w
+	}
+
+	// 有效期原样转发给Agent，由Agent到期后自动恢复，响应中带恢复时间
+	w := request(`{"level":"debug","duration":600}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"revert_at":"2026-01-01T00:10:00Z"`)
+	assert.Equal(t, map[string]interface{}{"action": "set", "level": "debug", "duration": float64(600)}, <-payloads)
+
+	// 未指定有效期时传 0，由Agent使用默认有效期
+	w = request(`{"level":"warn"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, float64(0), (<-payloads)["duration"])
+
+	// reset 立即恢复为配置的级别
+	w = request(`{"level":"reset"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "reset", (<-payloads)["action"])
+	assert.NotContains(t, w.Body.String(), "revert_at")
+
+	// 无效的参数不转发给Agent
+	assert.Equal(t, http.StatusBadRequest, request(`{"level":"debug","duration":-1}`).Code)
+	assert.Equal(t, http.StatusBadRequest, request(`{}`).Code)
+	assert.Empty(t, payloads)
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+// 心跳/重连请求的响应通道
+var agentPokeChannels sync.Map
+
+// agentPokeTimeout 等待Agent回复心跳的时间，正常的Agent会立即回复，无需等待 TimeoutSimpleQuery
+var agentPokeTimeout = 10 * time.Second
+
+// PokeAgent 要求Agent立即回复并上报一次监控数据和系统信息，用于确认疑似卡住的Agent是否存活。
+// 命令无法送达或Agent未回复时，关闭面板侧的连接，Agent下次发送失败后会自动重连
+func PokeAgent(c *gin.Context) {
+	pokeAgent(c, "heartbeat")
+}
+
+// ReconnectAgent 要求Agent关闭当前连接并重新建立，用于从卡住的连接中恢复，无需重启Agent。
+// 命令无法送达时同样关闭面板侧的连接
+func ReconnectAgent(c *gin.Context) {
+	pokeAgent(c, "reconnect")
+}
+
+func pokeAgent(c *gin.Context, action string) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+	server, err := models.GetServerByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+
+	response, status, err := callAgent(server.ID, "agent_poke", &agentPokeChannels, map[string]interface{}{
+		"action": action,
+	}, agentPokeTimeout)
+	if err != nil {
+		body := gin.H{"error": err.Error()}
+		// 发送失败或未回复说明连接已卡住，断开后由Agent重连
+		if status == http.StatusInternalServerError || status == http.StatusGatewayTimeout {
+			body["reconnect"] = dropAgentConnection(server.ID)
+		}
+		c.JSON(status, body)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// dropAgentConnection 关闭服务器当前的Agent连接，连接处理协程退出时完成离线标记和待处理请求的清理
+func dropAgentConnection(serverID uint) bool {
+	val, ok := ActiveAgentConnections.Load(serverID)
+	if !ok {
+		return false
+	}
+	conn, ok := val.(*SafeConn)
+	if !ok {
+		return false
+	}
+	log.Printf("服务器 %d 的Agent连接无响应，关闭连接等待重连", serverID)
+	// 不经过 SafeConn.Close：连接卡在写入时写锁一直被占用，直接关闭底层连接使写入立即返回
+	conn.Conn.Close()
+	return true
+}
+
+// HandleAgentPokeResponse 将Agent的心跳/重连响应传递给等待中的HTTP请求
+func HandleAgentPokeResponse(requestID string, data map[string]interface{}) {
+	deliverAgentResponse(&agentPokeChannels, requestID, data)
+}
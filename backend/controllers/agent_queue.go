@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/user/server-ops-backend/config"
+)
+
+// ErrAgentQueueTimeout 请求在调度队列中等待超时
+var ErrAgentQueueTimeout = NewError("Agent请求排队超时，请稍后重试")
+
+// agentRequestQueue 发往Agent的请求调度器。
+// 每个服务器的并发请求数受 perServer 限制，所有服务器共享 global 个并发名额；
+// 名额释放时按服务器轮询分配给排队中的请求，避免单个繁忙或缓慢的Agent
+// 占满共享容量，拖慢发往其他Agent的命令。
+type agentRequestQueue struct {
+	mu        sync.Mutex
+	perServer int
+	global    int
+	timeout   time.Duration // 默认排队等待超时
+	active    int
+	servers   map[uint]*serverRequestQueue
+	order     []uint // 轮询顺序
+	lastID    uint   // 最近一次获得名额的服务器，下一轮从它之后开始
+}
+
+// serverRequestQueue 单个服务器的调度状态
+type serverRequestQueue struct {
+	active  int
+	waiters []chan struct{}
+}
+
+// AgentQueueStats 单个服务器的排队情况
+type AgentQueueStats struct {
+	ServerID uint `json:"server_id"`
+	Active   int  `json:"active"`
+	Queued   int  `json:"queued"`
+}
+
+var (
+	agentQueue     *agentRequestQueue
+	agentQueueOnce sync.Once
+)
+
+// getAgentQueue 按配置懒加载全局调度器
+func getAgentQueue() *agentRequestQueue {
+	agentQueueOnce.Do(func() {
+		cfg := config.LoadConfig()
+		agentQueue = newAgentRequestQueue(cfg.AgentMaxConcurrency, cfg.AgentGlobalMaxActive, cfg.AgentQueueTimeout)
+	})
+	return agentQueue
+}
+
+func newAgentRequestQueue(perServer, global int, timeout time.Duration) *agentRequestQueue {
+	if perServer <= 0 {
+		perServer = 1
+	}
+	if global < perServer {
+		global = perServer
+	}
+	return &agentRequestQueue{
+		perServer: perServer,
+		global:    global,
+		timeout:   timeout,
+		servers:   make(map[uint]*serverRequestQueue),
+	}
+}
+
+// acquire 为指定服务器申请一个并发名额，最多等待 timeout
+func (q *agentRequestQueue) acquire(serverID uint, timeout time.Duration) error {
+	q.mu.Lock()
+	sq := q.server(serverID)
+	if len(sq.waiters) == 0 && sq.active < q.perServer && q.active < q.global {
+		sq.active++
+		q.active++
+		q.lastID = serverID
+		q.mu.Unlock()
+		return nil
+	}
+
+	ready := make(chan struct{})
+	sq.waiters = append(sq.waiters, ready)
+	q.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-ready:
+		return nil
+	case <-timer.C:
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// 超时与分配可能同时发生：已分配则视为成功
+	select {
+	case <-ready:
+		return nil
+	default:
+	}
+	for i, w := range sq.waiters {
+		if w == ready {
+			sq.waiters = append(sq.waiters[:i], sq.waiters[i+1:]...)
+			break
+		}
+	}
+	q.cleanup(serverID, sq)
+	return ErrAgentQueueTimeout
+}
+
+// release 归还名额并按轮询顺序唤醒其他服务器的排队请求
+func (q *agentRequestQueue) release(serverID uint) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if sq, ok := q.servers[serverID]; ok && sq.active > 0 {
+		sq.active--
+		q.active--
+	}
+	q.dispatch()
+	if sq, ok := q.servers[serverID]; ok {
+		q.cleanup(serverID, sq)
+	}
+}
+
+// dispatch 在持锁状态下按服务器轮询分配空闲名额
+func (q *agentRequestQueue) dispatch() {
+	for q.active < q.global && len(q.order) > 0 {
+		start := 0
+		for i, id := range q.order {
+			if id == q.lastID {
+				start = i + 1
+				break
+			}
+		}
+
+		granted := false
+		for i := 0; i < len(q.order); i++ {
+			id := q.order[(start+i)%len(q.order)]
+			sq := q.servers[id]
+			if len(sq.waiters) == 0 || sq.active >= q.perServer {
+				continue
+			}
+			ready := sq.waiters[0]
+			sq.waiters = sq.waiters[1:]
+			sq.active++
+			q.active++
+			q.lastID = id
+			close(ready)
+			granted = true
+			break
+		}
+		if !granted {
+			return
+		}
+	}
+}
+
+// server 获取或创建服务器的调度状态（需持锁）
+func (q *agentRequestQueue) server(serverID uint) *serverRequestQueue {
+	sq, ok := q.servers[serverID]
+	if !ok {
+		sq = &serverRequestQueue{}
+		q.servers[serverID] = sq
+		q.order = append(q.order, serverID)
+	}
+	return sq
+}
+
+// cleanup 服务器没有进行中和排队中的请求时移除其状态（需持锁）
+func (q *agentRequestQueue) cleanup(serverID uint, sq *serverRequestQueue) {
+	if sq.active > 0 || len(sq.waiters) > 0 {
+		return
+	}
+	delete(q.servers, serverID)
+	for i, id := range q.order {
+		if id == serverID {
+			q.order = append(q.order[:i], q.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// stats 返回各服务器当前的并发数和排队深度
+func (q *agentRequestQueue) stats() []AgentQueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	result := make([]AgentQueueStats, 0, len(q.servers))
+	for id, sq := range q.servers {
+		result = append(result, AgentQueueStats{ServerID: id, Active: sq.active, Queued: len(sq.waiters)})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ServerID < result[j].ServerID })
+	return result
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgentRequestQueueFairness(t *testing.T) {
+	q := newAgentRequestQueue(2, 2, time.Second)
+
+	// 服务器1占满全部名额，并继续排队两个请求
+	assert.NoError(t, q.acquire(1, time.Second))
+	assert.NoError(t, q.acquire(1, time.Second))
+
+	granted := make(chan uint, 3)
+	wait := func(serverID uint) {
+		if err := q.acquire(serverID, time.Second); err == nil {
+			granted <- serverID
+		}
+	}
+	go wait(1)
+	assert.Eventually(t, func() bool { return queuedFor(q, 1) == 1 }, time.Second, time.Millisecond)
+	go wait(2)
+	assert.Eventually(t, func() bool { return queuedFor(q, 2) == 1 }, time.Second, time.Millisecond)
+
+	// 名额释放后轮询到服务器2，而不是继续分配给排在前面的服务器1
+	q.release(1)
+	assert.Equal(t, uint(2), <-granted)
+	q.release(1)
+	assert.Equal(t, uint(1), <-granted)
+
+	// 名额被占满时排队超时返回错误
+	assert.ErrorIs(t, q.acquire(3, 20*time.Millisecond), ErrAgentQueueTimeout)
+	assert.Equal(t, 0, queuedFor(q, 3))
+}
+
+func queuedFor(q *agentRequestQueue, serverID uint) int {
+	for _, s := range q.stats() {
+		if s.ServerID == serverID {
+			return s.Queued
+		}
+	}
+	return 0
+}
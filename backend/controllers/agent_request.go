@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/user/server-ops-backend/models"
+)
+
+// requestAgent 向Agent发送一条请求并等待响应，
+// Agent的响应经 deliverAgentResponse 按请求ID投递到 channels 中登记的通道
+func requestAgent(c *gin.Context, msgType string, channels *sync.Map, payload map[string]interface{}) {
+	requestAgentWithTimeout(c, msgType, channels, payload, TimeoutSimpleQuery)
+}
+
+// requestAgentWithTimeout 与 requestAgent 相同，但可指定等待响应的超时时间，用于耗时较长的操作
+func requestAgentWithTimeout(c *gin.Context, msgType string, channels *sync.Map, payload map[string]interface{}, timeout time.Duration) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	server, err := models.GetServerByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+
+	response, status, err := callAgent(server.ID, msgType, channels, payload, timeout)
+	if err != nil {
+		body := gin.H{"error": err.Error()}
+		if status == http.StatusRequestEntityTooLarge {
+			body["error_code"] = "response_too_large"
+		}
+		c.JSON(status, body)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// callAgent 向Agent发送一条请求并等待响应，供需要在返回前处理响应（如保存到数据库）的接口使用。
+// 出错时返回应答给前端的HTTP状态码；Agent响应中的 error 字段同样视为错误
+func callAgent(serverID uint, msgType string, channels *sync.Map, payload map[string]interface{}, timeout time.Duration) (map[string]interface{}, int, error) {
+	agentConnVal, ok := ActiveAgentConnections.Load(serverID)
+	if !ok {
+		return nil, http.StatusServiceUnavailable, errors.New("服务器Agent未连接")
+	}
+	agentConn, ok := agentConnVal.(*SafeConn)
+	if !ok {
+		return nil, http.StatusInternalServerError, errors.New("服务器连接类型错误")
+	}
+
+	requestID := uuid.New().String()
+	responseChan := make(chan map[string]interface{}, 1)
+	channels.Store(requestID, responseChan)
+	defer channels.Delete(requestID)
+
+	message := map[string]interface{}{
+		"type":       msgType,
+		"request_id": requestID,
+		"payload":    payload,
+	}
+	if err := agentConn.WriteJSON(message); err != nil {
+		return nil, http.StatusInternalServerError, errors.New("发送请求到Agent失败")
+	}
+
+	select {
+	case response := <-responseChan:
+		if errMsg, ok := response["error"].(string); ok && errMsg != "" {
+			// Agent 因响应超过 max_response_mb 而拒绝发送，提示内容中包含可改用的接口
+			if code, _ := response["error_code"].(string); code == "response_too_large" {
+				return nil, http.StatusRequestEntityTooLarge, errors.New(errMsg)
+			}
+			return nil, http.StatusBadRequest, errors.New(errMsg)
+		}
+		return response, http.StatusOK, nil
+	case <-time.After(timeout):
+		return nil, http.StatusGatewayTimeout, errors.New("等待Agent响应超时，旧版本Agent可能不支持此功能")
+	}
+}
+
+// deliverAgentResponse 将Agent的响应传递给等待中的HTTP请求
+func deliverAgentResponse(channels *sync.Map, requestID string, data map[string]interface{}) {
+	chanVal, ok := channels.Load(requestID)
+	if !ok {
+		log.Printf("找不到请求ID: %s 的响应通道", requestID)
+		return
+	}
+	responseChan, ok := chanVal.(chan map[string]interface{})
+	if !ok {
+		return
+	}
+	select {
+	case responseChan <- data:
+	default:
+	}
+}
@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+	"github.com/user/server-ops-backend/utils"
+)
+
+// connectReverseAgent 模拟位于NAT之后的Agent：它只主动拨出到面板，自身不监听任何端口。
+// 面板一侧把连接登记到 ActiveAgentConnections，并把Agent的响应交给对应的处理函数。
+func connectReverseAgent(t *testing.T, serverID uint, respond func(msg map[string]interface{}) map[string]interface{}) {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+	registered := make(chan struct{})
+	panel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		safeConn := &SafeConn{Conn: conn}
+		ActiveAgentConnections.Store(serverID, safeConn)
+		close(registered)
+
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var resp struct {
+				Type      string                 `json:"type"`
+				RequestID string                 `json:"request_id"`
+				Data      map[string]interface{} `json:"data"`
+			}
+			if json.Unmarshal(message, &resp) != nil {
+				continue
+			}
+			switch resp.Type {
+			case "agent_log_level_response":
+				HandleAgentLogLevelResponse(resp.RequestID, resp.Data)
+			case TypeProcessResponse:
+				HandleProcessResponse(resp.RequestID, resp.Data)
+			case "agent_poke_response":
+				HandleAgentPokeResponse(resp.RequestID, resp.Data)
+			case "service_command_response":
+				HandleServiceCommandResponse(resp.RequestID, resp.Data)
+			case "package_command_response":
+				HandlePackageCommandResponse(resp.RequestID, resp.Data)
+			case "network_benchmark_response":
+				HandleNetworkBenchmarkResponse(resp.RequestID, resp.Data)
+			case "chunked_download_chunk_ack", "file_archive_ack", "file_content_response":
+				HandleFileResponse(resp.RequestID, map[string]interface{}{"type": resp.Type, "data": resp.Data})
+			case TypeError:
+				HandleFileError(resp.RequestID, resp.Data)
+			default:
+				_ = utils.HandleAgentResponse(message)
+			}
+		}
+	}))
+	t.Cleanup(panel.Close)
+
+	agentConn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(panel.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Agent连接面板失败: %v", err)
+	}
+	t.Cleanup(func() {
+		agentConn.Close()
+		ActiveAgentConnections.Delete(serverID)
+	})
+	<-registered
+
+	go func() {
+		for {
+			var msg map[string]interface{}
+			if err := agentConn.ReadJSON(&msg); err != nil {
+				return
+			}
+			if reply := respond(msg); reply != nil {
+				reply["request_id"] = msg["request_id"]
+				_ = agentConn.WriteJSON(reply)
+			}
+		}
+	}()
+}
+
+func TestSendCommandToAgentOverReverseConnection(t *testing.T) {
+	connectReverseAgent(t, 501, func(msg map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"type": "nginx_success",
+			"data": map[string]interface{}{"received": msg["type"]},
+		}
+	})
+
+	resp, err := utils.SendCommandToAgent(501, "secret", map[string]interface{}{"type": "nginx_command"})
+	assert.NoError(t, err)
+	assert.Contains(t, resp, "nginx_command")
+}
+
+func TestSendCommandToAgentWithoutConnection(t *testing.T) {
+	// Agent没有建立反向连接时直接失败，不会尝试主动连接Agent
+	start := time.Now()
+	_, err := utils.SendCommandToAgent(502, "secret", map[string]interface{}{"type": "nginx_command"})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestHTTPHandlersReachAgentOverReverseConnection(t *testing.T) {
+	setupTestDB(t)
+	server := models.Server{Name: "nat-agent", Status: "online", SecretKey: "secret"}
+	assert.NoError(t, models.DB.Create(&server).Error)
+	t.Cleanup(func() { models.DB.Unscoped().Delete(&server) })
+
+	connectReverseAgent(t, server.ID, func(msg map[string]interface{}) map[string]interface{} {
+		switch msg["type"] {
+		case "agent_log_level":
+			return map[string]interface{}{
+				"type": "agent_log_level_response",
+				"data": map[string]interface{}{"level": "DEBUG"},
+			}
+		case "process_list":
+			return map[string]interface{}{
+				"type": TypeProcessResponse,
+				"data": map[string]interface{}{"processes": []interface{}{}},
+			}
+		}
+		return nil
+	})
+
+	for _, handler := range []gin.HandlerFunc{GetAgentLogLevel, GetProcesses} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(server.ID), 10)}}
+
+		handler(c)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+}
+
+func TestPokeAgentOverReverseConnection(t *testing.T) {
+	setupTestDB(t)
+	server := models.Server{Name: "poke-agent", Status: "online", SecretKey: "secret"}
+	assert.NoError(t, models.DB.Create(&server).Error)
+	t.Cleanup(func() { models.DB.Unscoped().Delete(&server) })
+
+	connectReverseAgent(t, server.ID, func(msg map[string]interface{}) map[string]interface{} {
+		if msg["type"] != "agent_poke" {
+			return nil
+		}
+		return map[string]interface{}{
+			"type": "agent_poke_response",
+			"data": map[string]interface{}{"action": "heartbeat"},
+		}
+	})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(server.ID), 10)}}
+
+	PokeAgent(c)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "heartbeat")
+}
+
+func TestPokeAgentDropsUnresponsiveConnection(t *testing.T) {
+	setupTestDB(t)
+	server := models.Server{Name: "stuck-agent", Status: "online", SecretKey: "secret"}
+	assert.NoError(t, models.DB.Create(&server).Error)
+	t.Cleanup(func() { models.DB.Unscoped().Delete(&server) })
+
+	original := agentPokeTimeout
+	agentPokeTimeout = 200 * time.Millisecond
+	t.Cleanup(func() { agentPokeTimeout = original })
+
+	// Agent 收到命令但从不回复，模拟卡住的连接
+	connectReverseAgent(t, server.ID, func(msg map[string]interface{}) map[string]interface{} {
+		return nil
+	})
+	val, _ := ActiveAgentConnections.Load(server.ID)
+	conn := val.(*SafeConn)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(server.ID), 10)}}
+
+	PokeAgent(c)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"reconnect":true`)
+
+	// 面板侧连接已关闭，之后的写入失败
+	assert.Error(t, conn.WriteJSON(map[string]interface{}{"type": "ping"}))
+}
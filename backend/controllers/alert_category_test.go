@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestAlertRecordsFilterByCategory(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.AlertRecord{}, &models.Incident{}, &models.IncidentEvent{}))
+	db.Exec("DELETE FROM alert_records")
+	db.Exec("DELETE FROM incidents")
+
+	for _, alertType := range []string{"cpu", "memory", "status", "duplicate", "oom"} {
+		assert.NoError(t, models.CreateAlertRecord(&models.AlertRecord{ServerID: 1, AlertType: alertType}))
+	}
+	// 升级前的记录没有分类，启动时按类型回填
+	db.Exec("UPDATE alert_records SET category = '' WHERE alert_type = 'cpu'")
+	assert.NoError(t, models.BackfillAlertRecordCategories())
+
+	list := func(query string) (int, []models.AlertRecord) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/alerts/records?"+query, nil)
+		GetAlertRecords(c)
+		var resp struct {
+			Records []models.AlertRecord `json:"records"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Records
+	}
+
+	code, records := list("category=resource")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, records, 2)
+	for _, r := range records {
+		assert.Equal(t, models.AlertCategoryResource, r.Category)
+	}
+
+	code, records = list("category=security")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "duplicate", records[0].AlertType)
+	}
+
+	code, _ = list("category=unknown")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestNotificationChannelCategories(t *testing.T) {
+	normalized, err := normalizeAlertCategories(" certificate, resource,,certificate ")
+	assert.NoError(t, err)
+	assert.Equal(t, "certificate,resource", normalized)
+
+	_, err = normalizeAlertCategories("resource,cpu")
+	assert.Error(t, err)
+
+	platform := models.NotificationChannel{Categories: normalized}
+	assert.True(t, platform.AcceptsCategory(models.AlertCategoryCertificate))
+	assert.False(t, platform.AcceptsCategory(models.AlertCategoryAvailability))
+
+	// 未配置分类的渠道接收全部预警
+	onCall := models.NotificationChannel{}
+	assert.True(t, onCall.AcceptsCategory(models.AlertCategorySecurity))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/alerts/channels",
+		strings.NewReader(`{"type":"serverchan","name":"x","config":"{\"sendkey\":\"k\"}","categories":"bogus"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	CreateNotificationChannel(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -2,8 +2,10 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -48,8 +50,8 @@ func CreateAlertSetting(c *gin.Context) {
 		return
 	}
 
-	if setting.Type != "cpu" && setting.Type != "memory" && setting.Type != "network" && setting.Type != "status" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "预警类型必须是cpu、memory、network或status"})
+	if setting.Type != "cpu" && setting.Type != "memory" && setting.Type != "network" && setting.Type != "status" && setting.Type != "zombie" && setting.Type != "oom" && setting.Type != "duplicate" && setting.Type != "disk_health" && setting.Type != "agent_error" && setting.Type != "temperature" && setting.Type != "cert_expiry" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "预警类型必须是cpu、memory、network、status、zombie、oom、duplicate、disk_health、agent_error、temperature或cert_expiry"})
 		return
 	}
 
@@ -63,7 +65,20 @@ func CreateAlertSetting(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "持续时间不能为负数"})
 			return
 		}
+		setting.Smoothing = 0
+	} else if setting.Type == "oom" || setting.Type == "duplicate" || setting.Type == "disk_health" || setting.Type == "cert_expiry" {
+		// OOM、重复 Agent、磁盘故障预测和证书到期是一次性事件，发生即通知，持续时间无意义；证书到期的阈值为天数
+		if setting.Threshold <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "阈值必须大于0"})
+			return
+		}
+		setting.Duration = 0
+		setting.Smoothing = 0
 	} else {
+		if setting.Smoothing < 0 || setting.Smoothing > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "平滑系数必须在0到1之间"})
+			return
+		}
 		if setting.Threshold <= 0 {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "阈值必须大于0"})
 			return
@@ -74,6 +89,13 @@ func CreateAlertSetting(c *gin.Context) {
 		}
 	}
 
+	channelIDs, err := normalizeChannelIDs(setting.ChannelIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	setting.ChannelIDs = channelIDs
+
 	if err := models.CreateAlertSetting(&setting); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建预警设置失败"})
 		return
@@ -113,8 +135,8 @@ func UpdateAlertSetting(c *gin.Context) {
 	setting.Type = oldType         // 不允许修改预警类型
 	setting.ServerID = oldServerID // 不允许修改服务器ID
 
-	if setting.Type != "cpu" && setting.Type != "memory" && setting.Type != "network" && setting.Type != "status" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "预警类型必须是cpu、memory、network或status"})
+	if setting.Type != "cpu" && setting.Type != "memory" && setting.Type != "network" && setting.Type != "status" && setting.Type != "zombie" && setting.Type != "oom" && setting.Type != "duplicate" && setting.Type != "disk_health" && setting.Type != "agent_error" && setting.Type != "temperature" && setting.Type != "cert_expiry" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "预警类型必须是cpu、memory、network、status、zombie、oom、duplicate、disk_health、agent_error、temperature或cert_expiry"})
 		return
 	}
 
@@ -128,7 +150,20 @@ func UpdateAlertSetting(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "持续时间不能为负数"})
 			return
 		}
+		setting.Smoothing = 0
+	} else if setting.Type == "oom" || setting.Type == "duplicate" || setting.Type == "disk_health" || setting.Type == "cert_expiry" {
+		// OOM、重复 Agent、磁盘故障预测和证书到期是一次性事件，发生即通知，持续时间无意义；证书到期的阈值为天数
+		if setting.Threshold <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "阈值必须大于0"})
+			return
+		}
+		setting.Duration = 0
+		setting.Smoothing = 0
 	} else {
+		if setting.Smoothing < 0 || setting.Smoothing > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "平滑系数必须在0到1之间"})
+			return
+		}
 		if setting.Threshold <= 0 {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "阈值必须大于0"})
 			return
@@ -139,6 +174,13 @@ func UpdateAlertSetting(c *gin.Context) {
 		}
 	}
 
+	channelIDs, err := normalizeChannelIDs(setting.ChannelIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	setting.ChannelIDs = channelIDs
+
 	if err := models.UpdateAlertSetting(&setting); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新预警设置失败"})
 		return
@@ -190,13 +232,8 @@ func GetNotificationChannels(c *gin.Context) {
 		if channels[i].Config != "" {
 			var configMap map[string]string
 			if err := json.Unmarshal([]byte(channels[i].Config), &configMap); err == nil {
-				// 移除密码等敏感信息
-				if _, ok := configMap["password"]; ok {
-					configMap["password"] = "******"
-				}
-				if _, ok := configMap["sendkey"]; ok {
-					configMap["sendkey"] = "******"
-				}
+				// 移除密码、令牌、Webhook 地址等敏感信息
+				maskChannelConfig(configMap)
 				// 重新序列化
 				if newConfig, err := json.Marshal(configMap); err == nil {
 					channels[i].Config = string(newConfig)
@@ -227,10 +264,12 @@ func CreateNotificationChannel(c *gin.Context) {
 		return
 	}
 
-	if channel.Type != "email" && channel.Type != "serverchan" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "渠道类型必须是email或serverchan"})
+	categories, err := normalizeAlertCategories(channel.Categories)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	channel.Categories = categories
 
 	// 验证配置
 	var configMap map[string]string
@@ -240,20 +279,9 @@ func CreateNotificationChannel(c *gin.Context) {
 	}
 
 	// 根据类型验证必要的配置项
-	switch channel.Type {
-	case "email":
-		requiredFields := []string{"smtp_host", "username", "password", "from_email"}
-		for _, field := range requiredFields {
-			if _, ok := configMap[field]; !ok || configMap[field] == "" {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "邮件配置缺少必要字段: " + field})
-				return
-			}
-		}
-	case "serverchan":
-		if _, ok := configMap["sendkey"]; !ok || configMap["sendkey"] == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Server酱配置缺少sendkey"})
-			return
-		}
+	if err := services.ValidateChannelConfig(channel.Type, configMap); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
 	if err := models.CreateNotificationChannel(&channel); err != nil {
@@ -264,13 +292,7 @@ func CreateNotificationChannel(c *gin.Context) {
 	// 返回数据前清理敏感信息
 	var returnConfig map[string]string
 	json.Unmarshal([]byte(channel.Config), &returnConfig)
-
-	if _, ok := returnConfig["password"]; ok {
-		returnConfig["password"] = "******"
-	}
-	if _, ok := returnConfig["sendkey"]; ok {
-		returnConfig["sendkey"] = "******"
-	}
+	maskChannelConfig(returnConfig)
 
 	if cleanConfig, err := json.Marshal(returnConfig); err == nil {
 		channel.Config = string(cleanConfig)
@@ -306,6 +328,8 @@ func UpdateNotificationChannel(c *gin.Context) {
 		Type    string `json:"type"`
 		Config  string `json:"config"`
 		Enabled bool   `json:"enabled"`
+		// 为空字符串表示接收全部分类，未传入时保持不变
+		Categories *string `json:"categories"`
 	}
 
 	if err := c.ShouldBindJSON(&updateData); err != nil {
@@ -327,6 +351,15 @@ func UpdateNotificationChannel(c *gin.Context) {
 	// 更新启用状态
 	channel.Enabled = updateData.Enabled
 
+	if updateData.Categories != nil {
+		categories, err := normalizeAlertCategories(*updateData.Categories)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		channel.Categories = categories
+	}
+
 	// 处理配置更新
 	if updateData.Config != "" && updateData.Config != "[UNCHANGED]" {
 		var newConfig map[string]string
@@ -340,38 +373,23 @@ func UpdateNotificationChannel(c *gin.Context) {
 			originalConfig[k] = v
 		}
 
-		// 根据类型验证必要的配置项
-		switch channel.Type {
-		case "email":
-			requiredFields := []string{"smtp_host", "username", "from_email"}
-			for _, field := range requiredFields {
-				if _, ok := originalConfig[field]; !ok || originalConfig[field] == "" {
-					c.JSON(http.StatusBadRequest, gin.H{"error": "邮件配置缺少必要字段: " + field})
-					return
-				}
-			}
-			// 如果密码字段为空或占位符，恢复原密码
-			if pass, ok := originalConfig["password"]; !ok || pass == "" || pass == "******" {
-				var oldConfig map[string]string
-				json.Unmarshal([]byte(channel.Config), &oldConfig)
-				if oldPass, ok := oldConfig["password"]; ok {
-					originalConfig["password"] = oldPass
-				}
-			}
-		case "serverchan":
-			// 如果sendkey字段为空或占位符，恢复原sendkey
-			if key, ok := originalConfig["sendkey"]; !ok || key == "" || key == "******" {
-				var oldConfig map[string]string
-				json.Unmarshal([]byte(channel.Config), &oldConfig)
-				if oldKey, ok := oldConfig["sendkey"]; ok {
-					originalConfig["sendkey"] = oldKey
-				} else {
-					c.JSON(http.StatusBadRequest, gin.H{"error": "Server酱配置缺少sendkey"})
-					return
+		// 敏感字段为空或占位符时保留原值，前端拿到的是脱敏后的配置
+		var oldConfig map[string]string
+		json.Unmarshal([]byte(channel.Config), &oldConfig)
+		for k, v := range originalConfig {
+			if isSecretConfigKey(k) && (v == "" || v == "******") {
+				if oldValue, ok := oldConfig[k]; ok {
+					originalConfig[k] = oldValue
 				}
 			}
 		}
 
+		// 根据类型验证必要的配置项
+		if err := services.ValidateChannelConfig(channel.Type, originalConfig); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		// 更新配置
 		configJSON, _ := json.Marshal(originalConfig)
 		channel.Config = string(configJSON)
@@ -385,13 +403,7 @@ func UpdateNotificationChannel(c *gin.Context) {
 	// 返回数据前清理敏感信息
 	var returnConfig map[string]string
 	json.Unmarshal([]byte(channel.Config), &returnConfig)
-
-	if _, ok := returnConfig["password"]; ok {
-		returnConfig["password"] = "******"
-	}
-	if _, ok := returnConfig["sendkey"]; ok {
-		returnConfig["sendkey"] = "******"
-	}
+	maskChannelConfig(returnConfig)
 
 	if cleanConfig, err := json.Marshal(returnConfig); err == nil {
 		channel.Config = string(cleanConfig)
@@ -403,6 +415,59 @@ func UpdateNotificationChannel(c *gin.Context) {
 	})
 }
 
+// maskChannelConfig 将通知渠道配置中的密码、令牌、Webhook 地址等敏感字段替换为占位符
+func maskChannelConfig(config map[string]string) {
+	for k, v := range config {
+		if v != "" && isSecretConfigKey(k) {
+			config[k] = "******"
+		}
+	}
+}
+
+// normalizeChannelIDs 校验预警规则指定的通知渠道ID并去除空项和重复项
+func normalizeChannelIDs(raw string) (string, error) {
+	var result []string
+	seen := make(map[uint64]bool)
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(item, 10, 64)
+		if err != nil || id == 0 {
+			return "", fmt.Errorf("无效的通知渠道ID: %s", item)
+		}
+		if seen[id] {
+			continue
+		}
+		var channel models.NotificationChannel
+		if err := models.GetNotificationChannelByID(uint(id), &channel); err != nil {
+			return "", fmt.Errorf("通知渠道 %d 不存在", id)
+		}
+		seen[id] = true
+		result = append(result, item)
+	}
+	return strings.Join(result, ","), nil
+}
+
+// normalizeAlertCategories 校验逗号分隔的预警分类并去除空项和重复项
+func normalizeAlertCategories(raw string) (string, error) {
+	var result []string
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" || seen[item] {
+			continue
+		}
+		if !models.IsValidAlertCategory(item) {
+			return "", fmt.Errorf("无效的预警分类: %s", item)
+		}
+		seen[item] = true
+		result = append(result, item)
+	}
+	return strings.Join(result, ","), nil
+}
+
 // DeleteNotificationChannel 删除通知渠道
 func DeleteNotificationChannel(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
@@ -455,10 +520,113 @@ func TestNotificationChannel(c *gin.Context) {
 	}
 }
 
+// simulatedAlertDefaults 各预警类型模拟时默认的触发值和阈值
+var simulatedAlertDefaults = map[string][2]float64{
+	"cpu":         {95, 80},
+	"memory":      {95, 80},
+	"network":     {120, 100},
+	"zombie":      {20, 10},
+	"status":      {0, 2},
+	"oom":         {1, 1},
+	"duplicate":   {3, 3},
+	"disk_health": {1, 1},
+	"cert_expiry": {1, 14},
+	"agent_error": {30, 10},
+	"temperature": {92, 85},
+}
+
+// SimulateAlert 构造一条模拟预警，经由预警服务的消息格式和分类路由发送到指定通知渠道（未指定时为全部渠道），
+// 返回每个渠道的投递结果，用于验证新渠道的配置和修改后的通知链路
+func SimulateAlert(c *gin.Context) {
+	var req struct {
+		AlertType  string   `json:"alert_type"`
+		ChannelIDs []uint   `json:"channel_ids"`
+		ServerID   uint     `json:"server_id"`
+		Value      *float64 `json:"value"`
+		Threshold  *float64 `json:"threshold"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	if req.AlertType == "" {
+		req.AlertType = "cpu"
+	}
+	defaults, ok := simulatedAlertDefaults[req.AlertType]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的预警类型: " + req.AlertType})
+		return
+	}
+
+	alert := models.AlertRecord{
+		ServerID:   req.ServerID,
+		ServerName: "测试服务器",
+		AlertType:  req.AlertType,
+		Value:      defaults[0],
+		Threshold:  defaults[1],
+		NotifiedAt: time.Now(),
+	}
+	if req.Value != nil {
+		alert.Value = *req.Value
+	}
+	if req.Threshold != nil {
+		alert.Threshold = *req.Threshold
+	}
+	if req.ServerID > 0 {
+		server, err := models.GetServerByID(req.ServerID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+			return
+		}
+		alert.ServerName = server.Name
+	}
+
+	var channels []models.NotificationChannel
+	if len(req.ChannelIDs) == 0 {
+		var err error
+		if channels, err = models.GetAllNotificationChannels(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取通知渠道失败"})
+			return
+		}
+	} else {
+		for _, id := range req.ChannelIDs {
+			var channel models.NotificationChannel
+			if err := models.GetNotificationChannelByID(id, &channel); err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("通知渠道 %d 不存在", id)})
+				return
+			}
+			channels = append(channels, channel)
+		}
+	}
+	if len(channels) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有可用的通知渠道"})
+		return
+	}
+
+	results := services.GetAlertService().SimulateAlert(alert, channels)
+	delivered, failed := 0, 0
+	for _, r := range results {
+		if r.Delivered {
+			delivered++
+		} else if !r.Skipped {
+			failed++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"alert_type": alert.AlertType,
+		"category":   models.AlertCategoryOf(alert.AlertType),
+		"results":    results,
+		"delivered":  delivered,
+		"failed":     failed,
+	})
+}
+
 // GetAlertRecords 获取预警记录
 func GetAlertRecords(c *gin.Context) {
 	serverID, _ := strconv.ParseUint(c.DefaultQuery("server_id", "0"), 10, 64)
 	alertType := c.DefaultQuery("type", "")
+	category := c.DefaultQuery("category", "")
 	onlyUnresolved := c.DefaultQuery("unresolved", "false") == "true"
 
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
@@ -470,7 +638,12 @@ func GetAlertRecords(c *gin.Context) {
 		limit = 50
 	}
 
-	records, total, err := models.GetAlertRecords(uint(serverID), alertType, onlyUnresolved, page, limit)
+	if category != "" && !models.IsValidAlertCategory(category) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的预警分类"})
+		return
+	}
+
+	records, total, err := models.GetAlertRecords(uint(serverID), alertType, category, onlyUnresolved, page, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取预警记录失败"})
 		return
@@ -506,6 +679,11 @@ func ResolveAlertRecord(c *gin.Context) {
 	record.Resolved = true
 	record.ResolvedAt = time.Now()
 
+	// 先以当前用户关闭对应的事件，时间线记录解决人
+	if err := models.ResolveAlertIncident(record.ID, c.GetString("username"), "", record.ResolvedAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新事件失败"})
+		return
+	}
 	if err := models.UpdateAlertRecord(&record); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新预警记录失败"})
 		return
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+// 升级策略最多的步骤数
+const maxEscalationSteps = 10
+
+// escalationPolicyRequest 创建/更新升级策略的请求参数
+type escalationPolicyRequest struct {
+	Name      string                       `json:"name"`
+	AlertType string                       `json:"alert_type"`
+	Category  string                       `json:"category"`
+	Steps     []models.AlertEscalationStep `json:"steps"`
+	Enabled   *bool                        `json:"enabled"`
+}
+
+// GetEscalationPolicies 获取所有升级策略
+func GetEscalationPolicies(c *gin.Context) {
+	policies, err := models.GetAllEscalationPolicies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取升级策略失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"policies": policies})
+}
+
+// CreateEscalationPolicy 创建升级策略
+func CreateEscalationPolicy(c *gin.Context) {
+	var req escalationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	policy := models.AlertEscalationPolicy{Enabled: true}
+	if err := applyEscalationPolicy(&policy, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.CreateEscalationPolicy(&policy); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建升级策略失败"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "升级策略创建成功",
+		"policy":  policy,
+	})
+}
+
+// UpdateEscalationPolicy 更新升级策略，进行中的升级按新步骤继续
+func UpdateEscalationPolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的策略ID"})
+		return
+	}
+
+	var policy models.AlertEscalationPolicy
+	if err := models.GetEscalationPolicyByID(uint(id), &policy); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "升级策略不存在"})
+		return
+	}
+
+	var req escalationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	if err := applyEscalationPolicy(&policy, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.UpdateEscalationPolicy(&policy); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新升级策略失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "升级策略更新成功",
+		"policy":  policy,
+	})
+}
+
+// DeleteEscalationPolicy 删除升级策略
+func DeleteEscalationPolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的策略ID"})
+		return
+	}
+
+	if err := models.DeleteEscalationPolicy(uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除升级策略失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "升级策略删除成功"})
+}
+
+// applyEscalationPolicy 校验请求并写入策略，步骤按延迟升序保存
+func applyEscalationPolicy(policy *models.AlertEscalationPolicy, req escalationPolicyRequest) error {
+	if req.Name == "" {
+		return errors.New("策略名称不能为空")
+	}
+	if req.AlertType != "" {
+		// 可配置升级的预警类型与可模拟的预警类型一致
+		if _, ok := simulatedAlertDefaults[req.AlertType]; !ok {
+			return fmt.Errorf("不支持的预警类型: %s", req.AlertType)
+		}
+	}
+	if req.Category != "" && !models.IsValidAlertCategory(req.Category) {
+		return fmt.Errorf("无效的预警分类: %s", req.Category)
+	}
+	if len(req.Steps) == 0 {
+		return errors.New("至少需要一个升级步骤")
+	}
+	if len(req.Steps) > maxEscalationSteps {
+		return fmt.Errorf("升级步骤不能超过 %d 个", maxEscalationSteps)
+	}
+	for _, step := range req.Steps {
+		if step.DelayMinutes < 0 {
+			return errors.New("升级延迟不能为负数")
+		}
+		var channel models.NotificationChannel
+		if err := models.GetNotificationChannelByID(step.ChannelID, &channel); err != nil {
+			return fmt.Errorf("通知渠道 %d 不存在", step.ChannelID)
+		}
+	}
+
+	steps := append([]models.AlertEscalationStep(nil), req.Steps...)
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].DelayMinutes < steps[j].DelayMinutes })
+	stepsJSON, err := json.Marshal(steps)
+	if err != nil {
+		return err
+	}
+
+	policy.Name = req.Name
+	policy.AlertType = req.AlertType
+	policy.Category = req.Category
+	policy.Steps = string(stepsJSON)
+	if req.Enabled != nil {
+		policy.Enabled = *req.Enabled
+	}
+	return nil
+}
+
+// AcknowledgeAlertRecord 确认预警，停止该预警的升级通知；预警仍保持未解决，恢复后照常发送解决通知
+func AcknowledgeAlertRecord(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的记录ID"})
+		return
+	}
+
+	var record models.AlertRecord
+	if err := models.GetAlertRecordByID(uint(id), &record); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "预警记录不存在"})
+		return
+	}
+
+	acked, err := models.AcknowledgeAlertRecord(record.ID, c.GetString("username"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新预警记录失败"})
+		return
+	}
+	if !acked {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "预警记录已经确认或已经解决"})
+		return
+	}
+
+	models.GetAlertRecordByID(record.ID, &record)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "预警已确认，停止升级通知",
+		"record":  record,
+	})
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+	"github.com/user/server-ops-backend/services"
+)
+
+func TestAlertEscalationAdvancesUntilAcknowledged(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.NotificationChannel{}, &models.AlertRecord{}, &models.AlertEscalationPolicy{}, &models.Incident{}, &models.IncidentEvent{}))
+	db.Exec("DELETE FROM notification_channels")
+	db.Exec("DELETE FROM alert_records")
+	db.Exec("DELETE FROM incidents")
+	db.Exec("DELETE FROM alert_escalation_policies")
+
+	// 缺少 sendkey 的渠道会在发送前失败，不会访问网络
+	oncall := models.NotificationChannel{Type: "serverchan", Name: "oncall", Config: `{}`, Enabled: true}
+	manager := models.NotificationChannel{Type: "serverchan", Name: "manager", Config: `{}`, Enabled: true}
+	assert.NoError(t, db.Create(&oncall).Error)
+	assert.NoError(t, db.Create(&manager).Error)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := fmt.Sprintf(`{"name":"cpu","alert_type":"cpu","steps":[{"channel_id":%d,"delay_minutes":15},{"channel_id":%d,"delay_minutes":0}]}`,
+		manager.ID, oncall.ID)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/alerts/escalations", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	CreateEscalationPolicy(c)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var resp struct {
+		Policy models.AlertEscalationPolicy `json:"policy"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	steps, err := resp.Policy.GetSteps()
+	assert.NoError(t, err)
+	if assert.Len(t, steps, 2) {
+		assert.Equal(t, oncall.ID, steps[0].ChannelID, "步骤按延迟升序保存")
+	}
+
+	// 两条已通知第一步的预警，其中一条已确认
+	pending := models.AlertRecord{ServerID: 1, AlertType: "cpu", EscalationPolicyID: resp.Policy.ID, EscalationStep: 1}
+	acked := models.AlertRecord{ServerID: 2, AlertType: "cpu", EscalationPolicyID: resp.Policy.ID, EscalationStep: 1}
+	assert.NoError(t, models.CreateAlertRecord(&pending))
+	assert.NoError(t, models.CreateAlertRecord(&acked))
+
+	ack := func(id uint) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPut, "/", nil)
+		c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(id), 10)}}
+		c.Set("username", "admin")
+		AcknowledgeAlertRecord(c)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, ack(acked.ID))
+	assert.Equal(t, http.StatusBadRequest, ack(acked.ID), "重复确认")
+
+	service := services.GetAlertService()
+	stepOf := func(id uint) int {
+		var record models.AlertRecord
+		assert.NoError(t, models.GetAlertRecordByID(id, &record))
+		return record.EscalationStep
+	}
+
+	// 未到第二步的延迟时不升级
+	service.AdvanceEscalations(time.Now().Add(10 * time.Minute))
+	assert.Equal(t, 1, stepOf(pending.ID))
+
+	service.AdvanceEscalations(time.Now().Add(20 * time.Minute))
+	assert.Equal(t, 2, stepOf(pending.ID))
+	assert.Equal(t, 1, stepOf(acked.ID), "已确认的预警不再升级")
+
+	var record models.AlertRecord
+	assert.NoError(t, models.GetAlertRecordByID(acked.ID, &record))
+	assert.True(t, record.Acknowledged)
+	assert.Equal(t, "admin", record.AcknowledgedBy)
+}
+
+func TestMatchEscalationPolicyPrefersAlertType(t *testing.T) {
+	policies := []models.AlertEscalationPolicy{
+		{Name: "all", Enabled: true},
+		{Name: "resource", Category: models.AlertCategoryResource, Enabled: true},
+		{Name: "cpu", AlertType: "cpu", Enabled: true},
+		{Name: "memory", AlertType: "memory", Enabled: false},
+	}
+
+	assert.Equal(t, "cpu", models.MatchEscalationPolicy(policies, "cpu", models.AlertCategoryResource).Name)
+	assert.Equal(t, "resource", models.MatchEscalationPolicy(policies, "memory", models.AlertCategoryResource).Name)
+	assert.Equal(t, "all", models.MatchEscalationPolicy(policies, "status", models.AlertCategoryAvailability).Name)
+	assert.Nil(t, models.MatchEscalationPolicy(policies[2:], "status", models.AlertCategoryAvailability))
+}
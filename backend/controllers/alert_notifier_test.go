@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+	"github.com/user/server-ops-backend/services"
+)
+
+func TestWebhookNotificationChannel(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.NotificationChannel{}, &models.AlertSetting{}))
+
+	var received []map[string]string
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]string
+		_ = json.Unmarshal(body, &payload)
+		received = append(received, payload)
+
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write(body)
+		assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), r.Header.Get("X-BetterMonitor-Signature"))
+	}))
+	defer hook.Close()
+
+	create := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/alerts/channels", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		CreateNotificationChannel(c)
+		return w
+	}
+
+	// 配置不完整或类型未知时拒绝
+	assert.Equal(t, http.StatusBadRequest, create(`{"type":"slack","name":"ops","config":"{\"webhook_url\":\"not-a-url\"}"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, create(`{"type":"telegram","name":"ops","config":"{\"bot_token\":\"t\"}"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, create(`{"type":"pager","name":"ops","config":"{}"}`).Code)
+
+	config, _ := json.Marshal(map[string]string{"url": hook.URL, "secret": "s3cret", "rate_limit": "1"})
+	body, _ := json.Marshal(map[string]interface{}{"type": "webhook", "name": "hook", "enabled": true, "config": string(config)})
+	w := create(string(body))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var resp struct {
+		Channel models.NotificationChannel `json:"channel"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Contains(t, resp.Channel.Config, `"secret":"******"`)
+	assert.Contains(t, resp.Channel.Config, hook.URL)
+
+	test := func() int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(resp.Channel.ID), 10)}}
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/alerts/channels/test", nil)
+		TestNotificationChannel(c)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, test())
+	if assert.Len(t, received, 1) {
+		assert.Equal(t, "服务器监控系统测试通知", received[0]["title"])
+	}
+
+	// 超过每分钟的发送上限后丢弃
+	assert.Equal(t, http.StatusInternalServerError, test())
+	assert.Len(t, received, 1)
+
+	// 预警规则只能指定存在的渠道
+	_, err := normalizeChannelIDs("999")
+	assert.Error(t, err)
+	ids, err := normalizeChannelIDs(strconv.FormatUint(uint64(resp.Channel.ID), 10) + ", ")
+	assert.NoError(t, err)
+	setting := models.AlertSetting{ChannelIDs: ids}
+	assert.True(t, setting.NotifyChannelIDs()[resp.Channel.ID])
+}
+
+func TestAlertNotificationsSentInBackground(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.NotificationChannel{}, &models.AlertRecord{}, &models.AlertEscalationPolicy{}, &models.MaintenanceWindow{}))
+	db.Exec("DELETE FROM notification_channels")
+	db.Exec("DELETE FROM alert_escalation_policies")
+
+	// 第一次请求慢且返回 503，重试后成功
+	var hits atomic.Int32
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			time.Sleep(300 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer hook.Close()
+
+	config, _ := json.Marshal(map[string]string{"url": hook.URL})
+	channel := models.NotificationChannel{Type: "webhook", Name: "slow-hook", Config: string(config), Enabled: true}
+	assert.NoError(t, db.Create(&channel).Error)
+
+	start := time.Now()
+	recordID := services.GetAlertService().NotifyUptimeDown(models.UptimeCheck{Name: "api", Target: "https://example.com", ConsecutiveFailures: 3, FailureThreshold: 3}, "timeout")
+	assert.Less(t, time.Since(start), 200*time.Millisecond, "发送和重试不应阻塞预警检查")
+	var record models.AlertRecord
+	assert.NoError(t, models.GetAlertRecordByID(recordID, &record))
+	assert.Equal(t, strconv.FormatUint(uint64(channel.ID), 10), record.ChannelIDs)
+
+	assert.Eventually(t, func() bool { return hits.Load() == 2 }, 5*time.Second, 50*time.Millisecond)
+}
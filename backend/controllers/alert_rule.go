@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+	"github.com/user/server-ops-backend/services"
+)
+
+// alertRuleRequest 创建/更新表达式预警规则的请求参数
+type alertRuleRequest struct {
+	Name              string `json:"name"`
+	Expression        string `json:"expression"`
+	RecoverExpression string `json:"recover_expression"`
+	ServerID          uint   `json:"server_id"`
+	ChannelIDs        string `json:"channel_ids"`
+	Enabled           *bool  `json:"enabled"`
+}
+
+// GetAlertRules 获取所有表达式预警规则，同时返回表达式可用的变量
+func GetAlertRules(c *gin.Context) {
+	rules, err := models.GetAllAlertRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取预警规则失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"rules":     rules,
+		"variables": services.AlertExprVariables,
+	})
+}
+
+// CreateAlertRule 创建表达式预警规则
+func CreateAlertRule(c *gin.Context) {
+	var req alertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	rule := models.AlertRule{Enabled: true}
+	if err := applyAlertRule(&rule, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.CreateAlertRule(&rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建预警规则失败"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "预警规则创建成功",
+		"rule":    rule,
+	})
+}
+
+// UpdateAlertRule 更新表达式预警规则，已触发的预警按新条件判断恢复
+func UpdateAlertRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的规则ID"})
+		return
+	}
+
+	var rule models.AlertRule
+	if err := models.GetAlertRuleByID(uint(id), &rule); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "预警规则不存在"})
+		return
+	}
+
+	var req alertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	if err := applyAlertRule(&rule, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.UpdateAlertRule(&rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新预警规则失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "预警规则更新成功",
+		"rule":    rule,
+	})
+}
+
+// DeleteAlertRule 删除表达式预警规则
+func DeleteAlertRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的规则ID"})
+		return
+	}
+
+	if err := models.DeleteAlertRule(uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除预警规则失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "预警规则删除成功"})
+}
+
+// PreviewAlertRule 用服务器最新的监控数据试算表达式，保存规则前检查条件是否符合预期
+func PreviewAlertRule(c *gin.Context) {
+	var req struct {
+		Expression string `json:"expression"`
+		ServerID   uint   `json:"server_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.ServerID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	server, err := models.GetServerByID(req.ServerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+	latest, err := models.GetLatestMonitorData(server.ID, 1)
+	if err != nil || len(latest) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "服务器还没有监控数据"})
+		return
+	}
+
+	matched, values, err := services.PreviewAlertRule(req.Expression, *server, latest[0])
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"matched":   matched,
+		"values":    values,
+		"timestamp": latest[0].Timestamp,
+	})
+}
+
+// applyAlertRule 校验请求并写入规则，保存前编译表达式以便及时提示语法错误
+func applyAlertRule(rule *models.AlertRule, req alertRuleRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errors.New("规则名称不能为空")
+	}
+	if utf8.RuneCountInString(req.Name) > 50 {
+		return errors.New("规则名称不能超过 50 个字符")
+	}
+	req.Expression = strings.TrimSpace(req.Expression)
+	if _, err := services.CompileAlertExpression(req.Expression); err != nil {
+		return fmt.Errorf("触发条件无效: %v", err)
+	}
+	req.RecoverExpression = strings.TrimSpace(req.RecoverExpression)
+	if req.RecoverExpression != "" {
+		if _, err := services.CompileAlertExpression(req.RecoverExpression); err != nil {
+			return fmt.Errorf("恢复条件无效: %v", err)
+		}
+	}
+	if req.ServerID != 0 {
+		if _, err := models.GetServerByID(req.ServerID); err != nil {
+			return fmt.Errorf("服务器 %d 不存在", req.ServerID)
+		}
+	}
+	channelIDs, err := normalizeChannelIDs(req.ChannelIDs)
+	if err != nil {
+		return err
+	}
+
+	rule.Name = req.Name
+	rule.Expression = req.Expression
+	rule.RecoverExpression = req.RecoverExpression
+	rule.ServerID = req.ServerID
+	rule.ChannelIDs = channelIDs
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	return nil
+}
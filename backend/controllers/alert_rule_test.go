@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+	"github.com/user/server-ops-backend/services"
+)
+
+func TestAlertRuleValidationAndPreview(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.AlertRule{}, &models.ServerMonitor{}, &models.DiskMountStat{}, &models.NotificationChannel{}))
+	db.Exec("DELETE FROM alert_rules")
+
+	server := models.Server{Name: "rule-preview", CPUCores: 4}
+	assert.NoError(t, db.Create(&server).Error)
+	sampledAt := time.Now()
+	assert.NoError(t, models.CreateDiskMountStats([]models.DiskMountStat{
+		{ServerID: server.ID, Timestamp: sampledAt, Mount: "/", Total: 100 << 30, Used: 50 << 30, Free: 50 << 30,
+			InodesTotal: 1000, InodesUsed: 200, InodesFree: 800},
+		{ServerID: server.ID, Timestamp: sampledAt, Mount: "/data", Total: 100 << 30, Used: 97 << 30, Free: 3 << 30},
+		{ServerID: server.ID, Timestamp: sampledAt, Mount: "/var/lib/docker", Total: 100 << 30, Used: 80 << 30, Free: 20 << 30,
+			InodesTotal: 1000, InodesUsed: 950, InodesFree: 50},
+	}))
+	assert.NoError(t, db.Create(&models.ServerMonitor{
+		ServerID: server.ID, Timestamp: sampledAt, CPUUsage: 95, LoadAvg5: 9,
+		DiskUsed: 50 << 30, DiskTotal: 100 << 30,
+		CustomMetrics: `[{"name":"queue_depth","value":120},{"name":"Cache_Hits","value":30},{"name":"disk-io.wait","value":8}]`,
+	}).Error)
+
+	call := func(handler gin.HandlerFunc, body string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler(c)
+		var resp map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	// 语法、变量和类型错误在保存时提示
+	for _, expr := range []string{"cpu >", "cpuu > 90", "cpu + 90", "cpu > 90 and 5", "disk_free < 5GB on some mount", "cpu > 90 for 5 days",
+		`disk_usage > 90 on mount data`, `disk_usage > 90 on mount '/data`} {
+		code, resp := call(CreateAlertRule, `{"name":"bad","expression":"`+expr+`"}`)
+		assert.Equal(t, http.StatusBadRequest, code, expr)
+		assert.Contains(t, resp["error"], "触发条件无效", expr)
+	}
+	code, _ := call(CreateAlertRule, `{"name":"bad","expression":"cpu > 90","recover_expression":"cpu"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, resp := call(CreateAlertRule, `{"name":"cpu+load","expression":"cpu > 90 AND load_avg_5 > cores * 2 for 5m","recover_expression":"cpu < 70 for 1m"}`)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, true, resp["rule"].(map[string]interface{})["enabled"])
+
+	preview := func(expr string) (bool, string) {
+		body, _ := json.Marshal(map[string]interface{}{"expression": expr, "server_id": server.ID})
+		code, resp := call(PreviewAlertRule, string(body))
+		assert.Equal(t, http.StatusOK, code, expr)
+		matched, _ := resp["matched"].(bool)
+		values, _ := resp["values"].(string)
+		return matched, values
+	}
+
+	matched, values := preview("cpu > 90 AND load_avg_5 > cores * 2")
+	assert.True(t, matched)
+	assert.Equal(t, "cores=4, cpu=95, load_avg_5=9", values)
+	matched, _ = preview("cpu > 90 && !(load_avg_5 > cores * 2)")
+	assert.False(t, matched)
+
+	// 按挂载点判断，默认的 disk_* 为系统盘
+	matched, _ = preview("disk_free < 5GB")
+	assert.False(t, matched)
+	matched, _ = preview("disk_free < 5GB on any mount")
+	assert.True(t, matched)
+	matched, _ = preview("disk_usage > 40% ON ALL MOUNTS")
+	assert.True(t, matched)
+	matched, _ = preview("disk_usage > 60% on all mounts")
+	assert.False(t, matched)
+
+	// 指定挂载点和 inode 使用率，inode 变量默认为根目录
+	matched, values = preview(`disk_usage > 90% OR inode_usage > 90% on mount "/var/lib/docker"`)
+	assert.True(t, matched)
+	assert.Contains(t, values, "inode_usage(/var/lib/docker)=95.00")
+	matched, _ = preview(`disk_usage > 90% on mount "/var/lib/docker"`)
+	assert.False(t, matched)
+	matched, _ = preview(`disk_usage > 90% on mount '/data'`)
+	assert.True(t, matched)
+	matched, _ = preview(`disk_usage > 0 on mount "/missing"`)
+	assert.False(t, matched)
+	matched, values = preview("inode_usage > 15")
+	assert.True(t, matched)
+	assert.Equal(t, "inode_usage=20", values)
+
+	// 自定义指标，不存在的指标不满足任何比较
+	matched, _ = preview("custom.queue_depth >= 100")
+	assert.True(t, matched)
+	matched, _ = preview("custom.missing > 0 or custom.missing <= 0")
+	assert.False(t, matched)
+
+	// 自定义指标名不区分大小写，名称中的 "-" 属于变量名，两侧有空格时是减号
+	matched, _ = preview("custom.Cache_Hits > 20 and custom.cache_hits < 40")
+	assert.True(t, matched)
+	matched, _ = preview("custom.disk-io.wait > 5")
+	assert.True(t, matched)
+	matched, _ = preview("custom.disk-io.wait - 5 < 5")
+	assert.True(t, matched)
+	matched, _ = preview("cpu-90 > 0")
+	assert.True(t, matched)
+}
+
+func TestAlertRuleForDurationAndRecovery(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.AlertRule{}, &models.AlertRecord{}, &models.AlertEscalationPolicy{}, &models.Incident{}, &models.IncidentEvent{}))
+	db.Exec("DELETE FROM alert_rules")
+	db.Exec("DELETE FROM alert_records")
+	db.Exec("DELETE FROM incidents")
+
+	server := models.Server{Name: "rule-eval", CPUCores: 2}
+	assert.NoError(t, db.Create(&server).Error)
+	rule := models.AlertRule{Name: "hot", Expression: "cpu > 90 for 1m", RecoverExpression: "cpu < 70 for 30s", Enabled: true}
+	assert.NoError(t, models.CreateAlertRule(&rule))
+	rules := []models.AlertRule{rule}
+
+	alertService := services.NewAlertService()
+	start := time.Now()
+	step := func(offset time.Duration, cpu float64) {
+		alertService.EvaluateRules(server, models.ServerMonitor{CPUUsage: cpu}, rules, nil, start.Add(offset))
+	}
+	unresolved := func() []models.AlertRecord {
+		records, err := models.GetUnresolvedRuleAlerts()
+		assert.NoError(t, err)
+		return records
+	}
+
+	// 条件中断后重新计时
+	step(0, 95)
+	step(40*time.Second, 80)
+	step(50*time.Second, 95)
+	step(100*time.Second, 95)
+	assert.Empty(t, unresolved())
+	step(110*time.Second, 95)
+	records := unresolved()
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, rule.ID, records[0].RuleID)
+		assert.Equal(t, models.AlertCategoryResource, records[0].Category)
+	}
+
+	// 介于两个阈值之间不恢复，也不重复告警
+	step(120*time.Second, 80)
+	step(200*time.Second, 95)
+	step(210*time.Second, 60)
+	step(230*time.Second, 65)
+	assert.Len(t, unresolved(), 1)
+	step(240*time.Second, 65)
+	assert.Empty(t, unresolved())
+
+	// 重启后从未解决的记录恢复状态，恢复条件满足后解除
+	step(300*time.Second, 95)
+	step(360*time.Second, 95)
+	assert.Len(t, unresolved(), 1)
+	restarted := services.NewAlertService()
+	restarted.SyncRuleAlerts(rules)
+	restarted.EvaluateRules(server, models.ServerMonitor{CPUUsage: 60}, rules, nil, start.Add(400*time.Second))
+	restarted.EvaluateRules(server, models.ServerMonitor{CPUUsage: 60}, rules, nil, start.Add(430*time.Second))
+	assert.Empty(t, unresolved())
+
+	// 规则删除后未解决的预警直接解除
+	restarted.EvaluateRules(server, models.ServerMonitor{CPUUsage: 95}, rules, nil, start.Add(500*time.Second))
+	restarted.EvaluateRules(server, models.ServerMonitor{CPUUsage: 95}, rules, nil, start.Add(560*time.Second))
+	assert.Len(t, unresolved(), 1)
+	assert.NoError(t, models.DeleteAlertRule(rule.ID))
+	enabled, err := models.GetEnabledAlertRules()
+	assert.NoError(t, err)
+	restarted.SyncRuleAlerts(enabled)
+	assert.Empty(t, unresolved())
+}
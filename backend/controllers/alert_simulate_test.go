@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+	"github.com/user/server-ops-backend/services"
+)
+
+func TestSimulateAlertReportsPerChannel(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.NotificationChannel{}, &models.AlertRecord{}))
+	db.Exec("DELETE FROM notification_channels")
+	db.Exec("DELETE FROM alert_records")
+
+	// 缺少 sendkey 的渠道会在发送前失败，不会访问网络
+	broken := models.NotificationChannel{Type: "serverchan", Name: "oncall", Config: `{}`, Enabled: true}
+	platform := models.NotificationChannel{Type: "serverchan", Name: "platform", Config: `{"sendkey":"k"}`, Enabled: true, Categories: "certificate"}
+	disabled := models.NotificationChannel{Type: "serverchan", Name: "old", Config: `{"sendkey":"k"}`, Enabled: true}
+	for _, ch := range []*models.NotificationChannel{&broken, &platform, &disabled} {
+		assert.NoError(t, db.Create(ch).Error)
+	}
+	db.Model(&disabled).Update("enabled", false)
+
+	simulate := func(body string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/alerts/simulate", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		SimulateAlert(c)
+		var resp map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, resp := simulate(`{"alert_type":"cpu"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "resource", resp["category"])
+	assert.Equal(t, float64(0), resp["delivered"])
+	assert.Equal(t, float64(1), resp["failed"])
+
+	raw, _ := json.Marshal(resp["results"])
+	var results []services.ChannelDelivery
+	assert.NoError(t, json.Unmarshal(raw, &results))
+	byName := map[string]services.ChannelDelivery{}
+	for _, r := range results {
+		byName[r.Name] = r
+	}
+	assert.False(t, byName["oncall"].Skipped)
+	assert.Contains(t, byName["oncall"].Error, "sendkey")
+	assert.True(t, byName["platform"].Skipped, "证书渠道不接收资源类预警")
+	assert.True(t, byName["old"].Skipped, "未启用的渠道不发送")
+
+	// 模拟预警不写入预警记录
+	var count int64
+	db.Model(&models.AlertRecord{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	code, _ = simulate(`{"alert_type":"bogus"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = simulate(`{"channel_ids":[9999]}`)
+	assert.Equal(t, http.StatusNotFound, code)
+}
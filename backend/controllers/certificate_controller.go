@@ -117,6 +117,31 @@ func CreateCertificateAccount(c *gin.Context) {
 		} else {
 			delete(req.Config, "zone_token")
 		}
+	case "route53", "aws":
+		// 不填写密钥时 Agent 使用本机的 AWS 默认凭证（环境变量、~/.aws/credentials 或实例角色）
+		if (strings.TrimSpace(req.Config["access_key_id"]) == "") != (strings.TrimSpace(req.Config["secret_access_key"]) == "") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Route53账号需要同时提供access_key_id和secret_access_key"})
+			return
+		}
+	case "dnspod":
+		if req.Config["login_token"] == "" && (req.Config["api_id"] == "" || req.Config["api_token"] == "") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "DNSPod账号需要提供api_id和api_token"})
+			return
+		}
+	case "godaddy":
+		if req.Config["api_key"] == "" || req.Config["api_secret"] == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "GoDaddy账号需要提供api_key和api_secret"})
+			return
+		}
+	case "rfc2136":
+		if strings.TrimSpace(req.Config["nameserver"]) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "RFC2136账号需要提供nameserver"})
+			return
+		}
+		if (req.Config["tsig_key"] == "") != (req.Config["tsig_secret"] == "") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "RFC2136账号需要同时提供tsig_key和tsig_secret"})
+			return
+		}
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "暂不支持该DNS提供商"})
 		return
@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+// checkScriptRequest 新建或更新检查脚本的请求体
+type checkScriptRequest struct {
+	Name        string `json:"name"`
+	Interpreter string `json:"interpreter"`
+	Content     string `json:"content"`
+	Interval    int    `json:"interval"`
+	Timeout     int    `json:"timeout"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+// checkScriptView 检查脚本及其最近一次解析出的指标
+type checkScriptView struct {
+	models.CheckScript
+	Metrics map[string]float64 `json:"last_metrics,omitempty"`
+}
+
+// agentCheckScript 通过 Agent 设置接口下发的检查脚本
+type agentCheckScript struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Interpreter string `json:"interpreter"`
+	Content     string `json:"content"`
+	Interval    int    `json:"interval"`
+	Timeout     int    `json:"timeout"`
+}
+
+// CheckScriptResultPayload Agent 随监控数据上报的检查脚本最新结果
+type CheckScriptResultPayload struct {
+	CheckID    uint               `json:"check_id"`
+	Timestamp  int64              `json:"timestamp"` // Agent 时钟的 Unix 毫秒
+	ExitCode   int                `json:"exit_code"`
+	DurationMs int64              `json:"duration_ms"`
+	Metrics    map[string]float64 `json:"metrics"`
+	Output     string             `json:"output"`
+	Error      string             `json:"error"`
+}
+
+// agentCheckScripts 返回下发给服务器执行的检查脚本，监控模式的服务器不执行脚本，查询失败时返回空列表
+func agentCheckScripts(server *models.Server) []agentCheckScript {
+	list := []agentCheckScript{}
+	if isMonitorOnlyServer(server) {
+		return list
+	}
+	scripts, err := models.GetEnabledCheckScripts(server.ID)
+	if err != nil {
+		log.Printf("获取服务器 %d 的检查脚本失败: %v", server.ID, err)
+	}
+	for _, script := range scripts {
+		list = append(list, agentCheckScript{
+			ID:          script.ID,
+			Name:        script.Name,
+			Interpreter: script.Interpreter,
+			Content:     script.Content,
+			Interval:    script.Interval,
+			Timeout:     script.Timeout,
+		})
+	}
+	return list
+}
+
+// recordCheckScripts 保存 Agent 上报的检查脚本结果，返回写入监控记录的最新结果 JSON。
+// 只接受属于该服务器的脚本，执行时间按时钟偏差换算为面板时间，结果比已保存的更新时才更新脚本的最近状态
+func recordCheckScripts(server *models.Server, payload []CheckScriptResultPayload, now time.Time) string {
+	scripts, err := models.GetServerCheckScripts(server.ID)
+	if err != nil {
+		log.Printf("获取服务器 %d 的检查脚本失败: %v", server.ID, err)
+		return ""
+	}
+	byID := make(map[uint]*models.CheckScript, len(scripts))
+	for i := range scripts {
+		byID[scripts[i].ID] = &scripts[i]
+	}
+
+	var samples []models.CheckScriptSample
+	for _, r := range payload {
+		script, ok := byID[r.CheckID]
+		if !ok {
+			continue
+		}
+		at := now
+		if r.Timestamp > 0 {
+			at = time.UnixMilli(r.Timestamp - server.ClockOffsetMs)
+			if at.After(now) {
+				at = now
+			}
+		}
+		metrics := make(map[string]float64, len(r.Metrics))
+		for key, value := range r.Metrics {
+			if len(metrics) >= 32 {
+				break
+			}
+			metrics[strings.ToLower(key)] = value
+		}
+		samples = append(samples, models.CheckScriptSample{Name: script.Name, ExitCode: r.ExitCode, Metrics: metrics, RunAt: at})
+
+		if !at.After(script.LastRunAt) {
+			continue
+		}
+		script.LastRunAt = at
+		script.LastExitCode = r.ExitCode
+		script.LastDurationMs = r.DurationMs
+		script.LastOutput = truncateUTF8(r.Output, 4000)
+		script.LastError = truncateUTF8(r.Error, 1000)
+		script.LastMetrics = ""
+		if len(metrics) > 0 {
+			if data, err := json.Marshal(metrics); err == nil {
+				script.LastMetrics = string(data)
+			}
+		}
+		if err := models.UpdateCheckScriptResult(script); err != nil {
+			log.Printf("保存检查脚本 %d 的结果失败: %v", script.ID, err)
+		}
+	}
+	if len(samples) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(samples)
+	if err != nil {
+		log.Printf("序列化检查脚本结果失败: %v", err)
+		return ""
+	}
+	return string(data)
+}
+
+// applyCheckScriptRequest 将请求写入检查脚本并校验，名称在同一服务器内唯一
+func applyCheckScriptRequest(script *models.CheckScript, req checkScriptRequest) error {
+	script.Name = req.Name
+	script.Interpreter = req.Interpreter
+	script.Content = req.Content
+	script.Interval = req.Interval
+	script.Timeout = req.Timeout
+	if req.Enabled != nil {
+		script.Enabled = *req.Enabled
+	}
+	if !utf8.ValidString(script.Content) {
+		return fmt.Errorf("脚本内容必须是 UTF-8 文本")
+	}
+	if err := script.Normalize(); err != nil {
+		return err
+	}
+	if models.CheckScriptNameTaken(script.ServerID, script.Name, script.ID) {
+		return fmt.Errorf("已存在同名的检查脚本: %s", script.Name)
+	}
+	return nil
+}
+
+// parseCheckScript 解析路径中的服务器ID和脚本ID并加载脚本，失败时已写入响应
+func parseCheckScript(c *gin.Context) (*models.CheckScript, bool) {
+	serverID, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return nil, false
+	}
+	scriptID, err := parseUintParam(c, "script_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的脚本ID"})
+		return nil, false
+	}
+	script, err := models.GetCheckScript(serverID, scriptID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "检查脚本不存在"})
+		return nil, false
+	}
+	return script, true
+}
+
+// ListCheckScripts 列出服务器的检查脚本及最近一次执行结果
+func ListCheckScripts(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+	scripts, err := models.GetServerCheckScripts(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取检查脚本失败"})
+		return
+	}
+	views := make([]checkScriptView, 0, len(scripts))
+	for _, script := range scripts {
+		views = append(views, checkScriptView{CheckScript: script, Metrics: script.GetLastMetrics()})
+	}
+	c.JSON(http.StatusOK, gin.H{"scripts": views, "interpreters": models.CheckScriptInterpreters})
+}
+
+// CreateCheckScript 为服务器新建检查脚本，Agent 下一次拉取设置时开始执行
+func CreateCheckScript(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+	server, err := models.GetServerByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+	var req checkScriptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	count, err := models.CountCheckScripts(server.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取检查脚本失败"})
+		return
+	}
+	if count >= models.MaxCheckScriptsPerServer {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("每台服务器最多 %d 个检查脚本", models.MaxCheckScriptsPerServer)})
+		return
+	}
+	script := models.CheckScript{ServerID: server.ID, Enabled: true}
+	if err := applyCheckScriptRequest(&script, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.SaveCheckScript(&script); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存检查脚本失败"})
+		return
+	}
+	c.JSON(http.StatusOK, script)
+}
+
+// UpdateCheckScript 更新检查脚本，Agent 下一次拉取设置时按新内容立即执行一次
+func UpdateCheckScript(c *gin.Context) {
+	script, ok := parseCheckScript(c)
+	if !ok {
+		return
+	}
+	var req checkScriptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	if err := applyCheckScriptRequest(script, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.SaveCheckScript(script); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存检查脚本失败"})
+		return
+	}
+	c.JSON(http.StatusOK, script)
+}
+
+// DeleteCheckScript 删除检查脚本
+func DeleteCheckScript(c *gin.Context) {
+	script, ok := parseCheckScript(c)
+	if !ok {
+		return
+	}
+	if err := models.DeleteCheckScript(script.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除检查脚本失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "检查脚本已删除"})
+}
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+	"github.com/user/server-ops-backend/services"
+)
+
+// callCheckScriptHandler 以 JSON 请求体调用处理函数，params 为路径参数
+func callCheckScriptHandler(handler gin.HandlerFunc, method string, params gin.Params, body interface{}) (int, map[string]interface{}) {
+	data, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, "/", bytes.NewReader(data))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = params
+	handler(c)
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestCheckScripts(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.CheckScript{}, &models.ServerMonitor{}, &models.TrafficHourly{}, &models.DiskMountStat{}))
+	server := models.Server{Name: "script-host", SecretKey: "check-script-test"}
+	assert.NoError(t, db.Create(&server).Error)
+	other := models.Server{Name: "other-host", SecretKey: "check-script-other"}
+	assert.NoError(t, db.Create(&other).Error)
+	defer db.Unscoped().Delete(&server)
+	defer db.Unscoped().Delete(&other)
+	defer db.Where("1 = 1").Delete(&models.CheckScript{})
+	defer db.Where("server_id = ?", server.ID).Delete(&models.ServerMonitor{})
+
+	serverParams := gin.Params{{Key: "id", Value: fmt.Sprint(server.ID)}}
+	for _, body := range []map[string]interface{}{
+		{"name": "Queue-Depth", "interpreter": "sh", "content": "echo 1"},
+		{"name": "queue", "interpreter": "ruby", "content": "puts 1"},
+		{"name": "queue", "interpreter": "sh", "content": "  "},
+		{"name": "queue", "interpreter": "sh", "content": "echo 1", "interval": 10},
+		{"name": "queue", "interpreter": "sh", "content": "echo 1", "interval": 30, "timeout": 45},
+	} {
+		code, _ := callCheckScriptHandler(CreateCheckScript, http.MethodPost, serverParams, body)
+		assert.Equal(t, http.StatusBadRequest, code, body)
+	}
+
+	code, resp := callCheckScriptHandler(CreateCheckScript, http.MethodPost, serverParams, map[string]interface{}{
+		"name": "queue", "interpreter": "sh", "content": "echo depth=120",
+	})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(models.DefaultCheckScriptInterval), resp["interval"])
+	assert.Equal(t, float64(models.DefaultCheckScriptTimeout), resp["timeout"])
+	queueID := uint(resp["id"].(float64))
+	code, _ = callCheckScriptHandler(CreateCheckScript, http.MethodPost, serverParams, map[string]interface{}{
+		"name": "queue", "interpreter": "bash", "content": "echo 1",
+	})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, resp = callCheckScriptHandler(CreateCheckScript, http.MethodPost, serverParams, map[string]interface{}{
+		"name": "backup", "interpreter": "bash", "content": "exit 1", "enabled": false,
+	})
+	assert.Equal(t, http.StatusOK, code)
+	backupID := uint(resp["id"].(float64))
+
+	// 只下发已启用的脚本，监控模式的服务器不下发
+	scripts := agentCheckScripts(&server)
+	if assert.Len(t, scripts, 1) {
+		assert.Equal(t, "echo depth=120", scripts[0].Content)
+	}
+	monitorOnly := server
+	monitorOnly.AgentType = "monitor"
+	assert.Empty(t, agentCheckScripts(&monitorOnly))
+
+	// 其他服务器的路径不能访问该脚本
+	code, _ = callCheckScriptHandler(UpdateCheckScript, http.MethodPut, gin.Params{{Key: "id", Value: fmt.Sprint(other.ID)}, {Key: "script_id", Value: fmt.Sprint(queueID)}},
+		map[string]interface{}{"name": "queue", "interpreter": "sh", "content": "echo 1"})
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// 上报的结果：其他服务器的脚本被忽略，指标可在预警规则中使用
+	now := time.Now()
+	record, err := persistMonitorPayload(&server, &MonitorPayload{Scripts: []CheckScriptResultPayload{
+		{CheckID: queueID, Timestamp: now.UnixMilli(), ExitCode: 0, DurationMs: 12, Metrics: map[string]float64{"Depth": 120}, Output: "ok"},
+		{CheckID: backupID, Timestamp: now.UnixMilli(), ExitCode: 1, Error: "backup too old"},
+		{CheckID: 99999, Timestamp: now.UnixMilli(), ExitCode: 3},
+	}})
+	assert.NoError(t, err)
+	var saved models.ServerMonitor
+	assert.NoError(t, db.First(&saved, record.ID).Error)
+	assert.Len(t, saved.CheckScriptSamples(), 2)
+
+	payload, err := json.Marshal(buildMonitorData(&server, record))
+	assert.NoError(t, err)
+	assert.Contains(t, string(payload), `"scripts":[{"name":"queue"`)
+
+	for expr, want := range map[string]bool{
+		"script.queue.depth > 100":        true,
+		"script.queue.exit_code == 0":     true,
+		"script.backup.exit_code != 0":    true,
+		"scripts_failed == 1":             true,
+		"script.queue.missing > 0":        false,
+		"script.nosuch.exit_code == 0":    false,
+		"script.queue.depth > 100 for 1m": true,
+	} {
+		matched, _, err := services.PreviewAlertRule(expr, server, saved)
+		assert.NoError(t, err, expr)
+		assert.Equal(t, want, matched, expr)
+	}
+	_, _, err = services.PreviewAlertRule("script.queue > 0", server, saved)
+	assert.Error(t, err)
+
+	code, resp = callCheckScriptHandler(ListCheckScripts, http.MethodGet, serverParams, nil)
+	assert.Equal(t, http.StatusOK, code)
+	if list, ok := resp["scripts"].([]interface{}); assert.True(t, ok) && assert.Len(t, list, 2) {
+		queue := list[0].(map[string]interface{})
+		assert.Equal(t, "ok", queue["last_output"])
+		assert.Equal(t, map[string]interface{}{"depth": float64(120)}, queue["last_metrics"])
+	}
+
+	// 较旧的结果（如断线补发）不覆盖最近状态
+	_, err = persistMonitorPayload(&server, &MonitorPayload{Scripts: []CheckScriptResultPayload{
+		{CheckID: queueID, Timestamp: now.Add(-time.Minute).UnixMilli(), ExitCode: 2},
+	}})
+	assert.NoError(t, err)
+	script, err := models.GetCheckScript(server.ID, queueID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, script.LastExitCode)
+
+	queueParams := gin.Params{serverParams[0], {Key: "script_id", Value: fmt.Sprint(queueID)}}
+	code, resp = callCheckScriptHandler(UpdateCheckScript, http.MethodPut, queueParams, map[string]interface{}{
+		"name": "queue", "interpreter": "python3", "content": "print('depth=1')", "interval": 300, "timeout": 30,
+	})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "python3", resp["interpreter"])
+	code, _ = callCheckScriptHandler(DeleteCheckScript, http.MethodDelete, queueParams, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, agentCheckScripts(&server))
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/user/server-ops-backend/models"
+)
+
+// 时钟偏差超过该值时记录警告日志
+const clockOffsetWarnThreshold = 5 * time.Second
+
+// replyTimeSync 收到带发送时间戳的 system_info 后回复面板的接收/发送时间，
+// Agent 据此按 NTP 方式估算时钟偏差和往返时延，再通过 time_sync_result 上报。
+// 旧版本Agent不携带 sent_at，不做回复。
+func replyTimeSync(conn *SafeConn, message []byte, receivedAt time.Time) {
+	var req struct {
+		SentAt int64 `json:"sent_at"` // Agent发送时间(Unix毫秒)
+	}
+	if err := json.Unmarshal(message, &req); err != nil || req.SentAt <= 0 {
+		return
+	}
+
+	reply := map[string]interface{}{
+		"type": "time_sync",
+		"payload": map[string]interface{}{
+			"agent_sent_at":      req.SentAt,
+			"server_received_at": receivedAt.UnixMilli(),
+			"server_sent_at":     time.Now().UnixMilli(),
+		},
+	}
+	if err := conn.WriteJSON(reply); err != nil {
+		log.Printf("发送时钟同步响应失败: %v", err)
+	}
+}
+
+// handleTimeSyncResult 保存Agent上报的时钟偏差
+func handleTimeSyncResult(server *models.Server, payload json.RawMessage) {
+	var result struct {
+		OffsetMs int64 `json:"offset_ms"`
+		RTTMs    int64 `json:"rtt_ms"`
+	}
+	if err := json.Unmarshal(payload, &result); err != nil {
+		log.Printf("解析时钟同步结果失败: %v", err)
+		return
+	}
+	if result.RTTMs < 0 {
+		log.Printf("服务器 %d 上报的往返时延无效: %dms", server.ID, result.RTTMs)
+		return
+	}
+
+	offset := time.Duration(result.OffsetMs) * time.Millisecond
+	if offset > clockOffsetWarnThreshold || offset < -clockOffsetWarnThreshold {
+		log.Printf("警告: 服务器 %d 的时钟与面板相差 %s（往返时延 %dms），监控数据的时间可能不准确",
+			server.ID, offset, result.RTTMs)
+	}
+
+	now := time.Now()
+	server.ClockOffsetMs = result.OffsetMs
+	server.ClockRTTMs = result.RTTMs
+	server.ClockCheckedAt = &now
+	updates := map[string]interface{}{
+		"clock_offset_ms":  result.OffsetMs,
+		"clock_rtt_ms":     result.RTTMs,
+		"clock_checked_at": now,
+	}
+	if err := models.DB.Model(&models.Server{}).Where("id = ?", server.ID).Updates(updates).Error; err != nil {
+		log.Printf("保存服务器 %d 的时钟偏差失败: %v", server.ID, err)
+	}
+}
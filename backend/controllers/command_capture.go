@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 命令输出采集请求的响应通道
+var commandCaptureChannels sync.Map
+
+// commandCaptureRequest 启动命令输出采集的请求参数
+type commandCaptureRequest struct {
+	Command    string `json:"command"`
+	Dir        string `json:"dir"`         // 输出目录，默认 Agent 配置的 capture_roots 中的第一个
+	Name       string `json:"name"`        // 输出文件名前缀
+	TimeoutSec int    `json:"timeout_sec"` // 最长执行时间，不超过 Agent 的 capture_timeout
+	MaxSizeMB  int    `json:"max_size_mb"` // 输出文件大小上限，不超过 Agent 的 capture_max_size_mb
+}
+
+// StartCommandCapture 在服务器上后台执行非交互命令，输出写入 Agent 的 capture_roots 下的文件，
+// 立即返回输出文件路径，完成后通过文件管理下载。仅管理员可用
+func StartCommandCapture(c *gin.Context) {
+	var req commandCaptureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+	if strings.TrimSpace(req.Command) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "命令不能为空"})
+		return
+	}
+	if req.TimeoutSec < 0 || req.MaxSizeMB < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "超时和大小上限不能为负数"})
+		return
+	}
+
+	requestAgent(c, "command_capture", &commandCaptureChannels, map[string]interface{}{
+		"action":      "start",
+		"command":     req.Command,
+		"dir":         req.Dir,
+		"name":        req.Name,
+		"timeout_sec": req.TimeoutSec,
+		"max_size_mb": req.MaxSizeMB,
+	})
+}
+
+// ListCommandCaptures 返回进行中和最近结束的命令输出采集，以及 Agent 允许的输出目录和上限
+func ListCommandCaptures(c *gin.Context) {
+	requestAgent(c, "command_capture", &commandCaptureChannels, map[string]interface{}{
+		"action": "list",
+	})
+}
+
+// StopCommandCapture 终止进行中的命令输出采集，已写入的输出保留
+func StopCommandCapture(c *gin.Context) {
+	requestAgent(c, "command_capture", &commandCaptureChannels, map[string]interface{}{
+		"action": "stop",
+		"id":     c.Param("capture_id"),
+	})
+}
+
+// HandleCommandCaptureResponse 将Agent的命令输出采集响应传递给等待中的HTTP请求
+func HandleCommandCaptureResponse(requestID string, data map[string]interface{}) {
+	deliverAgentResponse(&commandCaptureChannels, requestID, data)
+}
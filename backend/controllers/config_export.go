@@ -0,0 +1,415 @@
+package controllers
+
+import (
+	"crypto/pbkdf2"
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+	"github.com/user/server-ops-backend/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// 导出文件格式版本，格式不兼容时递增
+	configExportVersion = 1
+	// 导出/导入时通过该请求头传递加密密钥的口令
+	configPassphraseHeader = "X-Export-Passphrase"
+	// 由口令派生密钥的迭代次数
+	configKeyIterations = 200000
+)
+
+// ConfigExport 面板配置的导出格式，用于备份和迁移到新实例。
+// 服务器之间的引用（预警规则的 server_id）使用导出时的原始ID，导入时重新映射
+type ConfigExport struct {
+	Version              int                     `json:"version"`
+	ExportedAt           time.Time               `json:"exported_at"`
+	SecretSalt           string                  `json:"secret_salt,omitempty"` // 非空表示包含加密的密钥
+	Settings             *models.SystemSettings  `json:"settings,omitempty"`
+	Servers              []ExportedServer        `json:"servers"`
+	AlertSettings        []ExportedAlertSetting  `json:"alert_settings"`
+	NotificationChannels []ExportedNotifyChannel `json:"notification_channels"`
+}
+
+// ExportedServer 导出的服务器配置，不包含 Agent 上报的系统信息和监控数据
+type ExportedServer struct {
+	ID              uint   `json:"id"`
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	Tags            string `json:"tags"`
+	AgentType       string `json:"agent_type"`
+	AllowPublicView bool   `json:"allow_public_view"`
+	TrafficResetDay int    `json:"traffic_reset_day"`
+	SortOrder       int    `json:"sort_order"`
+	SecretKey       string `json:"secret_key,omitempty"` // 加密后的 Agent 密钥，未提供口令时不导出
+}
+
+// ExportedAlertSetting 导出的预警规则，ServerID 为 0 表示全局规则
+type ExportedAlertSetting struct {
+	Type      string  `json:"type"`
+	Threshold float64 `json:"threshold"`
+	Duration  int     `json:"duration"`
+	Smoothing float64 `json:"smoothing"`
+	Enabled   bool    `json:"enabled"`
+	ServerID  uint    `json:"server_id"`
+}
+
+// ExportedNotifyChannel 导出的通知渠道，密码、SendKey 等敏感字段单独加密保存在 Secrets 中
+type ExportedNotifyChannel struct {
+	Type       string            `json:"type"`
+	Name       string            `json:"name"`
+	Enabled    bool              `json:"enabled"`
+	Categories string            `json:"categories,omitempty"`
+	Config     map[string]string `json:"config"`
+	Secrets    map[string]string `json:"secrets,omitempty"`
+}
+
+// ConfigImportResult 导入结果统计
+type ConfigImportResult struct {
+	ServersCreated  int      `json:"servers_created"`
+	ServersSkipped  int      `json:"servers_skipped"`
+	ChannelsCreated int      `json:"channels_created"`
+	ChannelsSkipped int      `json:"channels_skipped"`
+	AlertsCreated   int      `json:"alerts_created"`
+	AlertsSkipped   int      `json:"alerts_skipped"`
+	SettingsApplied bool     `json:"settings_applied"`
+	Warnings        []string `json:"warnings,omitempty"`
+}
+
+// isSecretConfigKey 判断通知渠道配置项是否为敏感信息
+func isSecretConfigKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range []string{"password", "secret", "token", "key", "webhook"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// newSecretBox 使用由口令派生的密钥对敏感字段做 AES-GCM 加密
+func newSecretBox(passphrase string, salt []byte) (*utils.SecretBox, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, configKeyIterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	return utils.NewSecretBox(key)
+}
+
+// buildConfigExport 汇总当前配置；box 为 nil 时不导出任何密钥
+func buildConfigExport(box *utils.SecretBox, salt []byte) (*ConfigExport, error) {
+	export := &ConfigExport{
+		Version:              configExportVersion,
+		ExportedAt:           time.Now(),
+		Servers:              []ExportedServer{},
+		AlertSettings:        []ExportedAlertSetting{},
+		NotificationChannels: []ExportedNotifyChannel{},
+	}
+	if box != nil {
+		export.SecretSalt = base64.StdEncoding.EncodeToString(salt)
+	}
+
+	settings, err := models.GetSettings()
+	if err != nil {
+		return nil, fmt.Errorf("读取系统设置失败: %w", err)
+	}
+	export.Settings = settings
+
+	servers, err := models.GetAllServers(0)
+	if err != nil {
+		return nil, fmt.Errorf("读取服务器列表失败: %w", err)
+	}
+	for _, server := range servers {
+		item := ExportedServer{
+			ID:              server.ID,
+			Name:            server.Name,
+			Description:     server.Description,
+			Tags:            server.Tags,
+			AgentType:       server.AgentType,
+			AllowPublicView: server.AllowPublicView,
+			TrafficResetDay: server.TrafficResetDay,
+			SortOrder:       server.SortOrder,
+		}
+		if box != nil && server.SecretKey != "" {
+			if item.SecretKey, err = box.Seal(server.SecretKey); err != nil {
+				return nil, err
+			}
+		}
+		export.Servers = append(export.Servers, item)
+	}
+
+	var alerts []models.AlertSetting
+	if err := models.DB.Order("id ASC").Find(&alerts).Error; err != nil {
+		return nil, fmt.Errorf("读取预警设置失败: %w", err)
+	}
+	for _, alert := range alerts {
+		export.AlertSettings = append(export.AlertSettings, ExportedAlertSetting{
+			Type:      alert.Type,
+			Threshold: alert.Threshold,
+			Duration:  alert.Duration,
+			Smoothing: alert.Smoothing,
+			Enabled:   alert.Enabled,
+			ServerID:  alert.ServerID,
+		})
+	}
+
+	channels, err := models.GetAllNotificationChannels()
+	if err != nil {
+		return nil, fmt.Errorf("读取通知渠道失败: %w", err)
+	}
+	for _, channel := range channels {
+		config, err := channel.GetChannelConfig()
+		if err != nil {
+			log.Printf("导出配置时解析通知渠道 %s 的配置失败: %v", channel.Name, err)
+			config = map[string]string{}
+		}
+		item := ExportedNotifyChannel{Type: channel.Type, Name: channel.Name, Enabled: channel.Enabled, Categories: channel.Categories, Config: map[string]string{}}
+		for key, value := range config {
+			if !isSecretConfigKey(key) {
+				item.Config[key] = value
+				continue
+			}
+			if box == nil || value == "" {
+				continue
+			}
+			if item.Secrets == nil {
+				item.Secrets = map[string]string{}
+			}
+			if item.Secrets[key], err = box.Seal(value); err != nil {
+				return nil, err
+			}
+		}
+		export.NotificationChannels = append(export.NotificationChannels, item)
+	}
+	return export, nil
+}
+
+// importConfig 将导出的配置写入当前实例。
+// 已存在的同名同类型通知渠道和相同的预警规则会被跳过；服务器只有在还原了密钥时才能识别为已存在，
+// 因此不带口令重复导入会再次创建服务器
+func importConfig(export *ConfigExport, box *utils.SecretBox) (*ConfigImportResult, error) {
+	result := &ConfigImportResult{}
+	if export.SecretSalt != "" && box == nil {
+		result.Warnings = append(result.Warnings, "导出文件包含加密的密钥但未提供口令，服务器将生成新密钥，通知渠道的密码等需要重新填写")
+	}
+
+	// 先解密全部密钥，口令错误时在写入任何数据之前返回
+	serverSecrets := make([]string, len(export.Servers))
+	channelConfigs := make([]map[string]string, len(export.NotificationChannels))
+	for i, item := range export.Servers {
+		if box == nil || item.SecretKey == "" {
+			continue
+		}
+		secret, err := box.Open(item.SecretKey)
+		if err != nil {
+			return nil, fmt.Errorf("解密服务器 %s 的密钥失败: %w", item.Name, err)
+		}
+		serverSecrets[i] = secret
+	}
+	for i, item := range export.NotificationChannels {
+		config := make(map[string]string, len(item.Config)+len(item.Secrets))
+		for key, value := range item.Config {
+			config[key] = value
+		}
+		for key, sealed := range item.Secrets {
+			if box == nil {
+				break
+			}
+			value, err := box.Open(sealed)
+			if err != nil {
+				return nil, fmt.Errorf("解密通知渠道 %s 的 %s 失败: %w", item.Name, key, err)
+			}
+			config[key] = value
+		}
+		channelConfigs[i] = config
+	}
+
+	// 系统设置和其他数据在同一个事务中写入，任何一项失败时都不会留下部分导入的结果
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if export.Settings != nil {
+			settings := *export.Settings
+			settings.Model = gorm.Model{}
+			if err := models.SaveSettingsTx(tx, &settings); err != nil {
+				return fmt.Errorf("导入系统设置失败: %w", err)
+			}
+			result.SettingsApplied = true
+		}
+
+		serverIDs := make(map[uint]uint, len(export.Servers))
+		for i, item := range export.Servers {
+			secret := serverSecrets[i]
+			if secret != "" {
+				var existing models.Server
+				if err := tx.Where("secret_key = ?", secret).First(&existing).Error; err == nil {
+					serverIDs[item.ID] = existing.ID
+					result.ServersSkipped++
+					continue
+				}
+			} else {
+				secret = generateRandomKey()
+			}
+
+			agentType := item.AgentType
+			if agentType != "full" && agentType != "monitor" {
+				agentType = "full"
+			}
+			server := models.Server{
+				Name:            item.Name,
+				Description:     item.Description,
+				Tags:            item.Tags,
+				AgentType:       agentType,
+				AllowPublicView: item.AllowPublicView,
+				TrafficResetDay: item.TrafficResetDay,
+				SortOrder:       item.SortOrder,
+				SecretKey:       secret,
+				Status:          "offline",
+			}
+			if err := tx.Create(&server).Error; err != nil {
+				return fmt.Errorf("创建服务器 %s 失败: %w", item.Name, err)
+			}
+			serverIDs[item.ID] = server.ID
+			result.ServersCreated++
+		}
+
+		for i, item := range export.NotificationChannels {
+			var count int64
+			tx.Model(&models.NotificationChannel{}).Where("type = ? AND name = ?", item.Type, item.Name).Count(&count)
+			if count > 0 {
+				result.ChannelsSkipped++
+				continue
+			}
+			configJSON, err := json.Marshal(channelConfigs[i])
+			if err != nil {
+				return err
+			}
+			categories, err := normalizeAlertCategories(item.Categories)
+			if err != nil {
+				return fmt.Errorf("通知渠道 %s: %w", item.Name, err)
+			}
+			channel := models.NotificationChannel{Type: item.Type, Name: item.Name, Config: string(configJSON), Categories: categories}
+			if err := createWithEnabled(tx, &channel, item.Enabled); err != nil {
+				return fmt.Errorf("创建通知渠道 %s 失败: %w", item.Name, err)
+			}
+			result.ChannelsCreated++
+		}
+
+		for _, item := range export.AlertSettings {
+			serverID := item.ServerID
+			if serverID != 0 {
+				mapped, ok := serverIDs[serverID]
+				if !ok {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("跳过引用了未知服务器 %d 的 %s 预警规则", serverID, item.Type))
+					result.AlertsSkipped++
+					continue
+				}
+				serverID = mapped
+			}
+			var count int64
+			tx.Model(&models.AlertSetting{}).Where("type = ? AND server_id = ? AND threshold = ?", item.Type, serverID, item.Threshold).Count(&count)
+			if count > 0 {
+				result.AlertsSkipped++
+				continue
+			}
+			alert := models.AlertSetting{
+				Type:      item.Type,
+				Threshold: item.Threshold,
+				Duration:  item.Duration,
+				Smoothing: item.Smoothing,
+				ServerID:  serverID,
+			}
+			if err := createWithEnabled(tx, &alert, item.Enabled); err != nil {
+				return fmt.Errorf("创建 %s 预警规则失败: %w", item.Type, err)
+			}
+			result.AlertsCreated++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// createWithEnabled 创建带 enabled 字段（默认值为 true）的记录。
+// GORM 创建时会用默认值替换零值，因此禁用状态需要在创建后单独更新
+func createWithEnabled(tx *gorm.DB, value interface{}, enabled bool) error {
+	if err := tx.Create(value).Error; err != nil {
+		return err
+	}
+	if enabled {
+		return nil
+	}
+	return tx.Model(value).Update("enabled", false).Error
+}
+
+// ExportConfig 导出服务器、预警规则、通知渠道和系统设置。
+// 请求头提供口令时一并导出加密后的 Agent 密钥和通知渠道密码，否则不包含任何密钥
+func ExportConfig(c *gin.Context) {
+	var box *utils.SecretBox
+	var salt []byte
+	if passphrase := c.GetHeader(configPassphraseHeader); passphrase != "" {
+		salt = make([]byte, 16)
+		if _, err := cryptorand.Read(salt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成加密参数失败"})
+			return
+		}
+		var err error
+		if box, err = newSecretBox(passphrase, salt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "初始化加密失败: " + err.Error()})
+			return
+		}
+	}
+
+	export, err := buildConfigExport(box, salt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("bettermonitor-config-%s.json", export.ExportedAt.Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.JSON(http.StatusOK, export)
+}
+
+// ImportConfig 导入 ExportConfig 生成的配置。导出时使用了口令的，需要在请求头提供相同口令才能还原密钥
+func ImportConfig(c *gin.Context) {
+	var export ConfigExport
+	if err := c.ShouldBindJSON(&export); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的导入文件: " + err.Error()})
+		return
+	}
+	if export.Version < 1 || export.Version > configExportVersion {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("不支持的导出文件版本: %d", export.Version)})
+		return
+	}
+
+	var box *utils.SecretBox
+	if passphrase := c.GetHeader(configPassphraseHeader); passphrase != "" && export.SecretSalt != "" {
+		salt, err := base64.StdEncoding.DecodeString(export.SecretSalt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "导入文件中的加密参数无效"})
+			return
+		}
+		if box, err = newSecretBox(passphrase, salt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "初始化解密失败: " + err.Error()})
+			return
+		}
+	}
+
+	result, err := importConfig(&export, box)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("导入配置完成: 新建服务器 %d 个，通知渠道 %d 个，预警规则 %d 条",
+		result.ServersCreated, result.ChannelsCreated, result.AlertsCreated)
+	c.JSON(http.StatusOK, gin.H{"success": true, "result": result})
+}
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+	"github.com/user/server-ops-backend/utils"
+)
+
+func TestConfigExportImportRoundTrip(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.AlertSetting{}, &models.NotificationChannel{}))
+	cleanup := func() {
+		db.Unscoped().Where("1 = 1").Delete(&models.Server{})
+		db.Unscoped().Where("1 = 1").Delete(&models.AlertSetting{})
+		db.Unscoped().Where("1 = 1").Delete(&models.NotificationChannel{})
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	server := models.Server{Name: "web-01", Tags: "env:prod", AgentType: "monitor", SecretKey: "export-secret"}
+	assert.NoError(t, db.Create(&server).Error)
+	assert.NoError(t, db.Create(&models.AlertSetting{Type: "cpu", Threshold: 90, Duration: 60, ServerID: server.ID}).Error)
+	channel := models.NotificationChannel{Type: "serverchan", Name: "运维群", Config: `{"sendkey":"SCT123","title":"告警"}`}
+	assert.NoError(t, db.Create(&channel).Error)
+	assert.NoError(t, db.Model(&channel).Update("enabled", false).Error)
+
+	// 不提供口令时不导出任何密钥
+	plain, err := buildConfigExport(nil, nil)
+	assert.NoError(t, err)
+	data, _ := json.Marshal(plain)
+	assert.NotContains(t, string(data), "export-secret")
+	assert.NotContains(t, string(data), "SCT123")
+
+	salt := []byte("0123456789abcdef")
+	box, err := newSecretBox("passphrase", salt)
+	assert.NoError(t, err)
+	export, err := buildConfigExport(box, salt)
+	assert.NoError(t, err)
+	data, _ = json.Marshal(export)
+	assert.NotContains(t, string(data), "export-secret")
+
+	// 模拟导入到全新的实例
+	cleanup()
+	var imported ConfigExport
+	assert.NoError(t, json.Unmarshal(data, &imported))
+
+	wrongBox, _ := newSecretBox("wrong", salt)
+	_, err = importConfig(&imported, wrongBox)
+	assert.ErrorIs(t, err, utils.ErrSecretBoxOpen)
+	var count int64
+	db.Model(&models.Server{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	result, err := importConfig(&imported, box)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.ServersCreated)
+	assert.Equal(t, 1, result.ChannelsCreated)
+	assert.Equal(t, 1, result.AlertsCreated)
+
+	var restored models.Server
+	assert.NoError(t, db.Where("secret_key = ?", "export-secret").First(&restored).Error)
+	assert.Equal(t, "web-01", restored.Name)
+	assert.Equal(t, "monitor", restored.AgentType)
+
+	var alert models.AlertSetting
+	assert.NoError(t, db.First(&alert).Error)
+	assert.Equal(t, restored.ID, alert.ServerID)
+
+	var restoredChannel models.NotificationChannel
+	assert.NoError(t, db.First(&restoredChannel).Error)
+	assert.False(t, restoredChannel.Enabled)
+	config, err := restoredChannel.GetChannelConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "SCT123", config["sendkey"])
+
+	// 重复导入时跳过已存在的配置
+	result, err = importConfig(&imported, box)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.ServersCreated+result.ChannelsCreated+result.AlertsCreated)
+}
+
+func TestConfigImportRollsBackSettings(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.AlertSetting{}, &models.NotificationChannel{}))
+	cleanup := func() {
+		db.Unscoped().Where("1 = 1").Delete(&models.Server{})
+		db.Unscoped().Where("1 = 1").Delete(&models.NotificationChannel{})
+		db.Unscoped().Where("1 = 1").Delete(&models.SystemSettings{})
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	current, err := models.GetSettings()
+	assert.NoError(t, err)
+	imported := *current
+	imported.MonitorInterval = "5m"
+
+	// 通知渠道的分类无效，事务失败时系统设置和已创建的服务器都不保留
+	_, err = importConfig(&ConfigExport{
+		Version:              configExportVersion,
+		Settings:             &imported,
+		Servers:              []ExportedServer{{Name: "web-01"}},
+		NotificationChannels: []ExportedNotifyChannel{{Type: "email", Name: "运维", Categories: "unknown"}},
+	}, nil)
+	assert.ErrorContains(t, err, "无效的预警分类")
+
+	after, err := models.GetSettings()
+	assert.NoError(t, err)
+	assert.Equal(t, current.MonitorInterval, after.MonitorInterval)
+	var count int64
+	db.Model(&models.Server{}).Count(&count)
+	assert.Zero(t, count)
+
+	// 系统设置无效时同样不写入任何数据
+	imported.MonitorInterval = "1ms"
+	_, err = importConfig(&ConfigExport{Version: configExportVersion, Settings: &imported, Servers: []ExportedServer{{Name: "web-01"}}}, nil)
+	assert.ErrorContains(t, err, "导入系统设置失败")
+	db.Model(&models.Server{}).Count(&count)
+	assert.Zero(t, count)
+}
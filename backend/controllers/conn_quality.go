@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+const (
+	// 写入延迟的滑动平均权重，新样本占 20%
+	connLatencyAlpha = 0.2
+	// 平均写入延迟低于该值视为链路良好
+	connLatencyGood = 50 * time.Millisecond
+	// 平均写入延迟高于该值视为链路较差
+	connLatencyPoor = 250 * time.Millisecond
+	// 最大写入延迟只统计最近这段时间，避免一次偶发卡顿长期影响指标
+	connLatencyMaxWindow = time.Minute
+)
+
+// ConnQuality 单个 WebSocket 连接的写入质量
+type ConnQuality struct {
+	AvgMs      float64 `json:"avg_ms"`      // 写入延迟的滑动平均
+	MaxMs      float64 `json:"max_ms"`      // 最近一分钟内的最大写入延迟
+	Samples    int64   `json:"samples"`     // 累计写入次数
+	SlowWrites int64   `json:"slow_writes"` // 超过较差阈值的写入次数
+	Level      string  `json:"level"`       // good/fair/poor，样本不足时为 unknown
+	LastWrite  int64   `json:"last_write"`  // 最近一次写入的时间戳（秒）
+}
+
+// connWriteStats 统计 WriteJSON/WriteMessage 的耗时。
+// 写入会阻塞在 TCP 发送缓冲区上，对端网络拥塞或处理变慢时耗时明显上升，
+// 因此可以据此区分终端卡顿是面板到用户、还是面板到 Agent 的链路问题
+type connWriteStats struct {
+	mu         sync.Mutex
+	avg        time.Duration
+	max        time.Duration
+	maxAt      time.Time
+	samples    int64
+	slowWrites int64
+	lastWrite  time.Time
+}
+
+func (s *connWriteStats) record(d time.Duration, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.samples == 0 {
+		s.avg = d
+	} else {
+		s.avg = time.Duration(connLatencyAlpha*float64(d) + (1-connLatencyAlpha)*float64(s.avg))
+	}
+	if d >= s.max || now.Sub(s.maxAt) > connLatencyMaxWindow {
+		s.max = d
+		s.maxAt = now
+	}
+	if d >= connLatencyPoor {
+		s.slowWrites++
+	}
+	s.samples++
+	s.lastWrite = now
+}
+
+func (s *connWriteStats) quality() ConnQuality {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q := ConnQuality{
+		AvgMs:      float64(s.avg.Microseconds()) / 1000,
+		MaxMs:      float64(s.max.Microseconds()) / 1000,
+		Samples:    s.samples,
+		SlowWrites: s.slowWrites,
+		Level:      "unknown",
+	}
+	if s.samples == 0 {
+		return q
+	}
+	q.LastWrite = s.lastWrite.Unix()
+	switch {
+	case s.avg < connLatencyGood:
+		q.Level = "good"
+	case s.avg < connLatencyPoor:
+		q.Level = "fair"
+	default:
+		q.Level = "poor"
+	}
+	return q
+}
+
+// connQualityOf 返回连接映射中保存的连接的写入质量，连接不存在时返回 nil
+func connQualityOf(m *sync.Map, key interface{}) *ConnQuality {
+	val, ok := m.Load(key)
+	if !ok {
+		return nil
+	}
+	conn, ok := val.(*SafeConn)
+	if !ok {
+		return nil
+	}
+	q := conn.Quality()
+	return &q
+}
+
+// GetConnectionQuality 返回面板到 Agent 的连接质量，
+// 指定 session 参数时同时返回该终端会话面板到用户浏览器的连接质量
+func GetConnectionQuality(c *gin.Context) {
+	serverID, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+	if _, err := models.GetServerByID(serverID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+
+	resp := gin.H{"agent": connQualityOf(&ActiveAgentConnections, serverID)}
+	if sessionID := c.Query("session"); sessionID != "" {
+		// 只返回当前用户自己在该服务器上的终端会话
+		var terminal *ConnQuality
+		if val, ok := terminalSessions.Load(sessionID); ok {
+			session, _ := val.(TerminalSession)
+			if userID, _ := c.Get("userId"); session.ServerID == serverID && session.UserID == userID {
+				terminal = connQualityOf(&ActiveTerminalConnections, sessionID)
+			}
+		}
+		resp["terminal"] = terminal
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnWriteStatsQuality(t *testing.T) {
+	var stats connWriteStats
+	assert.Equal(t, "unknown", stats.quality().Level)
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		stats.record(5*time.Millisecond, now)
+	}
+	q := stats.quality()
+	assert.Equal(t, "good", q.Level)
+	assert.Equal(t, int64(5), q.Samples)
+	assert.InDelta(t, 5, q.AvgMs, 0.01)
+
+	// 持续的慢写入会逐步拉高滑动平均
+	for i := 0; i < 20; i++ {
+		stats.record(400*time.Millisecond, now)
+	}
+	q = stats.quality()
+	assert.Equal(t, "poor", q.Level)
+	assert.Equal(t, int64(20), q.SlowWrites)
+	assert.InDelta(t, 400, q.MaxMs, 0.01)
+
+	// 最大值只保留最近一分钟
+	stats.record(time.Millisecond, now.Add(2*time.Minute))
+	assert.InDelta(t, 1, stats.quality().MaxMs, 0.01)
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 连接列表请求的响应通道
+var connectionListChannels sync.Map
+
+// 监听端口请求的响应通道
+var listeningPortsChannels sync.Map
+
+// netstat 请求的响应通道
+var netstatChannels sync.Map
+
+// GetConnections 获取服务器上的 TCP 连接及其所属进程。
+// 默认只返回 ESTABLISHED 状态，status=all 返回全部；结果数量由 Agent 限制在上限内
+func GetConnections(c *gin.Context) {
+	status := strings.TrimSpace(c.Query("status"))
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的limit参数"})
+			return
+		}
+		limit = parsed
+	}
+
+	requestAgentWithTimeout(c, "connection_list", &connectionListChannels, map[string]interface{}{
+		"status": status,
+		"limit":  limit,
+	}, TimeoutProcessQuery)
+}
+
+// HandleConnectionListResponse 将Agent的连接列表响应传递给等待中的HTTP请求
+func HandleConnectionListResponse(requestID string, data map[string]interface{}) {
+	deliverAgentResponse(&connectionListChannels, requestID, data)
+}
+
+// HandleConnectionListError Agent 获取连接列表失败时回复 error 类型的消息，交给等待中的HTTP请求；
+// 返回 false 表示不是连接列表请求
+func HandleConnectionListError(requestID string, data map[string]interface{}) bool {
+	if _, ok := connectionListChannels.Load(requestID); !ok {
+		return false
+	}
+	deliverAgentResponse(&connectionListChannels, requestID, data)
+	return true
+}
+
+// GetListeningPorts 获取服务器上所有监听中的 TCP/UDP 端口及其所属进程，结果数量由 Agent 限制在上限内
+func GetListeningPorts(c *gin.Context) {
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的limit参数"})
+			return
+		}
+		limit = parsed
+	}
+
+	requestAgentWithTimeout(c, "listening_ports", &listeningPortsChannels, map[string]interface{}{
+		"limit": limit,
+	}, TimeoutProcessQuery)
+}
+
+// HandleListeningPortsResponse 将Agent的监听端口响应传递给等待中的HTTP请求
+func HandleListeningPortsResponse(requestID string, data map[string]interface{}) {
+	deliverAgentResponse(&listeningPortsChannels, requestID, data)
+}
+
+// GetNetstat 获取服务器 TCP 连接的状态分布，listening=false 时不附带监听端口清单
+func GetNetstat(c *gin.Context) {
+	listening := true
+	if listeningStr := c.Query("listening"); listeningStr != "" {
+		parsed, err := strconv.ParseBool(listeningStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的listening参数"})
+			return
+		}
+		listening = parsed
+	}
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的limit参数"})
+			return
+		}
+		limit = parsed
+	}
+
+	requestAgentWithTimeout(c, "netstat", &netstatChannels, map[string]interface{}{
+		"listening": listening,
+		"limit":     limit,
+	}, TimeoutProcessQuery)
+}
+
+// HandleNetstatResponse 将Agent的 netstat 响应传递给等待中的HTTP请求
+func HandleNetstatResponse(requestID string, data map[string]interface{}) {
+	deliverAgentResponse(&netstatChannels, requestID, data)
+}
@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleConnectionListError(t *testing.T) {
+	respChan := make(chan map[string]interface{}, 1)
+	connectionListChannels.Store("conn_list_test", respChan)
+	defer connectionListChannels.Delete("conn_list_test")
+
+	// 不是连接列表请求时交给其他处理逻辑
+	assert.False(t, HandleConnectionListError("docker_test", map[string]interface{}{"error": "x"}))
+
+	// Agent 以 error 类型回复时，等待中的请求立即拿到错误而不是等到超时
+	assert.True(t, HandleConnectionListError("conn_list_test", map[string]interface{}{"error": "获取连接列表失败: permission denied"}))
+	resp := <-respChan
+	assert.Equal(t, "获取连接列表失败: permission denied", resp["error"])
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+const (
+	// 单次上报保存的容器数上限，避免异常的 Agent 放大写入量
+	maxContainerStatsPerReport = 200
+	// 容器资源统计历史的默认和最大查询范围
+	containerStatsDefaultRange = time.Hour
+	containerStatsMaxRange     = 31 * 24 * time.Hour
+)
+
+// ContainerStatsPayload Agent 上报的单个容器资源使用情况，与 docker stats 一致
+type ContainerStatsPayload struct {
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryUsage   uint64  `json:"memory_usage"`
+	MemoryLimit   uint64  `json:"memory_limit"`
+	MemoryPercent float64 `json:"memory_percent"`
+	NetworkRx     uint64  `json:"network_rx"`
+	NetworkTx     uint64  `json:"network_tx"`
+	BlockRead     uint64  `json:"block_read"`
+	BlockWrite    uint64  `json:"block_write"`
+	PIDs          uint64  `json:"pids"`
+}
+
+// recordContainerStats 保存Agent定时上报的容器资源统计
+func recordContainerStats(server *models.Server, payload json.RawMessage) {
+	var report struct {
+		Containers []ContainerStatsPayload `json:"containers"`
+	}
+	if err := json.Unmarshal(payload, &report); err != nil {
+		log.Printf("解析服务器 %d 的容器资源统计失败: %v", server.ID, err)
+		return
+	}
+
+	now := time.Now()
+	containers := report.Containers[:min(len(report.Containers), maxContainerStatsPerReport)]
+	stats := make([]models.ContainerStat, 0, len(containers))
+	for _, c := range containers {
+		stats = append(stats, models.ContainerStat{
+			ServerID:      server.ID,
+			Timestamp:     now,
+			ContainerID:   c.ID,
+			Name:          c.Name,
+			CPUPercent:    c.CPUPercent,
+			MemoryUsage:   c.MemoryUsage,
+			MemoryLimit:   c.MemoryLimit,
+			MemoryPercent: c.MemoryPercent,
+			NetworkRx:     c.NetworkRx,
+			NetworkTx:     c.NetworkTx,
+			BlockRead:     c.BlockRead,
+			BlockWrite:    c.BlockWrite,
+			PIDs:          c.PIDs,
+		})
+	}
+	if err := models.CreateContainerStats(stats); err != nil {
+		log.Printf("保存服务器 %d 的容器资源统计失败: %v", server.ID, err)
+	}
+}
+
+// GetContainerStatsHistory 获取服务器的容器资源统计历史
+// 查询参数：
+//   - container: 容器名称，为空时返回所有容器
+//   - range: 相对当前时间的窗口（如 6h），默认 1h，最长 31 天
+func GetContainerStatsHistory(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	window := containerStatsDefaultRange
+	if rangeStr := c.Query("range"); rangeStr != "" {
+		if window, err = time.ParseDuration(rangeStr); err != nil || window <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的时间范围"})
+			return
+		}
+	}
+	if window > containerStatsMaxRange {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "查询时间范围不能超过31天"})
+		return
+	}
+
+	endTime := time.Now()
+	stats, err := models.GetContainerStats(id, c.Query("container"), endTime.Add(-window), endTime)
+	if err != nil {
+		log.Printf("[ERROR] 获取服务器ID=%d容器资源统计失败: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取容器资源统计失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestContainerStatsHistory(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.ContainerStat{}))
+	server := models.Server{Name: "docker-01", SecretKey: "container-stats-test"}
+	assert.NoError(t, models.DB.Create(&server).Error)
+	defer models.DB.Unscoped().Delete(&server)
+	defer models.DB.Where("server_id = ?", server.ID).Delete(&models.ContainerStat{})
+
+	recordContainerStats(&server, json.RawMessage(`{"containers": [
+		{"id": "a1", "name": "web", "cpu_percent": 150.5, "memory_usage": 104857600, "memory_limit": 1073741824, "network_rx": 2048, "block_write": 4096, "pids": 9},
+		{"id": "b2", "name": "db", "cpu_percent": 3.2, "memory_usage": 524288000}
+	]}`))
+	// 格式错误的上报直接丢弃
+	recordContainerStats(&server, json.RawMessage(`{"containers": "bad"}`))
+
+	query := func(rawQuery string) (int, []models.ContainerStat) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(server.ID), 10)}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/?"+rawQuery, nil)
+		GetContainerStatsHistory(c)
+		var resp struct {
+			Stats []models.ContainerStat `json:"stats"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Stats
+	}
+
+	code, stats := query("")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, stats, 2)
+
+	code, stats = query("container=web&range=6h")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, stats, 1) {
+		assert.Equal(t, 150.5, stats[0].CPUPercent)
+		assert.Equal(t, uint64(4096), stats[0].BlockWrite)
+		assert.Equal(t, uint64(9), stats[0].PIDs)
+	}
+
+	code, _ = query("range=800h")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
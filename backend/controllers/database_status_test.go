@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+	"github.com/user/server-ops-backend/services"
+)
+
+func TestDatabaseStatusInMonitorData(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.ServerMonitor{}, &models.TrafficHourly{}, &models.DiskMountStat{}))
+	server := models.Server{Name: "db-host", SecretKey: "database-status-test"}
+	assert.NoError(t, db.Create(&server).Error)
+	defer db.Unscoped().Delete(&server)
+	defer db.Where("server_id = ?", server.ID).Delete(&models.ServerMonitor{})
+
+	record, err := persistMonitorPayload(&server, &MonitorPayload{Databases: []models.DatabaseStatus{
+		{Name: "main", Type: "mysql", Up: true, Connections: 140, MaxConnections: 151, QueryRate: 320, SlowQueries: 2, Role: "primary",
+			MemoryUsed: 100 << 20, MemoryLimit: 128 << 20},
+		{Name: "replica", Type: "postgres", Up: true, Connections: 10, MaxConnections: 100, Role: "replica",
+			ReplicationLag: 45, ReplicationError: "WAL 接收进程未连接到主库"},
+		{Name: "cache", Type: "redis", Error: strings.Repeat("x", 1000)},
+	}})
+	assert.NoError(t, err)
+
+	var saved models.ServerMonitor
+	assert.NoError(t, db.First(&saved, record.ID).Error)
+	if databases := saved.DatabaseStats(); assert.Len(t, databases, 3) {
+		assert.Equal(t, 140, databases[0].Connections)
+		assert.Len(t, databases[2].Error, 255)
+	}
+
+	payload, err := json.Marshal(buildMonitorData(&server, record))
+	assert.NoError(t, err)
+	var data struct {
+		Databases []models.DatabaseStatus `json:"databases"`
+	}
+	assert.NoError(t, json.Unmarshal(payload, &data))
+	if assert.Len(t, data.Databases, 3) {
+		assert.Equal(t, "replica", data.Databases[1].Role)
+	}
+
+	for expr, want := range map[string]bool{
+		"db_down > 0":                true,
+		"db_connections_pct > 90":    true,
+		"db_replication_lag >= 45":   true,
+		"db_replication_errors == 1": true,
+		"db_slow_queries > 2":        false,
+		"db_memory_pct > 75":         true,
+	} {
+		matched, _, err := services.PreviewAlertRule(expr, server, saved)
+		assert.NoError(t, err, expr)
+		assert.Equal(t, want, matched, expr)
+	}
+
+	// 未上报数据库指标的 Agent 不带该字段，规则不会触发
+	record, err = persistMonitorPayload(&server, &MonitorPayload{TCPConnections: 3})
+	assert.NoError(t, err)
+	assert.NotContains(t, buildMonitorData(&server, record), "databases")
+	matched, _, _ := services.PreviewAlertRule("db_down == 0", server, *record)
+	assert.False(t, matched)
+}
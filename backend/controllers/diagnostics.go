@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+// BackendMetrics 后端自身的运行指标，用于排查连接泄漏和数据库性能问题
+type BackendMetrics struct {
+	Timestamp             int64                     `json:"timestamp"`
+	UptimeSeconds         float64                   `json:"uptime_seconds"`
+	Goroutines            int                       `json:"goroutines"`
+	HeapAllocBytes        uint64                    `json:"heap_alloc_bytes"`
+	SysBytes              uint64                    `json:"sys_bytes"`
+	NumGC                 uint32                    `json:"num_gc"`
+	AgentConnections      int                       `json:"agent_connections"`
+	MonitorSubscribers    int                       `json:"monitor_subscribers"`
+	TerminalConnections   int                       `json:"terminal_connections"`
+	LogStreamConnections  int                       `json:"log_stream_connections"`
+	FileScanConnections   int                       `json:"file_scan_connections"`
+	PendingAgentRequests  int                       `json:"pending_agent_requests"`
+	PendingResponseWaiter int                       `json:"pending_response_waiters"`
+	AgentQueues           []AgentQueueStats         `json:"agent_queues"`
+	AgentLinks            []AgentLinkQuality        `json:"agent_links"`
+	DBQueries             []models.DBOperationStats `json:"db_queries"`
+}
+
+// AgentLinkQuality 面板到单个 Agent 的连接写入质量
+type AgentLinkQuality struct {
+	ServerID uint `json:"server_id"`
+	ConnQuality
+}
+
+// countSyncMap 统计 sync.Map 中的条目数
+func countSyncMap(m interface {
+	Range(func(key, value interface{}) bool)
+}) int {
+	count := 0
+	m.Range(func(_, _ interface{}) bool {
+		count++
+		return true
+	})
+	return count
+}
+
+// collectBackendMetrics 汇总当前的连接数、待处理请求数和运行时指标
+func collectBackendMetrics() BackendMetrics {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	metrics := BackendMetrics{
+		Timestamp:             time.Now().Unix(),
+		UptimeSeconds:         time.Since(startTime).Seconds(),
+		Goroutines:            runtime.NumGoroutine(),
+		HeapAllocBytes:        mem.HeapAlloc,
+		SysBytes:              mem.Sys,
+		NumGC:                 mem.NumGC,
+		AgentConnections:      countSyncMap(&ActiveAgentConnections),
+		TerminalConnections:   countSyncMap(&ActiveTerminalConnections),
+		LogStreamConnections:  countSyncMap(&ActiveLogStreamConnections),
+		FileScanConnections:   countSyncMap(&ActiveFileScanConnections),
+		PendingResponseWaiter: countSyncMap(&dockerResponseChannels),
+		AgentQueues:           getAgentQueue().stats(),
+		AgentLinks:            collectAgentLinkQuality(),
+		DBQueries:             models.GetDBQueryStats(),
+	}
+
+	ActivePublicMonitorConnections.Range(func(_, value interface{}) bool {
+		if set, ok := value.(*publicConnSet); ok {
+			metrics.MonitorSubscribers += set.len()
+		}
+		return true
+	})
+
+	serverPendingRequests.Range(func(_, value interface{}) bool {
+		if set, ok := value.(*pendingRequestSet); ok {
+			set.mu.Lock()
+			metrics.PendingAgentRequests += len(set.requestIDs)
+			set.mu.Unlock()
+		}
+		return true
+	})
+
+	return metrics
+}
+
+// collectAgentLinkQuality 按服务器ID排序返回所有在线 Agent 连接的写入质量
+func collectAgentLinkQuality() []AgentLinkQuality {
+	links := make([]AgentLinkQuality, 0)
+	ActiveAgentConnections.Range(func(key, value interface{}) bool {
+		serverID, ok := key.(uint)
+		conn, isConn := value.(*SafeConn)
+		if ok && isConn {
+			links = append(links, AgentLinkQuality{ServerID: serverID, ConnQuality: conn.Quality()})
+		}
+		return true
+	})
+	sort.Slice(links, func(i, j int) bool { return links[i].ServerID < links[j].ServerID })
+	return links
+}
+
+// GetBackendMetrics 获取后端自身的运行指标
+// 默认返回 JSON，format=prometheus 时返回 Prometheus 文本格式
+func GetBackendMetrics(c *gin.Context) {
+	metrics := collectBackendMetrics()
+
+	if c.Query("format") == "prometheus" {
+		c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(formatPrometheusMetrics(metrics)))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    metrics,
+	})
+}
+
+// formatPrometheusMetrics 将指标转换为 Prometheus 文本暴露格式
+func formatPrometheusMetrics(m BackendMetrics) string {
+	var b strings.Builder
+
+	gauge := func(name, help string, value interface{}) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s %v\n", name, help, name, name, value)
+	}
+
+	gauge("bettermonitor_uptime_seconds", "Backend uptime in seconds.", m.UptimeSeconds)
+	gauge("bettermonitor_goroutines", "Number of goroutines.", m.Goroutines)
+	gauge("bettermonitor_heap_alloc_bytes", "Heap bytes allocated.", m.HeapAllocBytes)
+	gauge("bettermonitor_sys_bytes", "Bytes obtained from the OS.", m.SysBytes)
+	gauge("bettermonitor_agent_connections", "Active agent WebSocket connections.", m.AgentConnections)
+	gauge("bettermonitor_monitor_subscribers", "Active monitor WebSocket subscribers.", m.MonitorSubscribers)
+	gauge("bettermonitor_terminal_connections", "Active terminal WebSocket connections.", m.TerminalConnections)
+	gauge("bettermonitor_log_stream_connections", "Active log stream connections.", m.LogStreamConnections)
+	gauge("bettermonitor_file_scan_connections", "In-progress file scans awaiting results.", m.FileScanConnections)
+	gauge("bettermonitor_pending_agent_requests", "Requests awaiting an agent response.", m.PendingAgentRequests)
+	gauge("bettermonitor_pending_response_waiters", "Registered request/response channels.", m.PendingResponseWaiter)
+
+	b.WriteString("# HELP bettermonitor_agent_active_requests In-flight requests per agent.\n# TYPE bettermonitor_agent_active_requests gauge\n")
+	for _, q := range m.AgentQueues {
+		fmt.Fprintf(&b, "bettermonitor_agent_active_requests{server_id=\"%d\"} %d\n", q.ServerID, q.Active)
+	}
+	b.WriteString("# HELP bettermonitor_agent_queued_requests Requests waiting in the dispatch queue per agent.\n# TYPE bettermonitor_agent_queued_requests gauge\n")
+	for _, q := range m.AgentQueues {
+		fmt.Fprintf(&b, "bettermonitor_agent_queued_requests{server_id=\"%d\"} %d\n", q.ServerID, q.Queued)
+	}
+
+	b.WriteString("# HELP bettermonitor_agent_write_latency_ms Smoothed WebSocket write latency per agent.\n# TYPE bettermonitor_agent_write_latency_ms gauge\n")
+	for _, l := range m.AgentLinks {
+		fmt.Fprintf(&b, "bettermonitor_agent_write_latency_ms{server_id=\"%d\"} %g\n", l.ServerID, l.AvgMs)
+	}
+	b.WriteString("# HELP bettermonitor_agent_slow_writes_total WebSocket writes to the agent slower than the poor threshold.\n# TYPE bettermonitor_agent_slow_writes_total counter\n")
+	for _, l := range m.AgentLinks {
+		fmt.Fprintf(&b, "bettermonitor_agent_slow_writes_total{server_id=\"%d\"} %d\n", l.ServerID, l.SlowWrites)
+	}
+
+	b.WriteString("# HELP bettermonitor_db_queries_total Database operations by type.\n# TYPE bettermonitor_db_queries_total counter\n")
+	for _, q := range m.DBQueries {
+		fmt.Fprintf(&b, "bettermonitor_db_queries_total{operation=%q} %d\n", q.Operation, q.Count)
+	}
+	b.WriteString("# HELP bettermonitor_db_query_errors_total Failed database operations by type.\n# TYPE bettermonitor_db_query_errors_total counter\n")
+	for _, q := range m.DBQueries {
+		fmt.Fprintf(&b, "bettermonitor_db_query_errors_total{operation=%q} %d\n", q.Operation, q.ErrorCount)
+	}
+	b.WriteString("# HELP bettermonitor_db_slow_queries_total Database operations slower than the slow threshold.\n# TYPE bettermonitor_db_slow_queries_total counter\n")
+	for _, q := range m.DBQueries {
+		fmt.Fprintf(&b, "bettermonitor_db_slow_queries_total{operation=%q} %d\n", q.Operation, q.SlowCount)
+	}
+	b.WriteString("# HELP bettermonitor_db_query_duration_seconds_sum Total time spent in database operations.\n# TYPE bettermonitor_db_query_duration_seconds_sum counter\n")
+	for _, q := range m.DBQueries {
+		fmt.Fprintf(&b, "bettermonitor_db_query_duration_seconds_sum{operation=%q} %g\n", q.Operation, q.TotalMs/1000)
+	}
+	b.WriteString("# HELP bettermonitor_db_query_duration_seconds_max Slowest database operation observed.\n# TYPE bettermonitor_db_query_duration_seconds_max gauge\n")
+	for _, q := range m.DBQueries {
+		fmt.Fprintf(&b, "bettermonitor_db_query_duration_seconds_max{operation=%q} %g\n", q.Operation, q.MaxMs/1000)
+	}
+
+	return b.String()
+}
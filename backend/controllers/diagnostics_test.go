@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// scrapeBackendMetrics 请求 Prometheus 格式的后端指标，解析为 "名称{标签}" -> 值；
+// 同时检查每个样本之前都声明了 HELP 和 TYPE
+func scrapeBackendMetrics(t *testing.T) map[string]float64 {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/admin/diagnostics/metrics?format=prometheus", nil)
+	GetBackendMetrics(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+
+	samples := make(map[string]float64)
+	declared := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(w.Body.String()))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "# HELP ") {
+			declared[strings.Fields(line)[2]] = ""
+			continue
+		}
+		if strings.HasPrefix(line, "# TYPE ") {
+			fields := strings.Fields(line)
+			_, hasHelp := declared[fields[2]]
+			assert.True(t, hasHelp, "TYPE 之前缺少 HELP: %s", line)
+			declared[fields[2]] = fields[3]
+			continue
+		}
+		key, raw, ok := strings.Cut(line, " ")
+		if !assert.True(t, ok, "无效的样本行: %q", line) {
+			continue
+		}
+		name, _, _ := strings.Cut(key, "{")
+		assert.NotEmpty(t, declared[name], "样本 %s 缺少 TYPE 声明", name)
+		value, err := strconv.ParseFloat(raw, 64)
+		assert.NoError(t, err, "样本 %s 的值无效", key)
+		samples[key] = value
+	}
+	return samples
+}
+
+func TestBackendMetricsPrometheusDBQueries(t *testing.T) {
+	type record struct {
+		ID   uint
+		Name string
+	}
+	db, err := gorm.Open(sqlite.Open("file:db_metrics_test?mode=memory"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("创建测试数据库失败: %v", err)
+	}
+	assert.NoError(t, db.AutoMigrate(&record{}))
+	assert.NoError(t, models.RegisterDBMetrics(db))
+
+	// 统计是进程级的累计值，按前后两次抓取的差值检查
+	before := scrapeBackendMetrics(t)
+
+	assert.NoError(t, db.Create(&record{Name: "a"}).Error)
+	assert.NoError(t, db.Create(&record{Name: "b"}).Error)
+	var r record
+	assert.NoError(t, db.First(&r, "name = ?", "a").Error)
+	// 记录不存在不算失败
+	assert.ErrorIs(t, db.First(&r, "name = ?", "missing").Error, gorm.ErrRecordNotFound)
+	// 查询不存在的表计入失败
+	assert.Error(t, db.Table("missing_table").First(&r).Error)
+	assert.NoError(t, db.Model(&record{}).Where("name = ?", "b").Update("name", "c").Error)
+	assert.NoError(t, db.Delete(&record{}, "name = ?", "c").Error)
+	assert.NoError(t, db.Exec("UPDATE records SET name = ?", "d").Error)
+
+	after := scrapeBackendMetrics(t)
+	delta := func(name, operation string) float64 {
+		key := name + `{operation="` + operation + `"}`
+		return after[key] - before[key]
+	}
+
+	assert.Equal(t, 2.0, delta("bettermonitor_db_queries_total", "create"))
+	assert.Equal(t, 3.0, delta("bettermonitor_db_queries_total", "query"))
+	assert.Equal(t, 1.0, delta("bettermonitor_db_queries_total", "update"))
+	assert.Equal(t, 1.0, delta("bettermonitor_db_queries_total", "delete"))
+	assert.Equal(t, 1.0, delta("bettermonitor_db_queries_total", "raw"))
+
+	assert.Equal(t, 1.0, delta("bettermonitor_db_query_errors_total", "query"))
+	assert.Zero(t, delta("bettermonitor_db_query_errors_total", "create"))
+	assert.Zero(t, delta("bettermonitor_db_query_errors_total", "raw"))
+
+	// 每类操作都输出慢查询数、总耗时和最大耗时，总耗时随查询增加
+	for _, operation := range []string{"create", "query", "update", "delete", "raw"} {
+		for _, name := range []string{
+			"bettermonitor_db_slow_queries_total",
+			"bettermonitor_db_query_duration_seconds_sum",
+			"bettermonitor_db_query_duration_seconds_max",
+		} {
+			assert.Contains(t, after, name+`{operation="`+operation+`"}`)
+		}
+		assert.Greater(t, delta("bettermonitor_db_query_duration_seconds_sum", operation), 0.0, operation)
+		assert.LessOrEqual(t,
+			after[`bettermonitor_db_query_duration_seconds_max{operation="`+operation+`"}`],
+			after[`bettermonitor_db_query_duration_seconds_sum{operation="`+operation+`"}`], operation)
+	}
+
+	// JSON 格式返回同样的统计，按操作名排序
+	stats := models.GetDBQueryStats()
+	for i := 1; i < len(stats); i++ {
+		assert.Less(t, stats[i-1].Operation, stats[i].Operation)
+	}
+	for _, stat := range stats {
+		assert.Equal(t, float64(stat.Count), after[`bettermonitor_db_queries_total{operation="`+stat.Operation+`"}`])
+		assert.InDelta(t, stat.TotalMs/float64(stat.Count), stat.AvgMs, 1e-9)
+	}
+}
@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+// GetServerDiskHealth 获取服务器各物理磁盘最近一次的 SMART 健康信息
+func GetServerDiskHealth(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	disks, err := models.GetDiskHealth(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取磁盘健康信息失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"disks": disks})
+}
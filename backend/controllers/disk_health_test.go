@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestPersistDiskHealth(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.ServerMonitor{}, &models.TrafficHourly{}, &models.DiskHealth{}))
+	server := models.Server{Name: "nas-01", SecretKey: "disk-health-test"}
+	assert.NoError(t, models.DB.Create(&server).Error)
+	defer models.DB.Unscoped().Delete(&server)
+	defer models.DeleteDiskHealth(server.ID)
+
+	_, err := persistMonitorPayload(&server, &MonitorPayload{SMART: []DiskHealthPayload{
+		{Device: "/dev/sdb", Model: "WDC WD40EFRX", Type: "hdd", Passed: true, Temperature: 38, WearLevel: -1},
+		{Device: "/dev/sda", Model: "Samsung SSD 860", Type: "ssd", Passed: true, WearLevel: 7},
+	}})
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(server.ID), 10)}}
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	GetServerDiskHealth(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Disks []models.DiskHealth `json:"disks"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Disks, 2) {
+		assert.Equal(t, "/dev/sda", resp.Disks[0].Device)
+		assert.Equal(t, 7, resp.Disks[0].WearLevel)
+		assert.False(t, resp.Disks[1].CheckedAt.IsZero())
+	}
+
+	// 只有新出现故障预测的磁盘需要告警，已移除的磁盘不再保留
+	failing, err := models.SaveDiskHealth(server.ID, []models.DiskHealth{
+		{Device: "/dev/sdb", PredictedFailure: true, FailingAttributes: "Reallocated_Sector_Ct"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, failing, 1)
+	failing, err = models.SaveDiskHealth(server.ID, []models.DiskHealth{{Device: "/dev/sdb", PredictedFailure: true}})
+	assert.NoError(t, err)
+	assert.Empty(t, failing)
+
+	disks, err := models.GetDiskHealth(server.ID)
+	assert.NoError(t, err)
+	assert.Len(t, disks, 1)
+}
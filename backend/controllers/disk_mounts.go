@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+// 挂载点使用历史的默认和最大查询范围
+const (
+	diskMountDefaultRange = 24 * time.Hour
+	diskMountMaxRange     = 31 * 24 * time.Hour
+)
+
+// GetServerDiskMounts 获取服务器最近一次上报的各挂载点空间和 inode 使用情况
+func GetServerDiskMounts(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	mounts, err := models.GetLatestDiskMountStats(id)
+	if err != nil {
+		log.Printf("[ERROR] 获取服务器ID=%d挂载点使用情况失败: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取挂载点使用情况失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"mounts": mounts})
+}
+
+// GetServerDiskMountHistory 获取服务器挂载点使用情况的历史
+// 查询参数：
+//   - mount: 挂载路径，为空时返回所有挂载点
+//   - range: 相对当前时间的窗口（如 6h），默认 24h，最长 31 天
+func GetServerDiskMountHistory(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	window := diskMountDefaultRange
+	if rangeStr := c.Query("range"); rangeStr != "" {
+		if window, err = time.ParseDuration(rangeStr); err != nil || window <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的时间范围"})
+			return
+		}
+	}
+	if window > diskMountMaxRange {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "查询时间范围不能超过31天"})
+		return
+	}
+
+	endTime := time.Now()
+	stats, err := models.GetDiskMountStats(id, c.Query("mount"), endTime.Add(-window), endTime)
+	if err != nil {
+		log.Printf("[ERROR] 获取服务器ID=%d挂载点使用历史失败: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取挂载点使用历史失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
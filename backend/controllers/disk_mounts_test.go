@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestDiskMountStats(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.ServerMonitor{}, &models.TrafficHourly{}, &models.DiskMountStat{}))
+	server := models.Server{Name: "mounts-01", SecretKey: "disk-mount-test"}
+	assert.NoError(t, db.Create(&server).Error)
+	defer db.Unscoped().Delete(&server)
+	defer db.Where("server_id = ?", server.ID).Delete(&models.ServerMonitor{})
+	defer db.Where("server_id = ?", server.ID).Delete(&models.DiskMountStat{})
+
+	report := func(dockerUsed uint64) *models.ServerMonitor {
+		record, err := persistMonitorPayload(&server, &MonitorPayload{
+			CPUUsage: 5,
+			Mounts: []DiskMountPayload{
+				{Mount: "/", FSType: "ext4", Total: 100, Used: 40, Free: 60, InodesTotal: 1000, InodesUsed: 100, InodesFree: 900},
+				{Mount: "/var/lib/docker", FSType: "xfs", Total: 100, Used: dockerUsed, Free: 100 - dockerUsed, InodesTotal: 500, InodesUsed: 480, InodesFree: 20},
+			},
+		})
+		assert.NoError(t, err)
+		return record
+	}
+	report(50)
+	record := report(92)
+
+	// 监控记录可以取到同一次上报的挂载点，预警规则据此按挂载点判断
+	mounts := record.MountStats()
+	if assert.Len(t, mounts, 2) {
+		assert.Equal(t, "/var/lib/docker", mounts[1].Mount)
+		assert.InDelta(t, 92, mounts[1].UsagePercent(), 0.01)
+		assert.InDelta(t, 96, mounts[1].InodeUsagePercent(), 0.01)
+	}
+
+	get := func(handler gin.HandlerFunc, rawQuery string) (int, map[string][]models.DiskMountStat) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(server.ID), 10)}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/?"+rawQuery, nil)
+		handler(c)
+		var resp map[string][]models.DiskMountStat
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, resp := get(GetServerDiskMounts, "")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, resp["mounts"], 2) {
+		assert.Equal(t, uint64(92), resp["mounts"][1].Used)
+		assert.Equal(t, uint64(480), resp["mounts"][1].InodesUsed)
+	}
+
+	code, resp = get(GetServerDiskMountHistory, "mount=/var/lib/docker&range=1h")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, resp["stats"], 2) {
+		assert.Equal(t, uint64(50), resp["stats"][0].Used)
+		assert.Equal(t, uint64(92), resp["stats"][1].Used)
+	}
+
+	code, _ = get(GetServerDiskMountHistory, "range=800h")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -281,6 +281,80 @@ func RestartContainer(c *gin.Context) {
 	c.JSON(http.StatusOK, responseData)
 }
 
+const (
+	// 容器一次性命令的默认与最长执行时间（秒），与Agent保持一致
+	defaultContainerExecTimeout = 30
+	maxContainerExecTimeout     = 300
+)
+
+// ExecContainerCommand 在容器内执行一条非交互命令，同步返回 stdout/stderr/退出码。
+// cmd 为参数数组，直接执行；也可只传 command 字符串，由容器内的 sh -c 解析
+func ExecContainerCommand(c *gin.Context) {
+	serverID, err := parseServerId(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	var requestBody struct {
+		Cmd     []string `json:"cmd"`
+		Command string   `json:"command"`
+		User    string   `json:"user"`
+		WorkDir string   `json:"workdir"`
+		Env     []string `json:"env"`
+		Timeout int      `json:"timeout"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	if len(requestBody.Cmd) == 0 && strings.TrimSpace(requestBody.Command) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "命令不能为空"})
+		return
+	}
+	if requestBody.Timeout <= 0 {
+		requestBody.Timeout = defaultContainerExecTimeout
+	}
+	if requestBody.Timeout > maxContainerExecTimeout {
+		requestBody.Timeout = maxContainerExecTimeout
+	}
+
+	server, err := models.GetServerByID(serverID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+
+	requestID := generateRequestID()
+	message := map[string]interface{}{
+		"type":       "docker_command",
+		"request_id": requestID,
+		"payload": map[string]interface{}{
+			"command": "containers",
+			"action":  "exec",
+			"params": map[string]interface{}{
+				"container_id": c.Param("container_id"),
+				"cmd":          requestBody.Cmd,
+				"command":      requestBody.Command,
+				"user":         requestBody.User,
+				"workdir":      requestBody.WorkDir,
+				"env":          requestBody.Env,
+				"timeout":      requestBody.Timeout,
+			},
+		},
+	}
+
+	// 命令超时由Agent返回 timed_out，这里多等一段时间留出传输余量
+	wait := time.Duration(requestBody.Timeout)*time.Second + 15*time.Second
+	responseData, err := sendAgentRequestWithTimeout(server, message, requestID, wait)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, responseData)
+}
+
 // RemoveContainer 删除容器
 func RemoveContainer(c *gin.Context) {
 	// 获取服务器ID和容器ID
@@ -403,16 +477,20 @@ func PullImage(c *gin.Context) {
 	// 生成请求ID
 	requestID := generateRequestID()
 
-	// 构建发送到Agent的消息
+	// 构建发送到Agent的消息，保存了该仓库的凭据时一并下发
+	params := map[string]interface{}{
+		"image": requestBody.Image,
+	}
+	if auth := registryAuthFor(server.ID, requestBody.Image); auth != nil {
+		params["auth"] = auth
+	}
 	message := map[string]interface{}{
 		"type":       "docker_command",
 		"request_id": requestID,
 		"payload": map[string]interface{}{
 			"command": "images",
 			"action":  "pull",
-			"params": map[string]interface{}{
-				"image": requestBody.Image,
-			},
+			"params":  params,
 		},
 	}
 
@@ -757,6 +835,107 @@ func CreateCompose(c *gin.Context) {
 	c.JSON(http.StatusOK, responseData)
 }
 
+// ValidateCompose 在保存前校验Compose配置内容，返回结构化的服务信息；
+// 已存在的项目会与其覆盖文件合并后校验。配置无效时返回 valid=false 和原因
+func ValidateCompose(c *gin.Context) {
+	id := c.Param("id")
+	serverID, err := parseServerId(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	var requestBody struct {
+		Content string `json:"content"`
+	}
+	if err := c.BindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	server, err := models.GetServerByID(serverID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+
+	requestID := generateRequestID()
+	message := map[string]interface{}{
+		"type":       "docker_command",
+		"request_id": requestID,
+		"payload": map[string]interface{}{
+			"command": "composes",
+			"action":  "validate",
+			"params": map[string]interface{}{
+				"name":    c.Param("name"),
+				"content": requestBody.Content,
+			},
+		},
+	}
+
+	responseData, err := sendAgentRequest(server, message, requestID)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, responseData)
+}
+
+// UpdateCompose 更新已有Compose项目的配置文件，Agent 先用 docker compose config 校验，
+// apply=true 时保存后立即执行 up -d，失败会恢复原配置
+func UpdateCompose(c *gin.Context) {
+	var requestBody struct {
+		Content string `json:"content"`
+		Apply   bool   `json:"apply"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil || strings.TrimSpace(requestBody.Content) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "配置内容不能为空"})
+		return
+	}
+
+	sendDockerCommand(c, "composes", "update", map[string]interface{}{
+		"name":    c.Param("name"),
+		"content": requestBody.Content,
+		"apply":   requestBody.Apply,
+	})
+}
+
+// GetComposeServices 获取Compose项目的服务列表及各服务的容器状态
+func GetComposeServices(c *gin.Context) {
+	sendDockerCommand(c, "composes", "services", map[string]interface{}{
+		"name": c.Param("name"),
+	})
+}
+
+// ComposeServiceAction 对Compose项目中的单个服务执行 restart/start/stop/scale
+func ComposeServiceAction(c *gin.Context) {
+	op := c.Param("op")
+	params := map[string]interface{}{
+		"name":    c.Param("name"),
+		"service": c.Param("service"),
+		"op":      op,
+	}
+
+	switch op {
+	case "restart", "start", "stop":
+	case "scale":
+		var requestBody struct {
+			Replicas *int `json:"replicas"`
+		}
+		if err := c.ShouldBindJSON(&requestBody); err != nil || requestBody.Replicas == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "缺少副本数"})
+			return
+		}
+		params["replicas"] = *requestBody.Replicas
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的服务操作"})
+		return
+	}
+
+	sendDockerCommand(c, "composes", "service", params)
+}
+
 // CreateContainer 创建Docker容器
 func CreateContainer(c *gin.Context) {
 	// 获取服务器ID
@@ -835,6 +1014,11 @@ func CreateContainer(c *gin.Context) {
 // 发送请求到Agent并处理响应
 // 【安全修复】添加success字段验证，确保Agent返回成功状态
 func sendAgentRequest(server *models.Server, message map[string]interface{}, requestID string) (map[string]interface{}, error) {
+	return sendAgentRequestWithTimeout(server, message, requestID, TimeoutSimpleQuery)
+}
+
+// sendAgentRequestWithTimeout 与 sendAgentRequest 相同，但使用指定的等待时间
+func sendAgentRequestWithTimeout(server *models.Server, message map[string]interface{}, requestID string, wait time.Duration) (map[string]interface{}, error) {
 	// 获取Agent连接
 	agentConnVal, ok := ActiveAgentConnections.Load(server.ID)
 	if !ok {
@@ -859,13 +1043,15 @@ func sendAgentRequest(server *models.Server, message map[string]interface{}, req
 	defer dockerRequestMap.Delete(requestID)
 
 	// 【安全修复】注册待处理请求，以便在Agent断开时能快速失败
-	registerPendingRequest(server.ID, requestID)
+	if err := registerPendingRequest(server.ID, requestID); err != nil {
+		return nil, err
+	}
 	defer unregisterPendingRequest(server.ID, requestID)
 
 	// 转换消息为JSON字符串以便日志记录
 	messageBytes, _ := json.Marshal(message)
 	fmt.Printf("[调试] 发送Docker命令到服务器ID=%d, 请求ID=%s, 消息内容: %s\n",
-		server.ID, requestID, string(messageBytes))
+		server.ID, requestID, redactPasswords(messageBytes))
 
 	// 发送消息到Agent
 	if err := agentConn.WriteJSON(message); err != nil {
@@ -876,7 +1062,7 @@ func sendAgentRequest(server *models.Server, message map[string]interface{}, req
 	fmt.Printf("[调试] 消息已发送，等待服务器ID=%d的响应, 请求ID=%s\n", server.ID, requestID)
 
 	// 设置超时时间
-	timeout := time.After(TimeoutSimpleQuery)
+	timeout := time.After(wait)
 
 	// 等待响应
 	select {
@@ -1082,6 +1268,7 @@ var (
 	ErrSendRequestFailed     = NewError("发送请求到Agent失败")
 	ErrRequestTimeout        = NewError("请求超时")
 	ErrInvalidResponseFormat = NewError("响应数据格式错误")
+	ErrAgentUpgrading        = NewError("Agent正在升级，请稍后重试")
 )
 
 // Error 自定义错误类型
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+// sendDockerCommand 向Agent发送 docker_command 并直接返回Agent的响应
+func sendDockerCommand(c *gin.Context, command, action string, params map[string]interface{}) {
+	serverID, err := parseServerId(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	server, err := models.GetServerByID(serverID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+
+	requestID := generateRequestID()
+	payload := map[string]interface{}{
+		"command": command,
+		"action":  action,
+	}
+	if params != nil {
+		payload["params"] = params
+	}
+	message := map[string]interface{}{
+		"type":       "docker_command",
+		"request_id": requestID,
+		"payload":    payload,
+	}
+
+	responseData, err := sendAgentRequest(server, message, requestID)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, responseData)
+}
+
+// GetNetworks 获取服务器上的Docker网络列表
+func GetNetworks(c *gin.Context) {
+	sendDockerCommand(c, "networks", "list", nil)
+}
+
+// InspectNetwork 获取Docker网络详情，包括已连接的容器
+func InspectNetwork(c *gin.Context) {
+	sendDockerCommand(c, "networks", "inspect", map[string]interface{}{
+		"network_id": c.Param("network_id"),
+	})
+}
+
+// CreateNetwork 创建Docker网络
+func CreateNetwork(c *gin.Context) {
+	var req struct {
+		Name     string            `json:"name" binding:"required"`
+		Driver   string            `json:"driver"`
+		Subnet   string            `json:"subnet"`
+		Gateway  string            `json:"gateway"`
+		Internal bool              `json:"internal"`
+		IPv6     bool              `json:"ipv6"`
+		Labels   map[string]string `json:"labels"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "网络名称不能为空"})
+		return
+	}
+
+	sendDockerCommand(c, "networks", "create", map[string]interface{}{
+		"name":     strings.TrimSpace(req.Name),
+		"driver":   req.Driver,
+		"subnet":   req.Subnet,
+		"gateway":  req.Gateway,
+		"internal": req.Internal,
+		"ipv6":     req.IPv6,
+		"labels":   req.Labels,
+	})
+}
+
+// RemoveNetwork 删除Docker网络
+func RemoveNetwork(c *gin.Context) {
+	sendDockerCommand(c, "networks", "remove", map[string]interface{}{
+		"network_id": c.Param("network_id"),
+	})
+}
+
+// PruneNetworks 删除所有未被容器使用的Docker网络
+func PruneNetworks(c *gin.Context) {
+	sendDockerCommand(c, "networks", "prune", nil)
+}
+
+// GetVolumes 获取服务器上的Docker卷列表
+func GetVolumes(c *gin.Context) {
+	sendDockerCommand(c, "volumes", "list", nil)
+}
+
+// InspectVolume 获取Docker卷详情，包括挂载了该卷的容器
+func InspectVolume(c *gin.Context) {
+	sendDockerCommand(c, "volumes", "inspect", map[string]interface{}{
+		"name": c.Param("name"),
+	})
+}
+
+// CreateVolume 创建Docker卷，名称为空时由Docker生成
+func CreateVolume(c *gin.Context) {
+	var req struct {
+		Name    string            `json:"name"`
+		Driver  string            `json:"driver"`
+		Labels  map[string]string `json:"labels"`
+		Options map[string]string `json:"options"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	sendDockerCommand(c, "volumes", "create", map[string]interface{}{
+		"name":    strings.TrimSpace(req.Name),
+		"driver":  req.Driver,
+		"labels":  req.Labels,
+		"options": req.Options,
+	})
+}
+
+// RemoveVolume 删除Docker卷
+func RemoveVolume(c *gin.Context) {
+	sendDockerCommand(c, "volumes", "remove", map[string]interface{}{
+		"name":  c.Param("name"),
+		"force": c.Query("force") == "true",
+	})
+}
+
+// PruneVolumes 删除未被容器使用的Docker卷，all=true 时同时清理具名卷
+func PruneVolumes(c *gin.Context) {
+	sendDockerCommand(c, "volumes", "prune", map[string]interface{}{
+		"all": c.Query("all") == "true",
+	})
+}
@@ -0,0 +1,326 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ─── 分片下载 ────────────────────────────────────────────────────────────────────
+//
+// 面板按偏移量逐片向 Agent 请求文件内容（chunked_download_chunk），每片带序号和 SHA-256，
+// 校验通过后立即写给浏览器，内存中只保留当前分片，不再受单条 WebSocket 消息大小的限制。
+// 下载中断后浏览器带 Range 头重新请求，面板从对应偏移量继续向 Agent 请求即可续传。
+
+const (
+	downloadChunkSize    = 1024 * 1024      // 每次向 Agent 请求的分片大小
+	downloadChunkRetries = 3                // 分片校验失败或超时后的重试次数
+	downloadProbeTimeout = 30 * time.Second // 首个分片的超时，旧版 Agent 不会响应分片下载请求
+)
+
+// downloadChunk Agent 返回的一个下载分片
+type downloadChunk struct {
+	Seq       int    `json:"seq"`
+	Offset    int64  `json:"offset"`
+	Size      int    `json:"size"`
+	TotalSize int64  `json:"total_size"`
+	ModTime   int64  `json:"mod_time"`
+	EOF       bool   `json:"eof"`
+	ChunkHash string `json:"chunk_hash"`
+	Content   string `json:"content"`
+
+	data []byte
+}
+
+// errAgentChunkFailed Agent 明确返回了失败（文件不存在、无权限等），重试不会成功
+type errAgentChunkFailed struct{ msg string }
+
+func (e errAgentChunkFailed) Error() string { return e.msg }
+
+// fetchDownloadChunk 请求文件从 offset 开始的一个分片
+func fetchDownloadChunk(serverID uint, containerID, path string, seq int, offset, length int64, timeout time.Duration) (*downloadChunk, error) {
+	payload := map[string]interface{}{
+		"path":   path,
+		"seq":    seq,
+		"offset": offset,
+		"length": length,
+	}
+	if containerID != "" {
+		payload["container_id"] = containerID
+	}
+	return requestVerifiedChunk(serverID, "chunked_download_chunk", payload, seq, offset, timeout)
+}
+
+// requestVerifiedChunk 向 Agent 请求一个分片，并校验序号、偏移量、长度和哈希；
+// 校验失败或超时按 downloadChunkRetries 重试
+func requestVerifiedChunk(serverID uint, msgType string, payload map[string]interface{}, seq int, offset int64, timeout time.Duration) (*downloadChunk, error) {
+	var lastErr error
+	for attempt := 0; attempt <= downloadChunkRetries; attempt++ {
+		if attempt > 0 {
+			log.Printf("下载分片失败，重试第 %d 次: server=%d, type=%s, seq=%d, error=%v", attempt, serverID, msgType, seq, lastErr)
+		}
+		resp, err := sendChunkedRequestTimeout(serverID, msgType, payload, timeout)
+		if err != nil {
+			lastErr = err
+			if _, connected := ActiveAgentConnections.Load(serverID); !connected {
+				break
+			}
+			continue
+		}
+		if ok, errMsg := checkAgentAck(resp); !ok {
+			return nil, errAgentChunkFailed{errMsg}
+		}
+
+		chunk, err := decodeDownloadChunk(resp["data"], seq, offset)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return chunk, nil
+	}
+	return nil, lastErr
+}
+
+// decodeDownloadChunk 解析并校验 Agent 返回的分片
+func decodeDownloadChunk(data interface{}, seq int, offset int64) (*downloadChunk, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var chunk downloadChunk
+	if err := json.Unmarshal(raw, &chunk); err != nil {
+		return nil, fmt.Errorf("无效的分片格式: %v", err)
+	}
+	if chunk.Seq != seq || chunk.Offset != offset {
+		return nil, fmt.Errorf("分片序号不匹配: 期望 %d@%d，收到 %d@%d", seq, offset, chunk.Seq, chunk.Offset)
+	}
+	chunk.data, err = base64.StdEncoding.DecodeString(chunk.Content)
+	if err != nil {
+		return nil, fmt.Errorf("解码分片内容失败: %v", err)
+	}
+	chunk.Content = ""
+	if len(chunk.data) != chunk.Size {
+		return nil, fmt.Errorf("分片长度不匹配: 期望 %d，收到 %d", chunk.Size, len(chunk.data))
+	}
+	sum := sha256.Sum256(chunk.data)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), chunk.ChunkHash) {
+		return nil, fmt.Errorf("分片 %d 校验失败", seq)
+	}
+	return &chunk, nil
+}
+
+// parseByteRange 解析 "bytes=start-" 或 "bytes=start-end" 形式的 Range 头，end 为 -1 表示到文件末尾。
+// 不支持后缀范围和多段范围，按 RFC 7233 忽略无法解析的 Range 返回整个文件
+func parseByteRange(header string) (start, end int64, ok bool) {
+	spec, found := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+	from, to, found := strings.Cut(spec, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(from), 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false
+	}
+	if strings.TrimSpace(to) == "" {
+		return start, -1, true
+	}
+	end, err = strconv.ParseInt(strings.TrimSpace(to), 10, 64)
+	if err != nil || end < start {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// serveChunkedDownload 以分片方式把宿主机或容器内的文件流式写给浏览器，支持 Range 续传。
+// legacy 不为空时，Agent 不支持分片下载（首个分片超时）则退回一次性下载
+func serveChunkedDownload(c *gin.Context, serverID uint, containerID, path string, legacy func() ([]byte, error)) {
+	start, end, hasRange := parseByteRange(c.GetHeader("Range"))
+
+	first, err := fetchDownloadChunk(serverID, containerID, path, 0, start, downloadChunkSize, downloadProbeTimeout)
+	if err != nil {
+		if errors.Is(err, errChunkedRequestTimeout) && legacy != nil {
+			log.Printf("服务器 %d 的Agent未响应分片下载请求，改用一次性下载: %s", serverID, path)
+			fileData, err := legacy()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("下载文件失败: %v", err)})
+				return
+			}
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filepath.Base(path)))
+			c.Data(http.StatusOK, "application/octet-stream", fileData)
+			return
+		}
+		if errors.Is(err, errChunkedRequestTimeout) {
+			err = fmt.Errorf("Agent 未响应分片下载请求，请升级 Agent")
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("下载文件失败: %v", err)})
+		return
+	}
+
+	total := first.TotalSize
+	etag := fmt.Sprintf(`"%x-%x"`, total, first.ModTime)
+	lastModified := time.Unix(first.ModTime, 0).UTC().Format(http.TimeFormat)
+
+	// If-Range 与当前文件不一致说明文件已变化，忽略 Range 重新下载整个文件
+	if ifRange := c.GetHeader("If-Range"); hasRange && ifRange != "" && ifRange != etag && ifRange != lastModified {
+		hasRange = false
+	}
+	if !hasRange && start != 0 {
+		start = 0
+		first, err = fetchDownloadChunk(serverID, containerID, path, 0, 0, downloadChunkSize, chunkedUploadRequestTimeout)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("下载文件失败: %v", err)})
+			return
+		}
+		total = first.TotalSize
+		etag = fmt.Sprintf(`"%x-%x"`, total, first.ModTime)
+		lastModified = time.Unix(first.ModTime, 0).UTC().Format(http.TimeFormat)
+	}
+
+	c.Header("Accept-Ranges", "bytes")
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", lastModified)
+	if hasRange && start >= total {
+		c.Header("Content-Range", fmt.Sprintf("bytes */%d", total))
+		c.JSON(http.StatusRequestedRangeNotSatisfiable, gin.H{"error": "请求的范围超出文件大小"})
+		return
+	}
+	if !hasRange || end < 0 || end >= total {
+		end = total - 1
+	}
+
+	status := http.StatusOK
+	if hasRange {
+		status = http.StatusPartialContent
+		c.Header("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, total))
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filepath.Base(path)))
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Length", strconv.FormatInt(end-start+1, 10))
+	c.Status(status)
+
+	// 响应头已发出，之后的失败只能中断连接，浏览器可带 Range 从已收到的位置续传
+	chunk, offset := first, start
+	for seq := 1; offset <= end; seq++ {
+		data := chunk.data
+		if remaining := end - offset + 1; int64(len(data)) > remaining {
+			data = data[:remaining]
+		}
+		if len(data) == 0 {
+			log.Printf("下载中断: 服务器 %d 的文件 %s 在偏移量 %d 处没有更多数据", serverID, path, offset)
+			return
+		}
+		if _, err := c.Writer.Write(data); err != nil {
+			log.Printf("下载中断: 客户端连接已断开: %v", err)
+			return
+		}
+		c.Writer.Flush()
+		offset += int64(len(data))
+		if offset > end {
+			return
+		}
+
+		chunk, err = fetchDownloadChunk(serverID, containerID, path, seq, offset, min(downloadChunkSize, end-offset+1), chunkedUploadRequestTimeout)
+		if err != nil {
+			log.Printf("下载中断: 服务器 %d 的文件 %s 在偏移量 %d 处读取失败: %v", serverID, path, offset, err)
+			return
+		}
+		if chunk.TotalSize != total || chunk.ModTime != first.ModTime {
+			log.Printf("下载中断: 服务器 %d 的文件 %s 在下载过程中被修改", serverID, path)
+			return
+		}
+	}
+}
+
+// ─── 目录打包下载 ────────────────────────────────────────────────────────────────
+//
+// Agent 边打包边按序号返回 tar.gz 分片（file_archive），面板逐片校验后写给浏览器。
+// 压缩包大小事先未知，不支持 Range 续传，中断后需要重新下载。
+
+// fetchArchiveChunk 请求打包会话的下一个分片
+func fetchArchiveChunk(serverID uint, archiveID string, seq int, offset int64) (*downloadChunk, error) {
+	payload := map[string]interface{}{
+		"action":     "chunk",
+		"archive_id": archiveID,
+		"seq":        seq,
+		"length":     downloadChunkSize,
+	}
+	return requestVerifiedChunk(serverID, "file_archive", payload, seq, offset, chunkedUploadRequestTimeout)
+}
+
+// serveArchiveDownload 把宿主机或容器内的目录打包为 tar.gz 流式写给浏览器
+func serveArchiveDownload(c *gin.Context, serverID uint, containerID, path string) {
+	archiveID := uuid.New().String()
+	payload := map[string]interface{}{
+		"action":     "start",
+		"archive_id": archiveID,
+		"path":       path,
+	}
+	if containerID != "" {
+		payload["container_id"] = containerID
+	}
+	resp, err := sendChunkedRequestTimeout(serverID, "file_archive", payload, downloadProbeTimeout)
+	if err == nil {
+		if ok, errMsg := checkAgentAck(resp); !ok {
+			err = errors.New(errMsg)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, errChunkedRequestTimeout) {
+			err = fmt.Errorf("Agent 未响应打包下载请求，请升级 Agent")
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("打包目录失败: %v", err)})
+		return
+	}
+
+	// 结束后释放 Agent 上的会话；中途失败时 Agent 随之停止打包
+	defer func() {
+		_, _ = sendChunkedRequest(serverID, "file_archive", map[string]interface{}{
+			"action":     "cancel",
+			"archive_id": archiveID,
+		})
+	}()
+
+	name := filepath.Base(path)
+	if name == "/" || name == "." {
+		name = "root"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.tar.gz", name))
+	c.Header("Content-Type", "application/gzip")
+	c.Status(http.StatusOK)
+
+	// 响应头已发出，之后的失败只能中断连接
+	var offset int64
+	for seq := 0; ; seq++ {
+		chunk, err := fetchArchiveChunk(serverID, archiveID, seq, offset)
+		if err != nil {
+			log.Printf("打包下载中断: 服务器 %d 的目录 %s 在偏移量 %d 处读取失败: %v", serverID, path, offset, err)
+			return
+		}
+		if len(chunk.data) > 0 {
+			if _, err := c.Writer.Write(chunk.data); err != nil {
+				log.Printf("打包下载中断: 客户端连接已断开: %v", err)
+				return
+			}
+			c.Writer.Flush()
+			offset += int64(len(chunk.data))
+		}
+		if chunk.EOF {
+			return
+		}
+	}
+}
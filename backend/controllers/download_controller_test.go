@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// connectChunkedDownloadAgent 模拟支持分片下载的 Agent，corrupt 返回 true 时该分片的哈希被篡改
+func connectChunkedDownloadAgent(t *testing.T, serverID uint, content []byte, corrupt func(seq int) bool) {
+	connectReverseAgent(t, serverID, func(msg map[string]interface{}) map[string]interface{} {
+		if msg["type"] != "chunked_download_chunk" {
+			return nil
+		}
+		payload := msg["payload"].(map[string]interface{})
+		seq := int(payload["seq"].(float64))
+		offset := int64(payload["offset"].(float64))
+		length := int64(payload["length"].(float64))
+
+		var data []byte
+		if offset < int64(len(content)) {
+			data = content[offset:min(offset+length, int64(len(content)))]
+		}
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		if corrupt != nil && corrupt(seq) {
+			hash = "bad"
+		}
+		return map[string]interface{}{
+			"type": "chunked_download_chunk_ack",
+			"data": map[string]interface{}{
+				"seq":        seq,
+				"offset":     offset,
+				"size":       len(data),
+				"total_size": len(content),
+				"mod_time":   1700000000,
+				"eof":        offset+int64(len(data)) >= int64(len(content)),
+				"chunk_hash": hash,
+				"content":    base64.StdEncoding.EncodeToString(data),
+				"success":    true,
+			},
+		}
+	})
+}
+
+func serveTestDownload(serverID uint, header http.Header) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.Header = header
+	serveChunkedDownload(c, serverID, "", "/data/big.bin", nil)
+	return w
+}
+
+func TestServeChunkedDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), downloadChunkSize/16*2+1000)
+	var corrupted atomic.Bool
+	connectChunkedDownloadAgent(t, 601, content, func(seq int) bool {
+		// 第二个分片第一次返回错误的哈希，应当重试后成功
+		return seq == 1 && corrupted.CompareAndSwap(false, true)
+	})
+
+	w := serveTestDownload(601, http.Header{})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, content, w.Body.Bytes())
+	assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"))
+	assert.True(t, corrupted.Load())
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// 从中断处续传
+	start := downloadChunkSize + 12345
+	w = serveTestDownload(601, http.Header{"Range": {"bytes=" + strconv.Itoa(start) + "-"}, "If-Range": {etag}})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, content[start:], w.Body.Bytes())
+	assert.Equal(t, "bytes "+strconv.Itoa(start)+"-"+strconv.Itoa(len(content)-1)+"/"+strconv.Itoa(len(content)), w.Header().Get("Content-Range"))
+
+	// 指定结束位置
+	w = serveTestDownload(601, http.Header{"Range": {"bytes=10-19"}})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, content[10:20], w.Body.Bytes())
+
+	// 文件已变化时忽略 Range 返回整个文件
+	w = serveTestDownload(601, http.Header{"Range": {"bytes=100-"}, "If-Range": {`"stale"`}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, content, w.Body.Bytes())
+
+	w = serveTestDownload(601, http.Header{"Range": {"bytes=" + strconv.Itoa(len(content)) + "-"}})
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+	assert.Equal(t, "bytes */"+strconv.Itoa(len(content)), w.Header().Get("Content-Range"))
+}
+
+func TestServeChunkedDownloadAgentError(t *testing.T) {
+	connectReverseAgent(t, 602, func(msg map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"type": "chunked_download_chunk_ack",
+			"data": map[string]interface{}{"success": false, "error": "打开文件失败: no such file"},
+		}
+	})
+
+	w := serveTestDownload(602, http.Header{})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "no such file")
+}
+
+func TestParseByteRange(t *testing.T) {
+	cases := []struct {
+		header     string
+		start, end int64
+		ok         bool
+	}{
+		{"bytes=0-", 0, -1, true},
+		{"bytes=100-199", 100, 199, true},
+		{"bytes=-500", 0, 0, false},
+		{"bytes=0-10,20-30", 0, 0, false},
+		{"bytes=20-10", 0, 0, false},
+		{"items=0-10", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tc := range cases {
+		start, end, ok := parseByteRange(tc.header)
+		assert.Equal(t, tc.ok, ok, tc.header)
+		if tc.ok {
+			assert.Equal(t, tc.start, start, tc.header)
+			assert.Equal(t, tc.end, end, tc.header)
+		}
+	}
+}
+
+func TestServeArchiveDownload(t *testing.T) {
+	archive := bytes.Repeat([]byte("tar.gz-bytes"), downloadChunkSize/12+500)
+	var canceled atomic.Bool
+	connectReverseAgent(t, 603, func(msg map[string]interface{}) map[string]interface{} {
+		if msg["type"] != "file_archive" {
+			return nil
+		}
+		payload := msg["payload"].(map[string]interface{})
+		data := map[string]interface{}{"archive_id": payload["archive_id"], "success": true}
+		switch payload["action"] {
+		case "chunk":
+			seq := int(payload["seq"].(float64))
+			offset := min(int64(seq)*downloadChunkSize, int64(len(archive)))
+			chunk := archive[offset:min(offset+downloadChunkSize, int64(len(archive)))]
+			sum := sha256.Sum256(chunk)
+			data["seq"] = seq
+			data["offset"] = offset
+			data["size"] = len(chunk)
+			data["eof"] = len(chunk) < downloadChunkSize
+			data["chunk_hash"] = hex.EncodeToString(sum[:])
+			data["content"] = base64.StdEncoding.EncodeToString(chunk)
+		case "cancel":
+			canceled.Store(true)
+		}
+		return map[string]interface{}{"type": "file_archive_ack", "data": data}
+	})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	serveArchiveDownload(c, 603, "", "/var/www/site")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, archive, w.Body.Bytes())
+	assert.Equal(t, "attachment; filename=site.tar.gz", w.Header().Get("Content-Disposition"))
+	assert.True(t, canceled.Load())
+}
+
+func TestServeArchiveDownloadAgentError(t *testing.T) {
+	connectReverseAgent(t, 604, func(msg map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"type": "file_archive_ack",
+			"data": map[string]interface{}{"success": false, "error": "只能打包目录"},
+		}
+	})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	serveArchiveDownload(c, 604, "", "/etc/hosts")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "只能打包目录")
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/user/server-ops-backend/config"
+)
+
+// fileListCache 文件列表/目录树响应的短期缓存。
+// 前端文件浏览器来回切换目录时会重复请求同一目录，缓存可以减少发往Agent的请求；
+// 任何写操作（保存、创建、上传、删除等）都会清空该服务器的全部缓存，保证修改后立即可见。
+type fileListCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	servers map[uint]*serverFileCache
+}
+
+// serverFileCache 单个服务器的缓存条目
+type serverFileCache struct {
+	generation uint64 // 每次失效时递增，丢弃失效前发出的请求的结果
+	entries    map[string]fileCacheEntry
+}
+
+type fileCacheEntry struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+var (
+	fileCache     *fileListCache
+	fileCacheOnce sync.Once
+)
+
+// getFileListCache 按配置懒加载全局缓存，TTL 为 0 时不缓存
+func getFileListCache() *fileListCache {
+	fileCacheOnce.Do(func() {
+		fileCache = newFileListCache(config.LoadConfig().FileListCacheTTL)
+	})
+	return fileCache
+}
+
+func newFileListCache(ttl time.Duration) *fileListCache {
+	return &fileListCache{
+		ttl:     ttl,
+		servers: make(map[uint]*serverFileCache),
+	}
+}
+
+// cachedFileListing 返回缓存的结果，未命中时调用 fetch 并缓存成功的结果
+func cachedFileListing(serverID uint, key string, fetch func() (interface{}, error)) (interface{}, error) {
+	return getFileListCache().getOrFetch(serverID, key, fetch)
+}
+
+// invalidateFileListCache 清空指定服务器的文件列表缓存。
+// 写操作函数以 defer 调用，无论成功与否都在操作结束后失效，
+// 避免请求期间发出的列表请求把修改前的结果写回缓存。
+func invalidateFileListCache(serverID uint) {
+	getFileListCache().invalidate(serverID)
+}
+
+func (fc *fileListCache) getOrFetch(serverID uint, key string, fetch func() (interface{}, error)) (interface{}, error) {
+	if fc.ttl <= 0 {
+		return fetch()
+	}
+
+	fc.mu.Lock()
+	sc := fc.server(serverID)
+	if entry, ok := sc.entries[key]; ok {
+		if time.Now().Before(entry.expiresAt) {
+			fc.mu.Unlock()
+			return entry.value, nil
+		}
+		delete(sc.entries, key)
+	}
+	generation := sc.generation
+	fc.mu.Unlock()
+
+	value, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	sc = fc.server(serverID)
+	// 请求期间发生了写操作，结果可能已过时，不缓存
+	if sc.generation == generation {
+		sc.entries[key] = fileCacheEntry{value: value, expiresAt: time.Now().Add(fc.ttl)}
+	}
+	return value, nil
+}
+
+func (fc *fileListCache) invalidate(serverID uint) {
+	if fc.ttl <= 0 {
+		return
+	}
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	sc := fc.server(serverID)
+	sc.generation++
+	sc.entries = make(map[string]fileCacheEntry)
+}
+
+// server 返回服务器的缓存，调用方需持有 fc.mu
+func (fc *fileListCache) server(serverID uint) *serverFileCache {
+	sc, ok := fc.servers[serverID]
+	if !ok {
+		sc = &serverFileCache{entries: make(map[string]fileCacheEntry)}
+		fc.servers[serverID] = sc
+	}
+	return sc
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileListCache(t *testing.T) {
+	fc := newFileListCache(50 * time.Millisecond)
+	calls := 0
+	fetch := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+
+	// 命中缓存时不再请求Agent，不同服务器、不同路径互不影响
+	v, _ := fc.getOrFetch(1, "list:/", fetch)
+	assert.Equal(t, 1, v)
+	v, _ = fc.getOrFetch(1, "list:/", fetch)
+	assert.Equal(t, 1, v)
+	v, _ = fc.getOrFetch(1, "list:/etc", fetch)
+	assert.Equal(t, 2, v)
+	v, _ = fc.getOrFetch(2, "list:/", fetch)
+	assert.Equal(t, 3, v)
+
+	// 写操作后该服务器的缓存全部失效
+	fc.invalidate(1)
+	v, _ = fc.getOrFetch(1, "list:/", fetch)
+	assert.Equal(t, 4, v)
+	v, _ = fc.getOrFetch(2, "list:/", fetch)
+	assert.Equal(t, 3, v)
+
+	// 过期后重新请求
+	time.Sleep(60 * time.Millisecond)
+	v, _ = fc.getOrFetch(1, "list:/", fetch)
+	assert.Equal(t, 5, v)
+
+	// 失败的结果不缓存
+	_, err := fc.getOrFetch(1, "list:/tmp", func() (interface{}, error) { return nil, errors.New("agent offline") })
+	assert.Error(t, err)
+	v, _ = fc.getOrFetch(1, "list:/tmp", fetch)
+	assert.Equal(t, 6, v)
+}
+
+func TestFileListCacheDiscardsResultsRacingWrites(t *testing.T) {
+	fc := newFileListCache(time.Minute)
+
+	// 请求期间发生写操作，返回的旧结果不写入缓存
+	v, _ := fc.getOrFetch(1, "list:/", func() (interface{}, error) {
+		fc.invalidate(1)
+		return "stale", nil
+	})
+	assert.Equal(t, "stale", v)
+	v, _ = fc.getOrFetch(1, "list:/", func() (interface{}, error) { return "fresh", nil })
+	assert.Equal(t, "fresh", v)
+}
+
+func TestFileListCacheDisabled(t *testing.T) {
+	fc := newFileListCache(0)
+	calls := 0
+	fetch := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+	fc.getOrFetch(1, "list:/", fetch)
+	fc.getOrFetch(1, "list:/", fetch)
+	assert.Equal(t, 2, calls)
+}
@@ -34,6 +34,10 @@ type FileInfo struct {
 	ModTime  string      `json:"mod_time"`           // 修改时间
 	IsDir    bool        `json:"is_dir"`             // 是否是目录
 	Mode     string      `json:"mode"`               // 文件权限
+	Owner    string      `json:"owner,omitempty"`    // 所有者用户名
+	Group    string      `json:"group,omitempty"`    // 所属组名
+	UID      *uint32     `json:"uid,omitempty"`      // 所有者UID
+	GID      *uint32     `json:"gid,omitempty"`      // 所属组GID
 	Children []*FileInfo `json:"children,omitempty"` // 子文件（目录树使用）
 }
 
@@ -63,7 +67,9 @@ func GetFileList(c *gin.Context) {
 	}
 
 	// 通过WebSocket获取文件列表
-	result, err := requestFileListViaWebSocket(server.ID, path)
+	result, err := cachedFileListing(server.ID, "list:"+path, func() (interface{}, error) {
+		return requestFileListViaWebSocket(server.ID, path)
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取文件列表失败: %v", err)})
 		return
@@ -91,7 +97,9 @@ func GetFileTree(c *gin.Context) {
 	}
 
 	// 通过WebSocket获取文件树
-	result, err := requestFileTreeViaWebSocket(server.ID, depth)
+	result, err := cachedFileListing(server.ID, "tree:"+depth, func() (interface{}, error) {
+		return requestFileTreeViaWebSocket(server.ID, depth)
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取文件树失败: %v", err)})
 		return
@@ -141,6 +149,7 @@ func SaveFileContent(c *gin.Context) {
 	var req struct {
 		Path    string `json:"path"`
 		Content string `json:"content"`
+		Verify  bool   `json:"verify"` // 写入后由 Agent 回读文件并校验 SHA-256
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -168,13 +177,18 @@ func SaveFileContent(c *gin.Context) {
 	}
 
 	// 通过WebSocket保存文件内容
-	err := saveFileContentViaWebSocket(server.ID, req.Path, req.Content)
+	err := saveFileContentViaWebSocket(server.ID, req.Path, req.Content, req.Verify)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("保存文件内容失败: %v", err)})
+		respondFileWriteError(c, "保存文件内容失败", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true, "message": "文件保存成功"})
+	resp := gin.H{"success": true, "message": "文件保存成功"}
+	if req.Verify {
+		resp["verified"] = true
+		resp["sha256"] = contentChecksum([]byte(req.Content))
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // CreateFile 创建文件
@@ -184,6 +198,7 @@ func CreateFile(c *gin.Context) {
 	var req struct {
 		Path    string `json:"path"`
 		Content string `json:"content"`
+		Verify  bool   `json:"verify"` // 写入后由 Agent 回读文件并校验 SHA-256
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -211,13 +226,18 @@ func CreateFile(c *gin.Context) {
 	}
 
 	// 通过WebSocket创建文件
-	err := createFileViaWebSocket(server.ID, req.Path, req.Content)
+	err := createFileViaWebSocket(server.ID, req.Path, req.Content, req.Verify)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("创建文件失败: %v", err)})
+		respondFileWriteError(c, "创建文件失败", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true, "message": "文件创建成功"})
+	resp := gin.H{"success": true, "message": "文件创建成功"}
+	if req.Verify {
+		resp["verified"] = true
+		resp["sha256"] = contentChecksum([]byte(req.Content))
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // CreateDirectory 创建目录
@@ -290,13 +310,20 @@ func UploadFile(c *gin.Context) {
 	}
 	defer file.Close()
 
+	verify := c.PostForm("verify") == "true"
 	uploadSvc := services.GetUploadService()
-	if err := uploadSvc.UploadFromMultipart(services.TargetHost, server.ID, "", path, file, header); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("上传文件失败: %v", err)})
+	checksum, err := uploadSvc.UploadFromMultipart(services.TargetHost, server.ID, "", path, file, header, verify)
+	if err != nil {
+		respondFileWriteError(c, "上传文件失败", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true, "message": "文件上传成功"})
+	resp := gin.H{"success": true, "message": "文件上传成功"}
+	if verify {
+		resp["verified"] = true
+		resp["sha256"] = checksum
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // DownloadFile 下载文件
@@ -331,21 +358,44 @@ func DownloadFile(c *gin.Context) {
 		return
 	}
 
-	// 通过WebSocket获取文件内容
-	fileData, err := downloadFileViaWebSocket(server.ID, path)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("下载文件失败: %v", err)})
+	// 分片读取文件并流式写出，支持 Range 续传
+	serveChunkedDownload(c, server.ID, "", path, nil)
+}
+
+// DownloadArchive 把目录打包为 tar.gz 下载，带 container_id 时打包容器内的目录
+func DownloadArchive(c *gin.Context) {
+	serverID := c.Param("id")
+	path := c.Query("path")
+	containerID := c.Query("container_id")
+	token := c.Query("token")
+
+	// 验证token
+	claims, err := utils.ParseToken(token)
+	if err != nil || claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权，请重新登录"})
 		return
 	}
 
-	// 设置文件名
-	filename := filepath.Base(path)
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-	c.Header("Content-Type", "application/octet-stream")
-	c.Header("Content-Length", fmt.Sprintf("%d", len(fileData)))
+	// 获取服务器信息
+	var server models.Server
+	if err := models.DB.First(&server, serverID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
 
-	// 发送文件
-	c.Data(http.StatusOK, "application/octet-stream", fileData)
+	// 检查服务器在线状态
+	if !server.Online {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "服务器离线"})
+		return
+	}
+
+	// 验证目录路径
+	if !isValidFilePath(path) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件路径"})
+		return
+	}
+
+	serveArchiveDownload(c, server.ID, containerID, path)
 }
 
 // DeleteFiles 删除文件或目录
@@ -416,7 +466,9 @@ func GetContainerFileList(c *gin.Context) {
 		return
 	}
 
-	result, err := requestContainerFileListViaWebSocket(server.ID, containerID, path)
+	result, err := cachedFileListing(server.ID, "container_list:"+containerID+":"+path, func() (interface{}, error) {
+		return requestContainerFileListViaWebSocket(server.ID, containerID, path)
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取容器文件列表失败: %v", err)})
 		return
@@ -447,7 +499,9 @@ func GetContainerDirectoryChildren(c *gin.Context) {
 		return
 	}
 
-	result, err := requestContainerDirectoryChildrenViaWebSocket(server.ID, containerID, path)
+	result, err := cachedFileListing(server.ID, "container_children:"+containerID+":"+path, func() (interface{}, error) {
+		return requestContainerDirectoryChildrenViaWebSocket(server.ID, containerID, path)
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取容器目录子节点失败: %v", err)})
 		return
@@ -645,7 +699,7 @@ func UploadContainerFile(c *gin.Context) {
 	defer file.Close()
 
 	uploadSvc := services.GetUploadService()
-	if err := uploadSvc.UploadFromMultipart(services.TargetContainer, server.ID, containerID, path, file, header); err != nil {
+	if _, err := uploadSvc.UploadFromMultipart(services.TargetContainer, server.ID, containerID, path, file, header, false); err != nil {
 		// 区分大小限制错误和其他错误
 		if strings.Contains(err.Error(), "文件太大") {
 			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
@@ -687,17 +741,10 @@ func DownloadContainerFile(c *gin.Context) {
 		return
 	}
 
-	fileData, err := downloadContainerFileViaWebSocket(server.ID, containerID, path)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("下载文件失败: %v", err)})
-		return
-	}
-
-	filename := filepath.Base(path)
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-	c.Header("Content-Type", "application/octet-stream")
-	c.Header("Content-Length", fmt.Sprintf("%d", len(fileData)))
-	c.Data(http.StatusOK, "application/octet-stream", fileData)
+	// 旧版 Agent 不支持分片下载时退回一次性下载
+	serveChunkedDownload(c, server.ID, containerID, path, func() ([]byte, error) {
+		return downloadContainerFileViaWebSocket(server.ID, containerID, path)
+	})
 }
 
 // DeleteContainerFiles 删除容器文件
@@ -765,7 +812,9 @@ func GetDirectoryChildren(c *gin.Context) {
 	}
 
 	// 通过WebSocket获取目录子节点（深度为1）
-	result, err := requestDirectoryChildrenViaWebSocket(server.ID, path)
+	result, err := cachedFileListing(server.ID, "children:"+path, func() (interface{}, error) {
+		return requestDirectoryChildrenViaWebSocket(server.ID, path)
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取目录子节点失败: %v", err)})
 		return
@@ -797,7 +846,12 @@ func requestFileListViaWebSocket(serverID uint, path string) ([]FileInfo, error)
 	fileRequestMutex.Unlock()
 
 	// 【安全修复】注册待处理请求，以便在Agent断开时能快速失败
-	registerPendingRequest(serverID, requestID)
+	if err := registerPendingRequest(serverID, requestID); err != nil {
+		fileRequestMutex.Lock()
+		delete(fileRequestMap, requestID)
+		fileRequestMutex.Unlock()
+		return nil, err
+	}
 	defer unregisterPendingRequest(serverID, requestID)
 
 	// 构造请求消息
@@ -889,7 +943,12 @@ func requestFileTreeViaWebSocket(serverID uint, depth string) ([]*FileInfo, erro
 	fileRequestMutex.Unlock()
 
 	// 【安全修复】注册待处理请求，以便在Agent断开时能快速失败
-	registerPendingRequest(serverID, requestID)
+	if err := registerPendingRequest(serverID, requestID); err != nil {
+		fileRequestMutex.Lock()
+		delete(fileRequestMap, requestID)
+		fileRequestMutex.Unlock()
+		return nil, err
+	}
 	defer unregisterPendingRequest(serverID, requestID)
 
 	// 构造请求消息
@@ -973,7 +1032,12 @@ func requestFileContentViaWebSocket(serverID uint, path string) (string, error)
 	fileRequestMutex.Unlock()
 
 	// 【安全修复】注册待处理请求，以便在Agent断开时能快速失败
-	registerPendingRequest(serverID, requestID)
+	if err := registerPendingRequest(serverID, requestID); err != nil {
+		fileRequestMutex.Lock()
+		delete(fileRequestMap, requestID)
+		fileRequestMutex.Unlock()
+		return "", err
+	}
 	defer unregisterPendingRequest(serverID, requestID)
 
 	// 构造请求消息
@@ -1032,6 +1096,10 @@ func convertToFileInfo(data map[string]interface{}) *FileInfo {
 		ModTime: getString(data, "mod_time"),
 		IsDir:   getBool(data, "is_dir"),
 		Mode:    getString(data, "mode"),
+		Owner:   getString(data, "owner"),
+		Group:   getString(data, "group"),
+		UID:     getUint32Ptr(data, "uid"),
+		GID:     getUint32Ptr(data, "gid"),
 	}
 
 	// 处理子文件
@@ -1054,6 +1122,16 @@ func getString(data map[string]interface{}, key string) string {
 	return ""
 }
 
+// 获取map中的可选uint32值，不存在时返回nil（例如Windows上的UID/GID）
+func getUint32Ptr(data map[string]interface{}, key string) *uint32 {
+	v, ok := data[key].(float64)
+	if !ok {
+		return nil
+	}
+	n := uint32(v)
+	return &n
+}
+
 // 获取map中的int64值
 func getInt64(data map[string]interface{}, key string) int64 {
 	switch v := data[key].(type) {
@@ -1099,8 +1177,27 @@ func HandleFileResponse(requestID string, data map[string]interface{}) {
 	delete(fileRequestMap, requestID)
 }
 
+// HandleFileError 把 Agent 对文件请求的 error 回复交给等待中的请求，不是文件请求时返回 false
+func HandleFileError(requestID string, data map[string]interface{}) bool {
+	fileRequestMutex.Lock()
+	_, ok := fileRequestMap[requestID]
+	fileRequestMutex.Unlock()
+	if !ok {
+		return false
+	}
+
+	HandleFileResponse(requestID, map[string]interface{}{
+		"type":  "error",
+		"error": data["error"],
+		"data":  data,
+	})
+	return true
+}
+
 // 通过WebSocket保存文件内容
-func saveFileContentViaWebSocket(serverID uint, path string, content string) error {
+func saveFileContentViaWebSocket(serverID uint, path string, content string, verify bool) error {
+	defer invalidateFileListCache(serverID)
+
 	// 获取Agent连接
 	agentConnVal, ok := ActiveAgentConnections.Load(serverID)
 	if !ok {
@@ -1122,7 +1219,12 @@ func saveFileContentViaWebSocket(serverID uint, path string, content string) err
 	fileRequestMutex.Unlock()
 
 	// 【安全修复】注册待处理请求，以便在Agent断开时能快速失败
-	registerPendingRequest(serverID, requestID)
+	if err := registerPendingRequest(serverID, requestID); err != nil {
+		fileRequestMutex.Lock()
+		delete(fileRequestMap, requestID)
+		fileRequestMutex.Unlock()
+		return err
+	}
 	defer unregisterPendingRequest(serverID, requestID)
 
 	// 构造请求消息
@@ -1133,6 +1235,7 @@ func saveFileContentViaWebSocket(serverID uint, path string, content string) err
 			"path":    path,
 			"action":  "save",
 			"content": content,
+			"verify":  verify,
 		},
 	}
 
@@ -1150,7 +1253,10 @@ func saveFileContentViaWebSocket(serverID uint, path string, content string) err
 	case resp := <-respChan:
 		// 处理响应
 		if resp["type"] == "error" {
-			return fmt.Errorf("Agent返回错误: %v", resp["error"])
+			return agentFileError(resp)
+		}
+		if verify {
+			return verifyWrittenChecksum(resp, []byte(content))
 		}
 
 		return nil
@@ -1165,7 +1271,9 @@ func saveFileContentViaWebSocket(serverID uint, path string, content string) err
 }
 
 // 通过WebSocket创建文件
-func createFileViaWebSocket(serverID uint, path string, content string) error {
+func createFileViaWebSocket(serverID uint, path string, content string, verify bool) error {
+	defer invalidateFileListCache(serverID)
+
 	// 获取Agent连接
 	agentConnVal, ok := ActiveAgentConnections.Load(serverID)
 	if !ok {
@@ -1187,7 +1295,12 @@ func createFileViaWebSocket(serverID uint, path string, content string) error {
 	fileRequestMutex.Unlock()
 
 	// 【安全修复】注册待处理请求，以便在Agent断开时能快速失败
-	registerPendingRequest(serverID, requestID)
+	if err := registerPendingRequest(serverID, requestID); err != nil {
+		fileRequestMutex.Lock()
+		delete(fileRequestMap, requestID)
+		fileRequestMutex.Unlock()
+		return err
+	}
 	defer unregisterPendingRequest(serverID, requestID)
 
 	// 构造请求消息
@@ -1198,6 +1311,7 @@ func createFileViaWebSocket(serverID uint, path string, content string) error {
 			"path":    path,
 			"action":  "create",
 			"content": content,
+			"verify":  verify,
 		},
 	}
 
@@ -1215,7 +1329,10 @@ func createFileViaWebSocket(serverID uint, path string, content string) error {
 	case resp := <-respChan:
 		// 处理响应
 		if resp["type"] == "error" {
-			return fmt.Errorf("Agent返回错误: %v", resp["error"])
+			return agentFileError(resp)
+		}
+		if verify {
+			return verifyWrittenChecksum(resp, []byte(content))
 		}
 
 		return nil
@@ -1231,6 +1348,8 @@ func createFileViaWebSocket(serverID uint, path string, content string) error {
 
 // 通过WebSocket创建目录
 func createDirectoryViaWebSocket(serverID uint, path string) error {
+	defer invalidateFileListCache(serverID)
+
 	// 获取Agent连接
 	agentConnVal, ok := ActiveAgentConnections.Load(serverID)
 	if !ok {
@@ -1252,7 +1371,12 @@ func createDirectoryViaWebSocket(serverID uint, path string) error {
 	fileRequestMutex.Unlock()
 
 	// 【安全修复】注册待处理请求，以便在Agent断开时能快速失败
-	registerPendingRequest(serverID, requestID)
+	if err := registerPendingRequest(serverID, requestID); err != nil {
+		fileRequestMutex.Lock()
+		delete(fileRequestMap, requestID)
+		fileRequestMutex.Unlock()
+		return err
+	}
 	defer unregisterPendingRequest(serverID, requestID)
 
 	// 构造请求消息
@@ -1294,7 +1418,9 @@ func createDirectoryViaWebSocket(serverID uint, path string) error {
 }
 
 // 通过WebSocket上传文件
-func uploadFileViaWebSocket(serverID uint, path string, content []byte) error {
+func uploadFileViaWebSocket(serverID uint, path string, content []byte, verify bool) error {
+	defer invalidateFileListCache(serverID)
+
 	// 获取Agent连接
 	agentConnVal, ok := ActiveAgentConnections.Load(serverID)
 	if !ok {
@@ -1316,7 +1442,12 @@ func uploadFileViaWebSocket(serverID uint, path string, content []byte) error {
 	fileRequestMutex.Unlock()
 
 	// 【安全修复】注册待处理请求，以便在Agent断开时能快速失败
-	registerPendingRequest(serverID, requestID)
+	if err := registerPendingRequest(serverID, requestID); err != nil {
+		fileRequestMutex.Lock()
+		delete(fileRequestMap, requestID)
+		fileRequestMutex.Unlock()
+		return err
+	}
 	defer unregisterPendingRequest(serverID, requestID)
 
 	// Base64编码文件内容
@@ -1338,6 +1469,7 @@ func uploadFileViaWebSocket(serverID uint, path string, content []byte) error {
 			"path":     dir,
 			"filename": filename,
 			"content":  base64Content,
+			"verify":   verify,
 		},
 	}
 
@@ -1355,7 +1487,10 @@ func uploadFileViaWebSocket(serverID uint, path string, content []byte) error {
 	case resp := <-respChan:
 		// 处理响应
 		if resp["type"] == "error" {
-			return fmt.Errorf("Agent返回错误: %v", resp["error"])
+			return agentFileError(resp)
+		}
+		if verify {
+			return verifyWrittenChecksum(resp, content)
 		}
 
 		return nil
@@ -1369,88 +1504,10 @@ func uploadFileViaWebSocket(serverID uint, path string, content []byte) error {
 	}
 }
 
-// 通过WebSocket下载文件
-func downloadFileViaWebSocket(serverID uint, path string) ([]byte, error) {
-	// 获取Agent连接
-	agentConnVal, ok := ActiveAgentConnections.Load(serverID)
-	if !ok {
-		return nil, fmt.Errorf("服务器Agent未连接")
-	}
-
-	agentConn, ok := agentConnVal.(*SafeConn)
-	if !ok {
-		return nil, fmt.Errorf("服务器连接类型错误")
-	}
-
-	// 创建请求ID
-	requestID := fmt.Sprintf("file_download_%d", time.Now().UnixNano())
-
-	// 创建响应通道
-	respChan := make(chan map[string]interface{}, 1)
-	fileRequestMutex.Lock()
-	fileRequestMap[requestID] = respChan
-	fileRequestMutex.Unlock()
-
-	// 【安全修复】注册待处理请求，以便在Agent断开时能快速失败
-	registerPendingRequest(serverID, requestID)
-	defer unregisterPendingRequest(serverID, requestID)
-
-	// 构造请求消息 - 这里使用file_content消息类型的"download"操作
-	request := map[string]interface{}{
-		"type":       "file_content",
-		"request_id": requestID,
-		"payload": map[string]interface{}{
-			"path":   path,
-			"action": "download",
-		},
-	}
-
-	// 发送请求
-	if err := agentConn.WriteJSON(request); err != nil {
-		fileRequestMutex.Lock()
-		delete(fileRequestMap, requestID)
-		fileRequestMutex.Unlock()
-
-		return nil, fmt.Errorf("发送请求失败: %v", err)
-	}
-
-	// 等待响应或超时
-	select {
-	case resp := <-respChan:
-		// 处理响应
-		if resp["type"] == "error" {
-			return nil, fmt.Errorf("Agent返回错误: %v", resp["error"])
-		}
-
-		contentData, ok := resp["data"].(map[string]interface{})
-		if !ok {
-			return nil, fmt.Errorf("无效的响应格式")
-		}
-
-		content, ok := contentData["content"].(string)
-		if !ok {
-			return nil, fmt.Errorf("无效的文件内容格式")
-		}
-
-		// 解码Base64内容
-		fileData, err := base64.StdEncoding.DecodeString(content)
-		if err != nil {
-			return nil, fmt.Errorf("解码文件内容失败: %v", err)
-		}
-
-		return fileData, nil
-
-	case <-time.After(fileRequestTimeout): // 下载可能需要更长时间
-		fileRequestMutex.Lock()
-		delete(fileRequestMap, requestID)
-		fileRequestMutex.Unlock()
-
-		return nil, fmt.Errorf("请求超时")
-	}
-}
-
 // 通过WebSocket删除文件
 func deleteFilesViaWebSocket(serverID uint, paths []string) error {
+	defer invalidateFileListCache(serverID)
+
 	// 获取Agent连接
 	agentConnVal, ok := ActiveAgentConnections.Load(serverID)
 	if !ok {
@@ -1472,7 +1529,12 @@ func deleteFilesViaWebSocket(serverID uint, paths []string) error {
 	fileRequestMutex.Unlock()
 
 	// 【安全修复】注册待处理请求，以便在Agent断开时能快速失败
-	registerPendingRequest(serverID, requestID)
+	if err := registerPendingRequest(serverID, requestID); err != nil {
+		fileRequestMutex.Lock()
+		delete(fileRequestMap, requestID)
+		fileRequestMutex.Unlock()
+		return err
+	}
 	defer unregisterPendingRequest(serverID, requestID)
 
 	// 将路径列表转为JSON字符串
@@ -1543,7 +1605,12 @@ func requestDirectoryChildrenViaWebSocket(serverID uint, path string) ([]*FileIn
 	fileRequestMutex.Unlock()
 
 	// 【安全修复】注册待处理请求，以便在Agent断开时能快速失败
-	registerPendingRequest(serverID, requestID)
+	if err := registerPendingRequest(serverID, requestID); err != nil {
+		fileRequestMutex.Lock()
+		delete(fileRequestMap, requestID)
+		fileRequestMutex.Unlock()
+		return nil, err
+	}
 	defer unregisterPendingRequest(serverID, requestID)
 
 	// 构造请求消息 - 使用深度1来只获取直接子目录
@@ -1627,7 +1694,12 @@ func requestContainerFileListViaWebSocket(serverID uint, containerID string, pat
 	fileRequestMutex.Unlock()
 
 	// 【安全修复】注册待处理请求，以便在Agent断开时能快速失败
-	registerPendingRequest(serverID, requestID)
+	if err := registerPendingRequest(serverID, requestID); err != nil {
+		fileRequestMutex.Lock()
+		delete(fileRequestMap, requestID)
+		fileRequestMutex.Unlock()
+		return nil, err
+	}
 	defer unregisterPendingRequest(serverID, requestID)
 
 	request := map[string]interface{}{
@@ -1700,7 +1772,12 @@ func requestContainerDirectoryChildrenViaWebSocket(serverID uint, containerID, p
 	fileRequestMutex.Unlock()
 
 	// 【安全修复】注册待处理请求，以便在Agent断开时能快速失败
-	registerPendingRequest(serverID, requestID)
+	if err := registerPendingRequest(serverID, requestID); err != nil {
+		fileRequestMutex.Lock()
+		delete(fileRequestMap, requestID)
+		fileRequestMutex.Unlock()
+		return nil, err
+	}
 	defer unregisterPendingRequest(serverID, requestID)
 
 	request := map[string]interface{}{
@@ -1772,7 +1849,12 @@ func requestContainerFileContentViaWebSocket(serverID uint, containerID, path st
 	fileRequestMutex.Unlock()
 
 	// 【安全修复】注册待处理请求，以便在Agent断开时能快速失败
-	registerPendingRequest(serverID, requestID)
+	if err := registerPendingRequest(serverID, requestID); err != nil {
+		fileRequestMutex.Lock()
+		delete(fileRequestMap, requestID)
+		fileRequestMutex.Unlock()
+		return "", err
+	}
 	defer unregisterPendingRequest(serverID, requestID)
 
 	request := map[string]interface{}{
@@ -1829,6 +1911,8 @@ func createContainerDirectoryViaWebSocket(serverID uint, containerID, path strin
 }
 
 func deleteContainerFilesViaWebSocket(serverID uint, containerID string, paths []string) error {
+	defer invalidateFileListCache(serverID)
+
 	agentConnVal, ok := ActiveAgentConnections.Load(serverID)
 	if !ok {
 		return fmt.Errorf("服务器Agent未连接")
@@ -1845,7 +1929,12 @@ func deleteContainerFilesViaWebSocket(serverID uint, containerID string, paths [
 	fileRequestMutex.Unlock()
 
 	// 【安全修复】注册待处理请求，以便在Agent断开时能快速失败
-	registerPendingRequest(serverID, requestID)
+	if err := registerPendingRequest(serverID, requestID); err != nil {
+		fileRequestMutex.Lock()
+		delete(fileRequestMap, requestID)
+		fileRequestMutex.Unlock()
+		return err
+	}
 	defer unregisterPendingRequest(serverID, requestID)
 
 	pathsJSON, err := json.Marshal(paths)
@@ -1889,6 +1978,8 @@ func deleteContainerFilesViaWebSocket(serverID uint, containerID string, paths [
 }
 
 func uploadContainerFileViaWebSocket(serverID uint, containerID, path string, content []byte) error {
+	defer invalidateFileListCache(serverID)
+
 	agentConnVal, ok := ActiveAgentConnections.Load(serverID)
 	if !ok {
 		return fmt.Errorf("服务器Agent未连接")
@@ -1905,7 +1996,12 @@ func uploadContainerFileViaWebSocket(serverID uint, containerID, path string, co
 	fileRequestMutex.Unlock()
 
 	// 【安全修复】注册待处理请求，以便在Agent断开时能快速失败
-	registerPendingRequest(serverID, requestID)
+	if err := registerPendingRequest(serverID, requestID); err != nil {
+		fileRequestMutex.Lock()
+		delete(fileRequestMap, requestID)
+		fileRequestMutex.Unlock()
+		return err
+	}
 	defer unregisterPendingRequest(serverID, requestID)
 
 	base64Content := base64.StdEncoding.EncodeToString(content)
@@ -1967,7 +2063,12 @@ func downloadContainerFileViaWebSocket(serverID uint, containerID, path string)
 	fileRequestMutex.Unlock()
 
 	// 【安全修复】注册待处理请求，以便在Agent断开时能快速失败
-	registerPendingRequest(serverID, requestID)
+	if err := registerPendingRequest(serverID, requestID); err != nil {
+		fileRequestMutex.Lock()
+		delete(fileRequestMap, requestID)
+		fileRequestMutex.Unlock()
+		return nil, err
+	}
 	defer unregisterPendingRequest(serverID, requestID)
 
 	request := map[string]interface{}{
@@ -2016,6 +2117,8 @@ func downloadContainerFileViaWebSocket(serverID uint, containerID, path string)
 }
 
 func genericContainerFileContentAction(serverID uint, containerID, path, action, content string) error {
+	defer invalidateFileListCache(serverID)
+
 	agentConnVal, ok := ActiveAgentConnections.Load(serverID)
 	if !ok {
 		return fmt.Errorf("服务器Agent未连接")
@@ -2032,7 +2135,12 @@ func genericContainerFileContentAction(serverID uint, containerID, path, action,
 	fileRequestMutex.Unlock()
 
 	// 【安全修复】注册待处理请求，以便在Agent断开时能快速失败
-	registerPendingRequest(serverID, requestID)
+	if err := registerPendingRequest(serverID, requestID); err != nil {
+		fileRequestMutex.Lock()
+		delete(fileRequestMap, requestID)
+		fileRequestMutex.Unlock()
+		return err
+	}
 	defer unregisterPendingRequest(serverID, requestID)
 
 	request := map[string]interface{}{
@@ -2069,46 +2177,30 @@ func genericContainerFileContentAction(serverID uint, containerID, path, action,
 	}
 }
 
+// bannedFilePaths 禁止访问的敏感路径列表
+var bannedFilePaths = []string{
+	"/etc/shadow",
+	"/etc/passwd",
+	"/etc/sudoers",
+	"/etc/gshadow",
+	"/etc/security",
+	"/root/.ssh",
+	"/home/.ssh",
+	"/proc",
+	"/sys",
+}
+
 // isValidFilePath 验证文件路径是否合法
 // 安全检查：在Clean之前先检查原始路径中的traversal段，防止目录穿越攻击
 func isValidFilePath(path string) bool {
-	if path == "" {
-		return false
-	}
-
-	// 【关键】在Clean之前检查原始路径中的traversal段
-	// 因为Clean会将 "../" 规范化，导致后续检查失效
-	// 例如："/www/../etc/passwd" 会被Clean为 "/etc/passwd"
-	normalizedSlash := filepath.ToSlash(path)
-	for _, seg := range strings.Split(normalizedSlash, "/") {
-		if seg == ".." {
-			return false
-		}
-	}
-
-	// 清理路径
-	cleanPath := filepath.Clean(path)
-
-	// 再次检查Clean后的路径（双重保险）
-	if cleanPath == ".." || strings.HasPrefix(cleanPath, ".."+string(filepath.Separator)) {
+	// 统一的路径规范化：拒绝空字节、".." 路径段和相对路径
+	cleanPath, err := utils.NormalizeAgentPath(path)
+	if err != nil {
 		return false
 	}
 
-	// 禁止访问的敏感路径列表
-	bannedPaths := []string{
-		"/etc/shadow",
-		"/etc/passwd",
-		"/etc/sudoers",
-		"/etc/gshadow",
-		"/etc/security",
-		"/root/.ssh",
-		"/home/.ssh",
-		"/proc",
-		"/sys",
-	}
-
 	// 检查是否在禁止访问的目录中
-	for _, banned := range bannedPaths {
+	for _, banned := range bannedFilePaths {
 		if cleanPath == banned || strings.HasPrefix(cleanPath, banned+"/") {
 			return false
 		}
@@ -2124,44 +2216,21 @@ func validatePathInBaseDir(baseDir, userPath string) (string, error) {
 		return "", fmt.Errorf("路径不能为空")
 	}
 
-	// 先检查原始路径中的traversal段
-	normalizedSlash := filepath.ToSlash(userPath)
-	for _, seg := range strings.Split(normalizedSlash, "/") {
-		if seg == ".." {
-			return "", fmt.Errorf("路径包含非法字符")
-		}
-	}
-
-	// 构造完整路径
-	var fullPath string
-	if filepath.IsAbs(userPath) {
-		fullPath = filepath.Clean(userPath)
-	} else {
-		fullPath = filepath.Clean(filepath.Join(baseDir, userPath))
-	}
-
-	// 获取基础目录的绝对路径
-	absBase, err := filepath.Abs(baseDir)
-	if err != nil {
-		return "", fmt.Errorf("无效的基础目录")
+	// 相对路径按基础目录展开后再统一规范化
+	fullPath := userPath
+	if !strings.HasPrefix(userPath, "/") {
+		fullPath = strings.TrimRight(baseDir, "/") + "/" + userPath
 	}
 
-	// 获取目标路径的绝对路径
-	absTarget, err := filepath.Abs(fullPath)
+	cleanPath, err := utils.NormalizeAgentPath(fullPath)
 	if err != nil {
-		return "", fmt.Errorf("无效的目标路径")
+		return "", err
 	}
 
 	// 验证目标路径是否在基础目录内
-	rel, err := filepath.Rel(absBase, absTarget)
-	if err != nil {
-		return "", fmt.Errorf("路径验证失败")
-	}
-
-	// 如果相对路径以..开头，说明目标路径不在基础目录内
-	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+	if !utils.PathWithinRoot(baseDir, cleanPath) {
 		return "", fmt.Errorf("路径越界")
 	}
 
-	return absTarget, nil
+	return cleanPath, nil
 }
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 文件对比请求的响应通道
+var fileDiffChannels sync.Map
+
+// fileDiffRequest 查看文件变更的请求参数
+type fileDiffRequest struct {
+	Path     string  `json:"path" binding:"required"` // 新版本文件
+	BasePath string  `json:"base_path"`               // 旧版本文件，为空时使用最近一次保存留下的备份
+	Content  *string `json:"content"`                 // 编辑器中尚未保存的内容，不为空时代替 path 作为新版本
+}
+
+// GetFileDiff 返回文件与其最近备份（或指定的另一个文件）之间的统一 diff，由Agent计算
+func GetFileDiff(c *gin.Context) {
+	var req fileDiffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定文件路径"})
+		return
+	}
+
+	payload := map[string]interface{}{
+		"path":      req.Path,
+		"base_path": req.BasePath,
+	}
+	if req.Content != nil {
+		payload["content"] = *req.Content
+	}
+	requestAgentWithTimeout(c, "file_diff", &fileDiffChannels, payload, TimeoutFileOperation)
+}
+
+// HandleFileDiffResponse 将Agent的文件对比响应传递给等待中的HTTP请求
+func HandleFileDiffResponse(requestID string, data map[string]interface{}) {
+	deliverAgentResponse(&fileDiffChannels, requestID, data)
+}
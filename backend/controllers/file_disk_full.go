@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// agentDiskFullError Agent 报告目标磁盘空间不足（code=disk_full）
+type agentDiskFullError struct {
+	message   string
+	available uint64
+	required  uint64
+}
+
+func (e *agentDiskFullError) Error() string { return e.message }
+
+// diskFullFromData 从 Agent 的错误数据中识别磁盘已满，不是时返回 nil
+func diskFullFromData(data map[string]interface{}, message string) *agentDiskFullError {
+	if code, _ := data["code"].(string); code != "disk_full" {
+		return nil
+	}
+	available, _ := data["available"].(float64)
+	required, _ := data["required"].(float64)
+	return &agentDiskFullError{message: message, available: uint64(available), required: uint64(required)}
+}
+
+// agentFileError 把 Agent 对文件写入请求的 error 回复转换为错误，磁盘已满时保留剩余和所需空间
+func agentFileError(resp map[string]interface{}) error {
+	message := fmt.Sprintf("Agent返回错误: %v", resp["error"])
+	data, _ := resp["data"].(map[string]interface{})
+	if restoreErr, _ := data["restore_error"].(string); restoreErr != "" {
+		message += fmt.Sprintf("；从备份恢复失败: %s，备份文件: %v", restoreErr, data["backup_path"])
+	}
+	if diskFull := diskFullFromData(data, message); diskFull != nil {
+		return diskFull
+	}
+	return errors.New(message)
+}
+
+// respondFileWriteError 返回文件写入失败的响应，磁盘已满时使用 507 并附带结构化字段
+func respondFileWriteError(c *gin.Context, prefix string, err error) {
+	var diskFull *agentDiskFullError
+	if errors.As(err, &diskFull) {
+		c.JSON(http.StatusInsufficientStorage, gin.H{
+			"error":     fmt.Sprintf("%s: 服务器磁盘空间不足，请清理后重试（%v）", prefix, err),
+			"code":      "disk_full",
+			"available": diskFull.available,
+			"required":  diskFull.required,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%s: %v", prefix, err)})
+}
+
+// respondAckError 返回分片上传 ACK 失败的响应，磁盘已满时同 respondFileWriteError
+func respondAckError(c *gin.Context, resp map[string]interface{}, errMsg string) {
+	data, _ := resp["data"].(map[string]interface{})
+	if diskFull := diskFullFromData(data, errMsg); diskFull != nil {
+		respondFileWriteError(c, "上传文件失败", diskFull)
+		return
+	}
+	c.JSON(http.StatusBadGateway, gin.H{"error": errMsg})
+}
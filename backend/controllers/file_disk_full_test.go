@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgentFileErrorDiskFull(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Agent 的 error 回复交给等待中的文件请求
+	respChan := make(chan map[string]interface{}, 1)
+	fileRequestMutex.Lock()
+	fileRequestMap["file_save_test"] = respChan
+	fileRequestMutex.Unlock()
+	assert.False(t, HandleFileError("docker_test", map[string]interface{}{"error": "x"}))
+	assert.True(t, HandleFileError("file_save_test", map[string]interface{}{
+		"error":     "磁盘空间不足: /etc 剩余 10 字节，需要 100 字节",
+		"code":      "disk_full",
+		"available": float64(10),
+		"required":  float64(100),
+	}))
+	resp := <-respChan
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	respondFileWriteError(c, "保存文件内容失败", agentFileError(resp))
+	assert.Equal(t, http.StatusInsufficientStorage, w.Code)
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "disk_full", body["code"])
+	assert.Equal(t, float64(10), body["available"])
+	assert.Equal(t, float64(100), body["required"])
+
+	// 其他错误仍返回 500，恢复失败时带上备份路径
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	respondFileWriteError(c, "保存文件内容失败", agentFileError(map[string]interface{}{
+		"type":  "error",
+		"error": "permission denied",
+		"data":  map[string]interface{}{"restore_error": "read-only file system", "backup_path": "/etc/app.conf.bak"},
+	}))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "/etc/app.conf.bak")
+}
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+// fileManageRequest 修改权限、所有者以及重命名、移动、复制的请求参数
+type fileManageRequest struct {
+	Path      string `json:"path" binding:"required"`
+	Mode      string `json:"mode"`      // chmod: 八进制权限，如 "755"
+	Owner     string `json:"owner"`     // chown: 用户名或 UID
+	Group     string `json:"group"`     // chown: 组名或 GID
+	Recursive bool   `json:"recursive"` // chmod/chown: 递归修改目录下的条目
+	Name      string `json:"name"`      // rename: 新名称
+	Target    string `json:"target"`    // move/copy: 目标路径，已存在的目录表示放入其中
+}
+
+// ChmodFile 修改文件或目录的权限
+func ChmodFile(c *gin.Context) {
+	runFileManageAction(c, "chmod", "修改权限失败", func(req *fileManageRequest) (map[string]interface{}, error) {
+		if strings.TrimSpace(req.Mode) == "" {
+			return nil, errors.New("请指定权限")
+		}
+		return map[string]interface{}{"mode": req.Mode, "recursive": req.Recursive}, nil
+	})
+}
+
+// ChownFile 修改文件或目录的所有者和所属组
+func ChownFile(c *gin.Context) {
+	runFileManageAction(c, "chown", "修改所有者失败", func(req *fileManageRequest) (map[string]interface{}, error) {
+		if strings.TrimSpace(req.Owner) == "" && strings.TrimSpace(req.Group) == "" {
+			return nil, errors.New("请指定所有者或所属组")
+		}
+		return map[string]interface{}{"owner": req.Owner, "group": req.Group, "recursive": req.Recursive}, nil
+	})
+}
+
+// RenameFile 在原目录内重命名文件或目录
+func RenameFile(c *gin.Context) {
+	runFileManageAction(c, "rename", "重命名失败", func(req *fileManageRequest) (map[string]interface{}, error) {
+		name := strings.TrimSpace(req.Name)
+		if name == "" || strings.ContainsAny(name, "/\\") || !isValidFilePath(path.Join(path.Dir(req.Path), name)) {
+			return nil, errors.New("无效的文件名")
+		}
+		return map[string]interface{}{"name": name}, nil
+	})
+}
+
+// MoveFile 移动文件或目录
+func MoveFile(c *gin.Context) {
+	runFileManageAction(c, "move", "移动失败", transferTarget)
+}
+
+// CopyFile 复制文件或目录
+func CopyFile(c *gin.Context) {
+	runFileManageAction(c, "copy", "复制失败", transferTarget)
+}
+
+// transferTarget 校验移动/复制的目标路径
+func transferTarget(req *fileManageRequest) (map[string]interface{}, error) {
+	if !isValidFilePath(req.Target) {
+		return nil, errors.New("无效的目标路径")
+	}
+	return map[string]interface{}{"target": req.Target}, nil
+}
+
+// runFileManageAction 校验请求后通过 file_content 协议把操作交给Agent执行。
+// params 返回该操作需要的额外参数，出错时以 400 返回
+func runFileManageAction(c *gin.Context, action, errPrefix string, params func(*fileManageRequest) (map[string]interface{}, error)) {
+	var req fileManageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+	if !isValidFilePath(req.Path) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件路径"})
+		return
+	}
+	payload, err := params(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	payload["path"] = req.Path
+	payload["action"] = action
+
+	var server models.Server
+	if err := models.DB.First(&server, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+	if !server.Online {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "服务器离线"})
+		return
+	}
+
+	// 复制或跨文件系统移动大目录耗时较长
+	timeout := fileRequestTimeout
+	if action == "move" || action == "copy" {
+		timeout = TimeoutLongOperation
+	}
+	data, err := fileManageViaWebSocket(server.ID, payload, timeout)
+	if err != nil {
+		respondFileWriteError(c, errPrefix, err)
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+// fileManageViaWebSocket 发送 file_content 修改类操作并返回Agent的结果
+func fileManageViaWebSocket(serverID uint, payload map[string]interface{}, timeout time.Duration) (map[string]interface{}, error) {
+	defer invalidateFileListCache(serverID)
+
+	resp, err := sendChunkedRequestTimeout(serverID, "file_content", payload, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if resp["type"] == "error" {
+		return nil, agentFileError(resp)
+	}
+	data, _ := resp["data"].(map[string]interface{})
+	if data == nil {
+		return nil, errors.New("Agent 返回无效的响应")
+	}
+	return data, nil
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestFileManageActions(t *testing.T) {
+	setupTestDB(t)
+	server := models.Server{Name: "file-agent", Online: true, SecretKey: "secret"}
+	assert.NoError(t, models.DB.Create(&server).Error)
+	t.Cleanup(func() { models.DB.Unscoped().Delete(&server) })
+
+	var mu sync.Mutex
+	var received []map[string]interface{}
+	requests := func() []map[string]interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]map[string]interface{}(nil), received...)
+	}
+	connectReverseAgent(t, server.ID, func(msg map[string]interface{}) map[string]interface{} {
+		if msg["type"] != "file_content" {
+			return nil
+		}
+		payload := msg["payload"].(map[string]interface{})
+		mu.Lock()
+		received = append(received, payload)
+		mu.Unlock()
+		if payload["action"] == "copy" {
+			return map[string]interface{}{
+				"type": TypeError,
+				"data": map[string]interface{}{"error": "磁盘空间不足", "code": "disk_full", "available": 10, "required": 100},
+			}
+		}
+		return map[string]interface{}{
+			"type": "file_content_response",
+			"data": map[string]interface{}{"path": payload["path"], "target": "/srv/app.conf.old", "success": true},
+		}
+	})
+
+	call := func(handler gin.HandlerFunc, body map[string]interface{}) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(raw))
+		c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(server.ID), 10)}}
+		handler(c)
+		return w
+	}
+
+	w := call(ChmodFile, map[string]interface{}{"path": "/srv/app", "mode": "750", "recursive": true})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = call(RenameFile, map[string]interface{}{"path": "/srv/app.conf", "name": "app.conf.old"})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "/srv/app.conf.old")
+	if got := requests(); assert.Len(t, got, 2) {
+		assert.Equal(t, map[string]interface{}{"path": "/srv/app", "action": "chmod", "mode": "750", "recursive": true}, got[0])
+		assert.Equal(t, "rename", got[1]["action"])
+	}
+
+	// Agent 报告磁盘已满时返回 507
+	w = call(CopyFile, map[string]interface{}{"path": "/srv/app", "target": "/backup"})
+	assert.Equal(t, http.StatusInsufficientStorage, w.Code, w.Body.String())
+
+	// 参数校验失败的请求不会发给 Agent
+	for _, tc := range []struct {
+		handler gin.HandlerFunc
+		body    map[string]interface{}
+	}{
+		{ChmodFile, map[string]interface{}{"path": "/srv/app"}},
+		{ChownFile, map[string]interface{}{"path": "/srv/app"}},
+		{RenameFile, map[string]interface{}{"path": "/srv/app", "name": "../etc"}},
+		{MoveFile, map[string]interface{}{"path": "/srv/app", "target": "/etc/shadow"}},
+		{CopyFile, map[string]interface{}{"path": "/root/.ssh", "target": "/tmp"}},
+	} {
+		w := call(tc.handler, tc.body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	assert.Len(t, requests(), 3)
+}
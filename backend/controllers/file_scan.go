@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/user/server-ops-backend/models"
+)
+
+// 存储进行中的文件扫描 - key: scanID, value: *SafeConn (用户连接)
+var ActiveFileScanConnections sync.Map
+
+// handleFileScan 处理文件搜索/磁盘占用扫描请求（用户 → Agent 转发），
+// 搜索支持与 SearchFiles 相同的条件（mode、include、case_sensitive 等），扫描期间推送进度并可取消
+func handleFileScan(conn *SafeConn, server *models.Server, payload json.RawMessage) {
+	var reqData struct {
+		Action string `json:"action"`
+		ScanID string `json:"scan_id"`
+	}
+	if err := json.Unmarshal(payload, &reqData); err != nil {
+		log.Printf("解析文件扫描请求参数失败: %v", err)
+		sendErrorMessage(conn, "文件扫描请求格式错误")
+		return
+	}
+	if reqData.ScanID == "" {
+		sendErrorMessage(conn, "文件扫描请求缺少 scan_id")
+		return
+	}
+
+	agentConnVal, ok := ActiveAgentConnections.Load(server.ID)
+	if !ok {
+		sendErrorMessage(conn, "服务器Agent未连接")
+		return
+	}
+	agentConn, ok := agentConnVal.(*SafeConn)
+	if !ok {
+		sendErrorMessage(conn, "服务器连接错误")
+		return
+	}
+
+	// 取消请求保留映射，Agent 仍会返回带 truncated 标记的部分结果
+	starting := reqData.Action == "" || reqData.Action == "start"
+	if starting {
+		ActiveFileScanConnections.Store(reqData.ScanID, conn)
+	}
+
+	agentMsg := map[string]interface{}{
+		"type":    "file_scan",
+		"payload": payload,
+	}
+	if err := agentConn.WriteJSON(agentMsg); err != nil {
+		log.Printf("发送文件扫描请求到Agent失败: %v", err)
+		sendErrorMessage(conn, "发送文件扫描请求到Agent失败")
+		if starting {
+			ActiveFileScanConnections.Delete(reqData.ScanID)
+		}
+		return
+	}
+
+	log.Printf("文件扫描请求已转发到Agent: action=%s, scan_id=%s, 服务器ID=%d", reqData.Action, reqData.ScanID, server.ID)
+}
+
+// forwardFileScanMessage 将Agent发回的扫描进度/结果转发给发起扫描的用户连接
+func forwardFileScanMessage(message []byte) {
+	var scanMsg struct {
+		Type   string                 `json:"type"`
+		ScanID string                 `json:"scan_id"`
+		Data   map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(message, &scanMsg); err != nil {
+		log.Printf("解析文件扫描消息失败: %v", err)
+		return
+	}
+	if scanMsg.ScanID == "" {
+		log.Printf("警告: 收到的文件扫描消息没有 scan_id")
+		return
+	}
+
+	userConnVal, ok := ActiveFileScanConnections.Load(scanMsg.ScanID)
+	if !ok {
+		return
+	}
+	// 搜索结果与一次性搜索一样去掉面板禁止访问的路径，内容搜索命中的行不能泄露敏感文件
+	if matches, ok := scanMsg.Data["matches"]; ok {
+		scanMsg.Data["matches"] = filterSearchMatches(matches)
+	}
+	if userConn, ok := userConnVal.(*SafeConn); ok {
+		if err := userConn.WriteJSON(scanMsg); err != nil {
+			log.Printf("转发文件扫描消息到用户失败: scan_id=%s, error=%v", scanMsg.ScanID, err)
+		}
+	}
+
+	if scanMsg.Type == "file_scan_result" {
+		ActiveFileScanConnections.Delete(scanMsg.ScanID)
+	}
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+// newTestConnPair 建立一对websocket连接，返回面板一侧的 SafeConn 和对端连接
+func newTestConnPair(t *testing.T) (*SafeConn, *websocket.Conn) {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	accepted := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		accepted <- conn
+	}))
+	t.Cleanup(srv.Close)
+
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("建立测试连接失败: %v", err)
+	}
+	conn := <-accepted
+	t.Cleanup(func() {
+		peer.Close()
+		conn.Close()
+	})
+	return &SafeConn{Conn: conn}, peer
+}
+
+// readTestJSON 读取对端收到的下一条消息
+func readTestJSON(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg map[string]interface{}
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("读取消息失败: %v", err)
+	}
+	return msg
+}
+
+func TestHandleFileScanForwardsToAgent(t *testing.T) {
+	server := &models.Server{}
+	server.ID = 601
+	userConn, userPeer := newTestConnPair(t)
+
+	// Agent 未连接时直接返回错误，不登记扫描
+	handleFileScan(userConn, server, json.RawMessage(`{"scan_id":"s1","kind":"search","path":"/etc","pattern":"*.conf"}`))
+	assert.Equal(t, "error", readTestJSON(t, userPeer)["type"])
+	_, ok := ActiveFileScanConnections.Load("s1")
+	assert.False(t, ok)
+
+	agentConn, agentPeer := newTestConnPair(t)
+	ActiveAgentConnections.Store(server.ID, agentConn)
+	t.Cleanup(func() {
+		ActiveAgentConnections.Delete(server.ID)
+		ActiveFileScanConnections.Delete("s1")
+	})
+
+	// 缺少 scan_id 时不转发
+	handleFileScan(userConn, server, json.RawMessage(`{"kind":"search"}`))
+	assert.Equal(t, "error", readTestJSON(t, userPeer)["type"])
+
+	// 开始扫描：原样转发搜索条件，登记发起扫描的用户连接
+	handleFileScan(userConn, server, json.RawMessage(`{"scan_id":"s1","kind":"search","path":"/etc","pattern":"listen","mode":"content","include":"*.conf"}`))
+	msg := readTestJSON(t, agentPeer)
+	assert.Equal(t, "file_scan", msg["type"])
+	assert.Equal(t, map[string]interface{}{
+		"scan_id": "s1", "kind": "search", "path": "/etc", "pattern": "listen", "mode": "content", "include": "*.conf",
+	}, msg["payload"])
+	val, ok := ActiveFileScanConnections.Load("s1")
+	assert.True(t, ok)
+	assert.Same(t, userConn, val)
+
+	// 取消请求同样转发，但保留映射以便转发带截断标记的部分结果
+	handleFileScan(userConn, server, json.RawMessage(`{"scan_id":"s1","action":"cancel"}`))
+	msg = readTestJSON(t, agentPeer)
+	assert.Equal(t, "cancel", msg["payload"].(map[string]interface{})["action"])
+	_, ok = ActiveFileScanConnections.Load("s1")
+	assert.True(t, ok)
+}
+
+func TestForwardFileScanMessage(t *testing.T) {
+	userConn, userPeer := newTestConnPair(t)
+	ActiveFileScanConnections.Store("s2", userConn)
+	t.Cleanup(func() { ActiveFileScanConnections.Delete("s2") })
+
+	// 进度消息原样转发给发起扫描的用户
+	forwardFileScanMessage([]byte(`{"type":"file_scan_progress","scan_id":"s2","data":{"files_scanned":10,"current_path":"/etc/nginx"}}`))
+	msg := readTestJSON(t, userPeer)
+	assert.Equal(t, "file_scan_progress", msg["type"])
+	assert.Equal(t, "s2", msg["scan_id"])
+	assert.Equal(t, map[string]interface{}{"files_scanned": float64(10), "current_path": "/etc/nginx"}, msg["data"])
+	_, ok := ActiveFileScanConnections.Load("s2")
+	assert.True(t, ok)
+
+	// 结果去掉面板禁止访问的路径后转发，截断标记保留，转发后删除映射
+	forwardFileScanMessage([]byte(`{"type":"file_scan_result","scan_id":"s2","data":{
+		"truncated":true,"reason":"cancelled",
+		"matches":[{"path":"/etc/nginx/nginx.conf","lines":[{"line":2,"text":"listen 80;"}]},{"path":"/etc/shadow","lines":[{"line":1,"text":"root:$6$..."}]}]
+	}}`))
+	msg = readTestJSON(t, userPeer)
+	data := msg["data"].(map[string]interface{})
+	assert.Equal(t, true, data["truncated"])
+	assert.Equal(t, "cancelled", data["reason"])
+	if matches, ok := data["matches"].([]interface{}); assert.True(t, ok) && assert.Len(t, matches, 1) {
+		assert.Equal(t, "/etc/nginx/nginx.conf", matches[0].(map[string]interface{})["path"])
+	}
+	_, ok = ActiveFileScanConnections.Load("s2")
+	assert.False(t, ok)
+
+	// 映射已删除或没有 scan_id 的消息不再转发
+	forwardFileScanMessage([]byte(`{"type":"file_scan_progress","scan_id":"s2","data":{}}`))
+	forwardFileScanMessage([]byte(`{"type":"file_scan_progress","data":{}}`))
+	forwardFileScanMessage([]byte(`not json`))
+	userPeer.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, _, err := userPeer.ReadMessage()
+	assert.Error(t, err)
+}
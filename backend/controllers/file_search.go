@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 文件搜索请求的响应通道
+var fileSearchChannels sync.Map
+
+// SearchFiles 在服务器目录下按文件名（子串或通配符）或文件内容搜索，由Agent执行并一次性返回结果。
+// 查询参数：path、pattern、mode（name/glob/content）、include、case_sensitive、
+// max_depth、max_file_size、max_results、timeout（秒），数值为 0 或省略时使用Agent的默认值
+func SearchFiles(c *gin.Context) {
+	path := c.Query("path")
+	pattern := c.Query("pattern")
+	if path == "" || strings.TrimSpace(pattern) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定搜索目录和搜索条件"})
+		return
+	}
+	if !isValidFilePath(path) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件路径"})
+		return
+	}
+
+	payload := map[string]interface{}{
+		"path":           path,
+		"pattern":        pattern,
+		"mode":           c.DefaultQuery("mode", "name"),
+		"include":        c.Query("include"),
+		"case_sensitive": c.DefaultQuery("case_sensitive", "false") == "true",
+	}
+	for _, key := range []string{"max_depth", "max_file_size", "max_results", "timeout"} {
+		raw := c.Query(key)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || value < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的参数: " + key})
+			return
+		}
+		payload[key] = value
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+	response, status, err := callAgent(uint(id), "file_search", &fileSearchChannels, payload, TimeoutLongOperation)
+	if err != nil {
+		body := gin.H{"error": err.Error()}
+		if status == http.StatusRequestEntityTooLarge {
+			body["error_code"] = "response_too_large"
+		}
+		c.JSON(status, body)
+		return
+	}
+	if result, ok := response["result"].(map[string]interface{}); ok {
+		result["matches"] = filterSearchMatches(result["matches"])
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// filterSearchMatches 去掉面板禁止访问的敏感路径，避免内容搜索泄露如 /etc/shadow 中的行
+func filterSearchMatches(raw interface{}) []interface{} {
+	matches, _ := raw.([]interface{})
+	filtered := make([]interface{}, 0, len(matches))
+	for _, m := range matches {
+		match, ok := m.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if path, _ := match["path"].(string); isValidFilePath(path) {
+			filtered = append(filtered, match)
+		}
+	}
+	return filtered
+}
+
+// HandleFileSearchResponse 将Agent的文件搜索响应传递给等待中的HTTP请求
+func HandleFileSearchResponse(requestID string, data map[string]interface{}) {
+	deliverAgentResponse(&fileSearchChannels, requestID, data)
+}
@@ -0,0 +1,19 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterSearchMatches(t *testing.T) {
+	matches := []interface{}{
+		map[string]interface{}{"path": "/etc/hosts"},
+		map[string]interface{}{"path": "/etc/shadow", "lines": []interface{}{"root:$6$..."}},
+		map[string]interface{}{"path": "/root/.ssh/id_rsa"},
+		"invalid",
+	}
+	filtered := filterSearchMatches(matches)
+	assert.Equal(t, []interface{}{map[string]interface{}{"path": "/etc/hosts"}}, filtered)
+	assert.Empty(t, filterSearchMatches(nil))
+}
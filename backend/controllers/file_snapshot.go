@@ -0,0 +1,281 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// 每个目录保留的快照数量
+	fileSnapshotKeep = 20
+	// Agent 最长遍历 2 分钟后返回部分结果，这里额外留出传输时间
+	fileSnapshotRequestTimeout = TimeoutLongOperation + 30*time.Second
+)
+
+// 文件快照请求的响应通道
+var fileSnapshotChannels sync.Map
+
+// fileSnapshotEntry 快照中的单个条目，与 Agent 上报的格式一致
+type fileSnapshotEntry struct {
+	Path    string `json:"path"`
+	IsDir   bool   `json:"is_dir,omitempty"`
+	Size    int64  `json:"size"`
+	Mode    string `json:"mode"`
+	ModTime int64  `json:"mod_time"`
+	Link    string `json:"link,omitempty"`
+	SHA256  string `json:"sha256,omitempty"`
+}
+
+// FileSnapshotChange 两次快照之间被修改的条目，Changes 列出发生变化的属性
+type FileSnapshotChange struct {
+	Path    string             `json:"path"`
+	Changes []string           `json:"changes"` // type/size/mode/mtime/hash/link
+	Before  *fileSnapshotEntry `json:"before"`
+	After   *fileSnapshotEntry `json:"after"`
+}
+
+// FileSnapshotDiff 两次快照的对比结果
+type FileSnapshotDiff struct {
+	FromID   uint                 `json:"from_id"`
+	ToID     uint                 `json:"to_id"`
+	Path     string               `json:"path"`
+	Added    []fileSnapshotEntry  `json:"added"`
+	Removed  []fileSnapshotEntry  `json:"removed"`
+	Modified []FileSnapshotChange `json:"modified"`
+	// 任一快照只记录了部分条目时，新增/删除的结果可能不准确
+	Partial bool `json:"partial"`
+}
+
+// diffFileSnapshots 对比两次快照的条目。
+// 目录的修改时间会随子条目增删而变化，只比较类型和权限；
+// 两次快照都有哈希时比较内容，否则以大小和修改时间判断
+func diffFileSnapshots(before, after []fileSnapshotEntry) (added, removed []fileSnapshotEntry, modified []FileSnapshotChange) {
+	added, removed, modified = []fileSnapshotEntry{}, []fileSnapshotEntry{}, []FileSnapshotChange{}
+	old := make(map[string]fileSnapshotEntry, len(before))
+	for _, e := range before {
+		old[e.Path] = e
+	}
+
+	for _, cur := range after {
+		prev, ok := old[cur.Path]
+		if !ok {
+			added = append(added, cur)
+			continue
+		}
+		delete(old, cur.Path)
+
+		var changes []string
+		if prev.IsDir != cur.IsDir {
+			changes = append(changes, "type")
+		}
+		if prev.Mode != cur.Mode {
+			changes = append(changes, "mode")
+		}
+		if !cur.IsDir {
+			if prev.Size != cur.Size {
+				changes = append(changes, "size")
+			}
+			if prev.ModTime != cur.ModTime {
+				changes = append(changes, "mtime")
+			}
+			if prev.SHA256 != "" && cur.SHA256 != "" && prev.SHA256 != cur.SHA256 {
+				changes = append(changes, "hash")
+			}
+			if prev.Link != cur.Link {
+				changes = append(changes, "link")
+			}
+		}
+		if len(changes) > 0 {
+			p, c := prev, cur
+			modified = append(modified, FileSnapshotChange{Path: cur.Path, Changes: changes, Before: &p, After: &c})
+		}
+	}
+	for _, e := range old {
+		removed = append(removed, e)
+	}
+
+	sort.Slice(added, func(i, j int) bool { return added[i].Path < added[j].Path })
+	sort.Slice(removed, func(i, j int) bool { return removed[i].Path < removed[j].Path })
+	sort.Slice(modified, func(i, j int) bool { return modified[i].Path < modified[j].Path })
+	return added, removed, modified
+}
+
+// compareFileSnapshots 对比同一目录的两次快照
+func compareFileSnapshots(from, to *models.FileSnapshot) (*FileSnapshotDiff, error) {
+	if from.Path != to.Path {
+		return nil, errors.New("只能对比同一目录的快照")
+	}
+	var before, after []fileSnapshotEntry
+	if err := json.Unmarshal([]byte(from.Entries), &before); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(to.Entries), &after); err != nil {
+		return nil, err
+	}
+	diff := &FileSnapshotDiff{FromID: from.ID, ToID: to.ID, Path: to.Path, Partial: from.Truncated || to.Truncated}
+	diff.Added, diff.Removed, diff.Modified = diffFileSnapshots(before, after)
+	return diff, nil
+}
+
+// CreateFileSnapshot 让Agent记录目录的元数据快照并保存，返回与该目录上一次快照的对比结果
+func CreateFileSnapshot(c *gin.Context) {
+	serverID, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+	var req struct {
+		Path       string `json:"path" binding:"required"`
+		Hash       bool   `json:"hash"`
+		MaxEntries int    `json:"max_entries"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定目录路径"})
+		return
+	}
+	if _, err := models.GetServerByID(serverID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+
+	response, status, err := callAgent(serverID, "file_snapshot", &fileSnapshotChannels, map[string]interface{}{
+		"path":        req.Path,
+		"hash":        req.Hash,
+		"max_entries": req.MaxEntries,
+	}, fileSnapshotRequestTimeout)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	raw, err := json.Marshal(response["snapshot"])
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Agent返回的快照格式错误"})
+		return
+	}
+	var reported struct {
+		Path      string              `json:"path"`
+		Hashed    bool                `json:"hashed"`
+		Truncated bool                `json:"truncated"`
+		Entries   []fileSnapshotEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(raw, &reported); err != nil || reported.Path == "" {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Agent返回的快照格式错误"})
+		return
+	}
+	entries, _ := json.Marshal(reported.Entries)
+
+	snapshot := &models.FileSnapshot{
+		ServerID:   serverID,
+		Path:       reported.Path,
+		Hashed:     reported.Hashed,
+		EntryCount: len(reported.Entries),
+		Truncated:  reported.Truncated,
+		Entries:    string(entries),
+	}
+	if err := models.CreateFileSnapshot(snapshot, fileSnapshotKeep); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存快照失败: " + err.Error()})
+		return
+	}
+
+	result := gin.H{"snapshot": snapshot, "reason": response["reason"]}
+	if previous, err := models.GetLatestFileSnapshot(serverID, snapshot.Path, snapshot.ID); err == nil {
+		if diff, err := compareFileSnapshots(previous, snapshot); err == nil {
+			result["diff"] = diff
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// ListFileSnapshots 获取服务器的文件快照，可按目录筛选
+func ListFileSnapshots(c *gin.Context) {
+	serverID, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+	snapshots, err := models.ListFileSnapshots(serverID, c.Query("path"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取快照列表失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"snapshots": snapshots})
+}
+
+// DiffFileSnapshots 对比两次快照；未指定 from 时与 to 之前的最近一次快照对比
+func DiffFileSnapshots(c *gin.Context) {
+	serverID, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+	toID, err := strconv.ParseUint(c.Query("to"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定要对比的快照"})
+		return
+	}
+	to, err := models.GetFileSnapshot(serverID, uint(toID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "快照不存在"})
+		return
+	}
+
+	var from *models.FileSnapshot
+	if fromParam := c.Query("from"); fromParam != "" {
+		fromID, err := strconv.ParseUint(fromParam, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的快照ID"})
+			return
+		}
+		from, err = models.GetFileSnapshot(serverID, uint(fromID))
+	} else {
+		from, err = models.GetLatestFileSnapshot(serverID, to.Path, to.ID)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "没有可对比的快照"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取快照失败"})
+		return
+	}
+
+	diff, err := compareFileSnapshots(from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"diff": diff})
+}
+
+// DeleteFileSnapshot 删除文件快照
+func DeleteFileSnapshot(c *gin.Context) {
+	serverID, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+	snapshotID, err := strconv.ParseUint(c.Param("snapshot_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的快照ID"})
+		return
+	}
+	if err := models.DeleteFileSnapshot(serverID, uint(snapshotID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除快照失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// HandleFileSnapshotResponse 将Agent的文件快照响应传递给等待中的HTTP请求
+func HandleFileSnapshotResponse(requestID string, data map[string]interface{}) {
+	deliverAgentResponse(&fileSnapshotChannels, requestID, data)
+}
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffFileSnapshots(t *testing.T) {
+	before := []fileSnapshotEntry{
+		{Path: "conf.d", IsDir: true, Mode: "drwxr-xr-x", ModTime: 100},
+		{Path: "conf.d/a.conf", Size: 10, Mode: "-rw-r--r--", ModTime: 100, SHA256: "aaa"},
+		{Path: "conf.d/b.conf", Size: 20, Mode: "-rw-r--r--", ModTime: 100, SHA256: "bbb"},
+		{Path: "old.conf", Size: 5, Mode: "-rw-r--r--", ModTime: 100},
+	}
+	after := []fileSnapshotEntry{
+		// 目录的修改时间变化不视为修改
+		{Path: "conf.d", IsDir: true, Mode: "drwxr-xr-x", ModTime: 200},
+		{Path: "conf.d/a.conf", Size: 10, Mode: "-rw-r--r--", ModTime: 100, SHA256: "changed"},
+		{Path: "conf.d/b.conf", Size: 20, Mode: "-rwxr-xr-x", ModTime: 100, SHA256: "bbb"},
+		{Path: "new.conf", Size: 1, Mode: "-rw-r--r--", ModTime: 200},
+	}
+
+	added, removed, modified := diffFileSnapshots(before, after)
+	assert.Len(t, added, 1)
+	assert.Equal(t, "new.conf", added[0].Path)
+	assert.Len(t, removed, 1)
+	assert.Equal(t, "old.conf", removed[0].Path)
+	assert.Len(t, modified, 2)
+	assert.Equal(t, "conf.d/a.conf", modified[0].Path)
+	assert.Equal(t, []string{"hash"}, modified[0].Changes)
+	assert.Equal(t, []string{"mode"}, modified[1].Changes)
+}
@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// contentChecksum 返回内容的 SHA-256（十六进制小写），与 Agent 回读文件后返回的格式一致
+func contentChecksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// verifyWrittenChecksum 比对 Agent 回读文件得到的 SHA-256 与发送内容的哈希。
+// 此时文件已经写入，校验失败说明写入不完整或内容被改变，需要调用方重新写入
+func verifyWrittenChecksum(resp map[string]interface{}, content []byte) error {
+	data, _ := resp["data"].(map[string]interface{})
+	actual, _ := data["sha256"].(string)
+	if actual == "" {
+		if verifyErr, _ := data["verify_error"].(string); verifyErr != "" {
+			return fmt.Errorf("文件已写入，但回读校验失败: %s", verifyErr)
+		}
+		return fmt.Errorf("文件已写入，但 Agent 未返回文件哈希，请升级 Agent 后重试")
+	}
+	if expected := contentChecksum(content); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("文件已写入，但内容校验不一致（期望 %s，实际 %s）", expected, actual)
+	}
+	return nil
+}
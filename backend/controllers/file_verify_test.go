@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyWrittenChecksum(t *testing.T) {
+	content := []byte("server {\n    listen 80;\n}\n")
+	sum := contentChecksum(content)
+
+	// 哈希一致（大小写不敏感）
+	resp := map[string]interface{}{"data": map[string]interface{}{"sha256": strings.ToUpper(sum)}}
+	assert.NoError(t, verifyWrittenChecksum(resp, content))
+
+	// 写入内容被截断
+	resp = map[string]interface{}{"data": map[string]interface{}{"sha256": contentChecksum(content[:10])}}
+	assert.Error(t, verifyWrittenChecksum(resp, content))
+
+	// Agent 回读失败
+	resp = map[string]interface{}{"data": map[string]interface{}{"verify_error": "permission denied"}}
+	err := verifyWrittenChecksum(resp, content)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "permission denied")
+
+	// 旧版本 Agent 不返回哈希
+	resp = map[string]interface{}{"data": map[string]interface{}{"success": true}}
+	assert.Error(t, verifyWrittenChecksum(resp, content))
+}
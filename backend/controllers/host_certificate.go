@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+	"github.com/user/server-ops-backend/services"
+	"github.com/user/server-ops-backend/utils"
+)
+
+// 按到期天数筛选证书时允许的最大天数
+const maxCertificateExpiryDays = 3650
+
+// CertificateScanPayload Agent 一次证书扫描的结果，没有证书时列表为空
+type CertificateScanPayload struct {
+	Certificates []HostCertificatePayload `json:"certificates"`
+}
+
+// HostCertificatePayload Agent 扫描到的单张证书，与 nginx_command 的 certbot_list 返回的格式相同
+type HostCertificatePayload struct {
+	Domain       string    `json:"domain"`
+	Expiry       time.Time `json:"expiry"`
+	CertPath     string    `json:"cert_path"`
+	KeyPath      string    `json:"key_path"`
+	IssueDate    time.Time `json:"issue_date"`
+	IssuerName   string    `json:"issuer_name"`
+	SerialNumber string    `json:"serial_number"`
+	Fingerprint  string    `json:"fingerprint"`
+	Source       string    `json:"source"`
+}
+
+// recordHostCertificates 保存Agent上报的证书列表，并检查是否有即将到期的证书需要告警
+func recordHostCertificates(server *models.Server, payload []HostCertificatePayload, scannedAt time.Time) {
+	certs := make([]models.HostCertificate, 0, len(payload))
+	for _, cert := range payload {
+		if cert.Expiry.IsZero() {
+			continue
+		}
+		certs = append(certs, models.HostCertificate{
+			Domain:       truncateUTF8(cert.Domain, 255),
+			Source:       truncateUTF8(cert.Source, 32),
+			CertPath:     truncateUTF8(cert.CertPath, 512),
+			KeyPath:      truncateUTF8(cert.KeyPath, 512),
+			Issuer:       truncateUTF8(cert.IssuerName, 255),
+			SerialNumber: truncateUTF8(cert.SerialNumber, 128),
+			Fingerprint:  truncateUTF8(cert.Fingerprint, 128),
+			IssuedAt:     cert.IssueDate,
+			ExpiresAt:    cert.Expiry,
+			ScannedAt:    scannedAt,
+		})
+	}
+	if err := models.SaveHostCertificates(server.ID, certs); err != nil {
+		log.Printf("保存服务器 %d 的证书列表失败: %v", server.ID, err)
+		return
+	}
+	go services.GetAlertService().NotifyCertificateExpiry(*server)
+}
+
+// GetServerHostCertificates 获取Agent最近一次扫描到的服务器证书，按到期时间排序
+func GetServerHostCertificates(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	certs, err := models.GetHostCertificates(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取证书列表失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"certificates": certs})
+}
+
+// ListHostCertificates 获取所有服务器的证书，days 指定时只返回该天数内到期（含已过期）的证书
+func ListHostCertificates(c *gin.Context) {
+	var expiresBefore time.Time
+	if raw := c.Query("days"); raw != "" {
+		days, err := strconv.Atoi(raw)
+		if err != nil || days < 0 || days > maxCertificateExpiryDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days 必须是 0 到 %d 之间的整数", maxCertificateExpiryDays)})
+			return
+		}
+		expiresBefore = time.Now().AddDate(0, 0, days)
+	}
+
+	certs, err := models.ListHostCertificates(expiresBefore)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取证书列表失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"certificates": certs})
+}
+
+// RenewHostCertificates 通过 nginx_command 让Agent执行 certbot renew，续期服务器上由 certbot 管理的证书，
+// 完成后重新读取证书列表，续期成功的证书随之更新
+func RenewHostCertificates(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+	server, err := models.GetServerByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+	models.CheckServerStatus(server)
+	if !server.Online {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "服务器当前离线，无法连接"})
+		return
+	}
+
+	resp, err := utils.SendCommandToAgent(server.ID, server.SecretKey, map[string]interface{}{
+		"type":    "nginx_command",
+		"payload": map[string]interface{}{"action": "certbot_renew"},
+	})
+	if err != nil {
+		// 超时时 certbot 仍在Agent上执行，证书列表会在下一次扫描时更新
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("续期失败: %v", err)})
+		return
+	}
+	var result struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+		Output  string `json:"output"`
+	}
+	if err := json.Unmarshal([]byte(resp), &result); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("解析响应失败: %v", err)})
+		return
+	}
+	log.Printf("服务器 %s(%d) 执行 certbot renew: %s", server.Name, server.ID, result.Message)
+
+	body := gin.H{"success": result.Success, "message": result.Message, "output": result.Output}
+	if result.Success {
+		if certs, err := refreshHostCertificates(server); err != nil {
+			log.Printf("续期后刷新服务器 %d 的证书列表失败: %v", server.ID, err)
+		} else {
+			body["certificates"] = certs
+		}
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// refreshHostCertificates 通过 nginx_command 的 certbot_list 立即读取服务器证书并保存，不等待Agent的定期扫描
+func refreshHostCertificates(server *models.Server) ([]models.HostCertificate, error) {
+	resp, err := utils.SendCommandToAgent(server.ID, server.SecretKey, map[string]interface{}{
+		"type":    "nginx_command",
+		"payload": map[string]interface{}{"action": "certbot_list"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var payload []HostCertificatePayload
+	if err := json.Unmarshal([]byte(resp), &payload); err != nil {
+		return nil, fmt.Errorf("解析证书列表失败: %w", err)
+	}
+	recordHostCertificates(server, payload, time.Now())
+	return models.GetHostCertificates(server.ID)
+}
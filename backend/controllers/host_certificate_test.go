@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestPersistHostCertificates(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.ServerMonitor{}, &models.TrafficHourly{}, &models.HostCertificate{}, &models.AlertSetting{}))
+	server := models.Server{Name: "web-cert", SecretKey: "host-cert-test"}
+	assert.NoError(t, models.DB.Create(&server).Error)
+	defer models.DB.Unscoped().Delete(&server)
+	defer models.DeleteHostCertificates(server.ID)
+
+	now := time.Now()
+	_, err := persistMonitorPayload(&server, &MonitorPayload{Certificates: &CertificateScanPayload{
+		Certificates: []HostCertificatePayload{
+			{Domain: "example.com", Expiry: now.AddDate(0, 0, 60), Fingerprint: "aa", Source: "certbot"},
+			{Domain: "old.example.com", Expiry: now.AddDate(0, 0, 5), Fingerprint: "bb", Source: "system"},
+			{Domain: "无到期时间"},
+		},
+	}})
+	assert.NoError(t, err)
+
+	list := func(params gin.Params, rawQuery string, handler gin.HandlerFunc) (int, []models.HostCertificate) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = params
+		c.Request = httptest.NewRequest(http.MethodGet, "/?"+rawQuery, nil)
+		handler(c)
+		var resp struct {
+			Certificates []models.HostCertificate `json:"certificates"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Certificates
+	}
+
+	serverParams := gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(server.ID), 10)}}
+	code, certs := list(serverParams, "", GetServerHostCertificates)
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, certs, 2) {
+		// 按到期时间排序
+		assert.Equal(t, "old.example.com", certs[0].Domain)
+		assert.False(t, certs[0].ScannedAt.IsZero())
+	}
+
+	_, certs = list(nil, "days=30", ListHostCertificates)
+	if assert.Len(t, certs, 1) {
+		assert.Equal(t, server.ID, certs[0].ServerID)
+	}
+	code, _ = list(nil, "days=-1", ListHostCertificates)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// 同一张证书保留通知时间，续期后指纹变化的证书重新计算
+	certs, err = models.GetHostCertificates(server.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, models.MarkHostCertificatesNotified([]uint{certs[0].ID, certs[1].ID}, now))
+	recordHostCertificates(&server, []HostCertificatePayload{
+		{Domain: "example.com", Expiry: now.AddDate(0, 0, 59), Fingerprint: "aa", Source: "certbot"},
+		{Domain: "old.example.com", Expiry: now.AddDate(0, 0, 90), Fingerprint: "cc", Source: "system"},
+	}, now)
+	certs, err = models.GetHostCertificates(server.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, certs, 2) {
+		assert.Equal(t, "example.com", certs[0].Domain)
+		assert.NotNil(t, certs[0].NotifiedAt)
+		assert.Nil(t, certs[1].NotifiedAt)
+	}
+
+	// 没有证书的扫描结果清空列表
+	_, err = persistMonitorPayload(&server, &MonitorPayload{Certificates: &CertificateScanPayload{}})
+	assert.NoError(t, err)
+	_, certs = list(serverParams, "", GetServerHostCertificates)
+	assert.Empty(t, certs)
+}
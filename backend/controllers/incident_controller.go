@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+// maxIncidentCommentLen 事件备注的最大字符数
+const maxIncidentCommentLen = 2000
+
+// incidentNoteRequest 添加备注或解决事件的请求体
+type incidentNoteRequest struct {
+	Content string `json:"content"`
+}
+
+// parseIncident 解析路径中的事件ID并加载事件，失败时已写入响应
+func parseIncident(c *gin.Context) (*models.Incident, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的事件ID"})
+		return nil, false
+	}
+	var incident models.Incident
+	if err := models.GetIncidentByID(uint(id), &incident); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "事件不存在"})
+		return nil, false
+	}
+	return &incident, true
+}
+
+// bindIncidentNote 解析备注内容，required 为 false 时允许请求体为空
+func bindIncidentNote(c *gin.Context, required bool) (string, bool) {
+	var req incidentNoteRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+			return "", false
+		}
+	}
+	content := strings.TrimSpace(req.Content)
+	if required && content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "备注内容不能为空"})
+		return "", false
+	}
+	if utf8.RuneCountInString(content) > maxIncidentCommentLen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "备注内容不能超过 2000 个字符"})
+		return "", false
+	}
+	return content, true
+}
+
+// incidentDetail 返回事件及其时间线
+func incidentDetail(c *gin.Context, status int, message string, incident *models.Incident) {
+	events, err := models.GetIncidentEvents(incident.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取事件时间线失败"})
+		return
+	}
+	resp := gin.H{"incident": incident, "events": events}
+	if message != "" {
+		resp["message"] = message
+	}
+	c.JSON(status, resp)
+}
+
+// GetIncidents 分页获取事件，可按状态（open、acknowledged、resolved）筛选
+func GetIncidents(c *gin.Context) {
+	status := c.DefaultQuery("status", "")
+	switch status {
+	case "", models.IncidentOpen, models.IncidentAcknowledged, models.IncidentResolved:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的事件状态"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	incidents, total, err := models.GetIncidents(status, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取事件失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"incidents": incidents,
+		"total":     total,
+		"page":      page,
+		"limit":     limit,
+	})
+}
+
+// GetIncident 获取事件详情和时间线
+func GetIncident(c *gin.Context) {
+	incident, ok := parseIncident(c)
+	if !ok {
+		return
+	}
+	incidentDetail(c, http.StatusOK, "", incident)
+}
+
+// AcknowledgeIncident 确认事件，停止对应预警的升级通知；预警恢复后仍会发送解决通知
+func AcknowledgeIncident(c *gin.Context) {
+	incident, ok := parseIncident(c)
+	if !ok {
+		return
+	}
+	if incident.Status != models.IncidentOpen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "事件已经确认或已经解决"})
+		return
+	}
+
+	acked, err := models.AcknowledgeAlertRecord(incident.AlertRecordID, c.GetString("username"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "确认事件失败"})
+		return
+	}
+	if !acked {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "事件已经确认或已经解决"})
+		return
+	}
+
+	models.GetIncidentByID(incident.ID, incident)
+	incidentDetail(c, http.StatusOK, "事件已确认，停止升级通知", incident)
+}
+
+// AddIncidentComment 为事件添加备注，已解决的事件同样可以补充备注
+func AddIncidentComment(c *gin.Context) {
+	incident, ok := parseIncident(c)
+	if !ok {
+		return
+	}
+	content, ok := bindIncidentNote(c, true)
+	if !ok {
+		return
+	}
+
+	event, err := models.AddIncidentComment(incident.ID, c.GetString("username"), content)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加备注失败"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "备注已添加", "event": event})
+}
+
+// ResolveIncident 手动解决事件，可附带说明；对应的预警同时标记为已解决，不发送解决通知。
+// 异常仍然存在时，预警在恢复后重新触发才会打开新的事件
+func ResolveIncident(c *gin.Context) {
+	incident, ok := parseIncident(c)
+	if !ok {
+		return
+	}
+	if incident.Status == models.IncidentResolved {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "事件已经解决"})
+		return
+	}
+	note, ok := bindIncidentNote(c, false)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if err := models.ResolveAlertIncident(incident.AlertRecordID, c.GetString("username"), note, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "解决事件失败"})
+		return
+	}
+	var record models.AlertRecord
+	if err := models.GetAlertRecordByID(incident.AlertRecordID, &record); err == nil && !record.Resolved {
+		record.Resolved = true
+		record.ResolvedAt = now
+		if err := models.UpdateAlertRecord(&record); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新预警记录失败"})
+			return
+		}
+	}
+
+	models.GetIncidentByID(incident.ID, incident)
+	incidentDetail(c, http.StatusOK, "事件已解决", incident)
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestIncidentLifecycle(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.AlertRecord{}, &models.Incident{}, &models.IncidentEvent{}))
+	db.Exec("DELETE FROM alert_records")
+	db.Exec("DELETE FROM incidents")
+	db.Exec("DELETE FROM incident_events")
+
+	call := func(handler gin.HandlerFunc, method string, id uint, body string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, "/", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		if id != 0 {
+			c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(id), 10)}}
+		}
+		c.Set("username", "alice")
+		handler(c)
+		var resp map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+	incidentOf := func(recordID uint) models.Incident {
+		var incident models.Incident
+		assert.NoError(t, db.Where("alert_record_id = ?", recordID).First(&incident).Error)
+		return incident
+	}
+
+	// 未解决的预警打开事件，即时事件不打开
+	cpu := models.AlertRecord{ServerID: 1, ServerName: "web", AlertType: "cpu", Value: 95, Threshold: 80}
+	oom := models.AlertRecord{ServerID: 1, ServerName: "web", AlertType: "oom", Resolved: true, ResolvedAt: time.Now()}
+	assert.NoError(t, models.CreateAlertRecord(&cpu))
+	assert.NoError(t, models.CreateAlertRecord(&oom))
+	var count int64
+	db.Model(&models.Incident{}).Where("alert_record_id = ?", oom.ID).Count(&count)
+	assert.Zero(t, count)
+
+	incident := incidentOf(cpu.ID)
+	assert.Equal(t, models.IncidentOpen, incident.Status)
+
+	code, resp := call(GetIncidents, http.MethodGet, 0, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(1), resp["total"])
+
+	// 确认后预警停止升级，重复确认被拒绝
+	code, _ = call(AcknowledgeIncident, http.MethodPut, incident.ID, "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = call(AcknowledgeIncident, http.MethodPut, incident.ID, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	var record models.AlertRecord
+	assert.NoError(t, models.GetAlertRecordByID(cpu.ID, &record))
+	assert.True(t, record.Acknowledged)
+	assert.Equal(t, "alice", record.AcknowledgedBy)
+
+	code, _ = call(AddIncidentComment, http.MethodPost, incident.ID, `{"content":"  "}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = call(AddIncidentComment, http.MethodPost, incident.ID, `{"content":"正在扩容"}`)
+	assert.Equal(t, http.StatusCreated, code)
+
+	// 手动解决同时解决预警
+	code, resp = call(ResolveIncident, http.MethodPut, incident.ID, `{"content":"已扩容"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, models.IncidentResolved, resp["incident"].(map[string]interface{})["status"])
+	events := resp["events"].([]interface{})
+	var types []string
+	for _, e := range events {
+		types = append(types, e.(map[string]interface{})["type"].(string))
+	}
+	assert.Equal(t, []string{models.IncidentEventOpened, models.IncidentEventAcknowledged, models.IncidentEventComment, models.IncidentEventResolved}, types)
+	assert.Equal(t, "已扩容", events[3].(map[string]interface{})["content"])
+	assert.NoError(t, models.GetAlertRecordByID(cpu.ID, &record))
+	assert.True(t, record.Resolved)
+	code, _ = call(ResolveIncident, http.MethodPut, incident.ID, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// 预警恢复后事件自动解决
+	mem := models.AlertRecord{ServerID: 2, ServerName: "db", AlertType: "memory", Value: 92, Threshold: 90}
+	assert.NoError(t, models.CreateAlertRecord(&mem))
+	mem.Resolved = true
+	mem.ResolvedAt = time.Now()
+	assert.NoError(t, models.UpdateAlertRecord(&mem))
+	incident = incidentOf(mem.ID)
+	assert.Equal(t, models.IncidentResolved, incident.Status)
+	assert.Empty(t, incident.ResolvedBy)
+
+	code, resp = call(GetIncidents, http.MethodGet, 0, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(2), resp["total"])
+
+	deleted, err := models.DeleteIncidentsBefore(time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+	db.Model(&models.IncidentEvent{}).Count(&count)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,298 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+const (
+	// 日志搜索默认和最多返回的条数
+	defaultLogSearchLimit = 200
+	maxLogSearchLimit     = 1000
+	// 正则过滤时每次从数据库读取的行数和单次搜索最多扫描的行数
+	logSearchChunk   = 2000
+	maxLogSearchScan = 100000
+	// 单次上报保存的最大行数和单行最大长度，防止异常 Agent 撑大数据库
+	maxLogLinesPerReport = 5000
+	maxLogLineLength     = 8192
+)
+
+// LogLinePayload Agent 随监控数据上报的一行日志
+type LogLinePayload struct {
+	Path      string `json:"path"`
+	Timestamp int64  `json:"timestamp"` // Agent 时钟的 Unix 毫秒
+	Line      string `json:"line"`
+}
+
+// splitLogPaths 将服务器保存的采集路径拆分为列表
+func splitLogPaths(raw string) []string {
+	paths := []string{}
+	for _, p := range strings.Split(raw, "\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// validateLogPath 检查采集路径：必须是绝对路径，通配符只能出现在文件名中，
+// 且不能指向（或通过通配符匹配到）禁止访问的敏感文件
+func validateLogPath(p string) bool {
+	if len(p) > models.MaxLogPathLength || !isValidFilePath(p) || path.Clean(p) != p {
+		return false
+	}
+	dir, file := path.Split(p)
+	if file == "" || strings.ContainsAny(dir, `*?[\`) {
+		return false
+	}
+	if _, err := path.Match(file, ""); err != nil {
+		return false
+	}
+	for _, banned := range bannedFilePaths {
+		if matched, _ := path.Match(p, banned); matched {
+			return false
+		}
+	}
+	return true
+}
+
+// recordLogLines 保存 Agent 采集上报的日志，时间按时钟偏差换算为面板时间
+func recordLogLines(server *models.Server, payload []LogLinePayload, dropped int, now time.Time) {
+	if dropped > 0 {
+		log.Printf("服务器 %d 的日志采集超出上限，Agent 丢弃了 %d 行", server.ID, dropped)
+	}
+	if len(payload) > maxLogLinesPerReport {
+		log.Printf("服务器 %d 上报的日志行数过多，只保存前 %d 行", server.ID, maxLogLinesPerReport)
+		payload = payload[:maxLogLinesPerReport]
+	}
+
+	entries := make([]models.LogEntry, 0, len(payload))
+	for _, l := range payload {
+		if l.Path == "" || len(l.Path) > models.MaxLogPathLength {
+			continue
+		}
+		// 按毫秒保存，翻页游标以毫秒传递
+		at := now.Truncate(time.Millisecond)
+		if l.Timestamp > 0 {
+			at = time.UnixMilli(l.Timestamp - server.ClockOffsetMs)
+			if at.After(now) {
+				at = now.Truncate(time.Millisecond)
+			}
+		}
+		entries = append(entries, models.LogEntry{
+			ServerID:  server.ID,
+			Path:      l.Path,
+			Timestamp: at,
+			Line:      strings.ToValidUTF8(truncateUTF8(l.Line, maxLogLineLength), "\uFFFD"),
+		})
+	}
+	if err := models.CreateLogEntries(entries); err != nil {
+		log.Printf("保存服务器 %d 的采集日志失败: %v", server.ID, err)
+	}
+}
+
+// GetServerLogPaths 获取服务器的日志采集路径
+func GetServerLogPaths(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+	server, err := models.GetServerByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"paths": splitLogPaths(server.LogPaths)})
+}
+
+// UpdateServerLogPaths 设置服务器的日志采集路径，Agent 下一次拉取设置时生效
+func UpdateServerLogPaths(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	var req struct {
+		Paths []string `json:"paths"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	paths := make([]string, 0, len(req.Paths))
+	seen := make(map[string]bool, len(req.Paths))
+	for _, p := range req.Paths {
+		p = strings.TrimSpace(p)
+		if p == "" || seen[p] {
+			continue
+		}
+		if !validateLogPath(p) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的采集路径或禁止访问该文件: " + p})
+			return
+		}
+		seen[p] = true
+		paths = append(paths, p)
+	}
+	if len(paths) > models.MaxLogPaths {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "采集路径不能超过" + strconv.Itoa(models.MaxLogPaths) + "个"})
+		return
+	}
+
+	server, err := models.GetServerByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+	if err := models.DB.Model(&models.Server{}).Where("id = ?", server.ID).Update("log_paths", strings.Join(paths, "\n")).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新采集路径失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"paths": paths})
+}
+
+// SearchLogs 搜索采集到的日志，按时间倒序返回
+// 查询参数：
+//   - server_id: 只搜索该服务器，默认所有服务器
+//   - path: 只搜索该文件
+//   - q: 行内包含的文本，不区分大小写
+//   - regex: 行需要匹配的正则表达式（RE2 语法）
+//   - from / to: 时间范围，默认最近 range（默认 1h），最长 31 天
+//   - limit: 最多返回条数，默认 200，最大 1000
+//   - before_id / before_ts: 上一页最后一条的 ID 和时间（Unix 毫秒），用于翻页
+func SearchLogs(c *gin.Context) {
+	var filter models.LogEntryFilter
+	var err error
+	if idStr := c.Query("server_id"); idStr != "" {
+		id, err := strconv.ParseUint(idStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+			return
+		}
+		filter.ServerID = uint(id)
+	}
+	filter.Path = strings.TrimSpace(c.Query("path"))
+	filter.Keyword = c.Query("q")
+
+	var re *regexp.Regexp
+	if expr := c.Query("regex"); expr != "" {
+		if re, err = regexp.Compile(expr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的正则表达式: " + err.Error()})
+			return
+		}
+	}
+
+	filter.End = time.Now()
+	if toStr := c.Query("to"); toStr != "" {
+		if filter.End, err = parseHistoryTime(toStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的结束时间格式"})
+			return
+		}
+	}
+	if fromStr := c.Query("from"); fromStr != "" {
+		if filter.Start, err = parseHistoryTime(fromStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的开始时间格式"})
+			return
+		}
+	} else {
+		window, err := time.ParseDuration(c.DefaultQuery("range", "1h"))
+		if err != nil || window <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的时间范围"})
+			return
+		}
+		filter.Start = filter.End.Add(-window)
+	}
+	if !filter.Start.Before(filter.End) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "开始时间必须早于结束时间"})
+		return
+	}
+	if filter.End.Sub(filter.Start) > maxHistoryRange {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "查询时间范围不能超过31天"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLogSearchLimit)))
+	if err != nil || limit <= 0 {
+		limit = defaultLogSearchLimit
+	}
+	limit = min(limit, maxLogSearchLimit)
+
+	var cursor models.LogEntryCursor
+	if beforeID, err := strconv.ParseUint(c.Query("before_id"), 10, 64); err == nil {
+		beforeTs, err := strconv.ParseInt(c.Query("before_ts"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "翻页需要同时提供 before_id 和 before_ts"})
+			return
+		}
+		cursor = models.LogEntryCursor{ID: uint(beforeID), Timestamp: time.UnixMilli(beforeTs)}
+	}
+
+	// 没有正则时数据库直接返回结果；有正则时分批读取并逐行匹配，扫描行数有上限
+	entries := make([]models.LogEntry, 0, limit)
+	scanned := 0
+	truncated, scanLimited := false, false
+	for {
+		chunk := limit + 1
+		if re != nil {
+			chunk = logSearchChunk
+		}
+		rows, err := models.FindLogEntries(filter, cursor, chunk)
+		if err != nil {
+			log.Printf("[ERROR] 搜索日志失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索日志失败"})
+			return
+		}
+		for _, row := range rows {
+			scanned++
+			cursor = models.LogEntryCursor{Timestamp: row.Timestamp, ID: row.ID}
+			if re != nil && !re.MatchString(row.Line) {
+				continue
+			}
+			if len(entries) == limit {
+				truncated = true
+				break
+			}
+			entries = append(entries, row)
+		}
+		if truncated || len(rows) < chunk {
+			break
+		}
+		if scanned >= maxLogSearchScan {
+			truncated, scanLimited = true, true
+			break
+		}
+	}
+
+	// 还有更多结果时返回下一页的游标：返回条数已满时从最后一条继续，扫描达到上限时从扫描位置继续
+	body := gin.H{"entries": entries, "truncated": truncated, "scanned": scanned}
+	if truncated {
+		next := cursor
+		if !scanLimited {
+			last := entries[len(entries)-1]
+			next = models.LogEntryCursor{Timestamp: last.Timestamp, ID: last.ID}
+		}
+		body["next"] = gin.H{"before_id": next.ID, "before_ts": next.Timestamp.UnixMilli()}
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// truncateUTF8 按字节截断字符串，不截断多字节字符
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
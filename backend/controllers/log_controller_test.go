@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestValidateLogPath(t *testing.T) {
+	for _, p := range []string{"/var/log/nginx/access.log", "/var/log/app/*.log", "/opt/app/logs/[ab]*.txt"} {
+		assert.True(t, validateLogPath(p), p)
+	}
+	for _, p := range []string{
+		"", "var/log/syslog", "/var/log/../../etc/shadow", "/var/log/", "/var/log//syslog",
+		"/etc/shadow", "/proc/1/environ", "/var/*/syslog", "/etc/sh*", "/etc/*", "/var/log/[",
+	} {
+		assert.False(t, validateLogPath(p), p)
+	}
+}
+
+func TestSearchLogs(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.LogEntry{}))
+	defer db.Where("1 = 1").Delete(&models.LogEntry{})
+
+	web := models.Server{Name: "logs-web", ClockOffsetMs: 1000}
+	db1 := models.Server{Name: "logs-db"}
+	for _, server := range []*models.Server{&web, &db1} {
+		assert.NoError(t, db.Create(server).Error)
+		defer db.Unscoped().Delete(server)
+	}
+
+	now := time.Now()
+	recordLogLines(&web, []LogLinePayload{
+		{Path: "/var/log/nginx/error.log", Timestamp: now.Add(-3 * time.Minute).UnixMilli(), Line: "upstream timed out 100%"},
+		{Path: "/var/log/nginx/access.log", Timestamp: now.Add(-2 * time.Minute).UnixMilli(), Line: "GET /index.html 200"},
+		{Path: "/var/log/nginx/access.log", Timestamp: now.Add(-time.Minute).UnixMilli(), Line: "GET /api/login 500"},
+		{Path: "/var/log/nginx/access.log", Timestamp: now.Add(-2 * time.Hour).UnixMilli(), Line: "GET /old 200"},
+		{Path: "", Line: "忽略没有路径的行"},
+	}, 0, now)
+	recordLogLines(&db1, []LogLinePayload{
+		{Path: "/var/log/mysql/error.log", Timestamp: now.UnixMilli(), Line: strings.Repeat("慢", maxLogLineLength)},
+	}, 3, now)
+
+	search := func(rawQuery string) (int, []models.LogEntry, map[string]json.RawMessage) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/?"+rawQuery, nil)
+		SearchLogs(c)
+		var resp map[string]json.RawMessage
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		var entries []models.LogEntry
+		_ = json.Unmarshal(resp["entries"], &entries)
+		return w.Code, entries, resp
+	}
+
+	code, entries, _ := search("")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, entries, 4) {
+		// 按时间倒序，时间按时钟偏差换算
+		assert.Equal(t, db1.ID, entries[0].ServerID)
+		assert.LessOrEqual(t, len(entries[0].Line), maxLogLineLength)
+		assert.Equal(t, "GET /api/login 500", entries[1].Line)
+		assert.WithinDuration(t, now.Add(-time.Minute-time.Second), entries[1].Timestamp, time.Millisecond)
+	}
+
+	_, entries, _ = search("server_id=" + strconv.FormatUint(uint64(web.ID), 10) + "&path=/var/log/nginx/access.log")
+	assert.Len(t, entries, 2)
+
+	// 关键字不区分大小写，通配符按字面匹配
+	_, entries, _ = search("q=UPSTREAM")
+	assert.Len(t, entries, 1)
+	_, entries, _ = search("q=100%25")
+	assert.Len(t, entries, 1)
+	_, entries, _ = search("q=0_")
+	assert.Empty(t, entries)
+
+	_, entries, _ = search("regex=" + "GET%20/(api|old)/")
+	assert.Len(t, entries, 1)
+	_, entries, _ = search("regex=GET&range=3h")
+	assert.Len(t, entries, 3)
+
+	// 翻页
+	_, entries, resp := search("limit=2")
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "true", string(resp["truncated"]))
+	var next struct {
+		BeforeID uint  `json:"before_id"`
+		BeforeTs int64 `json:"before_ts"`
+	}
+	assert.NoError(t, json.Unmarshal(resp["next"], &next))
+	_, entries, resp = search("limit=2&before_id=" + strconv.FormatUint(uint64(next.BeforeID), 10) + "&before_ts=" + strconv.FormatInt(next.BeforeTs, 10))
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "upstream timed out 100%", entries[1].Line)
+	}
+	assert.Equal(t, "false", string(resp["truncated"]))
+
+	code, _, _ = search("regex=(")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = search("range=800h")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
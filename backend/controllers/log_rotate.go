@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 日志轮转请求的响应通道
+var logRotateChannels sync.Map
+
+// logRotateRequest 清空/轮转日志文件的请求参数
+type logRotateRequest struct {
+	Path   string `json:"path"`   // 日志文件路径，truncate/copytruncate 模式必填
+	Mode   string `json:"mode"`   // truncate / copytruncate / logrotate，默认 copytruncate
+	Config string `json:"config"` // logrotate 模式使用的配置文件
+}
+
+// RotateLogFile 清空或轮转服务器上的日志文件，返回处理前后的文件大小。
+// 只能操作Agent配置的 log_rotate_roots 下的文件，仅管理员可用
+func RotateLogFile(c *gin.Context) {
+	var req logRotateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+
+	req.Mode = strings.ToLower(strings.TrimSpace(req.Mode))
+	if req.Mode == "" {
+		req.Mode = "copytruncate"
+	}
+	switch req.Mode {
+	case "truncate", "copytruncate":
+		if req.Path == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请指定日志文件路径"})
+			return
+		}
+	case "logrotate":
+		if req.Config == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "logrotate 模式需要指定配置文件"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的模式: " + req.Mode})
+		return
+	}
+
+	// 复制大文件或执行 logrotate 可能较慢
+	requestAgentWithTimeout(c, "log_rotate", &logRotateChannels, map[string]interface{}{
+		"path":   req.Path,
+		"mode":   req.Mode,
+		"config": req.Config,
+	}, TimeoutLongOperation)
+}
+
+// HandleLogRotateResponse 将Agent的日志轮转响应传递给等待中的HTTP请求
+func HandleLogRotateResponse(requestID string, data map[string]interface{}) {
+	deliverAgentResponse(&logRotateChannels, requestID, data)
+}
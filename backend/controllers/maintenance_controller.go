@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+// maintenanceWindowRequest 创建/更新维护窗口的请求参数
+type maintenanceWindowRequest struct {
+	Name            string    `json:"name"`
+	ServerID        uint      `json:"server_id"`
+	StartAt         time.Time `json:"start_at"`
+	EndAt           time.Time `json:"end_at"`
+	Cron            string    `json:"cron"`
+	DurationMinutes int       `json:"duration_minutes"`
+	Enabled         *bool     `json:"enabled"`
+}
+
+// maintenanceWindowView 维护窗口及其当前是否生效
+type maintenanceWindowView struct {
+	models.MaintenanceWindow
+	Active      bool       `json:"active"`
+	ActiveUntil *time.Time `json:"active_until,omitempty"` // 当前这次窗口的结束时间
+}
+
+func newMaintenanceWindowView(window models.MaintenanceWindow, now time.Time) maintenanceWindowView {
+	view := maintenanceWindowView{MaintenanceWindow: window}
+	if window.Enabled {
+		if active, until := window.ActiveAt(now); active {
+			view.Active = true
+			view.ActiveUntil = &until
+		}
+	}
+	return view
+}
+
+// GetMaintenanceWindows 获取所有维护窗口
+func GetMaintenanceWindows(c *gin.Context) {
+	windows, err := models.GetAllMaintenanceWindows()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取维护窗口失败"})
+		return
+	}
+
+	now := time.Now()
+	views := make([]maintenanceWindowView, 0, len(windows))
+	for _, window := range windows {
+		views = append(views, newMaintenanceWindowView(window, now))
+	}
+	c.JSON(http.StatusOK, gin.H{"windows": views})
+}
+
+// CreateMaintenanceWindow 创建维护窗口
+func CreateMaintenanceWindow(c *gin.Context) {
+	var req maintenanceWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	window := models.MaintenanceWindow{Enabled: true, CreatedBy: c.GetString("username")}
+	if err := applyMaintenanceWindow(&window, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.CreateMaintenanceWindow(&window); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建维护窗口失败"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "维护窗口创建成功",
+		"window":  newMaintenanceWindowView(window, time.Now()),
+	})
+}
+
+// UpdateMaintenanceWindow 更新维护窗口，提前结束维护可直接停用或修改结束时间
+func UpdateMaintenanceWindow(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的维护窗口ID"})
+		return
+	}
+
+	var window models.MaintenanceWindow
+	if err := models.GetMaintenanceWindowByID(uint(id), &window); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "维护窗口不存在"})
+		return
+	}
+
+	var req maintenanceWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	if err := applyMaintenanceWindow(&window, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.UpdateMaintenanceWindow(&window); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新维护窗口失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "维护窗口更新成功",
+		"window":  newMaintenanceWindowView(window, time.Now()),
+	})
+}
+
+// DeleteMaintenanceWindow 删除维护窗口
+func DeleteMaintenanceWindow(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的维护窗口ID"})
+		return
+	}
+
+	if err := models.DeleteMaintenanceWindow(uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除维护窗口失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "维护窗口删除成功"})
+}
+
+// applyMaintenanceWindow 校验请求并写入窗口：设置了 cron 的为周期窗口，否则为一次性窗口
+func applyMaintenanceWindow(window *models.MaintenanceWindow, req maintenanceWindowRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errors.New("维护窗口名称不能为空")
+	}
+	if utf8.RuneCountInString(req.Name) > 50 {
+		return errors.New("维护窗口名称不能超过 50 个字符")
+	}
+	if req.ServerID != 0 {
+		if _, err := models.GetServerByID(req.ServerID); err != nil {
+			return fmt.Errorf("服务器 %d 不存在", req.ServerID)
+		}
+	}
+
+	req.Cron = strings.Join(strings.Fields(req.Cron), " ")
+	if req.Cron != "" {
+		if _, err := models.ParseCron(req.Cron); err != nil {
+			return err
+		}
+		maxMinutes := int(models.MaxMaintenanceDuration / time.Minute)
+		if req.DurationMinutes <= 0 || req.DurationMinutes > maxMinutes {
+			return fmt.Errorf("周期窗口的持续时间应为 1-%d 分钟", maxMinutes)
+		}
+		req.StartAt, req.EndAt = time.Time{}, time.Time{}
+	} else {
+		if req.StartAt.IsZero() || req.EndAt.IsZero() {
+			return errors.New("一次性窗口需要设置开始和结束时间")
+		}
+		if !req.EndAt.After(req.StartAt) {
+			return errors.New("结束时间必须晚于开始时间")
+		}
+		req.DurationMinutes = 0
+	}
+
+	window.Name = req.Name
+	window.ServerID = req.ServerID
+	window.StartAt = req.StartAt
+	window.EndAt = req.EndAt
+	window.Cron = req.Cron
+	window.DurationMinutes = req.DurationMinutes
+	if req.Enabled != nil {
+		window.Enabled = *req.Enabled
+	}
+	return nil
+}
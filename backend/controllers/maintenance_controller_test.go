@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestMaintenanceWindowLifecycle(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.MaintenanceWindow{}, &models.ServerMonitor{}, &models.TrafficHourly{}))
+	db.Exec("DELETE FROM maintenance_windows")
+
+	web := models.Server{Name: "maint-web", SecretKey: "maint-web"}
+	db1 := models.Server{Name: "maint-db", SecretKey: "maint-db"}
+	assert.NoError(t, db.Create(&web).Error)
+	assert.NoError(t, db.Create(&db1).Error)
+	defer db.Unscoped().Delete(&web)
+	defer db.Unscoped().Delete(&db1)
+	defer db.Where("server_id = ?", web.ID).Delete(&models.ServerMonitor{})
+
+	call := func(handler gin.HandlerFunc, method, body string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, "/", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("username", "alice")
+		handler(c)
+		var resp map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	now := time.Now()
+	for _, body := range []string{
+		`{"name":"","cron":"0 3 * * 0","duration_minutes":60}`,
+		`{"name":"bad cron","cron":"0 3 * *","duration_minutes":60}`,
+		`{"name":"bad cron","cron":"0 25 * * *","duration_minutes":60}`,
+		`{"name":"no duration","cron":"0 3 * * 0"}`,
+		`{"name":"no times"}`,
+		fmt.Sprintf(`{"name":"reversed","start_at":%q,"end_at":%q}`, now.Format(time.RFC3339), now.Add(-time.Hour).Format(time.RFC3339)),
+		`{"name":"missing server","server_id":99999,"cron":"0 3 * * 0","duration_minutes":60}`,
+	} {
+		code, _ := call(CreateMaintenanceWindow, http.MethodPost, body)
+		assert.Equal(t, http.StatusBadRequest, code, body)
+	}
+
+	// 一次性窗口只影响指定服务器
+	body := fmt.Sprintf(`{"name":"升级内核","server_id":%d,"start_at":%q,"end_at":%q}`,
+		web.ID, now.Add(-time.Minute).Format(time.RFC3339), now.Add(time.Hour).Format(time.RFC3339))
+	code, resp := call(CreateMaintenanceWindow, http.MethodPost, body)
+	assert.Equal(t, http.StatusCreated, code)
+	window := resp["window"].(map[string]interface{})
+	assert.Equal(t, true, window["active"])
+	assert.Equal(t, "alice", window["created_by"])
+
+	assert.True(t, models.InMaintenance(web.ID, now))
+	assert.False(t, models.InMaintenance(db1.ID, now))
+	assert.False(t, models.InMaintenance(0, now), "服务器窗口不影响可用性检查")
+	assert.False(t, models.InMaintenance(web.ID, now.Add(2*time.Hour)))
+
+	// 维护期间的监控数据带标记，离线的服务器在公开页面显示为维护中
+	record, err := persistMonitorPayload(&web, &MonitorPayload{CPUUsage: 5})
+	assert.NoError(t, err)
+	assert.True(t, record.Maintenance)
+	record, err = persistMonitorPayload(&db1, &MonitorPayload{CPUUsage: 5})
+	assert.NoError(t, err)
+	assert.False(t, record.Maintenance)
+	db.Where("server_id = ?", db1.ID).Delete(&models.ServerMonitor{})
+
+	offline := web
+	offline.Online = false
+	assert.Equal(t, models.ServerStatusMaintenance, publicServerStatus(&offline))
+	offline = db1
+	offline.Online = false
+	assert.Equal(t, "offline", publicServerStatus(&offline))
+
+	code, resp = call(GetMaintenanceWindows, http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, resp["windows"], 1)
+
+	// 删除后立即生效
+	id := uint(window["ID"].(float64))
+	assert.NoError(t, models.DeleteMaintenanceWindow(id))
+	assert.False(t, models.InMaintenance(web.ID, now))
+}
+
+func TestRecurringMaintenanceWindow(t *testing.T) {
+	// 每周日 03:00 开始，持续 2 小时
+	window := models.MaintenanceWindow{Cron: "0 3 * * 0", DurationMinutes: 120}
+	sunday := time.Date(2024, 6, 2, 0, 0, 0, 0, time.Local)
+
+	active, until := window.ActiveAt(sunday.Add(3*time.Hour + 30*time.Minute))
+	assert.True(t, active)
+	assert.Equal(t, sunday.Add(5*time.Hour), until)
+	active, _ = window.ActiveAt(sunday.Add(3 * time.Hour))
+	assert.True(t, active)
+	active, _ = window.ActiveAt(sunday.Add(5 * time.Hour))
+	assert.False(t, active)
+	active, _ = window.ActiveAt(sunday.Add(2*time.Hour + 59*time.Minute))
+	assert.False(t, active)
+	active, _ = window.ActiveAt(sunday.Add(27*time.Hour + 30*time.Minute))
+	assert.False(t, active, "周一不在窗口内")
+
+	// 跨越午夜的窗口、列表和步长
+	window = models.MaintenanceWindow{Cron: "30 23 1,15 * *", DurationMinutes: 60}
+	active, _ = window.ActiveAt(time.Date(2024, 6, 16, 0, 15, 0, 0, time.Local))
+	assert.True(t, active)
+	active, _ = window.ActiveAt(time.Date(2024, 6, 2, 0, 45, 0, 0, time.Local))
+	assert.False(t, active)
+
+	window = models.MaintenanceWindow{Cron: "*/15 9-17 * * 1-5", DurationMinutes: 5}
+	active, _ = window.ActiveAt(time.Date(2024, 6, 3, 9, 47, 0, 0, time.Local))
+	assert.True(t, active)
+	active, _ = window.ActiveAt(time.Date(2024, 6, 3, 9, 51, 0, 0, time.Local))
+	assert.False(t, active)
+	active, _ = window.ActiveAt(time.Date(2024, 6, 8, 9, 47, 0, 0, time.Local))
+	assert.False(t, active, "周六不在窗口内")
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+// 互测矩阵的默认和最大统计窗口
+const (
+	meshDefaultRange = 10 * time.Minute
+	meshMaxRange     = 24 * time.Hour
+)
+
+// agentMeshPeer 通过 Agent 设置接口下发的互测节点
+type agentMeshPeer struct {
+	ServerID uint   `json:"server_id"`
+	Target   string `json:"target"`
+}
+
+// agentMesh 通过 Agent 设置接口下发的互测配置，Peers 为空时 Agent 停止互测
+type agentMesh struct {
+	Interval int             `json:"interval"`
+	Peers    []agentMeshPeer `json:"peers"`
+}
+
+// MeshResultPayload Agent 随监控数据上报的互测结果
+type MeshResultPayload struct {
+	PeerID    uint    `json:"peer_id"`
+	Timestamp int64   `json:"timestamp"` // Agent 时钟的 Unix 毫秒
+	Success   bool    `json:"success"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// meshTarget 返回其他节点 ping 该服务器使用的地址，优先使用公网 IP
+func meshTarget(server *models.Server) string {
+	if ip := strings.TrimSpace(server.PublicIP); ip != "" {
+		return ip
+	}
+	return strings.TrimSpace(server.IP)
+}
+
+// agentMeshConfig 返回下发给服务器的互测节点，即除自身外所有已知地址的服务器。
+// 未开启互测或查询失败时返回空列表
+func agentMeshConfig(serverID uint, interval int) agentMesh {
+	mesh := agentMesh{Interval: interval, Peers: []agentMeshPeer{}}
+	if interval <= 0 {
+		return mesh
+	}
+	servers, err := models.GetAllServers(0)
+	if err != nil {
+		log.Printf("获取服务器 %d 的互测节点失败: %v", serverID, err)
+		return mesh
+	}
+	for i := range servers {
+		target := meshTarget(&servers[i])
+		if servers[i].ID == serverID || target == "" {
+			continue
+		}
+		mesh.Peers = append(mesh.Peers, agentMeshPeer{ServerID: servers[i].ID, Target: target})
+		if len(mesh.Peers) >= models.MaxMeshPeers {
+			break
+		}
+	}
+	return mesh
+}
+
+// recordMeshResults 保存 Agent 上报的互测结果，时间按时钟偏差换算为面板时间
+func recordMeshResults(server *models.Server, payload []MeshResultPayload, now time.Time) {
+	results := make([]models.MeshLatency, 0, len(payload))
+	for _, r := range payload {
+		if r.PeerID == 0 || r.PeerID == server.ID {
+			continue
+		}
+		at := now
+		if r.Timestamp > 0 {
+			at = time.UnixMilli(r.Timestamp - server.ClockOffsetMs)
+			if at.After(now) {
+				at = now
+			}
+		}
+		result := models.MeshLatency{ServerID: server.ID, PeerID: r.PeerID, Timestamp: at, Success: r.Success}
+		if r.Success {
+			result.LatencyMs = r.LatencyMs
+		}
+		results = append(results, result)
+	}
+	if err := models.CreateMeshLatencies(results); err != nil {
+		log.Printf("保存服务器 %d 的互测结果失败: %v", server.ID, err)
+	}
+}
+
+// GetMeshLatency 获取节点互测矩阵，用于绘制节点间连通性热力图
+// 查询参数：
+//   - range: 统计窗口（如 1h），默认 10m，最长 24h
+func GetMeshLatency(c *gin.Context) {
+	window := meshDefaultRange
+	if rangeStr := c.Query("range"); rangeStr != "" {
+		var err error
+		if window, err = time.ParseDuration(rangeStr); err != nil || window <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的时间范围"})
+			return
+		}
+	}
+	if window > meshMaxRange {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "统计时间范围不能超过24小时"})
+		return
+	}
+
+	settings, err := models.GetSettings()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取系统设置失败"})
+		return
+	}
+	servers, err := models.GetAllServers(0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取服务器列表失败"})
+		return
+	}
+	cells, err := models.GetMeshLatencyMatrix(time.Now().Add(-window))
+	if err != nil {
+		log.Printf("[ERROR] 获取节点互测矩阵失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取节点互测矩阵失败"})
+		return
+	}
+
+	// 已删除的服务器的结果可能尚未清理，只返回现有服务器之间的数据
+	nodes := make([]gin.H, 0, len(servers))
+	exists := make(map[uint]bool, len(servers))
+	for _, server := range servers {
+		exists[server.ID] = true
+		nodes = append(nodes, gin.H{"id": server.ID, "name": server.Name, "online": server.Online})
+	}
+	matrix := make([]models.MeshLatencyCell, 0, len(cells))
+	for _, cell := range cells {
+		if exists[cell.ServerID] && exists[cell.PeerID] {
+			matrix = append(matrix, cell)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"interval": settings.MeshInterval,
+		"servers":  nodes,
+		"cells":    matrix,
+	})
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestMeshLatency(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.MeshLatency{}))
+	defer db.Where("1 = 1").Delete(&models.MeshLatency{})
+
+	tokyo := models.Server{Name: "mesh-tokyo", IP: "10.0.0.1", PublicIP: "203.0.113.1"}
+	frankfurt := models.Server{Name: "mesh-frankfurt", IP: "10.0.0.2"}
+	unknown := models.Server{Name: "mesh-unknown"}
+	for _, server := range []*models.Server{&tokyo, &frankfurt, &unknown} {
+		assert.NoError(t, db.Create(server).Error)
+		defer db.Unscoped().Delete(server)
+	}
+
+	// 未开启时不下发节点，开启后下发除自身外有地址的服务器，优先使用公网 IP
+	assert.Empty(t, agentMeshConfig(tokyo.ID, 0).Peers)
+	peers := agentMeshConfig(frankfurt.ID, 30).Peers
+	assert.Contains(t, peers, agentMeshPeer{ServerID: tokyo.ID, Target: "203.0.113.1"})
+	for _, peer := range peers {
+		assert.NotEqual(t, frankfurt.ID, peer.ServerID)
+		assert.NotEqual(t, unknown.ID, peer.ServerID)
+	}
+
+	now := time.Now()
+	recordMeshResults(&tokyo, []MeshResultPayload{
+		{PeerID: frankfurt.ID, Timestamp: now.UnixMilli(), Success: true, LatencyMs: 20},
+		{PeerID: frankfurt.ID, Timestamp: now.UnixMilli(), Success: true, LatencyMs: 30},
+		{PeerID: frankfurt.ID, Timestamp: now.UnixMilli(), Success: false},
+		{PeerID: frankfurt.ID, Timestamp: now.UnixMilli(), Success: false, LatencyMs: 999},
+		{PeerID: tokyo.ID, Success: true, LatencyMs: 1}, // 忽略对自身的结果
+	}, now)
+	recordMeshResults(&frankfurt, []MeshResultPayload{
+		{PeerID: tokyo.ID, Timestamp: now.Add(-time.Hour).UnixMilli(), Success: true, LatencyMs: 25},
+	}, now)
+
+	get := func(rawQuery string) (int, map[string]json.RawMessage) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/?"+rawQuery, nil)
+		GetMeshLatency(c)
+		var resp map[string]json.RawMessage
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, resp := get("")
+	assert.Equal(t, http.StatusOK, code)
+	var cells []models.MeshLatencyCell
+	assert.NoError(t, json.Unmarshal(resp["cells"], &cells))
+	if assert.Len(t, cells, 1) {
+		cell := cells[0]
+		assert.Equal(t, tokyo.ID, cell.ServerID)
+		assert.Equal(t, frankfurt.ID, cell.PeerID)
+		assert.Equal(t, int64(4), cell.Samples)
+		assert.Equal(t, int64(2), cell.Received)
+		assert.InDelta(t, 50, cell.Loss, 0.01)
+		assert.InDelta(t, 25, cell.AvgMs, 0.01)
+		assert.InDelta(t, 20, cell.MinMs, 0.01)
+		assert.InDelta(t, 30, cell.MaxMs, 0.01)
+	}
+
+	// 扩大窗口后包含一小时前的结果
+	_, resp = get("range=2h")
+	assert.NoError(t, json.Unmarshal(resp["cells"], &cells))
+	assert.Len(t, cells, 2)
+
+	code, _ = get("range=48h")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = get("range=abc")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestMonitorBatchWriter(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.ServerMonitor{}, &models.TrafficHourly{}))
+	server := models.Server{Name: "batch-host", SecretKey: "monitor-batch-test"}
+	assert.NoError(t, db.Create(&server).Error)
+	defer db.Unscoped().Delete(&server)
+	defer db.Where("server_id = ?", server.ID).Delete(&models.ServerMonitor{})
+	defer db.Where("server_id = ?", server.ID).Delete(&models.TrafficHourly{})
+
+	count := func() int64 {
+		var n int64
+		db.Model(&models.ServerMonitor{}).Where("server_id = ?", server.ID).Count(&n)
+		return n
+	}
+	reload := func() models.Server {
+		var s models.Server
+		assert.NoError(t, db.First(&s, server.ID).Error)
+		return s
+	}
+	sample := func(cpu float64, updates map[string]interface{}) {
+		assert.NoError(t, models.SaveMonitorSample(&models.ServerMonitor{ServerID: server.ID, CPUUsage: cpu, Timestamp: time.Now()}, updates))
+	}
+
+	// 刷新间隔很长，只有达到批量大小或停止时才写入
+	models.StartMonitorBatchWriter(3, time.Hour)
+	defer models.StopMonitorBatchWriter()
+
+	// 同一服务器的状态更新只保留最后一次，流量增量按小时合并
+	sample(10, map[string]interface{}{"status": "online", "last_heartbeat": time.Unix(100, 0)})
+	assert.NoError(t, models.SaveServerMonitorState(server.ID, map[string]interface{}{"status": "offline"}))
+	assert.NoError(t, models.AddTrafficSample(server.ID, time.Now(), 100, 10))
+	assert.NoError(t, models.AddTrafficSample(server.ID, time.Now(), 50, 5))
+	assert.Equal(t, int64(0), count(), "未达到批量大小时不写入")
+	sample(20, nil)
+	sample(30, map[string]interface{}{"status": "online"})
+	assert.Eventually(t, func() bool { return count() == 3 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "online", reload().Status)
+	var traffic []models.TrafficHourly
+	assert.NoError(t, db.Where("server_id = ?", server.ID).Find(&traffic).Error)
+	if assert.Len(t, traffic, 1) {
+		assert.Equal(t, uint64(150), traffic[0].BytesIn)
+		assert.Equal(t, uint64(15), traffic[0].BytesOut)
+	}
+
+	// 写入失败时数据放回缓冲区，数据库恢复后不丢失
+	assert.NoError(t, db.Migrator().DropTable(&models.ServerMonitor{}))
+	sample(40, map[string]interface{}{"status": "offline"})
+	assert.NoError(t, models.AddTrafficSample(server.ID, time.Now(), 1, 1))
+	sample(50, nil)
+	sample(60, nil)
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, "online", reload().Status, "失败的事务整体回滚")
+	assert.NoError(t, db.AutoMigrate(&models.ServerMonitor{}))
+	assert.NoError(t, models.SaveServerMonitorState(server.ID, map[string]interface{}{"last_heartbeat": time.Unix(200, 0)}))
+	sample(70, nil)
+
+	// 停止时写入剩余的数据，包括之前失败的批次
+	models.StopMonitorBatchWriter()
+	assert.Equal(t, int64(4), count())
+	updated := reload()
+	assert.Equal(t, "offline", updated.Status, "失败批次的状态与之后的更新合并")
+	assert.Equal(t, int64(200), updated.LastHeartbeat.Unix())
+	assert.NoError(t, db.Where("server_id = ?", server.ID).Find(&traffic).Error)
+	if assert.Len(t, traffic, 1) {
+		assert.Equal(t, uint64(151), traffic[0].BytesIn)
+	}
+
+	// 停止后恢复逐条写入
+	sample(80, nil)
+	assert.Equal(t, int64(5), count())
+}
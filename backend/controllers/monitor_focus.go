@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// 聚焦查看：运维人员打开单台服务器的详情页时，前端通过监控订阅连接发送 monitor_focus，
+// 请求临时提高该服务器的上报频率。后端汇总该服务器所有聚焦的查看者，取最短的间隔下发给Agent，
+// 最后一个查看者离开时通知Agent恢复配置的上报间隔。
+// Agent 端按租约生效，后端重启或消息丢失时也会在租约到期后自动恢复。
+const (
+	minMonitorFocusInterval     = time.Second     // 允许的最短上报间隔
+	defaultMonitorFocusInterval = 2 * time.Second // 未指定间隔时使用
+	monitorFocusLease           = 3 * time.Minute // Agent 端的租约时长
+	monitorFocusRenewInterval   = time.Minute     // 后端续约周期，需小于租约时长
+)
+
+// monitorFocusRequest 查看者发送的聚焦请求
+type monitorFocusRequest struct {
+	Enabled    bool  `json:"enabled"`
+	IntervalMs int64 `json:"interval_ms"`
+}
+
+// monitorFocusRegistry 记录每台服务器的聚焦查看者及其请求的间隔
+type monitorFocusRegistry struct {
+	mu      sync.Mutex
+	viewers map[uint]map[*SafeConn]time.Duration
+	send    func(serverID uint, interval time.Duration)
+}
+
+func newMonitorFocusRegistry(send func(serverID uint, interval time.Duration)) *monitorFocusRegistry {
+	return &monitorFocusRegistry{
+		viewers: make(map[uint]map[*SafeConn]time.Duration),
+		send:    send,
+	}
+}
+
+var (
+	monitorFocus          = newMonitorFocusRegistry(sendMonitorRate)
+	monitorFocusRenewOnce sync.Once
+)
+
+// clampMonitorFocusInterval 将请求的间隔限制在允许范围内
+func clampMonitorFocusInterval(intervalMs int64) time.Duration {
+	if intervalMs <= 0 {
+		return defaultMonitorFocusInterval
+	}
+	interval := time.Duration(intervalMs) * time.Millisecond
+	if interval < minMonitorFocusInterval {
+		return minMonitorFocusInterval
+	}
+	return interval
+}
+
+// set 登记或更新查看者的聚焦间隔，返回该服务器当前生效的间隔
+func (r *monitorFocusRegistry) set(serverID uint, conn *SafeConn, interval time.Duration) time.Duration {
+	r.mu.Lock()
+	before := r.effectiveLocked(serverID)
+	if r.viewers[serverID] == nil {
+		r.viewers[serverID] = make(map[*SafeConn]time.Duration)
+	}
+	r.viewers[serverID][conn] = interval
+	after := r.effectiveLocked(serverID)
+	r.mu.Unlock()
+
+	if after != before {
+		r.send(serverID, after)
+	}
+	return after
+}
+
+// clear 移除查看者，最后一个查看者离开时通知Agent恢复正常间隔
+func (r *monitorFocusRegistry) clear(serverID uint, conn *SafeConn) time.Duration {
+	r.mu.Lock()
+	if _, ok := r.viewers[serverID][conn]; !ok {
+		after := r.effectiveLocked(serverID)
+		r.mu.Unlock()
+		return after
+	}
+	before := r.effectiveLocked(serverID)
+	delete(r.viewers[serverID], conn)
+	if len(r.viewers[serverID]) == 0 {
+		delete(r.viewers, serverID)
+	}
+	after := r.effectiveLocked(serverID)
+	r.mu.Unlock()
+
+	if after != before {
+		r.send(serverID, after)
+	}
+	return after
+}
+
+// effectiveLocked 返回所有查看者中最短的间隔，无人聚焦时返回 0，调用方需持有 r.mu
+func (r *monitorFocusRegistry) effectiveLocked(serverID uint) time.Duration {
+	var effective time.Duration
+	for _, interval := range r.viewers[serverID] {
+		if effective == 0 || interval < effective {
+			effective = interval
+		}
+	}
+	return effective
+}
+
+// resend 重新下发指定服务器当前生效的间隔，用于Agent重连后恢复聚焦状态
+func (r *monitorFocusRegistry) resend(serverID uint) {
+	r.mu.Lock()
+	effective := r.effectiveLocked(serverID)
+	r.mu.Unlock()
+	if effective > 0 {
+		r.send(serverID, effective)
+	}
+}
+
+// renewAll 为所有仍有查看者的服务器续约
+func (r *monitorFocusRegistry) renewAll() {
+	r.mu.Lock()
+	active := make(map[uint]time.Duration, len(r.viewers))
+	for serverID := range r.viewers {
+		active[serverID] = r.effectiveLocked(serverID)
+	}
+	r.mu.Unlock()
+
+	for serverID, interval := range active {
+		r.send(serverID, interval)
+	}
+}
+
+// handleMonitorFocus 处理监控订阅连接的聚焦请求，并回复当前生效的间隔
+func handleMonitorFocus(conn *SafeConn, serverID uint, payload json.RawMessage) {
+	var req monitorFocusRequest
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &req); err != nil {
+			sendErrorMessage(conn, "聚焦请求格式错误")
+			return
+		}
+	}
+
+	monitorFocusRenewOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(monitorFocusRenewInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				monitorFocus.renewAll()
+			}
+		}()
+	})
+
+	var effective time.Duration
+	if req.Enabled {
+		effective = monitorFocus.set(serverID, conn, clampMonitorFocusInterval(req.IntervalMs))
+	} else {
+		effective = monitorFocus.clear(serverID, conn)
+	}
+
+	conn.WriteJSON(map[string]interface{}{
+		"type": "monitor_focus",
+		"data": map[string]interface{}{
+			"server_id":   serverID,
+			"enabled":     effective > 0,
+			"interval_ms": effective.Milliseconds(),
+		},
+	})
+}
+
+// clearMonitorFocus 监控订阅连接断开时移除其聚焦状态
+func clearMonitorFocus(serverID uint, conn *SafeConn) {
+	monitorFocus.clear(serverID, conn)
+}
+
+// sendMonitorRate 通知Agent调整上报间隔，interval 为 0 表示恢复配置的间隔
+func sendMonitorRate(serverID uint, interval time.Duration) {
+	agentConnVal, ok := ActiveAgentConnections.Load(serverID)
+	if !ok {
+		return
+	}
+	agentConn, ok := agentConnVal.(*SafeConn)
+	if !ok {
+		return
+	}
+
+	payload := map[string]interface{}{
+		"interval_ms": interval.Milliseconds(),
+		"lease_ms":    int64(0),
+	}
+	if interval > 0 {
+		payload["lease_ms"] = monitorFocusLease.Milliseconds()
+	}
+	if err := agentConn.WriteJSON(map[string]interface{}{
+		"type":    "monitor_rate",
+		"payload": payload,
+	}); err != nil {
+		log.Printf("向服务器 %d 下发监控上报间隔失败: %v", serverID, err)
+	}
+}
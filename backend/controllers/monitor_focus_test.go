@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMonitorFocusRegistry(t *testing.T) {
+	var sent []time.Duration
+	r := newMonitorFocusRegistry(func(serverID uint, interval time.Duration) {
+		sent = append(sent, interval)
+	})
+	a, b := &SafeConn{}, &SafeConn{}
+
+	// 取所有查看者中最短的间隔，间隔未变化时不重复下发
+	assert.Equal(t, 5*time.Second, r.set(1, a, 5*time.Second))
+	assert.Equal(t, 2*time.Second, r.set(1, b, 2*time.Second))
+	assert.Equal(t, 2*time.Second, r.set(1, a, 3*time.Second))
+	assert.Equal(t, []time.Duration{5 * time.Second, 2 * time.Second}, sent)
+
+	// 最短间隔的查看者离开后回退到其余查看者的间隔，最后一个离开时恢复
+	assert.Equal(t, 3*time.Second, r.clear(1, b))
+	assert.Equal(t, time.Duration(0), r.clear(1, a))
+	assert.Equal(t, time.Duration(0), r.clear(1, a))
+	assert.Equal(t, []time.Duration{5 * time.Second, 2 * time.Second, 3 * time.Second, 0}, sent)
+}
+
+func TestClampMonitorFocusInterval(t *testing.T) {
+	assert.Equal(t, defaultMonitorFocusInterval, clampMonitorFocusInterval(0))
+	assert.Equal(t, minMonitorFocusInterval, clampMonitorFocusInterval(100))
+	assert.Equal(t, 5*time.Second, clampMonitorFocusInterval(5000))
+}
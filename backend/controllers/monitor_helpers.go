@@ -1,10 +1,16 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/user/server-ops-backend/models"
+	"github.com/user/server-ops-backend/services"
 )
 
 // MonitorPayload 表示从Agent或HTTP上报的监控数据
@@ -30,18 +36,182 @@ type MonitorPayload struct {
 	Processes       int     `json:"processes"`
 	TCPConnections  int     `json:"tcp_connections"`
 	UDPConnections  int     `json:"udp_connections"`
+	Zombies         int     `json:"zombies"`                // 僵尸进程数
+	OOMKills        int     `json:"oom_kills"`              // 上报周期内新增的 OOM kill 次数
+	AgentErrors     int     `json:"agent_errors"`           // Agent 最近 5 分钟自身的错误数（发送失败、采集失败、重连、panic）
+	Live            bool    `json:"live,omitempty"`         // 聚焦查看期间的高频实时样本，只推送不入库
+	ReportInterval  uint64  `json:"report_interval"`        // Agent 当前生效的上报间隔(ms)，旧版 Agent 不上报
+	Idle            bool    `json:"idle,omitempty"`         // Agent 是否处于空闲降频状态
+	CollectedAt     int64   `json:"collected_at,omitempty"` // 采集时间(Agent 时钟的 Unix 毫秒)，只有断线期间缓冲的样本携带
+	Replay          bool    `json:"replay,omitempty"`       // Agent 重新连接后补发的缓冲样本
+
+	Custom    []CustomMetricPayload `json:"custom,omitempty"`     // Agent 自定义插件采集的指标
+	OOMEvents []OOMEventPayload     `json:"oom_events,omitempty"` // 新增 OOM kill 对应的内核日志事件
+	SMART     []DiskHealthPayload   `json:"smart,omitempty"`      // 磁盘 SMART 健康信息，Agent 每完成一次检查携带一次
+	TopCPU    []TopProcessPayload   `json:"top_cpu,omitempty"`    // CPU 占用最高的进程，面板设置了进程排行数时上报
+	TopMemory []TopProcessPayload   `json:"top_memory,omitempty"` // 内存占用最高的进程
+
+	Temperatures []TemperaturePayload `json:"temperatures,omitempty"` // hwmon 温度传感器（CPU、NVMe、主板等）
+	Fans         []FanPayload         `json:"fans,omitempty"`         // hwmon 风扇转速
+
+	Checks []UptimeResultPayload `json:"checks,omitempty"` // 分配给该 Agent 的可用性检查自上次上报以来的结果
+	Mesh   []MeshResultPayload   `json:"mesh,omitempty"`   // 对其他节点的 ping 结果
+
+	Logs        []LogLinePayload `json:"logs,omitempty"`         // 日志采集自上次上报以来读取到的行
+	LogsDropped int              `json:"logs_dropped,omitempty"` // Agent 超出暂存上限丢弃的行数
+
+	Certificates *CertificateScanPayload `json:"certificates,omitempty"` // 本机证书列表，Agent 每完成一次扫描携带一次
+
+	Mounts     []DiskMountPayload `json:"mounts,omitempty"`     // 各挂载点的空间和 inode 使用情况
+	Interfaces []InterfacePayload `json:"interfaces,omitempty"` // 各网卡的流量、错误和丢包
+	TCPStates  map[string]int     `json:"tcp_states,omitempty"` // 各状态的 TCP 连接数，如 ESTABLISHED、TIME_WAIT
+
+	Nginx *models.NginxStatus `json:"nginx,omitempty"` // Nginx stub_status 指标和上游可用性，Agent 配置了 nginx_status_url 或 nginx_upstreams 时上报
+
+	Databases []models.DatabaseStatus `json:"databases,omitempty"` // MySQL、PostgreSQL、Redis 的健康指标，Agent 配置了 database_checks 时上报
+
+	Scripts []CheckScriptResultPayload `json:"scripts,omitempty"` // 面板下发的检查脚本的最新结果，每次上报都携带
+}
+
+// InterfacePayload Agent 上报的单个网卡在两次采集之间的流量、错误和丢包
+type InterfacePayload struct {
+	Name      string  `json:"name"`
+	Virtual   bool    `json:"virtual,omitempty"` // 容器网桥等虚拟网卡
+	RxRate    float64 `json:"rx_rate"`           // bytes/s
+	TxRate    float64 `json:"tx_rate"`
+	RxBytes   uint64  `json:"rx_bytes"` // 周期内的增量
+	TxBytes   uint64  `json:"tx_bytes"`
+	RxErrors  uint64  `json:"rx_errors"`
+	TxErrors  uint64  `json:"tx_errors"`
+	RxDropped uint64  `json:"rx_dropped"`
+	TxDropped uint64  `json:"tx_dropped"`
 }
 
-// persistMonitorPayload 保存监控数据并更新服务器统计信息
+// DiskMountPayload Agent 上报的单个挂载点空间(bytes)和 inode 使用情况
+type DiskMountPayload struct {
+	Mount       string `json:"mount"`
+	FSType      string `json:"fstype"`
+	Total       uint64 `json:"total"`
+	Used        uint64 `json:"used"`
+	Free        uint64 `json:"free"`
+	InodesTotal uint64 `json:"inodes_total"`
+	InodesUsed  uint64 `json:"inodes_used"`
+	InodesFree  uint64 `json:"inodes_free"`
+}
+
+// TemperaturePayload Agent 从 hwmon 读取的温度传感器读数
+type TemperaturePayload struct {
+	Chip     string  `json:"chip"`
+	Label    string  `json:"label"`
+	Celsius  float64 `json:"celsius"`
+	High     float64 `json:"high,omitempty"`
+	Critical float64 `json:"critical,omitempty"`
+}
+
+// FanPayload Agent 从 hwmon 读取的风扇转速
+type FanPayload struct {
+	Chip  string `json:"chip"`
+	Label string `json:"label"`
+	RPM   int    `json:"rpm"`
+}
+
+// sensorRecord 监控记录中保存的传感器读数
+type sensorRecord struct {
+	Temperatures []TemperaturePayload `json:"temperatures"`
+	Fans         []FanPayload         `json:"fans"`
+}
+
+// TopProcessPayload 监控数据中 CPU 或内存占用排名靠前的进程
+type TopProcessPayload struct {
+	PID        int32   `json:"pid"`
+	Name       string  `json:"name"`
+	CPUPercent float64 `json:"cpu_percent"`
+	MemoryRSS  uint64  `json:"memory_rss"`
+}
+
+// topProcessRecord 监控记录中保存的进程排行
+type topProcessRecord struct {
+	CPU    []TopProcessPayload `json:"cpu"`
+	Memory []TopProcessPayload `json:"memory"`
+}
+
+// DiskHealthPayload Agent 通过 smartctl 读取的单块物理磁盘健康信息
+type DiskHealthPayload struct {
+	Device               string   `json:"device"`
+	Model                string   `json:"model"`
+	Serial               string   `json:"serial"`
+	Type                 string   `json:"type"`
+	CapacityBytes        uint64   `json:"capacity_bytes"`
+	Passed               bool     `json:"passed"`
+	Temperature          int      `json:"temperature"`
+	ReallocatedSectors   int64    `json:"reallocated_sectors"`
+	PendingSectors       int64    `json:"pending_sectors"`
+	UncorrectableSectors int64    `json:"uncorrectable_sectors"`
+	MediaErrors          int64    `json:"media_errors"`
+	WearLevel            int      `json:"wear_level"`
+	PowerOnHours         int64    `json:"power_on_hours"`
+	FailingAttributes    []string `json:"failing_attributes,omitempty"`
+	PredictedFailure     bool     `json:"predicted_failure"`
+}
+
+// OOMEventPayload Agent 从内核日志中解析出的 OOM kill 事件
+type OOMEventPayload struct {
+	Time    time.Time `json:"time"`
+	PID     int       `json:"pid"`
+	Process string    `json:"process"`
+	Message string    `json:"message"`
+}
+
+// CustomMetricPayload Agent 自定义插件上报的单个指标
+type CustomMetricPayload struct {
+	Name   string  `json:"name"`
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit,omitempty"`
+	Plugin string  `json:"plugin"`
+}
+
+// errDuplicateSample 补发的样本已经入库过（Agent 写出后未来得及从缓冲区移除就重启了）
+var errDuplicateSample = errors.New("duplicate replayed monitor sample")
+
+// replayedSamples 每台服务器已入库的最新补发样本采集时间(Agent 时钟的 Unix 毫秒)，用于丢弃重复补发的样本。
+// Agent 保证同一缓冲区内的采集时间严格递增
+var replayedSamples sync.Map
+
+// replayedSampleTime 校验补发样本是否重复，并换算为面板时钟的采集时间
+func replayedSampleTime(server *models.Server, payload *MonitorPayload, now time.Time) (time.Time, error) {
+	if last, ok := replayedSamples.Load(server.ID); ok && payload.CollectedAt <= last.(int64) {
+		return time.Time{}, errDuplicateSample
+	}
+	replayedSamples.Store(server.ID, payload.CollectedAt)
+
+	at := time.UnixMilli(payload.CollectedAt - server.ClockOffsetMs)
+	if at.After(now) {
+		at = now
+	}
+	return at, nil
+}
+
+// persistMonitorPayload 保存监控数据并更新服务器统计信息。
+// Agent 补发的缓冲样本按采集时间入库，重复的样本返回 errDuplicateSample
 func persistMonitorPayload(server *models.Server, payload *MonitorPayload) (*models.ServerMonitor, error) {
 	if server == nil || payload == nil {
 		return nil, fmt.Errorf("invalid monitor payload")
 	}
 
 	now := time.Now()
+	sampledAt := now
+	replay := payload.Replay && payload.CollectedAt > 0 && !payload.Live
+	if replay {
+		at, err := replayedSampleTime(server, payload, now)
+		if err != nil {
+			return nil, err
+		}
+		sampledAt = at
+	}
+
 	record := models.ServerMonitor{
 		ServerID:       server.ID,
-		Timestamp:      now,
+		Timestamp:      sampledAt,
 		CPUUsage:       payload.CPUUsage,
 		MemoryUsed:     payload.MemoryUsed,
 		MemoryTotal:    payload.MemoryTotal,
@@ -60,10 +230,89 @@ func persistMonitorPayload(server *models.Server, payload *MonitorPayload) (*mod
 		Processes:      payload.Processes,
 		TCPConnections: payload.TCPConnections,
 		UDPConnections: payload.UDPConnections,
+		Zombies:        payload.Zombies,
+		OOMKills:       payload.OOMKills,
+		AgentErrors:    payload.AgentErrors,
+		Maintenance:    models.InMaintenance(server.ID, sampledAt),
 	}
 
-	if err := models.AddMonitorData(&record); err != nil {
-		return nil, err
+	if len(payload.Custom) > 0 {
+		if customJSON, err := json.Marshal(payload.Custom); err != nil {
+			log.Printf("序列化自定义指标失败: %v", err)
+		} else {
+			record.CustomMetrics = string(customJSON)
+		}
+	}
+	if len(payload.TopCPU) > 0 || len(payload.TopMemory) > 0 {
+		top := topProcessRecord{
+			CPU:    truncateTopProcesses(payload.TopCPU),
+			Memory: truncateTopProcesses(payload.TopMemory),
+		}
+		if topJSON, err := json.Marshal(top); err != nil {
+			log.Printf("序列化进程排行失败: %v", err)
+		} else {
+			record.TopProcesses = string(topJSON)
+		}
+	}
+
+	if len(payload.Temperatures) > 0 || len(payload.Fans) > 0 {
+		for _, t := range payload.Temperatures {
+			record.MaxTemperature = max(record.MaxTemperature, t.Celsius)
+		}
+		sensors := sensorRecord{
+			Temperatures: payload.Temperatures[:min(len(payload.Temperatures), models.MaxSensorReadings)],
+			Fans:         payload.Fans[:min(len(payload.Fans), models.MaxSensorReadings)],
+		}
+		if sensorJSON, err := json.Marshal(sensors); err != nil {
+			log.Printf("序列化传感器读数失败: %v", err)
+		} else {
+			record.Sensors = string(sensorJSON)
+		}
+	}
+
+	if len(payload.Interfaces) > 0 {
+		interfaces := payload.Interfaces[:min(len(payload.Interfaces), models.MaxInterfaces)]
+		if interfaceJSON, err := json.Marshal(interfaces); err != nil {
+			log.Printf("序列化网卡统计失败: %v", err)
+		} else {
+			record.Interfaces = string(interfaceJSON)
+		}
+	}
+
+	if len(payload.TCPStates) > 0 {
+		if stateJSON, err := json.Marshal(payload.TCPStates); err != nil {
+			log.Printf("序列化 TCP 连接状态失败: %v", err)
+		} else {
+			record.TCPStates = string(stateJSON)
+		}
+	}
+
+	if payload.Nginx != nil {
+		nginx := *payload.Nginx
+		nginx.Error = truncateUTF8(nginx.Error, 255)
+		nginx.Upstreams = nginx.Upstreams[:min(len(nginx.Upstreams), models.MaxNginxUpstreams)]
+		if nginxJSON, err := json.Marshal(nginx); err != nil {
+			log.Printf("序列化 Nginx 指标失败: %v", err)
+		} else {
+			record.NginxStatus = string(nginxJSON)
+		}
+	}
+
+	if len(payload.Databases) > 0 {
+		databases := append([]models.DatabaseStatus(nil), payload.Databases[:min(len(payload.Databases), models.MaxDatabaseChecks)]...)
+		for i := range databases {
+			databases[i].Name = truncateUTF8(databases[i].Name, 64)
+			databases[i].Error = truncateUTF8(databases[i].Error, 255)
+			databases[i].ReplicationError = truncateUTF8(databases[i].ReplicationError, 255)
+		}
+		if databasesJSON, err := json.Marshal(databases); err != nil {
+			log.Printf("序列化数据库指标失败: %v", err)
+		} else {
+			record.Databases = string(databasesJSON)
+		}
+	}
+	if len(payload.Scripts) > 0 {
+		record.Scripts = recordCheckScripts(server, payload.Scripts, now)
 	}
 
 	// 更新服务器累计流量和网络质量
@@ -76,10 +325,10 @@ func persistMonitorPayload(server *models.Server, payload *MonitorPayload) (*mod
 	// - Agent 在每次上报时计算自上次上报以来的流量增量（覆盖整个上报周期）
 	// - Delta 基于系统网卡计数器的差值，确保不会遗漏任何流量
 	// - SampleDuration 记录实际上报周期时长，用于计算平均速率
+	// - 设置了每月重置日时，进入新的计费周期后先清零再累加
+	trafficReset := applyTrafficReset(server, now)
 	server.NetworkInTotal += payload.NetworkInDelta
 	server.NetworkOutTotal += payload.NetworkOutDelta
-	server.Latency = payload.Latency
-	server.PacketLoss = payload.PacketLoss
 	server.Status = "online"
 	server.Online = true
 	server.LastHeartbeat = now
@@ -87,20 +336,164 @@ func persistMonitorPayload(server *models.Server, payload *MonitorPayload) (*mod
 	updates := map[string]interface{}{
 		"network_in_total":  server.NetworkInTotal,
 		"network_out_total": server.NetworkOutTotal,
-		"latency":           server.Latency,
-		"packet_loss":       server.PacketLoss,
 		"last_heartbeat":    server.LastHeartbeat,
 		"online":            server.Online,
 		"status":            server.Status,
 	}
+	// 补发的样本是断线期间的旧数据，只累加流量，不覆盖服务器当前的网络质量和上报间隔
+	if !replay {
+		server.Latency = payload.Latency
+		server.PacketLoss = payload.PacketLoss
+		updates["latency"] = server.Latency
+		updates["packet_loss"] = server.PacketLoss
+	}
+	if payload.ReportInterval > 0 && !replay {
+		server.ReportInterval = int64(payload.ReportInterval)
+		server.MonitorIdle = payload.Idle
+		updates["report_interval"] = server.ReportInterval
+		updates["monitor_idle"] = server.MonitorIdle
+	}
+	if trafficReset {
+		updates["traffic_reset_at"] = now
+	}
+
+	// 每小时流量汇总：实时样本的增量同样计入累计流量，这里一并统计。
+	// 旧版 Agent 不上报增量时按速率和采样时长估算
+	bytesIn, bytesOut := payload.NetworkInDelta, payload.NetworkOutDelta
+	if bytesIn == 0 && bytesOut == 0 && payload.SampleDuration > 0 {
+		seconds := float64(payload.SampleDuration) / 1000
+		bytesIn, bytesOut = uint64(payload.NetworkIn*seconds), uint64(payload.NetworkOut*seconds)
+	}
+	if err := models.AddTrafficSample(server.ID, sampledAt, bytesIn, bytesOut); err != nil {
+		log.Printf("记录服务器 %d 的每小时流量失败: %v", server.ID, err)
+	}
+
+	// OOM 是一次性事件，实时样本中携带的也要保存并告警
+	if payload.OOMKills > 0 {
+		recordOOMEvents(server, payload)
+	}
+	if len(payload.SMART) > 0 {
+		recordDiskHealth(server, payload.SMART, sampledAt)
+	}
+	if len(payload.Checks) > 0 {
+		recordUptimeResults(server, payload.Checks, now)
+	}
+	if len(payload.Mesh) > 0 {
+		recordMeshResults(server, payload.Mesh, now)
+	}
+	if len(payload.Logs) > 0 || payload.LogsDropped > 0 {
+		recordLogLines(server, payload.Logs, payload.LogsDropped, now)
+	}
+	if payload.Certificates != nil {
+		recordHostCertificates(server, payload.Certificates.Certificates, now)
+	}
 
-	if err := models.DB.Model(&models.Server{}).Where("id = ?", server.ID).Updates(updates).Error; err != nil {
+	// 实时样本不写入监控记录，避免聚焦查看放大历史数据的写入量
+	if payload.Live {
+		if err := models.SaveServerMonitorState(server.ID, updates); err != nil {
+			return nil, err
+		}
+		return &record, nil
+	}
+
+	if len(payload.Mounts) > 0 {
+		recordDiskMounts(server, payload.Mounts, sampledAt)
+	}
+
+	// 启用批量写入时，监控记录和服务器状态会在下一个刷新窗口内统一提交
+	if err := models.SaveMonitorSample(&record, updates); err != nil {
 		return nil, err
 	}
 
 	return &record, nil
 }
 
+// recordDiskMounts 保存各挂载点的使用情况，时间戳与监控记录一致，预警规则据此按挂载点判断
+func recordDiskMounts(server *models.Server, mounts []DiskMountPayload, sampledAt time.Time) {
+	mounts = mounts[:min(len(mounts), models.MaxMounts)]
+	stats := make([]models.DiskMountStat, 0, len(mounts))
+	for _, m := range mounts {
+		stats = append(stats, models.DiskMountStat{
+			ServerID:    server.ID,
+			Timestamp:   sampledAt,
+			Mount:       m.Mount,
+			FSType:      m.FSType,
+			Total:       m.Total,
+			Used:        m.Used,
+			Free:        m.Free,
+			InodesTotal: m.InodesTotal,
+			InodesUsed:  m.InodesUsed,
+			InodesFree:  m.InodesFree,
+		})
+	}
+	if err := models.CreateDiskMountStats(stats); err != nil {
+		log.Printf("保存服务器 %d 的挂载点使用情况失败: %v", server.ID, err)
+	}
+}
+
+// recordOOMEvents 保存Agent上报的OOM事件并触发告警。
+// Agent 没有权限读取内核日志时只有次数，没有事件详情
+func recordOOMEvents(server *models.Server, payload *MonitorPayload) {
+	events := make([]models.OOMEvent, 0, len(payload.OOMEvents))
+	for _, e := range payload.OOMEvents {
+		events = append(events, models.OOMEvent{
+			ServerID:   server.ID,
+			OccurredAt: e.Time,
+			PID:        e.PID,
+			Process:    e.Process,
+			Message:    e.Message,
+		})
+	}
+	if err := models.CreateOOMEvents(events); err != nil {
+		log.Printf("保存服务器 %d 的OOM事件失败: %v", server.ID, err)
+	}
+
+	log.Printf("服务器 %s(%d) 发生 %d 次 OOM kill", server.Name, server.ID, payload.OOMKills)
+	go services.GetAlertService().NotifyOOMKills(*server, payload.OOMKills, events)
+}
+
+// truncateTopProcesses 限制保存的进程排行数，避免异常的 Agent 放大监控记录
+func truncateTopProcesses(list []TopProcessPayload) []TopProcessPayload {
+	if len(list) > models.MaxTopProcessCount {
+		return list[:models.MaxTopProcessCount]
+	}
+	return list
+}
+
+// recordDiskHealth 保存Agent上报的磁盘健康信息，有磁盘新出现故障预测时告警
+func recordDiskHealth(server *models.Server, payload []DiskHealthPayload, checkedAt time.Time) {
+	disks := make([]models.DiskHealth, 0, len(payload))
+	for _, d := range payload {
+		disks = append(disks, models.DiskHealth{
+			Device:               d.Device,
+			Model:                d.Model,
+			Serial:               d.Serial,
+			Type:                 d.Type,
+			CapacityBytes:        d.CapacityBytes,
+			Passed:               d.Passed,
+			Temperature:          d.Temperature,
+			ReallocatedSectors:   d.ReallocatedSectors,
+			PendingSectors:       d.PendingSectors,
+			UncorrectableSectors: d.UncorrectableSectors,
+			MediaErrors:          d.MediaErrors,
+			WearLevel:            d.WearLevel,
+			PowerOnHours:         d.PowerOnHours,
+			FailingAttributes:    strings.Join(d.FailingAttributes, ","),
+			PredictedFailure:     d.PredictedFailure,
+			CheckedAt:            checkedAt,
+		})
+	}
+	failing, err := models.SaveDiskHealth(server.ID, disks)
+	if err != nil {
+		log.Printf("保存服务器 %d 的磁盘健康信息失败: %v", server.ID, err)
+		return
+	}
+	if len(failing) > 0 {
+		log.Printf("服务器 %s(%d) 有 %d 块磁盘 SMART 预测即将故障", server.Name, server.ID, len(failing))
+		go services.GetAlertService().NotifyDiskFailures(*server, failing)
+	}
+}
+
 // isMonitorOnlyServer 检查服务器是否为监控模式（monitor-only）
 // 监控模式的服务器不支持终端、文件、进程、Docker、Nginx、证书等操作命令
 func isMonitorOnlyServer(server *models.Server) bool {
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/user/server-ops-backend/config"
+)
+
+// 监控订阅连接的最大ping间隔
+const monitorIdlePingInterval = 30 * time.Second
+
+// monitorIdleTimeout 返回监控订阅连接的空闲超时，0表示不限制
+func monitorIdleTimeout() time.Duration {
+	return config.LoadConfig().MonitorIdleTimeout
+}
+
+// startMonitorIdlePolicy 为监控订阅连接启用空闲断开策略：
+// 定期发送ping，收到pong或任意客户端消息时续期读超时；
+// 超过空闲窗口仍无响应时，ReadMessage 返回超时错误，读循环随之退出并释放连接。
+// 返回的 stop 函数需在连接处理结束时调用。
+func startMonitorIdlePolicy(conn *SafeConn, serverID uint) (touch func(), stop func()) {
+	return startIdlePolicy(conn, serverID, monitorIdleTimeout())
+}
+
+// startIdlePolicy 按指定的空闲窗口启用空闲断开策略，idle 不大于0时不做任何限制
+func startIdlePolicy(conn *SafeConn, serverID uint, idle time.Duration) (touch func(), stop func()) {
+	if idle <= 0 {
+		return func() {}, func() {}
+	}
+
+	touch = func() {
+		conn.SetReadDeadline(time.Now().Add(idle))
+	}
+	touch()
+	conn.SetPongHandler(func(string) error {
+		touch()
+		return nil
+	})
+
+	pingInterval := idlePingInterval(idle)
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)); err != nil {
+					log.Printf("服务器 %d 的监控订阅ping发送失败: %v", serverID, err)
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return touch, func() { close(done) }
+}
+
+// idlePingInterval 返回ping间隔：空闲窗口的三分之一，保证超时前至少有两次ping机会，最长不超过 monitorIdlePingInterval
+func idlePingInterval(idle time.Duration) time.Duration {
+	if interval := idle / 3; interval < monitorIdlePingInterval {
+		return interval
+	}
+	return monitorIdlePingInterval
+}
+
+// isIdleTimeout 判断读取错误是否由空闲超时引起
+func isIdleTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
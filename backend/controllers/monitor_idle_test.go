@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdlePingInterval(t *testing.T) {
+	tests := []struct {
+		idle time.Duration
+		want time.Duration
+	}{
+		{3 * time.Second, time.Second},
+		{60 * time.Second, 20 * time.Second},
+		{90 * time.Second, monitorIdlePingInterval},
+		{10 * time.Minute, monitorIdlePingInterval},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, idlePingInterval(tt.idle), "idle=%s", tt.idle)
+	}
+}
+
+// startIdlePolicyPair 建立一对websocket连接，面板一侧按 idle 启用空闲策略并持续读取，
+// 读取结束时把错误发送到返回的通道
+func startIdlePolicyPair(t *testing.T, idle time.Duration) (*websocket.Conn, <-chan error) {
+	t.Helper()
+	readErr := make(chan error, 1)
+	upgrader := websocket.Upgrader{}
+	panel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		safeConn := &SafeConn{Conn: conn}
+		touch, stop := startIdlePolicy(safeConn, 1, idle)
+		defer stop()
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				readErr <- err
+				return
+			}
+			touch()
+		}
+	}))
+	t.Cleanup(panel.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(panel.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client, readErr
+}
+
+func TestIdlePolicyDisconnectsSilentClient(t *testing.T) {
+	// 客户端不读取消息，也就不会回复ping：超过空闲窗口后面板读取超时
+	start := time.Now()
+	_, readErr := startIdlePolicyPair(t, 300*time.Millisecond)
+	select {
+	case err := <-readErr:
+		assert.True(t, isIdleTimeout(err), "应为空闲超时: %v", err)
+		assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+	case <-time.After(5 * time.Second):
+		t.Fatal("空闲连接未被断开")
+	}
+}
+
+func TestIdlePolicyKeepsResponsiveClient(t *testing.T) {
+	// 客户端持续读取时自动回复pong，连接在多个空闲窗口之后仍然保持
+	client, readErr := startIdlePolicyPair(t, 300*time.Millisecond)
+	go func() {
+		for {
+			if _, _, err := client.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	select {
+	case err := <-readErr:
+		t.Fatalf("回复pong的连接不应断开: %v", err)
+	case <-time.After(time.Second):
+	}
+
+	// 客户端主动关闭不属于空闲超时
+	client.Close()
+	select {
+	case err := <-readErr:
+		assert.False(t, isIdleTimeout(err), "不应为空闲超时: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("客户端关闭后读取未结束")
+	}
+}
+
+func TestIdlePolicyMessagesRenewDeadline(t *testing.T) {
+	// 客户端不读取消息（不回复ping），但持续发送消息时同样不会被断开
+	client, readErr := startIdlePolicyPair(t, 300*time.Millisecond)
+	deadline := time.After(time.Second)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for done := false; !done; {
+		select {
+		case err := <-readErr:
+			t.Fatalf("持续发送消息的连接不应断开: %v", err)
+		case <-ticker.C:
+			assert.NoError(t, client.WriteMessage(websocket.TextMessage, []byte(`{"type":"ping"}`)))
+		case <-deadline:
+			done = true
+		}
+	}
+}
+
+func TestIdlePolicyDisabled(t *testing.T) {
+	// 空闲超时为0时不设置读超时，也不发送ping
+	client, readErr := startIdlePolicyPair(t, 0)
+	pinged := make(chan struct{}, 1)
+	client.SetPingHandler(func(string) error {
+		pinged <- struct{}{}
+		return nil
+	})
+	go func() {
+		for {
+			if _, _, err := client.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	select {
+	case err := <-readErr:
+		t.Fatalf("未启用空闲超时时连接不应断开: %v", err)
+	case <-pinged:
+		t.Fatal("未启用空闲超时时不应发送ping")
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	assert.False(t, isIdleTimeout(errors.New("websocket: close 1000")))
+	assert.False(t, isIdleTimeout(nil))
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestPersistReplayedMonitorSamples(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.ServerMonitor{}, &models.TrafficHourly{}))
+	server := models.Server{Name: "web-01", SecretKey: "replay-test", Latency: 12, ClockOffsetMs: 1000}
+	assert.NoError(t, models.DB.Create(&server).Error)
+	defer models.DB.Unscoped().Delete(&server)
+	defer replayedSamples.Delete(server.ID)
+
+	collected := time.Now().Add(-10 * time.Minute).Truncate(time.Millisecond)
+	payload := MonitorPayload{
+		CPUUsage:       42,
+		Latency:        300,
+		NetworkInDelta: 1024,
+		CollectedAt:    collected.UnixMilli(),
+		Replay:         true,
+		ReportInterval: 5000,
+	}
+
+	record, err := persistMonitorPayload(&server, &payload)
+	assert.NoError(t, err)
+	// 按采集时间入库，并扣除 Agent 时钟超前的部分
+	assert.Equal(t, collected.Add(-time.Second).UnixMilli(), record.Timestamp.UnixMilli())
+	// 旧样本只累加流量，不覆盖当前的网络质量
+	assert.Equal(t, float64(12), server.Latency)
+	assert.Equal(t, uint64(1024), server.NetworkInTotal)
+
+	// Agent 重启后再次补发同一样本时丢弃
+	_, err = persistMonitorPayload(&server, &payload)
+	assert.ErrorIs(t, err, errDuplicateSample)
+	assert.Equal(t, uint64(1024), server.NetworkInTotal)
+
+	var count int64
+	models.DB.Model(&models.ServerMonitor{}).Where("server_id = ?", server.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// 实时上报的样本不受影响
+	_, err = persistMonitorPayload(&server, &MonitorPayload{CPUUsage: 10, Latency: 20})
+	assert.NoError(t, err)
+	assert.Equal(t, float64(20), server.Latency)
+}
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+// Agent 单次测速最长 3 分钟，这里额外留出传输时间
+const networkBenchmarkTimeout = 4 * time.Minute
+
+// 测速请求的响应通道
+var networkBenchmarkChannels sync.Map
+
+// networkBenchmarkTools 支持的测速工具，为空时由 Agent 根据 target 选择
+var networkBenchmarkTools = map[string]bool{"": true, "iperf3": true, "speedtest": true}
+
+// reportedBenchmark Agent 上报的测速结果
+type reportedBenchmark struct {
+	Tool        string  `json:"tool"`
+	Target      string  `json:"target"`
+	ServerName  string  `json:"server_name"`
+	DownloadBps float64 `json:"download_bps"`
+	UploadBps   float64 `json:"upload_bps"`
+	LatencyMs   float64 `json:"latency_ms"`
+	JitterMs    float64 `json:"jitter_ms"`
+	PacketLoss  float64 `json:"packet_loss"`
+	Duration    int     `json:"duration"`
+}
+
+// RunNetworkBenchmark 让Agent使用 iperf3 或 speedtest 测速并保存结果。
+// 请求体 target 为 iperf3 服务端地址（host[:port]）或 speedtest 节点 ID，duration 只对 iperf3 生效
+func RunNetworkBenchmark(c *gin.Context) {
+	serverID, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+	var req struct {
+		Tool     string `json:"tool"`
+		Target   string `json:"target"`
+		Duration int    `json:"duration"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+	req.Tool = strings.ToLower(strings.TrimSpace(req.Tool))
+	req.Target = strings.TrimSpace(req.Target)
+	if !networkBenchmarkTools[req.Tool] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的测速工具"})
+		return
+	}
+	if req.Tool == "iperf3" && req.Target == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "iperf3 需要指定服务端地址"})
+		return
+	}
+	if len(req.Target) > 255 || req.Duration < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+	if _, err := models.GetServerByID(serverID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+
+	response, status, err := callAgent(serverID, "network_benchmark", &networkBenchmarkChannels, map[string]interface{}{
+		"tool":     req.Tool,
+		"target":   req.Target,
+		"duration": req.Duration,
+	}, networkBenchmarkTimeout)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	benchmark, err := buildNetworkBenchmark(serverID, response["result"])
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	benchmark.Username = c.GetString("username")
+	if err := models.CreateNetworkBenchmark(benchmark); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存测速结果失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"benchmark": benchmark})
+}
+
+// buildNetworkBenchmark 把Agent上报的测速结果转换为数据库记录
+func buildNetworkBenchmark(serverID uint, result interface{}) (*models.NetworkBenchmark, error) {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return nil, errors.New("Agent返回的测速结果格式错误")
+	}
+	var reported reportedBenchmark
+	if err := json.Unmarshal(raw, &reported); err != nil || reported.Tool == "" {
+		return nil, errors.New("Agent返回的测速结果格式错误")
+	}
+	return &models.NetworkBenchmark{
+		ServerID:    serverID,
+		Tool:        reported.Tool,
+		Target:      reported.Target,
+		ServerName:  reported.ServerName,
+		DownloadBps: reported.DownloadBps,
+		UploadBps:   reported.UploadBps,
+		LatencyMs:   reported.LatencyMs,
+		JitterMs:    reported.JitterMs,
+		PacketLoss:  reported.PacketLoss,
+		Duration:    reported.Duration,
+	}, nil
+}
+
+// GetNetworkBenchmarks 获取服务器的历史测速记录，按时间倒序，查询参数 tool 按测速工具过滤
+func GetNetworkBenchmarks(c *gin.Context) {
+	serverID, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+	benchmarks, err := models.GetNetworkBenchmarks(serverID, strings.TrimSpace(c.Query("tool")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取测速记录失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"benchmarks": benchmarks})
+}
+
+// HandleNetworkBenchmarkResponse 将Agent的测速响应传递给等待中的HTTP请求
+func HandleNetworkBenchmarkResponse(requestID string, data map[string]interface{}) {
+	deliverAgentResponse(&networkBenchmarkChannels, requestID, data)
+}
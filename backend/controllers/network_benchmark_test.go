@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestRunNetworkBenchmark(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.NetworkBenchmark{}))
+	server := models.Server{Name: "bench", Status: "online", SecretKey: "secret"}
+	assert.NoError(t, db.Create(&server).Error)
+	t.Cleanup(func() {
+		db.Unscoped().Delete(&server)
+		db.Where("server_id = ?", server.ID).Delete(&models.NetworkBenchmark{})
+	})
+	serverID := strconv.FormatUint(uint64(server.ID), 10)
+
+	var payloads []map[string]interface{}
+	connectReverseAgent(t, server.ID, func(msg map[string]interface{}) map[string]interface{} {
+		payload, _ := msg["payload"].(map[string]interface{})
+		payloads = append(payloads, payload)
+		if payload["target"] == "busy.example.com" {
+			return map[string]interface{}{
+				"type": "network_benchmark_response",
+				"data": map[string]interface{}{"error": "iperf3: the server is busy running a test"},
+			}
+		}
+		return map[string]interface{}{
+			"type": "network_benchmark_response",
+			"data": map[string]interface{}{"result": map[string]interface{}{
+				"tool": "iperf3", "target": payload["target"], "download_bps": 940000000.0, "upload_bps": 480000000.0,
+				"latency_ms": 1.8, "jitter_ms": 0.25, "packet_loss": 0.0, "duration": 10,
+			}},
+		}
+	})
+
+	run := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: serverID}}
+		c.Set("username", "admin")
+		RunNetworkBenchmark(c)
+		return w
+	}
+
+	w := run(`{"tool":"iperf3","target":"10.0.0.2:5201","duration":10}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = run(`{"tool":"iperf3","target":"busy.example.com"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "server is busy")
+
+	// 参数错误时不请求Agent
+	for _, body := range []string{`{"tool":"ping"}`, `{"tool":"iperf3"}`, `{"duration":-1}`} {
+		assert.Equal(t, http.StatusBadRequest, run(body).Code, body)
+	}
+	assert.Len(t, payloads, 2)
+
+	// 失败的测速不保存
+	w = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Params = gin.Params{{Key: "id", Value: serverID}}
+	GetNetworkBenchmarks(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Benchmarks []models.NetworkBenchmark `json:"benchmarks"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Benchmarks, 1) {
+		assert.Equal(t, "10.0.0.2:5201", resp.Benchmarks[0].Target)
+		assert.Equal(t, 940000000.0, resp.Benchmarks[0].DownloadBps)
+		assert.Equal(t, 0.25, resp.Benchmarks[0].JitterMs)
+		assert.Equal(t, "admin", resp.Benchmarks[0].Username)
+	}
+}
+
+func TestCreateNetworkBenchmarkKeepsRecent(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.NetworkBenchmark{}))
+	t.Cleanup(func() { db.Where("server_id = ?", 9001).Delete(&models.NetworkBenchmark{}) })
+
+	for i := 0; i < models.NetworkBenchmarkLimit+5; i++ {
+		assert.NoError(t, models.CreateNetworkBenchmark(&models.NetworkBenchmark{ServerID: 9001, Tool: "speedtest", DownloadBps: float64(i)}))
+	}
+	benchmarks, err := models.GetNetworkBenchmarks(9001, "speedtest")
+	assert.NoError(t, err)
+	assert.Len(t, benchmarks, models.NetworkBenchmarkLimit)
+	assert.Equal(t, float64(models.NetworkBenchmarkLimit+4), benchmarks[0].DownloadBps)
+
+	benchmarks, err = models.GetNetworkBenchmarks(9001, "iperf3")
+	assert.NoError(t, err)
+	assert.Empty(t, benchmarks)
+}
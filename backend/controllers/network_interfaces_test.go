@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestNetworkInterfaceStats(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.ServerMonitor{}, &models.TrafficHourly{}))
+	server := models.Server{Name: "docker-host", SecretKey: "interface-test"}
+	assert.NoError(t, db.Create(&server).Error)
+	defer db.Unscoped().Delete(&server)
+	defer db.Where("server_id = ?", server.ID).Delete(&models.ServerMonitor{})
+
+	record, err := persistMonitorPayload(&server, &MonitorPayload{
+		NetworkIn: 1000,
+		Interfaces: []InterfacePayload{
+			{Name: "eth0", RxRate: 1000, TxRate: 200, RxBytes: 30000, RxErrors: 3, RxDropped: 1},
+			{Name: "docker0", Virtual: true, RxRate: 5000, TxRate: 5000},
+		},
+	})
+	assert.NoError(t, err)
+
+	// 历史记录和实时推送都带各网卡的统计
+	var saved models.ServerMonitor
+	assert.NoError(t, db.First(&saved, record.ID).Error)
+	var interfaces []InterfacePayload
+	assert.NoError(t, json.Unmarshal([]byte(saved.Interfaces), &interfaces))
+	if assert.Len(t, interfaces, 2) {
+		assert.Equal(t, "eth0", interfaces[0].Name)
+		assert.Equal(t, uint64(3), interfaces[0].RxErrors)
+		assert.True(t, interfaces[1].Virtual)
+	}
+
+	payload, err := json.Marshal(buildMonitorData(&server, record))
+	assert.NoError(t, err)
+	var data struct {
+		Interfaces []InterfacePayload `json:"interfaces"`
+	}
+	assert.NoError(t, json.Unmarshal(payload, &data))
+	if assert.Len(t, data.Interfaces, 2) {
+		assert.Equal(t, "docker0", data.Interfaces[1].Name)
+		assert.Equal(t, 5000.0, data.Interfaces[1].TxRate)
+	}
+
+	// 旧版 Agent 不上报网卡时不带该字段
+	record, err = persistMonitorPayload(&server, &MonitorPayload{CPUUsage: 5})
+	assert.NoError(t, err)
+	assert.NotContains(t, buildMonitorData(&server, record), "interfaces")
+}
@@ -22,6 +22,23 @@ type DeclarativeSiteRequest struct {
 	Config       map[string]interface{} `json:"config"`
 }
 
+// WebsiteTemplateRequest 按站点模板创建网站的请求，字段与 Agent 的 nginx.SiteTemplateParams 一致
+type WebsiteTemplateRequest struct {
+	Template          string   `json:"template"`
+	Domains           []string `json:"domains"`
+	Upstream          string   `json:"upstream"`
+	RootDir           string   `json:"root_dir"`
+	PHPVersion        string   `json:"php_version"`
+	HTTPS             bool     `json:"https"`
+	CertificateID     uint     `json:"certificate_id"` // 为 0 时使用Agent上该域名已签发的证书
+	Gzip              *bool    `json:"gzip"`
+	CacheExpires      string   `json:"cache_expires"`
+	ClientMaxBodySize string   `json:"client_max_body_size"`
+}
+
+// websiteTemplates 支持的站点模板
+var websiteTemplates = map[string]bool{"reverse_proxy": true, "static": true, "php": true, "websocket": true}
+
 type DeclarativeSSLRequest struct {
 	Domain     string            `json:"domain"`
 	Domains    []string          `json:"domains"`
@@ -494,7 +511,7 @@ func DownloadNginxLog(c *gin.Context) {
 	c.String(http.StatusOK, respData.Content)
 }
 
-// RestartNginx 重启Nginx服务
+// RestartNginx 重启Nginx服务，?server=apache|caddy 时重载对应的 Web 服务器
 func RestartNginx(c *gin.Context) {
 	serverId := c.Param("id")
 
@@ -504,6 +521,11 @@ func RestartNginx(c *gin.Context) {
 		return
 	}
 
+	payload, ok := webServerPayload(c, "nginx_restart", "webserver_reload")
+	if !ok {
+		return
+	}
+
 	// 获取服务器信息
 	var server models.Server
 	if err := models.DB.First(&server, id).Error; err != nil {
@@ -513,10 +535,8 @@ func RestartNginx(c *gin.Context) {
 
 	// 构建请求数据
 	reqData := map[string]interface{}{
-		"type": "nginx_command",
-		"payload": map[string]interface{}{
-			"action": "nginx_restart",
-		},
+		"type":    "nginx_command",
+		"payload": payload,
 	}
 
 	// 通过WebSocket发送命令给Agent
@@ -536,7 +556,7 @@ func RestartNginx(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
-// StopNginx 停止Nginx服务
+// StopNginx 停止Nginx服务，?server=apache|caddy 时停止对应的 Web 服务器
 func StopNginx(c *gin.Context) {
 	serverId := c.Param("id")
 
@@ -546,6 +566,11 @@ func StopNginx(c *gin.Context) {
 		return
 	}
 
+	payload, ok := webServerPayload(c, "nginx_stop", "webserver_stop")
+	if !ok {
+		return
+	}
+
 	// 获取服务器信息
 	var server models.Server
 	if err := models.DB.First(&server, id).Error; err != nil {
@@ -555,10 +580,8 @@ func StopNginx(c *gin.Context) {
 
 	// 构建请求数据
 	reqData := map[string]interface{}{
-		"type": "nginx_command",
-		"payload": map[string]interface{}{
-			"action": "nginx_stop",
-		},
+		"type":    "nginx_command",
+		"payload": payload,
 	}
 
 	// 通过WebSocket发送命令给Agent
@@ -578,7 +601,7 @@ func StopNginx(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
-// StartNginx 启动Nginx服务
+// StartNginx 启动Nginx服务，?server=apache|caddy 时启动对应的 Web 服务器
 func StartNginx(c *gin.Context) {
 	serverId := c.Param("id")
 
@@ -588,6 +611,11 @@ func StartNginx(c *gin.Context) {
 		return
 	}
 
+	payload, ok := webServerPayload(c, "nginx_start", "webserver_start")
+	if !ok {
+		return
+	}
+
 	// 获取服务器信息
 	var server models.Server
 	if err := models.DB.First(&server, id).Error; err != nil {
@@ -597,10 +625,8 @@ func StartNginx(c *gin.Context) {
 
 	// 构建请求数据
 	reqData := map[string]interface{}{
-		"type": "nginx_command",
-		"payload": map[string]interface{}{
-			"action": "nginx_start",
-		},
+		"type":    "nginx_command",
+		"payload": payload,
 	}
 
 	// 通过WebSocket发送命令给Agent
@@ -620,7 +646,7 @@ func StartNginx(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
-// TestNginxConfig 测试Nginx配置
+// TestNginxConfig 测试Nginx配置，?server=apache|caddy 时测试对应 Web 服务器的配置
 func TestNginxConfig(c *gin.Context) {
 	serverId := c.Param("id")
 
@@ -630,6 +656,11 @@ func TestNginxConfig(c *gin.Context) {
 		return
 	}
 
+	payload, ok := webServerPayload(c, "nginx_test_config", "webserver_test")
+	if !ok {
+		return
+	}
+
 	// 获取服务器信息
 	var server models.Server
 	if err := models.DB.First(&server, id).Error; err != nil {
@@ -638,21 +669,132 @@ func TestNginxConfig(c *gin.Context) {
 	}
 
 	// 构建请求数据
+	reqData := map[string]interface{}{
+		"type":    "nginx_command",
+		"payload": payload,
+	}
+
+	// 通过WebSocket发送命令给Agent
+	resp, err := utils.SendCommandToAgent(server.ID, server.SecretKey, reqData)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("发送命令失败: %v", err)})
+		return
+	}
+
+	// 测试未通过是正常结果（success=false），不走 parseAndValidateNginxResponse 的失败判定
+	result := nginxTestResult{
+		Errors:   []nginxConfigIssue{},
+		Warnings: []nginxConfigIssue{},
+	}
+	if err := json.Unmarshal([]byte(resp), &result); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("解析响应失败: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// webServerTypes 支持的 Web 服务器类型，httpd 是 apache 的别名
+var webServerTypes = map[string]bool{"nginx": true, "apache": true, "httpd": true, "caddy": true}
+
+// parseWebServerType 读取 ?server= 参数，为空时返回空字符串，类型不支持时返回 400
+func parseWebServerType(c *gin.Context) (string, bool) {
+	serverType := strings.ToLower(strings.TrimSpace(c.Query("server")))
+	if serverType != "" && !webServerTypes[serverType] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("不支持的Web服务器类型: %s，可用: nginx、apache、caddy", serverType)})
+		return "", false
+	}
+	return serverType, true
+}
+
+// webServerPayload 构造启停、重载和配置测试的命令：未指定类型或为 nginx 时沿用原有的 nginx_* 操作，
+// apache 和 caddy 使用 Agent 的 webserver_* 操作
+func webServerPayload(c *gin.Context, nginxAction, webServerAction string) (map[string]interface{}, bool) {
+	serverType, ok := parseWebServerType(c)
+	if !ok {
+		return nil, false
+	}
+	if serverType == "" || serverType == "nginx" {
+		return map[string]interface{}{"action": nginxAction}, true
+	}
+	return map[string]interface{}{"action": webServerAction, "server": serverType}, true
+}
+
+// DetectWebServers 检测服务器上安装的 Nginx、Apache httpd 和 Caddy
+func DetectWebServers(c *gin.Context) {
+	serverId := c.Param("id")
+
+	id, err := strconv.Atoi(serverId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	// 获取服务器信息
+	var server models.Server
+	if err := models.DB.First(&server, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+
 	reqData := map[string]interface{}{
 		"type": "nginx_command",
 		"payload": map[string]interface{}{
-			"action": "nginx_test_config",
+			"action": "webserver_detect",
+		},
+	}
+
+	resp, err := utils.SendCommandToAgent(server.ID, server.SecretKey, reqData)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("发送命令失败: %v", err)})
+		return
+	}
+
+	var result []map[string]interface{}
+	if err := json.Unmarshal([]byte(resp), &result); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("解析响应失败: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetWebServerVirtualHosts 列出 Web 服务器配置中的虚拟主机，?server= 为空时使用 Agent 检测到的第一个
+func GetWebServerVirtualHosts(c *gin.Context) {
+	serverId := c.Param("id")
+
+	id, err := strconv.Atoi(serverId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	serverType, ok := parseWebServerType(c)
+	if !ok {
+		return
+	}
+
+	// 获取服务器信息
+	var server models.Server
+	if err := models.DB.First(&server, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+
+	reqData := map[string]interface{}{
+		"type": "nginx_command",
+		"payload": map[string]interface{}{
+			"action": "webserver_vhosts",
+			"server": serverType,
 		},
 	}
 
-	// 通过WebSocket发送命令给Agent
 	resp, err := utils.SendCommandToAgent(server.ID, server.SecretKey, reqData)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("发送命令失败: %v", err)})
 		return
 	}
 
-	// 【安全修复】解析并验证响应
 	result, err := parseAndValidateNginxResponse(resp)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -662,6 +804,22 @@ func TestNginxConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// nginxConfigIssue nginx -t 输出中的一条错误或警告，由 Agent 解析
+type nginxConfigIssue struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+}
+
+// nginxTestResult 配置测试结果：整体是否通过、出错位置以及原始输出
+type nginxTestResult struct {
+	Success  bool               `json:"success"`
+	Output   string             `json:"output"`
+	Errors   []nginxConfigIssue `json:"errors"`
+	Warnings []nginxConfigIssue `json:"warnings"`
+}
+
 // GetNginxProcesses 获取Nginx相关进程
 func GetNginxProcesses(c *gin.Context) {
 	serverId := c.Param("id")
@@ -1296,6 +1454,87 @@ func ApplyWebsiteConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, respData)
 }
 
+// ApplyWebsiteTemplate 按站点模板（反向代理、静态站点、PHP、WebSocket）创建网站，
+// Agent 根据少量参数生成包含 HTTPS 跳转、gzip 和静态资源缓存的完整配置
+func ApplyWebsiteTemplate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	var server models.Server
+	if err := models.DB.First(&server, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+
+	var req WebsiteTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("请求参数无效: %v", err)})
+		return
+	}
+	if !websiteTemplates[req.Template] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的站点模板"})
+		return
+	}
+	if len(req.Domains) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请提供至少一个域名"})
+		return
+	}
+
+	payload := map[string]interface{}{
+		"action":               "apply_config",
+		"template":             req.Template,
+		"domains":              req.Domains,
+		"upstream":             req.Upstream,
+		"root_dir":             req.RootDir,
+		"php_version":          req.PHPVersion,
+		"https":                req.HTTPS,
+		"cache_expires":        req.CacheExpires,
+		"client_max_body_size": req.ClientMaxBodySize,
+	}
+	if req.Gzip != nil {
+		payload["gzip"] = *req.Gzip
+	}
+	if req.HTTPS && req.CertificateID > 0 {
+		cert, err := models.GetManagedCertificate(server.ID, req.CertificateID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("选择的证书不存在: %v", err)})
+			return
+		}
+		payload["certificate"] = cert.CertificatePath
+		payload["certificate_key"] = cert.KeyPath
+	}
+
+	models.CheckServerStatus(&server)
+	if !server.Online {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "服务器当前离线，无法连接"})
+		return
+	}
+
+	resp, err := utils.SendCommandToAgent(server.ID, server.SecretKey, map[string]interface{}{
+		"type":    "nginx_command",
+		"payload": payload,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("发送命令失败: %v", err)})
+		return
+	}
+
+	var respData map[string]interface{}
+	if err := json.Unmarshal([]byte(resp), &respData); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("解析响应失败: %v", err)})
+		return
+	}
+	if err := validateNginxResponse(respData); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, respData)
+}
+
 // IssueWebsiteCertificate 使用Lego签发证书
 func IssueWebsiteCertificate(c *gin.Context) {
 	serverID := c.Param("id")
@@ -1362,7 +1601,8 @@ func IssueWebsiteCertificate(c *gin.Context) {
 		if len(req.DNSConfig) > 0 {
 			dnsConfig = req.DNSConfig
 		}
-		if len(dnsConfig) == 0 {
+		// Route53 可以不填写密钥，使用 Agent 本机的 AWS 默认凭证
+		if len(dnsConfig) == 0 && provider != "route53" && provider != "aws" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "DNS验证需要提供账号配置"})
 			return
 		}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+	"github.com/user/server-ops-backend/utils"
+)
+
+// sendNginxSnapshotCommand 向Agent发送Nginx配置快照相关命令，失败时直接写入HTTP响应并返回false
+func sendNginxSnapshotCommand(c *gin.Context, payload map[string]interface{}) (string, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return "", false
+	}
+
+	var server models.Server
+	if err := models.DB.First(&server, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return "", false
+	}
+
+	resp, err := utils.SendCommandToAgent(server.ID, server.SecretKey, map[string]interface{}{
+		"type":    "nginx_command",
+		"payload": payload,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("发送命令失败: %v", err)})
+		return "", false
+	}
+	return resp, true
+}
+
+// ListNginxSnapshots 获取Nginx配置目录的历史版本，最新的在前
+func ListNginxSnapshots(c *gin.Context) {
+	resp, ok := sendNginxSnapshotCommand(c, map[string]interface{}{
+		"action": "nginx_snapshot_list",
+	})
+	if !ok {
+		return
+	}
+
+	var result []map[string]interface{}
+	if err := json.Unmarshal([]byte(resp), &result); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("解析响应失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"snapshots": result})
+}
+
+// CreateNginxSnapshot 立即快照当前的Nginx配置，内容未变化时返回最近的版本
+func CreateNginxSnapshot(c *gin.Context) {
+	resp, ok := sendNginxSnapshotCommand(c, map[string]interface{}{
+		"action": "nginx_snapshot_create",
+	})
+	if !ok {
+		return
+	}
+
+	result, err := parseAndValidateNginxResponse(resp)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// RestoreNginxSnapshot 把Nginx配置恢复到指定版本，恢复前Agent会自动快照当前配置。
+// 恢复后只返回配置检查结果，需要手动重载Nginx
+func RestoreNginxSnapshot(c *gin.Context) {
+	snapshotID := c.Param("snapshot_id")
+	if snapshotID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少快照ID"})
+		return
+	}
+
+	resp, ok := sendNginxSnapshotCommand(c, map[string]interface{}{
+		"action": "nginx_snapshot_restore",
+		"id":     snapshotID,
+	})
+	if !ok {
+		return
+	}
+
+	result, err := parseAndValidateNginxResponse(resp)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
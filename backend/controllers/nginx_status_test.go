@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+	"github.com/user/server-ops-backend/services"
+)
+
+func TestNginxStatusInMonitorData(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.ServerMonitor{}, &models.TrafficHourly{}, &models.DiskMountStat{}))
+	server := models.Server{Name: "web-nginx", SecretKey: "nginx-status-test"}
+	assert.NoError(t, db.Create(&server).Error)
+	defer db.Unscoped().Delete(&server)
+	defer db.Where("server_id = ?", server.ID).Delete(&models.ServerMonitor{})
+
+	record, err := persistMonitorPayload(&server, &MonitorPayload{Nginx: &models.NginxStatus{
+		Active: 120, Waiting: 80, Requests: 5000, RequestRate: 42.5, Dropped: 3, Up: true,
+		Upstreams: []models.NginxUpstreamHealth{
+			{Address: "127.0.0.1:8080", Up: true, Latency: 0.3},
+			{Address: "127.0.0.1:8081", Error: "connection refused"},
+		},
+	}})
+	assert.NoError(t, err)
+
+	var saved models.ServerMonitor
+	assert.NoError(t, db.First(&saved, record.ID).Error)
+	if nginx := saved.NginxStats(); assert.NotNil(t, nginx) {
+		assert.Equal(t, 120, nginx.Active)
+		assert.Equal(t, 1, nginx.UpstreamsDown())
+	}
+
+	payload, err := json.Marshal(buildMonitorData(&server, record))
+	assert.NoError(t, err)
+	var data struct {
+		Nginx models.NginxStatus `json:"nginx"`
+	}
+	assert.NoError(t, json.Unmarshal(payload, &data))
+	assert.Equal(t, 42.5, data.Nginx.RequestRate)
+
+	for expr, want := range map[string]bool{
+		"nginx_up == 1 AND nginx_active > 100":      true,
+		"nginx_requests > 40 AND nginx_dropped > 0": true,
+		"nginx_upstreams_down > 0":                  true,
+		"nginx_waiting > 100":                       false,
+	} {
+		matched, _, err := services.PreviewAlertRule(expr, server, saved)
+		assert.NoError(t, err, expr)
+		assert.Equal(t, want, matched, expr)
+	}
+
+	// stub_status 读取失败时 nginx_up 为 0，连接指标不存在
+	record, err = persistMonitorPayload(&server, &MonitorPayload{Nginx: &models.NginxStatus{Error: "connection refused"}})
+	assert.NoError(t, err)
+	matched, _, err := services.PreviewAlertRule("nginx_up == 0", server, *record)
+	assert.NoError(t, err)
+	assert.True(t, matched)
+	matched, _, _ = services.PreviewAlertRule("nginx_active < 1", server, *record)
+	assert.False(t, matched)
+
+	// 未上报 Nginx 指标的 Agent 不带该字段，规则不会触发
+	record, err = persistMonitorPayload(&server, &MonitorPayload{TCPConnections: 3})
+	assert.NoError(t, err)
+	assert.NotContains(t, buildMonitorData(&server, record), "nginx")
+	matched, _, _ = services.PreviewAlertRule("nginx_up == 0", server, *record)
+	assert.False(t, matched)
+}
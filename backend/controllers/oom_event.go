@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+// GetServerOOMEvents 获取服务器最近的OOM事件
+func GetServerOOMEvents(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 200 {
+		limit = 20
+	}
+
+	events, err := models.GetRecentOOMEvents(id, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取OOM事件失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
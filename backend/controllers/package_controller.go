@@ -0,0 +1,370 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// Agent 刷新软件源索引最长 3 分钟，读取清单最长 2 分钟，这里额外留出传输时间
+	packageInventoryTimeout = 6 * time.Minute
+	// Agent 安装、升级、卸载最长执行 9 分钟
+	packageCommandTimeout = 10 * time.Minute
+)
+
+// 软件包管理请求的响应通道
+var packageCommandChannels sync.Map
+
+// packageActions 支持的软件包操作
+var packageActions = map[string]bool{"install": true, "upgrade": true, "remove": true}
+
+// reportedInventory Agent 上报的软件包清单
+type reportedInventory struct {
+	Manager  string `json:"manager"`
+	Packages []struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		Arch    string `json:"arch"`
+	} `json:"packages"`
+	Updates []struct {
+		Name     string `json:"name"`
+		Arch     string `json:"arch"`
+		Version  string `json:"version"`
+		Repo     string `json:"repo"`
+		Security bool   `json:"security"`
+	} `json:"updates"`
+	CollectedAt time.Time `json:"collected_at"`
+}
+
+// buildPackageInventory 把Agent上报的清单转换为数据库记录，可用更新按包名和架构合并到已安装的软件包上
+func buildPackageInventory(serverID uint, reported *reportedInventory) (*models.PackageInventory, []models.ServerPackage) {
+	packages := make([]models.ServerPackage, 0, len(reported.Packages))
+	index := make(map[string]int, len(reported.Packages))
+	for _, p := range reported.Packages {
+		index[p.Name+"/"+p.Arch] = len(packages)
+		packages = append(packages, models.ServerPackage{ServerID: serverID, Name: p.Name, Version: p.Version, Arch: p.Arch})
+	}
+
+	inventory := &models.PackageInventory{
+		ServerID:     serverID,
+		Manager:      reported.Manager,
+		PackageCount: len(packages),
+		CollectedAt:  reported.CollectedAt,
+	}
+	if inventory.CollectedAt.IsZero() {
+		inventory.CollectedAt = time.Now()
+	}
+	for _, u := range reported.Updates {
+		i, ok := index[u.Name+"/"+u.Arch]
+		if !ok {
+			// apt 中 all 架构的软件包在可升级列表里可能显示为本机架构
+			if i, ok = index[u.Name+"/all"]; !ok {
+				continue
+			}
+		}
+		packages[i].UpdateVersion = u.Version
+		packages[i].UpdateRepo = u.Repo
+		packages[i].Security = u.Security
+		inventory.UpdateCount++
+		if u.Security {
+			inventory.SecurityCount++
+		}
+	}
+	return inventory, packages
+}
+
+// collectPackageInventory 让Agent读取软件包清单并保存，refresh 时Agent先刷新软件源索引
+func collectPackageInventory(serverID uint, refresh bool) (*models.PackageInventory, int, error) {
+	response, status, err := callAgent(serverID, "package_command", &packageCommandChannels, map[string]interface{}{
+		"action":  "inventory",
+		"refresh": refresh,
+	}, packageInventoryTimeout)
+	if err != nil {
+		return nil, status, err
+	}
+
+	raw, err := json.Marshal(response["inventory"])
+	if err != nil {
+		return nil, http.StatusBadGateway, errors.New("Agent返回的软件包清单格式错误")
+	}
+	var reported reportedInventory
+	if err := json.Unmarshal(raw, &reported); err != nil || reported.Manager == "" {
+		return nil, http.StatusBadGateway, errors.New("Agent返回的软件包清单格式错误")
+	}
+
+	inventory, packages := buildPackageInventory(serverID, &reported)
+	if err := models.SavePackageInventory(inventory, packages); err != nil {
+		return nil, http.StatusInternalServerError, errors.New("保存软件包清单失败: " + err.Error())
+	}
+	return inventory, http.StatusOK, nil
+}
+
+// GetPackages 获取服务器最近一次上报的软件包清单，不请求Agent。
+// 查询参数 search 按包名模糊匹配，updates=true 时只返回有可用更新的软件包
+func GetPackages(c *gin.Context) {
+	serverID, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+	inventory, err := models.GetPackageInventory(serverID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusOK, gin.H{"inventory": nil, "packages": []models.ServerPackage{}})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取软件包清单失败"})
+		return
+	}
+	packages, err := models.ListServerPackages(serverID, strings.TrimSpace(c.Query("search")), c.Query("updates") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取软件包清单失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"inventory": inventory, "packages": packages})
+}
+
+// RefreshPackages 让Agent重新读取已安装的软件包和可用更新并保存，
+// 请求体 refresh_index 为 true 时先刷新软件源索引（apt-get update / dnf makecache）
+func RefreshPackages(c *gin.Context) {
+	serverID, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+	var req struct {
+		RefreshIndex bool `json:"refresh_index"`
+	}
+	_ = c.ShouldBindJSON(&req)
+	if _, err := models.GetServerByID(serverID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+
+	inventory, status, err := collectPackageInventory(serverID, req.RefreshIndex)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"inventory": inventory})
+}
+
+// RunPackageCommand 安装、升级或卸载软件包。请求体 packages 为包名列表（upgrade 为空时升级全部），
+// dry_run 为 true 时只返回将要执行的变更；实际执行成功后重新读取并保存软件包清单
+func RunPackageCommand(c *gin.Context) {
+	serverID, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+	action := strings.ToLower(c.Param("action"))
+	if !packageActions[action] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的软件包操作: " + action})
+		return
+	}
+	var req struct {
+		Packages []string `json:"packages"`
+		DryRun   bool     `json:"dry_run"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+	if action != "upgrade" && len(req.Packages) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定软件包"})
+		return
+	}
+	if _, err := models.GetServerByID(serverID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+
+	response, status, err := callAgent(serverID, "package_command", &packageCommandChannels, map[string]interface{}{
+		"action":   action,
+		"packages": req.Packages,
+		"dry_run":  req.DryRun,
+	}, packageCommandTimeout)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := gin.H{"result": response["result"]}
+	if !req.DryRun {
+		if inventory, _, err := collectPackageInventory(serverID, false); err == nil {
+			result["inventory"] = inventory
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// packageInstallation 缺少补丁的服务器上该软件包的安装情况
+type packageInstallation struct {
+	ServerID      uint      `json:"server_id"`
+	ServerName    string    `json:"server_name"`
+	Version       string    `json:"version"`
+	Arch          string    `json:"arch"`
+	UpdateVersion string    `json:"update_version,omitempty"`
+	Security      bool      `json:"security"`
+	CollectedAt   time.Time `json:"collected_at"`
+}
+
+// FindServersMissingPackage 根据已保存的软件包清单查询缺少指定补丁的服务器。
+// 查询参数 name 为包名；version 为修复版本时返回已安装版本低于它的服务器，
+// 省略时返回该软件包有可用更新的服务器（security=true 时只看安全更新）
+func FindServersMissingPackage(c *gin.Context) {
+	name := strings.TrimSpace(c.Query("name"))
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定软件包名"})
+		return
+	}
+	fixedVersion := strings.TrimSpace(c.Query("version"))
+	securityOnly := c.Query("security") == "true"
+
+	installed, err := models.FindPackageInstallations(name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询软件包失败"})
+		return
+	}
+	var checked int64
+	models.DB.Model(&models.PackageInventory{}).Count(&checked)
+
+	servers := make(map[uint]*models.Server)
+	missing := make([]packageInstallation, 0)
+	for _, p := range installed {
+		if fixedVersion != "" {
+			if comparePackageVersions(p.Version, fixedVersion) >= 0 {
+				continue
+			}
+		} else if p.UpdateVersion == "" || (securityOnly && !p.Security) {
+			continue
+		}
+
+		server, ok := servers[p.ServerID]
+		if !ok {
+			if server, err = models.GetServerByID(p.ServerID); err != nil {
+				server = nil
+			}
+			servers[p.ServerID] = server
+		}
+		if server == nil {
+			continue
+		}
+		item := packageInstallation{
+			ServerID:      p.ServerID,
+			ServerName:    server.Name,
+			Version:       p.Version,
+			Arch:          p.Arch,
+			UpdateVersion: p.UpdateVersion,
+			Security:      p.Security,
+		}
+		if inventory, err := models.GetPackageInventory(p.ServerID); err == nil {
+			item.CollectedAt = inventory.CollectedAt
+		}
+		missing = append(missing, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":      name,
+		"version":   fixedVersion,
+		"servers":   missing,
+		"installed": len(installed),
+		"checked":   checked,
+	})
+}
+
+// HandlePackageCommandResponse 将Agent的软件包管理响应传递给等待中的HTTP请求
+func HandlePackageCommandResponse(requestID string, data map[string]interface{}) {
+	deliverAgentResponse(&packageCommandChannels, requestID, data)
+}
+
+// comparePackageVersions 按 dpkg 的规则比较 [epoch:]upstream[-revision] 形式的版本号，
+// a 较旧时返回负数。rpm 的 [epoch:]version-release 按同样的规则比较，结果与 rpm 基本一致
+func comparePackageVersions(a, b string) int {
+	epochA, restA := splitVersionEpoch(a)
+	epochB, restB := splitVersionEpoch(b)
+	if epochA != epochB {
+		if epochA < epochB {
+			return -1
+		}
+		return 1
+	}
+	upstreamA, revisionA := splitVersionRevision(restA)
+	upstreamB, revisionB := splitVersionRevision(restB)
+	if c := compareVersionPart(upstreamA, upstreamB); c != 0 {
+		return c
+	}
+	return compareVersionPart(revisionA, revisionB)
+}
+
+// splitVersionEpoch 拆出版本号开头的 epoch，没有时为 0
+func splitVersionEpoch(version string) (int, string) {
+	if i := strings.Index(version, ":"); i > 0 {
+		if epoch, err := strconv.Atoi(version[:i]); err == nil {
+			return epoch, version[i+1:]
+		}
+	}
+	return 0, version
+}
+
+// splitVersionRevision 以最后一个 - 拆分上游版本和修订号
+func splitVersionRevision(version string) (string, string) {
+	if i := strings.LastIndex(version, "-"); i >= 0 {
+		return version[:i], version[i+1:]
+	}
+	return version, ""
+}
+
+// compareVersionPart 交替比较非数字段和数字段：~ 排在最前（包括字符串结尾之前），字母排在其他符号之前，数字段按数值比较
+func compareVersionPart(a, b string) int {
+	isDigit := func(s string) bool { return s != "" && s[0] >= '0' && s[0] <= '9' }
+	order := func(s string) int {
+		switch {
+		case s == "" || isDigit(s):
+			return 0
+		case s[0] == '~':
+			return -1
+		case (s[0] >= 'a' && s[0] <= 'z') || (s[0] >= 'A' && s[0] <= 'Z'):
+			return int(s[0])
+		default:
+			return int(s[0]) + 256
+		}
+	}
+
+	for a != "" || b != "" {
+		for (a != "" && !isDigit(a)) || (b != "" && !isDigit(b)) {
+			if oa, ob := order(a), order(b); oa != ob {
+				return oa - ob
+			}
+			a, b = a[1:], b[1:]
+		}
+
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		firstDiff := 0
+		for isDigit(a) && isDigit(b) {
+			if firstDiff == 0 {
+				firstDiff = int(a[0]) - int(b[0])
+			}
+			a, b = a[1:], b[1:]
+		}
+		if isDigit(a) {
+			return 1
+		}
+		if isDigit(b) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return firstDiff
+		}
+	}
+	return 0
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestComparePackageVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"3.0.2-0ubuntu1.14", "3.0.2-0ubuntu1.15", -1},
+		{"3.0.2-0ubuntu1.15", "3.0.2-0ubuntu1.15", 0},
+		{"1.10", "1.9", 1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0", "1.0a", -1},
+		{"1:1.0", "2.0", 1},
+		{"1:3.0.7-24.el9", "1:3.0.7-25.el9_3", -1},
+		{"7.81.0-1ubuntu1.16", "7.81.0-1ubuntu1.9", 1},
+		{"2.35-0ubuntu3.6", "2.35-0ubuntu3", 1},
+	}
+	for _, tc := range cases {
+		got := comparePackageVersions(tc.a, tc.b)
+		switch {
+		case tc.want < 0:
+			assert.Negative(t, got, "%s < %s", tc.a, tc.b)
+		case tc.want > 0:
+			assert.Positive(t, got, "%s > %s", tc.a, tc.b)
+		default:
+			assert.Zero(t, got, "%s == %s", tc.a, tc.b)
+		}
+	}
+}
+
+func TestPackageInventoryAndMissingPatch(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.PackageInventory{}, &models.ServerPackage{}))
+	patched := models.Server{Name: "web-1", Status: "online", SecretKey: "secret"}
+	outdated := models.Server{Name: "web-2", Status: "online", SecretKey: "secret"}
+	assert.NoError(t, models.DB.Create(&patched).Error)
+	assert.NoError(t, models.DB.Create(&outdated).Error)
+	t.Cleanup(func() {
+		models.DB.Unscoped().Delete(&patched)
+		models.DB.Unscoped().Delete(&outdated)
+	})
+
+	inventories := map[uint]map[string]interface{}{
+		patched.ID: {
+			"manager":  "apt",
+			"packages": []interface{}{map[string]interface{}{"name": "openssl", "version": "3.0.2-0ubuntu1.15", "arch": "amd64"}},
+			"updates":  []interface{}{},
+		},
+		outdated.ID: {
+			"manager": "apt",
+			"packages": []interface{}{
+				map[string]interface{}{"name": "openssl", "version": "3.0.2-0ubuntu1.14", "arch": "amd64"},
+				map[string]interface{}{"name": "curl", "version": "7.81.0-1ubuntu1.15", "arch": "amd64"},
+			},
+			"updates": []interface{}{map[string]interface{}{
+				"name": "openssl", "arch": "amd64", "version": "3.0.2-0ubuntu1.15",
+				"repo": "jammy-security", "security": true,
+			}},
+		},
+	}
+	for _, server := range []models.Server{patched, outdated} {
+		inventory := inventories[server.ID]
+		connectReverseAgent(t, server.ID, func(msg map[string]interface{}) map[string]interface{} {
+			return map[string]interface{}{
+				"type": "package_command_response",
+				"data": map[string]interface{}{"inventory": inventory},
+			}
+		})
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"refresh_index":true}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(server.ID), 10)}}
+		RefreshPackages(c)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	inventory, err := models.GetPackageInventory(outdated.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, inventory.PackageCount)
+	assert.Equal(t, 1, inventory.SecurityCount)
+	updates, err := models.ListServerPackages(outdated.ID, "", true)
+	assert.NoError(t, err)
+	assert.Len(t, updates, 1)
+	assert.Equal(t, "3.0.2-0ubuntu1.15", updates[0].UpdateVersion)
+
+	query := func(rawQuery string) map[string]interface{} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/?"+rawQuery, nil)
+		FindServersMissingPackage(c)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// 按修复版本比较，以及按可用的安全更新判断
+	for _, rawQuery := range []string{"name=openssl&version=3.0.2-0ubuntu1.15", "name=openssl&security=true"} {
+		resp := query(rawQuery)
+		servers := resp["servers"].([]interface{})
+		assert.Len(t, servers, 1, rawQuery)
+		assert.Equal(t, "web-2", servers[0].(map[string]interface{})["server_name"])
+		assert.Equal(t, float64(2), resp["installed"])
+		assert.Equal(t, float64(2), resp["checked"])
+	}
+	assert.Empty(t, query("name=curl&security=true")["servers"])
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestCheckProbeTarget(t *testing.T) {
+	setupTestDB(t)
+
+	check := func(body string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/admin/settings/probe-targets/check", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		CheckProbeTarget(c)
+		var resp map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	// 元数据服务始终禁止，即使允许列表包含所有地址
+	code, resp := check(`{"host":"169.254.169.254","port":80,"probe_allow_targets":"*"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, false, resp["allowed"])
+	_, resp = check(`{"host":"::ffff:169.254.169.254","port":80,"probe_allow_targets":""}`)
+	assert.Equal(t, false, resp["allowed"])
+
+	rules := `,"probe_allow_targets":"10.0.0.0/8 80,8000-9000\n* 443","probe_deny_targets":"10.0.0.5"}`
+	_, resp = check(`{"host":"10.1.2.3","port":8080` + rules)
+	assert.Equal(t, true, resp["allowed"])
+	_, resp = check(`{"host":"10.1.2.3","port":22` + rules)
+	assert.Equal(t, false, resp["allowed"])
+	_, resp = check(`{"host":"10.0.0.5","port":80` + rules)
+	assert.Equal(t, false, resp["allowed"])
+	_, resp = check(`{"host":"[2001:db8::1]","port":443` + rules)
+	assert.Equal(t, true, resp["allowed"])
+
+	code, _ = check(`{"host":"10.1.2.3","port":80,"probe_deny_targets":"10.0.0.0/33"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestSaveSettingsRejectsInvalidProbeRules(t *testing.T) {
+	setupTestDB(t)
+	settings, err := models.GetSettings()
+	assert.NoError(t, err)
+
+	invalid := *settings
+	invalid.ProbeAllowTargets = "10.0.0.0/8 70000"
+	assert.Error(t, models.SaveSettings(&invalid))
+
+	valid := *settings
+	valid.ProbeAllowTargets = "# 内网服务\n10.0.0.0/8 80,443\n"
+	assert.NoError(t, models.SaveSettings(&valid))
+	saved, err := models.GetSettings()
+	assert.NoError(t, err)
+	assert.Equal(t, valid.ProbeAllowTargets, saved.ProbeAllowTargets)
+
+	saved.ProbeAllowTargets = ""
+	assert.NoError(t, models.SaveSettings(saved))
+}
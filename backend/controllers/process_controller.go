@@ -4,6 +4,7 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -104,6 +105,13 @@ func KillProcess(c *gin.Context) {
 		return
 	}
 
+	// 可选的终止信号：TERM 让进程正常退出，KILL 强制结束，不传时保持原有行为
+	signal := strings.ToUpper(strings.TrimSpace(c.Query("signal")))
+	if signal != "" && signal != "TERM" && signal != "KILL" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "信号只能是TERM或KILL"})
+		return
+	}
+
 	// 查找服务器
 	server, err := models.GetServerByID(uint(id))
 	if err != nil {
@@ -144,7 +152,8 @@ func KillProcess(c *gin.Context) {
 		"type":       "process_kill",
 		"request_id": requestID,
 		"payload": map[string]interface{}{
-			"pid": int32(pid),
+			"pid":    int32(pid),
+			"signal": signal,
 		},
 	}
 
@@ -187,4 +196,48 @@ func HandleProcessResponse(requestID string, response interface{}) {
 	default:
 		log.Printf("无法发送进程响应到通道，可能已关闭")
 	}
-} 
\ No newline at end of file
+} 
+// 按名称批量终止进程请求的响应通道
+var processKillByNameChannels sync.Map
+
+// KillProcessesByName 按名称或命令行模式批量终止进程。
+// dry_run=true 时只返回匹配的进程供预览；确认时需带上预览中选定的 pids，Agent 会重新匹配并跳过受保护的进程
+func KillProcessesByName(c *gin.Context) {
+	var req struct {
+		Pattern string  `json:"pattern"`
+		Cmdline bool    `json:"cmdline"`
+		DryRun  bool    `json:"dry_run"`
+		PIDs    []int32 `json:"pids"`
+		Signal  string  `json:"signal"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	if strings.TrimSpace(req.Pattern) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "匹配模式不能为空"})
+		return
+	}
+	signal := strings.ToUpper(strings.TrimSpace(req.Signal))
+	if signal != "" && signal != "TERM" && signal != "KILL" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "信号只能是TERM或KILL"})
+		return
+	}
+	if !req.DryRun && len(req.PIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请先预览并选择要终止的进程"})
+		return
+	}
+
+	requestAgentWithTimeout(c, "process_kill_by_name", &processKillByNameChannels, map[string]interface{}{
+		"pattern": req.Pattern,
+		"cmdline": req.Cmdline,
+		"dry_run": req.DryRun,
+		"pids":    req.PIDs,
+		"signal":  signal,
+	}, TimeoutProcessQuery)
+}
+
+// HandleProcessKillByNameResponse 将Agent的批量终止响应传递给等待中的HTTP请求
+func HandleProcessKillByNameResponse(requestID string, data map[string]interface{}) {
+	deliverAgentResponse(&processKillByNameChannels, requestID, data)
+}
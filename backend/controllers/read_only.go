@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+// 只读模式切换请求的响应通道
+var readOnlyModeChannels sync.Map
+
+// readOnlyPushTimeout 等待Agent确认只读模式的时间，旧版Agent不会回复，不宜等待过久
+var readOnlyPushTimeout = 5 * time.Second
+
+// SetServerReadOnly 开启或关闭服务器的只读模式。
+// 开启后面板拒绝该服务器的修改类操作，Agent 同时在本地拒绝所有修改类命令；
+// Agent 在线时立即推送，否则在 Agent 下一次拉取配置时生效
+func SetServerReadOnly(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	var req struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误，需要 enabled 字段"})
+		return
+	}
+
+	server, err := models.GetServerByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+
+	if err := models.DB.Model(&models.Server{}).Where("id = ?", server.ID).Update("read_only_mode", *req.Enabled).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新只读模式失败"})
+		return
+	}
+
+	body := gin.H{"read_only_mode": *req.Enabled, "pushed": false}
+	if _, ok := ActiveAgentConnections.Load(server.ID); ok {
+		response, _, err := callAgent(server.ID, "read_only_mode", &readOnlyModeChannels, map[string]interface{}{
+			"enabled": *req.Enabled,
+		}, readOnlyPushTimeout)
+		if err != nil {
+			// 旧版Agent不识别该命令，设置仍会在下一次拉取配置时下发
+			body["push_error"] = err.Error()
+		} else {
+			body["pushed"] = true
+			body["agent"] = response
+		}
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// HandleReadOnlyModeResponse 处理Agent对只读模式切换的确认
+func HandleReadOnlyModeResponse(requestID string, data map[string]interface{}) {
+	deliverAgentResponse(&readOnlyModeChannels, requestID, data)
+}
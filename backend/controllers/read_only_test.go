@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/middleware"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestReadOnlyModeGuardsOperations(t *testing.T) {
+	setupTestDB(t)
+	clearActiveConnections()
+	server := models.Server{Name: "db-01", AgentType: "full", SecretKey: "read-only-test"}
+	assert.NoError(t, models.DB.Create(&server).Error)
+	defer models.DB.Unscoped().Delete(&server)
+
+	r := gin.New()
+	api := r.Group("/api")
+	api.PUT("/servers/:id/read-only", SetServerReadOnly)
+	api.GET("/servers/:id/settings", GetAgentSettings)
+	ops := api.Group("/")
+	ops.Use(middleware.MonitorOnlyGuard())
+	ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{}) }
+	ops.GET("/servers/:id/files", ok)
+	ops.PUT("/servers/:id/files/content", ok)
+	ops.POST("/servers/:id/files/diff", ok)
+	ops.DELETE("/servers/:id/processes/:pid", ok)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+	base := "/api/servers/" + strconv.FormatUint(uint64(server.ID), 10)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodPut, base+"/files/content", "").Code)
+
+	w := do(http.MethodPut, base+"/read-only", `{"enabled":true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	// Agent 不在线时不推送，等待下一次拉取配置
+	assert.Equal(t, false, resp["pushed"])
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, base+"/files", "").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, base+"/files/diff", "").Code)
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPut, base+"/files/content", "").Code)
+	assert.Equal(t, http.StatusForbidden, do(http.MethodDelete, base+"/processes/1", "").Code)
+
+	w = do(http.MethodGet, base+"/settings", "")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, true, resp["read_only_mode"])
+	// 探测目标策略随检查列表下发，Agent 连接前按策略检查
+	if policy, ok := resp["probe_policy"].(map[string]interface{}); assert.True(t, ok) {
+		assert.Contains(t, policy, "allow")
+		assert.Contains(t, policy, "deny")
+	}
+
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, base+"/read-only", `{}`).Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodPut, base+"/read-only", `{"enabled":false}`).Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodPut, base+"/files/content", "").Code)
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxRegisterNameLength = 64
+	maxRegisterTagLength  = 32
+	maxServerTagsLength   = 255 // 与 Server.Tags 字段长度一致
+
+	// 环境和分组以带前缀的标签保存，便于按标签筛选
+	envTagPrefix   = "env:"
+	groupTagPrefix = "group:"
+)
+
+// registerLabels Agent 注册时提交的服务器名称和标签，批量接入时无需再逐台手动编辑
+type registerLabels struct {
+	Name        string   `json:"name"`
+	Tags        []string `json:"tags"`
+	Environment string   `json:"environment"`
+	Group       string   `json:"group"`
+}
+
+// normalize 去除首尾空白并校验取值
+func (l *registerLabels) normalize() error {
+	l.Name = strings.TrimSpace(l.Name)
+	if err := validateLabelValue("服务器名称", l.Name, maxRegisterNameLength); err != nil {
+		return err
+	}
+
+	tags := make([]string, 0, len(l.Tags))
+	for _, tag := range l.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if err := validateLabelValue("标签", tag, maxRegisterTagLength); err != nil {
+			return err
+		}
+		if strings.HasPrefix(tag, envTagPrefix) || strings.HasPrefix(tag, groupTagPrefix) {
+			return fmt.Errorf("标签 %q 使用了保留前缀，请通过 environment/group 字段设置", tag)
+		}
+		tags = append(tags, tag)
+	}
+	l.Tags = tags
+
+	l.Environment = strings.TrimSpace(l.Environment)
+	if err := validateLabelValue("环境", l.Environment, maxRegisterTagLength-len(envTagPrefix)); err != nil {
+		return err
+	}
+	l.Group = strings.TrimSpace(l.Group)
+	return validateLabelValue("分组", l.Group, maxRegisterTagLength-len(groupTagPrefix))
+}
+
+// mergeTags 将注册标签合并到服务器已有的标签（逗号分隔）中，
+// 环境和分组覆盖已有的同类标签，其余标签去重后追加
+func (l *registerLabels) mergeTags(existing string) (string, error) {
+	var merged []string
+	seen := make(map[string]bool)
+	add := func(tag string) {
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			merged = append(merged, tag)
+		}
+	}
+
+	for _, tag := range strings.Split(existing, ",") {
+		tag = strings.TrimSpace(tag)
+		if l.Environment != "" && strings.HasPrefix(tag, envTagPrefix) {
+			continue
+		}
+		if l.Group != "" && strings.HasPrefix(tag, groupTagPrefix) {
+			continue
+		}
+		add(tag)
+	}
+	for _, tag := range l.Tags {
+		add(tag)
+	}
+	if l.Environment != "" {
+		add(envTagPrefix + l.Environment)
+	}
+	if l.Group != "" {
+		add(groupTagPrefix + l.Group)
+	}
+
+	result := strings.Join(merged, ",")
+	if len(result) > maxServerTagsLength {
+		return "", fmt.Errorf("标签总长度超过 %d 个字符", maxServerTagsLength)
+	}
+	return result, nil
+}
+
+// validateLabelValue 校验名称/标签：不超过长度限制，不含控制字符和逗号（标签以逗号分隔保存）
+func validateLabelValue(field, value string, maxLength int) error {
+	if utf8.RuneCountInString(value) > maxLength {
+		return fmt.Errorf("%s不能超过 %d 个字符", field, maxLength)
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) || r == ',' {
+			return fmt.Errorf("%s %q 包含不允许的字符", field, value)
+		}
+	}
+	return nil
+}
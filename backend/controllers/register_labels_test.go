@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestRegisterLabelsMergeTags(t *testing.T) {
+	labels := registerLabels{
+		Name:        " web-01 ",
+		Tags:        []string{"nginx", " ", "web"},
+		Environment: "prod",
+		Group:       "asia",
+	}
+	assert.NoError(t, labels.normalize())
+	assert.Equal(t, "web-01", labels.Name)
+
+	// 环境/分组覆盖已有的同类标签，其余标签去重保留
+	tags, err := labels.mergeTags("web, env:dev,group:eu,db")
+	assert.NoError(t, err)
+	assert.Equal(t, "web,db,nginx,env:prod,group:asia", tags)
+
+	// 未提交标签时保持原样
+	empty := registerLabels{}
+	tags, err = empty.mergeTags("web,env:dev")
+	assert.NoError(t, err)
+	assert.Equal(t, "web,env:dev", tags)
+}
+
+func TestRegisterLabelsRejects(t *testing.T) {
+	tests := []registerLabels{
+		{Name: strings.Repeat("a", maxRegisterNameLength+1)},
+		{Name: "bad\nname"},
+		{Tags: []string{"a,b"}},
+		{Tags: []string{"env:prod"}},
+		{Environment: strings.Repeat("e", maxRegisterTagLength)},
+	}
+	for _, labels := range tests {
+		assert.Error(t, labels.normalize(), "%+v", labels)
+	}
+}
+
+func TestRegisterServerAppliesLabels(t *testing.T) {
+	db := setupTestDB(t)
+	server := models.Server{Name: "default", SecretKey: "register-labels-token", Tags: "env:dev"}
+	assert.NoError(t, db.Create(&server).Error)
+	t.Cleanup(func() { db.Unscoped().Delete(&models.Server{}, server.ID) })
+
+	register := func(body string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/agent/register", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request.RemoteAddr = "127.0.0.1:12345"
+		RegisterServer(c)
+
+		var resp map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, resp := register(`{"token":"register-labels-token","name":"web-01","tags":["nginx"],"environment":"prod","group":"asia"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, resp["success"])
+
+	updated, err := models.GetServerByID(server.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "web-01", updated.Name)
+	assert.Equal(t, "nginx,env:prod,group:asia", updated.Tags)
+
+	// 校验失败时不修改服务器信息
+	code, _ = register(`{"token":"register-labels-token","name":"bad\u0000name"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	updated, _ = models.GetServerByID(server.ID)
+	assert.Equal(t, "web-01", updated.Name)
+}
+
+func TestRegisterServerStoresSystemInfo(t *testing.T) {
+	db := setupTestDB(t)
+	server := models.Server{Name: "probe", SecretKey: "register-sysinfo-token", AgentType: "monitor"}
+	assert.NoError(t, db.Create(&server).Error)
+	t.Cleanup(func() { db.Unscoped().Delete(&models.Server{}, server.ID) })
+
+	body := `{"token":"register-sysinfo-token","system_info":{"hostname":"node-1","os":"linux","kernel_arch":"arm64",` +
+		`"cpu_cores":4,"memory_total":8589934592,"agent_type":"full","capabilities":{"docker":{"available":true}}}}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/agent/register", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.RemoteAddr = "127.0.0.1:12345"
+	RegisterServer(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	updated, err := models.GetServerByID(server.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "linux", updated.OS)
+	assert.Equal(t, "arm64", updated.Arch)
+	assert.Equal(t, "node-1", updated.Hostname)
+	assert.Equal(t, 4, updated.CPUCores)
+	assert.Equal(t, int64(8589934592), updated.MemoryTotal)
+	assert.Contains(t, updated.SystemInfo, `"capabilities"`)
+	// agent_type 以面板设置为准
+	assert.Equal(t, "monitor", updated.AgentType)
+
+	// 系统信息格式错误时返回错误，注册流程记录日志后忽略
+	_, err = registerSystemInfoUpdates([]byte(`"bad"`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// registerSystemInfoUpdates 解析 Agent 注册时附带的系统信息，返回需要写入服务器记录的字段。
+// 注册阶段 Agent 尚未建立 WebSocket 连接，预先写入操作系统、架构和能力等信息，
+// 使新接入的服务器在首次上报前就能在面板中正常展示；后续以 WebSocket 上报的系统信息为准
+func registerSystemInfoUpdates(raw json.RawMessage) (map[string]interface{}, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("解析系统信息失败: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	updates := map[string]interface{}{"system_info": string(raw)}
+	setString := func(key, column string) {
+		if val, ok := data[key].(string); ok && strings.TrimSpace(val) != "" {
+			updates[column] = strings.TrimSpace(val)
+		}
+	}
+	setString("os", "os")
+	setString("kernel_arch", "arch")
+	setString("cpu_model", "cpu_model")
+	setString("hostname", "hostname")
+	setString("agent_version", "agent_version")
+	setString("public_ip", "public_ip")
+
+	if cpuCores, ok := data["cpu_cores"].(float64); ok && cpuCores > 0 {
+		updates["cpu_cores"] = int(cpuCores)
+	}
+	if memoryTotal, ok := data["memory_total"].(float64); ok && memoryTotal > 0 {
+		updates["memory_total"] = int64(memoryTotal)
+	}
+	if diskTotal, ok := data["disk_total"].(float64); ok && diskTotal > 0 {
+		updates["disk_total"] = int64(diskTotal)
+	}
+
+	// 与 WebSocket 上报一致，agent_type 不在此更新，由 SwitchAgentType 和创建时管理
+	return updates, nil
+}
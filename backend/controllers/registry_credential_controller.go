@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+// passwordFieldPattern 匹配 JSON 中的 password 字段，用于在调试日志中隐藏凭据
+var passwordFieldPattern = regexp.MustCompile(`"password":"(?:[^"\\]|\\.)*"`)
+
+// redactPasswords 隐藏 JSON 文本中的密码
+func redactPasswords(data []byte) string {
+	return passwordFieldPattern.ReplaceAllString(string(data), `"password":"******"`)
+}
+
+// registryAuthFor 查找服务器拉取指定镜像时使用的仓库凭据，没有凭据或解密失败时返回 nil，
+// 由 Agent 按原有方式（匿名或本机 docker login）拉取
+func registryAuthFor(serverID uint, imageRef string) map[string]interface{} {
+	registry := models.ImageRegistry(imageRef)
+	cred, err := models.FindRegistryCredential(serverID, registry)
+	if err != nil || cred == nil {
+		if err != nil {
+			log.Printf("查询服务器 %d 的仓库 %s 凭据失败: %v", serverID, registry, err)
+		}
+		return nil
+	}
+	password, err := cred.DecryptPassword()
+	if err != nil {
+		log.Printf("解密仓库 %s 的凭据失败: %v", registry, err)
+		return nil
+	}
+	return map[string]interface{}{
+		"server_address": cred.Registry,
+		"username":       cred.Username,
+		"password":       password,
+	}
+}
+
+// withPullAuth 为镜像拉取流的 start 请求附加仓库凭据
+func withPullAuth(serverID uint, payload json.RawMessage) json.RawMessage {
+	var body map[string]interface{}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return payload
+	}
+	imageRef, _ := body["image"].(string)
+	auth := registryAuthFor(serverID, imageRef)
+	if auth == nil {
+		return payload
+	}
+	body["auth"] = auth
+	data, err := json.Marshal(body)
+	if err != nil {
+		return payload
+	}
+	return data
+}
+
+// registryCredentialRequest 新建或更新仓库凭据的请求体
+type registryCredentialRequest struct {
+	ServerID *uint  `json:"server_id"`
+	Registry string `json:"registry"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// ListRegistryCredentials 列出镜像仓库凭据（不返回密码）
+func ListRegistryCredentials(c *gin.Context) {
+	creds, err := models.ListRegistryCredentials()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取仓库凭据失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"credentials": creds})
+}
+
+// CreateRegistryCredential 新建镜像仓库凭据，server_id 为空时对所有服务器生效
+func CreateRegistryCredential(c *gin.Context) {
+	var req registryCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Username) == "" || req.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户名和密码不能为空"})
+		return
+	}
+	if req.ServerID != nil {
+		if _, err := models.GetServerByID(*req.ServerID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+			return
+		}
+	}
+
+	cred := models.RegistryCredential{
+		ServerID: req.ServerID,
+		Registry: req.Registry,
+		Username: strings.TrimSpace(req.Username),
+	}
+	if err := models.SaveRegistryCredential(&cred, req.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cred)
+}
+
+// UpdateRegistryCredential 更新镜像仓库凭据，密码为空时保留原密码
+func UpdateRegistryCredential(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的凭据ID"})
+		return
+	}
+	cred, err := models.GetRegistryCredential(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "凭据不存在"})
+		return
+	}
+
+	var req registryCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Username) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户名不能为空"})
+		return
+	}
+	if req.ServerID != nil {
+		if _, err := models.GetServerByID(*req.ServerID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+			return
+		}
+	}
+
+	cred.ServerID = req.ServerID
+	cred.Registry = req.Registry
+	cred.Username = strings.TrimSpace(req.Username)
+	if err := models.SaveRegistryCredential(cred, req.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cred)
+}
+
+// DeleteRegistryCredential 删除镜像仓库凭据
+func DeleteRegistryCredential(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的凭据ID"})
+		return
+	}
+	if err := models.DeleteRegistryCredential(uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除凭据失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "凭据已删除"})
+}
+
+// RegistryLogin 使用保存的凭据在服务器上执行 docker login，
+// 供 Compose 等直接调用 docker 命令的场景使用
+func RegistryLogin(c *gin.Context) {
+	serverID, err := parseServerId(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	var req struct {
+		Registry string `json:"registry"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	cred, err := models.FindRegistryCredential(serverID, req.Registry)
+	if err != nil || cred == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未找到该仓库的凭据"})
+		return
+	}
+	password, err := cred.DecryptPassword()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	sendDockerCommand(c, "registry", "login", map[string]interface{}{
+		"auth": map[string]interface{}{
+			"server_address": cred.Registry,
+			"username":       cred.Username,
+			"password":       password,
+		},
+	})
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestImageRegistry(t *testing.T) {
+	cases := map[string]string{
+		"nginx":                         "docker.io",
+		"library/nginx:1.25":            "docker.io",
+		"ghcr.io/org/app:1":             "ghcr.io",
+		"registry.example.com:5000/app": "registry.example.com:5000",
+		"localhost/app":                 "localhost",
+		"index.docker.io/library/redis": "docker.io",
+	}
+	for ref, want := range cases {
+		assert.Equal(t, want, models.ImageRegistry(ref), ref)
+	}
+	assert.Equal(t, "docker.io", models.NormalizeRegistry("https://index.docker.io/v1/"))
+	assert.Equal(t, "harbor.local", models.NormalizeRegistry(" HTTPS://Harbor.local/ "))
+}
+
+func TestRegistryCredentials(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.RegistryCredential{}))
+	assert.NoError(t, models.InitCredentialKey("registry-test-secret", ""))
+	defer models.DB.Where("1 = 1").Delete(&models.RegistryCredential{})
+
+	server := models.Server{Name: "registry-01", SecretKey: "registry-credential-test"}
+	assert.NoError(t, models.DB.Create(&server).Error)
+	defer models.DB.Unscoped().Delete(&server)
+
+	create := func(body map[string]interface{}) (int, map[string]interface{}) {
+		data, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+		c.Request.Header.Set("Content-Type", "application/json")
+		CreateRegistryCredential(c)
+		var resp map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, resp := create(map[string]interface{}{"registry": "ghcr.io", "username": "global", "password": "global-token"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotContains(t, resp, "password")
+
+	code, _ = create(map[string]interface{}{"registry": "https://ghcr.io/", "username": "dup", "password": "x"})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = create(map[string]interface{}{"server_id": server.ID, "registry": "ghcr.io", "username": "local", "password": "local-token"})
+	assert.Equal(t, http.StatusOK, code)
+
+	// 密码加密保存
+	var stored models.RegistryCredential
+	assert.NoError(t, models.DB.Where("username = ?", "local").First(&stored).Error)
+	assert.NotContains(t, stored.Password, "local-token")
+
+	// 服务器凭据优先于全局凭据
+	auth := registryAuthFor(server.ID, "ghcr.io/org/app:latest")
+	if assert.NotNil(t, auth) {
+		assert.Equal(t, "local", auth["username"])
+		assert.Equal(t, "local-token", auth["password"])
+	}
+	auth = registryAuthFor(server.ID+1000, "ghcr.io/org/app")
+	if assert.NotNil(t, auth) {
+		assert.Equal(t, "global-token", auth["password"])
+	}
+	assert.Nil(t, registryAuthFor(server.ID, "nginx"))
+
+	payload := withPullAuth(server.ID, json.RawMessage(`{"action":"start","stream_id":"s1","image":"ghcr.io/org/app"}`))
+	assert.Contains(t, string(payload), `"username":"local"`)
+	assert.Equal(t, `{"auth":{"password":"******"}}`, redactPasswords([]byte(`{"auth":{"password":"se\"cret"}}`)))
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+	"gorm.io/gorm"
+)
+
+// GetServerSensors 获取服务器最近一次上报的温度和风扇传感器读数
+func GetServerSensors(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	record, err := models.GetLatestSensorSample(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusOK, gin.H{"temperatures": []TemperaturePayload{}, "fans": []FanPayload{}})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取传感器读数失败"})
+		return
+	}
+
+	var sensors sensorRecord
+	if err := json.Unmarshal([]byte(record.Sensors), &sensors); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "解析传感器读数失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"timestamp":       record.Timestamp,
+		"max_temperature": record.MaxTemperature,
+		"temperatures":    sensors.Temperatures,
+		"fans":            sensors.Fans,
+	})
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestServerSensors(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.ServerMonitor{}, &models.TrafficHourly{}))
+	server := models.Server{Name: "nas-01", SecretKey: "sensor-test"}
+	assert.NoError(t, models.DB.Create(&server).Error)
+	defer models.DB.Unscoped().Delete(&server)
+	defer models.DB.Where("server_id = ?", server.ID).Delete(&models.ServerMonitor{})
+
+	query := func() map[string]json.RawMessage {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(server.ID), 10)}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		GetServerSensors(c)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]json.RawMessage
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+
+	// 没有上报过传感器时返回空列表
+	assert.Equal(t, "[]", string(query()["temperatures"]))
+
+	record, err := persistMonitorPayload(&server, &MonitorPayload{
+		Temperatures: []TemperaturePayload{
+			{Chip: "coretemp", Label: "Package id 0", Celsius: 71.5, High: 84, Critical: 100},
+			{Chip: "nvme", Label: "Composite", Celsius: 45},
+		},
+		Fans: []FanPayload{{Chip: "nct6775", Label: "fan1", RPM: 1250}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 71.5, record.MaxTemperature)
+	// 没有传感器的样本不覆盖最近一次读数
+	_, err = persistMonitorPayload(&server, &MonitorPayload{CPUUsage: 5})
+	assert.NoError(t, err)
+
+	resp := query()
+	assert.Equal(t, "71.5", string(resp["max_temperature"]))
+	var temps []TemperaturePayload
+	assert.NoError(t, json.Unmarshal(resp["temperatures"], &temps))
+	assert.Len(t, temps, 2)
+	var fans []FanPayload
+	assert.NoError(t, json.Unmarshal(resp["fans"], &fans))
+	if assert.Len(t, fans, 1) {
+		assert.Equal(t, 1250, fans[0].RPM)
+	}
+
+	assert.Equal(t, models.AlertCategoryResource, models.AlertCategoryOf("temperature"))
+}
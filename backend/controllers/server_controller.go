@@ -4,7 +4,9 @@ import (
 	cryptorand "crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net"
@@ -89,6 +91,7 @@ func GetAllServers(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取服务器列表失败"})
 		return
 	}
+	applyUserServerPreferences(currentUserID(c), servers)
 
 	c.JSON(http.StatusOK, gin.H{"servers": servers})
 }
@@ -131,7 +134,7 @@ func GetServerStatus(c *gin.Context) {
 	}
 
 	// 检查服务器是否真正在线 - 使用Online字段和心跳时间双重判断
-	isOnline := server.Online && time.Since(server.LastHeartbeat) <= 15*time.Second
+	isOnline := server.Online && time.Since(server.LastHeartbeat) <= models.HeartbeatTimeout(server)
 
 	// 如果数据库状态不一致，确保更新数据库
 	if isOnline != (server.Status == "online") {
@@ -200,9 +203,7 @@ func GetPublicServerMonitor(c *gin.Context) {
 	log.Printf("[DEBUG] 公开监控数据查询: server_id=%d, hours=%d, 数据条数=%d", id, hours, len(data))
 
 	// 如果数据量太大，进行采样
-	if len(data) > 500 {
-		data = sampleMonitorData(data, 500)
-	}
+	data = models.DownsampleMonitorData(data, 500)
 
 	c.JSON(http.StatusOK, gin.H{"data": data})
 }
@@ -227,6 +228,8 @@ func UpdateServer(c *gin.Context) {
 		Notes       string `json:"notes"`       // 前端发送的字段名
 		Description string `json:"description"` // 也支持直接的description字段
 		Tags        string `json:"tags"`
+		// 每月清零总流量的日期，0 表示不清零；使用指针区分"未提交"和"关闭"
+		TrafficResetDay *int `json:"traffic_reset_day"`
 	}
 
 	if err := c.ShouldBindJSON(&updateData); err != nil {
@@ -234,6 +237,19 @@ func UpdateServer(c *gin.Context) {
 		return
 	}
 
+	if day := updateData.TrafficResetDay; day != nil {
+		if *day < 0 || *day > 31 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "流量重置日必须在 1-31 之间，0 表示不重置"})
+			return
+		}
+		if *day != server.TrafficResetDay {
+			// 从修改时开始计算当前周期，避免修改后立即清零
+			now := time.Now()
+			server.TrafficResetDay = *day
+			server.TrafficResetAt = &now
+		}
+	}
+
 	// 更新服务器字段
 	if updateData.Name != "" {
 		server.Name = updateData.Name
@@ -337,6 +353,10 @@ func SwitchAgentType(c *gin.Context) {
 			targetVersion = ri.Version
 		}
 	}
+	if settings != nil && strings.TrimSpace(settings.AgentPinnedVersion) != "" {
+		// 固定了版本时只切换变体，不改变版本
+		targetVersion = strings.TrimSpace(settings.AgentPinnedVersion)
+	}
 	if targetVersion == "" {
 		// 无法获取最新版本时，使用 Agent 当前版本号（触发同版本的变体切换）
 		targetVersion = server.AgentVersion
@@ -506,7 +526,7 @@ func GetServerMonitor(c *gin.Context) {
 
 	// 如果数据量太大，需要进行采样
 	if len(data) > 1000 {
-		data = sampleMonitorData(data, 1000)
+		data = models.DownsampleMonitorData(data, 1000)
 		log.Printf("[DEBUG] 采样后剩余数据点: %d", len(data))
 	}
 
@@ -526,37 +546,118 @@ func GetServerMonitor(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": data})
 }
 
-// sampleMonitorData 对监控数据进行采样，减少数据点数量
-func sampleMonitorData(data []models.ServerMonitor, targetPoints int) []models.ServerMonitor {
-	dataLen := len(data)
-	if dataLen <= targetPoints {
-		return data
+// 历史监控查询的数据点上限
+const (
+	defaultHistoryMaxPoints = 500
+	maxHistoryMaxPoints     = 5000
+	maxHistoryRange         = 31 * 24 * time.Hour
+)
+
+// GetServerMonitorHistory 按时间范围获取服务器监控历史数据
+// 查询参数：
+//   - from / to: 起止时间，支持 RFC3339 或 Unix 秒级时间戳，to 默认为当前时间
+//   - range: 相对时间窗口（如 1h、30m），未指定 from 时使用，默认 1h
+//   - max_points: 最多返回的数据点数，超过时降采样，默认 500
+func GetServerMonitorHistory(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
 	}
 
-	// 计算采样间隔
-	interval := float64(dataLen) / float64(targetPoints)
-	sampledData := make([]models.ServerMonitor, 0, targetPoints)
+	endTime := time.Now()
+	if toStr := c.Query("to"); toStr != "" {
+		if endTime, err = parseHistoryTime(toStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的结束时间格式"})
+			return
+		}
+	}
 
-	for i := 0; i < targetPoints; i++ {
-		idx := int(float64(i) * interval)
-		if idx >= dataLen {
-			idx = dataLen - 1
+	var startTime time.Time
+	if fromStr := c.Query("from"); fromStr != "" {
+		if startTime, err = parseHistoryTime(fromStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的开始时间格式"})
+			return
+		}
+	} else {
+		window, err := time.ParseDuration(c.DefaultQuery("range", "1h"))
+		if err != nil || window <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的时间范围"})
+			return
 		}
-		sampledData = append(sampledData, data[idx])
+		startTime = endTime.Add(-window)
+	}
+
+	if !startTime.Before(endTime) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "开始时间必须早于结束时间"})
+		return
+	}
+	if endTime.Sub(startTime) > maxHistoryRange {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "查询时间范围不能超过31天"})
+		return
+	}
+
+	maxPoints, err := strconv.Atoi(c.DefaultQuery("max_points", strconv.Itoa(defaultHistoryMaxPoints)))
+	if err != nil || maxPoints <= 0 {
+		maxPoints = defaultHistoryMaxPoints
+	}
+	if maxPoints > maxHistoryMaxPoints {
+		maxPoints = maxHistoryMaxPoints
 	}
 
-	return sampledData
+	data, err := models.GetMonitorDataRange(id, startTime, endTime, maxPoints)
+	if err != nil {
+		log.Printf("[ERROR] 获取服务器ID=%d历史监控数据失败: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取监控数据失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       data,
+		"from":       startTime.Unix(),
+		"to":         endTime.Unix(),
+		"max_points": maxPoints,
+	})
+}
+
+// parseHistoryTime 解析 RFC3339 或 Unix 秒级时间戳
+func parseHistoryTime(value string) (time.Time, error) {
+	if ts, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(ts, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
 }
 
 // RegisterServer 处理Agent自动注册
+// 请求体可携带服务器名称、标签、环境和分组，注册成功后写入服务器信息
 func RegisterServer(c *gin.Context) {
-	// 获取并验证令牌
+	var req struct {
+		Token      string          `json:"token"`
+		Hostname   string          `json:"hostname"`
+		SystemInfo json.RawMessage `json:"system_info"`
+		registerLabels
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的注册请求"})
+		return
+	}
+
+	// 获取并验证令牌（兼容请求头和请求体两种方式）
 	token := c.GetHeader("X-Register-Token")
+	if token == "" {
+		token = req.Token
+	}
 	if token == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "注册令牌不能为空"})
 		return
 	}
 
+	labels := req.registerLabels
+	if err := labels.normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 查找匹配的服务器
 	servers, err := models.GetAllServers(0)
 	if err != nil {
@@ -598,14 +699,55 @@ func RegisterServer(c *gin.Context) {
 		return
 	}
 
+	// 写入Agent提交的名称和标签
+	tags, err := labels.mergeTags(matchedServer.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	labelUpdates := map[string]interface{}{}
+	if labels.Name != "" && labels.Name != matchedServer.Name {
+		labelUpdates["name"] = labels.Name
+		matchedServer.Name = labels.Name
+	}
+	if tags != matchedServer.Tags {
+		labelUpdates["tags"] = tags
+		matchedServer.Tags = tags
+	}
+	if len(labelUpdates) > 0 {
+		if err := models.DB.Model(&models.Server{}).Where("id = ?", matchedServer.ID).Updates(labelUpdates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新服务器名称和标签失败"})
+			return
+		}
+		log.Printf("服务器 %d 注册时更新名称/标签: %v", matchedServer.ID, labelUpdates)
+	}
+
+	// 写入Agent注册时探测的系统信息，失败不影响注册
+	geoIP := clientIP
+	if len(req.SystemInfo) > 0 {
+		infoUpdates, err := registerSystemInfoUpdates(req.SystemInfo)
+		if err != nil {
+			log.Printf("服务器 %d 注册时%v", matchedServer.ID, err)
+		} else if len(infoUpdates) > 0 {
+			if err := models.DB.Model(&models.Server{}).Where("id = ?", matchedServer.ID).Updates(infoUpdates).Error; err != nil {
+				log.Printf("服务器 %d 注册时写入系统信息失败: %v", matchedServer.ID, err)
+			} else if publicIP, ok := infoUpdates["public_ip"].(string); ok {
+				geoIP = publicIP
+			}
+		}
+	}
+
 	// 异步更新国家代码
-	go updateServerCountry(matchedServer.ID, clientIP)
+	go updateServerCountry(matchedServer.ID, geoIP)
 
 	// 返回服务器信息
 	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
 		"message":    "注册成功",
 		"server_id":  matchedServer.ID,
 		"secret_key": matchedServer.SecretKey,
+		"name":       matchedServer.Name,
+		"tags":       matchedServer.Tags,
 	})
 }
 
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestGetServerMonitorHistory(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.ServerMonitor{}))
+	db.Exec("DELETE FROM server_monitors")
+
+	// 写入最近 100 分钟、每分钟一条的监控数据
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, db.Create(&models.ServerMonitor{
+			ServerID:  1,
+			Timestamp: now.Add(-time.Duration(100-i) * time.Minute),
+			CPUUsage:  float64(i),
+		}).Error)
+	}
+
+	request := func(query string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: "1"}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/servers/1/monitor/history?"+query, nil)
+		GetServerMonitorHistory(c)
+
+		var resp map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	// 最近 1 小时的数据不超过 max_points，且保留最新一条
+	code, resp := request("range=1h&max_points=10")
+	assert.Equal(t, http.StatusOK, code)
+	data := resp["data"].([]interface{})
+	assert.Len(t, data, 10)
+	last := data[len(data)-1].(map[string]interface{})
+	assert.Equal(t, 99.0, last["cpu_usage"])
+
+	// 指定起止时间
+	from := now.Add(-30 * time.Minute).Unix()
+	code, resp = request(fmt.Sprintf("from=%d&to=%d&max_points=1000", from, now.Unix()))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, resp["data"].([]interface{}), 30)
+
+	// 非法参数
+	code, _ = request("from=bad")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = request(fmt.Sprintf("from=%d&to=%d", now.Unix(), from))
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestDownsampleMonitorData(t *testing.T) {
+	data := make([]models.ServerMonitor, 10)
+	for i := range data {
+		data[i].ID = uint(i)
+	}
+	ids := func(sampled []models.ServerMonitor) []uint {
+		var out []uint
+		for _, d := range sampled {
+			out = append(out, d.ID)
+		}
+		return out
+	}
+
+	// 不超过上限或上限无效时原样返回
+	assert.Len(t, models.DownsampleMonitorData(data, 10), 10)
+	assert.Len(t, models.DownsampleMonitorData(data, 0), 10)
+	// 等间隔采样，首尾两个点都保留
+	assert.Equal(t, []uint{0, 3, 6, 9}, ids(models.DownsampleMonitorData(data, 4)))
+	assert.Equal(t, []uint{0, 9}, ids(models.DownsampleMonitorData(data, 2)))
+	assert.Equal(t, []uint{9}, ids(models.DownsampleMonitorData(data, 1)))
+}
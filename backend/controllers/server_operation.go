@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+// GetServerOperations 获取服务器最近的操作记录（Docker、文件、终端、进程、Nginx 等），
+// 可通过 category 参数按类别过滤
+func GetServerOperations(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > models.ServerOperationLimit {
+		limit = 50
+	}
+
+	operations, err := models.GetRecentServerOperations(id, c.Query("category"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取操作记录失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"operations": operations})
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/middleware"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestServerOperationsRecorded(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.ServerOperation{}))
+	db.Exec("DELETE FROM server_operations")
+	defer db.Exec("DELETE FROM server_operations")
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api")
+	api.Use(func(c *gin.Context) {
+		c.Set("userId", uint(3))
+		c.Set("username", "alice")
+	})
+	ops := api.Group("/")
+	ops.Use(middleware.RecordServerOperations())
+	ops.POST("/servers/:id/nginx/restart", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	})
+	ops.DELETE("/servers/:id/docker/containers/:container_id", func(c *gin.Context) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "容器不存在"})
+	})
+	ops.GET("/servers/:id/files", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	api.GET("/servers/:id/operations", GetServerOperations)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+	do(http.MethodPost, "/api/servers/9/nginx/restart")
+	do(http.MethodDelete, "/api/servers/9/docker/containers/abc")
+	do(http.MethodGet, "/api/servers/9/files")
+	do(http.MethodPost, "/api/servers/10/nginx/restart")
+
+	w := do(http.MethodGet, "/api/servers/9/operations")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Operations []models.ServerOperation `json:"operations"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	// 只记录修改类请求，按时间倒序
+	if assert.Len(t, resp.Operations, 2) {
+		latest := resp.Operations[0]
+		assert.Equal(t, "docker", latest.Category)
+		assert.Equal(t, "DELETE /docker/containers/:container_id", latest.Action)
+		assert.Equal(t, "container_id=abc", latest.Target)
+		assert.False(t, latest.Success)
+		assert.Equal(t, "容器不存在", latest.Error)
+		assert.Equal(t, "alice", latest.Username)
+		assert.Equal(t, uint(3), latest.UserID)
+
+		assert.Equal(t, "nginx", resp.Operations[1].Category)
+		assert.True(t, resp.Operations[1].Success)
+	}
+
+	w = do(http.MethodGet, "/api/servers/9/operations?category=nginx")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Operations, 1)
+
+	// 超出上限后只保留最近的记录
+	for i := 0; i < models.ServerOperationLimit+5; i++ {
+		assert.NoError(t, models.RecordServerOperation(&models.ServerOperation{ServerID: 11, Category: "process"}))
+	}
+	var count int64
+	db.Model(&models.ServerOperation{}).Where("server_id = ?", 11).Count(&count)
+	assert.Equal(t, int64(models.ServerOperationLimit), count)
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+// currentUserID 获取当前登录用户的ID，未认证时返回 0
+func currentUserID(c *gin.Context) uint {
+	value, ok := c.Get("userId")
+	if !ok {
+		return 0
+	}
+	userID, _ := value.(uint)
+	return userID
+}
+
+// applyUserServerPreferences 按用户的收藏和自定义顺序调整服务器列表，读取偏好失败时保持全局顺序
+func applyUserServerPreferences(userID uint, servers []models.Server) {
+	if userID == 0 {
+		return
+	}
+	prefs, err := models.GetUserServerPreferences(userID)
+	if err != nil {
+		log.Printf("获取用户 %d 的服务器偏好失败: %v", userID, err)
+		return
+	}
+	models.ApplyServerPreferences(servers, prefs)
+}
+
+// SetServerFavorite 收藏或取消收藏服务器，只影响当前用户的服务器列表
+func SetServerFavorite(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
+		return
+	}
+	serverID, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+	var req struct {
+		Favorite bool `json:"favorite"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	if _, err := models.GetServerByID(serverID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+
+	if err := models.SetServerFavorite(userID, serverID, req.Favorite); err != nil {
+		log.Printf("更新服务器收藏失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新收藏失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"server_id": serverID, "favorite": req.Favorite})
+}
+
+// SaveMyServerOrder 保存当前用户的自定义服务器顺序，不影响其他用户和全局顺序
+func SaveMyServerOrder(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
+		return
+	}
+	var req struct {
+		OrderedIDs []uint `json:"orderedIds" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.OrderedIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "服务器ID列表不能为空"})
+		return
+	}
+
+	seen := make(map[uint]bool, len(req.OrderedIDs))
+	for _, id := range req.OrderedIDs {
+		if seen[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "服务器ID列表包含重复项"})
+			return
+		}
+		seen[id] = true
+	}
+	var count int64
+	if err := models.DB.Model(&models.Server{}).Where("id IN ?", req.OrderedIDs).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "验证服务器ID失败"})
+		return
+	}
+	if int(count) != len(req.OrderedIDs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "部分服务器ID不存在"})
+		return
+	}
+
+	if err := models.SaveUserServerOrder(userID, req.OrderedIDs); err != nil {
+		log.Printf("保存用户 %d 的服务器顺序失败: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存服务器顺序失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "服务器顺序已保存"})
+}
+
+// ResetMyServerOrder 清除当前用户的自定义顺序，恢复全局顺序（保留收藏）
+func ResetMyServerOrder(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
+		return
+	}
+	if err := models.ResetUserServerOrder(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重置服务器顺序失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已恢复默认顺序"})
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestServerPreferencesArePerUser(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.UserServerPreference{}))
+	db.Exec("DELETE FROM servers")
+	db.Exec("DELETE FROM user_server_preferences")
+	defer db.Exec("DELETE FROM servers")
+
+	var ids []uint
+	for i, name := range []string{"web", "db", "cache", "backup"} {
+		server := models.Server{Name: name, SortOrder: i + 1}
+		assert.NoError(t, db.Create(&server).Error)
+		ids = append(ids, server.ID)
+	}
+
+	call := func(userID uint, handler gin.HandlerFunc, method, body string, params gin.Params) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("userId", userID)
+		c.Params = params
+		c.Request = httptest.NewRequest(method, "/api/servers", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler(c)
+		return w
+	}
+	listNames := func(userID uint) []string {
+		w := call(userID, GetAllServers, http.MethodGet, "", nil)
+		var resp struct {
+			Servers []models.Server `json:"servers"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		var names []string
+		for _, s := range resp.Servers {
+			name := s.Name
+			if s.Favorite {
+				name += "*"
+			}
+			names = append(names, name)
+		}
+		return names
+	}
+
+	// 用户1：自定义顺序 backup, web，并收藏 cache
+	w := call(1, SaveMyServerOrder, http.MethodPut, `{"orderedIds":[`+uintList(ids[3], ids[0])+`]}`, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = call(1, SetServerFavorite, http.MethodPut, `{"favorite":true}`, gin.Params{{Key: "id", Value: uintList(ids[2])}})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 收藏在前，其次是自定义顺序，其余保持全局顺序
+	assert.Equal(t, []string{"cache*", "backup", "web", "db"}, listNames(1))
+	// 其他用户不受影响
+	assert.Equal(t, []string{"web", "db", "cache", "backup"}, listNames(2))
+
+	// 重置顺序后保留收藏
+	w = call(1, ResetMyServerOrder, http.MethodDelete, "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"cache*", "web", "db", "backup"}, listNames(1))
+
+	w = call(1, SaveMyServerOrder, http.MethodPut, `{"orderedIds":[`+uintList(ids[0], ids[0])+`]}`, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func uintList(ids ...uint) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		b, _ := json.Marshal(id)
+		parts[i] = string(b)
+	}
+	return strings.Join(parts, ",")
+}
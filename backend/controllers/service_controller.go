@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 服务管理请求的响应通道
+var serviceCommandChannels sync.Map
+
+// 与Agent一致的 systemd 单元名校验，提前拒绝明显无效的服务名
+var serviceNamePattern = regexp.MustCompile(`^[A-Za-z0-9:_.@\\][A-Za-z0-9:_.@\\-]*$`)
+
+// serviceControlActions 支持的服务操作
+var serviceControlActions = map[string]bool{
+	"start": true, "stop": true, "restart": true, "enable": true, "disable": true,
+}
+
+// GetServices 获取服务器上的 systemd 服务列表
+func GetServices(c *gin.Context) {
+	requestAgent(c, "service_command", &serviceCommandChannels, map[string]interface{}{
+		"action": "list",
+	})
+}
+
+// GetServiceStatus 获取单个服务的详细状态
+func GetServiceStatus(c *gin.Context) {
+	name, ok := serviceNameParam(c)
+	if !ok {
+		return
+	}
+	requestAgent(c, "service_command", &serviceCommandChannels, map[string]interface{}{
+		"action": "status",
+		"name":   name,
+	})
+}
+
+// GetServiceLogs 读取服务最近的日志（journalctl），查询参数 lines 指定行数，省略时使用Agent的默认值
+func GetServiceLogs(c *gin.Context) {
+	name, ok := serviceNameParam(c)
+	if !ok {
+		return
+	}
+	payload := map[string]interface{}{
+		"action": "logs",
+		"name":   name,
+	}
+	if raw := c.Query("lines"); raw != "" {
+		lines, err := strconv.Atoi(raw)
+		if err != nil || lines < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的参数: lines"})
+			return
+		}
+		payload["lines"] = lines
+	}
+	requestAgent(c, "service_command", &serviceCommandChannels, payload)
+}
+
+// ControlService 启动、停止、重启服务或设置开机启动，返回操作后的服务状态
+func ControlService(c *gin.Context) {
+	name, ok := serviceNameParam(c)
+	if !ok {
+		return
+	}
+	action := strings.ToLower(c.Param("action"))
+	if !serviceControlActions[action] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的服务操作: " + action})
+		return
+	}
+	// 服务启停需要等待 systemd 完成状态切换，可能较慢
+	requestAgentWithTimeout(c, "service_command", &serviceCommandChannels, map[string]interface{}{
+		"action": action,
+		"name":   name,
+	}, TimeoutLongOperation)
+}
+
+// serviceNameParam 读取并校验路径中的服务名，无效时返回 400
+func serviceNameParam(c *gin.Context) (string, bool) {
+	name := strings.TrimSpace(c.Param("name"))
+	if name == "" || len(name) > 256 || !serviceNamePattern.MatchString(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务名"})
+		return "", false
+	}
+	return name, true
+}
+
+// HandleServiceCommandResponse 将Agent的服务管理响应传递给等待中的HTTP请求
+func HandleServiceCommandResponse(requestID string, data map[string]interface{}) {
+	deliverAgentResponse(&serviceCommandChannels, requestID, data)
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestControlService(t *testing.T) {
+	setupTestDB(t)
+	server := models.Server{Name: "service-agent", Status: "online", SecretKey: "secret"}
+	assert.NoError(t, models.DB.Create(&server).Error)
+	t.Cleanup(func() { models.DB.Unscoped().Delete(&server) })
+
+	payloads := make(chan map[string]interface{}, 4)
+	connectReverseAgent(t, server.ID, func(msg map[string]interface{}) map[string]interface{} {
+		payload, _ := msg["payload"].(map[string]interface{})
+		payloads <- payload
+		if payload["action"] == "stop" {
+			return map[string]interface{}{
+				"type": "service_command_response",
+				"data": map[string]interface{}{"error": "Failed to stop nginx.service: Access denied"},
+			}
+		}
+		return map[string]interface{}{
+			"type": "service_command_response",
+			"data": map[string]interface{}{"service": map[string]interface{}{"name": "nginx.service"}},
+		}
+	})
+
+	serve := func(name, action string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+		c.Params = gin.Params{
+			{Key: "id", Value: strconv.FormatUint(uint64(server.ID), 10)},
+			{Key: "name", Value: name},
+			{Key: "action", Value: action},
+		}
+		ControlService(c)
+		return w
+	}
+
+	w := serve("nginx", "Restart")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, map[string]interface{}{"action": "restart", "name": "nginx"}, <-payloads)
+
+	w = serve("nginx", "stop")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Access denied")
+	<-payloads
+
+	// 无效的服务名和操作不会发送到Agent
+	assert.Equal(t, http.StatusBadRequest, serve("--all", "stop").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("nginx", "mask").Code)
+	assert.Empty(t, payloads)
+}
@@ -1,9 +1,11 @@
 package controllers
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/user/server-ops-backend/config"
@@ -52,6 +54,47 @@ func UpdateSystemSettings(c *gin.Context) {
 	})
 }
 
+// CheckProbeTarget 按当前（或请求中待保存的）探测目标策略检查目标是否允许探测，
+// 主机名会被解析，任一解析结果被禁止即视为不允许
+func CheckProbeTarget(c *gin.Context) {
+	var req struct {
+		Host         string  `json:"host" binding:"required"`
+		Port         int     `json:"port" binding:"required"`
+		AllowTargets *string `json:"probe_allow_targets"`
+		DenyTargets  *string `json:"probe_deny_targets"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "请指定主机和端口"})
+		return
+	}
+
+	settings, err := models.GetSettings()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "获取系统设置失败"})
+		return
+	}
+	allow, deny := settings.ProbeAllowTargets, settings.ProbeDenyTargets
+	if req.AllowTargets != nil {
+		allow = *req.AllowTargets
+	}
+	if req.DenyTargets != nil {
+		deny = *req.DenyTargets
+	}
+	policy, err := models.NewProbeTargetPolicy(allow, deny)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "探测目标策略无效: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+	if err := policy.CheckHost(ctx, req.Host, req.Port); err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": true, "allowed": false, "reason": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "allowed": true})
+}
+
 // GetAgentSettings 获取Agent设置接口 (供Agent使用)
 func GetAgentSettings(c *gin.Context) {
 	serverId := c.Param("id")
@@ -84,6 +127,16 @@ func GetAgentSettings(c *gin.Context) {
 		"agent_release_repo":    settings.AgentReleaseRepo,
 		"agent_release_channel": settings.AgentReleaseChannel,
 		"agent_release_mirror":  settings.AgentReleaseMirror,
+		"agent_pinned_version":  settings.AgentPinnedVersion,
+		"transfer_rate_limit":   settings.TransferRateLimit,
+		"agent_bandwidth_limit": settings.AgentBandwidthLimit,
+		"top_process_count":     settings.TopProcessCount,
+		"read_only_mode":        server.ReadOnlyMode,
+		"uptime_checks":         agentUptimeChecks(server.ID),
+		"probe_policy":          gin.H{"allow": settings.ProbeAllowTargets, "deny": settings.ProbeDenyTargets},
+		"mesh":                  agentMeshConfig(server.ID, settings.MeshInterval),
+		"log_paths":             splitLogPaths(server.LogPaths),
+		"check_scripts":         agentCheckScripts(server),
 	})
 }
 
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestTCPStatesInMonitorData(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.ServerMonitor{}, &models.TrafficHourly{}))
+	server := models.Server{Name: "web", SecretKey: "tcp-states-test"}
+	assert.NoError(t, db.Create(&server).Error)
+	defer db.Unscoped().Delete(&server)
+	defer db.Where("server_id = ?", server.ID).Delete(&models.ServerMonitor{})
+
+	record, err := persistMonitorPayload(&server, &MonitorPayload{
+		TCPConnections: 130,
+		TCPStates:      map[string]int{"ESTABLISHED": 100, "TIME_WAIT": 25, "LISTEN": 5},
+	})
+	assert.NoError(t, err)
+
+	var saved models.ServerMonitor
+	assert.NoError(t, db.First(&saved, record.ID).Error)
+	var states map[string]int
+	assert.NoError(t, json.Unmarshal([]byte(saved.TCPStates), &states))
+	assert.Equal(t, 25, states["TIME_WAIT"])
+
+	payload, err := json.Marshal(buildMonitorData(&server, record))
+	assert.NoError(t, err)
+	var data struct {
+		TCPStates map[string]int `json:"tcp_states"`
+	}
+	assert.NoError(t, json.Unmarshal(payload, &data))
+	assert.Equal(t, 100, data.TCPStates["ESTABLISHED"])
+
+	// 旧版 Agent 不上报连接状态时不带该字段
+	record, err = persistMonitorPayload(&server, &MonitorPayload{TCPConnections: 3})
+	assert.NoError(t, err)
+	assert.NotContains(t, buildMonitorData(&server, record), "tcp_states")
+}
+
+func TestGetNetstat_InvalidParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, query := range []string{"listening=maybe", "limit=0", "limit=abc"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: "1"}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/servers/1/netstat?"+query, nil)
+
+		GetNetstat(c)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+	"gorm.io/gorm"
+)
+
+// 查找进程排行时允许与指定时间相差的最长时间
+const topProcessSearchWindow = 10 * time.Minute
+
+// GetServerTopProcesses 获取服务器在指定时间（at，Unix 毫秒，默认当前时间）附近的进程排行，
+// 用于查看历史上某一时刻 CPU 和内存被哪些进程占用
+func GetServerTopProcesses(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	at := time.Now()
+	if raw := c.Query("at"); raw != "" {
+		ms, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || ms <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的时间"})
+			return
+		}
+		at = time.UnixMilli(ms)
+	}
+
+	record, err := models.FindTopProcessSample(id, at, topProcessSearchWindow)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "该时间附近没有进程排行记录，请确认已在系统设置中开启进程排行"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取进程排行失败"})
+		return
+	}
+
+	var top topProcessRecord
+	if err := json.Unmarshal([]byte(record.TopProcesses), &top); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "解析进程排行失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"timestamp":   record.Timestamp,
+		"cpu_usage":   record.CPUUsage,
+		"memory_used": record.MemoryUsed,
+		"top_cpu":     top.CPU,
+		"top_memory":  top.Memory,
+	})
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestServerTopProcessesHistory(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.ServerMonitor{}, &models.TrafficHourly{}))
+	server := models.Server{Name: "db-01", SecretKey: "top-process-test"}
+	assert.NoError(t, models.DB.Create(&server).Error)
+	defer models.DB.Unscoped().Delete(&server)
+	defer models.DB.Where("server_id = ?", server.ID).Delete(&models.ServerMonitor{})
+
+	_, err := persistMonitorPayload(&server, &MonitorPayload{
+		CPUUsage:  97,
+		TopCPU:    []TopProcessPayload{{PID: 812, Name: "mysqld", CPUPercent: 180.5, MemoryRSS: 4 << 30}},
+		TopMemory: []TopProcessPayload{{PID: 812, Name: "mysqld", CPUPercent: 180.5, MemoryRSS: 4 << 30}},
+	})
+	assert.NoError(t, err)
+	// 没有进程排行的样本不参与查找
+	_, err = persistMonitorPayload(&server, &MonitorPayload{CPUUsage: 5})
+	assert.NoError(t, err)
+
+	query := func(at time.Time) (int, map[string]json.RawMessage) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(server.ID), 10)}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/?at="+strconv.FormatInt(at.UnixMilli(), 10), nil)
+		GetServerTopProcesses(c)
+		var resp map[string]json.RawMessage
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, resp := query(time.Now().Add(3 * time.Minute))
+	assert.Equal(t, http.StatusOK, code)
+	var top []TopProcessPayload
+	assert.NoError(t, json.Unmarshal(resp["top_cpu"], &top))
+	if assert.Len(t, top, 1) {
+		assert.Equal(t, "mysqld", top[0].Name)
+	}
+	assert.Equal(t, "97", string(resp["cpu_usage"]))
+
+	code, _ = query(time.Now().Add(-time.Hour))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// 进程排行不出现在监控记录的 JSON 中（公开探针接口直接返回监控记录）
+	var record models.ServerMonitor
+	assert.NoError(t, models.DB.Where("server_id = ? AND top_processes <> ''", server.ID).First(&record).Error)
+	data, _ := json.Marshal(record)
+	assert.NotContains(t, string(data), "mysqld")
+}
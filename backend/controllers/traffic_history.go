@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+// 各汇总粒度未指定 from 时的默认时间范围
+var trafficDefaultRanges = map[string]time.Duration{
+	"hour":  24 * time.Hour,
+	"day":   30 * 24 * time.Hour,
+	"month": 365 * 24 * time.Hour,
+}
+
+// GetServerTrafficHistory 获取服务器的流量历史
+// 查询参数：
+//   - granularity: hour / day / month，默认 hour；天和月按面板时区划分
+//   - from / to: 起止时间，支持 RFC3339 或 Unix 秒级时间戳，to 默认为当前时间
+//   - range: 相对时间窗口（如 48h），未指定 from 时使用，默认按粒度取 24h / 30 天 / 365 天
+func GetServerTrafficHistory(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	granularity := c.DefaultQuery("granularity", "hour")
+	defaultRange, ok := trafficDefaultRanges[granularity]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的汇总粒度，可选 hour/day/month"})
+		return
+	}
+
+	endTime := time.Now()
+	if toStr := c.Query("to"); toStr != "" {
+		if endTime, err = parseHistoryTime(toStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的结束时间格式"})
+			return
+		}
+	}
+
+	var startTime time.Time
+	if fromStr := c.Query("from"); fromStr != "" {
+		if startTime, err = parseHistoryTime(fromStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的开始时间格式"})
+			return
+		}
+	} else {
+		window := defaultRange
+		if rangeStr := c.Query("range"); rangeStr != "" {
+			if window, err = time.ParseDuration(rangeStr); err != nil || window <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "无效的时间范围"})
+				return
+			}
+		}
+		startTime = endTime.Add(-window)
+	}
+
+	if !startTime.Before(endTime) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "开始时间必须早于结束时间"})
+		return
+	}
+	if endTime.Sub(startTime) > models.TrafficHourlyRetention {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "查询时间范围不能超过400天"})
+		return
+	}
+
+	rows, err := models.GetTrafficHourly(id, startTime, endTime)
+	if err != nil {
+		log.Printf("[ERROR] 获取服务器ID=%d流量历史失败: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取流量历史失败"})
+		return
+	}
+
+	var totalIn, totalOut uint64
+	for _, row := range rows {
+		totalIn += row.BytesIn
+		totalOut += row.BytesOut
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"granularity": granularity,
+		"data":        models.AggregateTraffic(rows, granularity, time.Local),
+		"total_in":    totalIn,
+		"total_out":   totalOut,
+		"from":        startTime.Unix(),
+		"to":          endTime.Unix(),
+	})
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestServerTrafficHistory(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.TrafficHourly{}))
+	db.Exec("DELETE FROM traffic_hourlies")
+	defer db.Exec("DELETE FROM traffic_hourlies")
+
+	base := time.Date(2026, 10, 1, 10, 0, 0, 0, time.Local)
+	// 同一小时内的多次上报合并为一条
+	assert.NoError(t, models.AddTrafficSample(7, base.Add(5*time.Minute), 100, 10))
+	assert.NoError(t, models.AddTrafficSample(7, base.Add(35*time.Minute), 200, 20))
+	assert.NoError(t, models.AddTrafficSample(7, base.Add(time.Hour), 300, 30))
+	assert.NoError(t, models.AddTrafficSample(7, base.Add(24*time.Hour), 400, 40))
+	assert.NoError(t, models.AddTrafficSample(8, base, 999, 999))
+	assert.NoError(t, models.AddTrafficSample(7, base, 0, 0))
+
+	query := func(params string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: "7"}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/servers/7/traffic?"+params, nil)
+		GetServerTrafficHistory(c)
+		var resp map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+	window := "from=" + strconv.FormatInt(base.Add(-time.Hour).Unix(), 10) + "&to=" + strconv.FormatInt(base.Add(48*time.Hour).Unix(), 10)
+
+	code, resp := query("granularity=hour&" + window)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, resp["data"], 3)
+	assert.Equal(t, float64(1000), resp["total_in"])
+	assert.Equal(t, float64(100), resp["total_out"])
+	first := resp["data"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, float64(300), first["bytes_in"])
+
+	_, resp = query("granularity=day&" + window)
+	if assert.Len(t, resp["data"], 2) {
+		day := resp["data"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, float64(600), day["bytes_in"])
+	}
+
+	_, resp = query("granularity=month&" + window)
+	assert.Len(t, resp["data"], 1)
+
+	code, _ = query("granularity=week")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	deleted, err := models.DeleteTrafficHourlyBefore(base.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"log"
+	"time"
+
+	"github.com/user/server-ops-backend/models"
+)
+
+// trafficPeriodStart 返回 now 所在计费周期的起始时间（本地时间零点）。
+// 重置日大于当月天数时取当月最后一天，例如 31 号在 2 月取 28/29 号
+func trafficPeriodStart(day int, now time.Time) time.Time {
+	start := trafficResetDate(now.Year(), now.Month(), day, now.Location())
+	if start.After(now) {
+		start = trafficResetDate(now.Year(), now.Month()-1, day, now.Location())
+	}
+	return start
+}
+
+func trafficResetDate(year int, month time.Month, day int, loc *time.Location) time.Time {
+	// 下个月的第 0 天即本月最后一天
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// applyTrafficReset 按服务器的每月重置日清零累计流量，用于按月计费/流量配额的场景。
+// 返回 true 表示本次已清零，调用方需要持久化 traffic_reset_at
+func applyTrafficReset(server *models.Server, now time.Time) bool {
+	// 连接期间持有的服务器对象不会刷新，重新读取面板上修改的设置；
+	// 批量写入时数据库中的清零时间可能尚未落盘，取两者中较新的一个
+	if day, resetAt, err := models.GetTrafficReset(server.ID); err != nil {
+		log.Printf("读取服务器 %d 的流量清零设置失败: %v", server.ID, err)
+	} else {
+		server.TrafficResetDay = day
+		if resetAt != nil && (server.TrafficResetAt == nil || resetAt.After(*server.TrafficResetAt)) {
+			server.TrafficResetAt = resetAt
+		}
+	}
+
+	if server.TrafficResetDay <= 0 {
+		return false
+	}
+	last := server.CreatedAt
+	if server.TrafficResetAt != nil {
+		last = *server.TrafficResetAt
+	}
+	if !last.Before(trafficPeriodStart(server.TrafficResetDay, now)) {
+		return false
+	}
+
+	log.Printf("服务器 %d 进入新的计费周期，累计流量清零 (入站=%d B, 出站=%d B)",
+		server.ID, server.NetworkInTotal, server.NetworkOutTotal)
+	server.NetworkInTotal = 0
+	server.NetworkOutTotal = 0
+	server.TrafficResetAt = &now
+	return true
+}
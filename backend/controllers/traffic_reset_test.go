@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+)
+
+func TestTrafficPeriodStart(t *testing.T) {
+	loc := time.UTC
+	tests := []struct {
+		day  int
+		now  time.Time
+		want time.Time
+	}{
+		{1, time.Date(2026, 10, 16, 12, 0, 0, 0, loc), time.Date(2026, 10, 1, 0, 0, 0, 0, loc)},
+		{20, time.Date(2026, 10, 16, 12, 0, 0, 0, loc), time.Date(2026, 9, 20, 0, 0, 0, 0, loc)},
+		{16, time.Date(2026, 10, 16, 0, 0, 0, 0, loc), time.Date(2026, 10, 16, 0, 0, 0, 0, loc)},
+		// 重置日超过当月天数时取月末
+		{31, time.Date(2026, 2, 28, 8, 0, 0, 0, loc), time.Date(2026, 2, 28, 0, 0, 0, 0, loc)},
+		{31, time.Date(2026, 3, 15, 8, 0, 0, 0, loc), time.Date(2026, 2, 28, 0, 0, 0, 0, loc)},
+		// 跨年
+		{5, time.Date(2026, 1, 3, 8, 0, 0, 0, loc), time.Date(2025, 12, 5, 0, 0, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, trafficPeriodStart(tt.day, tt.now), "day=%d now=%s", tt.day, tt.now)
+	}
+}
+
+func TestApplyTrafficReset(t *testing.T) {
+	db := setupTestDB(t)
+	lastMonth := time.Now().AddDate(0, -1, -1)
+	server := models.Server{
+		Name:            "traffic-reset",
+		NetworkInTotal:  1000,
+		NetworkOutTotal: 2000,
+		TrafficResetAt:  &lastMonth,
+	}
+	assert.NoError(t, db.Create(&server).Error)
+	t.Cleanup(func() { db.Unscoped().Delete(&models.Server{}, server.ID) })
+
+	// 未设置重置日时不清零
+	assert.False(t, applyTrafficReset(&server, time.Now()))
+	assert.Equal(t, uint64(1000), server.NetworkInTotal)
+
+	// 面板上设置重置日后，下一次上报时清零
+	assert.NoError(t, db.Model(&server).Update("traffic_reset_day", 1).Error)
+	now := time.Now()
+	assert.True(t, applyTrafficReset(&server, now))
+	assert.Zero(t, server.NetworkInTotal)
+	assert.Zero(t, server.NetworkOutTotal)
+
+	// 同一周期内不重复清零（数据库中的清零时间尚未更新）
+	server.NetworkInTotal = 500
+	assert.False(t, applyTrafficReset(&server, now.Add(time.Minute)))
+	assert.Equal(t, uint64(500), server.NetworkInTotal)
+}
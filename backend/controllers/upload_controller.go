@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -177,7 +178,7 @@ func InitUpload(c *gin.Context) {
 
 	if ok, errMsg := checkAgentAck(resp); !ok {
 		session.setStatus("failed", errMsg)
-		c.JSON(http.StatusBadGateway, gin.H{"error": errMsg})
+		respondAckError(c, resp, errMsg)
 		return
 	}
 
@@ -259,7 +260,7 @@ func UploadChunk(c *gin.Context) {
 
 	if ok, errMsg := checkAgentAck(resp); !ok {
 		session.setStatus("failed", errMsg)
-		c.JSON(http.StatusBadGateway, gin.H{"error": errMsg})
+		respondAckError(c, resp, errMsg)
 		return
 	}
 
@@ -306,16 +307,20 @@ func CompleteUpload(c *gin.Context) {
 
 	var req struct {
 		FileHash string `json:"file_hash"`
+		Verify   bool   `json:"verify"` // 写入最终位置后由 Agent 回读文件并返回 SHA-256
 	}
-	_ = c.ShouldBindJSON(&req) // file_hash 可选
+	_ = c.ShouldBindJSON(&req) // file_hash、verify 可选
 
 	session.setStatus("completing", "")
 
 	payload := map[string]interface{}{
 		"upload_id": uploadID,
 		"file_hash": strings.TrimSpace(req.FileHash),
+		"verify":    req.Verify,
 	}
 
+	// 合并后目录内容发生变化，清空文件列表缓存
+	defer invalidateFileListCache(serverID)
 	resp, err := sendChunkedRequest(serverID, "chunked_upload_complete", payload)
 	if err != nil {
 		session.setStatus("failed", err.Error())
@@ -325,15 +330,23 @@ func CompleteUpload(c *gin.Context) {
 
 	if ok, errMsg := checkAgentAck(resp); !ok {
 		session.setStatus("failed", errMsg)
-		c.JSON(http.StatusBadGateway, gin.H{"error": errMsg})
+		respondAckError(c, resp, errMsg)
 		return
 	}
 
 	session.setStatus("completed", "")
-	c.JSON(http.StatusOK, gin.H{
+	result := gin.H{
 		"upload_id": uploadID,
 		"status":    "completed",
-	})
+	}
+	if req.Verify {
+		// 提供 file_hash 时 Agent 已比对过回读的哈希；否则返回哈希供前端自行比对
+		data, _ := resp["data"].(map[string]interface{})
+		sum, _ := data["sha256"].(string)
+		result["sha256"] = sum
+		result["verified"] = sum != "" && req.FileHash != ""
+	}
+	c.JSON(http.StatusOK, result)
 }
 
 // CancelUpload 取消分片上传
@@ -416,8 +429,16 @@ func loadChunkedSession(uploadID string, serverID uint) (*chunkedUploadSession,
 	return session, nil
 }
 
+// errChunkedRequestTimeout 等待 Agent 的分片 ACK 超时
+var errChunkedRequestTimeout = errors.New("请求超时")
+
 // sendChunkedRequest 向 Agent 发送分片上传相关的 WebSocket 消息并等待 ACK
 func sendChunkedRequest(serverID uint, msgType string, payload map[string]interface{}) (map[string]interface{}, error) {
+	return sendChunkedRequestTimeout(serverID, msgType, payload, chunkedUploadRequestTimeout)
+}
+
+// sendChunkedRequestTimeout 与 sendChunkedRequest 相同，使用指定的超时时间
+func sendChunkedRequestTimeout(serverID uint, msgType string, payload map[string]interface{}, timeout time.Duration) (map[string]interface{}, error) {
 	// 获取 Agent 连接
 	agentConnVal, ok := ActiveAgentConnections.Load(serverID)
 	if !ok {
@@ -438,7 +459,12 @@ func sendChunkedRequest(serverID uint, msgType string, payload map[string]interf
 	fileRequestMutex.Unlock()
 
 	// 注册待处理请求，Agent 断连时可快速失败
-	registerPendingRequest(serverID, requestID)
+	if err := registerPendingRequest(serverID, requestID); err != nil {
+		fileRequestMutex.Lock()
+		delete(fileRequestMap, requestID)
+		fileRequestMutex.Unlock()
+		return nil, err
+	}
 	defer unregisterPendingRequest(serverID, requestID)
 
 	// 构造消息
@@ -460,11 +486,11 @@ func sendChunkedRequest(serverID uint, msgType string, payload map[string]interf
 	select {
 	case resp := <-respChan:
 		return resp, nil
-	case <-time.After(chunkedUploadRequestTimeout):
+	case <-time.After(timeout):
 		fileRequestMutex.Lock()
 		delete(fileRequestMap, requestID)
 		fileRequestMutex.Unlock()
-		return nil, fmt.Errorf("请求超时")
+		return nil, errChunkedRequestTimeout
 	}
 }
 
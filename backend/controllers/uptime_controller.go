@@ -0,0 +1,357 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+	"github.com/user/server-ops-backend/services"
+)
+
+// maxUptimeHistoryHours 可用性历史最多查询的小时数
+const maxUptimeHistoryHours = 24 * 30
+
+// uptimeCheckRequest 新建或更新可用性检查的请求体
+type uptimeCheckRequest struct {
+	Name             string `json:"name"`
+	Type             string `json:"type"`
+	Target           string `json:"target"`
+	Interval         int    `json:"interval"`
+	Timeout          int    `json:"timeout"`
+	ExpectedStatus   int    `json:"expected_status"`
+	FailureThreshold int    `json:"failure_threshold"`
+	ChannelIDs       string `json:"channel_ids"`
+	ServerIDs        string `json:"server_ids"`
+	AgentOnly        bool   `json:"agent_only"`
+	TLSServerName    string `json:"tls_server_name"`
+	TLSSkipVerify    bool   `json:"tls_skip_verify"`
+	CertMinDays      int    `json:"cert_min_days"`
+	Enabled          *bool  `json:"enabled"`
+}
+
+// uptimeCheckView 可用性检查及其最近 24 小时统计，tls 检查附带最近一次握手信息
+type uptimeCheckView struct {
+	models.UptimeCheck
+	Uptime24h models.UptimeSummary    `json:"uptime_24h"`
+	TLS       *models.TLSEndpointInfo `json:"tls_info,omitempty"`
+}
+
+// agentUptimeCheck 通过 Agent 设置接口下发的检查配置
+type agentUptimeCheck struct {
+	ID             uint   `json:"id"`
+	Type           string `json:"type"`
+	Target         string `json:"target"`
+	Interval       int    `json:"interval"`
+	Timeout        int    `json:"timeout"`
+	ExpectedStatus int    `json:"expected_status"`
+	ServerName     string `json:"server_name,omitempty"`
+	SkipVerify     bool   `json:"skip_verify,omitempty"`
+	CertMinDays    int    `json:"cert_min_days,omitempty"`
+}
+
+// UptimeResultPayload Agent 随监控数据上报的检查结果
+type UptimeResultPayload struct {
+	CheckID        uint   `json:"check_id"`
+	Timestamp      int64  `json:"timestamp"` // Agent 时钟的 Unix 毫秒
+	Success        bool   `json:"success"`
+	ResponseTimeMs int64  `json:"response_time_ms"`
+	StatusCode     int    `json:"status_code"`
+	Error          string `json:"error"`
+
+	TLS *models.TLSEndpointInfo `json:"tls,omitempty"` // 仅 tls 检查
+}
+
+// agentUptimeChecks 返回分配给服务器执行的检查，查询失败时返回空列表，Agent 停止执行所有检查
+func agentUptimeChecks(serverID uint) []agentUptimeCheck {
+	checks, err := models.GetAgentUptimeChecks(serverID)
+	if err != nil {
+		log.Printf("获取服务器 %d 的可用性检查失败: %v", serverID, err)
+	}
+	list := make([]agentUptimeCheck, 0, len(checks))
+	for _, check := range checks {
+		list = append(list, agentUptimeCheck{
+			ID:             check.ID,
+			Type:           check.Type,
+			Target:         check.Target,
+			Interval:       check.Interval,
+			Timeout:        check.Timeout,
+			ExpectedStatus: check.ExpectedStatus,
+			ServerName:     check.TLSServerName,
+			SkipVerify:     check.TLSSkipVerify,
+			CertMinDays:    check.CertMinDays,
+		})
+	}
+	return list
+}
+
+// recordUptimeResults 保存 Agent 上报的检查结果，检查时间按时钟偏差换算为面板时间
+func recordUptimeResults(server *models.Server, payload []UptimeResultPayload, now time.Time) {
+	results := make([]models.UptimeResult, 0, len(payload))
+	for _, r := range payload {
+		at := now
+		if r.Timestamp > 0 {
+			at = time.UnixMilli(r.Timestamp - server.ClockOffsetMs)
+			if at.After(now) {
+				at = now
+			}
+		}
+		results = append(results, models.UptimeResult{
+			CheckID:        r.CheckID,
+			Timestamp:      at,
+			Success:        r.Success,
+			ResponseTimeMs: r.ResponseTimeMs,
+			StatusCode:     r.StatusCode,
+			Error:          r.Error,
+			TLS:            r.TLS,
+		})
+	}
+	go services.GetUptimeService().RecordAgentResults(*server, results)
+}
+
+// applyUptimeCheckRequest 将请求写入检查配置并校验，目标需符合探测目标策略
+func applyUptimeCheckRequest(ctx context.Context, check *models.UptimeCheck, req uptimeCheckRequest) (int, string) {
+	check.Name = req.Name
+	check.Type = req.Type
+	check.Target = req.Target
+	check.Interval = req.Interval
+	check.Timeout = req.Timeout
+	check.ExpectedStatus = req.ExpectedStatus
+	check.FailureThreshold = req.FailureThreshold
+	check.ChannelIDs = req.ChannelIDs
+	check.ServerIDs = req.ServerIDs
+	check.AgentOnly = req.AgentOnly
+	check.TLSServerName = req.TLSServerName
+	check.TLSSkipVerify = req.TLSSkipVerify
+	check.CertMinDays = req.CertMinDays
+	if req.Enabled != nil {
+		check.Enabled = *req.Enabled
+	}
+
+	host, port, err := check.Normalize()
+	if err != nil {
+		return http.StatusBadRequest, err.Error()
+	}
+	for _, id := range check.AgentServerIDs() {
+		var count int64
+		if err := models.DB.Model(&models.Server{}).Where("id = ?", id).Count(&count).Error; err != nil || count == 0 {
+			return http.StatusBadRequest, fmt.Sprintf("服务器 %d 不存在", id)
+		}
+	}
+
+	policy, err := models.GetProbeTargetPolicy()
+	if err != nil {
+		return http.StatusInternalServerError, "读取探测目标策略失败"
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if port == 0 {
+		_, err = policy.ResolvePingTarget(ctx, host)
+	} else {
+		err = policy.CheckHost(ctx, host, port)
+	}
+	if err != nil {
+		return http.StatusBadRequest, err.Error()
+	}
+	return http.StatusOK, ""
+}
+
+// parseUptimeCheck 解析路径中的检查ID并加载检查，失败时已写入响应
+func parseUptimeCheck(c *gin.Context) (*models.UptimeCheck, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的检查ID"})
+		return nil, false
+	}
+	check, err := models.GetUptimeCheck(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "检查不存在"})
+		return nil, false
+	}
+	return check, true
+}
+
+// ListUptimeChecks 列出可用性检查及最近 24 小时的可用率
+func ListUptimeChecks(c *gin.Context) {
+	checks, err := models.ListUptimeChecks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取可用性检查失败"})
+		return
+	}
+
+	since := time.Now().Add(-24 * time.Hour)
+	views := make([]uptimeCheckView, 0, len(checks))
+	for _, check := range checks {
+		summary, err := models.GetUptimeSummary(check.ID, since)
+		if err != nil {
+			log.Printf("统计可用性检查 %d 失败: %v", check.ID, err)
+		}
+		views = append(views, uptimeCheckView{UptimeCheck: check, Uptime24h: summary, TLS: check.GetLastTLS()})
+	}
+	c.JSON(http.StatusOK, gin.H{"checks": views})
+}
+
+// CreateUptimeCheck 新建可用性检查
+func CreateUptimeCheck(c *gin.Context) {
+	var req uptimeCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	check := models.UptimeCheck{Enabled: true}
+	if status, msg := applyUptimeCheckRequest(c.Request.Context(), &check, req); status != http.StatusOK {
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+	if err := models.SaveUptimeCheck(&check); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存可用性检查失败"})
+		return
+	}
+	c.JSON(http.StatusOK, check)
+}
+
+// UpdateUptimeCheck 更新可用性检查，历史结果和当前告警状态保留
+func UpdateUptimeCheck(c *gin.Context) {
+	check, ok := parseUptimeCheck(c)
+	if !ok {
+		return
+	}
+	var req uptimeCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	if status, msg := applyUptimeCheckRequest(c.Request.Context(), check, req); status != http.StatusOK {
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+	if err := models.SaveUptimeCheck(check); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存可用性检查失败"})
+		return
+	}
+	c.JSON(http.StatusOK, check)
+}
+
+// DeleteUptimeCheck 删除可用性检查及其历史结果，未解决的告警标记为已解决
+func DeleteUptimeCheck(c *gin.Context) {
+	check, ok := parseUptimeCheck(c)
+	if !ok {
+		return
+	}
+
+	if check.AlertRecordID != 0 {
+		var record models.AlertRecord
+		if err := models.GetAlertRecordByID(check.AlertRecordID, &record); err == nil && !record.Resolved {
+			record.Resolved = true
+			record.ResolvedAt = time.Now()
+			if err := models.UpdateAlertRecord(&record); err != nil {
+				log.Printf("更新预警记录失败: %v", err)
+			}
+		}
+	}
+	if err := models.DeleteUptimeCheck(check.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除可用性检查失败"})
+		return
+	}
+	services.GetUptimeService().Forget(check.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "可用性检查已删除"})
+}
+
+// uptimeRegion 单个执行位置的统计，Name 为服务器名称，面板执行的结果为「面板」
+type uptimeRegion struct {
+	models.UptimeRegionSummary
+	Name        string `json:"name"`
+	CountryCode string `json:"country_code"`
+}
+
+// uptimeRegions 为各执行位置的统计补充服务器名称和国家代码
+func uptimeRegions(summaries []models.UptimeRegionSummary) []uptimeRegion {
+	regions := make([]uptimeRegion, 0, len(summaries))
+	for _, summary := range summaries {
+		region := uptimeRegion{UptimeRegionSummary: summary, Name: "面板"}
+		if summary.ServerID != 0 {
+			var server models.Server
+			if err := models.DB.Select("id", "name", "country_code").First(&server, summary.ServerID).Error; err == nil {
+				region.Name = server.Name
+				region.CountryCode = server.CountryCode
+			} else {
+				region.Name = fmt.Sprintf("服务器 %d", summary.ServerID)
+			}
+		}
+		regions = append(regions, region)
+	}
+	return regions
+}
+
+// GetUptimeCheckResults 获取检查最近若干小时（hours，默认 24，最多 30 天）的结果、合计统计和各执行位置的统计，
+// 供可用性页面绘图；server_id 指定时只返回该位置的结果（0 表示面板）
+func GetUptimeCheckResults(c *gin.Context) {
+	check, ok := parseUptimeCheck(c)
+	if !ok {
+		return
+	}
+
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的时间范围"})
+		return
+	}
+	if hours > maxUptimeHistoryHours {
+		hours = maxUptimeHistoryHours
+	}
+	var serverID *uint
+	if raw := c.Query("server_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+			return
+		}
+		v := uint(id)
+		serverID = &v
+	}
+
+	until := time.Now()
+	since := until.Add(-time.Duration(hours) * time.Hour)
+	results, err := models.GetUptimeResults(check.ID, serverID, since, until)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取检查结果失败"})
+		return
+	}
+	summary, err := models.GetUptimeSummary(check.ID, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "统计检查结果失败"})
+		return
+	}
+	regions, err := models.GetUptimeRegionSummaries(check.ID, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "统计检查结果失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"check":   check,
+		"tls":     check.GetLastTLS(),
+		"results": results,
+		"summary": summary,
+		"regions": uptimeRegions(regions),
+	})
+}
+
+// RunUptimeCheck 立即执行一次检查并返回结果
+func RunUptimeCheck(c *gin.Context) {
+	check, ok := parseUptimeCheck(c)
+	if !ok {
+		return
+	}
+	result, ok := services.GetUptimeService().RunCheck(*check)
+	if !ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "该检查正在执行，请稍后再试"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
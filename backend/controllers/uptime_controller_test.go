@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/user/server-ops-backend/models"
+	"github.com/user/server-ops-backend/services"
+)
+
+// callUptimeHandler 以 JSON 请求体调用处理函数，id 为路径参数
+func callUptimeHandler(handler gin.HandlerFunc, method, id string, body interface{}) (int, map[string]interface{}) {
+	data, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, "/", bytes.NewReader(data))
+	c.Request.Header.Set("Content-Type", "application/json")
+	if id != "" {
+		c.Params = gin.Params{{Key: "id", Value: id}}
+	}
+	handler(c)
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestUptimeChecks(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.UptimeCheck{}, &models.UptimeResult{}, &models.AlertRecord{}, &models.Incident{}, &models.IncidentEvent{}))
+	defer models.DB.Where("1 = 1").Delete(&models.UptimeResult{})
+	defer models.DB.Where("1 = 1").Delete(&models.UptimeCheck{})
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	// 目标校验：元数据服务地址被策略禁止，类型和格式错误直接拒绝
+	code, _ := callUptimeHandler(CreateUptimeCheck, http.MethodPost, "", map[string]interface{}{"name": "meta", "type": "http", "target": "http://169.254.169.254/"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = callUptimeHandler(CreateUptimeCheck, http.MethodPost, "", map[string]interface{}{"name": "bad", "type": "tcp", "target": "example.com"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = callUptimeHandler(CreateUptimeCheck, http.MethodPost, "", map[string]interface{}{"name": "bad", "type": "dns", "target": "example.com"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = callUptimeHandler(CreateUptimeCheck, http.MethodPost, "", map[string]interface{}{"name": "fast", "type": "http", "target": target.URL, "interval": 5})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, resp := callUptimeHandler(CreateUptimeCheck, http.MethodPost, "", map[string]interface{}{"name": "web", "type": "http", "target": target.URL})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(models.DefaultUptimeInterval), resp["interval"])
+	assert.Equal(t, true, resp["enabled"])
+	webID := fmt.Sprint(resp["id"])
+
+	code, resp = callUptimeHandler(RunUptimeCheck, http.MethodPost, webID, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, resp["success"])
+	assert.Equal(t, float64(http.StatusOK), resp["status_code"])
+
+	// 状态码不符合期望时失败，未达到连续失败阈值前不告警
+	code, resp = callUptimeHandler(CreateUptimeCheck, http.MethodPost, "", map[string]interface{}{
+		"name": "api", "type": "http", "target": target.URL, "expected_status": 204, "failure_threshold": 5,
+	})
+	assert.Equal(t, http.StatusOK, code)
+	apiCheckID := uint(resp["id"].(float64))
+	apiID := fmt.Sprint(apiCheckID)
+	code, resp = callUptimeHandler(RunUptimeCheck, http.MethodPost, apiID, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, false, resp["success"])
+	assert.Contains(t, resp["error"], "期望 204")
+
+	check, err := models.GetUptimeCheck(apiCheckID)
+	assert.NoError(t, err)
+	assert.Equal(t, "down", check.LastStatus)
+	assert.Equal(t, 1, check.ConsecutiveFailures)
+	assert.Zero(t, check.AlertRecordID)
+
+	code, resp = callUptimeHandler(GetUptimeCheckResults, http.MethodGet, webID, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, resp["results"], 1)
+	summary := resp["summary"].(map[string]interface{})
+	assert.Equal(t, float64(100), summary["uptime_percent"])
+
+	code, resp = callUptimeHandler(ListUptimeChecks, http.MethodGet, "", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, resp["checks"], 2)
+
+	code, _ = callUptimeHandler(DeleteUptimeCheck, http.MethodDelete, apiID, nil)
+	assert.Equal(t, http.StatusOK, code)
+	var remaining int64
+	models.DB.Model(&models.UptimeResult{}).Where("check_id = ?", apiID).Count(&remaining)
+	assert.Zero(t, remaining)
+}
+
+func TestAgentUptimeChecks(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.UptimeCheck{}, &models.UptimeResult{}, &models.AlertRecord{}, &models.Incident{}, &models.IncidentEvent{}))
+	defer models.DB.Where("1 = 1").Delete(&models.UptimeResult{})
+	defer models.DB.Where("1 = 1").Delete(&models.UptimeCheck{})
+
+	tokyo := models.Server{Name: "tokyo", CountryCode: "JP"}
+	frankfurt := models.Server{Name: "frankfurt", CountryCode: "DE"}
+	other := models.Server{Name: "other"}
+	for _, server := range []*models.Server{&tokyo, &frankfurt, &other} {
+		assert.NoError(t, models.DB.Create(server).Error)
+		defer models.DB.Unscoped().Delete(server)
+	}
+
+	// 只由 Agent 执行时必须指定服务器，且服务器必须存在
+	code, _ := callUptimeHandler(CreateUptimeCheck, http.MethodPost, "", map[string]interface{}{
+		"name": "site", "type": "tcp", "target": "127.0.0.1:443", "agent_only": true,
+	})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = callUptimeHandler(CreateUptimeCheck, http.MethodPost, "", map[string]interface{}{
+		"name": "site", "type": "tcp", "target": "127.0.0.1:443", "server_ids": "999999",
+	})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, resp := callUptimeHandler(CreateUptimeCheck, http.MethodPost, "", map[string]interface{}{
+		"name": "site", "type": "tcp", "target": "127.0.0.1:443", "agent_only": true, "failure_threshold": 2,
+		"server_ids": fmt.Sprintf("%d, %d,%d", frankfurt.ID, tokyo.ID, tokyo.ID),
+	})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, fmt.Sprintf("%d,%d", frankfurt.ID, tokyo.ID), resp["server_ids"])
+	checkID := uint(resp["id"].(float64))
+
+	assigned := agentUptimeChecks(tokyo.ID)
+	assert.Len(t, assigned, 1)
+	assert.Equal(t, checkID, assigned[0].ID)
+	assert.Empty(t, agentUptimeChecks(other.ID))
+
+	now := time.Now()
+	record := func(server models.Server, success bool, ms int64) {
+		services.GetUptimeService().RecordAgentResults(server, []models.UptimeResult{{
+			CheckID: checkID, Timestamp: now, Success: success, ResponseTimeMs: ms, Error: map[bool]string{false: "connection refused"}[success],
+		}})
+	}
+	record(tokyo, true, 40)
+	record(frankfurt, false, 0)
+	record(other, false, 0) // 未分配的服务器上报的结果被忽略
+
+	check, err := models.GetUptimeCheck(checkID)
+	assert.NoError(t, err)
+	assert.Equal(t, "down", check.LastStatus)
+	assert.Equal(t, 1, check.ConsecutiveFailures)
+	assert.Contains(t, check.LastError, "[frankfurt]")
+	assert.Zero(t, check.AlertRecordID)
+
+	code, resp = callUptimeHandler(GetUptimeCheckResults, http.MethodGet, fmt.Sprint(checkID), nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, resp["results"], 2)
+	regions := resp["regions"].([]interface{})
+	assert.Len(t, regions, 2)
+	for _, r := range regions {
+		region := r.(map[string]interface{})
+		switch region["name"] {
+		case "tokyo":
+			assert.Equal(t, "JP", region["country_code"])
+			assert.Equal(t, float64(100), region["uptime_percent"])
+			assert.Equal(t, float64(40), region["avg_response_ms"])
+		case "frankfurt":
+			assert.Equal(t, float64(0), region["uptime_percent"])
+		default:
+			t.Errorf("意外的执行位置: %v", region["name"])
+		}
+	}
+
+	// 位置恢复后状态转为正常
+	record(frankfurt, true, 120)
+	check, err = models.GetUptimeCheck(checkID)
+	assert.NoError(t, err)
+	assert.Equal(t, "up", check.LastStatus)
+	assert.Zero(t, check.ConsecutiveFailures)
+	services.GetUptimeService().Forget(checkID)
+}
+
+func TestTLSUptimeChecks(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&models.UptimeCheck{}, &models.UptimeResult{}, &models.AlertRecord{}, &models.Incident{}, &models.IncidentEvent{}))
+	defer models.DB.Where("1 = 1").Delete(&models.UptimeResult{})
+	defer models.DB.Where("1 = 1").Delete(&models.UptimeCheck{})
+
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+	addr := target.Listener.Addr().String()
+
+	code, _ := callUptimeHandler(CreateUptimeCheck, http.MethodPost, "", map[string]interface{}{"name": "bad", "type": "tls", "target": target.URL})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = callUptimeHandler(CreateUptimeCheck, http.MethodPost, "", map[string]interface{}{"name": "bad", "type": "tls", "target": addr, "cert_min_days": -1})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = callUptimeHandler(CreateUptimeCheck, http.MethodPost, "", map[string]interface{}{"name": "bad", "type": "tls", "target": addr, "tls_server_name": "a/b"})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// 测试服务器使用自签名证书，校验失败但仍记录证书链
+	code, resp := callUptimeHandler(CreateUptimeCheck, http.MethodPost, "", map[string]interface{}{"name": "device", "type": "tls", "target": addr, "failure_threshold": 5})
+	assert.Equal(t, http.StatusOK, code)
+	checkID := uint(resp["id"].(float64))
+	id := fmt.Sprint(checkID)
+	code, resp = callUptimeHandler(RunUptimeCheck, http.MethodPost, id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, false, resp["success"])
+	assert.Contains(t, resp["error"], "证书校验失败")
+
+	// 跳过校验后只检查有效期
+	code, _ = callUptimeHandler(UpdateUptimeCheck, http.MethodPut, id, map[string]interface{}{
+		"name": "device", "type": "tls", "target": addr, "tls_skip_verify": true, "cert_min_days": 30, "tls_server_name": "example.com",
+	})
+	assert.Equal(t, http.StatusOK, code)
+	code, resp = callUptimeHandler(RunUptimeCheck, http.MethodPost, id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, resp["success"])
+	info := resp["tls"].(map[string]interface{})
+	assert.Equal(t, "example.com", info["server_name"])
+	assert.NotEmpty(t, info["version"])
+	assert.Len(t, info["chain"], 1)
+
+	// 列表和结果接口返回最近一次握手信息
+	code, resp = callUptimeHandler(ListUptimeChecks, http.MethodGet, "", nil)
+	assert.Equal(t, http.StatusOK, code)
+	checks := resp["checks"].([]interface{})
+	if assert.Len(t, checks, 1) {
+		view := checks[0].(map[string]interface{})
+		assert.NotNil(t, view["tls_info"])
+		assert.NotContains(t, view, "last_tls")
+	}
+	code, resp = callUptimeHandler(GetUptimeCheckResults, http.MethodGet, id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotNil(t, resp["tls"])
+
+	// 改为 TCP 检查后清空 TLS 配置和握手信息
+	code, resp = callUptimeHandler(UpdateUptimeCheck, http.MethodPut, id, map[string]interface{}{
+		"name": "device", "type": "tcp", "target": addr, "tls_skip_verify": true, "cert_min_days": 30,
+	})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, false, resp["tls_skip_verify"])
+	assert.Equal(t, float64(0), resp["cert_min_days"])
+	check, err := models.GetUptimeCheck(checkID)
+	assert.NoError(t, err)
+	assert.Nil(t, check.GetLastTLS())
+	services.GetUptimeService().Forget(checkID)
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"runtime"
@@ -121,6 +122,14 @@ func GetLatestAgentRelease(c *gin.Context) {
 	}
 
 	info, err := services.FetchLatestAgentRelease(settings)
+	if errors.Is(err, services.ErrGitHubRateLimited) {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"success":      false,
+			"message":      fmt.Sprintf("获取最新版本失败: %v", err),
+			"rate_limited": true,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -143,9 +152,10 @@ func GetLatestAgentRelease(c *gin.Context) {
 // ForceAgentUpgrade 强制升级多个Agent
 func ForceAgentUpgrade(c *gin.Context) {
 	var req struct {
-		ServerIDs     []uint64 `json:"serverIds" binding:"required"`
-		TargetVersion string   `json:"targetVersion"`
-		Channel       string   `json:"channel"`
+		ServerIDs      []uint64 `json:"serverIds" binding:"required"`
+		TargetVersion  string   `json:"targetVersion"`
+		Channel        string   `json:"channel"`
+		AllowDowngrade bool     `json:"allowDowngrade"` // 显式允许降级，否则Agent拒绝安装低于当前版本的目标
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil || len(req.ServerIDs) == 0 {
@@ -172,14 +182,35 @@ func ForceAgentUpgrade(c *gin.Context) {
 
 	// 获取最新发行版信息（同时用于解析 targetVersion 和构建含 download_url/sha256 的 payload）
 	var releaseInfo *services.AgentReleaseInfo
-	if ri, err := services.FetchLatestAgentRelease(settings); err == nil && ri != nil {
+	ri, err := services.FetchLatestAgentRelease(settings)
+	if err == nil && ri != nil {
 		releaseInfo = ri
+	} else if errors.Is(err, services.ErrGitHubRateLimited) && strings.TrimSpace(req.TargetVersion) == "" {
+		// 未指定目标版本又无法获取最新版本时，明确提示限流而不是静默回退到面板版本
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"success":      false,
+			"message":      fmt.Sprintf("获取最新版本失败: %v", err),
+			"rate_limited": true,
+		})
+		return
 	}
 
 	targetVersion := strings.TrimSpace(req.TargetVersion)
 	if targetVersion == "" && releaseInfo != nil {
 		targetVersion = releaseInfo.Version
 	}
+	// 固定版本：未指定目标时升级到固定版本，指定了其他版本则拒绝
+	if pinned := strings.TrimSpace(settings.AgentPinnedVersion); pinned != "" {
+		if strings.TrimSpace(req.TargetVersion) == "" {
+			targetVersion = pinned
+		} else if !services.SameVersion(targetVersion, pinned) {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": fmt.Sprintf("Agent已固定在版本 %s，拒绝升级到 %s；如需变更请先修改系统设置中的固定版本", pinned, targetVersion),
+			})
+			return
+		}
+	}
 	if targetVersion == "" {
 		targetVersion = version.GetVersion().Version
 	}
@@ -221,6 +252,9 @@ func ForceAgentUpgrade(c *gin.Context) {
 
 		requestID := fmt.Sprintf("upgrade-%d-%d", server.ID, time.Now().UnixNano())
 		payload := services.BuildUpgradePayload(server, targetVersion, upgradeChannel, releaseInfo, "")
+		if req.AllowDowngrade {
+			payload["allow_downgrade"] = true
+		}
 		command := map[string]interface{}{
 			"type":       "agent_upgrade",
 			"request_id": requestID,
@@ -179,6 +179,64 @@ func TestGetLatestAgentRelease(t *testing.T) {
 	assert.Len(t, assets, 1)
 }
 
+func TestGetLatestAgentReleaseRateLimit(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.Create(&models.SystemSettings{
+		AgentReleaseRepo:    "demo/repo",
+		AgentReleaseChannel: "stable",
+	}).Error)
+
+	services.ClearReleaseCache()
+	defer services.ClearReleaseCache()
+
+	var calls int
+	exhausted := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if exhausted {
+			// 主限额耗尽，重置时间远超重试等待上限
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", fmt.Sprint(time.Now().Add(time.Hour).Unix()))
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if calls == 1 {
+			// 次级限流，按 Retry-After 等待后重试
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"tag_name": "v1.2.4", "name": "Agent v1.2.4", "assets": []}`)
+	}))
+	defer ts.Close()
+
+	services.SetReleaseAPIBaseURL(ts.URL)
+	defer services.ResetReleaseAPIBaseURL()
+	services.SetReleaseHTTPClient(ts.Client())
+	defer services.ResetReleaseHTTPClient()
+
+	call := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/agents/releases/latest", nil)
+		GetLatestAgentRelease(c)
+		return w
+	}
+
+	w := call()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, calls)
+
+	services.ClearReleaseCache()
+	exhausted = true
+	calls = 0
+	w = call()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, 1, calls, "限额重置前不应继续重试")
+	assert.Contains(t, w.Body.String(), "GITHUB_TOKEN")
+}
+
 func TestForceAgentUpgrade(t *testing.T) {
 	db := setupTestDB(t)
 	assert.NoError(t, db.Create(&models.SystemSettings{
@@ -291,3 +349,42 @@ func TestForceAgentUpgrade(t *testing.T) {
 	assert.Contains(t, result["missing"], float64(9999))
 	assert.Len(t, sentCommands, 2)
 }
+
+func TestForceAgentUpgradeRespectsPinnedVersion(t *testing.T) {
+	setupTestDB(t)
+	settings, err := models.GetSettings()
+	assert.NoError(t, err)
+	assert.NoError(t, models.DB.Model(settings).Update("agent_pinned_version", "1.5.0").Error)
+	defer models.DB.Model(settings).Update("agent_pinned_version", "")
+
+	services.ClearReleaseCache()
+	defer services.ClearReleaseCache()
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+	services.SetReleaseAPIBaseURL(ts.URL)
+	defer services.ResetReleaseAPIBaseURL()
+	services.SetReleaseHTTPClient(ts.Client())
+	defer services.ResetReleaseHTTPClient()
+
+	upgrade := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/servers/upgrade", bytes.NewBuffer(payload))
+		c.Request.Header.Set("Content-Type", "application/json")
+		ForceAgentUpgrade(c)
+		return w
+	}
+
+	// 指定了与固定版本不同的目标版本时拒绝
+	w := upgrade(map[string]interface{}{"serverIds": []uint64{9999}, "targetVersion": "2.0.0"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "1.5.0")
+
+	// 未指定目标版本时使用固定版本
+	w = upgrade(map[string]interface{}{"serverIds": []uint64{9999}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "1.5.0", resp["targetVersion"])
+}
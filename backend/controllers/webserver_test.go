@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebServerPayload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		query  string
+		action string
+		server string
+	}{
+		{"", "nginx_restart", ""},
+		{"server=nginx", "nginx_restart", ""},
+		{"server=Apache", "webserver_reload", "apache"},
+		{"server=caddy", "webserver_reload", "caddy"},
+	}
+	for _, tc := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/servers/1/nginx/restart?"+tc.query, nil)
+
+		payload, ok := webServerPayload(c, "nginx_restart", "webserver_reload")
+		assert.True(t, ok, tc.query)
+		assert.Equal(t, tc.action, payload["action"], tc.query)
+		if tc.server != "" {
+			assert.Equal(t, tc.server, payload["server"], tc.query)
+		}
+	}
+}
+
+func TestWebServerControl_InvalidServerType(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handlers := map[string]gin.HandlerFunc{
+		"restart": RestartNginx,
+		"stop":    StopNginx,
+		"start":   StartNginx,
+		"test":    TestNginxConfig,
+		"vhosts":  GetWebServerVirtualHosts,
+	}
+	for name, handler := range handlers {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: "1"}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/servers/1/nginx/"+name+"?server=lighttpd", nil)
+
+		handler(c)
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -121,21 +122,33 @@ var upgrader = websocket.Upgrader{
 // SafeConn 线程安全的WebSocket连接
 type SafeConn struct {
 	*websocket.Conn
-	mu sync.Mutex
+	mu    sync.Mutex
+	stats connWriteStats // 写入耗时统计，用于评估连接质量
 }
 
 // 安全地向WebSocket写入JSON数据
 func (c *SafeConn) WriteJSON(v interface{}) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	return c.Conn.WriteJSON(v)
+	start := time.Now()
+	err := c.Conn.WriteJSON(v)
+	c.stats.record(time.Since(start), time.Now())
+	return err
 }
 
 // 安全地向WebSocket写入消息
 func (c *SafeConn) WriteMessage(messageType int, data []byte) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	return c.Conn.WriteMessage(messageType, data)
+	start := time.Now()
+	err := c.Conn.WriteMessage(messageType, data)
+	c.stats.record(time.Since(start), time.Now())
+	return err
+}
+
+// Quality 返回连接的写入质量（只计入获取写锁后的实际写入耗时，不含排队等待）
+func (c *SafeConn) Quality() ConnQuality {
+	return c.stats.quality()
 }
 
 // 安全地关闭WebSocket连接
@@ -178,7 +191,7 @@ var ActiveAgentConnections sync.Map
 // 存储活跃的用户终端WebSocket连接 - 按会话ID索引
 var ActiveTerminalConnections sync.Map
 
-// 存储活跃的日志流连接 - key: streamID, value: *SafeConn (用户连接)
+// 存储活跃的日志流、容器资源统计流和镜像拉取进度流连接 - key: streamID, value: *SafeConn (用户连接)
 var ActiveLogStreamConnections sync.Map
 
 // 存储公开探针监控连接
@@ -187,6 +200,9 @@ var ActivePublicMonitorConnections sync.Map
 // 存储上次广播时间，用于限流
 var LastBroadcastTimes sync.Map
 
+// 存储正在升级重启的Agent - key: serverID, value: time.Time (进入升级状态的时间)
+var upgradingAgents sync.Map
+
 type publicConnSet struct {
 	mu    sync.Mutex
 	conns map[*SafeConn]struct{}
@@ -280,7 +296,20 @@ type pendingRequestSet struct {
 }
 
 // registerPendingRequest 注册一个待处理请求
-func registerPendingRequest(serverID uint, requestID string) {
+// 注册前会先在请求调度队列中为该服务器申请并发名额，排队超时或Agent正在升级时返回错误；
+// 注册成功后必须调用 unregisterPendingRequest 归还名额
+func registerPendingRequest(serverID uint, requestID string) error {
+	// Agent 升级重启期间不再下发新请求，直接失败而不是等待超时
+	if isAgentUpgrading(serverID) {
+		return ErrAgentUpgrading
+	}
+
+	queue := getAgentQueue()
+	if err := queue.acquire(serverID, queue.timeout); err != nil {
+		log.Printf("服务器 %d 的请求 %s 排队超时", serverID, requestID)
+		return err
+	}
+
 	val, _ := serverPendingRequests.LoadOrStore(serverID, &pendingRequestSet{
 		requestIDs: make(map[string]struct{}),
 	})
@@ -288,10 +317,13 @@ func registerPendingRequest(serverID uint, requestID string) {
 	set.mu.Lock()
 	defer set.mu.Unlock()
 	set.requestIDs[requestID] = struct{}{}
+	return nil
 }
 
-// unregisterPendingRequest 取消注册一个待处理请求
+// unregisterPendingRequest 取消注册一个待处理请求并归还调度名额
 func unregisterPendingRequest(serverID uint, requestID string) {
+	defer getAgentQueue().release(serverID)
+
 	val, ok := serverPendingRequests.Load(serverID)
 	if !ok {
 		return
@@ -438,11 +470,13 @@ func PublicServersWebSocketHandler(c *gin.Context) {
 	// 检查是否已认证（通过Token）
 	token := c.Query("token")
 	isAuthenticated := false
+	var userID uint
 	if token != "" {
 		// 验证JWT Token
 		claims, err := verifyJWTFromQuery(token)
 		if err == nil && claims != nil {
 			isAuthenticated = true
+			userID = claims.UserID
 		}
 	}
 
@@ -458,9 +492,12 @@ func PublicServersWebSocketHandler(c *gin.Context) {
 		if err != nil {
 			return err
 		}
+		// 已登录用户按个人的收藏和顺序展示
+		applyUserServerPreferences(userID, servers)
 
 		type PublicServer struct {
 			ID              uint    `json:"id"`
+			Favorite        bool    `json:"favorite,omitempty"`
 			Name            string  `json:"name"`
 			Status          string  `json:"status"`
 			IP              string  `json:"ip"`
@@ -495,10 +532,7 @@ func PublicServersWebSocketHandler(c *gin.Context) {
 				_ = json.Unmarshal([]byte(server.SystemInfo), &systemInfo)
 			}
 
-			status := "offline"
-			if server.Online && time.Since(server.LastHeartbeat) <= 15*time.Second {
-				status = "online"
-			}
+			status := publicServerStatus(&server)
 
 			monitorData, _ := models.GetLatestMonitorData(server.ID, 1)
 			lastMonitor := models.ServerMonitor{}
@@ -532,6 +566,7 @@ func PublicServersWebSocketHandler(c *gin.Context) {
 
 			list = append(list, PublicServer{
 				ID:              server.ID,
+				Favorite:        server.Favorite,
 				Name:            server.Name,
 				Status:          status,
 				IP:              ip,
@@ -603,6 +638,21 @@ func PublicServersWebSocketHandler(c *gin.Context) {
 	}
 }
 
+// publicServerStatus 公开页面显示的服务器状态：升级宽限期内为 upgrading，
+// 处于维护窗口内且未在线时为 maintenance，计划内停机不显示为离线
+func publicServerStatus(server *models.Server) string {
+	switch {
+	case server.Online && time.Since(server.LastHeartbeat) <= models.HeartbeatTimeout(server):
+		return "online"
+	case server.Status == models.ServerStatusUpgrading && time.Since(server.LastHeartbeat) <= models.AgentUpgradeGracePeriod:
+		return models.ServerStatusUpgrading
+	case models.InMaintenance(server.ID, time.Now()):
+		return models.ServerStatusMaintenance
+	default:
+		return "offline"
+	}
+}
+
 // 处理公开的WebSocket连接
 func handlePublicWebSocket(conn *SafeConn, server *models.Server, interrupt chan struct{}) {
 	log.Printf("开始处理服务器 %d 的公开WebSocket连接", server.ID)
@@ -627,7 +677,7 @@ func handlePublicWebSocket(conn *SafeConn, server *models.Server, interrupt chan
 		Message:    "连接成功，服务器ID: " + strconv.Itoa(int(server.ID)),
 		ServerID:   server.ID,
 		SystemInfo: safeSystemInfo(server),
-		Status:     server.Status,
+		Status:     publicServerStatus(server),
 		Name:       server.Name,
 		Hostname:   server.Hostname,
 		IP:         maskIP(server.IP),
@@ -652,18 +702,25 @@ func handlePublicWebSocket(conn *SafeConn, server *models.Server, interrupt chan
 	registerPublicMonitorConnection(server.ID, conn)
 	defer unregisterPublicMonitorConnection(server.ID, conn)
 
+	// 空闲订阅者自动断开
+	touchIdle, stopIdle := startMonitorIdlePolicy(conn, server.ID)
+	defer stopIdle()
+
 	// 处理接收到的消息
 	for {
 		// 读取消息
 		_, message, err := conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			if isIdleTimeout(err) {
+				log.Printf("服务器 %d 的公开监控订阅空闲超时，断开连接", server.ID)
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("服务器 %d 的WebSocket读取错误: %v", server.ID, err)
 			} else {
 				log.Printf("服务器 %d 的WebSocket连接正常关闭", server.ID)
 			}
 			break
 		}
+		touchIdle()
 
 		// 解析消息
 		var msg struct {
@@ -746,6 +803,7 @@ func buildMonitorData(server *models.Server, monitor *models.ServerMonitor) map[
 	// 避免用旧数据或无效数据覆盖前端已显示的正常值
 	if monitor.Processes > 0 {
 		data["processes"] = monitor.Processes
+		data["zombies"] = monitor.Zombies
 	}
 	if monitor.TCPConnections > 0 {
 		data["tcp_connections"] = monitor.TCPConnections
@@ -753,6 +811,28 @@ func buildMonitorData(server *models.Server, monitor *models.ServerMonitor) map[
 	if monitor.UDPConnections > 0 {
 		data["udp_connections"] = monitor.UDPConnections
 	}
+	if monitor.CustomMetrics != "" {
+		data["custom"] = json.RawMessage(monitor.CustomMetrics)
+	}
+	if monitor.Sensors != "" {
+		data["max_temperature"] = monitor.MaxTemperature
+		data["sensors"] = json.RawMessage(monitor.Sensors)
+	}
+	if monitor.Interfaces != "" {
+		data["interfaces"] = json.RawMessage(monitor.Interfaces)
+	}
+	if monitor.TCPStates != "" {
+		data["tcp_states"] = json.RawMessage(monitor.TCPStates)
+	}
+	if monitor.NginxStatus != "" {
+		data["nginx"] = json.RawMessage(monitor.NginxStatus)
+	}
+	if monitor.Databases != "" {
+		data["databases"] = json.RawMessage(monitor.Databases)
+	}
+	if monitor.Scripts != "" {
+		data["scripts"] = json.RawMessage(monitor.Scripts)
+	}
 
 	// 兼容旧数据中未设置的延迟/丢包
 	if monitor.Latency == 0 {
@@ -876,6 +956,12 @@ func WebSocketHandler(c *gin.Context) {
 	// 检查是否是监控专用WebSocket
 	isMonitorWs := strings.HasSuffix(c.Request.URL.Path, "/monitor-ws")
 
+	// 同一服务器ID被多台机器上的 Agent 反复抢占时记录并告警，按配置拒绝后来的连接
+	if isAgent && !checkDuplicateAgent(c, server) {
+		c.JSON(http.StatusConflict, gin.H{"error": "该服务器ID已有其他 Agent 在线，疑似重复部署"})
+		return
+	}
+
 	// 升级HTTP连接为WebSocket
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -902,18 +988,35 @@ func WebSocketHandler(c *gin.Context) {
 
 		// 设置函数在连接关闭时从映射中移除，并使所有待处理请求失败
 		defer func(id uint) {
+			// 已被新连接顶替时不能移除新连接，也不应让新连接的请求失败或通知离线
+			if !ActiveAgentConnections.CompareAndDelete(id, safeConn) {
+				log.Printf("服务器 %d 的旧Agent连接已关闭，当前连接已被替换", id)
+				return
+			}
 			log.Printf("Agent连接关闭，从映射中移除，服务器ID: %d", id)
-			ActiveAgentConnections.Delete(id)
 			// 【安全修复】使该服务器的所有待处理请求立即失败
 			failAllPendingRequests(id)
 
-			// 通知前端监控订阅者Agent已离线
-			broadcastPublicMonitor(id, map[string]interface{}{
-				"type":      "agent_offline",
-				"server_id": id,
-				"message":   "Agent连接已断开",
-				"timestamp": time.Now().Unix(),
-			})
+			if isAgentUpgrading(id) {
+				// 升级重启导致的断开：保持 upgrading 状态，等待新版本重连
+				if err := models.UpdateServerStatusOnly(id, models.ServerStatusUpgrading); err != nil {
+					log.Printf("更新服务器 %d 升级状态失败: %v", id, err)
+				}
+				broadcastPublicMonitor(id, map[string]interface{}{
+					"type":      "agent_upgrading",
+					"server_id": id,
+					"message":   "Agent正在升级重启",
+					"timestamp": time.Now().Unix(),
+				})
+			} else {
+				// 通知前端监控订阅者Agent已离线
+				broadcastPublicMonitor(id, map[string]interface{}{
+					"type":      "agent_offline",
+					"server_id": id,
+					"message":   "Agent连接已断开",
+					"timestamp": time.Now().Unix(),
+				})
+			}
 
 			// 通知该服务器所有终端会话用户Agent已断开
 			terminalSessions.Range(func(key, value interface{}) bool {
@@ -939,7 +1042,8 @@ func WebSocketHandler(c *gin.Context) {
 			})
 		}(server.ID)
 
-		// 更新服务器状态为在线
+		// 更新服务器状态为在线（升级后重连也在此恢复）
+		upgradingAgents.Delete(server.ID)
 		server.Status = "online"
 		err = models.UpdateServerStatus(server.ID, "online")
 		if err != nil {
@@ -947,6 +1051,9 @@ func WebSocketHandler(c *gin.Context) {
 		} else {
 			log.Printf("服务器 %d 状态已更新为在线", server.ID)
 		}
+
+		// 恢复重连前的聚焦查看状态
+		monitorFocus.resend(server.ID)
 	}
 
 	// 设置一个通道来接收中断信号
@@ -1001,29 +1108,42 @@ func handleMonitorWebSocket(conn *SafeConn, server *models.Server, interrupt cha
 
 	registerPublicMonitorConnection(server.ID, conn)
 	defer unregisterPublicMonitorConnection(server.ID, conn)
+	defer clearMonitorFocus(server.ID, conn)
+
+	// 空闲订阅者自动断开
+	touchIdle, stopIdle := startMonitorIdlePolicy(conn, server.ID)
+	defer stopIdle()
 
 	// 处理接收到的消息
 	for {
 		// 读取消息
 		_, message, err := conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			if isIdleTimeout(err) {
+				log.Printf("服务器 %d 的监控订阅空闲超时，断开连接", server.ID)
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("监控WebSocket读取错误: %v", err)
 			} else {
 				log.Printf("监控WebSocket连接正常关闭")
 			}
 			break
 		}
+		touchIdle()
 
 		// 解析消息
 		var msg struct {
-			Type string `json:"type"`
+			Type    string          `json:"type"`
+			Payload json.RawMessage `json:"payload"`
 		}
 		if err := json.Unmarshal(message, &msg); err != nil {
 			log.Printf("解析WebSocket消息错误: %v", err)
 			sendErrorMessage(conn, "消息格式错误")
 			continue
 		}
+
+		if msg.Type == "monitor_focus" {
+			handleMonitorFocus(conn, server.ID, msg.Payload)
+		}
 	}
 }
 
@@ -1068,7 +1188,15 @@ func handleWebSocket(conn *SafeConn, server *models.Server, interrupt chan struc
 
 		registerPublicMonitorConnection(server.ID, conn)
 		defer unregisterPublicMonitorConnection(server.ID, conn)
+		defer clearMonitorFocus(server.ID, conn)
+	}
+
+	// 监控订阅连接启用空闲断开策略
+	touchIdle, stopIdle := func() {}, func() {}
+	if isMonitor {
+		touchIdle, stopIdle = startMonitorIdlePolicy(conn, server.ID)
 	}
+	defer stopIdle()
 
 	// 如果是普通用户连接且有会话参数，说明是终端连接
 	if !isAgent && sessionParam != "" {
@@ -1125,13 +1253,17 @@ func handleWebSocket(conn *SafeConn, server *models.Server, interrupt chan struc
 		// 读取消息
 		_, message, err := conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			if isMonitor && isIdleTimeout(err) {
+				log.Printf("服务器 %d 的监控订阅空闲超时，断开连接", server.ID)
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("服务器 %d 的WebSocket读取错误: %v", server.ID, err)
 			} else {
 				log.Printf("服务器 %d 的WebSocket连接正常关闭", server.ID)
 			}
 			break
 		}
+		receivedAt := time.Now()
+		touchIdle()
 
 		// 解析消息
 		var msg Message
@@ -1155,9 +1287,18 @@ func handleWebSocket(conn *SafeConn, server *models.Server, interrupt chan struc
 		case TypeDockerCommand:
 			// Docker命令的处理
 			handleDockerCommand(conn, server, msg.Payload)
-		case "docker_logs_stream":
-			// Docker日志流的处理（start / stop）
-			handleDockerLogsStream(conn, server, msg.Payload)
+		case "docker_logs_stream", "docker_stats_stream", "docker_pull_stream", "diagnostic_stream", "file_tail_stream", "journal_query":
+			// Docker日志流、容器资源统计流、镜像拉取进度流、网络诊断输出流、文件跟踪流和 journal 查询的处理（start / stop）
+			handleDockerStream(conn, server, msg.Type, msg.Payload)
+		case "file_scan":
+			// 文件搜索/磁盘占用扫描的处理（start / cancel）
+			handleFileScan(conn, server, msg.Payload)
+		case "monitor_focus":
+			// 详情页聚焦查看，临时提高该服务器的上报频率
+			if !isMonitor {
+				continue
+			}
+			handleMonitorFocus(conn, server.ID, msg.Payload)
 		case TypeMonitor:
 			// Agent 上报监控数据
 			if !isAgent {
@@ -1178,11 +1319,19 @@ func handleWebSocket(conn *SafeConn, server *models.Server, interrupt chan struc
 			}
 
 			record, err := persistMonitorPayload(server, &monitorPayload)
+			if errors.Is(err, errDuplicateSample) {
+				continue
+			}
 			if err != nil {
 				log.Printf("保存监控数据失败: %v", err)
 				continue
 			}
 
+			// 补发的是断线期间的历史样本，不推送给实时订阅者
+			if monitorPayload.Replay {
+				continue
+			}
+
 			// 推送给公开探针的订阅者
 			broadcastData := buildMonitorData(server, record)
 			// 限流：每秒最多广播一次
@@ -1191,12 +1340,20 @@ func handleWebSocket(conn *SafeConn, server *models.Server, interrupt chan struc
 				broadcastPublicMonitor(server.ID, broadcastData)
 				LastBroadcastTimes.Store(server.ID, time.Now())
 			}
+		case "docker_stats":
+			// Agent 定时上报的容器资源统计
+			if !isAgent {
+				continue
+			}
+			recordContainerStats(server, msg.Payload)
 		case TypeSystemInfo:
 			// Agent 上报系统信息
 			if !isAgent {
 				log.Printf("非Agent连接发送系统信息，已忽略")
 				continue
 			}
+			// 尽早回复时间戳，供Agent估算时钟偏差
+			replyTimeSync(conn, message, receivedAt)
 
 			if len(msg.Payload) == 0 {
 				log.Printf("系统信息为空，服务器ID: %d", server.ID)
@@ -1329,6 +1486,12 @@ func handleWebSocket(conn *SafeConn, server *models.Server, interrupt chan struc
 					go updateServerCountry(server.ID, geoIP)
 				}
 			}
+		case "time_sync_result":
+			// Agent 上报时钟偏差测量结果
+			if !isAgent {
+				continue
+			}
+			handleTimeSyncResult(server, msg.Payload)
 		case "working_directory":
 			// 处理工作目录响应
 			log.Printf("收到工作目录响应消息，服务器ID: %d", server.ID)
@@ -1440,7 +1603,229 @@ func handleWebSocket(conn *SafeConn, server *models.Server, interrupt chan struc
 					}
 				}
 			}
-		case "docker_containers", "docker_images", "docker_composes", "docker_container_logs", "docker_compose_config", "success", "error":
+		case "agent_log_level_response":
+			// 处理Agent日志级别查询/调整响应
+			var levelResponse struct {
+				RequestID string                 `json:"request_id"`
+				Data      map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(message, &levelResponse); err != nil {
+				log.Printf("解析日志级别响应失败: %v", err)
+				continue
+			}
+			if levelResponse.RequestID != "" {
+				HandleAgentLogLevelResponse(levelResponse.RequestID, levelResponse.Data)
+			}
+		case "agent_error_stats_response":
+			// 处理Agent错误计数查询/清零响应
+			var statsResponse struct {
+				RequestID string                 `json:"request_id"`
+				Data      map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(message, &statsResponse); err != nil {
+				log.Printf("解析错误计数响应失败: %v", err)
+				continue
+			}
+			if statsResponse.RequestID != "" {
+				HandleAgentErrorStatsResponse(statsResponse.RequestID, statsResponse.Data)
+			}
+		case "read_only_mode_response":
+			// 处理Agent对只读模式切换的确认
+			var readOnlyResponse struct {
+				RequestID string                 `json:"request_id"`
+				Data      map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(message, &readOnlyResponse); err != nil {
+				log.Printf("解析只读模式响应失败: %v", err)
+				continue
+			}
+			if readOnlyResponse.RequestID != "" {
+				HandleReadOnlyModeResponse(readOnlyResponse.RequestID, readOnlyResponse.Data)
+			}
+		case "agent_poke_response":
+			// 处理Agent心跳/重连响应
+			var pokeResponse struct {
+				RequestID string                 `json:"request_id"`
+				Data      map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(message, &pokeResponse); err != nil {
+				log.Printf("解析心跳响应失败: %v", err)
+				continue
+			}
+			if pokeResponse.RequestID != "" {
+				HandleAgentPokeResponse(pokeResponse.RequestID, pokeResponse.Data)
+			}
+		case "process_kill_by_name_response":
+			// 处理按名称批量终止进程的响应
+			var killResponse struct {
+				RequestID string                 `json:"request_id"`
+				Data      map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(message, &killResponse); err != nil {
+				log.Printf("解析批量终止进程响应失败: %v", err)
+				continue
+			}
+			if killResponse.RequestID != "" {
+				HandleProcessKillByNameResponse(killResponse.RequestID, killResponse.Data)
+			}
+		case "connection_list_response":
+			// 处理网络连接列表响应
+			var connResponse struct {
+				RequestID string                 `json:"request_id"`
+				Data      map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(message, &connResponse); err != nil {
+				log.Printf("解析连接列表响应失败: %v", err)
+				continue
+			}
+			if connResponse.RequestID != "" {
+				HandleConnectionListResponse(connResponse.RequestID, connResponse.Data)
+			}
+		case "listening_ports_response":
+			// 处理监听端口列表响应
+			var portsResponse struct {
+				RequestID string                 `json:"request_id"`
+				Data      map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(message, &portsResponse); err != nil {
+				log.Printf("解析监听端口响应失败: %v", err)
+				continue
+			}
+			if portsResponse.RequestID != "" {
+				HandleListeningPortsResponse(portsResponse.RequestID, portsResponse.Data)
+			}
+		case "netstat_response":
+			// 处理 TCP 连接状态及监听端口响应
+			var netstatResponse struct {
+				RequestID string                 `json:"request_id"`
+				Data      map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(message, &netstatResponse); err != nil {
+				log.Printf("解析 netstat 响应失败: %v", err)
+				continue
+			}
+			if netstatResponse.RequestID != "" {
+				HandleNetstatResponse(netstatResponse.RequestID, netstatResponse.Data)
+			}
+		case "network_benchmark_response":
+			// 处理测速响应
+			var benchmarkResponse struct {
+				RequestID string                 `json:"request_id"`
+				Data      map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(message, &benchmarkResponse); err != nil {
+				log.Printf("解析测速响应失败: %v", err)
+				continue
+			}
+			if benchmarkResponse.RequestID != "" {
+				HandleNetworkBenchmarkResponse(benchmarkResponse.RequestID, benchmarkResponse.Data)
+			}
+		case "command_capture_response":
+			// 处理命令输出采集响应
+			var captureResponse struct {
+				RequestID string                 `json:"request_id"`
+				Data      map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(message, &captureResponse); err != nil {
+				log.Printf("解析命令输出采集响应失败: %v", err)
+				continue
+			}
+			if captureResponse.RequestID != "" {
+				HandleCommandCaptureResponse(captureResponse.RequestID, captureResponse.Data)
+			}
+		case "log_rotate_response":
+			// 处理日志文件清空/轮转响应
+			var rotateResponse struct {
+				RequestID string                 `json:"request_id"`
+				Data      map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(message, &rotateResponse); err != nil {
+				log.Printf("解析日志轮转响应失败: %v", err)
+				continue
+			}
+			if rotateResponse.RequestID != "" {
+				HandleLogRotateResponse(rotateResponse.RequestID, rotateResponse.Data)
+			}
+		case "file_search_response":
+			// 处理文件搜索响应
+			var searchResponse struct {
+				RequestID string                 `json:"request_id"`
+				Data      map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(message, &searchResponse); err != nil {
+				log.Printf("解析文件搜索响应失败: %v", err)
+				continue
+			}
+			if searchResponse.RequestID != "" {
+				HandleFileSearchResponse(searchResponse.RequestID, searchResponse.Data)
+			}
+		case "service_command_response":
+			// 处理服务管理响应
+			var serviceResponse struct {
+				RequestID string                 `json:"request_id"`
+				Data      map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(message, &serviceResponse); err != nil {
+				log.Printf("解析服务管理响应失败: %v", err)
+				continue
+			}
+			if serviceResponse.RequestID != "" {
+				HandleServiceCommandResponse(serviceResponse.RequestID, serviceResponse.Data)
+			}
+		case "package_command_response":
+			// 处理软件包管理响应
+			var packageResponse struct {
+				RequestID string                 `json:"request_id"`
+				Data      map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(message, &packageResponse); err != nil {
+				log.Printf("解析软件包管理响应失败: %v", err)
+				continue
+			}
+			if packageResponse.RequestID != "" {
+				HandlePackageCommandResponse(packageResponse.RequestID, packageResponse.Data)
+			}
+		case "file_diff_response":
+			// 处理文件变更对比响应
+			var diffResponse struct {
+				RequestID string                 `json:"request_id"`
+				Data      map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(message, &diffResponse); err != nil {
+				log.Printf("解析文件对比响应失败: %v", err)
+				continue
+			}
+			if diffResponse.RequestID != "" {
+				HandleFileDiffResponse(diffResponse.RequestID, diffResponse.Data)
+			}
+		case "file_snapshot_response":
+			// 处理文件快照响应
+			var snapshotResponse struct {
+				RequestID string                 `json:"request_id"`
+				Data      map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(message, &snapshotResponse); err != nil {
+				log.Printf("解析文件快照响应失败: %v", err)
+				continue
+			}
+			if snapshotResponse.RequestID != "" {
+				HandleFileSnapshotResponse(snapshotResponse.RequestID, snapshotResponse.Data)
+			}
+		case "update_config_response":
+			// 处理Agent远程配置查询/修改响应
+			var configResponse struct {
+				RequestID string                 `json:"request_id"`
+				Data      map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(message, &configResponse); err != nil {
+				log.Printf("解析远程配置响应失败: %v", err)
+				continue
+			}
+			if configResponse.RequestID != "" {
+				HandleAgentConfigResponse(configResponse.RequestID, configResponse.Data)
+			}
+		case "docker_containers", "docker_images", "docker_composes", "docker_container_logs", "docker_container_exec", "docker_compose_config",
+			"docker_compose_services", "docker_networks", "docker_network_detail", "docker_volumes", "docker_volume_detail", "success", "error":
 			// 处理Docker相关响应
 			var dockerResponse struct {
 				Type      string                 `json:"type"`
@@ -1452,6 +1837,12 @@ func handleWebSocket(conn *SafeConn, server *models.Server, interrupt chan struc
 				continue
 			}
 
+			// 文件操作和连接列表失败时 Agent 同样回复 error，交给等待中的请求，避免请求一直等到超时
+			if dockerResponse.Type == "error" &&
+				(HandleFileError(dockerResponse.RequestID, dockerResponse.Data) || HandleConnectionListError(dockerResponse.RequestID, dockerResponse.Data)) {
+				continue
+			}
+
 			log.Printf("收到Docker响应消息: 类型=%s, 请求ID=%s", dockerResponse.Type, dockerResponse.RequestID)
 
 			// 处理Docker响应
@@ -1500,8 +1891,14 @@ func handleWebSocket(conn *SafeConn, server *models.Server, interrupt chan struc
 				log.Printf("警告: 收到的Docker响应消息没有请求ID")
 			}
 
-		case "docker_logs_stream_data", "docker_logs_stream_end":
-			// 处理Agent发回的日志流数据/结束消息，转发给对应的用户连接
+		case "file_scan_progress", "file_scan_result":
+			// 处理Agent发回的扫描进度/结果，转发给对应的用户连接
+			forwardFileScanMessage(message)
+
+		case "docker_logs_stream_data", "docker_logs_stream_end", "docker_stats_stream_data", "docker_stats_stream_end",
+			"docker_pull_stream_data", "docker_pull_stream_end", "diagnostic_stream_data", "diagnostic_stream_end",
+			"file_tail_stream_data", "file_tail_stream_end", "journal_query_data", "journal_query_end":
+			// 处理Agent发回的日志流、资源统计流、镜像拉取进度、网络诊断输出、文件跟踪内容和 journal 日志及结束消息，转发给对应的用户连接
 			var streamMsg struct {
 				Type     string                 `json:"type"`
 				StreamID string                 `json:"stream_id"`
@@ -1531,7 +1928,7 @@ func handleWebSocket(conn *SafeConn, server *models.Server, interrupt chan struc
 			}
 
 			// 如果是流结束消息，清理映射
-			if msg.Type == "docker_logs_stream_end" {
+			if strings.HasSuffix(msg.Type, "_end") {
 				ActiveLogStreamConnections.Delete(streamMsg.StreamID)
 				log.Printf("日志流 %s 已结束，已清理连接映射", streamMsg.StreamID)
 			}
@@ -1618,7 +2015,8 @@ func handleWebSocket(conn *SafeConn, server *models.Server, interrupt chan struc
 
 		case "file_list_response", "file_content_response", "file_tree_response", "file_upload_response",
 			"docker_file_list", "docker_file_content", "docker_file_tree", "docker_file_upload",
-			"chunked_upload_init_ack", "chunked_upload_chunk_ack", "chunked_upload_complete_ack", "chunked_upload_cancel_ack":
+			"chunked_upload_init_ack", "chunked_upload_chunk_ack", "chunked_upload_complete_ack", "chunked_upload_cancel_ack",
+			"chunked_download_chunk_ack", "file_archive_ack":
 			// 处理文件 / 容器文件操作响应
 			var fileResponse struct {
 				Type      string                 `json:"type"`
@@ -1673,6 +2071,14 @@ func handleWebSocket(conn *SafeConn, server *models.Server, interrupt chan struc
 				log.Printf("收到Agent升级响应: server=%d request_id=%s", server.ID, upgradeResp.RequestID)
 			}
 
+			// Agent 进入排空/重启阶段后即将断开，标记为升级中而不是离线
+			switch status {
+			case "draining", "restarting":
+				markAgentUpgrading(server.ID)
+			case "failed":
+				upgradingAgents.Delete(server.ID)
+			}
+
 			// 推送升级状态到前端监控订阅者
 			broadcastPublicMonitor(server.ID, map[string]interface{}{
 				"type":       "agent_upgrade_status",
@@ -1690,6 +2096,28 @@ func handleWebSocket(conn *SafeConn, server *models.Server, interrupt chan struc
 	}
 }
 
+// markAgentUpgrading 记录Agent进入升级重启阶段
+func markAgentUpgrading(serverID uint) {
+	upgradingAgents.Store(serverID, time.Now())
+	if err := models.UpdateServerStatusOnly(serverID, models.ServerStatusUpgrading); err != nil {
+		log.Printf("更新服务器 %d 升级状态失败: %v", serverID, err)
+	}
+}
+
+// isAgentUpgrading 判断Agent是否处于升级宽限期内
+func isAgentUpgrading(serverID uint) bool {
+	val, ok := upgradingAgents.Load(serverID)
+	if !ok {
+		return false
+	}
+	since, ok := val.(time.Time)
+	if !ok || time.Since(since) > models.AgentUpgradeGracePeriod {
+		upgradingAgents.Delete(serverID)
+		return false
+	}
+	return true
+}
+
 // 处理Shell命令
 func handleShellCommand(conn *SafeConn, server *models.Server, payload json.RawMessage) {
 	log.Printf("处理终端命令")
@@ -1982,8 +2410,9 @@ func handleDockerCommand(conn *SafeConn, server *models.Server, payload json.Raw
 	log.Printf("Docker命令请求已发送到Agent，请求ID: %s", requestID)
 }
 
-// handleDockerLogsStream 处理Docker日志流请求（用户 → Agent 转发）
-func handleDockerLogsStream(conn *SafeConn, server *models.Server, payload json.RawMessage) {
+// handleDockerStream 处理Docker日志流、容器资源统计流等流式请求（用户 → Agent 转发），msgType 为转发给Agent的消息类型。
+// 网络诊断（ping/traceroute/mtr）的输出和 journal 查询结果同样按 stream_id 转发，也由这里处理
+func handleDockerStream(conn *SafeConn, server *models.Server, msgType string, payload json.RawMessage) {
 	var reqData struct {
 		Action   string `json:"action"`
 		StreamID string `json:"stream_id"`
@@ -1994,10 +2423,10 @@ func handleDockerLogsStream(conn *SafeConn, server *models.Server, payload json.
 		return
 	}
 
-	log.Printf("收到日志流请求: action=%s, stream_id=%s, 服务器ID=%d", reqData.Action, reqData.StreamID, server.ID)
+	log.Printf("收到%s请求: action=%s, stream_id=%s, 服务器ID=%d", msgType, reqData.Action, reqData.StreamID, server.ID)
 
 	if reqData.StreamID == "" {
-		sendErrorMessage(conn, "日志流请求缺少 stream_id")
+		sendErrorMessage(conn, "流请求缺少 stream_id")
 		return
 	}
 
@@ -2016,15 +2445,36 @@ func handleDockerLogsStream(conn *SafeConn, server *models.Server, payload json.
 		return
 	}
 
+	// 文件跟踪与文件内容接口使用相同的路径限制，直接以结束消息告知前端
+	if msgType == "file_tail_stream" && reqData.Action == "start" {
+		var tailReq struct {
+			Path string `json:"path"`
+		}
+		_ = json.Unmarshal(payload, &tailReq)
+		if !isValidFilePath(tailReq.Path) {
+			_ = conn.WriteJSON(map[string]interface{}{
+				"type":      "file_tail_stream_end",
+				"stream_id": reqData.StreamID,
+				"data":      map[string]interface{}{"reason": "无效的文件路径或禁止访问该文件"},
+			})
+			return
+		}
+	}
+
 	// start: 注册用户连接映射，以便后续转发日志流数据
 	if reqData.Action == "start" {
 		ActiveLogStreamConnections.Store(reqData.StreamID, conn)
 		log.Printf("已注册日志流 %s 的用户连接", reqData.StreamID)
 	}
 
+	// 镜像拉取附加该仓库保存的凭据
+	if msgType == "docker_pull_stream" && reqData.Action == "start" {
+		payload = withPullAuth(server.ID, payload)
+	}
+
 	// 构建转发给Agent的消息（保持原始 payload）
 	agentMsg := map[string]interface{}{
-		"type":    "docker_logs_stream",
+		"type":    msgType,
 		"payload": json.RawMessage(payload),
 	}
 
@@ -2044,7 +2494,7 @@ func handleDockerLogsStream(conn *SafeConn, server *models.Server, payload json.
 		log.Printf("已清理日志流 %s 的用户连接映射", reqData.StreamID)
 	}
 
-	log.Printf("日志流请求已转发到Agent: action=%s, stream_id=%s", reqData.Action, reqData.StreamID)
+	log.Printf("%s请求已转发到Agent: action=%s, stream_id=%s", msgType, reqData.Action, reqData.StreamID)
 }
 
 // 发送错误消息
@@ -2181,10 +2631,26 @@ func GetAgentConnection(serverID uint) (*websocket.Conn, error) {
 	return safeConn.Conn, nil
 }
 
+// SendToAgent 通过Agent主动建立的WebSocket连接发送消息，使用SafeConn的写锁串行化写入。
+// 面板与Agent之间的所有交互（文件、Docker、终端、日志等）都经由这条反向连接完成，
+// 面板从不主动连接Agent，因此位于NAT或防火墙之后、没有入站端口的Agent同样可以使用全部功能。
+func SendToAgent(serverID uint, message []byte) error {
+	val, ok := ActiveAgentConnections.Load(serverID)
+	if !ok {
+		return fmt.Errorf("服务器(ID: %d)未连接", serverID)
+	}
+	safeConn, ok := val.(*SafeConn)
+	if !ok || safeConn == nil || safeConn.Conn == nil {
+		return fmt.Errorf("服务器(ID: %d)连接类型错误", serverID)
+	}
+	return safeConn.WriteMessage(websocket.TextMessage, message)
+}
+
 // 在package init函数中设置utils.GetAgentConnectionFunc
 func init() {
 	// 导入utils包
 	utils.GetAgentConnectionFunc = GetAgentConnection
+	utils.SendToAgentFunc = SendToAgent
 }
 
 // requestTerminalWorkingDirectoryViaWebSocket 通过WebSocket获取终端当前工作目录
@@ -2209,7 +2675,9 @@ func requestTerminalWorkingDirectoryViaWebSocket(serverID uint, sessionID string
 	defer dockerResponseChannels.Delete(requestID)
 
 	// 注册待处理请求，以便在Agent断开时能快速失败
-	registerPendingRequest(serverID, requestID)
+	if err := registerPendingRequest(serverID, requestID); err != nil {
+		return "", err
+	}
 	defer unregisterPendingRequest(serverID, requestID)
 
 	// 构造获取工作目录的消息
@@ -2,6 +2,9 @@ package main
 
 import (
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/gzip"
@@ -46,6 +49,13 @@ func startCertificateRenewalService() *services.CertificateRenewalService {
 	return renewalService
 }
 
+// 启动可用性检查服务
+func startUptimeService() *services.UptimeService {
+	uptimeService := services.GetUptimeService()
+	go uptimeService.Start()
+	return uptimeService
+}
+
 // 启动数据清理服务
 func startDataCleanupService() {
 	// 每天凌晨3点执行数据清理
@@ -97,6 +107,43 @@ func cleanupOldData() {
 		log.Printf("成功清理 %s 之前的过期监控数据", cutoff.Format("2006-01-02 15:04:05"))
 	}
 
+	if deleted, err := models.DeleteContainerStatsBefore(cutoff); err != nil {
+		log.Printf("清理过期容器资源统计失败: %v", err)
+	} else if deleted > 0 {
+		log.Printf("成功清理过期容器资源统计，共删除 %d 条", deleted)
+	}
+
+	if deleted, err := models.DeleteDiskMountStatsBefore(cutoff); err != nil {
+		log.Printf("清理过期挂载点使用情况失败: %v", err)
+	} else if deleted > 0 {
+		log.Printf("成功清理过期挂载点使用情况，共删除 %d 条", deleted)
+	}
+
+	if deleted, err := models.DeleteUptimeResultsBefore(cutoff); err != nil {
+		log.Printf("清理过期可用性检查结果失败: %v", err)
+	} else if deleted > 0 {
+		log.Printf("成功清理过期可用性检查结果，共删除 %d 条", deleted)
+	}
+
+	if deleted, err := models.DeleteMeshLatenciesBefore(cutoff); err != nil {
+		log.Printf("清理过期节点互测结果失败: %v", err)
+	} else if deleted > 0 {
+		log.Printf("成功清理过期节点互测结果，共删除 %d 条", deleted)
+	}
+
+	if deleted, err := models.DeleteLogEntriesBefore(cutoff); err != nil {
+		log.Printf("清理过期采集日志失败: %v", err)
+	} else if deleted > 0 {
+		log.Printf("成功清理过期采集日志，共删除 %d 条", deleted)
+	}
+
+	// 每小时流量汇总保留时间较长，用于按月统计
+	if deleted, err := models.DeleteTrafficHourlyBefore(time.Now().Add(-models.TrafficHourlyRetention)); err != nil {
+		log.Printf("清理过期流量汇总失败: %v", err)
+	} else if deleted > 0 {
+		log.Printf("成功清理过期流量汇总，共删除 %d 条", deleted)
+	}
+
 	// 2. 清理生命探针数据（使用新的分类保留策略）
 	jobs.CleanupLifeProbeData()
 
@@ -110,6 +157,16 @@ func cleanupOldData() {
 		} else {
 			log.Printf("成功清理过期预警记录，共删除 %d 条", deleted)
 		}
+		if deleted, err := models.DeleteIncidentsBefore(alertCutoff); err != nil {
+			log.Printf("清理过期事件失败: %v", err)
+		} else if deleted > 0 {
+			log.Printf("成功清理过期事件，共删除 %d 条", deleted)
+		}
+		if deleted, err := models.DeleteOOMEventsBefore(alertCutoff); err != nil {
+			log.Printf("清理过期OOM事件失败: %v", err)
+		} else if deleted > 0 {
+			log.Printf("成功清理过期OOM事件，共删除 %d 条", deleted)
+		}
 	}
 
 	// 4. 清理生命探针事件日志（保留30天）
@@ -119,6 +176,18 @@ func cleanupOldData() {
 	jobs.CleanupStaleLifeProbes()
 }
 
+// handleShutdownSignals 收到退出信号时先写入缓冲中的监控数据再退出
+func handleShutdownSignals() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigCh
+		log.Printf("收到信号 %v，正在写入缓冲的监控数据...", sig)
+		models.StopMonitorBatchWriter()
+		os.Exit(0)
+	}()
+}
+
 func main() {
 	// 初始化配置
 	cfg := config.LoadConfig()
@@ -128,6 +197,18 @@ func main() {
 		log.Fatalf("数据库初始化失败: %v", err)
 	}
 
+	// 镜像仓库凭据等敏感数据的加密密钥
+	if err := models.InitCredentialKey(cfg.CredentialKey, cfg.CredentialKeyFile); err != nil {
+		log.Fatalf("凭据加密密钥初始化失败: %v", err)
+	}
+
+	// 启动监控数据批量写入（未配置时逐条写入）
+	models.StartMonitorBatchWriter(cfg.MonitorBatchSize, cfg.MonitorFlushInterval)
+	handleShutdownSignals()
+
+	// GitHub Release API 重试与超时
+	services.ConfigureReleaseAPI(cfg.ReleaseAPIRetries, cfg.ReleaseAPITimeout)
+
 	// 启动服务器状态检查器
 	startServerStatusChecker()
 
@@ -139,6 +220,10 @@ func main() {
 	renewalService := startCertificateRenewalService()
 	defer renewalService.Stop()
 
+	// 启动可用性检查服务
+	uptimeService := startUptimeService()
+	defer uptimeService.Stop()
+
 	// 启动数据清理服务
 	startDataCleanupService()
 
@@ -8,8 +8,20 @@ import (
 	"github.com/user/server-ops-backend/models"
 )
 
+// readOnlyAllowedRoutes 只读模式下仍然允许的非 GET 操作路由：只读取服务器上的数据，或停止/取消进行中的操作
+var readOnlyAllowedRoutes = map[string]bool{
+	"POST /api/servers/:id/files/diff":                        true,
+	"POST /api/servers/:id/files/snapshots":                   true,
+	"DELETE /api/servers/:id/files/snapshots/:snapshot_id":    true,
+	"POST /api/servers/:id/docker/composes/:name/validate":    true,
+	"DELETE /api/servers/:id/terminal/captures/:capture_id":   true,
+	"DELETE /api/servers/:id/files/upload/chunked/:upload_id": true,
+	"DELETE /api/servers/:id/terminal/sessions/:session_id":   true,
+}
+
 // MonitorOnlyGuard 拦截监控模式服务器的操作类请求。
-// 当 Server.AgentType == "monitor" 时，该中间件返回 403 Forbidden。
+// 当 Server.AgentType == "monitor" 时，该中间件返回 403 Forbidden；
+// 服务器处于只读模式时，除 readOnlyAllowedRoutes 外的非 GET 请求同样返回 403。
 // 仅适用于挂载在 /servers/:id 下的操作路由组（terminal、file、process、docker、nginx 等）。
 func MonitorOnlyGuard() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -40,6 +52,14 @@ func MonitorOnlyGuard() gin.HandlerFunc {
 			return
 		}
 
+		if server.ReadOnlyMode && c.Request.Method != http.MethodGet && !readOnlyAllowedRoutes[c.Request.Method+" "+c.FullPath()] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":      "该服务器处于只读模式，不允许修改类操作",
+				"error_code": "read_only_mode",
+			})
+			return
+		}
+
 		c.Next()
 	}
 }
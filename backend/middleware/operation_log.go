@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/server-ops-backend/models"
+)
+
+// operationCategories 路由中 /servers/:id/ 之后第一段与操作类别的对应关系
+var operationCategories = map[string]string{
+	"terminal":     "terminal",
+	"files":        "file",
+	"processes":    "process",
+	"services":     "service",
+	"packages":     "package",
+	"docker":       "docker",
+	"nginx":        "nginx",
+	"websites":     "nginx",
+	"cert":         "nginx",
+	"certificates": "nginx",
+}
+
+// maxCapturedErrorBody 失败响应最多读取的字节数，用于提取错误信息
+const maxCapturedErrorBody = 4096
+
+// errorCaptureWriter 在响应失败时保留响应体开头，用于记录错误原因
+type errorCaptureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *errorCaptureWriter) Write(data []byte) (int, error) {
+	if w.Status() >= http.StatusBadRequest && w.body.Len() < maxCapturedErrorBody {
+		remaining := maxCapturedErrorBody - w.body.Len()
+		if len(data) < remaining {
+			remaining = len(data)
+		}
+		w.body.Write(data[:remaining])
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// RecordServerOperations 记录 /servers/:id 下的修改类操作（非 GET 请求），
+// 包括执行的用户、路由参数和结果，供服务器详情页展示最近操作
+func RecordServerOperations() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		serverID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		writer := &errorCaptureWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		route := strings.TrimPrefix(c.FullPath(), "/api/servers/:id")
+		op := &models.ServerOperation{
+			ServerID: uint(serverID),
+			Category: operationCategory(route),
+			Action:   c.Request.Method + " " + route,
+			Target:   operationTarget(c.Params),
+			Status:   writer.Status(),
+			Success:  writer.Status() < http.StatusBadRequest,
+		}
+		if value, ok := c.Get("userId"); ok {
+			op.UserID, _ = value.(uint)
+		}
+		op.Username = c.GetString("username")
+		if !op.Success {
+			op.Error = operationError(writer.body.Bytes())
+		}
+		if err := models.RecordServerOperation(op); err != nil {
+			log.Printf("记录服务器 %d 的操作失败: %v", serverID, err)
+		}
+	}
+}
+
+// operationCategory 根据路由得到操作类别，未知的路由使用第一段路径
+func operationCategory(route string) string {
+	segment := strings.SplitN(strings.TrimPrefix(route, "/"), "/", 2)[0]
+	if category, ok := operationCategories[segment]; ok {
+		return category
+	}
+	return segment
+}
+
+// operationTarget 将除服务器ID以外的路由参数拼成 "key=value" 形式
+func operationTarget(params gin.Params) string {
+	var parts []string
+	for _, p := range params {
+		if p.Key == "id" {
+			continue
+		}
+		parts = append(parts, p.Key+"="+p.Value)
+	}
+	return strings.Join(parts, " ")
+}
+
+// operationError 从失败响应的 JSON 中提取 error 字段
+func operationError(body []byte) string {
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err == nil && resp.Error != "" {
+		return resp.Error
+	}
+	return strings.TrimSpace(string(body))
+}
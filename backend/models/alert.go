@@ -3,6 +3,7 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,11 +13,24 @@ import (
 // AlertSetting 预警设置模型
 type AlertSetting struct {
 	gorm.Model
-	Type        string  `json:"type" gorm:"type:varchar(20);not null"`  // cpu, memory, network, status
+	Type        string  `json:"type" gorm:"type:varchar(20);not null"`  // cpu, memory, network, status, zombie, oom, duplicate, agent_error, temperature
 	Threshold   float64 `json:"threshold" gorm:"not null"`              // 阈值百分比(0-100)或具体数值，对status类型：1表示上线报警，2表示离线报警，3表示上线和离线都报警
 	Duration    int     `json:"duration" gorm:"not null"`               // 持续时间(秒)
+	Smoothing   float64 `json:"smoothing" gorm:"default:0"`             // 指数移动平均系数(0-1)，按平滑后的值判断阈值，0表示使用原始值；仅对cpu、memory、network、zombie、agent_error、temperature有效
 	Enabled     bool    `json:"enabled" gorm:"default:true"`            // 是否启用
 	ServerID    uint    `json:"server_id" gorm:"default:0"`             // 0表示全局设置，非0表示特定服务器
+	ChannelIDs  string  `json:"channel_ids" gorm:"type:varchar(255)"`   // 指定的通知渠道ID，逗号分隔，为空表示按预警分类路由
+}
+
+// NotifyChannelIDs 返回规则指定的通知渠道ID集合，未指定时为空
+func (s *AlertSetting) NotifyChannelIDs() map[uint]bool {
+	ids := make(map[uint]bool)
+	for _, item := range strings.Split(s.ChannelIDs, ",") {
+		if id, err := strconv.ParseUint(strings.TrimSpace(item), 10, 64); err == nil && id > 0 {
+			ids[uint(id)] = true
+		}
+	}
+	return ids
 }
 
 // NotificationChannel 通知渠道模型
@@ -26,6 +40,64 @@ type NotificationChannel struct {
 	Name        string `json:"name" gorm:"type:varchar(50);not null"`  // 渠道名称
 	Config      string `json:"config" gorm:"type:text"`                // JSON格式配置，包含密钥等
 	Enabled     bool   `json:"enabled" gorm:"default:true"`            // 是否启用
+	Categories  string `json:"categories" gorm:"type:varchar(255)"`    // 接收的预警分类，逗号分隔，为空表示接收全部分类
+}
+
+// 预警分类，按产生预警的来源组件划分，用于筛选预警记录和按分类路由通知
+const (
+	AlertCategoryResource     = "resource"     // 资源指标：cpu、memory、network、zombie、temperature
+	AlertCategoryAvailability = "availability" // 在线状态：status
+	AlertCategorySystem       = "system"       // 系统事件：oom、agent_error
+	AlertCategorySecurity     = "security"     // 安全相关：duplicate
+	AlertCategoryCertificate  = "certificate"  // 证书相关
+)
+
+// AlertCategories 所有预警分类
+var AlertCategories = []string{
+	AlertCategoryResource,
+	AlertCategoryAvailability,
+	AlertCategorySystem,
+	AlertCategorySecurity,
+	AlertCategoryCertificate,
+}
+
+// AlertCategoryOf 返回预警类型所属的分类，未知类型归入 system
+func AlertCategoryOf(alertType string) string {
+	switch alertType {
+	case "cpu", "memory", "network", "zombie", "temperature", "rule":
+		return AlertCategoryResource
+	case "status", "uptime":
+		return AlertCategoryAvailability
+	case "duplicate":
+		return AlertCategorySecurity
+	case "certificate", "cert_expiry":
+		return AlertCategoryCertificate
+	default:
+		return AlertCategorySystem
+	}
+}
+
+// IsValidAlertCategory 判断是否为已知的预警分类
+func IsValidAlertCategory(category string) bool {
+	for _, c := range AlertCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// AcceptsCategory 判断通知渠道是否接收该分类的预警，未配置分类时接收全部
+func (c *NotificationChannel) AcceptsCategory(category string) bool {
+	if strings.TrimSpace(c.Categories) == "" {
+		return true
+	}
+	for _, item := range strings.Split(c.Categories, ",") {
+		if strings.TrimSpace(item) == category {
+			return true
+		}
+	}
+	return false
 }
 
 // AlertRecord 预警记录模型
@@ -33,13 +105,21 @@ type AlertRecord struct {
 	gorm.Model
 	ServerID     uint      `json:"server_id" gorm:"index"`
 	ServerName   string    `json:"server_name"`
-	AlertType    string    `json:"alert_type"`          // cpu, memory, network
+	AlertType    string    `json:"alert_type"`          // cpu, memory, network, zombie, oom
+	Category     string    `json:"category" gorm:"type:varchar(20);index"` // 预警分类，见 AlertCategoryOf
 	Value        float64   `json:"value"`               // 触发时的值
 	Threshold    float64   `json:"threshold"`           // 阈值
 	Resolved     bool      `json:"resolved"`            // 是否已解决
 	ResolvedAt   time.Time `json:"resolved_at"`         // 解决时间
 	NotifiedAt   time.Time `json:"notified_at"`         // 通知时间
 	ChannelIDs   string    `json:"channel_ids"`         // 通知渠道ID列表，逗号分隔
+
+	Acknowledged       bool      `json:"acknowledged"`                               // 是否已确认，确认后停止升级
+	AcknowledgedAt     time.Time `json:"acknowledged_at"`                            // 确认时间
+	AcknowledgedBy     string    `json:"acknowledged_by" gorm:"type:varchar(50)"`    // 确认人
+	EscalationPolicyID uint      `json:"escalation_policy_id" gorm:"default:0;index"` // 匹配的升级策略，0 表示按分类路由通知
+	EscalationStep     int       `json:"escalation_step"`                            // 已执行的升级步骤数
+	RuleID             uint      `json:"rule_id" gorm:"default:0;index"`             // 触发的表达式预警规则，0 表示阈值预警
 }
 
 // GetGlobalAlertSettings 获取全局预警设置
@@ -122,7 +202,7 @@ func DeleteNotificationChannel(id uint) error {
 }
 
 // GetAlertRecords 获取预警记录
-func GetAlertRecords(serverID uint, alertType string, onlyUnresolved bool, page, limit int) ([]AlertRecord, int64, error) {
+func GetAlertRecords(serverID uint, alertType, category string, onlyUnresolved bool, page, limit int) ([]AlertRecord, int64, error) {
 	var records []AlertRecord
 	var total int64
 	
@@ -135,6 +215,10 @@ func GetAlertRecords(serverID uint, alertType string, onlyUnresolved bool, page,
 	if alertType != "" {
 		query = query.Where("alert_type = ?", alertType)
 	}
+
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
 	
 	if onlyUnresolved {
 		query = query.Where("resolved = ?", false)
@@ -163,14 +247,46 @@ func GetAlertRecordByID(id uint, record *AlertRecord) error {
 	return DB.First(record, id).Error
 }
 
-// CreateAlertRecord 创建预警记录
+// CreateAlertRecord 创建预警记录，未指定分类时按预警类型填写；未解决的预警同时打开事件
 func CreateAlertRecord(record *AlertRecord) error {
-	return DB.Create(record).Error
+	if record.Category == "" {
+		record.Category = AlertCategoryOf(record.AlertType)
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		if record.Resolved {
+			return nil // 上线、OOM 等即时事件不打开事件
+		}
+		return openIncident(tx, record)
+	})
+}
+
+// BackfillAlertRecordCategories 为升级前没有分类的预警记录按预警类型补充分类
+func BackfillAlertRecordCategories() error {
+	var types []string
+	if err := DB.Model(&AlertRecord{}).Where("category IS NULL OR category = ''").Distinct().Pluck("alert_type", &types).Error; err != nil {
+		return err
+	}
+	for _, alertType := range types {
+		if err := DB.Model(&AlertRecord{}).Where("(category IS NULL OR category = '') AND alert_type = ?", alertType).
+			Update("category", AlertCategoryOf(alertType)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// UpdateAlertRecord 更新预警记录
+// UpdateAlertRecord 更新预警记录，预警已解决时同时关闭对应的事件
 func UpdateAlertRecord(record *AlertRecord) error {
-	return DB.Save(record).Error
+	if err := DB.Save(record).Error; err != nil {
+		return err
+	}
+	if !record.Resolved {
+		return nil
+	}
+	return ResolveAlertIncident(record.ID, "", "", record.ResolvedAt)
 }
 
 // GetChannelConfig 解析通知渠道配置
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AlertEscalationStep 升级链中的一步：预警触发 DelayMinutes 分钟后仍未确认、未解决时通知 ChannelID
+type AlertEscalationStep struct {
+	ChannelID    uint `json:"channel_id"`
+	DelayMinutes int  `json:"delay_minutes"`
+}
+
+// AlertEscalationPolicy 预警升级策略，按预警类型或分类匹配预警，匹配后按步骤依次通知，取代按分类路由的通知
+type AlertEscalationPolicy struct {
+	gorm.Model
+	Name      string `json:"name" gorm:"type:varchar(50);not null"`
+	AlertType string `json:"alert_type" gorm:"type:varchar(20)"` // 适用的预警类型，为空时按分类匹配
+	Category  string `json:"category" gorm:"type:varchar(20)"`   // 适用的预警分类，与预警类型均为空时适用于全部预警
+	Steps     string `json:"steps" gorm:"type:text"`             // JSON格式的升级步骤，按延迟升序
+	Enabled   bool   `json:"enabled" gorm:"default:true"`
+}
+
+// GetSteps 解析升级步骤
+func (p *AlertEscalationPolicy) GetSteps() ([]AlertEscalationStep, error) {
+	var steps []AlertEscalationStep
+	if p.Steps == "" {
+		return steps, nil
+	}
+	if err := json.Unmarshal([]byte(p.Steps), &steps); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+// Matches 返回策略与预警的匹配程度：2 为预警类型匹配，1 为分类匹配，0 为适用于全部预警，-1 为不匹配
+func (p *AlertEscalationPolicy) Matches(alertType, category string) int {
+	switch {
+	case p.AlertType != "":
+		if p.AlertType == alertType {
+			return 2
+		}
+		return -1
+	case p.Category != "":
+		if p.Category == category {
+			return 1
+		}
+		return -1
+	default:
+		return 0
+	}
+}
+
+// MatchEscalationPolicy 从启用的策略中选出最具体的匹配项，同等匹配时取先创建的策略；没有匹配时返回 nil
+func MatchEscalationPolicy(policies []AlertEscalationPolicy, alertType, category string) *AlertEscalationPolicy {
+	var best *AlertEscalationPolicy
+	bestScore := -1
+	for i := range policies {
+		if !policies[i].Enabled {
+			continue
+		}
+		if score := policies[i].Matches(alertType, category); score > bestScore {
+			best, bestScore = &policies[i], score
+		}
+	}
+	return best
+}
+
+// GetAllEscalationPolicies 获取所有升级策略
+func GetAllEscalationPolicies() ([]AlertEscalationPolicy, error) {
+	var policies []AlertEscalationPolicy
+	result := DB.Order("id ASC").Find(&policies)
+	return policies, result.Error
+}
+
+// GetEscalationPolicyByID 通过ID获取升级策略
+func GetEscalationPolicyByID(id uint, policy *AlertEscalationPolicy) error {
+	return DB.First(policy, id).Error
+}
+
+// CreateEscalationPolicy 创建升级策略
+func CreateEscalationPolicy(policy *AlertEscalationPolicy) error {
+	return DB.Create(policy).Error
+}
+
+// UpdateEscalationPolicy 更新升级策略
+func UpdateEscalationPolicy(policy *AlertEscalationPolicy) error {
+	return DB.Save(policy).Error
+}
+
+// DeleteEscalationPolicy 删除升级策略，进行中的升级在下一次检查时停止
+func DeleteEscalationPolicy(id uint) error {
+	return DB.Delete(&AlertEscalationPolicy{}, id).Error
+}
+
+// GetPendingEscalations 获取仍在升级中的预警：匹配了升级策略且未确认、未解决
+func GetPendingEscalations() ([]AlertRecord, error) {
+	var records []AlertRecord
+	result := DB.Where("escalation_policy_id > 0 AND acknowledged = ? AND resolved = ?", false, false).
+		Order("created_at ASC").Find(&records)
+	return records, result.Error
+}
+
+// AdvanceAlertEscalation 记录升级进度。只更新仍未确认、未解决的记录，避免覆盖升级期间用户的确认
+func AdvanceAlertEscalation(id uint, step int, channelIDs string) error {
+	return DB.Model(&AlertRecord{}).
+		Where("id = ? AND acknowledged = ? AND resolved = ?", id, false, false).
+		Updates(map[string]interface{}{"escalation_step": step, "channel_ids": channelIDs}).Error
+}
+
+// AcknowledgeAlertRecord 确认预警，确认后停止升级，对应的事件同时标记为已确认。返回 false 表示记录已确认或已解决
+func AcknowledgeAlertRecord(id uint, by string, at time.Time) (bool, error) {
+	acked := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&AlertRecord{}).
+			Where("id = ? AND acknowledged = ? AND resolved = ?", id, false, false).
+			Updates(map[string]interface{}{"acknowledged": true, "acknowledged_at": at, "acknowledged_by": by})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		acked = true
+		return acknowledgeAlertIncident(tx, id, by, at)
+	})
+	return acked && err == nil, err
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// AlertRule 表达式预警规则，例如 "cpu > 90 AND load_avg_5 > cores*2 for 5m"。
+// 条件持续满足 for 子句的时长后告警；设置了恢复条件时，恢复条件满足才解除告警，用于避免在阈值附近反复告警
+type AlertRule struct {
+	gorm.Model
+	Name              string `json:"name" gorm:"type:varchar(50);not null"`
+	Expression        string `json:"expression" gorm:"type:text"`
+	RecoverExpression string `json:"recover_expression" gorm:"type:text"` // 为空时触发条件不再满足即恢复
+	ServerID          uint   `json:"server_id" gorm:"default:0;index"`    // 0 表示适用于所有服务器
+	ChannelIDs        string `json:"channel_ids"`                         // 为空时按分类路由
+	Enabled           bool   `json:"enabled" gorm:"default:true"`
+}
+
+// AppliesTo 判断规则是否适用于服务器
+func (r *AlertRule) AppliesTo(serverID uint) bool {
+	return r.ServerID == 0 || r.ServerID == serverID
+}
+
+// GetAllAlertRules 获取所有预警规则
+func GetAllAlertRules() ([]AlertRule, error) {
+	var rules []AlertRule
+	result := DB.Order("id ASC").Find(&rules)
+	return rules, result.Error
+}
+
+// GetEnabledAlertRules 获取启用的预警规则
+func GetEnabledAlertRules() ([]AlertRule, error) {
+	var rules []AlertRule
+	result := DB.Where("enabled = ?", true).Order("id ASC").Find(&rules)
+	return rules, result.Error
+}
+
+// GetAlertRuleByID 通过ID获取预警规则
+func GetAlertRuleByID(id uint, rule *AlertRule) error {
+	return DB.First(rule, id).Error
+}
+
+// CreateAlertRule 创建预警规则
+func CreateAlertRule(rule *AlertRule) error {
+	return DB.Create(rule).Error
+}
+
+// UpdateAlertRule 更新预警规则
+func UpdateAlertRule(rule *AlertRule) error {
+	return DB.Save(rule).Error
+}
+
+// DeleteAlertRule 删除预警规则，未解决的规则预警在下一次检查时解除
+func DeleteAlertRule(id uint) error {
+	return DB.Delete(&AlertRule{}, id).Error
+}
+
+// GetUnresolvedRuleAlerts 获取规则产生的未解决预警
+func GetUnresolvedRuleAlerts() ([]AlertRecord, error) {
+	var records []AlertRecord
+	result := DB.Where("rule_id > 0 AND resolved = ?", false).Find(&records)
+	return records, result.Error
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 检查脚本的数量、内容和间隔限制，与 Agent 一致
+const (
+	MaxCheckScriptsPerServer   = 20
+	MaxCheckScriptSize         = 64 * 1024
+	MinCheckScriptInterval     = 30
+	DefaultCheckScriptInterval = 60
+	DefaultCheckScriptTimeout  = 10
+	MaxCheckScriptTimeout      = 60
+)
+
+// CheckScriptInterpreters 支持的解释器，Agent 上需要已安装对应的命令
+var CheckScriptInterpreters = []string{"sh", "bash", "python3", "powershell", "cmd"}
+
+// 脚本名称用作预警变量 script.<名称>.<指标> 的一部分，只允许小写字母、数字和下划线
+var checkScriptNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// CheckScript 为单台服务器配置的检查脚本，由 Agent 按间隔执行并上报退出码和 key=value 指标
+type CheckScript struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	ServerID    uint   `json:"server_id" gorm:"index;not null"`
+	Name        string `json:"name" gorm:"type:varchar(64);not null"`
+	Interpreter string `json:"interpreter" gorm:"type:varchar(20);not null"` // sh、bash、python3、powershell 或 cmd
+	Content     string `json:"content" gorm:"type:text"`
+	Interval    int    `json:"interval"` // 执行间隔(秒)
+	Timeout     int    `json:"timeout"`  // 单次执行超时(秒)
+	Enabled     bool   `json:"enabled"`  // 新建时由控制器默认启用；不设数据库默认值，否则无法创建停用的脚本
+
+	LastRunAt      time.Time `json:"last_run_at"`
+	LastExitCode   int       `json:"last_exit_code"` // 未能启动或超时为 -1
+	LastDurationMs int64     `json:"last_duration_ms"`
+	LastOutput     string    `json:"last_output" gorm:"type:text"`
+	LastError      string    `json:"last_error" gorm:"type:varchar(1000)"`
+	LastMetrics    string    `json:"-" gorm:"type:text"` // JSON格式的最近一次解析出的指标
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Normalize 填充默认值并校验名称、解释器、内容和间隔
+func (s *CheckScript) Normalize() error {
+	s.Name = strings.TrimSpace(s.Name)
+	if !checkScriptNamePattern.MatchString(s.Name) {
+		return fmt.Errorf("名称只能包含小写字母、数字和下划线，且以字母开头")
+	}
+	valid := false
+	for _, interpreter := range CheckScriptInterpreters {
+		if s.Interpreter == interpreter {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fmt.Errorf("不支持的解释器: %s", s.Interpreter)
+	}
+	if strings.TrimSpace(s.Content) == "" {
+		return fmt.Errorf("脚本内容不能为空")
+	}
+	if len(s.Content) > MaxCheckScriptSize {
+		return fmt.Errorf("脚本内容不能超过 %d KB", MaxCheckScriptSize/1024)
+	}
+	if s.Interval == 0 {
+		s.Interval = DefaultCheckScriptInterval
+	}
+	if s.Interval < MinCheckScriptInterval {
+		return fmt.Errorf("执行间隔不能小于 %d 秒", MinCheckScriptInterval)
+	}
+	if s.Timeout == 0 {
+		s.Timeout = DefaultCheckScriptTimeout
+	}
+	if s.Timeout < 1 || s.Timeout > MaxCheckScriptTimeout {
+		return fmt.Errorf("超时需在 1 到 %d 秒之间", MaxCheckScriptTimeout)
+	}
+	if s.Timeout > s.Interval {
+		return fmt.Errorf("超时不能大于执行间隔")
+	}
+	return nil
+}
+
+// GetLastMetrics 解析最近一次上报的指标
+func (s *CheckScript) GetLastMetrics() map[string]float64 {
+	if s.LastMetrics == "" {
+		return nil
+	}
+	var metrics map[string]float64
+	if err := json.Unmarshal([]byte(s.LastMetrics), &metrics); err != nil {
+		return nil
+	}
+	return metrics
+}
+
+// GetServerCheckScripts 获取服务器的检查脚本
+func GetServerCheckScripts(serverID uint) ([]CheckScript, error) {
+	var scripts []CheckScript
+	err := DB.Where("server_id = ?", serverID).Order("id ASC").Find(&scripts).Error
+	return scripts, err
+}
+
+// GetEnabledCheckScripts 获取服务器已启用的检查脚本，下发给 Agent 执行
+func GetEnabledCheckScripts(serverID uint) ([]CheckScript, error) {
+	var scripts []CheckScript
+	err := DB.Where("server_id = ? AND enabled = ?", serverID, true).Order("id ASC").Limit(MaxCheckScriptsPerServer).Find(&scripts).Error
+	return scripts, err
+}
+
+// GetCheckScript 获取服务器的一个检查脚本
+func GetCheckScript(serverID, id uint) (*CheckScript, error) {
+	var script CheckScript
+	if err := DB.Where("server_id = ?", serverID).First(&script, id).Error; err != nil {
+		return nil, err
+	}
+	return &script, nil
+}
+
+// CheckScriptNameTaken 同一服务器上是否已有其他同名脚本
+func CheckScriptNameTaken(serverID uint, name string, exceptID uint) bool {
+	var count int64
+	DB.Model(&CheckScript{}).Where("server_id = ? AND name = ? AND id <> ?", serverID, name, exceptID).Count(&count)
+	return count > 0
+}
+
+// CountCheckScripts 服务器的检查脚本数
+func CountCheckScripts(serverID uint) (int64, error) {
+	var count int64
+	err := DB.Model(&CheckScript{}).Where("server_id = ?", serverID).Count(&count).Error
+	return count, err
+}
+
+// SaveCheckScript 新建或更新检查脚本
+func SaveCheckScript(script *CheckScript) error {
+	return DB.Save(script).Error
+}
+
+// DeleteCheckScript 删除检查脚本
+func DeleteCheckScript(id uint) error {
+	return DB.Delete(&CheckScript{}, id).Error
+}
+
+// UpdateCheckScriptResult 保存 Agent 上报的最近一次执行结果，不影响用户可编辑的配置
+func UpdateCheckScriptResult(script *CheckScript) error {
+	return DB.Model(&CheckScript{}).Where("id = ?", script.ID).Updates(map[string]interface{}{
+		"last_run_at":      script.LastRunAt,
+		"last_exit_code":   script.LastExitCode,
+		"last_duration_ms": script.LastDurationMs,
+		"last_output":      script.LastOutput,
+		"last_error":       script.LastError,
+		"last_metrics":     script.LastMetrics,
+	}).Error
+}
+
+// CheckScriptSample 监控记录中保存的一个检查脚本的最新结果，供预警规则使用
+type CheckScriptSample struct {
+	Name     string             `json:"name"`
+	ExitCode int                `json:"exit_code"`
+	Metrics  map[string]float64 `json:"metrics,omitempty"`
+	RunAt    time.Time          `json:"run_at"`
+}
+
+// CheckScriptSamples 解析监控记录中的检查脚本结果，Agent 没有上报时返回 nil
+func (m *ServerMonitor) CheckScriptSamples() []CheckScriptSample {
+	if m.Scripts == "" {
+		return nil
+	}
+	var samples []CheckScriptSample
+	if err := json.Unmarshal([]byte(m.Scripts), &samples); err != nil {
+		return nil
+	}
+	return samples
+}
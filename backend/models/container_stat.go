@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+// ContainerStat Agent 定时上报的单个容器资源使用情况，随监控数据按保留天数清理
+type ContainerStat struct {
+	ID            uint      `json:"-" gorm:"primaryKey"`
+	ServerID      uint      `json:"server_id" gorm:"index:idx_container_stat_time"`
+	Timestamp     time.Time `json:"timestamp" gorm:"index:idx_container_stat_time"`
+	ContainerID   string    `json:"container_id" gorm:"type:varchar(64)"`
+	Name          string    `json:"name" gorm:"type:varchar(255)"`
+	CPUPercent    float64   `json:"cpu_percent"`
+	MemoryUsage   uint64    `json:"memory_usage"`
+	MemoryLimit   uint64    `json:"memory_limit"`
+	MemoryPercent float64   `json:"memory_percent"`
+	NetworkRx     uint64    `json:"network_rx"` // 容器启动以来的累计值
+	NetworkTx     uint64    `json:"network_tx"`
+	BlockRead     uint64    `json:"block_read"`
+	BlockWrite    uint64    `json:"block_write"`
+	PIDs          uint64    `json:"pids"`
+}
+
+// CreateContainerStats 保存一次上报的容器资源统计
+func CreateContainerStats(stats []ContainerStat) error {
+	if len(stats) == 0 {
+		return nil
+	}
+	return DB.Create(&stats).Error
+}
+
+// GetContainerStats 获取服务器在时间范围内的容器资源统计，name 为空时返回所有容器，按时间升序
+func GetContainerStats(serverID uint, name string, since, until time.Time) ([]ContainerStat, error) {
+	query := DB.Where("server_id = ? AND timestamp BETWEEN ? AND ?", serverID, since, until)
+	if name != "" {
+		query = query.Where("name = ?", name)
+	}
+	var stats []ContainerStat
+	err := query.Order("timestamp ASC").Find(&stats).Error
+	return stats, err
+}
+
+// DeleteContainerStatsBefore 删除指定时间之前的容器资源统计
+func DeleteContainerStatsBefore(before time.Time) (int64, error) {
+	result := DB.Where("timestamp < ?", before).Delete(&ContainerStat{})
+	return result.RowsAffected, result.Error
+}
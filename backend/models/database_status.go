@@ -0,0 +1,42 @@
+package models
+
+import (
+	"encoding/json"
+)
+
+// MaxDatabaseChecks 每次上报保存的数据库数上限，与 Agent 一致
+const MaxDatabaseChecks = 16
+
+// DatabaseStatus Agent 上报的一个数据库服务的健康指标，格式与 Agent 相同
+type DatabaseStatus struct {
+	Name    string  `json:"name"`
+	Type    string  `json:"type"` // mysql、postgres 或 redis
+	Up      bool    `json:"up"`
+	Error   string  `json:"error,omitempty"`
+	Version string  `json:"version,omitempty"`
+	Latency float64 `json:"latency"` // 完成一次采集的耗时(ms)
+
+	Connections    int     `json:"connections"`     // 当前连接数
+	MaxConnections int     `json:"max_connections"` // 连接数上限，0 表示未知
+	QueryRate      float64 `json:"query_rate"`      // 每秒查询数（PostgreSQL 为事务数，Redis 为命令数）
+	SlowQueries    uint64  `json:"slow_queries"`    // 上报周期内新增的慢查询数
+
+	Role             string  `json:"role,omitempty"` // primary 或 replica
+	ReplicationLag   float64 `json:"replication_lag"`
+	ReplicationError string  `json:"replication_error,omitempty"`
+
+	MemoryUsed  uint64 `json:"memory_used"`
+	MemoryLimit uint64 `json:"memory_limit"` // 0 表示不限制或未知
+}
+
+// DatabaseStats 解析监控记录中的数据库指标，Agent 未配置数据库采集时返回 nil
+func (m *ServerMonitor) DatabaseStats() []DatabaseStatus {
+	if m.Databases == "" {
+		return nil
+	}
+	var statuses []DatabaseStatus
+	if err := json.Unmarshal([]byte(m.Databases), &statuses); err != nil {
+		return nil
+	}
+	return statuses
+}
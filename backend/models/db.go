@@ -45,17 +45,45 @@ func InitDB() error {
 
 	DB = db
 
+	// 注册数据库耗时统计
+	if err := RegisterDBMetrics(DB); err != nil {
+		log.Printf("注册数据库耗时统计失败: %v", err)
+	}
+
 	// 自动迁移数据库结构
 	if err := DB.AutoMigrate(
 		&User{},
 		&Server{},
 		&ServerMonitor{},
+		&TrafficHourly{},
 		&SystemSettings{},
 		&AlertSetting{},
 		&NotificationChannel{},
 		&AlertRecord{},
+		&AlertEscalationPolicy{},
+		&AlertRule{},
+		&MaintenanceWindow{},
+		&Incident{},
+		&IncidentEvent{},
+		&OOMEvent{},
+		&DiskHealth{},
+		&ContainerStat{},
+		&DiskMountStat{},
+		&ServerOperation{},
+		&FileSnapshot{},
+		&PackageInventory{},
+		&ServerPackage{},
+		&NetworkBenchmark{},
+		&UserServerPreference{},
 		&CertificateAccount{},
 		&ManagedCertificate{},
+		&RegistryCredential{},
+		&UptimeCheck{},
+		&UptimeResult{},
+		&CheckScript{},
+		&MeshLatency{},
+		&LogEntry{},
+		&HostCertificate{},
 		&LifeProbe{},
 		&LifeLoggerEvent{},
 		&LifeHeartRate{},
@@ -78,6 +106,11 @@ func InitDB() error {
 		log.Println("服务器 sort_order 初始化完成")
 	}
 
+	// 回填升级前预警记录的分类
+	if err := BackfillAlertRecordCategories(); err != nil {
+		log.Printf("回填预警记录分类失败: %v", err)
+	}
+
 	// 检查是否需要创建管理员账户
 	var count int64
 	DB.Model(&User{}).Count(&count)
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 慢查询阈值，超过该时长的数据库操作计入 slow_count
+const slowQueryThreshold = 200 * time.Millisecond
+
+const dbMetricsStartKey = "db_metrics:start"
+
+// DBOperationStats 单类数据库操作的耗时统计
+type DBOperationStats struct {
+	Operation  string  `json:"operation"`
+	Count      uint64  `json:"count"`
+	ErrorCount uint64  `json:"error_count"`
+	SlowCount  uint64  `json:"slow_count"`
+	TotalMs    float64 `json:"total_ms"`
+	AvgMs      float64 `json:"avg_ms"`
+	MaxMs      float64 `json:"max_ms"`
+}
+
+type dbMetrics struct {
+	mu    sync.Mutex
+	stats map[string]*DBOperationStats
+}
+
+var queryMetrics = &dbMetrics{stats: make(map[string]*DBOperationStats)}
+
+func (m *dbMetrics) observe(operation string, elapsed time.Duration, failed bool) {
+	ms := float64(elapsed) / float64(time.Millisecond)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stat, ok := m.stats[operation]
+	if !ok {
+		stat = &DBOperationStats{Operation: operation}
+		m.stats[operation] = stat
+	}
+	stat.Count++
+	stat.TotalMs += ms
+	if ms > stat.MaxMs {
+		stat.MaxMs = ms
+	}
+	if elapsed >= slowQueryThreshold {
+		stat.SlowCount++
+	}
+	if failed {
+		stat.ErrorCount++
+	}
+}
+
+// GetDBQueryStats 获取进程启动以来的数据库操作耗时统计
+func GetDBQueryStats() []DBOperationStats {
+	queryMetrics.mu.Lock()
+	defer queryMetrics.mu.Unlock()
+
+	result := make([]DBOperationStats, 0, len(queryMetrics.stats))
+	for _, stat := range queryMetrics.stats {
+		snapshot := *stat
+		if snapshot.Count > 0 {
+			snapshot.AvgMs = snapshot.TotalMs / float64(snapshot.Count)
+		}
+		result = append(result, snapshot)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Operation < result[j].Operation })
+	return result
+}
+
+// RegisterDBMetrics 注册 GORM 回调，记录每类数据库操作的耗时。
+// InitDB 会自动注册，测试中单独打开的数据库需要自行调用
+func RegisterDBMetrics(db *gorm.DB) error {
+	before := func(tx *gorm.DB) {
+		tx.InstanceSet(dbMetricsStartKey, time.Now())
+	}
+	after := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			value, ok := tx.InstanceGet(dbMetricsStartKey)
+			if !ok {
+				return
+			}
+			start, ok := value.(time.Time)
+			if !ok {
+				return
+			}
+			failed := tx.Error != nil && tx.Error != gorm.ErrRecordNotFound
+			queryMetrics.observe(operation, time.Since(start), failed)
+		}
+	}
+
+	cb := db.Callback()
+	hooks := []struct {
+		operation string
+		register  func() error
+	}{
+		{"query", func() error {
+			if err := cb.Query().Before("gorm:query").Register("metrics:before_query", before); err != nil {
+				return err
+			}
+			return cb.Query().After("gorm:query").Register("metrics:after_query", after("query"))
+		}},
+		{"create", func() error {
+			if err := cb.Create().Before("gorm:create").Register("metrics:before_create", before); err != nil {
+				return err
+			}
+			return cb.Create().After("gorm:create").Register("metrics:after_create", after("create"))
+		}},
+		{"update", func() error {
+			if err := cb.Update().Before("gorm:update").Register("metrics:before_update", before); err != nil {
+				return err
+			}
+			return cb.Update().After("gorm:update").Register("metrics:after_update", after("update"))
+		}},
+		{"delete", func() error {
+			if err := cb.Delete().Before("gorm:delete").Register("metrics:before_delete", before); err != nil {
+				return err
+			}
+			return cb.Delete().After("gorm:delete").Register("metrics:after_delete", after("delete"))
+		}},
+		{"row", func() error {
+			if err := cb.Row().Before("gorm:row").Register("metrics:before_row", before); err != nil {
+				return err
+			}
+			return cb.Row().After("gorm:row").Register("metrics:after_row", after("row"))
+		}},
+		{"raw", func() error {
+			if err := cb.Raw().Before("gorm:raw").Register("metrics:before_raw", before); err != nil {
+				return err
+			}
+			return cb.Raw().After("gorm:raw").Register("metrics:after_raw", after("raw"))
+		}},
+	}
+
+	for _, hook := range hooks {
+		if err := hook.register(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
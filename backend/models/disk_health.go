@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DiskHealth 服务器物理磁盘最近一次的 SMART 健康信息，每块磁盘一条，随 Agent 的检查结果更新
+type DiskHealth struct {
+	ID                   uint      `json:"-" gorm:"primaryKey"`
+	ServerID             uint      `json:"server_id" gorm:"uniqueIndex:idx_disk_health_device"`
+	Device               string    `json:"device" gorm:"type:varchar(128);uniqueIndex:idx_disk_health_device"`
+	Model                string    `json:"model" gorm:"type:varchar(255)"`
+	Serial               string    `json:"serial" gorm:"type:varchar(128)"`
+	Type                 string    `json:"type" gorm:"type:varchar(16)"` // hdd、ssd、nvme
+	CapacityBytes        uint64    `json:"capacity_bytes"`
+	Passed               bool      `json:"passed"`
+	Temperature          int       `json:"temperature"`
+	ReallocatedSectors   int64     `json:"reallocated_sectors"`
+	PendingSectors       int64     `json:"pending_sectors"`
+	UncorrectableSectors int64     `json:"uncorrectable_sectors"`
+	MediaErrors          int64     `json:"media_errors"`
+	WearLevel            int       `json:"wear_level"` // 已用寿命百分比，-1 表示未知
+	PowerOnHours         int64     `json:"power_on_hours"`
+	FailingAttributes    string    `json:"failing_attributes"` // 低于阈值的属性，逗号分隔
+	PredictedFailure     bool      `json:"predicted_failure"`
+	CheckedAt            time.Time `json:"checked_at"`
+}
+
+// SaveDiskHealth 用最新的检查结果替换服务器的磁盘健康记录，返回本次新出现故障预测的磁盘。
+// 已经预测故障的磁盘不重复返回，避免每次检查都告警
+func SaveDiskHealth(serverID uint, disks []DiskHealth) ([]DiskHealth, error) {
+	var failing []DiskHealth
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var existing []DiskHealth
+		if err := tx.Where("server_id = ?", serverID).Find(&existing).Error; err != nil {
+			return err
+		}
+		wasFailing := make(map[string]bool, len(existing))
+		for _, disk := range existing {
+			wasFailing[disk.Device] = disk.PredictedFailure
+		}
+
+		if err := tx.Where("server_id = ?", serverID).Delete(&DiskHealth{}).Error; err != nil {
+			return err
+		}
+		for i := range disks {
+			disks[i].ID = 0
+			disks[i].ServerID = serverID
+			if disks[i].PredictedFailure && !wasFailing[disks[i].Device] {
+				failing = append(failing, disks[i])
+			}
+		}
+		if len(disks) == 0 {
+			return nil
+		}
+		return tx.Create(&disks).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return failing, nil
+}
+
+// GetDiskHealth 获取服务器所有磁盘的健康信息，按设备名排序
+func GetDiskHealth(serverID uint) ([]DiskHealth, error) {
+	var disks []DiskHealth
+	err := DB.Where("server_id = ?", serverID).Order("device ASC").Find(&disks).Error
+	return disks, err
+}
+
+// DeleteDiskHealth 删除服务器的磁盘健康记录
+func DeleteDiskHealth(serverID uint) error {
+	return DB.Where("server_id = ?", serverID).Delete(&DiskHealth{}).Error
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+)
+
+// MaxMounts 每次上报保存的挂载点数上限，与 Agent 一致
+const MaxMounts = 32
+
+// DiskMountStat Agent 上报的单个挂载点空间(bytes)和 inode 使用情况，与监控记录同一时间戳，随监控数据按保留天数清理
+type DiskMountStat struct {
+	ID          uint      `json:"-" gorm:"primaryKey"`
+	ServerID    uint      `json:"server_id" gorm:"index:idx_disk_mount_stat_time"`
+	Timestamp   time.Time `json:"timestamp" gorm:"index:idx_disk_mount_stat_time"`
+	Mount       string    `json:"mount" gorm:"type:varchar(255)"`
+	FSType      string    `json:"fstype" gorm:"type:varchar(32)"`
+	Total       uint64    `json:"total"`
+	Used        uint64    `json:"used"`
+	Free        uint64    `json:"free"`
+	InodesTotal uint64    `json:"inodes_total"` // 不限制 inode 的文件系统（如 btrfs）为 0
+	InodesUsed  uint64    `json:"inodes_used"`
+	InodesFree  uint64    `json:"inodes_free"`
+}
+
+// UsagePercent 空间使用率(%)
+func (s *DiskMountStat) UsagePercent() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Used) / float64(s.Total) * 100
+}
+
+// InodeUsagePercent inode 使用率(%)，文件系统不限制 inode 时为 0
+func (s *DiskMountStat) InodeUsagePercent() float64 {
+	if s.InodesTotal == 0 {
+		return 0
+	}
+	return float64(s.InodesUsed) / float64(s.InodesTotal) * 100
+}
+
+// CreateDiskMountStats 保存一次上报的各挂载点使用情况
+func CreateDiskMountStats(stats []DiskMountStat) error {
+	if len(stats) == 0 {
+		return nil
+	}
+	return DB.Create(&stats).Error
+}
+
+// MountStats 获取与监控记录同一次上报的各挂载点使用情况，旧版 Agent 上报的记录返回空列表
+func (m *ServerMonitor) MountStats() []DiskMountStat {
+	var stats []DiskMountStat
+	DB.Where("server_id = ? AND timestamp = ?", m.ServerID, m.Timestamp).Order("mount ASC").Find(&stats)
+	return stats
+}
+
+// GetDiskMountStats 获取服务器在时间范围内的挂载点使用情况，mount 为空时返回所有挂载点，按时间升序
+func GetDiskMountStats(serverID uint, mount string, since, until time.Time) ([]DiskMountStat, error) {
+	query := DB.Where("server_id = ? AND timestamp BETWEEN ? AND ?", serverID, since, until)
+	if mount != "" {
+		query = query.Where("mount = ?", mount)
+	}
+	var stats []DiskMountStat
+	err := query.Order("timestamp ASC, mount ASC").Find(&stats).Error
+	return stats, err
+}
+
+// GetLatestDiskMountStats 获取服务器最近一次上报的各挂载点使用情况
+func GetLatestDiskMountStats(serverID uint) ([]DiskMountStat, error) {
+	var latest DiskMountStat
+	result := DB.Where("server_id = ?", serverID).Order("timestamp DESC").Limit(1).Find(&latest)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return []DiskMountStat{}, result.Error
+	}
+	var stats []DiskMountStat
+	err := DB.Where("server_id = ? AND timestamp = ?", serverID, latest.Timestamp).
+		Order("mount ASC").Find(&stats).Error
+	return stats, err
+}
+
+// DeleteDiskMountStatsBefore 删除指定时间之前的挂载点使用情况
+func DeleteDiskMountStatsBefore(before time.Time) (int64, error) {
+	result := DB.Where("timestamp < ?", before).Delete(&DiskMountStat{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+)
+
+// FileSnapshot 服务器上某个目录树的元数据快照，用于对比两个时间点之间的文件变更
+type FileSnapshot struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ServerID   uint      `json:"server_id" gorm:"index:idx_file_snapshot_path"`
+	Path       string    `json:"path" gorm:"type:varchar(1024);index:idx_file_snapshot_path"`
+	Hashed     bool      `json:"hashed"`             // 是否包含文件哈希
+	EntryCount int       `json:"entry_count"`        // 记录的条目数
+	Truncated  bool      `json:"truncated"`          // 是否因条目上限或超时只记录了部分条目
+	Entries    string    `json:"-" gorm:"type:text"` // 条目列表 JSON
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+// CreateFileSnapshot 保存快照，并删除同一目录超出保留数量的旧快照
+func CreateFileSnapshot(snapshot *FileSnapshot, keep int) error {
+	if err := DB.Create(snapshot).Error; err != nil {
+		return err
+	}
+	var stale []uint
+	DB.Model(&FileSnapshot{}).
+		Where("server_id = ? AND path = ?", snapshot.ServerID, snapshot.Path).
+		Order("id DESC").Offset(keep).Pluck("id", &stale)
+	if len(stale) > 0 {
+		return DB.Delete(&FileSnapshot{}, stale).Error
+	}
+	return nil
+}
+
+// ListFileSnapshots 获取服务器的快照（不含条目），path 非空时只返回该目录的快照，最新的在前
+func ListFileSnapshots(serverID uint, path string) ([]FileSnapshot, error) {
+	var snapshots []FileSnapshot
+	query := DB.Omit("entries").Where("server_id = ?", serverID)
+	if path != "" {
+		query = query.Where("path = ?", path)
+	}
+	err := query.Order("id DESC").Find(&snapshots).Error
+	return snapshots, err
+}
+
+// GetFileSnapshot 获取服务器的指定快照（含条目）
+func GetFileSnapshot(serverID, id uint) (*FileSnapshot, error) {
+	var snapshot FileSnapshot
+	if err := DB.Where("server_id = ?", serverID).First(&snapshot, id).Error; err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// GetLatestFileSnapshot 获取目录最近一次的快照（含条目），beforeID 非 0 时只查找该快照之前的
+func GetLatestFileSnapshot(serverID uint, path string, beforeID uint) (*FileSnapshot, error) {
+	var snapshot FileSnapshot
+	query := DB.Where("server_id = ? AND path = ?", serverID, path)
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+	if err := query.Order("id DESC").First(&snapshot).Error; err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// DeleteFileSnapshot 删除服务器的指定快照
+func DeleteFileSnapshot(serverID, id uint) error {
+	return DB.Where("server_id = ?", serverID).Delete(&FileSnapshot{}, id).Error
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// HostCertificate Agent 在服务器上扫描到的 SSL 证书（certbot 管理的、证书目录中的和本机 HTTPS 端口使用的），
+// 每次扫描后整体替换，用于到期预警
+type HostCertificate struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	ServerID     uint       `json:"server_id" gorm:"index"`
+	Domain       string     `json:"domain" gorm:"type:varchar(255)"`
+	Source       string     `json:"source" gorm:"type:varchar(32)"` // certbot、system 或 nginx
+	CertPath     string     `json:"cert_path" gorm:"type:varchar(512)"`
+	KeyPath      string     `json:"key_path" gorm:"type:varchar(512)"`
+	Issuer       string     `json:"issuer" gorm:"type:varchar(255)"`
+	SerialNumber string     `json:"serial_number" gorm:"type:varchar(128)"`
+	Fingerprint  string     `json:"fingerprint" gorm:"type:varchar(128)"`
+	IssuedAt     time.Time  `json:"issued_at"`
+	ExpiresAt    time.Time  `json:"expires_at" gorm:"index"`
+	NotifiedAt   *time.Time `json:"notified_at"` // 已发送即将到期通知的时间，证书更换后清空
+	ScannedAt    time.Time  `json:"scanned_at"`
+}
+
+// certificateKey 区分同一服务器上的证书：优先使用指纹，旧版 Agent 未上报指纹时使用路径和域名
+func (c HostCertificate) certificateKey() string {
+	if c.Fingerprint != "" {
+		return c.Fingerprint
+	}
+	return c.CertPath + "|" + c.Domain
+}
+
+// SaveHostCertificates 用最新的扫描结果替换服务器的证书记录。
+// 同一张证书保留已通知的时间，避免每次扫描都重复通知；续期后指纹变化，视为新证书
+func SaveHostCertificates(serverID uint, certs []HostCertificate) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var existing []HostCertificate
+		if err := tx.Where("server_id = ?", serverID).Find(&existing).Error; err != nil {
+			return err
+		}
+		notified := make(map[string]*time.Time, len(existing))
+		for _, cert := range existing {
+			notified[cert.certificateKey()] = cert.NotifiedAt
+		}
+
+		if err := tx.Where("server_id = ?", serverID).Delete(&HostCertificate{}).Error; err != nil {
+			return err
+		}
+		for i := range certs {
+			certs[i].ID = 0
+			certs[i].ServerID = serverID
+			certs[i].NotifiedAt = notified[certs[i].certificateKey()]
+		}
+		if len(certs) == 0 {
+			return nil
+		}
+		return tx.Create(&certs).Error
+	})
+}
+
+// GetHostCertificates 获取服务器的证书，按到期时间排序
+func GetHostCertificates(serverID uint) ([]HostCertificate, error) {
+	var certs []HostCertificate
+	err := DB.Where("server_id = ?", serverID).Order("expires_at ASC, id ASC").Find(&certs).Error
+	return certs, err
+}
+
+// ListHostCertificates 获取所有服务器的证书，expiresBefore 不为零时只返回在此之前到期的，按到期时间排序
+func ListHostCertificates(expiresBefore time.Time) ([]HostCertificate, error) {
+	query := DB.Order("expires_at ASC, id ASC")
+	if !expiresBefore.IsZero() {
+		query = query.Where("expires_at < ?", expiresBefore)
+	}
+	var certs []HostCertificate
+	err := query.Find(&certs).Error
+	return certs, err
+}
+
+// MarkHostCertificatesNotified 记录证书已发送即将到期通知
+func MarkHostCertificatesNotified(ids []uint, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return DB.Model(&HostCertificate{}).Where("id IN ?", ids).Update("notified_at", at).Error
+}
+
+// DeleteHostCertificates 删除服务器的证书记录
+func DeleteHostCertificates(serverID uint) error {
+	return DB.Where("server_id = ?", serverID).Delete(&HostCertificate{}).Error
+}
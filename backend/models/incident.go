@@ -0,0 +1,165 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 事件状态
+const (
+	IncidentOpen         = "open"
+	IncidentAcknowledged = "acknowledged"
+	IncidentResolved     = "resolved"
+)
+
+// 事件时间线的条目类型
+const (
+	IncidentEventOpened       = "opened"
+	IncidentEventAcknowledged = "acknowledged"
+	IncidentEventComment      = "comment"
+	IncidentEventResolved     = "resolved"
+)
+
+// Incident 由未解决的预警打开的事件，一条预警记录对应一个事件。
+// 确认后停止该预警的升级通知，预警恢复或用户手动解决后关闭
+type Incident struct {
+	gorm.Model
+	AlertRecordID  uint      `json:"alert_record_id" gorm:"uniqueIndex"`
+	ServerID       uint      `json:"server_id" gorm:"index"`
+	ServerName     string    `json:"server_name"`
+	AlertType      string    `json:"alert_type" gorm:"type:varchar(20)"`
+	Category       string    `json:"category" gorm:"type:varchar(20);index"`
+	Status         string    `json:"status" gorm:"type:varchar(20);index"` // open, acknowledged, resolved
+	AcknowledgedAt time.Time `json:"acknowledged_at"`
+	AcknowledgedBy string    `json:"acknowledged_by" gorm:"type:varchar(50)"`
+	ResolvedAt     time.Time `json:"resolved_at"`
+	ResolvedBy     string    `json:"resolved_by" gorm:"type:varchar(50)"` // 为空表示预警恢复后自动解决
+}
+
+// IncidentEvent 事件时间线上的一条记录
+type IncidentEvent struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	IncidentID uint      `json:"incident_id" gorm:"index"`
+	Type       string    `json:"type" gorm:"type:varchar(20)"`
+	Author     string    `json:"author" gorm:"type:varchar(50)"` // 为空表示系统
+	Content    string    `json:"content" gorm:"type:text"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// openIncident 为新的未解决预警打开事件
+func openIncident(tx *gorm.DB, record *AlertRecord) error {
+	incident := Incident{
+		AlertRecordID: record.ID,
+		ServerID:      record.ServerID,
+		ServerName:    record.ServerName,
+		AlertType:     record.AlertType,
+		Category:      record.Category,
+		Status:        IncidentOpen,
+	}
+	if err := tx.Create(&incident).Error; err != nil {
+		return err
+	}
+	return tx.Create(&IncidentEvent{
+		IncidentID: incident.ID,
+		Type:       IncidentEventOpened,
+		Content:    fmt.Sprintf("预警触发：类型 %s，值 %.2f，阈值 %.2f", record.AlertType, record.Value, record.Threshold),
+		CreatedAt:  record.CreatedAt,
+	}).Error
+}
+
+// acknowledgeAlertIncident 预警确认后同步将其事件标记为已确认
+func acknowledgeAlertIncident(tx *gorm.DB, recordID uint, by string, at time.Time) error {
+	var incident Incident
+	if err := tx.Where("alert_record_id = ? AND status = ?", recordID, IncidentOpen).First(&incident).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // 升级前产生的预警没有事件
+		}
+		return err
+	}
+	if err := tx.Model(&incident).Updates(map[string]interface{}{
+		"status": IncidentAcknowledged, "acknowledged_at": at, "acknowledged_by": by,
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Create(&IncidentEvent{IncidentID: incident.ID, Type: IncidentEventAcknowledged, Author: by, CreatedAt: at}).Error
+}
+
+// ResolveAlertIncident 关闭预警对应的事件，by 为空表示预警恢复后自动解决。事件已解决或不存在时不做任何操作
+func ResolveAlertIncident(recordID uint, by, note string, at time.Time) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var incident Incident
+		if err := tx.Where("alert_record_id = ? AND status <> ?", recordID, IncidentResolved).First(&incident).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if err := tx.Model(&incident).Updates(map[string]interface{}{
+			"status": IncidentResolved, "resolved_at": at, "resolved_by": by,
+		}).Error; err != nil {
+			return err
+		}
+		if note == "" && by == "" {
+			note = "预警已恢复"
+		}
+		return tx.Create(&IncidentEvent{IncidentID: incident.ID, Type: IncidentEventResolved, Author: by, Content: note, CreatedAt: at}).Error
+	})
+}
+
+// GetIncidents 分页获取事件，status 为空时返回全部状态，未解决的事件排在前面
+func GetIncidents(status string, page, limit int) ([]Incident, int64, error) {
+	var incidents []Incident
+	var total int64
+
+	query := DB.Model(&Incident{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	result := query.Order(fmt.Sprintf("CASE status WHEN '%s' THEN 0 WHEN '%s' THEN 1 ELSE 2 END", IncidentOpen, IncidentAcknowledged)).
+		Order("created_at DESC").Offset(offset).Limit(limit).Find(&incidents)
+	return incidents, total, result.Error
+}
+
+// GetIncidentByID 通过ID获取事件
+func GetIncidentByID(id uint, incident *Incident) error {
+	return DB.First(incident, id).Error
+}
+
+// GetIncidentEvents 获取事件的时间线，按时间升序
+func GetIncidentEvents(incidentID uint) ([]IncidentEvent, error) {
+	var events []IncidentEvent
+	result := DB.Where("incident_id = ?", incidentID).Order("created_at ASC, id ASC").Find(&events)
+	return events, result.Error
+}
+
+// AddIncidentComment 为事件添加备注
+func AddIncidentComment(incidentID uint, by, content string) (*IncidentEvent, error) {
+	event := IncidentEvent{IncidentID: incidentID, Type: IncidentEventComment, Author: by, Content: content, CreatedAt: time.Now()}
+	if err := DB.Create(&event).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// DeleteIncidentsBefore 永久删除指定时间之前打开的事件及其时间线，与预警记录的保留天数一致
+func DeleteIncidentsBefore(cutoff time.Time) (int64, error) {
+	var deleted int64
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		ids := tx.Unscoped().Model(&Incident{}).Select("id").Where("created_at < ?", cutoff)
+		if err := tx.Where("incident_id IN (?)", ids).Delete(&IncidentEvent{}).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where("created_at < ?", cutoff).Delete(&Incident{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// 日志采集的限制
+const (
+	// MaxLogPaths 每台服务器最多配置的采集路径数
+	MaxLogPaths = 20
+	// MaxLogPathLength 单条采集路径的最大长度
+	MaxLogPathLength = 512
+)
+
+// LogEntry Agent 采集上报的一行日志，随监控数据按保留天数清理
+type LogEntry struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ServerID  uint      `json:"server_id" gorm:"index:idx_log_entry_time"`
+	Path      string    `json:"path" gorm:"type:varchar(512)"`
+	Timestamp time.Time `json:"timestamp" gorm:"index:idx_log_entry_time;index"`
+	Line      string    `json:"line" gorm:"type:text"`
+}
+
+// LogEntryFilter 日志搜索的数据库侧条件，正则在调用方逐行匹配
+type LogEntryFilter struct {
+	ServerID uint   // 0 表示所有服务器
+	Path     string // 为空表示所有文件
+	Keyword  string // 行内包含的文本，不区分大小写
+	Start    time.Time
+	End      time.Time
+}
+
+// LogEntryCursor 按时间倒序分页的位置，零值表示从最新的一条开始
+type LogEntryCursor struct {
+	Timestamp time.Time
+	ID        uint
+}
+
+// CreateLogEntries 保存一次上报的日志行
+func CreateLogEntries(entries []LogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return DB.CreateInBatches(entries, 500).Error
+}
+
+// FindLogEntries 按时间倒序返回 cursor 之后满足条件的最多 limit 条日志
+func FindLogEntries(filter LogEntryFilter, cursor LogEntryCursor, limit int) ([]LogEntry, error) {
+	query := DB.Model(&LogEntry{}).Where("timestamp >= ? AND timestamp <= ?", filter.Start, filter.End)
+	if filter.ServerID > 0 {
+		query = query.Where("server_id = ?", filter.ServerID)
+	}
+	if filter.Path != "" {
+		query = query.Where("path = ?", filter.Path)
+	}
+	if filter.Keyword != "" {
+		query = query.Where("LOWER(line) LIKE ? ESCAPE '\\'", "%"+escapeLike(strings.ToLower(filter.Keyword))+"%")
+	}
+	if cursor.ID > 0 {
+		query = query.Where("timestamp < ? OR (timestamp = ? AND id < ?)", cursor.Timestamp, cursor.Timestamp, cursor.ID)
+	}
+
+	var entries []LogEntry
+	err := query.Order("timestamp DESC, id DESC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+// DeleteLogEntriesBefore 删除指定时间之前的日志
+func DeleteLogEntriesBefore(before time.Time) (int64, error) {
+	result := DB.Where("timestamp < ?", before).Delete(&LogEntry{})
+	return result.RowsAffected, result.Error
+}
+
+// escapeLike 转义 LIKE 模式中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxMaintenanceDuration 周期维护窗口单次持续时间的上限
+const MaxMaintenanceDuration = 7 * 24 * time.Hour
+
+// maintenanceCacheTTL 维护窗口缓存的有效期，增删改时立即失效
+const maintenanceCacheTTL = 30 * time.Second
+
+// MaintenanceWindow 维护窗口：窗口内不发送预警通知，公开页面不显示离线，期间的监控数据带维护标记。
+// 一次性窗口使用 StartAt/EndAt；设置了 Cron 的周期窗口在每次匹配的时间开始，持续 DurationMinutes 分钟
+type MaintenanceWindow struct {
+	gorm.Model
+	Name            string    `json:"name" gorm:"type:varchar(50);not null"`
+	ServerID        uint      `json:"server_id" gorm:"default:0;index"` // 0 表示所有服务器
+	StartAt         time.Time `json:"start_at"`                         // 一次性窗口的开始时间
+	EndAt           time.Time `json:"end_at"`                           // 一次性窗口的结束时间
+	Cron            string    `json:"cron" gorm:"type:varchar(100)"`    // 周期窗口的开始时间，5 段 cron 表达式，按面板时区
+	DurationMinutes int       `json:"duration_minutes"`                 // 周期窗口每次持续的分钟数
+	Enabled         bool      `json:"enabled" gorm:"default:true"`
+	CreatedBy       string    `json:"created_by" gorm:"type:varchar(50)"`
+
+	schedule *CronSchedule
+}
+
+// Recurring 是否为周期窗口
+func (w *MaintenanceWindow) Recurring() bool {
+	return w.Cron != ""
+}
+
+// AppliesTo 判断窗口是否适用于服务器，serverID 为 0（如可用性检查）时只有全局窗口适用
+func (w *MaintenanceWindow) AppliesTo(serverID uint) bool {
+	return w.ServerID == 0 || w.ServerID == serverID
+}
+
+// ActiveAt 判断时间是否处于窗口内，返回本次窗口的结束时间
+func (w *MaintenanceWindow) ActiveAt(t time.Time) (bool, time.Time) {
+	if !w.Recurring() {
+		return !t.Before(w.StartAt) && t.Before(w.EndAt), w.EndAt
+	}
+	if w.schedule == nil {
+		schedule, err := ParseCron(w.Cron)
+		if err != nil {
+			return false, time.Time{}
+		}
+		w.schedule = schedule
+	}
+
+	// 向前查找持续时间内最近一次匹配的开始时间
+	duration := time.Duration(w.DurationMinutes) * time.Minute
+	if duration > MaxMaintenanceDuration {
+		duration = MaxMaintenanceDuration
+	}
+	for start := t.Truncate(time.Minute); t.Sub(start) < duration; start = start.Add(-time.Minute) {
+		if w.schedule.Matches(start) {
+			return true, start.Add(duration)
+		}
+	}
+	return false, time.Time{}
+}
+
+var maintenanceCache struct {
+	sync.Mutex
+	windows  []MaintenanceWindow
+	loadedAt time.Time
+}
+
+// invalidateMaintenanceCache 维护窗口变更后清空缓存
+func invalidateMaintenanceCache() {
+	maintenanceCache.Lock()
+	maintenanceCache.windows = nil
+	maintenanceCache.loadedAt = time.Time{}
+	maintenanceCache.Unlock()
+}
+
+// ActiveMaintenanceWindow 返回服务器在该时间所处的维护窗口，不在维护期间返回 nil。
+// 每次上报监控数据和检查预警都会调用，启用的窗口在内存中缓存
+func ActiveMaintenanceWindow(serverID uint, at time.Time) *MaintenanceWindow {
+	maintenanceCache.Lock()
+	defer maintenanceCache.Unlock()
+
+	if maintenanceCache.loadedAt.IsZero() || time.Since(maintenanceCache.loadedAt) > maintenanceCacheTTL {
+		var windows []MaintenanceWindow
+		if err := DB.Where("enabled = ?", true).Find(&windows).Error; err != nil {
+			log.Printf("加载维护窗口失败: %v", err)
+			return nil
+		}
+		maintenanceCache.windows = windows
+		maintenanceCache.loadedAt = time.Now()
+	}
+
+	for i := range maintenanceCache.windows {
+		w := &maintenanceCache.windows[i]
+		if !w.AppliesTo(serverID) {
+			continue
+		}
+		if active, _ := w.ActiveAt(at); active {
+			window := *w
+			return &window
+		}
+	}
+	return nil
+}
+
+// InMaintenance 判断服务器在该时间是否处于维护期间
+func InMaintenance(serverID uint, at time.Time) bool {
+	return ActiveMaintenanceWindow(serverID, at) != nil
+}
+
+// GetAllMaintenanceWindows 获取所有维护窗口
+func GetAllMaintenanceWindows() ([]MaintenanceWindow, error) {
+	var windows []MaintenanceWindow
+	result := DB.Order("id DESC").Find(&windows)
+	return windows, result.Error
+}
+
+// GetMaintenanceWindowByID 通过ID获取维护窗口
+func GetMaintenanceWindowByID(id uint, window *MaintenanceWindow) error {
+	return DB.First(window, id).Error
+}
+
+// CreateMaintenanceWindow 创建维护窗口
+func CreateMaintenanceWindow(window *MaintenanceWindow) error {
+	defer invalidateMaintenanceCache()
+	return DB.Create(window).Error
+}
+
+// UpdateMaintenanceWindow 更新维护窗口
+func UpdateMaintenanceWindow(window *MaintenanceWindow) error {
+	defer invalidateMaintenanceCache()
+	return DB.Save(window).Error
+}
+
+// DeleteMaintenanceWindow 删除维护窗口
+func DeleteMaintenanceWindow(id uint) error {
+	defer invalidateMaintenanceCache()
+	return DB.Delete(&MaintenanceWindow{}, id).Error
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+)
+
+// 节点互测的间隔范围(秒)，0 表示关闭
+const (
+	MinMeshInterval = 10
+	MaxMeshInterval = 3600
+	// MaxMeshPeers 下发给每个 Agent 的节点数上限
+	MaxMeshPeers = 100
+)
+
+// MeshLatency 一个节点 ping 另一个节点的单次结果，随监控数据按保留天数清理
+type MeshLatency struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	ServerID  uint      `json:"server_id" gorm:"index:idx_mesh_latency_time"` // 发起 ping 的节点
+	PeerID    uint      `json:"peer_id" gorm:"index"`                         // 被 ping 的节点
+	Timestamp time.Time `json:"timestamp" gorm:"index:idx_mesh_latency_time"`
+	Success   bool      `json:"success"`
+	LatencyMs float64   `json:"latency_ms"`
+}
+
+// MeshLatencyCell 矩阵中一对节点在时间窗口内的统计
+type MeshLatencyCell struct {
+	ServerID uint    `json:"server_id"`
+	PeerID   uint    `json:"peer_id"`
+	Samples  int64   `json:"samples"`
+	Received int64   `json:"received"`
+	Loss     float64 `json:"loss"`   // 丢包率(%)
+	AvgMs    float64 `json:"avg_ms"` // 全部丢包时为 0
+	MinMs    float64 `json:"min_ms"`
+	MaxMs    float64 `json:"max_ms"`
+}
+
+// CreateMeshLatencies 保存一次上报的互测结果
+func CreateMeshLatencies(results []MeshLatency) error {
+	if len(results) == 0 {
+		return nil
+	}
+	return DB.Create(&results).Error
+}
+
+// GetMeshLatencyMatrix 按节点对统计 since 之后的互测结果
+func GetMeshLatencyMatrix(since time.Time) ([]MeshLatencyCell, error) {
+	var cells []MeshLatencyCell
+	err := DB.Model(&MeshLatency{}).
+		Select(`server_id, peer_id, COUNT(*) AS samples,
+			SUM(CASE WHEN success THEN 1 ELSE 0 END) AS received,
+			COALESCE(AVG(CASE WHEN success THEN latency_ms END), 0) AS avg_ms,
+			COALESCE(MIN(CASE WHEN success THEN latency_ms END), 0) AS min_ms,
+			COALESCE(MAX(CASE WHEN success THEN latency_ms END), 0) AS max_ms`).
+		Where("timestamp >= ?", since).
+		Group("server_id, peer_id").
+		Order("server_id ASC, peer_id ASC").
+		Scan(&cells).Error
+	for i := range cells {
+		if cells[i].Samples > 0 {
+			cells[i].Loss = float64(cells[i].Samples-cells[i].Received) / float64(cells[i].Samples) * 100
+		}
+	}
+	return cells, err
+}
+
+// DeleteMeshLatenciesBefore 删除指定时间之前的互测结果
+func DeleteMeshLatenciesBefore(before time.Time) (int64, error) {
+	result := DB.Where("timestamp < ?", before).Delete(&MeshLatency{})
+	return result.RowsAffected, result.Error
+}
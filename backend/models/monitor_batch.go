@@ -0,0 +1,260 @@
+package models
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// monitorBatchWriter 监控数据批量写入器。
+// 样本先在内存中缓冲，达到批量大小或刷新间隔到期时在一个事务内批量插入，
+// 同时按字段合并同一服务器的状态更新（同名字段只保留最新一次）和同一小时的流量增量，大幅减少高负载下的数据库往返。
+// 写入失败（如 SQLite 忙）时缓冲的数据放回队列，在下一个刷新间隔重试；
+// 进程崩溃时最多丢失一个刷新窗口内的数据。
+type monitorBatchWriter struct {
+	mu            sync.Mutex
+	records       []ServerMonitor
+	serverUpdates map[uint]map[string]interface{}
+	traffic       map[trafficKey]*TrafficHourly
+
+	batchSize int
+	interval  time.Duration
+	flushCh   chan struct{}
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+	retryAt   time.Time // 上一次写入失败后，达到批量大小也要等到该时间再重试，只在 run 中访问
+}
+
+// monitorRetryBatches 写入失败时最多保留的批次数，超出的最旧记录被丢弃，避免数据库长时间不可用时内存无限增长
+const monitorRetryBatches = 50
+
+// monitorStopRetries 停止时写入失败的重试次数
+const monitorStopRetries = 3
+
+var (
+	monitorWriter   *monitorBatchWriter
+	monitorWriterMu sync.RWMutex
+)
+
+// StartMonitorBatchWriter 启用监控数据批量写入；batchSize<=1 时保持逐条写入
+func StartMonitorBatchWriter(batchSize int, interval time.Duration) {
+	if batchSize <= 1 || interval <= 0 {
+		return
+	}
+
+	monitorWriterMu.Lock()
+	defer monitorWriterMu.Unlock()
+	if monitorWriter != nil {
+		return
+	}
+
+	w := &monitorBatchWriter{
+		serverUpdates: make(map[uint]map[string]interface{}),
+		traffic:       make(map[trafficKey]*TrafficHourly),
+		batchSize:     batchSize,
+		interval:      interval,
+		flushCh:       make(chan struct{}, 1),
+		stopCh:        make(chan struct{}),
+		doneCh:        make(chan struct{}),
+	}
+	monitorWriter = w
+	go w.run()
+
+	log.Printf("监控数据批量写入已启用: 批量大小=%d, 刷新间隔=%s", batchSize, interval)
+}
+
+// StopMonitorBatchWriter 停止批量写入器并写入缓冲中的剩余数据
+func StopMonitorBatchWriter() {
+	monitorWriterMu.Lock()
+	w := monitorWriter
+	monitorWriter = nil
+	monitorWriterMu.Unlock()
+
+	if w == nil {
+		return
+	}
+	close(w.stopCh)
+	<-w.doneCh
+}
+
+// SaveMonitorSample 保存一条监控数据并更新服务器状态字段。
+// 启用批量写入时仅放入缓冲区，由后台协程统一提交；否则立即写入。
+func SaveMonitorSample(record *ServerMonitor, serverUpdates map[string]interface{}) error {
+	monitorWriterMu.RLock()
+	w := monitorWriter
+	monitorWriterMu.RUnlock()
+
+	if w == nil {
+		if err := AddMonitorData(record); err != nil {
+			return err
+		}
+		return DB.Model(&Server{}).Where("id = ?", record.ServerID).Updates(serverUpdates).Error
+	}
+
+	w.enqueue(*record, serverUpdates)
+	return nil
+}
+
+// SaveServerMonitorState 只更新服务器状态（累计流量、心跳等），不写入监控记录。
+// 用于聚焦查看时的高频实时样本；启用批量写入时同一刷新窗口内的同名字段只保留最后一次更新。
+func SaveServerMonitorState(serverID uint, serverUpdates map[string]interface{}) error {
+	monitorWriterMu.RLock()
+	w := monitorWriter
+	monitorWriterMu.RUnlock()
+
+	if w == nil {
+		return DB.Model(&Server{}).Where("id = ?", serverID).Updates(serverUpdates).Error
+	}
+
+	w.mu.Lock()
+	w.mergeServerUpdates(serverID, serverUpdates, true)
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *monitorBatchWriter) enqueue(record ServerMonitor, serverUpdates map[string]interface{}) {
+	w.mu.Lock()
+	w.records = append(w.records, record)
+	w.mergeServerUpdates(record.ServerID, serverUpdates, true)
+	full := len(w.records) >= w.batchSize
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// mergeServerUpdates 按字段合并同一服务器的状态更新，调用方需持有 w.mu。
+// override 为 true 时 updates 是更新的数据，覆盖已缓冲的同名字段；为 false 时只补充缺少的字段
+func (w *monitorBatchWriter) mergeServerUpdates(serverID uint, updates map[string]interface{}, override bool) {
+	if len(updates) == 0 {
+		return
+	}
+	merged, ok := w.serverUpdates[serverID]
+	if !ok {
+		merged = make(map[string]interface{}, len(updates))
+		w.serverUpdates[serverID] = merged
+	}
+	for key, value := range updates {
+		if _, exists := merged[key]; override || !exists {
+			merged[key] = value
+		}
+	}
+}
+
+func (w *monitorBatchWriter) run() {
+	defer close(w.doneCh)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.flushOrRetry()
+		case <-w.flushCh:
+			if time.Now().Before(w.retryAt) {
+				continue
+			}
+			w.flushOrRetry()
+		case <-w.stopCh:
+			for i := 0; ; i++ {
+				err := w.flush()
+				if err == nil {
+					return
+				}
+				if i == monitorStopRetries {
+					w.mu.Lock()
+					log.Printf("停止时写入监控数据失败，丢弃 %d 条记录: %v", len(w.records), err)
+					w.mu.Unlock()
+					return
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+		}
+	}
+}
+
+// flushOrRetry 写入缓冲的数据，失败时推迟到下一个刷新间隔再重试
+func (w *monitorBatchWriter) flushOrRetry() {
+	if err := w.flush(); err != nil {
+		w.retryAt = time.Now().Add(w.interval)
+		log.Printf("批量写入监控数据失败，%s 后重试: %v", w.interval, err)
+		return
+	}
+	w.retryAt = time.Time{}
+}
+
+// flush 在一个事务内写入缓冲的监控数据和服务器状态，失败时将数据放回缓冲区
+func (w *monitorBatchWriter) flush() error {
+	w.mu.Lock()
+	records := w.records
+	serverUpdates := w.serverUpdates
+	traffic := w.traffic
+	w.records = nil
+	w.serverUpdates = make(map[uint]map[string]interface{})
+	w.traffic = make(map[trafficKey]*TrafficHourly)
+	w.mu.Unlock()
+
+	if len(records) == 0 && len(serverUpdates) == 0 && len(traffic) == 0 {
+		return nil
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if len(records) > 0 {
+			if err := tx.CreateInBatches(records, w.batchSize).Error; err != nil {
+				return err
+			}
+		}
+		for serverID, updates := range serverUpdates {
+			if err := tx.Model(&Server{}).Where("id = ?", serverID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		for _, t := range traffic {
+			if err := addTrafficHourly(tx, t.ServerID, t.Hour, t.BytesIn, t.BytesOut); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		w.requeue(records, serverUpdates, traffic)
+	}
+	return err
+}
+
+// requeue 将写入失败的数据放回缓冲区：记录排在新样本之前，超过上限时丢弃最旧的；
+// 服务器状态以之后的更新为准，流量增量与之后的增量相加
+func (w *monitorBatchWriter) requeue(records []ServerMonitor, serverUpdates map[uint]map[string]interface{}, traffic map[trafficKey]*TrafficHourly) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// 事务已回滚，清除插入时回填的主键，重试时重新分配
+	for i := range records {
+		records[i].ID = 0
+	}
+	w.records = append(records, w.records...)
+	if limit := w.batchSize * monitorRetryBatches; len(w.records) > limit {
+		dropped := len(w.records) - limit
+		log.Printf("监控数据写入缓冲区已满，丢弃最旧的 %d 条记录", dropped)
+		w.records = append([]ServerMonitor(nil), w.records[dropped:]...)
+	}
+
+	for serverID, updates := range serverUpdates {
+		w.mergeServerUpdates(serverID, updates, false)
+	}
+	for key, t := range traffic {
+		if current, ok := w.traffic[key]; ok {
+			current.BytesIn += t.BytesIn
+			current.BytesOut += t.BytesOut
+		} else {
+			w.traffic[key] = t
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// NetworkBenchmarkLimit 每台服务器保留的测速记录数，超出后删除最早的记录
+const NetworkBenchmarkLimit = 100
+
+// NetworkBenchmark 一次在服务器上手动发起的测速结果，速率单位为 bits/s，用于对比不同时间的带宽
+type NetworkBenchmark struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	ServerID    uint      `json:"server_id" gorm:"index"`
+	Tool        string    `json:"tool" gorm:"type:varchar(16)"`         // iperf3 / speedtest
+	Target      string    `json:"target" gorm:"type:varchar(255)"`      // iperf3 服务端地址或 speedtest 节点 ID，为空表示自动选择
+	ServerName  string    `json:"server_name" gorm:"type:varchar(255)"` // speedtest 实际使用的测速节点
+	DownloadBps float64   `json:"download_bps"`
+	UploadBps   float64   `json:"upload_bps"`
+	LatencyMs   float64   `json:"latency_ms"`
+	JitterMs    float64   `json:"jitter_ms"`
+	PacketLoss  float64   `json:"packet_loss"` // 丢包率(%)
+	Duration    int       `json:"duration"`    // iperf3 上传、下载各自的测试时长(秒)
+	Username    string    `json:"username"`    // 发起测速的用户
+	CreatedAt   time.Time `json:"created_at" gorm:"index"`
+}
+
+// CreateNetworkBenchmark 保存一次测速结果，并只保留该服务器最近 NetworkBenchmarkLimit 条
+func CreateNetworkBenchmark(benchmark *NetworkBenchmark) error {
+	if err := DB.Create(benchmark).Error; err != nil {
+		return err
+	}
+	var cutoff NetworkBenchmark
+	err := DB.Select("id").Where("server_id = ?", benchmark.ServerID).
+		Order("id DESC").Offset(NetworkBenchmarkLimit - 1).Limit(1).
+		Find(&cutoff).Error
+	if err != nil || cutoff.ID == 0 {
+		return err
+	}
+	return DB.Where("server_id = ? AND id < ?", benchmark.ServerID, cutoff.ID).Delete(&NetworkBenchmark{}).Error
+}
+
+// GetNetworkBenchmarks 获取服务器的测速记录，按时间倒序；tool 为空时不过滤
+func GetNetworkBenchmarks(serverID uint, tool string) ([]NetworkBenchmark, error) {
+	query := DB.Where("server_id = ?", serverID)
+	if tool != "" {
+		query = query.Where("tool = ?", tool)
+	}
+	var benchmarks []NetworkBenchmark
+	err := query.Order("id DESC").Find(&benchmarks).Error
+	return benchmarks, err
+}
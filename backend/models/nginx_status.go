@@ -0,0 +1,61 @@
+package models
+
+import (
+	"encoding/json"
+)
+
+// MaxNginxUpstreams 每次上报保存的 Nginx 上游数上限，与 Agent 一致
+const MaxNginxUpstreams = 32
+
+// NginxStatus Agent 上报的 Nginx stub_status 指标和上游可用性，格式与 Agent 相同
+type NginxStatus struct {
+	Active      int     `json:"active"`       // 当前活动连接数
+	Reading     int     `json:"reading"`      // 正在读取请求头的连接数
+	Writing     int     `json:"writing"`      // 正在写响应的连接数
+	Waiting     int     `json:"waiting"`      // 空闲的 keep-alive 连接数
+	Accepts     uint64  `json:"accepts"`      // 累计接受的连接数
+	Handled     uint64  `json:"handled"`      // 累计处理的连接数
+	Requests    uint64  `json:"requests"`     // 累计请求数
+	RequestRate float64 `json:"request_rate"` // 两次采集之间的每秒请求数
+	Dropped     uint64  `json:"dropped"`      // 两次采集之间新增的未处理连接数，大于 0 说明达到了 worker_connections 上限
+	Up          bool    `json:"up"`           // 成功读取了 stub_status
+	Error       string  `json:"error,omitempty"`
+
+	Upstreams []NginxUpstreamHealth `json:"upstreams,omitempty"`
+}
+
+// NginxUpstreamHealth 一个上游地址的 TCP 连通性
+type NginxUpstreamHealth struct {
+	Address string  `json:"address"`
+	Up      bool    `json:"up"`
+	Latency float64 `json:"latency,omitempty"` // 建立连接耗时(ms)
+	Error   string  `json:"error,omitempty"`
+}
+
+// StubConfigured Agent 是否配置了 stub_status 地址，只配置了上游时为 false
+func (s *NginxStatus) StubConfigured() bool {
+	return s.Up || s.Error != ""
+}
+
+// UpstreamsDown 不可用的上游数
+func (s *NginxStatus) UpstreamsDown() int {
+	down := 0
+	for _, u := range s.Upstreams {
+		if !u.Up {
+			down++
+		}
+	}
+	return down
+}
+
+// NginxStats 解析监控记录中的 Nginx 指标，Agent 未配置 Nginx 采集时返回 nil
+func (m *ServerMonitor) NginxStats() *NginxStatus {
+	if m.NginxStatus == "" {
+		return nil
+	}
+	var status NginxStatus
+	if err := json.Unmarshal([]byte(m.NginxStatus), &status); err != nil {
+		return nil
+	}
+	return &status
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// OOMEvent 服务器上内核 OOM killer 杀死进程的事件
+type OOMEvent struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ServerID   uint      `json:"server_id" gorm:"index"`
+	OccurredAt time.Time `json:"occurred_at" gorm:"index"` // 内核日志中的时间
+	PID        int       `json:"pid"`
+	Process    string    `json:"process"`
+	Message    string    `json:"message" gorm:"type:text"` // 内核日志原文
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CreateOOMEvents 保存Agent上报的OOM事件
+func CreateOOMEvents(events []OOMEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return DB.Create(&events).Error
+}
+
+// GetRecentOOMEvents 获取服务器最近的OOM事件，按发生时间倒序
+func GetRecentOOMEvents(serverID uint, limit int) ([]OOMEvent, error) {
+	var events []OOMEvent
+	err := DB.Where("server_id = ?", serverID).
+		Order("occurred_at DESC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+// DeleteOOMEventsBefore 删除指定时间之前的OOM事件，随预警记录一起清理
+func DeleteOOMEventsBefore(cutoff time.Time) (int64, error) {
+	result := DB.Where("occurred_at < ?", cutoff).Delete(&OOMEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,255 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// 默认禁止探测的目标：链路本地地址及各云厂商的实例元数据服务，
+// 即使配置了允许列表也不能探测，避免探测功能被用来读取云主机凭证
+var defaultDeniedProbeNetworks = []string{
+	"169.254.0.0/16",     // IPv4 链路本地（含 AWS/GCP/Azure 的 169.254.169.254）
+	"fe80::/10",          // IPv6 链路本地
+	"100.100.100.200/32", // 阿里云元数据服务
+	"fd00:ec2::254/128",  // AWS IPv6 元数据服务
+}
+
+// portRange 闭区间端口范围
+type portRange struct {
+	From int
+	To   int
+}
+
+// ProbeTargetRule 探测目标规则，Network 为 nil 表示所有地址，Ports 为空表示所有端口
+type ProbeTargetRule struct {
+	Network *net.IPNet
+	Ports   []portRange
+	Raw     string
+}
+
+// matches 判断地址和端口是否命中规则
+func (r ProbeTargetRule) matches(ip net.IP, port int) bool {
+	if r.Network != nil && !r.Network.Contains(ip) {
+		return false
+	}
+	if len(r.Ports) == 0 {
+		return true
+	}
+	for _, p := range r.Ports {
+		if port >= p.From && port <= p.To {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseProbeTargetRules 解析探测目标规则，每行一条，格式为 "网段 [端口列表]"，例如：
+//
+//	10.0.0.0/8 80,443,8000-9000
+//	192.168.1.10
+//	* 443
+//
+// 单个 IP 视为 /32（IPv6 为 /128），* 表示所有地址；以 # 开头的行为注释
+func ParseProbeTargetRules(text string) ([]ProbeTargetRule, error) {
+	var rules []ProbeTargetRule
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseProbeTargetRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行规则无效（%s）: %w", i+1, line, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseProbeTargetRule(line string) (ProbeTargetRule, error) {
+	fields := strings.Fields(line)
+	if len(fields) > 2 {
+		return ProbeTargetRule{}, errors.New("格式应为 \"网段 [端口列表]\"")
+	}
+	rule := ProbeTargetRule{Raw: line}
+
+	network := fields[0]
+	switch {
+	case network == "*":
+	case strings.Contains(network, "/"):
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			return ProbeTargetRule{}, errors.New("无效的网段")
+		}
+		rule.Network = ipNet
+	default:
+		ip := net.ParseIP(network)
+		if ip == nil {
+			return ProbeTargetRule{}, errors.New("无效的IP地址")
+		}
+		rule.Network = singleIPNet(ip)
+	}
+
+	if len(fields) == 2 {
+		for _, part := range strings.Split(fields[1], ",") {
+			if part == "" {
+				continue
+			}
+			from, to, found := strings.Cut(part, "-")
+			if !found {
+				to = from
+			}
+			start, err1 := strconv.Atoi(from)
+			end, err2 := strconv.Atoi(to)
+			if err1 != nil || err2 != nil || start < 1 || end > 65535 || start > end {
+				return ProbeTargetRule{}, fmt.Errorf("无效的端口范围 %s", part)
+			}
+			rule.Ports = append(rule.Ports, portRange{From: start, To: end})
+		}
+	}
+	return rule, nil
+}
+
+func singleIPNet(ip net.IP) *net.IPNet {
+	if v4 := ip.To4(); v4 != nil {
+		return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
+
+// ProbeTargetPolicy 探测目标策略：先检查内置禁止列表和禁止列表，
+// 再检查允许列表；允许列表为空时放行其余所有目标
+type ProbeTargetPolicy struct {
+	Allow []ProbeTargetRule
+	Deny  []ProbeTargetRule
+}
+
+// NewProbeTargetPolicy 根据允许/禁止规则文本构建策略，并附加内置的禁止网段
+func NewProbeTargetPolicy(allowText, denyText string) (*ProbeTargetPolicy, error) {
+	allow, err := ParseProbeTargetRules(allowText)
+	if err != nil {
+		return nil, fmt.Errorf("允许列表: %w", err)
+	}
+	deny, err := ParseProbeTargetRules(denyText)
+	if err != nil {
+		return nil, fmt.Errorf("禁止列表: %w", err)
+	}
+	for _, cidr := range defaultDeniedProbeNetworks {
+		rule, _ := parseProbeTargetRule(cidr)
+		deny = append(deny, rule)
+	}
+	return &ProbeTargetPolicy{Allow: allow, Deny: deny}, nil
+}
+
+// GetProbeTargetPolicy 读取系统设置中的探测目标策略
+func GetProbeTargetPolicy() (*ProbeTargetPolicy, error) {
+	settings, err := GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	return NewProbeTargetPolicy(settings.ProbeAllowTargets, settings.ProbeDenyTargets)
+}
+
+// CheckIP 检查单个地址和端口是否允许探测
+func (p *ProbeTargetPolicy) CheckIP(ip net.IP, port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("无效的端口 %d", port)
+	}
+	return p.checkAddr(ip, port)
+}
+
+// checkAddr 按规则检查地址，port 为 0 表示 ICMP：只有不限端口的规则才会命中
+func (p *ProbeTargetPolicy) checkAddr(ip net.IP, port int) error {
+	// IPv4 映射的 IPv6 地址（::ffff:169.254.169.254）按 IPv4 处理，避免绕过 IPv4 规则
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	for _, rule := range p.Deny {
+		if rule.matches(ip, port) {
+			return fmt.Errorf("目标 %s 被禁止探测（规则: %s）", probeAddrString(ip, port), rule.Raw)
+		}
+	}
+	if len(p.Allow) == 0 {
+		return nil
+	}
+	for _, rule := range p.Allow {
+		if rule.matches(ip, port) {
+			return nil
+		}
+	}
+	return fmt.Errorf("目标 %s 不在允许探测的范围内", probeAddrString(ip, port))
+}
+
+// probeAddrString 格式化探测目标，ICMP 目标不带端口
+func probeAddrString(ip net.IP, port int) string {
+	if port == 0 {
+		return ip.String()
+	}
+	return net.JoinHostPort(ip.String(), strconv.Itoa(port))
+}
+
+// CheckHost 解析主机名并检查所有解析结果，任一地址被禁止即拒绝。
+// 用于保存探测配置时的校验；实际探测时还需通过 DialControl 检查最终连接的地址，防止 DNS 重绑定
+func (p *ProbeTargetPolicy) CheckHost(ctx context.Context, host string, port int) error {
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+		return p.CheckIP(ip, port)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("解析 %s 失败: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if err := p.CheckIP(addr.IP, port); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResolvePingTarget 解析 ICMP 探测目标并检查所有解析结果，返回用于 ping 的地址。
+// 直接 ping 返回的地址，避免检查与探测之间 DNS 结果变化
+func (p *ProbeTargetPolicy) ResolvePingTarget(ctx context.Context, host string) (net.IP, error) {
+	var ips []net.IP
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("解析 %s 失败: %w", host, err)
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("解析 %s 没有得到地址", host)
+	}
+	for _, ip := range ips {
+		if err := p.checkAddr(ip, 0); err != nil {
+			return nil, err
+		}
+	}
+	return ips[0], nil
+}
+
+// DialControl 可用作 net.Dialer.Control，在建立连接前检查实际要连接的地址
+func (p *ProbeTargetPolicy) DialControl(network, address string, _ syscall.RawConn) error {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("无效的目标地址 %s", address)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return fmt.Errorf("无效的目标端口 %s", portStr)
+	}
+	return p.CheckIP(ip, port)
+}
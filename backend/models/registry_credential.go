@@ -0,0 +1,188 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/user/server-ops-backend/utils"
+	"gorm.io/gorm"
+)
+
+// dockerHubRegistry Docker Hub 的规范名称，未写仓库地址的镜像都来自这里
+const dockerHubRegistry = "docker.io"
+
+// RegistryCredential 镜像仓库凭据，ServerID 为空时对所有服务器生效，
+// 同一仓库同时存在服务器凭据和全局凭据时优先使用服务器凭据
+type RegistryCredential struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ServerID  *uint     `json:"server_id" gorm:"index"`
+	Registry  string    `json:"registry" gorm:"index"`
+	Username  string    `json:"username"`
+	Password  string    `json:"-" gorm:"type:text"` // 加密后的密码或访问令牌
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ErrCredentialKeyNotReady 未初始化凭据加密密钥
+var ErrCredentialKeyNotReady = errors.New("凭据加密密钥未初始化")
+
+var credentialBox *utils.SecretBox
+
+// InitCredentialKey 初始化凭据加密密钥。secret 非空时由其派生密钥；
+// 否则读取 keyFile 中的随机密钥，文件不存在时生成并以 0600 权限保存
+func InitCredentialKey(secret, keyFile string) error {
+	var key []byte
+	if secret != "" {
+		sum := sha256.Sum256([]byte(secret))
+		key = sum[:]
+	} else {
+		data, err := os.ReadFile(keyFile)
+		switch {
+		case err == nil:
+			if key, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(data))); err != nil || len(key) != 32 {
+				return fmt.Errorf("凭据密钥文件 %s 格式错误", keyFile)
+			}
+		case os.IsNotExist(err):
+			key = make([]byte, 32)
+			if _, err := rand.Read(key); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(keyFile), 0700); err != nil {
+				return err
+			}
+			if err := os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600); err != nil {
+				return fmt.Errorf("保存凭据密钥文件失败: %w", err)
+			}
+		default:
+			return fmt.Errorf("读取凭据密钥文件失败: %w", err)
+		}
+	}
+
+	box, err := utils.NewSecretBox(key)
+	if err != nil {
+		return err
+	}
+	credentialBox = box
+	return nil
+}
+
+// sealCredential 加密敏感字段
+func sealCredential(plain string) (string, error) {
+	if credentialBox == nil {
+		return "", ErrCredentialKeyNotReady
+	}
+	return credentialBox.Seal(plain)
+}
+
+// openCredential 解密敏感字段
+func openCredential(sealed string) (string, error) {
+	if credentialBox == nil {
+		return "", ErrCredentialKeyNotReady
+	}
+	plain, err := credentialBox.Open(sealed)
+	if errors.Is(err, utils.ErrSecretBoxOpen) {
+		return "", errors.New("凭据解密失败，加密密钥可能已更换")
+	}
+	return plain, err
+}
+
+// NormalizeRegistry 规范化仓库地址：去掉协议和路径，Docker Hub 的各种写法统一为 docker.io
+func NormalizeRegistry(registry string) string {
+	registry = strings.ToLower(strings.TrimSpace(registry))
+	registry = strings.TrimPrefix(registry, "https://")
+	registry = strings.TrimPrefix(registry, "http://")
+	if i := strings.Index(registry, "/"); i >= 0 {
+		registry = registry[:i]
+	}
+	switch registry {
+	case "", "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return dockerHubRegistry
+	}
+	return registry
+}
+
+// ImageRegistry 返回镜像引用所在的仓库，例如 ghcr.io/org/app:1 为 ghcr.io，nginx 为 docker.io
+func ImageRegistry(imageRef string) string {
+	first, _, found := strings.Cut(strings.TrimSpace(imageRef), "/")
+	if !found || (!strings.ContainsAny(first, ".:") && first != "localhost") {
+		return dockerHubRegistry
+	}
+	return NormalizeRegistry(first)
+}
+
+// DecryptPassword 解密凭据密码
+func (c *RegistryCredential) DecryptPassword() (string, error) {
+	return openCredential(c.Password)
+}
+
+// SaveRegistryCredential 新建或更新镜像仓库凭据，password 为空时保留原密码
+func SaveRegistryCredential(cred *RegistryCredential, password string) error {
+	cred.Registry = NormalizeRegistry(cred.Registry)
+	if password != "" {
+		sealed, err := sealCredential(password)
+		if err != nil {
+			return err
+		}
+		cred.Password = sealed
+	} else if cred.ID == 0 {
+		return errors.New("密码不能为空")
+	}
+
+	query := DB.Model(&RegistryCredential{}).Where("registry = ? AND id <> ?", cred.Registry, cred.ID)
+	if cred.ServerID == nil {
+		query = query.Where("server_id IS NULL")
+	} else {
+		query = query.Where("server_id = ?", *cred.ServerID)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("仓库 %s 的凭据已存在", cred.Registry)
+	}
+
+	return DB.Save(cred).Error
+}
+
+// ListRegistryCredentials 列出所有镜像仓库凭据，全局凭据在前
+func ListRegistryCredentials() ([]RegistryCredential, error) {
+	var creds []RegistryCredential
+	err := DB.Order("server_id IS NOT NULL, server_id, registry").Find(&creds).Error
+	return creds, err
+}
+
+// GetRegistryCredential 获取镜像仓库凭据
+func GetRegistryCredential(id uint) (*RegistryCredential, error) {
+	var cred RegistryCredential
+	if err := DB.First(&cred, id).Error; err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+// DeleteRegistryCredential 删除镜像仓库凭据
+func DeleteRegistryCredential(id uint) error {
+	return DB.Delete(&RegistryCredential{}, id).Error
+}
+
+// FindRegistryCredential 查找服务器访问指定仓库时使用的凭据，没有时返回 nil
+func FindRegistryCredential(serverID uint, registry string) (*RegistryCredential, error) {
+	var cred RegistryCredential
+	err := DB.Where("registry = ? AND (server_id = ? OR server_id IS NULL)", NormalizeRegistry(registry), serverID).
+		Order("server_id IS NULL").First(&cred).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
@@ -0,0 +1,19 @@
+package models
+
+// MaxSensorReadings 每次上报保存的温度或风扇传感器数上限，与 Agent 一致
+const MaxSensorReadings = 64
+
+// MaxInterfaces 每次上报保存的网卡数上限，与 Agent 一致
+const MaxInterfaces = 32
+
+// GetLatestSensorSample 获取服务器最近一条携带传感器读数的监控记录，
+// 没有记录时返回 gorm.ErrRecordNotFound
+func GetLatestSensorSample(serverID uint) (*ServerMonitor, error) {
+	var record ServerMonitor
+	err := DB.Where("server_id = ? AND sensors <> ''", serverID).
+		Order("timestamp DESC").First(&record).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
@@ -32,11 +32,21 @@ type Server struct {
 	AgentVersion    string    `json:"agent_version" gorm:"type:varchar(64)"`  // Agent版本
 	AgentType       string    `json:"agent_type" gorm:"type:varchar(20);default:'full'"` // Agent类型: full 或 monitor
 	CountryCode     string    `json:"country_code" gorm:"type:varchar(10)"`   // 国家代码
-	NetworkInTotal  uint64    `json:"network_in_total" gorm:"default:0"`      // 总入网流量
-	NetworkOutTotal uint64    `json:"network_out_total" gorm:"default:0"`     // 总出网流量
+	NetworkInTotal  uint64    `json:"network_in_total" gorm:"default:0"`      // 总入网流量(bytes)，累加 Agent 上报的增量，统计网卡由 Agent 的 traffic_interface 决定
+	NetworkOutTotal uint64    `json:"network_out_total" gorm:"default:0"`     // 总出网流量(bytes)
+	TrafficResetDay int       `json:"traffic_reset_day" gorm:"default:0"`     // 每月清零总流量的日期(1-31，大于当月天数时取月末)，0表示不清零
+	TrafficResetAt  *time.Time `json:"traffic_reset_at"`                      // 上次清零（或开始计费周期）的时间
 	Latency         float64   `json:"latency" gorm:"default:0"`               // 延迟(ms)
 	PacketLoss      float64   `json:"packet_loss" gorm:"default:0"`           // 丢包率(%)
 	SortOrder       int       `json:"sort_order" gorm:"default:0;index"`      // 显示顺序
+	ClockOffsetMs   int64     `json:"clock_offset_ms" gorm:"default:0"`       // 时钟偏差(ms)：Agent时钟减去面板时钟，正数表示Agent时钟超前
+	ClockRTTMs      int64     `json:"clock_rtt_ms" gorm:"default:0"`          // 测量时钟偏差时的往返时延(ms)
+	ClockCheckedAt  *time.Time `json:"clock_checked_at"`                      // 最近一次测量时钟偏差的时间，为空表示尚未测量
+	ReportInterval  int64     `json:"report_interval" gorm:"default:0"`       // Agent 当前生效的上报间隔(ms)，0 表示未知
+	MonitorIdle     bool      `json:"monitor_idle" gorm:"default:false"`      // Agent 是否处于空闲降频状态
+	ReadOnlyMode    bool      `json:"read_only_mode" gorm:"default:false"`    // 只读模式：拒绝文件写入、进程终止、Docker/Nginx 修改、终端输入等修改类操作
+	LogPaths        string    `json:"log_paths" gorm:"type:text"`             // 日志采集路径，每行一个，支持通配符，为空表示不采集
+	Favorite        bool      `json:"favorite" gorm:"-"`                      // 当前用户是否收藏，由服务器列表接口按用户偏好填写
 	// Monitor 统计信息使用一对多关系
 	Monitors []ServerMonitor `json:"-"`
 }
@@ -64,6 +74,20 @@ type ServerMonitor struct {
 	Processes      int       `json:"processes"`       // 进程数
 	TCPConnections int       `json:"tcp_connections"` // TCP连接数
 	UDPConnections int       `json:"udp_connections"` // UDP连接数
+	Zombies        int       `json:"zombies"`         // 僵尸进程数
+	OOMKills       int       `json:"oom_kills"`       // 采样窗口内新增的 OOM kill 次数
+	AgentErrors    int       `json:"agent_errors"`    // Agent 最近 5 分钟自身的错误数
+	MaxTemperature float64   `json:"max_temperature"` // 所有硬件传感器中的最高温度(°C)，0 表示没有传感器
+
+	CustomMetrics string `json:"custom_metrics" gorm:"type:text"`  // 自定义插件指标 JSON
+	TopProcesses  string `json:"-" gorm:"type:text"`               // CPU、内存占用最高的进程 JSON，含进程名，只通过需要登录的接口返回
+	Sensors       string `json:"sensors" gorm:"type:text"`         // 温度和风扇传感器读数 JSON
+	Interfaces    string `json:"interfaces" gorm:"type:text"`      // 各网卡的流量、错误和丢包 JSON
+	TCPStates     string `json:"tcp_states" gorm:"type:text"`      // 各状态的 TCP 连接数 JSON，如 ESTABLISHED、TIME_WAIT
+	NginxStatus   string `json:"nginx" gorm:"type:text"`           // Nginx stub_status 指标和上游可用性 JSON
+	Databases     string `json:"databases" gorm:"type:text"`       // 数据库服务的健康指标 JSON
+	Scripts       string `json:"scripts" gorm:"type:text"`         // 检查脚本的最新退出码和指标 JSON
+	Maintenance   bool   `json:"maintenance" gorm:"default:false"` // 采样时服务器处于维护窗口内，图表据此标出维护期间
 }
 
 // ServerMonitorData 服务器监控数据
@@ -192,15 +216,52 @@ func GetServerByID(id uint) (*Server, error) {
 	return &server, nil
 }
 
+// ServerStatusUpgrading Agent 正在升级重启，短时间内断开连接属于预期行为
+const ServerStatusUpgrading = "upgrading"
+
+// ServerStatusMaintenance 公开页面中处于维护窗口内且未在线的服务器显示的状态
+const ServerStatusMaintenance = "maintenance"
+
+// AgentUpgradeGracePeriod 升级重启的宽限期，超过该时间仍未重连则视为离线
+const AgentUpgradeGracePeriod = 2 * time.Minute
+
+// defaultHeartbeatTimeout 默认的心跳超时时间
+const defaultHeartbeatTimeout = 15 * time.Second
+
+// HeartbeatTimeout 返回判定服务器离线的心跳超时。
+// Agent 空闲降频后上报间隔可能远大于默认超时，此时按两个上报间隔计算，避免降频被误判为离线
+func HeartbeatTimeout(server *Server) time.Duration {
+	timeout := defaultHeartbeatTimeout
+	if server.MonitorIdle {
+		if idle := 2 * time.Duration(server.ReportInterval) * time.Millisecond; idle > timeout {
+			timeout = idle
+		}
+	}
+	return timeout
+}
+
 // CheckServerStatus 检查服务器的在线状态
-// 如果最后心跳时间超过15秒，则将状态设置为离线
+// 如果最后心跳时间超过心跳超时（默认15秒），则将状态设置为离线
 func CheckServerStatus(server *Server) {
-	// 定义心跳超时时间为15秒
-	const heartbeatTimeout = 15 * time.Second
+	heartbeatTimeout := HeartbeatTimeout(server)
 
 	// 检查最后心跳时间是否超过超时时间
 	timeSinceLastHeartbeat := time.Since(server.LastHeartbeat)
 
+	// 升级中的服务器在宽限期内保持 upgrading 状态，由重连时恢复为在线
+	if server.Status == ServerStatusUpgrading {
+		if timeSinceLastHeartbeat > AgentUpgradeGracePeriod {
+			server.Online = false
+			server.Status = "offline"
+			if err := UpdateServerStatusOnly(server.ID, "offline"); err != nil {
+				log.Printf("更新服务器 %d 状态为离线失败: %v", server.ID, err)
+			} else {
+				log.Printf("服务器 %d 升级后未在宽限期内重连，状态已更新为离线", server.ID)
+			}
+		}
+		return
+	}
+
 	// 记录日志方便调试
 	log.Printf("服务器 %d (%s) 状态检查: 当前状态=%t, 上次心跳=%v, 距今=%v",
 		server.ID, server.Name, server.Online,
@@ -249,6 +310,14 @@ func CreateServer(server *Server) error {
 	return DB.Create(server).Error
 }
 
+// GetTrafficReset 读取服务器的流量清零设置。
+// WebSocket 连接持有的服务器对象在连接期间不会刷新，累加流量前需要重新读取
+func GetTrafficReset(serverID uint) (day int, resetAt *time.Time, err error) {
+	var server Server
+	err = DB.Select("traffic_reset_day", "traffic_reset_at").First(&server, serverID).Error
+	return server.TrafficResetDay, server.TrafficResetAt, err
+}
+
 // UpdateServer 更新服务器信息
 func UpdateServer(server *Server) error {
 	return DB.Save(server).Error
@@ -260,6 +329,51 @@ func DeleteServer(id uint) error {
 	if err := DB.Where("server_id = ?", id).Delete(&ServerMonitor{}).Error; err != nil {
 		return err
 	}
+	if err := DB.Where("server_id = ?", id).Delete(&OOMEvent{}).Error; err != nil {
+		return err
+	}
+	if err := DeleteDiskHealth(id); err != nil {
+		return err
+	}
+	if err := DB.Where("server_id = ?", id).Delete(&ContainerStat{}).Error; err != nil {
+		return err
+	}
+	if err := DB.Where("server_id = ?", id).Delete(&TrafficHourly{}).Error; err != nil {
+		return err
+	}
+	if err := DB.Where("server_id = ?", id).Delete(&FileSnapshot{}).Error; err != nil {
+		return err
+	}
+	if err := DB.Where("server_id = ?", id).Delete(&ServerOperation{}).Error; err != nil {
+		return err
+	}
+	if err := DeletePackageInventory(id); err != nil {
+		return err
+	}
+	if err := DB.Where("server_id = ?", id).Delete(&UserServerPreference{}).Error; err != nil {
+		return err
+	}
+	if err := DB.Where("server_id = ?", id).Delete(&RegistryCredential{}).Error; err != nil {
+		return err
+	}
+	if err := DB.Where("server_id = ?", id).Delete(&UptimeResult{}).Error; err != nil {
+		return err
+	}
+	if err := DB.Where("server_id = ?", id).Delete(&CheckScript{}).Error; err != nil {
+		return err
+	}
+	if err := DB.Where("server_id = ?", id).Delete(&NetworkBenchmark{}).Error; err != nil {
+		return err
+	}
+	if err := DB.Where("server_id = ? OR peer_id = ?", id, id).Delete(&MeshLatency{}).Error; err != nil {
+		return err
+	}
+	if err := DB.Where("server_id = ?", id).Delete(&LogEntry{}).Error; err != nil {
+		return err
+	}
+	if err := DeleteHostCertificates(id); err != nil {
+		return err
+	}
 	return DB.Delete(&Server{}, id).Error
 }
 
@@ -350,6 +464,44 @@ func GetLatestMonitorData(serverID uint, limit int) ([]ServerMonitor, error) {
 	return data, result.Error
 }
 
+// GetMonitorDataRange 获取指定时间范围内的监控数据，并降采样到最多 maxPoints 个点
+// maxPoints <= 0 表示不降采样
+func GetMonitorDataRange(serverID uint, from, to time.Time, maxPoints int) ([]ServerMonitor, error) {
+	var data []ServerMonitor
+	if err := DB.Where("server_id = ? AND timestamp BETWEEN ? AND ?", serverID, from, to).
+		Order("timestamp asc").
+		Find(&data).Error; err != nil {
+		return nil, err
+	}
+
+	return DownsampleMonitorData(data, maxPoints), nil
+}
+
+// DownsampleMonitorData 对按时间排序的监控数据等间隔采样，减少数据点数量
+// 始终保留最后一个点，保证图表末端与最新数据一致
+func DownsampleMonitorData(data []ServerMonitor, maxPoints int) []ServerMonitor {
+	dataLen := len(data)
+	if maxPoints <= 0 || dataLen <= maxPoints {
+		return data
+	}
+	if maxPoints == 1 {
+		return data[dataLen-1:]
+	}
+
+	interval := float64(dataLen-1) / float64(maxPoints-1)
+	sampled := make([]ServerMonitor, 0, maxPoints)
+	for i := 0; i < maxPoints; i++ {
+		idx := int(float64(i) * interval)
+		if idx >= dataLen {
+			idx = dataLen - 1
+		}
+		sampled = append(sampled, data[idx])
+	}
+	sampled[len(sampled)-1] = data[dataLen-1]
+
+	return sampled
+}
+
 // ReorderServers 批量更新服务器顺序
 func ReorderServers(orderedIDs []uint) error {
 	// 在事务中执行批量更新
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+)
+
+// ServerOperationLimit 每台服务器保留的最近操作记录数，超出后删除最早的记录
+const ServerOperationLimit = 200
+
+// ServerOperation 用户在服务器上执行的操作（Docker、文件、终端、进程、Nginx 等）
+type ServerOperation struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ServerID  uint      `json:"server_id" gorm:"index"`
+	UserID    uint      `json:"user_id"`
+	Username  string    `json:"username"`
+	Category  string    `json:"category" gorm:"index"` // docker/file/terminal/process/nginx 等
+	Action    string    `json:"action"`                // 请求方法和路由，如 "POST /nginx/restart"
+	Target    string    `json:"target"`                // 路由参数，如 container_id=abc
+	Status    int       `json:"status"`                // HTTP 状态码
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// RecordServerOperation 保存一条操作记录，并只保留该服务器最近 ServerOperationLimit 条
+func RecordServerOperation(op *ServerOperation) error {
+	if err := DB.Create(op).Error; err != nil {
+		return err
+	}
+	var cutoff ServerOperation
+	err := DB.Select("id").Where("server_id = ?", op.ServerID).
+		Order("id DESC").Offset(ServerOperationLimit - 1).Limit(1).
+		Find(&cutoff).Error
+	if err != nil || cutoff.ID == 0 {
+		return err
+	}
+	return DB.Where("server_id = ? AND id < ?", op.ServerID, cutoff.ID).Delete(&ServerOperation{}).Error
+}
+
+// GetRecentServerOperations 获取服务器最近的操作记录，按时间倒序；category 为空时不过滤
+func GetRecentServerOperations(serverID uint, category string, limit int) ([]ServerOperation, error) {
+	var ops []ServerOperation
+	query := DB.Where("server_id = ?", serverID)
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+	err := query.Order("id DESC").Limit(limit).Find(&ops).Error
+	return ops, err
+}
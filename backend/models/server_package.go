@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PackageInventory 服务器软件包清单的概况，每台服务器一条，随清单一起更新
+type PackageInventory struct {
+	ServerID      uint      `json:"server_id" gorm:"primaryKey;autoIncrement:false"`
+	Manager       string    `json:"manager" gorm:"type:varchar(16)"` // apt、dnf、yum
+	PackageCount  int       `json:"package_count"`
+	UpdateCount   int       `json:"update_count"`
+	SecurityCount int       `json:"security_count"`
+	CollectedAt   time.Time `json:"collected_at"` // Agent 读取清单的时间
+}
+
+// ServerPackage 服务器上已安装的软件包，有可用更新时记录可升级到的版本
+type ServerPackage struct {
+	ID            uint   `json:"-" gorm:"primaryKey"`
+	ServerID      uint   `json:"server_id" gorm:"index"`
+	Name          string `json:"name" gorm:"type:varchar(255);index"`
+	Version       string `json:"version" gorm:"type:varchar(255)"`
+	Arch          string `json:"arch" gorm:"type:varchar(32)"`
+	UpdateVersion string `json:"update_version,omitempty" gorm:"type:varchar(255)"`
+	UpdateRepo    string `json:"update_repo,omitempty" gorm:"type:varchar(255)"`
+	Security      bool   `json:"security"` // 可用更新是否为安全更新
+}
+
+// SavePackageInventory 用新的清单替换服务器原有的软件包记录
+func SavePackageInventory(inventory *PackageInventory, packages []ServerPackage) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("server_id = ?", inventory.ServerID).Delete(&ServerPackage{}).Error; err != nil {
+			return err
+		}
+		for i := range packages {
+			packages[i].ID = 0
+			packages[i].ServerID = inventory.ServerID
+		}
+		if len(packages) > 0 {
+			if err := tx.CreateInBatches(packages, 500).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(inventory).Error
+	})
+}
+
+// GetPackageInventory 获取服务器的软件包清单概况
+func GetPackageInventory(serverID uint) (*PackageInventory, error) {
+	var inventory PackageInventory
+	if err := DB.First(&inventory, "server_id = ?", serverID).Error; err != nil {
+		return nil, err
+	}
+	return &inventory, nil
+}
+
+// ListServerPackages 获取服务器的软件包，search 非空时按包名模糊匹配，updatesOnly 时只返回有可用更新的
+func ListServerPackages(serverID uint, search string, updatesOnly bool) ([]ServerPackage, error) {
+	var packages []ServerPackage
+	query := DB.Where("server_id = ?", serverID)
+	if search != "" {
+		query = query.Where("name LIKE ?", "%"+search+"%")
+	}
+	if updatesOnly {
+		query = query.Where("update_version <> ''")
+	}
+	err := query.Order("name ASC").Find(&packages).Error
+	return packages, err
+}
+
+// FindPackageInstallations 获取所有服务器上名为 name 的软件包
+func FindPackageInstallations(name string) ([]ServerPackage, error) {
+	var packages []ServerPackage
+	err := DB.Where("name = ?", name).Order("server_id ASC").Find(&packages).Error
+	return packages, err
+}
+
+// DeletePackageInventory 删除服务器的软件包清单
+func DeletePackageInventory(serverID uint) error {
+	if err := DB.Where("server_id = ?", serverID).Delete(&ServerPackage{}).Error; err != nil {
+		return err
+	}
+	return DB.Where("server_id = ?", serverID).Delete(&PackageInventory{}).Error
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserServerPreference 用户对单台服务器的个人偏好（收藏、自定义顺序），只影响该用户看到的服务器列表
+type UserServerPreference struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_user_server_pref"`
+	ServerID  uint      `json:"server_id" gorm:"uniqueIndex:idx_user_server_pref;index"`
+	Favorite  bool      `json:"favorite"`
+	Position  int       `json:"position"` // 用户自定义顺序（从1开始），0 表示沿用全局顺序
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GetUserServerPreferences 获取用户的全部服务器偏好，按服务器ID索引
+func GetUserServerPreferences(userID uint) (map[uint]UserServerPreference, error) {
+	var prefs []UserServerPreference
+	if err := DB.Where("user_id = ?", userID).Find(&prefs).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[uint]UserServerPreference, len(prefs))
+	for _, p := range prefs {
+		result[p.ServerID] = p
+	}
+	return result, nil
+}
+
+// SetServerFavorite 收藏或取消收藏服务器
+func SetServerFavorite(userID, serverID uint, favorite bool) error {
+	var pref UserServerPreference
+	err := DB.Where("user_id = ? AND server_id = ?", userID, serverID).First(&pref).Error
+	if err == gorm.ErrRecordNotFound {
+		return DB.Create(&UserServerPreference{UserID: userID, ServerID: serverID, Favorite: favorite}).Error
+	} else if err != nil {
+		return err
+	}
+	return DB.Model(&pref).Update("favorite", favorite).Error
+}
+
+// SaveUserServerOrder 保存用户的自定义服务器顺序，未出现在列表中的服务器恢复为全局顺序
+func SaveUserServerOrder(userID uint, orderedIDs []uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&UserServerPreference{}).Where("user_id = ?", userID).Update("position", 0).Error; err != nil {
+			return err
+		}
+		for index, serverID := range orderedIDs {
+			var pref UserServerPreference
+			err := tx.Where("user_id = ? AND server_id = ?", userID, serverID).First(&pref).Error
+			if err == gorm.ErrRecordNotFound {
+				pref = UserServerPreference{UserID: userID, ServerID: serverID, Position: index + 1}
+				if err := tx.Create(&pref).Error; err != nil {
+					return err
+				}
+				continue
+			} else if err != nil {
+				return err
+			}
+			if err := tx.Model(&pref).Update("position", index+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ResetUserServerOrder 清除用户的自定义顺序，保留收藏
+func ResetUserServerOrder(userID uint) error {
+	if err := DB.Model(&UserServerPreference{}).Where("user_id = ?", userID).Update("position", 0).Error; err != nil {
+		return err
+	}
+	return DB.Where("user_id = ? AND favorite = ?", userID, false).Delete(&UserServerPreference{}).Error
+}
+
+// ApplyServerPreferences 按用户偏好标记收藏并重新排序：收藏的服务器在前，
+// 同组内有自定义顺序的按用户顺序，其余保持传入的全局顺序
+func ApplyServerPreferences(servers []Server, prefs map[uint]UserServerPreference) {
+	if len(prefs) == 0 {
+		return
+	}
+	for i := range servers {
+		servers[i].Favorite = prefs[servers[i].ID].Favorite
+	}
+	sort.SliceStable(servers, func(i, j int) bool {
+		a, b := prefs[servers[i].ID], prefs[servers[j].ID]
+		if a.Favorite != b.Favorite {
+			return a.Favorite
+		}
+		if (a.Position > 0) != (b.Position > 0) {
+			return a.Position > 0
+		}
+		return a.Position < b.Position
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -42,6 +43,22 @@ type SystemSettings struct {
 	AgentReleaseRepo    string `json:"agent_release_repo" gorm:"default:'EnderKC/BetterMonitor'"` // GitHub仓库
 	AgentReleaseChannel string `json:"agent_release_channel" gorm:"default:'stable'"`             // stable/nightly等
 	AgentReleaseMirror  string `json:"agent_release_mirror" gorm:"default:''"`                    // 下载镜像（可选）
+	AgentPinnedVersion  string `json:"agent_pinned_version" gorm:"default:''"`                    // 固定版本，非空时Agent拒绝安装其他版本
+
+	// Agent带宽限制(KB/s)，作用于文件传输和日志流，0表示不限速
+	TransferRateLimit   int `json:"transfer_rate_limit" gorm:"default:0"`   // 单个传输的上限
+	AgentBandwidthLimit int `json:"agent_bandwidth_limit" gorm:"default:0"` // 每个Agent所有传输合计的上限
+
+	// 每次上报携带并保存的 CPU、内存占用最高的进程数，0 表示不上报
+	TopProcessCount int `json:"top_process_count" gorm:"default:0"`
+
+	// 探测目标策略，每行一条 "网段 [端口列表]" 规则，见 ParseProbeTargetRules。
+	// 链路本地地址和云元数据服务始终禁止探测
+	ProbeAllowTargets string `json:"probe_allow_targets" gorm:"type:text"` // 为空表示允许所有未被禁止的目标
+	ProbeDenyTargets  string `json:"probe_deny_targets" gorm:"type:text"`
+
+	// 节点互测间隔(秒)，开启后各 Agent 定期 ping 其他节点，0 表示关闭
+	MeshInterval int `json:"mesh_interval" gorm:"default:0"`
 }
 
 // GetLifeProbeRetention 获取生命探针保留配置
@@ -117,6 +134,11 @@ func ParseDuration(duration string) (time.Duration, error) {
 
 // SaveSettings 保存系统设置
 func SaveSettings(settings *SystemSettings) error {
+	return SaveSettingsTx(DB, settings)
+}
+
+// SaveSettingsTx 在指定的数据库连接（可以是事务）中校验并保存系统设置
+func SaveSettingsTx(tx *gorm.DB, settings *SystemSettings) error {
 	// 验证Duration格式
 	monitorInterval, err := time.ParseDuration(settings.MonitorInterval)
 	if err != nil {
@@ -134,13 +156,31 @@ func SaveSettings(settings *SystemSettings) error {
 		return errors.New("UI刷新间隔不能小于1秒")
 	}
 
+	if settings.TransferRateLimit < 0 || settings.AgentBandwidthLimit < 0 {
+		return errors.New("带宽上限不能为负数")
+	}
+
+	if settings.TopProcessCount < 0 || settings.TopProcessCount > MaxTopProcessCount {
+		return fmt.Errorf("进程排行数必须在 0 到 %d 之间", MaxTopProcessCount)
+	}
+
+	if settings.MeshInterval != 0 && (settings.MeshInterval < MinMeshInterval || settings.MeshInterval > MaxMeshInterval) {
+		return fmt.Errorf("节点互测间隔必须在 %d 到 %d 秒之间", MinMeshInterval, MaxMeshInterval)
+	}
+
+	if _, err := NewProbeTargetPolicy(settings.ProbeAllowTargets, settings.ProbeDenyTargets); err != nil {
+		return errors.New("探测目标策略无效: " + err.Error())
+	}
+
+	settings.AgentPinnedVersion = strings.TrimPrefix(strings.TrimSpace(settings.AgentPinnedVersion), "v")
+
 	var existingSettings SystemSettings
-	result := DB.First(&existingSettings)
+	result := tx.First(&existingSettings)
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			// 如果没有设置，创建新的
-			return DB.Create(settings).Error
+			return tx.Create(settings).Error
 		}
 		return result.Error
 	}
@@ -149,7 +189,7 @@ func SaveSettings(settings *SystemSettings) error {
 	// 注意：GORM 的 Updates(struct) 默认会忽略零值字段（false/0/""），
 	// 会导致布尔开关无法从 true 更新为 false。
 	// 通过 Select("*") 强制更新所有字段，同时 Omit 掉主键/时间戳等不可更新字段。
-	return DB.Model(&existingSettings).
+	return tx.Model(&existingSettings).
 		Select("*").
 		Omit("id", "created_at", "updated_at", "deleted_at").
 		Updates(settings).Error
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxTopProcessCount 每次上报的进程排行数上限，与 Agent 一致
+const MaxTopProcessCount = 20
+
+// FindTopProcessSample 查找离 at 最近、携带进程排行的监控记录，前后各最多 window。
+// 没有符合条件的记录时返回 gorm.ErrRecordNotFound
+func FindTopProcessSample(serverID uint, at time.Time, window time.Duration) (*ServerMonitor, error) {
+	base := func() *gorm.DB {
+		return DB.Where("server_id = ? AND top_processes <> ''", serverID)
+	}
+
+	var before, after ServerMonitor
+	errBefore := base().Where("timestamp BETWEEN ? AND ?", at.Add(-window), at).
+		Order("timestamp DESC").First(&before).Error
+	if errBefore != nil && !errors.Is(errBefore, gorm.ErrRecordNotFound) {
+		return nil, errBefore
+	}
+	errAfter := base().Where("timestamp > ? AND timestamp <= ?", at, at.Add(window)).
+		Order("timestamp ASC").First(&after).Error
+	if errAfter != nil && !errors.Is(errAfter, gorm.ErrRecordNotFound) {
+		return nil, errAfter
+	}
+
+	switch {
+	case errBefore != nil && errAfter != nil:
+		return nil, gorm.ErrRecordNotFound
+	case errAfter != nil:
+		return &before, nil
+	case errBefore != nil:
+		return &after, nil
+	case after.Timestamp.Sub(at) < at.Sub(before.Timestamp):
+		return &after, nil
+	default:
+		return &before, nil
+	}
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TrafficHourlyRetention 每小时流量汇总的保留时长，比原始监控数据长得多，用于按月统计和容量规划
+const TrafficHourlyRetention = 400 * 24 * time.Hour
+
+// TrafficHourly 服务器每小时的流量汇总，Hour 为该小时起点（UTC）
+type TrafficHourly struct {
+	ID       uint      `gorm:"primarykey" json:"-"`
+	ServerID uint      `gorm:"uniqueIndex:idx_traffic_server_hour" json:"server_id"`
+	Hour     time.Time `gorm:"uniqueIndex:idx_traffic_server_hour" json:"hour"`
+	BytesIn  uint64    `json:"bytes_in"`
+	BytesOut uint64    `json:"bytes_out"`
+}
+
+// TrafficBucket 按小时、天或月汇总的流量
+type TrafficBucket struct {
+	Time     time.Time `json:"time"`
+	BytesIn  uint64    `json:"bytes_in"`
+	BytesOut uint64    `json:"bytes_out"`
+}
+
+type trafficKey struct {
+	serverID uint
+	hour     time.Time
+}
+
+// AddTrafficSample 将一个上报周期的流量增量计入所在小时。
+// 启用批量写入时先在内存中按小时合并，随下一次刷新一起提交
+func AddTrafficSample(serverID uint, at time.Time, bytesIn, bytesOut uint64) error {
+	if bytesIn == 0 && bytesOut == 0 {
+		return nil
+	}
+	hour := at.UTC().Truncate(time.Hour)
+
+	monitorWriterMu.RLock()
+	w := monitorWriter
+	monitorWriterMu.RUnlock()
+
+	if w == nil {
+		return addTrafficHourly(DB, serverID, hour, bytesIn, bytesOut)
+	}
+
+	w.mu.Lock()
+	key := trafficKey{serverID: serverID, hour: hour}
+	if t, ok := w.traffic[key]; ok {
+		t.BytesIn += bytesIn
+		t.BytesOut += bytesOut
+	} else {
+		w.traffic[key] = &TrafficHourly{ServerID: serverID, Hour: hour, BytesIn: bytesIn, BytesOut: bytesOut}
+	}
+	w.mu.Unlock()
+	return nil
+}
+
+// addTrafficHourly 累加到对应小时的汇总记录，不存在时创建
+func addTrafficHourly(tx *gorm.DB, serverID uint, hour time.Time, bytesIn, bytesOut uint64) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "server_id"}, {Name: "hour"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"bytes_in":  gorm.Expr("bytes_in + ?", bytesIn),
+			"bytes_out": gorm.Expr("bytes_out + ?", bytesOut),
+		}),
+	}).Create(&TrafficHourly{ServerID: serverID, Hour: hour, BytesIn: bytesIn, BytesOut: bytesOut}).Error
+}
+
+// GetTrafficHourly 获取服务器在 [from, to) 内的每小时流量，按时间排序
+func GetTrafficHourly(serverID uint, from, to time.Time) ([]TrafficHourly, error) {
+	var rows []TrafficHourly
+	err := DB.Where("server_id = ? AND hour >= ? AND hour < ?", serverID, from.UTC().Truncate(time.Hour), to.UTC()).
+		Order("hour ASC").Find(&rows).Error
+	return rows, err
+}
+
+// AggregateTraffic 将每小时流量按 hour/day/month 汇总，天和月按 loc 时区划分
+func AggregateTraffic(rows []TrafficHourly, granularity string, loc *time.Location) []TrafficBucket {
+	buckets := []TrafficBucket{}
+	for _, row := range rows {
+		t := row.Hour.In(loc)
+		switch granularity {
+		case "day":
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		case "month":
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		if n := len(buckets); n > 0 && buckets[n-1].Time.Equal(t) {
+			buckets[n-1].BytesIn += row.BytesIn
+			buckets[n-1].BytesOut += row.BytesOut
+			continue
+		}
+		buckets = append(buckets, TrafficBucket{Time: t, BytesIn: row.BytesIn, BytesOut: row.BytesOut})
+	}
+	return buckets
+}
+
+// DeleteTrafficHourlyBefore 删除指定时间之前的每小时流量汇总
+func DeleteTrafficHourlyBefore(before time.Time) (int64, error) {
+	result := DB.Where("hour < ?", before.UTC()).Delete(&TrafficHourly{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,377 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 可用性检查类型
+const (
+	UptimeCheckHTTP = "http"
+	UptimeCheckTCP  = "tcp"
+	UptimeCheckICMP = "icmp"
+	UptimeCheckTLS  = "tls"
+)
+
+// 可用性检查的间隔与超时限制（秒）
+const (
+	MinUptimeInterval      = 10
+	DefaultUptimeInterval  = 60
+	DefaultUptimeTimeout   = 10
+	MaxUptimeTimeout       = 60
+	DefaultUptimeFailures  = 3
+	uptimeResultQueryLimit = 10000
+)
+
+// UptimeCheck 由面板后端定时执行的 HTTP/TCP/ICMP/TLS 可用性检查
+type UptimeCheck struct {
+	ID               uint   `json:"id" gorm:"primaryKey"`
+	Name             string `json:"name" gorm:"type:varchar(100);not null"`
+	Type             string `json:"type" gorm:"type:varchar(10);not null"`    // http, tcp, icmp, tls
+	Target           string `json:"target" gorm:"type:varchar(500);not null"` // http 为 URL，tcp 和 tls 为 host:port，icmp 为主机名或 IP
+	Interval         int    `json:"interval"`                                 // 检查间隔(秒)
+	Timeout          int    `json:"timeout"`                                  // 单次检查超时(秒)
+	ExpectedStatus   int    `json:"expected_status"`                          // http 期望的状态码，0 表示 2xx/3xx 均视为正常
+	FailureThreshold int    `json:"failure_threshold"`                        // 连续失败多少次后告警
+	ChannelIDs       string `json:"channel_ids" gorm:"type:varchar(255)"`     // 指定的通知渠道ID，逗号分隔，为空表示按可用性分类路由
+	ServerIDs        string `json:"server_ids" gorm:"type:varchar(255)"`      // 同时执行检查的 Agent 服务器ID，逗号分隔，用于从多个地区探测
+	AgentOnly        bool   `json:"agent_only"`                               // 只由指定的 Agent 执行，面板不再定时执行
+	TLSServerName    string `json:"tls_server_name" gorm:"type:varchar(255)"` // tls 握手的 SNI 和校验证书使用的主机名，为空时使用目标主机
+	TLSSkipVerify    bool   `json:"tls_skip_verify"`                          // tls 不校验证书链和主机名（如设备的自签名证书），仍检查有效期
+	CertMinDays      int    `json:"cert_min_days"`                            // tls 证书剩余有效期少于该天数时视为失败，0 表示过期时才失败
+	Enabled          bool   `json:"enabled" gorm:"default:true"`
+
+	LastStatus          string    `json:"last_status" gorm:"type:varchar(10)"` // up, down，未检查过为空
+	LastCheckedAt       time.Time `json:"last_checked_at"`
+	LastResponseMs      int64     `json:"last_response_ms"`
+	LastError           string    `json:"last_error" gorm:"type:varchar(500)"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	AlertRecordID       uint      `json:"alert_record_id"`    // 当前未解决的告警记录，0 表示没有
+	LastTLS             string    `json:"-" gorm:"type:text"` // JSON格式的最近一次 tls 握手信息（TLSEndpointInfo）
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UptimeResult 单次可用性检查结果，随监控数据按保留天数清理
+type UptimeResult struct {
+	ID             uint      `json:"-" gorm:"primaryKey"`
+	CheckID        uint      `json:"check_id" gorm:"index:idx_uptime_result_time"`
+	ServerID       uint      `json:"server_id" gorm:"default:0;index"` // 执行检查的 Agent 服务器，0 表示面板
+	Timestamp      time.Time `json:"timestamp" gorm:"index:idx_uptime_result_time"`
+	Success        bool      `json:"success"`
+	ResponseTimeMs int64     `json:"response_time_ms"`
+	StatusCode     int       `json:"status_code"` // 仅 http 检查
+	Error          string    `json:"error" gorm:"type:varchar(500)"`
+
+	TLS *TLSEndpointInfo `json:"tls,omitempty" gorm:"-"` // 仅 tls 检查，握手成功时携带，只保存最近一次到检查上
+}
+
+// TLSCertificateInfo tls 检查得到的证书链中的一张证书
+type TLSCertificateInfo struct {
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	DNSNames     []string  `json:"dns_names,omitempty"`
+	SerialNumber string    `json:"serial_number"`
+	Fingerprint  string    `json:"fingerprint"` // SHA-256，十六进制
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+}
+
+// TLSEndpointInfo tls 检查握手得到的协议版本、密码套件和证书链（第一张为服务器证书）
+type TLSEndpointInfo struct {
+	Version     string               `json:"version"`
+	CipherSuite string               `json:"cipher_suite"`
+	ServerName  string               `json:"server_name"`
+	VerifyError string               `json:"verify_error,omitempty"` // 证书链或主机名校验失败的原因
+	Chain       []TLSCertificateInfo `json:"chain"`
+	ServerID    uint                 `json:"server_id"` // 执行检查的位置，0 表示面板
+	CheckedAt   time.Time            `json:"checked_at"`
+}
+
+// GetLastTLS 解析最近一次 tls 握手信息，没有时返回 nil
+func (c *UptimeCheck) GetLastTLS() *TLSEndpointInfo {
+	if c.LastTLS == "" {
+		return nil
+	}
+	var info TLSEndpointInfo
+	if err := json.Unmarshal([]byte(c.LastTLS), &info); err != nil {
+		return nil
+	}
+	return &info
+}
+
+// SetLastTLS 保存最近一次 tls 握手信息
+func (c *UptimeCheck) SetLastTLS(info *TLSEndpointInfo) {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return
+	}
+	c.LastTLS = string(data)
+}
+
+// UptimeSummary 一段时间内的可用性统计
+type UptimeSummary struct {
+	Total         int64   `json:"total"`
+	Success       int64   `json:"success"`
+	UptimePercent float64 `json:"uptime_percent"` // 没有检查结果时为 -1
+	AvgResponseMs float64 `json:"avg_response_ms"`
+}
+
+// UptimeRegionSummary 单个执行位置（面板或某台 Agent 服务器）的可用性统计
+type UptimeRegionSummary struct {
+	ServerID uint `json:"server_id"`
+	UptimeSummary
+}
+
+// AgentServerIDs 返回执行检查的 Agent 服务器ID
+func (c *UptimeCheck) AgentServerIDs() []uint {
+	var ids []uint
+	seen := make(map[uint]bool)
+	for _, item := range strings.Split(c.ServerIDs, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(item), 10, 32)
+		if err != nil || id == 0 || seen[uint(id)] {
+			continue
+		}
+		seen[uint(id)] = true
+		ids = append(ids, uint(id))
+	}
+	return ids
+}
+
+// AssignedTo 判断检查是否分配给了指定的 Agent 服务器
+func (c *UptimeCheck) AssignedTo(serverID uint) bool {
+	for _, id := range c.AgentServerIDs() {
+		if id == serverID {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize 填充默认值并校验检查配置，返回目标的主机名和端口（icmp 端口为 0）
+func (c *UptimeCheck) Normalize() (host string, port int, err error) {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Type = strings.ToLower(strings.TrimSpace(c.Type))
+	c.Target = strings.TrimSpace(c.Target)
+	if c.Name == "" {
+		return "", 0, errors.New("名称不能为空")
+	}
+	if c.Interval == 0 {
+		c.Interval = DefaultUptimeInterval
+	}
+	if c.Interval < MinUptimeInterval {
+		return "", 0, fmt.Errorf("检查间隔不能小于 %d 秒", MinUptimeInterval)
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultUptimeTimeout
+	}
+	if c.Timeout > MaxUptimeTimeout {
+		c.Timeout = MaxUptimeTimeout
+	}
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = DefaultUptimeFailures
+	}
+	ids := c.AgentServerIDs()
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.FormatUint(uint64(id), 10))
+	}
+	c.ServerIDs = strings.Join(parts, ",")
+	if c.AgentOnly && len(ids) == 0 {
+		return "", 0, errors.New("只由 Agent 执行时需要至少指定一台服务器")
+	}
+	if c.ExpectedStatus != 0 && (c.ExpectedStatus < 100 || c.ExpectedStatus > 599) {
+		return "", 0, fmt.Errorf("无效的期望状态码 %d", c.ExpectedStatus)
+	}
+	c.TLSServerName = strings.TrimSpace(c.TLSServerName)
+	if c.Type != UptimeCheckTLS {
+		c.TLSServerName = ""
+		c.TLSSkipVerify = false
+		c.CertMinDays = 0
+		c.LastTLS = ""
+	}
+	if c.CertMinDays < 0 || c.CertMinDays > 3650 {
+		return "", 0, errors.New("证书剩余天数必须在 0 到 3650 之间")
+	}
+	if len(c.TLSServerName) > 255 || strings.ContainsAny(c.TLSServerName, " /:") {
+		return "", 0, errors.New("无效的 TLS 主机名")
+	}
+
+	switch c.Type {
+	case UptimeCheckHTTP:
+		u, err := url.Parse(c.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+			return "", 0, errors.New("HTTP 检查的目标必须是 http:// 或 https:// 开头的 URL")
+		}
+		port := 80
+		if u.Scheme == "https" {
+			port = 443
+		}
+		if p := u.Port(); p != "" {
+			port, err = strconv.Atoi(p)
+			if err != nil || port < 1 || port > 65535 {
+				return "", 0, fmt.Errorf("无效的端口 %s", p)
+			}
+		}
+		return u.Hostname(), port, nil
+	case UptimeCheckTCP, UptimeCheckTLS:
+		h, p, err := net.SplitHostPort(c.Target)
+		if err != nil || h == "" {
+			return "", 0, fmt.Errorf("%s 检查的目标格式应为 主机:端口", strings.ToUpper(c.Type))
+		}
+		port, err := strconv.Atoi(p)
+		if err != nil || port < 1 || port > 65535 {
+			return "", 0, fmt.Errorf("无效的端口 %s", p)
+		}
+		return h, port, nil
+	case UptimeCheckICMP:
+		if c.Target == "" || (strings.ContainsAny(c.Target, " /:") && net.ParseIP(c.Target) == nil) {
+			return "", 0, errors.New("ICMP 检查的目标应为主机名或 IP 地址")
+		}
+		return c.Target, 0, nil
+	default:
+		return "", 0, fmt.Errorf("不支持的检查类型: %s", c.Type)
+	}
+}
+
+// ListUptimeChecks 获取所有可用性检查
+func ListUptimeChecks() ([]UptimeCheck, error) {
+	var checks []UptimeCheck
+	err := DB.Order("id ASC").Find(&checks).Error
+	return checks, err
+}
+
+// GetEnabledUptimeChecks 获取已启用的可用性检查
+func GetEnabledUptimeChecks() ([]UptimeCheck, error) {
+	var checks []UptimeCheck
+	err := DB.Where("enabled = ?", true).Find(&checks).Error
+	return checks, err
+}
+
+// GetAgentUptimeChecks 获取分配给指定 Agent 服务器执行的已启用检查
+func GetAgentUptimeChecks(serverID uint) ([]UptimeCheck, error) {
+	var checks []UptimeCheck
+	if err := DB.Where("enabled = ? AND server_ids <> ''", true).Find(&checks).Error; err != nil {
+		return nil, err
+	}
+	assigned := checks[:0]
+	for _, check := range checks {
+		if check.AssignedTo(serverID) {
+			assigned = append(assigned, check)
+		}
+	}
+	return assigned, nil
+}
+
+// GetUptimeCheck 通过ID获取可用性检查
+func GetUptimeCheck(id uint) (*UptimeCheck, error) {
+	var check UptimeCheck
+	if err := DB.First(&check, id).Error; err != nil {
+		return nil, err
+	}
+	return &check, nil
+}
+
+// SaveUptimeCheck 新建或更新可用性检查
+func SaveUptimeCheck(check *UptimeCheck) error {
+	return DB.Save(check).Error
+}
+
+// DeleteUptimeCheck 删除可用性检查及其历史结果
+func DeleteUptimeCheck(id uint) error {
+	if err := DB.Where("check_id = ?", id).Delete(&UptimeResult{}).Error; err != nil {
+		return err
+	}
+	return DB.Delete(&UptimeCheck{}, id).Error
+}
+
+// UpdateUptimeCheckState 更新检查的最近状态字段，不影响用户可编辑的配置
+func UpdateUptimeCheckState(check *UptimeCheck) error {
+	return DB.Model(&UptimeCheck{}).Where("id = ?", check.ID).Updates(map[string]interface{}{
+		"last_status":          check.LastStatus,
+		"last_checked_at":      check.LastCheckedAt,
+		"last_response_ms":     check.LastResponseMs,
+		"last_error":           check.LastError,
+		"consecutive_failures": check.ConsecutiveFailures,
+		"alert_record_id":      check.AlertRecordID,
+		"last_tls":             check.LastTLS,
+	}).Error
+}
+
+// CreateUptimeResult 保存一次检查结果
+func CreateUptimeResult(result *UptimeResult) error {
+	return DB.Create(result).Error
+}
+
+// GetUptimeResults 获取检查在时间范围内的结果，按时间升序；serverID 为 nil 时返回所有执行位置的结果
+func GetUptimeResults(checkID uint, serverID *uint, since, until time.Time) ([]UptimeResult, error) {
+	query := DB.Where("check_id = ? AND timestamp BETWEEN ? AND ?", checkID, since, until)
+	if serverID != nil {
+		query = query.Where("server_id = ?", *serverID)
+	}
+	var results []UptimeResult
+	err := query.Order("timestamp ASC").Limit(uptimeResultQueryLimit).Find(&results).Error
+	return results, err
+}
+
+// uptimeSummarySelect 可用性统计的聚合字段，平均响应时间只计算成功的检查
+const uptimeSummarySelect = "COUNT(*) AS total, COALESCE(SUM(CASE WHEN success THEN 1 ELSE 0 END), 0) AS success, " +
+	"COALESCE(AVG(CASE WHEN success THEN response_time_ms END), 0) AS avg_ms"
+
+// uptimeSummaryRow 可用性统计的查询结果
+type uptimeSummaryRow struct {
+	ServerID uint
+	Total    int64
+	Success  int64
+	AvgMs    float64
+}
+
+func (r uptimeSummaryRow) summary() UptimeSummary {
+	summary := UptimeSummary{Total: r.Total, Success: r.Success, UptimePercent: -1, AvgResponseMs: r.AvgMs}
+	if r.Total > 0 {
+		summary.UptimePercent = float64(r.Success) * 100 / float64(r.Total)
+	}
+	return summary
+}
+
+// GetUptimeSummary 统计检查在指定时间之后所有执行位置合计的可用率和平均响应时间
+func GetUptimeSummary(checkID uint, since time.Time) (UptimeSummary, error) {
+	var row uptimeSummaryRow
+	err := DB.Model(&UptimeResult{}).Select(uptimeSummarySelect).
+		Where("check_id = ? AND timestamp >= ?", checkID, since).
+		Scan(&row).Error
+	if err != nil {
+		return UptimeSummary{}, err
+	}
+	return row.summary(), nil
+}
+
+// GetUptimeRegionSummaries 按执行位置分别统计检查在指定时间之后的可用率和平均响应时间，按服务器ID排序（面板在前）
+func GetUptimeRegionSummaries(checkID uint, since time.Time) ([]UptimeRegionSummary, error) {
+	var rows []uptimeSummaryRow
+	err := DB.Model(&UptimeResult{}).Select("server_id, "+uptimeSummarySelect).
+		Where("check_id = ? AND timestamp >= ?", checkID, since).
+		Group("server_id").Order("server_id ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]UptimeRegionSummary, 0, len(rows))
+	for _, row := range rows {
+		summaries = append(summaries, UptimeRegionSummary{ServerID: row.ServerID, UptimeSummary: row.summary()})
+	}
+	return summaries, nil
+}
+
+// DeleteUptimeResultsBefore 删除指定时间之前的检查结果
+func DeleteUptimeResultsBefore(before time.Time) (int64, error) {
+	result := DB.Where("timestamp < ?", before).Delete(&UptimeResult{})
+	return result.RowsAffected, result.Error
+}
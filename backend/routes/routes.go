@@ -61,6 +61,10 @@ func SetupRoutes(r *gin.Engine) {
 		api.GET("/version", controllers.GetDashboardVersion)
 		api.GET("/health", controllers.HealthCheck)
 
+		// Agent 使用注册令牌自动注册（可携带服务器名称和标签）
+		api.POST("/servers/register", controllers.RegisterServer)
+		api.POST("/agent/register", controllers.RegisterServer)
+
 		// Agent 获取配置接口
 		api.GET("/servers/:id/settings", controllers.GetAgentSettings)
 
@@ -89,9 +93,23 @@ func SetupRoutes(r *gin.Engine) {
 			auth.POST("/servers/:id/switch-agent-type", controllers.SwitchAgentType)
 			auth.DELETE("/servers/:id", controllers.DeleteServer)
 			auth.PUT("/servers/reorder", controllers.ReorderServers)
+			auth.PUT("/servers/my-order", controllers.SaveMyServerOrder)
+			auth.DELETE("/servers/my-order", controllers.ResetMyServerOrder)
+			auth.PUT("/servers/:id/favorite", controllers.SetServerFavorite)
 
 			// 监控数据
 			auth.GET("/servers/:id/monitor", controllers.GetServerMonitor)
+			auth.GET("/servers/:id/monitor/history", controllers.GetServerMonitorHistory)
+			auth.GET("/servers/:id/traffic", controllers.GetServerTrafficHistory)
+			auth.GET("/servers/:id/oom-events", controllers.GetServerOOMEvents)
+			auth.GET("/servers/:id/disk-health", controllers.GetServerDiskHealth)
+			auth.GET("/servers/:id/host-certificates", controllers.GetServerHostCertificates)
+			auth.GET("/host-certificates", controllers.ListHostCertificates)
+			auth.GET("/servers/:id/top-processes", controllers.GetServerTopProcesses)
+			auth.GET("/servers/:id/sensors", controllers.GetServerSensors)
+			auth.GET("/servers/:id/mounts", controllers.GetServerDiskMounts)
+			auth.GET("/servers/:id/mounts/history", controllers.GetServerDiskMountHistory)
+			auth.GET("/servers/:id/operations", controllers.GetServerOperations)
 
 			// 生命探针管理
 			auth.GET("/life-probes", controllers.ListLifeProbes)
@@ -105,32 +123,83 @@ func SetupRoutes(r *gin.Engine) {
 			auth.GET("/system/info", controllers.GetSystemInfo)
 			auth.GET("/servers/versions", controllers.GetServerVersions)
 
+			// 节点互测矩阵
+			auth.GET("/servers/mesh", controllers.GetMeshLatency)
+
+			// 日志采集与搜索（修改采集路径需要管理员权限）
+			auth.GET("/logs/search", controllers.SearchLogs)
+			auth.GET("/servers/:id/log-paths", controllers.GetServerLogPaths)
+			auth.PUT("/servers/:id/log-paths", middleware.AdminAuthMiddleware(), controllers.UpdateServerLogPaths)
+
+			// 按已保存的软件包清单查询缺少补丁的服务器
+			auth.GET("/packages/missing", controllers.FindServersMissingPackage)
+
 			// Agent升级管理
 			auth.GET("/agents/releases/latest", controllers.GetLatestAgentRelease)
 			auth.POST("/servers/upgrade", controllers.ForceAgentUpgrade)
 
+			// Agent运行时日志级别
+			auth.GET("/servers/:id/agent/log-level", controllers.GetAgentLogLevel)
+			auth.PUT("/servers/:id/agent/log-level", controllers.SetAgentLogLevel)
+
+			// Agent内部错误计数
+			auth.GET("/servers/:id/agent/errors", controllers.GetAgentErrorStats)
+			auth.DELETE("/servers/:id/agent/errors", controllers.ResetAgentErrorStats)
+
+			// Agent心跳与重连（无需重启Agent的恢复手段）
+			auth.POST("/servers/:id/agent/poke", controllers.PokeAgent)
+			auth.POST("/servers/:id/agent/reconnect", middleware.AdminAuthMiddleware(), controllers.ReconnectAgent)
+
+			// 只读模式（开关需要管理员权限）
+			auth.PUT("/servers/:id/read-only", middleware.AdminAuthMiddleware(), controllers.SetServerReadOnly)
+
+			// Agent远程配置（修改需要管理员权限）
+			auth.GET("/servers/:id/agent/config", controllers.GetAgentConfig)
+			auth.PUT("/servers/:id/agent/config", middleware.AdminAuthMiddleware(), controllers.UpdateAgentConfig)
+
+			// 配置导出/导入（备份与迁移，需要管理员权限）
+			auth.GET("/export", middleware.AdminAuthMiddleware(), controllers.ExportConfig)
+			auth.POST("/import", middleware.AdminAuthMiddleware(), controllers.ImportConfig)
+
 			// ===== 操作类路由（受 MonitorOnlyGuard 保护） =====
-			// 监控模式服务器访问以下路由时返回 403 Forbidden
+			// 监控模式服务器访问以下路由时返回 403 Forbidden，修改类请求会记录到服务器最近操作中
 			ops := auth.Group("/")
-			ops.Use(middleware.MonitorOnlyGuard())
+			ops.Use(middleware.MonitorOnlyGuard(), middleware.RecordServerOperations())
 			{
 				// 终端会话管理
 				ops.GET("/servers/:id/terminal/sessions", controllers.GetTerminalSessions)
+				ops.GET("/servers/:id/connection-quality", controllers.GetConnectionQuality)
 				ops.POST("/servers/:id/terminal/sessions", controllers.CreateTerminalSession)
 				ops.DELETE("/servers/:id/terminal/sessions/:session_id", controllers.DeleteTerminalSession)
 				ops.GET("/servers/:id/terminal/sessions/:session_id/cwd", controllers.GetTerminalWorkingDirectory)
+				ops.GET("/servers/:id/terminal/captures", controllers.ListCommandCaptures)
+				ops.POST("/servers/:id/terminal/captures", middleware.AdminAuthMiddleware(), controllers.StartCommandCapture)
+				ops.DELETE("/servers/:id/terminal/captures/:capture_id", middleware.AdminAuthMiddleware(), controllers.StopCommandCapture)
 
 				// 文件管理API
 				ops.GET("/servers/:id/files", controllers.GetFileList)
 				ops.GET("/servers/:id/files/tree", controllers.GetFileTree)
 				ops.GET("/servers/:id/files/children", controllers.GetDirectoryChildren)
+				ops.GET("/servers/:id/files/search", controllers.SearchFiles)
 				ops.GET("/servers/:id/files/content", controllers.GetFileContent)
 				ops.PUT("/servers/:id/files/content", controllers.SaveFileContent)
 				ops.POST("/servers/:id/files/create", controllers.CreateFile)
 				ops.POST("/servers/:id/files/mkdir", controllers.CreateDirectory)
 				ops.POST("/servers/:id/files/upload", controllers.UploadFile)
 				ops.GET("/servers/:id/files/download", controllers.DownloadFile)
+				ops.GET("/servers/:id/files/archive", controllers.DownloadArchive)
 				ops.POST("/servers/:id/files/delete", controllers.DeleteFiles)
+				ops.POST("/servers/:id/files/chmod", controllers.ChmodFile)
+				ops.POST("/servers/:id/files/chown", controllers.ChownFile)
+				ops.POST("/servers/:id/files/rename", controllers.RenameFile)
+				ops.POST("/servers/:id/files/move", controllers.MoveFile)
+				ops.POST("/servers/:id/files/copy", controllers.CopyFile)
+				ops.POST("/servers/:id/files/log-rotate", middleware.AdminAuthMiddleware(), controllers.RotateLogFile)
+				ops.POST("/servers/:id/files/diff", controllers.GetFileDiff)
+				ops.GET("/servers/:id/files/snapshots", controllers.ListFileSnapshots)
+				ops.POST("/servers/:id/files/snapshots", controllers.CreateFileSnapshot)
+				ops.GET("/servers/:id/files/snapshots/diff", controllers.DiffFileSnapshots)
+				ops.DELETE("/servers/:id/files/snapshots/:snapshot_id", controllers.DeleteFileSnapshot)
 
 				// 分片上传API
 				ops.POST("/servers/:id/files/upload/chunked/init", controllers.InitUpload)
@@ -142,13 +211,40 @@ func SetupRoutes(r *gin.Engine) {
 				// 进程管理API
 				ops.GET("/servers/:id/processes", controllers.GetProcesses)
 				ops.DELETE("/servers/:id/processes/:pid", controllers.KillProcess)
+				ops.POST("/servers/:id/processes/kill-by-name", controllers.KillProcessesByName)
+				ops.GET("/servers/:id/connections", controllers.GetConnections)
+				ops.GET("/servers/:id/listening-ports", middleware.AdminAuthMiddleware(), controllers.GetListeningPorts)
+				ops.GET("/servers/:id/netstat", middleware.AdminAuthMiddleware(), controllers.GetNetstat)
+
+				// 测速API（测速会占满带宽，发起需要管理员权限）
+				ops.GET("/servers/:id/benchmarks", controllers.GetNetworkBenchmarks)
+				ops.POST("/servers/:id/benchmarks", middleware.AdminAuthMiddleware(), controllers.RunNetworkBenchmark)
+
+				// 检查脚本（脚本在服务器上执行，修改需要管理员权限）
+				ops.GET("/servers/:id/check-scripts", controllers.ListCheckScripts)
+				ops.POST("/servers/:id/check-scripts", middleware.AdminAuthMiddleware(), controllers.CreateCheckScript)
+				ops.PUT("/servers/:id/check-scripts/:script_id", middleware.AdminAuthMiddleware(), controllers.UpdateCheckScript)
+				ops.DELETE("/servers/:id/check-scripts/:script_id", middleware.AdminAuthMiddleware(), controllers.DeleteCheckScript)
+
+				// systemd 服务管理API
+				ops.GET("/servers/:id/services", controllers.GetServices)
+				ops.GET("/servers/:id/services/:name", controllers.GetServiceStatus)
+				ops.GET("/servers/:id/services/:name/logs", controllers.GetServiceLogs)
+				ops.POST("/servers/:id/services/:name/:action", controllers.ControlService)
+
+				// 软件包管理API（安装、升级、卸载需要管理员权限）
+				ops.GET("/servers/:id/packages", controllers.GetPackages)
+				ops.POST("/servers/:id/packages/refresh", controllers.RefreshPackages)
+				ops.POST("/servers/:id/packages/:action", middleware.AdminAuthMiddleware(), controllers.RunPackageCommand)
 
 				// Docker管理API
 				ops.GET("/servers/:id/docker/containers", controllers.GetContainers)
+				ops.GET("/servers/:id/docker/stats", controllers.GetContainerStatsHistory)
 				ops.GET("/servers/:id/docker/containers/:container_id/logs", controllers.GetContainerLogs)
 				ops.POST("/servers/:id/docker/containers/:container_id/start", controllers.StartContainer)
 				ops.POST("/servers/:id/docker/containers/:container_id/stop", controllers.StopContainer)
 				ops.POST("/servers/:id/docker/containers/:container_id/restart", controllers.RestartContainer)
+				ops.POST("/servers/:id/docker/containers/:container_id/exec", controllers.ExecContainerCommand)
 				ops.DELETE("/servers/:id/docker/containers/:container_id", controllers.RemoveContainer)
 				ops.POST("/servers/:id/docker/containers", controllers.CreateContainer)
 
@@ -166,6 +262,7 @@ func SetupRoutes(r *gin.Engine) {
 				ops.GET("/servers/:id/docker/images", controllers.GetImages)
 				ops.POST("/servers/:id/docker/images/pull", controllers.PullImage)
 				ops.DELETE("/servers/:id/docker/images/:image_id", controllers.RemoveImage)
+				ops.POST("/servers/:id/docker/registries/login", controllers.RegistryLogin)
 
 				ops.GET("/servers/:id/docker/composes", controllers.GetComposes)
 				ops.GET("/servers/:id/docker/composes/:name/config", controllers.GetComposeConfig)
@@ -173,6 +270,22 @@ func SetupRoutes(r *gin.Engine) {
 				ops.POST("/servers/:id/docker/composes/:name/down", controllers.ComposeDown)
 				ops.DELETE("/servers/:id/docker/composes/:name", controllers.RemoveCompose)
 				ops.POST("/servers/:id/docker/composes", controllers.CreateCompose)
+				ops.POST("/servers/:id/docker/composes/:name/validate", controllers.ValidateCompose)
+				ops.PUT("/servers/:id/docker/composes/:name", controllers.UpdateCompose)
+				ops.GET("/servers/:id/docker/composes/:name/services", controllers.GetComposeServices)
+				ops.POST("/servers/:id/docker/composes/:name/services/:service/:op", controllers.ComposeServiceAction)
+
+				ops.GET("/servers/:id/docker/networks", controllers.GetNetworks)
+				ops.GET("/servers/:id/docker/networks/:network_id", controllers.InspectNetwork)
+				ops.POST("/servers/:id/docker/networks", controllers.CreateNetwork)
+				ops.DELETE("/servers/:id/docker/networks/:network_id", controllers.RemoveNetwork)
+				ops.POST("/servers/:id/docker/networks/prune", controllers.PruneNetworks)
+
+				ops.GET("/servers/:id/docker/volumes", controllers.GetVolumes)
+				ops.GET("/servers/:id/docker/volumes/:name", controllers.InspectVolume)
+				ops.POST("/servers/:id/docker/volumes", controllers.CreateVolume)
+				ops.DELETE("/servers/:id/docker/volumes/:name", controllers.RemoveVolume)
+				ops.POST("/servers/:id/docker/volumes/prune", controllers.PruneVolumes)
 
 				// Nginx管理API
 				ops.GET("/servers/:id/nginx/configs", controllers.NginxConfigsList)
@@ -189,6 +302,11 @@ func SetupRoutes(r *gin.Engine) {
 				ops.GET("/servers/:id/nginx/test", controllers.TestNginxConfig)
 				ops.GET("/servers/:id/nginx/processes", controllers.GetNginxProcesses)
 				ops.GET("/servers/:id/nginx/ports", controllers.GetNginxPorts)
+				ops.GET("/servers/:id/webservers", controllers.DetectWebServers)
+				ops.GET("/servers/:id/webservers/vhosts", controllers.GetWebServerVirtualHosts)
+				ops.GET("/servers/:id/nginx/snapshots", controllers.ListNginxSnapshots)
+				ops.POST("/servers/:id/nginx/snapshots", controllers.CreateNginxSnapshot)
+				ops.POST("/servers/:id/nginx/snapshots/:snapshot_id/restore", middleware.AdminAuthMiddleware(), controllers.RestoreNginxSnapshot)
 				ops.GET("/servers/:id/websites", controllers.ListWebsites)
 				ops.GET("/servers/:id/websites/:domain", controllers.GetWebsiteDetail)
 				ops.GET("/servers/:id/websites/:domain/nginx", controllers.GetWebsiteNginxConfig)
@@ -197,6 +315,7 @@ func SetupRoutes(r *gin.Engine) {
 				ops.POST("/servers/:id/nginx/openresty/install", controllers.InstallOpenResty)
 				ops.GET("/servers/:id/nginx/openresty/install-logs", controllers.GetOpenRestyInstallLogs)
 				ops.POST("/servers/:id/websites", controllers.ApplyWebsiteConfig)
+				ops.POST("/servers/:id/websites/template", controllers.ApplyWebsiteTemplate)
 				ops.POST("/servers/:id/websites/ssl", controllers.IssueWebsiteCertificate)
 				ops.POST("/servers/:id/nginx/declarative/apply", controllers.ApplyWebsiteConfig)
 				ops.POST("/servers/:id/nginx/declarative/ssl", controllers.IssueWebsiteCertificate)
@@ -207,6 +326,7 @@ func SetupRoutes(r *gin.Engine) {
 				ops.GET("/servers/:id/certificates/:cert_id/content", controllers.GetCertificateContent)
 				ops.POST("/servers/:id/certificates/:cert_id/renew", controllers.RenewCertificate)
 				ops.DELETE("/servers/:id/certificates/:cert_id", controllers.DeleteManagedCertificate)
+				ops.POST("/servers/:id/host-certificates/renew", controllers.RenewHostCertificates)
 			}
 
 			// 需要管理员权限的路由
@@ -219,13 +339,34 @@ func SetupRoutes(r *gin.Engine) {
 				// 系统设置管理
 				admin.GET("/settings", controllers.GetSystemSettings)
 				admin.PUT("/settings", controllers.UpdateSystemSettings)
+				admin.POST("/settings/probe-targets/check", controllers.CheckProbeTarget)
 
 				// 数据库统计信息
 				admin.GET("/database/stats", controllers.GetDatabaseStats)
 
+				// 后端运行指标（连接数、数据库耗时等）
+				admin.GET("/diagnostics/metrics", controllers.GetBackendMetrics)
+
+				// 镜像仓库凭据
+				admin.GET("/registry-credentials", controllers.ListRegistryCredentials)
+				admin.POST("/registry-credentials", controllers.CreateRegistryCredential)
+				admin.PUT("/registry-credentials/:id", controllers.UpdateRegistryCredential)
+				admin.DELETE("/registry-credentials/:id", controllers.DeleteRegistryCredential)
+
 				// 其他管理员功能
 			}
 
+			// 可用性检查（HTTP/TCP/ICMP），检查由面板后端执行，新建和修改需要管理员权限
+			uptime := auth.Group("/uptime")
+			{
+				uptime.GET("/checks", controllers.ListUptimeChecks)
+				uptime.POST("/checks", middleware.AdminAuthMiddleware(), controllers.CreateUptimeCheck)
+				uptime.PUT("/checks/:id", middleware.AdminAuthMiddleware(), controllers.UpdateUptimeCheck)
+				uptime.DELETE("/checks/:id", middleware.AdminAuthMiddleware(), controllers.DeleteUptimeCheck)
+				uptime.GET("/checks/:id/results", controllers.GetUptimeCheckResults)
+				uptime.POST("/checks/:id/run", controllers.RunUptimeCheck)
+			}
+
 			// 预警通知相关API
 			alerts := auth.Group("/alerts")
 			{
@@ -241,10 +382,44 @@ func SetupRoutes(r *gin.Engine) {
 				alerts.PUT("/channels/:id", controllers.UpdateNotificationChannel)
 				alerts.DELETE("/channels/:id", controllers.DeleteNotificationChannel)
 				alerts.POST("/channels/:id/test", controllers.TestNotificationChannel)
+				alerts.POST("/simulate", middleware.AdminAuthMiddleware(), controllers.SimulateAlert)
 
 				// 预警记录
 				alerts.GET("/records", controllers.GetAlertRecords)
 				alerts.PUT("/records/:id/resolve", controllers.ResolveAlertRecord)
+				alerts.PUT("/records/:id/ack", controllers.AcknowledgeAlertRecord)
+
+				// 升级策略
+				alerts.GET("/escalations", controllers.GetEscalationPolicies)
+				alerts.POST("/escalations", controllers.CreateEscalationPolicy)
+				alerts.PUT("/escalations/:id", controllers.UpdateEscalationPolicy)
+				alerts.DELETE("/escalations/:id", controllers.DeleteEscalationPolicy)
+
+				// 表达式预警规则
+				alerts.GET("/rules", controllers.GetAlertRules)
+				alerts.POST("/rules", controllers.CreateAlertRule)
+				alerts.POST("/rules/preview", controllers.PreviewAlertRule)
+				alerts.PUT("/rules/:id", controllers.UpdateAlertRule)
+				alerts.DELETE("/rules/:id", controllers.DeleteAlertRule)
+			}
+
+			// 事件：未解决的预警打开事件，支持确认、备注和解决
+			incidents := auth.Group("/incidents")
+			{
+				incidents.GET("", controllers.GetIncidents)
+				incidents.GET("/:id", controllers.GetIncident)
+				incidents.PUT("/:id/ack", controllers.AcknowledgeIncident)
+				incidents.POST("/:id/comments", controllers.AddIncidentComment)
+				incidents.PUT("/:id/resolve", controllers.ResolveIncident)
+			}
+
+			// 维护窗口：窗口内不发送预警通知，公开页面不显示离线
+			maintenance := auth.Group("/maintenance")
+			{
+				maintenance.GET("", controllers.GetMaintenanceWindows)
+				maintenance.POST("", controllers.CreateMaintenanceWindow)
+				maintenance.PUT("/:id", controllers.UpdateMaintenanceWindow)
+				maintenance.DELETE("/:id", controllers.DeleteMaintenanceWindow)
 			}
 		}
 	}
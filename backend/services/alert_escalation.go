@@ -0,0 +1,137 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/user/server-ops-backend/models"
+)
+
+// escalationPolicyFor 返回与预警匹配的启用的升级策略，没有匹配或策略没有步骤时返回 nil
+func (s *AlertService) escalationPolicyFor(record models.AlertRecord) (*models.AlertEscalationPolicy, []models.AlertEscalationStep) {
+	policies, err := models.GetAllEscalationPolicies()
+	if err != nil {
+		log.Printf("获取升级策略失败: %v", err)
+		return nil, nil
+	}
+	policy := models.MatchEscalationPolicy(policies, record.AlertType, record.Category)
+	if policy == nil {
+		return nil, nil
+	}
+	steps, err := policy.GetSteps()
+	if err != nil || len(steps) == 0 {
+		log.Printf("升级策略 %s 的步骤无效，按分类路由通知: %v", policy.Name, err)
+		return nil, nil
+	}
+	return policy, steps
+}
+
+// startEscalation 预警匹配升级策略时，记录策略并通知延迟为 0 的步骤，返回成功通知的渠道ID；
+// 未匹配时返回 false，调用方按分类路由通知
+func (s *AlertService) startEscalation(record *models.AlertRecord, send func(models.NotificationChannel) bool) ([]string, bool) {
+	policy, steps := s.escalationPolicyFor(*record)
+	if policy == nil {
+		return nil, false
+	}
+	record.EscalationPolicyID = policy.ID
+	log.Printf("预警 %s(服务器 %s) 匹配升级策略 %s", record.AlertType, record.ServerName, policy.Name)
+
+	var channelIDs []string
+	for record.EscalationStep < len(steps) && steps[record.EscalationStep].DelayMinutes <= 0 {
+		if channel, ok := escalationChannel(steps[record.EscalationStep]); ok && send(channel) {
+			channelIDs = append(channelIDs, strconv.FormatUint(uint64(channel.ID), 10))
+		}
+		record.EscalationStep++
+	}
+	return channelIDs, true
+}
+
+// AdvanceEscalations 推进未确认、未解决预警的升级链：触发时间超过步骤延迟的步骤依次通知对应渠道。
+// 预警确认或解决后不再推进；策略被删除或停用时停止升级
+func (s *AlertService) AdvanceEscalations(now time.Time) {
+	records, err := models.GetPendingEscalations()
+	if err != nil {
+		log.Printf("获取升级中的预警失败: %v", err)
+		return
+	}
+
+	policies := make(map[uint]*models.AlertEscalationPolicy)
+	for i := range records {
+		record := &records[i]
+		policy, ok := policies[record.EscalationPolicyID]
+		if !ok {
+			var p models.AlertEscalationPolicy
+			if err := models.GetEscalationPolicyByID(record.EscalationPolicyID, &p); err == nil && p.Enabled {
+				policy = &p
+			}
+			policies[record.EscalationPolicyID] = policy
+		}
+		if policy == nil {
+			continue
+		}
+		// 维护期间暂停升级，窗口结束后补发到期的步骤
+		if models.InMaintenance(record.ServerID, now) {
+			continue
+		}
+		steps, err := policy.GetSteps()
+		if err != nil {
+			continue
+		}
+
+		elapsed := now.Sub(record.CreatedAt)
+		step := record.EscalationStep
+		channelIDs := record.ChannelIDs
+		for step < len(steps) && elapsed >= time.Duration(steps[step].DelayMinutes)*time.Minute {
+			if channel, ok := escalationChannel(steps[step]); ok && s.sendEscalation(channel, *record, policy.Name, elapsed) {
+				channelIDs = appendChannelID(channelIDs, channel.ID)
+			}
+			step++
+		}
+		if step == record.EscalationStep {
+			continue
+		}
+		if err := models.AdvanceAlertEscalation(record.ID, step, channelIDs); err != nil {
+			log.Printf("更新预警 %d 的升级进度失败: %v", record.ID, err)
+		}
+	}
+}
+
+// escalationChannel 获取升级步骤的通知渠道，渠道不存在或未启用时跳过该步骤
+func escalationChannel(step models.AlertEscalationStep) (models.NotificationChannel, bool) {
+	var channel models.NotificationChannel
+	if err := models.GetNotificationChannelByID(step.ChannelID, &channel); err != nil {
+		log.Printf("升级步骤的通知渠道 %d 不存在，跳过", step.ChannelID)
+		return channel, false
+	}
+	if !channel.Enabled {
+		log.Printf("升级步骤的通知渠道 %s 未启用，跳过", channel.Name)
+		return channel, false
+	}
+	return channel, true
+}
+
+// sendEscalation 发送升级通知，标题带【升级】前缀并说明预警已持续未确认的时间
+func (s *AlertService) sendEscalation(channel models.NotificationChannel, alert models.AlertRecord, policyName string, elapsed time.Duration) bool {
+	title, content := alertMessage(alert)
+	title = "【升级】" + title
+	content += fmt.Sprintf("\n\n该预警已持续 %d 分钟未确认，按升级策略「%s」通知。确认预警后停止升级。",
+		int(elapsed.Minutes()), policyName)
+	return s.notify(channel, title, content)
+}
+
+// appendChannelID 将渠道ID加入逗号分隔的列表，已存在时不重复添加
+func appendChannelID(list string, id uint) string {
+	idStr := strconv.FormatUint(uint64(id), 10)
+	if list == "" {
+		return idStr
+	}
+	for _, item := range strings.Split(list, ",") {
+		if item == idStr {
+			return list
+		}
+	}
+	return list + "," + idStr
+}
@@ -0,0 +1,793 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/user/server-ops-backend/models"
+)
+
+// 表达式最大长度，防止过深的嵌套
+const maxAlertExprLen = 1000
+
+// AlertExprVariable 表达式中可用的变量
+type AlertExprVariable struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	PerMount    bool   `json:"per_mount"` // 可在 on any mount / on all mounts 中按挂载点取值
+}
+
+// AlertExprVariables 表达式中可用的变量，custom.<名称> 读取自定义插件指标，
+// script.<脚本名>.exit_code 和 script.<脚本名>.<指标> 读取检查脚本的退出码和指标
+var AlertExprVariables = []AlertExprVariable{
+	{Name: "cpu", Description: "CPU 使用率(%)"},
+	{Name: "memory", Description: "内存使用率(%)"},
+	{Name: "memory_used", Description: "已用内存(bytes)"},
+	{Name: "memory_total", Description: "内存总量(bytes)"},
+	{Name: "swap", Description: "Swap 使用率(%)"},
+	{Name: "disk_usage", Description: "磁盘使用率(%)，默认为系统盘", PerMount: true},
+	{Name: "disk_used", Description: "磁盘已用空间(bytes)", PerMount: true},
+	{Name: "disk_free", Description: "磁盘可用空间(bytes)", PerMount: true},
+	{Name: "disk_total", Description: "磁盘总空间(bytes)", PerMount: true},
+	{Name: "inode_usage", Description: "inode 使用率(%)，默认为根目录 /", PerMount: true},
+	{Name: "inodes_used", Description: "已用 inode 数", PerMount: true},
+	{Name: "inodes_free", Description: "可用 inode 数", PerMount: true},
+	{Name: "inodes_total", Description: "inode 总数", PerMount: true},
+	{Name: "load_avg_1", Description: "1 分钟平均负载"},
+	{Name: "load_avg_5", Description: "5 分钟平均负载"},
+	{Name: "load_avg_15", Description: "15 分钟平均负载"},
+	{Name: "cores", Description: "CPU 核心数"},
+	{Name: "net_in", Description: "入站速率(bytes/s)"},
+	{Name: "net_out", Description: "出站速率(bytes/s)"},
+	{Name: "latency", Description: "到面板的延迟(ms)"},
+	{Name: "packet_loss", Description: "丢包率(%)"},
+	{Name: "processes", Description: "进程数"},
+	{Name: "tcp", Description: "TCP 连接数"},
+	{Name: "udp", Description: "UDP 连接数"},
+	{Name: "zombies", Description: "僵尸进程数"},
+	{Name: "agent_errors", Description: "Agent 最近 5 分钟的错误数"},
+	{Name: "temperature", Description: "硬件传感器最高温度(°C)"},
+	{Name: "nginx_up", Description: "Nginx stub_status 可访问时为 1，否则为 0"},
+	{Name: "nginx_active", Description: "Nginx 活动连接数"},
+	{Name: "nginx_waiting", Description: "Nginx 空闲 keep-alive 连接数"},
+	{Name: "nginx_requests", Description: "Nginx 每秒请求数"},
+	{Name: "nginx_dropped", Description: "Nginx 上报周期内未处理的连接数"},
+	{Name: "nginx_upstreams_down", Description: "不可用的 Nginx 上游数"},
+	{Name: "db_down", Description: "无法连接的数据库数"},
+	{Name: "db_connections_pct", Description: "数据库连接数占上限的最高百分比"},
+	{Name: "db_replication_lag", Description: "数据库从库落后主库的最大秒数"},
+	{Name: "db_replication_errors", Description: "复制中断的数据库从库数"},
+	{Name: "db_slow_queries", Description: "数据库上报周期内新增的慢查询数"},
+	{Name: "db_memory_pct", Description: "数据库内存用量占上限的最高百分比"},
+	{Name: "scripts_failed", Description: "最近一次退出码非 0 的检查脚本数"},
+}
+
+// 数值后可跟的单位，容量按 1024 进制换算为 bytes，% 只是标注
+var alertExprUnits = map[string]float64{
+	"%": 1, "b": 1,
+	"k": 1 << 10, "kb": 1 << 10, "m": 1 << 20, "mb": 1 << 20,
+	"g": 1 << 30, "gb": 1 << 30, "t": 1 << 40, "tb": 1 << 40,
+}
+
+// for 子句的时间单位，省略时为秒
+var alertExprDurationUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hour": time.Hour, "hours": time.Hour,
+}
+
+// AlertExpression 编译后的预警表达式
+type AlertExpression struct {
+	root *exprNode
+	// For 条件需要持续满足的时长
+	For time.Duration
+	// Variables 表达式引用的变量，通知中列出这些变量的当前值
+	Variables []string
+	// Mounts 通过 on mount "<路径>" 指定的挂载点，通知中列出这些挂载点的磁盘变量
+	Mounts []string
+}
+
+// alertExprEnv 表达式求值时的一台服务器的数据
+type alertExprEnv struct {
+	vars   map[string]float64
+	mounts []models.DiskMountStat
+}
+
+type exprNode struct {
+	op          string // num, var, neg, not, and, or, any, all, mount, + - * /, > >= < <= == !=
+	value       float64
+	name        string
+	left, right *exprNode
+	boolean     bool
+}
+
+// newAlertExprEnv 从监控记录构造求值环境
+func newAlertExprEnv(server models.Server, sample models.ServerMonitor) *alertExprEnv {
+	percent := func(used, total uint64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(used) / float64(total) * 100
+	}
+	vars := map[string]float64{
+		"cpu":          sample.CPUUsage,
+		"memory":       percent(sample.MemoryUsed, sample.MemoryTotal),
+		"memory_used":  float64(sample.MemoryUsed),
+		"memory_total": float64(sample.MemoryTotal),
+		"swap":         percent(sample.SwapUsed, sample.SwapTotal),
+		"disk_usage":   percent(sample.DiskUsed, sample.DiskTotal),
+		"disk_used":    float64(sample.DiskUsed),
+		"disk_free":    float64(sample.DiskTotal - min(sample.DiskUsed, sample.DiskTotal)),
+		"disk_total":   float64(sample.DiskTotal),
+		"load_avg_1":   sample.LoadAvg1,
+		"load_avg_5":   sample.LoadAvg5,
+		"load_avg_15":  sample.LoadAvg15,
+		"cores":        float64(server.CPUCores),
+		"net_in":       sample.NetworkIn,
+		"net_out":      sample.NetworkOut,
+		"latency":      sample.Latency,
+		"packet_loss":  sample.PacketLoss,
+		"processes":    float64(sample.Processes),
+		"tcp":          float64(sample.TCPConnections),
+		"udp":          float64(sample.UDPConnections),
+		"zombies":      float64(sample.Zombies),
+		"agent_errors": float64(sample.AgentErrors),
+		"temperature":  sample.MaxTemperature,
+	}
+	if sample.CustomMetrics != "" {
+		var custom []struct {
+			Name  string  `json:"name"`
+			Value float64 `json:"value"`
+		}
+		if err := json.Unmarshal([]byte(sample.CustomMetrics), &custom); err == nil {
+			for _, metric := range custom {
+				vars["custom."+strings.ToLower(metric.Name)] = metric.Value
+			}
+		}
+	}
+	// Nginx 变量只在 Agent 上报了 Nginx 指标时存在
+	if nginx := sample.NginxStats(); nginx != nil {
+		if nginx.StubConfigured() {
+			vars["nginx_up"] = 0
+		}
+		if nginx.Up {
+			vars["nginx_up"] = 1
+			vars["nginx_active"] = float64(nginx.Active)
+			vars["nginx_waiting"] = float64(nginx.Waiting)
+			vars["nginx_requests"] = nginx.RequestRate
+			vars["nginx_dropped"] = float64(nginx.Dropped)
+		}
+		if len(nginx.Upstreams) > 0 {
+			vars["nginx_upstreams_down"] = float64(nginx.UpstreamsDown())
+		}
+	}
+	// 数据库变量只在 Agent 上报了数据库指标时存在，未连接上的数据库只计入 db_down
+	if databases := sample.DatabaseStats(); len(databases) > 0 {
+		var down, replicationErrors int
+		var connectionsPct, lag, memoryPct float64
+		var slow uint64
+		for _, db := range databases {
+			if !db.Up {
+				down++
+				continue
+			}
+			if db.MaxConnections > 0 {
+				connectionsPct = max(connectionsPct, float64(db.Connections)/float64(db.MaxConnections)*100)
+			}
+			if db.MemoryLimit > 0 {
+				memoryPct = max(memoryPct, percent(db.MemoryUsed, db.MemoryLimit))
+			}
+			lag = max(lag, db.ReplicationLag)
+			if db.ReplicationError != "" {
+				replicationErrors++
+			}
+			slow += db.SlowQueries
+		}
+		vars["db_down"] = float64(down)
+		vars["db_connections_pct"] = connectionsPct
+		vars["db_replication_lag"] = lag
+		vars["db_replication_errors"] = float64(replicationErrors)
+		vars["db_slow_queries"] = float64(slow)
+		vars["db_memory_pct"] = memoryPct
+	}
+	// 检查脚本变量只在 Agent 上报了脚本结果时存在
+	if scripts := sample.CheckScriptSamples(); len(scripts) > 0 {
+		failed := 0
+		for _, script := range scripts {
+			if script.ExitCode != 0 {
+				failed++
+			}
+			vars["script."+script.Name+".exit_code"] = float64(script.ExitCode)
+			for key, value := range script.Metrics {
+				vars["script."+script.Name+"."+strings.ToLower(key)] = value
+			}
+		}
+		vars["scripts_failed"] = float64(failed)
+	}
+	env := &alertExprEnv{vars: vars, mounts: sample.MountStats()}
+	// inode 变量默认取根目录，旧版 Agent 没有上报时不存在
+	if root := env.mount("/"); root != nil {
+		for _, name := range []string{"inode_usage", "inodes_used", "inodes_free", "inodes_total"} {
+			vars[name] = env.lookup(name, root)
+		}
+	}
+	return env
+}
+
+// mount 按路径查找挂载点，不存在时返回 nil
+func (env *alertExprEnv) mount(path string) *models.DiskMountStat {
+	for i := range env.mounts {
+		if env.mounts[i].Mount == path {
+			return &env.mounts[i]
+		}
+	}
+	return nil
+}
+
+// lookup 读取变量，mount 不为空时磁盘变量取该挂载点的值；不存在的自定义指标返回 NaN，比较结果和真假判断均为 false
+func (env *alertExprEnv) lookup(name string, mount *models.DiskMountStat) float64 {
+	if mount != nil {
+		switch name {
+		case "disk_usage":
+			return mount.UsagePercent()
+		case "disk_used":
+			return float64(mount.Used)
+		case "disk_free":
+			return float64(mount.Free)
+		case "disk_total":
+			return float64(mount.Total)
+		case "inode_usage":
+			return mount.InodeUsagePercent()
+		case "inodes_used":
+			return float64(mount.InodesUsed)
+		case "inodes_free":
+			return float64(mount.InodesFree)
+		case "inodes_total":
+			return float64(mount.InodesTotal)
+		}
+	}
+	if v, ok := env.vars[name]; ok {
+		return v
+	}
+	return math.NaN()
+}
+
+// Eval 对一台服务器的数据求值
+func (e *AlertExpression) Eval(env *alertExprEnv) bool {
+	return exprTruthy(e.root.eval(env, nil))
+}
+
+// exprTruthy 判断求值结果的真假：非 0 为真，NaN（指标缺失）为假
+func exprTruthy(v float64) bool {
+	return v == v && v != 0
+}
+
+func (n *exprNode) eval(env *alertExprEnv, mount *models.DiskMountStat) float64 {
+	truth := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	switch n.op {
+	case "num":
+		return n.value
+	case "var":
+		return env.lookup(n.name, mount)
+	case "neg":
+		return -n.left.eval(env, mount)
+	case "not":
+		return truth(!exprTruthy(n.left.eval(env, mount)))
+	case "and":
+		return truth(exprTruthy(n.left.eval(env, mount)) && exprTruthy(n.right.eval(env, mount)))
+	case "or":
+		return truth(exprTruthy(n.left.eval(env, mount)) || exprTruthy(n.right.eval(env, mount)))
+	case "any", "all":
+		// 旧版 Agent 没有上报挂载点时按系统盘判断
+		if len(env.mounts) == 0 {
+			return n.left.eval(env, nil)
+		}
+		for i := range env.mounts {
+			matched := exprTruthy(n.left.eval(env, &env.mounts[i]))
+			if n.op == "any" && matched {
+				return 1
+			}
+			if n.op == "all" && !matched {
+				return 0
+			}
+		}
+		return truth(n.op == "all")
+	case "mount":
+		// 指定的挂载点不存在（未挂载或旧版 Agent）时条件不满足
+		target := env.mount(n.name)
+		if target == nil {
+			return 0
+		}
+		return n.left.eval(env, target)
+	}
+
+	l, r := n.left.eval(env, mount), n.right.eval(env, mount)
+	switch n.op {
+	case "+":
+		return l + r
+	case "-":
+		return l - r
+	case "*":
+		return l * r
+	case "/":
+		if r == 0 {
+			return math.NaN()
+		}
+		return l / r
+	case ">":
+		return truth(l > r)
+	case ">=":
+		return truth(l >= r)
+	case "<":
+		return truth(l < r)
+	case "<=":
+		return truth(l <= r)
+	case "==":
+		return truth(l == r)
+	case "!=":
+		return truth(l != r)
+	}
+	return math.NaN()
+}
+
+// values 返回表达式引用的变量的当前值，用于通知内容，例如 "cpu=93.20, cores=4"
+func (e *AlertExpression) values(env *alertExprEnv) string {
+	parts := make([]string, 0, len(e.Variables))
+	for _, name := range e.Variables {
+		parts = append(parts, fmt.Sprintf("%s=%s", name, strconv.FormatFloat(env.lookup(name, nil), 'f', -1, 64)))
+	}
+	for _, path := range e.Mounts {
+		mount := env.mount(path)
+		if mount == nil {
+			continue
+		}
+		for _, v := range AlertExprVariables {
+			if v.PerMount && slices.Contains(e.Variables, v.Name) {
+				parts = append(parts, fmt.Sprintf("%s(%s)=%s", v.Name, path, strconv.FormatFloat(env.lookup(v.Name, mount), 'f', 2, 64)))
+			}
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+type exprToken struct {
+	kind string // num, ident, str, op, end
+	text string
+	pos  int
+}
+
+// tokenizeAlertExpr 将表达式拆分为数字、标识符、带引号的字符串和运算符
+func tokenizeAlertExpr(src string) ([]exprToken, error) {
+	var tokens []exprToken
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: "num", text: string(runes[start:i]), pos: start})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.' ||
+				isCustomMetricHyphen(runes, start, i)) {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: "ident", text: string(runes[start:i]), pos: start})
+		case c == '"' || c == '\'':
+			start := i
+			i++
+			for i < len(runes) && runes[i] != c {
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("第 %d 个字符处的引号没有结束", start+1)
+			}
+			tokens = append(tokens, exprToken{kind: "str", text: string(runes[start+1 : i]), pos: start})
+			i++
+		default:
+			two := ""
+			if i+1 < len(runes) {
+				two = string(runes[i : i+2])
+			}
+			switch two {
+			case ">=", "<=", "==", "!=", "&&", "||":
+				tokens = append(tokens, exprToken{kind: "op", text: two, pos: i})
+				i += 2
+				continue
+			}
+			if !strings.ContainsRune("<>+-*/()!%", c) {
+				return nil, fmt.Errorf("第 %d 个字符处有无法识别的符号 %q", i+1, c)
+			}
+			tokens = append(tokens, exprToken{kind: "op", text: string(c), pos: i})
+			i++
+		}
+	}
+	return append(tokens, exprToken{kind: "end", pos: len(runes)}), nil
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+	vars   map[string]bool
+	mounts map[string]bool
+}
+
+func (p *exprParser) peek() exprToken { return p.tokens[p.pos] }
+
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.pos]
+	if t.kind != "end" {
+		p.pos++
+	}
+	return t
+}
+
+// keyword 判断当前标识符是否为关键字（不区分大小写）
+func (p *exprParser) keyword(words ...string) bool {
+	t := p.peek()
+	if t.kind != "ident" {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *exprParser) isOp(ops ...string) bool {
+	t := p.peek()
+	if t.kind != "op" {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	t := p.peek()
+	where := "表达式末尾"
+	if t.kind != "end" {
+		where = fmt.Sprintf("第 %d 个字符 %q 处", t.pos+1, t.text)
+	}
+	return fmt.Errorf("%s: %s", where, fmt.Sprintf(format, args...))
+}
+
+// CompileAlertExpression 解析预警表达式，语法：
+//
+//	cpu > 90 AND load_avg_5 > cores * 2 for 5m
+//	disk_free < 5GB on any mount
+//	disk_usage > 90% OR inode_usage > 90% on mount "/var/lib/docker"
+//
+// 支持 AND/OR/NOT（或 && || !）、比较运算、四则运算和括号；数值可带 % 或 KB/MB/GB/TB 单位；
+// "on any mount"/"on all mounts" 对每个挂载点分别判断其前面的条件，"on mount <路径>" 只判断指定的挂载点；
+// 末尾的 "for <时长>" 指定条件需要持续的时间
+func CompileAlertExpression(src string) (*AlertExpression, error) {
+	src = strings.TrimSpace(src)
+	if src == "" {
+		return nil, fmt.Errorf("表达式不能为空")
+	}
+	if len(src) > maxAlertExprLen {
+		return nil, fmt.Errorf("表达式不能超过 %d 个字符", maxAlertExprLen)
+	}
+	tokens, err := tokenizeAlertExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens, vars: make(map[string]bool), mounts: make(map[string]bool)}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !root.boolean {
+		return nil, fmt.Errorf("表达式的结果必须是条件，例如 cpu > 90")
+	}
+
+	expr := &AlertExpression{root: root}
+	if p.keyword("for") {
+		p.next()
+		if expr.For, err = p.parseDuration(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek().kind != "end" {
+		return nil, p.errorf("多余的内容")
+	}
+	for name := range p.vars {
+		expr.Variables = append(expr.Variables, name)
+	}
+	sort.Strings(expr.Variables)
+	for path := range p.mounts {
+		expr.Mounts = append(expr.Mounts, path)
+	}
+	sort.Strings(expr.Mounts)
+	return expr, nil
+}
+
+func (p *exprParser) parseDuration() (time.Duration, error) {
+	t := p.next()
+	if t.kind != "num" {
+		return 0, p.errorf("for 后应为时长，例如 for 5m")
+	}
+	n, err := strconv.ParseFloat(t.text, 64)
+	if err != nil {
+		return 0, p.errorf("无效的时长 %s", t.text)
+	}
+	unit := time.Second
+	if p.peek().kind == "ident" {
+		u, ok := alertExprDurationUnits[strings.ToLower(p.peek().text)]
+		if !ok {
+			return 0, p.errorf("无效的时间单位，可用 s、m、h")
+		}
+		unit = u
+		p.next()
+	}
+	return time.Duration(n * float64(unit)), nil
+}
+
+func (p *exprParser) parseOr() (*exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") || p.isOp("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = p.logical("or", left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (*exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") || p.isOp("&&") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = p.logical("and", left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) logical(op string, left, right *exprNode) (*exprNode, error) {
+	if !left.boolean || !right.boolean {
+		return nil, fmt.Errorf("%s 两侧必须是条件", strings.ToUpper(op))
+	}
+	return &exprNode{op: op, left: left, right: right, boolean: true}, nil
+}
+
+// parseNot 解析取反和挂载点条件：NOT 条件、条件 on any mount、条件 on mount "/data"
+func (p *exprParser) parseNot() (*exprNode, error) {
+	if p.keyword("not") || p.isOp("!") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if !operand.boolean {
+			return nil, p.errorf("NOT 后必须是条件")
+		}
+		return &exprNode{op: "not", left: operand, boolean: true}, nil
+	}
+
+	node, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	if p.keyword("on") {
+		p.next()
+		if p.keyword("mount") {
+			p.next()
+			t := p.next()
+			if t.kind != "str" || !strings.HasPrefix(t.text, "/") {
+				return nil, p.errorf("on mount 后应为带引号的挂载路径，例如 on mount \"/data\"")
+			}
+			if !node.boolean {
+				return nil, p.errorf("on mount 前必须是条件")
+			}
+			p.mounts[t.text] = true
+			return &exprNode{op: "mount", name: t.text, left: node, boolean: true}, nil
+		}
+		var op string
+		switch {
+		case p.keyword("any"):
+			op = "any"
+		case p.keyword("all"):
+			op = "all"
+		default:
+			return nil, p.errorf("on 后应为 any mount、all mounts 或 mount \"<路径>\"")
+		}
+		p.next()
+		if !p.keyword("mount", "mounts") {
+			return nil, p.errorf("on %s 后应为 mount", op)
+		}
+		p.next()
+		if !node.boolean {
+			return nil, p.errorf("on %s mount 前必须是条件", op)
+		}
+		node = &exprNode{op: op, left: node, boolean: true}
+	}
+	return node, nil
+}
+
+func (p *exprParser) parseComparison() (*exprNode, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp(">", ">=", "<", "<=", "==", "!=") {
+		return left, nil
+	}
+	op := p.next().text
+	right, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if left.boolean || right.boolean {
+		return nil, fmt.Errorf("比较运算 %s 两侧必须是数值", op)
+	}
+	return &exprNode{op: op, left: left, right: right, boolean: true}, nil
+}
+
+func (p *exprParser) parseSum() (*exprNode, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+", "-") {
+		op := p.next().text
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = arithmetic(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseProduct() (*exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*", "/") {
+		op := p.next().text
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = arithmetic(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func arithmetic(op string, left, right *exprNode) (*exprNode, error) {
+	if left.boolean || right.boolean {
+		return nil, fmt.Errorf("运算 %s 两侧必须是数值", op)
+	}
+	return &exprNode{op: op, left: left, right: right}, nil
+}
+
+func (p *exprParser) parseUnary() (*exprNode, error) {
+	if p.isOp("-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if operand.boolean {
+			return nil, p.errorf("负号后必须是数值")
+		}
+		return &exprNode{op: "neg", left: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (*exprNode, error) {
+	t := p.peek()
+	switch {
+	case t.kind == "num":
+		p.next()
+		value, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的数值 %s", t.text)
+		}
+		// 紧跟的单位：90%、5GB、512 MB
+		if u := p.peek(); (u.kind == "ident" || (u.kind == "op" && u.text == "%")) && alertExprUnits[strings.ToLower(u.text)] > 0 {
+			value *= alertExprUnits[strings.ToLower(u.text)]
+			p.next()
+		}
+		return &exprNode{op: "num", value: value}, nil
+	case t.kind == "op" && t.text == "(":
+		p.next()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isOp(")") {
+			return nil, p.errorf("缺少右括号")
+		}
+		p.next()
+		return node, nil
+	case t.kind == "ident":
+		if p.keyword("and", "or", "not", "on", "for") {
+			return nil, p.errorf("缺少条件或数值")
+		}
+		name := strings.ToLower(t.text)
+		if !isAlertExprVariable(name) {
+			return nil, p.errorf("未知的变量 %s", t.text)
+		}
+		p.next()
+		p.vars[name] = true
+		return &exprNode{op: "var", name: name}, nil
+	}
+	return nil, p.errorf("缺少条件或数值")
+}
+
+// isCustomMetricHyphen 判断 runes[i] 是否为自定义指标名中的连字符：插件指标名允许 "-"，
+// 只有 custom. 变量中紧跟字母、数字或下划线的 "-" 属于变量名，其他位置仍是减号（减法两侧加空格即可区分）
+func isCustomMetricHyphen(runes []rune, start, i int) bool {
+	if runes[i] != '-' || i+1 >= len(runes) {
+		return false
+	}
+	if next := runes[i+1]; !unicode.IsLetter(next) && !unicode.IsDigit(next) && next != '_' {
+		return false
+	}
+	prefix := strings.ToLower(string(runes[start:i]))
+	return strings.HasPrefix(prefix, "custom.") && len(prefix) > len("custom.")
+}
+
+// isAlertExprVariable 判断是否为可用的变量，custom.<名称> 为自定义插件指标，script.<脚本名>.<指标> 为检查脚本的结果
+func isAlertExprVariable(name string) bool {
+	if strings.HasPrefix(name, "custom.") && len(name) > len("custom.") {
+		return true
+	}
+	if rest, ok := strings.CutPrefix(name, "script."); ok {
+		script, metric, ok := strings.Cut(rest, ".")
+		return ok && script != "" && metric != ""
+	}
+	for _, v := range AlertExprVariables {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/user/server-ops-backend/models"
+)
+
+// ruleState 一条表达式规则在一台服务器上的状态
+type ruleState struct {
+	pendingSince time.Time // 触发条件开始持续满足的时间，零值表示当前不满足
+	recoverSince time.Time // 告警后恢复条件开始持续满足的时间
+	alerted      bool
+	recordID     uint
+}
+
+// SyncRuleAlerts 用未解决的规则预警初始化状态，避免面板重启后重复告警；
+// 规则被删除、停用或不再适用于该服务器时直接解除预警
+func (s *AlertService) SyncRuleAlerts(rules []models.AlertRule) {
+	records, err := models.GetUnresolvedRuleAlerts()
+	if err != nil {
+		log.Printf("获取未解决的规则预警失败: %v", err)
+		return
+	}
+
+	active := make(map[uint]models.AlertRule, len(rules))
+	for _, rule := range rules {
+		active[rule.ID] = rule
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ruleID := range s.ruleStates {
+		if _, ok := active[ruleID]; !ok {
+			delete(s.ruleStates, ruleID)
+		}
+	}
+	for i := range records {
+		record := &records[i]
+		rule, ok := active[record.RuleID]
+		if !ok || !rule.AppliesTo(record.ServerID) {
+			record.Resolved = true
+			record.ResolvedAt = time.Now()
+			if err := models.UpdateAlertRecord(record); err != nil {
+				log.Printf("更新预警记录失败: %v", err)
+			}
+			if states, ok := s.ruleStates[record.RuleID]; ok {
+				delete(states, record.ServerID)
+			}
+			continue
+		}
+		states := s.ruleStatesFor(record.RuleID)
+		if _, ok := states[record.ServerID]; !ok {
+			states[record.ServerID] = &ruleState{alerted: true, recordID: record.ID}
+		}
+	}
+}
+
+func (s *AlertService) ruleStatesFor(ruleID uint) map[uint]*ruleState {
+	states, ok := s.ruleStates[ruleID]
+	if !ok {
+		states = make(map[uint]*ruleState)
+		s.ruleStates[ruleID] = states
+	}
+	return states
+}
+
+// EvaluateRules 用服务器最新的监控数据检查表达式预警规则：
+// 触发条件持续满足 for 时长后告警；告警后恢复条件（未设置时为触发条件不再满足）持续满足其 for 时长后解除
+func (s *AlertService) EvaluateRules(
+	server models.Server,
+	sample models.ServerMonitor,
+	rules []models.AlertRule,
+	channels []models.NotificationChannel,
+	now time.Time,
+) {
+	var env *alertExprEnv
+	for _, rule := range rules {
+		if !rule.Enabled || !rule.AppliesTo(server.ID) {
+			continue
+		}
+		trigger, err := CompileAlertExpression(rule.Expression)
+		if err != nil {
+			log.Printf("预警规则 %s(%d) 表达式无效: %v", rule.Name, rule.ID, err)
+			continue
+		}
+		var recovery *AlertExpression
+		if strings.TrimSpace(rule.RecoverExpression) != "" {
+			if recovery, err = CompileAlertExpression(rule.RecoverExpression); err != nil {
+				log.Printf("预警规则 %s(%d) 恢复条件无效: %v", rule.Name, rule.ID, err)
+				continue
+			}
+		}
+		if env == nil {
+			env = newAlertExprEnv(server, sample)
+		}
+		s.evaluateRule(rule, trigger, recovery, server, env, channels, now)
+	}
+}
+
+func (s *AlertService) evaluateRule(
+	rule models.AlertRule,
+	trigger, recovery *AlertExpression,
+	server models.Server,
+	env *alertExprEnv,
+	channels []models.NotificationChannel,
+	now time.Time,
+) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := s.ruleStatesFor(rule.ID)
+	state, ok := states[server.ID]
+	if !ok {
+		state = &ruleState{}
+		states[server.ID] = state
+	}
+
+	firing := trigger.Eval(env)
+	if !state.alerted {
+		if !firing {
+			state.pendingSince = time.Time{}
+			return
+		}
+		if state.pendingSince.IsZero() {
+			state.pendingSince = now
+		}
+		if now.Sub(state.pendingSince) < trigger.For {
+			return
+		}
+		state.alerted = true
+		state.recoverSince = time.Time{}
+		state.recordID = s.triggerRuleAlert(rule, trigger, server, env, channels)
+		return
+	}
+
+	recovered, hold := !firing, time.Duration(0)
+	if recovery != nil {
+		recovered, hold = recovery.Eval(env), recovery.For
+	}
+	if !recovered {
+		state.recoverSince = time.Time{}
+		return
+	}
+	if state.recoverSince.IsZero() {
+		state.recoverSince = now
+	}
+	if now.Sub(state.recoverSince) < hold {
+		return
+	}
+	delete(states, server.ID)
+	s.resolveRuleAlert(state.recordID, rule, server)
+}
+
+// triggerRuleAlert 创建规则预警记录并通知，返回预警记录ID
+func (s *AlertService) triggerRuleAlert(
+	rule models.AlertRule,
+	trigger *AlertExpression,
+	server models.Server,
+	env *alertExprEnv,
+	channels []models.NotificationChannel,
+) uint {
+	log.Printf("触发规则预警: 服务器 %s(%d), 规则 %s(%d)", server.Name, server.ID, rule.Name, rule.ID)
+
+	record := models.AlertRecord{
+		ServerID:   server.ID,
+		ServerName: server.Name,
+		AlertType:  "rule",
+		Category:   models.AlertCategoryOf("rule"),
+		RuleID:     rule.ID,
+		NotifiedAt: time.Now(),
+	}
+
+	title := fmt.Sprintf("【规则预警】%s - %s", rule.Name, server.Name)
+	content := fmt.Sprintf("服务器 %s (ID: %d) 满足预警规则 %s。\n条件: %s\n当前值: %s\n时间: %s",
+		server.Name, server.ID, rule.Name, rule.Expression, trigger.values(env), time.Now().Format("2006-01-02 15:04:05"))
+	send := func(channel models.NotificationChannel) bool {
+		return s.notify(channel, title, content)
+	}
+
+	channelIDs, escalated := s.startEscalation(&record, send)
+	if !escalated {
+		setting := models.AlertSetting{ChannelIDs: rule.ChannelIDs}
+		for _, channel := range channelsForSetting(channels, setting, record.Category) {
+			if send(channel) {
+				channelIDs = append(channelIDs, strconv.FormatUint(uint64(channel.ID), 10))
+			}
+		}
+	}
+	record.ChannelIDs = strings.Join(channelIDs, ",")
+	if err := models.CreateAlertRecord(&record); err != nil {
+		log.Printf("保存规则预警记录失败: %v", err)
+		return 0
+	}
+	return record.ID
+}
+
+// resolveRuleAlert 解除规则预警，并通知告警时通知过的渠道；已被手动解决的预警不再通知
+func (s *AlertService) resolveRuleAlert(recordID uint, rule models.AlertRule, server models.Server) {
+	if recordID == 0 {
+		return
+	}
+	var record models.AlertRecord
+	if err := models.GetAlertRecordByID(recordID, &record); err != nil {
+		log.Printf("查找规则预警记录 %d 失败: %v", recordID, err)
+		return
+	}
+	if record.Resolved {
+		return
+	}
+
+	log.Printf("规则预警解除: 服务器 %s(%d), 规则 %s(%d)", server.Name, server.ID, rule.Name, rule.ID)
+	record.Resolved = true
+	record.ResolvedAt = time.Now()
+	if err := models.UpdateAlertRecord(&record); err != nil {
+		log.Printf("更新预警记录失败: %v", err)
+	}
+
+	if record.ChannelIDs == "" {
+		return
+	}
+	for _, idStr := range strings.Split(record.ChannelIDs, ",") {
+		id, _ := strconv.ParseUint(idStr, 10, 64)
+		var channel models.NotificationChannel
+		if err := models.GetNotificationChannelByID(uint(id), &channel); err != nil {
+			continue
+		}
+		s.sendResolutionNotification(channel, record, 0)
+	}
+}
+
+// PreviewAlertRule 用服务器的监控数据试算表达式，返回是否满足条件和引用变量的当前值
+func PreviewAlertRule(expression string, server models.Server, sample models.ServerMonitor) (bool, string, error) {
+	expr, err := CompileAlertExpression(expression)
+	if err != nil {
+		return false, "", err
+	}
+	env := newAlertExprEnv(server, sample)
+	return expr.Eval(env), expr.values(env), nil
+}
@@ -1,7 +1,6 @@
 package services
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -21,9 +20,6 @@ var (
 	alertServiceOnce   sync.Once
 )
 
-// serverHeartbeatTimeout 用于估算“离线开始时间”，需要与 models.CheckServerStatus 的超时时间保持一致
-const serverHeartbeatTimeout = 15 * time.Second
-
 // MetricState 指标状态缓存结构
 type MetricState struct {
 	Value      float64
@@ -31,10 +27,21 @@ type MetricState struct {
 	Alerted    bool
 }
 
+// smoothedValue 指标的指数移动平均状态
+type smoothedValue struct {
+	value    float64
+	sampleAt time.Time // 最近计入的监控样本时间
+}
+
+// smoothingResetGap 相邻样本间隔超过该值（如 Agent 离线）时，平滑值从当前样本重新开始
+const smoothingResetGap = 5 * time.Minute
+
 // AlertService 预警服务
 type AlertService struct {
-	metricStates map[string]map[uint]MetricState // 格式: map[metricType]map[serverID]state
-	mu           sync.RWMutex                    // 用于保护metricStates的并发访问
+	metricStates map[string]map[uint]MetricState   // 格式: map[metricType]map[serverID]state
+	smoothed     map[string]map[uint]smoothedValue // 格式: map[metricType]map[serverID]平滑状态
+	ruleStates   map[uint]map[uint]*ruleState      // 格式: map[ruleID]map[serverID]表达式规则状态
+	mu           sync.RWMutex                      // 用于保护metricStates、smoothed和ruleStates的并发访问
 	stopChan     chan struct{}
 	testing      bool // 测试模式标志，用于单元测试
 }
@@ -43,6 +50,8 @@ type AlertService struct {
 func NewAlertService() *AlertService {
 	return &AlertService{
 		metricStates: make(map[string]map[uint]MetricState),
+		smoothed:     make(map[string]map[uint]smoothedValue),
+		ruleStates:   make(map[uint]map[uint]*ruleState),
 		stopChan:     make(chan struct{}),
 	}
 }
@@ -66,6 +75,7 @@ func (s *AlertService) Start() {
 		select {
 		case <-ticker.C:
 			s.checkAllServers()
+			s.AdvanceEscalations(time.Now())
 		case <-s.stopChan:
 			log.Println("预警服务已停止")
 			return
@@ -118,7 +128,20 @@ func (s *AlertService) checkAllServers() {
 		return
 	}
 
+	// 表达式预警规则，并同步重启前未解决的规则预警
+	rules, err := models.GetEnabledAlertRules()
+	if err != nil {
+		log.Printf("获取预警规则失败: %v", err)
+	} else {
+		s.SyncRuleAlerts(rules)
+	}
+
 	for _, server := range servers {
+		// 维护期间不检查，窗口结束后仍满足条件的预警照常触发
+		if models.InMaintenance(server.ID, time.Now()) {
+			continue
+		}
+
 		// 获取服务器特定的预警设置(如果有)
 		serverSettings, err := models.GetServerAlertSettings(server.ID)
 		if err != nil {
@@ -145,9 +168,12 @@ func (s *AlertService) checkAllServers() {
 			continue
 		}
 
+		sampleAt := latestData[0].Timestamp
+
 		// 检查CPU指标
 		if cpuSetting, ok := settings["cpu"]; ok {
-			s.checkMetric("cpu", server, latestData[0].CPUUsage, cpuSetting, channels)
+			cpuUsage := s.smoothMetric("cpu", server.ID, latestData[0].CPUUsage, cpuSetting.Smoothing, sampleAt)
+			s.checkMetric("cpu", server, cpuUsage, cpuSetting, channels)
 		}
 
 		// 检查内存指标
@@ -157,6 +183,7 @@ func (s *AlertService) checkAllServers() {
 			if latestData[0].MemoryTotal > 0 {
 				memoryUsage = float64(latestData[0].MemoryUsed) / float64(latestData[0].MemoryTotal) * 100
 			}
+			memoryUsage = s.smoothMetric("memory", server.ID, memoryUsage, memorySetting.Smoothing, sampleAt)
 			s.checkMetric("memory", server, memoryUsage, memorySetting, channels)
 		}
 
@@ -164,9 +191,63 @@ func (s *AlertService) checkAllServers() {
 		if networkSetting, ok := settings["network"]; ok {
 			// 计算网络流量 (MB/s)
 			networkTotal := (latestData[0].NetworkIn + latestData[0].NetworkOut) / 1024 / 1024
+			networkTotal = s.smoothMetric("network", server.ID, networkTotal, networkSetting.Smoothing, sampleAt)
 			s.checkMetric("network", server, networkTotal, networkSetting, channels)
 		}
+
+		// 检查僵尸进程数（阈值为进程个数），持续增长说明有父进程未回收子进程
+		if zombieSetting, ok := settings["zombie"]; ok {
+			zombies := s.smoothMetric("zombie", server.ID, float64(latestData[0].Zombies), zombieSetting.Smoothing, sampleAt)
+			s.checkMetric("zombie", server, zombies, zombieSetting, channels)
+		}
+
+		// 检查 Agent 自身最近 5 分钟的错误数，发现错误频繁但仍在线的 Agent
+		if agentErrorSetting, ok := settings["agent_error"]; ok {
+			agentErrors := s.smoothMetric("agent_error", server.ID, float64(latestData[0].AgentErrors), agentErrorSetting.Smoothing, sampleAt)
+			s.checkMetric("agent_error", server, agentErrors, agentErrorSetting, channels)
+		}
+
+		// 检查硬件传感器中的最高温度(°C)，没有传感器的服务器不检查
+		if temperatureSetting, ok := settings["temperature"]; ok && latestData[0].MaxTemperature > 0 {
+			temperature := s.smoothMetric("temperature", server.ID, latestData[0].MaxTemperature, temperatureSetting.Smoothing, sampleAt)
+			s.checkMetric("temperature", server, temperature, temperatureSetting, channels)
+		}
+
+		// 检查表达式预警规则，规则中的 for 子句自行处理持续时间，不做平滑
+		s.EvaluateRules(server, latestData[0], rules, channels, time.Now())
+	}
+}
+
+// smoothMetric 对指标做指数移动平均：平滑值 = alpha*原始值 + (1-alpha)*上次平滑值。
+// 短暂的尖峰只会小幅抬高平滑值，避免瞬时波动触发预警；数据库中保存的仍是原始值。
+//
+// 平滑按样本而不是按检查次数进行：预警每10秒检查一次，而 Agent 每个监控间隔才上报一个样本，
+// sampleAt 不晚于上次计入的样本时返回上次的平滑值，同一样本只计入一次，
+// 因此 alpha 的效果只取决于样本数（约为最近 1/alpha 个样本的平均），与检查频率无关。
+// 相邻样本间隔超过 smoothingResetGap 时从当前样本重新开始；
+// alpha 不在 (0,1) 内（0 为未启用）时清除平滑状态并返回原始值
+func (s *AlertService) smoothMetric(metricType string, serverID uint, raw, alpha float64, sampleAt time.Time) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.smoothed[metricType]; !ok {
+		s.smoothed[metricType] = make(map[uint]smoothedValue)
 	}
+	if alpha <= 0 || alpha >= 1 {
+		delete(s.smoothed[metricType], serverID)
+		return raw
+	}
+
+	prev, exists := s.smoothed[metricType][serverID]
+	if exists && !sampleAt.After(prev.sampleAt) {
+		return prev.value
+	}
+	value := raw
+	if exists && sampleAt.Sub(prev.sampleAt) <= smoothingResetGap {
+		value = alpha*raw + (1-alpha)*prev.value
+	}
+	s.smoothed[metricType][serverID] = smoothedValue{value: value, sampleAt: sampleAt}
+	return value
 }
 
 // checkMetric 检查单个指标并触发预警
@@ -240,18 +321,23 @@ func (s *AlertService) triggerAlert(
 		ServerID:   server.ID,
 		ServerName: server.Name,
 		AlertType:  metricType,
+		Category:   models.AlertCategoryOf(metricType),
 		Value:      value,
 		Threshold:  setting.Threshold,
 		Resolved:   false,
 		NotifiedAt: time.Now(),
 	}
 
-	// 收集成功通知的渠道ID
-	var channelIDs []string
-	for _, channel := range channels {
-		// 发送通知
-		if s.sendNotification(channel, record) {
-			channelIDs = append(channelIDs, strconv.FormatUint(uint64(channel.ID), 10))
+	// 匹配升级策略时按策略通知，否则按分类路由到所有接收该分类的渠道
+	channelIDs, escalated := s.startEscalation(&record, func(channel models.NotificationChannel) bool {
+		return s.sendNotification(channel, record)
+	})
+	if !escalated {
+		for _, channel := range channelsForSetting(channels, setting, record.Category) {
+			// 发送通知
+			if s.sendNotification(channel, record) {
+				channelIDs = append(channelIDs, strconv.FormatUint(uint64(channel.ID), 10))
+			}
 		}
 	}
 
@@ -302,13 +388,21 @@ func (s *AlertService) resolveAlert(metricType string, server models.Server, val
 
 // sendNotification 发送通知
 func (s *AlertService) sendNotification(channel models.NotificationChannel, alert models.AlertRecord) bool {
-	var config map[string]string
-	if err := json.Unmarshal([]byte(channel.Config), &config); err != nil {
-		log.Printf("解析通知配置失败: %v", err)
+	title, content := alertMessage(alert)
+	return s.notify(channel, title, content)
+}
+
+// notify 将通知放入后台发送队列，不等待发送结果；配置错误、超过发送上限或队列已满时记录日志并返回 false
+func (s *AlertService) notify(channel models.NotificationChannel, title, content string) bool {
+	if err := enqueueNotify(channel, title, content); err != nil {
+		log.Printf("发送%s通知失败(渠道=%s): %v", channel.Type, channel.Name, err)
 		return false
 	}
+	return true
+}
 
-	var title, content string
+// alertMessage 生成预警通知的标题和内容
+func alertMessage(alert models.AlertRecord) (title, content string) {
 	switch alert.AlertType {
 	case "cpu":
 		title = fmt.Sprintf("服务器 %s CPU使用率预警", alert.ServerName)
@@ -322,30 +416,70 @@ func (s *AlertService) sendNotification(channel models.NotificationChannel, aler
 		title = fmt.Sprintf("服务器 %s 网络流量预警", alert.ServerName)
 		content = fmt.Sprintf("服务器 %s 的网络流量达到 %.2f MB/s, 超过预设阈值 %.2f MB/s",
 			alert.ServerName, alert.Value, alert.Threshold)
+	case "zombie":
+		title = fmt.Sprintf("服务器 %s 僵尸进程预警", alert.ServerName)
+		content = fmt.Sprintf("服务器 %s 的僵尸进程数达到 %.0f 个, 超过预设阈值 %.0f 个，可能有父进程未回收子进程",
+			alert.ServerName, alert.Value, alert.Threshold)
+	case "agent_error":
+		title = fmt.Sprintf("服务器 %s 的 Agent 错误频繁", alert.ServerName)
+		content = fmt.Sprintf("服务器 %s 的 Agent 最近 5 分钟内部错误 %.0f 次, 超过预设阈值 %.0f 次，请检查 Agent 日志或错误计数",
+			alert.ServerName, alert.Value, alert.Threshold)
+	case "temperature":
+		title = fmt.Sprintf("服务器 %s 温度过高", alert.ServerName)
+		content = fmt.Sprintf("服务器 %s 的硬件温度达到 %.1f°C, 超过预设阈值 %.1f°C，请检查散热和风扇",
+			alert.ServerName, alert.Value, alert.Threshold)
 	case "test":
 		title = fmt.Sprintf("服务器监控系统测试通知")
 		content = fmt.Sprintf("这是一条测试通知，请忽略。测试值: %.2f, 测试阈值: %.2f",
 			alert.Value, alert.Threshold)
+	case "status":
+		title = fmt.Sprintf("【服务器离线】%s", alert.ServerName)
+		content = fmt.Sprintf("服务器 %s (ID: %d) 已离线，请关注。\n时间: %s",
+			alert.ServerName, alert.ServerID, time.Now().Format("2006-01-02 15:04:05"))
+	case "oom":
+		title = fmt.Sprintf("服务器 %s 发生 OOM", alert.ServerName)
+		content = fmt.Sprintf("服务器 %s (ID: %d) 内存耗尽，内核 OOM killer 杀死了 %.0f 个进程。",
+			alert.ServerName, alert.ServerID, alert.Value)
+	case "disk_health":
+		title = fmt.Sprintf("服务器 %s 磁盘即将故障", alert.ServerName)
+		content = fmt.Sprintf("服务器 %s (ID: %d) 有 %.0f 块磁盘的 SMART 自检预测即将故障，请尽快备份数据并更换磁盘。",
+			alert.ServerName, alert.ServerID, alert.Value)
+	case "cert_expiry":
+		title = fmt.Sprintf("服务器 %s 证书即将到期", alert.ServerName)
+		content = fmt.Sprintf("服务器 %s (ID: %d) 有 %.0f 张证书将在 %.0f 天内到期，请及时续期。",
+			alert.ServerName, alert.ServerID, alert.Value, alert.Threshold)
+	case "duplicate":
+		title = fmt.Sprintf("服务器 %s 疑似存在重复的 Agent", alert.ServerName)
+		content = fmt.Sprintf("服务器 %s (ID: %d) 的 Agent 连接在短时间内被不同机器反复抢占 %.0f 次。",
+			alert.ServerName, alert.ServerID, alert.Value)
+	case "rule":
+		title = fmt.Sprintf("【规则预警】%s", alert.ServerName)
+		content = fmt.Sprintf("服务器 %s (ID: %d) 满足预警规则 #%d 的条件。\n时间: %s",
+			alert.ServerName, alert.ServerID, alert.RuleID, time.Now().Format("2006-01-02 15:04:05"))
+	case "uptime":
+		title = fmt.Sprintf("【可用性检查失败】%s", alert.ServerName)
+		content = fmt.Sprintf("可用性检查 %s 已连续失败 %.0f 次（阈值 %.0f 次）。\n时间: %s",
+			alert.ServerName, alert.Value, alert.Threshold, time.Now().Format("2006-01-02 15:04:05"))
 	default:
 		title = fmt.Sprintf("服务器 %s 预警通知", alert.ServerName)
 		content = fmt.Sprintf("服务器 %s 的 %s 指标达到 %.2f, 超过预设阈值 %.2f",
 			alert.ServerName, alert.AlertType, alert.Value, alert.Threshold)
 	}
+	return title, content
+}
 
-	// 根据通知渠道类型选择不同的发送方式
-	switch channel.Type {
-	case "email":
-		return s.sendEmailNotification(config, title, content)
-	case "serverchan":
-		return s.sendServerChanNotification(config, title, content)
-	default:
-		log.Printf("不支持的通知类型: %s", channel.Type)
-		return false
+// deliver 按通知渠道类型同步发送通知并返回失败原因，用于需要立即得到结果的测试和模拟发送。
+// 超过渠道每分钟的发送上限时直接丢弃，临时性错误自动重试；不能在持有 s.mu 时调用
+func (s *AlertService) deliver(channel models.NotificationChannel, title, content string) error {
+	n, config, err := prepareNotify(channel)
+	if err != nil {
+		return err
 	}
+	return sendWithRetry(n, config, title, content)
 }
 
-// sendEmailNotification 发送邮件通知
-func (s *AlertService) sendEmailNotification(config map[string]string, title, content string) bool {
+// deliverEmail 发送邮件通知，所有收件人都发送失败时返回最后一个错误
+func deliverEmail(config map[string]string, title, content string) error {
 	emailConfig := utils.ParseEmailConfig(config)
 
 	// 构建HTML内容
@@ -421,55 +555,48 @@ func (s *AlertService) sendEmailNotification(config map[string]string, title, co
 	recipients = uniqueRecipients
 
 	if len(recipients) == 0 {
-		log.Printf("邮件通知发送失败：未找到收件人邮箱，请先在“个人资料”中设置管理员邮箱")
-		return false
+		return errors.New("未找到收件人邮箱，请先在“个人资料”中设置管理员邮箱")
 	}
 
 	successCount := 0
+	var lastErr error
 	for _, recipient := range recipients {
 		cfg := emailConfig
 		cfg.ToEmail = recipient
 		if err := utils.SendEmail(cfg, title, htmlContent); err != nil {
 			log.Printf("发送邮件通知失败(收件人=%s): %v", recipient, err)
+			lastErr = fmt.Errorf("收件人 %s: %w", recipient, err)
 			continue
 		}
 		successCount++
 	}
 
 	if successCount == 0 {
-		return false
+		return lastErr
 	}
 
 	log.Printf("邮件通知发送成功: %s (收件人数量=%d)", title, successCount)
-	return true
+	return nil
 }
 
-// sendServerChanNotification 发送Server酱通知
-func (s *AlertService) sendServerChanNotification(config map[string]string, title, content string) bool {
+// deliverServerChan 发送Server酱通知，返回失败原因
+func deliverServerChan(config map[string]string, title, content string) error {
 	sendkey, ok := config["sendkey"]
 	if !ok {
-		log.Printf("Server酱缺少sendkey配置")
-		return false
+		return permanentError{errors.New("Server酱缺少sendkey配置")}
 	}
 
 	resp, err := utils.ServerChanSend(sendkey, title, content)
 	if err != nil {
-		log.Printf("发送Server酱通知失败: %v", err)
-		return false
+		return err
 	}
 
 	log.Printf("Server酱通知发送成功: %v", resp)
-	return true
+	return nil
 }
 
 // sendResolutionNotification 发送解决通知
 func (s *AlertService) sendResolutionNotification(channel models.NotificationChannel, alert models.AlertRecord, currentValue float64) bool {
-	var config map[string]string
-	if err := json.Unmarshal([]byte(channel.Config), &config); err != nil {
-		log.Printf("解析通知配置失败: %v", err)
-		return false
-	}
-
 	var title, content string
 	switch alert.AlertType {
 	case "cpu":
@@ -484,31 +611,75 @@ func (s *AlertService) sendResolutionNotification(channel models.NotificationCha
 		title = fmt.Sprintf("服务器 %s 网络流量已恢复", alert.ServerName)
 		content = fmt.Sprintf("服务器 %s 的网络流量已恢复至 %.2f MB/s, 低于预设阈值 %.2f MB/s",
 			alert.ServerName, currentValue, alert.Threshold)
+	case "zombie":
+		title = fmt.Sprintf("服务器 %s 僵尸进程已恢复", alert.ServerName)
+		content = fmt.Sprintf("服务器 %s 的僵尸进程数已恢复至 %.0f 个, 低于预设阈值 %.0f 个",
+			alert.ServerName, currentValue, alert.Threshold)
+	case "agent_error":
+		title = fmt.Sprintf("服务器 %s 的 Agent 错误已恢复", alert.ServerName)
+		content = fmt.Sprintf("服务器 %s 的 Agent 最近 5 分钟内部错误已降至 %.0f 次, 低于预设阈值 %.0f 次",
+			alert.ServerName, currentValue, alert.Threshold)
+	case "temperature":
+		title = fmt.Sprintf("服务器 %s 温度已恢复", alert.ServerName)
+		content = fmt.Sprintf("服务器 %s 的硬件温度已降至 %.1f°C, 低于预设阈值 %.1f°C",
+			alert.ServerName, currentValue, alert.Threshold)
 	case "status":
 		title = fmt.Sprintf("服务器 %s 已恢复在线", alert.ServerName)
 		content = fmt.Sprintf("服务器 %s (ID: %d) 已恢复在线。\n时间: %s",
 			alert.ServerName,
 			alert.ServerID,
 			time.Now().Format("2006-01-02 15:04:05"))
+	case "rule":
+		title = fmt.Sprintf("服务器 %s 规则预警已恢复", alert.ServerName)
+		content = fmt.Sprintf("服务器 %s (ID: %d) 已不再满足预警规则 #%d 的条件。\n时间: %s",
+			alert.ServerName, alert.ServerID, alert.RuleID, time.Now().Format("2006-01-02 15:04:05"))
+	case "uptime":
+		title = fmt.Sprintf("可用性检查 %s 已恢复", alert.ServerName)
+		content = fmt.Sprintf("可用性检查 %s 已恢复正常，响应时间 %.0f ms。\n时间: %s",
+			alert.ServerName, currentValue, time.Now().Format("2006-01-02 15:04:05"))
 	default:
 		title = fmt.Sprintf("服务器 %s 预警已解除", alert.ServerName)
 		content = fmt.Sprintf("服务器 %s 的 %s 指标已恢复至 %.2f, 低于预设阈值 %.2f",
 			alert.ServerName, alert.AlertType, currentValue, alert.Threshold)
 	}
 
-	switch channel.Type {
-	case "email":
-		return s.sendEmailNotification(config, title, content)
-	case "serverchan":
-		return s.sendServerChanNotification(config, title, content)
-	default:
+	return s.notify(channel, title, content)
+}
+
+// SendTestNotification 同步发送测试通知，返回是否发送成功
+func (s *AlertService) SendTestNotification(channel models.NotificationChannel, alert models.AlertRecord) bool {
+	title, content := alertMessage(alert)
+	if err := s.deliver(channel, title, content); err != nil {
+		log.Printf("发送%s测试通知失败(渠道=%s): %v", channel.Type, channel.Name, err)
 		return false
 	}
+	return true
 }
 
-// SendTestNotification 发送测试通知
-func (s *AlertService) SendTestNotification(channel models.NotificationChannel, alert models.AlertRecord) bool {
-	return s.sendNotification(channel, alert)
+// channelsForCategory 筛选接收该分类预警的通知渠道
+func channelsForCategory(channels []models.NotificationChannel, category string) []models.NotificationChannel {
+	result := make([]models.NotificationChannel, 0, len(channels))
+	for _, channel := range channels {
+		if channel.AcceptsCategory(category) {
+			result = append(result, channel)
+		}
+	}
+	return result
+}
+
+// channelsForSetting 筛选预警规则的通知渠道：规则指定了渠道时只发送到这些渠道，否则按分类路由
+func channelsForSetting(channels []models.NotificationChannel, setting models.AlertSetting, category string) []models.NotificationChannel {
+	ids := setting.NotifyChannelIDs()
+	if len(ids) == 0 {
+		return channelsForCategory(channels, category)
+	}
+	result := make([]models.NotificationChannel, 0, len(ids))
+	for _, channel := range channels {
+		if ids[channel.ID] {
+			result = append(result, channel)
+		}
+	}
+	return result
 }
 
 // mergeSettings 合并全局设置和服务器特定设置
@@ -545,7 +716,7 @@ func offlineSince(server models.Server, now time.Time) time.Time {
 	if server.LastHeartbeat.IsZero() {
 		return now
 	}
-	t := server.LastHeartbeat.Add(serverHeartbeatTimeout)
+	t := server.LastHeartbeat.Add(models.HeartbeatTimeout(&server))
 	if t.After(now) {
 		return now
 	}
@@ -689,6 +860,7 @@ func (s *AlertService) sendStatusAlert(
 		ServerID:   server.ID,
 		ServerName: server.Name,
 		AlertType:  alertType,
+		Category:   models.AlertCategoryOf(alertType),
 		Value:      alertValue,
 		Threshold:  setting.Threshold,
 		NotifiedAt: time.Now(),
@@ -702,11 +874,19 @@ func (s *AlertService) sendStatusAlert(
 		record.ResolvedAt = time.Time{}
 	}
 
-	// 收集成功通知的渠道ID
+	// 离线告警匹配升级策略时按策略通知；上线通知是事件，不参与升级
 	var channelIDs []string
-	for _, channel := range channels {
-		if s.sendStatusNotification(channel, record, isOnline) {
-			channelIDs = append(channelIDs, strconv.FormatUint(uint64(channel.ID), 10))
+	escalated := false
+	if !isOnline {
+		channelIDs, escalated = s.startEscalation(&record, func(channel models.NotificationChannel) bool {
+			return s.sendStatusNotification(channel, record, false)
+		})
+	}
+	if !escalated {
+		for _, channel := range channelsForSetting(channels, setting, record.Category) {
+			if s.sendStatusNotification(channel, record, isOnline) {
+				channelIDs = append(channelIDs, strconv.FormatUint(uint64(channel.ID), 10))
+			}
 		}
 	}
 
@@ -736,21 +916,418 @@ func (s *AlertService) sendStatusNotification(
 		status,
 		time.Now().Format("2006-01-02 15:04:05"))
 
-	// 解析配置
-	config, err := channel.GetChannelConfig()
+	return s.notify(channel, title, content)
+}
+
+// NotifyOOMKills 服务器发生 OOM kill 时立即告警。
+// OOM 是一次性事件，没有"恢复"状态，记录直接标记为已解决；阈值为单次上报中的 OOM kill 次数
+func (s *AlertService) NotifyOOMKills(server models.Server, kills int, events []models.OOMEvent) {
+	if s.testing || kills <= 0 {
+		return
+	}
+	if models.InMaintenance(server.ID, time.Now()) {
+		log.Printf("服务器 %s(%d) 处于维护期间，不发送通知", server.Name, server.ID)
+		return
+	}
+
+	globalSettings, err := models.GetGlobalAlertSettings()
+	if err != nil {
+		log.Printf("获取全局预警设置失败: %v", err)
+		return
+	}
+	global := make(map[string]models.AlertSetting)
+	for _, setting := range globalSettings {
+		if setting.Enabled {
+			global[setting.Type] = setting
+		}
+	}
+	serverSettings, err := models.GetServerAlertSettings(server.ID)
+	if err != nil {
+		log.Printf("获取服务器 %d 预警设置失败: %v", server.ID, err)
+		return
+	}
+	setting, ok := s.mergeSettings(global, serverSettings)["oom"]
+	if !ok || float64(kills) < setting.Threshold {
+		return
+	}
+
+	channels, err := models.GetEnabledNotificationChannels()
 	if err != nil {
-		log.Printf("解析通知渠道配置失败: %v", err)
+		log.Printf("获取通知渠道失败: %v", err)
+		return
+	}
+
+	now := time.Now()
+	record := models.AlertRecord{
+		ServerID:   server.ID,
+		ServerName: server.Name,
+		AlertType:  "oom",
+		Category:   models.AlertCategoryOf("oom"),
+		Value:      float64(kills),
+		Threshold:  setting.Threshold,
+		Resolved:   true,
+		ResolvedAt: now,
+		NotifiedAt: now,
+	}
+
+	var channelIDs []string
+	for _, channel := range channelsForSetting(channels, setting, record.Category) {
+		if s.sendOOMNotification(channel, record, events) {
+			channelIDs = append(channelIDs, strconv.FormatUint(uint64(channel.ID), 10))
+		}
+	}
+	record.ChannelIDs = strings.Join(channelIDs, ",")
+	if err := models.CreateAlertRecord(&record); err != nil {
+		log.Printf("保存OOM预警记录失败: %v", err)
+	}
+}
+
+// NotifyDiskFailures 磁盘新出现 SMART 故障预测时告警。
+// 与 OOM 一样按一次性事件处理，记录直接标记为已解决；阈值为同时预测故障的磁盘数
+func (s *AlertService) NotifyDiskFailures(server models.Server, disks []models.DiskHealth) {
+	if s.testing || len(disks) == 0 {
+		return
+	}
+	if models.InMaintenance(server.ID, time.Now()) {
+		log.Printf("服务器 %s(%d) 处于维护期间，不发送通知", server.Name, server.ID)
+		return
+	}
+
+	globalSettings, err := models.GetGlobalAlertSettings()
+	if err != nil {
+		log.Printf("获取全局预警设置失败: %v", err)
+		return
+	}
+	global := make(map[string]models.AlertSetting)
+	for _, setting := range globalSettings {
+		if setting.Enabled {
+			global[setting.Type] = setting
+		}
+	}
+	serverSettings, err := models.GetServerAlertSettings(server.ID)
+	if err != nil {
+		log.Printf("获取服务器 %d 预警设置失败: %v", server.ID, err)
+		return
+	}
+	setting, ok := s.mergeSettings(global, serverSettings)["disk_health"]
+	if !ok || float64(len(disks)) < setting.Threshold {
+		return
+	}
+
+	channels, err := models.GetEnabledNotificationChannels()
+	if err != nil {
+		log.Printf("获取通知渠道失败: %v", err)
+		return
+	}
+
+	now := time.Now()
+	record := models.AlertRecord{
+		ServerID:   server.ID,
+		ServerName: server.Name,
+		AlertType:  "disk_health",
+		Category:   models.AlertCategoryOf("disk_health"),
+		Value:      float64(len(disks)),
+		Threshold:  setting.Threshold,
+		Resolved:   true,
+		ResolvedAt: now,
+		NotifiedAt: now,
+	}
+
+	title := fmt.Sprintf("服务器 %s 磁盘即将故障", server.Name)
+	var b strings.Builder
+	fmt.Fprintf(&b, "服务器 %s (ID: %d) 有 %d 块磁盘的 SMART 自检预测即将故障，请尽快备份数据并更换磁盘。",
+		server.Name, server.ID, len(disks))
+	for _, disk := range disks {
+		fmt.Fprintf(&b, "\n%s %s (SN: %s)", disk.Device, disk.Model, disk.Serial)
+		if disk.FailingAttributes != "" {
+			fmt.Fprintf(&b, " 异常属性: %s", disk.FailingAttributes)
+		}
+		if disk.ReallocatedSectors > 0 || disk.PendingSectors > 0 {
+			fmt.Fprintf(&b, " 重映射扇区: %d 待映射扇区: %d", disk.ReallocatedSectors, disk.PendingSectors)
+		}
+	}
+
+	var channelIDs []string
+	for _, channel := range channelsForSetting(channels, setting, record.Category) {
+		if s.notify(channel, title, b.String()) {
+			channelIDs = append(channelIDs, strconv.FormatUint(uint64(channel.ID), 10))
+		}
+	}
+	record.ChannelIDs = strings.Join(channelIDs, ",")
+	if err := models.CreateAlertRecord(&record); err != nil {
+		log.Printf("保存磁盘健康预警记录失败: %v", err)
+	}
+}
+
+// NotifyCertificateExpiry 服务器上有证书将在阈值天数内到期（或已过期）时告警，在 Agent 每次上报证书扫描结果后调用。
+// 每张证书只通知一次，续期后指纹变化视为新证书；维护期间不通知也不标记，维护结束后的下一次扫描再通知
+func (s *AlertService) NotifyCertificateExpiry(server models.Server) {
+	if s.testing {
+		return
+	}
+
+	globalSettings, err := models.GetGlobalAlertSettings()
+	if err != nil {
+		log.Printf("获取全局预警设置失败: %v", err)
+		return
+	}
+	global := make(map[string]models.AlertSetting)
+	for _, setting := range globalSettings {
+		if setting.Enabled {
+			global[setting.Type] = setting
+		}
+	}
+	serverSettings, err := models.GetServerAlertSettings(server.ID)
+	if err != nil {
+		log.Printf("获取服务器 %d 预警设置失败: %v", server.ID, err)
+		return
+	}
+	setting, ok := s.mergeSettings(global, serverSettings)["cert_expiry"]
+	if !ok {
+		return
+	}
+
+	certs, err := models.GetHostCertificates(server.ID)
+	if err != nil {
+		log.Printf("获取服务器 %d 的证书失败: %v", server.ID, err)
+		return
+	}
+	now := time.Now()
+	deadline := now.Add(time.Duration(setting.Threshold * float64(24*time.Hour)))
+	var expiring []models.HostCertificate
+	for _, cert := range certs {
+		if cert.NotifiedAt == nil && cert.ExpiresAt.Before(deadline) {
+			expiring = append(expiring, cert)
+		}
+	}
+	if len(expiring) == 0 {
+		return
+	}
+	if models.InMaintenance(server.ID, now) {
+		log.Printf("服务器 %s(%d) 处于维护期间，不发送通知", server.Name, server.ID)
+		return
+	}
+
+	channels, err := models.GetEnabledNotificationChannels()
+	if err != nil {
+		log.Printf("获取通知渠道失败: %v", err)
+		return
+	}
+
+	record := models.AlertRecord{
+		ServerID:   server.ID,
+		ServerName: server.Name,
+		AlertType:  "cert_expiry",
+		Category:   models.AlertCategoryOf("cert_expiry"),
+		Value:      float64(len(expiring)),
+		Threshold:  setting.Threshold,
+		Resolved:   true,
+		ResolvedAt: now,
+		NotifiedAt: now,
+	}
+
+	title := fmt.Sprintf("服务器 %s 证书即将到期", server.Name)
+	var b strings.Builder
+	fmt.Fprintf(&b, "服务器 %s (ID: %d) 有 %d 张证书将在 %.0f 天内到期，请及时续期。",
+		server.Name, server.ID, len(expiring), setting.Threshold)
+	certbot := false
+	ids := make([]uint, 0, len(expiring))
+	for _, cert := range expiring {
+		ids = append(ids, cert.ID)
+		if cert.ExpiresAt.Before(now) {
+			fmt.Fprintf(&b, "\n%s 已于 %s 过期", cert.Domain, cert.ExpiresAt.Format("2006-01-02"))
+		} else {
+			fmt.Fprintf(&b, "\n%s 将于 %s 到期（剩余 %d 天）", cert.Domain, cert.ExpiresAt.Format("2006-01-02"),
+				int(cert.ExpiresAt.Sub(now).Hours()/24))
+		}
+		if cert.CertPath != "" {
+			fmt.Fprintf(&b, " %s", cert.CertPath)
+		}
+		certbot = certbot || cert.Source == "certbot"
+	}
+	if certbot {
+		b.WriteString("\ncertbot 管理的证书可在面板的「证书到期」页面一键续期。")
+	}
+
+	var channelIDs []string
+	for _, channel := range channelsForSetting(channels, setting, record.Category) {
+		if s.notify(channel, title, b.String()) {
+			channelIDs = append(channelIDs, strconv.FormatUint(uint64(channel.ID), 10))
+		}
+	}
+	record.ChannelIDs = strings.Join(channelIDs, ",")
+	if err := models.CreateAlertRecord(&record); err != nil {
+		log.Printf("保存证书到期预警记录失败: %v", err)
+	}
+	if err := models.MarkHostCertificatesNotified(ids, now); err != nil {
+		log.Printf("标记服务器 %d 的证书已通知失败: %v", server.ID, err)
+	}
+}
+
+// NotifyDuplicateAgent 疑似同一服务器ID被多台机器上的 Agent 使用时告警。
+// 阈值为检测窗口内不同 Agent 之间的连接切换次数；返回是否已生成告警记录，调用方据此避免重复告警
+func (s *AlertService) NotifyDuplicateAgent(server models.Server, switches int, addrs []string) bool {
+	if s.testing || switches <= 0 {
+		return false
+	}
+	if models.InMaintenance(server.ID, time.Now()) {
+		log.Printf("服务器 %s(%d) 处于维护期间，不发送通知", server.Name, server.ID)
 		return false
 	}
 
-	// 根据渠道类型发送通知
-	switch channel.Type {
-	case "email":
-		return s.sendEmailNotification(config, title, content)
-	case "serverchan":
-		return s.sendServerChanNotification(config, title, content)
-	default:
-		log.Printf("不支持的通知渠道类型: %s", channel.Type)
+	globalSettings, err := models.GetGlobalAlertSettings()
+	if err != nil {
+		log.Printf("获取全局预警设置失败: %v", err)
 		return false
 	}
+	global := make(map[string]models.AlertSetting)
+	for _, setting := range globalSettings {
+		if setting.Enabled {
+			global[setting.Type] = setting
+		}
+	}
+	serverSettings, err := models.GetServerAlertSettings(server.ID)
+	if err != nil {
+		log.Printf("获取服务器 %d 预警设置失败: %v", server.ID, err)
+		return false
+	}
+	setting, ok := s.mergeSettings(global, serverSettings)["duplicate"]
+	if !ok || float64(switches) < setting.Threshold {
+		return false
+	}
+
+	channels, err := models.GetEnabledNotificationChannels()
+	if err != nil {
+		log.Printf("获取通知渠道失败: %v", err)
+		return false
+	}
+
+	now := time.Now()
+	record := models.AlertRecord{
+		ServerID:   server.ID,
+		ServerName: server.Name,
+		AlertType:  "duplicate",
+		Category:   models.AlertCategoryOf("duplicate"),
+		Value:      float64(switches),
+		Threshold:  setting.Threshold,
+		Resolved:   true,
+		ResolvedAt: now,
+		NotifiedAt: now,
+	}
+
+	title := fmt.Sprintf("服务器 %s 疑似存在重复的 Agent", server.Name)
+	content := fmt.Sprintf("服务器 %s (ID: %d) 的 Agent 连接在短时间内被不同机器反复抢占 %d 次，来源地址: %s。\n"+
+		"可能是克隆虚拟机或复制配置导致多台机器使用了相同的服务器ID和密钥，请为其他机器重新注册。",
+		server.Name, server.ID, switches, strings.Join(addrs, ", "))
+
+	var channelIDs []string
+	for _, channel := range channelsForSetting(channels, setting, record.Category) {
+		if s.notify(channel, title, content) {
+			channelIDs = append(channelIDs, strconv.FormatUint(uint64(channel.ID), 10))
+		}
+	}
+	record.ChannelIDs = strings.Join(channelIDs, ",")
+	if err := models.CreateAlertRecord(&record); err != nil {
+		log.Printf("保存重复Agent预警记录失败: %v", err)
+	}
+	return true
+}
+
+// NotifyUptimeDown 可用性检查连续失败达到阈值时告警，返回生成的未解决预警记录ID（0 表示未生成）。
+// 检查不属于任何服务器，记录的服务器ID为 0、服务器名称为检查名称；检查指定了渠道时只发送到这些渠道
+func (s *AlertService) NotifyUptimeDown(check models.UptimeCheck, errMsg string) uint {
+	if s.testing {
+		return 0
+	}
+
+	// 可用性检查不属于某台服务器，只受全局维护窗口影响；返回 0 使窗口结束后仍失败时再次通知
+	if models.InMaintenance(0, time.Now()) {
+		return 0
+	}
+
+	channels, err := models.GetEnabledNotificationChannels()
+	if err != nil {
+		log.Printf("获取通知渠道失败: %v", err)
+		return 0
+	}
+
+	record := models.AlertRecord{
+		ServerName: check.Name,
+		AlertType:  "uptime",
+		Category:   models.AlertCategoryOf("uptime"),
+		Value:      float64(check.ConsecutiveFailures),
+		Threshold:  float64(check.FailureThreshold),
+		NotifiedAt: time.Now(),
+	}
+
+	title, content := alertMessage(record)
+	content = fmt.Sprintf("%s\n目标: %s\n错误: %s", content, check.Target, errMsg)
+	send := func(channel models.NotificationChannel) bool {
+		return s.notify(channel, title, content)
+	}
+
+	channelIDs, escalated := s.startEscalation(&record, send)
+	if !escalated {
+		setting := models.AlertSetting{ChannelIDs: check.ChannelIDs}
+		for _, channel := range channelsForSetting(channels, setting, record.Category) {
+			if send(channel) {
+				channelIDs = append(channelIDs, strconv.FormatUint(uint64(channel.ID), 10))
+			}
+		}
+	}
+	record.ChannelIDs = strings.Join(channelIDs, ",")
+	if err := models.CreateAlertRecord(&record); err != nil {
+		log.Printf("保存可用性检查预警记录失败: %v", err)
+		return 0
+	}
+	return record.ID
+}
+
+// NotifyUptimeRecovered 可用性检查恢复时标记预警记录为已解决，并通知告警时通知过的渠道
+func (s *AlertService) NotifyUptimeRecovered(recordID uint, responseMs int64) {
+	if s.testing || recordID == 0 {
+		return
+	}
+
+	var record models.AlertRecord
+	if err := models.GetAlertRecordByID(recordID, &record); err != nil {
+		log.Printf("查找可用性检查预警记录 %d 失败: %v", recordID, err)
+		return
+	}
+	if record.Resolved {
+		return
+	}
+	record.Resolved = true
+	record.ResolvedAt = time.Now()
+	if err := models.UpdateAlertRecord(&record); err != nil {
+		log.Printf("更新预警记录失败: %v", err)
+	}
+
+	if record.ChannelIDs == "" {
+		return
+	}
+	for _, idStr := range strings.Split(record.ChannelIDs, ",") {
+		id, _ := strconv.ParseUint(idStr, 10, 64)
+		var channel models.NotificationChannel
+		if err := models.GetNotificationChannelByID(uint(id), &channel); err != nil {
+			continue
+		}
+		s.sendResolutionNotification(channel, record, float64(responseMs))
+	}
+}
+
+// sendOOMNotification 发送 OOM 通知，列出被杀死的进程
+func (s *AlertService) sendOOMNotification(channel models.NotificationChannel, alert models.AlertRecord, events []models.OOMEvent) bool {
+	title := fmt.Sprintf("服务器 %s 发生 OOM", alert.ServerName)
+	var b strings.Builder
+	fmt.Fprintf(&b, "服务器 %s (ID: %d) 内存耗尽，内核 OOM killer 杀死了 %.0f 个进程。",
+		alert.ServerName, alert.ServerID, alert.Value)
+	for _, event := range events {
+		fmt.Fprintf(&b, "\n%s  %s (PID %d)", event.OccurredAt.Format("2006-01-02 15:04:05"), event.Process, event.PID)
+	}
+	if len(events) == 0 {
+		b.WriteString("\nAgent 无权限读取内核日志，无法获取进程详情。")
+	}
+
+	return s.notify(channel, title, b.String())
 }
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSmoothMetric(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	type sample struct {
+		raw    float64
+		alpha  float64
+		offset time.Duration
+		want   float64
+	}
+	tests := []struct {
+		name    string
+		samples []sample
+	}{
+		{
+			name: "首个样本返回原始值",
+			samples: []sample{
+				{raw: 80, alpha: 0.5, want: 80},
+			},
+		},
+		{
+			name: "新样本按指数移动平均",
+			samples: []sample{
+				{raw: 20, alpha: 0.5, want: 20},
+				{raw: 100, alpha: 0.5, offset: 30 * time.Second, want: 60},
+				{raw: 100, alpha: 0.5, offset: time.Minute, want: 80},
+			},
+		},
+		{
+			name: "同一样本只计入一次，检查频率不影响平滑",
+			samples: []sample{
+				{raw: 20, alpha: 0.5, want: 20},
+				{raw: 100, alpha: 0.5, offset: 30 * time.Second, want: 60},
+				{raw: 100, alpha: 0.5, offset: 30 * time.Second, want: 60},
+				{raw: 100, alpha: 0.5, offset: 30 * time.Second, want: 60},
+			},
+		},
+		{
+			name: "比上次更早的样本返回上次的平滑值",
+			samples: []sample{
+				{raw: 20, alpha: 0.5, offset: time.Minute, want: 20},
+				{raw: 100, alpha: 0.5, want: 20},
+			},
+		},
+		{
+			name: "间隔不超过重置时间时继续平滑",
+			samples: []sample{
+				{raw: 20, alpha: 0.5, want: 20},
+				{raw: 100, alpha: 0.5, offset: smoothingResetGap, want: 60},
+			},
+		},
+		{
+			name: "间隔超过重置时间时从当前样本重新开始",
+			samples: []sample{
+				{raw: 20, alpha: 0.5, want: 20},
+				{raw: 100, alpha: 0.5, offset: smoothingResetGap + time.Second, want: 100},
+			},
+		},
+		{
+			name: "alpha 为 0 时不平滑并清除状态",
+			samples: []sample{
+				{raw: 20, alpha: 0.5, want: 20},
+				{raw: 100, alpha: 0, offset: 30 * time.Second, want: 100},
+				{raw: 40, alpha: 0.5, offset: time.Minute, want: 40},
+			},
+		},
+		{
+			name: "alpha 为 1 或超出范围时返回原始值",
+			samples: []sample{
+				{raw: 20, alpha: 0.5, want: 20},
+				{raw: 100, alpha: 1, offset: 30 * time.Second, want: 100},
+				{raw: 50, alpha: -0.5, offset: time.Minute, want: 50},
+				{raw: 70, alpha: 1.5, offset: 90 * time.Second, want: 70},
+				{raw: 30, alpha: 0.5, offset: 2 * time.Minute, want: 30},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewAlertService()
+			for i, sm := range tt.samples {
+				got := s.smoothMetric("cpu", 1, sm.raw, sm.alpha, base.Add(sm.offset))
+				assert.InDelta(t, sm.want, got, 1e-9, "第 %d 个样本", i+1)
+			}
+		})
+	}
+}
+
+func TestSmoothMetricSeparatesState(t *testing.T) {
+	s := NewAlertService()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 不同服务器、不同指标的平滑状态互不影响
+	assert.Equal(t, 20.0, s.smoothMetric("cpu", 1, 20, 0.5, base))
+	assert.Equal(t, 90.0, s.smoothMetric("cpu", 2, 90, 0.5, base))
+	assert.Equal(t, 40.0, s.smoothMetric("memory", 1, 40, 0.5, base))
+
+	next := base.Add(30 * time.Second)
+	assert.Equal(t, 60.0, s.smoothMetric("cpu", 1, 100, 0.5, next))
+	assert.Equal(t, 95.0, s.smoothMetric("cpu", 2, 100, 0.5, next))
+	assert.Equal(t, 70.0, s.smoothMetric("memory", 1, 100, 0.5, next))
+
+	// 清除某个服务器的状态不影响其他服务器
+	assert.Equal(t, 10.0, s.smoothMetric("cpu", 1, 10, 0, next.Add(30*time.Second)))
+	assert.NotContains(t, s.smoothed["cpu"], uint(1))
+	assert.Contains(t, s.smoothed["cpu"], uint(2))
+}
+
+func TestAlertExprMissingMetricIsFalse(t *testing.T) {
+	env := &alertExprEnv{vars: map[string]float64{"cpu": 95}}
+	missing := &exprNode{op: "var", name: "custom.missing"}
+	cpuHigh := &exprNode{op: ">", left: &exprNode{op: "var", name: "cpu"}, right: &exprNode{op: "num", value: 90}, boolean: true}
+
+	// 不存在的指标为 NaN，在 and/or/not 和整个表达式的结果中都按 false 处理
+	tests := []struct {
+		name string
+		root *exprNode
+		want bool
+	}{
+		{"单独的缺失指标", missing, false},
+		{"and", &exprNode{op: "and", left: missing, right: cpuHigh}, false},
+		{"or", &exprNode{op: "or", left: missing, right: &exprNode{op: "not", left: cpuHigh}}, false},
+		{"not", &exprNode{op: "not", left: missing}, true},
+		{"存在的条件不受影响", &exprNode{op: "or", left: missing, right: cpuHigh}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, (&AlertExpression{root: tt.root}).Eval(env))
+		})
+	}
+}
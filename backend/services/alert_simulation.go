@@ -0,0 +1,58 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/user/server-ops-backend/models"
+)
+
+// simulatedAlertNote 附加在模拟预警内容末尾的说明，避免接收方误以为发生了真实故障
+const simulatedAlertNote = "\n\n这是一条模拟预警，用于验证通知渠道和分类路由，请忽略。"
+
+// ChannelDelivery 模拟预警在单个通知渠道上的投递结果
+type ChannelDelivery struct {
+	ChannelID  uint   `json:"channel_id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Delivered  bool   `json:"delivered"`
+	Skipped    bool   `json:"skipped,omitempty"` // 渠道未启用或不接收该分类，未发送
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// SimulateAlert 构造一条模拟预警，按真实预警的消息格式和分类路由发送到给定的通知渠道，
+// 返回每个渠道的投递结果。模拟预警的标题带有【测试】前缀，不写入预警记录，也不影响预警状态
+func (s *AlertService) SimulateAlert(alert models.AlertRecord, channels []models.NotificationChannel) []ChannelDelivery {
+	if alert.Category == "" {
+		alert.Category = models.AlertCategoryOf(alert.AlertType)
+	}
+	title, content := alertMessage(alert)
+	title = "【测试】" + title
+	content += simulatedAlertNote
+
+	results := make([]ChannelDelivery, 0, len(channels))
+	for _, channel := range channels {
+		result := ChannelDelivery{ChannelID: channel.ID, Name: channel.Name, Type: channel.Type}
+		switch {
+		case !channel.Enabled:
+			result.Skipped, result.Error = true, "渠道未启用"
+		case !channel.AcceptsCategory(alert.Category):
+			result.Skipped, result.Error = true, fmt.Sprintf("渠道不接收 %s 分类的预警", alert.Category)
+		default:
+			start := time.Now()
+			err := s.deliver(channel, title, content)
+			result.DurationMs = time.Since(start).Milliseconds()
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Delivered = true
+			}
+		}
+		results = append(results, result)
+	}
+
+	log.Printf("已发送模拟预警: 类型 %s, 分类 %s, 渠道数 %d", alert.AlertType, alert.Category, len(channels))
+	return results
+}
@@ -124,8 +124,9 @@ func (s *CertificateRenewalService) renewCertificate(cert *models.ManagedCertifi
 		account = acc
 	}
 
-	// 如果没有关联账号且使用DNS方式，无法续期
-	if account == nil && (cert.Provider == "alidns" || cert.Provider == "aliyun" || cert.Provider == "cloudflare" || cert.Provider == "cf") {
+	// 如果没有关联账号且使用DNS方式，无法续期；Route53 可以不关联账号，使用 Agent 本机的 AWS 默认凭证
+	useAgentCredentials := cert.Provider == "route53" || cert.Provider == "aws"
+	if account == nil && cert.Provider != "" && cert.Provider != "http01" && !useAgentCredentials {
 		return fmt.Errorf("DNS验证方式需要配置DNS账号")
 	}
 
@@ -143,6 +144,8 @@ func (s *CertificateRenewalService) renewCertificate(cert *models.ManagedCertifi
 			return fmt.Errorf("解析DNS配置失败: %w", err)
 		}
 		payload["dns_config"] = dnsConfig
+	} else if useAgentCredentials {
+		payload["provider"] = cert.Provider
 	} else {
 		// HTTP-01验证方式（需要Agent自动处理webroot）
 		payload["provider"] = "http01"
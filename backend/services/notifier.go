@@ -0,0 +1,374 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/user/server-ops-backend/models"
+)
+
+// Notifier 一种通知渠道的发送实现，按 NotificationChannel.Type 注册
+type Notifier interface {
+	// Validate 校验渠道配置是否完整
+	Validate(config map[string]string) error
+	// Send 发送一条通知，配置错误或对方明确拒绝时返回 permanentError，不再重试
+	Send(config map[string]string, title, content string) error
+}
+
+var notifiers = map[string]Notifier{
+	"email":      emailNotifier{},
+	"serverchan": serverChanNotifier{},
+	"telegram":   telegramNotifier{},
+	"slack":      slackNotifier{},
+	"discord":    discordNotifier{},
+	"webhook":    webhookNotifier{},
+}
+
+// NotifierTypes 返回支持的通知渠道类型
+func NotifierTypes() []string {
+	types := make([]string, 0, len(notifiers))
+	for t := range notifiers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// ValidateChannelConfig 校验通知渠道类型和配置
+func ValidateChannelConfig(channelType string, config map[string]string) error {
+	n, ok := notifiers[channelType]
+	if !ok {
+		return fmt.Errorf("不支持的通知类型: %s，可选: %s", channelType, strings.Join(NotifierTypes(), "、"))
+	}
+	if raw := strings.TrimSpace(config["rate_limit"]); raw != "" {
+		if limit, err := strconv.Atoi(raw); err != nil || limit < 0 {
+			return errors.New("rate_limit 必须是非负整数（每分钟最多发送的通知数，0 表示不限制）")
+		}
+	}
+	return n.Validate(config)
+}
+
+// permanentError 重试也不会成功的发送错误
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// notifyRetryDelays 发送失败后的重试间隔，重试次数即其长度
+var notifyRetryDelays = []time.Duration{time.Second, 3 * time.Second}
+
+// defaultNotifyRateLimit 每个渠道每分钟最多发送的通知数，渠道配置 rate_limit 可覆盖，0 表示不限制
+const defaultNotifyRateLimit = 30
+
+// notifyLimiter 按渠道统计最近一分钟的发送时间，避免告警风暴刷屏或触发对方的限流
+var notifyLimiter = struct {
+	mu   sync.Mutex
+	sent map[uint][]time.Time
+}{sent: make(map[uint][]time.Time)}
+
+// allowNotify 判断渠道在当前一分钟窗口内是否还能发送
+func allowNotify(channel models.NotificationChannel, config map[string]string, now time.Time) bool {
+	limit := defaultNotifyRateLimit
+	if raw := strings.TrimSpace(config["rate_limit"]); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n >= 0 {
+			limit = n
+		}
+	}
+	if limit == 0 || channel.ID == 0 {
+		return true
+	}
+
+	notifyLimiter.mu.Lock()
+	defer notifyLimiter.mu.Unlock()
+	recent := notifyLimiter.sent[channel.ID][:0]
+	for _, at := range notifyLimiter.sent[channel.ID] {
+		if now.Sub(at) < time.Minute {
+			recent = append(recent, at)
+		}
+	}
+	if len(recent) >= limit {
+		notifyLimiter.sent[channel.ID] = recent
+		return false
+	}
+	notifyLimiter.sent[channel.ID] = append(recent, now)
+	return true
+}
+
+// notifyJob 等待后台发送的一条通知
+type notifyJob struct {
+	channel  models.NotificationChannel
+	notifier Notifier
+	config   map[string]string
+	title    string
+	content  string
+}
+
+// 后台发送队列的容量和并发数。预警检查在持有 AlertService.mu 时发送通知，
+// 发送和重试放到后台，避免一个无响应的渠道拖住所有服务器的预警检查
+const (
+	notifyQueueSize = 256
+	notifyWorkers   = 4
+)
+
+var (
+	notifyQueue       = make(chan notifyJob, notifyQueueSize)
+	notifyWorkersOnce sync.Once
+)
+
+// prepareNotify 解析渠道配置并检查发送频率，返回该渠道的发送实现
+func prepareNotify(channel models.NotificationChannel) (Notifier, map[string]string, error) {
+	config, err := channel.GetChannelConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("解析通知配置失败: %w", err)
+	}
+	n, ok := notifiers[channel.Type]
+	if !ok {
+		return nil, nil, fmt.Errorf("不支持的通知类型: %s", channel.Type)
+	}
+	if !allowNotify(channel, config, time.Now()) {
+		return nil, nil, errors.New("超过渠道每分钟的通知数上限，本条通知已丢弃")
+	}
+	return n, config, nil
+}
+
+// enqueueNotify 将通知放入后台发送队列，由后台按 notifyRetryDelays 重试，队列已满时返回错误
+func enqueueNotify(channel models.NotificationChannel, title, content string) error {
+	n, config, err := prepareNotify(channel)
+	if err != nil {
+		return err
+	}
+	notifyWorkersOnce.Do(func() {
+		for i := 0; i < notifyWorkers; i++ {
+			go runNotifyWorker()
+		}
+	})
+	select {
+	case notifyQueue <- notifyJob{channel: channel, notifier: n, config: config, title: title, content: content}:
+		return nil
+	default:
+		return errors.New("通知发送队列已满，本条通知已丢弃")
+	}
+}
+
+// runNotifyWorker 依次发送队列中的通知，最终失败时记录日志
+func runNotifyWorker() {
+	for job := range notifyQueue {
+		if err := sendWithRetry(job.notifier, job.config, job.title, job.content); err != nil {
+			log.Printf("发送%s通知失败(渠道=%s): %v", job.channel.Type, job.channel.Name, err)
+		}
+	}
+}
+
+// sendWithRetry 发送通知，临时性错误按 notifyRetryDelays 重试
+func sendWithRetry(n Notifier, config map[string]string, title, content string) error {
+	err := n.Send(config, title, content)
+	for _, delay := range notifyRetryDelays {
+		var permanent permanentError
+		if err == nil || errors.As(err, &permanent) {
+			break
+		}
+		log.Printf("通知发送失败，%s 后重试: %v", delay, err)
+		time.Sleep(delay)
+		err = n.Send(config, title, content)
+	}
+	return err
+}
+
+// requireFields 检查配置中的必填字段
+func requireFields(config map[string]string, label string, fields ...string) error {
+	for _, field := range fields {
+		if strings.TrimSpace(config[field]) == "" {
+			return fmt.Errorf("%s配置缺少必要字段: %s", label, field)
+		}
+	}
+	return nil
+}
+
+// requireURL 检查配置中的地址是否为 http(s) URL
+func requireURL(config map[string]string, label, field string) error {
+	if err := requireFields(config, label, field); err != nil {
+		return err
+	}
+	u, err := url.Parse(config[field])
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s配置的 %s 不是有效的 http(s) 地址", label, field)
+	}
+	return nil
+}
+
+var notifyHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// postJSON 以 JSON 请求体调用 Webhook，返回响应内容；secret 不为空时附带请求体签名。
+// 网络错误、429 和 5xx 可以重试，其余非 2xx 响应视为永久失败
+func postJSON(method, target string, payload interface{}, secret string) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, permanentError{err}
+	}
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, permanentError{fmt.Errorf("创建请求失败: %w", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		req.Header.Set(webhookSecretHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := notifyHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("发送请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return nil, err
+		}
+		return nil, permanentError{err}
+	}
+	return respBody, nil
+}
+
+// truncateRunes 按字符截断消息，适配对方平台的长度限制
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+type emailNotifier struct{}
+
+func (emailNotifier) Validate(config map[string]string) error {
+	return requireFields(config, "邮件", "smtp_host", "username", "password", "from_email")
+}
+
+func (emailNotifier) Send(config map[string]string, title, content string) error {
+	return deliverEmail(config, title, content)
+}
+
+type serverChanNotifier struct{}
+
+func (serverChanNotifier) Validate(config map[string]string) error {
+	return requireFields(config, "Server酱", "sendkey")
+}
+
+func (serverChanNotifier) Send(config map[string]string, title, content string) error {
+	return deliverServerChan(config, title, content)
+}
+
+// telegramNotifier 通过 Bot API 发送消息，api_url 可指向自建的 Bot API 代理
+type telegramNotifier struct{}
+
+func (telegramNotifier) Validate(config map[string]string) error {
+	if err := requireFields(config, "Telegram", "bot_token", "chat_id"); err != nil {
+		return err
+	}
+	if strings.TrimSpace(config["api_url"]) != "" {
+		return requireURL(config, "Telegram", "api_url")
+	}
+	return nil
+}
+
+func (telegramNotifier) Send(config map[string]string, title, content string) error {
+	api := strings.TrimRight(strings.TrimSpace(config["api_url"]), "/")
+	if api == "" {
+		api = "https://api.telegram.org"
+	}
+	payload := map[string]interface{}{
+		"chat_id":                  config["chat_id"],
+		"text":                     truncateRunes(title+"\n\n"+content, 4096),
+		"disable_web_page_preview": true,
+	}
+	body, err := postJSON(http.MethodPost, api+"/bot"+config["bot_token"]+"/sendMessage", payload, "")
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(body, &resp); err == nil && !resp.OK {
+		return permanentError{fmt.Errorf("Telegram 返回错误: %s", resp.Description)}
+	}
+	return nil
+}
+
+// slackNotifier 通过 Slack Incoming Webhook 发送消息
+type slackNotifier struct{}
+
+func (slackNotifier) Validate(config map[string]string) error {
+	return requireURL(config, "Slack", "webhook_url")
+}
+
+func (slackNotifier) Send(config map[string]string, title, content string) error {
+	_, err := postJSON(http.MethodPost, config["webhook_url"], map[string]string{
+		"text": "*" + title + "*\n" + content,
+	}, "")
+	return err
+}
+
+// discordNotifier 通过 Discord 频道 Webhook 发送消息
+type discordNotifier struct{}
+
+func (discordNotifier) Validate(config map[string]string) error {
+	return requireURL(config, "Discord", "webhook_url")
+}
+
+func (discordNotifier) Send(config map[string]string, title, content string) error {
+	_, err := postJSON(http.MethodPost, config["webhook_url"], map[string]string{
+		"content": truncateRunes("**"+title+"**\n"+content, 2000),
+	}, "")
+	return err
+}
+
+// webhookSecretHeader 通用 Webhook 的签名头，值为 "sha256=" 加请求体的 HMAC-SHA256
+const webhookSecretHeader = "X-BetterMonitor-Signature"
+
+// webhookNotifier 向任意地址发送 JSON 格式的通知：{"title","content","time"}。
+// 配置了 secret 时附带签名头，接收方据此校验请求来源
+type webhookNotifier struct{}
+
+func (webhookNotifier) Validate(config map[string]string) error {
+	if err := requireURL(config, "Webhook", "url"); err != nil {
+		return err
+	}
+	switch strings.ToUpper(strings.TrimSpace(config["method"])) {
+	case "", http.MethodPost, http.MethodPut:
+		return nil
+	default:
+		return errors.New("Webhook 的 method 只能是 POST 或 PUT")
+	}
+}
+
+func (webhookNotifier) Send(config map[string]string, title, content string) error {
+	method := strings.ToUpper(strings.TrimSpace(config["method"]))
+	if method == "" {
+		method = http.MethodPost
+	}
+	_, err := postJSON(method, config["url"], map[string]string{
+		"title":   title,
+		"content": content,
+		"time":    time.Now().Format(time.RFC3339),
+	}, config["secret"])
+	return err
+}
@@ -3,11 +3,13 @@ package services
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -16,10 +18,14 @@ import (
 )
 
 const (
-	releaseMaxRetries    = 3
 	releaseRetryBaseWait = 500 * time.Millisecond
+	// GitHub 建议的等待时间超过该值时不再重试，直接返回错误，避免阻塞升级请求
+	releaseMaxRetryWait = 30 * time.Second
 )
 
+// ErrGitHubRateLimited GitHub API 请求次数耗尽
+var ErrGitHubRateLimited = errors.New("GitHub API 请求次数已达上限，请配置 GITHUB_TOKEN 或 AGENT_RELEASE_GITHUB_TOKEN 以提升限额")
+
 type httpDoer interface {
 	Do(req *http.Request) (*http.Response, error)
 }
@@ -32,6 +38,7 @@ var (
 	releaseCacheMu           sync.Mutex
 	releaseCache             = make(map[string]cachedRelease)
 	releaseCacheTTL          = 5 * time.Minute
+	releaseRetries           = 2 // 首次请求失败后的重试次数
 )
 
 type cachedRelease struct {
@@ -76,6 +83,17 @@ type githubAsset struct {
 	Size               int64  `json:"size"`
 }
 
+// ConfigureReleaseAPI 设置 GitHub Release API 的重试次数和单次请求超时
+func ConfigureReleaseAPI(retries int, timeout time.Duration) {
+	if retries >= 0 {
+		releaseRetries = retries
+	}
+	if timeout > 0 {
+		defaultReleaseHTTPClient = &http.Client{Timeout: timeout}
+		releaseHTTPClient = defaultReleaseHTTPClient
+	}
+}
+
 // SetReleaseHTTPClient 仅用于测试自定义HTTP客户端
 func SetReleaseHTTPClient(client httpDoer) {
 	if client == nil {
@@ -146,31 +164,36 @@ func fetchReleaseFromGitHub(repo, channel string) (*githubRelease, error) {
 		endpoint = fmt.Sprintf("%s/repos/%s/releases?per_page=1", releaseAPIBaseURL, repo)
 	}
 
+	attempts := releaseRetries + 1
 	var lastErr error
-	for attempt := 0; attempt < releaseMaxRetries; attempt++ {
+	var wait time.Duration
+	for attempt := 0; attempt < attempts; attempt++ {
 		if attempt > 0 {
-			wait := releaseRetryBaseWait * (1 << (attempt - 1)) // 500ms, 1s
+			if wait <= 0 {
+				wait = releaseRetryBaseWait * (1 << (attempt - 1)) // 500ms, 1s, 2s...
+			}
 			log.Printf("GitHub API 请求失败，%v 后进行第 %d 次重试: %v", wait, attempt+1, lastErr)
 			time.Sleep(wait)
 		}
 
-		release, retryable, err := doFetchRelease(endpoint)
+		release, retryAfter, retryable, err := doFetchRelease(endpoint)
 		if err == nil {
 			return release, nil
 		}
 		lastErr = err
+		wait = retryAfter
 		if !retryable {
 			return nil, lastErr
 		}
 	}
-	return nil, fmt.Errorf("请求发布信息失败（已重试 %d 次）: %w", releaseMaxRetries, lastErr)
+	return nil, fmt.Errorf("请求发布信息失败（共尝试 %d 次）: %w", attempts, lastErr)
 }
 
-// doFetchRelease 执行单次 GitHub API 请求，返回结果、是否可重试、错误
-func doFetchRelease(endpoint string) (*githubRelease, bool, error) {
+// doFetchRelease 执行单次 GitHub API 请求，返回结果、GitHub 建议的重试等待时间、是否可重试、错误
+func doFetchRelease(endpoint string) (*githubRelease, time.Duration, bool, error) {
 	req, err := http.NewRequest("GET", endpoint, nil)
 	if err != nil {
-		return nil, false, fmt.Errorf("创建请求失败: %w", err)
+		return nil, 0, false, fmt.Errorf("创建请求失败: %w", err)
 	}
 	req.Header.Set("Accept", "application/vnd.github+json")
 
@@ -181,33 +204,74 @@ func doFetchRelease(endpoint string) (*githubRelease, bool, error) {
 	resp, err := releaseHTTPClient.Do(req)
 	if err != nil {
 		// 网络错误（超时、DNS、连接拒绝等）可重试
-		return nil, true, fmt.Errorf("请求发布信息失败: %w", err)
+		return nil, 0, true, fmt.Errorf("请求发布信息失败: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests {
+		wait, retryable, err := checkRateLimit(resp)
+		return nil, wait, retryable, err
+	}
 	if resp.StatusCode != http.StatusOK {
-		retryable := resp.StatusCode == http.StatusForbidden ||
-			resp.StatusCode == http.StatusTooManyRequests ||
-			resp.StatusCode >= http.StatusInternalServerError
-		return nil, retryable, fmt.Errorf("GitHub API 状态码异常: %d", resp.StatusCode)
+		retryable := resp.StatusCode >= http.StatusInternalServerError
+		return nil, 0, retryable, fmt.Errorf("GitHub API 状态码异常: %d", resp.StatusCode)
 	}
 
 	if strings.Contains(endpoint, "/releases?") {
 		var list []githubRelease
 		if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
-			return nil, false, fmt.Errorf("解析发布列表失败: %w", err)
+			return nil, 0, false, fmt.Errorf("解析发布列表失败: %w", err)
 		}
 		if len(list) == 0 {
-			return nil, false, fmt.Errorf("发布列表为空")
+			return nil, 0, false, fmt.Errorf("发布列表为空")
 		}
-		return &list[0], false, nil
+		return &list[0], 0, false, nil
 	}
 
 	var release githubRelease
 	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return nil, false, fmt.Errorf("解析发布信息失败: %w", err)
+		return nil, 0, false, fmt.Errorf("解析发布信息失败: %w", err)
+	}
+	return &release, 0, false, nil
+}
+
+// checkRateLimit 解析 403/429 响应中的限流信息。
+// 主限额耗尽时 X-RateLimit-Remaining 为 0，X-RateLimit-Reset 给出重置时间；
+// 次级限流通过 Retry-After 给出等待秒数。等待时间过长时不再重试。
+func checkRateLimit(resp *http.Response) (time.Duration, bool, error) {
+	var wait time.Duration
+	limited := false
+
+	if v := strings.TrimSpace(resp.Header.Get("Retry-After")); v != "" {
+		limited = true
+		if secs, err := strconv.Atoi(v); err == nil {
+			wait = time.Duration(secs) * time.Second
+		} else if t, err := http.ParseTime(v); err == nil {
+			wait = time.Until(t)
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		limited = true
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			if d := time.Until(time.Unix(reset, 0)); d > wait {
+				wait = d
+			}
+		}
+	}
+
+	if !limited {
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return 0, true, fmt.Errorf("%w（状态码 %d）", ErrGitHubRateLimited, resp.StatusCode)
+		}
+		return 0, false, fmt.Errorf("GitHub API 拒绝访问: %d", resp.StatusCode)
+	}
+	if wait > releaseMaxRetryWait {
+		return 0, false, fmt.Errorf("%w（约 %s 后重置）", ErrGitHubRateLimited, wait.Round(time.Second))
+	}
+	if wait < 0 {
+		wait = 0
 	}
-	return &release, false, nil
+	return wait, true, fmt.Errorf("%w（状态码 %d）", ErrGitHubRateLimited, resp.StatusCode)
 }
 
 func convertGithubRelease(release *githubRelease) *AgentReleaseInfo {
@@ -51,8 +51,17 @@ func FindMatchingAsset(assets []ReleaseAsset, serverOS, serverArch, agentType st
 	return nil
 }
 
+// SameVersion 判断两个版本号是否相同（忽略首尾空白和 v 前缀）
+func SameVersion(a, b string) bool {
+	return normalizeVersion(a) == normalizeVersion(b)
+}
+
+func normalizeVersion(v string) string {
+	return strings.TrimPrefix(strings.TrimSpace(v), "v")
+}
+
 // BuildUpgradePayload 根据服务器信息和 release 数据构建完整的升级指令 payload
-// 当 releaseInfo 可用时，会匹配对应平台的 download_url 和 sha256
+// 当 releaseInfo 可用且版本与目标一致时，会匹配对应平台的 download_url 和 sha256
 func BuildUpgradePayload(
 	server *models.Server,
 	targetVersion, channel string,
@@ -76,8 +85,9 @@ func BuildUpgradePayload(
 	}
 	payload["target_agent_type"] = agentType
 
-	// 尝试匹配 release asset 以提供 download_url 和 sha256
-	if releaseInfo != nil {
+	// 尝试匹配 release asset 以提供 download_url 和 sha256。
+	// 仅当 release 版本与目标版本一致时才附带，避免下载到与目标版本不符的安装包
+	if releaseInfo != nil && SameVersion(releaseInfo.Version, targetVersion) {
 		asset := FindMatchingAsset(releaseInfo.Assets, server.OS, server.Arch, agentType)
 		if asset != nil {
 			payload["download_url"] = asset.DownloadURL
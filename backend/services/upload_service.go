@@ -1,6 +1,8 @@
 package services
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -33,12 +35,13 @@ type UploadRequest struct {
 	Path        string // 目标目录路径
 	Filename    string // 文件名（sanitize 后填充）
 	Content     []byte // 文件内容
+	Verify      bool   // 写入后由 Agent 回读文件校验 SHA-256，仅主机上传支持
 }
 
 // AgentSender 定义向 Agent 发送上传请求的函数签名，由 controllers 注入
 type AgentSender struct {
 	// SendHostFile 向主机文件系统上传
-	SendHostFile func(serverID uint, targetPath string, content []byte) error
+	SendHostFile func(serverID uint, targetPath string, content []byte, verify bool) error
 	// SendContainerFile 向容器上传
 	SendContainerFile func(serverID uint, containerID, targetPath string, content []byte) error
 	// ValidateFilePath 校验文件路径
@@ -80,8 +83,8 @@ func (s *UploadService) Upload(req *UploadRequest) error {
 	return s.sendToAgent(req)
 }
 
-// UploadFromMultipart 从 multipart 文件构建请求并上传
-func (s *UploadService) UploadFromMultipart(target UploadTarget, serverID uint, containerID, path string, file multipart.File, header *multipart.FileHeader) error {
+// UploadFromMultipart 从 multipart 文件构建请求并上传。verify 为 true 时返回已校验的文件 SHA-256
+func (s *UploadService) UploadFromMultipart(target UploadTarget, serverID uint, containerID, path string, file multipart.File, header *multipart.FileHeader, verify bool) (string, error) {
 	// 确定大小限制
 	maxSize := MaxHostUploadSize
 	if target == TargetContainer {
@@ -90,26 +93,26 @@ func (s *UploadService) UploadFromMultipart(target UploadTarget, serverID uint,
 
 	// 校验文件大小
 	if header.Size <= 0 {
-		return fmt.Errorf("文件内容为空")
+		return "", fmt.Errorf("文件内容为空")
 	}
 	if header.Size > maxSize {
-		return fmt.Errorf("文件太大，最大允许%dMB", maxSize/1024/1024)
+		return "", fmt.Errorf("文件太大，最大允许%dMB", maxSize/1024/1024)
 	}
 
 	// 读取文件内容（使用 LimitReader 做额外保护）
 	limitedReader := io.LimitReader(file, maxSize+1)
 	content, err := io.ReadAll(limitedReader)
 	if err != nil {
-		return fmt.Errorf("读取上传文件失败: %w", err)
+		return "", fmt.Errorf("读取上传文件失败: %w", err)
 	}
 	if int64(len(content)) > maxSize {
-		return fmt.Errorf("文件太大，最大允许%dMB", maxSize/1024/1024)
+		return "", fmt.Errorf("文件太大，最大允许%dMB", maxSize/1024/1024)
 	}
 
 	// 清洁文件名
 	filename, err := s.SanitizeFilename(header.Filename)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// 构造目标路径
@@ -122,9 +125,17 @@ func (s *UploadService) UploadFromMultipart(target UploadTarget, serverID uint,
 		ContainerID: containerID,
 		Path:        targetPath,
 		Content:     content,
+		Verify:      verify,
 	}
 
-	return s.Upload(req)
+	if err := s.Upload(req); err != nil {
+		return "", err
+	}
+	if !verify {
+		return "", nil
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
 }
 
 // validateRequest 校验上传请求
@@ -150,6 +161,9 @@ func (s *UploadService) validateRequest(req *UploadRequest) error {
 	if req.Target == TargetContainer && strings.TrimSpace(req.ContainerID) == "" {
 		return fmt.Errorf("容器ID不能为空")
 	}
+	if req.Target == TargetContainer && req.Verify {
+		return fmt.Errorf("容器上传暂不支持完整性校验")
+	}
 
 	return nil
 }
@@ -177,7 +191,7 @@ func (s *UploadService) sendToAgent(req *UploadRequest) error {
 		if s.sender.SendHostFile == nil {
 			return fmt.Errorf("主机文件上传功能未注册")
 		}
-		return s.sender.SendHostFile(req.ServerID, req.Path, req.Content)
+		return s.sender.SendHostFile(req.ServerID, req.Path, req.Content, req.Verify)
 
 	case TargetContainer:
 		if s.sender.SendContainerFile == nil {
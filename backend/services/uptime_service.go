@@ -0,0 +1,464 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/user/server-ops-backend/models"
+)
+
+// 全局UptimeService实例
+var (
+	globalUptimeService *UptimeService
+	uptimeServiceOnce   sync.Once
+)
+
+// uptimeErrorLimit 检查结果中错误信息的最大长度，与数据库字段长度一致
+const uptimeErrorLimit = 500
+
+// uptimeTLSChainLimit 保存的 tls 证书链最多包含的证书数
+const uptimeTLSChainLimit = 10
+
+// pingTimePattern 从 ping 输出中提取往返时间，例如 "time=12.3 ms"
+var pingTimePattern = regexp.MustCompile(`time[=<]([0-9.]+)\s*ms`)
+
+// uptimeStaleSources 执行位置超过多少个检查间隔没有上报结果后不再参与判断（例如 Agent 离线或取消了分配）
+const uptimeStaleSources = 3
+
+// uptimeSourceState 单个执行位置的连续失败状态
+type uptimeSourceState struct {
+	failures  int
+	lastError string
+	updatedAt time.Time
+}
+
+// UptimeService 可用性检查服务，由面板后端定时执行 HTTP/TCP/ICMP 检查，
+// 并汇总分配给 Agent 执行的检查结果
+type UptimeService struct {
+	stopChan chan struct{}
+	mu       sync.Mutex
+	running  map[uint]bool      // 正在执行的检查，避免同一检查并发执行
+	lastRun  map[uint]time.Time // 面板上次执行检查的时间
+
+	stateMu sync.Mutex
+	sources map[uint]map[uint]*uptimeSourceState // 格式: map[checkID]map[serverID]状态，serverID 0 表示面板
+}
+
+// NewUptimeService 创建可用性检查服务
+func NewUptimeService() *UptimeService {
+	return &UptimeService{
+		stopChan: make(chan struct{}),
+		running:  make(map[uint]bool),
+		lastRun:  make(map[uint]time.Time),
+		sources:  make(map[uint]map[uint]*uptimeSourceState),
+	}
+}
+
+// GetUptimeService 获取全局可用性检查服务实例
+func GetUptimeService() *UptimeService {
+	uptimeServiceOnce.Do(func() {
+		globalUptimeService = NewUptimeService()
+	})
+	return globalUptimeService
+}
+
+// Start 启动可用性检查服务，每 5 秒执行一次到期的检查
+func (s *UptimeService) Start() {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	log.Println("可用性检查服务已启动")
+
+	for {
+		select {
+		case <-ticker.C:
+			s.runDueChecks()
+		case <-s.stopChan:
+			log.Println("可用性检查服务已停止")
+			return
+		}
+	}
+}
+
+// Stop 停止可用性检查服务
+func (s *UptimeService) Stop() {
+	close(s.stopChan)
+}
+
+// runDueChecks 并发执行所有到期的检查，只由 Agent 执行的检查跳过
+func (s *UptimeService) runDueChecks() {
+	checks, err := models.GetEnabledUptimeChecks()
+	if err != nil {
+		log.Printf("获取可用性检查失败: %v", err)
+		return
+	}
+	now := time.Now()
+	for _, check := range checks {
+		if check.AgentOnly {
+			continue
+		}
+		s.mu.Lock()
+		last := s.lastRun[check.ID]
+		s.mu.Unlock()
+		if last.IsZero() || !now.Before(last.Add(time.Duration(check.Interval)*time.Second)) {
+			go s.RunCheck(check)
+		}
+	}
+}
+
+// acquire 标记检查开始执行，已在执行时返回 false
+func (s *UptimeService) acquire(id uint) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[id] {
+		return false
+	}
+	s.running[id] = true
+	s.lastRun[id] = time.Now()
+	return true
+}
+
+func (s *UptimeService) release(id uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, id)
+}
+
+// Forget 删除检查时清理其执行状态
+func (s *UptimeService) Forget(checkID uint) {
+	s.mu.Lock()
+	delete(s.lastRun, checkID)
+	s.mu.Unlock()
+	s.stateMu.Lock()
+	delete(s.sources, checkID)
+	s.stateMu.Unlock()
+}
+
+// RunCheck 由面板立即执行一次检查并记录结果。同一检查正在执行时返回 false
+func (s *UptimeService) RunCheck(check models.UptimeCheck) (models.UptimeResult, bool) {
+	if !s.acquire(check.ID) {
+		return models.UptimeResult{}, false
+	}
+	defer s.release(check.ID)
+
+	result := ProbeUptimeCheck(check)
+	s.recordResult(result, "面板")
+	return result, true
+}
+
+// RecordAgentResults 保存 Agent 随监控数据上报的检查结果，忽略未分配给该服务器的检查
+func (s *UptimeService) RecordAgentResults(server models.Server, results []models.UptimeResult) {
+	for _, result := range results {
+		check, err := models.GetUptimeCheck(result.CheckID)
+		if err != nil || !check.AssignedTo(server.ID) {
+			continue
+		}
+		result.ServerID = server.ID
+		result.Error = truncateRunes(result.Error, uptimeErrorLimit)
+		if check.Type != models.UptimeCheckTLS {
+			result.TLS = nil
+		} else if result.TLS != nil && len(result.TLS.Chain) > uptimeTLSChainLimit {
+			result.TLS.Chain = result.TLS.Chain[:uptimeTLSChainLimit]
+		}
+		s.recordResult(result, server.Name)
+	}
+}
+
+// recordResult 保存检查结果并更新检查状态。每个执行位置分别计算连续失败次数，
+// 任一位置连续失败达到阈值时告警，所有位置都恢复后解除告警
+func (s *UptimeService) recordResult(result models.UptimeResult, source string) {
+	if err := models.CreateUptimeResult(&result); err != nil {
+		log.Printf("保存可用性检查 %d 的结果失败: %v", result.CheckID, err)
+	}
+
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	// 重新读取检查，拿到最新的配置和告警状态
+	check, err := models.GetUptimeCheck(result.CheckID)
+	if err != nil {
+		return
+	}
+
+	states := s.sources[check.ID]
+	if states == nil {
+		states = make(map[uint]*uptimeSourceState)
+		s.sources[check.ID] = states
+	}
+	state := states[result.ServerID]
+	if state == nil {
+		state = &uptimeSourceState{}
+		states[result.ServerID] = state
+	}
+	state.updatedAt = time.Now()
+	if result.Success {
+		state.failures = 0
+		state.lastError = ""
+	} else {
+		state.failures++
+		state.lastError = fmt.Sprintf("[%s] %s", source, result.Error)
+	}
+
+	// 只统计仍在执行该检查、且最近有上报的位置
+	staleBefore := time.Now().Add(-time.Duration(check.Interval*uptimeStaleSources) * time.Second)
+	worst := &uptimeSourceState{}
+	for serverID, st := range states {
+		active := (serverID == 0 && !check.AgentOnly) || (serverID != 0 && check.AssignedTo(serverID))
+		if serverID == result.ServerID {
+			active = true // 面板手动执行的结果同样计入
+		}
+		if !active || st.updatedAt.Before(staleBefore) {
+			delete(states, serverID)
+			continue
+		}
+		if st.failures > worst.failures {
+			worst = st
+		}
+	}
+
+	check.LastCheckedAt = result.Timestamp
+	check.LastResponseMs = result.ResponseTimeMs
+	if result.TLS != nil {
+		result.TLS.ServerID = result.ServerID
+		result.TLS.CheckedAt = result.Timestamp
+		check.SetLastTLS(result.TLS)
+	}
+	check.LastError = truncateRunes(worst.lastError, uptimeErrorLimit)
+	check.ConsecutiveFailures = worst.failures
+	if worst.failures == 0 {
+		if check.AlertRecordID != 0 {
+			GetAlertService().NotifyUptimeRecovered(check.AlertRecordID, result.ResponseTimeMs)
+			check.AlertRecordID = 0
+		}
+		check.LastStatus = "up"
+	} else {
+		check.LastStatus = "down"
+		if check.AlertRecordID == 0 && worst.failures >= check.FailureThreshold {
+			log.Printf("可用性检查 %s 连续失败 %d 次: %s", check.Name, worst.failures, worst.lastError)
+			check.AlertRecordID = GetAlertService().NotifyUptimeDown(*check, worst.lastError)
+		}
+	}
+	if err := models.UpdateUptimeCheckState(check); err != nil {
+		log.Printf("更新可用性检查 %s 的状态失败: %v", check.Name, err)
+	}
+}
+
+// ProbeUptimeCheck 执行一次检查并返回结果，不保存。
+// 所有连接都经过探测目标策略检查，禁止的目标直接判定为失败
+func ProbeUptimeCheck(check models.UptimeCheck) models.UptimeResult {
+	result := models.UptimeResult{CheckID: check.ID, Timestamp: time.Now()}
+
+	timeout := time.Duration(check.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = models.DefaultUptimeTimeout * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	policy, err := models.GetProbeTargetPolicy()
+	if err != nil {
+		result.Error = fmt.Sprintf("读取探测目标策略失败: %v", err)
+		return result
+	}
+
+	var elapsed time.Duration
+	switch check.Type {
+	case models.UptimeCheckHTTP:
+		result.StatusCode, elapsed, err = probeHTTP(ctx, policy, check)
+	case models.UptimeCheckTCP:
+		elapsed, err = probeTCP(ctx, policy, check.Target)
+	case models.UptimeCheckICMP:
+		elapsed, err = probeICMP(ctx, policy, check.Target, timeout)
+	case models.UptimeCheckTLS:
+		result.TLS, elapsed, err = probeTLS(ctx, policy, check)
+	default:
+		err = fmt.Errorf("不支持的检查类型: %s", check.Type)
+	}
+
+	result.ResponseTimeMs = elapsed.Milliseconds()
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("超过 %d 秒未响应", int(timeout/time.Second))
+		}
+		result.Error = truncateRunes(err.Error(), uptimeErrorLimit)
+		return result
+	}
+	result.Success = true
+	return result
+}
+
+// probeDialer 返回连接前检查目标地址的 Dialer，防止解析到被禁止的地址（DNS 重绑定）
+func probeDialer(policy *models.ProbeTargetPolicy) *net.Dialer {
+	return &net.Dialer{Control: policy.DialControl}
+}
+
+// probeHTTP 发起 GET 请求，返回状态码和收到响应头的耗时；状态码不符合期望时返回错误
+func probeHTTP(ctx context.Context, policy *models.ProbeTargetPolicy, check models.UptimeCheck) (int, time.Duration, error) {
+	transport := &http.Transport{
+		Proxy:             nil,
+		DialContext:       probeDialer(policy).DialContext,
+		DisableKeepAlives: true,
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{Transport: transport}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, check.Target, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("User-Agent", "BetterMonitor-Uptime/1.0")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	elapsed := time.Since(start)
+	if err != nil {
+		return 0, elapsed, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if check.ExpectedStatus != 0 {
+		if resp.StatusCode != check.ExpectedStatus {
+			return resp.StatusCode, elapsed, fmt.Errorf("状态码 %d，期望 %d", resp.StatusCode, check.ExpectedStatus)
+		}
+	} else if resp.StatusCode >= 400 {
+		return resp.StatusCode, elapsed, fmt.Errorf("状态码 %d", resp.StatusCode)
+	}
+	return resp.StatusCode, elapsed, nil
+}
+
+// probeTCP 建立 TCP 连接，返回连接耗时
+func probeTCP(ctx context.Context, policy *models.ProbeTargetPolicy, target string) (time.Duration, error) {
+	start := time.Now()
+	conn, err := probeDialer(policy).DialContext(ctx, "tcp", target)
+	elapsed := time.Since(start)
+	if err != nil {
+		return elapsed, err
+	}
+	conn.Close()
+	return elapsed, nil
+}
+
+// probeTLS 建立 TLS 连接，返回握手得到的协议、密码套件和证书链以及连接加握手的耗时。
+// 证书链或主机名校验失败、证书已过期或剩余天数不足时返回错误，握手成功时仍返回证书信息
+func probeTLS(ctx context.Context, policy *models.ProbeTargetPolicy, check models.UptimeCheck) (*models.TLSEndpointInfo, time.Duration, error) {
+	host, _, err := net.SplitHostPort(check.Target)
+	if err != nil {
+		return nil, 0, err
+	}
+	serverName := check.TLSServerName
+	if serverName == "" {
+		serverName = host
+	}
+
+	start := time.Now()
+	rawConn, err := probeDialer(policy).DialContext(ctx, "tcp", check.Target)
+	if err != nil {
+		return nil, time.Since(start), err
+	}
+	defer rawConn.Close()
+	// 证书在握手后单独校验，校验失败时仍能拿到证书链；IP 地址不能作为 SNI 发送
+	config := &tls.Config{InsecureSkipVerify: true}
+	if net.ParseIP(serverName) == nil {
+		config.ServerName = serverName
+	}
+	conn := tls.Client(rawConn, config)
+	err = conn.HandshakeContext(ctx)
+	elapsed := time.Since(start)
+	if err != nil {
+		return nil, elapsed, fmt.Errorf("TLS 握手失败: %v", err)
+	}
+
+	state := conn.ConnectionState()
+	now := time.Now()
+	info := &models.TLSEndpointInfo{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ServerName:  serverName,
+		CheckedAt:   now,
+	}
+	for _, cert := range state.PeerCertificates {
+		sum := sha256.Sum256(cert.Raw)
+		info.Chain = append(info.Chain, models.TLSCertificateInfo{
+			Subject:      cert.Subject.String(),
+			Issuer:       cert.Issuer.String(),
+			DNSNames:     cert.DNSNames,
+			SerialNumber: cert.SerialNumber.Text(16),
+			Fingerprint:  hex.EncodeToString(sum[:]),
+			NotBefore:    cert.NotBefore,
+			NotAfter:     cert.NotAfter,
+		})
+	}
+	if len(state.PeerCertificates) == 0 {
+		return info, elapsed, errors.New("服务器没有提供证书")
+	}
+
+	leaf := state.PeerCertificates[0]
+	if !check.TLSSkipVerify {
+		intermediates := x509.NewCertPool()
+		for _, cert := range state.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: serverName, Intermediates: intermediates}); err != nil {
+			info.VerifyError = err.Error()
+			return info, elapsed, fmt.Errorf("证书校验失败: %v", err)
+		}
+	}
+	if now.After(leaf.NotAfter) {
+		return info, elapsed, fmt.Errorf("证书已于 %s 过期", leaf.NotAfter.Local().Format("2006-01-02 15:04"))
+	}
+	if now.Before(leaf.NotBefore) {
+		return info, elapsed, fmt.Errorf("证书在 %s 之后才生效", leaf.NotBefore.Local().Format("2006-01-02 15:04"))
+	}
+	if check.CertMinDays > 0 {
+		if left := int(leaf.NotAfter.Sub(now).Hours() / 24); left < check.CertMinDays {
+			return info, elapsed, fmt.Errorf("证书 %d 天后过期（%s），少于 %d 天",
+				left, leaf.NotAfter.Local().Format("2006-01-02"), check.CertMinDays)
+		}
+	}
+	return info, elapsed, nil
+}
+
+// probeICMP 调用系统 ping 发送一个 ICMP 请求，后端无需 root 或 CAP_NET_RAW 权限。
+// 先解析并检查目标地址，再直接 ping 该地址
+func probeICMP(ctx context.Context, policy *models.ProbeTargetPolicy, target string, timeout time.Duration) (time.Duration, error) {
+	ip, err := policy.ResolvePingTarget(ctx, target)
+	if err != nil {
+		return 0, err
+	}
+
+	waitSeconds := int(timeout / time.Second)
+	if waitSeconds < 1 {
+		waitSeconds = 1
+	}
+	start := time.Now()
+	output, err := exec.CommandContext(ctx, "ping", "-c", "1", "-W", strconv.Itoa(waitSeconds), ip.String()).CombinedOutput()
+	elapsed := time.Since(start)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return elapsed, fmt.Errorf("%s 无响应", ip)
+		}
+		return elapsed, fmt.Errorf("执行 ping 失败: %v", err)
+	}
+	if m := pingTimePattern.FindSubmatch(output); m != nil {
+		if ms, err := strconv.ParseFloat(string(m[1]), 64); err == nil {
+			return time.Duration(ms * float64(time.Millisecond)), nil
+		}
+	}
+	return elapsed, nil
+}
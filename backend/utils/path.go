@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"fmt"
+	pathpkg "path"
+	"regexp"
+	"strings"
+)
+
+// Windows 盘符绝对路径，例如 C:\ 或 D:/data
+var windowsAbsPathPattern = regexp.MustCompile(`^[A-Za-z]:[\\/]`)
+
+// NormalizeAgentPath 校验并规范化下发给 Agent 的文件路径（主机或容器内路径）。
+// 规则与 Agent 端 FileManager/ContainerFileManager 保持一致：
+// 拒绝空路径和空字节、拒绝任何 ".." 路径段（在 Clean 之前检查，防止被规范化掉）、
+// 要求绝对路径；POSIX 路径会被 Clean，Windows 盘符路径仅统一分隔符。
+func NormalizeAgentPath(p string) (string, error) {
+	if p == "" {
+		return "", fmt.Errorf("路径不能为空")
+	}
+	if strings.ContainsRune(p, 0) {
+		return "", fmt.Errorf("路径包含空字节")
+	}
+
+	// 同时按 / 和 \ 拆分，避免通过 Windows 分隔符绕过检查
+	for _, seg := range strings.FieldsFunc(p, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if seg == ".." {
+			return "", fmt.Errorf("路径包含非法的上级目录引用")
+		}
+	}
+
+	if windowsAbsPathPattern.MatchString(p) {
+		return strings.ReplaceAll(p, "\\", "/"), nil
+	}
+	if !strings.HasPrefix(p, "/") {
+		return "", fmt.Errorf("必须使用绝对路径")
+	}
+	return pathpkg.Clean(p), nil
+}
+
+// PathWithinRoot 判断已规范化的路径是否位于 root 目录内（含 root 本身）
+func PathWithinRoot(root, p string) bool {
+	root = pathpkg.Clean(root)
+	if root == "/" {
+		return strings.HasPrefix(p, "/")
+	}
+	return p == root || strings.HasPrefix(p, root+"/")
+}
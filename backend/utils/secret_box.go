@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+)
+
+// ErrSecretBoxOpen 密文无法解密：密钥不一致或数据已被篡改
+var ErrSecretBoxOpen = errors.New("密钥错误或数据已损坏")
+
+// SecretBox 使用 AES-256-GCM 加密敏感字段，密文为 base64(nonce || ciphertext)。
+// 镜像仓库凭据和配置导出中的密钥都使用它加密，只是密钥来源不同
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox 使用 32 字节的密钥创建 SecretBox
+func NewSecretBox(key []byte) (*SecretBox, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// Seal 加密明文，每次使用随机 nonce
+func (b *SecretBox) Seal(plain string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b.aead.Seal(nonce, nonce, []byte(plain), nil)), nil
+}
+
+// Open 解密 Seal 生成的密文，密钥不一致或数据被篡改时返回 ErrSecretBoxOpen
+func (b *SecretBox) Open(sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return "", errors.New("密文格式错误")
+	}
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	plain, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrSecretBoxOpen
+	}
+	return string(plain), nil
+}
@@ -15,6 +15,10 @@ import (
 // 这里假设有一个导出的函数可以获取agent连接
 var GetAgentConnectionFunc func(serverID uint) (*websocket.Conn, error)
 
+// SendToAgentFunc 通过Agent主动建立的反向WebSocket连接发送消息。
+// 由controllers包注册，内部使用连接自带的写锁，避免与其他发送方并发写入同一连接。
+var SendToAgentFunc func(serverID uint, message []byte) error
+
 var (
 	// 缓存WebSocket连接 - 保留但逐步废弃
 	wsConnections = make(map[uint]*websocket.Conn)
@@ -29,29 +33,6 @@ func SendCommandToAgent(serverID uint, secretKey string, data map[string]interfa
 	data["server_id"] = serverID
 	data["secret_key"] = secretKey
 
-	// 获取WebSocket连接 - 优先使用新的连接池
-	var wsConn *websocket.Conn
-	var err error
-
-	// 如果有设置GetAgentConnectionFunc，优先使用它
-	if GetAgentConnectionFunc != nil {
-		log.Printf("[DEBUG] 尝试从新的连接池获取服务器 %d 的连接", serverID)
-		wsConn, err = GetAgentConnectionFunc(serverID)
-		if err != nil {
-			log.Printf("[WARN] 从新的连接池获取服务器 %d 的连接失败: %v，尝试旧连接池", serverID, err)
-		}
-	}
-
-	// 如果通过新池没有获取到连接，回退到旧池
-	if wsConn == nil {
-		log.Printf("[DEBUG] 尝试从旧的连接池获取服务器 %d 的连接", serverID)
-		wsConn, err = getAgentConnection(serverID)
-		if err != nil {
-			log.Printf("[ERROR] 获取服务器 %d 的WebSocket连接失败: %v", serverID, err)
-			return "", fmt.Errorf("无法获取代理连接: %v", err)
-		}
-	}
-
 	// 生成请求ID
 	requestID := fmt.Sprintf("%d-%d", serverID, time.Now().UnixNano())
 	data["request_id"] = requestID
@@ -75,11 +56,9 @@ func SendCommandToAgent(serverID uint, secretKey string, data map[string]interfa
 
 	log.Printf("[DEBUG] 已注册请求 %s 的响应处理器", requestID)
 
-	// 发送命令
-	wsConnMutex.Lock()
-	err = wsConn.WriteMessage(websocket.TextMessage, cmdData)
-	wsConnMutex.Unlock()
-	if err != nil {
+	// 发送命令：所有命令都经由Agent主动建立的WebSocket连接下发，
+	// 面板从不主动连接Agent，因此Agent无需开放任何入站端口
+	if err := sendToAgent(serverID, cmdData); err != nil {
 		log.Printf("[ERROR] 向服务器 %d 发送命令失败: %v", serverID, err)
 		return "", fmt.Errorf("发送命令失败: %v", err)
 	}
@@ -100,6 +79,27 @@ func SendCommandToAgent(serverID uint, secretKey string, data map[string]interfa
 	}
 }
 
+// sendToAgent 通过反向WebSocket连接发送消息，优先使用controllers注册的连接池
+func sendToAgent(serverID uint, message []byte) error {
+	if SendToAgentFunc != nil {
+		err := SendToAgentFunc(serverID, message)
+		if err == nil {
+			return nil
+		}
+		log.Printf("[WARN] 通过连接池向服务器 %d 发送消息失败: %v，尝试旧连接池", serverID, err)
+	}
+
+	wsConn, err := getAgentConnection(serverID)
+	if err != nil {
+		log.Printf("[ERROR] 获取服务器 %d 的WebSocket连接失败: %v", serverID, err)
+		return fmt.Errorf("无法获取代理连接: %v", err)
+	}
+
+	wsConnMutex.Lock()
+	defer wsConnMutex.Unlock()
+	return wsConn.WriteMessage(websocket.TextMessage, message)
+}
+
 // 响应处理器映射
 var (
 	responseHandlers      = make(map[string]chan string)
@@ -52,6 +52,8 @@ export interface UploadOptions {
   file: File
   /** 进度回调 */
   onProgress?: (percent: number) => void
+  /** 写入后由 Agent 回读文件校验 SHA-256（仅主机上传支持） */
+  verify?: boolean
 }
 
 /** 上传状态 */
@@ -149,6 +151,9 @@ export function useFileUpload() {
     const formData = new FormData()
     formData.append('file', options.file)
     formData.append('path', options.targetPath)
+    if (options.verify && !options.containerId) {
+      formData.append('verify', 'true')
+    }
 
     const endpoint = buildEndpoint(options.serverId, options.containerId)
 
@@ -339,7 +344,7 @@ export function useFileUpload() {
     // 3. 请求合并
     await request.post(
       `/servers/${options.serverId}/files/upload/chunked/${uploadId}/complete`,
-      {},
+      { verify: !!options.verify && !options.containerId },
       { signal: abortController!.signal, timeout: 120000 },
     )
 
@@ -213,6 +213,46 @@ const routes: Array<RouteRecordRaw> = [
           admin: true,
         },
       },
+      {
+        path: 'uptime',
+        name: 'UptimeChecks',
+        component: () => import('../views/uptime/UptimeChecks.vue'),
+        meta: {
+          title: '可用性检查',
+          requiresAuth: true,
+          manualLoading: true,
+        },
+      },
+      {
+        path: 'mesh',
+        name: 'MeshLatency',
+        component: () => import('../views/mesh/MeshLatency.vue'),
+        meta: {
+          title: '节点互测',
+          requiresAuth: true,
+          manualLoading: true,
+        },
+      },
+      {
+        path: 'logs',
+        name: 'LogSearch',
+        component: () => import('../views/logs/LogSearch.vue'),
+        meta: {
+          title: '日志搜索',
+          requiresAuth: true,
+          manualLoading: true,
+        },
+      },
+      {
+        path: 'certificates',
+        name: 'CertificateExpiry',
+        component: () => import('../views/certificates/CertificateExpiry.vue'),
+        meta: {
+          title: '证书到期',
+          requiresAuth: true,
+          manualLoading: true,
+        },
+      },
       {
         path: 'alerts/settings',
         name: 'AlertSettings',
@@ -243,6 +283,36 @@ const routes: Array<RouteRecordRaw> = [
           manualLoading: true,
         },
       },
+      {
+        path: 'alerts/rules',
+        name: 'AlertRules',
+        component: () => import('../views/server/AlertRules.vue'),
+        meta: {
+          title: '预警规则',
+          requiresAuth: true,
+          manualLoading: true,
+        },
+      },
+      {
+        path: 'alerts/maintenance',
+        name: 'MaintenanceWindows',
+        component: () => import('../views/server/MaintenanceWindows.vue'),
+        meta: {
+          title: '维护窗口',
+          requiresAuth: true,
+          manualLoading: true,
+        },
+      },
+      {
+        path: 'alerts/incidents',
+        name: 'Incidents',
+        component: () => import('../views/server/Incidents.vue'),
+        meta: {
+          title: '事件',
+          requiresAuth: true,
+          manualLoading: true,
+        },
+      },
     ],
   },
   {
@@ -7,8 +7,10 @@ interface AlertSetting {
   type: string;
   threshold: number;
   duration: number;
+  smoothing: number;
   enabled: boolean;
   server_id: number;
+  channel_ids: string;
   created_at: string;
   updated_at: string;
 }
@@ -19,6 +21,7 @@ interface NotificationChannel {
   name: string;
   config: string;
   enabled: boolean;
+  categories: string; // 接收的预警分类，逗号分隔，为空表示全部
   created_at: string;
   updated_at: string;
 }
@@ -28,16 +31,30 @@ interface AlertRecord {
   server_id: number;
   server_name: string;
   alert_type: string;
+  category: string;
   value: number;
   threshold: number;
   resolved: boolean;
   resolved_at: string;
   notified_at: string;
   channel_ids: string;
+  acknowledged: boolean;
+  acknowledged_at: string;
+  acknowledged_by: string;
+  escalation_policy_id: number;
   created_at: string;
   updated_at: string;
 }
 
+// 预警分类，与后端 models.AlertCategories 保持一致
+export const alertCategoryOptions = [
+  { value: 'resource', label: '资源指标', color: 'blue' },
+  { value: 'availability', label: '在线状态', color: 'purple' },
+  { value: 'system', label: '系统事件', color: 'magenta' },
+  { value: 'security', label: '安全', color: 'volcano' },
+  { value: 'certificate', label: '证书', color: 'cyan' },
+];
+
 // 后端API响应类型
 interface ApiResponse<T> {
   [key: string]: any;
@@ -73,6 +90,7 @@ export const useAlertStore = defineStore('alert', {
           type: setting.type,
           threshold: setting.threshold,
           duration: setting.duration,
+          smoothing: setting.smoothing || 0,
           enabled: setting.enabled,
           server_id: setting.server_id,
           created_at: setting.CreatedAt,
@@ -99,6 +117,7 @@ export const useAlertStore = defineStore('alert', {
           type: (response as any).setting.type,
           threshold: (response as any).setting.threshold,
           duration: (response as any).setting.duration,
+          smoothing: (response as any).setting.smoothing || 0,
           enabled: (response as any).setting.enabled,
           server_id: (response as any).setting.server_id,
           created_at: (response as any).setting.CreatedAt,
@@ -123,6 +142,7 @@ export const useAlertStore = defineStore('alert', {
           type: (response as any).setting.type,
           threshold: (response as any).setting.threshold,
           duration: (response as any).setting.duration,
+          smoothing: (response as any).setting.smoothing || 0,
           enabled: (response as any).setting.enabled,
           server_id: (response as any).setting.server_id,
           created_at: (response as any).setting.CreatedAt,
@@ -163,6 +183,7 @@ export const useAlertStore = defineStore('alert', {
           name: channel.name,
           config: channel.config,
           enabled: channel.enabled,
+          categories: channel.categories || '',
           created_at: channel.CreatedAt,
           updated_at: channel.UpdatedAt
         }));
@@ -189,6 +210,7 @@ export const useAlertStore = defineStore('alert', {
           name: (response as any).channel.name,
           config: (response as any).channel.config,
           enabled: (response as any).channel.enabled,
+          categories: (response as any).channel.categories || '',
           created_at: (response as any).channel.CreatedAt,
           updated_at: (response as any).channel.UpdatedAt
         } : null;
@@ -214,6 +236,7 @@ export const useAlertStore = defineStore('alert', {
           name: (response as any).channel.name,
           config: (response as any).channel.config,
           enabled: (response as any).channel.enabled,
+          categories: (response as any).channel.categories || '',
           created_at: (response as any).channel.CreatedAt,
           updated_at: (response as any).channel.UpdatedAt
         } : null;
@@ -260,10 +283,22 @@ export const useAlertStore = defineStore('alert', {
       }
     },
     
+    // 发送模拟预警，返回每个通知渠道的投递结果
+    async simulateAlert(params: { alert_type: string; channel_ids?: number[]; server_id?: number }) {
+      try {
+        return await request.post('/alerts/simulate', params) as any;
+      } catch (error: any) {
+        console.error('发送模拟预警失败:', error);
+        message.error(error.response?.data?.error || '发送模拟预警失败');
+        throw error;
+      }
+    },
+    
     // 获取预警记录
     async fetchAlertRecords(params: { 
       server_id?: number;
       type?: string;
+      category?: string;
       unresolved?: boolean;
       page?: number;
       limit?: number;
@@ -282,12 +317,17 @@ export const useAlertStore = defineStore('alert', {
           server_id: record.server_id,
           server_name: record.server_name,
           alert_type: record.alert_type,
+          category: record.category,
           value: record.value,
           threshold: record.threshold,
           resolved: record.resolved,
           resolved_at: record.resolved_at || record.ResolvedAt,
           notified_at: record.notified_at || record.NotifiedAt,
           channel_ids: record.channel_ids,
+          acknowledged: record.acknowledged,
+          acknowledged_at: record.acknowledged_at,
+          acknowledged_by: record.acknowledged_by,
+          escalation_policy_id: record.escalation_policy_id,
           created_at: record.CreatedAt,
           updated_at: record.UpdatedAt
         }));
@@ -314,12 +354,17 @@ export const useAlertStore = defineStore('alert', {
           server_id: (response as any).record.server_id,
           server_name: (response as any).record.server_name,
           alert_type: (response as any).record.alert_type,
+          category: (response as any).record.category,
           value: (response as any).record.value,
           threshold: (response as any).record.threshold,
           resolved: (response as any).record.resolved,
           resolved_at: (response as any).record.resolved_at || (response as any).record.ResolvedAt,
           notified_at: (response as any).record.notified_at || (response as any).record.NotifiedAt,
           channel_ids: (response as any).record.channel_ids,
+          acknowledged: (response as any).record.acknowledged,
+          acknowledged_at: (response as any).record.acknowledged_at,
+          acknowledged_by: (response as any).record.acknowledged_by,
+          escalation_policy_id: (response as any).record.escalation_policy_id,
           created_at: (response as any).record.CreatedAt,
           updated_at: (response as any).record.UpdatedAt
         } : null;
@@ -339,5 +384,28 @@ export const useAlertStore = defineStore('alert', {
         throw error;
       }
     },
+
+    // 确认预警记录，停止升级通知
+    async acknowledgeAlertRecord(id: number) {
+      try {
+        const response = await request.put<ApiResponse<{ record: AlertRecord }>>(`/alerts/records/${id}/ack`);
+        message.success('已确认，停止升级通知');
+
+        const index = this.alertRecords.findIndex(r => r.id === id);
+        const record = (response as any).record;
+        if (index !== -1 && record) {
+          this.alertRecords[index] = {
+            ...this.alertRecords[index],
+            acknowledged: record.acknowledged,
+            acknowledged_at: record.acknowledged_at,
+            acknowledged_by: record.acknowledged_by,
+          };
+        }
+      } catch (error: any) {
+        console.error('确认预警记录失败:', error);
+        message.error(error.response?.data?.error || '确认预警记录失败');
+        throw error;
+      }
+    },
   },
 }); 
\ No newline at end of file
@@ -23,6 +23,8 @@ interface ServerState {
   tags?: string;
   system_info?: any;
   sort_order?: number;
+  display_order?: number; // 服务器列表接口按当前用户偏好返回的位置
+  favorite?: boolean; // 当前用户是否收藏
   agent_type?: string; // Agent类型: "full" 或 "monitor"
   // 可选：最新的监控数据
   monitorData?: {
@@ -72,6 +74,12 @@ export const useServerStore = defineStore('serverStore', {
     getAllServers: (state) => {
       // 将对象转换为数组，并按 sort_order 排序
       return Object.values(state.servers).sort((a, b) => {
+        // 优先使用服务器列表接口返回的顺序（已包含当前用户的收藏和自定义顺序）
+        const displayA = a.display_order ?? Number.MAX_SAFE_INTEGER;
+        const displayB = b.display_order ?? Number.MAX_SAFE_INTEGER;
+        if (displayA !== displayB) {
+          return displayA - displayB;
+        }
         const orderA = a.sort_order || 0;
         const orderB = b.sort_order || 0;
         if (orderA !== orderB) {
@@ -191,7 +199,7 @@ export const useServerStore = defineStore('serverStore', {
           const currentIds = new Set<number>();
 
           // 处理服务器数据
-          responseData.servers.forEach((server: any) => {
+          responseData.servers.forEach((server: any, index: number) => {
             const serverId = server.ID || server.id;
             currentIds.add(serverId);
 
@@ -218,6 +226,8 @@ export const useServerStore = defineStore('serverStore', {
               secret_key: server.secret_key || server.SecretKey || existingServer?.secret_key,
               secretKey: server.secret_key || server.SecretKey || existingServer?.secretKey,
               sort_order: server.SortOrder || server.sort_order || 0,
+              display_order: index,
+              favorite: !!server.favorite,
               agent_type: server.AgentType || server.agent_type || 'full',
               lastUpdate: Date.now(),
               // 确保 monitorData 不被覆盖为空
@@ -284,24 +294,24 @@ export const useServerStore = defineStore('serverStore', {
       }
     },
 
-    // 批量更新服务器顺序
-    async reorderServers(orderedIds: number[]) {
+    // 批量更新服务器顺序；personal 为 true 时只保存当前用户的自定义顺序
+    async reorderServers(orderedIds: number[], personal = false) {
       try {
         console.log('[Store] 正在更新服务器顺序:', orderedIds);
 
         // 调用API更新顺序
-        await request.put('/servers/reorder', {
+        await request.put(personal ? '/servers/my-order' : '/servers/reorder', {
           orderedIds: orderedIds
         });
 
-        // 更新本地状态中的 sort_order
+        // 更新本地状态中的显示顺序
         orderedIds.forEach((serverId, index) => {
-          if (this.servers[serverId]) {
-            // TypeScript需要我们先读取再修改
-            const server = this.servers[serverId];
-            // @ts-ignore - 动态添加 sort_order 字段
+          const server = this.servers[serverId];
+          if (!server) return;
+          if (!personal) {
             server.sort_order = index + 1;
           }
+          server.display_order = index;
         });
 
         console.log('[Store] 服务器顺序更新成功');
@@ -309,6 +319,21 @@ export const useServerStore = defineStore('serverStore', {
         console.error('[Store] 更新服务器顺序失败:', error);
         throw error;
       }
+    },
+
+    // 清除当前用户的自定义顺序（保留收藏）
+    async resetMyServerOrder() {
+      await request.delete('/servers/my-order');
+      await this.fetchServers(true);
+    },
+
+    // 收藏或取消收藏服务器，收藏的服务器在列表中置顶
+    async setServerFavorite(serverId: number, favorite: boolean) {
+      await request.put(`/servers/${serverId}/favorite`, { favorite });
+      if (this.servers[serverId]) {
+        this.servers[serverId].favorite = favorite;
+      }
+      await this.fetchServers(true);
     }
   }
 });
@@ -0,0 +1,30 @@
+// 维护期间的监控数据在图表中以橙色背景标出
+
+interface MaintenancePoint {
+  time: string;
+  maintenance?: boolean;
+}
+
+// maintenanceMarkArea 把连续的维护数据点合并为 ECharts markArea 区间
+export function maintenanceMarkArea(points: MaintenancePoint[]) {
+  const areas: { xAxis: string }[][] = [];
+  let start: string | null = null;
+  points.forEach((point, index) => {
+    if (point.maintenance && start === null) {
+      start = point.time;
+    }
+    const last = index === points.length - 1;
+    if (start !== null && (!point.maintenance || last)) {
+      const end = point.maintenance ? point.time : points[index - 1].time;
+      areas.push([{ xAxis: start }, { xAxis: end }]);
+      start = null;
+    }
+  });
+
+  return {
+    silent: true,
+    itemStyle: { color: 'rgba(250, 173, 20, 0.15)' },
+    label: { show: true, position: 'insideTop', color: '#fa8c16', formatter: '维护' },
+    data: areas
+  };
+}